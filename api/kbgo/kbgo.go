@@ -16,9 +16,14 @@ type IKbgoV1 interface {
 	// Document related interfaces
 	DocumentsList(ctx context.Context, req *v1.DocumentsListReq) (res *v1.DocumentsListRes, err error)
 	DocumentsDelete(ctx context.Context, req *v1.DocumentsDeleteReq) (res *v1.DocumentsDeleteRes, err error)
+	DocumentsUpdateValidity(ctx context.Context, req *v1.DocumentsUpdateValidityReq) (res *v1.DocumentsUpdateValidityRes, err error)
+	DocumentsVersions(ctx context.Context, req *v1.DocumentsVersionsReq) (res *v1.DocumentsVersionsRes, err error)
 
 	// Indexing related interfaces
 	IndexDocuments(ctx context.Context, req *v1.IndexDocumentsReq) (res *v1.IndexDocumentsRes, err error)
+	ParserHealth(ctx context.Context, req *v1.ParserHealthReq) (res *v1.ParserHealthRes, err error)
+	IngestDeadLetterList(ctx context.Context, req *v1.IngestDeadLetterListReq) (res *v1.IngestDeadLetterListRes, err error)
+	IngestDeadLetterRequeue(ctx context.Context, req *v1.IngestDeadLetterRequeueReq) (res *v1.IngestDeadLetterRequeueRes, err error)
 
 	// Chunk related interfaces
 	ChunksList(ctx context.Context, req *v1.ChunksListReq) (res *v1.ChunksListRes, err error)
@@ -29,6 +34,12 @@ type IKbgoV1 interface {
 	KBGetList(ctx context.Context, req *v1.KBGetListReq) (res *v1.KBGetListRes, err error)
 	KBCreate(ctx context.Context, req *v1.KBCreateReq) (res *v1.KBCreateRes, err error)
 	KBDelete(ctx context.Context, req *v1.KBDeleteReq) (res *v1.KBDeleteRes, err error)
+	KBIngestHooksGet(ctx context.Context, req *v1.KBIngestHooksGetReq) (res *v1.KBIngestHooksGetRes, err error)
+	KBIngestHooksUpdate(ctx context.Context, req *v1.KBIngestHooksUpdateReq) (res *v1.KBIngestHooksUpdateRes, err error)
+	KBIngestHooksVersions(ctx context.Context, req *v1.KBIngestHooksVersionsReq) (res *v1.KBIngestHooksVersionsRes, err error)
+	KBIngestHooksRollback(ctx context.Context, req *v1.KBIngestHooksRollbackReq) (res *v1.KBIngestHooksRollbackRes, err error)
+	KBAdvice(ctx context.Context, req *v1.KBAdviceReq) (res *v1.KBAdviceRes, err error)
+	KBAdviceApply(ctx context.Context, req *v1.KBAdviceApplyReq) (res *v1.KBAdviceApplyRes, err error)
 
 	// Upload related interfaces
 	UploadFile(ctx context.Context, req *v1.UploadFileReq) (res *v1.UploadFileRes, err error)
@@ -42,11 +53,114 @@ type IKbgoV1 interface {
 	MCPRegistryDelete(ctx context.Context, req *v1.MCPRegistryDeleteReq) (res *v1.MCPRegistryDeleteRes, err error)
 	MCPRegistryGetOne(ctx context.Context, req *v1.MCPRegistryGetOneReq) (res *v1.MCPRegistryGetOneRes, err error)
 	MCPRegistryGetList(ctx context.Context, req *v1.MCPRegistryGetListReq) (res *v1.MCPRegistryGetListRes, err error)
+	MCPVerifyTools(ctx context.Context, req *v1.MCPVerifyToolsReq) (res *v1.MCPVerifyToolsRes, err error)
 
 	// Model management interfaces
 	ReloadModels(ctx context.Context, req *v1.ReloadModelsReq) (res *v1.ReloadModelsRes, err error)
 	ListModels(ctx context.Context, req *v1.ListModelsReq) (res *v1.ListModelsRes, err error)
 	GetModel(ctx context.Context, req *v1.GetModelReq) (res *v1.GetModelRes, err error)
+	OllamaModelList(ctx context.Context, req *v1.OllamaModelListReq) (res *v1.OllamaModelListRes, err error)
 	ChatCompletion(ctx context.Context, req *v1.ChatCompletionReq) (res *v1.ChatCompletionRes, err error)
 	EmbeddingCompletion(ctx context.Context, req *v1.EmbeddingReq) (res *v1.EmbeddingRes, err error)
+	Embeddings(ctx context.Context, req *v1.EmbeddingsReq) (res *v1.EmbeddingsRes, err error)
+	ChatCompletions(ctx context.Context, req *v1.ChatCompletionsReq) (res *v1.ChatCompletionRes, err error)
+	ReembedStart(ctx context.Context, req *v1.ReembedStartReq) (res *v1.ReembedStartRes, err error)
+	ReembedJobList(ctx context.Context, req *v1.ReembedJobListReq) (res *v1.ReembedJobListRes, err error)
+	ReembedJobGet(ctx context.Context, req *v1.ReembedJobGetReq) (res *v1.ReembedJobGetRes, err error)
+	ReembedJobPause(ctx context.Context, req *v1.ReembedJobPauseReq) (res *v1.ReembedJobPauseRes, err error)
+	ReembedJobResume(ctx context.Context, req *v1.ReembedJobResumeReq) (res *v1.ReembedJobResumeRes, err error)
+
+	// Analytics interfaces
+	AnalyticsUsage(ctx context.Context, req *v1.AnalyticsUsageReq) (res *v1.AnalyticsUsageRes, err error)
+	AnalyticsTools(ctx context.Context, req *v1.AnalyticsToolsReq) (res *v1.AnalyticsToolsRes, err error)
+	AnalyticsUnanswered(ctx context.Context, req *v1.AnalyticsUnansweredReq) (res *v1.AnalyticsUnansweredRes, err error)
+	AnalyticsRollup(ctx context.Context, req *v1.AnalyticsRollupReq) (res *v1.AnalyticsRollupRes, err error)
+	KnowledgeGapRun(ctx context.Context, req *v1.KnowledgeGapRunReq) (res *v1.KnowledgeGapRunRes, err error)
+	KnowledgeGapList(ctx context.Context, req *v1.KnowledgeGapListReq) (res *v1.KnowledgeGapListRes, err error)
+	FinetuneExport(ctx context.Context, req *v1.FinetuneExportReq) (res *v1.FinetuneExportRes, err error)
+
+	// Experiment interfaces
+	ExperimentList(ctx context.Context, req *v1.ExperimentListReq) (res *v1.ExperimentListRes, err error)
+	ExperimentMetrics(ctx context.Context, req *v1.ExperimentMetricsReq) (res *v1.ExperimentMetricsRes, err error)
+
+	// Shadow interfaces
+	ShadowResultList(ctx context.Context, req *v1.ShadowResultListReq) (res *v1.ShadowResultListRes, err error)
+	ShadowSummary(ctx context.Context, req *v1.ShadowSummaryReq) (res *v1.ShadowSummaryRes, err error)
+
+	// Conversation interfaces
+	ConversationDelete(ctx context.Context, req *v1.ConversationDeleteReq) (res *v1.ConversationDeleteRes, err error)
+	ConversationModelUpdate(ctx context.Context, req *v1.ConversationModelUpdateReq) (res *v1.ConversationModelUpdateRes, err error)
+	ConversationExport(ctx context.Context, req *v1.ConversationExportReq) (res *v1.ConversationExportRes, err error)
+	MessageFeedback(ctx context.Context, req *v1.MessageFeedbackReq) (res *v1.MessageFeedbackRes, err error)
+	MessageDiff(ctx context.Context, req *v1.MessageDiffReq) (res *v1.MessageDiffRes, err error)
+	AnswerDiffList(ctx context.Context, req *v1.AnswerDiffListReq) (res *v1.AnswerDiffListRes, err error)
+	MessageSaverStats(ctx context.Context, req *v1.MessageSaverStatsReq) (res *v1.MessageSaverStatsRes, err error)
+	WorkspaceList(ctx context.Context, req *v1.WorkspaceListReq) (res *v1.WorkspaceListRes, err error)
+	WorkspaceFileDelete(ctx context.Context, req *v1.WorkspaceFileDeleteReq) (res *v1.WorkspaceFileDeleteRes, err error)
+	ConversationParticipantList(ctx context.Context, req *v1.ConversationParticipantListReq) (res *v1.ConversationParticipantListRes, err error)
+	ConversationParticipantSave(ctx context.Context, req *v1.ConversationParticipantSaveReq) (res *v1.ConversationParticipantSaveRes, err error)
+	ConversationParticipantRemove(ctx context.Context, req *v1.ConversationParticipantRemoveReq) (res *v1.ConversationParticipantRemoveRes, err error)
+	ConversationEvents(ctx context.Context, req *v1.ConversationEventsReq) (res *v1.ConversationEventsRes, err error)
+	PIIScrubRun(ctx context.Context, req *v1.PIIScrubRunReq) (res *v1.PIIScrubRunRes, err error)
+	PIIScrubLogList(ctx context.Context, req *v1.PIIScrubLogListReq) (res *v1.PIIScrubLogListRes, err error)
+
+	// Handoff interfaces
+	HandoffTicketList(ctx context.Context, req *v1.HandoffTicketListReq) (res *v1.HandoffTicketListRes, err error)
+	HandoffTicketGet(ctx context.Context, req *v1.HandoffTicketGetReq) (res *v1.HandoffTicketGetRes, err error)
+	HandoffMessage(ctx context.Context, req *v1.HandoffMessageReq) (res *v1.HandoffMessageRes, err error)
+	HandoffResolve(ctx context.Context, req *v1.HandoffResolveReq) (res *v1.HandoffResolveRes, err error)
+	HandoffStream(ctx context.Context, req *v1.HandoffStreamReq) (res *v1.HandoffStreamRes, err error)
+
+	// Promotion interfaces
+	PromoteMessage(ctx context.Context, req *v1.PromoteMessageReq) (res *v1.PromoteMessageRes, err error)
+	PromotionList(ctx context.Context, req *v1.PromotionListReq) (res *v1.PromotionListRes, err error)
+	PromotionApprove(ctx context.Context, req *v1.PromotionApproveReq) (res *v1.PromotionApproveRes, err error)
+	PromotionReject(ctx context.Context, req *v1.PromotionRejectReq) (res *v1.PromotionRejectRes, err error)
+
+	// Image interfaces
+	ImageGet(ctx context.Context, req *v1.ImageGetReq) (res *v1.ImageGetRes, err error)
+
+	// Persona interfaces
+	PersonaCreate(ctx context.Context, req *v1.PersonaCreateReq) (res *v1.PersonaCreateRes, err error)
+	PersonaUpdate(ctx context.Context, req *v1.PersonaUpdateReq) (res *v1.PersonaUpdateRes, err error)
+	PersonaDelete(ctx context.Context, req *v1.PersonaDeleteReq) (res *v1.PersonaDeleteRes, err error)
+	PersonaGet(ctx context.Context, req *v1.PersonaGetReq) (res *v1.PersonaGetRes, err error)
+	PersonaList(ctx context.Context, req *v1.PersonaListReq) (res *v1.PersonaListRes, err error)
+
+	// Retrieval view interfaces
+	RetrievalViewCreate(ctx context.Context, req *v1.RetrievalViewCreateReq) (res *v1.RetrievalViewCreateRes, err error)
+	RetrievalViewUpdate(ctx context.Context, req *v1.RetrievalViewUpdateReq) (res *v1.RetrievalViewUpdateRes, err error)
+	RetrievalViewDelete(ctx context.Context, req *v1.RetrievalViewDeleteReq) (res *v1.RetrievalViewDeleteRes, err error)
+	RetrievalViewGet(ctx context.Context, req *v1.RetrievalViewGetReq) (res *v1.RetrievalViewGetRes, err error)
+	RetrievalViewList(ctx context.Context, req *v1.RetrievalViewListReq) (res *v1.RetrievalViewListRes, err error)
+
+	// Tool example interfaces
+	ToolExampleCreate(ctx context.Context, req *v1.ToolExampleCreateReq) (res *v1.ToolExampleCreateRes, err error)
+	ToolExampleUpdate(ctx context.Context, req *v1.ToolExampleUpdateReq) (res *v1.ToolExampleUpdateRes, err error)
+	ToolExampleDelete(ctx context.Context, req *v1.ToolExampleDeleteReq) (res *v1.ToolExampleDeleteRes, err error)
+	ToolExampleList(ctx context.Context, req *v1.ToolExampleListReq) (res *v1.ToolExampleListRes, err error)
+	ToolExampleTest(ctx context.Context, req *v1.ToolExampleTestReq) (res *v1.ToolExampleTestRes, err error)
+	ToolConfigValidate(ctx context.Context, req *v1.ToolConfigValidateReq) (res *v1.ToolConfigValidateRes, err error)
+
+	// Project interfaces
+	ProjectCreate(ctx context.Context, req *v1.ProjectCreateReq) (res *v1.ProjectCreateRes, err error)
+	ProjectUpdate(ctx context.Context, req *v1.ProjectUpdateReq) (res *v1.ProjectUpdateRes, err error)
+	ProjectDelete(ctx context.Context, req *v1.ProjectDeleteReq) (res *v1.ProjectDeleteRes, err error)
+	ProjectGet(ctx context.Context, req *v1.ProjectGetReq) (res *v1.ProjectGetRes, err error)
+	ProjectList(ctx context.Context, req *v1.ProjectListReq) (res *v1.ProjectListRes, err error)
+	ProjectResourceAdd(ctx context.Context, req *v1.ProjectResourceAddReq) (res *v1.ProjectResourceAddRes, err error)
+	ProjectResourceRemove(ctx context.Context, req *v1.ProjectResourceRemoveReq) (res *v1.ProjectResourceRemoveRes, err error)
+	ProjectResourceList(ctx context.Context, req *v1.ProjectResourceListReq) (res *v1.ProjectResourceListRes, err error)
+	ProjectMemberSave(ctx context.Context, req *v1.ProjectMemberSaveReq) (res *v1.ProjectMemberSaveRes, err error)
+	ProjectMemberRemove(ctx context.Context, req *v1.ProjectMemberRemoveReq) (res *v1.ProjectMemberRemoveRes, err error)
+	ProjectUsage(ctx context.Context, req *v1.ProjectUsageReq) (res *v1.ProjectUsageRes, err error)
+
+	// Usage interfaces
+	Usage(ctx context.Context, req *v1.UsageReq) (res *v1.UsageRes, err error)
+	UsageRollup(ctx context.Context, req *v1.UsageRollupReq) (res *v1.UsageRollupRes, err error)
+
+	// Feature flag interfaces
+	FeatureFlagList(ctx context.Context, req *v1.FeatureFlagListReq) (res *v1.FeatureFlagListRes, err error)
+	FeatureFlagSet(ctx context.Context, req *v1.FeatureFlagSetReq) (res *v1.FeatureFlagSetRes, err error)
+	FeatureFlagReset(ctx context.Context, req *v1.FeatureFlagResetReq) (res *v1.FeatureFlagResetRes, err error)
 }
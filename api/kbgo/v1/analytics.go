@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// AnalyticsUsageReq 会话使用统计请求（按天、按模型）
+type AnalyticsUsageReq struct {
+	g.Meta    `path:"/v1/analytics/usage" method:"get" tags:"analytics" summary:"Get daily conversation usage per model"`
+	StartDate string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate   string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+	ModelName string `json:"model_name" dc:"Model name filter (optional)"`
+}
+
+type AnalyticsUsageRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*AnalyticsUsageItem `json:"list" dc:"Daily usage rows"`
+}
+
+// AnalyticsUsageItem 单日单模型的统计数据
+type AnalyticsUsageItem struct {
+	StatDate          string  `json:"stat_date"`
+	ModelName         string  `json:"model_name"`
+	ConversationCount int64   `json:"conversation_count"`
+	MessageCount      int64   `json:"message_count"`
+	AssistantCount    int64   `json:"assistant_count"`
+	TotalTokens       int64   `json:"total_tokens"`
+	AvgLatencyMs      float64 `json:"avg_latency_ms"`
+	ToolCallCount     int64   `json:"tool_call_count"`
+	PositiveFeedback  int64   `json:"positive_feedback"`
+	NegativeFeedback  int64   `json:"negative_feedback"`
+	SatisfactionRatio float64 `json:"satisfaction_ratio"` // positive / (positive + negative)，无反馈时为 0
+}
+
+// AnalyticsToolsReq 工具使用统计请求
+type AnalyticsToolsReq struct {
+	g.Meta    `path:"/v1/analytics/tools" method:"get" tags:"analytics" summary:"Get MCP tool usage breakdown"`
+	StartDate string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate   string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+}
+
+type AnalyticsToolsRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*AnalyticsToolItem `json:"list" dc:"Tool usage rows, sorted by call count"`
+}
+
+// AnalyticsToolItem 单个工具在区间内的统计数据
+type AnalyticsToolItem struct {
+	ServiceName string  `json:"service_name"`
+	ToolName    string  `json:"tool_name"`
+	CallCount   int64   `json:"call_count"`
+	FailedCount int64   `json:"failed_count"`
+	AvgDuration float64 `json:"avg_duration"` // 毫秒
+}
+
+// AnalyticsUnansweredReq 未解答问题（低分检索）统计请求
+type AnalyticsUnansweredReq struct {
+	g.Meta      `path:"/v1/analytics/unanswered" method:"get" tags:"analytics" summary:"Get top unanswered questions"`
+	StartDate   string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate     string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+	KnowledgeId string `json:"knowledge_id" dc:"Knowledge base ID filter (optional)"`
+	Limit       int    `json:"limit" v:"min:1|max:200" d:"20" dc:"Max number of questions"`
+}
+
+type AnalyticsUnansweredRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*AnalyticsUnansweredItem `json:"list" dc:"Questions sorted by miss count"`
+}
+
+// AnalyticsUnansweredItem 未解答问题统计
+type AnalyticsUnansweredItem struct {
+	KnowledgeId string  `json:"knowledge_id"`
+	Question    string  `json:"question"`
+	MissCount   int64   `json:"miss_count"`
+	MaxScore    float64 `json:"max_score"`
+}
+
+// AnalyticsRollupReq 手动触发指定日期的汇总计算
+type AnalyticsRollupReq struct {
+	g.Meta `path:"/v1/analytics/rollup" method:"post" tags:"analytics" summary:"Rebuild analytics rollups for a date"`
+	Date   string `json:"date" v:"required|date-format:Y-m-d" dc:"Date to rebuild (yyyy-MM-dd)"`
+}
+
+type AnalyticsRollupRes struct {
+	g.Meta  `mime:"application/json"`
+	Success bool `json:"success"`
+}
+
+// KnowledgeGapRunReq 手动触发知识缺口挖掘
+type KnowledgeGapRunReq struct {
+	g.Meta     `path:"/v1/analytics/knowledge-gaps/run" method:"post" tags:"analytics" summary:"Run knowledge gap mining job"`
+	WindowDays int `json:"window_days" v:"min:0|max:90" dc:"Look-back window in days (default from config, 7)"`
+}
+
+type KnowledgeGapRunRes struct {
+	g.Meta   `mime:"application/json"`
+	BatchId  string `json:"batch_id" dc:"Generated report batch ID, empty when there is nothing to report"`
+	GapCount int    `json:"gap_count" dc:"Number of knowledge gaps found"`
+}
+
+// KnowledgeGapListReq 查询知识缺口报告
+type KnowledgeGapListReq struct {
+	g.Meta      `path:"/v1/analytics/knowledge-gaps" method:"get" tags:"analytics" summary:"List knowledge gap reports"`
+	BatchId     string `json:"batch_id" dc:"Report batch ID (default: latest batch)"`
+	KnowledgeId string `json:"knowledge_id" dc:"Knowledge base ID filter (optional)"`
+}
+
+type KnowledgeGapListRes struct {
+	g.Meta  `mime:"application/json"`
+	BatchId string              `json:"batch_id"`
+	List    []*KnowledgeGapItem `json:"list" dc:"Gaps sorted by question count"`
+}
+
+// KnowledgeGapItem 知识缺口
+type KnowledgeGapItem struct {
+	KnowledgeId      string   `json:"knowledge_id"`
+	Topic            string   `json:"topic"`             // 代表性问题
+	QuestionCount    int      `json:"question_count"`    // 问题总数
+	LowScoreCount    int      `json:"low_score_count"`   // 低分检索问题数
+	NegativeCount    int      `json:"negative_count"`    // 负反馈问题数
+	ExampleQuestions []string `json:"example_questions"` // 示例问题
+	PeriodStart      string   `json:"period_start"`
+	PeriodEnd        string   `json:"period_end"`
+}
+
+// FinetuneExportReq 导出嵌入模型微调数据：从点击、反馈日志和检索轨迹中组装 (查询, 正例分片, 负例分片) 三元组
+type FinetuneExportReq struct {
+	g.Meta       `path:"/v1/analytics/finetune/export" method:"get" tags:"analytics" summary:"Export embedding fine-tune triplets as JSONL"`
+	StartDate    string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate      string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+	KnowledgeId  string `json:"knowledge_id" dc:"Only export chunks of this knowledge base (optional)"`
+	Format       string `json:"format" v:"in:sentence-transformers,bge" d:"sentence-transformers" dc:"sentence-transformers: {anchor,positive,negative} per line; bge: {query,pos,neg} per line"`
+	MaxNegatives int    `json:"max_negatives" v:"min:1|max:20" d:"3" dc:"Maximum hard negatives per positive"`
+}
+
+// FinetuneExportRes 响应体为 JSONL 文件（每行一条训练样本），不使用统一 JSON 响应结构
+type FinetuneExportRes struct {
+	g.Meta `mime:"application/x-ndjson"`
+}
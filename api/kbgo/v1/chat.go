@@ -3,34 +3,120 @@ package v1
 import (
 	"mime/multipart"
 
+	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
 type ChatReq struct {
-	g.Meta           `path:"/v1/chat" method:"post" tags:"retriever" mime:"multipart/form-data"`
-	ConvID           string                  `json:"conv_id" v:"required"` // 会话id
-	Question         string                  `json:"question" v:"required"`
-	ModelID          string                  `json:"model_id" v:"required"` // LLM模型UUID（必填）
-	EmbeddingModelID string                  `json:"embedding_model_id"`    // Embedding模型UUID（可选，启用检索器时需要）
-	RerankModelID    string                  `json:"rerank_model_id"`       // Rerank模型UUID（可选，仅在使用rerank或rrf检索模式时需要）
-	KnowledgeId      string                  `json:"knowledge_id"`
-	EnableRetriever  bool                    `json:"enable_retriever"`  // Whether to enable knowledge base retrieval
-	TopK             int                     `json:"top_k"`             // 默认为5
-	Score            float64                 `json:"score"`             // 默认为0.2 （默认是rrf检索模式，相似度分数不重要）
-	RetrieveMode     string                  `json:"retrieve_mode"`     // 检索模式: milvus/rerank/rrf (默认rerank)
-	UseMCP           bool                    `json:"use_mcp"`           // 是否使用MCP
-	MCPServiceTools  map[string][]string     `json:"mcp_service_tools"` // 按服务指定允许调用的MCP工具列表
-	Stream           bool                    `json:"stream"`            // 是否流式返回
-	JsonFormat       bool                    `json:"jsonformat"`        // 是否需要JSON格式化输出
-	Files            []*multipart.FileHeader `json:"files" type:"file"` // 上传的多模态文件（图片、音频、视频）
+	g.Meta             `path:"/v1/chat" method:"post" tags:"retriever" mime:"multipart/form-data" x-sse-events:"stream 为 true 时返回 text/event-stream，每行一个事件（名称:JSON）：parse_progress（上传文档的解析进度，逐个文件，支持时逐页）、tool_progress（耗时工具执行进度）、documents（参考文档）、reasoning（推理内容 reasoning_content，按可见性策略发送）、data（回答增量 content）、confidence（回答置信度）、citations（回答引用的分片，chat.references.format 为 json 时发送）、follow_up（推荐追问）、latency_budget（指定延迟预算时返回预算使用情况和已执行的降级措施）、agent_summary（use_mcp 为 true 时工具调用结束后发送执行摘要：调用的工具、用时、返回行数、生成的文件和消耗的 token）、model_fallback（回答模型调用失败改用备用模型时发送切换记录）、correction（capture_corrections 为 true 且本轮问题被识别为更正时发送待审核的更正申请ID correction_id），以 data:[DONE] 结束；出错时发送 event: error。开始时发送 retry 字段（EventSource 重连等待毫秒数，sse.retryMs），空闲（检索、工具调用、等待首个 token）达到 sse.heartbeatInterval 时发送注释行 : ping 作为心跳，客户端应忽略以冒号开头的行"`
+	ConvID             string                  `json:"conv_id" v:"required"` // 会话id
+	UserID             string                  `json:"user_id"`              // 提问的用户ID（可选），共享会话中必须是可发送消息的参与者，记录为用户消息的发送者
+	Question           string                  `json:"question" v:"required"`
+	ModelID            string                  `json:"model_id"`           // LLM模型UUID（为空时使用会话保存的模型，与会话模型不同时切换会话模型）
+	EmbeddingModelID   string                  `json:"embedding_model_id"` // Embedding模型UUID（可选，启用检索器时需要）
+	RerankModelID      string                  `json:"rerank_model_id"`    // Rerank模型UUID（可选，仅在使用rerank或rrf检索模式时需要）
+	KnowledgeId        string                  `json:"knowledge_id"`
+	EnableRetriever    bool                    `json:"enable_retriever"`                                 // Whether to enable knowledge base retrieval
+	TopK               int                     `json:"top_k"`                                            // 默认为5
+	Score              float64                 `json:"score"`                                            // 默认为0.2 （默认是rrf检索模式，相似度分数不重要）
+	RetrieveMode       string                  `json:"retrieve_mode"`                                    // 检索模式: milvus/rerank/rrf/hybrid（关键词+向量 RRF 融合，不需要 rerank 模型）(默认rerank)
+	UseMCP             bool                    `json:"use_mcp"`                                          // 是否使用MCP
+	MCPServiceTools    map[string][]string     `json:"mcp_service_tools"`                                // 按服务指定允许调用的MCP工具列表
+	MaxToolIterations  int                     `json:"max_tool_iterations" v:"min:0"`                    // 工具调用最大轮数（可选，为 0 时使用项目默认值或 toolExecution.maxIterations 配置，不超过 toolExecution.maxIterationsLimit）
+	Stream             bool                    `json:"stream"`                                           // 是否流式返回
+	JsonFormat         bool                    `json:"jsonformat"`                                       // 是否需要JSON格式化输出
+	ResponseStyle      string                  `json:"response_style" v:"in:concise,detailed"`           // 回答风格: concise/detailed（可选）
+	OutputFormat       string                  `json:"output_format" v:"in:markdown,plain,bullet,table"` // 输出格式: markdown/plain/bullet/table（可选）
+	Language           string                  `json:"language"`                                         // 回答目标语言，如 zh/en/ja（可选）
+	PersonaID          string                  `json:"persona_id"`                                       // 人设ID（可选，为空时使用模型 extra.personaID 或 persona.default 配置的默认人设）
+	ProjectID          string                  `json:"project_id"`                                       // 项目ID（可选，为空时使用知识库所属项目，需是项目成员），未指定的参数使用项目默认设置
+	EnableFollowUp     bool                    `json:"enable_follow_up"`                                 // 是否在回答后生成推荐追问
+	RequestHuman       bool                    `json:"request_human"`                                    // 是否请求转人工客服（启用 handoff 配置时有效）
+	LatencyBudgetMs    int                     `json:"latency_budget_ms" v:"min:0"`                      // 延迟预算（毫秒，可选，为 0 时使用 budget.defaultMs 配置），剩余时间不足时依次跳过查询重写、减少 TopK、跳过重排、限制工具调用轮数
+	CaptureCorrections bool                    `json:"capture_corrections"`                              // 是否捕获用户对上一条回答的更正（如"其实保修期是3年"），作为待审核的知识库条目提交到 /v1/promotions 审核队列（需指定 knowledge_id）
+	RetrievalView      string                  `json:"retrieval_view"`                                   // 检索视图名称或ID（可选），引用时启用检索，未指定的知识库、模型和检索参数使用视图设置
+	RegenerateMsgID    string                  `json:"regenerate_msg_id"`                                // 重新生成的助手消息ID（可选），新回答保存为新消息后与原回答对比，差异通过 /v1/messages/{msg_id}/diffs 查询
+	Files              []*multipart.FileHeader `json:"files" type:"file"`                                // 上传的多模态文件（图片、音频、视频）
 }
 
 type ChatRes struct {
-	g.Meta     `mime:"application/json"`
-	Answer     string             `json:"answer"`
-	References []*schema.Document `json:"references"`
-	MCPResults []*MCPResult       `json:"mcp_results,omitempty"`
+	g.Meta            `mime:"application/json"`
+	Answer            string                 `json:"answer"`
+	ReasoningContent  string                 `json:"reasoning_content,omitempty"` // 推理模型的思考过程，按 reasoning.policy 可见性策略返回（默认不返回）
+	References        []*schema.Document     `json:"references"`
+	MCPResults        []*MCPResult           `json:"mcp_results,omitempty"`
+	FollowUpQuestions []string               `json:"follow_up_questions,omitempty"` // 推荐追问（enable_follow_up 为 true 时返回）
+	Confidence        *AnswerConfidence      `json:"confidence,omitempty"`          // 回答置信度（启用 confidence 配置时返回）
+	Handoff           *HandoffTicketItem     `json:"handoff,omitempty"`             // 会话已转人工时返回工单，此时回答为转接提示
+	CannedAnswer      *CannedAnswer          `json:"canned_answer,omitempty"`       // 问题与已审核问答几乎相同时返回来源，此时回答为预置回答，未调用模型
+	Citations         []*Citation            `json:"citations,omitempty"`           // 回答引用的分片（chat.references.format 为 json 时返回），回答中的标注已替换为 [n]
+	LatencyBudget     *LatencyBudget         `json:"latency_budget,omitempty"`      // 延迟预算使用情况（指定延迟预算时返回）
+	CorrectionID      string                 `json:"correction_id,omitempty"`       // 本轮问题被识别为更正时返回待审核的更正申请ID（capture_corrections 为 true 时）
+	AgentSummary      *AgentRunSummary       `json:"agent_summary,omitempty"`       // 工具调用执行摘要（use_mcp 为 true 且执行了工具调用时返回）
+	ModelFallbacks    []*model.FallbackEvent `json:"model_fallbacks,omitempty"`     // 模型调用失败或被限流后改用备用模型（extra.fallbackModels）时返回请求的模型、实际使用的模型和失败原因
+}
+
+// AgentRunSummary 一次工具调用执行（agent run）的摘要，同时保存在助手消息元数据的 agent_run 字段，供前端展示"agent 做了什么"
+type AgentRunSummary struct {
+	Iterations     int             `json:"iterations"`                // 模型调用轮数
+	DurationMs     int64           `json:"duration_ms"`               // 工具调用阶段总用时
+	TokensUsed     int             `json:"tokens_used"`               // 工具调用阶段模型消耗的 token
+	PromptTokens   []int           `json:"prompt_tokens,omitempty"`   // 各轮模型调用的输入 token 估算值
+	StopReason     string          `json:"stop_reason,omitempty"`     // 提前结束工具调用的原因：max_iterations / timeout / latency_budget / context_window / prompt_budget / cost_budget / loop_detected
+	RowsReturned   int             `json:"rows_returned"`             // 工具返回的数据行数合计（只统计能识别行数的 JSON 数组和表格结果）
+	FilesGenerated []string        `json:"files_generated,omitempty"` // 写入会话工作区的文件
+	Tools          []*AgentToolRun `json:"tools"`                     // 按调用顺序排列的工具调用
+}
+
+// AgentToolRun 一次工具调用
+type AgentToolRun struct {
+	ServiceName string `json:"service_name"`
+	ToolName    string `json:"tool_name"`
+	Status      string `json:"status"` // success / error / denied / timeout
+	DurationMs  int64  `json:"duration_ms"`
+	Rows        int    `json:"rows,omitempty"`  // 返回的数据行数
+	Error       string `json:"error,omitempty"` // 失败或被策略禁止的原因
+}
+
+// LatencyBudget 延迟预算使用情况
+type LatencyBudget struct {
+	BudgetMs     int64    `json:"budget_ms"`
+	ElapsedMs    int64    `json:"elapsed_ms"`   // 从收到请求到回答结束的用时
+	Degradations []string `json:"degradations"` // 已执行的降级措施：skip_rewrite、reduce_top_k、skip_rerank、cap_tool_iterations
+}
+
+// Citation 回答引用的参考分片，Index 对应回答中的 [n] 编号
+type Citation struct {
+	Index      int      `json:"index"`
+	ChunkID    string   `json:"chunk_id"`
+	DocumentID string   `json:"document_id,omitempty"`
+	Title      string   `json:"title,omitempty"`  // 分片所在章节标题
+	Source     string   `json:"source,omitempty"` // 来源文档
+	Score      float32  `json:"score,omitempty"`
+	Markers    []string `json:"markers"` // 模型输出的原始标注，如 [ref:分片ID]；流式回答不改写已发送内容，客户端按此替换为编号
+}
+
+// CannedAnswer 预置回答的来源：命中的已审核问答及其在知识库中的分片
+type CannedAnswer struct {
+	PromotionID     string  `json:"promotion_id"`
+	KnowledgeID     string  `json:"knowledge_id"`
+	DocumentID      string  `json:"document_id"`
+	ChunkID         string  `json:"chunk_id"`
+	MatchedQuestion string  `json:"matched_question"` // 命中的已审核问题
+	Score           float64 `json:"score"`            // 问题相似度
+	Reviewer        string  `json:"reviewer,omitempty"`
+}
+
+// AnswerConfidence 回答置信度，由检索得分、回答与参考资料的一致性和 token 概率加权得到
+// 各分项取值 0-1，不可用的分项为空且不参与加权
+type AnswerConfidence struct {
+	Score        float64  `json:"score"`                  // 综合置信度
+	Level        string   `json:"level"`                  // high / medium / low
+	Retrieval    *float64 `json:"retrieval,omitempty"`    // 检索得分
+	Groundedness *float64 `json:"groundedness,omitempty"` // 回答中能在参考资料中找到依据的句子比例
+	TokenProb    *float64 `json:"token_prob,omitempty"`   // 平均 token 概率（模型返回 logprobs 时可用）
+	Escalated    bool     `json:"escalated,omitempty"`    // 是否低于阈值并触发了升级通知
 }
 
 type MCPResult struct {
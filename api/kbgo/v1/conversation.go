@@ -0,0 +1,219 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ConversationDeleteReq 删除会话（同时删除消息和会话工作区）
+type ConversationDeleteReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}" method:"delete" tags:"conversation" summary:"Delete a conversation with its messages and workspace"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+}
+
+type ConversationDeleteRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ConversationModelUpdateReq 切换会话使用的模型，对后续轮次生效（历史消息会按新模型的能力转换）
+type ConversationModelUpdateReq struct {
+	g.Meta  `path:"/v1/conversations/{conv_id}/model" method:"put" tags:"conversation" summary:"Switch the chat model of a conversation"`
+	ConvID  string `json:"conv_id" v:"required" dc:"Conversation ID"`
+	ModelID string `json:"model_id" v:"required" dc:"LLM or multimodal model UUID"`
+}
+
+type ConversationModelUpdateRes struct {
+	g.Meta    `mime:"application/json"`
+	ConvID    string `json:"conv_id" dc:"Conversation ID"`
+	ModelID   string `json:"model_id" dc:"Model UUID"`
+	ModelName string `json:"model_name" dc:"Model name"`
+}
+
+// ConversationExportReq 把会话导出为 PDF 或 DOCX 报告（消息、引用的参考资料、图片和工具调用摘要）
+type ConversationExportReq struct {
+	g.Meta           `path:"/v1/conversations/{conv_id}/export" method:"get" tags:"conversation" summary:"Export a conversation as a PDF or DOCX report"`
+	ConvID           string `json:"conv_id" v:"required" dc:"Conversation ID"`
+	Format           string `json:"format" v:"in:pdf,docx" d:"pdf" dc:"pdf or docx"`
+	IncludeCitations bool   `json:"include_citations" d:"true" dc:"List the reference chunks cited by each answer"`
+	IncludeImages    bool   `json:"include_images" d:"true" dc:"Embed images attached to messages"`
+	IncludeTools     bool   `json:"include_tools" d:"true" dc:"Append a summary of the tool calls made in the conversation"`
+	UserID           string `json:"user_id" dc:"Requesting user, required for shared conversations"`
+}
+
+// ConversationExportRes 响应体为 PDF 或 DOCX 文件，不使用统一 JSON 响应结构
+type ConversationExportRes struct {
+	g.Meta `mime:"application/octet-stream"`
+}
+
+// MessageFeedbackReq 记录用户对助手消息的反馈和点击的参考分片（用于满意度统计和导出微调数据）
+type MessageFeedbackReq struct {
+	g.Meta          `path:"/v1/messages/{msg_id}/feedback" method:"post" tags:"conversation" summary:"Record feedback and clicked chunks of an assistant message"`
+	MsgID           string   `json:"msg_id" v:"required" dc:"Assistant message ID"`
+	Feedback        string   `json:"feedback" v:"in:positive,negative" dc:"positive or negative, empty keeps the current feedback"`
+	ClickedChunkIDs []string `json:"clicked_chunk_ids" dc:"IDs of reference chunks the user clicked, appended to previous clicks"`
+}
+
+type MessageFeedbackRes struct {
+	g.Meta          `mime:"application/json"`
+	MsgID           string   `json:"msg_id"`
+	Feedback        string   `json:"feedback"`
+	ClickedChunkIDs []string `json:"clicked_chunk_ids"`
+}
+
+// MessageDiffReq 查询助手消息作为原回答或重新生成的回答参与的差异
+type MessageDiffReq struct {
+	g.Meta `path:"/v1/messages/{msg_id}/diffs" method:"get" tags:"conversation" summary:"Get diffs between a message and its regenerated answers"`
+	MsgID  string `json:"msg_id" v:"required" dc:"Assistant message ID"`
+}
+
+type MessageDiffRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*AnswerDiffItem `json:"list" dc:"Diffs sorted by create time"`
+}
+
+// AnswerDiffListReq 分页查询回答差异（重新生成的回答和影子评估的候选回答）
+type AnswerDiffListReq struct {
+	g.Meta         `path:"/v1/answer-diffs" method:"get" tags:"conversation" summary:"List answer diffs of regenerated messages and shadow evaluations"`
+	ConvID         string `json:"conv_id" dc:"Conversation ID filter (optional)"`
+	Source         string `json:"source" v:"in:regenerate,shadow" dc:"Source filter: regenerate or shadow (optional)"`
+	ShadowResultID string `json:"shadow_result_id" dc:"Shadow result ID filter (optional)"`
+	Page           int    `json:"page" v:"min:1" d:"1" dc:"Page number"`
+	PageSize       int    `json:"page_size" v:"min:1|max:100" d:"20" dc:"Page size"`
+}
+
+type AnswerDiffListRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*AnswerDiffItem `json:"list" dc:"Diffs sorted by create time, newest first"`
+	Total  int64             `json:"total"`
+	Page   int               `json:"page"`
+}
+
+// AnswerDiffItem 新旧两个回答的句子级差异
+type AnswerDiffItem struct {
+	Id             string               `json:"id"`
+	ConvID         string               `json:"conv_id"`
+	Source         string               `json:"source"`                     // regenerate / shadow
+	BaseMsgID      string               `json:"base_msg_id,omitempty"`      // 原回答的消息ID
+	MsgID          string               `json:"msg_id,omitempty"`           // 重新生成的回答的消息ID
+	ShadowResultID string               `json:"shadow_result_id,omitempty"` // 影子评估结果ID（原回答为线上回答，新回答为候选回答）
+	BaseModelID    string               `json:"base_model_id,omitempty"`
+	ModelID        string               `json:"model_id,omitempty"`
+	Added          []string             `json:"added"`   // 新回答新增的句子
+	Removed        []string             `json:"removed"` // 新回答删除的句子
+	UnchangedCount int                  `json:"unchanged_count"`
+	Similarity     float64              `json:"similarity"` // 句子级相似度（0-1）
+	Segments       []*AnswerDiffSegment `json:"segments"`   // 按顺序排列的差异片段，用于逐句展示
+	CreateTime     string               `json:"create_time"`
+}
+
+// AnswerDiffSegment 差异片段
+type AnswerDiffSegment struct {
+	Op   string `json:"op"` // equal / added / removed
+	Text string `json:"text"`
+}
+
+// MessageSaverStatsReq 查询异步消息保存器的队列深度和累计计数
+type MessageSaverStatsReq struct {
+	g.Meta `path:"/v1/messages/saver/stats" method:"get" tags:"conversation" summary:"Get queue depth and dropped counts of the async message saver"`
+}
+
+type MessageSaverStatsRes struct {
+	g.Meta        `mime:"application/json"`
+	QueueSize     int   `json:"queue_size" dc:"Messages waiting in the save queue"`
+	QueueCapacity int   `json:"queue_capacity" dc:"Capacity of the save queue"`
+	Workers       int   `json:"workers"`
+	Saved         int64 `json:"saved" dc:"Messages saved since start, excluding replayed ones"`
+	Failed        int64 `json:"failed" dc:"Failed saves since start; failed async saves are spilled to the spool"`
+	Spilled       int64 `json:"spilled" dc:"Messages written to the spool since start"`
+	Replayed      int64 `json:"replayed" dc:"Messages saved from the spool since start"`
+	Dropped       int64 `json:"dropped" dc:"Messages lost since start (spool disabled or not writable)"`
+	DeadLettered  int64 `json:"dead_lettered" dc:"Messages moved to the spool dead-letter file after repeated save failures since start"`
+	SpoolEnabled  bool  `json:"spool_enabled"`
+	SpoolPending  int   `json:"spool_pending" dc:"Messages in the spool waiting to be replayed"`
+}
+
+// WorkspaceListReq 列出会话工作区文件
+type WorkspaceListReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}/workspace" method:"get" tags:"conversation" summary:"List conversation workspace files"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+}
+
+type WorkspaceListRes struct {
+	g.Meta       `mime:"application/json"`
+	List         []*WorkspaceFileItem `json:"list" dc:"Workspace files"`
+	TotalSize    int64                `json:"total_size" dc:"Total size in bytes"`
+	MaxTotalSize int64                `json:"max_total_size" dc:"Workspace size quota in bytes"`
+	MaxFiles     int                  `json:"max_files" dc:"Workspace file count quota"`
+}
+
+// WorkspaceFileItem 工作区文件信息
+type WorkspaceFileItem struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	UpdateTime string `json:"update_time"`
+}
+
+// WorkspaceFileDeleteReq 删除会话工作区中的单个文件
+type WorkspaceFileDeleteReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}/workspace/{name}" method:"delete" tags:"conversation" summary:"Delete a conversation workspace file"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+	Name   string `json:"name" v:"required" dc:"File name"`
+}
+
+type WorkspaceFileDeleteRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ConversationParticipant 会话参与者
+type ConversationParticipant struct {
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"` // owner / member / viewer
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// ConversationParticipantListReq 获取会话参与者，没有参与者的会话为单人会话
+type ConversationParticipantListReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}/participants" method:"get" tags:"conversation" summary:"List participants of a shared conversation"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+}
+
+type ConversationParticipantListRes struct {
+	g.Meta       `mime:"application/json"`
+	Participants []*ConversationParticipant `json:"participants"`
+}
+
+// ConversationParticipantSaveReq 添加会话参与者或修改参与者角色，添加参与者后会话变为共享会话，
+// 只有参与者可以读取（viewer 及以上）和发送消息（member 及以上）
+type ConversationParticipantSaveReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}/participants" method:"put" tags:"conversation" summary:"Add a conversation participant or change its role"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+	UserID string `json:"user_id" v:"required|length:1,100" dc:"Participant user ID"`
+	Role   string `json:"role" v:"in:owner,member,viewer" d:"member" dc:"owner, member or viewer (read only)"`
+}
+
+type ConversationParticipantSaveRes struct {
+	g.Meta      `mime:"application/json"`
+	Participant *ConversationParticipant `json:"participant"`
+}
+
+// ConversationParticipantRemoveReq 移除会话参与者，移除全部参与者后会话恢复为单人会话
+type ConversationParticipantRemoveReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}/participants/{user_id}" method:"delete" tags:"conversation" summary:"Remove a conversation participant"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+	UserID string `json:"user_id" v:"required" dc:"Participant user ID"`
+}
+
+type ConversationParticipantRemoveRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ConversationEventsReq 订阅会话的新消息（SSE），共享会话的每个参与者保持一个连接即可收到其他参与者的提问和助手的回答
+type ConversationEventsReq struct {
+	g.Meta `path:"/v1/conversations/{conv_id}/events" method:"get" tags:"conversation" summary:"Stream new messages of a conversation to its participants" x-sse-events:"message 事件（JSON：msg_id、role、author_id（用户消息的发送者）、content、created），助手回答在生成完成并保存后推送；开始时发送 retry 字段，空闲达到 sse.heartbeatInterval（默认 15 秒）时发送 : ping 心跳"`
+	ConvID string `json:"conv_id" v:"required" dc:"Conversation ID"`
+	UserID string `json:"user_id" dc:"Subscribing user, required for shared conversations"`
+}
+
+// ConversationEventsRes 订阅会话新消息响应，事件通过 HTTP 响应流返回
+type ConversationEventsRes struct {
+	g.Meta `mime:"text/event-stream"`
+}
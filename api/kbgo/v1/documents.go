@@ -3,6 +3,7 @@ package v1
 import (
 	"github.com/Malowking/kbgo/internal/model/entity"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
 )
 
 const (
@@ -47,3 +48,24 @@ type DocumentsReIndexRes struct {
 	g.Meta  `mime:"application/json"`
 	Message string `json:"message" dc:"Re-indexing task started"`
 }
+
+type DocumentsUpdateValidityReq struct {
+	g.Meta     `path:"/v1/documents/validity" method:"put" tags:"retriever" summary:"Update the validity window of a document"`
+	DocumentId string      `p:"document_id" dc:"document_id" v:"required"`
+	ValidFrom  *gtime.Time `p:"valid_from" dc:"Time from which the document is valid, empty clears the limit"`
+	ValidUntil *gtime.Time `p:"valid_until" dc:"Time after which the document is expired, empty clears the limit"`
+}
+
+type DocumentsUpdateValidityRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+type DocumentsVersionsReq struct {
+	g.Meta     `path:"/v1/documents/versions" method:"get" tags:"retriever" summary:"List all versions in the version chain of a document"`
+	DocumentId string `p:"document_id" dc:"any document id in the version chain" v:"required"`
+}
+
+type DocumentsVersionsRes struct {
+	g.Meta `mime:"application/json"`
+	Data   []entity.KnowledgeDocuments `json:"data" dc:"Versions ordered from latest to oldest"`
+}
@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ExperimentListReq 查询配置的 A/B 实验
+type ExperimentListReq struct {
+	g.Meta `path:"/v1/experiments" method:"get" tags:"experiment" summary:"List configured A/B experiments"`
+}
+
+type ExperimentListRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*ExperimentItem `json:"list" dc:"Experiments"`
+}
+
+// ExperimentItem 实验配置
+type ExperimentItem struct {
+	Name     string                   `json:"name"`
+	Enabled  bool                     `json:"enabled"` // 是否分配新流量
+	Variants []*ExperimentVariantItem `json:"variants"`
+}
+
+// ExperimentVariantItem 实验分组配置
+type ExperimentVariantItem struct {
+	Name            string  `json:"name"`
+	Weight          int     `json:"weight"`
+	TrafficRatio    float64 `json:"traffic_ratio"` // 权重占比
+	ModelID         string  `json:"model_id,omitempty"`
+	PromptVersion   string  `json:"prompt_version,omitempty"`
+	TopK            int     `json:"top_k,omitempty"`
+	Score           float64 `json:"score,omitempty"`
+	RetrieveMode    string  `json:"retrieve_mode,omitempty"`
+	CostPer1kTokens float64 `json:"cost_per_1k_tokens,omitempty"`
+}
+
+// ExperimentMetricsReq 查询实验各分组的对比指标
+type ExperimentMetricsReq struct {
+	g.Meta    `path:"/v1/experiments/{name}/metrics" method:"get" tags:"experiment" summary:"Get comparative metrics of experiment variants"`
+	Name      string `json:"name" v:"required" dc:"Experiment name"`
+	StartDate string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate   string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+}
+
+type ExperimentMetricsRes struct {
+	g.Meta `mime:"application/json"`
+	Name   string                   `json:"name"`
+	List   []*ExperimentMetricsItem `json:"list" dc:"Metrics per variant"`
+}
+
+// ExperimentMetricsItem 分组在统计区间内的指标（基于带实验标记的助手消息）
+type ExperimentMetricsItem struct {
+	Variant           string  `json:"variant"`
+	ConversationCount int64   `json:"conversation_count"`
+	AssistantCount    int64   `json:"assistant_count"`
+	AvgLatencyMs      float64 `json:"avg_latency_ms"`
+	TotalTokens       int64   `json:"total_tokens"`
+	PositiveFeedback  int64   `json:"positive_feedback"`
+	NegativeFeedback  int64   `json:"negative_feedback"`
+	SatisfactionRatio float64 `json:"satisfaction_ratio"` // positive / (positive + negative)，无反馈时为 0
+	EstimatedCost     float64 `json:"estimated_cost"`     // total_tokens / 1000 * cost_per_1k_tokens
+}
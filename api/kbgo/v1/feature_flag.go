@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// FeatureFlagItem 功能开关在全局或某个项目内的生效设置
+type FeatureFlagItem struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`              // 没有任何设置时是否开启
+	ProjectID   string `json:"project_id,omitempty"` // 查询的项目，空表示全局
+	Enabled     bool   `json:"enabled"`
+	Rollout     int    `json:"rollout"` // 开启时的灰度比例（0-100）
+	Source      string `json:"source"`  // 生效设置的来源：default / global / project / env（环境变量覆盖，管理接口无法修改）
+}
+
+// FeatureFlagListReq 功能开关列表请求
+type FeatureFlagListReq struct {
+	g.Meta    `path:"/v1/feature-flags" method:"get" tags:"feature-flag" summary:"List feature flags with their effective settings"`
+	ProjectID string `json:"project_id"` // 项目ID（可选），指定时返回该项目内的生效设置
+}
+
+// FeatureFlagListRes 功能开关列表响应
+type FeatureFlagListRes struct {
+	g.Meta `mime:"application/json"`
+	Flags  []*FeatureFlagItem `json:"flags"`
+}
+
+// FeatureFlagSetReq 设置功能开关请求，立即在本实例生效，其他实例在 featureFlags.refreshInterval 内生效
+type FeatureFlagSetReq struct {
+	g.Meta    `path:"/v1/feature-flags/:name" method:"put" tags:"feature-flag" summary:"Turn a feature flag on or off globally or for a project"`
+	Name      string `json:"name" v:"required"`
+	ProjectID string `json:"project_id"` // 项目ID（可选），为空时修改全局设置
+	Enabled   bool   `json:"enabled"`
+	Rollout   *int   `json:"rollout" v:"between:0,100"` // 灰度比例（可选，默认 100）
+}
+
+// FeatureFlagSetRes 设置功能开关响应
+type FeatureFlagSetRes struct {
+	g.Meta `mime:"application/json"`
+	Flag   *FeatureFlagItem `json:"flag"`
+}
+
+// FeatureFlagResetReq 删除功能开关设置请求，项目设置删除后使用全局设置，全局设置删除后使用默认值
+type FeatureFlagResetReq struct {
+	g.Meta    `path:"/v1/feature-flags/:name" method:"delete" tags:"feature-flag" summary:"Remove a global or project feature flag setting"`
+	Name      string `json:"name" v:"required"`
+	ProjectID string `json:"project_id"` // 项目ID（可选），为空时删除全局设置
+}
+
+// FeatureFlagResetRes 删除功能开关设置响应
+type FeatureFlagResetRes struct {
+	g.Meta `mime:"application/json"`
+	Flag   *FeatureFlagItem `json:"flag"`
+}
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// HandoffTicketItem 人工接管工单
+type HandoffTicketItem struct {
+	TicketID   string   `json:"ticket_id"`
+	ConvID     string   `json:"conv_id"`
+	Reason     string   `json:"reason"`               // low_confidence / user_request
+	Question   string   `json:"question,omitempty"`   // 触发转人工的用户问题
+	Confidence *float64 `json:"confidence,omitempty"` // 触发时的回答置信度
+	Status     string   `json:"status"`               // open / assigned / resolved
+	Assignee   string   `json:"assignee,omitempty"`   // 处理的人工客服
+	CreatedAt  string   `json:"created_at,omitempty"`
+	ResolvedAt string   `json:"resolved_at,omitempty"`
+}
+
+// HandoffTicketListReq 人工接管工单列表请求
+type HandoffTicketListReq struct {
+	g.Meta `path:"/v1/handoff/tickets" method:"get" tags:"handoff" summary:"List handoff tickets"`
+	ConvID string `json:"conv_id"` // 按会话过滤（可选）
+	Status string `json:"status"`  // 按状态过滤（可选）：open/assigned/resolved
+}
+
+// HandoffTicketListRes 人工接管工单列表响应
+type HandoffTicketListRes struct {
+	g.Meta  `mime:"application/json"`
+	Tickets []*HandoffTicketItem `json:"tickets"`
+}
+
+// HandoffTicketGetReq 人工接管工单详情请求
+type HandoffTicketGetReq struct {
+	g.Meta   `path:"/v1/handoff/tickets/:ticket_id" method:"get" tags:"handoff" summary:"Get a handoff ticket"`
+	TicketID string `json:"ticket_id" v:"required"` // 工单ID
+}
+
+// HandoffTicketGetRes 人工接管工单详情响应
+type HandoffTicketGetRes struct {
+	g.Meta `mime:"application/json"`
+	Ticket *HandoffTicketItem `json:"ticket"`
+}
+
+// HandoffMessageReq 人工客服发送消息请求
+type HandoffMessageReq struct {
+	g.Meta   `path:"/v1/handoff/tickets/:ticket_id/messages" method:"post" tags:"handoff" summary:"Post a human agent message into the conversation"`
+	TicketID string `json:"ticket_id" v:"required"` // 工单ID
+	Agent    string `json:"agent"`                  // 人工客服标识
+	Content  string `json:"content" v:"required"`   // 消息内容
+}
+
+// HandoffMessageRes 人工客服发送消息响应
+type HandoffMessageRes struct {
+	g.Meta `mime:"application/json"`
+	Ticket *HandoffTicketItem `json:"ticket"`
+}
+
+// HandoffResolveReq 结束人工接管请求
+type HandoffResolveReq struct {
+	g.Meta   `path:"/v1/handoff/tickets/:ticket_id/resolve" method:"post" tags:"handoff" summary:"Resolve a handoff ticket and return the conversation to the assistant"`
+	TicketID string `json:"ticket_id" v:"required"` // 工单ID
+}
+
+// HandoffResolveRes 结束人工接管响应
+type HandoffResolveRes struct {
+	g.Meta `mime:"application/json"`
+	Ticket *HandoffTicketItem `json:"ticket"`
+}
+
+// HandoffStreamReq 订阅会话人工接管事件请求（SSE）
+type HandoffStreamReq struct {
+	g.Meta `path:"/v1/handoff/stream" method:"get" tags:"handoff" summary:"Stream human agent messages for a conversation" x-sse-events:"handoff 事件（JSON，type 为 opened/message/resolved），开始时发送 retry 字段，空闲达到 sse.heartbeatInterval（默认 15 秒）时发送 : ping 心跳"`
+	ConvID string `json:"conv_id" v:"required"` // 会话ID
+}
+
+// HandoffStreamRes 订阅会话人工接管事件响应，事件通过 HTTP 响应流返回
+type HandoffStreamRes struct {
+	g.Meta `mime:"text/event-stream"`
+}
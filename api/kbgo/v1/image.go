@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ImageGetReq 获取上传图片（文档解析提取的图片、对话上传的图片），可按需返回缩略图或缩放版本
+type ImageGetReq struct {
+	g.Meta  `path:"/v1/images" method:"get" tags:"image" summary:"Serve an uploaded image, optionally resized, with ETag caching"`
+	Path    string `json:"path" v:"required" dc:"Image path, e.g. upload/image/xxx.png or image/xxx.png returned by document parsing"`
+	Width   int    `json:"w" v:"min:0" dc:"Max width in pixels (optional)"`
+	Height  int    `json:"h" v:"min:0" dc:"Max height in pixels (optional)"`
+	Size    string `json:"size" dc:"Size preset configured in imageService.presets, e.g. thumb/small/medium (optional)"`
+	Fit     string `json:"fit" v:"in:contain,cover" d:"contain" dc:"contain: fit within w x h; cover: fill w x h and crop the center"`
+	Quality int    `json:"quality" v:"min:0|max:100" dc:"JPEG quality (default imageService.quality)"`
+}
+
+// ImageGetRes 响应体为图片内容，不使用统一 JSON 响应结构；If-None-Match 与 ETag 相同时返回 304
+type ImageGetRes struct {
+	g.Meta `mime:"image/*"`
+}
@@ -3,6 +3,7 @@ package v1
 import (
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gtime"
 )
 
 // UploadFileReq File upload request (Indexer interface modified to pure upload)
@@ -11,6 +12,14 @@ type UploadFileReq struct {
 	File        *ghttp.UploadFile `p:"file" type:"file" dc:"If it's a local file, upload the file directly"`
 	URL         string            `p:"url" dc:"If it's a web file, just enter the URL" d:""`
 	KnowledgeId string            `p:"knowledge_id" dc:"Knowledge base ID" v:"required"`
+	// 分片安全标签，检索时按调用方权限过滤
+	SecurityLabel string `p:"security_label" dc:"Security label of the document, e.g. public/internal/confidential (optional)"`
+	// 文档有效期，检索时过滤已过期或尚未生效的内容
+	ValidFrom     *gtime.Time `p:"valid_from" dc:"Time from which the document is valid (optional)"`
+	ValidUntil    *gtime.Time `p:"valid_until" dc:"Time after which the document is expired (optional)"`
+	SectionLabels string      `p:"section_labels" dc:"JSON array of section label rules, e.g. [{\"keyword\":\"薪酬\",\"label\":\"confidential\"}] (optional)"`
+	// CSV / JSON Lines 按字段映射逐行生成分片
+	FieldMapping string `p:"field_mapping" dc:"Field mapping for csv/jsonl files, each row becomes one chunk, e.g. {\"content\":[\"question\",\"answer\"],\"title\":\"question\",\"metadata\":{\"price\":\"number\"}} (optional)"`
 }
 
 type UploadFileRes struct {
@@ -18,6 +27,7 @@ type UploadFileRes struct {
 	DocumentId string `json:"document_id" dc:"Document ID"`
 	Status     string `json:"status" dc:"Upload status"`
 	Message    string `json:"message" dc:"Status message"`
+	Version    int    `json:"version,omitempty" dc:"Version number, greater than 1 when a file with the same name is re-uploaded"`
 }
 
 // IndexDocumentsReq Document indexing request (batch splitting and vectorization)
@@ -34,3 +44,61 @@ type IndexDocumentsRes struct {
 	g.Meta  `mime:"application/json"`
 	Message string `json:"message" dc:"Indexing task started"`
 }
+
+// ParserHealthReq Document parser backends health check request
+type ParserHealthReq struct {
+	g.Meta `path:"/v1/parsers/health" method:"get" tags:"retriever" summary:"Check the health of configured document parser backends"`
+}
+
+type ParserHealthItem struct {
+	Name    string `json:"name" dc:"Parser backend name"`
+	Healthy bool   `json:"healthy" dc:"Whether the backend is available"`
+	Error   string `json:"error,omitempty" dc:"Health check error"`
+}
+
+type ParserHealthRes struct {
+	g.Meta  `mime:"application/json"`
+	Parsers []ParserHealthItem `json:"parsers"`
+}
+
+// IngestDeadLetterListReq List documents whose indexing failed after all retries
+type IngestDeadLetterListReq struct {
+	g.Meta      `path:"/v1/index/dead-letters" method:"get" tags:"retriever" summary:"List documents whose indexing failed after all retries"`
+	KnowledgeId string `p:"knowledge_id" dc:"Filter by knowledge base ID (optional)"`
+	Status      string `p:"status" dc:"Filter by status: dead or requeued (optional)" v:"in:dead,requeued"`
+	Page        int    `p:"page" dc:"Page number" d:"1" v:"min:1"`
+	PageSize    int    `p:"page_size" dc:"Page size" d:"20" v:"min:1|max:100"`
+}
+
+type IngestDeadLetterItem struct {
+	Id          string `json:"id" dc:"Dead letter ID"`
+	DocumentId  string `json:"document_id" dc:"Document ID"`
+	KnowledgeId string `json:"knowledge_id" dc:"Knowledge base ID"`
+	FileName    string `json:"file_name" dc:"File name"`
+	Step        string `json:"step" dc:"Indexing step that failed"`
+	Error       string `json:"error" dc:"Error of the last attempt"`
+	Attempts    int    `json:"attempts" dc:"Attempts made by the failed step"`
+	Failures    int    `json:"failures" dc:"Times the document has landed in the dead letter queue"`
+	Status      string `json:"status" dc:"dead or requeued"`
+	RequeueTime string `json:"requeue_time,omitempty" dc:"Time the document was last requeued"`
+	CreateTime  string `json:"create_time" dc:"Time of the first failure"`
+	UpdateTime  string `json:"update_time" dc:"Time of the last failure"`
+}
+
+type IngestDeadLetterListRes struct {
+	g.Meta `mime:"application/json"`
+	List   []IngestDeadLetterItem `json:"list"`
+	Total  int64                  `json:"total"`
+}
+
+// IngestDeadLetterRequeueReq Re-index a dead-lettered document with its original indexing parameters
+type IngestDeadLetterRequeueReq struct {
+	g.Meta `path:"/v1/index/dead-letters/{id}/requeue" method:"post" tags:"retriever" summary:"Re-index a dead-lettered document"`
+	Id     string `p:"id" v:"required" dc:"Dead letter ID"`
+}
+
+type IngestDeadLetterRequeueRes struct {
+	g.Meta     `mime:"application/json"`
+	DocumentId string `json:"document_id" dc:"Document being re-indexed"`
+	Message    string `json:"message"`
+}
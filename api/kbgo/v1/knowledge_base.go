@@ -18,6 +18,14 @@ type KBCreateReq struct {
 	Name        string `v:"required|length:3,50" dc:"kb name"`
 	Description string `v:"required|length:3,200" dc:"kb description"`
 	Category    string `v:"length:3,50" dc:"kb category"`
+	// 指定后按该模型的实际输出维度创建向量集合，否则使用配置文件中的 dim
+	EmbeddingModelId string `dc:"embedding model id used to detect the vector dimension (optional)"`
+	// 指定后创建带稀疏向量字段的集合，索引和检索时同时使用稀疏向量（创建后不可修改）
+	SparseModelId string  `dc:"sparse embedding model id (SPLADE/BM42) for hybrid retrieval (optional)"`
+	SparseWeight  float64 `v:"between:0,1" dc:"weight of sparse scores when fused with dense scores, 0 uses the configured default"`
+	// 新近度加权：越新的文档得分越高，0 表示不启用
+	RecencyWeight       float64 `v:"between:0,1" dc:"weight of the recency boost applied to retrieval scores, 0 disables it"`
+	RecencyHalfLifeDays int     `v:"min:0" dc:"half-life in days of the recency boost, 0 uses the configured default"`
 }
 
 type KBCreateRes struct {
@@ -31,6 +39,11 @@ type KBUpdateReq struct {
 	Description *string `v:"length:3,200" dc:"kb description"`
 	Category    *string `v:"length:3,50" dc:"kb category"`
 	Status      *Status `v:"in:1,2" dc:"kb status"`
+	// 稀疏检索融合权重，仅对配置了稀疏模型的知识库生效
+	SparseWeight *float64 `v:"between:0,1" dc:"weight of sparse scores when fused with dense scores"`
+	// 新近度加权配置
+	RecencyWeight       *float64 `v:"between:0,1" dc:"weight of the recency boost applied to retrieval scores, 0 disables it"`
+	RecencyHalfLifeDays *int     `v:"min:0" dc:"half-life in days of the recency boost, 0 uses the configured default"`
 }
 type KBUpdateRes struct{}
 
@@ -49,10 +62,11 @@ type KBGetOneRes struct {
 }
 
 type KBGetListReq struct {
-	g.Meta   `path:"/v1/kb" method:"get" tags:"kb" summary:"Get kbs"`
-	Name     *string `v:"length:3,50" dc:"kb name"`
-	Status   *Status `v:"in:1,2" dc:"kb age"`
-	Category *string `v:"length:3,50" dc:"kb category"`
+	g.Meta    `path:"/v1/kb" method:"get" tags:"kb" summary:"Get kbs"`
+	Name      *string `v:"length:3,50" dc:"kb name"`
+	Status    *Status `v:"in:1,2" dc:"kb age"`
+	Category  *string `v:"length:3,50" dc:"kb category"`
+	ProjectId string  `json:"project_id" dc:"only list kbs in this project"`
 }
 
 type KBGetListRes struct {
@@ -66,3 +80,131 @@ type KBUpdateStatusReq struct {
 }
 
 type KBUpdateStatusRes struct{}
+
+// 索引预处理钩子类型
+const (
+	IngestHookRegexReplace      = "regex_replace"       // 正则替换（清理噪声字符、统一格式）
+	IngestHookStripHeaderFooter = "strip_header_footer" // 去除重复出现的页眉页脚和页码
+	IngestHookRemoveBoilerplate = "remove_boilerplate"  // 删除包含指定套话的行（免责声明、版权声明等）
+	IngestHookExtractMetadata   = "extract_metadata"    // 调用 LLM 从文档开头提取元数据字段
+)
+
+// IngestHook 知识库索引预处理钩子，在文档解析之后、切分之前按顺序执行
+type IngestHook struct {
+	Type string `json:"type" v:"required|in:regex_replace,strip_header_footer,remove_boilerplate,extract_metadata" dc:"regex_replace, strip_header_footer, remove_boilerplate or extract_metadata"`
+	// regex_replace
+	Pattern     string `json:"pattern,omitempty" dc:"regex_replace: Go regular expression"`
+	Replacement string `json:"replacement,omitempty" dc:"regex_replace: replacement, supports $1 style group references"`
+	// strip_header_footer
+	MinRepeats    int `json:"min_repeats,omitempty" v:"min:0" dc:"strip_header_footer: a short line repeated at least this many times is treated as header/footer, 0 uses 3"`
+	MaxLineLength int `json:"max_line_length,omitempty" v:"min:0" dc:"strip_header_footer: only lines up to this many characters are considered, 0 uses 60"`
+	// remove_boilerplate
+	Phrases []string `json:"phrases,omitempty" dc:"remove_boilerplate: lines containing any of these phrases (case-insensitive) are removed"`
+	// extract_metadata
+	ModelId  string   `json:"model_id,omitempty" dc:"extract_metadata: LLM model UUID"`
+	Fields   []string `json:"fields,omitempty" dc:"extract_metadata: metadata field names to extract, e.g. author, publish_date"`
+	Prompt   string   `json:"prompt,omitempty" dc:"extract_metadata: extra extraction instructions (optional)"`
+	MaxChars int      `json:"max_chars,omitempty" v:"min:0" dc:"extract_metadata: leading characters of the document sent to the model, 0 uses 4000"`
+}
+
+// KBIngestHooksGetReq Get the ingestion hooks of a knowledge base
+type KBIngestHooksGetReq struct {
+	g.Meta  `path:"/v1/kb/{id}/ingest-hooks" method:"get" tags:"kb" summary:"Get the ingestion hooks of a knowledge base"`
+	Id      string `v:"required" dc:"kb id"`
+	Version int    `json:"version" v:"min:0" dc:"version to get, 0 gets the active (latest) version"`
+}
+
+type KBIngestHooksGetRes struct {
+	Version    int          `json:"version" dc:"version number, 0 when no hooks have been configured"`
+	Hooks      []IngestHook `json:"hooks"`
+	Comment    string       `json:"comment,omitempty"`
+	CreateTime string       `json:"create_time,omitempty"`
+}
+
+// KBIngestHooksUpdateReq Save the ingestion hooks of a knowledge base as a new version
+type KBIngestHooksUpdateReq struct {
+	g.Meta  `path:"/v1/kb/{id}/ingest-hooks" method:"put" tags:"kb" summary:"Save the ingestion hooks of a knowledge base as a new version"`
+	Id      string       `v:"required" dc:"kb id"`
+	Hooks   []IngestHook `json:"hooks" dc:"hooks executed in order between parsing and chunking, empty disables preprocessing"`
+	Comment string       `json:"comment" v:"length:0,500" dc:"change description"`
+}
+
+type KBIngestHooksUpdateRes struct {
+	Version int `json:"version" dc:"new active version, applies to documents indexed from now on"`
+}
+
+// KBIngestHooksVersionsReq List the versions of the ingestion hooks of a knowledge base
+type KBIngestHooksVersionsReq struct {
+	g.Meta `path:"/v1/kb/{id}/ingest-hooks/versions" method:"get" tags:"kb" summary:"List the versions of the ingestion hooks of a knowledge base"`
+	Id     string `v:"required" dc:"kb id"`
+}
+
+type IngestHookVersion struct {
+	Version    int    `json:"version"`
+	HookCount  int    `json:"hook_count"`
+	Comment    string `json:"comment,omitempty"`
+	CreateTime string `json:"create_time"`
+}
+
+type KBIngestHooksVersionsRes struct {
+	Active   int                 `json:"active" dc:"active version, 0 when no hooks have been configured"`
+	Versions []IngestHookVersion `json:"versions" dc:"versions, newest first"`
+}
+
+// KBIngestHooksRollbackReq Restore an earlier version of the ingestion hooks by saving it as a new version
+type KBIngestHooksRollbackReq struct {
+	g.Meta  `path:"/v1/kb/{id}/ingest-hooks/rollback" method:"post" tags:"kb" summary:"Restore an earlier version of the ingestion hooks"`
+	Id      string `v:"required" dc:"kb id"`
+	Version int    `json:"version" v:"required|min:1" dc:"version to restore"`
+}
+
+type KBIngestHooksRollbackRes struct {
+	Version int `json:"version" dc:"new active version"`
+}
+
+// KBAdviceReq Get the language mix, chunk statistics and optimization recommendations of a knowledge base
+type KBAdviceReq struct {
+	g.Meta  `path:"/v1/kb/{id}/advice" method:"get" tags:"kb" summary:"Get language analytics and embedding model recommendations of a knowledge base"`
+	Id      string `v:"required" dc:"kb id"`
+	Refresh bool   `json:"refresh" dc:"re-analyze now instead of returning the analysis saved after the last ingestion"`
+}
+
+// KBProfile language mix and chunk statistics of the sampled chunks
+type KBProfile struct {
+	ChunkCount       int                `json:"chunk_count" dc:"active chunks in the knowledge base"`
+	SampledChunks    int                `json:"sampled_chunks" dc:"chunks included in the analysis"`
+	Languages        map[string]float64 `json:"languages" dc:"share of chunks per language: zh, ja, ko, latin, cyrillic, arabic, other"`
+	DominantLanguage string             `json:"dominant_language"`
+	DominantShare    float64            `json:"dominant_share"`
+	MixedShare       float64            `json:"mixed_share" dc:"share of chunks mixing several languages"`
+	AvgChunkChars    float64            `json:"avg_chunk_chars"`
+	AvgChunkTokens   float64            `json:"avg_chunk_tokens" dc:"estimated"`
+	MaxChunkTokens   int                `json:"max_chunk_tokens" dc:"estimated"`
+	AnalyzedAt       string             `json:"analyzed_at,omitempty"`
+}
+
+// KBRecommendation optimization recommendation for a knowledge base
+type KBRecommendation struct {
+	Type      string `json:"type" dc:"embedding_model: switch the embedding model (apply via /advice/apply); chunk_size: use this chunk_size for future uploads"`
+	Reason    string `json:"reason"`
+	ModelId   string `json:"model_id,omitempty" dc:"recommended embedding model, empty when no suitable model is registered"`
+	ModelName string `json:"model_name,omitempty"`
+	ChunkSize int    `json:"chunk_size,omitempty" dc:"recommended chunk size in characters"`
+}
+
+type KBAdviceRes struct {
+	Profile          *KBProfile          `json:"profile"`
+	EmbeddingModelId string              `json:"embedding_model_id,omitempty" dc:"embedding model of the most recently indexed document"`
+	Recommendations  []*KBRecommendation `json:"recommendations"`
+}
+
+// KBAdviceApplyReq Start a background job that re-embeds the knowledge base with the recommended embedding model
+type KBAdviceApplyReq struct {
+	g.Meta  `path:"/v1/kb/{id}/advice/apply" method:"post" tags:"kb" summary:"Migrate a knowledge base to the recommended embedding model"`
+	Id      string `v:"required" dc:"kb id"`
+	ModelId string `json:"model_id" dc:"target embedding model, empty uses the recommended model"`
+}
+
+type KBAdviceApplyRes struct {
+	Job *ReembedJobItem `json:"job" dc:"migration job, track progress via /v1/model/reembed/jobs/{job_id}"`
+}
@@ -6,13 +6,14 @@ import (
 
 // MCPRegistryCreateReq MCP service registration request
 type MCPRegistryCreateReq struct {
-	g.Meta      `path:"/v1/mcp/registry" method:"post" tags:"mcp" summary:"Register MCP service"`
-	Name        string `v:"required|length:1,100" dc:"MCP service name (unique)"`
-	Description string `v:"length:0,500" dc:"Service description"`
-	Endpoint    string `v:"required|url" dc:"SSE endpoint URL"`
-	ApiKey      string `v:"length:0,500" dc:"Authentication API key (optional)"`
-	Headers     string `v:"json" dc:"Custom headers in JSON format (optional)"`
-	Timeout     *int   `v:"min:1|max:300" dc:"Timeout in seconds (default: 30)"`
+	g.Meta       `path:"/v1/mcp/registry" method:"post" tags:"mcp" summary:"Register MCP service"`
+	Name         string         `v:"required|length:1,100" dc:"MCP service name (unique)"`
+	Description  string         `v:"length:0,500" dc:"Service description"`
+	Endpoint     string         `v:"required|url" dc:"SSE endpoint URL"`
+	ApiKey       string         `v:"length:0,500" dc:"Authentication API key (optional)"`
+	Headers      string         `v:"json" dc:"Custom headers in JSON format (optional)"`
+	Timeout      *int           `v:"min:1|max:300" dc:"Timeout in seconds (default: 30)"`
+	ToolTimeouts map[string]int `dc:"Per-tool execution timeout in seconds keyed by tool name (0-3600, 0 means no limit), overrides the toolTimeout config"`
 }
 
 type MCPRegistryCreateRes struct {
@@ -21,15 +22,16 @@ type MCPRegistryCreateRes struct {
 
 // MCPRegistryUpdateReq MCP service update request
 type MCPRegistryUpdateReq struct {
-	g.Meta      `path:"/v1/mcp/registry/{id}" method:"put" tags:"mcp" summary:"Update MCP service"`
-	Id          string  `v:"required" dc:"MCP registry ID"`
-	Name        *string `v:"length:1,100" dc:"MCP service name"`
-	Description *string `v:"length:0,500" dc:"Service description"`
-	Endpoint    *string `v:"url" dc:"SSE endpoint URL"`
-	ApiKey      *string `v:"length:0,500" dc:"Authentication API key"`
-	Headers     *string `v:"json" dc:"Custom headers in JSON format"`
-	Timeout     *int    `v:"min:1|max:300" dc:"Timeout in seconds"`
-	Status      *int8   `v:"in:0,1" dc:"Status: 1-enabled, 0-disabled"`
+	g.Meta       `path:"/v1/mcp/registry/{id}" method:"put" tags:"mcp" summary:"Update MCP service"`
+	Id           string         `v:"required" dc:"MCP registry ID"`
+	Name         *string        `v:"length:1,100" dc:"MCP service name"`
+	Description  *string        `v:"length:0,500" dc:"Service description"`
+	Endpoint     *string        `v:"url" dc:"SSE endpoint URL"`
+	ApiKey       *string        `v:"length:0,500" dc:"Authentication API key"`
+	Headers      *string        `v:"json" dc:"Custom headers in JSON format"`
+	Timeout      *int           `v:"min:1|max:300" dc:"Timeout in seconds"`
+	ToolTimeouts map[string]int `dc:"Per-tool execution timeout in seconds keyed by tool name, replaces the existing settings (empty object clears them)"`
+	Status       *int8          `v:"in:0,1" dc:"Status: 1-enabled, 0-disabled"`
 }
 
 type MCPRegistryUpdateRes struct{}
@@ -49,16 +51,17 @@ type MCPRegistryGetOneReq struct {
 }
 
 type MCPRegistryGetOneRes struct {
-	Id          string `json:"id" dc:"MCP registry ID"`
-	Name        string `json:"name" dc:"Service name"`
-	Description string `json:"description" dc:"Service description"`
-	Endpoint    string `json:"endpoint" dc:"SSE endpoint URL"`
-	ApiKey      string `json:"api_key,omitempty" dc:"API key (masked)"`
-	Headers     string `json:"headers,omitempty" dc:"Custom headers"`
-	Timeout     int    `json:"timeout" dc:"Timeout in seconds"`
-	Status      int8   `json:"status" dc:"Status: 1-enabled, 0-disabled"`
-	CreateTime  string `json:"create_time" dc:"Create time"`
-	UpdateTime  string `json:"update_time" dc:"Update time"`
+	Id           string         `json:"id" dc:"MCP registry ID"`
+	Name         string         `json:"name" dc:"Service name"`
+	Description  string         `json:"description" dc:"Service description"`
+	Endpoint     string         `json:"endpoint" dc:"SSE endpoint URL"`
+	ApiKey       string         `json:"api_key,omitempty" dc:"API key (masked)"`
+	Headers      string         `json:"headers,omitempty" dc:"Custom headers"`
+	Timeout      int            `json:"timeout" dc:"Timeout in seconds"`
+	ToolTimeouts map[string]int `json:"tool_timeouts,omitempty" dc:"Per-tool execution timeout in seconds"`
+	Status       int8           `json:"status" dc:"Status: 1-enabled, 0-disabled"`
+	CreateTime   string         `json:"create_time" dc:"Create time"`
+	UpdateTime   string         `json:"update_time" dc:"Update time"`
 }
 
 // MCPRegistryGetListReq Get MCP services list request
@@ -119,9 +122,28 @@ type MCPListToolsRes struct {
 }
 
 type MCPToolInfo struct {
-	Name        string                 `json:"name" dc:"Tool name"`
-	Description string                 `json:"description" dc:"Tool description"`
-	InputSchema map[string]interface{} `json:"inputSchema" dc:"Input schema"`
+	Name         string                 `json:"name" dc:"Tool name"`
+	Description  string                 `json:"description" dc:"Tool description"`
+	InputSchema  map[string]interface{} `json:"inputSchema" dc:"Input schema"`
+	Deprecated   bool                   `json:"deprecated,omitempty" dc:"The tool was removed server-side and is no longer offered to the LLM"`
+	DeprecatedAt string                 `json:"deprecatedAt,omitempty" dc:"When the tool was found to be removed (RFC3339)"`
+	SchemaError  string                 `json:"schemaError,omitempty" dc:"Why the input schema is invalid, such tools are not offered to the LLM"`
+}
+
+// MCPVerifyToolsReq 立即校验 MCP 服务的工具缓存：重新获取工具列表，更新缓存的参数定义，标记服务端已删除的工具
+type MCPVerifyToolsReq struct {
+	g.Meta `path:"/v1/mcp/registry/{id}/tools/verify" method:"post" tags:"mcp" summary:"Verify cached MCP tool schemas against the live server"`
+	Id     string `v:"required" dc:"MCP registry ID"`
+}
+
+type MCPVerifyToolsRes struct {
+	Added      []string      `json:"added" dc:"Tools new on the server"`
+	Updated    []string      `json:"updated" dc:"Tools whose description or input schema changed"`
+	Deprecated []string      `json:"deprecated" dc:"Tools removed server-side since the last verification"`
+	Restored   []string      `json:"restored" dc:"Previously deprecated tools that are available again"`
+	Invalid    []string      `json:"invalid" dc:"Tools with an invalid input schema"`
+	Tools      []MCPToolInfo `json:"tools" dc:"Cached tools after verification"`
+	VerifiedAt string        `json:"verifiedAt" dc:"Verification time (RFC3339)"`
 }
 
 // MCPCallToolReq Call MCP tool request
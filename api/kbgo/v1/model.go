@@ -2,6 +2,7 @@ package v1
 
 import (
 	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -21,14 +22,15 @@ type ReloadModelsRes struct {
 // ListModelsReq 列出模型请求
 type ListModelsReq struct {
 	g.Meta    `path:"/v1/model/list" method:"get" tags:"model" summary:"List all models"`
-	ModelType string `json:"model_type"` // 可选，按类型过滤：llm, embedding, reranker, multimodal, image, video, audio
+	ModelType string `json:"model_type"` // 可选，按类型过滤：llm, embedding, sparse_embedding, reranker, multimodal, image, video, audio
 }
 
 // ListModelsRes 列出模型响应
 type ListModelsRes struct {
 	g.Meta `mime:"application/json"`
-	Models []*model.ModelConfig `json:"models"`
-	Count  int                  `json:"count"`
+	Models []*model.ModelConfig          `json:"models"`
+	Count  int                           `json:"count"`
+	Health map[string]*model.ModelHealth `json:"health,omitempty"` // 做过健康检查的模型的状态，key 为模型ID
 }
 
 // GetModelReq 获取模型详情请求
@@ -41,6 +43,30 @@ type GetModelReq struct {
 type GetModelRes struct {
 	g.Meta `mime:"application/json"`
 	Model  *model.ModelConfig `json:"model"`
+	Health *model.ModelHealth `json:"health,omitempty"` // 健康检查状态（ollama 模型或设置了 extra.healthCheck 的模型）
+}
+
+// OllamaModelListReq 列出 Ollama 服务上已下载的模型，用于选择要注册的本地模型
+type OllamaModelListReq struct {
+	g.Meta  `path:"/v1/model/ollama/models" method:"get" tags:"model" summary:"List models pulled on an Ollama server"`
+	BaseURL string `json:"base_url"` // Ollama 服务地址（可选，默认 http://localhost:11434，可带 /v1 后缀）
+}
+
+// OllamaModelListRes Ollama 模型列表
+type OllamaModelListRes struct {
+	g.Meta `mime:"application/json"`
+	Models []*OllamaModelItem `json:"models"`
+}
+
+// OllamaModelItem Ollama 服务上的模型
+type OllamaModelItem struct {
+	Name              string `json:"name"`
+	Size              int64  `json:"size"` // 字节数
+	Family            string `json:"family,omitempty"`
+	ParameterSize     string `json:"parameter_size,omitempty"`
+	QuantizationLevel string `json:"quantization_level,omitempty"`
+	ModifiedAt        string `json:"modified_at,omitempty"`
+	RegisteredModelID string `json:"registered_model_id,omitempty"` // 已注册为 ollama 模型时的模型ID
 }
 
 // ChatCompletionReq OpenAI 风格聊天请求
@@ -103,6 +129,10 @@ type ChatCompletionRes struct {
 	Model   string                 `json:"model"`
 	Choices []ChatCompletionChoice `json:"choices"`
 	Usage   ChatCompletionUsage    `json:"usage"`
+
+	// 以下字段仅 /v1/chat/completions 经过对话流程回答时返回
+	ConversationID string             `json:"conversation_id,omitempty"` // 保存本轮对话的会话ID，后续请求传入即可沿用服务端保存的历史
+	References     []*schema.Document `json:"references,omitempty"`      // 回答参考的知识库分片
 }
 
 // ChatCompletionChoice 响应选项
@@ -119,6 +149,23 @@ type ChatCompletionUsage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// ChatCompletionChunk 流式聊天的增量响应（gRPC ChatCompletionStream 返回）
+type ChatCompletionChunk struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"` // chat.completion.chunk
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []ChatCompletionChunkChoice `json:"choices"`
+	Usage   *ChatCompletionUsage        `json:"usage,omitempty"` // 仅最后一个增量返回
+}
+
+// ChatCompletionChunkChoice 增量响应选项
+type ChatCompletionChunkChoice struct {
+	Index        int                   `json:"index"`
+	Delta        ChatCompletionMessage `json:"delta"`         // 增量内容
+	FinishReason string                `json:"finish_reason"` // 最后一个增量返回：stop, length, tool_calls
+}
+
 // EmbeddingReq 向量化请求
 type EmbeddingReq struct {
 	g.Meta  `path:"/v1/model/embeddings" method:"post" tags:"model" summary:"Create embeddings"`
@@ -147,18 +194,77 @@ type EmbeddingUsage struct {
 	TotalTokens  int `json:"total_tokens"`
 }
 
+// EmbeddingsReq OpenAI 兼容的向量化请求，供外部服务复用平台的 embedding 模型
+type EmbeddingsReq struct {
+	g.Meta         `path:"/v1/embeddings" method:"post" tags:"model" summary:"Create embeddings (OpenAI compatible)"`
+	Model          string      `json:"model"`                               // 模型ID、embedding 模型名称或 embeddingsAPI.aliases 中的别名（可选，默认项目或全局默认 embedding 模型）
+	Input          interface{} `json:"input" v:"required"`                  // 输入文本，字符串或字符串数组
+	Dimensions     int         `json:"dimensions"`                          // 输出向量维度（可选，模型支持时生效）
+	EncodingFormat string      `json:"encoding_format" v:"in:float,base64"` // 向量编码：float（默认）或 base64（小端 float32）
+	User           string      `json:"user"`                                // 终端用户标识（可选，透传给模型服务）
+	ProjectID      string      `json:"project_id"`                          // 所属项目（可选），使用项目默认 embedding 模型并计入项目 token 配额
+}
+
+// EmbeddingsRes OpenAI 兼容的向量化响应
+type EmbeddingsRes struct {
+	g.Meta `mime:"application/json"`
+	Object string            `json:"object"` // list
+	Data   []*EmbeddingsData `json:"data"`
+	Model  string            `json:"model"` // 实际使用的模型名称
+	Usage  EmbeddingUsage    `json:"usage"` // 本次调用模型服务消耗的 token，命中缓存的输入不计
+}
+
+// EmbeddingsData 单条输入的向量
+type EmbeddingsData struct {
+	Object    string      `json:"object"` // embedding
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"` // []float32，encoding_format 为 base64 时为字符串
+}
+
+// ChatCompletionsReq OpenAI 兼容的聊天请求，OpenAI SDK 和前端可直接指向 kbgo。
+// 携带 tools 或工具调用消息的请求直接调用模型，tool_calls 原样返回给调用方执行；
+// 其他请求经过对话流程（知识库检索、MCP 工具、人设、项目配额），以最后一条用户消息为问题
+type ChatCompletionsReq struct {
+	g.Meta        `path:"/v1/chat/completions" method:"post" tags:"model" summary:"Chat completions (OpenAI compatible)" x-sse-events:"stream 为 true 时按 OpenAI 格式返回 data: chat.completion.chunk，以 data: [DONE] 结束；出错时返回 data: {\"error\":{...}}。经过对话流程时还会发送 documents 等命名事件，OpenAI SDK 会忽略"`
+	Model         string                  `json:"model"`                 // 模型ID或 LLM 模型名称（可选，经过对话流程时默认使用会话、项目的模型）
+	Messages      []ChatCompletionMessage `json:"messages" v:"required"` // 消息列表
+	Stream        bool                    `json:"stream"`                // 是否流式返回
+	StreamOptions *ChatStreamOptions      `json:"stream_options"`        // 流式选项（可选）
+	Tools         []ChatCompletionTool    `json:"tools"`                 // 调用方执行的工具（可选），传入时直接调用模型
+	ToolChoice    interface{}             `json:"tool_choice"`           // 工具选择策略（可选）
+	MaxTokens     int                     `json:"max_tokens"`            // 最大生成token数（可选，仅直接调用模型时生效）
+	Temperature   float32                 `json:"temperature"`           // 温度（可选，仅直接调用模型时生效）
+	TopP          float32                 `json:"top_p"`                 // 核采样（可选，仅直接调用模型时生效）
+	Stop          []string                `json:"stop"`                  // 停止词（可选，仅直接调用模型时生效）
+	User          string                  `json:"user"`                  // 终端用户标识（可选），经过对话流程时作为提问用户
+
+	// kbgo 扩展字段（OpenAI SDK 可通过 extra_body 传入）
+	ConversationID string `json:"conversation_id"` // 会话ID（可选），为空时新建会话并导入 messages 中此前的消息
+	KnowledgeID    string `json:"knowledge_id"`    // 检索的知识库（可选），传入时启用检索
+	ProjectID      string `json:"project_id"`      // 项目ID（可选），未指定的参数使用项目默认设置
+	UseMCP         bool   `json:"use_mcp"`         // 是否使用 MCP 工具
+	PersonaID      string `json:"persona_id"`      // 人设ID（可选）
+	RetrievalView  string `json:"retrieval_view"`  // 检索视图名称或ID（可选）
+}
+
+// ChatStreamOptions 流式选项
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // 是否在结束前发送用量增量
+}
+
 // RegisterModelReq 注册模型请求
 type RegisterModelReq struct {
 	g.Meta              `path:"/v1/model/register" method:"post" tags:"model" summary:"Register a new model"`
-	ModelName           string                 `json:"model_name" v:"required"`                                                        // 模型名称
-	ModelType           string                 `json:"model_type" v:"required|in:llm,embedding,reranker,multimodal,image,video,audio"` // 模型类型
-	Provider            string                 `json:"provider"`                                                                       // 提供商（openai, ollama等）（可选）
-	BaseURL             string                 `json:"base_url"`                                                                       // API基础URL（可选）
-	APIKey              string                 `json:"api_key"`                                                                        // API密钥（可选）
-	MaxCompletionTokens int                    `json:"max_completion_tokens"`                                                          // 最大输出token数（可选）
-	Dimension           int                    `json:"dimension"`                                                                      // 向量维度（embedding模型专用）
-	Config              map[string]interface{} `json:"config"`                                                                         // 其他配置（可选）
-	Enabled             bool                   `json:"enabled"`                                                                        // 是否启用（默认true）
+	ModelName           string                 `json:"model_name" v:"required"`                                                                         // 模型名称
+	ModelType           string                 `json:"model_type" v:"required|in:llm,embedding,sparse_embedding,reranker,multimodal,image,video,audio"` // 模型类型
+	Provider            string                 `json:"provider"`                                                                                        // 提供商（openai, ollama, anthropic等）（可选），LLM 模型填 anthropic 时使用 Anthropic Messages API（base_url 默认 https://api.anthropic.com），LLM 和多模态模型填 gemini 时使用 Gemini generateContent 接口（base_url 默认 https://generativelanguage.googleapis.com/v1beta），填 ollama 时聊天使用 Ollama 原生 /api/chat 接口并定期做健康检查（base_url 默认 http://localhost:11434），rerank 模型填 tei 时使用 Hugging Face TEI 的接口格式，其他使用 Cohere 兼容格式
+	BaseURL             string                 `json:"base_url"`                                                                                        // API基础URL（可选）
+	APIKey              string                 `json:"api_key"`                                                                                         // API密钥（可选）
+	MaxCompletionTokens int                    `json:"max_completion_tokens"`                                                                           // 最大输出token数（可选）
+	Dimension           int                    `json:"dimension"`                                                                                       // 向量维度（embedding模型专用）
+	ContextWindow       int                    `json:"context_window"`                                                                                  // 上下文窗口token数（可选），设置后按输入长度收缩最大输出token数
+	Config              map[string]interface{} `json:"config"`                                                                                          // 其他配置（可选）
+	Enabled             bool                   `json:"enabled"`                                                                                         // 是否启用（默认true）
 }
 
 // RegisterModelRes 注册模型响应
@@ -185,9 +291,10 @@ type UpdateModelReq struct {
 
 // UpdateModelRes 更新模型响应
 type UpdateModelRes struct {
-	g.Meta  `mime:"application/json"`
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	g.Meta       `mime:"application/json"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	ReembedJobID string `json:"reembed_job_id,omitempty"` // embedding 模型配置变更时自动创建的重新向量化任务ID
 }
 
 // DeleteModelReq 删除模型请求
@@ -202,3 +309,81 @@ type DeleteModelRes struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
+
+// ReembedStartReq 创建重新向量化任务请求
+type ReembedStartReq struct {
+	g.Meta      `path:"/v1/model/reembed" method:"post" tags:"model" summary:"Start a background re-embedding job for an embedding model"`
+	ModelID     string `json:"model_id" v:"required"` // embedding 模型ID
+	KnowledgeID string `json:"knowledge_id"`          // 限定知识库（可选），为空时处理该模型生成的全部文档
+}
+
+// ReembedStartRes 创建重新向量化任务响应
+type ReembedStartRes struct {
+	g.Meta `mime:"application/json"`
+	Job    *ReembedJobItem `json:"job"`
+}
+
+// ReembedJobListReq 重新向量化任务列表请求
+type ReembedJobListReq struct {
+	g.Meta  `path:"/v1/model/reembed/jobs" method:"get" tags:"model" summary:"List re-embedding jobs"`
+	ModelID string `json:"model_id"` // 按模型过滤（可选）
+	Status  string `json:"status"`   // 按状态过滤（可选）：running/paused/completed/canceled
+}
+
+// ReembedJobListRes 重新向量化任务列表响应
+type ReembedJobListRes struct {
+	g.Meta `mime:"application/json"`
+	Jobs   []*ReembedJobItem `json:"jobs"`
+}
+
+// ReembedJobGetReq 重新向量化任务详情请求
+type ReembedJobGetReq struct {
+	g.Meta `path:"/v1/model/reembed/jobs/:job_id" method:"get" tags:"model" summary:"Get re-embedding job progress"`
+	JobID  string `json:"job_id" v:"required"` // 任务ID
+}
+
+// ReembedJobGetRes 重新向量化任务详情响应
+type ReembedJobGetRes struct {
+	g.Meta `mime:"application/json"`
+	Job    *ReembedJobItem `json:"job"`
+}
+
+// ReembedJobPauseReq 暂停重新向量化任务请求
+type ReembedJobPauseReq struct {
+	g.Meta `path:"/v1/model/reembed/jobs/:job_id/pause" method:"post" tags:"model" summary:"Pause a re-embedding job"`
+	JobID  string `json:"job_id" v:"required"` // 任务ID
+}
+
+// ReembedJobPauseRes 暂停重新向量化任务响应
+type ReembedJobPauseRes struct {
+	g.Meta `mime:"application/json"`
+	Job    *ReembedJobItem `json:"job"`
+}
+
+// ReembedJobResumeReq 继续重新向量化任务请求
+type ReembedJobResumeReq struct {
+	g.Meta `path:"/v1/model/reembed/jobs/:job_id/resume" method:"post" tags:"model" summary:"Resume a paused re-embedding job"`
+	JobID  string `json:"job_id" v:"required"` // 任务ID
+}
+
+// ReembedJobResumeRes 继续重新向量化任务响应
+type ReembedJobResumeRes struct {
+	g.Meta `mime:"application/json"`
+	Job    *ReembedJobItem `json:"job"`
+}
+
+// ReembedJobItem 重新向量化任务进度
+type ReembedJobItem struct {
+	JobID       string  `json:"job_id"`
+	ModelID     string  `json:"model_id"`
+	KnowledgeID string  `json:"knowledge_id,omitempty"`
+	Migrate     bool    `json:"migrate,omitempty"` // 是否为切换知识库 embedding 模型的迁移任务
+	Status      string  `json:"status"`
+	Total       int     `json:"total"`     // 需要重新向量化的文档数
+	Processed   int     `json:"processed"` // 已成功处理的文档数
+	Failed      int     `json:"failed"`    // 处理失败的文档数
+	Progress    float64 `json:"progress"`  // 完成比例（0-1）
+	LastError   string  `json:"last_error,omitempty"`
+	StartedAt   string  `json:"started_at,omitempty"`
+	FinishedAt  string  `json:"finished_at,omitempty"`
+}
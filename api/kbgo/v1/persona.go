@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// PersonaItem 回答人设预设
+type PersonaItem struct {
+	PersonaID    string `json:"persona_id"`
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Tone         string `json:"tone,omitempty"`         // 语气，如 亲切耐心、专业严谨
+	Formality    string `json:"formality,omitempty"`    // 正式程度：formal / neutral / casual
+	EmojiPolicy  string `json:"emoji_policy,omitempty"` // 表情符号策略：none / sparing / free
+	Signature    string `json:"signature,omitempty"`    // 回答末尾的署名
+	Instructions string `json:"instructions,omitempty"` // 其他人设要求
+	CreatedAt    string `json:"created_at,omitempty"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+}
+
+// PersonaCreateReq 创建人设请求
+type PersonaCreateReq struct {
+	g.Meta       `path:"/v1/personas" method:"post" tags:"persona" summary:"Create a response persona preset"`
+	Name         string `json:"name" v:"required|length:1,100"`         // 人设名称（唯一）
+	Description  string `json:"description" v:"length:0,500"`           // 人设说明
+	Tone         string `json:"tone" v:"length:0,500"`                  // 语气
+	Formality    string `json:"formality" v:"in:formal,neutral,casual"` // 正式程度（可选）
+	EmojiPolicy  string `json:"emoji_policy" v:"in:none,sparing,free"`  // 表情符号策略（可选）
+	Signature    string `json:"signature" v:"length:0,500"`             // 署名（可选）
+	Instructions string `json:"instructions"`                           // 其他人设要求（可选）
+}
+
+// PersonaCreateRes 创建人设响应
+type PersonaCreateRes struct {
+	g.Meta  `mime:"application/json"`
+	Persona *PersonaItem `json:"persona"`
+}
+
+// PersonaUpdateReq 更新人设请求，未传的字段保持不变
+type PersonaUpdateReq struct {
+	g.Meta       `path:"/v1/personas/:persona_id" method:"put" tags:"persona" summary:"Update a response persona preset"`
+	PersonaID    string  `json:"persona_id" v:"required"`
+	Name         *string `json:"name" v:"length:1,100"`
+	Description  *string `json:"description" v:"length:0,500"`
+	Tone         *string `json:"tone" v:"length:0,500"`
+	Formality    *string `json:"formality" v:"in:formal,neutral,casual"`
+	EmojiPolicy  *string `json:"emoji_policy" v:"in:none,sparing,free"`
+	Signature    *string `json:"signature" v:"length:0,500"`
+	Instructions *string `json:"instructions"`
+}
+
+// PersonaUpdateRes 更新人设响应
+type PersonaUpdateRes struct {
+	g.Meta  `mime:"application/json"`
+	Persona *PersonaItem `json:"persona"`
+}
+
+// PersonaDeleteReq 删除人设请求
+type PersonaDeleteReq struct {
+	g.Meta    `path:"/v1/personas/:persona_id" method:"delete" tags:"persona" summary:"Delete a response persona preset"`
+	PersonaID string `json:"persona_id" v:"required"`
+}
+
+// PersonaDeleteRes 删除人设响应
+type PersonaDeleteRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// PersonaGetReq 获取人设请求
+type PersonaGetReq struct {
+	g.Meta    `path:"/v1/personas/:persona_id" method:"get" tags:"persona" summary:"Get a response persona preset"`
+	PersonaID string `json:"persona_id" v:"required"`
+}
+
+// PersonaGetRes 获取人设响应
+type PersonaGetRes struct {
+	g.Meta  `mime:"application/json"`
+	Persona *PersonaItem `json:"persona"`
+}
+
+// PersonaListReq 人设列表请求
+type PersonaListReq struct {
+	g.Meta `path:"/v1/personas" method:"get" tags:"persona" summary:"List response persona presets"`
+}
+
+// PersonaListRes 人设列表响应
+type PersonaListRes struct {
+	g.Meta   `mime:"application/json"`
+	Personas []*PersonaItem `json:"personas"`
+}
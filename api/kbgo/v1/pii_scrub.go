@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// PIIScrubRunReq 手动执行会话个人信息脱敏任务
+type PIIScrubRunReq struct {
+	g.Meta `path:"/v1/pii-scrub/run" method:"post" tags:"retention" summary:"Run conversation PII scrubbing job"`
+	DryRun bool `json:"dry_run" dc:"Only detect and count PII without modifying messages or writing audit logs"`
+}
+
+type PIIScrubRunRes struct {
+	g.Meta           `mime:"application/json"`
+	RunID            string         `json:"run_id" dc:"Run ID, used to query audit logs"`
+	DryRun           bool           `json:"dry_run"`
+	ScannedMessages  int            `json:"scanned_messages" dc:"Messages older than the retention threshold that were checked"`
+	ScrubbedMessages int            `json:"scrubbed_messages" dc:"Messages containing PII (masked unless dry_run)"`
+	Masked           map[string]int `json:"masked" dc:"Masked occurrences per PII type: email / phone / id_number"`
+}
+
+// PIIScrubLogListReq 查询个人信息脱敏审计记录
+type PIIScrubLogListReq struct {
+	g.Meta    `path:"/v1/pii-scrub/logs" method:"get" tags:"retention" summary:"List PII scrubbing audit logs"`
+	RunID     string `json:"run_id" dc:"Run ID filter (optional)"`
+	ConvID    string `json:"conv_id" dc:"Conversation ID filter (optional)"`
+	ProjectID string `json:"project_id" dc:"Project ID filter (optional)"`
+	Page      int    `json:"page" v:"min:1" d:"1" dc:"Page number"`
+	PageSize  int    `json:"page_size" v:"min:1|max:100" d:"20" dc:"Page size"`
+}
+
+type PIIScrubLogListRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*PIIScrubLogItem `json:"list" dc:"Audit logs, newest first"`
+	Total  int64              `json:"total"`
+	Page   int                `json:"page"`
+}
+
+// PIIScrubLogItem 单条消息的脱敏记录，不包含原文
+type PIIScrubLogItem struct {
+	RunID      string         `json:"run_id"`
+	ConvID     string         `json:"conv_id"`
+	MsgID      string         `json:"msg_id"`
+	ProjectID  string         `json:"project_id,omitempty"`
+	Fields     []string       `json:"fields"` // 被脱敏的字段：content / metadata / content_metadata
+	Counts     map[string]int `json:"counts"` // 各类信息的脱敏数量
+	CreateTime string         `json:"create_time"`
+}
@@ -0,0 +1,217 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ProjectSettings 项目默认设置，对话请求未指定的参数使用项目默认值（请求参数 > 会话模型 > 项目默认值 > 全局配置）
+type ProjectSettings struct {
+	ModelID           string  `json:"model_id,omitempty"`            // 默认对话模型（仅用于尚未选择模型的会话）
+	EmbeddingModelID  string  `json:"embedding_model_id,omitempty"`  // 默认 embedding 模型
+	RerankModelID     string  `json:"rerank_model_id,omitempty"`     // 默认 rerank 模型
+	KnowledgeID       string  `json:"knowledge_id,omitempty"`        // 默认知识库
+	PersonaID         string  `json:"persona_id,omitempty"`          // 默认人设
+	TopK              int     `json:"top_k,omitempty"`               // 默认检索数量
+	Score             float64 `json:"score,omitempty"`               // 默认检索分数阈值
+	RetrieveMode      string  `json:"retrieve_mode,omitempty"`       // 默认检索模式：milvus/rerank/rrf/hybrid
+	MaxToolIterations int     `json:"max_tool_iterations,omitempty"` // 默认工具调用最大轮数
+
+	PIIScrub *PIIScrubPolicy `json:"pii_scrub,omitempty"` // 会话个人信息脱敏策略（可选），未设置的项使用 piiScrub 全局配置
+}
+
+// PIIScrubPolicy 项目的会话个人信息脱敏策略，作用于模型属于该项目的会话
+type PIIScrubPolicy struct {
+	Enabled   *bool    `json:"enabled,omitempty"`    // 是否脱敏（可选，为空时使用全局配置）
+	AfterDays int      `json:"after_days,omitempty"` // 消息保存多少天后脱敏（可选，为 0 时使用全局配置）
+	Types     []string `json:"types,omitempty"`      // 脱敏的信息类型：email / phone / id_number（可选，为空时使用全局配置）
+}
+
+// ProjectQuota 项目配额，0 表示不限制
+type ProjectQuota struct {
+	MonthlyTokens  int64   `json:"monthly_tokens,omitempty"`   // 每月对话 token 预算
+	StorageGB      float64 `json:"storage_gb,omitempty"`       // 知识库向量存储上限（GB，按分片数 × 单个向量字节数 + 分片内容估算）
+	DailyQueries   int64   `json:"daily_queries,omitempty"`    // 每日对话次数
+	DailyToolCalls int64   `json:"daily_tool_calls,omitempty"` // 每日 MCP 工具调用次数
+}
+
+// QuotaUsage 单项配额的用量
+type QuotaUsage struct {
+	Metric   string  `json:"metric"`           // tokens / storage / queries / tool_calls
+	Period   string  `json:"period,omitempty"` // 统计周期：月度 yyyy-MM，每日 yyyy-MM-dd，存储为空
+	Unit     string  `json:"unit"`             // tokens / GB / queries / calls
+	Used     float64 `json:"used"`
+	Limit    float64 `json:"limit"` // 0 表示不限制
+	Exceeded bool    `json:"exceeded"`
+}
+
+// ProjectItem 项目
+type ProjectItem struct {
+	ProjectID     string           `json:"project_id"`
+	Name          string           `json:"name"`
+	Description   string           `json:"description,omitempty"`
+	Settings      *ProjectSettings `json:"settings"`
+	Quota         *ProjectQuota    `json:"quota"`
+	ResourceCount map[string]int   `json:"resource_count,omitempty"` // 各类资源数量（详情接口返回）
+	Members       []*ProjectMember `json:"members,omitempty"`        // 项目成员（详情接口返回）
+	CreatedAt     string           `json:"created_at,omitempty"`
+	UpdatedAt     string           `json:"updated_at,omitempty"`
+}
+
+// ProjectMember 项目成员
+type ProjectMember struct {
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"` // owner / editor / viewer
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// ProjectResourceItem 项目资源
+type ProjectResourceItem struct {
+	ResourceType string `json:"resource_type"` // knowledge_base / model / mcp_service / persona
+	ResourceID   string `json:"resource_id"`
+	Name         string `json:"name"`              // 资源名称，资源已被删除时为空
+	Missing      bool   `json:"missing,omitempty"` // 资源已不存在
+}
+
+// ProjectCreateReq 创建项目请求
+type ProjectCreateReq struct {
+	g.Meta      `path:"/v1/projects" method:"post" tags:"project" summary:"Create a project grouping models, knowledge bases and tools"`
+	Name        string           `json:"name" v:"required|length:1,100"`
+	Description string           `json:"description" v:"length:0,500"`
+	Settings    *ProjectSettings `json:"settings"` // 默认设置（可选）
+	Quota       *ProjectQuota    `json:"quota"`    // 配额（可选）
+}
+
+// ProjectCreateRes 创建项目响应
+type ProjectCreateRes struct {
+	g.Meta  `mime:"application/json"`
+	Project *ProjectItem `json:"project"`
+}
+
+// ProjectUpdateReq 更新项目请求，未传的字段保持不变，settings 和 quota 整体替换
+type ProjectUpdateReq struct {
+	g.Meta      `path:"/v1/projects/:project_id" method:"put" tags:"project" summary:"Update a project"`
+	ProjectID   string           `json:"project_id" v:"required"`
+	Name        *string          `json:"name" v:"length:1,100"`
+	Description *string          `json:"description" v:"length:0,500"`
+	Settings    *ProjectSettings `json:"settings"`
+	Quota       *ProjectQuota    `json:"quota"`
+}
+
+// ProjectUpdateRes 更新项目响应
+type ProjectUpdateRes struct {
+	g.Meta  `mime:"application/json"`
+	Project *ProjectItem `json:"project"`
+}
+
+// ProjectDeleteReq 删除项目请求，项目中的资源不会被删除
+type ProjectDeleteReq struct {
+	g.Meta    `path:"/v1/projects/:project_id" method:"delete" tags:"project" summary:"Delete a project (resources are kept)"`
+	ProjectID string `json:"project_id" v:"required"`
+}
+
+// ProjectDeleteRes 删除项目响应
+type ProjectDeleteRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ProjectGetReq 获取项目详情请求
+type ProjectGetReq struct {
+	g.Meta    `path:"/v1/projects/:project_id" method:"get" tags:"project" summary:"Get a project with resource counts and members"`
+	ProjectID string `json:"project_id" v:"required"`
+}
+
+// ProjectGetRes 获取项目详情响应
+type ProjectGetRes struct {
+	g.Meta  `mime:"application/json"`
+	Project *ProjectItem `json:"project"`
+}
+
+// ProjectListReq 项目列表请求
+type ProjectListReq struct {
+	g.Meta `path:"/v1/projects" method:"get" tags:"project" summary:"List projects"`
+	UserID string `json:"user_id"` // 只返回该成员参与的项目（可选）
+}
+
+// ProjectListRes 项目列表响应
+type ProjectListRes struct {
+	g.Meta   `mime:"application/json"`
+	Projects []*ProjectItem `json:"projects"`
+}
+
+// ProjectResourceAddReq 把资源加入项目，已属于其他项目的资源会移动到该项目
+type ProjectResourceAddReq struct {
+	g.Meta       `path:"/v1/projects/:project_id/resources" method:"post" tags:"project" summary:"Add resources to a project"`
+	ProjectID    string   `json:"project_id" v:"required"`
+	ResourceType string   `json:"resource_type" v:"required|in:knowledge_base,model,mcp_service,persona"`
+	ResourceIDs  []string `json:"resource_ids" v:"required"`
+}
+
+// ProjectResourceAddRes 加入资源响应
+type ProjectResourceAddRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ProjectResourceRemoveReq 把资源移出项目
+type ProjectResourceRemoveReq struct {
+	g.Meta       `path:"/v1/projects/:project_id/resources" method:"delete" tags:"project" summary:"Remove resources from a project"`
+	ProjectID    string   `json:"project_id" v:"required"`
+	ResourceType string   `json:"resource_type" v:"required|in:knowledge_base,model,mcp_service,persona"`
+	ResourceIDs  []string `json:"resource_ids" v:"required"`
+}
+
+// ProjectResourceRemoveRes 移出资源响应
+type ProjectResourceRemoveRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ProjectResourceListReq 获取项目资源请求
+type ProjectResourceListReq struct {
+	g.Meta       `path:"/v1/projects/:project_id/resources" method:"get" tags:"project" summary:"List resources in a project"`
+	ProjectID    string `json:"project_id" v:"required"`
+	ResourceType string `json:"resource_type" v:"in:knowledge_base,model,mcp_service,persona"` // 按类型过滤（可选）
+}
+
+// ProjectResourceListRes 获取项目资源响应
+type ProjectResourceListRes struct {
+	g.Meta    `mime:"application/json"`
+	Resources []*ProjectResourceItem `json:"resources"`
+}
+
+// ProjectMemberSaveReq 添加项目成员或修改成员角色
+type ProjectMemberSaveReq struct {
+	g.Meta    `path:"/v1/projects/:project_id/members" method:"put" tags:"project" summary:"Add a project member or change its role"`
+	ProjectID string `json:"project_id" v:"required"`
+	UserID    string `json:"user_id" v:"required|length:1,100"`
+	Role      string `json:"role" v:"required|in:owner,editor,viewer"`
+}
+
+// ProjectMemberSaveRes 保存项目成员响应
+type ProjectMemberSaveRes struct {
+	g.Meta `mime:"application/json"`
+	Member *ProjectMember `json:"member"`
+}
+
+// ProjectMemberRemoveReq 移除项目成员
+type ProjectMemberRemoveReq struct {
+	g.Meta    `path:"/v1/projects/:project_id/members/:user_id" method:"delete" tags:"project" summary:"Remove a project member"`
+	ProjectID string `json:"project_id" v:"required"`
+	UserID    string `json:"user_id" v:"required"`
+}
+
+// ProjectMemberRemoveRes 移除项目成员响应
+type ProjectMemberRemoveRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ProjectUsageReq 获取项目用量与配额请求
+type ProjectUsageReq struct {
+	g.Meta    `path:"/v1/projects/:project_id/usage" method:"get" tags:"project" summary:"Get project usage versus quota"`
+	ProjectID string `json:"project_id" v:"required"`
+}
+
+// ProjectUsageRes 获取项目用量与配额响应
+type ProjectUsageRes struct {
+	g.Meta `mime:"application/json"`
+	Quota  *ProjectQuota `json:"quota"`
+	Usage  []*QuotaUsage `json:"usage"`
+}
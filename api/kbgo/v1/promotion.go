@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// PromotionSource 沉淀的回答引用的参考分片
+type PromotionSource struct {
+	ChunkID string  `json:"chunk_id"`
+	Score   float32 `json:"score"`
+}
+
+// PromotionItem 回答沉淀申请
+type PromotionItem struct {
+	PromotionID    string             `json:"promotion_id"`
+	KnowledgeId    string             `json:"knowledge_id"`
+	Kind           string             `json:"kind"`   // answer：提交的助手回答；correction：对话中捕获的用户更正
+	MsgID          string             `json:"msg_id"` // 来源助手消息ID（更正申请为被更正的回答）
+	ConvID         string             `json:"conv_id"`
+	Question       string             `json:"question"`
+	Answer         string             `json:"answer"`
+	OriginalAnswer string             `json:"original_answer,omitempty"` // 被更正的助手回答（仅更正申请）
+	Sources        []*PromotionSource `json:"sources,omitempty"`         // 回答引用的参考分片
+	Status         string             `json:"status"`                    // pending / approved / rejected
+	RequestedBy    string             `json:"requested_by,omitempty"`    // 提交人
+	Reviewer       string             `json:"reviewer,omitempty"`        // 审核人
+	ReviewComment  string             `json:"review_comment,omitempty"`  // 审核意见
+	DocumentID     string             `json:"document_id,omitempty"`     // 写入的 FAQ 文档ID
+	ChunkID        string             `json:"chunk_id,omitempty"`        // 写入的分片ID
+	LastError      string             `json:"last_error,omitempty"`      // 最近一次写入知识库失败的原因
+	CreatedAt      string             `json:"created_at,omitempty"`
+	ReviewedAt     string             `json:"reviewed_at,omitempty"`
+}
+
+// PromoteMessageReq 将助手回答沉淀到知识库请求
+type PromoteMessageReq struct {
+	g.Meta      `path:"/v1/messages/:msg_id/promote" method:"post" tags:"promotion" summary:"Submit an assistant answer to be curated into a knowledge base as an FAQ chunk"`
+	MsgID       string `json:"msg_id" v:"required"`       // 助手消息ID
+	KnowledgeId string `json:"knowledge_id" v:"required"` // 目标知识库ID
+	Question    string `json:"question"`                  // FAQ 问题（可选，默认为用户原始问题）
+	Answer      string `json:"answer"`                    // FAQ 答案（可选，默认为助手回答）
+	RequestedBy string `json:"requested_by"`              // 提交人（按用户隔离时忽略，使用认证用户）
+}
+
+// PromoteMessageRes 将助手回答沉淀到知识库响应
+type PromoteMessageRes struct {
+	g.Meta    `mime:"application/json"`
+	Promotion *PromotionItem `json:"promotion"`
+}
+
+// PromotionListReq 回答沉淀申请列表请求
+type PromotionListReq struct {
+	g.Meta      `path:"/v1/promotions" method:"get" tags:"promotion" summary:"List answer promotions and captured chat corrections"`
+	KnowledgeId string `json:"knowledge_id"`                  // 按知识库过滤（可选）
+	Status      string `json:"status"`                        // 按状态过滤（可选）：pending/approved/rejected
+	Kind        string `json:"kind" v:"in:answer,correction"` // 按类型过滤（可选）：answer/correction
+}
+
+// PromotionListRes 回答沉淀申请列表响应
+type PromotionListRes struct {
+	g.Meta     `mime:"application/json"`
+	Promotions []*PromotionItem `json:"promotions"`
+}
+
+// PromotionApproveReq 审核通过回答沉淀申请请求
+type PromotionApproveReq struct {
+	g.Meta           `path:"/v1/promotions/:promotion_id/approve" method:"post" tags:"promotion" summary:"Approve an answer promotion and write it into the knowledge base"`
+	PromotionID      string `json:"promotion_id" v:"required"` // 申请ID
+	Reviewer         string `json:"reviewer"`                  // 审核人（按用户隔离时忽略，使用认证用户）
+	Comment          string `json:"comment"`                   // 审核意见
+	Question         string `json:"question"`                  // 修改后的问题（可选）
+	Answer           string `json:"answer"`                    // 修改后的答案（可选）
+	EmbeddingModelID string `json:"embedding_model_id"`        // Embedding模型UUID（可选，默认使用知识库最近索引文档的模型）
+}
+
+// PromotionApproveRes 审核通过回答沉淀申请响应
+type PromotionApproveRes struct {
+	g.Meta    `mime:"application/json"`
+	Promotion *PromotionItem `json:"promotion"`
+}
+
+// PromotionRejectReq 驳回回答沉淀申请请求
+type PromotionRejectReq struct {
+	g.Meta      `path:"/v1/promotions/:promotion_id/reject" method:"post" tags:"promotion" summary:"Reject an answer promotion"`
+	PromotionID string `json:"promotion_id" v:"required"` // 申请ID
+	Reviewer    string `json:"reviewer"`                  // 审核人（按用户隔离时忽略，使用认证用户）
+	Comment     string `json:"comment"`                   // 驳回原因
+}
+
+// PromotionRejectRes 驳回回答沉淀申请响应
+type PromotionRejectRes struct {
+	g.Meta    `mime:"application/json"`
+	Promotion *PromotionItem `json:"promotion"`
+}
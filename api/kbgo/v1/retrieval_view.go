@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// RetrievalViewItem 保存的检索视图（智能集合）
+type RetrievalViewItem struct {
+	ViewID           string                  `json:"view_id"`
+	Name             string                  `json:"name"`
+	Description      string                  `json:"description,omitempty"`
+	KnowledgeIds     []string                `json:"knowledge_ids"`                // 检索的知识库，多个知识库的结果按分数合并
+	MetadataFilter   *DocumentMetadataFilter `json:"metadata_filter,omitempty"`    // 文档元数据过滤条件
+	EmbeddingModelID string                  `json:"embedding_model_id,omitempty"` // 为空时使用知识库最近索引文档的模型
+	RerankModelID    string                  `json:"rerank_model_id,omitempty"`
+	RetrieveMode     string                  `json:"retrieve_mode,omitempty"` // milvus / rerank / rrf
+	TopK             int                     `json:"top_k,omitempty"`
+	Score            float64                 `json:"score,omitempty"`
+	EnableRewrite    bool                    `json:"enable_rewrite"`
+	CreatedAt        string                  `json:"created_at,omitempty"`
+	UpdatedAt        string                  `json:"updated_at,omitempty"`
+}
+
+// RetrievalViewCreateReq 创建检索视图请求
+type RetrievalViewCreateReq struct {
+	g.Meta           `path:"/v1/retrieval-views" method:"post" tags:"retrieval_view" summary:"Create a saved retrieval view (knowledge bases, metadata filter and retrieval settings)"`
+	Name             string                  `json:"name" v:"required|length:1,100"`                // 视图名称（唯一），对话和检索请求通过 retrieval_view 引用
+	Description      string                  `json:"description" v:"length:0,500"`                  // 视图说明
+	KnowledgeIds     []string                `json:"knowledge_ids" v:"required"`                    // 检索的知识库ID列表
+	MetadataFilter   *DocumentMetadataFilter `json:"metadata_filter"`                               // 文档元数据过滤条件（可选）
+	EmbeddingModelID string                  `json:"embedding_model_id"`                            // Embedding模型UUID（可选，默认使用第一个知识库最近索引文档的模型）
+	RerankModelID    string                  `json:"rerank_model_id"`                               // Rerank模型UUID（retrieve_mode 为 rerank 或 rrf 时需要）
+	RetrieveMode     string                  `json:"retrieve_mode" v:"in:milvus,rerank,rrf,hybrid"` // 检索模式（可选）
+	TopK             int                     `json:"top_k" v:"min:0"`                               // 返回数量（可选）
+	Score            float64                 `json:"score" v:"min:0"`                               // 最低分数（可选）
+	EnableRewrite    bool                    `json:"enable_rewrite"`                                // 是否启用查询重写
+}
+
+// RetrievalViewCreateRes 创建检索视图响应
+type RetrievalViewCreateRes struct {
+	g.Meta `mime:"application/json"`
+	View   *RetrievalViewItem `json:"view"`
+}
+
+// RetrievalViewUpdateReq 更新检索视图请求，未传的字段保持不变
+type RetrievalViewUpdateReq struct {
+	g.Meta           `path:"/v1/retrieval-views/:view_id" method:"put" tags:"retrieval_view" summary:"Update a saved retrieval view"`
+	ViewID           string                  `json:"view_id" v:"required"`
+	Name             *string                 `json:"name" v:"length:1,100"`
+	Description      *string                 `json:"description" v:"length:0,500"`
+	KnowledgeIds     []string                `json:"knowledge_ids"`   // 为空时保持不变
+	MetadataFilter   *DocumentMetadataFilter `json:"metadata_filter"` // 传空对象时清除过滤条件
+	EmbeddingModelID *string                 `json:"embedding_model_id"`
+	RerankModelID    *string                 `json:"rerank_model_id"`
+	RetrieveMode     *string                 `json:"retrieve_mode" v:"in:milvus,rerank,rrf,hybrid"`
+	TopK             *int                    `json:"top_k" v:"min:0"`
+	Score            *float64                `json:"score" v:"min:0"`
+	EnableRewrite    *bool                   `json:"enable_rewrite"`
+}
+
+// RetrievalViewUpdateRes 更新检索视图响应
+type RetrievalViewUpdateRes struct {
+	g.Meta `mime:"application/json"`
+	View   *RetrievalViewItem `json:"view"`
+}
+
+// RetrievalViewDeleteReq 删除检索视图请求
+type RetrievalViewDeleteReq struct {
+	g.Meta `path:"/v1/retrieval-views/:view_id" method:"delete" tags:"retrieval_view" summary:"Delete a saved retrieval view"`
+	ViewID string `json:"view_id" v:"required"`
+}
+
+// RetrievalViewDeleteRes 删除检索视图响应
+type RetrievalViewDeleteRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// RetrievalViewGetReq 获取检索视图请求
+type RetrievalViewGetReq struct {
+	g.Meta `path:"/v1/retrieval-views/:view_id" method:"get" tags:"retrieval_view" summary:"Get a saved retrieval view by ID or name"`
+	ViewID string `json:"view_id" v:"required"` // 视图ID或名称
+}
+
+// RetrievalViewGetRes 获取检索视图响应
+type RetrievalViewGetRes struct {
+	g.Meta `mime:"application/json"`
+	View   *RetrievalViewItem `json:"view"`
+}
+
+// RetrievalViewListReq 检索视图列表请求
+type RetrievalViewListReq struct {
+	g.Meta `path:"/v1/retrieval-views" method:"get" tags:"retrieval_view" summary:"List saved retrieval views"`
+}
+
+// RetrievalViewListRes 检索视图列表响应
+type RetrievalViewListRes struct {
+	g.Meta `mime:"application/json"`
+	Views  []*RetrievalViewItem `json:"views"`
+}
@@ -3,22 +3,53 @@ package v1
 import (
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
 )
 
 type RetrieverReq struct {
 	g.Meta           `path:"/v1/retriever" method:"post" tags:"retriever"`
-	Question         string  `json:"question" v:"required"`
-	EmbeddingModelID string  `json:"embedding_model_id" v:"required"` // Embedding模型UUID（必填）
-	RerankModelID    string  `json:"rerank_model_id"`                 // Rerank模型UUID（可选，仅在retrieve_mode为rerank或rrf时需要）
-	TopK             int     `json:"top_k"`                           // Default is 5
-	Score            float64 `json:"score"`                           // Default is 0.2
-	KnowledgeId      string  `json:"knowledge_id" v:"required"`
-	EnableRewrite    bool    `json:"enable_rewrite"`   // Whether to enable query rewriting (default false)
-	RewriteAttempts  int     `json:"rewrite_attempts"` // Number of query rewriting attempts (default 3, only effective when enable_rewrite=true)
-	RetrieveMode     string  `json:"retrieve_mode"`    // Retrieval mode: milvus/rerank/rrf (default rerank)
+	Question         string      `json:"question" v:"required"`
+	EmbeddingModelID string      `json:"embedding_model_id" v:"required-without:RetrievalView"` // Embedding模型UUID（未引用检索视图时必填）
+	RerankModelID    string      `json:"rerank_model_id"`                                       // Rerank模型UUID（可选，仅在retrieve_mode为rerank或rrf时需要）
+	TopK             int         `json:"top_k"`                                                 // Default is 5
+	Score            float64     `json:"score"`                                                 // Default is 0.2
+	KnowledgeId      string      `json:"knowledge_id" v:"required-without:RetrievalView"`
+	EnableRewrite    bool        `json:"enable_rewrite"`   // Whether to enable query rewriting (default false)
+	RewriteAttempts  int         `json:"rewrite_attempts"` // Number of query rewriting attempts (default 3, only effective when enable_rewrite=true)
+	RetrieveMode     string      `json:"retrieve_mode"`    // Retrieval mode: milvus/rerank/rrf/hybrid (keyword BM25 + vector, RRF fused, no rerank model needed) (default rerank)
+	AsOf             *gtime.Time `json:"as_of"`            // Retrieve the document versions valid at this time (default: latest versions)
+	// 按索引时提取的文档元数据过滤（需启用 metadataExtraction）
+	MetadataFilter *DocumentMetadataFilter `json:"metadata_filter"`
+	// 同时检索的其他知识库，结果与 knowledge_id 的结果按分数合并后取 top_k；各知识库优先使用其最近索引文档的 embedding 模型
+	KnowledgeIds []string `json:"knowledge_ids"`
+	// 引用的检索视图名称或ID，视图的知识库参与检索，请求未指定的模型、过滤条件和检索参数使用视图设置
+	RetrievalView string `json:"retrieval_view"`
+	// 是否在每个结果分片的 metadata.explain 中返回匹配说明：命中的关键词、各阶段分数、重排序分数变化、生效的加权和过滤条件
+	Explain bool `json:"explain"`
+	// 是否启用自适应分数阈值：通过阈值的结果太少时自动降低阈值（不低于下限），结果充足时提高阈值，不传时使用 retriever.adaptiveScore.enabled
+	AdaptiveScore *bool `json:"adaptive_score"`
+}
+
+// DocumentMetadataFilter Filter retrieval results by the metadata extracted at ingestion, all conditions must match
+type DocumentMetadataFilter struct {
+	Title    string   `json:"title" dc:"title contains (case-insensitive)"`
+	Author   string   `json:"author" dc:"author contains (case-insensitive)"`
+	Topics   []string `json:"topics" dc:"has any of these topics"`
+	DateFrom string   `json:"date_from" dc:"document date on or after, e.g. 2024-01-01"`
+	DateTo   string   `json:"date_to" dc:"document date on or before, e.g. 2024-12-31"`
 }
 
 type RetrieverRes struct {
 	g.Meta   `mime:"application/json"`
 	Document []*schema.Document `json:"document"`
+	// 启用自适应分数阈值时返回实际使用的阈值，用于调整阈值配置
+	ScoreThreshold *ScoreThreshold `json:"score_threshold,omitempty"`
+}
+
+// ScoreThreshold 自适应分数阈值的调整结果
+type ScoreThreshold struct {
+	Configured float64 `json:"configured"` // 请求或配置的阈值
+	Effective  float64 `json:"effective"`  // 实际使用的阈值
+	Adjustment string  `json:"adjustment"` // lowered / raised / unchanged
+	Candidates int     `json:"candidates"` // 不低于阈值下限的候选结果数
 }
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ShadowResultListReq 查询影子评估结果（线上回答与候选模型回答对比）
+type ShadowResultListReq struct {
+	g.Meta           `path:"/v1/shadow/results" method:"get" tags:"shadow" summary:"List shadow evaluation results"`
+	ConvID           string `json:"conv_id" dc:"Conversation ID filter (optional)"`
+	CandidateModelID string `json:"candidate_model_id" dc:"Candidate model ID filter (optional)"`
+	Page             int    `json:"page" v:"min:1" d:"1" dc:"Page number"`
+	PageSize         int    `json:"page_size" v:"min:1|max:100" d:"20" dc:"Page size"`
+}
+
+type ShadowResultListRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*ShadowResultItem `json:"list" dc:"Results sorted by create time, newest first"`
+	Total  int64               `json:"total"`
+	Page   int                 `json:"page"`
+}
+
+// ShadowResultItem 单次影子评估结果
+type ShadowResultItem struct {
+	Id                    string   `json:"id"`
+	ConvID                string   `json:"conv_id"`
+	Question              string   `json:"question"`
+	LiveModelID           string   `json:"live_model_id"`
+	LiveAnswer            string   `json:"live_answer"`
+	LiveLatencyMs         int64    `json:"live_latency_ms"`
+	LiveGroundedness      *float64 `json:"live_groundedness,omitempty"`
+	CandidateModelID      string   `json:"candidate_model_id"`
+	CandidateAnswer       string   `json:"candidate_answer"`
+	CandidateLatencyMs    int64    `json:"candidate_latency_ms"`
+	CandidateTokens       int      `json:"candidate_tokens"`
+	CandidateGroundedness *float64 `json:"candidate_groundedness,omitempty"`
+	Agreement             float64  `json:"agreement"` // 两个回答的字面相似度（0-1）
+	Error                 string   `json:"error,omitempty"`
+	CreateTime            string   `json:"create_time"`
+}
+
+// ShadowSummaryReq 按线上模型和候选模型汇总影子评估指标
+type ShadowSummaryReq struct {
+	g.Meta    `path:"/v1/shadow/summary" method:"get" tags:"shadow" summary:"Summarize shadow evaluation per live and candidate model"`
+	StartDate string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate   string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+}
+
+type ShadowSummaryRes struct {
+	g.Meta `mime:"application/json"`
+	List   []*ShadowSummaryItem `json:"list"`
+}
+
+// ShadowSummaryItem 线上模型与候选模型的对比指标，一致性和相似度只统计有值的样本
+type ShadowSummaryItem struct {
+	LiveModelID              string   `json:"live_model_id"`
+	CandidateModelID         string   `json:"candidate_model_id"`
+	SampleCount              int64    `json:"sample_count"`
+	ErrorCount               int64    `json:"error_count"` // 候选模型调用失败数
+	AvgLiveLatencyMs         float64  `json:"avg_live_latency_ms"`
+	AvgCandidateLatencyMs    float64  `json:"avg_candidate_latency_ms"`
+	AvgCandidateTokens       float64  `json:"avg_candidate_tokens"`
+	AvgLiveGroundedness      *float64 `json:"avg_live_groundedness,omitempty"`
+	AvgCandidateGroundedness *float64 `json:"avg_candidate_groundedness,omitempty"`
+	AvgAgreement             float64  `json:"avg_agreement"`
+}
@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ToolConfig 一项工具配置，对应对话中可用的一类工具
+type ToolConfig struct {
+	Type          string   `json:"type" v:"required"` // 工具类型：knowledge_base / retrieval_view / mcp / local（本地工具和插件）
+	KnowledgeIDs  []string `json:"knowledge_ids"`     // knowledge_base：检索的知识库ID
+	RetrievalView string   `json:"retrieval_view"`    // retrieval_view：检索视图名称或ID
+	ServiceName   string   `json:"service_name"`      // mcp / local：服务名
+	ToolNames     []string `json:"tool_names"`        // mcp / local：使用的工具，为空表示服务提供的全部工具
+}
+
+// ToolConfigValidateReq 校验工具配置请求：在保存或使用前检查配置引用的资源是否存在且可用
+type ToolConfigValidateReq struct {
+	g.Meta `path:"/v1/tool-configs/validate" method:"post" tags:"mcp" summary:"Validate tool configs and return a readiness report"`
+	Tools  []*ToolConfig `json:"tools" v:"required"` // 完整的工具配置列表
+}
+
+// ToolConfigValidateRes 工具配置就绪报告
+type ToolConfigValidateRes struct {
+	g.Meta `mime:"application/json"`
+	Ready  bool                `json:"ready"` // 所有配置项是否都已就绪
+	Tools  []*ToolConfigReport `json:"tools"` // 按请求顺序排列的各配置项报告
+}
+
+// ToolConfigReport 一项工具配置的校验结果
+type ToolConfigReport struct {
+	Index  int                `json:"index"` // 配置项在请求中的位置（从 0 开始）
+	Type   string             `json:"type"`
+	Ready  bool               `json:"ready"`
+	Checks []*ToolConfigCheck `json:"checks"`
+}
+
+// ToolConfigCheck 一项检查，Target 为被检查的资源，如 knowledge_base:kb1、mcp:weather/get_weather
+type ToolConfigCheck struct {
+	Target  string `json:"target"`
+	Status  string `json:"status"` // ok / error
+	Message string `json:"message,omitempty"`
+}
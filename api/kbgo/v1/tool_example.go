@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ToolExampleItem 工具调用示例
+type ToolExampleItem struct {
+	ExampleID   string                 `json:"example_id"`
+	ModelID     string                 `json:"model_id"` // 适用的模型，为空时适用于所有模型
+	Question    string                 `json:"question"`
+	ServiceName string                 `json:"service_name"`
+	ToolName    string                 `json:"tool_name"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"`
+	Note        string                 `json:"note,omitempty"`
+	Enabled     bool                   `json:"enabled"`
+	CreatedAt   string                 `json:"created_at,omitempty"`
+	UpdatedAt   string                 `json:"updated_at,omitempty"`
+}
+
+// ToolExampleCreateReq 创建工具调用示例请求
+type ToolExampleCreateReq struct {
+	g.Meta      `path:"/v1/mcp/examples" method:"post" tags:"mcp" summary:"Create a few-shot tool-use example"`
+	ModelID     string                 `json:"model_id"`                  // 适用的模型（可选，为空时适用于所有模型）
+	Question    string                 `json:"question" v:"required"`     // 示例问题
+	ServiceName string                 `json:"service_name" v:"required"` // MCP 服务名（本地工具为 local）
+	ToolName    string                 `json:"tool_name" v:"required"`    // 工具名
+	Arguments   map[string]interface{} `json:"arguments"`                 // 调用参数
+	Note        string                 `json:"note" v:"length:0,500"`     // 说明（可选）
+	Enabled     *bool                  `json:"enabled"`                   // 是否启用（默认 true）
+}
+
+// ToolExampleCreateRes 创建工具调用示例响应
+type ToolExampleCreateRes struct {
+	g.Meta  `mime:"application/json"`
+	Example *ToolExampleItem `json:"example"`
+}
+
+// ToolExampleUpdateReq 更新工具调用示例请求，未传的字段保持不变
+type ToolExampleUpdateReq struct {
+	g.Meta      `path:"/v1/mcp/examples/:example_id" method:"put" tags:"mcp" summary:"Update a few-shot tool-use example"`
+	ExampleID   string                 `json:"example_id" v:"required"`
+	ModelID     *string                `json:"model_id"`
+	Question    *string                `json:"question"`
+	ServiceName *string                `json:"service_name"`
+	ToolName    *string                `json:"tool_name"`
+	Arguments   map[string]interface{} `json:"arguments"`
+	Note        *string                `json:"note" v:"length:0,500"`
+	Enabled     *bool                  `json:"enabled"`
+}
+
+// ToolExampleUpdateRes 更新工具调用示例响应
+type ToolExampleUpdateRes struct {
+	g.Meta  `mime:"application/json"`
+	Example *ToolExampleItem `json:"example"`
+}
+
+// ToolExampleDeleteReq 删除工具调用示例请求
+type ToolExampleDeleteReq struct {
+	g.Meta    `path:"/v1/mcp/examples/:example_id" method:"delete" tags:"mcp" summary:"Delete a few-shot tool-use example"`
+	ExampleID string `json:"example_id" v:"required"`
+}
+
+// ToolExampleDeleteRes 删除工具调用示例响应
+type ToolExampleDeleteRes struct {
+	g.Meta `mime:"application/json"`
+}
+
+// ToolExampleListReq 工具调用示例列表请求
+type ToolExampleListReq struct {
+	g.Meta      `path:"/v1/mcp/examples" method:"get" tags:"mcp" summary:"List few-shot tool-use examples"`
+	ModelID     string `json:"model_id"`     // 按模型过滤（可选）
+	ServiceName string `json:"service_name"` // 按服务过滤（可选）
+}
+
+// ToolExampleListRes 工具调用示例列表响应
+type ToolExampleListRes struct {
+	g.Meta   `mime:"application/json"`
+	Examples []*ToolExampleItem `json:"examples"`
+}
+
+// ToolExampleTestCase 测试问题，可指定期望调用的工具
+type ToolExampleTestCase struct {
+	Question        string `json:"question" v:"required"`
+	ExpectedService string `json:"expected_service"` // 期望的服务名（可选）
+	ExpectedTool    string `json:"expected_tool"`    // 期望的工具名（可选）
+}
+
+// ToolExampleTestReq 用样例问题测试工具调用示例的效果（只生成工具调用，不执行工具）
+type ToolExampleTestReq struct {
+	g.Meta          `path:"/v1/mcp/examples/test" method:"post" tags:"mcp" summary:"Test tool selection with few-shot examples against sample questions"`
+	ModelID         string                 `json:"model_id" v:"required"` // 对话模型
+	Cases           []*ToolExampleTestCase `json:"cases" v:"required"`    // 测试问题
+	MCPServiceTools map[string][]string    `json:"mcp_service_tools"`     // 按服务限制可选工具（可选）
+	Compare         bool                   `json:"compare"`               // 是否同时测试不注入示例的结果用于对比
+}
+
+// ToolCallPreview LLM 生成的工具调用
+type ToolCallPreview struct {
+	ServiceName string `json:"service_name"`
+	ToolName    string `json:"tool_name"`
+	Arguments   string `json:"arguments"`
+}
+
+// ToolExampleTestResult 单个测试问题的结果
+type ToolExampleTestResult struct {
+	Question          string             `json:"question"`
+	ToolCalls         []*ToolCallPreview `json:"tool_calls"`                    // 注入示例时的工具调用
+	ExampleIDs        []string           `json:"example_ids"`                   // 注入的示例
+	Matched           *bool              `json:"matched,omitempty"`             // 是否调用了期望的工具（指定期望工具时返回）
+	BaselineToolCalls []*ToolCallPreview `json:"baseline_tool_calls,omitempty"` // 不注入示例时的工具调用（compare 为 true 时返回）
+	BaselineMatched   *bool              `json:"baseline_matched,omitempty"`
+	Error             string             `json:"error,omitempty"`
+}
+
+// ToolExampleTestRes 测试工具调用示例响应
+type ToolExampleTestRes struct {
+	g.Meta           `mime:"application/json"`
+	Results          []*ToolExampleTestResult `json:"results"`
+	Accuracy         *float64                 `json:"accuracy,omitempty"`          // 指定期望工具的问题中调用正确的比例
+	BaselineAccuracy *float64                 `json:"baseline_accuracy,omitempty"` // 不注入示例时的准确率（compare 为 true 时返回）
+}
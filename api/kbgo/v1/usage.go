@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// UsageReq token 用量查询请求（按天、按用户、按模型）
+type UsageReq struct {
+	g.Meta    `path:"/v1/usage" method:"get" tags:"usage" summary:"Get daily token usage per user and model"`
+	StartDate string `json:"start_date" v:"required|date-format:Y-m-d" dc:"Start date (yyyy-MM-dd)"`
+	EndDate   string `json:"end_date" v:"required|date-format:Y-m-d" dc:"End date (yyyy-MM-dd), inclusive"`
+	UserID    string `json:"user_id" dc:"User ID filter (optional); authenticated callers can only query their own usage"`
+	ModelID   string `json:"model_id" dc:"Model ID filter (optional)"`
+}
+
+type UsageRes struct {
+	g.Meta      `mime:"application/json"`
+	List        []*UsageItem `json:"list" dc:"Daily usage rows"`
+	TotalTokens int64        `json:"total_tokens" dc:"Total tokens of the listed rows"`
+	Quota       *QuotaUsage  `json:"quota,omitempty" dc:"Monthly token quota of the user (returned when user_id is set)"`
+}
+
+// UsageItem 单日、单用户、单模型的 token 用量
+type UsageItem struct {
+	StatDate       string `json:"stat_date"`
+	UserID         string `json:"user_id"`
+	ModelID        string `json:"model_id"`
+	ModelName      string `json:"model_name"`
+	MessageCount   int64  `json:"message_count"`
+	DirectRequests int64  `json:"direct_requests"` // 直接调用模型（如 /v1/chat/completions 透传请求）的请求数，不保存会话消息
+	TotalTokens    int64  `json:"total_tokens"`
+}
+
+// UsageRollupReq 手动重新汇总指定日期的 token 用量
+type UsageRollupReq struct {
+	g.Meta `path:"/v1/usage/rollup" method:"post" tags:"usage" summary:"Rebuild token usage for a date"`
+	Date   string `json:"date" v:"required|date-format:Y-m-d" dc:"Date to rebuild (yyyy-MM-dd)"`
+}
+
+type UsageRollupRes struct {
+	g.Meta  `mime:"application/json"`
+	Success bool `json:"success"`
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/canned"
+	"github.com/Malowking/kbgo/internal/logic/promotion"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// CannedHandler 预置回答处理器
+type CannedHandler struct{}
+
+// NewCannedHandler 创建预置回答处理器
+func NewCannedHandler() *CannedHandler {
+	return &CannedHandler{}
+}
+
+// Intercept 问题与知识库中已审核的问答几乎相同时直接返回该回答，不调用检索和模型
+// 返回 nil 表示继续由完整流程回答；匹配出错时只记录日志，不影响正常回答
+func (h *CannedHandler) Intercept(ctx context.Context, req *v1.ChatReq) *v1.ChatRes {
+	match, err := canned.Find(ctx, req.KnowledgeId, req.EmbeddingModelID, req.Question)
+	if err != nil {
+		g.Log().Warningf(ctx, "Canned answer matching failed, falling back to assistant: %v", err)
+		return nil
+	}
+	if match == nil {
+		return nil
+	}
+
+	p := match.Promotion
+	g.Log().Infof(ctx, "Question matched approved promotion %s (score %.4f), returning canned answer", p.ID, match.Score)
+	attribution := &v1.CannedAnswer{
+		PromotionID:     p.ID,
+		KnowledgeID:     p.KnowledgeID,
+		DocumentID:      p.DocumentID,
+		ChunkID:         p.ChunkID,
+		MatchedQuestion: p.Question,
+		Score:           match.Score,
+		Reviewer:        p.Reviewer,
+	}
+
+	manager := history.NewManager()
+	if err = manager.SaveMessage(ctx, &schema.Message{Role: schema.User, Content: req.Question}, req.ConvID); err != nil {
+		g.Log().Errorf(ctx, "Failed to save user message: %v", err)
+	}
+	if err = manager.SaveMessageWithMetadata(ctx, &schema.Message{Role: schema.Assistant, Content: p.Answer}, req.ConvID, map[string]interface{}{
+		canned.MetadataKey: attribution,
+	}); err != nil {
+		g.Log().Errorf(ctx, "Failed to save canned answer message: %v", err)
+	}
+
+	return &v1.ChatRes{
+		Answer: p.Answer,
+		References: []*schema.Document{{
+			ID:      p.ChunkID,
+			Content: promotion.FAQContent(p.Question, p.Answer),
+			Score:   float32(match.Score),
+			MetaData: map[string]interface{}{
+				"source":       promotion.SourceCuratedFAQ,
+				"promotion_id": p.ID,
+				"document_id":  p.DocumentID,
+				"knowledge_id": p.KnowledgeID,
+			},
+		}},
+		CannedAnswer: attribution,
+	}
+}
+
+// StreamAnswer 以流式响应返回预置回答，参考文档事件中包含来源问答，res.CorrectionID 不为空时以 correction 事件发送
+func (h *CannedHandler) StreamAnswer(ctx context.Context, res *v1.ChatRes) error {
+	streamReader, streamWriter := schema.Pipe[*schema.Message](1)
+	streamWriter.Send(&schema.Message{Role: schema.Assistant, Content: res.Answer}, nil)
+	streamWriter.Close()
+	return common.SteamResponse(ctx, streamReader, res.References, common.StreamHooks{CorrectionID: res.CorrectionID})
+}
@@ -2,10 +2,16 @@ package chat
 
 import (
 	"context"
+	"time"
 
 	"github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/common"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/logic/budget"
 	"github.com/Malowking/kbgo/internal/logic/chat"
+	"github.com/Malowking/kbgo/internal/logic/handoff"
+	"github.com/Malowking/kbgo/internal/logic/persona"
 	"github.com/Malowking/kbgo/internal/logic/retriever"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -21,9 +27,15 @@ func NewChatHandler() *ChatHandler {
 
 // Handle basic chat request (non-streaming)
 func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles []*common.MultimodalFile) (*v1.ChatRes, error) {
+	// 意图路由：闲聊跳过检索和工具调用，单一意图的问题只执行对应阶段
+	NewIntentRouter().Apply(ctx, req)
+
 	// Get retriever configuration
 	cfg := retriever.GetRetrieverConfig()
 
+	// 助手消息在此之后保存，用于关联工具调用的执行摘要
+	start := time.Now()
+
 	// Initialize response
 	res := &v1.ChatRes{}
 
@@ -59,7 +71,7 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 			// chat接口默认开启查询重写
 			rewriteAttempts := 3
 
-			retrieverRes, err := retriever.ProcessRetrieval(ctx, &v1.RetrieverReq{
+			retrieverReq := &v1.RetrieverReq{
 				Question:         req.Question,
 				EmbeddingModelID: req.EmbeddingModelID,
 				RerankModelID:    req.RerankModelID,
@@ -69,7 +81,11 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 				EnableRewrite:    true, // chat接口默认开启查询重写
 				RewriteAttempts:  rewriteAttempts,
 				RetrieveMode:     retrieveMode,
-			})
+				RetrievalView:    req.RetrievalView,
+			}
+			// 延迟预算不足时跳过查询重写、减少 TopK 或跳过重排
+			budget.FromContext(ctx).PlanRetrieval(ctx, retrieverReq, cfg.TopK)
+			retrieverRes, err := retriever.ProcessRetrieval(ctx, retrieverReq)
 			if err != nil {
 				result.err = err
 			} else {
@@ -154,20 +170,35 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 	// 4. 调用Chat逻辑生成答案
 	chatI := chat.GetChat()
 
-	var answer string
+	var answer, reasoning string
+	var confidence *v1.AnswerConfidence
 	var err error
+	preset, err := persona.Resolve(ctx, req.PersonaID, req.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	style := chat.NewResponseStyle(req.ResponseStyle, req.OutputFormat, req.Language).WithPersona(preset)
 
 	// 根据是否有文件或文档内容选择不同的处理方式
 	if len(fileParseRes.multimodalFiles) > 0 || fileParseRes.fileContent != "" || len(fileParseRes.fileImages) > 0 {
 		// 有文件或文档内容：使用文件对话模式
 		g.Log().Infof(ctx, "Using file-based chat with %d multimodal files, text content length: %d, %d images",
 			len(fileParseRes.multimodalFiles), len(fileParseRes.fileContent), len(fileParseRes.fileImages))
-		answer, err = chatI.GetAnswerWithParsedFiles(ctx, req.ModelID, req.ConvID, documents, req.Question,
-			fileParseRes.multimodalFiles, fileParseRes.fileContent, fileParseRes.fileImages, req.JsonFormat)
+		answer, reasoning, confidence, err = chatI.GetAnswerWithParsedFiles(ctx, req.ModelID, req.ConvID, documents, req.Question,
+			fileParseRes.multimodalFiles, fileParseRes.fileContent, fileParseRes.fileImages, req.JsonFormat, style)
 	} else {
 		// 无文件：普通对话模式
 		g.Log().Infof(ctx, "Using standard chat without files")
-		answer, err = chatI.GetAnswer(ctx, req.ModelID, req.ConvID, documents, req.Question, req.JsonFormat)
+		// 影子评估：按采样率在后台用候选模型回答同一问题并保存对比结果
+		var shadowRun *chat.ShadowRun
+		if !req.JsonFormat {
+			shadowRun = chatI.StartShadow(ctx, req.ConvID, req.ModelID, req.Question, documents)
+		}
+		answerStart := time.Now()
+		answer, reasoning, confidence, err = chatI.GetAnswer(ctx, req.ModelID, req.ConvID, documents, req.Question, req.JsonFormat, style)
+		if err == nil {
+			shadowRun.Finish(ctx, answer, time.Since(answerStart).Milliseconds())
+		}
 	}
 
 	if err != nil {
@@ -175,6 +206,18 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 	}
 
 	res.Answer = answer
+	if chat.ReferenceFormat(ctx) == chat.ReferenceFormatJSON {
+		// 回答中的 [ref:分片ID] 标注替换为引用编号
+		res.Answer, res.Citations = chat.ResolveCitations(answer, documents)
+	}
+	res.ReasoningContent = reasoning
+	res.Confidence = confidence
+	// 低置信度回答触发转人工时返回工单，后续消息由人工客服回复
+	if confidence != nil && confidence.Escalated {
+		if ticket, ticketErr := handoff.ActiveTicket(ctx, req.ConvID); ticketErr == nil && ticket != nil {
+			res.Handoff = ToHandoffTicketItem(ticket)
+		}
+	}
 
 	// 5. 如果启用MCP，进行MCP工具调用（单次调用）
 	if req.UseMCP {
@@ -190,7 +233,7 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 			toolSelectionQuestion := h.buildToolSelectionQuestion(ctx, req.Question, documents, fileParseRes.fileContent)
 
 			// 使用LLM选择工具
-			selectedTools, selectErr := h.selectToolsWithLLM(ctx, toolSelectionQuestion)
+			selectedTools, selectErr := h.selectToolsWithLLM(ctx, req.ModelID, req.Question, toolSelectionQuestion)
 			if selectErr != nil {
 				g.Log().Errorf(ctx, "工具选择失败: %v", selectErr)
 				// 工具选择失败，使用原有的工具列表（如果为空则调用所有工具）
@@ -202,7 +245,11 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 		}
 
 		// 5.2 执行MCP工具调用，传入知识检索和文件解析的结果
+		ctx := agentrun.WithContext(ctx)
 		mcpDocs, mcpResults, mcpErr := mcpHandler.CallMCPToolsWithLLM(ctx, req, documents, fileParseRes.fileContent)
+		// 回答已保存，执行摘要在后台补充到助手消息元数据
+		res.AgentSummary = agentrun.FromContext(ctx).Summary()
+		agentrun.Persist(ctx, req.ConvID, start, res.AgentSummary)
 		if mcpErr != nil {
 			g.Log().Errorf(ctx, "MCP tool call failed: %v", mcpErr)
 		} else if len(mcpResults) > 0 {
@@ -217,5 +264,18 @@ func (h *ChatHandler) Chat(ctx context.Context, req *v1.ChatReq, uploadedFiles [
 		}
 	}
 
+	// 6. 生成推荐追问（可选）
+	if req.EnableFollowUp {
+		questions, followUpErr := chatI.GenerateFollowUpQuestions(ctx, req.ModelID, req.ConvID, res.References, req.Question, res.Answer)
+		if followUpErr != nil {
+			g.Log().Errorf(ctx, "生成推荐追问失败: %v", followUpErr)
+		} else {
+			res.FollowUpQuestions = questions
+		}
+	}
+
+	res.LatencyBudget = budget.FromContext(ctx).Report()
+	res.ModelFallbacks = coreModel.FallbackRecorderFromContext(ctx).Events()
+
 	return res, nil
 }
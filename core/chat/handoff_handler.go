@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/logic/handoff"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/bytedance/sonic"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// HandoffHandler 人工接管处理器
+type HandoffHandler struct{}
+
+// NewHandoffHandler 创建人工接管处理器
+func NewHandoffHandler() *HandoffHandler {
+	return &HandoffHandler{}
+}
+
+// Intercept 会话已转人工或用户要求人工服务时接管请求，不调用模型
+// 返回 nil 表示继续由 AI 回答
+func (h *HandoffHandler) Intercept(ctx context.Context, req *v1.ChatReq) (*v1.ChatRes, error) {
+	if !handoff.Enabled(ctx) {
+		return nil, nil
+	}
+	ticket, err := handoff.ActiveTicket(ctx, req.ConvID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket == nil {
+		if !req.RequestHuman && !handoff.RequestsHuman(ctx, req.Question) {
+			return nil, nil
+		}
+		ticket, err = handoff.Open(ctx, req.ConvID, gormModel.HandoffReasonUserRequest, req.Question, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g.Log().Infof(ctx, "Conversation %s is handed off to a human agent (ticket %s), skipping assistant", req.ConvID, ticket.ID)
+	if err = handoff.ForwardUserMessage(ctx, ticket, req.Question); err != nil {
+		return nil, err
+	}
+	return &v1.ChatRes{
+		Answer:  handoff.Notice(ctx),
+		Handoff: ToHandoffTicketItem(ticket),
+	}, nil
+}
+
+// StreamNotice 以流式响应返回转人工提示
+func (h *HandoffHandler) StreamNotice(ctx context.Context, res *v1.ChatRes) error {
+	streamReader, streamWriter := schema.Pipe[*schema.Message](1)
+	streamWriter.Send(&schema.Message{Role: schema.Assistant, Content: res.Answer}, nil)
+	streamWriter.Close()
+	return common.SteamResponse(ctx, streamReader, nil, common.StreamHooks{})
+}
+
+// StreamEvents 以SSE推送会话的人工接管事件（转人工、人工客服消息、结束），直到客户端断开
+func (h *HandoffHandler) StreamEvents(ctx context.Context, convID string) error {
+	events, cancel := handoff.Subscribe(convID)
+	defer cancel()
+
+	httpResp := ghttp.RequestFromCtx(ctx).Response
+	defer common.StartSSE(ctx, httpResp)()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			marshal, _ := sonic.Marshal(event)
+			common.WriteSSEEvent(httpResp, "handoff", string(marshal))
+		}
+	}
+}
+
+// ToHandoffTicketItem 转换工单为接口响应
+func ToHandoffTicketItem(ticket *gormModel.HandoffTicket) *v1.HandoffTicketItem {
+	item := &v1.HandoffTicketItem{
+		TicketID:   ticket.ID,
+		ConvID:     ticket.ConvID,
+		Reason:     ticket.Reason,
+		Question:   ticket.Question,
+		Confidence: ticket.Confidence,
+		Status:     ticket.Status,
+		Assignee:   ticket.Assignee,
+	}
+	if ticket.CreateTime != nil {
+		item.CreatedAt = ticket.CreateTime.Format(time.DateTime)
+	}
+	if ticket.ResolvedAt != nil {
+		item.ResolvedAt = ticket.ResolvedAt.Format(time.DateTime)
+	}
+	return item
+}
@@ -0,0 +1,183 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+// 问题意图
+const (
+	IntentSmallTalk = "small_talk" // 闲聊，直接由模型回答
+	IntentKnowledge = "knowledge"  // 文档/知识类问题，只做知识检索
+	IntentTool      = "tool"       // 需要调用外部工具（数据查询、操作等），只调用 MCP 工具
+	IntentMixed     = "mixed"      // 无法判断或同时需要，按请求参数执行全部阶段
+)
+
+// smallTalkPhrases 启发式识别闲聊的常见短语
+var smallTalkPhrases = []string{
+	"你好", "您好", "嗨", "哈喽", "在吗", "在不在", "早上好", "中午好", "下午好", "晚上好", "晚安",
+	"谢谢", "多谢", "感谢", "辛苦了", "好的", "好", "嗯", "嗯嗯", "哈哈", "再见", "拜拜", "你是谁",
+	"hi", "hello", "hey", "thanks", "thank you", "ok", "okay", "bye", "good morning", "good night", "who are you",
+}
+
+// IntentRoute 意图路由结果
+type IntentRoute struct {
+	Intent   string
+	Source   string // heuristic / model
+	Retrieve bool   // 是否执行知识检索
+	UseMCP   bool   // 是否调用 MCP 工具
+}
+
+// IntentRouter 对话前的轻量意图分类，跳过与问题无关的检索和工具调用
+type IntentRouter struct{}
+
+// NewIntentRouter 创建意图路由器
+func NewIntentRouter() *IntentRouter {
+	return &IntentRouter{}
+}
+
+// Apply 对请求做意图路由，按结果关闭不需要的检索和工具调用（只会关闭请求中已开启的阶段）
+func (r *IntentRouter) Apply(ctx context.Context, req *v1.ChatReq) *IntentRoute {
+	if !g.Cfg().MustGet(ctx, "intentRouter.enabled", false).Bool() || (!req.EnableRetriever && !req.UseMCP) {
+		return nil
+	}
+	route := r.route(ctx, req)
+	req.EnableRetriever = req.EnableRetriever && route.Retrieve
+	req.UseMCP = req.UseMCP && route.UseMCP
+	g.Log().Infof(ctx, "Intent router - intent=%s (%s), retrieve=%v, mcp=%v", route.Intent, route.Source, req.EnableRetriever, req.UseMCP)
+	return route
+}
+
+// route 先用启发式规则识别闲聊，无法判断且配置了分类模型时再调用模型
+func (r *IntentRouter) route(ctx context.Context, req *v1.ChatReq) *IntentRoute {
+	if isSmallTalk(req.Question) {
+		return newIntentRoute(IntentSmallTalk, "heuristic")
+	}
+	modelID := g.Cfg().MustGet(ctx, "intentRouter.modelID", "").String()
+	if modelID == "" {
+		return newIntentRoute(IntentMixed, "heuristic")
+	}
+	intent, err := r.classify(ctx, modelID, req)
+	if err != nil {
+		g.Log().Warningf(ctx, "Intent classification failed, running all stages: %v", err)
+		return newIntentRoute(IntentMixed, "heuristic")
+	}
+	return newIntentRoute(intent, "model")
+}
+
+// classify 使用分类模型判断问题意图，相同问题复用缓存的分类结果
+func (r *IntentRouter) classify(ctx context.Context, modelID string, req *v1.ChatReq) (string, error) {
+	mc := model.Registry.Get(modelID)
+	if mc == nil {
+		return "", fmt.Errorf("intent model not found: %s", modelID)
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model: mc.Name,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: r.buildPrompt(ctx, req)},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+		// temperature 为 0 时会被 omitempty 省略，用最小正数代替
+		Temperature:         math.SmallestNonzeroFloat32,
+		MaxCompletionTokens: 50,
+	}
+	resp, err := model.CachedChatCompletion(ctx, mc.BaseURL, chatReq, func() (*openai.ChatCompletionResponse, error) {
+		resp, err := mc.Client.CreateChatCompletion(ctx, chatReq)
+		return &resp, err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty response from intent model")
+	}
+	return parseIntent(resp.Choices[0].Message.Content)
+}
+
+// buildPrompt 构建意图分类 prompt，启用 MCP 时附上可用服务说明
+func (r *IntentRouter) buildPrompt(ctx context.Context, req *v1.ChatReq) string {
+	var builder strings.Builder
+	builder.WriteString("判断用户问题的意图，只返回 JSON 对象 {\"intent\": \"...\"}，intent 取值：\n")
+	builder.WriteString("- small_talk：问候、感谢、闲聊等不需要任何资料的对话\n")
+	builder.WriteString("- knowledge：需要查阅文档、知识库资料回答的问题\n")
+	builder.WriteString("- tool：需要调用外部工具查询实时数据或执行操作的问题\n")
+	builder.WriteString("- mixed：同时需要资料和工具，或无法判断\n")
+	if req.UseMCP {
+		registries, err := dao.MCPRegistry.ListActive(ctx)
+		if err == nil && len(registries) > 0 {
+			builder.WriteString("\n可用的外部工具服务：\n")
+			for _, registry := range registries {
+				builder.WriteString(fmt.Sprintf("- %s：%s\n", registry.Name, registry.Description))
+			}
+		}
+	}
+	builder.WriteString("\n用户问题：\n")
+	builder.WriteString(req.Question)
+	return builder.String()
+}
+
+// parseIntent 解析分类模型的输出
+func parseIntent(content string) (string, error) {
+	var result struct {
+		Intent string `json:"intent"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &result); err != nil {
+		return "", fmt.Errorf("invalid intent response %q: %w", content, err)
+	}
+	switch intent := strings.ToLower(strings.TrimSpace(result.Intent)); intent {
+	case IntentSmallTalk, IntentKnowledge, IntentTool, IntentMixed:
+		return intent, nil
+	default:
+		return "", fmt.Errorf("unknown intent %q", result.Intent)
+	}
+}
+
+func newIntentRoute(intent, source string) *IntentRoute {
+	route := &IntentRoute{Intent: intent, Source: source}
+	switch intent {
+	case IntentSmallTalk:
+	case IntentKnowledge:
+		route.Retrieve = true
+	case IntentTool:
+		route.UseMCP = true
+	default:
+		route.Retrieve, route.UseMCP = true, true
+	}
+	return route
+}
+
+// isSmallTalk 问题去掉标点和空白后是否为常见闲聊短语（允许带少量语气词，如“你好呀”）
+func isSmallTalk(question string) bool {
+	normalized := strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, question))
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	if normalized == "" {
+		return false
+	}
+	for _, phrase := range smallTalkPhrases {
+		if !strings.HasPrefix(normalized, phrase) {
+			continue
+		}
+		if len([]rune(normalized))-len([]rune(phrase)) <= 2 {
+			return true
+		}
+	}
+	return false
+}
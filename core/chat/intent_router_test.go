@@ -0,0 +1,44 @@
+package chat
+
+import "testing"
+
+func TestIsSmallTalk(t *testing.T) {
+	tests := []struct {
+		question string
+		want     bool
+	}{
+		{"你好", true},
+		{"你好呀！", true},
+		{"  Hello! ", true},
+		{"谢谢～", true},
+		{"Thank you.", true},
+		{"", false},
+		{"你好，请问年假怎么申请？", false},
+		{"ok google 是什么", false},
+		{"查询上个月的销售额", false},
+	}
+	for _, tt := range tests {
+		if got := isSmallTalk(tt.question); got != tt.want {
+			t.Errorf("isSmallTalk(%q) = %v, want %v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestParseIntent(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		{`{"intent": "knowledge"}`, IntentKnowledge, false},
+		{` {"intent": " TOOL "} `, IntentTool, false},
+		{`{"intent": "nl2sql"}`, "", true},
+		{`knowledge`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseIntent(tt.content)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseIntent(%q) = %q, %v, want %q (err %v)", tt.content, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -36,7 +36,8 @@ func (h *MCPHandler) CallMCPToolsWithLLM(ctx context.Context, req *v1.ChatReq, d
 
 	// 使用 LLM 智能选择并调用工具
 	// 传递 MCPServiceTools 作为过滤器，限制 LLM 只能选择指定的工具
-	mcpDocuments, mcpResults, err := toolCaller.CallToolsWithLLM(ctx, req.ModelID, fullQuestion, req.ConvID, req.MCPServiceTools)
+	ctx = mcp.WithMaxIterations(ctx, req.MaxToolIterations)
+	mcpDocuments, mcpResults, err := toolCaller.CallToolsWithLLM(ctx, req.ModelID, fullQuestion, req.Question, req.ConvID, req.MCPServiceTools)
 	if err != nil {
 		return nil, nil, fmt.Errorf("LLM intelligent tool call failed: %w", err)
 	}
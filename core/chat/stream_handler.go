@@ -8,10 +8,17 @@ import (
 
 	"github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/common"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/logic/budget"
 	"github.com/Malowking/kbgo/internal/logic/chat"
+	"github.com/Malowking/kbgo/internal/logic/persona"
 	"github.com/Malowking/kbgo/internal/logic/retriever"
+	"github.com/Malowking/kbgo/internal/mcp"
 	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/bytedance/sonic"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
 )
 
 // StreamHandler 流式聊天处理器
@@ -23,7 +30,25 @@ func NewStreamHandler() *StreamHandler {
 }
 
 // StreamChat 处理流式聊天请求
-func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploadedFiles []*common.MultimodalFile) error {
+// 进入后立即开始 SSE 响应并发送心跳，检索和工具调用期间连接也不会空闲；之后的错误以 error 事件返回
+// correctionID 为本轮捕获的更正申请ID，不为空时在结束前以 correction 事件发送
+func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploadedFiles []*common.MultimodalFile, correctionID string) error {
+	httpReq := ghttp.RequestFromCtx(ctx)
+	if httpReq == nil {
+		return h.streamChat(ctx, req, uploadedFiles, correctionID)
+	}
+	defer common.StartSSE(ctx, httpReq.Response)()
+	err := h.streamChat(ctx, req, uploadedFiles, correctionID)
+	if err != nil {
+		common.WriteSSEError(httpReq.Response, err)
+	}
+	return err
+}
+
+func (h *StreamHandler) streamChat(ctx context.Context, req *v1.ChatReq, uploadedFiles []*common.MultimodalFile, correctionID string) error {
+	// 意图路由：闲聊跳过检索和工具调用，单一意图的问题只执行对应阶段
+	NewIntentRouter().Apply(ctx, req)
+
 	// 获取检索配置
 	cfg := retriever.GetRetrieverConfig()
 
@@ -56,7 +81,7 @@ func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploade
 			enableRewrite := true
 			rewriteAttempts := 3
 
-			retrieverRes, err := retriever.ProcessRetrieval(ctx, &v1.RetrieverReq{
+			retrieverReq := &v1.RetrieverReq{
 				Question:         req.Question,
 				EmbeddingModelID: req.EmbeddingModelID,
 				RerankModelID:    req.RerankModelID,
@@ -66,7 +91,11 @@ func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploade
 				EnableRewrite:    enableRewrite,
 				RewriteAttempts:  rewriteAttempts,
 				RetrieveMode:     retrieveMode,
-			})
+				RetrievalView:    req.RetrievalView,
+			}
+			// 延迟预算不足时跳过查询重写、减少 TopK 或跳过重排
+			budget.FromContext(ctx).PlanRetrieval(ctx, retrieverReq, cfg.TopK)
+			retrieverRes, err := retriever.ProcessRetrieval(ctx, retrieverReq)
 			if err != nil {
 				g.Log().Errorf(ctx, "知识检索失败: %v", err)
 				result.err = err
@@ -97,13 +126,19 @@ func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploade
 	documents = retrievalRes.documents
 
 	// 2. 执行MCP工具调用（检索完成后，MCP需要检索结果）
-	// MCP调用是同步的，会等待所有工具调用完成后才返回
+	// 流式输出工具调用的最终答案时，工具调用与回答输出同时进行，否则同步等待所有工具调用完成
+	agentStream := req.UseMCP && len(uploadedFiles) == 0 && !req.JsonFormat && chat.AgentStreamEnabled(ctx)
 	var mcpRes mcpResult
 	if req.UseMCP {
+		// 记录执行摘要，工具调用结束后以 agent_summary 事件发送，并随回答保存到助手消息元数据
+		ctx = agentrun.WithContext(ctx)
+	}
+	if req.UseMCP && !agentStream {
 		g.Log().Infof(ctx, "开始执行MCP工具调用...")
 		mcpHandler := NewMCPHandler()
 		// 传入检索到的文档，流式处理中没有文件解析内容
-		_, mcpResults, err := mcpHandler.CallMCPToolsWithLLM(ctx, req, documents, "")
+		_, mcpResults, err := mcpHandler.CallMCPToolsWithLLM(h.withToolProgress(ctx), req, documents, "")
+		h.writeAgentSummary(ctx)
 		if err != nil {
 			g.Log().Errorf(ctx, "MCP智能工具调用失败: %v", err)
 			mcpRes.err = err
@@ -125,35 +160,38 @@ func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploade
 	// 获取Chat实例
 	chatI := chat.GetChat()
 
-	// 过滤出多模态文件（只有图片、音频、视频才使用多模态）
-	var multimodalFiles []*common.MultimodalFile
-	for _, file := range uploadedFiles {
-		if file.FileType == common.FileTypeImage ||
-			file.FileType == common.FileTypeAudio ||
-			file.FileType == common.FileTypeVideo {
-			multimodalFiles = append(multimodalFiles, file)
-		} else {
-			g.Log().Infof(ctx, "Skipping non-multimodal file in stream: %s (type: %s)", file.FileName, file.FileType)
-		}
-	}
-
 	// 记录开始时间
 	start := time.Now()
 
 	// 获取流式响应
 	var streamReader *schema.StreamReader[*schema.Message]
+	var shadowRun *chat.ShadowRun // 影子评估采样，流结束后在后台用候选模型回答同一问题
 	var err error
-	if len(multimodalFiles) > 0 {
-		g.Log().Infof(ctx, "Using multimodal stream chat with %d files", len(multimodalFiles))
-		streamReader, err = chatI.GetAnswerStreamWithFiles(ctx, req.ModelID, req.ConvID, documents, req.Question, multimodalFiles, req.JsonFormat)
+	preset, err := persona.Resolve(ctx, req.PersonaID, req.ModelID)
+	if err != nil {
+		return err
+	}
+	style := chat.NewResponseStyle(req.ResponseStyle, req.OutputFormat, req.Language).WithPersona(preset)
+	if len(uploadedFiles) > 0 {
+		// 文档文件在生成回答之前解析，解析进度以 parse_progress 事件发送
+		g.Log().Infof(ctx, "Using file-based stream chat with %d files", len(uploadedFiles))
+		streamReader, err = chatI.GetAnswerStreamWithFiles(h.withParseProgress(ctx), req.ModelID, req.ConvID, documents, req.Question, uploadedFiles, req.JsonFormat, style)
+	} else if agentStream {
+		// 工具调用进度和执行摘要照常以事件发送，最终答案按 token 流式输出
+		g.Log().Infof(ctx, "开始执行MCP工具调用，流式输出最终答案...")
+		streamReader, err = chatI.StreamAgentAnswer(ctx, req.ModelID, req.ConvID, documents, req.Question, style, h.agentAnswer(req, documents))
 	} else {
-		streamReader, err = chatI.GetAnswerStream(ctx, req.ModelID, req.ConvID, documents, req.Question, req.JsonFormat)
+		if !req.JsonFormat {
+			shadowRun = chatI.StartShadow(ctx, req.ConvID, req.ModelID, req.Question, documents)
+		}
+		streamReader, err = chatI.GetAnswerStream(ctx, req.ModelID, req.ConvID, documents, req.Question, req.JsonFormat, style)
 	}
 	if err != nil {
 		g.Log().Error(ctx, err)
 		return err
 	}
 	defer streamReader.Close()
+	h.writeModelFallback(ctx)
 
 	// 在流式响应中添加MCP结果
 	allDocuments := h.buildAllDocuments(documents, mcpRes.mcpResults)
@@ -172,7 +210,41 @@ func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploade
 	}
 
 	// 处理流式响应和内容收集
-	err = h.handleStreamResponse(ctx, streamReader, allDocuments, start, req.ConvID, metadata, chatI)
+	hooks := common.StreamHooks{CorrectionID: correctionID}
+	if chat.ConfidenceEnabled(ctx) {
+		// 只用知识库检索结果评估，升级通知已在保存消息时发出
+		hooks.Confidence = func(answer string) any {
+			if confidence := chat.ScoreAnswer(ctx, documents, answer, nil); confidence != nil {
+				return confidence
+			}
+			return nil
+		}
+	}
+	if chat.ReferenceFormat(ctx) == chat.ReferenceFormatJSON && len(documents) > 0 {
+		// 已发送的内容无法改写，客户端按 markers 将标注替换为编号
+		hooks.Citations = func(answer string) any {
+			if _, citations := chat.ResolveCitations(answer, documents); len(citations) > 0 {
+				return citations
+			}
+			return nil
+		}
+	}
+	if req.EnableFollowUp {
+		hooks.FollowUp = func(answer string) []string {
+			questions, err := chatI.GenerateFollowUpQuestions(ctx, req.ModelID, req.ConvID, allDocuments, req.Question, answer)
+			if err != nil {
+				g.Log().Errorf(ctx, "生成推荐追问失败: %v", err)
+				return nil
+			}
+			return questions
+		}
+	}
+	if latencyBudget := budget.FromContext(ctx); latencyBudget != nil {
+		hooks.LatencyBudget = func() any {
+			return latencyBudget.Report()
+		}
+	}
+	err = h.handleStreamResponse(ctx, streamReader, allDocuments, start, req.ConvID, metadata, chatI, hooks, shadowRun)
 	if err != nil {
 		g.Log().Error(ctx, err)
 		return err
@@ -181,6 +253,86 @@ func (h *StreamHandler) StreamChat(ctx context.Context, req *v1.ChatReq, uploade
 	return nil
 }
 
+// agentAnswer 在回答流中执行工具调用循环，返回 LLM 基于工具结果给出的最终答案
+func (h *StreamHandler) agentAnswer(req *v1.ChatReq, documents []*schema.Document) chat.AgentFunc {
+	return func(ctx context.Context, emit func(delta string)) (string, error) {
+		// 传入检索到的文档，流式处理中没有文件解析内容
+		mcpDocuments, mcpResults, err := NewMCPHandler().CallMCPToolsWithLLM(mcp.WithAnswerStream(h.withToolProgress(ctx), emit), req, documents, "")
+		h.writeAgentSummary(ctx)
+		if err != nil {
+			g.Log().Errorf(ctx, "MCP智能工具调用失败: %v", err)
+			return "", err
+		}
+		g.Log().Infof(ctx, "MCP工具调用完成，返回 %d 个结果", len(mcpResults))
+		for _, doc := range mcpDocuments {
+			if doc.ID == "llm_final_answer" {
+				return doc.Content, nil
+			}
+		}
+		return "", nil
+	}
+}
+
+// withToolProgress 耗时工具（如长文档摘要）上报的进度以 tool_progress 事件实时发送给客户端
+func (h *StreamHandler) withToolProgress(ctx context.Context) context.Context {
+	httpReq := ghttp.RequestFromCtx(ctx)
+	if httpReq == nil {
+		return ctx
+	}
+	httpResp := httpReq.Response
+	return mcp.WithProgress(ctx, func(progress *mcp.ToolProgress) {
+		marshal, err := sonic.Marshal(progress)
+		if err != nil {
+			return
+		}
+		common.WriteSSEEvent(httpResp, "tool_progress", string(marshal))
+	})
+}
+
+// withParseProgress 上传文档的解析进度（逐个文件，支持时逐页）以 parse_progress 事件实时发送给客户端
+func (h *StreamHandler) withParseProgress(ctx context.Context) context.Context {
+	httpReq := ghttp.RequestFromCtx(ctx)
+	if httpReq == nil {
+		return ctx
+	}
+	httpResp := httpReq.Response
+	return chat.WithParseProgress(ctx, func(progress *chat.ParseProgress) {
+		marshal, err := sonic.Marshal(progress)
+		if err != nil {
+			return
+		}
+		common.WriteSSEEvent(httpResp, "parse_progress", string(marshal))
+	})
+}
+
+// writeAgentSummary 工具调用结束后以 agent_summary 事件发送执行摘要
+func (h *StreamHandler) writeAgentSummary(ctx context.Context) {
+	summary := agentrun.FromContext(ctx).Summary()
+	httpReq := ghttp.RequestFromCtx(ctx)
+	if summary == nil || httpReq == nil {
+		return
+	}
+	marshal, err := sonic.Marshal(summary)
+	if err != nil {
+		return
+	}
+	common.WriteSSEEvent(httpReq.Response, "agent_summary", string(marshal))
+}
+
+// writeModelFallback 回答模型建立流时改用了备用模型，以 model_fallback 事件发送切换记录
+func (h *StreamHandler) writeModelFallback(ctx context.Context) {
+	events := coreModel.FallbackRecorderFromContext(ctx).Events()
+	httpReq := ghttp.RequestFromCtx(ctx)
+	if len(events) == 0 || httpReq == nil {
+		return
+	}
+	marshal, err := sonic.Marshal(events)
+	if err != nil {
+		return
+	}
+	common.WriteSSEEvent(httpReq.Response, "model_fallback", string(marshal))
+}
+
 // buildAllDocuments 构建所有文档（包括MCP结果）
 func (h *StreamHandler) buildAllDocuments(documents []*schema.Document, mcpResults []*v1.MCPResult) []*schema.Document {
 	var allDocuments []*schema.Document
@@ -216,7 +368,7 @@ func (h *StreamHandler) buildMetadata(retrieverMetadata map[string]interface{},
 }
 
 // handleStreamResponse 处理流式响应
-func (h *StreamHandler) handleStreamResponse(ctx context.Context, streamReader *schema.StreamReader[*schema.Message], allDocuments []*schema.Document, start time.Time, convID string, metadata map[string]interface{}, chatI interface{}) error {
+func (h *StreamHandler) handleStreamResponse(ctx context.Context, streamReader *schema.StreamReader[*schema.Message], allDocuments []*schema.Document, start time.Time, convID string, metadata map[string]interface{}, chatI interface{}, hooks common.StreamHooks, shadowRun *chat.ShadowRun) error {
 	// 收集流式响应内容以保存完整消息
 	var fullContent strings.Builder
 
@@ -235,6 +387,8 @@ func (h *StreamHandler) handleStreamResponse(ctx context.Context, streamReader *
 					break
 				}
 				g.Log().Errorf(ctx, "Error collecting stream content: %v", err)
+				// 回答不完整，不参与影子评估
+				shadowRun = nil
 				break
 			}
 			if msg != nil {
@@ -243,7 +397,8 @@ func (h *StreamHandler) handleStreamResponse(ctx context.Context, streamReader *
 		}
 
 		// 计算延迟
-		_ = time.Since(start).Milliseconds()
+		latencyMs := time.Since(start).Milliseconds()
+		shadowRun.Finish(ctx, fullContent.String(), latencyMs)
 
 		// TODO: 这里可能需要将latencyMs和tokens_used传递给前端或者其他地方
 
@@ -259,7 +414,7 @@ func (h *StreamHandler) handleStreamResponse(ctx context.Context, streamReader *
 		}
 	}()
 
-	err := common.SteamResponse(ctx, streamReader, allDocuments)
+	err := common.SteamResponse(ctx, streamReader, allDocuments, hooks)
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 	"time"
@@ -11,6 +12,8 @@ import (
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/toolexample"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/sashabaranov/go-openai"
@@ -112,7 +115,7 @@ func (h *ChatHandler) getAllMCPTools(ctx context.Context) (map[string][]v1.MCPTo
 }
 
 // buildToolSelectionPrompt 构建工具选择的prompt
-func (h *ChatHandler) buildToolSelectionPrompt(ctx context.Context, question string, allTools map[string][]v1.MCPToolInfo) string {
+func (h *ChatHandler) buildToolSelectionPrompt(ctx context.Context, question string, allTools map[string][]v1.MCPToolInfo, examples []*gormModel.ToolExample) string {
 	var builder strings.Builder
 
 	builder.WriteString("你是一个智能工具选择助手。根据用户问题和可用的工具列表，选择最合适的工具来回答用户问题。\n\n")
@@ -129,6 +132,14 @@ func (h *ChatHandler) buildToolSelectionPrompt(ctx context.Context, question str
 		}
 	}
 
+	// 工具调用示例：相似问题应选择示例中的工具
+	for i, example := range examples {
+		if i == 0 {
+			builder.WriteString("\n工具选择示例：\n")
+		}
+		builder.WriteString(fmt.Sprintf("  - 问题：%s → 服务 %s 的工具 %s\n", example.Question, example.ServiceName, example.ToolName))
+	}
+
 	builder.WriteString("\n请根据用户问题选择最合适的工具（最多选择5个工具）。\n")
 	builder.WriteString("要求：\n")
 	builder.WriteString("1. 只选择与问题相关的工具\n")
@@ -139,8 +150,8 @@ func (h *ChatHandler) buildToolSelectionPrompt(ctx context.Context, question str
 	return builder.String()
 }
 
-// selectToolsWithLLM 使用LLM选择工具
-func (h *ChatHandler) selectToolsWithLLM(ctx context.Context, question string) (map[string][]string, error) {
+// selectToolsWithLLM 使用LLM选择工具，modelID 和 userQuestion 用于选取工具调用示例
+func (h *ChatHandler) selectToolsWithLLM(ctx context.Context, modelID string, userQuestion string, question string) (map[string][]string, error) {
 	// 1. 获取所有可用的MCP工具
 	allTools, err := h.getAllMCPTools(ctx)
 	if err != nil {
@@ -155,19 +166,26 @@ func (h *ChatHandler) selectToolsWithLLM(ctx context.Context, question string) (
 	g.Log().Infof(ctx, "加载了 %d 个MCP服务的工具", len(allTools))
 
 	// 2. 随机选择一个LLM模型
-	modelID, err := h.selectRandomLLMModel(ctx)
+	selectorModelID, err := h.selectRandomLLMModel(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("选择LLM模型失败: %w", err)
 	}
 
 	// 获取模型配置
-	mc := model.Registry.Get(modelID)
+	mc := model.Registry.Get(selectorModelID)
 	if mc == nil {
-		return nil, fmt.Errorf("模型不存在: %s", modelID)
+		return nil, fmt.Errorf("模型不存在: %s", selectorModelID)
 	}
 
-	// 3. 构建工具选择的prompt
-	prompt := h.buildToolSelectionPrompt(ctx, question, allTools)
+	// 3. 构建工具选择的prompt（附加对话模型的工具调用示例）
+	var toolNames []string
+	for serviceName, tools := range allTools {
+		for _, tool := range tools {
+			toolNames = append(toolNames, serviceName+"__"+tool.Name)
+		}
+	}
+	examples := toolexample.Select(ctx, modelID, userQuestion, toolNames)
+	prompt := h.buildToolSelectionPrompt(ctx, question, allTools, examples)
 	g.Log().Debugf(ctx, "工具选择prompt长度: %d", len(prompt))
 
 	// 4. 构建请求消息
@@ -185,10 +203,15 @@ func (h *ChatHandler) selectToolsWithLLM(ctx context.Context, question string) (
 		ResponseFormat: &openai.ChatCompletionResponseFormat{
 			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
 		},
-		Temperature: 0.3, // 使用较低的温度以获得更稳定的输出
+		// 工具选择只依赖问题和工具列表，使用确定性输出以便相同请求复用缓存
+		// temperature 为 0 时会被 omitempty 省略，用最小正数代替
+		Temperature: math.SmallestNonzeroFloat32,
 	}
 
-	resp, err := mc.Client.CreateChatCompletion(ctx, chatReq)
+	resp, err := model.CachedChatCompletion(ctx, mc.BaseURL, chatReq, func() (*openai.ChatCompletionResponse, error) {
+		resp, err := mc.Client.CreateChatCompletion(ctx, chatReq)
+		return &resp, err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("LLM调用失败: %w", err)
 	}
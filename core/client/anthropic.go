@@ -0,0 +1,552 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// ProviderAnthropic 使用 Anthropic Messages API 的模型提供商
+	ProviderAnthropic = "anthropic"
+
+	anthropicDefaultBaseURL   = "https://api.anthropic.com"
+	anthropicVersion          = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
+)
+
+// NewAnthropicHTTPClient 创建调用 Anthropic Messages API 的 HTTP 客户端，供 go-openai 客户端使用：
+// 把 OpenAI 格式的 /chat/completions 请求转换为 /v1/messages 请求（system 消息合并为 system 参数，工具调用和结果转换为 tool_use / tool_result 块），
+// 响应和流式事件转换回 OpenAI 格式，因此调用方无需区分提供商。只支持聊天接口，其他接口返回错误
+func NewAnthropicHTTPClient(apiKey, baseURL string) *http.Client {
+	if baseURL == "" {
+		baseURL = anthropicDefaultBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+	return &http.Client{Transport: &anthropicTransport{
+		apiKey:      apiKey,
+		messagesURL: baseURL + "/messages",
+		base:        http.DefaultTransport,
+	}}
+}
+
+// anthropicTransport 在 OpenAI 聊天接口和 Anthropic Messages API 之间转换请求和响应
+type anthropicTransport struct {
+	apiKey      string
+	messagesURL string
+	base        http.RoundTripper
+}
+
+// OpenAI 格式的聊天请求（只解析需要转换的字段）
+type openAIChatRequest struct {
+	Model               string          `json:"model"`
+	Messages            []openAIMessage `json:"messages"`
+	MaxTokens           int             `json:"max_tokens"`
+	MaxCompletionTokens int             `json:"max_completion_tokens"`
+	Temperature         *float32        `json:"temperature"`
+	TopP                *float32        `json:"top_p"`
+	Stop                []string        `json:"stop"`
+	N                   int             `json:"n"`
+	Stream              bool            `json:"stream"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+	Tools          []openai.Tool   `json:"tools"`
+	ToolChoice     json.RawMessage `json:"tool_choice"`
+	ResponseFormat *struct {
+		Type       string `json:"type"`
+		JSONSchema *struct {
+			Schema json.RawMessage `json:"schema"`
+		} `json:"json_schema"`
+	} `json:"response_format"`
+}
+
+type openAIMessage struct {
+	Role       string            `json:"role"`
+	Name       string            `json:"name"`
+	Content    json.RawMessage   `json:"content"` // 字符串或内容块数组
+	ToolCalls  []openai.ToolCall `json:"tool_calls"`
+	ToolCallID string            `json:"tool_call_id"`
+}
+
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []*anthropicMessage  `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   *float32             `json:"temperature,omitempty"`
+	TopP          *float32             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"` // user / assistant
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock 内容块：text、image、tool_use、tool_result、thinking
+type anthropicBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	Thinking  string                `json:"thinking,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64 / url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // auto / any / tool / none
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicEvent 流式事件，按 type 使用对应字段
+type anthropicEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message"`
+	ContentBlock *anthropicBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (t *anthropicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return openAIErrorResponse(req, http.StatusNotFound, "invalid_request_error",
+			fmt.Sprintf("%s is not supported by the anthropic provider, only chat completions are", req.URL.Path)), nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var chatReq openAIChatRequest
+	if err = json.Unmarshal(body, &chatReq); err != nil {
+		return openAIErrorResponse(req, http.StatusBadRequest, "invalid_request_error", err.Error()), nil
+	}
+	payload, err := json.Marshal(toAnthropicRequest(&chatReq))
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, err := http.NewRequestWithContext(req.Context(), http.MethodPost, t.messagesURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	upstream.Header.Set("Content-Type", "application/json")
+	upstream.Header.Set("x-api-key", t.apiKey)
+	upstream.Header.Set("anthropic-version", anthropicVersion)
+	if chatReq.Stream {
+		upstream.Header.Set("Accept", "text/event-stream")
+	}
+	resp, err := t.base.RoundTrip(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		var apiErr anthropicError
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error.Message == "" {
+			apiErr.Error.Type, apiErr.Error.Message = "api_error", strings.TrimSpace(string(data))
+		}
+		return openAIErrorResponse(req, resp.StatusCode, apiErr.Error.Type, apiErr.Error.Message), nil
+	}
+
+	if chatReq.Stream {
+		includeUsage := chatReq.StreamOptions != nil && chatReq.StreamOptions.IncludeUsage
+		pr, pw := io.Pipe()
+		go func() {
+			defer resp.Body.Close()
+			pw.CloseWithError(convertAnthropicStream(resp.Body, pw, includeUsage))
+		}()
+		return newResponse(req, http.StatusOK, "text/event-stream", pr), nil
+	}
+
+	defer resp.Body.Close()
+	var msg anthropicResponse
+	if err = json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to decode anthropic response: %w", err)
+	}
+	data, err := json.Marshal(toOpenAIResponse(&msg))
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, http.StatusOK, "application/json", io.NopCloser(bytes.NewReader(data))), nil
+}
+
+// toAnthropicRequest 转换请求：system 消息合并为 system 参数，工具结果作为用户消息中的 tool_result 块，相邻同角色消息合并（Messages API 要求角色交替）
+func toAnthropicRequest(req *openAIChatRequest) *anthropicRequest {
+	out := &anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxCompletionTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        req.Stream,
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = req.MaxTokens
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = anthropicDefaultMaxTokens
+	}
+	// Anthropic 的温度范围为 0-1，且部分模型不允许同时指定 temperature 和 top_p，同时指定时只保留 temperature
+	if out.Temperature != nil && *out.Temperature > 1 {
+		one := float32(1)
+		out.Temperature = &one
+	}
+	if out.Temperature != nil && out.TopP != nil {
+		out.TopP = nil
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			if text := textContent(msg.Content); text != "" {
+				system = append(system, text)
+			}
+		case "assistant":
+			blocks := contentBlocks(msg.Content)
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+			out.appendBlocks("assistant", blocks)
+		case "tool":
+			out.appendBlocks("user", []anthropicBlock{{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: textContent(msg.Content)}})
+		default:
+			out.appendBlocks("user", contentBlocks(msg.Content))
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		out.Tools = append(out.Tools, anthropicTool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: schema})
+	}
+	if len(out.Tools) > 0 {
+		out.ToolChoice = toAnthropicToolChoice(req.ToolChoice)
+	}
+	return out
+}
+
+// appendBlocks 追加消息，与上一条消息角色相同时合并内容块
+func (r *anthropicRequest) appendBlocks(role string, blocks []anthropicBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+	if n := len(r.Messages); n > 0 && r.Messages[n-1].Role == role {
+		r.Messages[n-1].Content = append(r.Messages[n-1].Content, blocks...)
+		return
+	}
+	r.Messages = append(r.Messages, &anthropicMessage{Role: role, Content: blocks})
+}
+
+// textContent 消息的文本内容，内容块数组时拼接所有文本块
+func textContent(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text
+	}
+	var parts []openai.ChatMessagePart
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == openai.ChatMessagePartTypeText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// contentBlocks 转换消息内容：文本为 text 块，图片为 image 块（data URL 转为 base64 来源），不支持的内容（音频、视频）忽略
+func contentBlocks(raw json.RawMessage) []anthropicBlock {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if text == "" {
+			return nil
+		}
+		return []anthropicBlock{{Type: "text", Text: text}}
+	}
+	var parts []openai.ChatMessagePart
+	if json.Unmarshal(raw, &parts) != nil {
+		return nil
+	}
+	var blocks []anthropicBlock
+	for _, part := range parts {
+		switch {
+		case part.Type == openai.ChatMessagePartTypeText && part.Text != "":
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+		case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: imageSource(part.ImageURL.URL)})
+		}
+	}
+	return blocks
+}
+
+// imageSource data:image/png;base64,... 转换为 base64 来源，其他地址作为 url 来源
+func imageSource(url string) *anthropicImageSource {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if meta, data, found := strings.Cut(rest, ","); found && strings.HasSuffix(meta, ";base64") {
+			return &anthropicImageSource{Type: "base64", MediaType: strings.TrimSuffix(meta, ";base64"), Data: data}
+		}
+	}
+	return &anthropicImageSource{Type: "url", URL: url}
+}
+
+// toAnthropicToolChoice 转换工具选择策略：auto / none / required（any）/ 指定函数（tool）
+func toAnthropicToolChoice(raw json.RawMessage) *anthropicToolChoice {
+	var choice string
+	if json.Unmarshal(raw, &choice) == nil {
+		switch choice {
+		case "none":
+			return &anthropicToolChoice{Type: "none"}
+		case "required":
+			return &anthropicToolChoice{Type: "any"}
+		default:
+			return &anthropicToolChoice{Type: "auto"}
+		}
+	}
+	var named openai.ToolChoice
+	if json.Unmarshal(raw, &named) == nil && named.Function.Name != "" {
+		return &anthropicToolChoice{Type: "tool", Name: named.Function.Name}
+	}
+	return &anthropicToolChoice{Type: "auto"}
+}
+
+// toOpenAIResponse 转换非流式响应：文本块拼接为 content，thinking 块为 reasoning_content，tool_use 块为 tool_calls
+func toOpenAIResponse(msg *anthropicResponse) *openai.ChatCompletionResponse {
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	var texts, thinking []string
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+		case "thinking":
+			thinking = append(thinking, block.Thinking)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:       block.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	message.Content = strings.Join(texts, "")
+	message.ReasoningContent = strings.Join(thinking, "")
+	return &openai.ChatCompletionResponse{
+		ID:      msg.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   msg.Model,
+		Choices: []openai.ChatCompletionChoice{{Index: 0, Message: message, FinishReason: finishReason(msg.StopReason)}},
+		Usage: openai.Usage{
+			PromptTokens:     msg.Usage.InputTokens,
+			CompletionTokens: msg.Usage.OutputTokens,
+			TotalTokens:      msg.Usage.InputTokens + msg.Usage.OutputTokens,
+		},
+	}
+}
+
+// finishReason 结束原因：end_turn / stop_sequence -> stop，max_tokens -> length，tool_use -> tool_calls
+func finishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "":
+		return openai.FinishReasonNull
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+// convertAnthropicStream 把 Anthropic 流式事件转换为 OpenAI chat.completion.chunk，以 data: [DONE] 结束；
+// tool_use 块按出现顺序编号为 tool_calls 的 index，参数增量作为 arguments 片段发送
+func convertAnthropicStream(r io.Reader, w io.Writer, includeUsage bool) error {
+	chunk := openai.ChatCompletionStreamResponse{Object: "chat.completion.chunk", Created: time.Now().Unix()}
+	write := func(delta openai.ChatCompletionStreamChoiceDelta, reason openai.FinishReason, usage *openai.Usage) error {
+		chunk.Choices = []openai.ChatCompletionStreamChoice{{Index: 0, Delta: delta, FinishReason: reason}}
+		if usage != nil {
+			chunk.Choices = []openai.ChatCompletionStreamChoice{}
+		}
+		chunk.Usage = usage
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+
+	toolIndex := make(map[int]int) // 内容块 index -> tool_calls index
+	var usage anthropicUsage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return fmt.Errorf("failed to decode anthropic stream event: %w", err)
+		}
+
+		var err error
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				chunk.ID, chunk.Model = event.Message.ID, event.Message.Model
+				usage.InputTokens = event.Message.Usage.InputTokens
+			}
+			err = write(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, "", nil)
+		case "content_block_start":
+			if block := event.ContentBlock; block != nil && block.Type == "tool_use" {
+				index := len(toolIndex)
+				toolIndex[event.Index] = index
+				err = write(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+					Index: &index, ID: block.ID, Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: block.Name},
+				}}}, "", nil)
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				err = write(openai.ChatCompletionStreamChoiceDelta{Content: event.Delta.Text}, "", nil)
+			case "thinking_delta":
+				err = write(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: event.Delta.Thinking}, "", nil)
+			case "input_json_delta":
+				index := toolIndex[event.Index]
+				err = write(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+					Index: &index, Function: openai.FunctionCall{Arguments: event.Delta.PartialJSON},
+				}}}, "", nil)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+			if err = write(openai.ChatCompletionStreamChoiceDelta{}, finishReason(event.Delta.StopReason), nil); err == nil && includeUsage {
+				err = write(openai.ChatCompletionStreamChoiceDelta{}, "", &openai.Usage{
+					PromptTokens:     usage.InputTokens,
+					CompletionTokens: usage.OutputTokens,
+					TotalTokens:      usage.InputTokens + usage.OutputTokens,
+				})
+			}
+		case "message_stop":
+			_, err = io.WriteString(w, "data: [DONE]\n\n")
+			if err == nil {
+				return nil
+			}
+		case "error":
+			message := "anthropic stream error"
+			errType := "api_error"
+			if event.Error != nil {
+				message, errType = event.Error.Message, event.Error.Type
+			}
+			data, _ := json.Marshal(map[string]any{"error": map[string]string{"message": message, "type": errType}})
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			if err == nil {
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// openAIErrorResponse OpenAI 格式的错误响应，go-openai 客户端解析为 APIError
+func openAIErrorResponse(req *http.Request, status int, errType, message string) *http.Response {
+	data, _ := json.Marshal(map[string]any{"error": map[string]string{"message": message, "type": errType}})
+	return newResponse(req, status, "application/json", io.NopCloser(bytes.NewReader(data)))
+}
+
+func newResponse(req *http.Request, status int, contentType string, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       body,
+		Request:    req,
+	}
+}
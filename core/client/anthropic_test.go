@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newAnthropicServer 假 Messages API：记录收到的请求，按 handler 返回响应
+func newAnthropicServer(t *testing.T, handler func(w http.ResponseWriter, req *anthropicRequest)) (*openai.Client, *anthropicRequest) {
+	t.Helper()
+	received := &anthropicRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "sk-test" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("unexpected request %s, headers %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handler(w, received)
+	}))
+	t.Cleanup(server.Close)
+	return NewProviderClient(ProviderAnthropic, "sk-test", server.URL), received
+}
+
+func TestAnthropicChatCompletion(t *testing.T) {
+	c, received := newAnthropicServer(t, func(w http.ResponseWriter, _ *anthropicRequest) {
+		io.WriteString(w, `{"id":"msg_1","model":"claude-sonnet","stop_reason":"tool_use",
+			"content":[{"type":"text","text":"查询天气"},{"type":"tool_use","id":"toolu_2","name":"weather","input":{"city":"上海"}}],
+			"usage":{"input_tokens":20,"output_tokens":8}}`)
+	})
+
+	temperature := float32(1.5)
+	resp, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:       "claude-sonnet",
+		Temperature: temperature,
+		TopP:        0.9,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: "system", Content: "你是助手"},
+			{Role: "user", Content: "北京天气？"},
+			{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "toolu_1", Type: "function", Function: openai.FunctionCall{Name: "weather", Arguments: `{"city":"北京"}`}}}},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "晴"},
+			{Role: "user", Content: "上海呢？"},
+		},
+		Tools:      []openai.Tool{{Type: "function", Function: &openai.FunctionDefinition{Name: "weather", Parameters: map[string]any{"type": "object"}}}},
+		ToolChoice: "required",
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if received.System != "你是助手" || received.MaxTokens != anthropicDefaultMaxTokens || *received.Temperature != 1 || received.TopP != nil {
+		t.Errorf("request params = system %q, max_tokens %d, temperature %v, top_p %v", received.System, received.MaxTokens, received.Temperature, received.TopP)
+	}
+	// 工具结果和随后的用户消息合并为同一条用户消息
+	if len(received.Messages) != 3 || received.Messages[1].Content[0].Type != "tool_use" || len(received.Messages[2].Content) != 2 ||
+		received.Messages[2].Content[0].Type != "tool_result" || received.Messages[2].Content[0].ToolUseID != "toolu_1" {
+		data, _ := json.Marshal(received.Messages)
+		t.Errorf("messages = %s", data)
+	}
+	if len(received.Tools) != 1 || received.ToolChoice == nil || received.ToolChoice.Type != "any" {
+		t.Errorf("tools = %v, tool_choice = %v", received.Tools, received.ToolChoice)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != "查询天气" || choice.FinishReason != openai.FinishReasonToolCalls || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("choice = %+v", choice)
+	}
+	if call := choice.Message.ToolCalls[0]; call.ID != "toolu_2" || call.Function.Arguments != `{"city":"上海"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if resp.Usage.TotalTokens != 28 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicChatCompletionStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet","usage":{"input_tokens":10}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"想一想"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"你好"}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"search"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"q\":"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"kb\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop"}`,
+	}
+	c, received := newAnthropicServer(t, func(w http.ResponseWriter, _ *anthropicRequest) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			io.WriteString(w, "event: x\ndata: "+event+"\n\n")
+		}
+	})
+
+	stream, err := c.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:         "claude-sonnet",
+		Messages:      []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, reasoning, args strings.Builder
+	var finish openai.FinishReason
+	var usage *openai.Usage
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+		for _, choice := range resp.Choices {
+			content.WriteString(choice.Delta.Content)
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			for _, call := range choice.Delta.ToolCalls {
+				if *call.Index != 0 {
+					t.Errorf("tool call index = %d, want 0", *call.Index)
+				}
+				args.WriteString(call.Function.Arguments)
+			}
+			if choice.FinishReason != "" {
+				finish = choice.FinishReason
+			}
+		}
+	}
+	if !received.Stream {
+		t.Errorf("upstream request is not streaming")
+	}
+	if content.String() != "你好" || reasoning.String() != "想一想" || args.String() != `{"q":"kb"}` || finish != openai.FinishReasonToolCalls {
+		t.Errorf("stream = content %q, reasoning %q, args %q, finish %q", content.String(), reasoning.String(), args.String(), finish)
+	}
+	if usage == nil || usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestAnthropicError(t *testing.T) {
+	c, _ := newAnthropicServer(t, func(w http.ResponseWriter, _ *anthropicRequest) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens too large"}}`)
+	})
+	_, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "claude-sonnet",
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadRequest || apiErr.Message != "max_tokens too large" {
+		t.Errorf("error = %v, want APIError 400", err)
+	}
+
+	if _, err = c.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{Input: []string{"x"}, Model: "claude"}); err == nil {
+		t.Errorf("embeddings must not be supported by the anthropic provider")
+	}
+}
+
+func TestImageSource(t *testing.T) {
+	if src := imageSource("data:image/png;base64,AAAA"); src.Type != "base64" || src.MediaType != "image/png" || src.Data != "AAAA" {
+		t.Errorf("imageSource(data url) = %+v", src)
+	}
+	if src := imageSource("https://example.com/a.png"); src.Type != "url" || src.URL != "https://example.com/a.png" {
+		t.Errorf("imageSource(url) = %+v", src)
+	}
+}
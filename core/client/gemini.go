@@ -0,0 +1,587 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// ProviderGemini 使用 Google Gemini generateContent 接口的模型提供商
+	ProviderGemini = "gemini"
+
+	geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+)
+
+// NewGeminiHTTPClient 创建调用 Gemini generateContent 接口的 HTTP 客户端，供 go-openai 客户端使用：
+// 把 OpenAI 格式的 /chat/completions 请求转换为 models/{model}:generateContent 请求（system 消息转为 systemInstruction，
+// 图片转为 inlineData，工具调用和结果转换为 functionCall / functionResponse），响应和流式数据转换回 OpenAI 格式。只支持聊天接口
+func NewGeminiHTTPClient(apiKey, baseURL string) *http.Client {
+	if baseURL == "" {
+		baseURL = geminiDefaultBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/v1") && !strings.HasSuffix(baseURL, "/v1beta") && !strings.HasSuffix(baseURL, "/v1alpha") {
+		baseURL += "/v1beta"
+	}
+	return &http.Client{Transport: &geminiTransport{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		base:    http.DefaultTransport,
+	}}
+}
+
+// geminiTransport 在 OpenAI 聊天接口和 Gemini generateContent 接口之间转换请求和响应
+type geminiTransport struct {
+	apiKey  string
+	baseURL string
+	base    http.RoundTripper
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []*geminiContent        `json:"contents"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"` // user / model
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart 内容片段：text、inlineData、fileData、functionCall、functionResponse，thought 为 true 时是推理内容
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"` // AUTO / ANY / NONE
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"topP,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	CandidateCount   int      `json:"candidateCount,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+type geminiResponse struct {
+	ResponseID   string            `json:"responseId"`
+	ModelVersion string            `json:"modelVersion"`
+	Candidates   []geminiCandidate `json:"candidates"`
+	Usage        *geminiUsage      `json:"usageMetadata"`
+	Error        *geminiErrorBody  `json:"error"`
+}
+
+type geminiCandidate struct {
+	Index        int            `json:"index"`
+	Content      *geminiContent `json:"content"`
+	FinishReason string         `json:"finishReason"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiErrorBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (t *geminiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return openAIErrorResponse(req, http.StatusNotFound, "invalid_request_error",
+			fmt.Sprintf("%s is not supported by the gemini provider, only chat completions are", req.URL.Path)), nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var chatReq openAIChatRequest
+	if err = json.Unmarshal(body, &chatReq); err != nil {
+		return openAIErrorResponse(req, http.StatusBadRequest, "invalid_request_error", err.Error()), nil
+	}
+	payload, err := json.Marshal(toGeminiRequest(&chatReq))
+	if err != nil {
+		return nil, err
+	}
+
+	model := strings.TrimPrefix(chatReq.Model, "models/")
+	url := fmt.Sprintf("%s/models/%s:generateContent", t.baseURL, model)
+	if chatReq.Stream {
+		url = fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", t.baseURL, model)
+	}
+	upstream, err := http.NewRequestWithContext(req.Context(), http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	upstream.Header.Set("Content-Type", "application/json")
+	upstream.Header.Set("x-goog-api-key", t.apiKey)
+	resp, err := t.base.RoundTrip(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		var apiErr geminiResponse
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == nil || apiErr.Error.Message == "" {
+			apiErr.Error = &geminiErrorBody{Status: "api_error", Message: strings.TrimSpace(string(data))}
+		}
+		return openAIErrorResponse(req, resp.StatusCode, apiErr.Error.Status, apiErr.Error.Message), nil
+	}
+
+	if chatReq.Stream {
+		includeUsage := chatReq.StreamOptions != nil && chatReq.StreamOptions.IncludeUsage
+		pr, pw := io.Pipe()
+		go func() {
+			defer resp.Body.Close()
+			pw.CloseWithError(convertGeminiStream(resp.Body, pw, chatReq.Model, includeUsage))
+		}()
+		return newResponse(req, http.StatusOK, "text/event-stream", pr), nil
+	}
+
+	defer resp.Body.Close()
+	var out geminiResponse
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode gemini response: %w", err)
+	}
+	data, err := json.Marshal(geminiToOpenAIResponse(&out, chatReq.Model))
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, http.StatusOK, "application/json", io.NopCloser(bytes.NewReader(data))), nil
+}
+
+// toGeminiRequest 转换请求：system 消息合并为 systemInstruction，助手消息角色为 model，
+// 工具结果按工具调用ID找到函数名后作为 functionResponse，相邻同角色消息合并
+func toGeminiRequest(req *openAIChatRequest) *geminiRequest {
+	out := &geminiRequest{}
+	config := &geminiGenerationConfig{
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		MaxOutputTokens: req.MaxCompletionTokens,
+		StopSequences:   req.Stop,
+	}
+	if config.MaxOutputTokens == 0 {
+		config.MaxOutputTokens = req.MaxTokens
+	}
+	if req.N > 1 {
+		config.CandidateCount = req.N
+	}
+	if req.ResponseFormat != nil && (req.ResponseFormat.Type == "json_object" || req.ResponseFormat.Type == "json_schema") {
+		config.ResponseMimeType = "application/json"
+	}
+	out.GenerationConfig = config
+
+	callNames := make(map[string]string) // 工具调用ID -> 函数名
+	var system []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			if text := textContent(msg.Content); text != "" {
+				system = append(system, text)
+			}
+		case "assistant":
+			parts := geminiParts(msg.Content)
+			for _, call := range msg.ToolCalls {
+				callNames[call.ID] = call.Function.Name
+				args := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Function.Name, Args: args}})
+			}
+			out.appendParts("model", parts)
+		case "tool":
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			out.appendParts("user", []geminiPart{{FunctionResponse: &geminiFunctionResponse{
+				Name:     name,
+				Response: functionResponse(textContent(msg.Content)),
+			}}})
+		default:
+			out.appendParts("user", geminiParts(msg.Content))
+		}
+	}
+	if len(system) > 0 {
+		out.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: strings.Join(system, "\n\n")}}}
+	}
+
+	var declarations []geminiFunctionDeclaration
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		declarations = append(declarations, geminiFunctionDeclaration{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  geminiSchema(tool.Function.Parameters),
+		})
+	}
+	if len(declarations) > 0 {
+		out.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+		out.ToolConfig = toGeminiToolConfig(req.ToolChoice)
+	}
+	return out
+}
+
+// appendParts 追加消息，与上一条消息角色相同时合并内容片段（多个工具结果需要在同一条消息中返回）
+func (r *geminiRequest) appendParts(role string, parts []geminiPart) {
+	if len(parts) == 0 {
+		return
+	}
+	if n := len(r.Contents); n > 0 && r.Contents[n-1].Role == role {
+		r.Contents[n-1].Parts = append(r.Contents[n-1].Parts, parts...)
+		return
+	}
+	r.Contents = append(r.Contents, &geminiContent{Role: role, Parts: parts})
+}
+
+// geminiParts 转换消息内容：文本为 text，data URL 图片为 inlineData，其他图片地址为 fileData，不支持的内容忽略
+func geminiParts(raw json.RawMessage) []geminiPart {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if text == "" {
+			return nil
+		}
+		return []geminiPart{{Text: text}}
+	}
+	var parts []openai.ChatMessagePart
+	if json.Unmarshal(raw, &parts) != nil {
+		return nil
+	}
+	var result []geminiPart
+	for _, part := range parts {
+		switch {
+		case part.Type == openai.ChatMessagePartTypeText && part.Text != "":
+			result = append(result, geminiPart{Text: part.Text})
+		case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+			src := imageSource(part.ImageURL.URL)
+			if src.Type == "base64" {
+				result = append(result, geminiPart{InlineData: &geminiBlob{MimeType: src.MediaType, Data: src.Data}})
+				continue
+			}
+			mimeType := mime.TypeByExtension(path.Ext(strings.SplitN(src.URL, "?", 2)[0]))
+			if mimeType == "" {
+				mimeType = "image/jpeg"
+			}
+			result = append(result, geminiPart{FileData: &geminiFileData{MimeType: mimeType, FileURI: src.URL}})
+		}
+	}
+	return result
+}
+
+// functionResponse 工具结果：JSON 对象直接作为 response，其他内容包装为 {"result": 内容}
+func functionResponse(content string) json.RawMessage {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	data, _ := json.Marshal(map[string]string{"result": content})
+	return data
+}
+
+// geminiUnsupportedSchemaKeys Gemini 函数参数不支持的 JSON Schema 字段，发送前去掉
+var geminiUnsupportedSchemaKeys = []string{"$schema", "$id", "$defs", "definitions", "additionalProperties"}
+
+// geminiSchema 转换函数参数：序列化后递归去掉不支持的字段，没有参数时返回 nil
+func geminiSchema(params any) any {
+	if params == nil {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return params
+	}
+	var schema any
+	if json.Unmarshal(data, &schema) != nil {
+		return params
+	}
+	if m, ok := schema.(map[string]any); ok {
+		if props, ok := m["properties"].(map[string]any); m["type"] == "object" && (!ok || len(props) == 0) {
+			return nil
+		}
+	}
+	return stripSchemaKeys(schema)
+}
+
+func stripSchemaKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range geminiUnsupportedSchemaKeys {
+			delete(v, key)
+		}
+		for key, item := range v {
+			v[key] = stripSchemaKeys(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = stripSchemaKeys(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// toGeminiToolConfig 转换工具选择策略：auto（AUTO）/ none（NONE）/ required（ANY）/ 指定函数（ANY + allowedFunctionNames）
+func toGeminiToolConfig(raw json.RawMessage) *geminiToolConfig {
+	config := &geminiToolConfig{}
+	config.FunctionCallingConfig.Mode = "AUTO"
+	var choice string
+	if json.Unmarshal(raw, &choice) == nil {
+		switch choice {
+		case "none":
+			config.FunctionCallingConfig.Mode = "NONE"
+		case "required":
+			config.FunctionCallingConfig.Mode = "ANY"
+		}
+		return config
+	}
+	var named openai.ToolChoice
+	if json.Unmarshal(raw, &named) == nil && named.Function.Name != "" {
+		config.FunctionCallingConfig.Mode = "ANY"
+		config.FunctionCallingConfig.AllowedFunctionNames = []string{named.Function.Name}
+	}
+	return config
+}
+
+// geminiToOpenAIResponse 转换非流式响应：每个候选为一个 choice，文本片段拼接为 content，thought 片段为 reasoning_content，
+// functionCall 为 tool_calls（Gemini 未返回ID时按顺序生成）
+func geminiToOpenAIResponse(resp *geminiResponse, model string) *openai.ChatCompletionResponse {
+	out := &openai.ChatCompletionResponse{
+		ID:      resp.ResponseID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openai.ChatCompletionChoice{},
+	}
+	if resp.ModelVersion != "" {
+		out.Model = resp.ModelVersion
+	}
+	for i, candidate := range resp.Candidates {
+		message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+		var texts, thoughts []string
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					message.ToolCalls = append(message.ToolCalls, geminiToolCall(part.FunctionCall, len(message.ToolCalls)))
+				case part.Thought:
+					thoughts = append(thoughts, part.Text)
+				default:
+					texts = append(texts, part.Text)
+				}
+			}
+		}
+		message.Content = strings.Join(texts, "")
+		message.ReasoningContent = strings.Join(thoughts, "")
+		out.Choices = append(out.Choices, openai.ChatCompletionChoice{
+			Index:        i,
+			Message:      message,
+			FinishReason: geminiFinishReason(candidate.FinishReason, len(message.ToolCalls) > 0),
+		})
+	}
+	if resp.Usage != nil {
+		out.Usage = geminiOpenAIUsage(resp.Usage)
+	}
+	return out
+}
+
+// geminiToolCall 转换函数调用，Gemini 未返回调用ID时生成 call_<序号>
+func geminiToolCall(call *geminiFunctionCall, index int) openai.ToolCall {
+	id := call.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%d", index)
+	}
+	args := string(call.Args)
+	if args == "" || args == "null" {
+		args = "{}"
+	}
+	return openai.ToolCall{ID: id, Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: call.Name, Arguments: args}}
+}
+
+// geminiFinishReason 结束原因：有函数调用时为 tool_calls，MAX_TOKENS -> length，SAFETY 等内容拦截 -> content_filter，其他 -> stop
+func geminiFinishReason(reason string, toolCalls bool) openai.FinishReason {
+	switch {
+	case reason == "":
+		return openai.FinishReasonNull
+	case toolCalls:
+		return openai.FinishReasonToolCalls
+	case reason == "MAX_TOKENS":
+		return openai.FinishReasonLength
+	case reason == "SAFETY" || reason == "RECITATION" || reason == "BLOCKLIST" || reason == "PROHIBITED_CONTENT" || reason == "SPII":
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+// geminiOpenAIUsage 用量，推理 token 计入 completion_tokens
+func geminiOpenAIUsage(usage *geminiUsage) openai.Usage {
+	completion := usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	total := usage.TotalTokenCount
+	if total == 0 {
+		total = usage.PromptTokenCount + completion
+	}
+	return openai.Usage{PromptTokens: usage.PromptTokenCount, CompletionTokens: completion, TotalTokens: total}
+}
+
+// convertGeminiStream 把 Gemini 流式响应（每个 data 为一个 GenerateContentResponse 片段）转换为 OpenAI chat.completion.chunk，
+// 函数调用在一个片段中完整返回，按出现顺序编号为 tool_calls 的 index；上游结束后发送用量（include_usage 时）和 data: [DONE]
+func convertGeminiStream(r io.Reader, w io.Writer, model string, includeUsage bool) error {
+	chunk := openai.ChatCompletionStreamResponse{Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: model}
+	write := func(delta openai.ChatCompletionStreamChoiceDelta, reason openai.FinishReason, usage *openai.Usage) error {
+		chunk.Choices = []openai.ChatCompletionStreamChoice{{Index: 0, Delta: delta, FinishReason: reason}}
+		if usage != nil {
+			chunk.Choices = []openai.ChatCompletionStreamChoice{}
+		}
+		chunk.Usage = usage
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+
+	started := false
+	toolCalls := 0
+	var usage *geminiUsage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event geminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return fmt.Errorf("failed to decode gemini stream event: %w", err)
+		}
+		if event.Error != nil {
+			data, _ := json.Marshal(map[string]any{"error": map[string]string{"message": event.Error.Message, "type": event.Error.Status}})
+			_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+			return err
+		}
+		if event.Usage != nil {
+			usage = event.Usage
+		}
+		if !started {
+			started = true
+			chunk.ID = event.ResponseID
+			if event.ModelVersion != "" {
+				chunk.Model = event.ModelVersion
+			}
+			if err := write(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, "", nil); err != nil {
+				return err
+			}
+		}
+		if len(event.Candidates) == 0 {
+			continue
+		}
+		candidate := event.Candidates[0]
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				var err error
+				switch {
+				case part.FunctionCall != nil:
+					index := toolCalls
+					call := geminiToolCall(part.FunctionCall, index)
+					call.Index = &index
+					toolCalls++
+					err = write(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{call}}, "", nil)
+				case part.Thought && part.Text != "":
+					err = write(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: part.Text}, "", nil)
+				case part.Text != "":
+					err = write(openai.ChatCompletionStreamChoiceDelta{Content: part.Text}, "", nil)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if candidate.FinishReason != "" {
+			if err := write(openai.ChatCompletionStreamChoiceDelta{}, geminiFinishReason(candidate.FinishReason, toolCalls > 0), nil); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !started {
+		return io.ErrUnexpectedEOF
+	}
+	if includeUsage && usage != nil {
+		u := geminiOpenAIUsage(usage)
+		if err := write(openai.ChatCompletionStreamChoiceDelta{}, "", &u); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newGeminiServer 假 generateContent 接口：记录收到的请求路径和请求体，按 handler 返回响应
+func newGeminiServer(t *testing.T, handler func(w http.ResponseWriter)) (*openai.Client, *geminiRequest, *string) {
+	t.Helper()
+	received := &geminiRequest{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "key-test" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		path = r.URL.RequestURI()
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handler(w)
+	}))
+	t.Cleanup(server.Close)
+	return NewProviderClient(ProviderGemini, "key-test", server.URL), received, &path
+}
+
+func TestGeminiChatCompletion(t *testing.T) {
+	c, received, path := newGeminiServer(t, func(w http.ResponseWriter) {
+		io.WriteString(w, `{"responseId":"r1","modelVersion":"gemini-2.5-flash","candidates":[{"content":{"role":"model","parts":[
+			{"text":"先想想","thought":true},{"text":"查询天气"},{"functionCall":{"name":"weather","args":{"city":"上海"}}}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":8,"thoughtsTokenCount":2,"totalTokenCount":30}}`)
+	})
+
+	resp, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:               "gemini-2.5-flash",
+		MaxCompletionTokens: 512,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: "system", Content: "你是助手"},
+			{Role: "user", MultiContent: []openai.ChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "这张图是哪里？"},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,AAAA"}},
+			}},
+			{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "call_0", Type: "function", Function: openai.FunctionCall{Name: "weather", Arguments: `{"city":"北京"}`}}}},
+			{Role: "tool", ToolCallID: "call_0", Content: "晴"},
+			{Role: "user", Content: "上海呢？"},
+		},
+		Tools: []openai.Tool{{Type: "function", Function: &openai.FunctionDefinition{Name: "weather", Parameters: map[string]any{
+			"type": "object", "additionalProperties": false, "properties": map[string]any{"city": map[string]any{"type": "string"}},
+		}}}},
+		ToolChoice: openai.ToolChoice{Type: "function", Function: openai.ToolFunction{Name: "weather"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if *path != "/v1beta/models/gemini-2.5-flash:generateContent" {
+		t.Errorf("path = %s", *path)
+	}
+	if received.SystemInstruction == nil || received.SystemInstruction.Parts[0].Text != "你是助手" || received.GenerationConfig.MaxOutputTokens != 512 {
+		t.Errorf("system = %+v, generation config = %+v", received.SystemInstruction, received.GenerationConfig)
+	}
+	// 工具结果和随后的用户消息合并为同一条用户消息，工具结果按调用ID找到函数名
+	contents := received.Contents
+	if len(contents) != 3 || contents[0].Parts[1].InlineData == nil || contents[0].Parts[1].InlineData.MimeType != "image/png" ||
+		contents[1].Role != "model" || contents[1].Parts[0].FunctionCall == nil ||
+		len(contents[2].Parts) != 2 || contents[2].Parts[0].FunctionResponse == nil || contents[2].Parts[0].FunctionResponse.Name != "weather" ||
+		string(contents[2].Parts[0].FunctionResponse.Response) != `{"result":"晴"}` {
+		data, _ := json.Marshal(contents)
+		t.Errorf("contents = %s", data)
+	}
+	params, _ := json.Marshal(received.Tools[0].FunctionDeclarations[0].Parameters)
+	if strings.Contains(string(params), "additionalProperties") {
+		t.Errorf("unsupported schema keys must be removed: %s", params)
+	}
+	if mode := received.ToolConfig.FunctionCallingConfig; mode.Mode != "ANY" || len(mode.AllowedFunctionNames) != 1 {
+		t.Errorf("tool config = %+v", mode)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != "查询天气" || choice.Message.ReasoningContent != "先想想" || choice.FinishReason != openai.FinishReasonToolCalls {
+		t.Fatalf("choice = %+v", choice)
+	}
+	if call := choice.Message.ToolCalls[0]; call.ID != "call_0" || call.Function.Name != "weather" || call.Function.Arguments != `{"city":"上海"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if resp.Usage.CompletionTokens != 10 || resp.Usage.TotalTokens != 30 || resp.Model != "gemini-2.5-flash" {
+		t.Errorf("usage = %+v, model = %s", resp.Usage, resp.Model)
+	}
+}
+
+func TestGeminiChatCompletionStream(t *testing.T) {
+	events := []string{
+		`{"responseId":"r1","candidates":[{"content":{"role":"model","parts":[{"text":"想一想","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"你"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"好"},{"functionCall":{"name":"search","args":{"q":"kb"}}}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}`,
+	}
+	c, _, path := newGeminiServer(t, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			io.WriteString(w, "data: "+strings.ReplaceAll(event, "\n", "")+"\r\n\r\n")
+		}
+	})
+
+	stream, err := c.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:         "gemini-2.5-flash",
+		Messages:      []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, reasoning, args strings.Builder
+	var finish openai.FinishReason
+	var usage *openai.Usage
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+		for _, choice := range resp.Choices {
+			content.WriteString(choice.Delta.Content)
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			for _, call := range choice.Delta.ToolCalls {
+				args.WriteString(call.Function.Arguments)
+			}
+			if choice.FinishReason != "" {
+				finish = choice.FinishReason
+			}
+		}
+	}
+	if *path != "/v1beta/models/gemini-2.5-flash:streamGenerateContent?alt=sse" {
+		t.Errorf("path = %s", *path)
+	}
+	if content.String() != "你好" || reasoning.String() != "想一想" || args.String() != `{"q":"kb"}` || finish != openai.FinishReasonToolCalls {
+		t.Errorf("stream = content %q, reasoning %q, args %q, finish %q", content.String(), reasoning.String(), args.String(), finish)
+	}
+	if usage == nil || usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestGeminiError(t *testing.T) {
+	c, _, _ := newGeminiServer(t, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`)
+	})
+	_, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "gemini-2.5-flash",
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadRequest || apiErr.Message != "API key not valid" {
+		t.Errorf("error = %v, want APIError 400", err)
+	}
+}
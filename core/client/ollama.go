@@ -0,0 +1,462 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// ProviderOllama 使用 Ollama 原生接口（/api/chat）的本地模型提供商
+	ProviderOllama = "ollama"
+
+	ollamaDefaultBaseURL = "http://localhost:11434"
+)
+
+// OllamaBaseURL Ollama 服务根地址：去掉 OpenAI 兼容接口的 /v1 后缀，未设置时使用本机默认端口
+func OllamaBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return ollamaDefaultBaseURL
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1")
+}
+
+// OllamaOpenAIBaseURL Ollama 的 OpenAI 兼容接口地址（根地址 + /v1），向量化等直接按 OpenAI 格式调用的场景使用
+func OllamaOpenAIBaseURL(baseURL string) string {
+	return OllamaBaseURL(baseURL) + "/v1"
+}
+
+// NewOllamaHTTPClient 创建调用 Ollama 的 HTTP 客户端，供 go-openai 客户端使用：
+// /chat/completions 请求转换为原生的 /api/chat 请求（图片转为 images，推理内容 thinking 转为 reasoning_content，流式响应从 NDJSON 转换为 SSE），
+// 其他请求（如 /embeddings、/models）使用 Ollama 的 OpenAI 兼容接口
+func NewOllamaHTTPClient(baseURL string) *http.Client {
+	return &http.Client{Transport: &ollamaTransport{
+		baseURL: OllamaBaseURL(baseURL),
+		base:    http.DefaultTransport,
+	}}
+}
+
+// ollamaTransport 在 OpenAI 聊天接口和 Ollama /api/chat 接口之间转换请求和响应
+type ollamaTransport struct {
+	baseURL string
+	base    http.RoundTripper
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openai.Tool   `json:"tools,omitempty"`
+	Format   json.RawMessage `json:"format,omitempty"` // "json" 或 JSON Schema
+	Options  map[string]any  `json:"options,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"` // base64 编码的图片（不带 data URL 前缀）
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function ollamaFunctionCall `json:"function"`
+}
+
+type ollamaFunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"` // JSON 对象
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (t *ollamaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var chatReq openAIChatRequest
+	if err = json.Unmarshal(body, &chatReq); err != nil {
+		return openAIErrorResponse(req, http.StatusBadRequest, "invalid_request_error", err.Error()), nil
+	}
+	payload, err := json.Marshal(toOllamaRequest(&chatReq))
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, err := http.NewRequestWithContext(req.Context(), http.MethodPost, t.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	upstream.Header.Set("Content-Type", "application/json")
+	resp, err := t.base.RoundTrip(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return openAIErrorResponse(req, resp.StatusCode, "api_error", ollamaErrorMessage(data)), nil
+	}
+
+	if chatReq.Stream {
+		includeUsage := chatReq.StreamOptions != nil && chatReq.StreamOptions.IncludeUsage
+		pr, pw := io.Pipe()
+		go func() {
+			defer resp.Body.Close()
+			pw.CloseWithError(convertOllamaStream(resp.Body, pw, chatReq.Model, includeUsage))
+		}()
+		return newResponse(req, http.StatusOK, "text/event-stream", pr), nil
+	}
+
+	defer resp.Body.Close()
+	var out ollamaChatResponse
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	data, err := json.Marshal(ollamaToOpenAIResponse(&out, chatReq.Model))
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, http.StatusOK, "application/json", io.NopCloser(bytes.NewReader(data))), nil
+}
+
+// ollamaErrorMessage 解析 Ollama 的错误响应 {"error": "..."}，无法解析时返回原始内容
+func ollamaErrorMessage(data []byte) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// toOllamaRequest 转换请求：采样参数放入 options，图片只支持 data URL（Ollama 不下载远程图片），
+// 工具结果按工具调用ID找到函数名后作为 tool_name
+func toOllamaRequest(req *openAIChatRequest) *ollamaChatRequest {
+	out := &ollamaChatRequest{Model: req.Model, Tools: req.Tools, Stream: req.Stream}
+	options := map[string]any{}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if maxTokens := req.MaxCompletionTokens; maxTokens > 0 {
+		options["num_predict"] = maxTokens
+	} else if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+	if len(options) > 0 {
+		out.Options = options
+	}
+	if req.ResponseFormat != nil {
+		switch {
+		case req.ResponseFormat.Type == "json_schema" && req.ResponseFormat.JSONSchema != nil && len(req.ResponseFormat.JSONSchema.Schema) > 0:
+			out.Format = req.ResponseFormat.JSONSchema.Schema
+		case req.ResponseFormat.Type == "json_object" || req.ResponseFormat.Type == "json_schema":
+			out.Format = json.RawMessage(`"json"`)
+		}
+	}
+
+	callNames := make(map[string]string) // 工具调用ID -> 函数名
+	for _, msg := range req.Messages {
+		role := msg.Role
+		if role == "developer" {
+			role = "system"
+		}
+		om := ollamaMessage{Role: role}
+		var text string
+		if json.Unmarshal(msg.Content, &text) == nil {
+			om.Content = text
+		} else {
+			var parts []openai.ChatMessagePart
+			_ = json.Unmarshal(msg.Content, &parts)
+			var texts []string
+			for _, part := range parts {
+				switch {
+				case part.Type == openai.ChatMessagePartTypeText:
+					texts = append(texts, part.Text)
+				case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+					if src := imageSource(part.ImageURL.URL); src.Type == "base64" {
+						om.Images = append(om.Images, src.Data)
+					}
+				}
+			}
+			om.Content = strings.Join(texts, "\n")
+		}
+		for _, call := range msg.ToolCalls {
+			callNames[call.ID] = call.Function.Name
+			args := json.RawMessage(call.Function.Arguments)
+			if !json.Valid(args) {
+				args = json.RawMessage("{}")
+			}
+			om.ToolCalls = append(om.ToolCalls, ollamaToolCall{Function: ollamaFunctionCall{Name: call.Function.Name, Arguments: args}})
+		}
+		if role == "tool" {
+			om.ToolName = msg.Name
+			if om.ToolName == "" {
+				om.ToolName = callNames[msg.ToolCallID]
+			}
+		}
+		out.Messages = append(out.Messages, om)
+	}
+	return out
+}
+
+// ollamaToOpenAIResponse 转换非流式响应，thinking 为 reasoning_content，工具调用按顺序生成ID
+func ollamaToOpenAIResponse(resp *ollamaChatResponse, model string) *openai.ChatCompletionResponse {
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	message := openai.ChatCompletionMessage{
+		Role:             openai.ChatMessageRoleAssistant,
+		Content:          resp.Message.Content,
+		ReasoningContent: resp.Message.Thinking,
+	}
+	for i, call := range resp.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, ollamaOpenAIToolCall(call, i))
+	}
+	return &openai.ChatCompletionResponse{
+		ID:      fmt.Sprintf("chatcmpl-ollama-%d", time.Now().UnixNano()),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openai.ChatCompletionChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: ollamaFinishReason(resp.DoneReason, len(message.ToolCalls) > 0),
+		}},
+		Usage: ollamaOpenAIUsage(resp),
+	}
+}
+
+// ollamaOpenAIToolCall 转换工具调用，Ollama 不返回调用ID，生成 call_<序号>
+func ollamaOpenAIToolCall(call ollamaToolCall, index int) openai.ToolCall {
+	args := string(call.Function.Arguments)
+	if args == "" || args == "null" {
+		args = "{}"
+	}
+	return openai.ToolCall{
+		ID:       fmt.Sprintf("call_%d", index),
+		Type:     openai.ToolTypeFunction,
+		Function: openai.FunctionCall{Name: call.Function.Name, Arguments: args},
+	}
+}
+
+// ollamaFinishReason 结束原因：有工具调用时为 tool_calls，length -> length，其他 -> stop
+func ollamaFinishReason(reason string, toolCalls bool) openai.FinishReason {
+	switch {
+	case toolCalls:
+		return openai.FinishReasonToolCalls
+	case reason == "length":
+		return openai.FinishReasonLength
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+func ollamaOpenAIUsage(resp *ollamaChatResponse) openai.Usage {
+	return openai.Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
+
+// convertOllamaStream 把 Ollama 流式响应（每行一个 JSON 片段，最后一行 done 为 true 并带用量）转换为 OpenAI chat.completion.chunk，
+// 工具调用在一个片段中完整返回，按出现顺序编号；结束后发送用量（include_usage 时）和 data: [DONE]
+func convertOllamaStream(r io.Reader, w io.Writer, model string, includeUsage bool) error {
+	chunk := openai.ChatCompletionStreamResponse{
+		ID:      fmt.Sprintf("chatcmpl-ollama-%d", time.Now().UnixNano()),
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+	write := func(delta openai.ChatCompletionStreamChoiceDelta, reason openai.FinishReason, usage *openai.Usage) error {
+		chunk.Choices = []openai.ChatCompletionStreamChoice{{Index: 0, Delta: delta, FinishReason: reason}}
+		if usage != nil {
+			chunk.Choices = []openai.ChatCompletionStreamChoice{}
+		}
+		chunk.Usage = usage
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+
+	if err := write(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, "", nil); err != nil {
+		return err
+	}
+	toolCalls := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event ollamaChatResponse
+		if err := json.Unmarshal(line, &event); err != nil {
+			return fmt.Errorf("failed to decode ollama stream event: %w", err)
+		}
+		if event.Error != "" {
+			data, _ := json.Marshal(map[string]any{"error": map[string]string{"message": event.Error, "type": "api_error"}})
+			_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+			return err
+		}
+		if event.Model != "" {
+			chunk.Model = event.Model
+		}
+		if event.Message.Thinking != "" {
+			if err := write(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: event.Message.Thinking}, "", nil); err != nil {
+				return err
+			}
+		}
+		if event.Message.Content != "" {
+			if err := write(openai.ChatCompletionStreamChoiceDelta{Content: event.Message.Content}, "", nil); err != nil {
+				return err
+			}
+		}
+		for _, call := range event.Message.ToolCalls {
+			index := toolCalls
+			toolCall := ollamaOpenAIToolCall(call, index)
+			toolCall.Index = &index
+			toolCalls++
+			if err := write(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{toolCall}}, "", nil); err != nil {
+				return err
+			}
+		}
+		if !event.Done {
+			continue
+		}
+		if err := write(openai.ChatCompletionStreamChoiceDelta{}, ollamaFinishReason(event.DoneReason, toolCalls > 0), nil); err != nil {
+			return err
+		}
+		if includeUsage {
+			usage := ollamaOpenAIUsage(&event)
+			if err := write(openai.ChatCompletionStreamChoiceDelta{}, "", &usage); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "data: [DONE]\n\n")
+		return err
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// OllamaModel Ollama 服务上已下载的模型
+type OllamaModel struct {
+	Name       string `json:"name"`
+	Model      string `json:"model"`
+	Size       int64  `json:"size"`
+	ModifiedAt string `json:"modified_at"`
+	Details    struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// ListOllamaModels 列出 Ollama 服务上已下载的模型（GET /api/tags）
+func ListOllamaModels(ctx context.Context, baseURL string) ([]OllamaModel, error) {
+	var out struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := ollamaCall(ctx, http.MethodGet, OllamaBaseURL(baseURL)+"/api/tags", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Models, nil
+}
+
+// HasOllamaModel 模型是否已下载，未指定标签的名称按 :latest 匹配
+func HasOllamaModel(models []OllamaModel, name string) bool {
+	for _, m := range models {
+		if m.Name == name || m.Model == name || m.Name == name+":latest" {
+			return true
+		}
+	}
+	return false
+}
+
+// WarmUpOllamaModel 预加载模型到内存，避免第一次请求等待加载：聊天模型发送不带消息的 /api/chat 请求，
+// 向量化模型发送一次 /api/embed 请求；keepAlive 为加载后保持在内存中的时间（如 30m，为空时使用 Ollama 默认值）
+func WarmUpOllamaModel(ctx context.Context, baseURL, model string, embedding bool, keepAlive string) error {
+	body := map[string]any{"model": model}
+	if keepAlive != "" {
+		body["keep_alive"] = keepAlive
+	}
+	path := "/api/chat"
+	if embedding {
+		path = "/api/embed"
+		body["input"] = "warm up"
+	} else {
+		body["messages"] = []any{}
+		body["stream"] = false
+	}
+	return ollamaCall(ctx, http.MethodPost, OllamaBaseURL(baseURL)+path, body, nil)
+}
+
+// ollamaCall 调用 Ollama 管理接口，out 不为 nil 时解析响应
+func ollamaCall(ctx context.Context, method, url string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("ollama %s returned %d: %s", url, resp.StatusCode, ollamaErrorMessage(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
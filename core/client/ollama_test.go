@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newOllamaServer 假 Ollama 服务：记录 /api/chat 收到的请求体，按 handler 返回响应；/api/tags 返回已下载的模型
+func newOllamaServer(t *testing.T, handler func(w http.ResponseWriter)) (*openai.Client, *ollamaChatRequest, string) {
+	t.Helper()
+	received := &ollamaChatRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			io.WriteString(w, `{"models":[{"name":"qwen3:8b","model":"qwen3:8b","size":5200000000,"details":{"family":"qwen3","parameter_size":"8.2B"}},
+				{"name":"bge-m3:latest","model":"bge-m3:latest"}]}`)
+		case "/api/chat":
+			if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			handler(w)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	// 注册时填写的是 OpenAI 兼容地址（/v1 后缀），原生接口使用根地址
+	return NewProviderClient(ProviderOllama, "", server.URL+"/v1"), received, server.URL
+}
+
+func TestOllamaChatCompletion(t *testing.T) {
+	c, received, _ := newOllamaServer(t, func(w http.ResponseWriter) {
+		io.WriteString(w, `{"model":"qwen3:8b","message":{"role":"assistant","content":"","thinking":"先查天气",
+			"tool_calls":[{"function":{"name":"weather","arguments":{"city":"上海"}}}]},"done":true,"done_reason":"stop",
+			"prompt_eval_count":20,"eval_count":8}`)
+	})
+
+	temperature := float32(0.2)
+	resp, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:               "qwen3:8b",
+		Temperature:         temperature,
+		MaxCompletionTokens: 256,
+		ResponseFormat:      &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		Messages: []openai.ChatCompletionMessage{
+			{Role: "system", Content: "你是助手"},
+			{Role: "user", MultiContent: []openai.ChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "这张图是哪里？"},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,AAAA"}},
+			}},
+			{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "call_0", Type: "function", Function: openai.FunctionCall{Name: "weather", Arguments: `{"city":"北京"}`}}}},
+			{Role: "tool", ToolCallID: "call_0", Content: "晴"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if received.Stream || received.Options["num_predict"] != float64(256) || string(received.Format) != `"json"` {
+		t.Errorf("request = stream %v, options %v, format %s", received.Stream, received.Options, received.Format)
+	}
+	msgs := received.Messages
+	if len(msgs) != 4 || msgs[1].Content != "这张图是哪里？" || len(msgs[1].Images) != 1 || msgs[1].Images[0] != "AAAA" ||
+		string(msgs[2].ToolCalls[0].Function.Arguments) != `{"city":"北京"}` || msgs[3].ToolName != "weather" {
+		data, _ := json.Marshal(msgs)
+		t.Errorf("messages = %s", data)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.ReasoningContent != "先查天气" || choice.FinishReason != openai.FinishReasonToolCalls {
+		t.Fatalf("choice = %+v", choice)
+	}
+	if call := choice.Message.ToolCalls[0]; call.ID != "call_0" || call.Function.Name != "weather" || call.Function.Arguments != `{"city":"上海"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if resp.Usage.TotalTokens != 28 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOllamaChatCompletionStream(t *testing.T) {
+	c, received, _ := newOllamaServer(t, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, `{"model":"qwen3:8b","message":{"role":"assistant","content":"","thinking":"想一想"},"done":false}
+{"model":"qwen3:8b","message":{"role":"assistant","content":"你"},"done":false}
+{"model":"qwen3:8b","message":{"role":"assistant","content":"好"},"done":false}
+{"model":"qwen3:8b","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":10,"eval_count":5}
+`)
+	})
+
+	stream, err := c.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:         "qwen3:8b",
+		Messages:      []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream() error = %v", err)
+	}
+	defer stream.Close()
+
+	var content, reasoning strings.Builder
+	var finish openai.FinishReason
+	var usage *openai.Usage
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+		for _, choice := range resp.Choices {
+			content.WriteString(choice.Delta.Content)
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			if choice.FinishReason != "" {
+				finish = choice.FinishReason
+			}
+		}
+	}
+	if !received.Stream {
+		t.Errorf("upstream request must be streaming")
+	}
+	if content.String() != "你好" || reasoning.String() != "想一想" || finish != openai.FinishReasonLength {
+		t.Errorf("stream = content %q, reasoning %q, finish %q", content.String(), reasoning.String(), finish)
+	}
+	if usage == nil || usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestOllamaError(t *testing.T) {
+	c, _, _ := newOllamaServer(t, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"model \"llama3\" not found, try pulling it first"}`)
+	})
+	_, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "llama3",
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusNotFound || !strings.Contains(apiErr.Message, "try pulling it first") {
+		t.Errorf("error = %v, want APIError 404", err)
+	}
+}
+
+func TestListOllamaModels(t *testing.T) {
+	_, _, baseURL := newOllamaServer(t, nil)
+	models, err := ListOllamaModels(context.Background(), baseURL+"/v1/")
+	if err != nil {
+		t.Fatalf("ListOllamaModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].Details.ParameterSize != "8.2B" {
+		t.Fatalf("models = %+v", models)
+	}
+	for name, want := range map[string]bool{"qwen3:8b": true, "bge-m3": true, "qwen3": false, "llama3": false} {
+		if got := HasOllamaModel(models, name); got != want {
+			t.Errorf("HasOllamaModel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 )
@@ -24,6 +25,31 @@ func NewOpenAIClient(apiKey, baseURL string) *OpenAIClient {
 	}
 }
 
+// NewProviderClient 按提供商创建 go-openai 客户端：anthropic 通过 Messages API 适配（见 NewAnthropicHTTPClient），
+// gemini 通过 generateContent 接口适配（见 NewGeminiHTTPClient），ollama 的聊天使用原生 /api/chat 接口（见 NewOllamaHTTPClient），
+// 其他提供商使用 OpenAI 兼容接口
+func NewProviderClient(provider, apiKey, baseURL string) *openai.Client {
+	config := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		config.BaseURL = baseURL
+	}
+	switch {
+	case strings.EqualFold(provider, ProviderAnthropic):
+		config.HTTPClient = NewAnthropicHTTPClient(apiKey, baseURL)
+	case strings.EqualFold(provider, ProviderGemini):
+		config.HTTPClient = NewGeminiHTTPClient(apiKey, baseURL)
+	case strings.EqualFold(provider, ProviderOllama):
+		config.BaseURL = OllamaOpenAIBaseURL(baseURL)
+		config.HTTPClient = NewOllamaHTTPClient(baseURL)
+	}
+	return openai.NewClientWithConfig(config)
+}
+
+// WrapOpenAIClient 使用已创建的 go-openai 客户端（如模型注册表中按提供商创建的客户端）
+func WrapOpenAIClient(c *openai.Client) *OpenAIClient {
+	return &OpenAIClient{client: c}
+}
+
 // ChatCompletionRequest 聊天请求参数
 type ChatCompletionRequest struct {
 	Model               string
@@ -39,6 +65,7 @@ type ChatCompletionRequest struct {
 	ToolChoice          any
 	ResponseFormat      *openai.ChatCompletionResponseFormat
 	Stream              bool
+	LogProbs            bool
 }
 
 // ChatCompletion 非流式对话
@@ -56,6 +83,7 @@ func (c *OpenAIClient) ChatCompletion(ctx context.Context, req ChatCompletionReq
 		Tools:               req.Tools,
 		ToolChoice:          req.ToolChoice,
 		ResponseFormat:      req.ResponseFormat,
+		LogProbs:            req.LogProbs,
 	}
 
 	resp, err := c.client.CreateChatCompletion(ctx, openaiReq)
@@ -76,7 +76,7 @@ func GetRewriteClient(ctx context.Context) (*client.OpenAIClient, error) {
 		selectedModel.Name, selectedModel.ModelID, selectedModel.Provider)
 
 	// 使用选中的模型创建 OpenAI 客户端
-	return client.NewOpenAIClient(selectedModel.APIKey, selectedModel.BaseURL), nil
+	return client.WrapOpenAIClient(selectedModel.Client), nil
 }
 
 // GetRerankClient 获取 rerank 客户端
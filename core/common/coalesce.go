@@ -0,0 +1,97 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 合并的增量类型
+const (
+	deltaContent   = "data"
+	deltaReasoning = "reasoning"
+)
+
+// CoalesceConfig 流式增量合并配置，两项都为 0 时不合并，每个模型增量单独发送一个事件
+type CoalesceConfig struct {
+	Interval time.Duration // 距上次发送达到该时间时发送缓冲的内容
+	MaxChars int           // 缓冲的字符数达到该值时立即发送
+}
+
+// LoadCoalesceConfig 从 sse.coalesce 配置读取增量合并配置
+func LoadCoalesceConfig(ctx context.Context) CoalesceConfig {
+	return CoalesceConfig{
+		Interval: time.Duration(g.Cfg().MustGet(ctx, "sse.coalesce.intervalMs", 0).Int()) * time.Millisecond,
+		MaxChars: g.Cfg().MustGet(ctx, "sse.coalesce.maxChars", 0).Int(),
+	}
+}
+
+// Enabled 是否合并增量
+func (c CoalesceConfig) Enabled() bool {
+	return c.Interval > 0 || c.MaxChars > 0
+}
+
+// streamDelta 一次要发送的增量
+type streamDelta struct {
+	kind string
+	text string
+}
+
+// deltaCoalescer 把模型的细碎增量合并为较少的 SSE 事件：每种增量的第一段立即发送以保证首字延迟，
+// 之后缓冲到字符数达到 MaxChars 或距上次发送达到 Interval 时再发送；增量类型变化时先发送缓冲内容，保证顺序
+type deltaCoalescer struct {
+	cfg       CoalesceConfig
+	kind      string
+	buf       strings.Builder
+	chars     int
+	lastFlush time.Time
+	started   map[string]bool
+}
+
+func newDeltaCoalescer(cfg CoalesceConfig) *deltaCoalescer {
+	return &deltaCoalescer{cfg: cfg, started: map[string]bool{}}
+}
+
+// add 加入一段增量，返回需要立即发送的增量
+func (c *deltaCoalescer) add(kind, text string, now time.Time) []streamDelta {
+	if !c.cfg.Enabled() {
+		return []streamDelta{{kind: kind, text: text}}
+	}
+	var deltas []streamDelta
+	if c.kind != kind {
+		deltas = c.appendFlush(deltas, now)
+		c.kind = kind
+	}
+	c.buf.WriteString(text)
+	c.chars += len([]rune(text))
+	if !c.started[kind] ||
+		(c.cfg.MaxChars > 0 && c.chars >= c.cfg.MaxChars) ||
+		(c.cfg.Interval > 0 && now.Sub(c.lastFlush) >= c.cfg.Interval) {
+		c.started[kind] = true
+		deltas = c.appendFlush(deltas, now)
+	}
+	return deltas
+}
+
+// due 缓冲中是否有距上次发送已达到 Interval 的内容
+func (c *deltaCoalescer) due(now time.Time) bool {
+	return c.chars > 0 && c.cfg.Interval > 0 && now.Sub(c.lastFlush) >= c.cfg.Interval
+}
+
+// flush 取出缓冲的全部内容，没有内容时返回 nil
+func (c *deltaCoalescer) flush(now time.Time) []streamDelta {
+	return c.appendFlush(nil, now)
+}
+
+func (c *deltaCoalescer) appendFlush(deltas []streamDelta, now time.Time) []streamDelta {
+	if c.chars == 0 {
+		return deltas
+	}
+	deltas = append(deltas, streamDelta{kind: c.kind, text: c.buf.String()})
+	c.buf.Reset()
+	c.chars = 0
+	c.lastFlush = now
+	return deltas
+}
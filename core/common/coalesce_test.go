@@ -0,0 +1,66 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeltaCoalescer(t *testing.T) {
+	start := time.Unix(0, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	t.Run("未启用时逐个发送", func(t *testing.T) {
+		c := newDeltaCoalescer(CoalesceConfig{})
+		for _, text := range []string{"你", "好"} {
+			if got := c.add(deltaContent, text, start); len(got) != 1 || got[0].text != text {
+				t.Errorf("add(%q) = %+v", text, got)
+			}
+		}
+	})
+
+	t.Run("首段立即发送，之后按字符数和时间合并", func(t *testing.T) {
+		c := newDeltaCoalescer(CoalesceConfig{Interval: 100 * time.Millisecond, MaxChars: 5})
+		steps := []struct {
+			text string
+			ms   int
+			want []streamDelta
+		}{
+			{"保修", 0, []streamDelta{{deltaContent, "保修"}}},
+			{"期", 10, nil},
+			{"是", 20, nil},
+			{"三年，", 30, []streamDelta{{deltaContent, "期是三年，"}}},
+			{"自", 40, nil},
+			{"购", 150, []streamDelta{{deltaContent, "自购"}}},
+		}
+		for _, step := range steps {
+			if got := c.add(deltaContent, step.text, at(step.ms)); !reflect.DeepEqual(got, step.want) {
+				t.Errorf("add(%q) at %dms = %+v, want %+v", step.text, step.ms, got, step.want)
+			}
+		}
+		c.add(deltaContent, "买", at(160))
+		if c.due(at(200)) {
+			t.Error("due() before interval = true")
+		}
+		if !c.due(at(250)) {
+			t.Error("due() after interval = false")
+		}
+		if got := c.flush(at(250)); !reflect.DeepEqual(got, []streamDelta{{deltaContent, "买"}}) {
+			t.Errorf("flush() = %+v", got)
+		}
+		if got := c.flush(at(260)); got != nil {
+			t.Errorf("flush() on empty buffer = %+v, want nil", got)
+		}
+	})
+
+	t.Run("类型变化时先发送缓冲内容", func(t *testing.T) {
+		c := newDeltaCoalescer(CoalesceConfig{MaxChars: 100})
+		c.add(deltaReasoning, "先想", start)
+		c.add(deltaReasoning, "一想", start)
+		got := c.add(deltaContent, "答案", start)
+		want := []streamDelta{{deltaReasoning, "一想"}, {deltaContent, "答案"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("add() = %+v, want %+v", got, want)
+		}
+	})
+}
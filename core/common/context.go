@@ -0,0 +1,23 @@
+package common
+
+import (
+	"context"
+)
+
+// DetachContext 返回不随原上下文取消或超时的上下文，保留其中的值（用户、trace、语言、项目、实验分组等），
+// 用于请求返回或客户端断开后仍需继续执行的后台任务（保存消息、记录统计、发送 webhook、异步索引等），
+// 不应使用 context.Background() 替代，否则后台任务的日志会丢失 trace ID 等请求信息
+func DetachContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// SafeGoDetached 在与请求分离的上下文中安全启动 goroutine，fn 收到的上下文保留请求中的值但不随请求取消
+func SafeGoDetached(ctx context.Context, taskName string, fn func(ctx context.Context)) {
+	detached := DetachContext(ctx)
+	SafeGo(detached, taskName, func() {
+		fn(detached)
+	})
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDetachContext(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "trace-1"), time.Minute)
+	detached := DetachContext(parent)
+	cancel()
+
+	if parent.Err() == nil {
+		t.Fatal("parent context should be cancelled")
+	}
+	if err := detached.Err(); err != nil {
+		t.Errorf("detached context Err() = %v, want nil", err)
+	}
+	if _, ok := detached.Deadline(); ok {
+		t.Error("detached context should have no deadline")
+	}
+	if got := detached.Value(key{}); got != "trace-1" {
+		t.Errorf("detached context Value() = %v, want trace-1", got)
+	}
+	if DetachContext(nil) == nil {
+		t.Error("DetachContext(nil) should return a usable context")
+	}
+}
+
+func TestSafeGoDetached(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "user-1"))
+	cancel()
+
+	done := make(chan context.Context, 1)
+	SafeGoDetached(parent, "test-detached", func(ctx context.Context) {
+		done <- ctx
+	})
+	select {
+	case ctx := <-done:
+		if ctx.Err() != nil || ctx.Value(key{}) != "user-1" {
+			t.Errorf("detached task context: err=%v, value=%v", ctx.Err(), ctx.Value(key{}))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("detached task did not run")
+	}
+}
@@ -107,9 +107,12 @@ func (e *CustomEmbedder) EmbedStrings(ctx context.Context, texts []string, dimen
 	}
 
 	req := EmbeddingRequest{
-		Input:      texts,
-		Model:      e.model,
-		Dimensions: &dimensions,
+		Input: texts,
+		Model: e.model,
+	}
+	// dimensions <= 0 时不传该参数，由模型返回原生维度
+	if dimensions > 0 {
+		req.Dimensions = &dimensions
 	}
 
 	// 序列化请求
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// dimensionProbeText 用于探测向量维度的测试文本
+const dimensionProbeText = "dimension probe"
+
+// dimensionCache 已探测的模型维度缓存，key 为 baseURL + 模型名
+var dimensionCache sync.Map
+
+func dimensionCacheKey(baseURL, model string) string {
+	return baseURL + "|" + model
+}
+
+// DetectDimension 通过向量化一条测试文本探测模型实际输出的维度，结果按模型缓存
+func (e *CustomEmbedder) DetectDimension(ctx context.Context) (int, error) {
+	key := dimensionCacheKey(e.baseURL, e.model)
+	if dim, ok := dimensionCache.Load(key); ok {
+		return dim.(int), nil
+	}
+
+	vectors, err := e.EmbedStrings(ctx, []string{dimensionProbeText}, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe embedding dimension of model %s: %w", e.model, err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return 0, fmt.Errorf("failed to probe embedding dimension of model %s: empty vector", e.model)
+	}
+
+	dim := len(vectors[0])
+	dimensionCache.Store(key, dim)
+	g.Log().Infof(ctx, "Detected embedding dimension %d for model %s", dim, e.model)
+	return dim, nil
+}
+
+// ConfiguredDimension 模型配置 extra 中指定的向量维度（支持降维输出的模型），未指定时返回 0
+func ConfiguredDimension(mc *model.ModelConfig) int {
+	if mc == nil {
+		return 0
+	}
+	switch dim := mc.Extra["dimension"].(type) {
+	case float64:
+		return int(dim)
+	case int:
+		return dim
+	case string:
+		n, _ := strconv.Atoi(dim)
+		return n
+	}
+	return 0
+}
+
+// registeredModel 按 baseURL 和模型名称在模型注册表中查找向量模型配置
+func (e *CustomEmbedder) registeredModel() *model.ModelConfig {
+	for _, mc := range model.Registry.GetByType(model.ModelTypeEmbedding) {
+		if mc.Name == e.model && mc.BaseURL == e.baseURL {
+			return mc
+		}
+	}
+	return nil
+}
+
+// ResolveDimension 获取模型的向量维度，索引和检索共用，保证查询向量与集合的维度一致：
+// 模型配置 extra.dimension 优先，其次探测模型实际输出的维度，探测失败时使用 fallback（通常为配置文件中的 dim）
+func (e *CustomEmbedder) ResolveDimension(ctx context.Context, fallback int) int {
+	if dim := ConfiguredDimension(e.registeredModel()); dim > 0 {
+		return dim
+	}
+	dim, err := e.DetectDimension(ctx)
+	if err != nil {
+		g.Log().Warningf(ctx, "%v, using configured dimension %d", err, fallback)
+		return fallback
+	}
+	if fallback > 0 && fallback != dim {
+		g.Log().Warningf(ctx, "Configured dimension %d does not match model %s output %d, using detected dimension",
+			fallback, e.model, dim)
+	}
+	return dim
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/model"
+)
+
+type mockEmbeddingConfig struct {
+	baseURL string
+	model   string
+}
+
+func (m *mockEmbeddingConfig) GetAPIKey() string         { return "test-key" }
+func (m *mockEmbeddingConfig) GetBaseURL() string        { return m.baseURL }
+func (m *mockEmbeddingConfig) GetEmbeddingModel() string { return m.model }
+
+// TestDetectDimension 测试维度探测及缓存
+func TestDetectDimension(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req EmbeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Dimensions != nil {
+			t.Errorf("probe request should not send dimensions, got %d", *req.Dimensions)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{{"embedding": make([]float64, 768), "index": 0}},
+		})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	embedder, err := NewEmbedding(ctx, &mockEmbeddingConfig{baseURL: server.URL, model: "probe-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		dim, err := embedder.DetectDimension(ctx)
+		if err != nil {
+			t.Fatalf("DetectDimension() error = %v", err)
+		}
+		if dim != 768 {
+			t.Errorf("DetectDimension() = %d, want 768", dim)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 probe request, got %d", calls)
+	}
+	if got := embedder.ResolveDimension(ctx, 1024); got != 768 {
+		t.Errorf("ResolveDimension() = %d, want detected 768", got)
+	}
+}
+
+// TestResolveDimensionFallback 测试探测失败时使用配置维度
+func TestResolveDimensionFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"message":"unavailable"}}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	embedder, err := NewEmbedding(ctx, &mockEmbeddingConfig{baseURL: server.URL, model: "probe-fail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := embedder.ResolveDimension(ctx, 1024); got != 1024 {
+		t.Errorf("ResolveDimension() = %d, want fallback 1024", got)
+	}
+}
+
+// TestResolveDimensionConfigured 测试模型配置 extra.dimension 优先于探测到的原生维度，索引和检索使用相同维度
+func TestResolveDimensionConfigured(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{{"embedding": make([]float64, 1536), "index": 0}},
+		})
+	}))
+	defer server.Close()
+
+	mc := &model.ModelConfig{ModelID: "dim-test", Name: "reduced", Type: model.ModelTypeEmbedding, BaseURL: server.URL, Extra: map[string]any{"dimension": float64(256)}}
+	model.Registry.Register(mc)
+	defer model.Registry.Unregister(mc.ModelID)
+
+	ctx := context.Background()
+	embedder, err := NewEmbedding(ctx, &mockEmbeddingConfig{baseURL: server.URL, model: "reduced"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := embedder.ResolveDimension(ctx, 1024); got != 256 {
+		t.Errorf("ResolveDimension() = %d, want configured 256", got)
+	}
+	if calls != 0 {
+		t.Errorf("expected no probe request, got %d", calls)
+	}
+	if got := ConfiguredDimension(&model.ModelConfig{Extra: map[string]any{"dimension": "512"}}); got != 512 {
+		t.Errorf("ConfiguredDimension() = %d, want 512", got)
+	}
+}
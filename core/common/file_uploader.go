@@ -1,6 +1,7 @@
 package common
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/Malowking/kbgo/core/media"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gfile"
 	"github.com/google/uuid"
@@ -123,7 +125,7 @@ func (fu *FileUploader) GetFileType(filename string) FileType {
 	ext := strings.ToLower(filepath.Ext(filename))
 
 	// 图片类型
-	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".svg", ".ico", ".tiff"}
+	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".svg", ".ico", ".tiff", ".heic", ".heif", ".avif"}
 	for _, imgExt := range imageExts {
 		if ext == imgExt {
 			return FileTypeImage
@@ -194,6 +196,19 @@ func (fu *FileUploader) saveFile(file *multipart.FileHeader) (*MultimodalFile, e
 	}
 	defer src.Close()
 
+	// 读取文件头校验内容与扩展名一致，拒绝伪装成图片/音视频的其他文件
+	head := make([]byte, media.SniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	head = head[:n]
+	if fileType != FileTypeOther {
+		if _, err = media.Check(ext, head); err != nil {
+			return nil, fmt.Errorf("file %s rejected: %w", file.Filename, err)
+		}
+	}
+
 	// 创建目标文件
 	dst, err := os.Create(targetPath)
 	if err != nil {
@@ -202,7 +217,7 @@ func (fu *FileUploader) saveFile(file *multipart.FileHeader) (*MultimodalFile, e
 	defer dst.Close()
 
 	// 复制文件内容
-	size, err := io.Copy(dst, src)
+	size, err := io.Copy(dst, io.MultiReader(bytes.NewReader(head), src))
 	if err != nil {
 		return nil, fmt.Errorf("failed to copy file content: %w", err)
 	}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 分片内容类型，记录在分片元数据 content_type 中，普通文本分片不记录
+const (
+	ContentTypeKey   = "content_type"
+	ContentTypeText  = "text"
+	ContentTypeCode  = "code"  // 包含围栏代码块
+	ContentTypeMath  = "math"  // 包含公式（$$ 公式块或行内 $...$）
+	ContentTypeMixed = "mixed" // 同时包含代码块和公式
+)
+
+var (
+	inlineCodePattern = regexp.MustCompile("`[^`\n]+`")
+	// inlineMathPattern 行内公式：$ 之后和结束 $ 之前不能是空白，结束 $ 后紧跟数字的视为金额（如 $5 和 $10）
+	inlineMathPattern = regexp.MustCompile(`\$[^\s$](?:[^$\n]*[^\s$\\])?\$`)
+)
+
+// BlockTracker 逐行跟踪 Markdown 围栏代码块（``` 或 ~~~）和 $$ 公式块，用于切分和清理文本时不破坏块内容
+type BlockTracker struct {
+	fence string // 当前块的结束标记，为空表示不在块内
+}
+
+// Line 处理一行文本，返回该行是否属于代码块或公式块（包括起止标记行）
+func (t *BlockTracker) Line(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if t.fence != "" {
+		if t.closes(trimmed) {
+			t.fence = ""
+		}
+		return true
+	}
+	if fence := openingFence(line); fence != "" {
+		t.fence = fence
+		return true
+	}
+	if strings.HasPrefix(trimmed, "$$") {
+		// $$...$$ 写在同一行的公式块
+		if len(trimmed) >= 4 && strings.HasSuffix(trimmed, "$$") {
+			return true
+		}
+		t.fence = "$$"
+		return true
+	}
+	return false
+}
+
+// Inside 当前是否处于未闭合的块内
+func (t *BlockTracker) Inside() bool {
+	return t.fence != ""
+}
+
+// Math 当前块是否为公式块
+func (t *BlockTracker) Math() bool {
+	return t.fence == "$$"
+}
+
+// Fence 当前代码块的起始标记（如 ```），不在代码块内时为空
+func (t *BlockTracker) Fence() string {
+	if t.Math() {
+		return ""
+	}
+	return t.fence
+}
+
+// closes 判断一行是否结束当前块：代码块的结束标记由同一字符组成且不短于起始标记，公式块以 $$ 结尾
+func (t *BlockTracker) closes(trimmed string) bool {
+	if t.fence == "$$" {
+		return strings.HasSuffix(trimmed, "$$")
+	}
+	return len(trimmed) >= len(t.fence) && strings.Trim(trimmed, t.fence[:1]) == ""
+}
+
+// openingFence 代码块起始行（最多缩进 3 个空格）返回起始标记，否则返回空
+func openingFence(line string) string {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return ""
+	}
+	line = line[indent:]
+	if len(line) < 3 || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := len(line) - len(strings.TrimLeft(line, line[:1]))
+	if n < 3 {
+		return ""
+	}
+	// 反引号代码块的语言标识中不能再出现反引号
+	if line[0] == '`' && strings.Contains(line[n:], "`") {
+		return ""
+	}
+	return line[:n]
+}
+
+// InlineSpans 返回文本中行内代码和行内公式的字节区间 [start, end)，切分文本时不应从区间内部断开
+func InlineSpans(text string) [][]int {
+	spans := inlineCodePattern.FindAllStringIndex(text, -1)
+	for _, span := range inlineMathPattern.FindAllStringIndex(text, -1) {
+		if span[1] < len(text) && text[span[1]] >= '0' && text[span[1]] <= '9' {
+			continue
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// DetectContentType 判断文本包含的格式化内容：围栏代码块、公式块或行内公式，都没有时返回 ContentTypeText
+func DetectContentType(text string) string {
+	var tracker BlockTracker
+	var code, math bool
+	for _, line := range strings.Split(text, "\n") {
+		wasInside := tracker.Inside()
+		if !tracker.Line(line) {
+			continue
+		}
+		// 块的起始行决定块的类型，单行 $$...$$ 公式块不进入块内
+		if !wasInside {
+			if tracker.Fence() != "" {
+				code = true
+			} else {
+				math = true
+			}
+		}
+	}
+	if !math {
+		for _, span := range InlineSpans(text) {
+			if text[span[0]] == '$' {
+				math = true
+				break
+			}
+		}
+	}
+	switch {
+	case code && math:
+		return ContentTypeMixed
+	case code:
+		return ContentTypeCode
+	case math:
+		return ContentTypeMath
+	}
+	return ContentTypeText
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestBlockTracker(t *testing.T) {
+	lines := []string{"text", "```go", "# not a heading", "~~~", "```", "after", "$$", "x^2", "$$", "$$ y $$", "end"}
+	want := []bool{false, true, true, true, true, false, true, true, true, true, false}
+	var tracker BlockTracker
+	for i, line := range lines {
+		if got := tracker.Line(line); got != want[i] {
+			t.Errorf("Line(%q) = %v, want %v", line, got, want[i])
+		}
+	}
+	if tracker.Inside() {
+		t.Error("tracker should not be inside a block at the end")
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "普通文本", text: "普通文本，价格 $5 到 $10", expected: ContentTypeText},
+		{name: "行内代码", text: "调用 `fmt.Println` 输出", expected: ContentTypeText},
+		{name: "代码块", text: "示例：\n```go\nfmt.Println(1)\n```", expected: ContentTypeCode},
+		{name: "波浪线代码块", text: "~~~\ncode\n~~~", expected: ContentTypeCode},
+		{name: "公式块", text: "$$\nE = mc^2\n$$", expected: ContentTypeMath},
+		{name: "行内公式", text: "其中 $a^2 + b^2 = c^2$ 成立", expected: ContentTypeMath},
+		{name: "代码和公式", text: "$x$\n```\ncode\n```", expected: ContentTypeMixed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContentType(tt.text); got != tt.expected {
+				t.Errorf("DetectContentType() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package common
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/Malowking/kbgo/core/media"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 )
@@ -96,21 +98,17 @@ func (b *MultimodalMessageBuilder) buildInputPart(file *MultimodalFile, useBase6
 
 // buildChatMessagePart 构建ChatMessagePart（用于MultiContent字段）
 func (b *MultimodalMessageBuilder) buildChatMessagePart(file *MultimodalFile, useBase64 bool) (schema.ChatMessagePart, error) {
-	ext := filepath.Ext(file.FileName)
-	mimeType := getMimeType(ext)
-
 	switch file.FileType {
 	case FileTypeImage:
 		if useBase64 {
-			data, err := os.ReadFile(file.FilePath)
+			dataURI, err := media.ImageDataURI(context.Background(), file.FilePath)
 			if err != nil {
-				return schema.ChatMessagePart{}, fmt.Errorf("failed to read image file: %w", err)
+				return schema.ChatMessagePart{}, err
 			}
-			base64Data := base64.StdEncoding.EncodeToString(data)
 			return schema.ChatMessagePart{
 				Type: schema.ChatMessagePartTypeImageURL,
 				ImageURL: &schema.ChatMessageImageURL{
-					URL:    fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data),
+					URL:    dataURI,
 					Detail: schema.ImageURLDetailAuto,
 				},
 			}, nil
@@ -133,13 +131,13 @@ func (b *MultimodalMessageBuilder) buildChatMessagePart(file *MultimodalFile, us
 // buildImageInputPart 构建图片输入部分
 func (b *MultimodalMessageBuilder) buildImageInputPart(file *MultimodalFile, useBase64 bool) (schema.MessageInputPart, error) {
 	ext := filepath.Ext(file.FileName)
-	mimeType := getMimeType(ext)
+	mimeType := media.ExtensionType(ext)
 
 	if useBase64 {
-		// 读取文件并转换为base64
-		data, err := os.ReadFile(file.FilePath)
+		// 读取文件并转换为base64，按内容识别类型，HEIC/AVIF 转换为 JPEG，超大图片等比缩小
+		data, mimeType, err := media.LoadImage(context.Background(), file.FilePath)
 		if err != nil {
-			return schema.MessageInputPart{}, fmt.Errorf("failed to read image file: %w", err)
+			return schema.MessageInputPart{}, err
 		}
 
 		base64Data := base64.StdEncoding.EncodeToString(data)
@@ -174,13 +172,16 @@ func (b *MultimodalMessageBuilder) buildImageInputPart(file *MultimodalFile, use
 // buildAudioInputPart 构建音频输入部分
 func (b *MultimodalMessageBuilder) buildAudioInputPart(file *MultimodalFile, useBase64 bool) (schema.MessageInputPart, error) {
 	ext := filepath.Ext(file.FileName)
-	mimeType := getMimeType(ext)
+	mimeType := media.ExtensionType(ext)
 
 	if useBase64 {
 		data, err := os.ReadFile(file.FilePath)
 		if err != nil {
 			return schema.MessageInputPart{}, fmt.Errorf("failed to read audio file: %w", err)
 		}
+		if mimeType, err = media.Check(filepath.Ext(file.FilePath), data); err != nil {
+			return schema.MessageInputPart{}, err
+		}
 
 		base64Data := base64.StdEncoding.EncodeToString(data)
 
@@ -211,7 +212,7 @@ func (b *MultimodalMessageBuilder) buildAudioInputPart(file *MultimodalFile, use
 // buildVideoInputPart 构建视频输入部分
 func (b *MultimodalMessageBuilder) buildVideoInputPart(file *MultimodalFile, useBase64 bool) (schema.MessageInputPart, error) {
 	ext := filepath.Ext(file.FileName)
-	mimeType := getMimeType(ext)
+	mimeType := media.ExtensionType(ext)
 
 	// 视频文件通常较大，只支持URL方式
 	return schema.MessageInputPart{
@@ -224,45 +225,3 @@ func (b *MultimodalMessageBuilder) buildVideoInputPart(file *MultimodalFile, use
 		},
 	}, nil
 }
-
-// getMimeType 根据文件扩展名获取MIME类型
-func getMimeType(ext string) string {
-	mimeTypes := map[string]string{
-		// 图片
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".png":  "image/png",
-		".gif":  "image/gif",
-		".bmp":  "image/bmp",
-		".webp": "image/webp",
-		".svg":  "image/svg+xml",
-		".ico":  "image/x-icon",
-		".tiff": "image/tiff",
-
-		// 音频
-		".mp3":  "audio/mpeg",
-		".wav":  "audio/wav",
-		".flac": "audio/flac",
-		".aac":  "audio/aac",
-		".ogg":  "audio/ogg",
-		".m4a":  "audio/mp4",
-		".wma":  "audio/x-ms-wma",
-
-		// 视频
-		".mp4":  "video/mp4",
-		".avi":  "video/x-msvideo",
-		".mkv":  "video/x-matroska",
-		".mov":  "video/quicktime",
-		".wmv":  "video/x-ms-wmv",
-		".flv":  "video/x-flv",
-		".webm": "video/webm",
-		".m4v":  "video/mp4",
-		".mpeg": "video/mpeg",
-		".mpg":  "video/mpeg",
-	}
-
-	if mime, ok := mimeTypes[ext]; ok {
-		return mime
-	}
-	return "application/octet-stream"
-}
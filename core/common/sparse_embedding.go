@@ -0,0 +1,157 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SparseVector 稀疏向量（词项ID -> 权重），由 SPLADE、BM42 等稀疏模型产生
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// Len 返回非零项数量
+func (v SparseVector) Len() int {
+	return len(v.Indices)
+}
+
+// SparseEmbedder 稀疏向量化接口
+type SparseEmbedder interface {
+	EmbedSparse(ctx context.Context, texts []string) ([]SparseVector, error)
+}
+
+// SparseEmbedderFactory 根据模型配置创建稀疏向量化实例
+type SparseEmbedderFactory func(ctx context.Context, conf EmbeddingConfig) (SparseEmbedder, error)
+
+// DefaultSparseProvider 未指定提供方时使用的稀疏向量协议（HuggingFace TEI 的 /embed_sparse）
+const DefaultSparseProvider = "tei"
+
+var (
+	sparseFactoriesMu sync.RWMutex
+	sparseFactories   = map[string]SparseEmbedderFactory{
+		DefaultSparseProvider: func(ctx context.Context, conf EmbeddingConfig) (SparseEmbedder, error) {
+			return NewTEISparseEmbedder(conf)
+		},
+	}
+)
+
+// RegisterSparseEmbedder 注册稀疏向量化实现，provider 对应模型的 provider 字段
+func RegisterSparseEmbedder(provider string, factory SparseEmbedderFactory) {
+	sparseFactoriesMu.Lock()
+	defer sparseFactoriesMu.Unlock()
+	sparseFactories[strings.ToLower(provider)] = factory
+}
+
+// NewSparseEmbedding 按 provider 创建稀疏向量化实例，未注册的 provider 使用 TEI 协议
+func NewSparseEmbedding(ctx context.Context, provider string, conf EmbeddingConfig) (SparseEmbedder, error) {
+	sparseFactoriesMu.RLock()
+	factory, ok := sparseFactories[strings.ToLower(provider)]
+	if !ok {
+		factory = sparseFactories[DefaultSparseProvider]
+	}
+	sparseFactoriesMu.RUnlock()
+	return factory(ctx, conf)
+}
+
+// TEISparseEmbedder 兼容 text-embeddings-inference /embed_sparse 接口的稀疏向量化客户端
+type TEISparseEmbedder struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// teiSparseRequest /embed_sparse 请求结构
+type teiSparseRequest struct {
+	Inputs []string `json:"inputs"`
+	Model  string   `json:"model,omitempty"`
+}
+
+// teiSparseValue /embed_sparse 响应中的单个词项
+type teiSparseValue struct {
+	Index uint32  `json:"index"`
+	Value float32 `json:"value"`
+}
+
+// NewTEISparseEmbedder 创建 TEI 稀疏向量化客户端
+func NewTEISparseEmbedder(conf EmbeddingConfig) (*TEISparseEmbedder, error) {
+	baseURL := strings.TrimRight(conf.GetBaseURL(), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("sparse embedding base url is empty")
+	}
+	return &TEISparseEmbedder{
+		apiKey:     conf.GetAPIKey(),
+		baseURL:    baseURL,
+		model:      conf.GetEmbeddingModel(),
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}, nil
+}
+
+// EmbedSparse 批量生成稀疏向量，结果按词项ID升序排列
+func (e *TEISparseEmbedder) EmbedSparse(ctx context.Context, texts []string) ([]SparseVector, error) {
+	if len(texts) == 0 {
+		return []SparseVector{}, nil
+	}
+
+	jsonData, err := json.Marshal(teiSparseRequest{Inputs: texts, Model: e.model})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embed_sparse", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("sparse embedding API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var embResp [][]teiSparseValue
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(embResp) != len(texts) {
+		return nil, fmt.Errorf("response data length (%d) doesn't match input length (%d)", len(embResp), len(texts))
+	}
+
+	result := make([]SparseVector, len(embResp))
+	for i, items := range embResp {
+		sort.Slice(items, func(a, b int) bool {
+			return items[a].Index < items[b].Index
+		})
+		vec := SparseVector{
+			Indices: make([]uint32, 0, len(items)),
+			Values:  make([]float32, 0, len(items)),
+		}
+		for _, item := range items {
+			if item.Value == 0 {
+				continue
+			}
+			vec.Indices = append(vec.Indices, item.Index)
+			vec.Values = append(vec.Values, item.Value)
+		}
+		result[i] = vec
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+const (
+	// defaultHeartbeatInterval SSE 连接允许的最长空闲时间，超过后发送心跳
+	defaultHeartbeatInterval = 15 * time.Second
+	// defaultRetryMs EventSource 客户端断线后重连前等待的毫秒数
+	defaultRetryMs = 3000
+)
+
+// sseStream 正在输出的 SSE 响应：事件和心跳写入互斥，并记录最近一次写入时间用于判断空闲
+type sseStream struct {
+	mu        sync.Mutex
+	lastWrite time.Time
+}
+
+// sseStreams 已开始输出的 SSE 响应（*ghttp.Response -> *sseStream）
+var sseStreams sync.Map
+
+// StartSSE 开始 SSE 响应：设置响应头，发送 retry 字段（sse.retryMs，EventSource 断线重连等待时间），
+// 并在连接空闲达到 sse.heartbeatInterval 时发送注释行 ": ping" 作为心跳，避免长时间工具调用期间代理断开连接。
+// 同一响应重复调用只有第一次生效，返回的函数用于结束心跳，应在响应结束时调用；
+// 该函数等待心跳协程退出后才返回，返回后不会再有心跳写入响应
+func StartSSE(ctx context.Context, resp *ghttp.Response) (stop func()) {
+	stream := &sseStream{lastWrite: time.Now()}
+	if _, loaded := sseStreams.LoadOrStore(resp, stream); loaded {
+		return func() {}
+	}
+	SetSSEHeaders(resp)
+	// OpenAI SDK 把 retry 字段也当作事件解析，设置了格式的响应不发送
+	if retryMs := g.Cfg().MustGet(ctx, "sse.retryMs", defaultRetryMs).Int(); retryMs > 0 && streamFormatOf(resp) == nil {
+		writeSSE(resp, fmt.Sprintf("retry: %d\n", retryMs))
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if interval := g.Cfg().MustGet(ctx, "sse.heartbeatInterval", defaultHeartbeatInterval).Duration(); interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream.heartbeat(ctx, resp, interval, done)
+		}()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			// 等待进行中的心跳写入完成后再移除，之后的写入不再与心跳并发
+			wg.Wait()
+			sseStreams.Delete(resp)
+		})
+	}
+}
+
+// heartbeat 每半个心跳间隔检查一次，空闲达到半个间隔即发送心跳，保证任意两次写入的间隔不超过 interval
+func (s *sseStream) heartbeat(ctx context.Context, resp *ghttp.Response, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			// 停止与计时同时发生时 select 可能选中计时，已停止则不再写入
+			if stopped(done) {
+				s.mu.Unlock()
+				return
+			}
+			if now.Sub(s.lastWrite) >= interval/2 {
+				resp.Writeln(": ping\n")
+				resp.Flush()
+				s.lastWrite = now
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// stopped 判断心跳是否已停止
+func stopped(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeSSE 写入一段 SSE 内容并立即发送，与心跳写入互斥
+func writeSSE(resp *ghttp.Response, text string) {
+	if v, ok := sseStreams.Load(resp); ok {
+		stream := v.(*sseStream)
+		stream.mu.Lock()
+		defer stream.mu.Unlock()
+		stream.lastWrite = time.Now()
+	}
+	resp.Writeln(text)
+	resp.Flush()
+}
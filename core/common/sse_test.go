@@ -0,0 +1,57 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// TestStartSSEStopWaitsForHeartbeat 测试在心跳即将发送时结束 SSE：结束函数返回后不再有心跳写入，
+// 之后的事件写入不与心跳并发（需配合 -race 运行）
+func TestStartSSEStopWaitsForHeartbeat(t *testing.T) {
+	const interval = 2 * time.Millisecond
+	adapter, err := gcfg.NewAdapterContent(fmt.Sprintf("sse:\n  retryMs: 0\n  heartbeatInterval: %s\n", interval))
+	if err != nil {
+		t.Fatalf("NewAdapterContent() error = %v", err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	s := g.Server(fmt.Sprintf("sse-stop-test-%d", time.Now().UnixNano()))
+	s.SetAddr("127.0.0.1:0")
+	s.SetDumpRouterMap(false)
+	s.BindHandler("/sse", func(r *ghttp.Request) {
+		for i := 0; i < 50; i++ {
+			stop := StartSSE(r.Context(), r.Response)
+			// 等到心跳计时到期附近再结束
+			time.Sleep(interval / 2)
+			stop()
+			writeSSE(r.Response, "data: after-stop\n")
+		}
+		writeSSE(r.Response, "data: end\n")
+		// 心跳已结束，等待几个间隔确认没有迟到的心跳
+		time.Sleep(3 * interval)
+	})
+	if err = s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/sse", s.GetListenedPort()))
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasSuffix(string(body), "data: end\n\n") {
+		t.Errorf("body should end with the last event, got tail %q", string(body[max(0, len(body)-40):]))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/Malowking/kbgo/pkg/schema"
@@ -14,22 +15,46 @@ import (
 )
 
 type StreamData struct {
-	Id       string             `json:"id"`      // 同一个消息里面的id是相同的
-	Created  int64              `json:"created"` // 消息初始生成时间
-	Content  string             `json:"content"` // 消息具体内容
-	Document []*schema.Document `json:"document"`
+	Id           string             `json:"id"`      // 同一个消息里面的id是相同的
+	Created      int64              `json:"created"` // 消息初始生成时间
+	Content      string             `json:"content"` // 消息具体内容
+	Document     []*schema.Document `json:"document"`
+	Reasoning    string             `json:"reasoning_content,omitempty"` // 推理内容，仅在 reasoning 事件中返回
+	FollowUp     []string           `json:"follow_up,omitempty"`         // 推荐追问，仅在结束前的 follow_up 事件中返回
+	Confidence   any                `json:"confidence,omitempty"`        // 回答置信度，仅在结束前的 confidence 事件中返回
+	Citations    any                `json:"citations,omitempty"`         // 回答引用的分片，仅在结束前的 citations 事件中返回
+	Budget       any                `json:"latency_budget,omitempty"`    // 延迟预算使用情况，仅在结束前的 latency_budget 事件中返回
+	CorrectionID string             `json:"correction_id,omitempty"`     // 待审核的更正申请ID，仅在结束前的 correction 事件中返回
 }
 
-func SteamResponse(ctx context.Context, streamReader *schema.StreamReader[*schema.Message], docs []*schema.Document) (err error) {
+// FollowUpFunc 根据完整回答生成推荐追问，在发送结束事件前调用
+type FollowUpFunc func(answer string) []string
+
+// ConfidenceFunc 根据完整回答计算置信度，返回 nil 时不发送 confidence 事件
+type ConfidenceFunc func(answer string) any
+
+// CitationsFunc 根据完整回答解析引用的分片，返回 nil 时不发送 citations 事件
+type CitationsFunc func(answer string) any
+
+// LatencyBudgetFunc 返回延迟预算使用情况，在发送结束事件前调用
+type LatencyBudgetFunc func() any
+
+// StreamHooks 流式输出结束、发送结束事件前执行的回调，未设置的回调会被跳过
+type StreamHooks struct {
+	FollowUp   FollowUpFunc
+	Confidence ConfidenceFunc
+	Citations  CitationsFunc
+	// LatencyBudget 在推荐追问之后调用，用时包含生成追问
+	LatencyBudget LatencyBudgetFunc
+	// CorrectionID 本轮问题被识别为更正时的更正申请ID，不为空时在结束前以 correction 事件发送
+	CorrectionID string
+}
+
+func SteamResponse(ctx context.Context, streamReader *schema.StreamReader[*schema.Message], docs []*schema.Document, hooks StreamHooks) (err error) {
 	// 获取HTTP响应对象
 	httpReq := ghttp.RequestFromCtx(ctx)
 	httpResp := httpReq.Response
-	// 设置响应头
-	httpResp.Header().Set("Content-Type", "text/event-stream")
-	httpResp.Header().Set("Cache-Control", "no-cache")
-	httpResp.Header().Set("Connection", "keep-alive")
-	httpResp.Header().Set("X-Accel-Buffering", "no") // 禁用Nginx缓冲
-	httpResp.Header().Set("Access-Control-Allow-Origin", "*")
+	defer StartSSE(ctx, httpResp)()
 	sd := &StreamData{
 		Id:      uuid.NewString(),
 		Created: time.Now().Unix(),
@@ -40,53 +65,187 @@ func SteamResponse(ctx context.Context, streamReader *schema.StreamReader[*schem
 		writeSSEDocuments(httpResp, string(marshal))
 	}
 	sd.Document = nil // 置空，发一次就够了
-	// 处理流式响应
+	// 收集完整回答，用于生成推荐追问
+	var fullContent strings.Builder
+	// 处理流式响应：模型增量按 sse.coalesce 配置合并后发送
+	coalescer := newDeltaCoalescer(LoadCoalesceConfig(ctx))
+	format := streamFormatOf(httpResp)
+	emit := func(deltas []streamDelta) {
+		for _, delta := range deltas {
+			if format != nil {
+				if delta.kind == deltaReasoning {
+					writeSSEData(httpResp, format.Chunk("", delta.text))
+				} else {
+					writeSSEData(httpResp, format.Chunk(delta.text, ""))
+				}
+				continue
+			}
+			if delta.kind == deltaReasoning {
+				sd.Reasoning = delta.text
+				marshal, _ := sonic.Marshal(sd)
+				writeSSEReasoning(httpResp, string(marshal))
+				sd.Reasoning = ""
+				continue
+			}
+			sd.Content = delta.text
+			marshal, _ := sonic.Marshal(sd)
+			// 发送数据事件
+			writeSSEData(httpResp, string(marshal))
+		}
+	}
+	// 在独立的 goroutine 中接收增量，等待下一个增量期间也能按时间发送缓冲的内容
+	type received struct {
+		chunk *schema.Message
+		err   error
+	}
+	chunks := make(chan received)
+	go func() {
+		for {
+			chunk, err := streamReader.Recv()
+			chunks <- received{chunk: chunk, err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	var tick <-chan time.Time
+	if coalescer.cfg.Interval > 0 {
+		ticker := time.NewTicker(coalescer.cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+recvLoop:
 	for {
-		chunk, err := streamReader.Recv()
-		if err == io.EOF {
-			break
+		select {
+		case now := <-tick:
+			if coalescer.due(now) {
+				emit(coalescer.flush(now))
+			}
+		case r := <-chunks:
+			if r.err == io.EOF {
+				break recvLoop
+			}
+			if r.err != nil {
+				emit(coalescer.flush(time.Now()))
+				WriteSSEError(httpResp, r.err)
+				break recvLoop
+			}
+			if r.chunk.ReasoningContent != "" {
+				emit(coalescer.add(deltaReasoning, r.chunk.ReasoningContent, time.Now()))
+			}
+			if len(r.chunk.Content) == 0 {
+				continue
+			}
+			fullContent.WriteString(r.chunk.Content)
+			emit(coalescer.add(deltaContent, r.chunk.Content, time.Now()))
+		}
+	}
+	emit(coalescer.flush(time.Now()))
+	if format != nil {
+		writeSSEData(httpResp, format.Finish())
+	}
+	sd.Content = ""
+	// 发送置信度事件
+	if hooks.Confidence != nil && fullContent.Len() > 0 {
+		if confidence := hooks.Confidence(fullContent.String()); confidence != nil {
+			sd.Confidence = confidence
+			marshal, _ := sonic.Marshal(sd)
+			writeSSEConfidence(httpResp, string(marshal))
+			sd.Confidence = nil
 		}
-		if err != nil {
-			writeSSEError(httpResp, err)
-			break
+	}
+	// 发送引用事件
+	if hooks.Citations != nil && fullContent.Len() > 0 {
+		if citations := hooks.Citations(fullContent.String()); citations != nil {
+			sd.Citations = citations
+			marshal, _ := sonic.Marshal(sd)
+			writeSSECitations(httpResp, string(marshal))
+			sd.Citations = nil
 		}
-		if len(chunk.Content) == 0 {
-			continue
+	}
+	// 发送推荐追问事件
+	if hooks.FollowUp != nil && fullContent.Len() > 0 {
+		if questions := hooks.FollowUp(fullContent.String()); len(questions) > 0 {
+			sd.FollowUp = questions
+			marshal, _ := sonic.Marshal(sd)
+			writeSSEFollowUp(httpResp, string(marshal))
 		}
-
-		sd.Content = chunk.Content
+	}
+	// 发送延迟预算事件
+	if hooks.LatencyBudget != nil {
+		if report := hooks.LatencyBudget(); report != nil {
+			sd.FollowUp = nil
+			sd.Budget = report
+			marshal, _ := sonic.Marshal(sd)
+			WriteSSEEvent(httpResp, "latency_budget", string(marshal))
+		}
+	}
+	// 发送更正申请事件
+	if hooks.CorrectionID != "" {
+		sd.FollowUp = nil
+		sd.Budget = nil
+		sd.CorrectionID = hooks.CorrectionID
 		marshal, _ := sonic.Marshal(sd)
-		// 发送数据事件
-		writeSSEData(httpResp, string(marshal))
+		WriteSSEEvent(httpResp, "correction", string(marshal))
 	}
 	// 发送结束事件
 	writeSSEDone(httpResp)
 	return nil
 }
 
+// SetSSEHeaders 设置SSE响应头
+func SetSSEHeaders(resp *ghttp.Response) {
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.Header().Set("X-Accel-Buffering", "no") // 禁用Nginx缓冲
+	resp.Header().Set("Access-Control-Allow-Origin", "*")
+}
+
+// WriteSSEEvent 写入指定名称的SSE事件（格式与 documents、follow_up 事件一致）
+func WriteSSEEvent(resp *ghttp.Response, name string, data string) {
+	writeSSE(resp, fmt.Sprintf("%s:%s\n", name, data))
+}
+
 // writeSSEData 写入SSE事件
 func writeSSEData(resp *ghttp.Response, data string) {
 	if len(data) == 0 {
 		return
 	}
 	// g.Log().Infof(context.Background(), "data: %s", data)
-	resp.Writeln(fmt.Sprintf("data:%s\n", data))
-	resp.Flush()
+	writeSSE(resp, fmt.Sprintf("data:%s\n", data))
 }
 
 func writeSSEDone(resp *ghttp.Response) {
-	resp.Writeln(fmt.Sprintf("data:%s\n", "[DONE]"))
-	resp.Flush()
+	writeSSE(resp, fmt.Sprintf("data:%s\n", "[DONE]"))
 }
 
 func writeSSEDocuments(resp *ghttp.Response, data string) {
-	resp.Writeln(fmt.Sprintf("documents:%s\n", data))
-	resp.Flush()
+	writeSSE(resp, fmt.Sprintf("documents:%s\n", data))
+}
+
+func writeSSEReasoning(resp *ghttp.Response, data string) {
+	writeSSE(resp, fmt.Sprintf("reasoning:%s\n", data))
 }
 
-// writeSSEError 写入SSE错误
-func writeSSEError(resp *ghttp.Response, err error) {
+func writeSSEConfidence(resp *ghttp.Response, data string) {
+	writeSSE(resp, fmt.Sprintf("confidence:%s\n", data))
+}
+
+func writeSSECitations(resp *ghttp.Response, data string) {
+	writeSSE(resp, fmt.Sprintf("citations:%s\n", data))
+}
+
+func writeSSEFollowUp(resp *ghttp.Response, data string) {
+	writeSSE(resp, fmt.Sprintf("follow_up:%s\n", data))
+}
+
+// WriteSSEError 写入SSE错误事件
+func WriteSSEError(resp *ghttp.Response, err error) {
 	g.Log().Error(context.Background(), err)
-	resp.Writeln(fmt.Sprintf("event: error\ndata: %s\n\n", err.Error()))
-	resp.Flush()
+	if format := streamFormatOf(resp); format != nil {
+		writeSSEData(resp, format.Error(err))
+		return
+	}
+	writeSSE(resp, fmt.Sprintf("event: error\ndata: %s\n\n", err.Error()))
 }
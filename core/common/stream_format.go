@@ -0,0 +1,35 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// StreamFormat 替换 SSE 回答事件的格式，OpenAI 兼容接口（/v1/chat/completions）用于输出 chat.completion.chunk。
+// 设置后：回答增量的 data 事件内容由 Chunk 生成，回答结束时以 data 事件发送 Finish 的内容，错误以 data 事件发送 Error 的内容，
+// 不发送 retry 字段；其他命名事件（documents、follow_up 等）保持不变，OpenAI SDK 会忽略未知字段
+type StreamFormat interface {
+	Chunk(content, reasoning string) string
+	Finish() string
+	Error(err error) string
+}
+
+// streamFormats 设置了格式的 SSE 响应（*ghttp.Response -> StreamFormat）
+var streamFormats sync.Map
+
+// SetStreamFormat 设置响应的 SSE 格式，返回的函数用于清除设置，应在响应结束时调用
+func SetStreamFormat(resp *ghttp.Response, format StreamFormat) (clear func()) {
+	streamFormats.Store(resp, format)
+	return func() {
+		streamFormats.Delete(resp)
+	}
+}
+
+// streamFormatOf 响应的 SSE 格式，未设置时返回 nil
+func streamFormatOf(resp *ghttp.Response) StreamFormat {
+	if v, ok := streamFormats.Load(resp); ok {
+		return v.(StreamFormat)
+	}
+	return nil
+}
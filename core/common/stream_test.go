@@ -0,0 +1,52 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// TestSteamResponseCorrection 测试流式响应在结束事件前以 correction 事件发送更正申请ID
+func TestSteamResponseCorrection(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("sse:\n  retryMs: 0\n  heartbeatInterval: 0\n")
+	if err != nil {
+		t.Fatalf("NewAdapterContent() error = %v", err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	s := g.Server(fmt.Sprintf("stream-correction-test-%d", time.Now().UnixNano()))
+	s.SetAddr("127.0.0.1:0")
+	s.SetDumpRouterMap(false)
+	s.BindHandler("/stream", func(r *ghttp.Request) {
+		streamReader, streamWriter := schema.Pipe[*schema.Message](1)
+		streamWriter.Send(&schema.Message{Role: schema.Assistant, Content: "保修期为3年"}, nil)
+		streamWriter.Close()
+		_ = SteamResponse(r.Context(), streamReader, nil, StreamHooks{CorrectionID: "corr-1"})
+	})
+	if err = s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/stream", s.GetListenedPort()))
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	correction := strings.Index(string(body), "correction:")
+	done := strings.Index(string(body), "data:[DONE]")
+	if correction < 0 || done < correction || !strings.Contains(string(body), `"correction_id":"corr-1"`) {
+		t.Errorf("correction event should precede [DONE], body = %s", body)
+	}
+}
@@ -3,12 +3,20 @@ package config
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/vector_store"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
+// VideoCacheDir 视频关键帧、音轨和转写结果的缓存目录（multimodal.video.cacheDir），默认位于状态目录
+func VideoCacheDir(ctx context.Context) string {
+	return g.Cfg().MustGet(ctx, "multimodal.video.cacheDir", StatePath("video_frames")).String()
+}
+
 // ValidateConfiguration validates all required configuration items
 func ValidateConfiguration(ctx context.Context) error {
 	var missingConfigs []string
@@ -39,6 +47,21 @@ func ValidateConfiguration(ctx context.Context) error {
 		if pgDatabase == "" {
 			missingConfigs = append(missingConfigs, "postgres.database")
 		}
+	case "qdrant":
+		// 验证 Qdrant 配置
+		if g.Cfg().MustGet(ctx, "qdrant.address", "").String() == "" {
+			missingConfigs = append(missingConfigs, "qdrant.address")
+		}
+	case "elasticsearch":
+		// 验证 Elasticsearch / OpenSearch 配置
+		if g.Cfg().MustGet(ctx, "elasticsearch.address", "").String() == "" {
+			missingConfigs = append(missingConfigs, "elasticsearch.address")
+		}
+		switch flavor := g.Cfg().MustGet(ctx, "elasticsearch.flavor", "elasticsearch").String(); flavor {
+		case "elasticsearch", "opensearch":
+		default:
+			warnings = append(warnings, fmt.Sprintf("Unknown elasticsearch.flavor: %s, defaulting to elasticsearch", flavor))
+		}
 	default:
 		warnings = append(warnings, fmt.Sprintf("Unknown vector store type: %s, defaulting to milvus", vectorStoreType))
 	}
@@ -62,6 +85,22 @@ func ValidateConfiguration(ctx context.Context) error {
 		missingConfigs = append(missingConfigs, "database.default.name")
 	}
 
+	// 消息溢出日志、会话工作区和视频抽帧缓存包含会话内容，不能位于静态文件根目录内，否则可以不经认证直接下载
+	var exposedPaths []string
+	for key, path := range map[string]string{
+		"messageSaver.spoolPath":    g.Cfg().MustGet(ctx, "messageSaver.spoolPath", StatePath("message_spool.wal")).String(),
+		"workspace.root":            g.Cfg().MustGet(ctx, "workspace.root", StatePath("workspace")).String(),
+		"multimodal.video.cacheDir": VideoCacheDir(ctx),
+	} {
+		if path != "" && UnderServerRoot(path) {
+			exposedPaths = append(exposedPaths, fmt.Sprintf("%s (%s)", key, path))
+		}
+	}
+	if len(exposedPaths) > 0 {
+		sort.Strings(exposedPaths)
+		return fmt.Errorf("the following paths are inside the static file root and would be downloadable without authentication:\n- %s\n\nPlease move them outside the working directory (default: %s)", strings.Join(exposedPaths, "\n- "), StateDir())
+	}
+
 	// 输出警告信息
 	if len(warnings) > 0 {
 		g.Log().Warningf(ctx, "Configuration warnings:\n- %s", strings.Join(warnings, "\n- "))
@@ -93,7 +132,25 @@ type Config struct {
 // RetrieverConfig Retriever专用配置，组合基础配置和向量存储
 type RetrieverConfig struct {
 	RetrieverConfigBase
-	VectorStore vector_store.VectorStore // 向量数据库接口
+	VectorStore    vector_store.VectorStore // 向量数据库接口
+	SparseEmbedder common.SparseEmbedder    // 稀疏向量化实例，为 nil 时仅使用稠密检索
+	SparseWeight   float64                  // 稀疏检索分数的融合权重（0-1）
+
+	RecencyWeight       float64 // 新近度加权权重（0-1），0 表示不启用
+	RecencyHalfLifeDays int     // 新近度半衰期（天）
+
+	AsOf *time.Time // 按历史时间点检索，为 nil 时检索当前有效的最新版本
+
+	MetadataFilter *MetadataFilter // 按索引时提取的文档元数据过滤，为 nil 时不过滤
+}
+
+// MetadataFilter 文档元数据过滤条件，各条件同时满足，未提取到对应字段的文档不满足该条件
+type MetadataFilter struct {
+	Title    string   // 标题包含（不区分大小写）
+	Author   string   // 作者包含（不区分大小写）
+	Topics   []string // 包含任一主题（不区分大小写）
+	DateFrom string   // 文档日期不早于（YYYY-MM-DD）
+	DateTo   string   // 文档日期不晚于（YYYY-MM-DD）
 }
 
 // IndexerConfig Indexer专用配置
@@ -118,9 +175,10 @@ func (c *RetrieverConfig) GetBaseURL() string        { return c.BaseURL }
 func (c *RetrieverConfig) GetEmbeddingModel() string { return c.EmbeddingModel }
 
 // RetrieverConfig 实现 rerank config 接口
-func (c *RetrieverConfig) GetRerankAPIKey() string  { return c.RerankAPIKey }
-func (c *RetrieverConfig) GetRerankBaseURL() string { return c.RerankBaseURL }
-func (c *RetrieverConfig) GetRerankModel() string   { return c.RerankModel }
+func (c *RetrieverConfig) GetRerankAPIKey() string   { return c.RerankAPIKey }
+func (c *RetrieverConfig) GetRerankBaseURL() string  { return c.RerankBaseURL }
+func (c *RetrieverConfig) GetRerankModel() string    { return c.RerankModel }
+func (c *RetrieverConfig) GetRerankProvider() string { return c.RerankProvider }
 
 // RetrieverConfig 实现 GeneralRetrieverConfig 接口
 func (c *RetrieverConfig) GetTopK() int            { return c.TopK }
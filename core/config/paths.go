@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServerRoot HTTP 服务的静态文件根目录，其中的文件不经认证即可下载
+const ServerRoot = "."
+
+// StateDir 服务运行状态（消息溢出日志、会话工作区等）的默认目录，位于静态文件根目录之外：
+// $XDG_STATE_HOME/kbgo，未设置时为 ~/.local/state/kbgo，无法获取用户目录时使用临时目录
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "kbgo")
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return filepath.Join(home, ".local", "state", "kbgo")
+	}
+	return filepath.Join(os.TempDir(), "kbgo")
+}
+
+// StatePath 状态目录下的路径
+func StatePath(name string) string {
+	return filepath.Join(StateDir(), name)
+}
+
+// UnderServerRoot 路径是否位于静态文件根目录内（可被直接下载）
+func UnderServerRoot(path string) bool {
+	return underDir(ServerRoot, path)
+}
+
+func underDir(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
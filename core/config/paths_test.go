@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+func TestUnderDir(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "root itself", path: root, want: true},
+		{name: "nested file", path: filepath.Join(root, "data", "message_spool.wal"), want: true},
+		{name: "dot-dot escapes root", path: filepath.Join(root, "..", "state", "message_spool.wal"), want: false},
+		{name: "sibling with common prefix", path: root + "-state", want: false},
+		{name: "name starting with dots", path: filepath.Join(root, "..hidden"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := underDir(root, tt.path); got != tt.want {
+				t.Errorf("underDir(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVideoCacheDir 视频抽帧缓存默认位于状态目录，不在静态文件根目录内
+func TestVideoCacheDir(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("multimodal:\n  video:\n    frames: 8\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	dir := VideoCacheDir(context.Background())
+	if want := filepath.Join(state, "kbgo", "video_frames"); dir != want {
+		t.Errorf("VideoCacheDir() = %q, want %q", dir, want)
+	}
+	if UnderServerRoot(dir) {
+		t.Errorf("default video cache %q should not be inside the static file root", dir)
+	}
+}
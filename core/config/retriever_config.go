@@ -10,9 +10,10 @@ type RetrieverConfigBase struct {
 	RerankAPIKey    string  // Rerank API密钥
 	RerankBaseURL   string  // Rerank API基础URL
 	RerankModel     string  // Rerank模型名称
+	RerankProvider  string  // Rerank模型提供商，决定接口格式（cohere/tei，默认 cohere）
 	EnableRewrite   bool    // 是否启用查询重写（默认 false）
 	RewriteAttempts int     // 查询重写尝试次数（默认 3）
-	RetrieveMode    string  // 检索模式: milvus/rerank/rrf（默认 rerank）
+	RetrieveMode    string  // 检索模式: milvus/rerank/rrf/hybrid（默认 rerank）
 	TopK            int     // 默认返回结果数量（默认 5）
 	Score           float64 // 默认分数阈值（默认 0.2）
 }
@@ -23,9 +24,10 @@ func (c *RetrieverConfigBase) GetBaseURL() string        { return c.BaseURL }
 func (c *RetrieverConfigBase) GetEmbeddingModel() string { return c.EmbeddingModel }
 
 // RetrieverConfigBase 实现 rerank config 接口
-func (c *RetrieverConfigBase) GetRerankAPIKey() string  { return c.RerankAPIKey }
-func (c *RetrieverConfigBase) GetRerankBaseURL() string { return c.RerankBaseURL }
-func (c *RetrieverConfigBase) GetRerankModel() string   { return c.RerankModel }
+func (c *RetrieverConfigBase) GetRerankAPIKey() string   { return c.RerankAPIKey }
+func (c *RetrieverConfigBase) GetRerankBaseURL() string  { return c.RerankBaseURL }
+func (c *RetrieverConfigBase) GetRerankModel() string    { return c.RerankModel }
+func (c *RetrieverConfigBase) GetRerankProvider() string { return c.RerankProvider }
 
 // RetrieverConfigBase 实现 GeneralRetrieverConfig 接口
 func (c *RetrieverConfigBase) GetTopK() int            { return c.TopK }
@@ -0,0 +1,184 @@
+package file_export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+)
+
+const (
+	docxEMUPerPixel = 9525    // 96 DPI 下每像素的 EMU
+	docxMaxWidthEMU = 5486400 // 图片最大宽度 6 英寸
+)
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Default Extension="png" ContentType="image/png"/>` +
+	`<Default Extension="jpeg" ContentType="image/jpeg"/>` +
+	`<Default Extension="gif" ContentType="image/gif"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`</Types>`
+
+const docxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`</Relationships>`
+
+// docxRun 一段文字的格式
+type docxRun struct {
+	size  int // 字号（半磅）
+	bold  bool
+	color string
+}
+
+var (
+	docxTitleRun   = docxRun{size: 36, bold: true}
+	docxMetaRun    = docxRun{size: 18, color: "808080"}
+	docxHeadingRun = docxRun{size: 26, bold: true}
+	docxBodyRun    = docxRun{size: 21}
+	docxNoteRun    = docxRun{size: 18, color: "666666"}
+)
+
+// WriteDOCX 把文档写为 DOCX 文件
+func WriteDOCX(w io.Writer, doc *Document) error {
+	var body bytes.Buffer
+	var media []docxMedia
+
+	writeDOCXParagraph(&body, doc.Title, docxTitleRun)
+	for _, line := range doc.Meta {
+		writeDOCXParagraph(&body, line, docxMetaRun)
+	}
+	for _, block := range doc.Blocks {
+		switch block.Kind {
+		case BlockHeading:
+			writeDOCXParagraph(&body, block.Text, docxHeadingRun)
+		case BlockParagraph:
+			writeDOCXParagraph(&body, block.Text, docxBodyRun)
+		case BlockNote:
+			writeDOCXParagraph(&body, block.Text, docxNoteRun)
+		case BlockImage:
+			m, ok := newDOCXMedia(len(media)+1, block.Image)
+			if !ok {
+				writeDOCXParagraph(&body, "[图片无法导出] "+block.Text, docxNoteRun)
+				continue
+			}
+			media = append(media, m)
+			writeDOCXImage(&body, m)
+			if block.Text != "" {
+				writeDOCXParagraph(&body, block.Text, docxNoteRun)
+			}
+		}
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"word/_rels/document.xml.rels", docxDocumentRels(media)},
+		{"word/document.xml", docxDocument(body.String())},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	for _, m := range media {
+		fw, err := zw.Create("word/media/" + m.name)
+		if err != nil {
+			return err
+		}
+		if _, err = fw.Write(m.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// docxMedia 嵌入文档的图片
+type docxMedia struct {
+	id            int
+	name          string
+	data          []byte
+	width, height int // EMU
+}
+
+func newDOCXMedia(id int, data []byte) (docxMedia, bool) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return docxMedia{}, false
+	}
+	width, height := cfg.Width*docxEMUPerPixel, cfg.Height*docxEMUPerPixel
+	if width > docxMaxWidthEMU {
+		height = height * docxMaxWidthEMU / width
+		width = docxMaxWidthEMU
+	}
+	return docxMedia{id: id, name: fmt.Sprintf("image%d.%s", id, format), data: data, width: width, height: height}, true
+}
+
+func docxDocumentRels(media []docxMedia) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, m := range media {
+		fmt.Fprintf(&b, `<Relationship Id="rIdImage%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/%s"/>`, m.id, m.name)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func docxDocument(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"` +
+		` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+		` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"` +
+		` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+		` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">` +
+		`<w:body>` + body +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr>` +
+		`</w:body></w:document>`
+}
+
+// writeDOCXParagraph 写入一个段落，文本中的换行写为段内换行
+func writeDOCXParagraph(b *bytes.Buffer, text string, run docxRun) {
+	b.WriteString(`<w:p><w:pPr><w:spacing w:after="120"/></w:pPr><w:r><w:rPr><w:rFonts w:eastAsia="宋体"/>`)
+	if run.bold {
+		b.WriteString(`<w:b/>`)
+	}
+	if run.color != "" {
+		fmt.Fprintf(b, `<w:color w:val="%s"/>`, run.color)
+	}
+	fmt.Fprintf(b, `<w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr>`, run.size, run.size)
+	for i, line := range splitLines(text) {
+		if i > 0 {
+			b.WriteString(`<w:br/>`)
+		}
+		b.WriteString(`<w:t xml:space="preserve">`)
+		_ = xml.EscapeText(b, []byte(line))
+		b.WriteString(`</w:t>`)
+	}
+	b.WriteString(`</w:r></w:p>`)
+}
+
+func writeDOCXImage(b *bytes.Buffer, m docxMedia) {
+	fmt.Fprintf(b, `<w:p><w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%d" cy="%d"/><wp:docPr id="%d" name="Picture %d"/>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic>`+
+		`<pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="rIdImage%d"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`,
+		m.width, m.height, m.id, m.id, m.id, m.name, m.id, m.width, m.height)
+}
@@ -0,0 +1,84 @@
+// Package file_export 把结构化文档（标题、段落、引用、图片）导出为 PDF 或 DOCX 文件，
+// 只依赖标准库：PDF 使用阅读器内置的 STSong-Light 中文字体（不嵌入字体），DOCX 为 WordprocessingML 压缩包
+package file_export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 导出格式
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+)
+
+// BlockKind 文档块类型
+type BlockKind int
+
+const (
+	BlockHeading   BlockKind = iota // 小节标题
+	BlockParagraph                  // 正文段落，文本中的换行保留为分行
+	BlockNote                       // 补充说明（引用、工具摘要等），以较小的灰色文字显示
+	BlockImage                      // 图片，Image 为 JPEG/PNG/GIF 数据
+)
+
+// Block 文档块
+type Block struct {
+	Kind  BlockKind
+	Text  string // 图片块为图片说明
+	Image []byte
+}
+
+// Document 待导出的文档
+type Document struct {
+	Title  string
+	Meta   []string // 标题下方的说明行（如导出时间、模型）
+	Blocks []Block
+}
+
+// Heading 追加小节标题
+func (d *Document) Heading(text string) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockHeading, Text: text})
+}
+
+// Paragraph 追加正文段落
+func (d *Document) Paragraph(text string) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockParagraph, Text: text})
+}
+
+// Note 追加补充说明
+func (d *Document) Note(text string) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockNote, Text: text})
+}
+
+// Image 追加图片
+func (d *Document) Image(data []byte, caption string) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockImage, Text: caption, Image: data})
+}
+
+// Write 按格式把文档写入 w
+func Write(w io.Writer, format string, doc *Document) error {
+	switch strings.ToLower(format) {
+	case FormatPDF:
+		return WritePDF(w, doc)
+	case FormatDOCX:
+		return WriteDOCX(w, doc)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// ContentType 导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	if strings.EqualFold(format, FormatDOCX) {
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	}
+	return "application/pdf"
+}
+
+// splitLines 按换行拆分文本，统一换行符
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimRight(text, "\n"), "\n")
+}
@@ -0,0 +1,109 @@
+package file_export
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth float64
+		want     []string
+	}{
+		{name: "empty", text: "", maxWidth: 100, want: []string{""}},
+		{name: "fits", text: "hello 世界", maxWidth: 100, want: []string{"hello 世界"}},
+		{name: "cjk", text: "一二三四五六", maxWidth: 30, want: []string{"一二三", "四五六"}},
+		{name: "keeps words", text: "hello world again", maxWidth: 40, want: []string{"hello", "world", "again"}},
+		{name: "long word", text: "abcdefghij", maxWidth: 25, want: []string{"abcde", "fghij"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, 10, tt.maxWidth)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrapText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeUCS2(t *testing.T) {
+	if got := encodeUCS2("A中😀"); got != "00414E2D003F" {
+		t.Errorf("encodeUCS2() = %s, want 00414E2D003F", got)
+	}
+}
+
+func testDocument(t *testing.T) *Document {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	doc := &Document{Title: "会话记录 <1>", Meta: []string{"导出时间：2026-10-16"}}
+	doc.Heading("用户")
+	doc.Paragraph("第一行\n第二行 & more")
+	doc.Image(buf.Bytes(), "截图")
+	doc.Image([]byte("not an image"), "损坏的图片")
+	doc.Note("[1] 参考资料")
+	return doc
+}
+
+func TestWriteDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOCX(&buf, testDocument(t)); err != nil {
+		t.Fatalf("WriteDOCX() error = %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid docx archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/_rels/document.xml.rels", "word/media/image1.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	document := files["word/document.xml"]
+	for _, want := range []string{"会话记录 &lt;1&gt;", "第一行</w:t><w:br/>", "第二行 &amp; more", `r:embed="rIdImage1"`, "[图片无法导出] 损坏的图片"} {
+		if !strings.Contains(document, want) {
+			t.Errorf("document.xml missing %q", want)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	doc := testDocument(t)
+	for i := 0; i < 120; i++ {
+		doc.Paragraph("很长的会话内容需要分页显示")
+	}
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, doc); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+	pdf := buf.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("invalid pdf header or trailer")
+	}
+	count := regexp.MustCompile(`/Count (\d+)`).FindStringSubmatch(pdf)
+	if count == nil || count[1] == "1" {
+		t.Errorf("expected multiple pages, got %v", count)
+	}
+	if !strings.Contains(pdf, "/Subtype /Image") {
+		t.Error("expected image xobject")
+	}
+}
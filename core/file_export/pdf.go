@@ -0,0 +1,293 @@
+package file_export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const (
+	pdfPageWidth    = 595.28 // A4
+	pdfPageHeight   = 841.89
+	pdfMargin       = 56.0
+	pdfContentWidth = pdfPageWidth - 2*pdfMargin
+	pdfMaxImageH    = 360.0
+
+	// 固定对象编号：目录、页面树、字体
+	pdfCatalogID = 1
+	pdfPagesID   = 2
+	pdfFontID    = 3
+	pdfCIDFontID = 4
+)
+
+// pdfStyle 文字样式
+type pdfStyle struct {
+	size   float64
+	gray   float64 // 0 为黑色
+	bold   bool    // 使用描边模拟粗体（内置中文字体没有粗体）
+	before float64 // 段前间距
+}
+
+var (
+	pdfTitleStyle   = pdfStyle{size: 18, bold: true}
+	pdfMetaStyle    = pdfStyle{size: 9, gray: 0.5, before: 4}
+	pdfHeadingStyle = pdfStyle{size: 13, bold: true, before: 14}
+	pdfBodyStyle    = pdfStyle{size: 10.5, before: 6}
+	pdfNoteStyle    = pdfStyle{size: 9, gray: 0.4, before: 4}
+)
+
+// pdfWriter 逐页排版的 PDF 生成器
+type pdfWriter struct {
+	objects [][]byte // objects[i] 为编号 i+1 的对象
+	pages   []int
+	content bytes.Buffer   // 当前页内容流
+	images  map[string]int // 当前页使用的图片名称 -> 对象编号
+	y       float64        // 当前页下一行顶部的纵坐标
+	started bool           // 当前页是否已创建
+	imageN  int
+}
+
+// WritePDF 把文档写为 PDF 文件
+// 文字使用 Adobe-GB1 的 STSong-Light 字体（阅读器内置，不嵌入），不在基本多文种平面的字符（如 emoji）输出为 "?"
+func WritePDF(w io.Writer, doc *Document) error {
+	p := &pdfWriter{objects: make([][]byte, pdfCIDFontID)}
+	p.objects[pdfFontID-1] = []byte("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+	p.objects[pdfCIDFontID-1] = []byte("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> " +
+		"/FontDescriptor << /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >> /DW 1000 /W [1 95 500] >>")
+
+	p.text(doc.Title, pdfTitleStyle)
+	for _, line := range doc.Meta {
+		p.text(line, pdfMetaStyle)
+	}
+	p.y -= 6
+	for _, block := range doc.Blocks {
+		switch block.Kind {
+		case BlockHeading:
+			p.text(block.Text, pdfHeadingStyle)
+		case BlockParagraph:
+			p.text(block.Text, pdfBodyStyle)
+		case BlockNote:
+			p.text(block.Text, pdfNoteStyle)
+		case BlockImage:
+			if !p.image(block.Image) {
+				p.text("[图片无法导出] "+block.Text, pdfNoteStyle)
+			} else if block.Text != "" {
+				p.text(block.Text, pdfNoteStyle)
+			}
+		}
+	}
+	p.finishPage()
+
+	kids := make([]string, 0, len(p.pages))
+	for _, id := range p.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	p.objects[pdfCatalogID-1] = []byte("<< /Type /Catalog /Pages 2 0 R >>")
+	p.objects[pdfPagesID-1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	return p.write(w)
+}
+
+func (p *pdfWriter) add(object []byte) int {
+	p.objects = append(p.objects, object)
+	return len(p.objects)
+}
+
+// addStream 添加 zlib 压缩的流对象，dict 为除 Length 和 Filter 之外的字典项
+func (p *pdfWriter) addStream(dict string, data []byte, compress bool) int {
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+		data = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< %s /Length %d%s >>\nstream\n", dict, len(data), filter)
+	obj.Write(data)
+	obj.WriteString("\nendstream")
+	return p.add(obj.Bytes())
+}
+
+// ensureSpace 当前页剩余高度不足时换页
+func (p *pdfWriter) ensureSpace(height float64) {
+	if !p.started || p.y-height < pdfMargin {
+		p.finishPage()
+		p.started = true
+		p.y = pdfPageHeight - pdfMargin
+		p.images = map[string]int{}
+	}
+}
+
+func (p *pdfWriter) finishPage() {
+	if !p.started {
+		return
+	}
+	contentID := p.addStream("", p.content.Bytes(), true)
+	p.content.Reset()
+	var xobjects strings.Builder
+	for name, id := range p.images {
+		fmt.Fprintf(&xobjects, " /%s %d 0 R", name, id)
+	}
+	resources := fmt.Sprintf("/Font << /F1 %d 0 R >>", pdfFontID)
+	if xobjects.Len() > 0 {
+		resources += " /XObject <<" + xobjects.String() + " >>"
+	}
+	p.pages = append(p.pages, p.add([]byte(fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R >>",
+		pdfPagesID, pdfPageWidth, pdfPageHeight, resources, contentID))))
+	p.started = false
+}
+
+// text 按内容宽度折行输出文字
+func (p *pdfWriter) text(text string, style pdfStyle) {
+	leading := style.size * 1.6
+	p.y -= style.before
+	for _, paragraph := range splitLines(text) {
+		for _, line := range wrapText(paragraph, style.size, pdfContentWidth) {
+			p.ensureSpace(leading)
+			p.y -= leading
+			fmt.Fprintf(&p.content, "BT /F1 %.1f Tf %.2f g", style.size, style.gray)
+			if style.bold {
+				fmt.Fprintf(&p.content, " 2 Tr %.2f w %.2f G", style.size/40, style.gray)
+			} else {
+				p.content.WriteString(" 0 Tr")
+			}
+			fmt.Fprintf(&p.content, " %.2f %.2f Td <%s> Tj ET\n", pdfMargin, p.y+style.size*0.4, encodeUCS2(line))
+		}
+	}
+}
+
+// image 按内容宽度缩放并输出图片，无法解码时返回 false
+func (p *pdfWriter) image(data []byte) bool {
+	id, width, height, ok := p.addImage(data)
+	if !ok {
+		return false
+	}
+	w, h := float64(width), float64(height)
+	if w > pdfContentWidth {
+		h, w = h*pdfContentWidth/w, pdfContentWidth
+	}
+	if h > pdfMaxImageH {
+		w, h = w*pdfMaxImageH/h, pdfMaxImageH
+	}
+	p.ensureSpace(h + 8)
+	p.y -= h + 8
+	p.imageN++
+	name := fmt.Sprintf("Im%d", p.imageN)
+	p.images[name] = id
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, pdfMargin, p.y, name)
+	return true
+}
+
+// addImage 添加图片对象：JPEG（RGB 或灰度）直接嵌入，其他格式解码后以 RGB 压缩嵌入，透明部分以白色填充
+func (p *pdfWriter) addImage(data []byte) (id, width, height int, ok bool) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return 0, 0, 0, false
+	}
+	if format == "jpeg" && (cfg.ColorModel == color.YCbCrModel || cfg.ColorModel == color.GrayModel) {
+		colorSpace := "/DeviceRGB"
+		if cfg.ColorModel == color.GrayModel {
+			colorSpace = "/DeviceGray"
+		}
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+			cfg.Width, cfg.Height, colorSpace)
+		return p.addStream(dict, data, false), cfg.Width, cfg.Height, true
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// 预乘 alpha 的颜色叠加到白色背景
+			white := 0xffff - a
+			rgb = append(rgb, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8",
+		bounds.Dx(), bounds.Dy())
+	return p.addStream(dict, rgb, true), bounds.Dx(), bounds.Dy(), true
+}
+
+func (p *pdfWriter) write(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(p.objects))
+	for i, obj := range p.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.objects)+1, pdfCatalogID, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// runeWidth 字符宽度（em）：ASCII 为半角，其他为全角
+func runeWidth(r rune) float64 {
+	if r < 0x80 {
+		return 0.5
+	}
+	return 1
+}
+
+// wrapText 按宽度折行，英文单词尽量不拆开
+func wrapText(text string, size, maxWidth float64) []string {
+	runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+	if len(runes) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	start, width, lastSpace := 0, 0.0, -1
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		width += runeWidth(r) * size
+		if r == ' ' {
+			lastSpace = i
+		}
+		if width <= maxWidth || i == start {
+			continue
+		}
+		end := i
+		// 当前字符是英文单词的一部分时回退到上一个空格
+		if r < 0x80 && !unicode.IsSpace(r) && lastSpace > start {
+			end = lastSpace + 1
+		}
+		lines = append(lines, strings.TrimRight(string(runes[start:end]), " "))
+		start, width, lastSpace = end, 0, -1
+		i = end - 1
+	}
+	return append(lines, string(runes[start:]))
+}
+
+// encodeUCS2 把文字编码为 UniGB-UCS2-H 使用的 UTF-16BE 十六进制串
+func encodeUCS2(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r > 0xffff || unicode.IsControl(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
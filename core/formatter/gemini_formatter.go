@@ -0,0 +1,160 @@
+package formatter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Malowking/kbgo/core/media"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+// GeminiFormatter Gemini 消息格式适配器，配合 gemini 提供商的客户端（generateContent 接口）使用
+// 图片统一转换为 data URI（客户端转为 inlineData 内联发送，Gemini 不能读取本地路径），
+// 保留助手消息的工具调用，工具结果消息带上函数名（Gemini 的 functionResponse 按函数名对应调用）
+type GeminiFormatter struct{}
+
+// NewGeminiFormatter 创建Gemini格式适配器
+func NewGeminiFormatter() *GeminiFormatter {
+	return &GeminiFormatter{}
+}
+
+// FormatMessages 转换消息格式为Gemini客户端可转换的OpenAI格式
+func (f *GeminiFormatter) FormatMessages(messages []*schema.Message) ([]openai.ChatCompletionMessage, error) {
+	result := make([]openai.ChatCompletionMessage, 0, len(messages))
+	callNames := make(map[string]string) // 工具调用ID -> 函数名
+
+	for _, msg := range messages {
+		openaiMsg := openai.ChatCompletionMessage{
+			Role: string(msg.Role),
+		}
+
+		switch {
+		case len(msg.UserInputMultiContent) > 0:
+			openaiMsg.MultiContent = f.convertUserInputMultiContent(msg.UserInputMultiContent)
+		case len(msg.MultiContent) > 0:
+			openaiMsg.MultiContent = f.convertMultiContent(msg.MultiContent)
+		default:
+			openaiMsg.Content = msg.Content
+		}
+
+		for _, call := range msg.ToolCalls {
+			callNames[call.ID] = call.Function.Name
+			openaiMsg.ToolCalls = append(openaiMsg.ToolCalls, openai.ToolCall{
+				ID:       call.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: call.Function.Name, Arguments: call.Function.Arguments},
+			})
+		}
+		if msg.Role == schema.Tool {
+			openaiMsg.ToolCallID = msg.ToolCallID
+			openaiMsg.Name = callNames[msg.ToolCallID]
+		}
+
+		result = append(result, openaiMsg)
+	}
+
+	return result, nil
+}
+
+// convertUserInputMultiContent 转换UserInputMultiContent，音频和视频作为文本描述
+func (f *GeminiFormatter) convertUserInputMultiContent(parts []schema.MessageInputPart) []openai.ChatMessagePart {
+	var contentParts []openai.ChatMessagePart
+
+	for _, part := range parts {
+		switch part.Type {
+		case schema.ChatMessagePartTypeText:
+			contentParts = append(contentParts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: part.Text,
+			})
+
+		case schema.ChatMessagePartTypeImageURL:
+			if part.Image != nil {
+				if imageURL := f.buildImageURL(part.Image); imageURL != "" {
+					contentParts = append(contentParts, openai.ChatMessagePart{
+						Type:     openai.ChatMessagePartTypeImageURL,
+						ImageURL: &openai.ChatMessageImageURL{URL: imageURL},
+					})
+				}
+			}
+
+		case schema.ChatMessagePartTypeAudioURL:
+			contentParts = append(contentParts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: "[音频文件]",
+			})
+
+		case schema.ChatMessagePartTypeVideoURL:
+			contentParts = append(contentParts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: "[视频文件]",
+			})
+		}
+	}
+
+	return contentParts
+}
+
+// convertMultiContent 转换旧版MultiContent
+func (f *GeminiFormatter) convertMultiContent(parts []schema.ChatMessagePart) []openai.ChatMessagePart {
+	var contentParts []openai.ChatMessagePart
+
+	for _, part := range parts {
+		switch part.Type {
+		case schema.ChatMessagePartTypeText:
+			contentParts = append(contentParts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: part.Text,
+			})
+
+		case schema.ChatMessagePartTypeImageURL:
+			if part.ImageURL != nil {
+				imageURL := part.ImageURL.URL
+				if len(imageURL) > 0 && (imageURL[0] == '/' || imageURL[0] == '.') {
+					imageURL = f.filePathToDataURI(imageURL)
+				}
+				if imageURL != "" {
+					contentParts = append(contentParts, openai.ChatMessagePart{
+						Type:     openai.ChatMessagePartTypeImageURL,
+						ImageURL: &openai.ChatMessageImageURL{URL: imageURL},
+					})
+				}
+			}
+		}
+	}
+
+	return contentParts
+}
+
+// buildImageURL 构建图片URL，优先使用Base64Data（内联发送），本地文件读取后转换为data URI
+func (f *GeminiFormatter) buildImageURL(image *schema.MessageInputImage) string {
+	if image.Base64Data != nil && *image.Base64Data != "" {
+		mimeType := image.MIMEType
+		if mimeType == "" {
+			mimeType = "image/jpeg"
+		}
+		return fmt.Sprintf("data:%s;base64,%s", mimeType, *image.Base64Data)
+	}
+
+	if image.URL != nil && *image.URL != "" {
+		urlStr := *image.URL
+		if urlStr[0] == '/' || urlStr[0] == '.' {
+			return f.filePathToDataURI(urlStr)
+		}
+		return urlStr
+	}
+
+	return ""
+}
+
+// filePathToDataURI 将文件路径转换为data URI，按文件内容识别类型，HEIC/AVIF 转换为 JPEG，超大图片等比缩小
+func (f *GeminiFormatter) filePathToDataURI(filePath string) string {
+	dataURI, err := media.ImageDataURI(context.Background(), filePath)
+	if err != nil {
+		g.Log().Warningf(context.Background(), "Failed to load image file %s: %v, skipping", filePath, err)
+		return ""
+	}
+	return dataURI
+}
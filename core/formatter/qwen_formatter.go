@@ -2,11 +2,9 @@ package formatter
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
-	"os"
-	"path/filepath"
 
+	"github.com/Malowking/kbgo/core/media"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/sashabaranov/go-openai"
@@ -137,7 +135,7 @@ func (f *QwenFormatter) convertMultiContent(parts []schema.ChatMessagePart) []op
 
 				// 如果是文件路径，需要读取文件并转换为base64
 				if len(imageURL) > 0 && (imageURL[0] == '/' || imageURL[0] == '.') {
-					imageURL = f.filePathToDataURI(imageURL, "")
+					imageURL = f.filePathToDataURI(imageURL)
 				}
 
 				if imageURL != "" {
@@ -172,7 +170,7 @@ func (f *QwenFormatter) buildImageURL(image *schema.MessageInputImage) string {
 		urlStr := *image.URL
 		// 如果是文件路径，需要读取文件并转换为base64
 		if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-			return f.filePathToDataURI(urlStr, image.MIMEType)
+			return f.filePathToDataURI(urlStr)
 		}
 		// 假设是有效的HTTP URL或data URI
 		return urlStr
@@ -181,36 +179,12 @@ func (f *QwenFormatter) buildImageURL(image *schema.MessageInputImage) string {
 	return ""
 }
 
-// filePathToDataURI 将文件路径转换为data URI
-func (f *QwenFormatter) filePathToDataURI(filePath, mimeType string) string {
-	data, err := os.ReadFile(filePath)
+// filePathToDataURI 将文件路径转换为data URI，按文件内容识别类型，HEIC/AVIF 转换为 JPEG，超大图片等比缩小
+func (f *QwenFormatter) filePathToDataURI(filePath string) string {
+	dataURI, err := media.ImageDataURI(context.Background(), filePath)
 	if err != nil {
-		g.Log().Warningf(context.Background(), "Failed to read image file %s: %v, skipping", filePath, err)
+		g.Log().Warningf(context.Background(), "Failed to load image file %s: %v, skipping", filePath, err)
 		return ""
 	}
-
-	if mimeType == "" {
-		ext := filepath.Ext(filePath)
-		mimeType = getMimeType(ext)
-	}
-
-	base64Data := base64.StdEncoding.EncodeToString(data)
-	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
-}
-
-// getMimeType 获取MIME类型
-func getMimeType(ext string) string {
-	mimeTypes := map[string]string{
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".png":  "image/png",
-		".gif":  "image/gif",
-		".bmp":  "image/bmp",
-		".webp": "image/webp",
-	}
-
-	if mime, ok := mimeTypes[ext]; ok {
-		return mime
-	}
-	return "image/jpeg"
+	return dataURI
 }
@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/model/entity"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// AppendChunks 向已有文档追加分片并向量化（如审核通过后沉淀的 FAQ），不解析文件
+// 分片的 MetaData 同时保存到分片 ext 字段；向量化失败时删除已保存的分片，文档保持可检索
+func (s *DocumentIndexer) AppendChunks(ctx context.Context, documentId string, modelID string, chunks []*schema.Document) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	idxCtx := &indexContext{
+		ctx:        ctx,
+		modelID:    modelID,
+		documentId: documentId,
+	}
+	if err := s.stepGetDocument(idxCtx); err != nil {
+		return fmt.Errorf("Get document info failed: %w", err)
+	}
+
+	existing, err := knowledge.GetAllChunksByDocId(ctx, documentId, "id")
+	if err != nil {
+		return fmt.Errorf("Failed to load chunks: %w", err)
+	}
+	chunkEntities := make([]entity.KnowledgeChunks, len(chunks))
+	chunkIds := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		if chunk.ID == "" {
+			chunk.ID = uuid.New().String()
+		}
+		ext := map[string]interface{}{}
+		for k, v := range chunk.MetaData {
+			ext[k] = v
+		}
+		ext["chunk_order"] = len(existing) + i
+		extJSON, err := json.Marshal(ext)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal chunk ext: %w", err)
+		}
+		chunkEntities[i] = entity.KnowledgeChunks{
+			Id:             chunk.ID,
+			KnowledgeDocId: documentId,
+			Content:        chunk.Content,
+			Ext:            string(extJSON),
+			CollectionName: idxCtx.collectionName,
+			Status:         1,
+		}
+		chunkIds[i] = chunk.ID
+	}
+	if err = knowledge.AppendChunksData(ctx, chunkEntities); err != nil {
+		return fmt.Errorf("Failed to save chunks to database: %w", err)
+	}
+
+	idxCtx.chunks = chunks
+	if err = s.stepApplySecurityLabels(idxCtx); err != nil {
+		return fmt.Errorf("Apply security labels failed: %w", err)
+	}
+	if err = s.stepVectorizeAndStore(idxCtx); err != nil {
+		if delErr := knowledge.DeleteChunksByIds(ctx, chunkIds); delErr != nil {
+			g.Log().Errorf(ctx, "Failed to delete appended chunks, documentId=%s, err=%v", documentId, delErr)
+		}
+		knowledge.UpdateDocumentsStatus(ctx, documentId, int(v1.StatusActive))
+		return fmt.Errorf("Vectorize and store failed: %w", err)
+	}
+	if err = knowledge.UpdateDocumentsStatus(ctx, documentId, int(v1.StatusActive)); err != nil {
+		return err
+	}
+	g.Log().Infof(ctx, "Chunks appended, documentId=%s, modelID=%s, chunks=%d", documentId, modelID, len(chunks))
+	return nil
+}
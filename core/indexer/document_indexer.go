@@ -3,6 +3,7 @@ package indexer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -14,7 +15,13 @@ import (
 	"github.com/Malowking/kbgo/core/file_store"
 	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/internal/logic/docmeta"
+	"github.com/Malowking/kbgo/internal/logic/ingest"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
 	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/outline"
+	"github.com/Malowking/kbgo/internal/logic/preprocess"
+	"github.com/Malowking/kbgo/internal/logic/security"
 	"github.com/Malowking/kbgo/internal/model/entity"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -22,6 +29,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// metadataSourceBytes 提取文档元数据时最多拼接的文档开头字节数
+const metadataSourceBytes = 32 * 1024
+
 // DocumentIndexer Document indexing service
 type DocumentIndexer struct {
 	Config      *config.IndexerConfig
@@ -147,30 +157,89 @@ func (s *DocumentIndexer) DocumentIndex(ctx context.Context, req *IndexReq) erro
 	}
 
 	// Define Pipeline steps
-	pipeline := []struct {
-		name string
-		fn   func(*indexContext) error
-	}{
-		{"Get document info", s.stepGetDocument},
-		{"Clean old data", s.stepCleanOldData},
-		{"Prepare file", s.stepPrepareFile},
-		{"Parse and split document", s.stepParseDocument},
-		{"Save chunks", s.stepSaveChunks},
-		{"Vectorize and store", s.stepVectorizeAndStore},
-		{"Update status", s.stepUpdateStatus},
-	}
-
-	// Execute Pipeline
+	pipeline := []pipelineStep{
+		{name: "Get document info", fn: s.stepGetDocument},
+		{name: "Clean old data", fn: s.stepCleanOldData},
+		{name: "Prepare file", fn: s.stepPrepareFile},
+		{name: "Parse and split document", fn: s.stepParseDocument},
+		{name: "Extract metadata", fn: s.stepExtractMetadata},
+		{name: "Apply security labels", fn: s.stepApplySecurityLabels},
+		{name: "Save chunks", fn: s.stepSaveChunks},
+		{name: "Build outline", fn: s.stepBuildOutline},
+		{name: "Vectorize and store", fn: s.stepVectorizeAndStore, reset: s.resetVectors},
+		{name: "Update status", fn: s.stepUpdateStatus},
+	}
+
+	// Execute Pipeline, retrying failed steps with exponential backoff
+	policy := ingest.LoadRetryPolicy(ctx)
 	for _, step := range pipeline {
 		g.Log().Debugf(ctx, "Executing step: %s, documentId=%s", step.name, req.DocumentId)
-		if err := step.fn(idxCtx); err != nil {
+		attempts, err := s.runStep(idxCtx, step, policy)
+		if err != nil {
+			// 重试耗尽或不可重试的失败进入死信队列，可通过接口查看原因并重新提交
+			ingest.DeadLetter(ctx, &ingest.Failure{
+				DocumentID:  req.DocumentId,
+				KnowledgeID: idxCtx.doc.KnowledgeId,
+				FileName:    idxCtx.doc.FileName,
+				Step:        step.name,
+				Err:         err,
+				Attempts:    attempts,
+				ModelID:     req.ModelID,
+				ChunkSize:   req.ChunkSize,
+				OverlapSize: req.OverlapSize,
+				Separator:   req.Separator,
+			})
 			return fmt.Errorf("%s failed: %w", step.name, err)
 		}
 	}
 
+	ingest.Resolve(ctx, req.DocumentId)
 	return nil
 }
 
+// pipelineStep Indexing pipeline step
+type pipelineStep struct {
+	name string
+	fn   func(*indexContext) error
+	// reset 重试前清理本步骤失败时留下的部分结果（可选），步骤本身可重复执行时为空
+	reset func(*indexContext) error
+}
+
+// runStep 执行步骤，失败且可重试时按退避策略重试，返回尝试次数
+func (s *DocumentIndexer) runStep(idxCtx *indexContext, step pipelineStep, policy ingest.RetryPolicy) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := step.fn(idxCtx)
+		if err == nil || attempt >= policy.MaxAttempts || isPermanent(err) {
+			return attempt, err
+		}
+		g.Log().Warningf(idxCtx.ctx, "Step %s failed (attempt %d/%d), retrying in %s, documentId=%s, err=%v",
+			step.name, attempt, policy.MaxAttempts, policy.Delay(attempt), idxCtx.documentId, err)
+		if waitErr := policy.Wait(idxCtx.ctx, attempt); waitErr != nil {
+			return attempt, err
+		}
+		if step.reset != nil {
+			if resetErr := step.reset(idxCtx); resetErr != nil {
+				g.Log().Errorf(idxCtx.ctx, "Failed to reset step %s before retry, documentId=%s, err=%v", step.name, idxCtx.documentId, resetErr)
+				return attempt, err
+			}
+		}
+	}
+}
+
+// isPermanent 是否为重试也无法成功的错误（如文件不存在、内容无法解析、模型配置错误）
+func isPermanent(err error) bool {
+	var permanentErr *permanentError
+	return errors.As(err, &permanentErr)
+}
+
+// resetVectors 删除向量化失败时已写入的部分向量，避免重试后重复写入
+func (s *DocumentIndexer) resetVectors(idxCtx *indexContext) error {
+	if idxCtx.collectionName == "" {
+		return nil
+	}
+	return s.VectorStore.DeleteByDocumentID(idxCtx.ctx, idxCtx.collectionName, idxCtx.documentId)
+}
+
 // stepGetDocument Step 1: Get document information
 func (s *DocumentIndexer) stepGetDocument(idxCtx *indexContext) error {
 	doc, err := knowledge.GetDocumentById(idxCtx.ctx, idxCtx.documentId)
@@ -223,7 +292,7 @@ func (s *DocumentIndexer) stepPrepareFile(idxCtx *indexContext) error {
 	} else {
 		// Local storage: Directly use the local_file_path stored in database (relative path)
 		if idxCtx.doc.LocalFilePath == "" {
-			err := fmt.Errorf("Local file path is empty, documentId=%s", idxCtx.documentId)
+			err := permanent(fmt.Errorf("Local file path is empty, documentId=%s", idxCtx.documentId))
 			g.Log().Errorf(idxCtx.ctx, "Local file path is empty, documentId=%s", idxCtx.documentId)
 			knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
 			return err
@@ -233,7 +302,7 @@ func (s *DocumentIndexer) stepPrepareFile(idxCtx *indexContext) error {
 
 	// Check if file exists
 	if idxCtx.localFilePath == "" || !fileExists(idxCtx.localFilePath) {
-		err := fmt.Errorf("File does not exist, path=%s", idxCtx.localFilePath)
+		err := permanent(fmt.Errorf("File does not exist, path=%s", idxCtx.localFilePath))
 		g.Log().Errorf(idxCtx.ctx, "File does not exist, documentId=%s, path=%s", idxCtx.documentId, idxCtx.localFilePath)
 		knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
 		return err
@@ -242,31 +311,49 @@ func (s *DocumentIndexer) stepPrepareFile(idxCtx *indexContext) error {
 	return nil
 }
 
-// stepParseDocument Step 4: Parse and split document using file_parse service
+// stepParseDocument Step 4: Parse and split document using the configured parser backends
 func (s *DocumentIndexer) stepParseDocument(idxCtx *indexContext) error {
-	// Create file_parse loader
-	fileParseLoader, err := NewFileParseLoader(idxCtx.ctx, idxCtx.chunkSize, idxCtx.overlapSize, idxCtx.separator)
+	// 配置了字段映射的 CSV / JSON Lines 文件按行生成分片，不经过解析器切分
+	if idxCtx.doc.FieldMapping != "" {
+		return s.loadStructured(idxCtx)
+	}
+
+	// Create document parser (file type routing with automatic fallback)
+	parser, err := NewDocumentParser(idxCtx.ctx, idxCtx.chunkSize, idxCtx.overlapSize, idxCtx.separator)
 	if err != nil {
-		g.Log().Errorf(idxCtx.ctx, "Failed to create file_parse loader, documentId=%s, err=%v", idxCtx.documentId, err)
+		g.Log().Errorf(idxCtx.ctx, "Failed to create document parser, documentId=%s, err=%v", idxCtx.documentId, err)
 		// 不修改数据库状态，直接返回错误
 		return err
 	}
 
-	// Load and parse document using file_parse service
-	chunks, err := fileParseLoader.Load(idxCtx.ctx, idxCtx.localFilePath)
+	// 知识库配置了预处理钩子时，解析器在切分前执行
+	parseCtx := idxCtx.ctx
+	pipeline, err := preprocess.Load(idxCtx.ctx, idxCtx.doc.KnowledgeId)
 	if err != nil {
-		g.Log().Errorf(idxCtx.ctx, "Failed to parse document with file_parse service, documentId=%s, err=%v", idxCtx.documentId, err)
+		g.Log().Errorf(idxCtx.ctx, "Failed to load ingest hooks, documentId=%s, err=%v", idxCtx.documentId, err)
+		return err
+	}
+	if pipeline != nil {
+		g.Log().Infof(idxCtx.ctx, "Applying ingest hooks version %d, documentId=%s", pipeline.Version, idxCtx.documentId)
+		parseCtx = WithTextProcessor(parseCtx, pipeline)
+	}
+
+	// Load and parse document
+	chunks, err := parser.Load(parseCtx, idxCtx.localFilePath)
+	if err != nil {
+		g.Log().Errorf(idxCtx.ctx, "Failed to parse document, documentId=%s, err=%v", idxCtx.documentId, err)
 		errMsg := err.Error()
-		// 检查是否是 file_parse 服务未启动或超时的错误
-		if strings.Contains(errMsg, "file_parse server is not running") ||
+		// 检查是否是解析服务全部不可用或超时的错误
+		if errors.Is(err, ErrParserUnavailable) ||
+			strings.Contains(errMsg, "file_parse server is not running") ||
 			strings.Contains(errMsg, "timeout") ||
 			strings.Contains(errMsg, "unreachable") {
 			// 对于服务未启动或超时错误，不修改状态，直接返回
 			return fmt.Errorf("file_parse service unavailable: %w", err)
 		}
-		// 其他错误（如文件解析失败），标记为失败
+		// 其他错误（如文件解析失败），标记为失败，不再重试
 		knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
-		return err
+		return permanent(err)
 	}
 
 	idxCtx.chunks = chunks
@@ -274,6 +361,72 @@ func (s *DocumentIndexer) stepParseDocument(idxCtx *indexContext) error {
 	return nil
 }
 
+// loadStructured 按字段映射加载结构化数据文件，映射或文件内容有误时标记为失败，不再重试
+func (s *DocumentIndexer) loadStructured(idxCtx *indexContext) error {
+	mapping, err := ParseFieldMapping(idxCtx.doc.FieldMapping)
+	if err == nil {
+		idxCtx.chunks, err = LoadStructured(idxCtx.ctx, idxCtx.localFilePath, mapping)
+	}
+	if err != nil {
+		g.Log().Errorf(idxCtx.ctx, "Failed to load structured document, documentId=%s, err=%v", idxCtx.documentId, err)
+		knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
+		return permanent(err)
+	}
+	g.Log().Infof(idxCtx.ctx, "Structured document loaded, documentId=%s, rows=%d", idxCtx.documentId, len(idxCtx.chunks))
+	return nil
+}
+
+// stepExtractMetadata 调用 LLM 提取文档元数据（标题、作者、日期、主题、摘要），保存到文档记录并写入分片元数据
+// 未启用 metadataExtraction 时跳过，模型调用失败只记录告警，不影响索引
+func (s *DocumentIndexer) stepExtractMetadata(idxCtx *indexContext) error {
+	if len(idxCtx.chunks) == 0 || !docmeta.Enabled(idxCtx.ctx) {
+		return nil
+	}
+
+	// 元数据从文档开头提取，超长部分由 Extract 截断
+	var builder strings.Builder
+	for _, chunk := range idxCtx.chunks {
+		if builder.Len() >= metadataSourceBytes {
+			break
+		}
+		builder.WriteString(chunk.Content)
+		builder.WriteString("\n")
+	}
+	metadata, err := docmeta.Extract(idxCtx.ctx, idxCtx.doc.FileName, builder.String())
+	if err != nil {
+		g.Log().Warningf(idxCtx.ctx, "Failed to extract document metadata, skipping, documentId=%s, err=%v", idxCtx.documentId, err)
+		return nil
+	}
+	if err = knowledge.UpdateDocumentMetadata(idxCtx.ctx, idxCtx.documentId, metadata); err != nil {
+		g.Log().Errorf(idxCtx.ctx, "Failed to save document metadata, documentId=%s, err=%v", idxCtx.documentId, err)
+		return err
+	}
+	if metadata.IsEmpty() {
+		return nil
+	}
+	chunkMetadata := docmeta.ChunkMetadata(metadata)
+	for _, chunk := range idxCtx.chunks {
+		if chunk.MetaData == nil {
+			chunk.MetaData = make(map[string]any)
+		}
+		chunk.MetaData[docmeta.MetadataKey] = chunkMetadata
+	}
+	g.Log().Infof(idxCtx.ctx, "Document metadata extracted, documentId=%s, title=%q, author=%q, date=%s, topics=%v",
+		idxCtx.documentId, metadata.Title, metadata.Author, metadata.Date, metadata.Topics)
+	return nil
+}
+
+// stepApplySecurityLabels Write document/section security labels into chunk metadata
+func (s *DocumentIndexer) stepApplySecurityLabels(idxCtx *indexContext) error {
+	sections, err := security.ParseSectionLabels(idxCtx.doc.SectionLabels)
+	if err != nil {
+		// 规则在上传时已校验，这里解析失败只记录告警，按文档标签处理
+		g.Log().Warningf(idxCtx.ctx, "Failed to parse section labels, documentId=%s, err=%v", idxCtx.documentId, err)
+	}
+	security.ApplyLabels(idxCtx.ctx, idxCtx.chunks, idxCtx.doc.SecurityLabel, sections)
+	return nil
+}
+
 // stepSaveChunks Step 5: Save chunks to database
 func (s *DocumentIndexer) stepSaveChunks(idxCtx *indexContext) error {
 	if len(idxCtx.chunks) == 0 {
@@ -284,13 +437,18 @@ func (s *DocumentIndexer) stepSaveChunks(idxCtx *indexContext) error {
 	for i, chunk := range idxCtx.chunks {
 		chunkId := uuid.New().String()
 
-		// 从 metadata 中提取 chunk_index，存储到 ext 字段
+		// 从 metadata 中提取 chunk_index 和安全标签，存储到 ext 字段（按文档读取分片时据此过滤无权访问的分片）
 		var extData string
+		ext := make(map[string]interface{})
 		if chunkIndex, ok := chunk.MetaData["chunk_index"].(int); ok {
-			// 将 chunk_index 转换为 JSON 字符串存储
-			extJSON, err := json.Marshal(map[string]interface{}{
-				"chunk_index": chunkIndex,
-			})
+			ext["chunk_index"] = chunkIndex
+		}
+		if label, ok := chunk.MetaData[security.MetadataKey].(string); ok && label != "" {
+			ext[security.MetadataKey] = label
+		}
+		if len(ext) > 0 {
+			// 转换为 JSON 字符串存储
+			extJSON, err := json.Marshal(ext)
 			if err == nil {
 				extData = string(extJSON)
 			}
@@ -317,12 +475,26 @@ func (s *DocumentIndexer) stepSaveChunks(idxCtx *indexContext) error {
 	return nil
 }
 
+// stepBuildOutline Build the heading outline from chunks so sections can be read by heading path
+func (s *DocumentIndexer) stepBuildOutline(idxCtx *indexContext) error {
+	texts := make([]string, 0, len(idxCtx.chunks))
+	for _, chunk := range idxCtx.chunks {
+		texts = append(texts, chunk.Content)
+	}
+	toc := outline.Marshal(outline.Build(outline.JoinChunks(texts)))
+	// 目录只用于按章节读取，保存失败不影响索引
+	if err := knowledge.UpdateDocumentToc(idxCtx.ctx, idxCtx.documentId, toc); err != nil {
+		g.Log().Warningf(idxCtx.ctx, "Failed to save document outline, documentId=%s, err=%v", idxCtx.documentId, err)
+	}
+	return nil
+}
+
 // stepVectorizeAndStore Step 7: Vectorize and store
 func (s *DocumentIndexer) stepVectorizeAndStore(idxCtx *indexContext) error {
 	// 从 Registry 获取 embedding 模型信息
 	modelConfig := model.Registry.Get(idxCtx.modelID)
 	if modelConfig == nil {
-		err := fmt.Errorf("embedding model not found in registry: %s", idxCtx.modelID)
+		err := permanent(fmt.Errorf("embedding model not found in registry: %s", idxCtx.modelID))
 		g.Log().Errorf(idxCtx.ctx, "Failed to get embedding model, documentId=%s, modelID=%s", idxCtx.documentId, idxCtx.modelID)
 		knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
 		return err
@@ -330,7 +502,7 @@ func (s *DocumentIndexer) stepVectorizeAndStore(idxCtx *indexContext) error {
 
 	// 验证模型类型
 	if modelConfig.Type != model.ModelTypeEmbedding {
-		err := fmt.Errorf("model %s is not an embedding model, got type: %s", idxCtx.modelID, modelConfig.Type)
+		err := permanent(fmt.Errorf("model %s is not an embedding model, got type: %s", idxCtx.modelID, modelConfig.Type))
 		g.Log().Errorf(idxCtx.ctx, "Invalid model type, documentId=%s, modelID=%s, type=%s",
 			idxCtx.documentId, idxCtx.modelID, modelConfig.Type)
 		knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
@@ -359,6 +531,26 @@ func (s *DocumentIndexer) stepVectorizeAndStore(idxCtx *indexContext) error {
 		return fmt.Errorf("Failed to create vector embedder: %w", err)
 	}
 
+	// 知识库配置了稀疏模型时，同时写入稀疏向量
+	if idxCtx.doc.KnowledgeId != "" {
+		kb, err := knowledge.GetKnowledgeBaseById(idxCtx.ctx, idxCtx.doc.KnowledgeId)
+		if err != nil {
+			g.Log().Errorf(idxCtx.ctx, "Failed to get knowledge base, documentId=%s, err=%v", idxCtx.documentId, err)
+			knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
+			return err
+		}
+		sparseEmbedder, _, err := knowledge.NewKBSparseEmbedder(idxCtx.ctx, kb)
+		if err != nil {
+			g.Log().Errorf(idxCtx.ctx, "Failed to create sparse embedder, documentId=%s, err=%v", idxCtx.documentId, err)
+			knowledge.UpdateDocumentsStatus(idxCtx.ctx, idxCtx.documentId, int(v1.StatusFailed))
+			return err
+		}
+		if sparseEmbedder != nil {
+			embedder.SetSparseEmbedder(sparseEmbedder)
+			g.Log().Infof(idxCtx.ctx, "Using sparse embedding model, documentId=%s, sparseModelID=%s", idxCtx.documentId, kb.SparseModelID)
+		}
+	}
+
 	// Set context, pass necessary information
 	ctx := context.WithValue(idxCtx.ctx, common.DocumentId, idxCtx.documentId)
 	if idxCtx.doc.KnowledgeId != "" {
@@ -376,6 +568,10 @@ func (s *DocumentIndexer) stepVectorizeAndStore(idxCtx *indexContext) error {
 	g.Log().Infof(idxCtx.ctx, "Vectorization completed, documentId=%s, collectionName=%s, chunks count=%d, successfully stored=%d",
 		idxCtx.documentId, idxCtx.collectionName, len(idxCtx.chunks), len(chunkIds))
 
+	// 记录生成向量的模型配置，模型配置变更后据此找出需要重新向量化的文档
+	if err := knowledge.UpdateDocumentEmbedding(idxCtx.ctx, idxCtx.documentId, idxCtx.modelID, modelConfig.Fingerprint()); err != nil {
+		g.Log().Warningf(idxCtx.ctx, "Failed to record embedding model, documentId=%s, err=%v", idxCtx.documentId, err)
+	}
 	return nil
 }
 
@@ -386,6 +582,12 @@ func (s *DocumentIndexer) stepUpdateStatus(idxCtx *indexContext) error {
 		g.Log().Errorf(idxCtx.ctx, "Failed to update document status, documentId=%s, err=%v", idxCtx.documentId, err)
 		return err
 	}
+
+	// 新版本可检索后再取代旧版本，索引失败时旧版本继续生效
+	if err = knowledge.SupersedePreviousVersions(idxCtx.ctx, idxCtx.documentId); err != nil {
+		g.Log().Warningf(idxCtx.ctx, "Failed to supersede previous versions, documentId=%s, err=%v", idxCtx.documentId, err)
+	}
+	kbevent.Publish(idxCtx.ctx, &kbevent.Event{Type: kbevent.TypeIndexed, KnowledgeID: idxCtx.doc.KnowledgeId, DocumentID: idxCtx.documentId})
 	return nil
 }
 
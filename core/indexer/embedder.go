@@ -2,13 +2,13 @@ package indexer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/core/vector_store"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -20,6 +20,8 @@ type VectorStoreEmbedder struct {
 	vectorStore vector_store.VectorStore
 	modelConfig interface{} // 保存模型配置，用于提取维度信息
 	configDim   int         // 配置文件中的向量维度（fallback）
+
+	sparseEmbedding common.SparseEmbedder // 稀疏向量化实例，为 nil 时只写入稠密向量
 }
 
 // BatchInfo 批次信息
@@ -55,6 +57,11 @@ func NewVectorStoreEmbedder(ctx context.Context, conf common.EmbeddingConfig, ve
 	}, nil
 }
 
+// SetSparseEmbedder 设置稀疏向量化实例，设置后同时写入稀疏向量（集合需包含稀疏向量字段）
+func (v *VectorStoreEmbedder) SetSparseEmbedder(sparseEmbedding common.SparseEmbedder) {
+	v.sparseEmbedding = sparseEmbedding
+}
+
 // EmbedAndStore 嵌入向量并存储（增强版，支持重试和并发）
 func (v *VectorStoreEmbedder) EmbedAndStore(ctx context.Context, collectionName string, chunks []*schema.Document) ([]string, error) {
 	if len(chunks) == 0 {
@@ -103,8 +110,21 @@ func (v *VectorStoreEmbedder) EmbedAndStore(ctx context.Context, collectionName
 				return
 			}
 
-			// 存储到向量数据库
-			chunkIds, err := v.vectorStore.InsertVectors(ctx, collectionName, b.Chunks, vectors)
+			// 存储到向量数据库，配置了稀疏模型时同时生成并写入稀疏向量
+			var chunkIds []string
+			if v.sparseEmbedding != nil {
+				sparseVectors, sparseErr := v.embedSparseWithRetry(ctx, b.Texts, maxRetries, initialDelay, maxDelay, multiplier)
+				if sparseErr != nil {
+					resultChan <- BatchResult{
+						BatchIndex: b.Index,
+						Error:      fmt.Errorf("batch %d sparse embedding failed: %w", b.Index, sparseErr),
+					}
+					return
+				}
+				chunkIds, err = v.vectorStore.InsertHybridVectors(ctx, collectionName, b.Chunks, vectors, sparseVectors)
+			} else {
+				chunkIds, err = v.vectorStore.InsertVectors(ctx, collectionName, b.Chunks, vectors)
+			}
 			if err != nil {
 				resultChan <- BatchResult{
 					BatchIndex: b.Index,
@@ -183,48 +203,19 @@ func (v *VectorStoreEmbedder) createBatches(chunks []*schema.Document, batchSize
 	return batches
 }
 
-// getDimension 获取embedding维度
-// 1. 首先尝试从模型配置的extra字段中解析dimension
-// 2. 如果没有，使用配置文件中的dim作为fallback
+// getDimension 获取embedding维度，与检索时的查询向量使用同一解析规则（见 common.CustomEmbedder.ResolveDimension）：
+// 1. 模型配置的extra字段中的dimension
+// 2. 探测模型实际输出的维度（按模型缓存）
+// 3. 探测失败时使用配置文件中的dim作为fallback
 func (v *VectorStoreEmbedder) getDimension(ctx context.Context) int {
-	// 尝试从模型配置的extra字段中提取dimension
-	if v.modelConfig != nil {
-		// 尝试将modelConfig转换为map类型
-		if configMap, ok := v.modelConfig.(map[string]any); ok {
-			if extra, exists := configMap["Extra"]; exists {
-				if extraMap, ok := extra.(map[string]any); ok {
-					if dim, exists := extraMap["dimension"]; exists {
-						if dimInt, ok := dim.(int); ok {
-							g.Log().Debugf(ctx, "Using dimension from model extra field: %d", dimInt)
-							return dimInt
-						}
-						if dimFloat, ok := dim.(float64); ok {
-							dimInt := int(dimFloat)
-							g.Log().Debugf(ctx, "Using dimension from model extra field: %d", dimInt)
-							return dimInt
-						}
-					}
-				} else if extraStr, ok := extra.(string); ok && extraStr != "" {
-					// 尝试解析字符串形式的JSON
-					var extraMap map[string]any
-					if err := json.Unmarshal([]byte(extraStr), &extraMap); err == nil {
-						if dim, exists := extraMap["dimension"]; exists {
-							if dimFloat, ok := dim.(float64); ok {
-								dimInt := int(dimFloat)
-								g.Log().Debugf(ctx, "Using dimension from model extra JSON: %d", dimInt)
-								return dimInt
-							}
-						}
-					}
-				}
-			}
+	if mc, ok := v.modelConfig.(*model.ModelConfig); ok {
+		if dim := common.ConfiguredDimension(mc); dim > 0 {
+			g.Log().Debugf(ctx, "Using dimension from model extra field: %d", dim)
+			return dim
 		}
 	}
-
-	// Fallback：使用配置文件中的dim
-	if v.configDim > 0 {
-		g.Log().Debugf(ctx, "Using dimension from config file: %d", v.configDim)
-		return v.configDim
+	if dim := v.embedding.ResolveDimension(ctx, v.configDim); dim > 0 {
+		return dim
 	}
 
 	// 默认值
@@ -269,3 +260,33 @@ func (v *VectorStoreEmbedder) embedTextsWithRetry(ctx context.Context, texts []s
 
 	return nil, fmt.Errorf("embedding failed after %d retries, last error: %w", maxRetries, lastErr)
 }
+
+// embedSparseWithRetry 带重试的稀疏向量化
+func (v *VectorStoreEmbedder) embedSparseWithRetry(ctx context.Context, texts []string, maxRetries int, initialDelay, maxDelay time.Duration, multiplier float64) ([]common.SparseVector, error) {
+	var lastErr error
+	delay := initialDelay
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+				delay = time.Duration(float64(delay) * multiplier)
+				if delay > maxDelay {
+					delay = maxDelay
+				}
+			}
+		}
+
+		vectors, err := v.sparseEmbedding.EmbedSparse(ctx, texts)
+		if err != nil {
+			lastErr = err
+			g.Log().Warningf(ctx, "Sparse embedding attempt %d failed: %v", attempt+1, err)
+			continue
+		}
+		return vectors, nil
+	}
+
+	return nil, fmt.Errorf("sparse embedding failed after %d retries, last error: %w", maxRetries, lastErr)
+}
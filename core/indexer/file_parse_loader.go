@@ -80,9 +80,8 @@ func NewFileParseLoader(ctx context.Context, chunkSize, chunkOverlap int, separa
 		chunkOverlap = 200
 	}
 
-	// 使用 gf 的轻量级 HTTP 客户端
-	client := g.Client()
-	client.SetTimeout(time.Duration(timeout) * time.Second)
+	// 使用共享连接池的 HTTP 客户端
+	client := newParserClient(ctx, time.Duration(timeout)*time.Second)
 
 	return &FileParseLoader{
 		ctx:          ctx,
@@ -108,8 +107,19 @@ func NewFileParseLoaderForChat(ctx context.Context, chunkSize, chunkOverlap int,
 	return loader, nil
 }
 
-// CheckHealth 检查 file_parse 服务健康状态
+// Name 解析后端名称
+func (l *FileParseLoader) Name() string { return ParserFileParse }
+
+// Supports file_parse 服务支持所有上传允许的文件类型
+func (l *FileParseLoader) Supports(ext string) bool { return true }
+
+// CheckHealth 检查 file_parse 服务健康状态，熔断期间直接返回失败
 func (l *FileParseLoader) CheckHealth(ctx context.Context) error {
+	return guardFor(ctx, ParserFileParse).CheckHealth(ctx, l.checkHealth)
+}
+
+// checkHealth 请求 file_parse 服务的健康检查接口
+func (l *FileParseLoader) checkHealth(ctx context.Context) error {
 	healthURL := fmt.Sprintf("%s/health", l.fileParseURL)
 
 	resp, err := l.client.Get(ctx, healthURL)
@@ -136,16 +146,10 @@ func (l *FileParseLoader) CheckHealth(ctx context.Context) error {
 	return nil
 }
 
-// Load 加载并解析文档，调用 file_parse 服务
+// Load 加载并解析文档，调用 file_parse 服务（健康检查由 ParserRouter 在调用前完成）
 func (l *FileParseLoader) Load(ctx context.Context, filePath string) ([]*schema.Document, error) {
 	g.Log().Infof(ctx, "Starting to parse file using file_parse service: %s", filePath)
 
-	// 首先检查服务健康状态
-	if err := l.CheckHealth(ctx); err != nil {
-		g.Log().Errorf(ctx, "file_parse server health check failed: %v", err)
-		return nil, fmt.Errorf("file_parse server is not running: %w", err)
-	}
-
 	// 确保文件路径是绝对路径
 	absFilePath := filePath
 	if !filepath.IsAbs(filePath) {
@@ -186,32 +190,37 @@ func (l *FileParseLoader) Load(ctx context.Context, filePath string) ([]*schema.
 	g.Log().Infof(ctx, "Calling file_parse service: %s with params: chunkSize=%d, chunkOverlap=%d, separators=%v",
 		parseURL, parseReq.ChunkSize, parseReq.ChunkOverlap, parseReq.Separators)
 
-	// 使用 gf 的 HTTP 客户端发送 POST 请求
-	resp, err := l.client.ContentJson().Post(ctx, parseURL, parseReq)
-	if err != nil {
-		// 检查是否是超时错误
-		if os.IsTimeout(err) {
-			return nil, fmt.Errorf("file_parse request timeout after %v: %w", time.Since(startTime), err)
+	// 在熔断、排队限流和重试保护下发送 POST 请求
+	var parseResp ParseResponse
+	err := guardFor(ctx, ParserFileParse).Do(ctx, func(ctx context.Context) error {
+		resp, err := l.client.ContentJson().Post(ctx, parseURL, parseReq)
+		if err != nil {
+			// 检查是否是超时错误
+			if os.IsTimeout(err) {
+				return fmt.Errorf("file_parse request timeout after %v: %w", time.Since(startTime), err)
+			}
+			return fmt.Errorf("failed to call file_parse service: %w", err)
 		}
-		return nil, fmt.Errorf("failed to call file_parse service: %w", err)
-	}
-	defer resp.Close()
+		defer resp.Close()
 
-	// 检查 HTTP 状态码
-	if resp.StatusCode != http.StatusOK {
-		body := resp.ReadAllString()
-		g.Log().Errorf(ctx, "file_parse service error response: %s", body)
-		return nil, fmt.Errorf("file_parse service returned error status %d: %s", resp.StatusCode, body)
-	}
-
-	// 解析响应
-	var parseResp ParseResponse
-	if err := json.Unmarshal(resp.ReadAll(), &parseResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal parse response: %w", err)
-	}
+		// 检查 HTTP 状态码
+		if resp.StatusCode != http.StatusOK {
+			body := resp.ReadAllString()
+			g.Log().Errorf(ctx, "file_parse service error response: %s", body)
+			return statusError("file_parse", resp.StatusCode, body)
+		}
 
-	if !parseResp.Success {
-		return nil, fmt.Errorf("file_parse service returned success=false")
+		// 解析响应
+		if err := json.Unmarshal(resp.ReadAll(), &parseResp); err != nil {
+			return permanent(fmt.Errorf("failed to unmarshal parse response: %w", err))
+		}
+		if !parseResp.Success {
+			return permanent(fmt.Errorf("file_parse service returned success=false"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	g.Log().Infof(ctx, "File parsed successfully: %d chunks, %d images (took %v)", parseResp.TotalChunks, parseResp.TotalImages, time.Since(startTime))
@@ -235,6 +244,9 @@ func (l *FileParseLoader) Load(ctx context.Context, filePath string) ([]*schema.
 		}
 	}
 
+	// 服务端已完成切分，预处理钩子作用于各个分片
+	documents = markContentTypes(processChunks(ctx, documents))
+
 	g.Log().Infof(ctx, "Converted %d chunks to documents", len(documents))
 	return documents, nil
 }
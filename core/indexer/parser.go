@@ -0,0 +1,323 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// 解析后端名称
+const (
+	ParserFileParse    = "file_parse"   // Python file_parse 服务
+	ParserNative       = "native"       // Go 原生解析（pdf/docx/纯文本）
+	ParserUnstructured = "unstructured" // Unstructured API
+	ParserMinerU       = "mineru"       // MinerU 服务
+)
+
+// ErrParserUnavailable 所有解析后端均不可用（健康检查失败或超时）
+var ErrParserUnavailable = errors.New("no document parser available")
+
+// DocumentParser 文档解析后端
+type DocumentParser interface {
+	// Name 后端名称
+	Name() string
+	// Supports 是否支持该文件类型（不带点的小写扩展名）
+	Supports(ext string) bool
+	// CheckHealth 检查后端是否可用
+	CheckHealth(ctx context.Context) error
+	// Load 解析并切分文档
+	Load(ctx context.Context, filePath string) ([]*schema.Document, error)
+}
+
+// ParserRouter 按文件类型选择解析后端，主后端出错或超时时按顺序回退到下一个后端
+type ParserRouter struct {
+	parsers  map[string]DocumentParser
+	defaults []string            // 默认后端顺序
+	routes   map[string][]string // 文件扩展名 -> 后端顺序
+	timeout  time.Duration       // 单个后端的总超时（含排队和重试）
+}
+
+// ParserHealth 解析后端健康状态
+type ParserHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewDocumentParser 创建文档解析器，后端顺序和文件类型路由从 fileParse 配置读取
+func NewDocumentParser(ctx context.Context, chunkSize, chunkOverlap int, separator string) (*ParserRouter, error) {
+	fileParseLoader, err := NewFileParseLoader(ctx, chunkSize, chunkOverlap, separator)
+	if err != nil {
+		return nil, err
+	}
+	return newParserRouter(ctx, fileParseLoader), nil
+}
+
+// NewDocumentParserForChat 创建用于文件对话的文档解析器，file_parse 后端返回图片绝对路径
+func NewDocumentParserForChat(ctx context.Context, chunkSize, chunkOverlap int, separator string) (*ParserRouter, error) {
+	fileParseLoader, err := NewFileParseLoaderForChat(ctx, chunkSize, chunkOverlap, separator)
+	if err != nil {
+		return nil, err
+	}
+	return newParserRouter(ctx, fileParseLoader), nil
+}
+
+// newParserRouter 以 file_parse 的切分参数为准创建其他后端
+func newParserRouter(ctx context.Context, fileParseLoader *FileParseLoader) *ParserRouter {
+	chunker := textChunker{
+		chunkSize:    fileParseLoader.chunkSize,
+		chunkOverlap: fileParseLoader.chunkOverlap,
+		separators:   fileParseLoader.separators,
+	}
+	timeout := time.Duration(g.Cfg().MustGet(ctx, "fileParse.timeout", 120).Int()) * time.Second
+	// 单个后端的总超时包含排队和重试时间
+	backendTimeout := time.Duration(g.Cfg().MustGet(ctx, "fileParse.backendTimeout", 600).Int()) * time.Second
+
+	router := &ParserRouter{
+		parsers: map[string]DocumentParser{
+			ParserFileParse:    fileParseLoader,
+			ParserNative:       &NativeParser{chunker: chunker},
+			ParserUnstructured: newUnstructuredParser(ctx, chunker, timeout),
+			ParserMinerU:       newMinerUParser(ctx, chunker, timeout),
+		},
+		defaults: normalizeBackends(g.Cfg().MustGet(ctx, "fileParse.backends", []string{ParserFileParse}).Strings()),
+		routes:   make(map[string][]string),
+		timeout:  backendTimeout,
+	}
+	for ext, backends := range g.Cfg().MustGet(ctx, "fileParse.routes").Map() {
+		router.routes[normalizeExt(ext)] = normalizeBackends(gconv.Strings(backends))
+	}
+	return router
+}
+
+// backendsFor 获取文件类型对应的后端顺序，未配置路由时使用默认顺序
+func (r *ParserRouter) backendsFor(ext string) []string {
+	if backends, ok := r.routes[normalizeExt(ext)]; ok && len(backends) > 0 {
+		return backends
+	}
+	if len(r.defaults) > 0 {
+		return r.defaults
+	}
+	return []string{ParserFileParse}
+}
+
+// Load 按路由顺序尝试各解析后端，返回第一个成功的解析结果
+func (r *ParserRouter) Load(ctx context.Context, filePath string) ([]*schema.Document, error) {
+	ext := strings.TrimPrefix(filepath.Ext(filePath), ".")
+	backends := r.backendsFor(ext)
+
+	var errs []error
+	allUnavailable := true
+	for _, name := range backends {
+		parser, ok := r.parsers[name]
+		if !ok {
+			g.Log().Warningf(ctx, "Unknown document parser backend %q, skipping", name)
+			continue
+		}
+		if !parser.Supports(normalizeExt(ext)) {
+			g.Log().Debugf(ctx, "Parser %s does not support file type %q, skipping", name, ext)
+			continue
+		}
+
+		if err := parser.CheckHealth(ctx); err != nil {
+			g.Log().Warningf(ctx, "Parser %s is unhealthy, falling back: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		docs, err := r.loadWithTimeout(ctx, parser, filePath)
+		if err == nil {
+			g.Log().Infof(ctx, "Document parsed by %s: %s (%d chunks)", name, filePath, len(docs))
+			return docs, nil
+		}
+		if !isUnavailable(err) {
+			allUnavailable = false
+		}
+		g.Log().Warningf(ctx, "Parser %s failed on %s, falling back: %v", name, filePath, err)
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no parser backend supports file type %q (backends: %v)", ext, backends)
+	}
+	if allUnavailable {
+		return nil, fmt.Errorf("%w: %w", ErrParserUnavailable, errors.Join(errs...))
+	}
+	return nil, fmt.Errorf("all parser backends failed: %w", errors.Join(errs...))
+}
+
+// loadWithTimeout 在超时时间内执行解析，超时后放弃该后端
+func (r *ParserRouter) loadWithTimeout(ctx context.Context, parser DocumentParser, filePath string) ([]*schema.Document, error) {
+	if r.timeout <= 0 {
+		return parser.Load(ctx, filePath)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return parser.Load(attemptCtx, filePath)
+}
+
+// CheckHealth 检查所有已配置后端的健康状态
+func (r *ParserRouter) CheckHealth(ctx context.Context) []ParserHealth {
+	seen := make(map[string]bool)
+	names := append([]string{}, r.defaults...)
+	for _, backends := range r.routes {
+		names = append(names, backends...)
+	}
+
+	var result []ParserHealth
+	for _, name := range names {
+		parser, ok := r.parsers[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		health := ParserHealth{Name: name, Healthy: true}
+		if err := parser.CheckHealth(ctx); err != nil {
+			health.Healthy = false
+			health.Error = err.Error()
+		}
+		result = append(result, health)
+	}
+	return result
+}
+
+// isUnavailable 判断是否为服务不可用类错误（超时、熔断、排队已满），而非文件内容问题
+func isUnavailable(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) ||
+		errors.Is(err, ErrParserCircuitOpen) || errors.Is(err, ErrParserBusy) ||
+		strings.Contains(err.Error(), "timeout")
+}
+
+// normalizeExt 统一扩展名格式（小写、不带点）
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+// normalizeBackends 统一后端名称格式
+func normalizeBackends(backends []string) []string {
+	result := make([]string, 0, len(backends))
+	for _, backend := range backends {
+		if backend = strings.ToLower(strings.TrimSpace(backend)); backend != "" {
+			result = append(result, backend)
+		}
+	}
+	return result
+}
+
+// textChunker 将纯文本解析结果切分为分片，供不自带切分能力的后端使用
+type textChunker struct {
+	chunkSize    int
+	chunkOverlap int
+	separators   []string
+}
+
+// toDocuments 切分文本并转换为 schema.Document，上下文中有文本处理器时先处理整篇文本再切分
+func (c textChunker) toDocuments(ctx context.Context, text string) []*schema.Document {
+	var metadata map[string]any
+	if processor := textProcessorFrom(ctx); processor != nil {
+		var segments []string
+		segments, metadata = processor.Process(ctx, []string{text})
+		text = strings.Join(segments, "\n")
+	}
+	chunks := splitText(text, c.chunkSize, c.chunkOverlap, c.separators)
+	documents := make([]*schema.Document, len(chunks))
+	for i, chunk := range chunks {
+		documents[i] = &schema.Document{
+			Content: chunk,
+			MetaData: map[string]interface{}{
+				"chunk_index": i,
+			},
+		}
+		for k, v := range metadata {
+			documents[i].MetaData[k] = v
+		}
+	}
+	return markContentTypes(documents)
+}
+
+// markContentTypes 在包含代码块或公式的分片元数据中记录 content_type，回答时提示模型原样保留格式
+func markContentTypes(documents []*schema.Document) []*schema.Document {
+	for _, doc := range documents {
+		contentType := common.DetectContentType(doc.Content)
+		if contentType == common.ContentTypeText {
+			continue
+		}
+		if doc.MetaData == nil {
+			doc.MetaData = make(map[string]any)
+		}
+		doc.MetaData[common.ContentTypeKey] = contentType
+	}
+	return documents
+}
+
+// TextProcessor 在文档解析之后、切分之前处理文本（知识库索引预处理钩子）。
+// segments 为解析出的文本：本地切分的后端为整篇文本，file_parse 等服务端切分的后端为各个分片；
+// 返回处理后的文本和需要写入每个分片的元数据
+type TextProcessor interface {
+	Process(ctx context.Context, segments []string) ([]string, map[string]any)
+}
+
+type textProcessorKey struct{}
+
+// WithTextProcessor 把文本处理器放入上下文，解析器在切分前调用
+func WithTextProcessor(ctx context.Context, processor TextProcessor) context.Context {
+	return context.WithValue(ctx, textProcessorKey{}, processor)
+}
+
+func textProcessorFrom(ctx context.Context) TextProcessor {
+	processor, _ := ctx.Value(textProcessorKey{}).(TextProcessor)
+	return processor
+}
+
+// PageProgressFunc 接收逐页解析进度的回调，done 为已解析页数，total 为总页数
+type PageProgressFunc func(done, total int)
+
+type pageProgressKey struct{}
+
+// WithPageProgress 把逐页解析进度回调放入上下文，能按页解析的后端（native 解析 PDF）每解析一页调用一次
+func WithPageProgress(ctx context.Context, fn PageProgressFunc) context.Context {
+	return context.WithValue(ctx, pageProgressKey{}, fn)
+}
+
+func reportPageProgress(ctx context.Context, done, total int) {
+	if fn, ok := ctx.Value(pageProgressKey{}).(PageProgressFunc); ok && fn != nil {
+		fn(done, total)
+	}
+}
+
+// processChunks 对服务端已切分的分片执行文本处理器，丢弃处理后为空的分片
+func processChunks(ctx context.Context, documents []*schema.Document) []*schema.Document {
+	processor := textProcessorFrom(ctx)
+	if processor == nil || len(documents) == 0 {
+		return documents
+	}
+	segments := make([]string, len(documents))
+	for i, doc := range documents {
+		segments[i] = doc.Content
+	}
+	segments, metadata := processor.Process(ctx, segments)
+	processed := documents[:0]
+	for i, doc := range documents {
+		if strings.TrimSpace(segments[i]) == "" {
+			continue
+		}
+		doc.Content = segments[i]
+		if doc.MetaData == nil {
+			doc.MetaData = make(map[string]any)
+		}
+		for k, v := range metadata {
+			doc.MetaData[k] = v
+		}
+		processed = append(processed, doc)
+	}
+	return processed
+}
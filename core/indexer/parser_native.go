@@ -0,0 +1,162 @@
+package indexer
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/ledongthuc/pdf"
+)
+
+// NativeParser Go 原生解析后端，不依赖外部服务，仅提取文本（不含图片和表格结构）
+// 适合作为外部解析服务不可用时的兜底后端；扫描件 PDF 无法提取文本时返回错误
+type NativeParser struct {
+	chunker textChunker
+}
+
+// nativeTextExtensions 直接按文本读取的文件类型
+var nativeTextExtensions = map[string]bool{
+	"txt": true, "md": true, "markdown": true, "csv": true, "json": true, "log": true,
+}
+
+// Name 解析后端名称
+func (p *NativeParser) Name() string { return ParserNative }
+
+// Supports 支持 pdf、docx 和纯文本类文件
+func (p *NativeParser) Supports(ext string) bool {
+	return ext == "pdf" || ext == "docx" || nativeTextExtensions[ext]
+}
+
+// CheckHealth 原生解析不依赖外部服务，始终可用
+func (p *NativeParser) CheckHealth(ctx context.Context) error { return nil }
+
+// Load 提取文档文本并切分
+func (p *NativeParser) Load(ctx context.Context, filePath string) ([]*schema.Document, error) {
+	ext := normalizeExt(filepath.Ext(filePath))
+
+	var text string
+	var err error
+	switch {
+	case ext == "pdf":
+		text, err = extractPDFText(ctx, filePath)
+	case ext == "docx":
+		text, err = extractDocxText(filePath)
+	case nativeTextExtensions[ext]:
+		var content []byte
+		content, err = os.ReadFile(filePath)
+		text = string(content)
+	default:
+		return nil, fmt.Errorf("native parser does not support file type %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("native parser extracted no text from %s", filepath.Base(filePath))
+	}
+	return p.chunker.toDocuments(ctx, text), nil
+}
+
+// extractPDFText 按页提取 PDF 文本并上报逐页进度，上下文取消时停止
+func extractPDFText(ctx context.Context, filePath string) (text string, err error) {
+	// pdf 库遇到损坏文件可能 panic，转换为错误以便回退到其他后端
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse pdf: %v", r)
+		}
+	}()
+
+	file, reader, err := pdf.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open pdf: %w", err)
+	}
+	defer file.Close()
+
+	var builder strings.Builder
+	total := reader.NumPage()
+	for i := 1; i <= total; i++ {
+		if err = ctx.Err(); err != nil {
+			return "", err
+		}
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			reportPageProgress(ctx, i, total)
+			continue
+		}
+		pageText, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract text from pdf page %d: %w", i, err)
+		}
+		builder.WriteString(pageText)
+		builder.WriteString("\n\n")
+		reportPageProgress(ctx, i, total)
+	}
+	return builder.String(), nil
+}
+
+// extractDocxText 从 docx 的 word/document.xml 中提取段落文本
+func extractDocxText(filePath string) (string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open docx: %w", err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to read docx content: %w", err)
+		}
+		defer rc.Close()
+		return parseDocxXML(rc)
+	}
+	return "", fmt.Errorf("invalid docx: word/document.xml not found")
+}
+
+// parseDocxXML 解析 WordprocessingML：w:t 为文本，w:p 为段落，w:tab/w:br 为制表符和换行
+func parseDocxXML(r io.Reader) (string, error) {
+	decoder := xml.NewDecoder(r)
+	var builder strings.Builder
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse docx xml: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				builder.WriteString("\t")
+			case "br":
+				builder.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				builder.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if inText {
+				builder.Write(t)
+			}
+		}
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,186 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gclient"
+)
+
+// UnstructuredParser 调用 Unstructured API 解析文档
+type UnstructuredParser struct {
+	url     string
+	apiKey  string
+	client  *gclient.Client
+	chunker textChunker
+}
+
+// unstructuredElement Unstructured API 返回的文档元素
+type unstructuredElement struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func newUnstructuredParser(ctx context.Context, chunker textChunker, timeout time.Duration) *UnstructuredParser {
+	return &UnstructuredParser{
+		url:     strings.TrimRight(g.Cfg().MustGet(ctx, "fileParse.unstructured.url", "").String(), "/"),
+		apiKey:  g.Cfg().MustGet(ctx, "fileParse.unstructured.apiKey", "").String(),
+		client:  newParserClient(ctx, timeout),
+		chunker: chunker,
+	}
+}
+
+// Name 解析后端名称
+func (p *UnstructuredParser) Name() string { return ParserUnstructured }
+
+// Supports Unstructured 支持常见的办公文档格式，具体以服务端为准
+func (p *UnstructuredParser) Supports(ext string) bool { return true }
+
+// CheckHealth 检查 Unstructured API 健康状态
+func (p *UnstructuredParser) CheckHealth(ctx context.Context) error {
+	if p.url == "" {
+		return fmt.Errorf("unstructured parser is not configured (fileParse.unstructured.url)")
+	}
+	return guardFor(ctx, ParserUnstructured).CheckHealth(ctx, func(ctx context.Context) error {
+		return checkHTTPHealth(ctx, p.client, p.url+"/healthcheck")
+	})
+}
+
+// Load 上传文件到 Unstructured API，合并返回元素的文本后切分
+func (p *UnstructuredParser) Load(ctx context.Context, filePath string) ([]*schema.Document, error) {
+	client := p.client
+	if p.apiKey != "" {
+		client = client.Header(map[string]string{"unstructured-api-key": p.apiKey})
+	}
+
+	var elements []unstructuredElement
+	err := guardFor(ctx, ParserUnstructured).Do(ctx, func(ctx context.Context) error {
+		resp, err := client.Post(ctx, p.url+"/general/v0/general", g.Map{"files": "@file:" + filePath})
+		if err != nil {
+			return fmt.Errorf("failed to call unstructured api: %w", err)
+		}
+		defer resp.Close()
+		if resp.StatusCode != http.StatusOK {
+			return statusError("unstructured", resp.StatusCode, resp.ReadAllString())
+		}
+		if err := json.Unmarshal(resp.ReadAll(), &elements); err != nil {
+			return permanent(fmt.Errorf("failed to unmarshal unstructured response: %w", err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	for _, element := range elements {
+		if strings.TrimSpace(element.Text) == "" {
+			continue
+		}
+		builder.WriteString(element.Text)
+		builder.WriteString("\n\n")
+	}
+	if builder.Len() == 0 {
+		return nil, fmt.Errorf("unstructured api extracted no text from %s", filepath.Base(filePath))
+	}
+	return p.chunker.toDocuments(ctx, builder.String()), nil
+}
+
+// MinerUParser 调用 MinerU 服务（mineru-api）将文档解析为 Markdown
+type MinerUParser struct {
+	url     string
+	client  *gclient.Client
+	chunker textChunker
+}
+
+// minerUResponse MinerU /file_parse 接口响应，results 的 key 为文件名（不含扩展名）
+type minerUResponse struct {
+	Results map[string]struct {
+		MdContent string `json:"md_content"`
+	} `json:"results"`
+}
+
+func newMinerUParser(ctx context.Context, chunker textChunker, timeout time.Duration) *MinerUParser {
+	return &MinerUParser{
+		url:     strings.TrimRight(g.Cfg().MustGet(ctx, "fileParse.mineru.url", "").String(), "/"),
+		client:  newParserClient(ctx, timeout),
+		chunker: chunker,
+	}
+}
+
+// Name 解析后端名称
+func (p *MinerUParser) Name() string { return ParserMinerU }
+
+// Supports MinerU 主要用于 PDF 和图片的版面解析
+func (p *MinerUParser) Supports(ext string) bool {
+	switch ext {
+	case "pdf", "png", "jpg", "jpeg":
+		return true
+	}
+	return false
+}
+
+// CheckHealth 检查 MinerU 服务是否可访问（mineru-api 基于 FastAPI，使用 /docs 探活）
+func (p *MinerUParser) CheckHealth(ctx context.Context) error {
+	if p.url == "" {
+		return fmt.Errorf("mineru parser is not configured (fileParse.mineru.url)")
+	}
+	return guardFor(ctx, ParserMinerU).CheckHealth(ctx, func(ctx context.Context) error {
+		return checkHTTPHealth(ctx, p.client, p.url+"/docs")
+	})
+}
+
+// Load 上传文件到 MinerU，获取 Markdown 内容后切分
+func (p *MinerUParser) Load(ctx context.Context, filePath string) ([]*schema.Document, error) {
+	var parseResp minerUResponse
+	err := guardFor(ctx, ParserMinerU).Do(ctx, func(ctx context.Context) error {
+		resp, err := p.client.Post(ctx, p.url+"/file_parse", g.Map{
+			"files":     "@file:" + filePath,
+			"return_md": "true",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to call mineru api: %w", err)
+		}
+		defer resp.Close()
+		if resp.StatusCode != http.StatusOK {
+			return statusError("mineru", resp.StatusCode, resp.ReadAllString())
+		}
+		if err := json.Unmarshal(resp.ReadAll(), &parseResp); err != nil {
+			return permanent(fmt.Errorf("failed to unmarshal mineru response: %w", err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	for _, result := range parseResp.Results {
+		builder.WriteString(result.MdContent)
+		builder.WriteString("\n\n")
+	}
+	if strings.TrimSpace(builder.String()) == "" {
+		return nil, fmt.Errorf("mineru api extracted no text from %s", filepath.Base(filePath))
+	}
+	return p.chunker.toDocuments(ctx, builder.String()), nil
+}
+
+// checkHTTPHealth 请求健康检查地址，返回 200 视为健康
+func checkHTTPHealth(ctx context.Context, client *gclient.Client, healthURL string) error {
+	resp, err := client.Get(ctx, healthURL)
+	if err != nil {
+		return fmt.Errorf("parser server is not running or unreachable: %w", err)
+	}
+	defer resp.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("parser server health check failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
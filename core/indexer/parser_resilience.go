@@ -0,0 +1,239 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gclient"
+)
+
+var (
+	// ErrParserCircuitOpen 解析服务连续失败触发熔断，冷却期内直接失败
+	ErrParserCircuitOpen = errors.New("parser circuit breaker is open")
+	// ErrParserBusy 解析请求排队已满或排队超时
+	ErrParserBusy = errors.New("parser is busy")
+)
+
+var (
+	parserTransportOnce sync.Once
+	parserTransport     *http.Transport
+
+	parserGuardsMu sync.Mutex
+	parserGuards   = make(map[string]*parserGuard)
+)
+
+// newParserClient 创建使用共享连接池的解析服务 HTTP 客户端
+// gclient 默认关闭 keep-alive 且响应头超时为 30 秒，不适合耗时较长的解析请求
+func newParserClient(ctx context.Context, timeout time.Duration) *gclient.Client {
+	parserTransportOnce.Do(func() {
+		maxIdleConns := g.Cfg().MustGet(ctx, "fileParse.maxIdleConns", 20).Int()
+		parserTransport = &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConns:        maxIdleConns,
+			MaxIdleConnsPerHost: maxIdleConns,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+		}
+	})
+
+	client := g.Client()
+	client.Transport = parserTransport
+	client.SetTimeout(timeout)
+	return client
+}
+
+// permanentError 不可重试的错误（如文件内容无法解析、请求参数错误），服务本身正常，不计入熔断
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// permanent 将错误标记为不可重试
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// statusError 根据 HTTP 状态码生成错误：429 和 5xx 可重试，其他 4xx 不可重试
+func statusError(service string, statusCode int, body string) error {
+	err := fmt.Errorf("%s service returned error status %d: %s", service, statusCode, body)
+	if statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError {
+		return err
+	}
+	return permanent(err)
+}
+
+// circuitBreaker 连续失败达到阈值后熔断，冷却期内直接失败
+// 冷却期结束后放行请求（半开），再次失败立即重新熔断，成功则恢复
+type circuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// Allow 判断是否允许请求
+func (b *circuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= b.threshold {
+		if remaining := time.Until(b.openUntil); remaining > 0 {
+			return fmt.Errorf("%w: %s failed %d times in a row, fast failing for another %s",
+				ErrParserCircuitOpen, b.name, b.failures, remaining.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// Success 记录成功，恢复为关闭状态
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// Failure 记录失败，达到阈值时（重新）熔断
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// parseQueue 限制同时发往解析服务的请求数，超出的请求排队等待
+type parseQueue struct {
+	slots       chan struct{}
+	waiting     atomic.Int32
+	maxWaiting  int32
+	waitTimeout time.Duration
+}
+
+// Acquire 获取执行许可，返回释放函数
+func (q *parseQueue) Acquire(ctx context.Context) (func(), error) {
+	if q.waiting.Add(1) > q.maxWaiting {
+		q.waiting.Add(-1)
+		return nil, fmt.Errorf("%w: parse queue is full (%d waiting)", ErrParserBusy, q.maxWaiting)
+	}
+	defer q.waiting.Add(-1)
+
+	timer := time.NewTimer(q.waitTimeout)
+	defer timer.Stop()
+	select {
+	case q.slots <- struct{}{}:
+		return func() { <-q.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: waited %s in parse queue", ErrParserBusy, q.waitTimeout)
+	}
+}
+
+// parserGuard 为单个解析后端提供熔断、排队限流和重试
+type parserGuard struct {
+	name         string
+	breaker      *circuitBreaker
+	queue        *parseQueue
+	maxRetries   int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+}
+
+// guardFor 获取解析后端的 guard，同一后端在进程内共享熔断状态和并发限制
+func guardFor(ctx context.Context, name string) *parserGuard {
+	parserGuardsMu.Lock()
+	defer parserGuardsMu.Unlock()
+	if guard, ok := parserGuards[name]; ok {
+		return guard
+	}
+
+	concurrency := max(g.Cfg().MustGet(ctx, "fileParse.maxConcurrency", 4).Int(), 1)
+	guard := &parserGuard{
+		name: name,
+		breaker: &circuitBreaker{
+			name:      name,
+			threshold: max(g.Cfg().MustGet(ctx, "fileParse.circuitBreaker.failureThreshold", 5).Int(), 1),
+			cooldown:  time.Duration(g.Cfg().MustGet(ctx, "fileParse.circuitBreaker.cooldown", 30).Int()) * time.Second,
+		},
+		queue: &parseQueue{
+			slots:       make(chan struct{}, concurrency),
+			maxWaiting:  int32(g.Cfg().MustGet(ctx, "fileParse.maxQueue", 100).Int()),
+			waitTimeout: time.Duration(g.Cfg().MustGet(ctx, "fileParse.queueTimeout", 300).Int()) * time.Second,
+		},
+		maxRetries:   max(g.Cfg().MustGet(ctx, "fileParse.maxRetries", 3).Int(), 0),
+		initialDelay: 1 * time.Second,
+		maxDelay:     15 * time.Second,
+	}
+	parserGuards[name] = guard
+	return guard
+}
+
+// Do 在熔断、排队和重试保护下执行解析请求
+func (p *parserGuard) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := p.breaker.Allow(); err != nil {
+		return err
+	}
+
+	release, err := p.queue.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	delay := p.initialDelay
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			p.breaker.Success()
+			return nil
+		}
+
+		var permErr *permanentError
+		if errors.As(err, &permErr) {
+			p.breaker.Success()
+			return permErr.err
+		}
+		if attempt >= p.maxRetries || ctx.Err() != nil {
+			p.breaker.Failure()
+			return fmt.Errorf("%s request failed after %d attempts: %w", p.name, attempt+1, err)
+		}
+
+		g.Log().Warningf(ctx, "%s request attempt %d/%d failed, retrying in %v: %v",
+			p.name, attempt+1, p.maxRetries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			p.breaker.Failure()
+			return fmt.Errorf("%s request failed after %d attempts: %w", p.name, attempt+1, ctx.Err())
+		case <-time.After(delay):
+			// 指数退避
+			delay = min(delay*2, p.maxDelay)
+		}
+	}
+}
+
+// CheckHealth 熔断期间直接返回熔断错误，否则执行健康检查；健康检查失败计入熔断
+func (p *parserGuard) CheckHealth(ctx context.Context, check func(ctx context.Context) error) error {
+	if err := p.breaker.Allow(); err != nil {
+		return err
+	}
+	if err := check(ctx); err != nil {
+		p.breaker.Failure()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestGuard(maxRetries, threshold int) *parserGuard {
+	return &parserGuard{
+		name:    "test",
+		breaker: &circuitBreaker{name: "test", threshold: threshold, cooldown: time.Minute},
+		queue: &parseQueue{
+			slots:       make(chan struct{}, 1),
+			maxWaiting:  1,
+			waitTimeout: 50 * time.Millisecond,
+		},
+		maxRetries:   maxRetries,
+		initialDelay: time.Millisecond,
+		maxDelay:     time.Millisecond,
+	}
+}
+
+func TestParserGuardRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error // 每次调用依次返回的错误，超出部分返回 nil
+		wantCalls int
+		wantErr   bool
+		wantOpen  bool
+	}{
+		{
+			name:      "transient error is retried",
+			errs:      []error{errors.New("connection reset"), statusError("file_parse", 503, "")},
+			wantCalls: 3,
+		},
+		{
+			name:      "permanent error is not retried and does not trip breaker",
+			errs:      []error{statusError("file_parse", 400, "bad request")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "exhausted retries trip breaker",
+			errs:      []error{errors.New("e1"), errors.New("e2"), errors.New("e3")},
+			wantCalls: 3,
+			wantErr:   true,
+			wantOpen:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := newTestGuard(2, 1)
+			calls := 0
+			err := guard.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if open := errors.Is(guard.breaker.Allow(), ErrParserCircuitOpen); open != tt.wantOpen {
+				t.Errorf("breaker open = %v, want %v", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := &circuitBreaker{name: "test", threshold: 2, cooldown: time.Minute}
+
+	breaker.Failure()
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("breaker should stay closed below threshold: %v", err)
+	}
+	breaker.Failure()
+	if err := breaker.Allow(); !errors.Is(err, ErrParserCircuitOpen) {
+		t.Fatalf("breaker should open at threshold, got %v", err)
+	}
+
+	// 冷却期结束后半开放行，再次失败立即重新熔断
+	breaker.openUntil = time.Now().Add(-time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("breaker should allow a probe after cooldown: %v", err)
+	}
+	breaker.Failure()
+	if err := breaker.Allow(); !errors.Is(err, ErrParserCircuitOpen) {
+		t.Fatalf("breaker should reopen after a failed probe, got %v", err)
+	}
+
+	breaker.openUntil = time.Now().Add(-time.Second)
+	breaker.Success()
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("breaker should close after success: %v", err)
+	}
+}
+
+func TestParseQueue(t *testing.T) {
+	queue := &parseQueue{
+		slots:       make(chan struct{}, 1),
+		maxWaiting:  1,
+		waitTimeout: 20 * time.Millisecond,
+	}
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 唯一的并发许可被占用时，排队等待超时
+	if _, err := queue.Acquire(context.Background()); !errors.Is(err, ErrParserBusy) {
+		t.Fatalf("expected ErrParserBusy while slot is taken, got %v", err)
+	}
+
+	release()
+	release, err = queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected slot after release, got %v", err)
+	}
+	release()
+}
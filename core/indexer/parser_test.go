@@ -0,0 +1,257 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// fakeParser 测试用解析后端
+type fakeParser struct {
+	name      string
+	healthErr error
+	loadErr   error
+	calls     int
+}
+
+func (p *fakeParser) Name() string                          { return p.name }
+func (p *fakeParser) Supports(ext string) bool              { return ext != "exe" }
+func (p *fakeParser) CheckHealth(ctx context.Context) error { return p.healthErr }
+func (p *fakeParser) Load(ctx context.Context, filePath string) ([]*schema.Document, error) {
+	p.calls++
+	if p.loadErr != nil {
+		return nil, p.loadErr
+	}
+	return []*schema.Document{{Content: p.name}}, nil
+}
+
+func TestParserRouterFallback(t *testing.T) {
+	tests := []struct {
+		name            string
+		primary         *fakeParser
+		secondary       *fakeParser
+		file            string
+		wantParser      string
+		wantUnavailable bool
+	}{
+		{
+			name:       "primary succeeds",
+			primary:    &fakeParser{name: "a"},
+			secondary:  &fakeParser{name: "b"},
+			file:       "doc.pdf",
+			wantParser: "a",
+		},
+		{
+			name:       "unhealthy primary falls back",
+			primary:    &fakeParser{name: "a", healthErr: errors.New("unreachable")},
+			secondary:  &fakeParser{name: "b"},
+			file:       "doc.pdf",
+			wantParser: "b",
+		},
+		{
+			name:       "primary error falls back",
+			primary:    &fakeParser{name: "a", loadErr: errors.New("corrupted file")},
+			secondary:  &fakeParser{name: "b"},
+			file:       "doc.pdf",
+			wantParser: "b",
+		},
+		{
+			name:            "all backends unavailable",
+			primary:         &fakeParser{name: "a", healthErr: errors.New("unreachable")},
+			secondary:       &fakeParser{name: "b", loadErr: context.DeadlineExceeded},
+			file:            "doc.pdf",
+			wantUnavailable: true,
+		},
+		{
+			name:      "all backends fail on content",
+			primary:   &fakeParser{name: "a", loadErr: errors.New("corrupted file")},
+			secondary: &fakeParser{name: "b", healthErr: errors.New("unreachable")},
+			file:      "doc.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &ParserRouter{
+				parsers:  map[string]DocumentParser{"a": tt.primary, "b": tt.secondary},
+				defaults: []string{"a", "b"},
+			}
+			docs, err := router.Load(context.Background(), tt.file)
+			if tt.wantParser == "" {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if got := errors.Is(err, ErrParserUnavailable); got != tt.wantUnavailable {
+					t.Errorf("errors.Is(err, ErrParserUnavailable) = %v, want %v (err: %v)", got, tt.wantUnavailable, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(docs) != 1 || docs[0].Content != tt.wantParser {
+				t.Errorf("parsed by %v, want %s", docs, tt.wantParser)
+			}
+		})
+	}
+}
+
+func TestParserRouterBackendsFor(t *testing.T) {
+	router := &ParserRouter{
+		defaults: []string{ParserFileParse, ParserNative},
+		routes:   map[string][]string{"pdf": {ParserMinerU, ParserFileParse}},
+	}
+	tests := []struct {
+		ext  string
+		want []string
+	}{
+		{ext: "pdf", want: []string{ParserMinerU, ParserFileParse}},
+		{ext: ".PDF", want: []string{ParserMinerU, ParserFileParse}},
+		{ext: "docx", want: []string{ParserFileParse, ParserNative}},
+	}
+	for _, tt := range tests {
+		if got := router.backendsFor(tt.ext); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("backendsFor(%q) = %v, want %v", tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		chunkSize int
+		overlap   int
+		wantCount int
+	}{
+		{name: "no split", text: "短文本", chunkSize: -1, wantCount: 1},
+		{name: "fits in one chunk", text: "第一段。\n\n第二段。", chunkSize: 100, wantCount: 1},
+		{name: "paragraphs split", text: strings.Repeat("这是一个段落。", 10) + "\n\n" + strings.Repeat("另一个段落。", 10), chunkSize: 80, wantCount: 2},
+		{name: "long text without separators", text: strings.Repeat("字", 250), chunkSize: 100, wantCount: 3},
+		{name: "empty text", text: "   ", chunkSize: 100, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitText(tt.text, tt.chunkSize, tt.overlap, nil)
+			if len(chunks) != tt.wantCount {
+				t.Fatalf("splitText() returned %d chunks, want %d: %q", len(chunks), tt.wantCount, chunks)
+			}
+			for _, chunk := range chunks {
+				if tt.chunkSize > 0 && utf8.RuneCountInString(chunk) > tt.chunkSize {
+					t.Errorf("chunk exceeds chunk size %d: %d runes", tt.chunkSize, utf8.RuneCountInString(chunk))
+				}
+			}
+		})
+	}
+}
+
+func TestSplitTextOverlap(t *testing.T) {
+	text := "第一句。第二句。第三句。第四句。第五句。第六句。"
+	chunks := splitText(text, 12, 4, nil)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %q", chunks)
+	}
+	for i := 1; i < len(chunks); i++ {
+		prevTail := []rune(chunks[i-1])
+		if !strings.HasPrefix(chunks[i], string(prevTail[len(prevTail)-4:])) {
+			t.Errorf("chunk %d %q does not overlap with previous chunk %q", i, chunks[i], chunks[i-1])
+		}
+	}
+}
+
+func TestParseDocxXML(t *testing.T) {
+	xmlContent := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>标题</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>第一行</w:t><w:br/><w:t>第二行</w:t><w:tab/><w:t>列</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+	got, err := parseDocxXML(strings.NewReader(xmlContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "标题\n\n第一行\n第二行\t列\n\n"
+	if got != want {
+		t.Errorf("parseDocxXML() = %q, want %q", got, want)
+	}
+}
+
+// upperProcessor 测试用文本处理器：去除首尾空白并转为大写
+type upperProcessor struct{}
+
+func (upperProcessor) Process(ctx context.Context, segments []string) ([]string, map[string]any) {
+	for i, segment := range segments {
+		segments[i] = strings.ToUpper(strings.TrimSpace(segment))
+	}
+	return segments, map[string]any{"processed": true}
+}
+
+func TestTextProcessor(t *testing.T) {
+	ctx := WithTextProcessor(context.Background(), upperProcessor{})
+
+	docs := textChunker{chunkSize: 100}.toDocuments(ctx, "hello world")
+	if len(docs) != 1 || docs[0].Content != "HELLO WORLD" || docs[0].MetaData["processed"] != true {
+		t.Fatalf("toDocuments with processor = %+v", docs)
+	}
+
+	chunks := []*schema.Document{
+		{Content: "first", MetaData: map[string]any{"chunk_index": 0}},
+		{Content: "   ", MetaData: map[string]any{"chunk_index": 1}},
+		{Content: "third", MetaData: map[string]any{"chunk_index": 2}},
+	}
+	chunks = processChunks(ctx, chunks)
+	if len(chunks) != 2 || chunks[0].Content != "FIRST" || chunks[1].Content != "THIRD" {
+		t.Fatalf("processChunks = %+v", chunks)
+	}
+	if chunks[1].MetaData["chunk_index"] != 2 || chunks[1].MetaData["processed"] != true {
+		t.Errorf("processChunks metadata = %v", chunks[1].MetaData)
+	}
+
+	plain := textChunker{chunkSize: 100}.toDocuments(context.Background(), "hello")
+	if plain[0].Content != "hello" {
+		t.Errorf("toDocuments without processor = %q", plain[0].Content)
+	}
+}
+
+func TestSplitTextPreservesBlocks(t *testing.T) {
+	code := "```go\nfunc main() {\n\tfmt.Println(\"hello, world\")\n}\n```"
+	math := "$$\n\\int_0^1 x^2 \\, dx = \\frac{1}{3}\n$$"
+	text := strings.Repeat("说明文字。", 8) + "\n" + code + "\n" + strings.Repeat("推导过程。", 8) + "\n" + math
+	chunks := splitText(text, 60, 0, nil)
+	joined := strings.Join(chunks, "\n")
+	if !strings.Contains(joined, code) || !strings.Contains(joined, math) {
+		t.Fatalf("blocks split across chunks: %q", chunks)
+	}
+
+	// 超长代码块按行拆分，每段都是完整的代码块
+	long := "```python\n" + strings.Repeat("print('line')\n", 10) + "```"
+	chunks = splitText(long, 60, 0, nil)
+	if len(chunks) < 2 {
+		t.Fatalf("expected long code block to be split, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "```python\n") || !strings.HasSuffix(chunk, "```") {
+			t.Errorf("chunk is not a complete code block: %q", chunk)
+		}
+	}
+
+	// 行内公式不从中间断开
+	chunks = splitText("面积公式为 $S = \\pi r^2$ 其中 r 为半径", 12, 0, []string{" ", ""})
+	if joined := strings.Join(chunks, "|"); !strings.Contains(joined, "$S = \\pi r^2$") {
+		t.Errorf("inline math split: %q", chunks)
+	}
+}
+
+func TestMarkContentTypes(t *testing.T) {
+	docs := textChunker{chunkSize: 1000}.toDocuments(context.Background(), "使用 ```\ncode\n``` 示例")
+	if len(docs) != 1 || docs[0].MetaData["content_type"] != "code" {
+		t.Fatalf("toDocuments content_type = %+v", docs)
+	}
+	plain := textChunker{chunkSize: 1000}.toDocuments(context.Background(), "价格为 $5 和 $10")
+	if _, ok := plain[0].MetaData["content_type"]; ok {
+		t.Errorf("plain text marked with content_type: %v", plain[0].MetaData)
+	}
+}
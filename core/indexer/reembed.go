@@ -0,0 +1,59 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ReembedDocument 使用指定 embedding 模型重新向量化文档
+// 直接复用数据库中已保存的分片，不重新解析文件；分片ID保持不变，因此分片状态和对话引用不受影响
+func (s *DocumentIndexer) ReembedDocument(ctx context.Context, documentId string, modelID string) error {
+	idxCtx := &indexContext{
+		ctx:        ctx,
+		modelID:    modelID,
+		documentId: documentId,
+	}
+	if err := s.stepGetDocument(idxCtx); err != nil {
+		return fmt.Errorf("Get document info failed: %w", err)
+	}
+
+	chunks, err := knowledge.GetAllChunksByDocId(ctx, documentId)
+	if err != nil {
+		return fmt.Errorf("Failed to load chunks: %w", err)
+	}
+	idxCtx.chunks = make([]*schema.Document, 0, len(chunks))
+	for _, chunk := range chunks {
+		metaData := map[string]interface{}{}
+		if chunk.Ext != "" {
+			var ext map[string]interface{}
+			if err := json.Unmarshal([]byte(chunk.Ext), &ext); err == nil {
+				if chunkIndex, ok := ext["chunk_index"].(float64); ok {
+					metaData["chunk_index"] = int(chunkIndex)
+				}
+			}
+		}
+		idxCtx.chunks = append(idxCtx.chunks, &schema.Document{
+			ID:       chunk.Id,
+			Content:  chunk.Content,
+			MetaData: metaData,
+		})
+	}
+	if err := s.stepApplySecurityLabels(idxCtx); err != nil {
+		return fmt.Errorf("Apply security labels failed: %w", err)
+	}
+
+	// 分片ID不变，需先删除旧向量再写入新向量
+	if err := s.VectorStore.DeleteByDocumentID(ctx, idxCtx.collectionName, documentId); err != nil {
+		return fmt.Errorf("Failed to delete old vectors: %w", err)
+	}
+	if err := s.stepVectorizeAndStore(idxCtx); err != nil {
+		return fmt.Errorf("Vectorize and store failed: %w", err)
+	}
+	g.Log().Infof(ctx, "Document re-embedded, documentId=%s, modelID=%s, chunks=%d", documentId, modelID, len(idxCtx.chunks))
+	return nil
+}
@@ -0,0 +1,303 @@
+package indexer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 结构化数据分片元数据中的字段
+const (
+	StructuredFieldsKey = "fields"    // 按映射转换类型后的字段值
+	StructuredTitleKey  = "title"     // 标题字段的值
+	StructuredRowKey    = "row_index" // 数据行号（从 1 开始，不含 CSV 表头）
+)
+
+// 字段类型
+const (
+	FieldTypeString = "string"
+	FieldTypeNumber = "number"
+	FieldTypeInt    = "int"
+	FieldTypeBool   = "bool"
+	FieldTypeDate   = "date"
+)
+
+// maxStructuredLineBytes JSON Lines 单行的最大字节数
+const maxStructuredLineBytes = 4 * 1024 * 1024
+
+// FieldMapping 结构化数据（CSV、JSON Lines）的字段映射，每一行生成一个分片，不再切分
+type FieldMapping struct {
+	Content  []string          `json:"content"`  // 拼接为分片内容的字段，多个字段时每行为"字段: 值"
+	Title    string            `json:"title"`    // 标题字段（可选），写入分片元数据，不在 content 中时作为内容第一行
+	Metadata map[string]string `json:"metadata"` // 写入分片元数据的字段及类型：string / number / int / bool / date
+}
+
+// IsStructuredExt 是否为支持字段映射的结构化数据文件
+func IsStructuredExt(ext string) bool {
+	switch normalizeExt(ext) {
+	case "csv", "jsonl", "ndjson":
+		return true
+	}
+	return false
+}
+
+// ParseFieldMapping 解析并校验字段映射 JSON，raw 为空时返回 nil
+func ParseFieldMapping(raw string) (*FieldMapping, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var mapping FieldMapping
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid field mapping: %w", err)
+	}
+	if len(mapping.Content) == 0 {
+		return nil, fmt.Errorf("field mapping must specify at least one content field")
+	}
+	for field, fieldType := range mapping.Metadata {
+		switch fieldType {
+		case FieldTypeString, FieldTypeNumber, FieldTypeInt, FieldTypeBool, FieldTypeDate:
+		case "":
+			mapping.Metadata[field] = FieldTypeString
+		default:
+			return nil, fmt.Errorf("unsupported type %q for metadata field %s", fieldType, field)
+		}
+	}
+	return &mapping, nil
+}
+
+// LoadStructured 按字段映射把 CSV 或 JSON Lines 文件的每一行转换为一个分片，内容为空的行跳过
+func LoadStructured(ctx context.Context, filePath string, mapping *FieldMapping) ([]*schema.Document, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rows []map[string]any
+	switch normalizeExt(filepath.Ext(filePath)) {
+	case "csv":
+		rows, err = readCSVRows(file)
+	case "jsonl", "ndjson":
+		rows, err = readJSONLRows(file)
+	default:
+		return nil, fmt.Errorf("field mapping is only supported for csv and jsonl files: %s", filepath.Base(filePath))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*schema.Document
+	invalid := 0
+	for i, row := range rows {
+		doc, skipped := mapping.toDocument(row)
+		invalid += skipped
+		if doc == nil {
+			continue
+		}
+		doc.MetaData[StructuredRowKey] = i + 1
+		doc.MetaData["chunk_index"] = len(docs)
+		docs = append(docs, doc)
+	}
+	if invalid > 0 {
+		g.Log().Warningf(ctx, "Structured ingestion skipped %d metadata values that do not match the mapped types, file=%s", invalid, filepath.Base(filePath))
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no rows with content found in %s", filepath.Base(filePath))
+	}
+	return docs, nil
+}
+
+// toDocument 把一行数据转换为分片，内容为空时返回 nil；返回值 skipped 为类型不匹配而跳过的元数据字段数
+func (m *FieldMapping) toDocument(row map[string]any) (doc *schema.Document, skipped int) {
+	title := formatValue(lookupField(row, m.Title))
+	var lines []string
+	titleInContent := false
+	for _, field := range m.Content {
+		value := formatValue(lookupField(row, field))
+		if value == "" {
+			continue
+		}
+		if field == m.Title {
+			titleInContent = true
+		}
+		if len(m.Content) == 1 {
+			lines = append(lines, value)
+		} else {
+			lines = append(lines, field+": "+value)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, 0
+	}
+	if title != "" && !titleInContent {
+		lines = append([]string{title}, lines...)
+	}
+
+	metadata := map[string]any{}
+	if title != "" {
+		metadata[StructuredTitleKey] = title
+	}
+	fields := map[string]any{}
+	for field, fieldType := range m.Metadata {
+		raw := lookupField(row, field)
+		if raw == nil || formatValue(raw) == "" {
+			continue
+		}
+		value, ok := convertValue(raw, fieldType)
+		if !ok {
+			skipped++
+			continue
+		}
+		fields[field] = value
+	}
+	if len(fields) > 0 {
+		metadata[StructuredFieldsKey] = fields
+	}
+	return &schema.Document{Content: strings.Join(lines, "\n"), MetaData: metadata}, skipped
+}
+
+// readCSVRows 读取 CSV，第一行为表头
+func readCSVRows(r io.Reader) ([]map[string]any, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	var rows []map[string]any
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(name)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readJSONLRows 读取 JSON Lines，每个非空行为一个 JSON 对象
+func readJSONLRows(r io.Reader) ([]map[string]any, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStructuredLineBytes)
+	var rows []map[string]any
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var row map[string]any
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("invalid json on line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jsonl: %w", err)
+	}
+	return rows, nil
+}
+
+// lookupField 读取字段值，JSON 嵌套字段用点号分隔（如 product.name）
+func lookupField(row map[string]any, field string) any {
+	if field == "" {
+		return nil
+	}
+	if value, ok := row[field]; ok {
+		return value
+	}
+	var current any = row
+	for _, part := range strings.Split(field, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if current, ok = object[part]; !ok {
+			return nil
+		}
+	}
+	return current
+}
+
+// formatValue 把字段值转换为内容文本，对象和数组保留为 JSON
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// convertValue 按映射的类型转换元数据值，无法转换时返回 false
+func convertValue(value any, fieldType string) (any, bool) {
+	text := formatValue(value)
+	switch fieldType {
+	case FieldTypeNumber:
+		if f, ok := value.(float64); ok {
+			return f, true
+		}
+		f, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+		return f, err == nil
+	case FieldTypeInt:
+		if f, ok := value.(float64); ok && f == float64(int64(f)) {
+			return int64(f), true
+		}
+		i, err := strconv.ParseInt(strings.ReplaceAll(text, ",", ""), 10, 64)
+		return i, err == nil
+	case FieldTypeBool:
+		if b, ok := value.(bool); ok {
+			return b, true
+		}
+		switch strings.ToLower(text) {
+		case "true", "yes", "y", "1", "是":
+			return true, true
+		case "false", "no", "n", "0", "否":
+			return false, true
+		}
+		return nil, false
+	case FieldTypeDate:
+		for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "2006/01/02", "2006/1/2"} {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t.Format("2006-01-02"), true
+			}
+		}
+		return nil, false
+	default:
+		return text, true
+	}
+}
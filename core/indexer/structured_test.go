@@ -0,0 +1,112 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFieldMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", raw: "", wantNil: true},
+		{name: "valid", raw: `{"content":["answer"],"title":"question","metadata":{"price":"number","tag":""}}`},
+		{name: "missing content", raw: `{"title":"question"}`, wantErr: true},
+		{name: "unsupported type", raw: `{"content":["a"],"metadata":{"b":"float"}}`, wantErr: true},
+		{name: "invalid json", raw: `{"content":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := ParseFieldMapping(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (mapping == nil) != tt.wantNil {
+				t.Fatalf("mapping = %v, wantNil %v", mapping, tt.wantNil)
+			}
+			if mapping != nil && mapping.Metadata["tag"] != FieldTypeString {
+				t.Errorf("empty type should default to string, got %q", mapping.Metadata["tag"])
+			}
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		value     any
+		fieldType string
+		want      any
+		wantOK    bool
+	}{
+		{"1,299.5", FieldTypeNumber, 1299.5, true},
+		{float64(42), FieldTypeInt, int64(42), true},
+		{"12", FieldTypeInt, int64(12), true},
+		{"1.5", FieldTypeInt, nil, false},
+		{"是", FieldTypeBool, true, true},
+		{"maybe", FieldTypeBool, nil, false},
+		{"2024/3/5", FieldTypeDate, "2024-03-05", true},
+		{"2024-03-05T08:00:00Z", FieldTypeDate, "2024-03-05", true},
+		{"next week", FieldTypeDate, nil, false},
+		{float64(7), FieldTypeString, "7", true},
+	}
+	for _, tt := range tests {
+		got, ok := convertValue(tt.value, tt.fieldType)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("convertValue(%v, %s) = %v, %v, want %v, %v", tt.value, tt.fieldType, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoadStructured(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "faq.csv")
+	csvData := "\ufeffquestion,answer,price\n如何退货,七天内可无理由退货,abc\n,,\n如何开票,在订单页申请,10\n"
+	if err := os.WriteFile(csvPath, []byte(csvData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapping := &FieldMapping{Content: []string{"answer"}, Title: "question", Metadata: map[string]string{"price": FieldTypeInt}}
+	docs, err := LoadStructured(context.Background(), csvPath, mapping)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(docs))
+	}
+	if docs[0].Content != "如何退货\n七天内可无理由退货" || docs[0].MetaData[StructuredTitleKey] != "如何退货" {
+		t.Errorf("unexpected first chunk: %q %v", docs[0].Content, docs[0].MetaData)
+	}
+	if _, ok := docs[0].MetaData[StructuredFieldsKey]; ok {
+		t.Errorf("invalid price should be skipped, got %v", docs[0].MetaData[StructuredFieldsKey])
+	}
+	if docs[1].MetaData[StructuredRowKey] != 3 || docs[1].MetaData["chunk_index"] != 1 {
+		t.Errorf("unexpected row metadata: %v", docs[1].MetaData)
+	}
+	if fields, _ := docs[1].MetaData[StructuredFieldsKey].(map[string]any); fields["price"] != int64(10) {
+		t.Errorf("expected typed price, got %v", docs[1].MetaData[StructuredFieldsKey])
+	}
+
+	jsonlPath := filepath.Join(dir, "catalog.jsonl")
+	jsonlData := `{"name":"键盘","spec":{"color":"黑色"},"in_stock":true}` + "\n\n" + `{"name":"鼠标","spec":{"color":"白色"},"in_stock":"否"}` + "\n"
+	if err := os.WriteFile(jsonlPath, []byte(jsonlData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapping = &FieldMapping{Content: []string{"name", "spec.color"}, Metadata: map[string]string{"in_stock": FieldTypeBool}}
+	docs, err = LoadStructured(context.Background(), jsonlPath, mapping)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 2 || docs[1].Content != "name: 鼠标\nspec.color: 白色" {
+		t.Fatalf("unexpected chunks: %v", docs)
+	}
+	if fields, _ := docs[1].MetaData[StructuredFieldsKey].(map[string]any); fields["in_stock"] != false {
+		t.Errorf("expected in_stock=false, got %v", docs[1].MetaData[StructuredFieldsKey])
+	}
+}
@@ -0,0 +1,220 @@
+package indexer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Malowking/kbgo/core/common"
+)
+
+// defaultSeparators 默认的递归切分分隔符，按优先级从段落到字符
+var defaultSeparators = []string{"\n\n", "\n", "。", "！", "？", ". ", "; ", "，", " ", ""}
+
+// splitText 递归字符切分：优先按高优先级分隔符切分，再合并为不超过 chunkSize（按字符计）的分片
+// 围栏代码块和 $$ 公式块作为整体不被切开，行内代码和行内公式不从中间断开；chunkSize <= 0 表示不切分，整段文本作为一个分片
+func splitText(text string, chunkSize, chunkOverlap int, separators []string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if chunkSize <= 0 {
+		return []string{text}
+	}
+	if chunkOverlap < 0 || chunkOverlap >= chunkSize {
+		chunkOverlap = 0
+	}
+	if len(separators) == 0 {
+		separators = defaultSeparators
+	}
+	return mergePieces(splitBlocks(text, chunkSize, separators), chunkSize, chunkOverlap)
+}
+
+// splitBlocks 按行区分普通文本和代码块、公式块：普通文本递归切分，块整体作为一个片段
+// 超过 chunkSize 的代码块按行拆成多段，每段补上起止标记；公式块拆开后无法渲染，始终保持完整
+func splitBlocks(text string, chunkSize int, separators []string) []string {
+	var pieces []string
+	var prose, block strings.Builder
+	var tracker common.BlockTracker
+	var fence, opener string
+
+	flushProse := func() {
+		if prose.Len() > 0 {
+			pieces = append(pieces, splitPieces(prose.String(), chunkSize, separators)...)
+			prose.Reset()
+		}
+	}
+	flushBlock := func() {
+		if block.Len() > 0 {
+			pieces = append(pieces, splitCodeBlock(block.String(), opener, fence, chunkSize)...)
+			block.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		wasInside := tracker.Inside()
+		if !tracker.Line(line) {
+			prose.WriteString(line)
+			continue
+		}
+		if !wasInside {
+			flushProse()
+			fence, opener = tracker.Fence(), line
+		}
+		block.WriteString(line)
+		if !tracker.Inside() {
+			flushBlock()
+		}
+	}
+	// 未闭合的块延续到文本末尾
+	flushBlock()
+	flushProse()
+	return pieces
+}
+
+// splitCodeBlock 代码块不超过 chunkSize 时保持完整，否则按行拆分，每段以 opener 开头、fence 结尾；
+// fence 为空（公式块）时不拆分，超长的单行也不拆开
+func splitCodeBlock(block, opener, fence string, chunkSize int) []string {
+	if fence == "" || utf8.RuneCountInString(block) <= chunkSize {
+		return []string{block}
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(block, "\n"), "\n")
+	body := lines[1:]
+	if len(body) > 0 {
+		if last := strings.TrimSpace(body[len(body)-1]); last != "" && strings.Trim(last, fence[:1]) == "" {
+			body = body[:len(body)-1]
+		}
+	}
+	opener = strings.TrimRight(opener, "\n") + "\n"
+	closer := fence + "\n"
+	budget := chunkSize - utf8.RuneCountInString(opener) - utf8.RuneCountInString(closer)
+
+	var parts []string
+	var current strings.Builder
+	currentLen := 0
+	for _, line := range body {
+		lineLen := utf8.RuneCountInString(line)
+		if currentLen > 0 && currentLen+lineLen > budget {
+			parts = append(parts, opener+current.String()+closer)
+			current.Reset()
+			currentLen = 0
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		current.WriteString(line)
+		currentLen += lineLen
+	}
+	if currentLen > 0 {
+		parts = append(parts, opener+current.String()+closer)
+	}
+	return parts
+}
+
+// splitPieces 将文本切成不超过 chunkSize 的片段，分隔符保留在片段末尾
+func splitPieces(text string, chunkSize int, separators []string) []string {
+	if utf8.RuneCountInString(text) <= chunkSize {
+		return []string{text}
+	}
+
+	separator, rest := separators[0], separators[1:]
+	if separator == "" {
+		return splitRunesKeepSpans(text, chunkSize)
+	}
+	parts := joinInlineSpans(text, strings.SplitAfter(text, separator))
+
+	var pieces []string
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if utf8.RuneCountInString(part) > chunkSize && len(rest) > 0 {
+			pieces = append(pieces, splitPieces(part, chunkSize, rest)...)
+			continue
+		}
+		if utf8.RuneCountInString(part) > chunkSize {
+			pieces = append(pieces, splitRunesKeepSpans(part, chunkSize)...)
+			continue
+		}
+		pieces = append(pieces, part)
+	}
+	return pieces
+}
+
+// mergePieces 将片段合并为分片，相邻分片保留约 chunkOverlap 个字符的重叠
+func mergePieces(pieces []string, chunkSize, chunkOverlap int) []string {
+	var chunks []string
+	var current []string
+	currentLen := 0
+
+	flush := func() {
+		if chunk := strings.TrimSpace(strings.Join(current, "")); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	for _, piece := range pieces {
+		pieceLen := utf8.RuneCountInString(piece)
+		if currentLen+pieceLen > chunkSize && len(current) > 0 {
+			flush()
+			// 从末尾保留不超过 chunkOverlap 的片段作为下一个分片的开头
+			for currentLen > chunkOverlap || (currentLen+pieceLen > chunkSize && currentLen > 0) {
+				currentLen -= utf8.RuneCountInString(current[0])
+				current = current[1:]
+			}
+		}
+		current = append(current, piece)
+		currentLen += pieceLen
+	}
+	if len(current) > 0 {
+		flush()
+	}
+	return chunks
+}
+
+// joinInlineSpans 合并断点落在行内代码或行内公式内部的相邻片段
+func joinInlineSpans(text string, parts []string) []string {
+	spans := common.InlineSpans(text)
+	if len(spans) == 0 {
+		return parts
+	}
+	inside := func(pos int) bool {
+		for _, span := range spans {
+			if span[0] < pos && pos < span[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	joined := make([]string, 0, len(parts))
+	var current strings.Builder
+	pos := 0
+	for _, part := range parts {
+		current.WriteString(part)
+		pos += len(part)
+		if !inside(pos) {
+			joined = append(joined, current.String())
+			current.Reset()
+		}
+	}
+	if current.Len() > 0 {
+		joined = append(joined, current.String())
+	}
+	return joined
+}
+
+// splitRunesKeepSpans 按固定字符数切分，跨越行内代码或行内公式的片段合并，此时片段可能超过 size
+func splitRunesKeepSpans(text string, size int) []string {
+	return joinInlineSpans(text, splitRunes(text, size))
+}
+
+// splitRunes 按固定字符数切分
+func splitRunes(text string, size int) []string {
+	runes := []rune(text)
+	parts := make([]string, 0, len(runes)/size+1)
+	for start := 0; start < len(runes); start += size {
+		end := min(start+size, len(runes))
+		parts = append(parts, string(runes[start:end]))
+	}
+	return parts
+}
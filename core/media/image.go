@@ -0,0 +1,190 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	defaultMaxImageSide   = 2048
+	defaultJPEGQuality    = 90
+	defaultConvertTimeout = 30 * time.Second
+)
+
+// converters 未配置 multimodal.imageConverter 时按顺序查找的转换工具，{input}/{output} 为输入输出文件路径
+var converters = [][]string{
+	{"magick", "{input}", "-auto-orient", "{output}"},
+	{"convert", "{input}", "-auto-orient", "{output}"},
+	{"heif-convert", "{input}", "{output}"},
+	{"ffmpeg", "-y", "-loglevel", "error", "-i", "{input}", "-frames:v", "1", "{output}"},
+}
+
+// NeedsConversion 是否为大多数视觉模型接口不支持、需要先转换为 JPEG 的图片格式
+func NeedsConversion(mimeType string) bool {
+	switch mimeType {
+	case "image/heic", "image/heif", "image/avif":
+		return true
+	}
+	return false
+}
+
+// LoadImage 读取图片文件，校验内容与扩展名一致，并转换为视觉模型可以接受的格式和尺寸
+func LoadImage(ctx context.Context, path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image file: %w", err)
+	}
+	mimeType, err := Check(filepath.Ext(path), data)
+	if err != nil {
+		return nil, "", err
+	}
+	if Class(mimeType) != "image" {
+		return nil, "", fmt.Errorf("file %s is %s, not an image", filepath.Base(path), mimeType)
+	}
+	return PrepareImage(ctx, data, mimeType)
+}
+
+// PrepareImage HEIC/HEIF/AVIF 转换为 JPEG，最长边超过 multimodal.maxImageSide 的图片等比缩小
+// 缩小失败时沿用原图，转换失败时返回错误（原格式会被模型接口拒绝）
+func PrepareImage(ctx context.Context, data []byte, mimeType string) ([]byte, string, error) {
+	if NeedsConversion(mimeType) {
+		converted, err := convertToJPEG(ctx, data, mimeType)
+		if err != nil {
+			return nil, "", err
+		}
+		data, mimeType = converted, "image/jpeg"
+	}
+
+	maxSide := g.Cfg().MustGet(ctx, "multimodal.maxImageSide", defaultMaxImageSide).Int()
+	if maxSide <= 0 {
+		return data, mimeType, nil
+	}
+	resized, resizedType, err := downscale(data, maxSide)
+	if err != nil {
+		g.Log().Warningf(ctx, "缩小图片失败，使用原图: %v", err)
+		return data, mimeType, nil
+	}
+	if resized == nil {
+		return data, mimeType, nil
+	}
+	return resized, resizedType, nil
+}
+
+// ImageDataURI 读取并处理图片，返回 data:<mime>;base64,<data> 形式的 URI
+func ImageDataURI(ctx context.Context, path string) (string, error) {
+	data, mimeType, err := LoadImage(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return DataURI(mimeType, data), nil
+}
+
+// FileDataURI 读取音频、视频等文件，校验内容与扩展名一致后返回 data URI
+func FileDataURI(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	mimeType, err := Check(filepath.Ext(path), data)
+	if err != nil {
+		return "", err
+	}
+	return DataURI(mimeType, data), nil
+}
+
+// DataURI 构建 base64 编码的 data URI
+func DataURI(mimeType string, data []byte) string {
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+}
+
+// downscale 解码 JPEG/PNG/GIF 并把最长边缩小到 maxSide，JPEG 编码为 JPEG，其他编码为 PNG
+// 不需要缩小或格式无法解码（如 webp、svg）时返回 nil
+func downscale(data []byte, maxSide int) ([]byte, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", nil
+	}
+	dstW, dstH, crop, ok := TargetSize(config.Width, config.Height, maxSide, maxSide, FitContain)
+	if !ok {
+		return nil, "", nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	dst := Resize(src, crop, dstW, dstH)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: defaultJPEGQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err = png.Encode(&buf, dst); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// convertToJPEG 调用外部工具把 HEIC/AVIF 转换为 JPEG
+// 优先使用 multimodal.imageConverter 配置的命令，否则依次查找 ImageMagick、libheif、ffmpeg
+func convertToJPEG(ctx context.Context, data []byte, mimeType string) ([]byte, error) {
+	command, err := converterCommand(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %s to JPEG: %w", mimeType, err)
+	}
+
+	dir, err := os.MkdirTemp("", "kbgo-image-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "input."+strings.TrimPrefix(mimeType, "image/"))
+	output := filepath.Join(dir, "output.jpg")
+	if err = os.WriteFile(input, data, 0600); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = strings.NewReplacer("{input}", input, "{output}", output).Replace(arg)
+	}
+	timeout := g.Cfg().MustGet(ctx, "multimodal.convertTimeout", defaultConvertTimeout).Duration()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to convert %s to JPEG with %s: %w: %s", mimeType, args[0], err, strings.TrimSpace(string(out)))
+	}
+	converted, err := os.ReadFile(output)
+	if err != nil {
+		return nil, fmt.Errorf("image converter %s produced no output: %w", args[0], err)
+	}
+	return converted, nil
+}
+
+// converterCommand 返回转换命令，配置的命令按空白拆分参数
+func converterCommand(ctx context.Context) ([]string, error) {
+	if configured := strings.Fields(g.Cfg().MustGet(ctx, "multimodal.imageConverter", "").String()); len(configured) > 0 {
+		return configured, nil
+	}
+	for _, command := range converters {
+		if _, err := exec.LookPath(command[0]); err == nil {
+			return command, nil
+		}
+	}
+	return nil, fmt.Errorf("no image converter found, install ImageMagick or libheif, or set multimodal.imageConverter")
+}
@@ -0,0 +1,155 @@
+// Package media 识别上传文件的实际类型，并在构建多模态消息前把图片转换为视觉模型普遍支持的格式和尺寸
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SniffLen 识别文件类型需要读取的文件头长度
+const SniffLen = 512
+
+// extensionTypes 扩展名对应的 MIME 类型
+var extensionTypes = map[string]string{
+	// 图片
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+	".ico":  "image/x-icon",
+	".tiff": "image/tiff",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".avif": "image/avif",
+
+	// 音频
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".m4a":  "audio/mp4",
+	".wma":  "audio/x-ms-wma",
+
+	// 视频
+	".mp4":  "video/mp4",
+	".avi":  "video/x-msvideo",
+	".mkv":  "video/x-matroska",
+	".mov":  "video/quicktime",
+	".wmv":  "video/x-ms-wmv",
+	".flv":  "video/x-flv",
+	".webm": "video/webm",
+	".m4v":  "video/mp4",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+}
+
+// ExtensionType 根据扩展名返回 MIME 类型，未知扩展名返回 application/octet-stream
+func ExtensionType(ext string) string {
+	if mimeType, ok := extensionTypes[strings.ToLower(ext)]; ok {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// ftypBrands ISO BMFF 容器（HEIC/AVIF/MP4/MOV）ftyp 盒中的主品牌对应的类型
+var ftypBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic",
+	"hevx": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
+	"avif": "image/avif",
+	"avis": "image/avif",
+	"M4A ": "audio/mp4",
+	"M4B ": "audio/mp4",
+	"qt  ": "video/quicktime",
+	"M4V ": "video/mp4",
+}
+
+// Detect 根据文件内容识别 MIME 类型，无法识别时返回空字符串
+func Detect(data []byte) string {
+	if len(data) > SniffLen {
+		data = data[:SniffLen]
+	}
+	// ISO BMFF：第 4-8 字节为 "ftyp"，8-12 字节为主品牌
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		if mimeType, ok := ftypBrands[string(data[8:12])]; ok {
+			return mimeType
+		}
+		return "video/mp4"
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(data, []byte("\x1a\x45\xdf\xa3")):
+		// Matroska 与 WebM 使用相同的 EBML 头
+		if bytes.Contains(data, []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case bytes.HasPrefix(data, []byte("FLV")):
+		return "video/x-flv"
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(data, []byte("\x30\x26\xb2\x75\x8e\x66\xcf\x11")):
+		// ASF 容器，扩展名区分 wma/wmv
+		return "video/x-ms-asf"
+	case len(data) >= 2 && data[0] == 0xff && data[1]&0xf6 == 0xf0:
+		return "audio/aac"
+	}
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("<svg")) ||
+		(bytes.HasPrefix(trimmed, []byte("<?xml")) && bytes.Contains(trimmed, []byte("<svg"))) {
+		return "image/svg+xml"
+	}
+
+	mimeType := http.DetectContentType(data)
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	switch mimeType {
+	case "application/octet-stream", "text/plain":
+		return ""
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	case "video/avi":
+		return "video/x-msvideo"
+	}
+	return mimeType
+}
+
+// Check 校验文件内容与扩展名声明的类型是否一致，返回实际类型
+// 同一大类（如 .png 实际为 JPEG）以实际类型为准；大类不同（如 .jpg 实际为 HTML 或可执行文件）时返回错误；
+// 无法从内容识别的格式（如 wma、ape）沿用扩展名类型
+func Check(ext string, data []byte) (string, error) {
+	declared := ExtensionType(ext)
+	detected := Detect(data)
+	switch {
+	case detected == "":
+		return declared, nil
+	case declared == "application/octet-stream":
+		return detected, nil
+	case detected == "video/x-ms-asf" && (declared == "audio/x-ms-wma" || declared == "video/x-ms-wmv"):
+		return declared, nil
+	case detected == "video/mp4" && Class(declared) == "audio":
+		// MP4 容器中的音频（如 .m4a）
+		return declared, nil
+	case Class(detected) == Class(declared):
+		return detected, nil
+	}
+	return "", fmt.Errorf("file content is %s but the extension %s declares %s", detected, ext, declared)
+}
+
+// Class 返回 MIME 类型的大类，如 image、audio、video
+func Class(mimeType string) string {
+	class, _, _ := strings.Cut(mimeType, "/")
+	return class
+}
@@ -0,0 +1,149 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), "image/heic"},
+		{"avif", []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1"), "image/avif"},
+		{"mp4", []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00isomiso2"), "video/mp4"},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00M4A mp42"), "audio/mp4"},
+		{"svg", []byte("<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), "image/svg+xml"},
+		{"html", []byte("<!DOCTYPE html><html><body>hi</body></html>"), "text/html"},
+		{"unknown", []byte{0x01, 0x02, 0x03}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.data); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	tests := []struct {
+		name    string
+		ext     string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"matching", ".jpg", jpeg, "image/jpeg", false},
+		{"same class uses content type", ".png", jpeg, "image/jpeg", false},
+		{"heic named jpg", ".JPG", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), "image/heic", false},
+		{"html named png", ".png", []byte("<html><script>alert(1)</script></html>"), "", true},
+		{"video named image", ".jpg", []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00isomiso2"), "", true},
+		{"m4a audio in mp4 container", ".m4a", []byte("\x00\x00\x00\x20ftypmp42\x00\x00\x00\x00mp42isom"), "audio/mp4", false},
+		{"undetectable keeps extension type", ".wma", []byte{0x01, 0x02}, "audio/x-ms-wma", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Check(tt.ext, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 100))); err != nil {
+		t.Fatal(err)
+	}
+	data, mimeType, err := downscale(buf.Bytes(), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || mimeType != "image/png" || config.Width != 200 || config.Height != 50 {
+		t.Errorf("downscale() = %dx%d %s, %v, want 200x50 image/png", config.Width, config.Height, mimeType, err)
+	}
+	if data, _, _ = downscale(buf.Bytes(), 1000); data != nil {
+		t.Error("images within the limit should not be re-encoded")
+	}
+}
+
+func TestTargetSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		srcW, srcH    int
+		width, height int
+		fit           string
+		wantW, wantH  int
+		wantCrop      image.Rectangle
+		wantResize    bool
+	}{
+		{"contain by width", 1000, 500, 200, 0, FitContain, 200, 100, image.Rect(0, 0, 1000, 500), true},
+		{"contain within box", 1000, 500, 200, 200, FitContain, 200, 100, image.Rect(0, 0, 1000, 500), true},
+		{"no upscale", 100, 50, 200, 200, FitContain, 100, 50, image.Rect(0, 0, 100, 50), false},
+		{"cover crops center", 1000, 500, 200, 200, FitCover, 200, 200, image.Rect(250, 0, 750, 500), true},
+		{"cover with one side falls back to contain", 1000, 500, 0, 100, FitCover, 200, 100, image.Rect(0, 0, 1000, 500), true},
+		{"no size", 1000, 500, 0, 0, FitContain, 1000, 500, image.Rect(0, 0, 1000, 500), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, crop, ok := TargetSize(tt.srcW, tt.srcH, tt.width, tt.height, tt.fit)
+			if w != tt.wantW || h != tt.wantH || crop != tt.wantCrop || ok != tt.wantResize {
+				t.Errorf("TargetSize() = %d, %d, %v, %v, want %d, %d, %v, %v", w, h, crop, ok, tt.wantW, tt.wantH, tt.wantCrop, tt.wantResize)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	// 左半边黑、右半边白，缩小为 2x1 后两个像素保持原来的颜色
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.RGBA{A: 255}
+			if x >= 4 {
+				c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	dst := Resize(src, src.Bounds(), 2, 1)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{A: 255}) {
+		t.Errorf("left pixel = %v", got)
+	}
+	if got := dst.RGBAAt(1, 0); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("right pixel = %v", got)
+	}
+}
+
+func TestFrameTimes(t *testing.T) {
+	got := FrameTimes(80, 4)
+	want := []float64{10, 30, 50, 70}
+	if len(got) != len(want) {
+		t.Fatalf("FrameTimes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("FrameTimes()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got := FrameTimes(0, 4); len(got) != 1 || got[0] != 0 {
+		t.Errorf("FrameTimes(0, 4) = %v, want [0]", got)
+	}
+	if got := FrameTimes(80, 0); got != nil {
+		t.Errorf("FrameTimes(80, 0) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,89 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+)
+
+// 缩放方式
+const (
+	FitContain = "contain" // 等比缩放到宽高范围内
+	FitCover   = "cover"   // 等比缩放后居中裁剪，填满宽高
+)
+
+// TargetSize 计算缩放后的尺寸和需要保留的原图区域，只缩小不放大
+// width/height 为 0 时按另一边等比计算；返回 ok=false 表示不需要缩放
+func TargetSize(srcW, srcH, width, height int, fit string) (dstW, dstH int, crop image.Rectangle, ok bool) {
+	crop = image.Rect(0, 0, srcW, srcH)
+	if srcW <= 0 || srcH <= 0 || (width <= 0 && height <= 0) {
+		return srcW, srcH, crop, false
+	}
+	if width <= 0 || height <= 0 || fit != FitCover {
+		// contain：取两边中缩放比例更小的一边
+		scale := 1.0
+		if width > 0 {
+			scale = min(scale, float64(width)/float64(srcW))
+		}
+		if height > 0 {
+			scale = min(scale, float64(height)/float64(srcH))
+		}
+		if scale >= 1 {
+			return srcW, srcH, crop, false
+		}
+		return max(int(float64(srcW)*scale+0.5), 1), max(int(float64(srcH)*scale+0.5), 1), crop, true
+	}
+
+	// cover：目标尺寸不超过原图，按目标宽高比从原图中心裁剪
+	dstW, dstH = min(width, srcW), min(height, srcH)
+	if float64(srcW)*float64(dstH) > float64(srcH)*float64(dstW) {
+		cropW := int(float64(srcH)*float64(dstW)/float64(dstH) + 0.5)
+		x := (srcW - cropW) / 2
+		crop = image.Rect(x, 0, x+cropW, srcH)
+	} else {
+		cropH := int(float64(srcW)*float64(dstH)/float64(dstW) + 0.5)
+		y := (srcH - cropH) / 2
+		crop = image.Rect(0, y, srcW, y+cropH)
+	}
+	if dstW == srcW && dstH == srcH {
+		return srcW, srcH, crop, false
+	}
+	return dstW, dstH, crop, true
+}
+
+// Resize 将原图的 crop 区域缩小到 dstW x dstH，每个目标像素取覆盖的原图像素平均值（区域平均），
+// 缩小时比最近邻采样平滑，且不依赖第三方图像库
+func Resize(src image.Image, crop image.Rectangle, dstW, dstH int) *image.RGBA {
+	// 先转换为 RGBA（预乘 alpha），透明像素平均时不会带出颜色
+	crop = crop.Add(src.Bounds().Min)
+	rgba := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, crop.Min, draw.Src)
+
+	srcW, srcH := crop.Dx(), crop.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := y * srcH / dstH
+		y1 := max((y+1)*srcH/dstH, y0+1)
+		for x := 0; x < dstW; x++ {
+			x0 := x * srcW / dstW
+			x1 := max((x+1)*srcW/dstW, x0+1)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[offset])
+					g += uint32(rgba.Pix[offset+1])
+					b += uint32(rgba.Pix[offset+2])
+					a += uint32(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
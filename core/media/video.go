@@ -0,0 +1,144 @@
+package media
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	defaultFrameSide      = 768
+	defaultFFmpegTimeout  = 2 * time.Minute
+	videoAudioFileName    = "audio.mp3"
+	videoFrameFilePattern = "frame_%d_%03d.jpg"
+)
+
+// VideoSample 视频抽帧结果，抽取的帧和音轨缓存在 Dir 目录，会话历史直接引用帧图片路径
+type VideoSample struct {
+	Dir      string    // 缓存目录，视频文件变化时目录随之变化
+	Duration float64   // 视频时长（秒），无法获取时为 0
+	Frames   []string  // 关键帧图片路径
+	Times    []float64 // 各帧对应的时间点（秒）
+
+	file string
+}
+
+// SampleVideo 用 ffmpeg 从视频中按时长均匀抽取 n 个关键帧，帧的最长边不超过 multimodal.video.frameSide，结果缓存在 cacheDir 下
+// 已抽取的帧直接复用；n 为 0 时只准备缓存目录（用于提取音轨）；未安装 ffmpeg 时返回错误
+func SampleVideo(ctx context.Context, path, cacheDir string, n int) (*VideoSample, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("video file not found: %w", err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	sample := &VideoSample{
+		Dir:  filepath.Join(cacheDir, hash(fmt.Sprintf("%s|%d|%d", abs, info.Size(), info.ModTime().UnixNano()))),
+		file: path,
+	}
+	if err = os.MkdirAll(sample.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if sample.Duration, err = probeDuration(ctx, path); err != nil {
+		g.Log().Warningf(ctx, "获取视频时长失败，只抽取第一帧 %s: %v", path, err)
+	}
+	if sample.Duration <= 0 && n > 0 {
+		n = 1
+	}
+	frameSide := g.Cfg().MustGet(ctx, "multimodal.video.frameSide", defaultFrameSide).Int()
+	scale := fmt.Sprintf("scale=w='min(%d,iw)':h='min(%d,ih)':force_original_aspect_ratio=decrease", frameSide, frameSide)
+
+	for i, at := range FrameTimes(sample.Duration, n) {
+		frame := filepath.Join(sample.Dir, fmt.Sprintf(videoFrameFilePattern, n, i))
+		if _, err = os.Stat(frame); err != nil {
+			// -skip_frame nokey 只解码关键帧，取时间点之后的第一个关键帧，避免逐帧解码长视频
+			err = runFFmpeg(ctx, "-skip_frame", "nokey", "-ss", strconv.FormatFloat(at, 'f', 3, 64), "-i", path,
+				"-frames:v", "1", "-vf", scale, "-q:v", "3", frame)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// 时间点之后没有关键帧时 ffmpeg 不输出文件，跳过该帧
+		if _, err = os.Stat(frame); err == nil {
+			sample.Frames = append(sample.Frames, frame)
+			sample.Times = append(sample.Times, at)
+		}
+	}
+	if n > 0 && len(sample.Frames) == 0 {
+		return nil, fmt.Errorf("no frames extracted from %s", filepath.Base(path))
+	}
+	return sample, nil
+}
+
+// Audio 提取视频音轨为 16kHz 单声道 MP3（用于语音转写），视频没有音轨时返回错误
+func (s *VideoSample) Audio(ctx context.Context) (string, error) {
+	audio := filepath.Join(s.Dir, videoAudioFileName)
+	if _, err := os.Stat(audio); err == nil {
+		return audio, nil
+	}
+	if err := runFFmpeg(ctx, "-i", s.file, "-vn", "-ac", "1", "-ar", "16000", "-b:a", "32k", audio); err != nil {
+		return "", err
+	}
+	return audio, nil
+}
+
+// FrameTimes 把视频时长均匀分成 n 段，取每段中点作为抽帧时间点，避开片头片尾的黑屏
+func FrameTimes(duration float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	if duration <= 0 {
+		return []float64{0}
+	}
+	times := make([]float64, n)
+	for i := range times {
+		times[i] = duration * float64(2*i+1) / float64(2*n)
+	}
+	return times
+}
+
+// probeDuration 用 ffprobe 读取视频时长（秒）
+func probeDuration(ctx context.Context, path string) (float64, error) {
+	ffprobe := g.Cfg().MustGet(ctx, "multimodal.video.ffprobe", "ffprobe").String()
+	ctx, cancel := context.WithTimeout(ctx, ffmpegTimeout(ctx))
+	defer cancel()
+	out, err := exec.CommandContext(ctx, ffprobe, "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", path).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+}
+
+// runFFmpeg 执行 ffmpeg，输出文件已存在时覆盖
+func runFFmpeg(ctx context.Context, args ...string) error {
+	ffmpeg := g.Cfg().MustGet(ctx, "multimodal.video.ffmpeg", "ffmpeg").String()
+	ctx, cancel := context.WithTimeout(ctx, ffmpegTimeout(ctx))
+	defer cancel()
+	args = append([]string{"-y", "-loglevel", "error"}, args...)
+	if out, err := exec.CommandContext(ctx, ffmpeg, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func ffmpegTimeout(ctx context.Context) time.Duration {
+	return g.Cfg().MustGet(ctx, "multimodal.video.timeout", defaultFFmpegTimeout).Duration()
+}
+
+func hash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+// FallbackModelsKey 模型 extra 中的备用模型列表（模型ID或名称），按顺序在主模型多次失败或被限流后改用
+const FallbackModelsKey = "fallbackModels"
+
+// FallbackEvent 一次改用备用模型的记录
+type FallbackEvent struct {
+	RequestedModelID string   `json:"requested_model_id"`
+	RequestedModel   string   `json:"requested_model"`
+	ServedModelID    string   `json:"served_model_id"`
+	ServedModel      string   `json:"served_model"`
+	Errors           []string `json:"errors"` // 此前每次失败的模型和原因
+}
+
+// FallbackRecorder 记录一次请求中发生的备用模型切换，nil 表示不记录
+type FallbackRecorder struct {
+	mu     sync.Mutex
+	events []*FallbackEvent
+}
+
+type fallbackContextKey struct{}
+
+// WithFallbackRecorder 在上下文中放入新的备用模型切换记录器
+func WithFallbackRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fallbackContextKey{}, &FallbackRecorder{})
+}
+
+// FallbackRecorderFromContext 读取上下文中的记录器，没有时返回 nil
+func FallbackRecorderFromContext(ctx context.Context) *FallbackRecorder {
+	r, _ := ctx.Value(fallbackContextKey{}).(*FallbackRecorder)
+	return r
+}
+
+func (r *FallbackRecorder) add(event *FallbackEvent) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events 已记录的切换，没有切换时返回 nil
+func (r *FallbackRecorder) Events() []*FallbackEvent {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return nil
+	}
+	return append([]*FallbackEvent(nil), r.events...)
+}
+
+// FallbackChain 按 extra.fallbackModels 解析模型的备用模型（按模型ID或名称匹配已加载的模型），
+// 跳过不存在的模型和主模型本身；备用模型自己的 fallbackModels 不再展开
+func (r *ModelRegistry) FallbackChain(mc *ModelConfig) []*ModelConfig {
+	refs, _ := mc.Extra[FallbackModelsKey].([]any)
+	if len(refs) == 0 {
+		return nil
+	}
+	seen := map[string]bool{mc.ModelID: true}
+	var chain []*ModelConfig
+	for _, ref := range refs {
+		name, _ := ref.(string)
+		fallback := r.Get(name)
+		if fallback == nil {
+			fallback = r.getByName(name)
+		}
+		if fallback == nil || seen[fallback.ModelID] {
+			continue
+		}
+		seen[fallback.ModelID] = true
+		chain = append(chain, fallback)
+	}
+	return chain
+}
+
+// getByName 按名称查找模型，名称重复时返回任意一个
+func (r *ModelRegistry) getByName(name string) *ModelConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, mc := range r.models {
+		if mc.Name == name {
+			return mc
+		}
+	}
+	return nil
+}
+
+// CheckChainAvailable 主模型或任一备用模型未被健康检查标记为不可用时返回 nil，否则返回主模型的错误
+func (r *ModelRegistry) CheckChainAvailable(modelID string) error {
+	err := r.CheckAvailable(modelID)
+	if err == nil {
+		return nil
+	}
+	if mc := r.Get(modelID); mc != nil {
+		for _, fallback := range r.FallbackChain(mc) {
+			if r.CheckAvailable(fallback.ModelID) == nil {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// ShouldFallback 错误是否值得重试或改用备用模型：模型不可用、被限流、超时、服务端错误和网络错误，
+// 请求参数错误（如 400、401）换模型也无法解决，直接返回
+func ShouldFallback(err error) bool {
+	if errors.Is(err, ErrModelUnavailable) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= http.StatusInternalServerError
+}
+
+// fallbackConfig 备用模型切换配置（modelFallback.*）
+type fallbackConfig struct {
+	MaxAttempts int           // 每个模型的最多尝试次数，用完后改用下一个模型
+	RetryDelay  time.Duration // 同一模型两次尝试之间的等待时间
+}
+
+func loadFallbackConfig(ctx context.Context) fallbackConfig {
+	conf := fallbackConfig{
+		MaxAttempts: g.Cfg().MustGet(ctx, "modelFallback.maxAttempts", 2).Int(),
+		RetryDelay:  time.Duration(g.Cfg().MustGet(ctx, "modelFallback.retryDelayMs", 500).Int()) * time.Millisecond,
+	}
+	if conf.MaxAttempts < 1 {
+		conf.MaxAttempts = 1
+	}
+	return conf
+}
+
+// callWithFallback 依次在主模型和备用模型上调用 call：每个模型最多尝试 modelFallback.maxAttempts 次，
+// 可重试的错误（见 ShouldFallback）用完次数后改用下一个模型，其他错误直接返回；
+// 由备用模型完成时记录到上下文中的 FallbackRecorder。没有备用模型时只调用一次，行为与之前相同
+func callWithFallback[T any](ctx context.Context, s *ModelService, params ChatCompletionParams, call func(target *ModelService, params ChatCompletionParams) (T, error)) (T, error) {
+	var zero T
+	if len(s.fallbacks) == 0 {
+		return call(s, params)
+	}
+	conf := loadFallbackConfig(ctx)
+	targets := append([]*ModelService{s}, s.fallbacks...)
+	var failures []string
+	var lastErr error
+	for i, target := range targets {
+		p := params
+		if i > 0 {
+			// 备用模型的上下文窗口可能比主模型小，按备用模型的配置重新计算输出 token 上限
+			p.ModelName = target.modelName
+			p.ContextWindow = target.contextWindow
+		}
+		for attempt := 1; attempt <= conf.MaxAttempts; attempt++ {
+			result, err := call(target, p)
+			if err == nil {
+				if i > 0 {
+					g.Log().Warningf(ctx, "Model %s failed, request served by fallback model %s", params.ModelName, p.ModelName)
+					FallbackRecorderFromContext(ctx).add(&FallbackEvent{
+						RequestedModelID: s.modelID,
+						RequestedModel:   params.ModelName,
+						ServedModelID:    target.modelID,
+						ServedModel:      p.ModelName,
+						Errors:           failures,
+					})
+				}
+				return result, nil
+			}
+			if ctx.Err() != nil || !ShouldFallback(err) {
+				return zero, err
+			}
+			lastErr = err
+			failures = append(failures, fmt.Sprintf("%s: %v", p.ModelName, err))
+			if errors.Is(err, ErrModelUnavailable) || attempt == conf.MaxAttempts {
+				break
+			}
+			g.Log().Warningf(ctx, "Model %s call failed (attempt %d/%d), retrying: %v", p.ModelName, attempt, conf.MaxAttempts, err)
+			select {
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			case <-time.After(conf.RetryDelay):
+			}
+		}
+	}
+	return zero, fmt.Errorf("all models in fallback chain failed: %w", lastErr)
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/formatter"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/sashabaranov/go-openai"
+)
+
+// newChatServer 假 OpenAI 兼容聊天接口，status 不为 200 时返回错误，记录调用次数
+func newChatServer(t *testing.T, status int, calls *int32) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			fmt.Fprintf(w, `{"error":{"message":"status %d","type":"api_error"}}`, status)
+			return
+		}
+		io.WriteString(w, `{"id":"c1","model":"qwen-max","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// TestChatCompletionFallback 测试主模型被限流时重试后改用备用模型，参数错误不切换模型
+func TestChatCompletionFallback(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("modelFallback:\n  maxAttempts: 2\n  retryDelayMs: 10\n")
+	if err != nil {
+		t.Fatalf("NewAdapterContent() error = %v", err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	tests := []struct {
+		name          string
+		primaryStatus int
+		wantPrimary   int32
+		wantFallback  int32
+		wantErr       bool
+	}{
+		{name: "Rate limited primary falls back", primaryStatus: http.StatusTooManyRequests, wantPrimary: 2, wantFallback: 1},
+		{name: "Server error falls back", primaryStatus: http.StatusBadGateway, wantPrimary: 2, wantFallback: 1},
+		{name: "Bad request is returned directly", primaryStatus: http.StatusBadRequest, wantPrimary: 1, wantFallback: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primaryCalls, fallbackCalls int32
+			primary := &ModelConfig{ModelID: "primary-" + tt.name, Name: "gpt-4o", Type: ModelTypeLLM,
+				BaseURL: newChatServer(t, tt.primaryStatus, &primaryCalls), Extra: map[string]any{FallbackModelsKey: []any{"missing", "qwen-max-" + tt.name}}}
+			fallback := &ModelConfig{ModelID: "fallback-" + tt.name, Name: "qwen-max-" + tt.name, Type: ModelTypeLLM,
+				BaseURL: newChatServer(t, http.StatusOK, &fallbackCalls)}
+			Registry.Register(primary)
+			Registry.Register(fallback)
+			t.Cleanup(func() {
+				Registry.Unregister(primary.ModelID)
+				Registry.Unregister(fallback.ModelID)
+			})
+
+			ctx := WithFallbackRecorder(context.Background())
+			svc := NewModelServiceFor(primary, formatter.NewOpenAIFormatter())
+			resp, err := svc.ChatCompletion(ctx, ChatCompletionParams{
+				ModelName: primary.Name,
+				Messages:  []*schema.Message{{Role: schema.User, Content: "hi"}},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChatCompletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if primaryCalls != tt.wantPrimary || fallbackCalls != tt.wantFallback {
+				t.Errorf("calls = primary %d, fallback %d, want %d, %d", primaryCalls, fallbackCalls, tt.wantPrimary, tt.wantFallback)
+			}
+			events := FallbackRecorderFromContext(ctx).Events()
+			if tt.wantErr {
+				if events != nil {
+					t.Errorf("events = %+v, want none", events)
+				}
+				return
+			}
+			if resp.Choices[0].Message.Content != "ok" {
+				t.Errorf("content = %q", resp.Choices[0].Message.Content)
+			}
+			if len(events) != 1 || events[0].RequestedModelID != primary.ModelID || events[0].ServedModelID != fallback.ModelID || len(events[0].Errors) != 2 {
+				t.Errorf("events = %+v", events)
+			}
+		})
+	}
+}
+
+// TestShouldFallback 测试哪些错误会触发重试或切换模型
+func TestShouldFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Model unavailable", fmt.Errorf("%w: ollama down", ErrModelUnavailable), true},
+		{"Rate limited", fmt.Errorf("failed: %w", &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests}), true},
+		{"Service unavailable", &openai.RequestError{HTTPStatusCode: http.StatusServiceUnavailable}, true},
+		{"Unauthorized", &openai.APIError{HTTPStatusCode: http.StatusUnauthorized}, false},
+		{"Format error", errors.New("failed to format messages"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldFallback(tt.err); got != tt.want {
+				t.Errorf("ShouldFallback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCallWithFallbackContextWindow 测试改用备用模型时使用备用模型配置的上下文窗口
+func TestCallWithFallbackContextWindow(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("modelFallback:\n  maxAttempts: 1\n")
+	if err != nil {
+		t.Fatalf("NewAdapterContent() error = %v", err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	s := &ModelService{modelName: "gpt-4o", fallbacks: []*ModelService{
+		{modelName: "small", contextWindow: 8192},
+		{modelName: "unset"},
+	}}
+	var windows []int
+	_, err = callWithFallback(context.Background(), s, ChatCompletionParams{ModelName: "gpt-4o", ContextWindow: 128000},
+		func(target *ModelService, params ChatCompletionParams) (string, error) {
+			windows = append(windows, params.ContextWindow)
+			return "", ErrModelUnavailable
+		})
+	if err == nil {
+		t.Fatal("callWithFallback() error = nil, want error")
+	}
+	if fmt.Sprint(windows) != "[128000 8192 0]" {
+		t.Errorf("context windows = %v, want [128000 8192 0]", windows)
+	}
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/client"
+)
+
+// ErrModelUnavailable 模型被健康检查标记为不可用，调用方据此直接失败而不是等待请求超时
+var ErrModelUnavailable = errors.New("model unavailable")
+
+// ModelHealth 模型健康状态
+type ModelHealth struct {
+	Available bool      `json:"available"`
+	Reason    string    `json:"reason,omitempty"` // 不可用的原因
+	CheckedAt time.Time `json:"checked_at"`       // 最近一次检查时间
+	Since     time.Time `json:"since"`            // 当前状态的开始时间
+}
+
+// NeedsHealthCheck 是否对模型做定期健康检查：ollama 模型总是检查，其他模型在 extra.healthCheck 为 true 时检查
+func NeedsHealthCheck(mc *ModelConfig) bool {
+	if strings.EqualFold(mc.Provider, client.ProviderOllama) {
+		return true
+	}
+	enabled, _ := mc.Extra["healthCheck"].(bool)
+	return enabled
+}
+
+// CheckHealth 检查模型是否可用：ollama 模型检查服务可以连接且模型已下载，其他模型调用 OpenAI 兼容的 /models 接口
+func CheckHealth(ctx context.Context, mc *ModelConfig) error {
+	if strings.EqualFold(mc.Provider, client.ProviderOllama) {
+		models, err := client.ListOllamaModels(ctx, mc.BaseURL)
+		if err != nil {
+			return fmt.Errorf("ollama server unreachable: %w", err)
+		}
+		if !client.HasOllamaModel(models, mc.Name) {
+			return fmt.Errorf("model %s is not pulled on the ollama server", mc.Name)
+		}
+		return nil
+	}
+	if mc.Client == nil {
+		return fmt.Errorf("model client not initialized")
+	}
+	if _, err := mc.Client.ListModels(ctx); err != nil {
+		return fmt.Errorf("model endpoint unreachable: %w", err)
+	}
+	return nil
+}
+
+// SetHealth 记录模型的检查结果，返回状态是否发生变化（首次检查视为变化）
+func (r *ModelRegistry) SetHealth(modelID string, available bool, reason string) bool {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.health == nil {
+		r.health = make(map[string]*ModelHealth)
+	}
+	prev := r.health[modelID]
+	health := &ModelHealth{Available: available, Reason: reason, CheckedAt: now, Since: now}
+	if prev != nil && prev.Available == available {
+		health.Since = prev.Since
+	}
+	r.health[modelID] = health
+	return prev == nil || prev.Available != available
+}
+
+// Health 模型的健康状态，未做过健康检查时返回 nil
+func (r *ModelRegistry) Health(modelID string) *ModelHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if health := r.health[modelID]; health != nil {
+		h := *health
+		return &h
+	}
+	return nil
+}
+
+// CheckAvailable 模型被健康检查标记为不可用时返回错误（包装 ErrModelUnavailable），未做过检查的模型视为可用
+func (r *ModelRegistry) CheckAvailable(modelID string) error {
+	health := r.Health(modelID)
+	if health == nil || health.Available {
+		return nil
+	}
+	name := modelID
+	if mc := r.Get(modelID); mc != nil {
+		name = fmt.Sprintf("%s (%s)", mc.Name, modelID)
+	}
+	return fmt.Errorf("%w: %s failed health check since %s: %s",
+		ErrModelUnavailable, name, health.Since.Format(time.RFC3339), health.Reason)
+}
@@ -3,9 +3,11 @@ package model
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/Malowking/kbgo/core/client"
 	"github.com/Malowking/kbgo/core/formatter"
+	"github.com/Malowking/kbgo/core/tokenizer"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/sashabaranov/go-openai"
 )
@@ -15,6 +17,11 @@ import (
 type ModelService struct {
 	client    *client.OpenAIClient
 	formatter formatter.MessageFormatter
+	modelID   string // 已注册模型的ID，调用前检查模型是否被健康检查标记为不可用
+	modelName string
+	fallbacks []*ModelService // 备用模型（extra.fallbackModels），主模型多次失败或被限流后依次改用
+
+	contextWindow int // 模型配置的上下文窗口（extra.context_window），改用备用模型时替换请求参数中主模型的窗口
 }
 
 // NewModelService 创建模型服务
@@ -25,6 +32,59 @@ func NewModelService(apiKey, baseURL string, formatter formatter.MessageFormatte
 	}
 }
 
+// NewModelServiceFor 使用已注册模型的客户端创建模型服务，按模型的提供商适配接口（如 Anthropic Messages API）
+func NewModelServiceFor(mc *ModelConfig, formatter formatter.MessageFormatter) *ModelService {
+	c := mc.Client
+	if c == nil {
+		c = client.NewProviderClient(mc.Provider, mc.APIKey, mc.BaseURL)
+	}
+	s := &ModelService{
+		client:    client.WrapOpenAIClient(c),
+		formatter: formatter,
+		modelID:   mc.ModelID,
+		modelName: mc.Name,
+	}
+	for _, fallback := range Registry.FallbackChain(mc) {
+		fc := fallback.Client
+		if fc == nil {
+			fc = client.NewProviderClient(fallback.Provider, fallback.APIKey, fallback.BaseURL)
+		}
+		s.fallbacks = append(s.fallbacks, &ModelService{
+			client:    client.WrapOpenAIClient(fc),
+			formatter: FormatterFor(fallback),
+			modelID:   fallback.ModelID,
+			modelName: fallback.Name,
+
+			contextWindow: ContextWindow(fallback.Extra),
+		})
+	}
+	return s
+}
+
+// ContextWindow 读取模型配置的上下文窗口 token 数（extra.context_window），未配置时返回 0；
+// 注册模型时保存为 int，从数据库加载的 JSON 数字为 float64
+func ContextWindow(extra map[string]any) int {
+	switch contextWindow := extra["context_window"].(type) {
+	case float64:
+		return int(contextWindow)
+	case int:
+		return contextWindow
+	}
+	return 0
+}
+
+// FormatterFor 按模型选择消息格式适配器：gemini 提供商使用 Gemini 格式，名称以 qwen 开头的模型使用通义千问格式，其他使用 OpenAI 标准格式
+func FormatterFor(mc *ModelConfig) formatter.MessageFormatter {
+	switch {
+	case strings.EqualFold(mc.Provider, client.ProviderGemini):
+		return formatter.NewGeminiFormatter()
+	case strings.HasPrefix(strings.ToLower(mc.Name), "qwen"):
+		return formatter.NewQwenFormatter()
+	default:
+		return formatter.NewOpenAIFormatter()
+	}
+}
+
 // ChatCompletionParams 聊天参数
 type ChatCompletionParams struct {
 	ModelName           string
@@ -39,10 +99,21 @@ type ChatCompletionParams struct {
 	Tools               []openai.Tool
 	ToolChoice          any
 	ResponseFormat      *openai.ChatCompletionResponseFormat
+	LogProbs            bool // 是否返回输出 token 的对数概率（仅非流式，部分服务商不支持）
+	ContextWindow       int  // 模型上下文窗口 token 数，大于 0 时按输入长度收缩 MaxCompletionTokens，避免超出窗口
 }
 
-// ChatCompletion 非流式对话
+// ChatCompletion 非流式对话，配置了备用模型时失败后依次改用备用模型（见 callWithFallback）
 func (s *ModelService) ChatCompletion(ctx context.Context, params ChatCompletionParams) (*openai.ChatCompletionResponse, error) {
+	return callWithFallback(ctx, s, params, func(target *ModelService, params ChatCompletionParams) (*openai.ChatCompletionResponse, error) {
+		return target.chatCompletion(ctx, params)
+	})
+}
+
+func (s *ModelService) chatCompletion(ctx context.Context, params ChatCompletionParams) (*openai.ChatCompletionResponse, error) {
+	if err := Registry.CheckAvailable(s.modelID); err != nil {
+		return nil, err
+	}
 	// 使用格式适配器转换消息
 	openaiMessages, err := s.formatter.FormatMessages(params.Messages)
 	if err != nil {
@@ -54,7 +125,7 @@ func (s *ModelService) ChatCompletion(ctx context.Context, params ChatCompletion
 		Model:               params.ModelName,
 		Messages:            openaiMessages,
 		Temperature:         params.Temperature,
-		MaxCompletionTokens: params.MaxCompletionTokens,
+		MaxCompletionTokens: tokenizer.CompletionBudget(params.ModelName, params.Messages, params.ContextWindow, params.MaxCompletionTokens),
 		TopP:                params.TopP,
 		FrequencyPenalty:    params.FrequencyPenalty,
 		PresencePenalty:     params.PresencePenalty,
@@ -63,13 +134,23 @@ func (s *ModelService) ChatCompletion(ctx context.Context, params ChatCompletion
 		Tools:               params.Tools,
 		ToolChoice:          params.ToolChoice,
 		ResponseFormat:      params.ResponseFormat,
+		LogProbs:            params.LogProbs,
 	}
 
 	return s.client.ChatCompletion(ctx, req)
 }
 
-// ChatCompletionStream 流式对话
+// ChatCompletionStream 流式对话，建立流失败时按备用模型配置重试或改用备用模型，流开始输出后的错误不再切换
 func (s *ModelService) ChatCompletionStream(ctx context.Context, params ChatCompletionParams) (*openai.ChatCompletionStream, error) {
+	return callWithFallback(ctx, s, params, func(target *ModelService, params ChatCompletionParams) (*openai.ChatCompletionStream, error) {
+		return target.chatCompletionStream(ctx, params)
+	})
+}
+
+func (s *ModelService) chatCompletionStream(ctx context.Context, params ChatCompletionParams) (*openai.ChatCompletionStream, error) {
+	if err := Registry.CheckAvailable(s.modelID); err != nil {
+		return nil, err
+	}
 	// 使用格式适配器转换消息
 	openaiMessages, err := s.formatter.FormatMessages(params.Messages)
 	if err != nil {
@@ -81,7 +162,7 @@ func (s *ModelService) ChatCompletionStream(ctx context.Context, params ChatComp
 		Model:               params.ModelName,
 		Messages:            openaiMessages,
 		Temperature:         params.Temperature,
-		MaxCompletionTokens: params.MaxCompletionTokens,
+		MaxCompletionTokens: tokenizer.CompletionBudget(params.ModelName, params.Messages, params.ContextWindow, params.MaxCompletionTokens),
 		TopP:                params.TopP,
 		FrequencyPenalty:    params.FrequencyPenalty,
 		PresencePenalty:     params.PresencePenalty,
@@ -2,9 +2,14 @@ package model
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 
+	"github.com/Malowking/kbgo/core/client"
 	"github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/sashabaranov/go-openai"
@@ -22,6 +27,8 @@ const (
 	ModelTypeImage      ModelType = "image"      // 文生图模型
 	ModelTypeVideo      ModelType = "video"      // 文生视频模型
 	ModelTypeAudio      ModelType = "audio"      // 文生音频模型
+
+	ModelTypeSparseEmbedding ModelType = "sparse_embedding" // 稀疏向量化模型（SPLADE/BM42 等）
 )
 
 // ModelConfig 模型配置（内存缓存）
@@ -41,6 +48,7 @@ type ModelConfig struct {
 type ModelRegistry struct {
 	mu     sync.RWMutex
 	models map[string]*ModelConfig // key = model_id (UUID)
+	health map[string]*ModelHealth // key = model_id，定期健康检查的结果
 }
 
 // Registry 全局单例
@@ -113,27 +121,72 @@ func (r *ModelRegistry) Reload(ctx context.Context, db *gormdb.DB) error {
 			}
 		}
 
-		// 创建 OpenAI 客户端
-		config := openai.DefaultConfig(m.APIKey)
-		config.BaseURL = m.BaseURL
+		// ollama 模型的 BaseURL 统一为 OpenAI 兼容接口地址，直接按 OpenAI 格式调用的向量化等场景可以使用
+		if strings.EqualFold(m.Provider, client.ProviderOllama) {
+			mc.BaseURL = client.OllamaOpenAIBaseURL(m.BaseURL)
+		}
+
+		// 按提供商创建客户端（anthropic、gemini、ollama 通过各自的原生接口适配，其他使用 OpenAI 兼容接口）
 		// Note: HTTPClient timeout should be set through the http.Client directly if needed
-		mc.Client = openai.NewClientWithConfig(config)
+		mc.Client = client.NewProviderClient(m.Provider, m.APIKey, m.BaseURL)
 
 		newMap[m.ModelID] = mc
 	}
 
 	// 原子替换（所有旧请求继续使用旧缓存，新请求使用新缓存）
+	// 配置可能已修正，清除健康检查结果，由下一次检查重新判断
 	r.mu.Lock()
 	r.models = newMap
+	r.health = make(map[string]*ModelHealth)
 	r.mu.Unlock()
 
 	g.Log().Infof(ctx, "Model registry reloaded successfully, total models: %d", len(newMap))
 	return nil
 }
 
+// Register 注册单个模型（不经过数据库），未设置客户端时按 Provider、BaseURL 和 APIKey 创建，已存在相同ID的模型时覆盖
+// 用于测试或嵌入式场景，下次 Reload 时会被数据库中的配置替换
+func (r *ModelRegistry) Register(mc *ModelConfig) {
+	if strings.EqualFold(mc.Provider, client.ProviderOllama) {
+		mc.BaseURL = client.OllamaOpenAIBaseURL(mc.BaseURL)
+	}
+	if mc.Client == nil {
+		mc.Client = client.NewProviderClient(mc.Provider, mc.APIKey, mc.BaseURL)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.models == nil {
+		r.models = make(map[string]*ModelConfig)
+	}
+	r.models[mc.ModelID] = mc
+}
+
+// Unregister 移除单个模型
+func (r *ModelRegistry) Unregister(modelID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.models, modelID)
+	delete(r.health, modelID)
+}
+
 // Count 返回当前加载的模型数量
 func (r *ModelRegistry) Count() int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	return len(r.models)
 }
+
+// Fingerprint 计算模型配置指纹（名称、版本、Base URL、向量维度），任一项变化都会改变向量空间
+// 用于判断已写入的向量是否由当前配置生成
+func (mc *ModelConfig) Fingerprint() string {
+	dimension := ""
+	if mc.Extra != nil {
+		if dim, ok := mc.Extra["dimension"]; ok {
+			dimension = fmt.Sprint(dim)
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		mc.Name, mc.Version, strings.TrimRight(mc.BaseURL, "/"), dimension,
+	}, "|")))
+	return hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/sashabaranov/go-openai"
+)
+
+var (
+	responseCacheOnce sync.Once
+	responseCache     *gcache.Cache
+)
+
+// getResponseCache 懒加载模型响应缓存（内存 LRU，容量由 modelCache.maxEntries 配置）
+func getResponseCache(ctx context.Context) *gcache.Cache {
+	responseCacheOnce.Do(func() {
+		responseCache = gcache.New(g.Cfg().MustGet(ctx, "modelCache.maxEntries", 1000).Int())
+	})
+	return responseCache
+}
+
+// ResponseCacheKey 计算缓存键：模型服务地址 + 完整请求（模型、消息、参数）的哈希
+func ResponseCacheKey(scope string, req any) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(scope+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CachedChatCompletion 确定性系统任务（temperature=0，如工具选择）的模型调用缓存
+// 请求完全相同时直接返回缓存的响应；调用失败、没有返回内容或输出被截断时不缓存
+func CachedChatCompletion(ctx context.Context, scope string, req any, call func() (*openai.ChatCompletionResponse, error)) (*openai.ChatCompletionResponse, error) {
+	if !g.Cfg().MustGet(ctx, "modelCache.enabled", true).Bool() {
+		return call()
+	}
+	key, err := ResponseCacheKey(scope, req)
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to build model response cache key, calling model directly: %v", err)
+		return call()
+	}
+
+	ttl := time.Duration(g.Cfg().MustGet(ctx, "modelCache.ttl", 3600).Int()) * time.Second
+	return cachedCompletion(ctx, getResponseCache(ctx), key, ttl, call)
+}
+
+// cachedCompletion 按缓存键读取或写入模型响应
+func cachedCompletion(ctx context.Context, cache *gcache.Cache, key string, ttl time.Duration, call func() (*openai.ChatCompletionResponse, error)) (*openai.ChatCompletionResponse, error) {
+	if cached, err := cache.Get(ctx, key); err == nil && cached != nil {
+		if resp, ok := cached.Val().(*openai.ChatCompletionResponse); ok {
+			g.Log().Debugf(ctx, "Model response cache hit: %s", key[:12])
+			return cloneResponse(resp), nil
+		}
+	}
+
+	resp, err := call()
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) > 0 && resp.Choices[0].FinishReason != openai.FinishReasonLength {
+		if err = cache.Set(ctx, key, cloneResponse(resp), ttl); err != nil {
+			g.Log().Warningf(ctx, "Failed to cache model response: %v", err)
+		}
+	}
+	return resp, nil
+}
+
+// cloneResponse 复制响应，避免调用方修改缓存中的内容
+func cloneResponse(resp *openai.ChatCompletionResponse) *openai.ChatCompletionResponse {
+	clone := *resp
+	clone.Choices = append([]openai.ChatCompletionChoice(nil), resp.Choices...)
+	return &clone
+}
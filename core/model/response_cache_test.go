@@ -0,0 +1,72 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/sashabaranov/go-openai"
+)
+
+// TestCachedCompletion 测试相同请求复用缓存，失败和被截断的响应不缓存
+func TestCachedCompletion(t *testing.T) {
+	ctx := context.Background()
+	cache := gcache.New()
+	tests := []struct {
+		name      string
+		req       string
+		resp      *openai.ChatCompletionResponse
+		err       error
+		wantCalls int
+	}{
+		{
+			name:      "Successful response is cached",
+			req:       "select tools for question A",
+			resp:      &openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{FinishReason: openai.FinishReasonStop}}},
+			wantCalls: 1,
+		},
+		{
+			name:      "Error is not cached",
+			req:       "select tools for question B",
+			err:       errors.New("service unavailable"),
+			wantCalls: 2,
+		},
+		{
+			name:      "Truncated response is not cached",
+			req:       "select tools for question C",
+			resp:      &openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{FinishReason: openai.FinishReasonLength}}},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			call := func() (*openai.ChatCompletionResponse, error) {
+				calls++
+				return tt.resp, tt.err
+			}
+			key, err := ResponseCacheKey("http://llm.local", tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := cachedCompletion(ctx, cache, key, time.Minute, call); (err != nil) != (tt.err != nil) {
+					t.Fatalf("cachedCompletion() error = %v, want %v", err, tt.err)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("model called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	// 不同服务地址的相同请求互不共享缓存
+	keyA, _ := ResponseCacheKey("http://a", "req")
+	keyB, _ := ResponseCacheKey("http://b", "req")
+	if keyA == keyB {
+		t.Error("cache keys should differ across scopes")
+	}
+}
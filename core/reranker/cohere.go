@@ -1,40 +1,23 @@
-package common
+package reranker
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net"
 	"net/http"
-	"os"
-	"time"
 )
 
-// RerankConfig 接口，用于提取rerank配置
-type RerankConfig interface {
-	GetRerankAPIKey() string
-	GetRerankBaseURL() string
-	GetRerankModel() string
-}
-
-// CustomReranker 自定义rerank客户端
-type CustomReranker struct {
+// CohereReranker Cohere 兼容接口的rerank客户端
+type CohereReranker struct {
 	apiKey     string
 	baseURL    string
 	model      string
 	httpClient *http.Client
 }
 
-// RerankDocument 简化的文档结构
-type RerankDocument struct {
-	ID      string
-	Content string
-	Score   float64
-}
-
-// RerankRequest rerank API请求结构
-type RerankRequest struct {
+// cohereRequest rerank API请求结构
+type cohereRequest struct {
 	Model           string   `json:"model"`
 	Query           string   `json:"query"`
 	Documents       []string `json:"documents"`
@@ -44,20 +27,20 @@ type RerankRequest struct {
 	OverlapTokens   int      `json:"overlap_tokens,omitempty"`
 }
 
-// RerankResult rerank结果项
-type RerankResult struct {
+// cohereResult rerank结果项
+type cohereResult struct {
 	Index          int     `json:"index"`
 	RelevanceScore float64 `json:"relevance_score"`
 }
 
-// RerankResponse rerank API响应结构
-type RerankResponse struct {
+// cohereResponse rerank API响应结构
+type cohereResponse struct {
 	ID      string          `json:"id"`
-	Results []*RerankResult `json:"results"`
+	Results []*cohereResult `json:"results"`
 }
 
-// RerankErrorResponse API错误响应
-type RerankErrorResponse struct {
+// cohereErrorResponse API错误响应
+type cohereErrorResponse struct {
 	Error struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -65,63 +48,13 @@ type RerankErrorResponse struct {
 	} `json:"error"`
 }
 
-// NewReranker 创建rerank客户端
-func NewReranker(ctx context.Context, conf RerankConfig) (*CustomReranker, error) {
-	apiKey := conf.GetRerankAPIKey()
-	baseURL := conf.GetRerankBaseURL()
-	model := conf.GetRerankModel()
-
-	if apiKey == "" {
-		apiKey = os.Getenv("RERANK_API_KEY")
-	}
-	if baseURL == "" {
-		baseURL = os.Getenv("RERANK_BASE_URL")
-		if baseURL == "" {
-			return nil, fmt.Errorf("rerank baseURL is required")
-		}
-	}
-	if model == "" {
-		model = "rerank-v1"
-	}
-
-	// 创建自定义HTTP客户端，优化连接复用和超时
-	httpClient := &http.Client{
-		Timeout: 2 * time.Minute, // rerank 通常比 embedding 快
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   30 * time.Second, // 连接超时
-				KeepAlive: 30 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   30 * time.Second, // TLS握手超时
-			ResponseHeaderTimeout: 60 * time.Second, // 等待响应头超时
-			ExpectContinueTimeout: 1 * time.Second,
-			IdleConnTimeout:       90 * time.Second,
-			MaxIdleConns:          100,
-			MaxIdleConnsPerHost:   20, // 增加每个host的连接数，支持并发
-		},
-	}
-
-	return &CustomReranker{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		model:      model,
-		httpClient: httpClient,
-	}, nil
-}
-
 // Rerank 执行重排序
-func (r *CustomReranker) Rerank(ctx context.Context, query string, docs []RerankDocument, topK int) ([]RerankDocument, error) {
+func (r *CohereReranker) Rerank(ctx context.Context, query string, docs []Document, topK int) ([]Document, error) {
 	if len(docs) == 0 {
-		return []RerankDocument{}, nil
+		return []Document{}, nil
 	}
 
-	// 如果文档数量少于等于topK，仍然需要rerank来获取相关性分数
-	if topK <= 0 {
-		topK = len(docs)
-	}
-	if topK > len(docs) {
-		topK = len(docs)
-	}
+	topK = clampTopK(topK, len(docs))
 
 	// 提取文档内容
 	documents := make([]string, len(docs))
@@ -130,7 +63,7 @@ func (r *CustomReranker) Rerank(ctx context.Context, query string, docs []Rerank
 	}
 
 	// 构造请求
-	req := RerankRequest{
+	req := cohereRequest{
 		Model:           r.model,
 		Query:           query,
 		Documents:       documents,
@@ -166,7 +99,7 @@ func (r *CustomReranker) Rerank(ctx context.Context, query string, docs []Rerank
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		var errResp RerankErrorResponse
+		var errResp cohereErrorResponse
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
 			return nil, fmt.Errorf("HTTP %d: failed to decode error response: %w", resp.StatusCode, err)
 		}
@@ -174,18 +107,18 @@ func (r *CustomReranker) Rerank(ctx context.Context, query string, docs []Rerank
 	}
 
 	// 解析响应
-	var rerankResp RerankResponse
+	var rerankResp cohereResponse
 	if err := json.NewDecoder(resp.Body).Decode(&rerankResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	// 验证响应数据
 	if len(rerankResp.Results) == 0 {
-		return []RerankDocument{}, nil
+		return []Document{}, nil
 	}
 
 	// 构造返回结果
-	result := make([]RerankDocument, 0, len(rerankResp.Results))
+	result := make([]Document, 0, len(rerankResp.Results))
 	for _, res := range rerankResp.Results {
 		if res.Index >= len(docs) {
 			return nil, fmt.Errorf("invalid result index: %d", res.Index)
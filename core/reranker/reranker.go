@@ -0,0 +1,103 @@
+// Package reranker 向量召回之后的重排序阶段：用交叉编码器模型对候选文档与查询的相关性重新打分，
+// 按 rerank 模型的提供商选择接口格式（Cohere 兼容的 /rerank 接口或 Hugging Face TEI 的 /rerank 接口）
+package reranker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 提供商
+const (
+	ProviderCohere = "cohere" // Cohere 兼容接口（Cohere、Jina、SiliconFlow 的 bge-reranker 等），未指定提供商时使用
+	ProviderTEI    = "tei"    // Hugging Face text-embeddings-inference 部署的 bge-reranker 等模型
+)
+
+// Config 接口，用于提取rerank配置
+type Config interface {
+	GetRerankAPIKey() string
+	GetRerankBaseURL() string
+	GetRerankModel() string
+	GetRerankProvider() string
+}
+
+// Document 简化的文档结构
+type Document struct {
+	ID      string
+	Content string
+	Score   float64
+}
+
+// Reranker 重排序接口，返回按相关性从高到低排列的前 topK 个文档，Score 为重排序模型给出的相关性分数
+type Reranker interface {
+	Rerank(ctx context.Context, query string, docs []Document, topK int) ([]Document, error)
+}
+
+// New 按配置的提供商创建重排序客户端
+func New(ctx context.Context, conf Config) (Reranker, error) {
+	apiKey := conf.GetRerankAPIKey()
+	baseURL := conf.GetRerankBaseURL()
+	model := conf.GetRerankModel()
+
+	if apiKey == "" {
+		apiKey = os.Getenv("RERANK_API_KEY")
+	}
+	if baseURL == "" {
+		baseURL = os.Getenv("RERANK_BASE_URL")
+		if baseURL == "" {
+			return nil, fmt.Errorf("rerank baseURL is required")
+		}
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	switch provider := strings.ToLower(conf.GetRerankProvider()); provider {
+	case ProviderTEI:
+		return &TEIReranker{
+			apiKey:     apiKey,
+			baseURL:    baseURL,
+			httpClient: newHTTPClient(),
+		}, nil
+	default:
+		if model == "" {
+			model = "rerank-v1"
+		}
+		return &CohereReranker{
+			apiKey:     apiKey,
+			baseURL:    baseURL,
+			model:      model,
+			httpClient: newHTTPClient(),
+		}, nil
+	}
+}
+
+// newHTTPClient 创建自定义HTTP客户端，优化连接复用和超时
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 2 * time.Minute, // rerank 通常比 embedding 快
+		Transport: &http.Transport{
+			Dial: (&net.Dialer{
+				Timeout:   30 * time.Second, // 连接超时
+				KeepAlive: 30 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout:   30 * time.Second, // TLS握手超时
+			ResponseHeaderTimeout: 60 * time.Second, // 等待响应头超时
+			ExpectContinueTimeout: 1 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   20, // 增加每个host的连接数，支持并发
+		},
+	}
+}
+
+// clampTopK 如果文档数量少于等于topK，仍然需要rerank来获取相关性分数
+func clampTopK(topK, n int) int {
+	if topK <= 0 || topK > n {
+		return n
+	}
+	return topK
+}
@@ -1,15 +1,19 @@
-package common
+package reranker
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
 // MockRerankConfig 用于测试的mock配置
 type MockRerankConfig struct {
-	apiKey  string
-	baseURL string
-	model   string
+	apiKey   string
+	baseURL  string
+	model    string
+	provider string
 }
 
 func (m *MockRerankConfig) GetRerankAPIKey() string {
@@ -24,13 +28,18 @@ func (m *MockRerankConfig) GetRerankModel() string {
 	return m.model
 }
 
-func TestNewReranker(t *testing.T) {
+func (m *MockRerankConfig) GetRerankProvider() string {
+	return m.provider
+}
+
+func TestNew(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name    string
-		config  *MockRerankConfig
-		wantErr bool
+		name     string
+		config   *MockRerankConfig
+		wantErr  bool
+		wantType string
 	}{
 		{
 			name: "valid config",
@@ -68,22 +77,44 @@ func TestNewReranker(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "tei provider",
+			config: &MockRerankConfig{
+				baseURL:  "http://tei:8080/",
+				provider: "TEI",
+			},
+			wantErr:  false,
+			wantType: "tei",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reranker, err := NewReranker(ctx, tt.config)
+			reranker, err := New(ctx, tt.config)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("NewReranker() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !tt.wantErr {
 				if reranker == nil {
-					t.Error("NewReranker() returned nil reranker")
+					t.Error("New() returned nil reranker")
 					return
 				}
-				if reranker.httpClient == nil {
-					t.Error("NewReranker() httpClient is nil")
+				switch r := reranker.(type) {
+				case *CohereReranker:
+					if tt.wantType != "" {
+						t.Errorf("New() returned Cohere reranker, want %s", tt.wantType)
+					}
+					if r.httpClient == nil {
+						t.Error("New() httpClient is nil")
+					}
+				case *TEIReranker:
+					if tt.wantType != "tei" {
+						t.Error("New() returned TEI reranker, want Cohere")
+					}
+					if r.baseURL != "http://tei:8080" {
+						t.Errorf("New() baseURL = %q, want trailing slash trimmed", r.baseURL)
+					}
 				}
 			}
 		})
@@ -98,13 +129,13 @@ func TestRerankEmptyDocs(t *testing.T) {
 		model:   "rerank-test",
 	}
 
-	reranker, err := NewReranker(ctx, config)
+	reranker, err := New(ctx, config)
 	if err != nil {
 		t.Fatalf("Failed to create reranker: %v", err)
 	}
 
 	// Test with empty documents
-	result, err := reranker.Rerank(ctx, "test query", []RerankDocument{}, 5)
+	result, err := reranker.Rerank(ctx, "test query", []Document{}, 5)
 	if err != nil {
 		t.Errorf("Rerank() with empty docs error = %v, want nil", err)
 	}
@@ -121,12 +152,12 @@ func TestRerankTopKHandling(t *testing.T) {
 		model:   "rerank-test",
 	}
 
-	reranker, err := NewReranker(ctx, config)
+	reranker, err := New(ctx, config)
 	if err != nil {
 		t.Fatalf("Failed to create reranker: %v", err)
 	}
 
-	docs := []RerankDocument{
+	docs := []Document{
 		{ID: "1", Content: "Document 1", Score: 0.0},
 		{ID: "2", Content: "Document 2", Score: 0.0},
 		{ID: "3", Content: "Document 3", Score: 0.0},
@@ -167,6 +198,38 @@ func TestRerankTopKHandling(t *testing.T) {
 	}
 }
 
+func TestTEIRerank(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req teiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/rerank" || len(req.Texts) != 3 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"bad request","error_type":"Validation"}`))
+			return
+		}
+		// TEI 按分数从高到低返回全部文档
+		w.Write([]byte(`[{"index":2,"score":0.9},{"index":0,"score":0.5},{"index":1,"score":0.1}]`))
+	}))
+	defer server.Close()
+
+	reranker, err := New(context.Background(), &MockRerankConfig{baseURL: server.URL, provider: ProviderTEI})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	docs := []Document{{ID: "a", Content: "A"}, {ID: "b", Content: "B"}, {ID: "c", Content: "C"}}
+
+	result, err := reranker.Rerank(context.Background(), "q", docs, 2)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if len(result) != 2 || result[0].ID != "c" || result[0].Score != 0.9 || result[1].ID != "a" {
+		t.Errorf("Rerank() = %+v, want c(0.9), a(0.5)", result)
+	}
+
+	if _, err = reranker.Rerank(context.Background(), "q", docs[:1], 2); err == nil {
+		t.Error("Rerank() with rejected request error = nil, want error")
+	}
+}
+
 // TestRerankIntegration 集成测试（需要真实的API配置）
 // 运行此测试需要设置环境变量或配置文件
 func TestRerankIntegration(t *testing.T) {
@@ -185,12 +248,12 @@ func TestRerankIntegration(t *testing.T) {
 		model:   "rerank-model",
 	}
 
-	reranker, err := NewReranker(ctx, config)
+	reranker, err := New(ctx, config)
 	if err != nil {
 		t.Fatalf("Failed to create reranker: %v", err)
 	}
 
-	docs := []RerankDocument{
+	docs := []Document{
 		{
 			ID:      "1",
 			Content: "# 分布式训练技术原理- 数据并行 n- FSDP n- FSDP算法是由来自DeepSpeed的ZeroRedundancyOptimizer技术驱动的",
@@ -0,0 +1,101 @@
+package reranker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// TEIReranker Hugging Face text-embeddings-inference 的rerank客户端，模型由服务启动参数决定
+type TEIReranker struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// teiRequest TEI rerank API请求结构
+type teiRequest struct {
+	Query     string   `json:"query"`
+	Texts     []string `json:"texts"`
+	Truncate  bool     `json:"truncate"`
+	RawScores bool     `json:"raw_scores"`
+}
+
+// teiResult TEI rerank结果项
+type teiResult struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// teiErrorResponse TEI API错误响应
+type teiErrorResponse struct {
+	Error     string `json:"error"`
+	ErrorType string `json:"error_type"`
+}
+
+// Rerank 执行重排序，超过模型最大长度的文档由服务端截断
+func (r *TEIReranker) Rerank(ctx context.Context, query string, docs []Document, topK int) ([]Document, error) {
+	if len(docs) == 0 {
+		return []Document{}, nil
+	}
+	topK = clampTopK(topK, len(docs))
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Content
+	}
+	jsonData, err := json.Marshal(teiRequest{Query: query, Texts: texts, Truncate: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", r.baseURL+"/rerank", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teiErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return nil, fmt.Errorf("HTTP %d: failed to decode error response: %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("API error (HTTP %d): %s", resp.StatusCode, errResp.Error)
+	}
+
+	var results []teiResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// TEI 返回全部文档的分数，按分数排序后截取 topK
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+
+	result := make([]Document, 0, len(results))
+	for _, res := range results {
+		if res.Index < 0 || res.Index >= len(docs) {
+			return nil, fmt.Errorf("invalid result index: %d", res.Index)
+		}
+		doc := docs[res.Index]
+		doc.Score = res.Score
+		result = append(result, doc)
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package retriever
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// 自适应分数阈值的调整方向
+const (
+	ThresholdLowered   = "lowered"
+	ThresholdRaised    = "raised"
+	ThresholdUnchanged = "unchanged"
+)
+
+// AdaptiveScoreConfig 自适应分数阈值：通过阈值的结果太少时逐步降低阈值（不低于 Floor），
+// 结果充足时逐步提高阈值（不高于 Ceiling），去掉低分的尾部结果
+type AdaptiveScoreConfig struct {
+	Floor      float64 // 阈值下限，检索时按该值召回候选结果
+	Ceiling    float64 // 阈值上限
+	Step       float64 // 每次调整的幅度
+	MinResults int     // 通过阈值的结果少于该数量时降低阈值
+	MaxResults int     // 通过阈值的结果多于该数量时提高阈值，提高后至少保留该数量的结果，0 表示不提高
+}
+
+// AdaptiveThreshold 根据候选结果的分数计算实际使用的阈值和调整方向，base 为请求或配置的阈值
+func AdaptiveThreshold(docs []*schema.Document, base float64, conf AdaptiveScoreConfig) (float64, string) {
+	count := func(threshold float64) int {
+		n := 0
+		for _, doc := range docs {
+			if doc.Score >= float32(threshold) {
+				n++
+			}
+		}
+		return n
+	}
+	step := conf.Step
+	if step <= 0 {
+		step = 0.05
+	}
+
+	threshold := base
+	switch {
+	case count(threshold) < conf.MinResults && threshold > conf.Floor:
+		for threshold > conf.Floor && count(threshold) < conf.MinResults {
+			threshold = roundThreshold(math.Max(threshold-step, conf.Floor))
+		}
+		return threshold, ThresholdLowered
+	case conf.MaxResults > 0 && count(threshold) > conf.MaxResults && threshold < conf.Ceiling:
+		for threshold < conf.Ceiling {
+			next := roundThreshold(math.Min(threshold+step, conf.Ceiling))
+			if count(next) < conf.MaxResults {
+				break
+			}
+			threshold = next
+		}
+		if threshold > base {
+			return threshold, ThresholdRaised
+		}
+	}
+	return base, ThresholdUnchanged
+}
+
+// roundThreshold 保留 4 位小数，避免逐步加减累积浮点误差
+func roundThreshold(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// FilterByScore 去掉分数低于阈值的结果，并把匹配说明中的分数过滤条件更新为实际使用的阈值
+func FilterByScore(docs []*schema.Document, threshold float64, adjustment string) []*schema.Document {
+	result := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Score < float32(threshold) {
+			continue
+		}
+		if ex, ok := doc.MetaData[ExplainKey].(*Explanation); ok {
+			filters := make([]string, 0, len(ex.Filters))
+			for _, filter := range ex.Filters {
+				if !strings.HasPrefix(filter, "score >= ") {
+					filters = append(filters, filter)
+				}
+			}
+			ex.Filters = append(filters, fmt.Sprintf("score >= %.2f (adaptive, %s)", threshold, adjustment))
+		}
+		result = append(result, doc)
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package retriever
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func docsWithScores(scores ...float32) []*schema.Document {
+	docs := make([]*schema.Document, 0, len(scores))
+	for _, score := range scores {
+		docs = append(docs, &schema.Document{Score: score})
+	}
+	return docs
+}
+
+func TestAdaptiveThreshold(t *testing.T) {
+	conf := AdaptiveScoreConfig{Floor: 0.05, Ceiling: 0.9, Step: 0.05, MinResults: 2, MaxResults: 3}
+	tests := []struct {
+		name          string
+		scores        []float32
+		base          float64
+		wantThreshold float64
+		wantDirection string
+	}{
+		{"lowered until enough results", []float32{0.9, 0.35, 0.15, 0.1}, 0.4, 0.35, ThresholdLowered},
+		{"lowered to floor", []float32{0.01}, 0.3, 0.05, ThresholdLowered},
+		{"raised while enough results remain", []float32{0.9, 0.85, 0.8, 0.7, 0.5, 0.3}, 0.2, 0.8, ThresholdRaised},
+		{"unchanged", []float32{0.5, 0.4, 0.3}, 0.2, 0.2, ThresholdUnchanged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threshold, direction := AdaptiveThreshold(docsWithScores(tt.scores...), tt.base, conf)
+			if threshold != tt.wantThreshold || direction != tt.wantDirection {
+				t.Errorf("AdaptiveThreshold() = %v, %s, want %v, %s", threshold, direction, tt.wantThreshold, tt.wantDirection)
+			}
+		})
+	}
+}
+
+func TestFilterByScore(t *testing.T) {
+	docs := docsWithScores(0.6, 0.3, 0.1)
+	docs[0].MetaData = map[string]any{ExplainKey: &Explanation{Filters: []string{"author contains alice", "score >= 0.05"}}}
+
+	kept := FilterByScore(docs, 0.3, ThresholdRaised)
+	if len(kept) != 2 {
+		t.Fatalf("kept %d documents, want 2", len(kept))
+	}
+	want := []string{"author contains alice", "score >= 0.30 (adaptive, raised)"}
+	if filters := kept[0].MetaData[ExplainKey].(*Explanation).Filters; !reflect.DeepEqual(filters, want) {
+		t.Errorf("Filters = %v, want %v", filters, want)
+	}
+}
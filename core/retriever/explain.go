@@ -0,0 +1,197 @@
+package retriever
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// ExplainKey 检索结果说明在分片元数据中的字段名
+const ExplainKey = "explain"
+
+// Explanation 单个检索结果的匹配说明，帮助知识库维护者定位误匹配的原因
+// 分数为各阶段结束时的分数，未经过的阶段不返回
+type Explanation struct {
+	RetrieveMode   string   `json:"retrieve_mode"`              // 实际使用的检索模式
+	Query          string   `json:"query"`                      // 实际检索的查询，开启查询重写时为改写后的查询
+	MatchedTerms   []string `json:"matched_terms"`              // 查询中出现在分片内容里的关键词（分词规则与关键词检索相同）
+	VectorScore    *float32 `json:"vector_score,omitempty"`     // 向量相似度（0-1）
+	VectorRank     int      `json:"vector_rank,omitempty"`      // 在向量召回中的排名，从 1 开始
+	KeywordScore   *float32 `json:"keyword_score,omitempty"`    // 关键词召回的原始分数（BM25 或稀疏向量内积）
+	KeywordRank    int      `json:"keyword_rank,omitempty"`     // 在关键词召回中的排名，从 1 开始
+	FusedScore     *float32 `json:"fused_score,omitempty"`      // 多路融合后的分数（稀疏加权或 RRF）
+	PreRerankScore *float32 `json:"pre_rerank_score,omitempty"` // 重排序前的分数
+	RerankScore    *float32 `json:"rerank_score,omitempty"`     // 重排序模型给出的分数
+	RerankDelta    *float32 `json:"rerank_delta,omitempty"`     // 重排序分数 - 重排序前的分数
+	RecencyFactor  *float32 `json:"recency_factor,omitempty"`   // 新近度加权系数，最终分数已乘以该系数
+	Boosts         []string `json:"boosts,omitempty"`           // 生效的加权
+	Filters        []string `json:"filters,omitempty"`          // 生效的过滤条件，返回的分片均已通过
+	FinalScore     float32  `json:"final_score"`                // 最终分数
+}
+
+// explainer 记录单次检索各阶段的分数，RetrieveReq.Explain 关闭时为 nil，所有方法都不做任何事
+// 同一文档在召回和重排序阶段只记录第一次出现的分数（rrf 模式会执行两次底层检索），融合分数记录最后一次融合的结果
+type explainer struct {
+	entries map[string]*Explanation
+}
+
+func newExplainer() *explainer {
+	return &explainer{entries: make(map[string]*Explanation)}
+}
+
+func (e *explainer) entry(id string) *Explanation {
+	ex, ok := e.entries[id]
+	if !ok {
+		ex = &Explanation{}
+		e.entries[id] = ex
+	}
+	return ex
+}
+
+// recordVector 记录向量召回的分数和排名
+func (e *explainer) recordVector(docs []*schema.Document) {
+	if e == nil {
+		return
+	}
+	for rank, doc := range docs {
+		if ex := e.entry(doc.ID); ex.VectorScore == nil {
+			ex.VectorScore, ex.VectorRank = scorePtr(doc.Score), rank+1
+		}
+	}
+}
+
+// recordKeyword 记录关键词召回的分数和排名
+func (e *explainer) recordKeyword(docs []*schema.Document) {
+	if e == nil {
+		return
+	}
+	for rank, doc := range docs {
+		if ex := e.entry(doc.ID); ex.KeywordScore == nil {
+			ex.KeywordScore, ex.KeywordRank = scorePtr(doc.Score), rank+1
+		}
+	}
+}
+
+// recordFused 记录融合后的分数
+func (e *explainer) recordFused(docs []*schema.Document) {
+	if e == nil {
+		return
+	}
+	for _, doc := range docs {
+		e.entry(doc.ID).FusedScore = scorePtr(doc.Score)
+	}
+}
+
+// scores 重排序前各文档的分数，供 recordRerank 计算分数变化
+func (e *explainer) scores(docs []*schema.Document) map[string]float32 {
+	if e == nil {
+		return nil
+	}
+	scores := make(map[string]float32, len(docs))
+	for _, doc := range docs {
+		scores[doc.ID] = doc.Score
+	}
+	return scores
+}
+
+// recordRerank 记录重排序分数及相对重排序前分数的变化
+func (e *explainer) recordRerank(before map[string]float32, docs []*schema.Document) {
+	if e == nil {
+		return
+	}
+	for _, doc := range docs {
+		ex := e.entry(doc.ID)
+		if ex.RerankScore != nil {
+			continue
+		}
+		ex.RerankScore = scorePtr(doc.Score)
+		if prev, ok := before[doc.ID]; ok {
+			ex.PreRerankScore = scorePtr(prev)
+			ex.RerankDelta = scorePtr(doc.Score - prev)
+		}
+	}
+}
+
+// recordRecency 记录新近度加权系数
+func (e *explainer) recordRecency(id string, factor float64) {
+	if e == nil {
+		return
+	}
+	e.entry(id).RecencyFactor = scorePtr(float32(factor))
+}
+
+// attach 把说明写入最终返回的分片元数据
+func (e *explainer) attach(conf *config.RetrieverConfig, req *RetrieveReq, docs []*schema.Document) {
+	if e == nil {
+		return
+	}
+	boosts, filters := appliedBoosts(conf), appliedFilters(conf, req)
+	for _, doc := range docs {
+		ex := e.entry(doc.ID)
+		ex.RetrieveMode = string(*req.RetrieveMode)
+		ex.Query = req.optQuery
+		ex.MatchedTerms = vector_store.MatchedTerms(req.optQuery, doc.Content)
+		ex.Boosts, ex.Filters = boosts, filters
+		ex.FinalScore = doc.Score
+		if doc.MetaData == nil {
+			doc.MetaData = make(map[string]any)
+		}
+		doc.MetaData[ExplainKey] = ex
+	}
+}
+
+// appliedBoosts 本次检索生效的加权
+func appliedBoosts(conf *config.RetrieverConfig) []string {
+	var boosts []string
+	if conf.SparseEmbedder != nil && conf.SparseWeight > 0 {
+		boosts = append(boosts, fmt.Sprintf("sparse fusion weight %.2f", conf.SparseWeight))
+	}
+	if conf.RecencyWeight > 0 && conf.RecencyHalfLifeDays > 0 {
+		boosts = append(boosts, fmt.Sprintf("recency weight %.2f, half-life %d days", conf.RecencyWeight, conf.RecencyHalfLifeDays))
+	}
+	return boosts
+}
+
+// appliedFilters 本次检索生效的过滤条件
+func appliedFilters(conf *config.RetrieverConfig, req *RetrieveReq) []string {
+	filters := []string{"access control"}
+	if conf.AsOf != nil {
+		filters = append(filters, "valid as of "+conf.AsOf.Format(time.RFC3339))
+	} else {
+		filters = append(filters, "current document versions")
+	}
+	if f := conf.MetadataFilter; f != nil {
+		var conds []string
+		if f.Title != "" {
+			conds = append(conds, "title~"+f.Title)
+		}
+		if f.Author != "" {
+			conds = append(conds, "author~"+f.Author)
+		}
+		if len(f.Topics) > 0 {
+			conds = append(conds, "topics in ["+strings.Join(f.Topics, ", ")+"]")
+		}
+		if f.DateFrom != "" {
+			conds = append(conds, "date>="+f.DateFrom)
+		}
+		if f.DateTo != "" {
+			conds = append(conds, "date<="+f.DateTo)
+		}
+		filters = append(filters, "metadata: "+strings.Join(conds, ", "))
+	}
+	if len(req.excludeIDs) > 0 {
+		filters = append(filters, fmt.Sprintf("excluded %d chunks", len(req.excludeIDs)))
+	}
+	if req.Score != nil {
+		filters = append(filters, fmt.Sprintf("score >= %.2f", *req.Score))
+	}
+	return filters
+}
+
+func scorePtr(score float32) *float32 {
+	return &score
+}
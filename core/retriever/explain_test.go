@@ -0,0 +1,60 @@
+package retriever
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestExplainerAttach(t *testing.T) {
+	e := newExplainer()
+	vector := []*schema.Document{{ID: "a", Content: "保修期为两年", Score: 0.8}, {ID: "b", Content: "退货流程", Score: 0.6}}
+	keyword := []*schema.Document{{ID: "a", Content: "保修期为两年", Score: 3.2}}
+	e.recordVector(vector)
+	e.recordKeyword(keyword)
+	fused := fuseRRF(vector, keyword)
+	e.recordFused(fused)
+
+	before := e.scores(fused)
+	fused[0].Score = 0.95
+	e.recordRerank(before, fused[:1])
+
+	mode, score := RetrieveModeHybrid, 0.2
+	req := &RetrieveReq{RetrieveMode: &mode, Score: &score, optQuery: "保修期"}
+	conf := &config.RetrieverConfig{MetadataFilter: &config.MetadataFilter{Author: "alice"}}
+	e.attach(conf, req, fused[:1])
+
+	ex, ok := fused[0].MetaData[ExplainKey].(*Explanation)
+	if !ok {
+		t.Fatalf("explanation not attached: %v", fused[0].MetaData)
+	}
+	if !reflect.DeepEqual(ex.MatchedTerms, []string{"保修", "修期"}) {
+		t.Errorf("MatchedTerms = %v", ex.MatchedTerms)
+	}
+	if *ex.VectorScore != 0.8 || ex.VectorRank != 1 || *ex.KeywordScore != 3.2 || ex.KeywordRank != 1 {
+		t.Errorf("recall scores = %v/%d, %v/%d", *ex.VectorScore, ex.VectorRank, *ex.KeywordScore, ex.KeywordRank)
+	}
+	if *ex.FusedScore != 1 || *ex.PreRerankScore != 1 || *ex.RerankScore != 0.95 || *ex.RerankDelta > -0.049 || ex.FinalScore != 0.95 {
+		t.Errorf("fused/rerank = %v, %v, %v, %v, final %v", *ex.FusedScore, *ex.PreRerankScore, *ex.RerankScore, *ex.RerankDelta, ex.FinalScore)
+	}
+	wantFilters := []string{"access control", "current document versions", "metadata: author~alice", "score >= 0.20"}
+	if !reflect.DeepEqual(ex.Filters, wantFilters) {
+		t.Errorf("Filters = %v, want %v", ex.Filters, wantFilters)
+	}
+	if _, ok := vector[1].MetaData[ExplainKey]; ok {
+		t.Errorf("explanation attached to a doc that was not returned")
+	}
+}
+
+func TestExplainerDisabled(t *testing.T) {
+	var e *explainer
+	docs := []*schema.Document{{ID: "a", Score: 0.5}}
+	e.recordVector(docs)
+	e.recordRerank(e.scores(docs), docs)
+	e.attach(&config.RetrieverConfig{}, &RetrieveReq{}, docs)
+	if docs[0].MetaData != nil {
+		t.Errorf("disabled explainer must not touch metadata, got %v", docs[0].MetaData)
+	}
+}
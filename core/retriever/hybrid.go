@@ -0,0 +1,102 @@
+package retriever
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// rrfK RRF 常数，降低排名靠前文档之间的分数差距
+const rrfK = 60.0
+
+// retrieveWithHybrid 关键词与向量两路召回后按 RRF 融合：向量检索擅长语义相近的表述，
+// 关键词检索擅长型号、编号、专有名词等精确匹配，融合后不需要 rerank 模型
+// 关键词检索失败时记录告警并只使用向量检索结果
+func retrieveWithHybrid(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
+	dense, err := retrieveDense(ctx, conf, req)
+	if err != nil {
+		g.Log().Errorf(ctx, "retrieve failed, err=%v", err)
+		return nil, err
+	}
+
+	keyword, err := keywordSearch(ctx, conf, req, candidateTopK(conf, req))
+	if err != nil {
+		g.Log().Warningf(ctx, "Keyword search failed, using vector results only: %v", err)
+	}
+	g.Log().Infof(ctx, "Hybrid retrieval: %d vector docs, %d keyword docs", len(dense), len(keyword))
+	req.explain.recordKeyword(keyword)
+	fused := fuseRRF(dense, keyword)
+	req.explain.recordFused(fused)
+
+	// 按调用方权限和文档有效期过滤，需在截取 TopK 之前执行
+	docs := filterRetrievable(ctx, conf, fused)
+	if len(docs) > *req.TopK {
+		docs = docs[:*req.TopK]
+	}
+
+	// 过滤低分文档
+	relatedDocs := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Score < float32(*req.Score) {
+			g.Log().Debugf(ctx, "score less: %v, related: %v", doc.Score, doc.Content)
+			continue
+		}
+		relatedDocs = append(relatedDocs, doc)
+	}
+	return relatedDocs, nil
+}
+
+// keywordSearch 关键词召回：知识库配置了稀疏模型时使用稀疏向量检索，否则使用向量库的全文检索
+func keywordSearch(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq, topK int) ([]*schema.Document, error) {
+	if conf.SparseEmbedder != nil {
+		return sparseSearch(ctx, conf, req, topK)
+	}
+
+	var options []vector_store.Option
+	options = append(options, vector_store.WithKnowledgeID(req.KnowledgeId))
+	docs, err := conf.VectorStore.KeywordSearch(ctx, req.KnowledgeId, req.optQuery, topK, options...)
+	if err != nil {
+		return nil, err
+	}
+	return excludeDocs(docs, req.excludeIDs), nil
+}
+
+// fuseRRF 按 RRF 融合多路已排序的检索结果：score = sum(1/(k+rank))，
+// 按有结果的路数能得到的最高分归一化到 0-1，同一文档保留最先出现的一路中的对象，同一路中重复的文档只计一次
+func fuseRRF(lists ...[]*schema.Document) []*schema.Document {
+	scores := make(map[string]float64)
+	var docs []*schema.Document
+	nonEmpty := 0
+	for _, list := range lists {
+		if len(list) > 0 {
+			nonEmpty++
+		}
+		seen := make(map[string]bool, len(list))
+		for rank, doc := range list {
+			if seen[doc.ID] {
+				continue
+			}
+			seen[doc.ID] = true
+			if _, exists := scores[doc.ID]; !exists {
+				docs = append(docs, doc)
+			}
+			scores[doc.ID] += 1.0 / (rrfK + float64(rank+1))
+		}
+	}
+	if nonEmpty == 0 {
+		return []*schema.Document{}
+	}
+
+	maxPossibleScore := float64(nonEmpty) / (rrfK + 1.0)
+	for _, doc := range docs {
+		doc.Score = float32(min(scores[doc.ID]/maxPossibleScore, 1.0))
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+	return docs
+}
@@ -0,0 +1,59 @@
+package retriever
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestFuseRRF(t *testing.T) {
+	tests := []struct {
+		name    string
+		vector  []*schema.Document
+		keyword []*schema.Document
+		wantIDs []string
+		want    []float32
+	}{
+		{
+			name:    "doc found by both legs ranks first",
+			vector:  []*schema.Document{{ID: "a"}, {ID: "b"}},
+			keyword: []*schema.Document{{ID: "b"}, {ID: "c"}},
+			wantIDs: []string{"b", "a", "c"},
+			want:    []float32{0.9919, 0.5, 0.4919},
+		},
+		{
+			name:    "single leg is not penalized",
+			vector:  []*schema.Document{{ID: "a"}, {ID: "b"}},
+			wantIDs: []string{"a", "b"},
+			want:    []float32{1, 0.9839},
+		},
+		{
+			name:    "duplicates within a leg count once",
+			vector:  []*schema.Document{{ID: "a"}, {ID: "a"}},
+			keyword: []*schema.Document{{ID: "a"}},
+			wantIDs: []string{"a"},
+			want:    []float32{1},
+		},
+		{
+			name:    "no results",
+			wantIDs: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fuseRRF(tt.vector, tt.keyword)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d docs, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, doc := range got {
+				if doc.ID != tt.wantIDs[i] {
+					t.Errorf("doc %d = %s, want %s", i, doc.ID, tt.wantIDs[i])
+				}
+				if math.Abs(float64(doc.Score-tt.want[i])) > 1e-3 {
+					t.Errorf("doc %s score = %.4f, want %.4f", doc.ID, doc.Score, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package retriever
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// filterMetadata 过滤不满足元数据条件的文档分片，查询元数据失败时不过滤
+func filterMetadata(ctx context.Context, docs []*schema.Document, filter *config.MetadataFilter) []*schema.Document {
+	if filter == nil || len(docs) == 0 {
+		return docs
+	}
+
+	metadata, err := knowledge.GetDocumentsMetadata(ctx, documentIDs(docs))
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to load document metadata, skipping metadata filter: %v", err)
+		return docs
+	}
+
+	filtered := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if matchesMetadata(metadata[documentIDOf(doc)], filter) {
+			filtered = append(filtered, doc)
+		}
+	}
+	if dropped := len(docs) - len(filtered); dropped > 0 {
+		g.Log().Debugf(ctx, "Metadata filter dropped %d of %d chunks", dropped, len(docs))
+	}
+	return filtered
+}
+
+// matchesMetadata 文档元数据是否满足全部过滤条件
+func matchesMetadata(m knowledge.DocumentMetadata, filter *config.MetadataFilter) bool {
+	if filter.Title != "" && !containsFold(m.Title, filter.Title) {
+		return false
+	}
+	if filter.Author != "" && !containsFold(m.Author, filter.Author) {
+		return false
+	}
+	if len(filter.Topics) > 0 && !hasAnyTopic(m.Topics, filter.Topics) {
+		return false
+	}
+	// 日期可能只精确到月或年，按时间段是否重叠判断，如 2024-03 同时满足 date_from=2024-03-15 和 date_to=2024-03-01
+	if filter.DateFrom != "" && (m.Date == "" || m.Date < filter.DateFrom[:min(len(m.Date), len(filter.DateFrom))]) {
+		return false
+	}
+	if filter.DateTo != "" && (m.Date == "" || m.Date > filter.DateTo) {
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func hasAnyTopic(topics, wanted []string) bool {
+	for _, topic := range topics {
+		for _, w := range wanted {
+			if strings.EqualFold(strings.TrimSpace(topic), strings.TrimSpace(w)) {
+				return true
+			}
+		}
+	}
+	return false
+}
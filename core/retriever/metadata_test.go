@@ -0,0 +1,41 @@
+package retriever
+
+import (
+	"testing"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+)
+
+func TestMatchesMetadata(t *testing.T) {
+	doc := knowledge.DocumentMetadata{Title: "2024 年度报告", Author: "ACME 财务部", Date: "2024-03", Topics: []string{"财务", "Strategy"}}
+	tests := []struct {
+		name   string
+		filter config.MetadataFilter
+		want   bool
+	}{
+		{"empty filter", config.MetadataFilter{}, true},
+		{"title contains", config.MetadataFilter{Title: "年度"}, true},
+		{"author case-insensitive", config.MetadataFilter{Author: "acme"}, true},
+		{"author mismatch", config.MetadataFilter{Author: "法务"}, false},
+		{"any topic", config.MetadataFilter{Topics: []string{"法律", "strategy"}}, true},
+		{"no topic", config.MetadataFilter{Topics: []string{"法律"}}, false},
+		{"month overlaps date_from", config.MetadataFilter{DateFrom: "2024-03-15"}, true},
+		{"month overlaps date_to", config.MetadataFilter{DateTo: "2024-03-01"}, true},
+		{"before date_from", config.MetadataFilter{DateFrom: "2024-04-01"}, false},
+		{"after date_to", config.MetadataFilter{DateTo: "2024-02-29"}, false},
+		{"all conditions", config.MetadataFilter{Author: "ACME", Topics: []string{"财务"}, DateFrom: "2024-01-01", DateTo: "2024-12-31"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesMetadata(doc, &tt.filter); got != tt.want {
+				t.Errorf("matchesMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 未提取到日期的文档不满足日期条件
+	if matchesMetadata(knowledge.DocumentMetadata{}, &config.MetadataFilter{DateTo: "2024-12-31"}) {
+		t.Error("document without date should not match a date filter")
+	}
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/config"
-	"github.com/Malowking/kbgo/core/formatter"
 	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -62,8 +61,7 @@ func Retrieve(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveRe
 	g.Log().Infof(ctx, "Selected LLM model for rewrite: %s (Provider: %s)", selectedModel.Name, selectedModel.Provider)
 
 	// 创建模型服务
-	modelFormatter := formatter.NewOpenAIFormatter()
-	modelService := model.NewModelService(selectedModel.APIKey, selectedModel.BaseURL, modelFormatter)
+	modelService := model.NewModelServiceFor(selectedModel, model.FormatterFor(selectedModel))
 
 	// 确定重写次数，默认为3次
 	rewriteAttempts := *req.RewriteAttempts
@@ -156,21 +154,47 @@ func Retrieve(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveRe
 	return msg, nil
 }
 
-// retrieveDoOnce 单次检索分发
+// retrieveDoOnce 单次检索，按最终分数叠加新近度加权；开启 Explain 时在结果中附加匹配说明
 func retrieveDoOnce(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
+	if req.Explain {
+		req.explain = newExplainer()
+	}
+	docs, err := retrieveByMode(ctx, conf, req)
+	if err != nil {
+		return nil, err
+	}
+	docs = applyRecencyBoost(ctx, conf, docs, req.explain)
+	req.explain.attach(conf, req, docs)
+	return docs, nil
+}
+
+// retrieveByMode 单次检索分发
+func retrieveByMode(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
 	g.Log().Infof(ctx, "query: %v, retrieve_mode: %v", req.optQuery, *req.RetrieveMode)
 
 	// 根据检索模式选择不同的处理策略
 	switch *req.RetrieveMode {
 	case RetrieveModeMilvus:
 		// 模式1: 仅使用Milvus向量检索，直接调用VectorStore的方法
-		return conf.VectorStore.VectorSearchOnly(ctx, conf, req.optQuery, req.KnowledgeId, *req.TopK, *req.Score)
+		// 知识库配置了稀疏模型时，使用稠密+稀疏融合检索
+		if conf.SparseEmbedder != nil {
+			return retrieveHybridOnly(ctx, conf, req)
+		}
+		docs, err := conf.VectorStore.VectorSearchOnly(ctx, conf, req.optQuery, req.KnowledgeId, *req.TopK, *req.Score)
+		if err != nil {
+			return nil, err
+		}
+		req.explain.recordVector(docs)
+		return filterRetrievable(ctx, conf, docs), nil
 	case RetrieveModeRerank:
 		// 模式2: Milvus + Rerank
 		return retrieveWithRerank(ctx, conf, req)
 	case RetrieveModeRRF:
 		// 模式3: RRF混合检索
 		return retrieveWithRRF(ctx, conf, req)
+	case RetrieveModeHybrid:
+		// 模式4: 关键词 + 向量检索，RRF融合
+		return retrieveWithHybrid(ctx, conf, req)
 	default:
 		// 默认使用Rerank模式
 		return retrieveWithRerank(ctx, conf, req)
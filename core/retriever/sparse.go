@@ -0,0 +1,133 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// fuseWithSparse 对知识库配置了稀疏模型的检索，补充稀疏向量检索结果并与稠密分数加权融合
+// 稀疏检索失败时记录告警并返回原始稠密结果
+func fuseWithSparse(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq, dense []*schema.Document, topK int) []*schema.Document {
+	if conf.SparseEmbedder == nil || conf.SparseWeight <= 0 {
+		return dense
+	}
+
+	sparse, err := sparseSearch(ctx, conf, req, topK)
+	if err != nil {
+		g.Log().Warningf(ctx, "%v, using dense results only", err)
+		return dense
+	}
+
+	g.Log().Infof(ctx, "Sparse search returned %d docs, fusing with %d dense docs (weight: %.2f)",
+		len(sparse), len(dense), conf.SparseWeight)
+	req.explain.recordKeyword(sparse)
+	fused := fuseScores(dense, sparse, conf.SparseWeight)
+	req.explain.recordFused(fused)
+	return fused
+}
+
+// sparseSearch 使用知识库的稀疏模型检索，排除已检索过的ID
+func sparseSearch(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq, topK int) ([]*schema.Document, error) {
+	queryVectors, err := conf.SparseEmbedder.EmbedSparse(ctx, []string{req.optQuery})
+	if err != nil || len(queryVectors) != 1 {
+		return nil, fmt.Errorf("sparse query embedding failed: %v", err)
+	}
+
+	sparse, err := conf.VectorStore.SparseSearch(ctx, req.KnowledgeId, queryVectors[0], topK, vector_store.WithKnowledgeID(req.KnowledgeId))
+	if err != nil {
+		return nil, fmt.Errorf("sparse search failed: %w", err)
+	}
+
+	// 稀疏检索不支持 filter 表达式，这里手动排除已检索过的ID
+	return excludeDocs(sparse, req.excludeIDs), nil
+}
+
+// excludeDocs 去掉 ID 在 excludeIDs 中的文档
+func excludeDocs(docs []*schema.Document, excludeIDs []string) []*schema.Document {
+	if len(excludeIDs) == 0 {
+		return docs
+	}
+	excluded := make(map[string]bool, len(excludeIDs))
+	for _, id := range excludeIDs {
+		excluded[id] = true
+	}
+	kept := docs[:0]
+	for _, doc := range docs {
+		if !excluded[doc.ID] {
+			kept = append(kept, doc)
+		}
+	}
+	return kept
+}
+
+// retrieveHybridOnly 不经过 rerank 的稠密+稀疏融合检索，按融合分数截取 TopK 并过滤低分文档
+func retrieveHybridOnly(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
+	docs, err := retrieve(ctx, conf, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) > *req.TopK {
+		docs = docs[:*req.TopK]
+	}
+	relatedDocs := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Score < float32(*req.Score) {
+			continue
+		}
+		relatedDocs = append(relatedDocs, doc)
+	}
+	return relatedDocs, nil
+}
+
+// fuseScores 按 (1-w)*稠密分数 + w*归一化稀疏分数 融合两路结果，并按融合分数降序排列
+// 稀疏分数（内积）按本次结果中的最大值归一化到 0-1，只出现在一路结果中的文档另一路按 0 计
+func fuseScores(dense, sparse []*schema.Document, weight float64) []*schema.Document {
+	if len(sparse) == 0 {
+		return dense
+	}
+
+	var maxSparse float32
+	for _, doc := range sparse {
+		if doc.Score > maxSparse {
+			maxSparse = doc.Score
+		}
+	}
+
+	sparseScores := make(map[string]float64, len(sparse))
+	for _, doc := range sparse {
+		if maxSparse > 0 {
+			sparseScores[doc.ID] = float64(doc.Score / maxSparse)
+		}
+	}
+
+	fused := make([]*schema.Document, 0, len(dense)+len(sparse))
+	seen := make(map[string]bool, len(dense)+len(sparse))
+	for _, doc := range dense {
+		if seen[doc.ID] {
+			continue
+		}
+		seen[doc.ID] = true
+		doc.Score = float32((1-weight)*float64(doc.Score) + weight*sparseScores[doc.ID])
+		fused = append(fused, doc)
+	}
+	for _, doc := range sparse {
+		if seen[doc.ID] {
+			continue
+		}
+		seen[doc.ID] = true
+		doc.Score = float32(weight * sparseScores[doc.ID])
+		fused = append(fused, doc)
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
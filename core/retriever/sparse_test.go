@@ -0,0 +1,60 @@
+package retriever
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestFuseScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		dense   []*schema.Document
+		sparse  []*schema.Document
+		weight  float64
+		wantIDs []string
+		want    []float32
+	}{
+		{
+			name:    "no sparse results keeps dense",
+			dense:   []*schema.Document{{ID: "a", Score: 0.8}, {ID: "b", Score: 0.6}},
+			weight:  0.3,
+			wantIDs: []string{"a", "b"},
+			want:    []float32{0.8, 0.6},
+		},
+		{
+			name:    "exact term match is boosted",
+			dense:   []*schema.Document{{ID: "a", Score: 0.8}, {ID: "b", Score: 0.7}},
+			sparse:  []*schema.Document{{ID: "b", Score: 12}, {ID: "a", Score: 3}},
+			weight:  0.5,
+			wantIDs: []string{"b", "a"},
+			want:    []float32{0.85, 0.525},
+		},
+		{
+			name:    "sparse only docs are appended",
+			dense:   []*schema.Document{{ID: "a", Score: 0.6}},
+			sparse:  []*schema.Document{{ID: "c", Score: 4}},
+			weight:  0.3,
+			wantIDs: []string{"a", "c"},
+			want:    []float32{0.42, 0.3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fuseScores(tt.dense, tt.sparse, tt.weight)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("fuseScores() returned %d docs, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, doc := range got {
+				if doc.ID != tt.wantIDs[i] {
+					t.Errorf("doc[%d].ID = %s, want %s", i, doc.ID, tt.wantIDs[i])
+				}
+				if math.Abs(float64(doc.Score-tt.want[i])) > 1e-5 {
+					t.Errorf("doc[%d].Score = %v, want %v", i, doc.Score, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -2,20 +2,19 @@ package retriever
 
 import (
 	"context"
-	"math"
-	"sort"
 
 	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/reranker"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
-// convertToRerankDocs 将 schema.Document 转换为 common.RerankDocument
-func convertToRerankDocs(docs []*schema.Document) []common.RerankDocument {
-	result := make([]common.RerankDocument, len(docs))
+// convertToRerankDocs 将 schema.Document 转换为 reranker.Document
+func convertToRerankDocs(docs []*schema.Document) []reranker.Document {
+	result := make([]reranker.Document, len(docs))
 	for i, doc := range docs {
-		result[i] = common.RerankDocument{
+		result[i] = reranker.Document{
 			ID:      doc.ID,
 			Content: doc.Content,
 			Score:   float64(doc.Score), // Convert float32 to float64 for reranker
@@ -24,8 +23,8 @@ func convertToRerankDocs(docs []*schema.Document) []common.RerankDocument {
 	return result
 }
 
-// convertFromRerankDocs 将 common.RerankDocument 转换回 schema.Document
-func convertFromRerankDocs(rerankDocs []common.RerankDocument, originalDocs []*schema.Document) []*schema.Document {
+// convertFromRerankDocs 将 reranker.Document 转换回 schema.Document
+func convertFromRerankDocs(rerankDocs []reranker.Document, originalDocs []*schema.Document) []*schema.Document {
 	// 创建一个映射，快速查找原始文档
 	docMap := make(map[string]*schema.Document)
 	for _, doc := range originalDocs {
@@ -58,7 +57,7 @@ func retrieveWithRerank(ctx context.Context, conf *config.RetrieverConfig, req *
 	})
 
 	// 创建 rerank 客户端
-	reranker, err := common.NewReranker(ctx, conf)
+	rr, err := reranker.New(ctx, conf)
 	if err != nil {
 		g.Log().Errorf(ctx, "Failed to create reranker, err=%v", err)
 		return nil, err
@@ -66,9 +65,10 @@ func retrieveWithRerank(ctx context.Context, conf *config.RetrieverConfig, req *
 
 	// 转换文档格式
 	rerankDocs := convertToRerankDocs(docs)
+	before := req.explain.scores(docs)
 
 	// 使用Rerank重排序，直接使用req中已设置好的TopK
-	rerankResults, err := reranker.Rerank(ctx, req.optQuery, rerankDocs, *req.TopK)
+	rerankResults, err := rr.Rerank(ctx, req.optQuery, rerankDocs, *req.TopK)
 	if err != nil {
 		g.Log().Errorf(ctx, "Rerank failed, err=%v", err)
 		return nil, err
@@ -76,6 +76,7 @@ func retrieveWithRerank(ctx context.Context, conf *config.RetrieverConfig, req *
 
 	// 转换回 schema.Document
 	docs = convertFromRerankDocs(rerankResults, docs)
+	req.explain.recordRerank(before, docs)
 
 	// 过滤低分文档
 	var relatedDocs []*schema.Document
@@ -92,8 +93,6 @@ func retrieveWithRerank(ctx context.Context, conf *config.RetrieverConfig, req *
 // retrieveWithRRF 使用RRF (Reciprocal Rank Fusion) 混合检索
 // RRF公式: score = sum(1/(k+rank)), k通常为60
 func retrieveWithRRF(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
-	const k = 60.0 // RRF常数
-
 	// 1. 原始查询检索
 	docs1, err := retrieve(ctx, conf, req)
 	if err != nil {
@@ -109,7 +108,7 @@ func retrieveWithRRF(ctx context.Context, conf *config.RetrieverConfig, req *Ret
 	}
 
 	// 创建 rerank 客户端
-	reranker, err := common.NewReranker(ctx, conf)
+	rr, err := reranker.New(ctx, conf)
 	if err != nil {
 		g.Log().Errorf(ctx, "Failed to create reranker, err=%v", err)
 		return nil, err
@@ -117,57 +116,25 @@ func retrieveWithRRF(ctx context.Context, conf *config.RetrieverConfig, req *Ret
 
 	// 转换文档格式并执行 rerank
 	rerankDocs2 := convertToRerankDocs(docs2)
-	rerankResults2, err := reranker.Rerank(ctx, req.optQuery, rerankDocs2, (*req.TopK)*2)
+	before := req.explain.scores(docs2)
+	rerankResults2, err := rr.Rerank(ctx, req.optQuery, rerankDocs2, (*req.TopK)*2)
 	if err != nil {
 		g.Log().Errorf(ctx, "Rerank failed, err=%v", err)
 		return nil, err
 	}
 	docs2 = convertFromRerankDocs(rerankResults2, docs2)
+	req.explain.recordRerank(before, docs2)
 
-	// 3. RRF融合
-	rrfScores := make(map[string]float64) // docID -> RRF score
-	docMap := make(map[string]*schema.Document)
-
-	// 计算第一路检索的RRF分数
-	for rank, doc := range docs1 {
-		rrfScore := 1.0 / (k + float64(rank+1))
-		rrfScores[doc.ID] = rrfScore
-		docMap[doc.ID] = doc
-	}
-
-	// 计算第二路检索的RRF分数并累加
-	for rank, doc := range docs2 {
-		rrfScore := 1.0 / (k + float64(rank+1))
-		rrfScores[doc.ID] += rrfScore
-		if _, exists := docMap[doc.ID]; !exists {
-			docMap[doc.ID] = doc
-		}
-	}
-
-	// 4. 将RRF分数转换为文档列表
-	var docs []*schema.Document
-	for docID, doc := range docMap {
-		// 归一化RRF分数到0-1范围
-		// 最大可能分数是 2/(k+1) (两路都排第一)
-		maxPossibleScore := 2.0 / (k + 1.0)
-		normalizedScore := rrfScores[docID] / maxPossibleScore
-		normalizedScore = math.Min(normalizedScore, 1.0) // 确保不超过1
-
-		doc.Score = float32(normalizedScore) // Convert to float32
-		docs = append(docs, doc)
-	}
-
-	// 5. 按RRF分数排序
-	sort.Slice(docs, func(i, j int) bool {
-		return docs[i].Score > docs[j].Score
-	})
+	// 3. RRF融合，按分数排序
+	docs := fuseRRF(docs1, docs2)
+	req.explain.recordFused(docs)
 
-	// 6. 截取TopK，直接使用req中已设置好的TopK
+	// 4. 截取TopK，直接使用req中已设置好的TopK
 	if len(docs) > *req.TopK {
 		docs = docs[:*req.TopK]
 	}
 
-	// 7. 过滤低分文档
+	// 5. 过滤低分文档
 	var relatedDocs []*schema.Document
 	for _, doc := range docs {
 		if doc.Score < float32(*req.Score) {
@@ -0,0 +1,110 @@
+package retriever
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// referenceTime 检索的参考时间：指定了历史时间点时使用该时间，否则为当前时间
+func referenceTime(conf *config.RetrieverConfig) time.Time {
+	if conf.AsOf != nil {
+		return *conf.AsOf
+	}
+	return time.Now()
+}
+
+// filterExpired 过滤在参考时间点已过期、尚未生效或已被新版本取代的文档分片，查询时效信息失败时不过滤
+func filterExpired(ctx context.Context, docs []*schema.Document, at time.Time) []*schema.Document {
+	if len(docs) == 0 {
+		return docs
+	}
+
+	validity, err := knowledge.GetDocumentsValidity(ctx, documentIDs(docs))
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to load document validity, skipping expiry filter: %v", err)
+		return docs
+	}
+
+	filtered := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if v, ok := validity[documentIDOf(doc)]; ok && !v.IsValidAt(at) {
+			continue
+		}
+		filtered = append(filtered, doc)
+	}
+	if dropped := len(docs) - len(filtered); dropped > 0 {
+		g.Log().Debugf(ctx, "Validity filter dropped %d of %d chunks", dropped, len(docs))
+	}
+	return filtered
+}
+
+// applyRecencyBoost 按文档生效时间（或创建时间）对分数做新近度加权并重新排序，加权系数记录到 e
+func applyRecencyBoost(ctx context.Context, conf *config.RetrieverConfig, docs []*schema.Document, e *explainer) []*schema.Document {
+	if conf.RecencyWeight <= 0 || conf.RecencyHalfLifeDays <= 0 || len(docs) == 0 {
+		return docs
+	}
+
+	validity, err := knowledge.GetDocumentsValidity(ctx, documentIDs(docs))
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to load document validity, skipping recency boost: %v", err)
+		return docs
+	}
+
+	now := referenceTime(conf)
+	for _, doc := range docs {
+		v, ok := validity[documentIDOf(doc)]
+		if !ok || v.EffectiveTime() == nil {
+			continue
+		}
+		ageDays := now.Sub(*v.EffectiveTime()).Hours() / 24
+		factor := recencyFactor(ageDays, conf.RecencyWeight, float64(conf.RecencyHalfLifeDays))
+		doc.Score *= float32(factor)
+		e.recordRecency(doc.ID, factor)
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+	return docs
+}
+
+// recencyFactor 新近度系数：(1-w) + w*0.5^(age/halfLife)，新文档接近 1，越旧越接近 1-w
+func recencyFactor(ageDays, weight, halfLifeDays float64) float64 {
+	if ageDays < 0 {
+		ageDays = 0
+	}
+	decay := math.Pow(0.5, ageDays/halfLifeDays)
+	return 1 - weight + weight*decay
+}
+
+// documentIDs 收集分片所属的文档ID（去重）
+func documentIDs(docs []*schema.Document) []string {
+	seen := make(map[string]bool, len(docs))
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		id := documentIDOf(doc)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// documentIDOf 获取分片所属的文档ID
+func documentIDOf(doc *schema.Document) string {
+	if doc == nil || doc.MetaData == nil {
+		return ""
+	}
+	id, _ := doc.MetaData[common.DocumentId].(string)
+	return id
+}
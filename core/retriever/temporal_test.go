@@ -0,0 +1,62 @@
+package retriever
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+)
+
+func TestRecencyFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		ageDays  float64
+		weight   float64
+		halfLife float64
+		want     float64
+	}{
+		{name: "brand new document keeps score", ageDays: 0, weight: 0.4, halfLife: 180, want: 1},
+		{name: "one half-life halves the boost", ageDays: 180, weight: 0.4, halfLife: 180, want: 0.8},
+		{name: "future dated treated as new", ageDays: -10, weight: 0.4, halfLife: 180, want: 1},
+		{name: "very old approaches 1-w", ageDays: 18000, weight: 0.4, halfLife: 180, want: 0.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := recencyFactor(tt.ageDays, tt.weight, tt.halfLife)
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("recencyFactor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentValidityIsValidAt(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	before := now.AddDate(0, -1, 0)
+	after := now.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name     string
+		validity knowledge.DocumentValidity
+		want     bool
+	}{
+		{name: "no window", validity: knowledge.DocumentValidity{}, want: true},
+		{name: "within window", validity: knowledge.DocumentValidity{ValidFrom: &before, ValidUntil: &after}, want: true},
+		{name: "expired", validity: knowledge.DocumentValidity{ValidUntil: &before}, want: false},
+		{name: "not yet effective", validity: knowledge.DocumentValidity{ValidFrom: &after}, want: false},
+		{name: "expires exactly now", validity: knowledge.DocumentValidity{ValidUntil: &now}, want: false},
+		{name: "superseded by newer version", validity: knowledge.DocumentValidity{CreateTime: &before, SupersededAt: &now}, want: false},
+		{name: "superseded later is valid as of now", validity: knowledge.DocumentValidity{CreateTime: &before, SupersededAt: &after}, want: true},
+		{name: "uploaded after reference time", validity: knowledge.DocumentValidity{CreateTime: &after}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.validity.IsValidAt(now); got != tt.want {
+				t.Errorf("IsValidAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ const (
 	RetrieveModeRerank RetrieveMode = "rerank"
 	// RetrieveModeRRF 使用RRF (Reciprocal Rank Fusion) 混合检索
 	RetrieveModeRRF RetrieveMode = "rrf"
+	// RetrieveModeHybrid 关键词（BM25 全文检索，知识库配置了稀疏模型时使用稀疏向量）与向量检索两路召回，按 RRF 融合，不需要 rerank 模型
+	RetrieveModeHybrid RetrieveMode = "hybrid"
 )
 
 // RetrieveReq 检索请求参数
@@ -26,10 +28,12 @@ type RetrieveReq struct {
 	EnableRewrite   *bool         // 是否启用查询重写（可选）
 	RewriteAttempts *int          // 查询重写尝试次数（可选）
 	RetrieveMode    *RetrieveMode // 检索模式（可选）
+	Explain         bool          // 是否在结果分片元数据中返回匹配说明（ExplainKey）
 
 	// 内部使用字段
-	optQuery   string   // 优化后的检索关键词（内部使用）
-	excludeIDs []string // 要排除的 _id 列表（内部使用）
+	optQuery   string     // 优化后的检索关键词（内部使用）
+	excludeIDs []string   // 要排除的 _id 列表（内部使用）
+	explain    *explainer // 单次检索的匹配说明记录，Explain 开启时由 retrieveDoOnce 创建（内部使用）
 }
 
 // Copy 创建请求的副本
@@ -42,6 +46,7 @@ func (r *RetrieveReq) Copy() *RetrieveReq {
 		EnableRewrite:   r.EnableRewrite,
 		RewriteAttempts: r.RewriteAttempts,
 		RetrieveMode:    r.RetrieveMode,
+		Explain:         r.Explain,
 		optQuery:        r.optQuery,
 		excludeIDs:      r.excludeIDs,
 	}
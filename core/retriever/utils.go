@@ -5,12 +5,29 @@ import (
 
 	"github.com/Malowking/kbgo/core/config"
 	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/internal/logic/security"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
 // retrieve 执行底层的 Milvus 检索
 func retrieve(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
+	msg, err := retrieveDense(ctx, conf, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 知识库配置了稀疏模型时，融合稀疏向量检索结果
+	msg = fuseWithSparse(ctx, conf, req, msg, candidateTopK(conf, req))
+
+	// 按调用方权限和文档有效期过滤，需在 rerank 之前执行
+	msg = filterRetrievable(ctx, conf, msg)
+
+	return msg, nil
+}
+
+// retrieveDense 稠密向量检索，返回 candidateTopK 个候选，分数归一化到 0-1
+func retrieveDense(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveReq) ([]*schema.Document, error) {
 	var filter string
 	// 如果有需要排除的ID，添加到 filter 中
 	if len(req.excludeIDs) > 0 {
@@ -38,21 +55,9 @@ func retrieve(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveRe
 		return nil, err
 	}
 
-	// 获取 TopK 值（从配置或请求中）
-	topK := conf.TopK
-	if req.TopK != nil {
-		topK = *req.TopK
-	}
-
-	// 因为后续会经过 rerank 重新排序，所以增大TopK
-	realTopK := topK * 3 // 取3倍数量，给 rerank 更多选择空间
-	if realTopK < 15 {
-		realTopK = 15 // 至少取15个
-	}
-
-	// 执行检索
+	// 执行检索，限定在知识库内，避免共享集合或误写入的分片跨知识库泄露
 	var options []vector_store.Option
-	options = append(options, vector_store.WithTopK(realTopK))
+	options = append(options, vector_store.WithTopK(candidateTopK(conf, req)), vector_store.WithKnowledgeID(req.KnowledgeId))
 
 	// 只有在有过滤条件时才添加 filter
 	if filter != "" {
@@ -71,6 +76,29 @@ func retrieve(ctx context.Context, conf *config.RetrieverConfig, req *RetrieveRe
 		normalizedScore := s.Score / 2.0
 		s.Score = normalizedScore
 	}
-
+	req.explain.recordVector(msg)
 	return msg, nil
 }
+
+// candidateTopK 底层检索的候选数量
+func candidateTopK(conf *config.RetrieverConfig, req *RetrieveReq) int {
+	// 获取 TopK 值（从配置或请求中）
+	topK := conf.TopK
+	if req.TopK != nil {
+		topK = *req.TopK
+	}
+
+	// 因为后续会经过 rerank 重新排序，所以增大TopK
+	realTopK := topK * 3 // 取3倍数量，给 rerank 更多选择空间
+	if realTopK < 15 {
+		realTopK = 15 // 至少取15个
+	}
+	return realTopK
+}
+
+// filterRetrievable 过滤调用方无权访问的分片、在检索时间点不是有效版本的文档以及不满足元数据条件的文档
+func filterRetrievable(ctx context.Context, conf *config.RetrieverConfig, docs []*schema.Document) []*schema.Document {
+	docs = security.FilterDocuments(ctx, docs)
+	docs = filterExpired(ctx, docs, referenceTime(conf))
+	return filterMetadata(ctx, docs, conf.MetadataFilter)
+}
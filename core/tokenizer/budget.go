@@ -0,0 +1,21 @@
+package tokenizer
+
+import (
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// MinCompletionTokens 按上下文窗口收缩后至少保留的生成 token 数
+const MinCompletionTokens = 256
+
+// CompletionBudget 按模型上下文窗口限制生成 token 数：不超过 maxTokens（<= 0 表示不限制），也不超过窗口减去输入后的剩余空间
+// contextWindow <= 0 表示未配置窗口，直接返回 maxTokens；剩余空间不足时至少保留 MinCompletionTokens，由服务商报告输入超长
+func CompletionBudget(modelName string, messages []*schema.Message, contextWindow, maxTokens int) int {
+	if contextWindow <= 0 {
+		return maxTokens
+	}
+	budget := max(contextWindow-CountMessages(modelName, messages), MinCompletionTokens)
+	if maxTokens > 0 && maxTokens < budget {
+		return maxTokens
+	}
+	return budget
+}
@@ -0,0 +1,211 @@
+// Package tokenizer 按模型族计算 token 数：OpenAI 系模型使用 tiktoken 编码（BPE 词表内置，无需联网），
+// 其他模型可通过 tokenizer.families 配置指定编码，未知模型使用按文字类型估算的启发式计数
+package tokenizer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenLoader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// 编码名称
+const (
+	EncodingO200K     = "o200k_base"
+	EncodingCL100K    = "cl100k_base"
+	EncodingHeuristic = "heuristic"
+)
+
+const (
+	// MessageOverhead 对话格式中每条消息的固定开销（角色和分隔符）
+	MessageOverhead = 3
+	// ReplyOverhead 模型回答前缀的固定开销
+	ReplyOverhead = 3
+)
+
+// TokenCounter 计算文本的 token 数
+type TokenCounter interface {
+	// Encoding 返回使用的编码名称
+	Encoding() string
+	// Count 返回文本的 token 数
+	Count(text string) int
+}
+
+// family 模型名前缀到编码的映射
+type family struct {
+	prefix   string
+	encoding string
+}
+
+// defaultFamilies 内置的模型族，按前缀最长匹配
+var defaultFamilies = []family{
+	{"gpt-4o", EncodingO200K},
+	{"chatgpt-4o", EncodingO200K},
+	{"gpt-4.1", EncodingO200K},
+	{"gpt-4.5", EncodingO200K},
+	{"gpt-5", EncodingO200K},
+	{"o1", EncodingO200K},
+	{"o3", EncodingO200K},
+	{"o4", EncodingO200K},
+	{"gpt-4", EncodingCL100K},
+	{"gpt-3.5", EncodingCL100K},
+	{"text-embedding-3", EncodingCL100K},
+	{"text-embedding-ada-002", EncodingCL100K},
+}
+
+var (
+	familiesMu sync.RWMutex
+	families   = append([]family(nil), defaultFamilies...)
+
+	counters sync.Map // encoding -> TokenCounter
+	loadOnce sync.Once
+)
+
+// LoadConfig 读取 tokenizer.families 配置（模型名前缀 -> 编码），用于为其他厂商的模型指定近似编码
+func LoadConfig(ctx context.Context) {
+	for prefix, encoding := range g.Cfg().MustGet(ctx, "tokenizer.families").MapStrStr() {
+		RegisterFamily(prefix, encoding)
+	}
+}
+
+// RegisterFamily 指定模型名前缀使用的编码（不区分大小写），已存在的前缀会被覆盖
+// encoding 为 heuristic 时该族使用启发式计数
+func RegisterFamily(prefix, encoding string) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return
+	}
+	familiesMu.Lock()
+	defer familiesMu.Unlock()
+	for i := range families {
+		if families[i].prefix == prefix {
+			families[i].encoding = encoding
+			return
+		}
+	}
+	families = append(families, family{prefix: prefix, encoding: encoding})
+}
+
+// EncodingForModel 返回模型使用的编码，忽略 provider/ 前缀，未知模型返回 heuristic
+func EncodingForModel(modelName string) string {
+	name := strings.ToLower(strings.TrimSpace(modelName))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	familiesMu.RLock()
+	defer familiesMu.RUnlock()
+	encoding, matched := EncodingHeuristic, 0
+	for _, f := range families {
+		if len(f.prefix) > matched && strings.HasPrefix(name, f.prefix) {
+			encoding, matched = f.encoding, len(f.prefix)
+		}
+	}
+	return encoding
+}
+
+// ForModel 返回模型的 TokenCounter，编码加载失败时退回启发式计数
+func ForModel(modelName string) TokenCounter {
+	return ForEncoding(EncodingForModel(modelName))
+}
+
+// ForEncoding 返回指定编码的 TokenCounter，编码首次使用时加载并缓存
+func ForEncoding(encoding string) TokenCounter {
+	if encoding == EncodingHeuristic || encoding == "" {
+		return Heuristic
+	}
+	if counter, ok := counters.Load(encoding); ok {
+		return counter.(TokenCounter)
+	}
+	loadOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktokenLoader.NewOfflineLoader())
+	})
+	var counter TokenCounter = Heuristic
+	if enc, err := tiktoken.GetEncoding(encoding); err != nil {
+		g.Log().Warningf(context.Background(), "Failed to load tokenizer encoding %s, falling back to heuristic counting: %v", encoding, err)
+	} else {
+		counter = &tiktokenCounter{encoding: encoding, enc: enc}
+	}
+	actual, _ := counters.LoadOrStore(encoding, counter)
+	return actual.(TokenCounter)
+}
+
+// Count 使用模型的编码计算文本的 token 数
+func Count(modelName, text string) int {
+	return ForModel(modelName).Count(text)
+}
+
+// CountMessages 计算对话消息的输入 token 数，包括每条消息的格式开销、工具调用和回答前缀开销
+func CountMessages(modelName string, messages []*schema.Message) int {
+	counter := ForModel(modelName)
+	tokens := ReplyOverhead
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		tokens += MessageOverhead + counter.Count(msg.Content)
+		for _, part := range msg.UserInputMultiContent {
+			tokens += counter.Count(part.Text)
+		}
+		for _, call := range msg.ToolCalls {
+			tokens += counter.Count(call.Function.Name) + counter.Count(call.Function.Arguments)
+		}
+	}
+	return tokens
+}
+
+// tiktokenCounter 使用 tiktoken 编码计数
+type tiktokenCounter struct {
+	encoding string
+	enc      *tiktoken.Tiktoken
+}
+
+func (c *tiktokenCounter) Encoding() string {
+	return c.encoding
+}
+
+// Count 按普通文本编码，文本中的特殊 token 字面量不做特殊处理
+func (c *tiktokenCounter) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(c.enc.EncodeOrdinary(text))
+}
+
+// Heuristic 启发式计数，用于没有对应编码的模型
+var Heuristic TokenCounter = heuristicCounter{}
+
+// heuristicCounter 按文字类型估算：中日韩文字每字 1 个 token，连续的字母数字每 4 个字符 1 个 token，其他符号每个 1 个 token
+type heuristicCounter struct{}
+
+func (heuristicCounter) Encoding() string {
+	return EncodingHeuristic
+}
+
+func (heuristicCounter) Count(text string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":         EncodingO200K,
+		"openai/gpt-4.1-nano": EncodingO200K,
+		"o3-mini":             EncodingO200K,
+		"gpt-4-turbo":         EncodingCL100K,
+		"GPT-3.5-Turbo":       EncodingCL100K,
+		"qwen-plus":           EncodingHeuristic,
+		"":                    EncodingHeuristic,
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %s, want %s", model, got, want)
+		}
+	}
+
+	RegisterFamily("DeepSeek", EncodingCL100K)
+	defer RegisterFamily("deepseek", EncodingHeuristic)
+	if got := EncodingForModel("deepseek-chat"); got != EncodingCL100K {
+		t.Errorf("registered family not applied, got %s", got)
+	}
+}
+
+func TestTiktokenCount(t *testing.T) {
+	// 与 OpenAI tiktoken 的结果一致
+	if got := ForEncoding(EncodingCL100K).Count("hello world"); got != 2 {
+		t.Errorf("cl100k count = %d, want 2", got)
+	}
+	if got := Count("gpt-4o", "hello world"); got != 2 {
+		t.Errorf("o200k count = %d, want 2", got)
+	}
+	// 特殊 token 字面量按普通文本计数，不会 panic
+	if got := Count("gpt-4", "<|endoftext|>"); got <= 1 {
+		t.Errorf("special token literal should be encoded as plain text, got %d tokens", got)
+	}
+	if ForModel("gpt-4").Encoding() != EncodingCL100K {
+		t.Error("expected cl100k counter for gpt-4")
+	}
+}
+
+func TestHeuristicCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"退款政策", 4},
+		{"hello world", 4},
+		{"a, b", 3},
+		{"年假 policy", 4},
+	}
+	for _, tt := range tests {
+		if got := Heuristic.Count(tt.text); got != tt.want {
+			t.Errorf("Heuristic.Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	messages := []*schema.Message{
+		{Role: schema.System, Content: "hello world"},
+		{Role: schema.Assistant, ToolCalls: []schema.ToolCall{{Function: schema.FunctionCall{Name: "search", Arguments: `{"q":"x"}`}}}},
+	}
+	want := ReplyOverhead + 2*MessageOverhead + Count("gpt-4", "hello world") + Count("gpt-4", "search") + Count("gpt-4", `{"q":"x"}`)
+	if got := CountMessages("gpt-4", messages); got != want {
+		t.Errorf("CountMessages() = %d, want %d", got, want)
+	}
+}
+
+func TestCompletionBudget(t *testing.T) {
+	messages := []*schema.Message{{Role: schema.User, Content: strings.Repeat("word ", 1000)}}
+	prompt := CountMessages("gpt-4", messages)
+
+	if got := CompletionBudget("gpt-4", messages, 0, 2000); got != 2000 {
+		t.Errorf("without context window got %d, want 2000", got)
+	}
+	if got := CompletionBudget("gpt-4", messages, prompt+5000, 2000); got != 2000 {
+		t.Errorf("with enough room got %d, want 2000", got)
+	}
+	if got := CompletionBudget("gpt-4", messages, prompt+1000, 2000); got != 1000 {
+		t.Errorf("with limited room got %d, want 1000", got)
+	}
+	if got := CompletionBudget("gpt-4", messages, prompt+10, 2000); got != MinCompletionTokens {
+		t.Errorf("with no room got %d, want %d", got, MinCompletionTokens)
+	}
+	if got := CompletionBudget("gpt-4", messages, prompt+1000, 0); got != 1000 {
+		t.Errorf("unlimited max tokens got %d, want 1000", got)
+	}
+}
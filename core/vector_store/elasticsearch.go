@@ -0,0 +1,683 @@
+package vector_store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// 集群类型，两者的向量字段类型和 kNN 查询语法不同
+const (
+	ElasticsearchFlavor = "elasticsearch" // Elasticsearch 8.x：dense_vector + 顶层 knn 检索
+	OpenSearchFlavor    = "opensearch"    // OpenSearch 2.x：knn_vector（lucene 引擎）+ knn 查询
+)
+
+// 索引字段
+const (
+	esVectorField = "vector" // 稠密向量
+	esSparseField = "sparse" // 稀疏向量（rank_features，键为词项ID），由 CreateHybridCollection 创建
+)
+
+// ElasticsearchClient Elasticsearch / OpenSearch REST API 客户端
+type ElasticsearchClient struct {
+	baseURL     string
+	username    string
+	password    string
+	apiKey      string
+	flavor      string
+	indexPrefix string
+	httpClient  *http.Client
+}
+
+// NewElasticsearchClient 创建 REST API 客户端，flavor 为空时按 Elasticsearch 处理
+// indexPrefix 加在集合名前作为索引名，便于多个部署共用集群
+func NewElasticsearchClient(address, username, password, apiKey, flavor, indexPrefix string) *ElasticsearchClient {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+	if !strings.EqualFold(flavor, OpenSearchFlavor) {
+		flavor = ElasticsearchFlavor
+	}
+	return &ElasticsearchClient{
+		baseURL:     strings.TrimSuffix(address, "/"),
+		username:    username,
+		password:    password,
+		apiKey:      apiKey,
+		flavor:      strings.ToLower(flavor),
+		indexPrefix: indexPrefix,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// esErrorResponse 错误响应，error 可能是对象或字符串
+type esErrorResponse struct {
+	Error json.RawMessage `json:"error"`
+}
+
+// do 发送请求，out 不为 nil 时解析响应；返回 HTTP 状态码
+func (c *ElasticsearchClient) do(ctx context.Context, method, path, contentType string, body io.Reader, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp esErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return resp.StatusCode, fmt.Errorf("API error (HTTP %d): %s", resp.StatusCode, esErrorReason(errResp.Error))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// doJSON 发送 JSON 请求
+func (c *ElasticsearchClient) doJSON(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	return c.do(ctx, method, path, "application/json", reader, out)
+}
+
+// esErrorReason 提取错误原因，优先使用 root_cause 中更具体的原因
+func esErrorReason(raw json.RawMessage) string {
+	var reason string
+	if json.Unmarshal(raw, &reason) == nil {
+		return reason
+	}
+	var detail struct {
+		Type      string `json:"type"`
+		Reason    string `json:"reason"`
+		RootCause []struct {
+			Reason string `json:"reason"`
+		} `json:"root_cause"`
+	}
+	if json.Unmarshal(raw, &detail) != nil {
+		return string(raw)
+	}
+	if len(detail.RootCause) > 0 && detail.RootCause[0].Reason != "" {
+		return detail.RootCause[0].Reason
+	}
+	return detail.Type + ": " + detail.Reason
+}
+
+// indexName 集合名转换为索引名：加前缀、转小写，索引名不允许的字符替换为下划线
+func (c *ElasticsearchClient) indexName(collectionName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(c.indexPrefix + collectionName) {
+		if strings.ContainsRune(`\/*?"<>| ,#:`, r) {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimLeft(b.String(), "-_+")
+}
+
+// ElasticsearchStore Elasticsearch / OpenSearch 向量数据库实现，每个知识库一个索引
+type ElasticsearchStore struct {
+	client *ElasticsearchClient
+}
+
+// InitializeElasticsearchStore 初始化 Elasticsearch / OpenSearch 向量存储
+func InitializeElasticsearchStore(ctx context.Context) (VectorStore, error) {
+	address := g.Cfg().MustGet(ctx, "elasticsearch.address", "").String()
+	if address == "" {
+		return nil, fmt.Errorf("elasticsearch.address is required but not found in config file")
+	}
+	client := NewElasticsearchClient(
+		address,
+		g.Cfg().MustGet(ctx, "elasticsearch.username", "").String(),
+		g.Cfg().MustGet(ctx, "elasticsearch.password", "").String(),
+		g.Cfg().MustGet(ctx, "elasticsearch.apiKey", "").String(),
+		g.Cfg().MustGet(ctx, "elasticsearch.flavor", ElasticsearchFlavor).String(),
+		g.Cfg().MustGet(ctx, "elasticsearch.indexPrefix", "kbgo_").String(),
+	)
+
+	g.Log().Infof(ctx, "Connecting to %s at: %s", client.flavor, address)
+	return NewElasticsearchStore(&VectorStoreConfig{
+		Type:   VectorStoreTypeElasticsearch,
+		Client: client,
+	})
+}
+
+// NewElasticsearchStore 创建 Elasticsearch / OpenSearch 向量存储实例
+func NewElasticsearchStore(config *VectorStoreConfig) (VectorStore, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	client, ok := config.Client.(*ElasticsearchClient)
+	if !ok {
+		return nil, fmt.Errorf("client must be *ElasticsearchClient")
+	}
+
+	return &ElasticsearchStore{client: client}, nil
+}
+
+// CreateDatabaseIfNotExists 集群没有数据库的概念，只检查服务是否可用
+func (e *ElasticsearchStore) CreateDatabaseIfNotExists(ctx context.Context) error {
+	if _, err := e.client.doJSON(ctx, http.MethodGet, "/", nil, nil); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", e.client.flavor, err)
+	}
+	g.Log().Infof(ctx, "%s is ready", e.client.flavor)
+	return nil
+}
+
+// CreateCollection 创建集合（使用配置文件中的向量维度）
+func (e *ElasticsearchStore) CreateCollection(ctx context.Context, collectionName string) error {
+	return e.CreateCollectionWithDim(ctx, collectionName, g.Cfg().MustGet(ctx, "elasticsearch.dim", 1024).Int())
+}
+
+// CreateCollectionWithDim 使用指定向量维度创建集合
+func (e *ElasticsearchStore) CreateCollectionWithDim(ctx context.Context, collectionName string, dim int) error {
+	return e.createIndex(ctx, collectionName, dim, g.Cfg().MustGet(ctx, "vectordb.metricType", "COSINE").String(), false)
+}
+
+// CreateHybridCollection 创建同时包含稠密向量和稀疏向量字段的集合
+func (e *ElasticsearchStore) CreateHybridCollection(ctx context.Context, collectionName string, dim int) error {
+	return e.createIndex(ctx, collectionName, dim, g.Cfg().MustGet(ctx, "vectordb.metricType", "COSINE").String(), true)
+}
+
+// createIndex 创建索引
+func (e *ElasticsearchStore) createIndex(ctx context.Context, collectionName string, dim int, metricType string, hybrid bool) error {
+	index := e.client.indexName(collectionName)
+	if _, err := e.client.doJSON(ctx, http.MethodPut, "/"+index, esIndexBody(e.client.flavor, dim, metricType, hybrid), nil); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", index, err)
+	}
+	g.Log().Infof(ctx, "Index '%s' created with dimension %d (hybrid: %v)", index, dim, hybrid)
+	return nil
+}
+
+// esIndexBody 索引设置和映射：文档ID和知识库ID为 keyword 用于过滤和删除，文本使用默认分词器做 BM25 检索，
+// 元数据只保存不建索引，避免不同文档的同名元数据类型不一致导致写入失败
+func esIndexBody(flavor string, dim int, metricType string, hybrid bool) map[string]any {
+	properties := map[string]any{
+		"id":                 map[string]any{"type": "keyword"},
+		common.FieldContent:  map[string]any{"type": "text"},
+		common.DocumentId:    map[string]any{"type": "keyword"},
+		common.KnowledgeId:   map[string]any{"type": "keyword"},
+		common.FieldMetadata: map[string]any{"type": "object", "enabled": false},
+	}
+	body := map[string]any{}
+	if flavor == OpenSearchFlavor {
+		properties[esVectorField] = map[string]any{
+			"type":      "knn_vector",
+			"dimension": dim,
+			"method": map[string]any{
+				"name":       "hnsw",
+				"engine":     "lucene",
+				"space_type": openSearchSpaceType(metricType),
+			},
+		}
+		body["settings"] = map[string]any{"index": map[string]any{"knn": true}}
+	} else {
+		properties[esVectorField] = map[string]any{
+			"type":       "dense_vector",
+			"dims":       dim,
+			"index":      true,
+			"similarity": esSimilarity(metricType),
+		}
+	}
+	if hybrid {
+		properties[esSparseField] = map[string]any{"type": "rank_features"}
+	}
+	body["mappings"] = map[string]any{"properties": properties}
+	return body
+}
+
+// esSimilarity 把 vectordb.metricType 转换为 dense_vector 的 similarity
+func esSimilarity(metricType string) string {
+	switch strings.ToUpper(metricType) {
+	case "L2":
+		return "l2_norm"
+	case "IP", "INNER_PRODUCT":
+		return "dot_product"
+	default:
+		return "cosine"
+	}
+}
+
+// openSearchSpaceType 把 vectordb.metricType 转换为 knn_vector 的 space_type
+func openSearchSpaceType(metricType string) string {
+	switch strings.ToUpper(metricType) {
+	case "L2":
+		return "l2"
+	case "IP", "INNER_PRODUCT":
+		return "innerproduct"
+	default:
+		return "cosinesimil"
+	}
+}
+
+// CollectionExists 检查集合（索引）是否存在
+func (e *ElasticsearchStore) CollectionExists(ctx context.Context, collectionName string) (bool, error) {
+	status, err := e.client.doJSON(ctx, http.MethodHead, "/"+e.client.indexName(collectionName), nil, nil)
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check if index exists: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteCollection 删除集合（索引）
+func (e *ElasticsearchStore) DeleteCollection(ctx context.Context, collectionName string) error {
+	index := e.client.indexName(collectionName)
+	status, err := e.client.doJSON(ctx, http.MethodDelete, "/"+index, nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("failed to delete index %s: %w", index, err)
+	}
+	g.Log().Infof(ctx, "Index '%s' deleted", index)
+	return nil
+}
+
+// InsertVectors 插入向量数据
+func (e *ElasticsearchStore) InsertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32) ([]string, error) {
+	return e.insertVectors(ctx, collectionName, chunks, vectors, nil)
+}
+
+// InsertHybridVectors 同时插入稠密向量和稀疏向量
+func (e *ElasticsearchStore) InsertHybridVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(chunks) != len(sparseVectors) {
+		return nil, fmt.Errorf("chunks and sparse vectors length mismatch: %d vs %d", len(chunks), len(sparseVectors))
+	}
+	return e.insertVectors(ctx, collectionName, chunks, vectors, sparseVectors)
+}
+
+// esBulkResponse 批量写入响应，只在 errors 为 true 时检查每一项
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// insertVectors 通过 _bulk 接口写入，分片ID作为文档 _id，写入后等待刷新以便立即可检索
+func (e *ElasticsearchStore) insertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(chunks) != len(vectors) {
+		return nil, fmt.Errorf("chunks and vectors length mismatch: %d vs %d", len(chunks), len(vectors))
+	}
+
+	// 从上下文中提取knowledge_id和document_id
+	var knowledgeId string
+	if value, ok := ctx.Value(common.KnowledgeId).(string); ok {
+		knowledgeId = value
+	}
+	var contextDocumentId string
+	if value, ok := ctx.Value(common.DocumentId).(string); ok {
+		contextDocumentId = value
+	}
+
+	index := e.client.indexName(collectionName)
+	ids := make([]string, len(chunks))
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for idx, chunk := range chunks {
+		// 生成chunk ID（如果不存在）
+		if len(chunk.ID) == 0 {
+			chunk.ID = uuid.New().String()
+		}
+		ids[idx] = chunk.ID
+
+		if contextDocumentId == "" {
+			return nil, fmt.Errorf("document_id not found in context for chunk %s", chunk.ID)
+		}
+
+		// 构建metadata
+		metaCopy := make(map[string]any)
+		for k, v := range chunk.MetaData {
+			metaCopy[k] = v
+		}
+		if knowledgeId != "" {
+			metaCopy[common.KnowledgeId] = knowledgeId
+		}
+
+		source := map[string]any{
+			"id":                 chunk.ID,
+			common.FieldContent:  truncateString(chunk.Content, 65535),
+			common.DocumentId:    contextDocumentId,
+			common.KnowledgeId:   knowledgeId,
+			common.FieldMetadata: metaCopy,
+			esVectorField:        vectors[idx],
+		}
+		if sparseVectors != nil && sparseVectors[idx].Len() > 0 {
+			source[esSparseField] = esRankFeatures(sparseVectors[idx])
+		}
+
+		action := map[string]any{"index": map[string]any{"_index": index, "_id": chunk.ID}}
+		if err := encoder.Encode(action); err != nil {
+			return nil, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(source); err != nil {
+			return nil, fmt.Errorf("failed to marshal chunk %s: %w", chunk.ID, err)
+		}
+	}
+
+	var result esBulkResponse
+	if _, err := e.client.do(ctx, http.MethodPost, "/_bulk?refresh=wait_for", "application/x-ndjson", &body, &result); err != nil {
+		return nil, fmt.Errorf("failed to insert vectors: %w", err)
+	}
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, op := range item {
+				if op.Status >= 300 {
+					return nil, fmt.Errorf("failed to insert vector for chunk %s: %s", op.ID, esErrorReason(op.Error))
+				}
+			}
+		}
+	}
+
+	g.Log().Infof(ctx, "Successfully inserted %d vectors into index '%s'", len(chunks), index)
+	return ids, nil
+}
+
+// esRankFeatures 稀疏向量转换为 rank_features 字段值，同一词项的权重累加
+// rank_features 只接受正数，非正的权重被忽略
+func esRankFeatures(v common.SparseVector) map[string]float32 {
+	features := make(map[string]float32, len(v.Indices))
+	for i, idx := range v.Indices {
+		if v.Values[i] > 0 {
+			features[strconv.FormatUint(uint64(idx), 10)] += v.Values[i]
+		}
+	}
+	return features
+}
+
+// DeleteByDocumentID 根据文档ID删除所有相关chunks
+func (e *ElasticsearchStore) DeleteByDocumentID(ctx context.Context, collectionName string, documentID string) error {
+	if !common.ValidateUUID(documentID) {
+		return fmt.Errorf("invalid document ID format: %s (must be valid UUID)", documentID)
+	}
+
+	g.Log().Infof(ctx, "Deleting all chunks of document %s from index %s", documentID, e.client.indexName(collectionName))
+	return e.deleteByTerm(ctx, collectionName, common.DocumentId, documentID)
+}
+
+// DeleteByChunkID 根据chunkID删除单个chunk
+func (e *ElasticsearchStore) DeleteByChunkID(ctx context.Context, collectionName string, chunkID string) error {
+	if !common.ValidateUUID(chunkID) {
+		return fmt.Errorf("invalid chunk ID format: %s (must be valid UUID)", chunkID)
+	}
+
+	g.Log().Infof(ctx, "Deleting chunk %s from index %s", chunkID, e.client.indexName(collectionName))
+	return e.deleteByTerm(ctx, collectionName, "id", chunkID)
+}
+
+// deleteByTerm 删除字段等于指定值的文档
+func (e *ElasticsearchStore) deleteByTerm(ctx context.Context, collectionName, field, value string) error {
+	body := map[string]any{"query": map[string]any{"term": map[string]any{field: value}}}
+	var result struct {
+		Deleted int `json:"deleted"`
+	}
+	path := "/" + e.client.indexName(collectionName) + "/_delete_by_query?refresh=true&conflicts=proceed"
+	if _, err := e.client.doJSON(ctx, http.MethodPost, path, body, &result); err != nil {
+		return fmt.Errorf("failed to delete where %s=%s: %w", field, value, err)
+	}
+	g.Log().Infof(ctx, "Delete operation completed for %s=%s, affected rows: %d", field, value, result.Deleted)
+	return nil
+}
+
+// GetClient 返回底层客户端
+func (e *ElasticsearchStore) GetClient() interface{} {
+	return e.client
+}
+
+// NewRetriever 创建检索器实例
+func (e *ElasticsearchStore) NewRetriever(ctx context.Context, conf interface{}, collectionName string) (Retriever, error) {
+	if collectionName == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+
+	exists, err := e.CollectionExists(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("index '%s' not found", e.client.indexName(collectionName))
+	}
+
+	return &elasticsearchRetriever{
+		store:          e,
+		collectionName: collectionName,
+		config:         conf,
+	}, nil
+}
+
+// VectorSearchOnly 仅使用向量检索的通用方法
+func (e *ElasticsearchStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error) {
+	// knowledge name == collection name
+	r, err := e.NewRetriever(ctx, conf, knowledgeId)
+	if err != nil {
+		g.Log().Errorf(ctx, "failed to create retriever for index %s, err=%v", knowledgeId, err)
+		return nil, err
+	}
+
+	// 检索的 TopK，可以设置得比最终需要的数量大一些
+	esTopK := max(topK*5, 20)
+	return r.(*elasticsearchRetriever).vectorSearchWithThreshold(ctx, query, esTopK, score, knowledgeScope(ctx, knowledgeId))
+}
+
+// SparseSearch 稀疏向量检索：每个查询词项一个 rank_feature 线性打分子句，总分为查询与文档稀疏向量的内积（未归一化）
+// 线性打分需要 Elasticsearch 7.12+，OpenSearch 不支持；Milvus 过滤表达式不适用，只支持 WithKnowledgeID
+func (e *ElasticsearchStore) SparseSearch(ctx context.Context, collectionName string, query common.SparseVector, topK int, opts ...Option) ([]*schema.Document, error) {
+	if query.Len() == 0 {
+		return []*schema.Document{}, nil
+	}
+	if e.client.flavor == OpenSearchFlavor {
+		return nil, fmt.Errorf("sparse vector search is not supported on opensearch")
+	}
+
+	features := esRankFeatures(query)
+	should := make([]map[string]any, 0, len(features))
+	for term, weight := range features {
+		should = append(should, map[string]any{"rank_feature": map[string]any{
+			"field":  esSparseField + "." + term,
+			"linear": map[string]any{},
+			"boost":  weight,
+		}})
+	}
+	boolQuery := map[string]any{"should": should, "minimum_should_match": 1}
+	if filter := esKnowledgeFilter(knowledgeScope(ctx, GetCommonOptions(nil, opts...).KnowledgeID)); filter != nil {
+		boolQuery["filter"] = filter
+	}
+	return e.search(ctx, collectionName, map[string]any{
+		"size":    topK,
+		"query":   map[string]any{"bool": boolQuery},
+		"_source": esSourceExcludes(),
+	})
+}
+
+// KeywordSearch 关键词检索：使用集群原生的 BM25 全文检索，默认分词器把中日韩文字切分为单字，分数未归一化
+func (e *ElasticsearchStore) KeywordSearch(ctx context.Context, collectionName string, query string, topK int, opts ...Option) ([]*schema.Document, error) {
+	if len(keywordTerms(query)) == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	boolQuery := map[string]any{
+		"must": map[string]any{"match": map[string]any{common.FieldContent: map[string]any{"query": query}}},
+	}
+	if filter := esKnowledgeFilter(knowledgeScope(ctx, GetCommonOptions(nil, opts...).KnowledgeID)); filter != nil {
+		boolQuery["filter"] = filter
+	}
+	return e.search(ctx, collectionName, map[string]any{
+		"size":    topK,
+		"query":   map[string]any{"bool": boolQuery},
+		"_source": esSourceExcludes(),
+	})
+}
+
+// esKnowledgeFilter 按 knowledge_id 过滤，knowledgeID 为空时返回 nil
+func esKnowledgeFilter(knowledgeID string) []map[string]any {
+	if knowledgeID == "" {
+		return nil
+	}
+	return []map[string]any{{"term": map[string]any{common.KnowledgeId: knowledgeID}}}
+}
+
+// esSourceExcludes 检索结果不返回向量字段
+func esSourceExcludes() map[string]any {
+	return map[string]any{"excludes": []string{esVectorField, esSparseField}}
+}
+
+// esSearchResponse 检索响应
+type esSearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Score  float32        `json:"_score"`
+			Source map[string]any `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// search 执行检索并转换为文档，过滤掉已禁用的分片
+func (e *ElasticsearchStore) search(ctx context.Context, collectionName string, body map[string]any) ([]*schema.Document, error) {
+	var result esSearchResponse
+	if _, err := e.client.doJSON(ctx, http.MethodPost, "/"+e.client.indexName(collectionName)+"/_search", body, &result); err != nil {
+		return nil, fmt.Errorf("search has error: %w", err)
+	}
+
+	docs := make([]*schema.Document, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		doc := &schema.Document{MetaData: make(map[string]any), Score: hit.Score}
+		doc.ID, _ = hit.Source["id"].(string)
+		doc.Content, _ = hit.Source[common.FieldContent].(string)
+		if metadata, ok := hit.Source[common.FieldMetadata].(map[string]any); ok {
+			for k, v := range metadata {
+				doc.MetaData[k] = v
+			}
+		}
+		if documentID, ok := hit.Source[common.DocumentId].(string); ok {
+			doc.MetaData[common.DocumentId] = documentID
+		}
+		docs = append(docs, doc)
+	}
+	return activeDocuments(ctx, docs)
+}
+
+// esKNNQuery 构造 kNN 检索请求：Elasticsearch 使用顶层 knn（过滤在近邻搜索中执行），OpenSearch 使用 knn 查询（lucene 引擎支持过滤）
+func esKNNQuery(flavor string, vector []float32, topK int, knowledgeID string) map[string]any {
+	filter := esKnowledgeFilter(knowledgeID)
+	if flavor == OpenSearchFlavor {
+		knn := map[string]any{"vector": vector, "k": topK}
+		if filter != nil {
+			knn["filter"] = map[string]any{"bool": map[string]any{"filter": filter}}
+		}
+		return map[string]any{
+			"size":    topK,
+			"query":   map[string]any{"knn": map[string]any{esVectorField: knn}},
+			"_source": esSourceExcludes(),
+		}
+	}
+
+	knn := map[string]any{
+		"field":          esVectorField,
+		"query_vector":   vector,
+		"k":              topK,
+		"num_candidates": max(topK*10, 100),
+	}
+	if filter != nil {
+		knn["filter"] = filter
+	}
+	return map[string]any{
+		"size":    topK,
+		"knn":     knn,
+		"_source": esSourceExcludes(),
+	}
+}
+
+// elasticsearchRetriever 实现了 Retriever 接口
+type elasticsearchRetriever struct {
+	store          *ElasticsearchStore
+	collectionName string
+	config         interface{}
+}
+
+// Retrieve 实现检索功能
+func (r *elasticsearchRetriever) Retrieve(ctx context.Context, query string, opts ...Option) ([]*schema.Document, error) {
+	topK := 5
+	threshold := 0.0
+
+	// 解析选项，Milvus 过滤表达式和分区不适用于 Elasticsearch
+	options := GetCommonOptions(&Options{TopK: &topK, ScoreThreshold: &threshold}, opts...)
+
+	return r.vectorSearchWithThreshold(ctx, query, *options.TopK, *options.ScoreThreshold, knowledgeScope(ctx, options.KnowledgeID))
+}
+
+// vectorSearchWithThreshold 带阈值的向量搜索，knowledgeID 不为空时只检索该知识库的分片
+// 分数由集群归一化到 0-1（余弦为 (1+cos)/2），越大越相似
+func (r *elasticsearchRetriever) vectorSearchWithThreshold(ctx context.Context, query string, topK int, threshold float64, knowledgeID string) ([]*schema.Document, error) {
+	queryVector, err := embedQuery(ctx, retrieverEmbeddingConfig(r.config), query, "elasticsearch.dim")
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := r.store.search(ctx, r.collectionName, esKNNQuery(r.store.client.flavor, queryVector, topK, knowledgeID))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if float64(doc.Score) >= threshold {
+			results = append(results, doc)
+		}
+	}
+
+	// 去重
+	results = common.RemoveDuplicates(results, func(doc *schema.Document) string {
+		return doc.ID
+	})
+
+	// 按相似度排序
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+// GetType 返回检索器类型
+func (r *elasticsearchRetriever) GetType() string {
+	return "ElasticsearchRetriever"
+}
+
+// IsCallbacksEnabled 返回是否启用回调
+func (r *elasticsearchRetriever) IsCallbacksEnabled() bool {
+	return false
+}
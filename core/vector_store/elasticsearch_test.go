@@ -0,0 +1,186 @@
+package vector_store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// esRequest 模拟服务收到的请求，_bulk 请求体按行解析
+type esRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Lines  []map[string]any
+}
+
+// newElasticsearchTestServer 模拟 Elasticsearch REST API，记录收到的请求，不存在的索引返回 404
+func newElasticsearchTestServer(t *testing.T, indexes map[string]bool, response string) (*ElasticsearchStore, *[]esRequest) {
+	var requests []esRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := esRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery}
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 1<<20), 1<<20)
+		for scanner.Scan() {
+			var line map[string]any
+			_ = json.Unmarshal(scanner.Bytes(), &line)
+			req.Lines = append(req.Lines, line)
+		}
+		requests = append(requests, req)
+
+		if r.Method == http.MethodHead && !indexes[r.URL.Path[1:]] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewElasticsearchClient(server.URL, "elastic", "secret", "", ElasticsearchFlavor, "kbgo_")
+	store, err := NewElasticsearchStore(&VectorStoreConfig{Type: VectorStoreTypeElasticsearch, Client: client})
+	require.NoError(t, err)
+	return store.(*ElasticsearchStore), &requests
+}
+
+func TestElasticsearchStoreCreation(t *testing.T) {
+	_, err := NewElasticsearchStore(nil)
+	assert.Error(t, err)
+
+	_, err = NewElasticsearchStore(&VectorStoreConfig{Type: VectorStoreTypeElasticsearch, Client: "invalid_client"})
+	assert.ErrorContains(t, err, "must be *ElasticsearchClient")
+
+	client := NewElasticsearchClient("localhost:9200", "", "", "", "OpenSearch", "")
+	store, err := NewVectorStore(&VectorStoreConfig{Type: VectorStoreTypeElasticsearch, Client: client})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9200", store.GetClient().(*ElasticsearchClient).baseURL)
+	assert.Equal(t, OpenSearchFlavor, client.flavor)
+
+	assert.Equal(t, ElasticsearchFlavor, NewElasticsearchClient("localhost:9200", "", "", "", "", "").flavor)
+}
+
+func TestElasticsearchIndexName(t *testing.T) {
+	client := NewElasticsearchClient("localhost:9200", "", "", "", "", "kbgo_")
+	assert.Equal(t, "kbgo_kb_abc-123", client.indexName("KB_Abc-123"))
+	assert.Equal(t, "kbgo_a_b_c", client.indexName("a/b c"))
+	assert.Equal(t, "kb", NewElasticsearchClient("localhost:9200", "", "", "", "", "").indexName("_kb"))
+}
+
+func TestElasticsearchIndexBody(t *testing.T) {
+	body := esIndexBody(ElasticsearchFlavor, 768, "IP", true)
+	properties := body["mappings"].(map[string]any)["properties"].(map[string]any)
+	vector := properties[esVectorField].(map[string]any)
+	assert.Equal(t, "dense_vector", vector["type"])
+	assert.Equal(t, 768, vector["dims"])
+	assert.Equal(t, "dot_product", vector["similarity"])
+	assert.Equal(t, "rank_features", properties[esSparseField].(map[string]any)["type"])
+	assert.Equal(t, "keyword", properties[common.KnowledgeId].(map[string]any)["type"])
+	assert.NotContains(t, body, "settings")
+
+	body = esIndexBody(OpenSearchFlavor, 1024, "L2", false)
+	properties = body["mappings"].(map[string]any)["properties"].(map[string]any)
+	vector = properties[esVectorField].(map[string]any)
+	assert.Equal(t, "knn_vector", vector["type"])
+	assert.Equal(t, 1024, vector["dimension"])
+	assert.Equal(t, "l2", vector["method"].(map[string]any)["space_type"])
+	assert.NotContains(t, properties, esSparseField)
+	assert.Equal(t, true, body["settings"].(map[string]any)["index"].(map[string]any)["knn"])
+}
+
+func TestElasticsearchCollectionExists(t *testing.T) {
+	store, _ := newElasticsearchTestServer(t, map[string]bool{"kbgo_kb_1": true}, `{}`)
+	ctx := context.Background()
+
+	exists, err := store.CollectionExists(ctx, "kb_1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = store.CollectionExists(ctx, "kb_2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestElasticsearchInsertAndDelete(t *testing.T) {
+	store, requests := newElasticsearchTestServer(t, nil, `{"errors":false,"items":[],"deleted":1}`)
+	ctx := context.WithValue(context.Background(), common.KnowledgeId, "kb_1")
+	ctx = context.WithValue(ctx, common.DocumentId, uuid.New().String())
+
+	chunks := []*schema.Document{{Content: "hello", MetaData: map[string]any{"chunk_index": 0}}}
+	sparse := []common.SparseVector{{Indices: []uint32{7, 7, 9}, Values: []float32{0.5, 0.25, -1}}}
+	ids, err := store.InsertHybridVectors(ctx, "kb_1", chunks, [][]float32{{0.1, 0.2}}, sparse)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	bulk := (*requests)[0]
+	assert.Equal(t, "/_bulk", bulk.Path)
+	assert.Equal(t, "refresh=wait_for", bulk.Query)
+	require.Len(t, bulk.Lines, 2)
+	action := bulk.Lines[0]["index"].(map[string]any)
+	assert.Equal(t, "kbgo_kb_1", action["_index"])
+	assert.Equal(t, ids[0], action["_id"])
+	source := bulk.Lines[1]
+	assert.Equal(t, "hello", source[common.FieldContent])
+	assert.Equal(t, "kb_1", source[common.KnowledgeId])
+	assert.Equal(t, map[string]any{"7": 0.75}, source[esSparseField])
+	assert.Equal(t, "kb_1", source[common.FieldMetadata].(map[string]any)[common.KnowledgeId])
+
+	// 缺少 document_id 时拒绝写入
+	_, err = store.InsertVectors(context.Background(), "kb_1", []*schema.Document{{Content: "x"}}, [][]float32{{0.1}})
+	assert.ErrorContains(t, err, "document_id not found")
+
+	chunkID := uuid.New().String()
+	require.NoError(t, store.DeleteByChunkID(ctx, "kb_1", chunkID))
+	del := (*requests)[len(*requests)-1]
+	assert.Equal(t, "/kbgo_kb_1/_delete_by_query", del.Path)
+	assert.Equal(t, map[string]any{"term": map[string]any{"id": chunkID}}, del.Lines[0]["query"])
+
+	assert.Error(t, store.DeleteByDocumentID(ctx, "kb_1", "not-a-uuid"))
+}
+
+func TestElasticsearchBulkErrors(t *testing.T) {
+	response := `{"errors":true,"items":[{"index":{"_id":"c1","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`
+	store, _ := newElasticsearchTestServer(t, nil, response)
+	ctx := context.WithValue(context.Background(), common.DocumentId, uuid.New().String())
+
+	_, err := store.InsertVectors(ctx, "kb_1", []*schema.Document{{ID: "c1", Content: "x"}}, [][]float32{{0.1}})
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestElasticsearchHelpers(t *testing.T) {
+	t.Run("error reason", func(t *testing.T) {
+		assert.Equal(t, "no such index", esErrorReason(json.RawMessage(`{"type":"index_not_found_exception","reason":"x","root_cause":[{"reason":"no such index"}]}`)))
+		assert.Equal(t, "a: b", esErrorReason(json.RawMessage(`{"type":"a","reason":"b"}`)))
+		assert.Equal(t, "plain", esErrorReason(json.RawMessage(`"plain"`)))
+	})
+
+	t.Run("knn query", func(t *testing.T) {
+		query := esKNNQuery(ElasticsearchFlavor, []float32{0.1}, 5, "kb_1")
+		knn := query["knn"].(map[string]any)
+		assert.Equal(t, 100, knn["num_candidates"])
+		assert.Equal(t, esKnowledgeFilter("kb_1"), knn["filter"])
+
+		query = esKNNQuery(ElasticsearchFlavor, []float32{0.1}, 20, "")
+		assert.NotContains(t, query["knn"], "filter")
+		assert.Equal(t, 200, query["knn"].(map[string]any)["num_candidates"])
+
+		query = esKNNQuery(OpenSearchFlavor, []float32{0.1}, 5, "kb_1")
+		knn = query["query"].(map[string]any)["knn"].(map[string]any)[esVectorField].(map[string]any)
+		assert.Equal(t, 5, knn["k"])
+		assert.Contains(t, knn, "filter")
+	})
+
+	t.Run("metric mapping", func(t *testing.T) {
+		assert.Equal(t, "cosine", esSimilarity("COSINE"))
+		assert.Equal(t, "l2_norm", esSimilarity("L2"))
+		assert.Equal(t, "cosinesimil", openSearchSpaceType(""))
+		assert.Equal(t, "innerproduct", openSearchSpaceType("IP"))
+	})
+}
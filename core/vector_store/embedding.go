@@ -0,0 +1,43 @@
+package vector_store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// retrieverEmbeddingConfig 从检索配置中读取向量模型配置，使用接口方法获取，避免循环依赖
+func retrieverEmbeddingConfig(config any) *embeddingConfigWrapper {
+	type embeddingConfigGetter interface {
+		GetAPIKey() string
+		GetBaseURL() string
+		GetEmbeddingModel() string
+	}
+	wrapper := &embeddingConfigWrapper{}
+	if configGetter, ok := config.(embeddingConfigGetter); ok {
+		wrapper.apiKey = configGetter.GetAPIKey()
+		wrapper.baseURL = configGetter.GetBaseURL()
+		wrapper.embeddingModel = configGetter.GetEmbeddingModel()
+	}
+	return wrapper
+}
+
+// embedQuery 把查询文本向量化，维度与索引时相同（见 common.CustomEmbedder.ResolveDimension），
+// dimKey 为向量库配置中作为兜底的维度配置项
+func embedQuery(ctx context.Context, conf common.EmbeddingConfig, query, dimKey string) ([]float32, error) {
+	embedder, err := common.NewEmbedding(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedder: %w", err)
+	}
+	dim := embedder.ResolveDimension(ctx, g.Cfg().MustGet(ctx, dimKey, 1024).Int())
+	vectors, err := embedder.EmbedStrings(ctx, []string{query}, dim)
+	if err != nil {
+		return nil, fmt.Errorf("embedding has error: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("invalid return length of vector, got=%d, expected=1", len(vectors))
+	}
+	return vectors[0], nil
+}
@@ -15,6 +15,10 @@ func NewVectorStore(config *VectorStoreConfig) (VectorStore, error) {
 		return NewMilvusStore(config)
 	case VectorStoreTypePostgreSQL:
 		return NewPostgresStore(config)
+	case VectorStoreTypeQdrant:
+		return NewQdrantStore(config)
+	case VectorStoreTypeElasticsearch:
+		return NewElasticsearchStore(config)
 	default:
 		return nil, fmt.Errorf("unsupported vector store type: %s", config.Type)
 	}
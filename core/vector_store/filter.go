@@ -0,0 +1,48 @@
+package vector_store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// CombineFilters 用 and 组合多个 Milvus 过滤表达式，忽略空表达式
+func CombineFilters(filters ...string) string {
+	var parts []string
+	for _, filter := range filters {
+		if filter = strings.TrimSpace(filter); filter != "" {
+			parts = append(parts, filter)
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, ") and (") + ")"
+}
+
+// knowledgeScope 返回检索需要限定的知识库ID，vectorStore.knowledgeFilter 为 false 时不限定
+// （未在分片元数据中记录 knowledge_id 的旧数据需关闭该配置才能检索到）
+func knowledgeScope(ctx context.Context, knowledgeID string) string {
+	if knowledgeID == "" || !g.Cfg().MustGet(ctx, "vectorStore.knowledgeFilter", true).Bool() {
+		return ""
+	}
+	return knowledgeID
+}
+
+// milvusKnowledgeFilter 按分片元数据中的 knowledge_id 过滤的 Milvus 表达式，knowledgeID 为空时返回空字符串
+func milvusKnowledgeFilter(knowledgeID string) string {
+	if knowledgeID == "" {
+		return ""
+	}
+	return `metadata["` + common.KnowledgeId + `"] == "` + escapeMilvusString(knowledgeID) + `"`
+}
+
+// escapeMilvusString 转义 Milvus 表达式中双引号字符串的反斜杠和双引号
+func escapeMilvusString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,39 @@
+package vector_store
+
+import "testing"
+
+func TestCombineFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		want    string
+	}{
+		{"none", nil, ""},
+		{"empty filters ignored", []string{"", "  "}, ""},
+		{"single", []string{`id not in ["a"]`}, `id not in ["a"]`},
+		{"multiple", []string{`id not in ["a"]`, "", `metadata["knowledge_id"] == "kb1"`}, `(id not in ["a"]) and (metadata["knowledge_id"] == "kb1")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CombineFilters(tt.filters...); got != tt.want {
+				t.Errorf("CombineFilters() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFilterCombines(t *testing.T) {
+	opts := GetCommonOptions(nil, WithFilter("a > 1"), WithFilter("b < 2"), WithKnowledgeID("kb1"))
+	if opts.Filter != "(a > 1) and (b < 2)" || opts.KnowledgeID != "kb1" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+}
+
+func TestMilvusKnowledgeFilter(t *testing.T) {
+	if got := milvusKnowledgeFilter(""); got != "" {
+		t.Errorf("expected empty filter, got %q", got)
+	}
+	if got := milvusKnowledgeFilter(`kb"1\`); got != `metadata["knowledge_id"] == "kb\"1\\"` {
+		t.Errorf("unexpected filter: %q", got)
+	}
+}
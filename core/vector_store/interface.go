@@ -3,6 +3,7 @@ package vector_store
 import (
 	"context"
 
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/pkg/schema"
 )
 
@@ -27,6 +28,7 @@ type Options struct {
 	ScoreThreshold *float64
 	Filter         string
 	Partition      string
+	KnowledgeID    string
 }
 
 // WithTopK sets the number of top results to return
@@ -43,10 +45,18 @@ func WithScoreThreshold(threshold float64) Option {
 	}
 }
 
-// WithFilter sets the filter expression for Milvus
+// WithFilter adds a filter expression for Milvus, multiple filters are combined with "and"
 func WithFilter(filter string) Option {
 	return func(o *Options) {
-		o.Filter = filter
+		o.Filter = CombineFilters(o.Filter, filter)
+	}
+}
+
+// WithKnowledgeID scopes the search to chunks whose metadata knowledge_id matches,
+// so shared collections or misrouted inserts can't leak chunks across knowledge bases
+func WithKnowledgeID(knowledgeID string) Option {
+	return func(o *Options) {
+		o.KnowledgeID = knowledgeID
 	}
 }
 
@@ -73,8 +83,10 @@ func GetCommonOptions(defaultOpts *Options, opts ...Option) *Options {
 type VectorStoreType string
 
 const (
-	VectorStoreTypeMilvus     VectorStoreType = "milvus"
-	VectorStoreTypePostgreSQL VectorStoreType = "pgvector"
+	VectorStoreTypeMilvus        VectorStoreType = "milvus"
+	VectorStoreTypePostgreSQL    VectorStoreType = "pgvector"
+	VectorStoreTypeQdrant        VectorStoreType = "qdrant"
+	VectorStoreTypeElasticsearch VectorStoreType = "elasticsearch"
 	// 未来可以扩展其他类型
 	// VectorStoreTypeChroma VectorStoreType = "chroma"
 	// VectorStoreTypeWeaviate VectorStoreType = "weaviate"
@@ -102,18 +114,27 @@ type VectorStoreConfig struct {
 
 // VectorStore 向量数据库接口
 type VectorStore interface {
-	// CreateCollection 创建集合
+	// CreateCollection 创建集合（使用配置文件中的向量维度）
 	CreateCollection(ctx context.Context, collectionName string) error
 
+	// CreateCollectionWithDim 使用指定向量维度创建集合
+	CreateCollectionWithDim(ctx context.Context, collectionName string, dim int) error
+
 	// CollectionExists 检查集合是否存在
 	CollectionExists(ctx context.Context, collectionName string) (bool, error)
 
 	// DeleteCollection 删除集合
 	DeleteCollection(ctx context.Context, collectionName string) error
 
+	// CreateHybridCollection 创建同时包含稠密向量和稀疏向量字段的集合
+	CreateHybridCollection(ctx context.Context, collectionName string, dim int) error
+
 	// InsertVectors 插入向量数据 - 使用float32以直接与向量库兼容，无需转换
 	InsertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32) ([]string, error)
 
+	// InsertHybridVectors 同时插入稠密向量和稀疏向量（集合需由 CreateHybridCollection 创建）
+	InsertHybridVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error)
+
 	// DeleteByDocumentID 根据文档ID删除所有相关chunks
 	DeleteByDocumentID(ctx context.Context, collectionName string, documentID string) error
 
@@ -132,4 +153,16 @@ type VectorStore interface {
 	// VectorSearchOnly 仅使用向量检索的通用方法
 	// 执行向量相似度搜索，去重，排序，并按分数过滤结果
 	VectorSearchOnly(ctx context.Context, conf GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error)
+
+	// SparseSearch 稀疏向量检索，返回按内积降序排列的文档（分数未归一化），支持 WithKnowledgeID 限定知识库
+	SparseSearch(ctx context.Context, collectionName string, query common.SparseVector, topK int, opts ...Option) ([]*schema.Document, error)
+
+	// KeywordSearch 关键词（全文）检索，返回按 BM25 分数降序排列的文档（分数未归一化），支持 WithKnowledgeID 限定知识库
+	KeywordSearch(ctx context.Context, collectionName string, query string, topK int, opts ...Option) ([]*schema.Document, error)
+}
+
+// CollectionLoader 支持把集合预加载到内存的向量存储（如 Milvus），预热任务在首次检索前加载常用集合
+type CollectionLoader interface {
+	// LoadCollection 加载集合并等待加载完成，已加载的集合直接返回
+	LoadCollection(ctx context.Context, collectionName string) error
 }
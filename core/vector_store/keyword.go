@@ -0,0 +1,165 @@
+package vector_store
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// 关键词检索参数
+const (
+	keywordMaxTerms      = 32   // 查询最多使用的关键词数
+	keywordMinCandidates = 100  // 向量库返回的最少候选数
+	keywordMaxCandidates = 1000 // 向量库返回的最多候选数
+	bm25K1               = 1.2
+	bm25B                = 0.75
+)
+
+// keywordTerm 查询关键词，cjk 为 true 时是中日韩文字的字符二元组（或单字），需要按子串匹配
+type keywordTerm struct {
+	text string
+	cjk  bool
+}
+
+// keywordTerms 对查询分词：字母数字按单词切分并转为小写，中日韩文字没有空格分词，按字符二元组切分
+func keywordTerms(query string) []keywordTerm {
+	var terms []keywordTerm
+	seen := make(map[string]bool)
+	add := func(text string, cjk bool) {
+		if text == "" || seen[text] || len(terms) >= keywordMaxTerms {
+			return
+		}
+		seen[text] = true
+		terms = append(terms, keywordTerm{text: text, cjk: cjk})
+	}
+	addCJK := func(run []rune) {
+		if len(run) == 1 {
+			add(string(run), true)
+			return
+		}
+		for i := 0; i+1 < len(run); i++ {
+			add(string(run[i:i+2]), true)
+		}
+	}
+
+	var word, cjk []rune
+	flush := func() {
+		add(string(word), false)
+		addCJK(cjk)
+		word, cjk = word[:0], cjk[:0]
+	}
+	for _, r := range strings.ToLower(query) {
+		switch {
+		case isCJK(r):
+			add(string(word), false)
+			word = word[:0]
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			addCJK(cjk)
+			cjk = cjk[:0]
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// keywordCandidates 向量库按关键词匹配返回的候选数，候选在本地按 BM25 打分后再截取 topK
+func keywordCandidates(topK int) int {
+	return min(max(topK*10, keywordMinCandidates), keywordMaxCandidates)
+}
+
+// rankBM25 按 BM25 对候选文档打分并降序排列，丢弃不包含任何关键词的文档
+// IDF 和平均文档长度按本次候选集合估算，分数只用于排序和融合，不同查询之间不可比较
+func rankBM25(docs []*schema.Document, terms []keywordTerm) []*schema.Document {
+	if len(docs) == 0 || len(terms) == 0 {
+		return []*schema.Document{}
+	}
+
+	contents := make([]string, len(docs))
+	lengths := make([]float64, len(docs))
+	var totalLength float64
+	for i, doc := range docs {
+		contents[i] = strings.ToLower(doc.Content)
+		lengths[i] = float64(utf8.RuneCountInString(doc.Content))
+		totalLength += lengths[i]
+	}
+	avgLength := totalLength / float64(len(docs))
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	frequencies := make([][]int, len(terms))
+	idf := make([]float64, len(terms))
+	for t, term := range terms {
+		frequencies[t] = make([]int, len(docs))
+		matched := 0
+		for i, content := range contents {
+			if n := countTerm(content, term); n > 0 {
+				frequencies[t][i] = n
+				matched++
+			}
+		}
+		n := float64(len(docs))
+		idf[t] = math.Log(1 + (n-float64(matched)+0.5)/(float64(matched)+0.5))
+	}
+
+	ranked := make([]*schema.Document, 0, len(docs))
+	for i, doc := range docs {
+		var score float64
+		for t := range terms {
+			tf := float64(frequencies[t][i])
+			if tf == 0 {
+				continue
+			}
+			score += idf[t] * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*lengths[i]/avgLength))
+		}
+		if score <= 0 {
+			continue
+		}
+		doc.Score = float32(score)
+		ranked = append(ranked, doc)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// countTerm 统计关键词在已转小写的内容中出现的次数，单词需要完整匹配，中日韩文字按子串匹配
+func countTerm(content string, term keywordTerm) int {
+	if term.cjk {
+		return strings.Count(content, term.text)
+	}
+	count := 0
+	for _, word := range strings.FieldsFunc(content, func(r rune) bool {
+		return isCJK(r) || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	}) {
+		if word == term.text {
+			count++
+		}
+	}
+	return count
+}
+
+// MatchedTerms 查询中出现在内容里的关键词，分词和匹配规则与关键词检索（BM25）相同，用于解释检索结果
+func MatchedTerms(query, content string) []string {
+	content = strings.ToLower(content)
+	var matched []string
+	for _, term := range keywordTerms(query) {
+		if countTerm(content, term) > 0 {
+			matched = append(matched, term.text)
+		}
+	}
+	return matched
+}
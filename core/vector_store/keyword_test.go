@@ -0,0 +1,67 @@
+package vector_store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestKeywordTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []keywordTerm
+	}{
+		{"words lowercased and deduplicated", "GPU gpu, Milvus-2.6", []keywordTerm{{"gpu", false}, {"milvus", false}, {"2", false}, {"6", false}}},
+		{"chinese bigrams", "保修期多久", []keywordTerm{{"保修", true}, {"修期", true}, {"期多", true}, {"多久", true}}},
+		{"mixed", "iPhone保修", []keywordTerm{{"iphone", false}, {"保修", true}}},
+		{"single character", "猫 cat", []keywordTerm{{"猫", true}, {"cat", false}}},
+		{"punctuation only", "？！", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keywordTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keywordTerms(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankBM25(t *testing.T) {
+	docs := []*schema.Document{
+		{ID: "unrelated", Content: "Milvus 支持稀疏向量检索"},
+		{ID: "once", Content: "产品保修期为两年，请保留发票"},
+		{ID: "twice", Content: "保修期内免费维修，保修期外收费"},
+		{ID: "partial", Content: "gpuserver 不应匹配 gpu 之外的单词"},
+	}
+	ranked := rankBM25(docs, keywordTerms("保修期"))
+	var ids []string
+	for _, doc := range ranked {
+		ids = append(ids, doc.ID)
+	}
+	if want := []string{"twice", "once"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("rankBM25 order = %v, want %v", ids, want)
+	}
+	if ranked[0].Score <= ranked[1].Score || ranked[1].Score <= 0 {
+		t.Errorf("unexpected scores: %v, %v", ranked[0].Score, ranked[1].Score)
+	}
+
+	if got := rankBM25(docs, keywordTerms("server")); len(got) != 0 {
+		t.Errorf("words must match whole tokens, got %d docs", len(got))
+	}
+}
+
+func TestMatchedTerms(t *testing.T) {
+	got := MatchedTerms("GPU 保修期", "本产品的保修期为两年，gpuserver 除外")
+	if want := []string{"保修", "修期"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchedTerms() = %v, want %v", got, want)
+	}
+}
+
+func TestMilvusKeywordFilter(t *testing.T) {
+	got := milvusKeywordFilter(keywordTerms("GPU 显卡"))
+	if want := `(text like "%gpu%" or text like "%显卡%")`; got != want {
+		t.Errorf("milvusKeywordFilter() = %q, want %q", got, want)
+	}
+}
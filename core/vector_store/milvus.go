@@ -45,6 +45,28 @@ func InitializeMilvusStore(ctx context.Context) (VectorStore, error) {
 		return nil, fmt.Errorf("milvus.address is required but not found in config file. Please check your config.yaml file and ensure milvus.address is properly set")
 	}
 
+	return connectMilvus(ctx, address, database)
+}
+
+// InitializeMilvusReplicas 连接 milvus.readReplicas 配置的只读副本，连接失败的副本跳过并记录日志
+func InitializeMilvusReplicas(ctx context.Context) ([]VectorStore, []string) {
+	database := g.Cfg().MustGet(ctx, "milvus.database", "default").String()
+	var stores []VectorStore
+	var names []string
+	for _, address := range g.Cfg().MustGet(ctx, "milvus.readReplicas").Strings() {
+		store, err := connectMilvus(ctx, address, database)
+		if err != nil {
+			g.Log().Warningf(ctx, "Skipping Milvus read replica %s: %v", address, err)
+			continue
+		}
+		stores = append(stores, store)
+		names = append(names, address)
+	}
+	return stores, names
+}
+
+// connectMilvus 连接 Milvus 并创建向量存储实例
+func connectMilvus(ctx context.Context, address, database string) (VectorStore, error) {
 	g.Log().Infof(ctx, "Connecting to Milvus at: %s, database: %s", address, database)
 
 	// Create Milvus client directly using milvusclient
@@ -118,21 +140,44 @@ func (m *MilvusStore) CreateDatabaseIfNotExists(ctx context.Context) error {
 
 // CreateCollection 创建集合
 func (m *MilvusStore) CreateCollection(ctx context.Context, collectionName string) error {
-	// 获取向量维度，优先从配置文件读取
-	dim := g.Cfg().MustGet(ctx, "milvus.dim", 1024).Int()
+	return m.CreateCollectionWithDim(ctx, collectionName, g.Cfg().MustGet(ctx, "milvus.dim", 1024).Int())
+}
+
+// CreateCollectionWithDim 使用指定向量维度创建集合
+func (m *MilvusStore) CreateCollectionWithDim(ctx context.Context, collectionName string, dim int) error {
+	return m.createCollection(ctx, collectionName, dim, false)
+}
+
+// CreateHybridCollection 创建同时包含稠密向量和稀疏向量字段的集合
+func (m *MilvusStore) CreateHybridCollection(ctx context.Context, collectionName string, dim int) error {
+	return m.createCollection(ctx, collectionName, dim, true)
+}
+
+// createCollection 创建集合，hybrid 为 true 时额外创建稀疏向量字段及其倒排索引
+func (m *MilvusStore) createCollection(ctx context.Context, collectionName string, dim int, hybrid bool) error {
 	dimStr := fmt.Sprintf("%d", dim)
 
 	// 使用标准 text collection schema
+	fields := milvusModel.GetStandardCollectionFields(dimStr)
+	if hybrid {
+		fields = milvusModel.GetHybridCollectionFields(dimStr)
+	}
 	schema := &entity.Schema{
 		CollectionName: collectionName,
 		Description:    "存储文档分片及其向量",
 		AutoID:         false,
-		Fields:         milvusModel.GetStandardCollectionFields(dimStr),
+		Fields:         fields,
 	}
 
 	// 创建文档片段集合，并设置vector为索引
-	err := m.client.CreateCollection(ctx, milvusclient.NewCreateCollectionOption(collectionName, schema).WithIndexOptions(
-		milvusclient.NewCreateIndexOption(collectionName, "vector", index.NewHNSWIndex(entity.L2, 64, 128))))
+	indexOptions := []milvusclient.CreateIndexOption{
+		milvusclient.NewCreateIndexOption(collectionName, "vector", index.NewHNSWIndex(entity.L2, 64, 128)),
+	}
+	if hybrid {
+		indexOptions = append(indexOptions, milvusclient.NewCreateIndexOption(collectionName, milvusModel.SparseVectorField,
+			index.NewSparseInvertedIndex(entity.IP, 0.2)))
+	}
+	err := m.client.CreateCollection(ctx, milvusclient.NewCreateCollectionOption(collectionName, schema).WithIndexOptions(indexOptions...))
 	if err != nil {
 		return fmt.Errorf("failed to create Milvus collection: %w", err)
 	}
@@ -143,7 +188,7 @@ func (m *MilvusStore) CreateCollection(ctx context.Context, collectionName strin
 		return fmt.Errorf("failed to load Milvus collection: %w", err)
 	}
 
-	g.Log().Infof(ctx, "Collection '%s' created with dimension %d, index built and loaded", collectionName, dim)
+	g.Log().Infof(ctx, "Collection '%s' created with dimension %d (hybrid: %v), index built and loaded", collectionName, dim, hybrid)
 	return nil
 }
 
@@ -156,6 +201,18 @@ func (m *MilvusStore) CollectionExists(ctx context.Context, collectionName strin
 	return has, nil
 }
 
+// LoadCollection 把集合加载到内存并等待加载完成（Milvus 重启或集合被释放后首次检索不再等待加载）
+func (m *MilvusStore) LoadCollection(ctx context.Context, collectionName string) error {
+	task, err := m.client.LoadCollection(ctx, milvusclient.NewLoadCollectionOption(collectionName))
+	if err != nil {
+		return fmt.Errorf("failed to load collection %s: %w", collectionName, err)
+	}
+	if err = task.Await(ctx); err != nil {
+		return fmt.Errorf("failed to wait for collection %s to load: %w", collectionName, err)
+	}
+	return nil
+}
+
 // DeleteCollection 删除集合
 func (m *MilvusStore) DeleteCollection(ctx context.Context, collectionName string) error {
 	err := m.client.DropCollection(ctx, milvusclient.NewDropCollectionOption(collectionName))
@@ -168,6 +225,19 @@ func (m *MilvusStore) DeleteCollection(ctx context.Context, collectionName strin
 
 // InsertVectors 插入向量数据 - 直接使用float32向量
 func (m *MilvusStore) InsertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32) ([]string, error) {
+	return m.insertVectors(ctx, collectionName, chunks, vectors, nil)
+}
+
+// InsertHybridVectors 同时插入稠密向量和稀疏向量
+func (m *MilvusStore) InsertHybridVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(chunks) != len(sparseVectors) {
+		return nil, fmt.Errorf("chunks and sparse vectors length mismatch: %d vs %d", len(chunks), len(sparseVectors))
+	}
+	return m.insertVectors(ctx, collectionName, chunks, vectors, sparseVectors)
+}
+
+// insertVectors 插入向量数据，sparseVectors 为 nil 时不写入稀疏向量字段
+func (m *MilvusStore) insertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
 	if len(chunks) != len(vectors) {
 		return nil, fmt.Errorf("chunks and vectors length mismatch: %d vs %d", len(chunks), len(vectors))
 	}
@@ -229,8 +299,11 @@ func (m *MilvusStore) InsertVectors(ctx context.Context, collectionName string,
 		metadataList[idx] = metaBytes
 	}
 
-	// 获取向量维度，优先从配置文件读取
+	// 向量维度以实际向量为准，避免配置与模型输出不一致
 	dim := g.Cfg().MustGet(ctx, "milvus.dim", 1024).Int()
+	if len(vectors) > 0 && len(vectors[0]) > 0 {
+		dim = len(vectors[0])
+	}
 
 	// 创建列数据 - 直接使用传入的float32向量
 	columns := []column.Column{
@@ -240,6 +313,17 @@ func (m *MilvusStore) InsertVectors(ctx context.Context, collectionName string,
 		column.NewColumnVarChar("document_id", documentIds),
 		column.NewColumnJSONBytes("metadata", metadataList),
 	}
+	if sparseVectors != nil {
+		sparseEmbeddings := make([]entity.SparseEmbedding, len(sparseVectors))
+		for idx, sv := range sparseVectors {
+			embedding, err := entity.NewSliceSparseEmbedding(sv.Indices, sv.Values)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sparse vector for chunk %s: %w", ids[idx], err)
+			}
+			sparseEmbeddings[idx] = embedding
+		}
+		columns = append(columns, column.NewColumnSparseVectors(milvusModel.SparseVectorField, sparseEmbeddings))
+	}
 
 	// 插入数据
 	insertOpt := milvusclient.NewColumnBasedInsertOption(collectionName, columns...)
@@ -374,19 +458,9 @@ func (r *milvusRetriever) Retrieve(ctx context.Context, query string, opts ...Op
 		scoreThreshold = options.ScoreThreshold
 	}
 
-	// 获取 Milvus 特定选项（filter, partition）
-	var filter, partition string
-	for _, opt := range opts {
-		// 尝试应用到临时Options来提取filter和partition
-		tempOpts := &Options{}
-		opt(tempOpts)
-		if tempOpts.Filter != "" {
-			filter = tempOpts.Filter
-		}
-		if tempOpts.Partition != "" {
-			partition = tempOpts.Partition
-		}
-	}
+	// 获取 Milvus 特定选项（filter, partition），多个过滤条件与知识库限定用 and 组合
+	filter := CombineFilters(options.Filter, milvusKnowledgeFilter(knowledgeScope(ctx, options.KnowledgeID)))
+	partition := options.Partition
 
 	// 创建embedding实例 - 使用接口方法获取配置,避免反射
 	var apiKey, baseURL, embeddingModel string
@@ -439,28 +513,14 @@ func (r *milvusRetriever) Retrieve(ctx context.Context, query string, opts ...Op
 		embeddingModel: embeddingModel,
 	}
 
-	embedder, err := common.NewEmbedding(ctx, embeddingConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder: %w", err)
-	}
-
 	// embedding查询 - 直接获取float32向量
-	// 获取向量维度，优先从配置文件读取
-	dim := g.Cfg().MustGet(ctx, "milvus.dim", 1024).Int()
-	vectors, err := embedder.EmbedStrings(ctx, []string{query}, dim)
+	queryVector, err := embedQuery(ctx, embeddingConfig, query, "milvus.dim")
 	if err != nil {
-		return nil, fmt.Errorf("embedding has error: %w", err)
-	}
-	// 检查 embedding result
-	if len(vectors) != 1 {
-		return nil, fmt.Errorf("invalid return length of vector, got=%d, expected=1", len(vectors))
+		return nil, err
 	}
 
 	// 将float32向量转换为entity.Vector
-	entityVectors := make([]entity.Vector, len(vectors))
-	for i, vec := range vectors {
-		entityVectors[i] = entity.FloatVector(vec)
-	}
+	entityVectors := []entity.Vector{entity.FloatVector(queryVector)}
 
 	// 准备分区
 	partitions := []string{}
@@ -787,6 +847,78 @@ func (m *MilvusStore) ConvertSearchResultsToDocuments(ctx context.Context, colum
 	return result, nil
 }
 
+// SparseSearch 稀疏向量检索，分数为内积（未归一化）
+func (m *MilvusStore) SparseSearch(ctx context.Context, collectionName string, query common.SparseVector, topK int, opts ...Option) ([]*schema.Document, error) {
+	if query.Len() == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	queryVector, err := entity.NewSliceSparseEmbedding(query.Indices, query.Values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sparse query vector: %w", err)
+	}
+
+	searchOpt := milvusclient.NewSearchOption(collectionName, topK, []entity.Vector{queryVector}).
+		WithANNSField(milvusModel.SparseVectorField).
+		WithOutputFields("id", "text", "document_id", "metadata").
+		WithConsistencyLevel(entity.ClBounded)
+
+	options := GetCommonOptions(nil, opts...)
+	if filter := CombineFilters(options.Filter, milvusKnowledgeFilter(knowledgeScope(ctx, options.KnowledgeID))); filter != "" {
+		searchOpt = searchOpt.WithFilter(filter)
+	}
+
+	results, err := m.client.Search(ctx, searchOpt)
+	if err != nil {
+		return nil, fmt.Errorf("sparse search has error: %w", err)
+	}
+	if len(results) == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	return m.ConvertSearchResultsToDocuments(ctx, results[0].Fields, results[0].Scores)
+}
+
+// KeywordSearch 关键词检索：按文本字段的 like 条件取出包含任一关键词的候选分片，在本地按 BM25 打分排序
+// 文本字段没有全文索引，候选数有上限（见 keywordCandidates），大集合上只适合作为向量检索的补充召回
+func (m *MilvusStore) KeywordSearch(ctx context.Context, collectionName string, query string, topK int, opts ...Option) ([]*schema.Document, error) {
+	terms := keywordTerms(query)
+	if len(terms) == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	options := GetCommonOptions(nil, opts...)
+	queryOpt := milvusclient.NewQueryOption(collectionName).
+		WithFilter(CombineFilters(milvusKeywordFilter(terms), options.Filter, milvusKnowledgeFilter(knowledgeScope(ctx, options.KnowledgeID)))).
+		WithOutputFields("id", "text", "document_id", "metadata").
+		WithLimit(keywordCandidates(topK)).
+		WithConsistencyLevel(entity.ClBounded)
+
+	rs, err := m.client.Query(ctx, queryOpt)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search has error: %w", err)
+	}
+	docs, err := m.ConvertSearchResultsToDocuments(ctx, rs.Fields, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	docs = rankBM25(docs, terms)
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// milvusKeywordFilter 匹配包含任一关键词的分片，关键词只含字母数字和中日韩文字，无需转义
+func milvusKeywordFilter(terms []keywordTerm) string {
+	conditions := make([]string, len(terms))
+	for i, term := range terms {
+		conditions[i] = fmt.Sprintf(`%s like "%%%s%%"`, common.FieldContent, escapeMilvusString(term.text))
+	}
+	return "(" + strings.Join(conditions, " or ") + ")"
+}
+
 // VectorSearchOnly 仅使用向量检索的通用方法
 func (m *MilvusStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error) {
 	var filter string
@@ -807,9 +939,9 @@ func (m *MilvusStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrieve
 		milvusTopK = 20 // 至少取20个
 	}
 
-	// 执行检索
+	// 执行检索，限定在知识库内
 	var options []Option
-	options = append(options, WithTopK(milvusTopK))
+	options = append(options, WithTopK(milvusTopK), WithKnowledgeID(knowledgeId))
 
 	// 只有在有过滤条件时才添加 filter
 	if filter != "" {
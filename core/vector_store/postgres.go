@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"sort"
 	"strings"
 
@@ -37,6 +38,38 @@ func InitializePostgresStore(ctx context.Context) (VectorStore, error) {
 		return nil, fmt.Errorf("postgres configuration is incomplete. Required: host, user, database")
 	}
 
+	return connectPostgres(ctx, host, port, user, password, database, sslMode)
+}
+
+// InitializePostgresReplicas 连接 postgres.readReplicas 配置的只读副本（host 或 host:port，用户名、密码和数据库与主库相同），
+// 连接失败的副本跳过并记录日志
+func InitializePostgresReplicas(ctx context.Context) ([]VectorStore, []string) {
+	port := g.Cfg().MustGet(ctx, "postgres.port", "5432").String()
+	user := g.Cfg().MustGet(ctx, "postgres.user", "").String()
+	password := g.Cfg().MustGet(ctx, "postgres.password", "").String()
+	database := g.Cfg().MustGet(ctx, "postgres.database", "").String()
+	sslMode := g.Cfg().MustGet(ctx, "postgres.sslmode", "disable").String()
+
+	var stores []VectorStore
+	var names []string
+	for _, endpoint := range g.Cfg().MustGet(ctx, "postgres.readReplicas").Strings() {
+		replicaHost, replicaPort := endpoint, port
+		if h, p, err := net.SplitHostPort(endpoint); err == nil {
+			replicaHost, replicaPort = h, p
+		}
+		store, err := connectPostgres(ctx, replicaHost, replicaPort, user, password, database, sslMode)
+		if err != nil {
+			g.Log().Warningf(ctx, "Skipping PostgreSQL read replica %s: %v", endpoint, err)
+			continue
+		}
+		stores = append(stores, store)
+		names = append(names, endpoint)
+	}
+	return stores, names
+}
+
+// connectPostgres 创建 PostgreSQL 连接池并创建向量存储实例
+func connectPostgres(ctx context.Context, host, port, user, password, database, sslMode string) (VectorStore, error) {
 	// 构建连接字符串（去掉空密码的 password= 参数）
 	var connStr string
 	if password != "" {
@@ -132,9 +165,13 @@ func (p *PostgresStore) CreateDatabaseIfNotExists(ctx context.Context) error {
 
 // CreateCollection 创建集合（表）- 使用模型定义
 func (p *PostgresStore) CreateCollection(ctx context.Context, collectionName string) error {
+	return p.CreateCollectionWithDim(ctx, collectionName, g.Cfg().MustGet(ctx, "postgres.dim", 1024).Int())
+}
+
+// CreateCollectionWithDim 使用指定向量维度创建集合（表）
+func (p *PostgresStore) CreateCollectionWithDim(ctx context.Context, collectionName string, dim int) error {
 	// 清理表名，防止SQL注入
 	tableName := p.sanitizeTableName(collectionName)
-	dim := g.Cfg().MustGet(ctx, "milvus.dim", 1024).Int()
 
 	// 使用标准表结构模型
 	schema := pgvectorModel.TableSchema{}
@@ -159,6 +196,22 @@ func (p *PostgresStore) CreateCollection(ctx context.Context, collectionName str
 	return nil
 }
 
+// CreateHybridCollection 创建同时包含稠密向量和稀疏向量列的表
+func (p *PostgresStore) CreateHybridCollection(ctx context.Context, collectionName string, dim int) error {
+	if err := p.CreateCollectionWithDim(ctx, collectionName, dim); err != nil {
+		return err
+	}
+
+	tableName := p.sanitizeTableName(collectionName)
+	alterSQL := pgvectorModel.TableSchema{}.GenerateAddSparseColumnSQL(p.schema, tableName)
+	if _, err := p.pool.Exec(ctx, alterSQL); err != nil {
+		return fmt.Errorf("failed to add sparse vector column to table %s.%s: %w", p.schema, tableName, err)
+	}
+
+	g.Log().Infof(ctx, "Table '%s.%s' created with sparse vector column", p.schema, tableName)
+	return nil
+}
+
 // CollectionExists 检查集合（表）是否存在
 func (p *PostgresStore) CollectionExists(ctx context.Context, collectionName string) (bool, error) {
 	tableName := p.sanitizeTableName(collectionName)
@@ -192,6 +245,19 @@ func (p *PostgresStore) DeleteCollection(ctx context.Context, collectionName str
 
 // InsertVectors 插入向量数据
 func (p *PostgresStore) InsertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32) ([]string, error) {
+	return p.insertVectors(ctx, collectionName, chunks, vectors, nil)
+}
+
+// InsertHybridVectors 同时插入稠密向量和稀疏向量
+func (p *PostgresStore) InsertHybridVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(chunks) != len(sparseVectors) {
+		return nil, fmt.Errorf("chunks and sparse vectors length mismatch: %d vs %d", len(chunks), len(sparseVectors))
+	}
+	return p.insertVectors(ctx, collectionName, chunks, vectors, sparseVectors)
+}
+
+// insertVectors 插入向量数据，sparseVectors 为 nil 时不写入稀疏向量列
+func (p *PostgresStore) insertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
 	if len(chunks) != len(vectors) {
 		return nil, fmt.Errorf("chunks and vectors length mismatch: %d vs %d", len(chunks), len(vectors))
 	}
@@ -223,6 +289,12 @@ func (p *PostgresStore) InsertVectors(ctx context.Context, collectionName string
 		INSERT INTO %s (id, text, vector, document_id, metadata)
 		VALUES ($1, $2, $3, $4, $5)
 	`, fullTableName)
+	if sparseVectors != nil {
+		insertSQL = fmt.Sprintf(`
+		INSERT INTO %s (id, text, vector, document_id, metadata, %s)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, fullTableName, pgvectorModel.SparseVectorColumn)
+	}
 
 	for idx, chunk := range chunks {
 		// 生成chunk ID（如果不存在）
@@ -265,7 +337,11 @@ func (p *PostgresStore) InsertVectors(ctx context.Context, collectionName string
 		}
 
 		// 插入数据
-		_, err = tx.Exec(ctx, insertSQL, chunk.ID, text, pgVector, docID, metaBytes)
+		args := []any{chunk.ID, text, pgVector, docID, metaBytes}
+		if sparseVectors != nil {
+			args = append(args, toPgSparseVector(sparseVectors[idx]))
+		}
+		_, err = tx.Exec(ctx, insertSQL, args...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert vector for chunk %s: %w", chunk.ID, err)
 		}
@@ -395,12 +471,200 @@ func (p *PostgresStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrie
 
 	// 执行检索 - 使用反射调用Retrieve方法或者直接类型断言
 	if pgRetriever, ok := r.(*postgresRetriever); ok {
-		return pgRetriever.vectorSearchWithThreshold(ctx, query, postgresTopK, score)
+		return pgRetriever.vectorSearchWithThreshold(ctx, query, postgresTopK, score, knowledgeScope(ctx, knowledgeId))
 	}
 
 	return nil, fmt.Errorf("failed to cast retriever to postgresRetriever")
 }
 
+// SparseSearch 稀疏向量检索，分数为内积（未归一化），Milvus 过滤表达式不适用于 PostgreSQL，只支持 WithKnowledgeID
+func (p *PostgresStore) SparseSearch(ctx context.Context, collectionName string, query common.SparseVector, topK int, opts ...Option) ([]*schema.Document, error) {
+	if query.Len() == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	fullTableName := fmt.Sprintf("%s.%s", p.schema, p.sanitizeTableName(collectionName))
+	args := []any{toPgSparseVector(query), topK}
+	knowledgeCond := ""
+	if knowledgeID := knowledgeScope(ctx, GetCommonOptions(nil, opts...).KnowledgeID); knowledgeID != "" {
+		args = append(args, knowledgeID)
+		knowledgeCond = pgKnowledgeCondition(len(args))
+	}
+	// <#> 返回负内积，取反后作为分数
+	searchSQL := fmt.Sprintf(`
+		SELECT id, text, document_id, metadata,
+		       ((%[2]s <#> $1) * -1) as similarity_score
+		FROM %[1]s
+		WHERE %[2]s IS NOT NULL%[3]s
+		ORDER BY %[2]s <#> $1
+		LIMIT $2
+	`, fullTableName, pgvectorModel.SparseVectorColumn, knowledgeCond)
+
+	rows, err := p.pool.Query(ctx, searchSQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute sparse search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*schema.Document
+	chunkIDs := make([]string, 0, topK)
+	for rows.Next() {
+		var id, text, documentId string
+		var metadataBytes []byte
+		var score float64
+		if err := rows.Scan(&id, &text, &documentId, &metadataBytes, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		doc := &schema.Document{
+			ID:       id,
+			Content:  text,
+			MetaData: make(map[string]any),
+			Score:    float32(score),
+		}
+		if len(metadataBytes) > 0 {
+			var metadata map[string]any
+			if err := json.Unmarshal(metadataBytes, &metadata); err == nil {
+				for k, v := range metadata {
+					doc.MetaData[k] = v
+				}
+			}
+		}
+		doc.MetaData[common.DocumentId] = documentId
+		results = append(results, doc)
+		chunkIDs = append(chunkIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// 权限控制：过滤掉status != 1的chunks
+	activeIDs, err := dao.KnowledgeChunks.GetActiveChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk status: %w", err)
+	}
+	filtered := make([]*schema.Document, 0, len(results))
+	for _, doc := range results {
+		if activeIDs.Contains(doc.ID) {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered, nil
+}
+
+// KeywordSearch 关键词检索：单词通过全文检索表达式（有 GIN 索引）匹配，中日韩文字的字符二元组通过 ILIKE 匹配，
+// 按 ts_rank_cd 取出候选分片后在本地按 BM25 打分排序，与 Milvus 的分数含义一致
+func (p *PostgresStore) KeywordSearch(ctx context.Context, collectionName string, query string, topK int, opts ...Option) ([]*schema.Document, error) {
+	terms := keywordTerms(query)
+	if len(terms) == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	var words, patterns []string
+	for _, term := range terms {
+		if term.cjk {
+			patterns = append(patterns, "%"+term.text+"%")
+		} else {
+			words = append(words, term.text)
+		}
+	}
+	var conditions []string
+	args := []any{strings.Join(words, " | "), keywordCandidates(topK)}
+	if len(words) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s @@ to_tsquery('simple', $1)", pgvectorModel.TextSearchVector))
+	}
+	if len(patterns) > 0 {
+		args = append(args, patterns)
+		conditions = append(conditions, fmt.Sprintf("text ILIKE ANY($%d)", len(args)))
+	}
+	knowledgeCond := ""
+	if knowledgeID := knowledgeScope(ctx, GetCommonOptions(nil, opts...).KnowledgeID); knowledgeID != "" {
+		args = append(args, knowledgeID)
+		knowledgeCond = pgKnowledgeCondition(len(args))
+	}
+
+	fullTableName := fmt.Sprintf("%s.%s", p.schema, p.sanitizeTableName(collectionName))
+	searchSQL := fmt.Sprintf(`
+		SELECT id, text, document_id, metadata
+		FROM %s
+		WHERE (%s)%s
+		ORDER BY ts_rank_cd(%s, to_tsquery('simple', $1)) DESC
+		LIMIT $2
+	`, fullTableName, strings.Join(conditions, " OR "), knowledgeCond, pgvectorModel.TextSearchVector)
+
+	rows, err := p.pool.Query(ctx, searchSQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute keyword search: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*schema.Document
+	chunkIDs := make([]string, 0, topK)
+	for rows.Next() {
+		var id, text, documentId string
+		var metadataBytes []byte
+		if err := rows.Scan(&id, &text, &documentId, &metadataBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		doc := &schema.Document{
+			ID:       id,
+			Content:  text,
+			MetaData: make(map[string]any),
+		}
+		if len(metadataBytes) > 0 {
+			var metadata map[string]any
+			if err := json.Unmarshal(metadataBytes, &metadata); err == nil {
+				for k, v := range metadata {
+					doc.MetaData[k] = v
+				}
+			}
+		}
+		doc.MetaData[common.DocumentId] = documentId
+		candidates = append(candidates, doc)
+		chunkIDs = append(chunkIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	if len(candidates) == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	// 权限控制：过滤掉status != 1的chunks
+	activeIDs, err := dao.KnowledgeChunks.GetActiveChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk status: %w", err)
+	}
+	active := make([]*schema.Document, 0, len(candidates))
+	for _, doc := range candidates {
+		if activeIDs.Contains(doc.ID) {
+			active = append(active, doc)
+		}
+	}
+
+	docs := rankBM25(active, terms)
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// pgSparseDim sparsevec 的维度上限，稀疏模型的词项ID（含 BM42 的哈希ID）超出时取模折叠
+const pgSparseDim = 1000000000
+
+// toPgSparseVector 将稀疏向量转换为 pgvector sparsevec
+func toPgSparseVector(v common.SparseVector) pgvector.SparseVector {
+	elements := make(map[int32]float32, len(v.Indices))
+	for i, idx := range v.Indices {
+		elements[int32(idx%pgSparseDim)] += v.Values[i]
+	}
+	return pgvector.NewSparseVectorFromMap(elements, pgSparseDim)
+}
+
 // Helper functions
 
 func (p *PostgresStore) sanitizeTableName(name string) string {
@@ -442,15 +706,21 @@ type postgresRetriever struct {
 func (r *postgresRetriever) Retrieve(ctx context.Context, query string, opts ...Option) ([]*schema.Document, error) {
 	// 默认参数
 	topK := 5
+	threshold := 0.0
 
-	// 解析选项（这里简化处理，实际使用时需要更完整的选项解析）
-	_ = opts // 暂时忽略选项
+	// 解析选项，Milvus 过滤表达式和分区不适用于 PostgreSQL
+	options := GetCommonOptions(&Options{TopK: &topK, ScoreThreshold: &threshold}, opts...)
 
-	return r.vectorSearchWithThreshold(ctx, query, topK, 0.0)
+	return r.vectorSearchWithThreshold(ctx, query, *options.TopK, *options.ScoreThreshold, knowledgeScope(ctx, options.KnowledgeID))
 }
 
-// vectorSearchWithThreshold 带阈值的向量搜索
-func (r *postgresRetriever) vectorSearchWithThreshold(ctx context.Context, query string, topK int, threshold float64) ([]*schema.Document, error) {
+// pgKnowledgeCondition 按分片元数据中的 knowledge_id 过滤的条件，知识库ID为第 index 个参数
+func pgKnowledgeCondition(index int) string {
+	return fmt.Sprintf(" AND metadata->>'%s' = $%d", common.KnowledgeId, index)
+}
+
+// vectorSearchWithThreshold 带阈值的向量搜索，knowledgeID 不为空时只检索该知识库的分片
+func (r *postgresRetriever) vectorSearchWithThreshold(ctx context.Context, query string, topK int, threshold float64, knowledgeID string) ([]*schema.Document, error) {
 	// 获取embedding配置 - 使用接口方法获取,避免循环依赖
 	var apiKey, baseURL, embeddingModel string
 	if r.config != nil {
@@ -488,26 +758,14 @@ func (r *postgresRetriever) vectorSearchWithThreshold(ctx context.Context, query
 		embeddingModel: embeddingModel,
 	}
 
-	// 创建embedder
-	embedder, err := common.NewEmbedding(ctx, embeddingConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder: %w", err)
-	}
-
 	// 生成查询向量
-	// 获取向量维度，优先从配置文件读取
-	dim := g.Cfg().MustGet(ctx, "postgres.dim", 1024).Int()
-	vectors, err := embedder.EmbedStrings(ctx, []string{query}, dim)
+	vector, err := embedQuery(ctx, embeddingConfig, query, "postgres.dim")
 	if err != nil {
-		return nil, fmt.Errorf("embedding has error: %w", err)
-	}
-
-	if len(vectors) != 1 {
-		return nil, fmt.Errorf("invalid return length of vector, got=%d, expected=1", len(vectors))
+		return nil, err
 	}
 
 	// 直接使用float32向量
-	queryVector := pgvector.NewVector(vectors[0])
+	queryVector := pgvector.NewVector(vector)
 
 	// 获取距离度量类型，从配置文件读取
 	metricType := g.Cfg().MustGet(ctx, "vectordb.metricType", "COSINE").String()
@@ -535,16 +793,22 @@ func (r *postgresRetriever) vectorSearchWithThreshold(ctx context.Context, query
 	}
 
 	// 执行向量相似度搜索
+	args := []any{queryVector, threshold, topK}
+	knowledgeCond := ""
+	if knowledgeID != "" {
+		args = append(args, knowledgeID)
+		knowledgeCond = pgKnowledgeCondition(len(args))
+	}
 	searchSQL := fmt.Sprintf(`
 		SELECT id, text, document_id, metadata,
 		       %s as similarity_score
 		FROM %s
-		WHERE %s >= $2
+		WHERE %s >= $2%s
 		ORDER BY %s
 		LIMIT $3
-	`, scoreCalc, r.tableName, scoreCalc, orderBy)
+	`, scoreCalc, r.tableName, scoreCalc, knowledgeCond, orderBy)
 
-	rows, err := r.pool.Query(ctx, searchSQL, queryVector, threshold, topK)
+	rows, err := r.pool.Query(ctx, searchSQL, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute vector search: %w", err)
 	}
@@ -0,0 +1,608 @@
+package vector_store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// Qdrant 集合中的命名向量和 payload 字段
+const (
+	qdrantDenseVector  = "vector" // 稠密向量
+	qdrantSparseVector = "sparse" // 稀疏向量，由 CreateHybridCollection 创建
+	qdrantMetadataKey  = "metadata"
+)
+
+// qdrantIDNamespace 分片ID不是 UUID 时，按该命名空间生成确定的 UUID 作为 Qdrant 点ID（点ID只支持 UUID 和无符号整数）
+var qdrantIDNamespace = uuid.MustParse("6f1c3b5e-2d4a-4f8e-9b7c-0a1d2e3f4a5b")
+
+// QdrantClient Qdrant REST API 客户端
+type QdrantClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewQdrantClient 创建 Qdrant REST API 客户端，address 为 http(s)://host:6333
+func NewQdrantClient(address, apiKey string) *QdrantClient {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+	return &QdrantClient{
+		baseURL:    strings.TrimSuffix(address, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// qdrantResponse Qdrant API 响应，出错时 status 为 {"error": "..."}
+type qdrantResponse struct {
+	Result json.RawMessage `json:"result"`
+	Status json.RawMessage `json:"status"`
+}
+
+// qdrantStatusError 出错时 status 字段的结构
+type qdrantStatusError struct {
+	Error string `json:"error"`
+}
+
+// do 发送请求，out 不为 nil 时解析响应中的 result；返回 HTTP 状态码
+func (c *QdrantClient) do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("api-key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result qdrantResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: failed to decode response: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var status qdrantStatusError
+		_ = json.Unmarshal(result.Status, &status)
+		return resp.StatusCode, fmt.Errorf("API error (HTTP %d): %s", resp.StatusCode, status.Error)
+	}
+	if out != nil && len(result.Result) > 0 {
+		if err := json.Unmarshal(result.Result, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode result: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// QdrantStore Qdrant 向量数据库实现（REST API），每个知识库一个集合
+type QdrantStore struct {
+	client *QdrantClient
+}
+
+// InitializeQdrantStore 初始化 Qdrant 向量存储
+func InitializeQdrantStore(ctx context.Context) (VectorStore, error) {
+	address := g.Cfg().MustGet(ctx, "qdrant.address", "").String()
+	apiKey := g.Cfg().MustGet(ctx, "qdrant.apiKey", "").String()
+	if address == "" {
+		return nil, fmt.Errorf("qdrant.address is required but not found in config file")
+	}
+
+	g.Log().Infof(ctx, "Connecting to Qdrant at: %s", address)
+	return NewQdrantStore(&VectorStoreConfig{
+		Type:   VectorStoreTypeQdrant,
+		Client: NewQdrantClient(address, apiKey),
+	})
+}
+
+// NewQdrantStore 创建 Qdrant 向量存储实例
+func NewQdrantStore(config *VectorStoreConfig) (VectorStore, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	client, ok := config.Client.(*QdrantClient)
+	if !ok {
+		return nil, fmt.Errorf("client must be *QdrantClient")
+	}
+
+	return &QdrantStore{client: client}, nil
+}
+
+// CreateDatabaseIfNotExists Qdrant 没有数据库的概念，只检查服务是否可用
+func (q *QdrantStore) CreateDatabaseIfNotExists(ctx context.Context) error {
+	if _, err := q.client.do(ctx, http.MethodGet, "/collections", nil, nil); err != nil {
+		return fmt.Errorf("failed to connect to qdrant: %w", err)
+	}
+	g.Log().Infof(ctx, "Qdrant is ready")
+	return nil
+}
+
+// CreateCollection 创建集合（使用配置文件中的向量维度）
+func (q *QdrantStore) CreateCollection(ctx context.Context, collectionName string) error {
+	return q.CreateCollectionWithDim(ctx, collectionName, g.Cfg().MustGet(ctx, "qdrant.dim", 1024).Int())
+}
+
+// CreateCollectionWithDim 使用指定向量维度创建集合
+func (q *QdrantStore) CreateCollectionWithDim(ctx context.Context, collectionName string, dim int) error {
+	return q.createCollection(ctx, collectionName, dim, qdrantDistance(g.Cfg().MustGet(ctx, "vectordb.metricType", "COSINE").String()), false)
+}
+
+// CreateHybridCollection 创建同时包含稠密向量和稀疏向量的集合
+func (q *QdrantStore) CreateHybridCollection(ctx context.Context, collectionName string, dim int) error {
+	return q.createCollection(ctx, collectionName, dim, qdrantDistance(g.Cfg().MustGet(ctx, "vectordb.metricType", "COSINE").String()), true)
+}
+
+// createCollection 创建集合，并为按文档删除和按知识库过滤使用的 payload 字段创建索引
+func (q *QdrantStore) createCollection(ctx context.Context, collectionName string, dim int, distance string, hybrid bool) error {
+	body := map[string]any{
+		"vectors": map[string]any{
+			qdrantDenseVector: map[string]any{"size": dim, "distance": distance},
+		},
+	}
+	if hybrid {
+		body["sparse_vectors"] = map[string]any{qdrantSparseVector: map[string]any{}}
+	}
+	if _, err := q.client.do(ctx, http.MethodPut, collectionPath(collectionName), body, nil); err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", collectionName, err)
+	}
+
+	for _, field := range []string{common.DocumentId, "id", qdrantMetadataKey + "." + common.KnowledgeId} {
+		index := map[string]any{"field_name": field, "field_schema": "keyword"}
+		if _, err := q.client.do(ctx, http.MethodPut, collectionPath(collectionName)+"/index?wait=true", index, nil); err != nil {
+			return fmt.Errorf("failed to create payload index %s on collection %s: %w", field, collectionName, err)
+		}
+	}
+
+	g.Log().Infof(ctx, "Collection '%s' created with dimension %d (hybrid: %v)", collectionName, dim, hybrid)
+	return nil
+}
+
+// CollectionExists 检查集合是否存在
+func (q *QdrantStore) CollectionExists(ctx context.Context, collectionName string) (bool, error) {
+	status, err := q.client.do(ctx, http.MethodGet, collectionPath(collectionName), nil, nil)
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteCollection 删除集合
+func (q *QdrantStore) DeleteCollection(ctx context.Context, collectionName string) error {
+	if _, err := q.client.do(ctx, http.MethodDelete, collectionPath(collectionName), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	g.Log().Infof(ctx, "Collection '%s' deleted", collectionName)
+	return nil
+}
+
+// InsertVectors 插入向量数据
+func (q *QdrantStore) InsertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32) ([]string, error) {
+	return q.insertVectors(ctx, collectionName, chunks, vectors, nil)
+}
+
+// InsertHybridVectors 同时插入稠密向量和稀疏向量
+func (q *QdrantStore) InsertHybridVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(chunks) != len(sparseVectors) {
+		return nil, fmt.Errorf("chunks and sparse vectors length mismatch: %d vs %d", len(chunks), len(sparseVectors))
+	}
+	return q.insertVectors(ctx, collectionName, chunks, vectors, sparseVectors)
+}
+
+// qdrantPoint 写入的点，分片ID、文本和文档ID保存在 payload 中
+type qdrantPoint struct {
+	ID      string         `json:"id"`
+	Vector  map[string]any `json:"vector"`
+	Payload map[string]any `json:"payload"`
+}
+
+// insertVectors 插入向量数据，sparseVectors 为 nil 时不写入稀疏向量
+func (q *QdrantStore) insertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(chunks) != len(vectors) {
+		return nil, fmt.Errorf("chunks and vectors length mismatch: %d vs %d", len(chunks), len(vectors))
+	}
+
+	// 从上下文中提取knowledge_id和document_id
+	var knowledgeId string
+	if value, ok := ctx.Value(common.KnowledgeId).(string); ok {
+		knowledgeId = value
+	}
+	var contextDocumentId string
+	if value, ok := ctx.Value(common.DocumentId).(string); ok {
+		contextDocumentId = value
+	}
+
+	ids := make([]string, len(chunks))
+	points := make([]qdrantPoint, len(chunks))
+	for idx, chunk := range chunks {
+		// 生成chunk ID（如果不存在）
+		if len(chunk.ID) == 0 {
+			chunk.ID = uuid.New().String()
+		}
+		ids[idx] = chunk.ID
+
+		if contextDocumentId == "" {
+			return nil, fmt.Errorf("document_id not found in context for chunk %s", chunk.ID)
+		}
+
+		// 构建metadata
+		metaCopy := make(map[string]any)
+		for k, v := range chunk.MetaData {
+			metaCopy[k] = v
+		}
+		if knowledgeId != "" {
+			metaCopy[common.KnowledgeId] = knowledgeId
+		}
+
+		vector := map[string]any{qdrantDenseVector: vectors[idx]}
+		if sparseVectors != nil && sparseVectors[idx].Len() > 0 {
+			vector[qdrantSparseVector] = sparseVectors[idx]
+		}
+		points[idx] = qdrantPoint{
+			ID:     qdrantPointID(chunk.ID),
+			Vector: vector,
+			Payload: map[string]any{
+				"id":                chunk.ID,
+				common.FieldContent: truncateString(chunk.Content, 65535),
+				common.DocumentId:   contextDocumentId,
+				qdrantMetadataKey:   metaCopy,
+			},
+		}
+	}
+
+	body := map[string]any{"points": points}
+	if _, err := q.client.do(ctx, http.MethodPut, collectionPath(collectionName)+"/points?wait=true", body, nil); err != nil {
+		return nil, fmt.Errorf("failed to insert vectors: %w", err)
+	}
+
+	g.Log().Infof(ctx, "Successfully inserted %d vectors into collection '%s'", len(points), collectionName)
+	return ids, nil
+}
+
+// DeleteByDocumentID 根据文档ID删除所有相关chunks
+func (q *QdrantStore) DeleteByDocumentID(ctx context.Context, collectionName string, documentID string) error {
+	if !common.ValidateUUID(documentID) {
+		return fmt.Errorf("invalid document ID format: %s (must be valid UUID)", documentID)
+	}
+
+	g.Log().Infof(ctx, "Deleting all chunks of document %s from collection %s", documentID, collectionName)
+	return q.deleteWhere(ctx, collectionName, common.DocumentId, documentID)
+}
+
+// DeleteByChunkID 根据chunkID删除单个chunk
+func (q *QdrantStore) DeleteByChunkID(ctx context.Context, collectionName string, chunkID string) error {
+	if !common.ValidateUUID(chunkID) {
+		return fmt.Errorf("invalid chunk ID format: %s (must be valid UUID)", chunkID)
+	}
+
+	g.Log().Infof(ctx, "Deleting chunk %s from collection %s", chunkID, collectionName)
+	return q.deleteWhere(ctx, collectionName, "id", chunkID)
+}
+
+// deleteWhere 删除 payload 字段等于指定值的点
+func (q *QdrantStore) deleteWhere(ctx context.Context, collectionName, key, value string) error {
+	body := map[string]any{"filter": qdrantFilter{Must: []qdrantCondition{matchValue(key, value)}}}
+	if _, err := q.client.do(ctx, http.MethodPost, collectionPath(collectionName)+"/points/delete?wait=true", body, nil); err != nil {
+		return fmt.Errorf("failed to delete points where %s=%s: %w", key, value, err)
+	}
+	return nil
+}
+
+// GetClient 返回底层 Qdrant 客户端
+func (q *QdrantStore) GetClient() interface{} {
+	return q.client
+}
+
+// NewRetriever 创建 Qdrant 检索器实例
+func (q *QdrantStore) NewRetriever(ctx context.Context, conf interface{}, collectionName string) (Retriever, error) {
+	if collectionName == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+
+	exists, err := q.CollectionExists(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+
+	return &qdrantRetriever{
+		store:          q,
+		collectionName: collectionName,
+		config:         conf,
+	}, nil
+}
+
+// VectorSearchOnly 仅使用向量检索的通用方法
+func (q *QdrantStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error) {
+	// knowledge name == collection name
+	r, err := q.NewRetriever(ctx, conf, knowledgeId)
+	if err != nil {
+		g.Log().Errorf(ctx, "failed to create retriever for collection %s, err=%v", knowledgeId, err)
+		return nil, err
+	}
+
+	// Qdrant 检索的 TopK，可以设置得比最终需要的数量大一些
+	qdrantTopK := max(topK*5, 20)
+	return r.(*qdrantRetriever).vectorSearchWithThreshold(ctx, query, qdrantTopK, score, knowledgeScope(ctx, knowledgeId))
+}
+
+// SparseSearch 稀疏向量检索，分数为内积（未归一化），Milvus 过滤表达式不适用于 Qdrant，只支持 WithKnowledgeID
+func (q *QdrantStore) SparseSearch(ctx context.Context, collectionName string, query common.SparseVector, topK int, opts ...Option) ([]*schema.Document, error) {
+	if query.Len() == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	body := map[string]any{
+		"vector":       map[string]any{"name": qdrantSparseVector, "vector": query},
+		"limit":        topK,
+		"with_payload": true,
+	}
+	if filter := knowledgeFilter(knowledgeScope(ctx, GetCommonOptions(nil, opts...).KnowledgeID)); filter != nil {
+		body["filter"] = filter
+	}
+
+	var points []qdrantScoredPoint
+	if _, err := q.client.do(ctx, http.MethodPost, collectionPath(collectionName)+"/points/search", body, &points); err != nil {
+		return nil, fmt.Errorf("sparse search has error: %w", err)
+	}
+	return activeDocuments(ctx, pointsToDocuments(points))
+}
+
+// KeywordSearch 关键词检索：按文本字段的全文匹配条件取出包含任一关键词的候选分片，在本地按 BM25 打分排序
+// 文本字段不建全文索引，匹配条件按子串匹配，中日韩文字的二元组也能命中；候选数有上限（见 keywordCandidates）
+func (q *QdrantStore) KeywordSearch(ctx context.Context, collectionName string, query string, topK int, opts ...Option) ([]*schema.Document, error) {
+	terms := keywordTerms(query)
+	if len(terms) == 0 {
+		return []*schema.Document{}, nil
+	}
+
+	filter := qdrantKeywordFilter(terms)
+	if knowledge := knowledgeFilter(knowledgeScope(ctx, GetCommonOptions(nil, opts...).KnowledgeID)); knowledge != nil {
+		filter.Must = knowledge.Must
+	}
+	body := map[string]any{
+		"filter":       filter,
+		"limit":        keywordCandidates(topK),
+		"with_payload": true,
+		"with_vector":  false,
+	}
+
+	var page struct {
+		Points []qdrantScoredPoint `json:"points"`
+	}
+	if _, err := q.client.do(ctx, http.MethodPost, collectionPath(collectionName)+"/points/scroll", body, &page); err != nil {
+		return nil, fmt.Errorf("keyword search has error: %w", err)
+	}
+	docs, err := activeDocuments(ctx, pointsToDocuments(page.Points))
+	if err != nil {
+		return nil, err
+	}
+
+	docs = rankBM25(docs, terms)
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// qdrantFilter Qdrant 过滤条件：must 全部满足，should 至少满足一个
+type qdrantFilter struct {
+	Must   []qdrantCondition `json:"must,omitempty"`
+	Should []qdrantCondition `json:"should,omitempty"`
+}
+
+// qdrantCondition payload 字段匹配条件，value 为精确匹配，text 为全文匹配
+type qdrantCondition struct {
+	Key   string         `json:"key"`
+	Match map[string]any `json:"match"`
+}
+
+func matchValue(key, value string) qdrantCondition {
+	return qdrantCondition{Key: key, Match: map[string]any{"value": value}}
+}
+
+// knowledgeFilter 按分片元数据中的 knowledge_id 过滤，knowledgeID 为空时返回 nil
+func knowledgeFilter(knowledgeID string) *qdrantFilter {
+	if knowledgeID == "" {
+		return nil
+	}
+	return &qdrantFilter{Must: []qdrantCondition{matchValue(qdrantMetadataKey+"."+common.KnowledgeId, knowledgeID)}}
+}
+
+// qdrantKeywordFilter 匹配包含任一关键词的分片
+func qdrantKeywordFilter(terms []keywordTerm) *qdrantFilter {
+	filter := &qdrantFilter{Should: make([]qdrantCondition, len(terms))}
+	for i, term := range terms {
+		filter.Should[i] = qdrantCondition{Key: common.FieldContent, Match: map[string]any{"text": term.text}}
+	}
+	return filter
+}
+
+// qdrantScoredPoint 检索或遍历返回的点
+type qdrantScoredPoint struct {
+	Score   float32        `json:"score"`
+	Payload map[string]any `json:"payload"`
+}
+
+// pointsToDocuments 转换 Qdrant 的点为文档，分片ID取 payload 中保存的原始ID
+func pointsToDocuments(points []qdrantScoredPoint) []*schema.Document {
+	docs := make([]*schema.Document, 0, len(points))
+	for _, point := range points {
+		doc := &schema.Document{MetaData: make(map[string]any), Score: point.Score}
+		doc.ID, _ = point.Payload["id"].(string)
+		doc.Content, _ = point.Payload[common.FieldContent].(string)
+		if metadata, ok := point.Payload[qdrantMetadataKey].(map[string]any); ok {
+			for k, v := range metadata {
+				doc.MetaData[k] = v
+			}
+		}
+		if documentID, ok := point.Payload[common.DocumentId].(string); ok {
+			doc.MetaData[common.DocumentId] = documentID
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// activeDocuments 权限控制：过滤掉status != 1的chunks
+func activeDocuments(ctx context.Context, docs []*schema.Document) ([]*schema.Document, error) {
+	if len(docs) == 0 {
+		return []*schema.Document{}, nil
+	}
+	chunkIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		chunkIDs = append(chunkIDs, doc.ID)
+	}
+	activeIDs, err := dao.KnowledgeChunks.GetActiveChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk status: %w", err)
+	}
+	filtered := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if activeIDs.Contains(doc.ID) {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered, nil
+}
+
+// qdrantPointID 分片ID是 UUID 时直接作为点ID，否则生成确定的 UUID
+func qdrantPointID(chunkID string) string {
+	if id, err := uuid.Parse(chunkID); err == nil {
+		return id.String()
+	}
+	return uuid.NewSHA1(qdrantIDNamespace, []byte(chunkID)).String()
+}
+
+// qdrantDistance 把 vectordb.metricType 转换为 Qdrant 的距离类型
+func qdrantDistance(metricType string) string {
+	switch strings.ToUpper(metricType) {
+	case "L2":
+		return "Euclid"
+	case "IP", "INNER_PRODUCT":
+		return "Dot"
+	default:
+		return "Cosine"
+	}
+}
+
+// qdrantScore 把 Qdrant 返回的分数转换为越大越相似的分数：余弦和内积直接使用，欧氏距离按 1/(1+d) 归一化，与 pgvector 一致
+func qdrantScore(metricType string, score float32) float32 {
+	if qdrantDistance(metricType) == "Euclid" {
+		return 1 / (1 + score)
+	}
+	return score
+}
+
+func collectionPath(collectionName string) string {
+	return "/collections/" + url.PathEscape(collectionName)
+}
+
+// qdrantRetriever 实现了 Retriever 接口
+type qdrantRetriever struct {
+	store          *QdrantStore
+	collectionName string
+	config         interface{}
+}
+
+// Retrieve 实现检索功能
+func (r *qdrantRetriever) Retrieve(ctx context.Context, query string, opts ...Option) ([]*schema.Document, error) {
+	topK := 5
+	threshold := 0.0
+
+	// 解析选项，Milvus 过滤表达式和分区不适用于 Qdrant
+	options := GetCommonOptions(&Options{TopK: &topK, ScoreThreshold: &threshold}, opts...)
+
+	return r.vectorSearchWithThreshold(ctx, query, *options.TopK, *options.ScoreThreshold, knowledgeScope(ctx, options.KnowledgeID))
+}
+
+// vectorSearchWithThreshold 带阈值的向量搜索，knowledgeID 不为空时只检索该知识库的分片
+func (r *qdrantRetriever) vectorSearchWithThreshold(ctx context.Context, query string, topK int, threshold float64, knowledgeID string) ([]*schema.Document, error) {
+	queryVector, err := embedQuery(ctx, retrieverEmbeddingConfig(r.config), query, "qdrant.dim")
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]any{
+		"vector":       map[string]any{"name": qdrantDenseVector, "vector": queryVector},
+		"limit":        topK,
+		"with_payload": true,
+	}
+	if filter := knowledgeFilter(knowledgeID); filter != nil {
+		body["filter"] = filter
+	}
+
+	var points []qdrantScoredPoint
+	if _, err := r.store.client.do(ctx, http.MethodPost, collectionPath(r.collectionName)+"/points/search", body, &points); err != nil {
+		return nil, fmt.Errorf("search has error: %w", err)
+	}
+
+	// 分数阈值在转换分数后过滤，欧氏距离的原始分数越小越相似，不能直接使用 Qdrant 的 score_threshold
+	metricType := g.Cfg().MustGet(ctx, "vectordb.metricType", "COSINE").String()
+	docs := pointsToDocuments(points)
+	results := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		doc.Score = qdrantScore(metricType, doc.Score)
+		if float64(doc.Score) >= threshold {
+			results = append(results, doc)
+		}
+	}
+
+	results, err = activeDocuments(ctx, results)
+	if err != nil {
+		return nil, err
+	}
+
+	// 去重
+	results = common.RemoveDuplicates(results, func(doc *schema.Document) string {
+		return doc.ID
+	})
+
+	// 按相似度排序
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+// GetType 返回检索器类型
+func (r *qdrantRetriever) GetType() string {
+	return "QdrantRetriever"
+}
+
+// IsCallbacksEnabled 返回是否启用回调
+func (r *qdrantRetriever) IsCallbacksEnabled() bool {
+	return false
+}
@@ -0,0 +1,145 @@
+package vector_store
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// qdrantRequest 模拟服务收到的请求
+type qdrantRequest struct {
+	Method string
+	Path   string
+	Body   map[string]any
+}
+
+// newQdrantTestServer 模拟 Qdrant REST API，记录收到的请求，不存在的集合返回 404
+func newQdrantTestServer(t *testing.T, collections map[string]bool) (*QdrantStore, *[]qdrantRequest) {
+	var requests []qdrantRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := qdrantRequest{Method: r.Method, Path: r.URL.Path}
+		_ = json.NewDecoder(r.Body).Decode(&req.Body)
+		requests = append(requests, req)
+
+		if r.Method == http.MethodGet && r.URL.Path != "/collections" && !collections[r.URL.Path[len("/collections/"):]] {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":{"error":"Not found: Collection doesn't exist!"},"time":0}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":true,"status":"ok","time":0}`))
+	}))
+	t.Cleanup(server.Close)
+
+	store, err := NewQdrantStore(&VectorStoreConfig{Type: VectorStoreTypeQdrant, Client: NewQdrantClient(server.URL, "")})
+	require.NoError(t, err)
+	return store.(*QdrantStore), &requests
+}
+
+func TestQdrantStoreCreation(t *testing.T) {
+	_, err := NewQdrantStore(nil)
+	assert.Error(t, err)
+
+	_, err = NewQdrantStore(&VectorStoreConfig{Type: VectorStoreTypeQdrant, Client: "invalid_client"})
+	assert.ErrorContains(t, err, "must be *QdrantClient")
+
+	store, err := NewVectorStore(&VectorStoreConfig{Type: VectorStoreTypeQdrant, Client: NewQdrantClient("localhost:6333", "")})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:6333", store.GetClient().(*QdrantClient).baseURL)
+}
+
+func TestQdrantCollectionExists(t *testing.T) {
+	store, _ := newQdrantTestServer(t, map[string]bool{"kb_1": true})
+	ctx := context.Background()
+
+	exists, err := store.CollectionExists(ctx, "kb_1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = store.CollectionExists(ctx, "kb_2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestQdrantCreateHybridCollection(t *testing.T) {
+	store, requests := newQdrantTestServer(t, nil)
+	require.NoError(t, store.createCollection(context.Background(), "kb_1", 768, "Cosine", true))
+
+	require.Len(t, *requests, 4)
+	create := (*requests)[0]
+	assert.Equal(t, http.MethodPut, create.Method)
+	assert.Equal(t, "/collections/kb_1", create.Path)
+	vectors := create.Body["vectors"].(map[string]any)[qdrantDenseVector].(map[string]any)
+	assert.Equal(t, float64(768), vectors["size"])
+	assert.Contains(t, create.Body["sparse_vectors"], qdrantSparseVector)
+
+	var indexed []string
+	for _, req := range (*requests)[1:] {
+		assert.Equal(t, "/collections/kb_1/index", req.Path)
+		indexed = append(indexed, req.Body["field_name"].(string))
+	}
+	assert.ElementsMatch(t, []string{common.DocumentId, "id", "metadata." + common.KnowledgeId}, indexed)
+}
+
+func TestQdrantInsertAndDelete(t *testing.T) {
+	store, requests := newQdrantTestServer(t, nil)
+	documentID := uuid.New().String()
+	ctx := context.WithValue(context.WithValue(context.Background(), common.KnowledgeId, "kb-1"), common.DocumentId, documentID)
+
+	chunks := []*schema.Document{
+		{ID: uuid.New().String(), Content: "hello", MetaData: map[string]any{"chunk_index": 0}},
+		{ID: "chunk-not-uuid", Content: "world"},
+	}
+	ids, err := store.InsertHybridVectors(ctx, "kb_1", chunks, [][]float32{{0.1, 0.2}, {0.3, 0.4}},
+		[]common.SparseVector{{Indices: []uint32{1}, Values: []float32{0.5}}, {}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{chunks[0].ID, "chunk-not-uuid"}, ids)
+
+	insert := (*requests)[0]
+	assert.Equal(t, "/collections/kb_1/points", insert.Path)
+	points := insert.Body["points"].([]any)
+	require.Len(t, points, 2)
+	first := points[0].(map[string]any)
+	assert.Equal(t, chunks[0].ID, first["id"])
+	assert.Contains(t, first["vector"], qdrantSparseVector)
+	payload := first["payload"].(map[string]any)
+	assert.Equal(t, documentID, payload[common.DocumentId])
+	assert.Equal(t, "kb-1", payload[qdrantMetadataKey].(map[string]any)[common.KnowledgeId])
+	second := points[1].(map[string]any)
+	assert.Equal(t, qdrantPointID("chunk-not-uuid"), second["id"])
+	assert.Equal(t, "chunk-not-uuid", second["payload"].(map[string]any)["id"])
+	assert.NotContains(t, second["vector"], qdrantSparseVector)
+
+	require.NoError(t, store.DeleteByDocumentID(ctx, "kb_1", documentID))
+	remove := (*requests)[1]
+	assert.Equal(t, "/collections/kb_1/points/delete", remove.Path)
+	condition := remove.Body["filter"].(map[string]any)["must"].([]any)[0].(map[string]any)
+	assert.Equal(t, common.DocumentId, condition["key"])
+
+	assert.Error(t, store.DeleteByChunkID(ctx, "kb_1", "not-a-uuid"))
+}
+
+func TestQdrantHelpers(t *testing.T) {
+	id := uuid.New().String()
+	assert.Equal(t, id, qdrantPointID(id))
+	assert.Equal(t, qdrantPointID("a"), qdrantPointID("a"))
+	assert.NotEqual(t, qdrantPointID("a"), qdrantPointID("b"))
+
+	assert.Equal(t, "Cosine", qdrantDistance("COSINE"))
+	assert.Equal(t, "Euclid", qdrantDistance("l2"))
+	assert.Equal(t, "Dot", qdrantDistance("IP"))
+	assert.Equal(t, float32(0.5), qdrantScore("L2", 1))
+	assert.Equal(t, float32(0.8), qdrantScore("COSINE", 0.8))
+
+	assert.Nil(t, knowledgeFilter(""))
+	filter := qdrantKeywordFilter(keywordTerms("Qdrant 向量"))
+	assert.Len(t, filter.Should, 2)
+	assert.Equal(t, map[string]any{"text": "qdrant"}, filter.Should[0].Match)
+}
@@ -0,0 +1,165 @@
+package vector_store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// defaultReplicaCooldown 只读副本查询失败后暂停使用的时间
+const defaultReplicaCooldown = 30 * time.Second
+
+// ReplicaStore 带只读副本的向量存储：检索查询按轮询分发到只读副本，副本查询失败时自动回退到主库，
+// 并在冷却时间内不再使用该副本；建集合、写入、删除等操作始终在主库执行，避免检索高峰拖慢文档索引
+type ReplicaStore struct {
+	VectorStore // 主库，未覆盖的方法都在主库执行
+
+	replicas []*replica
+	cooldown time.Duration
+	next     atomic.Uint64
+}
+
+// replica 只读副本及其健康状态
+type replica struct {
+	name  string
+	store VectorStore
+
+	mu          sync.Mutex
+	unavailable time.Time // 在此时间之前不使用该副本
+}
+
+// NewReplicaStore 创建带只读副本的向量存储，names 与 replicas 一一对应，用于日志；没有副本时直接返回主库
+func NewReplicaStore(primary VectorStore, replicas []VectorStore, names []string, cooldown time.Duration) VectorStore {
+	if len(replicas) == 0 {
+		return primary
+	}
+	if cooldown <= 0 {
+		cooldown = defaultReplicaCooldown
+	}
+	store := &ReplicaStore{VectorStore: primary, cooldown: cooldown}
+	for i, r := range replicas {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		store.replicas = append(store.replicas, &replica{name: name, store: r})
+	}
+	return store
+}
+
+// Primary 返回主库
+func (s *ReplicaStore) Primary() VectorStore {
+	return s.VectorStore
+}
+
+// pick 按轮询选择一个可用的只读副本，全部不可用时返回 nil
+func (s *ReplicaStore) pick(now time.Time) *replica {
+	start := s.next.Add(1) - 1
+	for i := 0; i < len(s.replicas); i++ {
+		r := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		if r.available(now) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *replica) available(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !now.Before(r.unavailable)
+}
+
+// markFailed 副本查询失败，冷却时间内不再使用
+func (r *replica) markFailed(ctx context.Context, err error, cooldown time.Duration) {
+	r.mu.Lock()
+	r.unavailable = time.Now().Add(cooldown)
+	r.mu.Unlock()
+	g.Log().Warningf(ctx, "Vector store read replica %s failed, falling back to primary for %s: %v", r.name, cooldown, err)
+}
+
+// readWithFailback 在可用的只读副本上执行查询，失败时标记副本并回退到主库执行
+func readWithFailback[T any](ctx context.Context, s *ReplicaStore, fn func(store VectorStore) (T, error)) (T, error) {
+	if r := s.pick(time.Now()); r != nil {
+		result, err := fn(r.store)
+		if err == nil || ctx.Err() != nil {
+			return result, err
+		}
+		r.markFailed(ctx, err, s.cooldown)
+	}
+	return fn(s.VectorStore)
+}
+
+// VectorSearchOnly 在只读副本上执行向量检索
+func (s *ReplicaStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error) {
+	return readWithFailback(ctx, s, func(store VectorStore) ([]*schema.Document, error) {
+		return store.VectorSearchOnly(ctx, conf, query, knowledgeId, topK, score)
+	})
+}
+
+// SparseSearch 在只读副本上执行稀疏向量检索
+func (s *ReplicaStore) SparseSearch(ctx context.Context, collectionName string, query common.SparseVector, topK int, opts ...Option) ([]*schema.Document, error) {
+	return readWithFailback(ctx, s, func(store VectorStore) ([]*schema.Document, error) {
+		return store.SparseSearch(ctx, collectionName, query, topK, opts...)
+	})
+}
+
+// KeywordSearch 在只读副本上执行关键词检索
+func (s *ReplicaStore) KeywordSearch(ctx context.Context, collectionName string, query string, topK int, opts ...Option) ([]*schema.Document, error) {
+	return readWithFailback(ctx, s, func(store VectorStore) ([]*schema.Document, error) {
+		return store.KeywordSearch(ctx, collectionName, query, topK, opts...)
+	})
+}
+
+// NewRetriever 创建在只读副本上检索的检索器，检索失败时回退到主库
+func (s *ReplicaStore) NewRetriever(ctx context.Context, conf interface{}, collectionName string) (Retriever, error) {
+	return &replicaRetriever{store: s, conf: conf, collectionName: collectionName}, nil
+}
+
+// LoadCollection 在主库和所有只读副本上加载集合，不支持预加载的存储跳过
+func (s *ReplicaStore) LoadCollection(ctx context.Context, collectionName string) error {
+	if loader, ok := s.VectorStore.(CollectionLoader); ok {
+		if err := loader.LoadCollection(ctx, collectionName); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.replicas {
+		if loader, ok := r.store.(CollectionLoader); ok {
+			if err := loader.LoadCollection(ctx, collectionName); err != nil {
+				return fmt.Errorf("replica %s: %w", r.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// replicaRetriever 每次检索时选择只读副本创建检索器，失败时回退到主库
+type replicaRetriever struct {
+	store          *ReplicaStore
+	conf           interface{}
+	collectionName string
+}
+
+func (r *replicaRetriever) Retrieve(ctx context.Context, query string, opts ...Option) ([]*schema.Document, error) {
+	return readWithFailback(ctx, r.store, func(store VectorStore) ([]*schema.Document, error) {
+		retriever, err := store.NewRetriever(ctx, r.conf, r.collectionName)
+		if err != nil {
+			return nil, err
+		}
+		return retriever.Retrieve(ctx, query, opts...)
+	})
+}
+
+func (r *replicaRetriever) GetType() string {
+	return "ReplicaRetriever"
+}
+
+func (r *replicaRetriever) IsCallbacksEnabled() bool {
+	return false
+}
@@ -0,0 +1,93 @@
+package vector_store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// fakeStore 记录检索调用次数，fail 为 true 时检索返回错误
+type fakeStore struct {
+	VectorStore
+	name     string
+	fail     bool
+	searches int
+	deletes  int
+}
+
+func (f *fakeStore) VectorSearchOnly(ctx context.Context, conf GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error) {
+	f.searches++
+	if f.fail {
+		return nil, errors.New("replica unavailable")
+	}
+	return []*schema.Document{{ID: f.name}}, nil
+}
+
+func (f *fakeStore) DeleteByDocumentID(ctx context.Context, collectionName string, documentID string) error {
+	f.deletes++
+	return nil
+}
+
+func TestReplicaStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("没有副本时返回主库", func(t *testing.T) {
+		primary := &fakeStore{name: "primary"}
+		if store := NewReplicaStore(primary, nil, nil, 0); store != primary {
+			t.Errorf("NewReplicaStore() without replicas = %T, want primary", store)
+		}
+	})
+
+	t.Run("检索轮询副本，写入在主库", func(t *testing.T) {
+		primary := &fakeStore{name: "primary"}
+		r1, r2 := &fakeStore{name: "r1"}, &fakeStore{name: "r2"}
+		store := NewReplicaStore(primary, []VectorStore{r1, r2}, []string{"r1", "r2"}, time.Minute)
+		var got []string
+		for i := 0; i < 4; i++ {
+			docs, err := store.VectorSearchOnly(ctx, nil, "q", "kb", 5, 0)
+			if err != nil {
+				t.Fatalf("VectorSearchOnly() error = %v", err)
+			}
+			got = append(got, docs[0].ID)
+		}
+		if want := []string{"r1", "r2", "r1", "r2"}; !equalStrings(got, want) {
+			t.Errorf("served by %v, want %v", got, want)
+		}
+		if err := store.DeleteByDocumentID(ctx, "kb", "doc"); err != nil {
+			t.Fatalf("DeleteByDocumentID() error = %v", err)
+		}
+		if primary.deletes != 1 || r1.deletes+r2.deletes != 0 {
+			t.Errorf("deletes primary=%d replicas=%d, want only primary", primary.deletes, r1.deletes+r2.deletes)
+		}
+	})
+
+	t.Run("副本失败时回退到主库并进入冷却", func(t *testing.T) {
+		primary := &fakeStore{name: "primary"}
+		broken := &fakeStore{name: "broken", fail: true}
+		store := NewReplicaStore(primary, []VectorStore{broken}, []string{"broken"}, time.Minute)
+		for i := 0; i < 3; i++ {
+			docs, err := store.VectorSearchOnly(ctx, nil, "q", "kb", 5, 0)
+			if err != nil || docs[0].ID != "primary" {
+				t.Fatalf("VectorSearchOnly() = %v, %v, want primary result", docs, err)
+			}
+		}
+		if broken.searches != 1 {
+			t.Errorf("broken replica searched %d times, want 1 (cooldown)", broken.searches)
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
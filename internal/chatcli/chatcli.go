@@ -0,0 +1,284 @@
+// Package chatcli 交互式终端对话客户端：连接运行中的 kbgo 服务，支持选择模型、人设、知识库和检索视图，
+// 流式输出回答、附带文件提问，会话由服务端按会话ID保存，客户端把最近一次会话的设置保存在本地以便继续对话
+package chatcli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/pkg/client"
+)
+
+// Settings 对话设置，同时作为本地保存的会话状态
+type Settings struct {
+	ConvID           string `json:"conv_id"`
+	ModelID          string `json:"model_id,omitempty"`
+	EmbeddingModelID string `json:"embedding_model_id,omitempty"`
+	RerankModelID    string `json:"rerank_model_id,omitempty"`
+	KnowledgeID      string `json:"knowledge_id,omitempty"`
+	RetrievalView    string `json:"retrieval_view,omitempty"`
+	PersonaID        string `json:"persona_id,omitempty"`
+	UseMCP           bool   `json:"use_mcp,omitempty"`
+	ShowReasoning    bool   `json:"show_reasoning,omitempty"`
+}
+
+// NewConvID 生成新的会话ID
+func NewConvID() string {
+	return fmt.Sprintf("cli-%d", time.Now().UnixNano())
+}
+
+// DefaultStateFile 本地会话状态文件，位于用户配置目录下
+func DefaultStateFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "kbgo", "chat.json")
+}
+
+// LoadSettings 读取本地保存的会话设置，文件不存在时返回 nil
+func LoadSettings(path string) (*Settings, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings := new(Settings)
+	if err = json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("invalid chat state file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// SaveSettings 保存会话设置
+func SaveSettings(path string, settings *Settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Session 一次终端对话
+type Session struct {
+	client    *client.Client
+	settings  *Settings
+	out       io.Writer
+	stateFile string   // 为空时不保存会话设置
+	files     []string // 下一个问题附带的文件
+}
+
+// NewSession 创建终端对话，stateFile 为空时不在本地保存会话设置
+func NewSession(c *client.Client, settings *Settings, out io.Writer, stateFile string) *Session {
+	if settings.ConvID == "" {
+		settings.ConvID = NewConvID()
+	}
+	return &Session{client: c, settings: settings, out: out, stateFile: stateFile}
+}
+
+// Settings 返回当前设置
+func (s *Session) Settings() *Settings {
+	return s.settings
+}
+
+// Run 逐行读取输入：以 / 开头的是命令，其他内容作为问题发送，输入结束或 /quit 时返回
+func (s *Session) Run(ctx context.Context, in io.Reader) error {
+	s.printf("kbgo chat - conversation %s, type /help for commands\n", s.settings.ConvID)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		s.printf("> ")
+		if !scanner.Scan() {
+			s.printf("\n")
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			quit, err := s.Command(ctx, line)
+			if err != nil {
+				s.printf("error: %v\n", err)
+			}
+			if quit {
+				break
+			}
+			continue
+		}
+		if err := s.Ask(ctx, line); err != nil {
+			s.printf("\nerror: %v\n", err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	s.printf("resume this conversation with: kbgo chat --conv %s\n", s.settings.ConvID)
+	return scanner.Err()
+}
+
+// Ask 以流式方式提问，附带已添加的文件，成功后清空文件列表并保存会话设置
+func (s *Session) Ask(ctx context.Context, question string) error {
+	req := s.chatReq(question)
+	var stream *client.ChatStream
+	var err error
+	if len(s.files) > 0 {
+		attachments, closeAll, openErr := openAttachments(s.files)
+		if openErr != nil {
+			return openErr
+		}
+		defer closeAll()
+		stream, err = s.client.ChatStreamWithFiles(ctx, req, attachments)
+	} else {
+		stream, err = s.client.ChatStream(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err = s.render(stream); err != nil {
+		return err
+	}
+	s.files = nil
+	return s.save()
+}
+
+// chatReq 按当前设置构建对话请求
+func (s *Session) chatReq(question string) *v1.ChatReq {
+	return &v1.ChatReq{
+		ConvID:           s.settings.ConvID,
+		Question:         question,
+		ModelID:          s.settings.ModelID,
+		EmbeddingModelID: s.settings.EmbeddingModelID,
+		RerankModelID:    s.settings.RerankModelID,
+		KnowledgeId:      s.settings.KnowledgeID,
+		EnableRetriever:  s.settings.KnowledgeID != "",
+		RetrievalView:    s.settings.RetrievalView,
+		PersonaID:        s.settings.PersonaID,
+		UseMCP:           s.settings.UseMCP,
+		Stream:           true,
+	}
+}
+
+// render 输出流式事件：回答内容逐段输出，进度和参考资料等单独成行
+func (s *Session) render(stream *client.ChatStream) error {
+	var references, followUp []string
+	inReasoning := false
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch chunk.Event {
+		case client.EventData:
+			if inReasoning {
+				s.printf("\n\n")
+				inReasoning = false
+			}
+			s.printf("%s", chunk.Content)
+		case client.EventReasoning:
+			if !s.settings.ShowReasoning {
+				continue
+			}
+			if !inReasoning {
+				s.printf("[reasoning] ")
+				inReasoning = true
+			}
+			s.printf("%s", chunk.Reasoning)
+		case client.EventParseProgress:
+			if p := chunk.Parse; p != nil {
+				s.printf("[parse] %s %s\n", p.FileName, p.Stage)
+			}
+		case client.EventToolProgress:
+			if p := chunk.Progress; p != nil {
+				s.printf("[tool] %s/%s %s %d/%d\n", p.ServiceName, p.ToolName, p.Stage, p.Done, p.Total)
+			}
+		case client.EventAgentSummary:
+			if a := chunk.Agent; a != nil {
+				s.printf("[agent] %d tool call(s), %d iteration(s), %d ms\n", len(a.Tools), a.Iterations, a.DurationMs)
+			}
+		case client.EventDocuments:
+			references = references[:0]
+			for _, doc := range chunk.Documents {
+				references = append(references, referenceLabel(doc.ID, doc.MetaData))
+			}
+		case client.EventFollowUp:
+			followUp = chunk.FollowUp
+		}
+	}
+	s.printf("\n")
+	if len(references) > 0 {
+		s.printf("references:\n")
+		for i, ref := range references {
+			s.printf("  [%d] %s\n", i+1, ref)
+		}
+	}
+	if len(followUp) > 0 {
+		s.printf("follow-up:\n")
+		for _, q := range followUp {
+			s.printf("  - %s\n", q)
+		}
+	}
+	return nil
+}
+
+// referenceLabel 参考资料的显示名称：优先使用文档名，否则使用分片ID
+func referenceLabel(id string, metadata map[string]interface{}) string {
+	for _, key := range []string{"document_name", "file_name", "source"} {
+		if name, ok := metadata[key].(string); ok && name != "" {
+			return fmt.Sprintf("%s (%s)", name, id)
+		}
+	}
+	return id
+}
+
+// openAttachments 打开待附带的文件，返回关闭所有文件的函数
+func openAttachments(paths []string) ([]client.Attachment, func(), error) {
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	attachments := make([]client.Attachment, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		attachments = append(attachments, client.Attachment{Filename: filepath.Base(path), Reader: f})
+	}
+	return attachments, closeAll, nil
+}
+
+// save 保存会话设置，未指定状态文件时不保存
+func (s *Session) save() error {
+	if s.stateFile == "" {
+		return nil
+	}
+	return SaveSettings(s.stateFile, s.settings)
+}
+
+func (s *Session) printf(format string, args ...any) {
+	fmt.Fprintf(s.out, format, args...)
+}
@@ -0,0 +1,113 @@
+package chatcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/client"
+)
+
+// newServer 模拟对话接口，记录收到的请求
+func newServer(t *testing.T) (*httptest.Server, *[]map[string]string) {
+	var requests []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/model/list":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"code":0,"message":"OK","data":{"models":[{"model_id":"m1","name":"qwen"}],"count":1}}`)
+		case "/api/v1/chat":
+			fields := map[string]string{}
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+				r.ParseMultipartForm(1 << 20)
+				for key, values := range r.MultipartForm.Value {
+					fields[key] = values[0]
+				}
+				for _, header := range r.MultipartForm.File["files"] {
+					fields["file"] = header.Filename
+				}
+			} else {
+				var body map[string]any
+				json.NewDecoder(r.Body).Decode(&body)
+				for key, value := range body {
+					if s, ok := value.(string); ok {
+						fields[key] = s
+					}
+				}
+			}
+			requests = append(requests, fields)
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "documents:{\"id\":\"a\",\"document\":[{\"id\":\"c1\",\"content\":\"c\",\"metadata\":{\"document_name\":\"policy.pdf\"}}]}\n\n")
+			io.WriteString(w, "data:{\"id\":\"a\",\"content\":\"answer to \"}\n\n")
+			io.WriteString(w, "data:{\"id\":\"a\",\"content\":\""+fields["question"]+"\"}\n\n")
+			io.WriteString(w, "follow_up:{\"id\":\"a\",\"follow_up\":[\"next?\"]}\n\n")
+			io.WriteString(w, "data:[DONE]\n\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestSessionRun(t *testing.T) {
+	server, requests := newServer(t)
+	dir := t.TempDir()
+	attachment := filepath.Join(dir, "notes.txt")
+	os.WriteFile(attachment, []byte("notes"), 0o600)
+	stateFile := filepath.Join(dir, "chat.json")
+
+	var out bytes.Buffer
+	session := NewSession(client.New(server.URL), &Settings{ConvID: "c1"}, &out, stateFile)
+	input := strings.Join([]string{
+		"/model",
+		"/model m1",
+		"/kb kb1",
+		"hello",
+		"/attach " + attachment,
+		"summarize",
+		"/unknown",
+		"/quit",
+		"never sent",
+	}, "\n")
+	if err := session.Run(context.Background(), strings.NewReader(input)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := out.String()
+	for _, want := range []string{"m1  qwen", "answer to hello", "policy.pdf (c1)", "next?", "unknown command /unknown", "kbgo chat --conv c1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 chat requests, got %d", len(*requests))
+	}
+	first, second := (*requests)[0], (*requests)[1]
+	if first["conv_id"] != "c1" || first["model_id"] != "m1" || first["knowledge_id"] != "kb1" || first["file"] != "" {
+		t.Errorf("unexpected first request: %v", first)
+	}
+	if second["question"] != "summarize" || second["file"] != "notes.txt" || second["stream"] != "true" {
+		t.Errorf("unexpected second request: %v", second)
+	}
+
+	saved, err := LoadSettings(stateFile)
+	if err != nil || saved == nil || saved.ConvID != "c1" || saved.ModelID != "m1" || saved.KnowledgeID != "kb1" {
+		t.Errorf("unexpected saved settings: %+v, %v", saved, err)
+	}
+}
+
+func TestLoadSettingsMissing(t *testing.T) {
+	settings, err := LoadSettings(filepath.Join(t.TempDir(), "missing.json"))
+	if settings != nil || err != nil {
+		t.Errorf("expected no settings, got %+v, %v", settings, err)
+	}
+}
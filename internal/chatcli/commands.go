@@ -0,0 +1,214 @@
+package chatcli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+)
+
+const helpText = `commands:
+  /model [id]        show or set the LLM model, lists LLM models without id
+  /persona [id|off]  show or set the persona, lists personas without id
+  /kb [id|off]       show or set the knowledge base used for retrieval, lists knowledge bases without id
+  /view [name|off]   show or set the retrieval view
+  /embedding [id]    set the embedding model used for retrieval
+  /rerank [id]       set the rerank model used for retrieval
+  /mcp [on|off]      show or toggle MCP tool calling (agent mode)
+  /reasoning on|off  show or hide reasoning content
+  /attach <path>     attach a file to the next question
+  /files             list attached files, /files clear removes them
+  /conv [id]         show the conversation id or switch to another conversation
+  /new               start a new conversation with the same settings
+  /settings          show current settings
+  /quit              exit`
+
+// Command 执行一条以 / 开头的命令，quit 为 true 表示退出
+func (s *Session) Command(ctx context.Context, line string) (quit bool, err error) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, "/"), " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(name) {
+	case "quit", "exit", "q":
+		return true, nil
+	case "help", "h", "?":
+		s.printf("%s\n", helpText)
+	case "model":
+		if arg == "" {
+			return false, s.listModels(ctx, "llm")
+		}
+		s.settings.ModelID = arg
+		s.printf("model: %s\n", arg)
+	case "embedding":
+		if arg == "" {
+			return false, s.listModels(ctx, "embedding")
+		}
+		s.settings.EmbeddingModelID = arg
+		s.printf("embedding model: %s\n", arg)
+	case "rerank":
+		if arg == "" {
+			return false, s.listModels(ctx, "reranker")
+		}
+		s.settings.RerankModelID = arg
+		s.printf("rerank model: %s\n", arg)
+	case "persona":
+		if arg == "" {
+			return false, s.listPersonas(ctx)
+		}
+		s.settings.PersonaID = unsetIfOff(arg)
+		s.printf("persona: %s\n", orNone(s.settings.PersonaID))
+	case "kb":
+		if arg == "" {
+			return false, s.listKnowledgeBases(ctx)
+		}
+		s.settings.KnowledgeID = unsetIfOff(arg)
+		s.printf("knowledge base: %s\n", orNone(s.settings.KnowledgeID))
+	case "view":
+		if arg != "" {
+			s.settings.RetrievalView = unsetIfOff(arg)
+		}
+		s.printf("retrieval view: %s\n", orNone(s.settings.RetrievalView))
+	case "mcp":
+		if arg != "" {
+			if s.settings.UseMCP, err = parseSwitch(arg); err != nil {
+				return false, err
+			}
+		}
+		s.printf("mcp: %s\n", onOff(s.settings.UseMCP))
+	case "reasoning":
+		if arg != "" {
+			if s.settings.ShowReasoning, err = parseSwitch(arg); err != nil {
+				return false, err
+			}
+		}
+		s.printf("reasoning: %s\n", onOff(s.settings.ShowReasoning))
+	case "attach":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /attach <path>")
+		}
+		info, statErr := os.Stat(arg)
+		if statErr != nil {
+			return false, statErr
+		}
+		if info.IsDir() {
+			return false, fmt.Errorf("%s is a directory", arg)
+		}
+		s.files = append(s.files, arg)
+		s.printf("attached %s (%d file(s) for the next question)\n", arg, len(s.files))
+	case "files":
+		if arg == "clear" {
+			s.files = nil
+		}
+		if len(s.files) == 0 {
+			s.printf("no attached files\n")
+		}
+		for _, f := range s.files {
+			s.printf("  %s\n", f)
+		}
+	case "conv":
+		if arg != "" {
+			s.settings.ConvID = arg
+			s.files = nil
+		}
+		s.printf("conversation: %s\n", s.settings.ConvID)
+	case "new":
+		s.settings.ConvID = NewConvID()
+		s.files = nil
+		s.printf("new conversation: %s\n", s.settings.ConvID)
+	case "settings":
+		s.printSettings()
+	default:
+		return false, fmt.Errorf("unknown command /%s, type /help for commands", name)
+	}
+	return false, nil
+}
+
+func (s *Session) printSettings() {
+	st := s.settings
+	s.printf("conversation:   %s\n", st.ConvID)
+	s.printf("model:          %s\n", orNone(st.ModelID))
+	s.printf("persona:        %s\n", orNone(st.PersonaID))
+	s.printf("knowledge base: %s\n", orNone(st.KnowledgeID))
+	s.printf("retrieval view: %s\n", orNone(st.RetrievalView))
+	s.printf("embedding:      %s\n", orNone(st.EmbeddingModelID))
+	s.printf("rerank:         %s\n", orNone(st.RerankModelID))
+	s.printf("mcp:            %s\n", onOff(st.UseMCP))
+	s.printf("reasoning:      %s\n", onOff(st.ShowReasoning))
+}
+
+func (s *Session) listModels(ctx context.Context, modelType string) error {
+	res, err := s.client.ListModels(ctx, &v1.ListModelsReq{ModelType: modelType})
+	if err != nil {
+		return err
+	}
+	if len(res.Models) == 0 {
+		s.printf("no %s models registered\n", modelType)
+	}
+	for _, m := range res.Models {
+		s.printf("  %s  %s\n", m.ModelID, m.Name)
+	}
+	return nil
+}
+
+func (s *Session) listPersonas(ctx context.Context) error {
+	res, err := s.client.PersonaList(ctx, &v1.PersonaListReq{})
+	if err != nil {
+		return err
+	}
+	if len(res.Personas) == 0 {
+		s.printf("no personas\n")
+	}
+	for _, p := range res.Personas {
+		s.printf("  %s  %s\n", p.PersonaID, p.Name)
+	}
+	return nil
+}
+
+func (s *Session) listKnowledgeBases(ctx context.Context) error {
+	res, err := s.client.KBGetList(ctx, &v1.KBGetListReq{})
+	if err != nil {
+		return err
+	}
+	if len(res.List) == 0 {
+		s.printf("no knowledge bases\n")
+	}
+	for _, kb := range res.List {
+		s.printf("  %s  %s\n", kb.Id, kb.Name)
+	}
+	return nil
+}
+
+// parseSwitch 解析 on/off 参数
+func parseSwitch(arg string) (bool, error) {
+	switch strings.ToLower(arg) {
+	case "on", "true", "1", "yes":
+		return true, nil
+	case "off", "false", "0", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, got %q", arg)
+}
+
+// unsetIfOff off/none 表示清除设置
+func unsetIfOff(arg string) string {
+	switch strings.ToLower(arg) {
+	case "off", "none":
+		return ""
+	}
+	return arg
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/chatcli"
+	"github.com/Malowking/kbgo/pkg/client"
+	"github.com/gogf/gf/v2/os/gcmd"
+)
+
+// Chat 交互式终端对话客户端，连接运行中的实例，用于快速冒烟测试和未部署前端的环境
+var Chat = gcmd.Command{
+	Name:  "chat",
+	Usage: "kbgo chat [OPTION]",
+	Brief: "interactive terminal chat client for a running instance",
+	Description: `Questions are streamed from /v1/chat; type /help in the session for commands (model, persona, knowledge base,
+retrieval view, MCP tools, file attachments, conversations). Conversation history is kept by the server under the
+conversation id; the last session's settings are saved locally so --resume continues where you left off.
+With --question the question is asked once and the command exits, which is useful in scripts.`,
+	Arguments: []gcmd.Argument{
+		{Name: "url", Short: "u", Brief: "base url of the running instance (default http://localhost:8000)"},
+		{Name: "conv", Short: "c", Brief: "conversation id to continue; a new conversation is started when empty"},
+		{Name: "model", Short: "m", Brief: "LLM model id"},
+		{Name: "persona", Short: "p", Brief: "persona id"},
+		{Name: "knowledge", Short: "k", Brief: "knowledge base id (enables retrieval)"},
+		{Name: "view", Brief: "retrieval view name"},
+		{Name: "embedding", Short: "e", Brief: "embedding model id for retrieval"},
+		{Name: "rerank", Brief: "rerank model id for retrieval"},
+		{Name: "file", Short: "f", Brief: "files to attach to the first question, comma separated"},
+		{Name: "question", Short: "q", Brief: "ask a single question and exit"},
+		{Name: "state", Brief: "local state file (default <user config dir>/kbgo/chat.json)"},
+		{Name: "header", Short: "H", Brief: "extra request headers, comma separated \"Key: Value\" pairs"},
+		{Name: "timeout", Brief: "per request timeout (default 10m)"},
+		{Name: "mcp", Brief: "enable MCP tool calling", Orphan: true},
+		{Name: "reasoning", Brief: "show reasoning content", Orphan: true},
+		{Name: "resume", Short: "r", Brief: "continue the last conversation and its settings", Orphan: true},
+	},
+	Func: func(ctx context.Context, parser *gcmd.Parser) error {
+		timeout, err := time.ParseDuration(parser.GetOpt("timeout", "10m").String())
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		stateFile := parser.GetOpt("state", chatcli.DefaultStateFile()).String()
+
+		settings := new(chatcli.Settings)
+		if parser.GetOpt("resume") != nil {
+			saved, err := chatcli.LoadSettings(stateFile)
+			if err != nil {
+				return err
+			}
+			if saved != nil {
+				settings = saved
+			}
+		}
+		// 命令行参数覆盖保存的设置
+		for name, target := range map[string]*string{
+			"conv":      &settings.ConvID,
+			"model":     &settings.ModelID,
+			"persona":   &settings.PersonaID,
+			"knowledge": &settings.KnowledgeID,
+			"view":      &settings.RetrievalView,
+			"embedding": &settings.EmbeddingModelID,
+			"rerank":    &settings.RerankModelID,
+		} {
+			if value := parser.GetOpt(name, "").String(); value != "" {
+				*target = value
+			}
+		}
+		if parser.GetOpt("mcp") != nil {
+			settings.UseMCP = true
+		}
+		if parser.GetOpt("reasoning") != nil {
+			settings.ShowReasoning = true
+		}
+
+		opts := []client.Option{client.WithTimeout(timeout)}
+		for _, header := range strings.Split(parser.GetOpt("header", "").String(), ",") {
+			if key, value, ok := strings.Cut(header, ":"); ok {
+				opts = append(opts, client.WithHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+			}
+		}
+		c := client.New(parser.GetOpt("url", "http://localhost:8000").String(), opts...)
+		session := chatcli.NewSession(c, settings, os.Stdout, stateFile)
+
+		for _, path := range strings.Split(parser.GetOpt("file", "").String(), ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				if _, err = session.Command(ctx, "/attach "+path); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Ctrl+C 中断当前回答并退出
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if question := parser.GetOpt("question", "").String(); question != "" {
+			return session.Ask(ctx, question)
+		}
+		return session.Run(ctx, os.Stdin)
+	},
+}
+
+func init() {
+	if err := Main.AddCommand(&Chat); err != nil {
+		panic(err)
+	}
+}
@@ -3,7 +3,11 @@ package cmd
 import (
 	"context"
 
+	"github.com/Malowking/kbgo/core/config"
 	"github.com/Malowking/kbgo/internal/controller/kbgo"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/completion"
+	"github.com/Malowking/kbgo/internal/rpc"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
 	"github.com/gogf/gf/v2/os/gcmd"
@@ -15,19 +19,33 @@ var (
 		Usage: "main",
 		Brief: "start http server",
 		Func: func(ctx context.Context, parser *gcmd.Parser) (err error) {
+			initAll()
+
 			s := g.Server()
+			configureOpenApi(s)
 
 			// 配置静态文件服务
-			s.SetServerRoot(".")
-			s.AddStaticPath("/", ".")
+			s.SetServerRoot(config.ServerRoot)
+			s.AddStaticPath("/", config.ServerRoot)
 
+			controller := kbgo.NewV1()
 			s.Group("/api", func(group *ghttp.RouterGroup) {
-				group.Middleware(MiddlewareHandlerResponse, ghttp.MiddlewareCORS)
+				group.Middleware(apiMiddlewares...)
 				group.Bind(
-					kbgo.NewV1(),
+					controller,
 				)
 			})
+
+			// 内部服务调用的 gRPC 接口，与 HTTP 接口共用控制器
+			stopRPC, err := rpc.Start(ctx, controller, completion.Stream)
+			if err != nil {
+				return err
+			}
 			s.Run()
+
+			// 先停止 gRPC 服务，等待进行中的流式对话保存消息；之后队列中尚未保存的消息写入溢出日志，下次启动时回放
+			stopRPC()
+			history.GetGlobalAsyncSaver().Shutdown()
 			return nil
 		},
 	}
@@ -6,16 +6,26 @@ import (
 	"github.com/Malowking/kbgo/core/config"
 	"github.com/Malowking/kbgo/core/file_store"
 	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/tokenizer"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
 	"github.com/Malowking/kbgo/internal/logic/chat"
 	"github.com/Malowking/kbgo/internal/logic/index"
+	"github.com/Malowking/kbgo/internal/logic/modelhealth"
+	"github.com/Malowking/kbgo/internal/logic/piiscrub"
+	"github.com/Malowking/kbgo/internal/logic/reembed"
 	"github.com/Malowking/kbgo/internal/logic/retriever"
+	"github.com/Malowking/kbgo/internal/logic/usage"
+	"github.com/Malowking/kbgo/internal/logic/warmup"
+	"github.com/Malowking/kbgo/internal/mcp"
 	"github.com/Malowking/kbgo/internal/service"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
-// InitAll initializes all components of the application
-func init() {
+// initAll initializes all components of the application.
+// It runs when the http server starts, so client-side commands (e.g. loadtest) do not need the database or vector store
+func initAll() {
 	ctx := context.Background()
 
 	// Validate configuration before initializing components
@@ -49,6 +59,9 @@ func init() {
 	// Initialize chat history manager
 	chat.InitHistory()
 
+	// Initialize async message saver and replay messages spilled before the last shutdown (messageSaver)
+	history.InitAsyncSaver(ctx)
+
 	// Initialize model registry from database
 	g.Log().Info(ctx, "Initializing model registry...")
 	err = model.Registry.Reload(ctx, dao.GetDB())
@@ -59,5 +72,32 @@ func init() {
 		g.Log().Infof(ctx, "✓ Model registry initialized successfully with %d models", model.Registry.Count())
 	}
 
+	// Load tokenizer encodings for additional model families (tokenizer.families)
+	tokenizer.LoadConfig(ctx)
+
+	// Periodically check local (ollama) models and warm them up when they become available
+	modelhealth.InitModelHealth()
+
+	// Load local tool plugins (localTools.plugins)
+	mcp.LoadPlugins(ctx)
+
+	// Periodically verify cached MCP tool schemas against live servers
+	mcp.InitToolVerifier()
+
+	// Initialize analytics rollup scheduler
+	analytics.InitAnalytics()
+
+	// Aggregate per-user token usage used by /v1/usage and user quotas
+	usage.InitUsage()
+
+	// Schedule conversation PII scrubbing before long-term retention
+	piiscrub.InitPIIScrub()
+
+	// Resume unfinished re-embedding jobs (requires model registry)
+	reembed.InitReembed()
+
+	// Load frequently used collections, prime model connections and connect busy MCP services (requires model registry and vector store)
+	warmup.InitWarmUp()
+
 	g.Log().Info(ctx, "✓ All components initialized successfully")
 }
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/loadtest"
+	"github.com/Malowking/kbgo/pkg/client"
+	"github.com/gogf/gf/v2/os/gcmd"
+)
+
+// Loadtest 对运行中的实例回放录制的或合成的对话/检索流量，输出各接口延迟分位数、流式首字延迟和错误率
+var Loadtest = gcmd.Command{
+	Name:  "loadtest",
+	Usage: "kbgo loadtest [OPTION]",
+	Brief: "replay recorded or synthetic chat/retrieval traffic against a running instance",
+	Description: `Traffic is sent open-loop at the target QPS; requests that would exceed the concurrency limit are dropped and counted.
+Recorded traffic is a JSONL file, one request per line: {"endpoint":"chat|chat_stream|retriever","request":{...}},
+where request is the same body as the /v1/chat or /v1/retriever API. Chat requests without conv_id use a new "loadtest-" conversation.`,
+	Arguments: []gcmd.Argument{
+		{Name: "url", Short: "u", Brief: "base url of the running instance (default http://localhost:8000)"},
+		{Name: "qps", Short: "q", Brief: "target requests per second (default 5)"},
+		{Name: "duration", Short: "d", Brief: "how long to send requests, e.g. 30s, 5m (default 1m)"},
+		{Name: "concurrency", Short: "c", Brief: "max in-flight requests (default 50)"},
+		{Name: "requests", Short: "n", Brief: "max requests to send, 0 means unlimited (default 0)"},
+		{Name: "input", Short: "i", Brief: "recorded traffic file (JSONL); synthetic traffic is generated when empty"},
+		{Name: "mix", Brief: "synthetic traffic weights (default chat_stream=1)"},
+		{Name: "questions", Brief: "file with one question per line for synthetic traffic"},
+		{Name: "knowledge", Short: "k", Brief: "knowledge base id for synthetic traffic (enables retrieval)"},
+		{Name: "model", Short: "m", Brief: "LLM model id for synthetic chat traffic"},
+		{Name: "embedding", Short: "e", Brief: "embedding model id for synthetic traffic"},
+		{Name: "rerank", Brief: "rerank model id for synthetic traffic"},
+		{Name: "topk", Brief: "top_k for synthetic traffic (default 5)"},
+		{Name: "header", Short: "H", Brief: "extra request headers, comma separated \"Key: Value\" pairs"},
+		{Name: "timeout", Brief: "per request timeout (default 2m)"},
+		{Name: "json", Brief: "print the report as JSON", Orphan: true},
+	},
+	Func: func(ctx context.Context, parser *gcmd.Parser) error {
+		qps := parser.GetOpt("qps", 5).Float64()
+		duration, err := time.ParseDuration(parser.GetOpt("duration", "1m").String())
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		timeout, err := time.ParseDuration(parser.GetOpt("timeout", "2m").String())
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+
+		source, err := loadtestSource(parser)
+		if err != nil {
+			return err
+		}
+
+		opts := []client.Option{client.WithTimeout(timeout)}
+		for _, header := range strings.Split(parser.GetOpt("header", "").String(), ",") {
+			if key, value, ok := strings.Cut(header, ":"); ok {
+				opts = append(opts, client.WithHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+			}
+		}
+		c := client.New(parser.GetOpt("url", "http://localhost:8000").String(), opts...)
+
+		// Ctrl+C 停止发送新请求，仍然输出已完成请求的报告
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Fprintf(os.Stderr, "loadtest: %.2f qps for %s against %s\n", qps, duration, parser.GetOpt("url", "http://localhost:8000").String())
+		report := loadtest.Run(ctx, c, source, loadtest.Options{
+			QPS:         qps,
+			Duration:    duration,
+			Concurrency: parser.GetOpt("concurrency", 50).Int(),
+			MaxRequests: parser.GetOpt("requests", 0).Int(),
+		})
+
+		if parser.GetOpt("json") != nil {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+		return report.Write(os.Stdout)
+	},
+}
+
+func init() {
+	if err := Main.AddCommand(&Loadtest); err != nil {
+		panic(err)
+	}
+}
+
+// loadtestSource 读取录制的流量文件，未指定时按参数生成合成流量
+func loadtestSource(parser *gcmd.Parser) (loadtest.Source, error) {
+	if input := parser.GetOpt("input", "").String(); input != "" {
+		return loadtest.LoadRecorded(input)
+	}
+	mix, err := loadtest.ParseMix(parser.GetOpt("mix", loadtest.EndpointChatStream).String())
+	if err != nil {
+		return nil, err
+	}
+	var questions []string
+	if path := parser.GetOpt("questions", "").String(); path != "" {
+		if questions, err = loadtest.LoadQuestions(path); err != nil {
+			return nil, err
+		}
+	}
+	return loadtest.NewSynthetic(loadtest.SyntheticOptions{
+		Questions:        questions,
+		Mix:              mix,
+		KnowledgeID:      parser.GetOpt("knowledge", "").String(),
+		ModelID:          parser.GetOpt("model", "").String(),
+		EmbeddingModelID: parser.GetOpt("embedding", "").String(),
+		RerankModelID:    parser.GetOpt("rerank", "").String(),
+		TopK:             parser.GetOpt("topk", 5).Int(),
+		Seed:             time.Now().UnixNano(),
+	})
+}
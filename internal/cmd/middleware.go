@@ -4,9 +4,13 @@ import (
 	"mime"
 	"net/http"
 	"reflect"
+	"time"
 
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/security"
 	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
 	"github.com/gogf/gf/v2/util/gmeta"
 )
@@ -15,6 +19,8 @@ const (
 	contentTypeEventStream  = "text/event-stream"
 	contentTypeOctetStream  = "application/octet-stream"
 	contentTypeMixedReplace = "multipart/x-mixed-replace"
+
+	defaultClearanceHeader = "X-Security-Clearance"
 )
 
 var (
@@ -31,6 +37,11 @@ func MiddlewareHandlerResponse(r *ghttp.Request) {
 		return
 	}
 
+	// Conditional requests answered with 304 must not have a body.
+	if r.Response.Status == http.StatusNotModified {
+		return
+	}
+
 	// It does not output common response content if it is stream response.
 	mediaType, _, _ := mime.ParseMediaType(r.Response.Header().Get("Content-Type"))
 	for _, ct := range streamContentType {
@@ -79,6 +90,47 @@ func MiddlewareHandlerResponse(r *ghttp.Request) {
 	})
 }
 
+// apiMiddlewares /api 路由组的中间件链：统一响应格式、跨域、识别调用用户、读取安全权限
+var apiMiddlewares = []ghttp.HandlerFunc{MiddlewareHandlerResponse, ghttp.MiddlewareCORS, MiddlewareIdentity, MiddlewareClearance}
+
+// MiddlewareClearance 确定调用方的安全权限并写入上下文，供检索时过滤分片安全标签
+// 权限来自 security.userClearances 中认证用户的配置；仅在开启 security.trustClearanceHeader（请求经由完成认证的网关转发）时
+// 读取权限请求头，客户端直接传入的请求头不被信任；都没有时使用 security.defaultClearances
+func MiddlewareClearance(r *ghttp.Request) {
+	ctx := r.Context()
+	var clearances []string
+	if security.TrustClearanceHeader(ctx) {
+		header := g.Cfg().MustGet(ctx, "security.clearanceHeader", defaultClearanceHeader).String()
+		clearances = security.ParseClearances(r.Header.Get(header))
+	}
+	if len(clearances) == 0 {
+		clearances = security.UserClearances(ctx)
+	}
+	if len(clearances) > 0 {
+		r.SetCtx(security.WithClearances(ctx, clearances))
+	}
+	r.Middleware.Next()
+}
+
+// MiddlewareIdentity 从 JWT、API Key 或可信用户请求头识别调用用户并写入上下文（配置见 auth 段）
+// 携带的凭证无效，或配置要求认证而请求未携带凭证时返回 401；配置了凭证时按用户隔离会话等资源
+func MiddlewareIdentity(r *ghttp.Request) {
+	cfg := identity.LoadConfig(r.Context())
+	userID, err := cfg.Authenticate(r.Header.Get, time.Now())
+	if err != nil {
+		r.Response.WriteHeader(http.StatusUnauthorized)
+		r.SetError(gerror.NewCode(gcode.CodeNotAuthorized, err.Error()))
+		return
+	}
+	if cfg.Isolated() {
+		r.SetCtx(identity.WithIsolation(r.Context()))
+	}
+	if userID != "" {
+		r.SetCtx(identity.WithUser(r.Context(), userID))
+	}
+	r.Middleware.Next()
+}
+
 // 中间件中判断
 func noWrapResp(r *ghttp.Request) bool {
 	handler := r.GetServeHandler().Handler
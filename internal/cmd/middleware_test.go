@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/security"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+type whoAmIReq struct {
+	g.Meta `path:"/whoami" method:"get"`
+}
+
+type whoAmIRes struct {
+	UserID string `json:"user_id"`
+}
+
+type whoAmIController struct{}
+
+func (whoAmIController) WhoAmI(ctx context.Context, req *whoAmIReq) (*whoAmIRes, error) {
+	return &whoAmIRes{UserID: identity.UserID(ctx)}, nil
+}
+
+type visibleChunksReq struct {
+	g.Meta `path:"/chunks" method:"get"`
+}
+
+type visibleChunksRes struct {
+	Labels []string `json:"labels"`
+}
+
+type visibleChunksController struct{}
+
+// VisibleChunks 返回按调用方权限过滤后可见分片的安全标签
+func (visibleChunksController) VisibleChunks(ctx context.Context, req *visibleChunksReq) (*visibleChunksRes, error) {
+	var docs []*schema.Document
+	for _, label := range []string{security.LabelPublic, security.LabelInternal, security.LabelConfidential} {
+		docs = append(docs, &schema.Document{ID: label, MetaData: map[string]any{security.MetadataKey: label}})
+	}
+	res := &visibleChunksRes{}
+	for _, doc := range security.FilterDocuments(ctx, docs) {
+		res.Labels = append(res.Labels, doc.ID)
+	}
+	return res, nil
+}
+
+// TestAPIMiddlewaresClearance 测试 /api 中间件链确定调用方权限：客户端直接传入的权限请求头不被信任，
+// 权限来自认证用户的配置，只有开启 trustClearanceHeader 时才读取请求头
+func TestAPIMiddlewaresClearance(t *testing.T) {
+	const base = "auth:\n  apiKeys:\n    key-1: alice\nsecurity:\n  enabled: true\n  userClearances:\n    alice: [\"internal\"]\n"
+	tests := []struct {
+		name       string
+		config     string
+		apiKey     string
+		wantLabels string
+	}{
+		{name: "Unauthenticated header ignored", config: base, wantLabels: "public"},
+		{name: "Authenticated header ignored", config: base, apiKey: "key-1", wantLabels: "public,internal"},
+		{name: "Trusted gateway header", config: base + "  trustClearanceHeader: true\n", wantLabels: "public,internal,confidential"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := gcfg.NewAdapterContent(tt.config)
+			if err != nil {
+				t.Fatalf("NewAdapterContent() error = %v", err)
+			}
+			original := g.Cfg().GetAdapter()
+			g.Cfg().SetAdapter(adapter)
+			defer g.Cfg().SetAdapter(original)
+
+			s := g.Server(fmt.Sprintf("clearance-test-%d", i))
+			s.SetAddr("127.0.0.1:0")
+			s.SetDumpRouterMap(false)
+			s.Group("/api", func(group *ghttp.RouterGroup) {
+				group.Middleware(apiMiddlewares...)
+				group.Bind(visibleChunksController{})
+			})
+			if err = s.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer s.Shutdown()
+
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/api/chunks", s.GetListenedPort()), nil)
+			req.Header.Set(defaultClearanceHeader, "confidential")
+			if tt.apiKey != "" {
+				req.Header.Set(identity.HeaderAPIKey, tt.apiKey)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			var res ghttp.DefaultHandlerResponse
+			var data visibleChunksRes
+			res.Data = &data
+			if err = g.NewVar(body).Scan(&res); err != nil {
+				t.Fatalf("decode error = %v, body = %s", err, body)
+			}
+			if got := strings.Join(data.Labels, ","); got != tt.wantLabels {
+				t.Errorf("visible labels = %q, want %q, body = %s", got, tt.wantLabels, body)
+			}
+		})
+	}
+}
+
+// TestAPIMiddlewaresBearer 测试 /api 中间件链对 Bearer 凭证的处理：默认配置下忽略 OpenAI SDK 发送的任意 api_key，
+// 配置了 API Key 时按 Key 识别用户，未知的 Key 返回 401
+func TestAPIMiddlewaresBearer(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		bearer     string
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "Default config ignores SDK key", config: "auth:\n  required: false\n", bearer: "sk-anything", wantStatus: http.StatusOK, wantUser: identity.DefaultUserID},
+		{name: "Configured key", config: "auth:\n  apiKeys:\n    key-1: alice\n", bearer: "key-1", wantStatus: http.StatusOK, wantUser: "alice"},
+		{name: "Unknown key with keys configured", config: "auth:\n  apiKeys:\n    key-1: alice\n", bearer: "sk-anything", wantStatus: http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := gcfg.NewAdapterContent(tt.config)
+			if err != nil {
+				t.Fatalf("NewAdapterContent() error = %v", err)
+			}
+			original := g.Cfg().GetAdapter()
+			g.Cfg().SetAdapter(adapter)
+			defer g.Cfg().SetAdapter(original)
+
+			s := g.Server(fmt.Sprintf("middleware-test-%d", i))
+			s.SetAddr("127.0.0.1:0")
+			s.SetDumpRouterMap(false)
+			s.Group("/api", func(group *ghttp.RouterGroup) {
+				group.Middleware(apiMiddlewares...)
+				group.Bind(whoAmIController{})
+			})
+			if err = s.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer s.Shutdown()
+
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/api/whoami", s.GetListenedPort()), nil)
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantUser != "" {
+				var res ghttp.DefaultHandlerResponse
+				var data whoAmIRes
+				res.Data = &data
+				if err = g.NewVar(body).Scan(&res); err != nil || data.UserID != tt.wantUser {
+					t.Errorf("user = %q (err %v), want %q, body = %s", data.UserID, err, tt.wantUser, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+const (
+	// openapiVersion 接口文档版本，接口有不兼容变更时同步修改
+	openapiVersion = "v1"
+
+	// swaggerUIAssetPath 内置 Swagger UI 静态资源的访问路径
+	swaggerUIAssetPath = "/swagger-ui"
+
+	// swaggerUITemplate Swagger UI 页面，{SwaggerUIDocUrl} 由 GoFrame 替换为 server.openapiPath
+	swaggerUITemplate = `<!DOCTYPE html>
+<html>
+<head>
+	<title>KBGO API</title>
+	<meta charset="utf-8"/>
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<link rel="stylesheet" href="` + swaggerUIAssetPath + `/swagger-ui.css"/>
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="` + swaggerUIAssetPath + `/swagger-ui-bundle.js"></script>
+	<script>
+		window.onload = () => {
+			window.ui = SwaggerUIBundle({url: "{SwaggerUIDocUrl}", dom_id: "#swagger-ui", deepLinking: true});
+		};
+	</script>
+</body>
+</html>`
+)
+
+// swaggerUIFiles Swagger UI 静态资源（swagger-ui-dist，见 swaggerui/README.md），打包进二进制以免依赖外部 CDN
+//
+//go:embed swaggerui/swagger-ui.css swaggerui/swagger-ui-bundle.js
+var swaggerUIFiles embed.FS
+
+// configureOpenApi 配置接口文档
+// 文档由 GoFrame 在启动时根据 api/ 中请求结构体的 g.Meta 和字段标签生成，与路由始终一致；
+// 流式接口的 SSE 事件通过 g.Meta 中的 x-sse-events 扩展字段说明
+func configureOpenApi(s *ghttp.Server) {
+	oai := s.GetOpenApi()
+	oai.Info.Title = "KBGO API"
+	oai.Info.Description = "知识库管理与 RAG 对话接口。除 SSE 流式接口外，响应均为 {code, message, data} 结构，code 为 0 表示成功。"
+	oai.Info.Version = openapiVersion
+	oai.Config.CommonResponse = ghttp.DefaultHandlerResponse{}
+	oai.Config.CommonResponseDataField = "Data"
+	s.SetSwaggerUITemplate(swaggerUITemplate)
+	s.BindHandler(swaggerUIAssetPath+"/*any", swaggerUIAssets())
+}
+
+// swaggerUIAssets 提供内置的 Swagger UI 静态资源
+func swaggerUIAssets() ghttp.HandlerFunc {
+	assets, _ := fs.Sub(swaggerUIFiles, "swaggerui")
+	fileServer := http.StripPrefix(swaggerUIAssetPath, http.FileServer(http.FS(assets)))
+	return func(r *ghttp.Request) {
+		r.Response.Header().Set("Cache-Control", "public, max-age=86400")
+		fileServer.ServeHTTP(r.Response.BufferWriter, r.Request)
+	}
+}
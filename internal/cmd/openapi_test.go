@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+func TestSwaggerUIAssets(t *testing.T) {
+	if strings.Contains(swaggerUITemplate, "://") {
+		t.Errorf("swagger UI template should only reference local assets")
+	}
+
+	s := g.Server(fmt.Sprintf("swagger-ui-test-%d", time.Now().UnixNano()))
+	s.SetAddr("127.0.0.1:0")
+	s.SetDumpRouterMap(false)
+	s.BindHandler(swaggerUIAssetPath+"/*any", swaggerUIAssets())
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	for _, name := range []string{"swagger-ui.css", "swagger-ui-bundle.js"} {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s/%s", s.GetListenedPort(), swaggerUIAssetPath, name))
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(body) == 0 {
+			t.Errorf("%s: status = %d, length = %d", name, resp.StatusCode, len(body))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package kbgo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// AnalyticsUsage 查询按天、按模型的会话使用统计
+func (c *ControllerV1) AnalyticsUsage(ctx context.Context, req *v1.AnalyticsUsageReq) (res *v1.AnalyticsUsageRes, err error) {
+	g.Log().Infof(ctx, "AnalyticsUsage request received - StartDate: %s, EndDate: %s, ModelName: %s", req.StartDate, req.EndDate, req.ModelName)
+
+	list, err := analytics.GetUsage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AnalyticsUsageRes{List: list}, nil
+}
+
+// AnalyticsTools 查询工具使用分布
+func (c *ControllerV1) AnalyticsTools(ctx context.Context, req *v1.AnalyticsToolsReq) (res *v1.AnalyticsToolsRes, err error) {
+	g.Log().Infof(ctx, "AnalyticsTools request received - StartDate: %s, EndDate: %s", req.StartDate, req.EndDate)
+
+	list, err := analytics.GetToolUsage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AnalyticsToolsRes{List: list}, nil
+}
+
+// AnalyticsUnanswered 查询未解答（低分检索）问题排行
+func (c *ControllerV1) AnalyticsUnanswered(ctx context.Context, req *v1.AnalyticsUnansweredReq) (res *v1.AnalyticsUnansweredRes, err error) {
+	g.Log().Infof(ctx, "AnalyticsUnanswered request received - StartDate: %s, EndDate: %s, KnowledgeId: %s, Limit: %d",
+		req.StartDate, req.EndDate, req.KnowledgeId, req.Limit)
+
+	list, err := analytics.GetUnansweredQuestions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AnalyticsUnansweredRes{List: list}, nil
+}
+
+// AnalyticsRollup 手动重建指定日期的汇总数据
+func (c *ControllerV1) AnalyticsRollup(ctx context.Context, req *v1.AnalyticsRollupReq) (res *v1.AnalyticsRollupRes, err error) {
+	g.Log().Infof(ctx, "AnalyticsRollup request received - Date: %s", req.Date)
+
+	day, err := time.ParseInLocation(analytics.DateLayout, req.Date, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid date: %s", req.Date)
+	}
+	if err = analytics.RunRollup(ctx, day); err != nil {
+		return nil, err
+	}
+	return &v1.AnalyticsRollupRes{Success: true}, nil
+}
+
+// KnowledgeGapRun 手动触发知识缺口挖掘任务
+func (c *ControllerV1) KnowledgeGapRun(ctx context.Context, req *v1.KnowledgeGapRunReq) (res *v1.KnowledgeGapRunRes, err error) {
+	g.Log().Infof(ctx, "KnowledgeGapRun request received - WindowDays: %d", req.WindowDays)
+
+	batchID, count, err := analytics.RunKnowledgeGapJob(ctx, req.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.KnowledgeGapRunRes{BatchId: batchID, GapCount: count}, nil
+}
+
+// KnowledgeGapList 查询知识缺口报告
+func (c *ControllerV1) KnowledgeGapList(ctx context.Context, req *v1.KnowledgeGapListReq) (res *v1.KnowledgeGapListRes, err error) {
+	g.Log().Infof(ctx, "KnowledgeGapList request received - BatchId: %s, KnowledgeId: %s", req.BatchId, req.KnowledgeId)
+
+	batchID, list, err := analytics.GetKnowledgeGaps(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.KnowledgeGapListRes{BatchId: batchID, List: list}, nil
+}
+
+// FinetuneExport 导出嵌入模型微调数据（JSONL 文件下载）
+func (c *ControllerV1) FinetuneExport(ctx context.Context, req *v1.FinetuneExportReq) (res *v1.FinetuneExportRes, err error) {
+	g.Log().Infof(ctx, "FinetuneExport request received - StartDate: %s, EndDate: %s, KnowledgeId: %s, Format: %s",
+		req.StartDate, req.EndDate, req.KnowledgeId, req.Format)
+
+	start, err := time.ParseInLocation(analytics.DateLayout, req.StartDate, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid start_date: %s", req.StartDate)
+	}
+	end, err := time.ParseInLocation(analytics.DateLayout, req.EndDate, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid end_date: %s", req.EndDate)
+	}
+
+	// 先写入缓冲区，导出失败时仍可返回统一的错误响应
+	var buf bytes.Buffer
+	lines, err := analytics.ExportFinetuneData(ctx, &analytics.FinetuneExportOptions{
+		Start:        start,
+		End:          end.AddDate(0, 0, 1),
+		KnowledgeID:  req.KnowledgeId,
+		Format:       req.Format,
+		MaxNegatives: req.MaxNegatives,
+	}, &buf)
+	if err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "FinetuneExport generated %d lines", lines)
+
+	r := g.RequestFromCtx(ctx)
+	r.Response.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	r.Response.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="finetune-%s-%s-%s.jsonl"`, req.Format, req.StartDate, req.EndDate))
+	r.Response.Write(buf.Bytes())
+	return nil, nil
+}
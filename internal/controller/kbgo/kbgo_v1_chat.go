@@ -7,6 +7,20 @@ import (
 	"github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/chat"
 	"github.com/Malowking/kbgo/core/common"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/answerdiff"
+	"github.com/Malowking/kbgo/internal/logic/budget"
+	"github.com/Malowking/kbgo/internal/logic/chatcompletions"
+	"github.com/Malowking/kbgo/internal/logic/conversation"
+	"github.com/Malowking/kbgo/internal/logic/experiment"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/participant"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/promotion"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/Malowking/kbgo/internal/logic/retrievalview"
+	"github.com/Malowking/kbgo/internal/logic/usage"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -15,6 +29,91 @@ func (c *ControllerV1) Chat(ctx context.Context, req *v1.ChatReq) (res *v1.ChatR
 	g.Log().Infof(ctx, "Chat request received - ConvID: %s, Question: %s, ModelID: %s, EmbeddingModelID: %s, RerankModelID: %s, KnowledgeId: %s, EnableRetriever: %v, TopK: %d, Score: %f, UseMCP: %v, Stream: %v",
 		req.ConvID, req.Question, req.ModelID, req.EmbeddingModelID, req.RerankModelID, req.KnowledgeId, req.EnableRetriever, req.TopK, req.Score, req.UseMCP, req.Stream)
 
+	// 延迟预算从收到请求开始计时
+	ctx = budget.WithContext(ctx, budget.New(ctx, req.LatencyBudgetMs))
+	// 记录模型调用改用备用模型的情况，随回答返回
+	ctx = coreModel.WithFallbackRecorder(ctx)
+
+	// 已认证的请求以认证用户为提问用户；共享会话只有可发送消息的参与者可以提问，保存的用户消息记录发送者
+	req.UserID = identity.Resolve(ctx, req.UserID)
+	if err = participant.CanWrite(ctx, req.ConvID, req.UserID); err != nil {
+		return nil, err
+	}
+	ctx = history.WithAuthor(ctx, req.UserID)
+
+	// 会话已转人工或用户要求人工服务时，由人工客服接管，不调用模型
+	handoffHandler := chat.NewHandoffHandler()
+	handoffRes, err := handoffHandler.Intercept(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if handoffRes != nil {
+		if req.Stream {
+			return nil, handoffHandler.StreamNotice(ctx, handoffRes)
+		}
+		return handoffRes, nil
+	}
+
+	// 检索视图：请求未指定的知识库、模型和检索参数使用视图设置，优先于项目默认设置
+	if err = retrievalview.ApplyToChat(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// 项目默认设置：请求未指定的模型、知识库和检索参数使用项目默认值
+	ctx, err = project.ApplyDefaults(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if projectID := project.FromContext(ctx); projectID != "" {
+		g.Log().Infof(ctx, "Applied project defaults - ProjectID: %s, ModelID: %s, KnowledgeId: %s", projectID, req.ModelID, req.KnowledgeId)
+	}
+
+	// 知识库权限：预置回答、更正捕获和检索都会读写请求的知识库，属于项目的知识库只有项目成员可以使用
+	if err = project.CheckKnowledgeAccess(ctx, req.KnowledgeId); err != nil {
+		return nil, err
+	}
+
+	// 项目配额：月度 token 预算和每日对话次数
+	if err = quota.CheckChat(ctx); err != nil {
+		return nil, err
+	}
+	// 用户配额：提问用户本月消耗的 token（共享会话中各参与者的用量分别计入本人）
+	if err = usage.CheckChat(ctx, req.ConvID, req.UserID); err != nil {
+		return nil, err
+	}
+	// OpenAI 兼容接口新建的会话：校验通过后再导入客户端发送的此前消息
+	if err = chatcompletions.ImportHistory(ctx, req.ConvID); err != nil {
+		return nil, err
+	}
+
+	// 用户更正上一条回答时，把更正提交为待审核的知识库条目，捕获失败不影响对话
+	var correctionID string
+	if req.CaptureCorrections {
+		correction, captureErr := promotion.CaptureCorrection(ctx, req.ConvID, req.KnowledgeId, req.Question)
+		if captureErr != nil {
+			g.Log().Warningf(ctx, "Failed to capture chat correction - ConvID: %s, err: %v", req.ConvID, captureErr)
+		} else if correction != nil {
+			correctionID = correction.ID
+		}
+	}
+
+	// 重新生成回答：记录原回答，新回答保存后计算两者的差异
+	if req.RegenerateMsgID != "" {
+		if ctx, err = answerdiff.WithRegenerate(ctx, req.ConvID, req.RegenerateMsgID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 确定本轮使用的模型：未指定时沿用会话模型，指定了不同模型时切换会话模型
+	req.ModelID, err = conversation.ResolveModel(ctx, req.ConvID, req.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	answerdiff.SetModel(ctx, req.ModelID)
+
+	// A/B 实验：按会话分组覆盖模型、提示词和检索参数（不修改会话保存的模型）
+	ctx = experiment.Assign(ctx, req)
+
 	// 手动获取上传的文件（GoFrame 的 type:"file" 标签可能无法从独立 FormData 字段正确解析）
 	r := g.RequestFromCtx(ctx)
 	uploadFiles := r.GetUploadFiles("files")
@@ -31,6 +130,18 @@ func (c *ControllerV1) Chat(ctx context.Context, req *v1.ChatReq) (res *v1.ChatR
 		}
 	}
 
+	// 问题与知识库已审核问答几乎相同时直接返回预置回答（上传了文件的问题需要结合文件回答，不走预置回答）
+	if len(fileHeaders) == 0 {
+		cannedHandler := chat.NewCannedHandler()
+		if cannedRes := cannedHandler.Intercept(ctx, req); cannedRes != nil {
+			cannedRes.CorrectionID = correctionID
+			if req.Stream {
+				return nil, cannedHandler.StreamAnswer(ctx, cannedRes)
+			}
+			return cannedRes, nil
+		}
+	}
+
 	// 异步处理文件上传
 	var uploadedFiles []*common.MultimodalFile
 	if len(fileHeaders) > 0 {
@@ -58,21 +169,25 @@ func (c *ControllerV1) Chat(ctx context.Context, req *v1.ChatReq) (res *v1.ChatR
 
 	// 如果启用流式返回，执行流式逻辑
 	if req.Stream {
-		return nil, c.handleStreamChat(ctx, req, uploadedFiles)
+		return nil, c.handleStreamChat(ctx, req, uploadedFiles, correctionID)
 	}
 
 	// 使用新的聊天处理器
 	chatHandler := chat.NewChatHandler()
-	return chatHandler.Chat(ctx, req, uploadedFiles)
+	res, err = chatHandler.Chat(ctx, req, uploadedFiles)
+	if res != nil {
+		res.CorrectionID = correctionID
+	}
+	return res, err
 }
 
-// handleStreamChat 处理流式聊天请求
-func (c *ControllerV1) handleStreamChat(ctx context.Context, req *v1.ChatReq, uploadedFiles []*common.MultimodalFile) error {
+// handleStreamChat 处理流式聊天请求，correctionID 为本轮捕获的更正申请ID
+func (c *ControllerV1) handleStreamChat(ctx context.Context, req *v1.ChatReq, uploadedFiles []*common.MultimodalFile, correctionID string) error {
 	// Log request parameters
 	g.Log().Infof(ctx, "Stream chat request received - ConvID: %s, Question: %s, ModelID: %s, EmbeddingModelID: %s, RerankModelID: %s, KnowledgeId: %s, EnableRetriever: %v, TopK: %d, Score: %f, UseMCP: %v, Files: %d",
 		req.ConvID, req.Question, req.ModelID, req.EmbeddingModelID, req.RerankModelID, req.KnowledgeId, req.EnableRetriever, req.TopK, req.Score, req.UseMCP, len(req.Files))
 
 	// 使用新的流式聊天处理器
 	streamHandler := chat.NewStreamHandler()
-	return streamHandler.StreamChat(ctx, req, uploadedFiles)
+	return streamHandler.StreamChat(ctx, req, uploadedFiles, correctionID)
 }
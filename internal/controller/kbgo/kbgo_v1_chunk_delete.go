@@ -7,6 +7,7 @@ import (
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/internal/dao"
 	"github.com/Malowking/kbgo/internal/logic/index"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
 	"github.com/Malowking/kbgo/internal/logic/knowledge"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
@@ -73,6 +74,18 @@ func (c *ControllerV1) ChunkDelete(ctx context.Context, req *v1.ChunkDeleteReq)
 		err = gerror.Newf("failed to commit transaction: %v", err)
 		return
 	}
+	publishChunkChange(ctx, chunk.KnowledgeDocId)
 
 	return &v1.ChunkDeleteRes{}, nil
 }
+
+// publishChunkChange 分片被删除或修改后发布所属知识库的变更事件，查不到文档时按未知知识库处理
+func publishChunkChange(ctx context.Context, documentID string) {
+	event := &kbevent.Event{Type: kbevent.TypeUpdated, DocumentID: documentID}
+	if documentID != "" {
+		if document, err := knowledge.GetDocumentById(ctx, documentID); err == nil {
+			event.KnowledgeID = document.KnowledgeId
+		}
+	}
+	kbevent.Publish(ctx, event)
+}
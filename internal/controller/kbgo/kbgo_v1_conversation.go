@@ -0,0 +1,202 @@
+package kbgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/file_export"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/answerdiff"
+	"github.com/Malowking/kbgo/internal/logic/conversation"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/participant"
+	"github.com/Malowking/kbgo/internal/logic/workspace"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ConversationDelete 删除会话及其消息，并清理会话工作区
+func (c *ControllerV1) ConversationDelete(ctx context.Context, req *v1.ConversationDeleteReq) (res *v1.ConversationDeleteRes, err error) {
+	g.Log().Infof(ctx, "ConversationDelete request received - ConvID: %s", req.ConvID)
+
+	if err = participant.CanManage(ctx, req.ConvID); err != nil {
+		return nil, err
+	}
+	if err = dao.Conversation.DeleteWithMessages(ctx, req.ConvID); err != nil {
+		return nil, gerror.Wrap(err, "failed to delete conversation")
+	}
+
+	// 工作区清理失败不影响会话删除结果
+	if err = workspace.Clear(ctx, req.ConvID); err != nil {
+		g.Log().Errorf(ctx, "清理会话工作区失败: %v", err)
+	}
+	return &v1.ConversationDeleteRes{}, nil
+}
+
+// ConversationModelUpdate 切换会话使用的模型
+func (c *ControllerV1) ConversationModelUpdate(ctx context.Context, req *v1.ConversationModelUpdateReq) (res *v1.ConversationModelUpdateRes, err error) {
+	g.Log().Infof(ctx, "ConversationModelUpdate request received - ConvID: %s, ModelID: %s", req.ConvID, req.ModelID)
+
+	if err = participant.CanWrite(ctx, req.ConvID, identity.UserID(ctx)); err != nil {
+		return nil, err
+	}
+	conv, err := conversation.SwitchModel(ctx, req.ConvID, req.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ConversationModelUpdateRes{
+		ConvID:    conv.ConvID,
+		ModelID:   conv.ModelID,
+		ModelName: conv.ModelName,
+	}, nil
+}
+
+// ConversationExport 把会话导出为 PDF 或 DOCX 报告
+func (c *ControllerV1) ConversationExport(ctx context.Context, req *v1.ConversationExportReq) (res *v1.ConversationExportRes, err error) {
+	g.Log().Infof(ctx, "ConversationExport request received - ConvID: %s, Format: %s, Citations: %v, Images: %v, Tools: %v",
+		req.ConvID, req.Format, req.IncludeCitations, req.IncludeImages, req.IncludeTools)
+
+	if err = participant.CanRead(ctx, req.ConvID, req.UserID); err != nil {
+		return nil, err
+	}
+
+	doc, err := conversation.Export(ctx, req.ConvID, conversation.ExportOptions{
+		Citations: req.IncludeCitations,
+		Images:    req.IncludeImages,
+		Tools:     req.IncludeTools,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 先写入缓冲区，导出失败时仍可返回统一的错误响应
+	var buf bytes.Buffer
+	if err = file_export.Write(&buf, req.Format, doc); err != nil {
+		return nil, gerror.Wrap(err, "failed to export conversation")
+	}
+
+	r := g.RequestFromCtx(ctx)
+	r.Response.Header().Set("Content-Type", file_export.ContentType(req.Format))
+	r.Response.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.%s"`, req.ConvID, req.Format))
+	r.Response.Write(buf.Bytes())
+	return nil, nil
+}
+
+// MessageFeedback 记录用户对助手消息的反馈和点击的参考分片
+func (c *ControllerV1) MessageFeedback(ctx context.Context, req *v1.MessageFeedbackReq) (res *v1.MessageFeedbackRes, err error) {
+	g.Log().Infof(ctx, "MessageFeedback request received - MsgID: %s, Feedback: %s, Clicked: %d", req.MsgID, req.Feedback, len(req.ClickedChunkIDs))
+
+	if req.Feedback == "" && len(req.ClickedChunkIDs) == 0 {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, "feedback or clicked_chunk_ids is required")
+	}
+	if err = participant.CanReadMessage(ctx, req.MsgID); err != nil {
+		return nil, err
+	}
+	feedback, clicked, err := analytics.RecordFeedback(ctx, req.MsgID, req.Feedback, req.ClickedChunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.MessageFeedbackRes{MsgID: req.MsgID, Feedback: feedback, ClickedChunkIDs: clicked}, nil
+}
+
+// MessageDiff 查询助手消息作为原回答或重新生成的回答参与的差异
+func (c *ControllerV1) MessageDiff(ctx context.Context, req *v1.MessageDiffReq) (res *v1.MessageDiffRes, err error) {
+	if err = participant.CanReadMessage(ctx, req.MsgID); err != nil {
+		return nil, err
+	}
+	list, err := answerdiff.ListByMessage(ctx, req.MsgID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.MessageDiffRes{List: list}, nil
+}
+
+// AnswerDiffList 分页查询回答差异，按用户隔离时必须指定可读取的会话
+func (c *ControllerV1) AnswerDiffList(ctx context.Context, req *v1.AnswerDiffListReq) (res *v1.AnswerDiffListRes, err error) {
+	if req.ConvID != "" {
+		if err = participant.CanRead(ctx, req.ConvID, identity.UserID(ctx)); err != nil {
+			return nil, err
+		}
+	} else if identity.Isolated(ctx) {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, "conv_id is required")
+	}
+	diffs, total, err := dao.AnswerDiff.List(ctx, req.ConvID, req.Source, req.ShadowResultID, req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	res = &v1.AnswerDiffListRes{List: make([]*v1.AnswerDiffItem, 0, len(diffs)), Total: total, Page: req.Page}
+	for _, diff := range diffs {
+		res.List = append(res.List, answerdiff.ToItem(diff))
+	}
+	return res, nil
+}
+
+// MessageSaverStats 查询异步消息保存器的队列深度和累计计数
+func (c *ControllerV1) MessageSaverStats(ctx context.Context, req *v1.MessageSaverStatsReq) (res *v1.MessageSaverStatsRes, err error) {
+	stats := history.GetGlobalAsyncSaver().Stats()
+	return &v1.MessageSaverStatsRes{
+		QueueSize:     stats.QueueSize,
+		QueueCapacity: stats.QueueCapacity,
+		Workers:       stats.Workers,
+		Saved:         stats.Saved,
+		Failed:        stats.Failed,
+		Spilled:       stats.Spilled,
+		Replayed:      stats.Replayed,
+		Dropped:       stats.Dropped,
+		DeadLettered:  stats.DeadLettered,
+		SpoolEnabled:  stats.SpoolEnabled,
+		SpoolPending:  stats.SpoolPending,
+	}, nil
+}
+
+// WorkspaceList 列出会话工作区文件
+func (c *ControllerV1) WorkspaceList(ctx context.Context, req *v1.WorkspaceListReq) (res *v1.WorkspaceListRes, err error) {
+	g.Log().Infof(ctx, "WorkspaceList request received - ConvID: %s", req.ConvID)
+
+	if err = participant.CanRead(ctx, req.ConvID, identity.UserID(ctx)); err != nil {
+		return nil, err
+	}
+	files, totalSize, err := workspace.ListFiles(ctx, req.ConvID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := workspace.GetQuota(ctx)
+	res = &v1.WorkspaceListRes{
+		List:         make([]*v1.WorkspaceFileItem, 0, len(files)),
+		TotalSize:    totalSize,
+		MaxTotalSize: quota.MaxTotalSize,
+		MaxFiles:     quota.MaxFiles,
+	}
+	for _, f := range files {
+		res.List = append(res.List, &v1.WorkspaceFileItem{
+			Name:       f.Name,
+			Size:       f.Size,
+			UpdateTime: f.ModTime.Format(time.DateTime),
+		})
+	}
+	return res, nil
+}
+
+// WorkspaceFileDelete 删除会话工作区中的文件
+func (c *ControllerV1) WorkspaceFileDelete(ctx context.Context, req *v1.WorkspaceFileDeleteReq) (res *v1.WorkspaceFileDeleteRes, err error) {
+	g.Log().Infof(ctx, "WorkspaceFileDelete request received - ConvID: %s, Name: %s", req.ConvID, req.Name)
+
+	if err = participant.CanWrite(ctx, req.ConvID, identity.UserID(ctx)); err != nil {
+		return nil, err
+	}
+	if err = workspace.DeleteFile(ctx, req.ConvID, req.Name); err != nil {
+		if errors.Is(err, workspace.ErrFileNotFound) {
+			return nil, gerror.Newf("workspace file not found: %s", req.Name)
+		}
+		return nil, err
+	}
+	return &v1.WorkspaceFileDeleteRes{}, nil
+}
@@ -0,0 +1,82 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/logic/participant"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/bytedance/sonic"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ConversationParticipantList 获取会话参与者
+func (c *ControllerV1) ConversationParticipantList(ctx context.Context, req *v1.ConversationParticipantListReq) (res *v1.ConversationParticipantListRes, err error) {
+	g.Log().Infof(ctx, "ConversationParticipantList request received - ConvID: %s", req.ConvID)
+
+	participants, err := participant.List(ctx, req.ConvID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list conversation participants")
+	}
+	res = &v1.ConversationParticipantListRes{Participants: make([]*v1.ConversationParticipant, 0, len(participants))}
+	for _, p := range participants {
+		res.Participants = append(res.Participants, toConversationParticipant(p))
+	}
+	return res, nil
+}
+
+// ConversationParticipantSave 添加会话参与者或修改参与者角色
+func (c *ControllerV1) ConversationParticipantSave(ctx context.Context, req *v1.ConversationParticipantSaveReq) (res *v1.ConversationParticipantSaveRes, err error) {
+	g.Log().Infof(ctx, "ConversationParticipantSave request received - ConvID: %s, UserID: %s, Role: %s", req.ConvID, req.UserID, req.Role)
+
+	p, err := participant.Save(ctx, req.ConvID, req.UserID, req.Role)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to save conversation participant")
+	}
+	return &v1.ConversationParticipantSaveRes{Participant: toConversationParticipant(p)}, nil
+}
+
+// ConversationParticipantRemove 移除会话参与者
+func (c *ControllerV1) ConversationParticipantRemove(ctx context.Context, req *v1.ConversationParticipantRemoveReq) (res *v1.ConversationParticipantRemoveRes, err error) {
+	g.Log().Infof(ctx, "ConversationParticipantRemove request received - ConvID: %s, UserID: %s", req.ConvID, req.UserID)
+
+	if err = participant.Remove(ctx, req.ConvID, req.UserID); err != nil {
+		return nil, gerror.Wrap(err, "failed to remove conversation participant")
+	}
+	return &v1.ConversationParticipantRemoveRes{}, nil
+}
+
+// ConversationEvents 以SSE推送会话中新保存的消息，直到客户端断开
+func (c *ControllerV1) ConversationEvents(ctx context.Context, req *v1.ConversationEventsReq) (res *v1.ConversationEventsRes, err error) {
+	g.Log().Infof(ctx, "ConversationEvents request received - ConvID: %s, UserID: %s", req.ConvID, req.UserID)
+
+	if err = participant.CanRead(ctx, req.ConvID, req.UserID); err != nil {
+		return nil, err
+	}
+	events, cancel := participant.Subscribe(req.ConvID)
+	defer cancel()
+
+	httpResp := g.RequestFromCtx(ctx).Response
+	defer common.StartSSE(ctx, httpResp)()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case event := <-events:
+			marshal, _ := sonic.Marshal(event)
+			common.WriteSSEEvent(httpResp, "message", string(marshal))
+		}
+	}
+}
+
+func toConversationParticipant(p *gormModel.ConversationParticipant) *v1.ConversationParticipant {
+	item := &v1.ConversationParticipant{UserID: p.UserID, Role: p.Role}
+	if p.CreateTime != nil {
+		item.CreatedAt = p.CreateTime.Format(time.RFC3339)
+	}
+	return item
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/Malowking/kbgo/core/file_store"
 	"github.com/Malowking/kbgo/internal/dao"
 	"github.com/Malowking/kbgo/internal/logic/index"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
 	"github.com/Malowking/kbgo/internal/logic/knowledge"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/gogf/gf/v2/errors/gerror"
@@ -107,6 +108,7 @@ func (c *ControllerV1) DocumentsDelete(ctx context.Context, req *v1.DocumentsDel
 		g.Log().Errorf(ctx, "DocumentsDelete: transaction commit failed, err: %v", err)
 		return nil, gerror.Newf("failed to commit transaction: %v", err)
 	}
+	kbevent.Publish(ctx, &kbevent.Event{Type: kbevent.TypeDeleted, KnowledgeID: document.KnowledgeId, DocumentID: req.DocumentId})
 
 	// 事务成功提交后，删除存储中的文件（这个操作失败不影响数据一致性）
 	if needDeleteFromRustFS && rustfsBucket != "" && rustfsLocation != "" {
@@ -6,6 +6,7 @@ import (
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/internal/logic/knowledge"
 	"github.com/Malowking/kbgo/internal/model/entity"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -30,3 +31,25 @@ func (c *ControllerV1) DocumentsList(ctx context.Context, req *v1.DocumentsListR
 
 	return
 }
+
+// DocumentsUpdateValidity 更新文档有效期，检索时实时生效
+func (c *ControllerV1) DocumentsUpdateValidity(ctx context.Context, req *v1.DocumentsUpdateValidityReq) (res *v1.DocumentsUpdateValidityRes, err error) {
+	g.Log().Infof(ctx, "DocumentsUpdateValidity request received - DocumentId: %s, ValidFrom: %v, ValidUntil: %v",
+		req.DocumentId, req.ValidFrom, req.ValidUntil)
+
+	if err = knowledge.UpdateDocumentValidity(ctx, req.DocumentId, req.ValidFrom, req.ValidUntil); err != nil {
+		return nil, gerror.Wrap(err, "failed to update document validity")
+	}
+	return &v1.DocumentsUpdateValidityRes{}, nil
+}
+
+// DocumentsVersions 获取文档的版本链
+func (c *ControllerV1) DocumentsVersions(ctx context.Context, req *v1.DocumentsVersionsReq) (res *v1.DocumentsVersionsRes, err error) {
+	g.Log().Infof(ctx, "DocumentsVersions request received - DocumentId: %s", req.DocumentId)
+
+	versions, err := knowledge.GetDocumentVersions(ctx, req.DocumentId)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get document versions")
+	}
+	return &v1.DocumentsVersionsRes{Data: versions}, nil
+}
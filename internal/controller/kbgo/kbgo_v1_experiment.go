@@ -0,0 +1,79 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/experiment"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ExperimentList 查询配置的 A/B 实验
+func (c *ControllerV1) ExperimentList(ctx context.Context, req *v1.ExperimentListReq) (res *v1.ExperimentListRes, err error) {
+	experiments := experiment.Load(ctx)
+	res = &v1.ExperimentListRes{List: make([]*v1.ExperimentItem, 0, len(experiments))}
+	for _, exp := range experiments {
+		item := &v1.ExperimentItem{Name: exp.Name, Enabled: exp.Enabled}
+		total := exp.TotalWeight()
+		for _, v := range exp.Variants {
+			if v == nil || v.Name == "" || v.Weight <= 0 {
+				continue
+			}
+			item.Variants = append(item.Variants, &v1.ExperimentVariantItem{
+				Name:            v.Name,
+				Weight:          v.Weight,
+				TrafficRatio:    float64(v.Weight) / float64(total),
+				ModelID:         v.ModelID,
+				PromptVersion:   v.PromptVersion,
+				TopK:            v.TopK,
+				Score:           v.Score,
+				RetrieveMode:    v.RetrieveMode,
+				CostPer1kTokens: v.CostPer1kTokens,
+			})
+		}
+		res.List = append(res.List, item)
+	}
+	return res, nil
+}
+
+// ExperimentMetrics 查询实验各分组的延迟、反馈和成本对比
+func (c *ControllerV1) ExperimentMetrics(ctx context.Context, req *v1.ExperimentMetricsReq) (res *v1.ExperimentMetricsRes, err error) {
+	g.Log().Infof(ctx, "ExperimentMetrics request received - Name: %s, StartDate: %s, EndDate: %s", req.Name, req.StartDate, req.EndDate)
+
+	exp := experiment.Get(ctx, req.Name)
+	if exp == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "experiment not found: %s", req.Name)
+	}
+	start, err := time.ParseInLocation(analytics.DateLayout, req.StartDate, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid start_date: %s", req.StartDate)
+	}
+	end, err := time.ParseInLocation(analytics.DateLayout, req.EndDate, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid end_date: %s", req.EndDate)
+	}
+
+	metrics, err := experiment.Metrics(ctx, exp, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	res = &v1.ExperimentMetricsRes{Name: exp.Name, List: make([]*v1.ExperimentMetricsItem, 0, len(metrics))}
+	for _, m := range metrics {
+		res.List = append(res.List, &v1.ExperimentMetricsItem{
+			Variant:           m.Variant,
+			ConversationCount: m.ConversationCount,
+			AssistantCount:    m.AssistantCount,
+			AvgLatencyMs:      m.AvgLatencyMs,
+			TotalTokens:       m.TotalTokens,
+			PositiveFeedback:  m.PositiveFeedback,
+			NegativeFeedback:  m.NegativeFeedback,
+			SatisfactionRatio: m.SatisfactionRatio,
+			EstimatedCost:     m.EstimatedCost,
+		})
+	}
+	return res, nil
+}
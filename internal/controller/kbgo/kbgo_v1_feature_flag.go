@@ -0,0 +1,63 @@
+package kbgo
+
+import (
+	"context"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/featureflag"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// FeatureFlagList 获取功能开关在全局或某个项目内的生效设置
+func (c *ControllerV1) FeatureFlagList(ctx context.Context, req *v1.FeatureFlagListReq) (res *v1.FeatureFlagListRes, err error) {
+	if req.ProjectID != "" {
+		if _, err = project.Get(ctx, req.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	res = &v1.FeatureFlagListRes{Flags: make([]*v1.FeatureFlagItem, 0, len(featureflag.Definitions()))}
+	for _, def := range featureflag.Definitions() {
+		res.Flags = append(res.Flags, toFeatureFlagItem(ctx, def, req.ProjectID))
+	}
+	return res, nil
+}
+
+// FeatureFlagSet 开启或关闭功能开关
+func (c *ControllerV1) FeatureFlagSet(ctx context.Context, req *v1.FeatureFlagSetReq) (res *v1.FeatureFlagSetRes, err error) {
+	rollout := 100
+	if req.Rollout != nil {
+		rollout = *req.Rollout
+	}
+	g.Log().Infof(ctx, "FeatureFlagSet request received - Name: %s, ProjectID: %s, Enabled: %v, Rollout: %d",
+		req.Name, req.ProjectID, req.Enabled, rollout)
+
+	if err = featureflag.Set(ctx, req.Name, req.ProjectID, req.Enabled, rollout); err != nil {
+		return nil, gerror.Wrap(err, "failed to set feature flag")
+	}
+	return &v1.FeatureFlagSetRes{Flag: toFeatureFlagItem(ctx, featureflag.Lookup(req.Name), req.ProjectID)}, nil
+}
+
+// FeatureFlagReset 删除功能开关在全局或某个项目的设置
+func (c *ControllerV1) FeatureFlagReset(ctx context.Context, req *v1.FeatureFlagResetReq) (res *v1.FeatureFlagResetRes, err error) {
+	g.Log().Infof(ctx, "FeatureFlagReset request received - Name: %s, ProjectID: %s", req.Name, req.ProjectID)
+
+	if err = featureflag.Reset(ctx, req.Name, req.ProjectID); err != nil {
+		return nil, gerror.Wrap(err, "failed to reset feature flag")
+	}
+	return &v1.FeatureFlagResetRes{Flag: toFeatureFlagItem(ctx, featureflag.Lookup(req.Name), req.ProjectID)}, nil
+}
+
+func toFeatureFlagItem(ctx context.Context, def *featureflag.Definition, projectID string) *v1.FeatureFlagItem {
+	state := featureflag.Resolve(ctx, def, projectID)
+	return &v1.FeatureFlagItem{
+		Name:        def.Name,
+		Description: def.Description,
+		Default:     def.Default,
+		ProjectID:   projectID,
+		Enabled:     state.Enabled,
+		Rollout:     state.Rollout,
+		Source:      state.Source,
+	}
+}
@@ -0,0 +1,97 @@
+package kbgo
+
+import (
+	"context"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/chat"
+	"github.com/Malowking/kbgo/internal/logic/handoff"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/participant"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// HandoffTicketList 获取人工接管工单列表
+func (c *ControllerV1) HandoffTicketList(ctx context.Context, req *v1.HandoffTicketListReq) (res *v1.HandoffTicketListRes, err error) {
+	g.Log().Infof(ctx, "HandoffTicketList request received - ConvID: %s, Status: %s", req.ConvID, req.Status)
+
+	if _, err = authorizeAgent(ctx); err != nil {
+		return nil, err
+	}
+
+	tickets, err := handoff.ListTickets(ctx, req.ConvID, req.Status)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list handoff tickets")
+	}
+	res = &v1.HandoffTicketListRes{Tickets: make([]*v1.HandoffTicketItem, 0, len(tickets))}
+	for _, ticket := range tickets {
+		res.Tickets = append(res.Tickets, chat.ToHandoffTicketItem(ticket))
+	}
+	return res, nil
+}
+
+// HandoffTicketGet 获取人工接管工单详情
+func (c *ControllerV1) HandoffTicketGet(ctx context.Context, req *v1.HandoffTicketGetReq) (res *v1.HandoffTicketGetRes, err error) {
+	g.Log().Infof(ctx, "HandoffTicketGet request received - TicketID: %s", req.TicketID)
+
+	if _, err = authorizeAgent(ctx); err != nil {
+		return nil, err
+	}
+
+	ticket, err := handoff.GetTicket(ctx, req.TicketID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get handoff ticket")
+	}
+	return &v1.HandoffTicketGetRes{Ticket: chat.ToHandoffTicketItem(ticket)}, nil
+}
+
+// HandoffMessage 人工客服向会话发送消息
+func (c *ControllerV1) HandoffMessage(ctx context.Context, req *v1.HandoffMessageReq) (res *v1.HandoffMessageRes, err error) {
+	g.Log().Infof(ctx, "HandoffMessage request received - TicketID: %s, Agent: %s", req.TicketID, req.Agent)
+
+	agent, err := authorizeAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if agent == "" {
+		agent = req.Agent
+	}
+	ticket, err := handoff.PostAgentMessage(ctx, req.TicketID, agent, req.Content)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to post handoff message")
+	}
+	return &v1.HandoffMessageRes{Ticket: chat.ToHandoffTicketItem(ticket)}, nil
+}
+
+// HandoffResolve 结束人工接管，会话恢复由 AI 回答
+func (c *ControllerV1) HandoffResolve(ctx context.Context, req *v1.HandoffResolveReq) (res *v1.HandoffResolveRes, err error) {
+	g.Log().Infof(ctx, "HandoffResolve request received - TicketID: %s", req.TicketID)
+
+	if _, err = authorizeAgent(ctx); err != nil {
+		return nil, err
+	}
+
+	ticket, err := handoff.Resolve(ctx, req.TicketID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to resolve handoff ticket")
+	}
+	return &v1.HandoffResolveRes{Ticket: chat.ToHandoffTicketItem(ticket)}, nil
+}
+
+// HandoffStream 订阅会话的人工接管事件（SSE），人工客服或可读取会话的用户可以订阅
+func (c *ControllerV1) HandoffStream(ctx context.Context, req *v1.HandoffStreamReq) (res *v1.HandoffStreamRes, err error) {
+	g.Log().Infof(ctx, "HandoffStream request received - ConvID: %s", req.ConvID)
+
+	if _, agentErr := authorizeAgent(ctx); agentErr != nil {
+		if err = participant.CanRead(ctx, req.ConvID, identity.UserID(ctx)); err != nil {
+			return nil, err
+		}
+	}
+	return nil, chat.NewHandoffHandler().StreamEvents(ctx, req.ConvID)
+}
+
+// authorizeAgent 人工客服接口使用单独的客服凭证（handoff.agentKeys），返回凭证对应的客服名称
+func authorizeAgent(ctx context.Context) (string, error) {
+	return handoff.AuthorizeAgent(ctx, g.RequestFromCtx(ctx).Header.Get(handoff.HeaderAgentKey))
+}
@@ -0,0 +1,49 @@
+package kbgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/thumbnail"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// defaultImageMaxAge 图片响应的浏览器缓存时间（秒）
+const defaultImageMaxAge = 86400
+
+// ImageGet 返回上传图片或其缩放版本，If-None-Match 命中时返回 304
+func (c *ControllerV1) ImageGet(ctx context.Context, req *v1.ImageGetReq) (res *v1.ImageGetRes, err error) {
+	g.Log().Debugf(ctx, "ImageGet request received - Path: %s, Width: %d, Height: %d, Size: %s, Fit: %s",
+		req.Path, req.Width, req.Height, req.Size, req.Fit)
+
+	variant, err := thumbnail.Resolve(ctx, req.Path, thumbnail.Options{
+		Width:   req.Width,
+		Height:  req.Height,
+		Size:    req.Size,
+		Fit:     req.Fit,
+		Quality: req.Quality,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := g.RequestFromCtx(ctx)
+	maxAge := g.Cfg().MustGet(ctx, "imageService.maxAge", defaultImageMaxAge).Int()
+	r.Response.Header().Set("ETag", variant.ETag)
+	r.Response.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	if thumbnail.MatchETag(r.Header.Get("If-None-Match"), variant.ETag) {
+		r.Response.WriteHeader(http.StatusNotModified)
+		return nil, nil
+	}
+
+	image, err := variant.Load(ctx)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to load image")
+	}
+	r.Response.Header().Set("Content-Type", image.ContentType)
+	r.Response.Write(image.Data)
+	return nil, nil
+}
@@ -3,10 +3,16 @@ package kbgo
 import (
 	"context"
 	"fmt"
+	"time"
 
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/indexer"
 	"github.com/Malowking/kbgo/internal/logic/index"
+	"github.com/Malowking/kbgo/internal/logic/ingest"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -18,6 +24,22 @@ func (c *ControllerV1) IndexDocuments(ctx context.Context, req *v1.IndexDocument
 
 	g.Log().Infof(ctx, "收到批量索引请求，文档数量: %d", len(req.DocumentIds))
 
+	// 文档所在知识库所属项目已达到向量存储配额时拒绝索引
+	checked := make(map[string]bool)
+	for _, documentId := range req.DocumentIds {
+		document, err := knowledge.GetDocumentById(ctx, documentId)
+		if err != nil {
+			return nil, err
+		}
+		if document.KnowledgeId == "" || checked[document.KnowledgeId] {
+			continue
+		}
+		checked[document.KnowledgeId] = true
+		if err = quota.CheckStorage(ctx, document.KnowledgeId); err != nil {
+			return nil, err
+		}
+	}
+
 	// 获取文档索引服务实例
 	docIndexSvr := index.GetDocIndexSvr()
 
@@ -31,8 +53,7 @@ func (c *ControllerV1) IndexDocuments(ctx context.Context, req *v1.IndexDocument
 	}
 
 	// 异步启动批量索引任务
-	go func() {
-		asyncCtx := context.Background()
+	common.SafeGoDetached(ctx, "BatchDocumentIndex", func(asyncCtx context.Context) {
 		g.Log().Infof(asyncCtx, "开始异步批量索引文档，文档数量: %d", len(req.DocumentIds))
 
 		// 使用 BatchDocumentIndex 方法处理批量索引
@@ -43,7 +64,7 @@ func (c *ControllerV1) IndexDocuments(ctx context.Context, req *v1.IndexDocument
 		}
 
 		g.Log().Infof(asyncCtx, "批量索引任务已成功启动")
-	}()
+	})
 
 	// 立即返回响应
 	res = &v1.IndexDocumentsRes{
@@ -51,3 +72,89 @@ func (c *ControllerV1) IndexDocuments(ctx context.Context, req *v1.IndexDocument
 	}
 	return
 }
+
+// ParserHealth 检查已配置的文档解析后端健康状态
+func (c *ControllerV1) ParserHealth(ctx context.Context, req *v1.ParserHealthReq) (res *v1.ParserHealthRes, err error) {
+	g.Log().Infof(ctx, "ParserHealth request received")
+
+	parser, err := indexer.NewDocumentParser(ctx, 0, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res = &v1.ParserHealthRes{}
+	for _, health := range parser.CheckHealth(ctx) {
+		res.Parsers = append(res.Parsers, v1.ParserHealthItem{
+			Name:    health.Name,
+			Healthy: health.Healthy,
+			Error:   health.Error,
+		})
+	}
+	return res, nil
+}
+
+// IngestDeadLetterList 查询重试耗尽后进入死信队列的文档
+func (c *ControllerV1) IngestDeadLetterList(ctx context.Context, req *v1.IngestDeadLetterListReq) (res *v1.IngestDeadLetterListRes, err error) {
+	g.Log().Infof(ctx, "IngestDeadLetterList request received - KnowledgeId: %s, Status: %s, Page: %d, PageSize: %d",
+		req.KnowledgeId, req.Status, req.Page, req.PageSize)
+
+	letters, total, err := ingest.List(ctx, req.KnowledgeId, req.Status, req.Page, req.PageSize)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list ingest dead letters")
+	}
+
+	res = &v1.IngestDeadLetterListRes{List: make([]v1.IngestDeadLetterItem, 0, len(letters)), Total: total}
+	for _, letter := range letters {
+		item := v1.IngestDeadLetterItem{
+			Id:          letter.ID,
+			DocumentId:  letter.DocumentID,
+			KnowledgeId: letter.KnowledgeID,
+			FileName:    letter.FileName,
+			Step:        letter.Step,
+			Error:       letter.Error,
+			Attempts:    letter.Attempts,
+			Failures:    letter.Failures,
+			Status:      letter.Status,
+		}
+		if letter.RequeueTime != nil {
+			item.RequeueTime = letter.RequeueTime.Format(time.RFC3339)
+		}
+		if letter.CreateTime != nil {
+			item.CreateTime = letter.CreateTime.Format(time.RFC3339)
+		}
+		if letter.UpdateTime != nil {
+			item.UpdateTime = letter.UpdateTime.Format(time.RFC3339)
+		}
+		res.List = append(res.List, item)
+	}
+	return res, nil
+}
+
+// IngestDeadLetterRequeue 按死信记录的索引参数重新索引文档 - 异步接口
+func (c *ControllerV1) IngestDeadLetterRequeue(ctx context.Context, req *v1.IngestDeadLetterRequeueReq) (res *v1.IngestDeadLetterRequeueRes, err error) {
+	g.Log().Infof(ctx, "IngestDeadLetterRequeue request received - Id: %s", req.Id)
+
+	letter, err := ingest.Requeue(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	indexReq := &indexer.IndexReq{
+		ModelID:     letter.ModelID,
+		DocumentId:  letter.DocumentID,
+		ChunkSize:   letter.ChunkSize,
+		OverlapSize: letter.OverlapSize,
+		Separator:   letter.Separator,
+	}
+	common.SafeGoDetached(ctx, "IngestDeadLetterRequeue", func(asyncCtx context.Context) {
+		// 再次失败时由索引流程重新写入死信队列
+		if err := index.GetDocIndexSvr().DocumentIndex(asyncCtx, indexReq); err != nil {
+			g.Log().Errorf(asyncCtx, "重新索引死信文档失败, documentId=%s, err=%v", letter.DocumentID, err)
+		}
+	})
+
+	return &v1.IngestDeadLetterRequeueRes{
+		DocumentId: letter.DocumentID,
+		Message:    "已重新提交文档索引任务",
+	}, nil
+}
@@ -6,11 +6,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/file_store"
 	"github.com/Malowking/kbgo/internal/dao"
 	"github.com/Malowking/kbgo/internal/logic/index"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/preprocess"
+	"github.com/Malowking/kbgo/internal/logic/project"
 	"github.com/Malowking/kbgo/internal/model/do"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/gogf/gf/v2/errors/gerror"
@@ -21,22 +26,33 @@ import (
 
 func (c *ControllerV1) KBCreate(ctx context.Context, req *v1.KBCreateReq) (res *v1.KBCreateRes, err error) {
 	// Log request parameters
-	g.Log().Infof(ctx, "KBCreate request received - Name: %s, Description: %s, Category: %s",
-		req.Name, req.Description, req.Category)
+	g.Log().Infof(ctx, "KBCreate request received - Name: %s, Description: %s, Category: %s, EmbeddingModelId: %s, SparseModelId: %s",
+		req.Name, req.Description, req.Category, req.EmbeddingModelId, req.SparseModelId)
 
 	res = &v1.KBCreateRes{}
 
+	// 稀疏模型决定集合结构，创建前先校验
+	if req.SparseModelId != "" {
+		if _, err = knowledge.ValidateSparseModel(req.SparseModelId); err != nil {
+			return nil, gerror.Wrap(err, "invalid sparse model")
+		}
+	}
+
 	// 生成 UUID 作为知识库 ID (使用与项目其他地方相同的格式)
 	knowledgeId := "kb_" + strings.ReplaceAll(uuid.New().String(), "-", "")
 
 	// 使用 GORM 模型确保自动填充 CreateTime 和 UpdateTime
 	kb := &gormModel.KnowledgeBase{
-		ID:             knowledgeId,
-		Name:           req.Name,
-		Description:    req.Description,
-		Category:       req.Category,
-		CollectionName: knowledgeId, // 使用知识库ID作为默认的CollectionName
-		Status:         1,           // 默认启用
+		ID:                  knowledgeId,
+		Name:                req.Name,
+		Description:         req.Description,
+		Category:            req.Category,
+		CollectionName:      knowledgeId, // 使用知识库ID作为默认的CollectionName
+		Status:              1,           // 默认启用
+		SparseModelID:       req.SparseModelId,
+		SparseWeight:        req.SparseWeight,
+		RecencyWeight:       req.RecencyWeight,
+		RecencyHalfLifeDays: req.RecencyHalfLifeDays,
 	}
 
 	err = dao.GetDB().WithContext(ctx).Create(kb).Error
@@ -44,9 +60,18 @@ func (c *ControllerV1) KBCreate(ctx context.Context, req *v1.KBCreateReq) (res *
 		return nil, err
 	}
 
-	// 创建 Milvus collection
+	// 确定向量维度并创建 Milvus collection
+	dim, err := index.ResolveCollectionDim(ctx, req.EmbeddingModelId)
+	if err != nil {
+		dao.GetDB().WithContext(ctx).Delete(&gormModel.KnowledgeBase{}, "id = ?", knowledgeId)
+		return nil, gerror.Wrap(err, "failed to resolve embedding dimension")
+	}
 	docIndexSvr := index.GetDocIndexSvr()
-	err = docIndexSvr.GetVectorStore().CreateCollection(ctx, knowledgeId)
+	if req.SparseModelId != "" {
+		err = docIndexSvr.GetVectorStore().CreateHybridCollection(ctx, knowledgeId, dim)
+	} else {
+		err = docIndexSvr.GetVectorStore().CreateCollectionWithDim(ctx, knowledgeId, dim)
+	}
 	if err != nil {
 		// 如果创建 Milvus collection 失败，删除已创建的数据库记录并返回错误
 		dao.GetDB().WithContext(ctx).Delete(&gormModel.KnowledgeBase{}, "id = ?", knowledgeId)
@@ -161,6 +186,13 @@ func (c *ControllerV1) KBDelete(ctx context.Context, req *v1.KBDeleteReq) (res *
 		return nil, result.Error
 	}
 
+	// 删除知识库的索引钩子配置（含全部历史版本）
+	result = tx.WithContext(ctx).Where("knowledge_id = ?", req.Id).Delete(&gormModel.IngestHookConfig{})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, result.Error
+	}
+
 	// 6. 删除 Milvus collection
 	err = docIndexSvr.GetVectorStore().DeleteCollection(ctx, req.Id)
 	if err != nil {
@@ -172,6 +204,7 @@ func (c *ControllerV1) KBDelete(ctx context.Context, req *v1.KBDeleteReq) (res *
 	if err = tx.Commit().Error; err != nil {
 		return nil, gerror.Newf("failed to commit transaction: %v", err)
 	}
+	kbevent.Publish(ctx, &kbevent.Event{Type: kbevent.TypeDeleted, KnowledgeID: req.Id})
 
 	// 7. 事务成功提交后，删除存储中的文件（这个操作失败不影响数据一致性）
 	if storageType == file_store.StorageTypeRustFS {
@@ -231,15 +264,26 @@ func (c *ControllerV1) KBDelete(ctx context.Context, req *v1.KBDeleteReq) (res *
 
 func (c *ControllerV1) KBGetList(ctx context.Context, req *v1.KBGetListReq) (res *v1.KBGetListRes, err error) {
 	// Log request parameters
-	g.Log().Infof(ctx, "KBGetList request received - Name: %v, Status: %v, Category: %v",
-		req.Name, req.Status, req.Category)
+	g.Log().Infof(ctx, "KBGetList request received - Name: %v, Status: %v, Category: %v, ProjectId: %s",
+		req.Name, req.Status, req.Category, req.ProjectId)
 
 	res = &v1.KBGetListRes{}
-	err = dao.KnowledgeBase.Ctx(ctx).Where(do.KnowledgeBase{
+	m := dao.KnowledgeBase.Ctx(ctx).Where(do.KnowledgeBase{
 		Status:   req.Status,
 		Name:     req.Name,
 		Category: req.Category,
-	}).Scan(&res.List)
+	})
+	if req.ProjectId != "" {
+		ids, err := project.ResourceIDs(ctx, req.ProjectId, project.ResourceKnowledgeBase)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return res, nil
+		}
+		m = m.WhereIn(dao.KnowledgeBase.Columns().Id, ids)
+	}
+	err = m.Scan(&res.List)
 	return
 }
 
@@ -273,6 +317,15 @@ func (c *ControllerV1) KBUpdate(ctx context.Context, req *v1.KBUpdateReq) (res *
 		"description": req.Description,
 		"category":    req.Category,
 	}
+	if req.SparseWeight != nil {
+		updateData["sparse_weight"] = *req.SparseWeight
+	}
+	if req.RecencyWeight != nil {
+		updateData["recency_weight"] = *req.RecencyWeight
+	}
+	if req.RecencyHalfLifeDays != nil {
+		updateData["recency_half_life_days"] = *req.RecencyHalfLifeDays
+	}
 	result := tx.WithContext(ctx).Model(&gormModel.KnowledgeBase{}).Where("id = ?", req.Id).Updates(updateData)
 	if result.Error != nil {
 		tx.Rollback()
@@ -326,3 +379,76 @@ func (c *ControllerV1) KBUpdateStatus(ctx context.Context, req *v1.KBUpdateStatu
 
 	return &v1.KBUpdateStatusRes{}, nil
 }
+
+// KBIngestHooksGet 获取知识库的索引预处理钩子配置
+func (c *ControllerV1) KBIngestHooksGet(ctx context.Context, req *v1.KBIngestHooksGetReq) (res *v1.KBIngestHooksGetRes, err error) {
+	g.Log().Infof(ctx, "KBIngestHooksGet request received - Id: %s, Version: %d", req.Id, req.Version)
+
+	config, hooks, err := preprocess.Get(ctx, req.Id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	res = &v1.KBIngestHooksGetRes{Hooks: []v1.IngestHook{}}
+	if config == nil {
+		return res, nil
+	}
+	res.Version = config.Version
+	res.Hooks = hooks
+	res.Comment = config.Comment
+	if config.CreateTime != nil {
+		res.CreateTime = config.CreateTime.Format(time.RFC3339)
+	}
+	return res, nil
+}
+
+// KBIngestHooksUpdate 把知识库的索引预处理钩子保存为新版本
+func (c *ControllerV1) KBIngestHooksUpdate(ctx context.Context, req *v1.KBIngestHooksUpdateReq) (res *v1.KBIngestHooksUpdateRes, err error) {
+	g.Log().Infof(ctx, "KBIngestHooksUpdate request received - Id: %s, Hooks: %d, Comment: %s", req.Id, len(req.Hooks), req.Comment)
+
+	config, err := preprocess.Save(ctx, req.Id, req.Hooks, req.Comment)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.KBIngestHooksUpdateRes{Version: config.Version}, nil
+}
+
+// KBIngestHooksVersions 列出知识库索引预处理钩子的全部版本
+func (c *ControllerV1) KBIngestHooksVersions(ctx context.Context, req *v1.KBIngestHooksVersionsReq) (res *v1.KBIngestHooksVersionsRes, err error) {
+	g.Log().Infof(ctx, "KBIngestHooksVersions request received - Id: %s", req.Id)
+
+	configs, err := preprocess.Versions(ctx, req.Id)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list ingest hook versions")
+	}
+	res = &v1.KBIngestHooksVersionsRes{Versions: make([]v1.IngestHookVersion, 0, len(configs))}
+	for _, config := range configs {
+		hooks, err := preprocess.DecodeHooks(config.Hooks)
+		if err != nil {
+			return nil, err
+		}
+		version := v1.IngestHookVersion{
+			Version:   config.Version,
+			HookCount: len(hooks),
+			Comment:   config.Comment,
+		}
+		if config.CreateTime != nil {
+			version.CreateTime = config.CreateTime.Format(time.RFC3339)
+		}
+		res.Versions = append(res.Versions, version)
+	}
+	if len(configs) > 0 {
+		res.Active = configs[0].Version
+	}
+	return res, nil
+}
+
+// KBIngestHooksRollback 把历史版本的索引预处理钩子保存为新版本
+func (c *ControllerV1) KBIngestHooksRollback(ctx context.Context, req *v1.KBIngestHooksRollbackReq) (res *v1.KBIngestHooksRollbackRes, err error) {
+	g.Log().Infof(ctx, "KBIngestHooksRollback request received - Id: %s, Version: %d", req.Id, req.Version)
+
+	config, err := preprocess.Rollback(ctx, req.Id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.KBIngestHooksRollbackRes{Version: config.Version}, nil
+}
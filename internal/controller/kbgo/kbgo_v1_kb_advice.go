@@ -0,0 +1,61 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/kbadvisor"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// KBAdvice 获取知识库的语言构成、分片统计和优化建议
+func (c *ControllerV1) KBAdvice(ctx context.Context, req *v1.KBAdviceReq) (res *v1.KBAdviceRes, err error) {
+	g.Log().Infof(ctx, "KBAdvice request received - Id: %s, Refresh: %v", req.Id, req.Refresh)
+
+	advice, err := kbadvisor.Get(ctx, req.Id, req.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	profile := advice.Profile
+	res = &v1.KBAdviceRes{
+		Profile: &v1.KBProfile{
+			ChunkCount:       profile.ChunkCount,
+			SampledChunks:    profile.SampledChunks,
+			Languages:        advice.Languages,
+			DominantLanguage: profile.DominantLanguage,
+			DominantShare:    profile.DominantShare,
+			MixedShare:       profile.MixedShare,
+			AvgChunkChars:    profile.AvgChunkChars,
+			AvgChunkTokens:   profile.AvgChunkTokens,
+			MaxChunkTokens:   profile.MaxChunkTokens,
+		},
+		EmbeddingModelId: advice.EmbeddingModelID,
+		Recommendations:  make([]*v1.KBRecommendation, 0, len(advice.Recommendations)),
+	}
+	if profile.AnalyzedAt != nil {
+		res.Profile.AnalyzedAt = profile.AnalyzedAt.Format(time.RFC3339)
+	}
+	for _, rec := range advice.Recommendations {
+		res.Recommendations = append(res.Recommendations, &v1.KBRecommendation{
+			Type:      rec.Type,
+			Reason:    rec.Reason,
+			ModelId:   rec.ModelID,
+			ModelName: rec.ModelName,
+			ChunkSize: rec.ChunkSize,
+		})
+	}
+	return res, nil
+}
+
+// KBAdviceApply 按建议的 embedding 模型创建知识库迁移任务
+func (c *ControllerV1) KBAdviceApply(ctx context.Context, req *v1.KBAdviceApplyReq) (res *v1.KBAdviceApplyRes, err error) {
+	g.Log().Infof(ctx, "KBAdviceApply request received - Id: %s, ModelId: %s", req.Id, req.ModelId)
+
+	job, err := kbadvisor.Apply(ctx, req.Id, req.ModelId)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to start embedding model migration")
+	}
+	return &v1.KBAdviceApplyRes{Job: toReembedJobItem(job)}, nil
+}
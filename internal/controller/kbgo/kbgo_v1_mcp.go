@@ -8,8 +8,11 @@ import (
 
 	"github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/workspace"
+	"github.com/Malowking/kbgo/internal/mcp"
 	"github.com/Malowking/kbgo/internal/mcp/client"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/google/uuid"
@@ -38,18 +41,23 @@ func (c *ControllerV1) MCPRegistryCreate(ctx context.Context, req *v1.MCPRegistr
 	if req.Timeout != nil {
 		timeout = *req.Timeout
 	}
+	toolTimeouts, err := mcp.EncodeToolTimeouts(req.ToolTimeouts)
+	if err != nil {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, err.Error())
+	}
 
 	// 创建注册记录
 	registry := &gormModel.MCPRegistry{
-		ID:          id,
-		Name:        req.Name,
-		Description: req.Description,
-		Endpoint:    req.Endpoint,
-		ApiKey:      req.ApiKey,
-		Headers:     req.Headers,
-		Timeout:     timeout,
-		Status:      1,    // 默认启用
-		Tools:       "[]", // 默认空工具列表
+		ID:           id,
+		Name:         req.Name,
+		Description:  req.Description,
+		Endpoint:     req.Endpoint,
+		ApiKey:       req.ApiKey,
+		Headers:      req.Headers,
+		Timeout:      timeout,
+		ToolTimeouts: toolTimeouts,
+		Status:       1,    // 默认启用
+		Tools:        "[]", // 默认空工具列表
 	}
 
 	if err := dao.MCPRegistry.Create(ctx, registry); err != nil {
@@ -99,6 +107,11 @@ func (c *ControllerV1) MCPRegistryUpdate(ctx context.Context, req *v1.MCPRegistr
 	if req.Timeout != nil {
 		registry.Timeout = *req.Timeout
 	}
+	if req.ToolTimeouts != nil {
+		if registry.ToolTimeouts, err = mcp.EncodeToolTimeouts(req.ToolTimeouts); err != nil {
+			return nil, gerror.NewCode(gcode.CodeInvalidParameter, err.Error())
+		}
+	}
 	if req.Status != nil {
 		registry.Status = *req.Status
 	}
@@ -150,16 +163,17 @@ func (c *ControllerV1) MCPRegistryGetOne(ctx context.Context, req *v1.MCPRegistr
 	}
 
 	return &v1.MCPRegistryGetOneRes{
-		Id:          registry.ID,
-		Name:        registry.Name,
-		Description: registry.Description,
-		Endpoint:    registry.Endpoint,
-		ApiKey:      maskedApiKey,
-		Headers:     registry.Headers,
-		Timeout:     registry.Timeout,
-		Status:      registry.Status,
-		CreateTime:  registry.CreateTime.Format(time.RFC3339),
-		UpdateTime:  registry.UpdateTime.Format(time.RFC3339),
+		Id:           registry.ID,
+		Name:         registry.Name,
+		Description:  registry.Description,
+		Endpoint:     registry.Endpoint,
+		ApiKey:       maskedApiKey,
+		Headers:      registry.Headers,
+		Timeout:      registry.Timeout,
+		ToolTimeouts: mcp.ParseToolTimeouts(registry.ToolTimeouts),
+		Status:       registry.Status,
+		CreateTime:   registry.CreateTime.Format(time.RFC3339),
+		UpdateTime:   registry.UpdateTime.Format(time.RFC3339),
 	}, nil
 }
 
@@ -291,26 +305,33 @@ func (c *ControllerV1) MCPListTools(ctx context.Context, req *v1.MCPListToolsReq
 		return nil, gerror.Wrap(err, "failed to list MCP tools")
 	}
 
-	// 转换为响应格式
-	toolInfos := make([]v1.MCPToolInfo, 0, len(tools))
-	for _, tool := range tools {
-		toolInfos = append(toolInfos, v1.MCPToolInfo{
-			Name:        tool.Name,
-			Description: tool.Description,
-			InputSchema: tool.InputSchema,
-		})
-	}
-
-	// 将工具列表保存到数据库中
-	toolsJSON, err := json.Marshal(toolInfos)
-	if err == nil {
-		registry.Tools = string(toolsJSON)
-		if updateErr := dao.MCPRegistry.Update(ctx, registry); updateErr != nil {
-			g.Log().Errorf(ctx, "Failed to update MCP registry tools: %v", updateErr)
+	// 更新工具缓存：服务端已删除的工具标记为已弃用
+	verified, err := mcp.SaveLiveTools(ctx, registry, tools)
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to update MCP registry tools: %v", err)
+		toolInfos := make([]v1.MCPToolInfo, 0, len(tools))
+		for _, tool := range tools {
+			toolInfos = append(toolInfos, v1.MCPToolInfo{
+				Name:        tool.Name,
+				Description: tool.Description,
+				InputSchema: tool.InputSchema,
+			})
 		}
+		return &v1.MCPListToolsRes{Tools: toolInfos}, nil
 	}
 
-	return &v1.MCPListToolsRes{Tools: toolInfos}, nil
+	return &v1.MCPListToolsRes{Tools: verified.Tools}, nil
+}
+
+// MCPVerifyTools 立即核对MCP服务的工具缓存
+func (c *ControllerV1) MCPVerifyTools(ctx context.Context, req *v1.MCPVerifyToolsReq) (res *v1.MCPVerifyToolsRes, err error) {
+	g.Log().Infof(ctx, "MCPVerifyTools request received - Id: %s", req.Id)
+
+	registry, err := dao.MCPRegistry.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, gerror.Wrap(err, "MCP service not found")
+	}
+	return mcp.VerifyTools(ctx, registry)
 }
 
 // MCPCallTool 调用MCP工具
@@ -337,6 +358,13 @@ func (c *ControllerV1) MCPCallTool(ctx context.Context, req *v1.MCPCallToolReq)
 		return nil, gerror.New("MCP service is disabled")
 	}
 
+	// 服务端已删除的工具直接返回明确的错误
+	for _, tool := range mcp.ParseCachedTools(registry.Tools) {
+		if tool.Name == req.ToolName && tool.Deprecated {
+			return nil, gerror.Newf("tool %s was removed from MCP service %s at %s", req.ToolName, registry.Name, tool.DeprecatedAt)
+		}
+	}
+
 	// 创建客户端
 	mcpClient := client.NewMCPClient(registry)
 
@@ -349,8 +377,17 @@ func (c *ControllerV1) MCPCallTool(ctx context.Context, req *v1.MCPCallToolReq)
 		return nil, gerror.Wrap(err, "failed to initialize MCP connection")
 	}
 
+	// 将参数中 workspace://文件名 的引用替换为会话工作区文件内容
+	arguments := req.Arguments
+	if req.ConversationID != "" {
+		arguments, err = workspace.ResolveReferences(ctx, req.ConversationID, req.Arguments)
+		if err != nil {
+			return nil, gerror.Wrap(err, "failed to resolve workspace references")
+		}
+	}
+
 	// 调用工具
-	result, err := mcpClient.CallTool(ctx, req.ToolName, req.Arguments)
+	result, err := mcpClient.CallTool(ctx, req.ToolName, arguments)
 
 	// 计算耗时
 	duration := int(time.Since(startTime).Milliseconds())
@@ -2,10 +2,14 @@ package kbgo
 
 import (
 	"context"
+	"strings"
 
 	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/client"
 	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/modelhealth"
+	"github.com/Malowking/kbgo/internal/logic/reembed"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/gogf/gf/v2/encoding/gjson"
 	"github.com/gogf/gf/v2/errors/gerror"
@@ -24,6 +28,7 @@ func (c *ControllerV1) ReloadModels(ctx context.Context, req *v1.ReloadModelsReq
 		g.Log().Errorf(ctx, "Failed to reload models: %v", err)
 		return nil, err
 	}
+	modelhealth.CheckNow()
 
 	return &v1.ReloadModelsRes{
 		Success: true,
@@ -44,9 +49,17 @@ func (c *ControllerV1) ListModels(ctx context.Context, req *v1.ListModelsReq) (r
 		models = model.Registry.List()
 	}
 
+	health := make(map[string]*model.ModelHealth)
+	for _, mc := range models {
+		if h := model.Registry.Health(mc.ModelID); h != nil {
+			health[mc.ModelID] = h
+		}
+	}
+
 	return &v1.ListModelsRes{
 		Models: models,
 		Count:  len(models),
+		Health: health,
 	}, nil
 }
 
@@ -61,10 +74,47 @@ func (c *ControllerV1) GetModel(ctx context.Context, req *v1.GetModelReq) (res *
 	}
 
 	return &v1.GetModelRes{
-		Model: mc,
+		Model:  mc,
+		Health: model.Registry.Health(mc.ModelID),
 	}, nil
 }
 
+// OllamaModelList 列出 Ollama 服务上已下载的模型，并标注已注册的模型
+func (c *ControllerV1) OllamaModelList(ctx context.Context, req *v1.OllamaModelListReq) (res *v1.OllamaModelListRes, err error) {
+	g.Log().Infof(ctx, "OllamaModelList request received - BaseURL: %s", req.BaseURL)
+
+	models, err := client.ListOllamaModels(ctx, req.BaseURL)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list ollama models")
+	}
+
+	// 已注册的 ollama 模型：服务地址 + 模型名 -> 模型ID
+	registered := make(map[string]string)
+	for _, mc := range model.Registry.List() {
+		if strings.EqualFold(mc.Provider, client.ProviderOllama) {
+			registered[client.OllamaBaseURL(mc.BaseURL)+"|"+mc.Name] = mc.ModelID
+		}
+	}
+	baseURL := client.OllamaBaseURL(req.BaseURL)
+	res = &v1.OllamaModelListRes{Models: make([]*v1.OllamaModelItem, 0, len(models))}
+	for _, m := range models {
+		modelID := registered[baseURL+"|"+m.Name]
+		if modelID == "" {
+			modelID = registered[baseURL+"|"+strings.TrimSuffix(m.Name, ":latest")]
+		}
+		res.Models = append(res.Models, &v1.OllamaModelItem{
+			Name:              m.Name,
+			Size:              m.Size,
+			Family:            m.Details.Family,
+			ParameterSize:     m.Details.ParameterSize,
+			QuantizationLevel: m.Details.QuantizationLevel,
+			ModifiedAt:        m.ModifiedAt,
+			RegisteredModelID: modelID,
+		})
+	}
+	return res, nil
+}
+
 // RegisterModel 注册新模型
 func (c *ControllerV1) RegisterModel(ctx context.Context, req *v1.RegisterModelReq) (res *v1.RegisterModelRes, err error) {
 	g.Log().Infof(ctx, "RegisterModel request received - ModelName: %s, ModelType: %s", req.ModelName, req.ModelType)
@@ -77,6 +127,9 @@ func (c *ControllerV1) RegisterModel(ctx context.Context, req *v1.RegisterModelR
 	if req.Dimension > 0 {
 		extra["dimension"] = req.Dimension
 	}
+	if req.ContextWindow > 0 {
+		extra["context_window"] = req.ContextWindow
+	}
 	if req.Config != nil {
 		for k, v := range req.Config {
 			extra[k] = v
@@ -124,6 +177,7 @@ func (c *ControllerV1) RegisterModel(ctx context.Context, req *v1.RegisterModelR
 		}, nil
 	}
 
+	modelhealth.CheckNow()
 	g.Log().Infof(ctx, "Model registered successfully with ID: %s", aiModel.ModelID)
 	return &v1.RegisterModelRes{
 		Success: true,
@@ -147,6 +201,9 @@ func (c *ControllerV1) UpdateModel(ctx context.Context, req *v1.UpdateModelReq)
 		return nil, gerror.Newf("Model not found: %s", req.ModelID)
 	}
 
+	// 记录更新前的配置，用于判断 embedding 模型配置变更后是否需要重新向量化
+	previousConfig := model.Registry.Get(req.ModelID)
+
 	// 只更新传入的字段（使用指针判断是否传值）
 	if req.ModelName != nil {
 		existingModel.ModelName = *req.ModelName
@@ -195,11 +252,26 @@ func (c *ControllerV1) UpdateModel(ctx context.Context, req *v1.UpdateModelReq)
 		}, nil
 	}
 
+	modelhealth.CheckNow()
 	g.Log().Infof(ctx, "Model updated successfully: %s", req.ModelID)
-	return &v1.UpdateModelRes{
+	res = &v1.UpdateModelRes{
 		Success: true,
 		Message: "Model updated and reloaded successfully",
-	}, nil
+	}
+
+	// embedding 模型地址/版本等变更后，已有向量与新模型不再一致，后台重新向量化
+	if reembed.NeedsReembed(previousConfig, model.Registry.Get(req.ModelID)) &&
+		g.Cfg().MustGet(ctx, "reembed.autoStart", true).Bool() {
+		job, err := reembed.StartJob(ctx, req.ModelID, "")
+		if err != nil {
+			g.Log().Errorf(ctx, "Failed to start re-embedding job for model %s: %v", req.ModelID, err)
+			res.Message = "Model updated and reloaded successfully, but failed to start re-embedding job. Please call /v1/model/reembed manually."
+		} else {
+			res.ReembedJobID = job.ID
+			res.Message = "Model updated and reloaded successfully, re-embedding job started"
+		}
+	}
+	return res, nil
 }
 
 // DeleteModel 删除模型
@@ -4,7 +4,11 @@ import (
 	"context"
 
 	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/logic/chatcompletions"
+	"github.com/Malowking/kbgo/internal/logic/completion"
+	"github.com/Malowking/kbgo/internal/logic/embeddings"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/sashabaranov/go-openai"
@@ -14,134 +18,7 @@ import (
 func (c *ControllerV1) ChatCompletion(ctx context.Context, req *v1.ChatCompletionReq) (res *v1.ChatCompletionRes, err error) {
 	g.Log().Infof(ctx, "ChatCompletion request received - ModelID: %s, Messages: %d", req.ModelID, len(req.Messages))
 
-	// 获取模型配置
-	mc := model.Registry.Get(req.ModelID)
-	if mc == nil {
-		g.Log().Errorf(ctx, "Model not found: %s", req.ModelID)
-		return nil, gerror.Newf("Model not found: %s", req.ModelID)
-	}
-
-	// 设置默认值
-	if req.MaxTokens == 0 {
-		req.MaxTokens = 4096
-	}
-	if req.Temperature == 0 {
-		req.Temperature = 0.7
-	}
-	if req.TopP == 0 {
-		req.TopP = 0.9
-	}
-
-	// 转换消息格式
-	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-			Name:    msg.Name,
-		}
-
-		// 转换工具调用
-		if len(msg.ToolCalls) > 0 {
-			toolCalls := make([]openai.ToolCall, len(msg.ToolCalls))
-			for j, tc := range msg.ToolCalls {
-				toolCalls[j] = openai.ToolCall{
-					ID:   tc.ID,
-					Type: openai.ToolType(tc.Type),
-					Function: openai.FunctionCall{
-						Name:      tc.Function.Name,
-						Arguments: tc.Function.Arguments,
-					},
-				}
-			}
-			messages[i].ToolCalls = toolCalls
-		}
-
-		if msg.ToolCallID != "" {
-			messages[i].ToolCallID = msg.ToolCallID
-		}
-	}
-
-	// 构建请求
-	chatReq := openai.ChatCompletionRequest{
-		Model:            mc.Name, // 使用模型名称而非UUID
-		Messages:         messages,
-		MaxTokens:        req.MaxTokens,
-		Temperature:      req.Temperature,
-		TopP:             req.TopP,
-		FrequencyPenalty: req.FrequencyPenalty,
-		PresencePenalty:  req.PresencePenalty,
-		Stop:             req.Stop,
-		Stream:           req.Stream,
-	}
-
-	// 转换工具定义
-	if len(req.Tools) > 0 {
-		tools := make([]openai.Tool, len(req.Tools))
-		for i, tool := range req.Tools {
-			tools[i] = openai.Tool{
-				Type: openai.ToolType(tool.Type),
-				Function: &openai.FunctionDefinition{
-					Name:        tool.Function.Name,
-					Description: tool.Function.Description,
-					Parameters:  tool.Function.Parameters,
-				},
-			}
-		}
-		chatReq.Tools = tools
-	}
-
-	// 调用模型
-	resp, err := mc.Client.CreateChatCompletion(ctx, chatReq)
-	if err != nil {
-		g.Log().Errorf(ctx, "Failed to call model: %v", err)
-		return nil, err
-	}
-
-	// 转换响应格式
-	choices := make([]v1.ChatCompletionChoice, len(resp.Choices))
-	for i, choice := range resp.Choices {
-		msg := v1.ChatCompletionMessage{
-			Role:    choice.Message.Role,
-			Content: choice.Message.Content,
-			Name:    choice.Message.Name,
-		}
-
-		// 转换工具调用
-		if len(choice.Message.ToolCalls) > 0 {
-			toolCalls := make([]v1.ChatCompletionToolCall, len(choice.Message.ToolCalls))
-			for j, tc := range choice.Message.ToolCalls {
-				toolCalls[j] = v1.ChatCompletionToolCall{
-					ID:   tc.ID,
-					Type: string(tc.Type),
-					Function: v1.ChatCompletionToolCallFunc{
-						Name:      tc.Function.Name,
-						Arguments: tc.Function.Arguments,
-					},
-				}
-			}
-			msg.ToolCalls = toolCalls
-		}
-
-		choices[i] = v1.ChatCompletionChoice{
-			Index:        choice.Index,
-			Message:      msg,
-			FinishReason: string(choice.FinishReason),
-		}
-	}
-
-	return &v1.ChatCompletionRes{
-		ID:      resp.ID,
-		Object:  resp.Object,
-		Created: resp.Created,
-		Model:   resp.Model,
-		Choices: choices,
-		Usage: v1.ChatCompletionUsage{
-			PromptTokens:     resp.Usage.PromptTokens,
-			CompletionTokens: resp.Usage.CompletionTokens,
-			TotalTokens:      resp.Usage.TotalTokens,
-		},
-	}, nil
+	return completion.Complete(ctx, req)
 }
 
 // EmbeddingCompletion 向量化接口
@@ -192,3 +69,41 @@ func (c *ControllerV1) EmbeddingCompletion(ctx context.Context, req *v1.Embeddin
 		},
 	}, nil
 }
+
+// Embeddings OpenAI 兼容的向量化接口：按模型ID、名称或别名路由，分批调用并缓存，计入项目 token 配额
+func (c *ControllerV1) Embeddings(ctx context.Context, req *v1.EmbeddingsReq) (res *v1.EmbeddingsRes, err error) {
+	g.Log().Infof(ctx, "Embeddings request received - Model: %s, ProjectID: %s", req.Model, req.ProjectID)
+
+	return embeddings.Create(ctx, req)
+}
+
+// ChatCompletions OpenAI 兼容的聊天接口：携带工具定义或工具调用消息时直接调用模型并透传 tool_calls，
+// 其他请求经过对话流程（检索、MCP 工具、人设、项目配额），按 OpenAI 格式返回
+func (c *ControllerV1) ChatCompletions(ctx context.Context, req *v1.ChatCompletionsReq) (res *v1.ChatCompletionRes, err error) {
+	g.Log().Infof(ctx, "ChatCompletions request received - Model: %s, Messages: %d, Tools: %d, Stream: %v, ConversationID: %s",
+		req.Model, len(req.Messages), len(req.Tools), req.Stream, req.ConversationID)
+
+	if chatcompletions.Passthrough(req) {
+		if req.Stream {
+			return nil, chatcompletions.Stream(ctx, req)
+		}
+		return chatcompletions.Complete(ctx, req)
+	}
+
+	ctx, chatReq, err := chatcompletions.NewChatReq(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Stream {
+		// 对话流程的 SSE 回答增量改为 chat.completion.chunk 格式
+		r := g.RequestFromCtx(ctx)
+		defer common.SetStreamFormat(r.Response, chatcompletions.NewChunkFormat(chatReq, "", chatcompletions.IncludeUsage(req)))()
+		_, err = c.Chat(ctx, chatReq)
+		return nil, err
+	}
+	chatRes, err := c.Chat(ctx, chatReq)
+	if err != nil {
+		return nil, err
+	}
+	return chatcompletions.ToResponse(chatReq, chatRes), nil
+}
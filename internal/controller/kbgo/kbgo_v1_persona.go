@@ -0,0 +1,106 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/persona"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// PersonaCreate 创建回答人设
+func (c *ControllerV1) PersonaCreate(ctx context.Context, req *v1.PersonaCreateReq) (res *v1.PersonaCreateRes, err error) {
+	g.Log().Infof(ctx, "PersonaCreate request received - Name: %s", req.Name)
+
+	p, err := persona.Create(ctx, &persona.Fields{
+		Name:         &req.Name,
+		Description:  &req.Description,
+		Tone:         &req.Tone,
+		Formality:    &req.Formality,
+		EmojiPolicy:  &req.EmojiPolicy,
+		Signature:    &req.Signature,
+		Instructions: &req.Instructions,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to create persona")
+	}
+	return &v1.PersonaCreateRes{Persona: toPersonaItem(p)}, nil
+}
+
+// PersonaUpdate 更新回答人设
+func (c *ControllerV1) PersonaUpdate(ctx context.Context, req *v1.PersonaUpdateReq) (res *v1.PersonaUpdateRes, err error) {
+	g.Log().Infof(ctx, "PersonaUpdate request received - PersonaID: %s", req.PersonaID)
+
+	p, err := persona.Update(ctx, req.PersonaID, &persona.Fields{
+		Name:         req.Name,
+		Description:  req.Description,
+		Tone:         req.Tone,
+		Formality:    req.Formality,
+		EmojiPolicy:  req.EmojiPolicy,
+		Signature:    req.Signature,
+		Instructions: req.Instructions,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to update persona")
+	}
+	return &v1.PersonaUpdateRes{Persona: toPersonaItem(p)}, nil
+}
+
+// PersonaDelete 删除回答人设
+func (c *ControllerV1) PersonaDelete(ctx context.Context, req *v1.PersonaDeleteReq) (res *v1.PersonaDeleteRes, err error) {
+	g.Log().Infof(ctx, "PersonaDelete request received - PersonaID: %s", req.PersonaID)
+
+	if err = persona.Delete(ctx, req.PersonaID); err != nil {
+		return nil, gerror.Wrap(err, "failed to delete persona")
+	}
+	return &v1.PersonaDeleteRes{}, nil
+}
+
+// PersonaGet 获取回答人设
+func (c *ControllerV1) PersonaGet(ctx context.Context, req *v1.PersonaGetReq) (res *v1.PersonaGetRes, err error) {
+	g.Log().Infof(ctx, "PersonaGet request received - PersonaID: %s", req.PersonaID)
+
+	p, err := persona.Get(ctx, req.PersonaID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get persona")
+	}
+	return &v1.PersonaGetRes{Persona: toPersonaItem(p)}, nil
+}
+
+// PersonaList 获取回答人设列表
+func (c *ControllerV1) PersonaList(ctx context.Context, req *v1.PersonaListReq) (res *v1.PersonaListRes, err error) {
+	g.Log().Infof(ctx, "PersonaList request received")
+
+	list, err := persona.List(ctx)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list personas")
+	}
+	res = &v1.PersonaListRes{Personas: make([]*v1.PersonaItem, 0, len(list))}
+	for _, p := range list {
+		res.Personas = append(res.Personas, toPersonaItem(p))
+	}
+	return res, nil
+}
+
+func toPersonaItem(p *gormModel.Persona) *v1.PersonaItem {
+	item := &v1.PersonaItem{
+		PersonaID:    p.ID,
+		Name:         p.Name,
+		Description:  p.Description,
+		Tone:         p.Tone,
+		Formality:    p.Formality,
+		EmojiPolicy:  p.EmojiPolicy,
+		Signature:    p.Signature,
+		Instructions: p.Instructions,
+	}
+	if p.CreateTime != nil {
+		item.CreatedAt = p.CreateTime.Format(time.RFC3339)
+	}
+	if p.UpdateTime != nil {
+		item.UpdatedAt = p.UpdateTime.Format(time.RFC3339)
+	}
+	return item
+}
@@ -0,0 +1,32 @@
+package kbgo
+
+import (
+	"context"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/piiscrub"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// PIIScrubRun 立即执行会话个人信息脱敏任务
+func (c *ControllerV1) PIIScrubRun(ctx context.Context, req *v1.PIIScrubRunReq) (res *v1.PIIScrubRunRes, err error) {
+	g.Log().Infof(ctx, "PIIScrubRun request received - DryRun: %v", req.DryRun)
+
+	res, err = piiscrub.Run(ctx, req.DryRun)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to run PII scrubbing job")
+	}
+	return res, nil
+}
+
+// PIIScrubLogList 分页查询个人信息脱敏审计记录
+func (c *ControllerV1) PIIScrubLogList(ctx context.Context, req *v1.PIIScrubLogListReq) (res *v1.PIIScrubLogListRes, err error) {
+	g.Log().Infof(ctx, "PIIScrubLogList request received - RunID: %s, ConvID: %s, ProjectID: %s", req.RunID, req.ConvID, req.ProjectID)
+
+	res, err = piiscrub.ListLogs(ctx, req)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list PII scrubbing logs")
+	}
+	return res, nil
+}
@@ -0,0 +1,180 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ProjectCreate 创建项目
+func (c *ControllerV1) ProjectCreate(ctx context.Context, req *v1.ProjectCreateReq) (res *v1.ProjectCreateRes, err error) {
+	g.Log().Infof(ctx, "ProjectCreate request received - Name: %s", req.Name)
+
+	fields := &project.Fields{Name: &req.Name, Description: &req.Description, Settings: req.Settings, Quota: req.Quota}
+	p, err := project.Create(ctx, fields)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to create project")
+	}
+	return &v1.ProjectCreateRes{Project: toProjectItem(p)}, nil
+}
+
+// ProjectUpdate 更新项目
+func (c *ControllerV1) ProjectUpdate(ctx context.Context, req *v1.ProjectUpdateReq) (res *v1.ProjectUpdateRes, err error) {
+	g.Log().Infof(ctx, "ProjectUpdate request received - ProjectID: %s", req.ProjectID)
+
+	p, err := project.Update(ctx, req.ProjectID, &project.Fields{
+		Name:        req.Name,
+		Description: req.Description,
+		Settings:    req.Settings,
+		Quota:       req.Quota,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to update project")
+	}
+	return &v1.ProjectUpdateRes{Project: toProjectItem(p)}, nil
+}
+
+// ProjectDelete 删除项目
+func (c *ControllerV1) ProjectDelete(ctx context.Context, req *v1.ProjectDeleteReq) (res *v1.ProjectDeleteRes, err error) {
+	g.Log().Infof(ctx, "ProjectDelete request received - ProjectID: %s", req.ProjectID)
+
+	if err = project.Delete(ctx, req.ProjectID); err != nil {
+		return nil, gerror.Wrap(err, "failed to delete project")
+	}
+	return &v1.ProjectDeleteRes{}, nil
+}
+
+// ProjectGet 获取项目详情，包含各类资源数量和成员
+func (c *ControllerV1) ProjectGet(ctx context.Context, req *v1.ProjectGetReq) (res *v1.ProjectGetRes, err error) {
+	g.Log().Infof(ctx, "ProjectGet request received - ProjectID: %s", req.ProjectID)
+
+	p, err := project.Get(ctx, req.ProjectID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get project")
+	}
+	item := toProjectItem(p)
+	if item.ResourceCount, err = project.ResourceCount(ctx, p.ID); err != nil {
+		return nil, gerror.Wrap(err, "failed to count project resources")
+	}
+	members, err := project.Members(ctx, p.ID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list project members")
+	}
+	for _, m := range members {
+		item.Members = append(item.Members, toProjectMember(m))
+	}
+	return &v1.ProjectGetRes{Project: item}, nil
+}
+
+// ProjectList 获取项目列表
+func (c *ControllerV1) ProjectList(ctx context.Context, req *v1.ProjectListReq) (res *v1.ProjectListRes, err error) {
+	g.Log().Infof(ctx, "ProjectList request received - UserID: %s", req.UserID)
+
+	list, err := project.List(ctx, req.UserID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list projects")
+	}
+	res = &v1.ProjectListRes{Projects: make([]*v1.ProjectItem, 0, len(list))}
+	for _, p := range list {
+		res.Projects = append(res.Projects, toProjectItem(p))
+	}
+	return res, nil
+}
+
+// ProjectResourceAdd 把资源加入项目
+func (c *ControllerV1) ProjectResourceAdd(ctx context.Context, req *v1.ProjectResourceAddReq) (res *v1.ProjectResourceAddRes, err error) {
+	g.Log().Infof(ctx, "ProjectResourceAdd request received - ProjectID: %s, ResourceType: %s, ResourceIDs: %v", req.ProjectID, req.ResourceType, req.ResourceIDs)
+
+	if err = project.AddResources(ctx, req.ProjectID, req.ResourceType, req.ResourceIDs); err != nil {
+		return nil, gerror.Wrap(err, "failed to add project resources")
+	}
+	return &v1.ProjectResourceAddRes{}, nil
+}
+
+// ProjectResourceRemove 把资源移出项目
+func (c *ControllerV1) ProjectResourceRemove(ctx context.Context, req *v1.ProjectResourceRemoveReq) (res *v1.ProjectResourceRemoveRes, err error) {
+	g.Log().Infof(ctx, "ProjectResourceRemove request received - ProjectID: %s, ResourceType: %s, ResourceIDs: %v", req.ProjectID, req.ResourceType, req.ResourceIDs)
+
+	if err = project.RemoveResources(ctx, req.ProjectID, req.ResourceType, req.ResourceIDs); err != nil {
+		return nil, gerror.Wrap(err, "failed to remove project resources")
+	}
+	return &v1.ProjectResourceRemoveRes{}, nil
+}
+
+// ProjectResourceList 获取项目资源
+func (c *ControllerV1) ProjectResourceList(ctx context.Context, req *v1.ProjectResourceListReq) (res *v1.ProjectResourceListRes, err error) {
+	g.Log().Infof(ctx, "ProjectResourceList request received - ProjectID: %s, ResourceType: %s", req.ProjectID, req.ResourceType)
+
+	resources, err := project.Resources(ctx, req.ProjectID, req.ResourceType)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list project resources")
+	}
+	return &v1.ProjectResourceListRes{Resources: resources}, nil
+}
+
+// ProjectMemberSave 添加项目成员或修改成员角色
+func (c *ControllerV1) ProjectMemberSave(ctx context.Context, req *v1.ProjectMemberSaveReq) (res *v1.ProjectMemberSaveRes, err error) {
+	g.Log().Infof(ctx, "ProjectMemberSave request received - ProjectID: %s, UserID: %s, Role: %s", req.ProjectID, req.UserID, req.Role)
+
+	member, err := project.SaveMember(ctx, req.ProjectID, req.UserID, req.Role)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to save project member")
+	}
+	return &v1.ProjectMemberSaveRes{Member: toProjectMember(member)}, nil
+}
+
+// ProjectMemberRemove 移除项目成员
+func (c *ControllerV1) ProjectMemberRemove(ctx context.Context, req *v1.ProjectMemberRemoveReq) (res *v1.ProjectMemberRemoveRes, err error) {
+	g.Log().Infof(ctx, "ProjectMemberRemove request received - ProjectID: %s, UserID: %s", req.ProjectID, req.UserID)
+
+	if err = project.RemoveMember(ctx, req.ProjectID, req.UserID); err != nil {
+		return nil, gerror.Wrap(err, "failed to remove project member")
+	}
+	return &v1.ProjectMemberRemoveRes{}, nil
+}
+
+// ProjectUsage 获取项目用量与配额
+func (c *ControllerV1) ProjectUsage(ctx context.Context, req *v1.ProjectUsageReq) (res *v1.ProjectUsageRes, err error) {
+	g.Log().Infof(ctx, "ProjectUsage request received - ProjectID: %s", req.ProjectID)
+
+	p, err := project.Get(ctx, req.ProjectID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get project")
+	}
+	usage, err := quota.Usage(ctx, p)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get project usage")
+	}
+	return &v1.ProjectUsageRes{Quota: project.ParseQuota(p), Usage: usage}, nil
+}
+
+func toProjectItem(p *gormModel.Project) *v1.ProjectItem {
+	item := &v1.ProjectItem{
+		ProjectID:   p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Settings:    project.ParseSettings(p),
+		Quota:       project.ParseQuota(p),
+	}
+	if p.CreateTime != nil {
+		item.CreatedAt = p.CreateTime.Format(time.RFC3339)
+	}
+	if p.UpdateTime != nil {
+		item.UpdatedAt = p.UpdateTime.Format(time.RFC3339)
+	}
+	return item
+}
+
+func toProjectMember(m *gormModel.ProjectMember) *v1.ProjectMember {
+	member := &v1.ProjectMember{UserID: m.UserID, Role: m.Role}
+	if m.CreateTime != nil {
+		member.CreatedAt = m.CreateTime.Format(time.RFC3339)
+	}
+	return member
+}
@@ -0,0 +1,103 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/promotion"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// PromoteMessage 将助手回答提交为知识库 FAQ 沉淀申请
+func (c *ControllerV1) PromoteMessage(ctx context.Context, req *v1.PromoteMessageReq) (res *v1.PromoteMessageRes, err error) {
+	g.Log().Infof(ctx, "PromoteMessage request received - MsgID: %s, KnowledgeId: %s, RequestedBy: %s", req.MsgID, req.KnowledgeId, req.RequestedBy)
+
+	p, err := promotion.Promote(ctx, &promotion.PromoteOptions{
+		MsgID:       req.MsgID,
+		KnowledgeID: req.KnowledgeId,
+		Question:    req.Question,
+		Answer:      req.Answer,
+		RequestedBy: identity.Resolve(ctx, req.RequestedBy),
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to promote message")
+	}
+	return &v1.PromoteMessageRes{Promotion: toPromotionItem(p)}, nil
+}
+
+// PromotionList 获取回答沉淀申请列表
+func (c *ControllerV1) PromotionList(ctx context.Context, req *v1.PromotionListReq) (res *v1.PromotionListRes, err error) {
+	g.Log().Infof(ctx, "PromotionList request received - KnowledgeId: %s, Status: %s, Kind: %s", req.KnowledgeId, req.Status, req.Kind)
+
+	list, err := promotion.List(ctx, req.KnowledgeId, req.Status, req.Kind)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list promotions")
+	}
+	res = &v1.PromotionListRes{Promotions: make([]*v1.PromotionItem, 0, len(list))}
+	for _, p := range list {
+		res.Promotions = append(res.Promotions, toPromotionItem(p))
+	}
+	return res, nil
+}
+
+// PromotionApprove 审核通过回答沉淀申请，问答写入知识库
+func (c *ControllerV1) PromotionApprove(ctx context.Context, req *v1.PromotionApproveReq) (res *v1.PromotionApproveRes, err error) {
+	g.Log().Infof(ctx, "PromotionApprove request received - PromotionID: %s, Reviewer: %s", req.PromotionID, req.Reviewer)
+
+	p, err := promotion.Approve(ctx, req.PromotionID, &promotion.ReviewOptions{
+		Reviewer:         identity.Resolve(ctx, req.Reviewer),
+		Comment:          req.Comment,
+		Question:         req.Question,
+		Answer:           req.Answer,
+		EmbeddingModelID: req.EmbeddingModelID,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to approve promotion")
+	}
+	return &v1.PromotionApproveRes{Promotion: toPromotionItem(p)}, nil
+}
+
+// PromotionReject 驳回回答沉淀申请
+func (c *ControllerV1) PromotionReject(ctx context.Context, req *v1.PromotionRejectReq) (res *v1.PromotionRejectRes, err error) {
+	g.Log().Infof(ctx, "PromotionReject request received - PromotionID: %s, Reviewer: %s", req.PromotionID, req.Reviewer)
+
+	p, err := promotion.Reject(ctx, req.PromotionID, identity.Resolve(ctx, req.Reviewer), req.Comment)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to reject promotion")
+	}
+	return &v1.PromotionRejectRes{Promotion: toPromotionItem(p)}, nil
+}
+
+func toPromotionItem(p *gormModel.KBPromotion) *v1.PromotionItem {
+	item := &v1.PromotionItem{
+		PromotionID:    p.ID,
+		KnowledgeId:    p.KnowledgeID,
+		Kind:           p.Kind,
+		MsgID:          p.MsgID,
+		ConvID:         p.ConvID,
+		Question:       p.Question,
+		Answer:         p.Answer,
+		OriginalAnswer: p.OriginalAnswer,
+		Status:         p.Status,
+		RequestedBy:    p.RequestedBy,
+		Reviewer:       p.Reviewer,
+		ReviewComment:  p.ReviewComment,
+		DocumentID:     p.DocumentID,
+		ChunkID:        p.ChunkID,
+		LastError:      p.LastError,
+	}
+	for _, source := range promotion.ParseSources(p.Sources) {
+		item.Sources = append(item.Sources, &v1.PromotionSource{ChunkID: source.ID, Score: source.Score})
+	}
+	if p.CreateTime != nil {
+		item.CreatedAt = p.CreateTime.Format(time.RFC3339)
+	}
+	if p.ReviewedAt != nil {
+		item.ReviewedAt = p.ReviewedAt.Format(time.RFC3339)
+	}
+	return item
+}
@@ -0,0 +1,98 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/reembed"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ReembedStart 创建重新向量化任务
+func (c *ControllerV1) ReembedStart(ctx context.Context, req *v1.ReembedStartReq) (res *v1.ReembedStartRes, err error) {
+	g.Log().Infof(ctx, "ReembedStart request received - ModelID: %s, KnowledgeID: %s", req.ModelID, req.KnowledgeID)
+
+	job, err := reembed.StartJob(ctx, req.ModelID, req.KnowledgeID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to start re-embedding job")
+	}
+	return &v1.ReembedStartRes{Job: toReembedJobItem(job)}, nil
+}
+
+// ReembedJobList 获取重新向量化任务列表
+func (c *ControllerV1) ReembedJobList(ctx context.Context, req *v1.ReembedJobListReq) (res *v1.ReembedJobListRes, err error) {
+	g.Log().Infof(ctx, "ReembedJobList request received - ModelID: %s, Status: %s", req.ModelID, req.Status)
+
+	jobs, err := reembed.ListJobs(ctx, req.ModelID, req.Status)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list re-embedding jobs")
+	}
+	res = &v1.ReembedJobListRes{Jobs: make([]*v1.ReembedJobItem, 0, len(jobs))}
+	for _, job := range jobs {
+		res.Jobs = append(res.Jobs, toReembedJobItem(job))
+	}
+	return res, nil
+}
+
+// ReembedJobGet 获取重新向量化任务进度
+func (c *ControllerV1) ReembedJobGet(ctx context.Context, req *v1.ReembedJobGetReq) (res *v1.ReembedJobGetRes, err error) {
+	g.Log().Infof(ctx, "ReembedJobGet request received - JobID: %s", req.JobID)
+
+	job, err := reembed.GetJob(ctx, req.JobID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get re-embedding job")
+	}
+	return &v1.ReembedJobGetRes{Job: toReembedJobItem(job)}, nil
+}
+
+// ReembedJobPause 暂停重新向量化任务
+func (c *ControllerV1) ReembedJobPause(ctx context.Context, req *v1.ReembedJobPauseReq) (res *v1.ReembedJobPauseRes, err error) {
+	g.Log().Infof(ctx, "ReembedJobPause request received - JobID: %s", req.JobID)
+
+	job, err := reembed.PauseJob(ctx, req.JobID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to pause re-embedding job")
+	}
+	return &v1.ReembedJobPauseRes{Job: toReembedJobItem(job)}, nil
+}
+
+// ReembedJobResume 继续已暂停的重新向量化任务
+func (c *ControllerV1) ReembedJobResume(ctx context.Context, req *v1.ReembedJobResumeReq) (res *v1.ReembedJobResumeRes, err error) {
+	g.Log().Infof(ctx, "ReembedJobResume request received - JobID: %s", req.JobID)
+
+	job, err := reembed.ResumeJob(ctx, req.JobID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to resume re-embedding job")
+	}
+	return &v1.ReembedJobResumeRes{Job: toReembedJobItem(job)}, nil
+}
+
+// toReembedJobItem 转换任务为接口响应
+func toReembedJobItem(job *gormModel.ReembedJob) *v1.ReembedJobItem {
+	item := &v1.ReembedJobItem{
+		JobID:       job.ID,
+		ModelID:     job.ModelID,
+		KnowledgeID: job.KnowledgeID,
+		Migrate:     job.Migrate,
+		Status:      job.Status,
+		Total:       job.Total,
+		Processed:   job.Processed,
+		Failed:      job.Failed,
+		LastError:   job.LastError,
+	}
+	if job.Total > 0 {
+		item.Progress = min(float64(job.Processed+job.Failed)/float64(job.Total), 1)
+	} else if job.Status == gormModel.ReembedStatusCompleted {
+		item.Progress = 1
+	}
+	if job.StartedAt != nil {
+		item.StartedAt = job.StartedAt.Format(time.DateTime)
+	}
+	if job.FinishedAt != nil {
+		item.FinishedAt = job.FinishedAt.Format(time.DateTime)
+	}
+	return item
+}
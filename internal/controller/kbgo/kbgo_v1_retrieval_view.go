@@ -0,0 +1,114 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/retrievalview"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// RetrievalViewCreate 创建检索视图
+func (c *ControllerV1) RetrievalViewCreate(ctx context.Context, req *v1.RetrievalViewCreateReq) (res *v1.RetrievalViewCreateRes, err error) {
+	g.Log().Infof(ctx, "RetrievalViewCreate request received - Name: %s, KnowledgeIds: %v", req.Name, req.KnowledgeIds)
+
+	view, err := retrievalview.Create(ctx, &retrievalview.Fields{
+		Name:             &req.Name,
+		Description:      &req.Description,
+		KnowledgeIDs:     req.KnowledgeIds,
+		MetadataFilter:   req.MetadataFilter,
+		EmbeddingModelID: &req.EmbeddingModelID,
+		RerankModelID:    &req.RerankModelID,
+		RetrieveMode:     &req.RetrieveMode,
+		TopK:             &req.TopK,
+		Score:            &req.Score,
+		EnableRewrite:    &req.EnableRewrite,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to create retrieval view")
+	}
+	return &v1.RetrievalViewCreateRes{View: toRetrievalViewItem(view)}, nil
+}
+
+// RetrievalViewUpdate 更新检索视图
+func (c *ControllerV1) RetrievalViewUpdate(ctx context.Context, req *v1.RetrievalViewUpdateReq) (res *v1.RetrievalViewUpdateRes, err error) {
+	g.Log().Infof(ctx, "RetrievalViewUpdate request received - ViewID: %s", req.ViewID)
+
+	view, err := retrievalview.Update(ctx, req.ViewID, &retrievalview.Fields{
+		Name:             req.Name,
+		Description:      req.Description,
+		KnowledgeIDs:     req.KnowledgeIds,
+		MetadataFilter:   req.MetadataFilter,
+		EmbeddingModelID: req.EmbeddingModelID,
+		RerankModelID:    req.RerankModelID,
+		RetrieveMode:     req.RetrieveMode,
+		TopK:             req.TopK,
+		Score:            req.Score,
+		EnableRewrite:    req.EnableRewrite,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to update retrieval view")
+	}
+	return &v1.RetrievalViewUpdateRes{View: toRetrievalViewItem(view)}, nil
+}
+
+// RetrievalViewDelete 删除检索视图
+func (c *ControllerV1) RetrievalViewDelete(ctx context.Context, req *v1.RetrievalViewDeleteReq) (res *v1.RetrievalViewDeleteRes, err error) {
+	g.Log().Infof(ctx, "RetrievalViewDelete request received - ViewID: %s", req.ViewID)
+
+	if err = retrievalview.Delete(ctx, req.ViewID); err != nil {
+		return nil, gerror.Wrap(err, "failed to delete retrieval view")
+	}
+	return &v1.RetrievalViewDeleteRes{}, nil
+}
+
+// RetrievalViewGet 按ID或名称获取检索视图
+func (c *ControllerV1) RetrievalViewGet(ctx context.Context, req *v1.RetrievalViewGetReq) (res *v1.RetrievalViewGetRes, err error) {
+	g.Log().Infof(ctx, "RetrievalViewGet request received - ViewID: %s", req.ViewID)
+
+	view, err := retrievalview.Resolve(ctx, req.ViewID)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to get retrieval view")
+	}
+	return &v1.RetrievalViewGetRes{View: toRetrievalViewItem(view)}, nil
+}
+
+// RetrievalViewList 获取检索视图列表
+func (c *ControllerV1) RetrievalViewList(ctx context.Context, req *v1.RetrievalViewListReq) (res *v1.RetrievalViewListRes, err error) {
+	g.Log().Infof(ctx, "RetrievalViewList request received")
+
+	list, err := retrievalview.List(ctx)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list retrieval views")
+	}
+	res = &v1.RetrievalViewListRes{Views: make([]*v1.RetrievalViewItem, 0, len(list))}
+	for _, view := range list {
+		res.Views = append(res.Views, toRetrievalViewItem(view))
+	}
+	return res, nil
+}
+
+func toRetrievalViewItem(view *retrievalview.View) *v1.RetrievalViewItem {
+	item := &v1.RetrievalViewItem{
+		ViewID:           view.ID,
+		Name:             view.Name,
+		Description:      view.Description,
+		KnowledgeIds:     view.KnowledgeIDs,
+		MetadataFilter:   view.MetadataFilter,
+		EmbeddingModelID: view.EmbeddingModelID,
+		RerankModelID:    view.RerankModelID,
+		RetrieveMode:     view.RetrieveMode,
+		TopK:             view.TopK,
+		Score:            view.Score,
+		EnableRewrite:    view.EnableRewrite,
+	}
+	if view.CreateTime != nil {
+		item.CreatedAt = view.CreateTime.Format(time.RFC3339)
+	}
+	if view.UpdateTime != nil {
+		item.UpdatedAt = view.UpdateTime.Format(time.RFC3339)
+	}
+	return item
+}
@@ -10,8 +10,8 @@ import (
 
 func (c *ControllerV1) Retriever(ctx context.Context, req *v1.RetrieverReq) (res *v1.RetrieverRes, err error) {
 	// Log request parameters
-	g.Log().Infof(ctx, "Retriever request received - Question: %s, EmbeddingModelID: %s, RerankModelID: %s, TopK: %d, Score: %f, KnowledgeId: %s, EnableRewrite: %v, RewriteAttempts: %d, RetrieveMode: %s",
-		req.Question, req.EmbeddingModelID, req.RerankModelID, req.TopK, req.Score, req.KnowledgeId, req.EnableRewrite, req.RewriteAttempts, req.RetrieveMode)
+	g.Log().Infof(ctx, "Retriever request received - Question: %s, EmbeddingModelID: %s, RerankModelID: %s, TopK: %d, Score: %f, KnowledgeId: %s, EnableRewrite: %v, RewriteAttempts: %d, RetrieveMode: %s, AsOf: %v",
+		req.Question, req.EmbeddingModelID, req.RerankModelID, req.TopK, req.Score, req.KnowledgeId, req.EnableRewrite, req.RewriteAttempts, req.RetrieveMode, req.AsOf)
 
 	g.Log().Infof(ctx, "Received retriever request: %+v", req)
 
@@ -0,0 +1,65 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/chat"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ShadowResultList 查询影子评估结果
+func (c *ControllerV1) ShadowResultList(ctx context.Context, req *v1.ShadowResultListReq) (res *v1.ShadowResultListRes, err error) {
+	results, total, err := dao.ShadowResult.List(ctx, req.ConvID, req.CandidateModelID, req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	res = &v1.ShadowResultListRes{List: make([]*v1.ShadowResultItem, 0, len(results)), Total: total, Page: req.Page}
+	for _, r := range results {
+		item := &v1.ShadowResultItem{
+			Id:                    r.ID,
+			ConvID:                r.ConvID,
+			Question:              r.Question,
+			LiveModelID:           r.LiveModelID,
+			LiveAnswer:            r.LiveAnswer,
+			LiveLatencyMs:         r.LiveLatencyMs,
+			LiveGroundedness:      r.LiveGroundedness,
+			CandidateModelID:      r.CandidateModelID,
+			CandidateAnswer:       r.CandidateAnswer,
+			CandidateLatencyMs:    r.CandidateLatencyMs,
+			CandidateTokens:       r.CandidateTokens,
+			CandidateGroundedness: r.CandidateGroundedness,
+			Agreement:             r.Agreement,
+			Error:                 r.Error,
+		}
+		if r.CreateTime != nil {
+			item.CreateTime = r.CreateTime.Format(time.RFC3339)
+		}
+		res.List = append(res.List, item)
+	}
+	return res, nil
+}
+
+// ShadowSummary 按线上模型和候选模型汇总影子评估指标
+func (c *ControllerV1) ShadowSummary(ctx context.Context, req *v1.ShadowSummaryReq) (res *v1.ShadowSummaryRes, err error) {
+	g.Log().Infof(ctx, "ShadowSummary request received - StartDate: %s, EndDate: %s", req.StartDate, req.EndDate)
+
+	start, err := time.ParseInLocation(analytics.DateLayout, req.StartDate, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid start_date: %s", req.StartDate)
+	}
+	end, err := time.ParseInLocation(analytics.DateLayout, req.EndDate, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid end_date: %s", req.EndDate)
+	}
+
+	list, err := chat.ShadowSummary(ctx, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ShadowSummaryRes{List: list}, nil
+}
@@ -0,0 +1,18 @@
+package kbgo
+
+import (
+	"context"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/toolconfig"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ToolConfigValidate 校验工具配置，返回各配置项的就绪报告
+func (c *ControllerV1) ToolConfigValidate(ctx context.Context, req *v1.ToolConfigValidateReq) (res *v1.ToolConfigValidateRes, err error) {
+	g.Log().Infof(ctx, "ToolConfigValidate request received - Tools: %d", len(req.Tools))
+
+	res = toolconfig.Validate(ctx, req.Tools)
+	g.Log().Infof(ctx, "ToolConfigValidate finished - Ready: %v", res.Ready)
+	return res, nil
+}
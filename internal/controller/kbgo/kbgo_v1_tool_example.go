@@ -0,0 +1,112 @@
+package kbgo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/toolexample"
+	"github.com/Malowking/kbgo/internal/mcp"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ToolExampleCreate 创建工具调用示例
+func (c *ControllerV1) ToolExampleCreate(ctx context.Context, req *v1.ToolExampleCreateReq) (res *v1.ToolExampleCreateRes, err error) {
+	g.Log().Infof(ctx, "ToolExampleCreate request received - ModelID: %s, Tool: %s/%s", req.ModelID, req.ServiceName, req.ToolName)
+
+	example, err := toolexample.Create(ctx, &toolexample.Fields{
+		ModelID:     &req.ModelID,
+		Question:    &req.Question,
+		ServiceName: &req.ServiceName,
+		ToolName:    &req.ToolName,
+		Arguments:   req.Arguments,
+		Note:        &req.Note,
+		Enabled:     req.Enabled,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to create tool example")
+	}
+	return &v1.ToolExampleCreateRes{Example: toToolExampleItem(example)}, nil
+}
+
+// ToolExampleUpdate 更新工具调用示例
+func (c *ControllerV1) ToolExampleUpdate(ctx context.Context, req *v1.ToolExampleUpdateReq) (res *v1.ToolExampleUpdateRes, err error) {
+	g.Log().Infof(ctx, "ToolExampleUpdate request received - ExampleID: %s", req.ExampleID)
+
+	example, err := toolexample.Update(ctx, req.ExampleID, &toolexample.Fields{
+		ModelID:     req.ModelID,
+		Question:    req.Question,
+		ServiceName: req.ServiceName,
+		ToolName:    req.ToolName,
+		Arguments:   req.Arguments,
+		Note:        req.Note,
+		Enabled:     req.Enabled,
+	})
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to update tool example")
+	}
+	return &v1.ToolExampleUpdateRes{Example: toToolExampleItem(example)}, nil
+}
+
+// ToolExampleDelete 删除工具调用示例
+func (c *ControllerV1) ToolExampleDelete(ctx context.Context, req *v1.ToolExampleDeleteReq) (res *v1.ToolExampleDeleteRes, err error) {
+	g.Log().Infof(ctx, "ToolExampleDelete request received - ExampleID: %s", req.ExampleID)
+
+	if err = toolexample.Delete(ctx, req.ExampleID); err != nil {
+		return nil, gerror.Wrap(err, "failed to delete tool example")
+	}
+	return &v1.ToolExampleDeleteRes{}, nil
+}
+
+// ToolExampleList 获取工具调用示例列表
+func (c *ControllerV1) ToolExampleList(ctx context.Context, req *v1.ToolExampleListReq) (res *v1.ToolExampleListRes, err error) {
+	g.Log().Infof(ctx, "ToolExampleList request received - ModelID: %s, ServiceName: %s", req.ModelID, req.ServiceName)
+
+	list, err := toolexample.List(ctx, req.ModelID, req.ServiceName)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to list tool examples")
+	}
+	res = &v1.ToolExampleListRes{Examples: make([]*v1.ToolExampleItem, 0, len(list))}
+	for _, example := range list {
+		res.Examples = append(res.Examples, toToolExampleItem(example))
+	}
+	return res, nil
+}
+
+// ToolExampleTest 用样例问题测试工具调用示例的效果
+func (c *ControllerV1) ToolExampleTest(ctx context.Context, req *v1.ToolExampleTestReq) (res *v1.ToolExampleTestRes, err error) {
+	g.Log().Infof(ctx, "ToolExampleTest request received - ModelID: %s, Cases: %d, Compare: %v", req.ModelID, len(req.Cases), req.Compare)
+
+	toolCaller, err := mcp.NewMCPToolCaller(ctx)
+	if err != nil {
+		return nil, gerror.Wrap(err, "failed to create MCP tool caller")
+	}
+	defer toolCaller.Close()
+
+	return toolCaller.TestToolExamples(ctx, req), nil
+}
+
+func toToolExampleItem(example *gormModel.ToolExample) *v1.ToolExampleItem {
+	item := &v1.ToolExampleItem{
+		ExampleID:   example.ID,
+		ModelID:     example.ModelID,
+		Question:    example.Question,
+		ServiceName: example.ServiceName,
+		ToolName:    example.ToolName,
+		Note:        example.Note,
+		Enabled:     example.Enabled,
+	}
+	if example.Arguments != "" {
+		_ = json.Unmarshal([]byte(example.Arguments), &item.Arguments)
+	}
+	if example.CreateTime != nil {
+		item.CreatedAt = example.CreateTime.Format(time.RFC3339)
+	}
+	if example.UpdateTime != nil {
+		item.UpdatedAt = example.UpdateTime.Format(time.RFC3339)
+	}
+	return item
+}
@@ -5,6 +5,7 @@ import (
 
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
 	"github.com/Malowking/kbgo/internal/logic/knowledge"
 	"github.com/Malowking/kbgo/internal/model/entity"
 	"github.com/gogf/gf/v2/errors/gerror"
@@ -37,6 +38,8 @@ func (c *ControllerV1) UpdateChunk(ctx context.Context, req *v1.UpdateChunkReq)
 	if err = tx.Commit().Error; err != nil {
 		return nil, gerror.Newf("failed to commit transaction: %v", err)
 	}
+	// 批量修改的分片可能属于多个知识库，按未知知识库发布
+	kbevent.Publish(ctx, &kbevent.Event{Type: kbevent.TypeUpdated})
 
 	return &v1.UpdateChunkRes{}, nil
 }
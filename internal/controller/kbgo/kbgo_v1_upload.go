@@ -10,8 +10,12 @@ import (
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/file_store"
+	"github.com/Malowking/kbgo/core/indexer"
 	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/Malowking/kbgo/internal/logic/security"
 	"github.com/Malowking/kbgo/internal/model/entity"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gfile"
 	"github.com/google/uuid"
@@ -20,10 +24,31 @@ import (
 // UploadFile File upload interface
 func (c *ControllerV1) UploadFile(ctx context.Context, req *v1.UploadFileReq) (res *v1.UploadFileRes, err error) {
 	// Log request parameters
-	g.Log().Infof(ctx, "UploadFile request received - URL: %s, KnowledgeId: %s", req.URL, req.KnowledgeId)
+	g.Log().Infof(ctx, "UploadFile request received - URL: %s, KnowledgeId: %s, SecurityLabel: %s",
+		req.URL, req.KnowledgeId, req.SecurityLabel)
 
 	res = &v1.UploadFileRes{}
 
+	// 校验安全标签，入库时写入分片 metadata
+	if err = security.ValidateLabel(req.SecurityLabel); err != nil {
+		return nil, gerror.Wrap(err, "invalid security_label")
+	}
+	if _, err = security.ParseSectionLabels(req.SectionLabels); err != nil {
+		return nil, gerror.Wrap(err, "invalid section_labels")
+	}
+	if _, err = indexer.ParseFieldMapping(req.FieldMapping); err != nil {
+		return nil, gerror.Wrap(err, "invalid field_mapping")
+	}
+	req.SecurityLabel = security.NormalizeLabel(req.SecurityLabel)
+	if err = knowledge.ValidateValidity(req.ValidFrom, req.ValidUntil); err != nil {
+		return nil, gerror.Wrap(err, "invalid validity window")
+	}
+
+	// 知识库所属项目已达到向量存储配额时拒绝上传
+	if err = quota.CheckStorage(ctx, req.KnowledgeId); err != nil {
+		return nil, err
+	}
+
 	// Get storage type
 	storageType := file_store.GetStorageType()
 
@@ -49,6 +74,11 @@ func (c *ControllerV1) uploadToRustFS(ctx context.Context, req *v1.UploadFileReq
 		res.Message = "Failed to process file upload pre-steps: " + err.Error()
 		return res, err
 	}
+	if err = checkFieldMapping(req.FieldMapping, fileExt); err != nil {
+		res.Status = "failed"
+		res.Message = err.Error()
+		return res, err
+	}
 	defer func() {
 		if closer, ok := fileReader.(io.Closer); ok {
 			_ = closer.Close()
@@ -96,6 +126,20 @@ func (c *ControllerV1) uploadToRustFS(ctx context.Context, req *v1.UploadFileReq
 		RustfsLocation: rustfsKey,
 		LocalFilePath:  localPath, // Save local file path
 		Status:         int(v1.StatusPending),
+		SecurityLabel:  req.SecurityLabel,
+		SectionLabels:  req.SectionLabels,
+		FieldMapping:   req.FieldMapping,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+	}
+
+	// 同名文件重新上传时链接到历史版本
+	if err = knowledge.LinkPreviousVersion(ctx, &documents); err != nil {
+		g.Log().Errorf(ctx, "Failed to link document version: %v", err)
+		res.Status = "failed"
+		res.Message = "Failed to link document version: " + err.Error()
+		_ = gfile.Remove(localPath)
+		return res, err
 	}
 
 	// Save to database
@@ -109,6 +153,7 @@ func (c *ControllerV1) uploadToRustFS(ctx context.Context, req *v1.UploadFileReq
 		return res, err
 	}
 	res.DocumentId = documents.Id
+	res.Version = documents.Version
 	res.Status = "success"
 	res.Message = "File uploaded successfully"
 	return res, nil
@@ -125,6 +170,11 @@ func (c *ControllerV1) uploadToLocal(ctx context.Context, req *v1.UploadFileReq)
 		res.Message = "Failed to process file: " + err.Error()
 		return res, err
 	}
+	if err = checkFieldMapping(req.FieldMapping, fileExt); err != nil {
+		res.Status = "failed"
+		res.Message = err.Error()
+		return res, err
+	}
 	defer func() {
 		if closer, ok := fileReader.(io.Closer); ok {
 			_ = closer.Close()
@@ -207,6 +257,20 @@ func (c *ControllerV1) uploadToLocal(ctx context.Context, req *v1.UploadFileReq)
 		SHA256:         fileSha256,
 		LocalFilePath:  finalPath,
 		Status:         int(v1.StatusPending),
+		SecurityLabel:  req.SecurityLabel,
+		SectionLabels:  req.SectionLabels,
+		FieldMapping:   req.FieldMapping,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+	}
+
+	// 同名文件重新上传时链接到历史版本
+	if err = knowledge.LinkPreviousVersion(ctx, &documents); err != nil {
+		g.Log().Errorf(ctx, "Failed to link document version: %v", err)
+		res.Status = "failed"
+		res.Message = "Failed to link document version: " + err.Error()
+		_ = gfile.Remove(finalPath)
+		return res, err
 	}
 
 	// Save to database
@@ -220,7 +284,16 @@ func (c *ControllerV1) uploadToLocal(ctx context.Context, req *v1.UploadFileReq)
 		return res, err
 	}
 	res.DocumentId = documents.Id
+	res.Version = documents.Version
 	res.Status = "success"
 	res.Message = "File uploaded successfully"
 	return res, nil
 }
+
+// checkFieldMapping 字段映射只适用于 CSV 和 JSON Lines 文件
+func checkFieldMapping(fieldMapping, fileExt string) error {
+	if fieldMapping == "" || indexer.IsStructuredExt(fileExt) {
+		return nil
+	}
+	return gerror.Newf("field_mapping is only supported for csv and jsonl files, got %s", fileExt)
+}
@@ -0,0 +1,33 @@
+package kbgo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/usage"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// Usage 查询按天、按用户、按模型的 token 用量
+func (c *ControllerV1) Usage(ctx context.Context, req *v1.UsageReq) (res *v1.UsageRes, err error) {
+	g.Log().Infof(ctx, "Usage request received - StartDate: %s, EndDate: %s, UserID: %s, ModelID: %s",
+		req.StartDate, req.EndDate, req.UserID, req.ModelID)
+
+	return usage.Query(ctx, req)
+}
+
+// UsageRollup 手动重新汇总指定日期的 token 用量
+func (c *ControllerV1) UsageRollup(ctx context.Context, req *v1.UsageRollupReq) (res *v1.UsageRollupRes, err error) {
+	g.Log().Infof(ctx, "UsageRollup request received - Date: %s", req.Date)
+
+	day, err := time.ParseInLocation(usage.DateLayout, req.Date, time.Local)
+	if err != nil {
+		return nil, gerror.Newf("invalid date: %s", req.Date)
+	}
+	if err = usage.RunRollup(ctx, day); err != nil {
+		return nil, err
+	}
+	return &v1.UsageRollupRes{Success: true}, nil
+}
@@ -0,0 +1,368 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// AnalyticsDAO 统计分析数据访问对象
+type AnalyticsDAO struct{}
+
+var Analytics = &AnalyticsDAO{}
+
+// ConversationStatRow 会话维度的原始统计行（汇总任务使用）
+type ConversationStatRow struct {
+	ModelName         string
+	ConversationCount int64
+}
+
+// MessageStatRow 消息维度的原始统计行（汇总任务使用）
+type MessageStatRow struct {
+	ModelName      string
+	MessageCount   int64
+	AssistantCount int64
+	TotalTokens    int64
+	AvgLatencyMs   float64
+}
+
+// ToolStatRow 工具维度的原始统计行（汇总任务使用）
+type ToolStatRow struct {
+	ServiceName string
+	ToolName    string
+	CallCount   int64
+	FailedCount int64
+	AvgDuration float64
+}
+
+// CountActiveConversations 统计时间段内有消息的会话数（按模型分组）
+func (d *AnalyticsDAO) CountActiveConversations(ctx context.Context, start, end time.Time) ([]*ConversationStatRow, error) {
+	var rows []*ConversationStatRow
+	err := GetDB().WithContext(ctx).Table("messages m").
+		Select("c.model_name AS model_name, COUNT(DISTINCT m.conv_id) AS conversation_count").
+		Joins("JOIN conversations c ON c.conv_id = m.conv_id").
+		Where("m.create_time >= ? AND m.create_time < ?", start, end).
+		Group("c.model_name").
+		Scan(&rows).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计活跃会话失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// AggregateMessages 统计时间段内的消息数、token 与延迟（按模型分组）
+func (d *AnalyticsDAO) AggregateMessages(ctx context.Context, start, end time.Time) ([]*MessageStatRow, error) {
+	var rows []*MessageStatRow
+	err := GetDB().WithContext(ctx).Table("messages m").
+		Select("c.model_name AS model_name, "+
+			"COUNT(*) AS message_count, "+
+			"SUM(CASE WHEN m.role = 'assistant' THEN 1 ELSE 0 END) AS assistant_count, "+
+			"COALESCE(SUM(m.tokens_used), 0) AS total_tokens, "+
+			"COALESCE(AVG(CASE WHEN m.role = 'assistant' AND m.latency_ms > 0 THEN m.latency_ms END), 0) AS avg_latency_ms").
+		Joins("JOIN conversations c ON c.conv_id = m.conv_id").
+		Where("m.create_time >= ? AND m.create_time < ?", start, end).
+		Group("c.model_name").
+		Scan(&rows).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计消息失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ListMessageMetadata 获取时间段内带元数据的助手消息（用于统计反馈）
+func (d *AnalyticsDAO) ListMessageMetadata(ctx context.Context, start, end time.Time) ([]*gormModel.Message, error) {
+	var messages []*gormModel.Message
+	err := GetDB().WithContext(ctx).Model(&gormModel.Message{}).
+		Select("msg_id, conv_id, metadata, create_time").
+		Where("role = ? AND create_time >= ? AND create_time < ? AND metadata IS NOT NULL", "assistant", start, end).
+		Find(&messages).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询消息元数据失败: %v", err)
+		return nil, err
+	}
+	return messages, nil
+}
+
+// ListAssistantMetrics 获取时间段内助手消息的指标和元数据（用于统计实验分组）
+func (d *AnalyticsDAO) ListAssistantMetrics(ctx context.Context, start, end time.Time) ([]*gormModel.Message, error) {
+	var messages []*gormModel.Message
+	err := GetDB().WithContext(ctx).Model(&gormModel.Message{}).
+		Select("msg_id, conv_id, tokens_used, latency_ms, metadata, create_time").
+		Where("role = ? AND create_time >= ? AND create_time < ? AND metadata IS NOT NULL", "assistant", start, end).
+		Find(&messages).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询助手消息指标失败: %v", err)
+		return nil, err
+	}
+	return messages, nil
+}
+
+// MapConversationModels 获取会话ID到模型名称的映射
+func (d *AnalyticsDAO) MapConversationModels(ctx context.Context, convIDs []string) (map[string]string, error) {
+	result := make(map[string]string, len(convIDs))
+	if len(convIDs) == 0 {
+		return result, nil
+	}
+	var conversations []*gormModel.Conversation
+	if err := GetDB().WithContext(ctx).Select("conv_id, model_name").Where("conv_id IN ?", convIDs).Find(&conversations).Error; err != nil {
+		g.Log().Errorf(ctx, "查询会话模型失败: %v", err)
+		return nil, err
+	}
+	for _, c := range conversations {
+		result[c.ConvID] = c.ModelName
+	}
+	return result, nil
+}
+
+// AggregateToolCalls 统计时间段内的 MCP 工具调用（按服务和工具分组）
+func (d *AnalyticsDAO) AggregateToolCalls(ctx context.Context, start, end time.Time) ([]*ToolStatRow, error) {
+	var rows []*ToolStatRow
+	err := GetDB().WithContext(ctx).Model(&gormModel.MCPCallLog{}).
+		Select("mcp_service_name AS service_name, tool_name, "+
+			"COUNT(*) AS call_count, "+
+			"SUM(CASE WHEN status <> 1 THEN 1 ELSE 0 END) AS failed_count, "+
+			"COALESCE(AVG(duration), 0) AS avg_duration").
+		Where("create_time >= ? AND create_time < ?", start, end).
+		Group("mcp_service_name, tool_name").
+		Scan(&rows).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计工具调用失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// CountToolCallsByModel 统计时间段内各模型会话触发的工具调用次数
+func (d *AnalyticsDAO) CountToolCallsByModel(ctx context.Context, start, end time.Time) (map[string]int64, error) {
+	var rows []struct {
+		ModelName string
+		CallCount int64
+	}
+	err := GetDB().WithContext(ctx).Table("mcp_call_log l").
+		Select("c.model_name AS model_name, COUNT(*) AS call_count").
+		Joins("JOIN conversations c ON c.conv_id = l.conversation_id").
+		Where("l.create_time >= ? AND l.create_time < ?", start, end).
+		Group("c.model_name").
+		Scan(&rows).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计模型工具调用失败: %v", err)
+		return nil, err
+	}
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.ModelName] = row.CallCount
+	}
+	return result, nil
+}
+
+// ListRetrievalMisses 获取时间段内的低分检索记录
+func (d *AnalyticsDAO) ListRetrievalMisses(ctx context.Context, start, end time.Time) ([]*gormModel.RetrievalMissLog, error) {
+	var logs []*gormModel.RetrievalMissLog
+	if err := GetDB().WithContext(ctx).Where("create_time >= ? AND create_time < ?", start, end).Find(&logs).Error; err != nil {
+		g.Log().Errorf(ctx, "查询低分检索记录失败: %v", err)
+		return nil, err
+	}
+	return logs, nil
+}
+
+// CreateRetrievalMiss 记录一次低分检索
+func (d *AnalyticsDAO) CreateRetrievalMiss(ctx context.Context, log *gormModel.RetrievalMissLog) error {
+	if err := GetDB().WithContext(ctx).Create(log).Error; err != nil {
+		g.Log().Errorf(ctx, "记录低分检索失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ReplaceRollups 以事务方式替换指定日期的全部汇总数据
+func (d *AnalyticsDAO) ReplaceRollups(ctx context.Context, statDate string,
+	daily []*gormModel.AnalyticsDailyRollup,
+	tools []*gormModel.AnalyticsToolRollup,
+	unanswered []*gormModel.AnalyticsUnansweredRollup) error {
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("stat_date = ?", statDate).Delete(&gormModel.AnalyticsDailyRollup{}).Error; err != nil {
+			g.Log().Errorf(ctx, "清理会话汇总失败: %v", err)
+			return err
+		}
+		if err := tx.Where("stat_date = ?", statDate).Delete(&gormModel.AnalyticsToolRollup{}).Error; err != nil {
+			g.Log().Errorf(ctx, "清理工具汇总失败: %v", err)
+			return err
+		}
+		if err := tx.Where("stat_date = ?", statDate).Delete(&gormModel.AnalyticsUnansweredRollup{}).Error; err != nil {
+			g.Log().Errorf(ctx, "清理未解答问题汇总失败: %v", err)
+			return err
+		}
+		if len(daily) > 0 {
+			if err := tx.Create(&daily).Error; err != nil {
+				g.Log().Errorf(ctx, "写入会话汇总失败: %v", err)
+				return err
+			}
+		}
+		if len(tools) > 0 {
+			if err := tx.Create(&tools).Error; err != nil {
+				g.Log().Errorf(ctx, "写入工具汇总失败: %v", err)
+				return err
+			}
+		}
+		if len(unanswered) > 0 {
+			if err := tx.Create(&unanswered).Error; err != nil {
+				g.Log().Errorf(ctx, "写入未解答问题汇总失败: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListDailyRollups 查询日期区间内的会话汇总
+func (d *AnalyticsDAO) ListDailyRollups(ctx context.Context, startDate, endDate, modelName string) ([]*gormModel.AnalyticsDailyRollup, error) {
+	var rows []*gormModel.AnalyticsDailyRollup
+	query := GetDB().WithContext(ctx).Where("stat_date >= ? AND stat_date <= ?", startDate, endDate)
+	if modelName != "" {
+		query = query.Where("model_name = ?", modelName)
+	}
+	if err := query.Order("stat_date ASC, model_name ASC").Find(&rows).Error; err != nil {
+		g.Log().Errorf(ctx, "查询会话汇总失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ListToolRollups 查询日期区间内的工具汇总
+func (d *AnalyticsDAO) ListToolRollups(ctx context.Context, startDate, endDate string) ([]*gormModel.AnalyticsToolRollup, error) {
+	var rows []*gormModel.AnalyticsToolRollup
+	if err := GetDB().WithContext(ctx).
+		Where("stat_date >= ? AND stat_date <= ?", startDate, endDate).
+		Order("stat_date ASC, call_count DESC").
+		Find(&rows).Error; err != nil {
+		g.Log().Errorf(ctx, "查询工具汇总失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ListUnansweredRollups 查询日期区间内的未解答问题汇总
+func (d *AnalyticsDAO) ListUnansweredRollups(ctx context.Context, startDate, endDate, knowledgeID string) ([]*gormModel.AnalyticsUnansweredRollup, error) {
+	var rows []*gormModel.AnalyticsUnansweredRollup
+	query := GetDB().WithContext(ctx).Where("stat_date >= ? AND stat_date <= ?", startDate, endDate)
+	if knowledgeID != "" {
+		query = query.Where("knowledge_id = ?", knowledgeID)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		g.Log().Errorf(ctx, "查询未解答问题汇总失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// MapChunkContents 获取启用状态分片的内容（分片ID -> 内容），knowledgeID 不为空时只返回该知识库的分片
+func (d *AnalyticsDAO) MapChunkContents(ctx context.Context, chunkIDs []string, knowledgeID string) (map[string]string, error) {
+	result := make(map[string]string, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return result, nil
+	}
+	query := GetDB().WithContext(ctx).Model(&gormModel.KnowledgeChunks{}).
+		Select("knowledge_chunks.id, knowledge_chunks.content").
+		Where("knowledge_chunks.id IN ? AND knowledge_chunks.status = ?", chunkIDs, 1)
+	if knowledgeID != "" {
+		query = query.Joins("JOIN knowledge_documents ON knowledge_documents.id = knowledge_chunks.knowledge_doc_id").
+			Where("knowledge_documents.knowledge_id = ?", knowledgeID)
+	}
+	var chunks []*gormModel.KnowledgeChunks
+	if err := query.Find(&chunks).Error; err != nil {
+		g.Log().Errorf(ctx, "查询分片内容失败: %v", err)
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		result[chunk.ID] = chunk.Content
+	}
+	return result, nil
+}
+
+// MapChunkKnowledgeIDs 查询分片所属的知识库，返回 分片ID -> 知识库ID
+func (d *AnalyticsDAO) MapChunkKnowledgeIDs(ctx context.Context, chunkIDs []string) (map[string]string, error) {
+	result := make(map[string]string, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		ID          string
+		KnowledgeID string
+	}
+	err := GetDB().WithContext(ctx).Model(&gormModel.KnowledgeChunks{}).
+		Select("knowledge_chunks.id AS id, knowledge_documents.knowledge_id AS knowledge_id").
+		Joins("JOIN knowledge_documents ON knowledge_documents.id = knowledge_chunks.knowledge_doc_id").
+		Where("knowledge_chunks.id IN ?", chunkIDs).
+		Scan(&rows).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询分片所属知识库失败: %v", err)
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ID] = row.KnowledgeID
+	}
+	return result, nil
+}
+
+// GetPrecedingUserText 获取会话中某时间点之前最近一条用户消息的文本
+func (d *AnalyticsDAO) GetPrecedingUserText(ctx context.Context, convID string, before time.Time) (string, error) {
+	var texts []string
+	err := GetDB().WithContext(ctx).Table("messages m").
+		Joins("JOIN message_contents mc ON mc.msg_id = m.msg_id").
+		Where("m.conv_id = ? AND m.role = ? AND m.create_time <= ? AND mc.content_type = ?", convID, "user", before, "text").
+		Order("m.create_time DESC, mc.sort_order ASC").
+		Limit(1).
+		Pluck("mc.text_content", &texts).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询用户问题失败: %v", err)
+		return "", err
+	}
+	if len(texts) == 0 {
+		return "", nil
+	}
+	return texts[0], nil
+}
+
+// CreateKnowledgeGaps 批量写入知识缺口报告
+func (d *AnalyticsDAO) CreateKnowledgeGaps(ctx context.Context, gaps []*gormModel.KnowledgeGap) error {
+	if len(gaps) == 0 {
+		return nil
+	}
+	if err := GetDB().WithContext(ctx).Create(&gaps).Error; err != nil {
+		g.Log().Errorf(ctx, "写入知识缺口报告失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetLatestGapBatchID 获取最近一次生成的知识缺口批次ID
+func (d *AnalyticsDAO) GetLatestGapBatchID(ctx context.Context) (string, error) {
+	var gap gormModel.KnowledgeGap
+	if err := GetDB().WithContext(ctx).Order("create_time DESC, id DESC").First(&gap).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		g.Log().Errorf(ctx, "查询知识缺口批次失败: %v", err)
+		return "", err
+	}
+	return gap.BatchID, nil
+}
+
+// ListKnowledgeGaps 查询指定批次的知识缺口报告
+func (d *AnalyticsDAO) ListKnowledgeGaps(ctx context.Context, batchID, knowledgeID string) ([]*gormModel.KnowledgeGap, error) {
+	var gaps []*gormModel.KnowledgeGap
+	query := GetDB().WithContext(ctx).Where("batch_id = ?", batchID)
+	if knowledgeID != "" {
+		query = query.Where("knowledge_id = ?", knowledgeID)
+	}
+	if err := query.Order("question_count DESC").Find(&gaps).Error; err != nil {
+		g.Log().Errorf(ctx, "查询知识缺口报告失败: %v", err)
+		return nil, err
+	}
+	return gaps, nil
+}
@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// AnswerDiffDAO 回答差异数据访问对象
+type AnswerDiffDAO struct{}
+
+var AnswerDiff = &AnswerDiffDAO{}
+
+// Create 保存回答差异
+func (d *AnswerDiffDAO) Create(ctx context.Context, diff *gormModel.AnswerDiff) error {
+	if err := GetDB().WithContext(ctx).Create(diff).Error; err != nil {
+		g.Log().Errorf(ctx, "保存回答差异失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListByMsgID 获取消息作为原回答或新回答参与的差异，按创建时间排序
+func (d *AnswerDiffDAO) ListByMsgID(ctx context.Context, msgID string) ([]*gormModel.AnswerDiff, error) {
+	var diffs []*gormModel.AnswerDiff
+	if err := GetDB().WithContext(ctx).Where("msg_id = ? OR base_msg_id = ?", msgID, msgID).Order("create_time").Find(&diffs).Error; err != nil {
+		g.Log().Errorf(ctx, "查询消息的回答差异失败: %v", err)
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// List 分页查询回答差异，按创建时间倒序
+func (d *AnswerDiffDAO) List(ctx context.Context, convID, source, shadowResultID string, page, pageSize int) ([]*gormModel.AnswerDiff, int64, error) {
+	var diffs []*gormModel.AnswerDiff
+	var total int64
+
+	query := GetDB().WithContext(ctx).Model(&gormModel.AnswerDiff{})
+	if convID != "" {
+		query = query.Where("conv_id = ?", convID)
+	}
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if shadowResultID != "" {
+		query = query.Where("shadow_result_id = ?", shadowResultID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		g.Log().Errorf(ctx, "统计回答差异失败: %v", err)
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("create_time DESC").Offset(offset).Limit(pageSize).Find(&diffs).Error; err != nil {
+		g.Log().Errorf(ctx, "查询回答差异失败: %v", err)
+		return nil, 0, err
+	}
+	return diffs, total, nil
+}
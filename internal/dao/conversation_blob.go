@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// ConversationBlobDAO 会话元数据转存字段数据访问对象
+type ConversationBlobDAO struct{}
+
+var ConversationBlob = &ConversationBlobDAO{}
+
+// Put 保存会话元数据字段，替换该会话同名字段已有的内容
+func (d *ConversationBlobDAO) Put(ctx context.Context, blob *gormModel.ConversationBlob) error {
+	err := GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conv_id = ? AND meta_key = ?", blob.ConvID, blob.MetaKey).Delete(&gormModel.ConversationBlob{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(blob).Error
+	})
+	if err != nil {
+		g.Log().Errorf(ctx, "保存会话元数据字段失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Get 获取会话元数据字段，不存在时返回 nil
+func (d *ConversationBlobDAO) Get(ctx context.Context, convID, key string) (*gormModel.ConversationBlob, error) {
+	var blob gormModel.ConversationBlob
+	if err := GetDB().WithContext(ctx).Where("conv_id = ? AND meta_key = ?", convID, key).First(&blob).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询会话元数据字段失败: %v", err)
+		return nil, err
+	}
+	return &blob, nil
+}
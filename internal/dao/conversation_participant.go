@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// ConversationParticipantDAO 会话参与者数据访问对象
+type ConversationParticipantDAO struct{}
+
+var ConversationParticipant = &ConversationParticipantDAO{}
+
+// List 获取会话参与者
+func (d *ConversationParticipantDAO) List(ctx context.Context, convID string) ([]*gormModel.ConversationParticipant, error) {
+	var participants []*gormModel.ConversationParticipant
+	if err := GetDB().WithContext(ctx).Where("conv_id = ?", convID).Order("create_time ASC").Find(&participants).Error; err != nil {
+		g.Log().Errorf(ctx, "查询会话参与者失败: %v", err)
+		return nil, err
+	}
+	return participants, nil
+}
+
+// Save 添加会话参与者或更新参与者角色
+func (d *ConversationParticipantDAO) Save(ctx context.Context, participant *gormModel.ConversationParticipant) error {
+	var existing gormModel.ConversationParticipant
+	err := GetDB().WithContext(ctx).Where("conv_id = ? AND user_id = ?", participant.ConvID, participant.UserID).First(&existing).Error
+	switch {
+	case err == nil:
+		participant.ID = existing.ID
+		participant.CreateTime = existing.CreateTime
+		err = GetDB().WithContext(ctx).Save(participant).Error
+	case err == gorm.ErrRecordNotFound:
+		err = GetDB().WithContext(ctx).Create(participant).Error
+	}
+	if err != nil {
+		g.Log().Errorf(ctx, "保存会话参与者失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Remove 移除会话参与者，返回是否存在该参与者
+func (d *ConversationParticipantDAO) Remove(ctx context.Context, convID, userID string) (bool, error) {
+	result := GetDB().WithContext(ctx).Where("conv_id = ? AND user_id = ?", convID, userID).Delete(&gormModel.ConversationParticipant{})
+	if result.Error != nil {
+		g.Log().Errorf(ctx, "移除会话参与者失败: %v", result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
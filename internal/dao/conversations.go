@@ -2,6 +2,7 @@ package dao
 
 import (
 	"context"
+	"time"
 
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/gogf/gf/v2/frame/g"
@@ -84,3 +85,61 @@ func (d *ConversationDAO) UpdateMetadata(ctx context.Context, convID string, met
 	}
 	return nil
 }
+
+// UpdateModel 更新会话使用的模型
+func (d *ConversationDAO) UpdateModel(ctx context.Context, convID string, modelID string, modelName string) error {
+	if err := GetDB().WithContext(ctx).Model(&gormModel.Conversation{}).Where("conv_id = ?", convID).Updates(map[string]interface{}{
+		"model_id":    modelID,
+		"model_name":  modelName,
+		"update_time": time.Now(),
+	}).Error; err != nil {
+		g.Log().Errorf(ctx, "更新会话模型失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteWithMessages 删除会话及其全部消息、内容块、元数据转存字段和参与者
+func (d *ConversationDAO) DeleteWithMessages(ctx context.Context, convID string) error {
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		msgIDs := tx.Model(&gormModel.Message{}).Select("msg_id").Where("conv_id = ?", convID)
+		if err := tx.Where("msg_id IN (?)", msgIDs).Delete(&gormModel.MessageContent{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除会话消息内容块失败: %v", err)
+			return err
+		}
+		if err := tx.Where("conv_id = ?", convID).Delete(&gormModel.Message{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除会话消息失败: %v", err)
+			return err
+		}
+		if err := tx.Where("conv_id = ?", convID).Delete(&gormModel.ConversationBlob{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除会话元数据转存字段失败: %v", err)
+			return err
+		}
+		if err := tx.Where("conv_id = ?", convID).Delete(&gormModel.ConversationParticipant{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除会话参与者失败: %v", err)
+			return err
+		}
+		if err := tx.Where("conv_id = ?", convID).Delete(&gormModel.Conversation{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除会话失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// TopModelIDs 按会话数排序返回 since 之后有更新的会话中最常用的模型ID（最多 limit 个）
+func (d *ConversationDAO) TopModelIDs(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := GetDB().WithContext(ctx).Model(&gormModel.Conversation{}).
+		Select("model_id").
+		Where("update_time >= ? AND model_id <> ''", since).
+		Group("model_id").
+		Order("COUNT(*) DESC").
+		Limit(limit).
+		Pluck("model_id", &ids).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计常用模型失败: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
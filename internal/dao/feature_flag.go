@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// FeatureFlagDAO 功能开关数据访问对象
+type FeatureFlagDAO struct{}
+
+var FeatureFlag = &FeatureFlagDAO{}
+
+// List 获取全部功能开关设置
+func (d *FeatureFlagDAO) List(ctx context.Context) ([]*gormModel.FeatureFlag, error) {
+	var flags []*gormModel.FeatureFlag
+	if err := GetDB().WithContext(ctx).Order("name ASC, project_id ASC").Find(&flags).Error; err != nil {
+		g.Log().Errorf(ctx, "查询功能开关失败: %v", err)
+		return nil, err
+	}
+	return flags, nil
+}
+
+// Save 保存功能开关在全局或某个项目的设置，已存在时覆盖
+func (d *FeatureFlagDAO) Save(ctx context.Context, flag *gormModel.FeatureFlag) error {
+	var existing gormModel.FeatureFlag
+	err := GetDB().WithContext(ctx).Where("name = ? AND project_id = ?", flag.Name, flag.ProjectID).First(&existing).Error
+	switch {
+	case err == nil:
+		flag.ID = existing.ID
+		flag.CreateTime = existing.CreateTime
+		err = GetDB().WithContext(ctx).Save(flag).Error
+	case err == gorm.ErrRecordNotFound:
+		err = GetDB().WithContext(ctx).Create(flag).Error
+	}
+	if err != nil {
+		g.Log().Errorf(ctx, "保存功能开关失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除功能开关在全局或某个项目的设置
+func (d *FeatureFlagDAO) Delete(ctx context.Context, name, projectID string) error {
+	if err := GetDB().WithContext(ctx).Delete(&gormModel.FeatureFlag{}, "name = ? AND project_id = ?", name, projectID).Error; err != nil {
+		g.Log().Errorf(ctx, "删除功能开关失败: %v", err)
+		return err
+	}
+	return nil
+}
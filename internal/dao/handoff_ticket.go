@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// HandoffTicketDAO 人工接管工单数据访问对象
+type HandoffTicketDAO struct{}
+
+var HandoffTicket = &HandoffTicketDAO{}
+
+// Create 创建工单
+func (d *HandoffTicketDAO) Create(ctx context.Context, ticket *gormModel.HandoffTicket) error {
+	if err := GetDB().WithContext(ctx).Create(ticket).Error; err != nil {
+		g.Log().Errorf(ctx, "创建人工接管工单失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取工单，不存在时返回 nil
+func (d *HandoffTicketDAO) GetByID(ctx context.Context, id string) (*gormModel.HandoffTicket, error) {
+	var ticket gormModel.HandoffTicket
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&ticket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询人工接管工单失败: %v", err)
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// GetActiveByConvID 获取会话未结束的工单，不存在时返回 nil
+func (d *HandoffTicketDAO) GetActiveByConvID(ctx context.Context, convID string) (*gormModel.HandoffTicket, error) {
+	var ticket gormModel.HandoffTicket
+	err := GetDB().WithContext(ctx).
+		Where("conv_id = ? AND status <> ?", convID, gormModel.HandoffStatusResolved).
+		Order("create_time DESC").
+		First(&ticket).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询会话人工接管工单失败: %v", err)
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// List 获取工单列表，按创建时间倒序
+func (d *HandoffTicketDAO) List(ctx context.Context, convID, status string) ([]*gormModel.HandoffTicket, error) {
+	var tickets []*gormModel.HandoffTicket
+	db := GetDB().WithContext(ctx).Model(&gormModel.HandoffTicket{})
+	if convID != "" {
+		db = db.Where("conv_id = ?", convID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Order("create_time DESC").Find(&tickets).Error; err != nil {
+		g.Log().Errorf(ctx, "查询人工接管工单列表失败: %v", err)
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// Update 更新工单的指定字段
+func (d *HandoffTicketDAO) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	if err := GetDB().WithContext(ctx).Model(&gormModel.HandoffTicket{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		g.Log().Errorf(ctx, "更新人工接管工单失败: %v", err)
+		return err
+	}
+	return nil
+}
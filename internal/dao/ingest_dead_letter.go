@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IngestDeadLetterDAO 索引死信数据访问对象
+type IngestDeadLetterDAO struct{}
+
+var IngestDeadLetter = &IngestDeadLetterDAO{}
+
+// Save 写入死信，文档已有死信时覆盖失败信息并累加失败次数，返回保存后的记录
+func (d *IngestDeadLetterDAO) Save(ctx context.Context, letter *gormModel.IngestDeadLetter) (*gormModel.IngestDeadLetter, error) {
+	err := GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "document_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"knowledge_id": letter.KnowledgeID,
+			"file_name":    letter.FileName,
+			"step":         letter.Step,
+			"error":        letter.Error,
+			"attempts":     letter.Attempts,
+			"failures":     gorm.Expr("ingest_dead_letters.failures + 1"),
+			"model_id":     letter.ModelID,
+			"chunk_size":   letter.ChunkSize,
+			"overlap_size": letter.OverlapSize,
+			"separator":    letter.Separator,
+			"status":       letter.Status,
+			"update_time":  time.Now(),
+		}),
+	}).Create(letter).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "保存索引死信失败: %v", err)
+		return nil, err
+	}
+	return d.GetByDocumentID(ctx, letter.DocumentID)
+}
+
+// GetByID 根据ID获取死信，不存在时返回 nil
+func (d *IngestDeadLetterDAO) GetByID(ctx context.Context, id string) (*gormModel.IngestDeadLetter, error) {
+	var letter gormModel.IngestDeadLetter
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&letter).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询索引死信失败: %v", err)
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// GetByDocumentID 根据文档ID获取死信，不存在时返回 nil
+func (d *IngestDeadLetterDAO) GetByDocumentID(ctx context.Context, documentID string) (*gormModel.IngestDeadLetter, error) {
+	var letter gormModel.IngestDeadLetter
+	if err := GetDB().WithContext(ctx).Where("document_id = ?", documentID).First(&letter).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询索引死信失败: %v", err)
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// List 分页获取死信列表，按更新时间倒序
+func (d *IngestDeadLetterDAO) List(ctx context.Context, knowledgeID, status string, page, pageSize int) ([]*gormModel.IngestDeadLetter, int64, error) {
+	var letters []*gormModel.IngestDeadLetter
+	var total int64
+	db := GetDB().WithContext(ctx).Model(&gormModel.IngestDeadLetter{})
+	if knowledgeID != "" {
+		db = db.Where("knowledge_id = ?", knowledgeID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		g.Log().Errorf(ctx, "统计索引死信失败: %v", err)
+		return nil, 0, err
+	}
+	if err := db.Order("update_time DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&letters).Error; err != nil {
+		g.Log().Errorf(ctx, "查询索引死信列表失败: %v", err)
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+// Update 更新死信的指定字段
+func (d *IngestDeadLetterDAO) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	if err := GetDB().WithContext(ctx).Model(&gormModel.IngestDeadLetter{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		g.Log().Errorf(ctx, "更新索引死信失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteByDocumentID 删除文档的死信（文档重新索引成功后调用）
+func (d *IngestDeadLetterDAO) DeleteByDocumentID(ctx context.Context, documentID string) error {
+	if err := GetDB().WithContext(ctx).Where("document_id = ?", documentID).Delete(&gormModel.IngestDeadLetter{}).Error; err != nil {
+		g.Log().Errorf(ctx, "删除索引死信失败: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// IngestHookConfigDAO 知识库索引预处理钩子配置数据访问对象
+type IngestHookConfigDAO struct{}
+
+var IngestHookConfig = &IngestHookConfigDAO{}
+
+// CreateVersion 以知识库当前最大版本号加一保存新版本
+func (d *IngestHookConfigDAO) CreateVersion(ctx context.Context, config *gormModel.IngestHookConfig) error {
+	err := GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&gormModel.IngestHookConfig{}).
+			Where("knowledge_id = ?", config.KnowledgeID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		config.Version = latest + 1
+		return tx.Create(config).Error
+	})
+	if err != nil {
+		g.Log().Errorf(ctx, "保存索引钩子配置失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetLatest 获取知识库当前生效（最新版本）的配置，没有配置时返回 nil
+func (d *IngestHookConfigDAO) GetLatest(ctx context.Context, knowledgeID string) (*gormModel.IngestHookConfig, error) {
+	var config gormModel.IngestHookConfig
+	if err := GetDB().WithContext(ctx).Where("knowledge_id = ?", knowledgeID).Order("version DESC").First(&config).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询索引钩子配置失败: %v", err)
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetByVersion 获取知识库指定版本的配置，不存在时返回 nil
+func (d *IngestHookConfigDAO) GetByVersion(ctx context.Context, knowledgeID string, version int) (*gormModel.IngestHookConfig, error) {
+	var config gormModel.IngestHookConfig
+	if err := GetDB().WithContext(ctx).Where("knowledge_id = ? AND version = ?", knowledgeID, version).First(&config).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询索引钩子配置版本失败: %v", err)
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ListByKnowledgeID 获取知识库的全部配置版本，按版本号倒序
+func (d *IngestHookConfigDAO) ListByKnowledgeID(ctx context.Context, knowledgeID string) ([]*gormModel.IngestHookConfig, error) {
+	var configs []*gormModel.IngestHookConfig
+	if err := GetDB().WithContext(ctx).Where("knowledge_id = ?", knowledgeID).Order("version DESC").Find(&configs).Error; err != nil {
+		g.Log().Errorf(ctx, "查询索引钩子配置版本列表失败: %v", err)
+		return nil, err
+	}
+	return configs, nil
+}
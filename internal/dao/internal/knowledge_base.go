@@ -20,26 +20,34 @@ type KnowledgeBaseDao struct {
 
 // KnowledgeBaseColumns defines and stores column names for the table knowledge_base.
 type KnowledgeBaseColumns struct {
-	Id             string // 主键ID
-	Name           string // 知识库名称
-	Description    string // 知识库描述
-	Category       string // 知识库分类
-	CollectionName string // milvus collection name
-	Status         string // 状态：1-启用,2-禁用
-	CreateTime     string // 创建时间
-	UpdateTime     string // 更新时间
+	Id                  string // 主键ID
+	Name                string // 知识库名称
+	Description         string // 知识库描述
+	Category            string // 知识库分类
+	CollectionName      string // milvus collection name
+	Status              string // 状态：1-启用,2-禁用
+	SparseModelId       string // 稀疏向量模型ID
+	SparseWeight        string // 稀疏检索融合权重
+	RecencyWeight       string // 新近度加权权重
+	RecencyHalfLifeDays string // 新近度半衰期（天）
+	CreateTime          string // 创建时间
+	UpdateTime          string // 更新时间
 }
 
 // knowledgeBaseColumns holds the columns for the table knowledge_base.
 var knowledgeBaseColumns = KnowledgeBaseColumns{
-	Id:             "id",
-	Name:           "name",
-	Description:    "description",
-	Category:       "category",
-	CollectionName: "collection_name",
-	Status:         "status",
-	CreateTime:     "create_time",
-	UpdateTime:     "update_time",
+	Id:                  "id",
+	Name:                "name",
+	Description:         "description",
+	Category:            "category",
+	CollectionName:      "collection_name",
+	Status:              "status",
+	SparseModelId:       "sparse_model_id",
+	SparseWeight:        "sparse_weight",
+	RecencyWeight:       "recency_weight",
+	RecencyHalfLifeDays: "recency_half_life_days",
+	CreateTime:          "create_time",
+	UpdateTime:          "update_time",
 }
 
 // NewKnowledgeBaseDao creates and returns a new DAO object for table data access.
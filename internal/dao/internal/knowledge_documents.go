@@ -21,32 +21,66 @@ type KnowledgeDocumentsDao struct {
 
 // KnowledgeDocumentsColumns defines and stores column names for the table knowledge_documents.
 type KnowledgeDocumentsColumns struct {
-	Id             string //
-	KnowledgeId    string //
-	FileName       string //
-	FileExtension  string // 文件后缀名
-	CollectionName string // milvus collection name
-	RustfsBucket   string // rustfs bucket
-	RustfsLocation string // rustfs location
-	LocalFilePath  string // local file path
-	Status         string //
-	CreateTime     string //
-	UpdateTime     string //
+	Id                   string //
+	KnowledgeId          string //
+	FileName             string //
+	FileExtension        string // 文件后缀名
+	CollectionName       string // milvus collection name
+	RustfsBucket         string // rustfs bucket
+	RustfsLocation       string // rustfs location
+	LocalFilePath        string // local file path
+	Status               string //
+	SecurityLabel        string // 文档安全标签
+	SectionLabels        string // 分段安全标签规则（JSON）
+	FieldMapping         string // 结构化数据字段映射（JSON）
+	ValidFrom            string // 生效时间
+	ValidUntil           string // 失效时间
+	VersionGroup         string // 版本链ID
+	Version              string // 版本号
+	PreviousId           string // 上一个版本的文档ID
+	SupersededAt         string // 被新版本取代的时间
+	EmbeddingModelId     string // 生成向量使用的 embedding 模型ID
+	EmbeddingFingerprint string // 生成向量时的 embedding 模型配置指纹
+	Toc                  string // 标题目录（JSON）
+	MetaTitle            string // LLM 提取的文档标题
+	MetaAuthor           string // LLM 提取的作者
+	MetaDate             string // LLM 提取的日期
+	MetaTopics           string // LLM 提取的主题（JSON）
+	MetaSummary          string // LLM 生成的摘要
+	CreateTime           string //
+	UpdateTime           string //
 }
 
 // knowledgeDocumentsColumns holds the columns for the table knowledge_documents.
 var knowledgeDocumentsColumns = KnowledgeDocumentsColumns{
-	Id:             "id",
-	KnowledgeId:    "knowledge_id",
-	FileName:       "file_name",
-	FileExtension:  "file_extension",  // 添加文件后缀名字段
-	CollectionName: "collection_name", // milvus collection name
-	RustfsBucket:   "rustfs_bucket",
-	RustfsLocation: "rustfs_location",
-	LocalFilePath:  "local_file_path",
-	Status:         "status",
-	CreateTime:     "create_time",
-	UpdateTime:     "update_time",
+	Id:                   "id",
+	KnowledgeId:          "knowledge_id",
+	FileName:             "file_name",
+	FileExtension:        "file_extension",  // 添加文件后缀名字段
+	CollectionName:       "collection_name", // milvus collection name
+	RustfsBucket:         "rustfs_bucket",
+	RustfsLocation:       "rustfs_location",
+	LocalFilePath:        "local_file_path",
+	Status:               "status",
+	SecurityLabel:        "security_label",
+	SectionLabels:        "section_labels",
+	FieldMapping:         "field_mapping",
+	ValidFrom:            "valid_from",
+	ValidUntil:           "valid_until",
+	VersionGroup:         "version_group",
+	Version:              "version",
+	PreviousId:           "previous_id",
+	SupersededAt:         "superseded_at",
+	EmbeddingModelId:     "embedding_model_id",
+	EmbeddingFingerprint: "embedding_fingerprint",
+	Toc:                  "toc",
+	MetaTitle:            "meta_title",
+	MetaAuthor:           "meta_author",
+	MetaDate:             "meta_date",
+	MetaTopics:           "meta_topics",
+	MetaSummary:          "meta_summary",
+	CreateTime:           "create_time",
+	UpdateTime:           "update_time",
 }
 
 // NewKnowledgeDocumentsDao creates and returns a new DAO object for table data access.
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// KBPromotionDAO 回答沉淀申请数据访问对象
+type KBPromotionDAO struct{}
+
+var KBPromotion = &KBPromotionDAO{}
+
+// Create 创建沉淀申请
+func (d *KBPromotionDAO) Create(ctx context.Context, promotion *gormModel.KBPromotion) error {
+	if err := GetDB().WithContext(ctx).Create(promotion).Error; err != nil {
+		g.Log().Errorf(ctx, "创建回答沉淀申请失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取沉淀申请，不存在时返回 nil
+func (d *KBPromotionDAO) GetByID(ctx context.Context, id string) (*gormModel.KBPromotion, error) {
+	var promotion gormModel.KBPromotion
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&promotion).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询回答沉淀申请失败: %v", err)
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// GetOpenByMsgID 获取消息沉淀到指定知识库的指定类型的待审核或已通过的申请，不存在时返回 nil
+func (d *KBPromotionDAO) GetOpenByMsgID(ctx context.Context, msgID, knowledgeID, kind string) (*gormModel.KBPromotion, error) {
+	var promotion gormModel.KBPromotion
+	err := GetDB().WithContext(ctx).
+		Where("msg_id = ? AND knowledge_id = ? AND kind = ? AND status <> ?", msgID, knowledgeID, kind, gormModel.PromotionStatusRejected).
+		Order("create_time DESC").
+		First(&promotion).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询消息沉淀申请失败: %v", err)
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// List 获取沉淀申请列表，按创建时间倒序
+func (d *KBPromotionDAO) List(ctx context.Context, knowledgeID, status, kind string) ([]*gormModel.KBPromotion, error) {
+	var promotions []*gormModel.KBPromotion
+	db := GetDB().WithContext(ctx).Model(&gormModel.KBPromotion{})
+	if knowledgeID != "" {
+		db = db.Where("knowledge_id = ?", knowledgeID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	if err := db.Order("create_time DESC").Find(&promotions).Error; err != nil {
+		g.Log().Errorf(ctx, "查询回答沉淀申请列表失败: %v", err)
+		return nil, err
+	}
+	return promotions, nil
+}
+
+// Update 更新沉淀申请的指定字段
+func (d *KBPromotionDAO) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	if err := GetDB().WithContext(ctx).Model(&gormModel.KBPromotion{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		g.Log().Errorf(ctx, "更新回答沉淀申请失败: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// KnowledgeProfileDAO 知识库内容画像数据访问对象
+type KnowledgeProfileDAO struct{}
+
+var KnowledgeProfile = &KnowledgeProfileDAO{}
+
+// Get 获取知识库的内容画像，不存在时返回 nil
+func (d *KnowledgeProfileDAO) Get(ctx context.Context, knowledgeID string) (*gormModel.KnowledgeProfile, error) {
+	var profile gormModel.KnowledgeProfile
+	err := GetDB().WithContext(ctx).Where("knowledge_id = ?", knowledgeID).First(&profile).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		g.Log().Errorf(ctx, "查询知识库画像失败: %v", err)
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Save 保存知识库的内容画像，已存在时覆盖
+func (d *KnowledgeProfileDAO) Save(ctx context.Context, profile *gormModel.KnowledgeProfile) error {
+	var existing gormModel.KnowledgeProfile
+	err := GetDB().WithContext(ctx).Where("knowledge_id = ?", profile.KnowledgeID).First(&existing).Error
+	switch {
+	case err == nil:
+		profile.CreateTime = existing.CreateTime
+		err = GetDB().WithContext(ctx).Save(profile).Error
+	case err == gorm.ErrRecordNotFound:
+		err = GetDB().WithContext(ctx).Create(profile).Error
+	}
+	if err != nil {
+		g.Log().Errorf(ctx, "保存知识库画像失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除知识库的内容画像
+func (d *KnowledgeProfileDAO) Delete(ctx context.Context, knowledgeID string) error {
+	if err := GetDB().WithContext(ctx).Delete(&gormModel.KnowledgeProfile{}, "knowledge_id = ?", knowledgeID).Error; err != nil {
+		g.Log().Errorf(ctx, "删除知识库画像失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// activeChunks 构造知识库中已索引文档的有效分片查询
+func (d *KnowledgeProfileDAO) activeChunks(ctx context.Context, knowledgeID string, documentStatus int) *gorm.DB {
+	return GetDB().WithContext(ctx).Model(&gormModel.KnowledgeChunks{}).
+		Joins("JOIN knowledge_documents ON knowledge_documents.id = knowledge_chunks.knowledge_doc_id").
+		Where("knowledge_documents.knowledge_id = ? AND knowledge_documents.status = ? AND knowledge_chunks.status = 1",
+			knowledgeID, documentStatus)
+}
+
+// CountChunks 统计知识库中已索引文档的有效分片数
+func (d *KnowledgeProfileDAO) CountChunks(ctx context.Context, knowledgeID string, documentStatus int) (int64, error) {
+	var count int64
+	if err := d.activeChunks(ctx, knowledgeID, documentStatus).Count(&count).Error; err != nil {
+		g.Log().Errorf(ctx, "统计知识库分片失败: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// SampleChunkContents 抽样获取知识库有效分片的内容，按分片ID（UUID）排序截取，近似随机抽样
+func (d *KnowledgeProfileDAO) SampleChunkContents(ctx context.Context, knowledgeID string, documentStatus int, limit int) ([]string, error) {
+	var contents []string
+	err := d.activeChunks(ctx, knowledgeID, documentStatus).
+		Order("knowledge_chunks.id ASC").
+		Limit(limit).
+		Pluck("knowledge_chunks.content", &contents).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "抽样知识库分片失败: %v", err)
+		return nil, err
+	}
+	return contents, nil
+}
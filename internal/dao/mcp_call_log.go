@@ -54,6 +54,20 @@ func (d *MCPCallLogDAO) ListByConversationID(ctx context.Context, conversationID
 	return logs, total, nil
 }
 
+// ListSucceededTools 查询对话中调用成功过的工具（去重，仅返回服务名和工具名）
+func (d *MCPCallLogDAO) ListSucceededTools(ctx context.Context, conversationID string) ([]*gormModel.MCPCallLog, error) {
+	var logs []*gormModel.MCPCallLog
+	err := GetDB().WithContext(ctx).Model(&gormModel.MCPCallLog{}).
+		Distinct("mcp_service_name", "tool_name").
+		Where("conversation_id = ? AND status = ?", conversationID, 1).
+		Find(&logs).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to list succeeded MCP tools: %v", err)
+		return nil, err
+	}
+	return logs, nil
+}
+
 // ListByMCPRegistry 根据MCP服务ID查询调用日志
 func (d *MCPCallLogDAO) ListByMCPRegistry(ctx context.Context, registryID string, page, pageSize int) ([]*gormModel.MCPCallLog, int64, error) {
 	var logs []*gormModel.MCPCallLog
@@ -188,3 +202,20 @@ type MCPCallStats struct {
 	FailedCalls  int64   // 失败次数
 	AvgDuration  float64 // 平均耗时（毫秒）
 }
+
+// TopRegistryIDs 按调用次数排序返回 since 之后调用最多的 MCP 服务ID（最多 limit 个）
+func (d *MCPCallLogDAO) TopRegistryIDs(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := GetDB().WithContext(ctx).Model(&gormModel.MCPCallLog{}).
+		Select("mcp_registry_id").
+		Where("create_time >= ?", since).
+		Group("mcp_registry_id").
+		Order("COUNT(*) DESC").
+		Limit(limit).
+		Pluck("mcp_registry_id", &ids).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to count MCP calls by service: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
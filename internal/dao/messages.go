@@ -57,6 +57,19 @@ func (d *MessageDAO) GetByMsgID(ctx context.Context, msgID string) (*gormModel.M
 	return &message, nil
 }
 
+// GetLatestByRole 获取会话中指定角色的最近一条消息，不存在时返回 nil
+func (d *MessageDAO) GetLatestByRole(ctx context.Context, convID, role string) (*gormModel.Message, error) {
+	var message gormModel.Message
+	if err := GetDB().WithContext(ctx).Where("conv_id = ? AND role = ?", convID, role).Order("create_time DESC").First(&message).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询会话最近消息失败: %v", err)
+		return nil, err
+	}
+	return &message, nil
+}
+
 // ListByConvID 根据会话ID获取消息列表
 func (d *MessageDAO) ListByConvID(ctx context.Context, convID string, page, pageSize int) ([]*gormModel.Message, int64, error) {
 	var messages []*gormModel.Message
@@ -112,6 +125,15 @@ func (d *MessageDAO) Update(ctx context.Context, message *gormModel.Message) err
 	return nil
 }
 
+// UpdateMetadata 更新消息元数据
+func (d *MessageDAO) UpdateMetadata(ctx context.Context, msgID string, metadata gormModel.JSON) error {
+	if err := GetDB().WithContext(ctx).Model(&gormModel.Message{}).Where("msg_id = ?", msgID).Update("metadata", metadata).Error; err != nil {
+		g.Log().Errorf(ctx, "更新消息元数据失败: %v", err)
+		return err
+	}
+	return nil
+}
+
 // Delete 删除消息
 func (d *MessageDAO) Delete(ctx context.Context, msgID string) error {
 	if err := GetDB().WithContext(ctx).Where("msg_id = ?", msgID).Delete(&gormModel.Message{}).Error; err != nil {
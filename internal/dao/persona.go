@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// PersonaDAO 回答人设预设数据访问对象
+type PersonaDAO struct{}
+
+var Persona = &PersonaDAO{}
+
+// Create 创建人设
+func (d *PersonaDAO) Create(ctx context.Context, persona *gormModel.Persona) error {
+	if err := GetDB().WithContext(ctx).Create(persona).Error; err != nil {
+		g.Log().Errorf(ctx, "创建人设失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 保存人设的全部字段
+func (d *PersonaDAO) Update(ctx context.Context, persona *gormModel.Persona) error {
+	if err := GetDB().WithContext(ctx).Save(persona).Error; err != nil {
+		g.Log().Errorf(ctx, "更新人设失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除人设
+func (d *PersonaDAO) Delete(ctx context.Context, id string) error {
+	if err := GetDB().WithContext(ctx).Delete(&gormModel.Persona{}, "id = ?", id).Error; err != nil {
+		g.Log().Errorf(ctx, "删除人设失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取人设，不存在时返回 nil
+func (d *PersonaDAO) GetByID(ctx context.Context, id string) (*gormModel.Persona, error) {
+	var persona gormModel.Persona
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&persona).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询人设失败: %v", err)
+		return nil, err
+	}
+	return &persona, nil
+}
+
+// List 获取全部人设，按名称排序
+func (d *PersonaDAO) List(ctx context.Context) ([]*gormModel.Persona, error) {
+	var personas []*gormModel.Persona
+	if err := GetDB().WithContext(ctx).Order("name ASC").Find(&personas).Error; err != nil {
+		g.Log().Errorf(ctx, "查询人设列表失败: %v", err)
+		return nil, err
+	}
+	return personas, nil
+}
+
+// NameExists 检查人设名称是否已被其他人设使用
+func (d *PersonaDAO) NameExists(ctx context.Context, name, excludeID string) (bool, error) {
+	var count int64
+	db := GetDB().WithContext(ctx).Model(&gormModel.Persona{}).Where("name = ?", name)
+	if excludeID != "" {
+		db = db.Where("id <> ?", excludeID)
+	}
+	if err := db.Count(&count).Error; err != nil {
+		g.Log().Errorf(ctx, "检查人设名称失败: %v", err)
+		return false, err
+	}
+	return count > 0, nil
+}
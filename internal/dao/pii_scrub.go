@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// PIIScrubDAO 个人信息脱敏任务数据访问对象
+type PIIScrubDAO struct{}
+
+var PIIScrub = &PIIScrubDAO{}
+
+// ListCandidates 按ID顺序获取 before 之前创建、尚未脱敏的消息，afterID 用于分批读取
+func (d *PIIScrubDAO) ListCandidates(ctx context.Context, before time.Time, afterID uint64, limit int) ([]*gormModel.Message, error) {
+	var messages []*gormModel.Message
+	err := GetDB().WithContext(ctx).
+		Where("create_time < ? AND pii_scrubbed_at IS NULL AND id > ?", before, afterID).
+		Order("id ASC").Limit(limit).Find(&messages).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询待脱敏消息失败: %v", err)
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Apply 在同一事务中保存脱敏后的消息元数据和内容块、标记消息已处理，并写入审计记录（log 为 nil 时不写）
+func (d *PIIScrubDAO) Apply(ctx context.Context, msg *gormModel.Message, contents []*gormModel.MessageContent, log *gormModel.PIIScrubLog, scrubbedAt time.Time) error {
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, content := range contents {
+			if err := tx.Model(&gormModel.MessageContent{}).Where("id = ?", content.ID).
+				Updates(map[string]interface{}{"text_content": content.TextContent, "metadata": content.Metadata}).Error; err != nil {
+				g.Log().Errorf(ctx, "保存脱敏后的消息内容失败: %v", err)
+				return err
+			}
+		}
+		if err := tx.Model(&gormModel.Message{}).Where("id = ?", msg.ID).
+			Updates(map[string]interface{}{"metadata": msg.Metadata, "pii_scrubbed_at": scrubbedAt}).Error; err != nil {
+			g.Log().Errorf(ctx, "保存脱敏后的消息失败: %v", err)
+			return err
+		}
+		if log != nil {
+			if err := tx.Create(log).Error; err != nil {
+				g.Log().Errorf(ctx, "写入脱敏审计记录失败: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MarkScrubbed 标记不含个人信息的消息已处理
+func (d *PIIScrubDAO) MarkScrubbed(ctx context.Context, ids []uint64, scrubbedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := GetDB().WithContext(ctx).Model(&gormModel.Message{}).Where("id IN ?", ids).
+		Update("pii_scrubbed_at", scrubbedAt).Error; err != nil {
+		g.Log().Errorf(ctx, "标记消息已脱敏失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListLogs 分页查询脱敏审计记录，按时间倒序
+func (d *PIIScrubDAO) ListLogs(ctx context.Context, runID, convID, projectID string, page, pageSize int) ([]*gormModel.PIIScrubLog, int64, error) {
+	var logs []*gormModel.PIIScrubLog
+	var total int64
+
+	query := GetDB().WithContext(ctx).Model(&gormModel.PIIScrubLog{})
+	if runID != "" {
+		query = query.Where("run_id = ?", runID)
+	}
+	if convID != "" {
+		query = query.Where("conv_id = ?", convID)
+	}
+	if projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		g.Log().Errorf(ctx, "统计脱敏审计记录失败: %v", err)
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		g.Log().Errorf(ctx, "查询脱敏审计记录失败: %v", err)
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
@@ -0,0 +1,243 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProjectDAO 项目数据访问对象
+type ProjectDAO struct{}
+
+var Project = &ProjectDAO{}
+
+// Create 创建项目
+func (d *ProjectDAO) Create(ctx context.Context, project *gormModel.Project) error {
+	if err := GetDB().WithContext(ctx).Create(project).Error; err != nil {
+		g.Log().Errorf(ctx, "创建项目失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 保存项目的全部字段
+func (d *ProjectDAO) Update(ctx context.Context, project *gormModel.Project) error {
+	if err := GetDB().WithContext(ctx).Save(project).Error; err != nil {
+		g.Log().Errorf(ctx, "更新项目失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除项目及其资源归属、成员和用量记录，资源本身不受影响
+func (d *ProjectDAO) Delete(ctx context.Context, id string) error {
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", id).Delete(&gormModel.ProjectResource{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除项目资源失败: %v", err)
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&gormModel.ProjectMember{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除项目成员失败: %v", err)
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&gormModel.ProjectUsage{}).Error; err != nil {
+			g.Log().Errorf(ctx, "删除项目用量失败: %v", err)
+			return err
+		}
+		if err := tx.Delete(&gormModel.Project{}, "id = ?", id).Error; err != nil {
+			g.Log().Errorf(ctx, "删除项目失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// GetByID 根据ID获取项目，不存在时返回 nil
+func (d *ProjectDAO) GetByID(ctx context.Context, id string) (*gormModel.Project, error) {
+	var project gormModel.Project
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&project).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询项目失败: %v", err)
+		return nil, err
+	}
+	return &project, nil
+}
+
+// List 获取项目列表，userID 不为空时只返回该用户参与的项目
+func (d *ProjectDAO) List(ctx context.Context, userID string) ([]*gormModel.Project, error) {
+	var projects []*gormModel.Project
+	db := GetDB().WithContext(ctx)
+	if userID != "" {
+		db = db.Where("id IN (?)", GetDB().Model(&gormModel.ProjectMember{}).Select("project_id").Where("user_id = ?", userID))
+	}
+	if err := db.Order("name ASC").Find(&projects).Error; err != nil {
+		g.Log().Errorf(ctx, "查询项目列表失败: %v", err)
+		return nil, err
+	}
+	return projects, nil
+}
+
+// NameExists 检查项目名称是否已被其他项目使用
+func (d *ProjectDAO) NameExists(ctx context.Context, name, excludeID string) (bool, error) {
+	var count int64
+	db := GetDB().WithContext(ctx).Model(&gormModel.Project{}).Where("name = ?", name)
+	if excludeID != "" {
+		db = db.Where("id <> ?", excludeID)
+	}
+	if err := db.Count(&count).Error; err != nil {
+		g.Log().Errorf(ctx, "检查项目名称失败: %v", err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddResources 把资源加入项目，已属于其他项目的资源移动到该项目
+func (d *ProjectDAO) AddResources(ctx context.Context, projectID, resourceType string, resourceIDs []string) error {
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("resource_type = ? AND resource_id IN ?", resourceType, resourceIDs).
+			Delete(&gormModel.ProjectResource{}).Error; err != nil {
+			g.Log().Errorf(ctx, "清理资源原项目归属失败: %v", err)
+			return err
+		}
+		resources := make([]*gormModel.ProjectResource, 0, len(resourceIDs))
+		for _, id := range resourceIDs {
+			resources = append(resources, &gormModel.ProjectResource{ProjectID: projectID, ResourceType: resourceType, ResourceID: id})
+		}
+		if err := tx.Create(&resources).Error; err != nil {
+			g.Log().Errorf(ctx, "添加项目资源失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// RemoveResources 把资源移出项目
+func (d *ProjectDAO) RemoveResources(ctx context.Context, projectID, resourceType string, resourceIDs []string) error {
+	err := GetDB().WithContext(ctx).
+		Where("project_id = ? AND resource_type = ? AND resource_id IN ?", projectID, resourceType, resourceIDs).
+		Delete(&gormModel.ProjectResource{}).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "移除项目资源失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListResources 获取项目资源，resourceType 为空时返回全部类型
+func (d *ProjectDAO) ListResources(ctx context.Context, projectID, resourceType string) ([]*gormModel.ProjectResource, error) {
+	var resources []*gormModel.ProjectResource
+	db := GetDB().WithContext(ctx).Where("project_id = ?", projectID)
+	if resourceType != "" {
+		db = db.Where("resource_type = ?", resourceType)
+	}
+	if err := db.Order("resource_type ASC, create_time ASC").Find(&resources).Error; err != nil {
+		g.Log().Errorf(ctx, "查询项目资源失败: %v", err)
+		return nil, err
+	}
+	return resources, nil
+}
+
+// GetResourceProject 获取资源所属的项目ID，不属于任何项目时返回空字符串
+func (d *ProjectDAO) GetResourceProject(ctx context.Context, resourceType, resourceID string) (string, error) {
+	var resource gormModel.ProjectResource
+	err := GetDB().WithContext(ctx).Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).First(&resource).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		g.Log().Errorf(ctx, "查询资源所属项目失败: %v", err)
+		return "", err
+	}
+	return resource.ProjectID, nil
+}
+
+// SaveMember 添加项目成员或更新成员角色
+func (d *ProjectDAO) SaveMember(ctx context.Context, member *gormModel.ProjectMember) error {
+	var existing gormModel.ProjectMember
+	err := GetDB().WithContext(ctx).Where("project_id = ? AND user_id = ?", member.ProjectID, member.UserID).First(&existing).Error
+	switch {
+	case err == nil:
+		member.ID = existing.ID
+		member.CreateTime = existing.CreateTime
+		err = GetDB().WithContext(ctx).Save(member).Error
+	case err == gorm.ErrRecordNotFound:
+		err = GetDB().WithContext(ctx).Create(member).Error
+	}
+	if err != nil {
+		g.Log().Errorf(ctx, "保存项目成员失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RemoveMember 移除项目成员，返回是否存在该成员
+func (d *ProjectDAO) RemoveMember(ctx context.Context, projectID, userID string) (bool, error) {
+	result := GetDB().WithContext(ctx).Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&gormModel.ProjectMember{})
+	if result.Error != nil {
+		g.Log().Errorf(ctx, "移除项目成员失败: %v", result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListMembers 获取项目成员
+func (d *ProjectDAO) ListMembers(ctx context.Context, projectID string) ([]*gormModel.ProjectMember, error) {
+	var members []*gormModel.ProjectMember
+	if err := GetDB().WithContext(ctx).Where("project_id = ?", projectID).Order("create_time ASC").Find(&members).Error; err != nil {
+		g.Log().Errorf(ctx, "查询项目成员失败: %v", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddUsage 累加项目在统计周期内的用量
+func (d *ProjectDAO) AddUsage(ctx context.Context, projectID, metric, period string, amount int64) error {
+	usage := &gormModel.ProjectUsage{ProjectID: projectID, Metric: metric, Period: period, Amount: amount}
+	err := GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "metric"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"amount": gorm.Expr("project_usage.amount + ?", amount)}),
+	}).Create(usage).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "累加项目用量失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetUsage 获取项目在统计周期内的用量，没有记录时返回 0
+func (d *ProjectDAO) GetUsage(ctx context.Context, projectID, metric, period string) (int64, error) {
+	var amount int64
+	err := GetDB().WithContext(ctx).Model(&gormModel.ProjectUsage{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("project_id = ? AND metric = ? AND period = ?", projectID, metric, period).
+		Scan(&amount).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询项目用量失败: %v", err)
+		return 0, err
+	}
+	return amount, nil
+}
+
+// KnowledgeStorage 统计项目知识库的分片数量和分片内容字节数
+func (d *ProjectDAO) KnowledgeStorage(ctx context.Context, projectID string) (chunks int64, contentBytes int64, err error) {
+	var row struct {
+		Chunks       int64
+		ContentBytes int64
+	}
+	err = GetDB().WithContext(ctx).Table("knowledge_chunks c").
+		Select("COUNT(*) AS chunks, COALESCE(SUM(OCTET_LENGTH(c.content)), 0) AS content_bytes").
+		Joins("JOIN knowledge_documents d ON d.id = c.knowledge_doc_id").
+		Joins("JOIN project_resources r ON r.resource_id = d.knowledge_id AND r.resource_type = ?", "knowledge_base").
+		Where("r.project_id = ?", projectID).
+		Scan(&row).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计项目知识库存储失败: %v", err)
+		return 0, 0, err
+	}
+	return row.Chunks, row.ContentBytes, nil
+}
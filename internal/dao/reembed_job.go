@@ -0,0 +1,117 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// ReembedJobDAO 重新向量化任务数据访问对象
+type ReembedJobDAO struct{}
+
+var ReembedJob = &ReembedJobDAO{}
+
+// Create 创建任务
+func (d *ReembedJobDAO) Create(ctx context.Context, job *gormModel.ReembedJob) error {
+	if err := GetDB().WithContext(ctx).Create(job).Error; err != nil {
+		g.Log().Errorf(ctx, "创建重新向量化任务失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取任务，不存在时返回 nil
+func (d *ReembedJobDAO) GetByID(ctx context.Context, id string) (*gormModel.ReembedJob, error) {
+	var job gormModel.ReembedJob
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询重新向量化任务失败: %v", err)
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 获取任务列表，按创建时间倒序
+func (d *ReembedJobDAO) List(ctx context.Context, modelID, status string) ([]*gormModel.ReembedJob, error) {
+	var jobs []*gormModel.ReembedJob
+	db := GetDB().WithContext(ctx).Model(&gormModel.ReembedJob{})
+	if modelID != "" {
+		db = db.Where("model_id = ?", modelID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Order("create_time DESC").Find(&jobs).Error; err != nil {
+		g.Log().Errorf(ctx, "查询重新向量化任务列表失败: %v", err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Update 更新任务的指定字段（进度和状态分别更新，避免并发的暂停操作被进度写入覆盖）
+func (d *ReembedJobDAO) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	if err := GetDB().WithContext(ctx).Model(&gormModel.ReembedJob{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		g.Log().Errorf(ctx, "更新重新向量化任务失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CancelUnfinished 取消同一模型、同一范围内未完成的任务，返回被取消的任务数
+func (d *ReembedJobDAO) CancelUnfinished(ctx context.Context, modelID, knowledgeID string) (int64, error) {
+	result := GetDB().WithContext(ctx).Model(&gormModel.ReembedJob{}).
+		Where("model_id = ? AND knowledge_id = ? AND status IN ?", modelID, knowledgeID,
+			[]string{gormModel.ReembedStatusRunning, gormModel.ReembedStatusPaused}).
+		Update("status", gormModel.ReembedStatusCanceled)
+	if result.Error != nil {
+		g.Log().Errorf(ctx, "取消重新向量化任务失败: %v", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// staleDocuments 构造需要重新向量化的文档查询：由该模型生成但指纹与目标不一致的文档
+// 限定知识库时，同时包含未记录 embedding 模型的历史文档；迁移任务包含知识库中由其他模型生成的全部文档
+func (d *ReembedJobDAO) staleDocuments(ctx context.Context, job *gormModel.ReembedJob, status int) *gorm.DB {
+	db := GetDB().WithContext(ctx).Model(&gormModel.KnowledgeDocuments{}).Where("status = ?", status)
+	if job.Migrate && job.KnowledgeID != "" {
+		return db.Where("knowledge_id = ? AND (embedding_model_id <> ? OR embedding_model_id IS NULL OR embedding_fingerprint <> ? OR embedding_fingerprint IS NULL)",
+			job.KnowledgeID, job.ModelID, job.Fingerprint)
+	}
+	if job.KnowledgeID != "" {
+		db = db.Where("knowledge_id = ? AND (embedding_model_id = ? OR embedding_model_id = '' OR embedding_model_id IS NULL)",
+			job.KnowledgeID, job.ModelID)
+	} else {
+		db = db.Where("embedding_model_id = ?", job.ModelID)
+	}
+	return db.Where("(embedding_fingerprint <> ? OR embedding_fingerprint IS NULL)", job.Fingerprint)
+}
+
+// CountStaleDocuments 统计需要重新向量化的文档数
+func (d *ReembedJobDAO) CountStaleDocuments(ctx context.Context, job *gormModel.ReembedJob, status int) (int64, error) {
+	var count int64
+	if err := d.staleDocuments(ctx, job, status).Count(&count).Error; err != nil {
+		g.Log().Errorf(ctx, "统计待重新向量化文档失败: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListStaleDocumentIDs 按文档ID顺序获取断点之后需要重新向量化的文档
+func (d *ReembedJobDAO) ListStaleDocumentIDs(ctx context.Context, job *gormModel.ReembedJob, status int, limit int) ([]string, error) {
+	var ids []string
+	err := d.staleDocuments(ctx, job, status).
+		Where("id > ?", job.LastDocumentID).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询待重新向量化文档失败: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
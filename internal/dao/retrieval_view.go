@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// RetrievalViewDAO 检索视图数据访问对象
+type RetrievalViewDAO struct{}
+
+var RetrievalView = &RetrievalViewDAO{}
+
+// Create 创建检索视图
+func (d *RetrievalViewDAO) Create(ctx context.Context, view *gormModel.RetrievalView) error {
+	if err := GetDB().WithContext(ctx).Create(view).Error; err != nil {
+		g.Log().Errorf(ctx, "创建检索视图失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 保存检索视图的全部字段
+func (d *RetrievalViewDAO) Update(ctx context.Context, view *gormModel.RetrievalView) error {
+	if err := GetDB().WithContext(ctx).Save(view).Error; err != nil {
+		g.Log().Errorf(ctx, "更新检索视图失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除检索视图
+func (d *RetrievalViewDAO) Delete(ctx context.Context, id string) error {
+	if err := GetDB().WithContext(ctx).Delete(&gormModel.RetrievalView{}, "id = ?", id).Error; err != nil {
+		g.Log().Errorf(ctx, "删除检索视图失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取检索视图，不存在时返回 nil
+func (d *RetrievalViewDAO) GetByID(ctx context.Context, id string) (*gormModel.RetrievalView, error) {
+	return d.first(ctx, "id = ?", id)
+}
+
+// GetByName 根据名称获取检索视图，不存在时返回 nil
+func (d *RetrievalViewDAO) GetByName(ctx context.Context, name string) (*gormModel.RetrievalView, error) {
+	return d.first(ctx, "name = ?", name)
+}
+
+func (d *RetrievalViewDAO) first(ctx context.Context, query string, arg string) (*gormModel.RetrievalView, error) {
+	var view gormModel.RetrievalView
+	if err := GetDB().WithContext(ctx).Where(query, arg).First(&view).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询检索视图失败: %v", err)
+		return nil, err
+	}
+	return &view, nil
+}
+
+// List 获取全部检索视图，按名称排序
+func (d *RetrievalViewDAO) List(ctx context.Context) ([]*gormModel.RetrievalView, error) {
+	var views []*gormModel.RetrievalView
+	if err := GetDB().WithContext(ctx).Order("name ASC").Find(&views).Error; err != nil {
+		g.Log().Errorf(ctx, "查询检索视图列表失败: %v", err)
+		return nil, err
+	}
+	return views, nil
+}
+
+// NameExists 检查检索视图名称是否已被其他视图使用
+func (d *RetrievalViewDAO) NameExists(ctx context.Context, name, excludeID string) (bool, error) {
+	var count int64
+	db := GetDB().WithContext(ctx).Model(&gormModel.RetrievalView{}).Where("name = ?", name)
+	if excludeID != "" {
+		db = db.Where("id <> ?", excludeID)
+	}
+	if err := db.Count(&count).Error; err != nil {
+		g.Log().Errorf(ctx, "检查检索视图名称失败: %v", err)
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ShadowResultDAO 影子评估结果数据访问对象
+type ShadowResultDAO struct{}
+
+var ShadowResult = &ShadowResultDAO{}
+
+// Create 保存影子评估结果
+func (d *ShadowResultDAO) Create(ctx context.Context, result *gormModel.ShadowResult) error {
+	if err := GetDB().WithContext(ctx).Create(result).Error; err != nil {
+		g.Log().Errorf(ctx, "保存影子评估结果失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// List 分页查询影子评估结果，按创建时间倒序
+func (d *ShadowResultDAO) List(ctx context.Context, convID, candidateModelID string, page, pageSize int) ([]*gormModel.ShadowResult, int64, error) {
+	var results []*gormModel.ShadowResult
+	var total int64
+
+	query := GetDB().WithContext(ctx).Model(&gormModel.ShadowResult{})
+	if convID != "" {
+		query = query.Where("conv_id = ?", convID)
+	}
+	if candidateModelID != "" {
+		query = query.Where("candidate_model_id = ?", candidateModelID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		g.Log().Errorf(ctx, "统计影子评估结果失败: %v", err)
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("create_time DESC").Offset(offset).Limit(pageSize).Find(&results).Error; err != nil {
+		g.Log().Errorf(ctx, "查询影子评估结果失败: %v", err)
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+// ListBetween 获取时间段内的影子评估指标（不含回答内容，用于汇总）
+func (d *ShadowResultDAO) ListBetween(ctx context.Context, start, end time.Time) ([]*gormModel.ShadowResult, error) {
+	var results []*gormModel.ShadowResult
+	err := GetDB().WithContext(ctx).Model(&gormModel.ShadowResult{}).
+		Select("id, live_model_id, live_latency_ms, live_groundedness, candidate_model_id, candidate_latency_ms, candidate_tokens, candidate_groundedness, agreement, error").
+		Where("create_time >= ? AND create_time < ?", start, end).
+		Find(&results).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询影子评估指标失败: %v", err)
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,85 @@
+package dao
+
+import (
+	"context"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// ToolExampleDAO 工具调用示例数据访问对象
+type ToolExampleDAO struct{}
+
+var ToolExample = &ToolExampleDAO{}
+
+// Create 创建工具调用示例
+func (d *ToolExampleDAO) Create(ctx context.Context, example *gormModel.ToolExample) error {
+	if err := GetDB().WithContext(ctx).Create(example).Error; err != nil {
+		g.Log().Errorf(ctx, "创建工具调用示例失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 保存工具调用示例的全部字段
+func (d *ToolExampleDAO) Update(ctx context.Context, example *gormModel.ToolExample) error {
+	if err := GetDB().WithContext(ctx).Save(example).Error; err != nil {
+		g.Log().Errorf(ctx, "更新工具调用示例失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除工具调用示例
+func (d *ToolExampleDAO) Delete(ctx context.Context, id string) error {
+	if err := GetDB().WithContext(ctx).Delete(&gormModel.ToolExample{}, "id = ?", id).Error; err != nil {
+		g.Log().Errorf(ctx, "删除工具调用示例失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取工具调用示例，不存在时返回 nil
+func (d *ToolExampleDAO) GetByID(ctx context.Context, id string) (*gormModel.ToolExample, error) {
+	var example gormModel.ToolExample
+	if err := GetDB().WithContext(ctx).Where("id = ?", id).First(&example).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		g.Log().Errorf(ctx, "查询工具调用示例失败: %v", err)
+		return nil, err
+	}
+	return &example, nil
+}
+
+// List 获取工具调用示例，modelID 为空时返回全部，否则返回该模型的示例；serviceName 不为空时只返回该服务的示例
+func (d *ToolExampleDAO) List(ctx context.Context, modelID, serviceName string) ([]*gormModel.ToolExample, error) {
+	var examples []*gormModel.ToolExample
+	db := GetDB().WithContext(ctx)
+	if modelID != "" {
+		db = db.Where("model_id = ?", modelID)
+	}
+	if serviceName != "" {
+		db = db.Where("service_name = ?", serviceName)
+	}
+	if err := db.Order("create_time ASC").Find(&examples).Error; err != nil {
+		g.Log().Errorf(ctx, "查询工具调用示例列表失败: %v", err)
+		return nil, err
+	}
+	return examples, nil
+}
+
+// ListEnabledForModel 获取适用于指定模型的已启用示例（含适用于所有模型的示例）
+func (d *ToolExampleDAO) ListEnabledForModel(ctx context.Context, modelID string) ([]*gormModel.ToolExample, error) {
+	var examples []*gormModel.ToolExample
+	err := GetDB().WithContext(ctx).
+		Where("enabled = ? AND model_id IN ?", true, []string{"", modelID}).
+		Order("create_time ASC").
+		Find(&examples).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "查询模型工具调用示例失败: %v", err)
+		return nil, err
+	}
+	return examples, nil
+}
@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageDAO token 用量数据访问对象
+type UsageDAO struct{}
+
+var Usage = &UsageDAO{}
+
+// UsageStatRow 按用户、模型分组的原始 token 统计行（汇总任务使用）
+type UsageStatRow struct {
+	UserID       string
+	ModelID      string
+	ModelName    string
+	MessageCount int64
+	TotalTokens  int64
+}
+
+// usageUserExpr 消息计费的用户：消息记录了所属用户（本轮提问的用户，共享会话中为各参与者）时计入该用户，
+// 否则（未记录用户的历史消息）计入会话所属用户
+const usageUserExpr = "COALESCE(NULLIF(m.author_id, ''), c.user_id, '')"
+
+// AggregateTokens 统计时间段内的消息数和 token 消耗（按消息所属用户和生成消息的模型分组）。
+// 消息记录了生成它的模型（助手消息，含改用的备用模型）时按该模型统计，否则按会话当前使用的模型统计
+func (d *UsageDAO) AggregateTokens(ctx context.Context, start, end time.Time) ([]*UsageStatRow, error) {
+	const modelExpr = "COALESCE(NULLIF(m.model_id, ''), c.model_id, '')"
+	var rows []*UsageStatRow
+	err := GetDB().WithContext(ctx).Table("messages m").
+		Select(usageUserExpr+" AS user_id, "+modelExpr+" AS model_id, "+
+			"COALESCE(MAX(md.model_name), MAX(c.model_name)) AS model_name, "+
+			"COUNT(*) AS message_count, "+
+			"COALESCE(SUM(m.tokens_used), 0) AS total_tokens").
+		Joins("JOIN conversations c ON c.conv_id = m.conv_id").
+		Joins("LEFT JOIN model md ON md.model_id = "+modelExpr).
+		Where("m.create_time >= ? AND m.create_time < ?", start, end).
+		Group(usageUserExpr + ", " + modelExpr).
+		Scan(&rows).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计 token 用量失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ReplaceDaily 覆盖写入指定日期的用量汇总
+func (d *UsageDAO) ReplaceDaily(ctx context.Context, statDate string, rows []*gormModel.UsageDaily) error {
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("stat_date = ?", statDate).Delete(&gormModel.UsageDaily{}).Error; err != nil {
+			g.Log().Errorf(ctx, "清理用量汇总失败: %v", err)
+			return err
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				g.Log().Errorf(ctx, "写入用量汇总失败: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListDaily 查询日期区间内的用量汇总，userID、modelID 为空时不过滤
+func (d *UsageDAO) ListDaily(ctx context.Context, startDate, endDate, userID, modelID string) ([]*gormModel.UsageDaily, error) {
+	var rows []*gormModel.UsageDaily
+	query := GetDB().WithContext(ctx).Where("stat_date >= ? AND stat_date <= ?", startDate, endDate)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if modelID != "" {
+		query = query.Where("model_id = ?", modelID)
+	}
+	if err := query.Order("stat_date ASC, user_id ASC, model_id ASC").Find(&rows).Error; err != nil {
+		g.Log().Errorf(ctx, "查询用量汇总失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SumUserTokens 统计用户在日期区间内的 token 消耗
+func (d *UsageDAO) SumUserTokens(ctx context.Context, userID, startDate, endDate string) (int64, error) {
+	var total int64
+	err := GetDB().WithContext(ctx).Model(&gormModel.UsageDaily{}).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Where("user_id = ? AND stat_date >= ? AND stat_date <= ?", userID, startDate, endDate).
+		Scan(&total).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计用户 token 用量失败: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
+
+// SumLiveUserTokens 直接从消息表统计用户（消息所属用户）在时间段内的 token 消耗，用于还未汇总的当天用量
+func (d *UsageDAO) SumLiveUserTokens(ctx context.Context, userID string, start, end time.Time) (int64, error) {
+	var total int64
+	err := GetDB().WithContext(ctx).Table("messages m").
+		Select("COALESCE(SUM(m.tokens_used), 0)").
+		Joins("JOIN conversations c ON c.conv_id = m.conv_id").
+		Where(usageUserExpr+" = ? AND m.create_time >= ? AND m.create_time < ?", userID, start, end).
+		Scan(&total).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计用户当天 token 用量失败: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
+
+// AddDirect 累加一次直接调用模型的请求和 token 消耗
+func (d *UsageDAO) AddDirect(ctx context.Context, statDate, userID, modelID, modelName string, tokens int64) error {
+	row := &gormModel.UsageDirect{StatDate: statDate, UserID: userID, ModelID: modelID, ModelName: modelName, RequestCount: 1, TotalTokens: tokens}
+	err := GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "stat_date"}, {Name: "user_id"}, {Name: "model_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"request_count": gorm.Expr("usage_direct.request_count + 1"),
+			"total_tokens":  gorm.Expr("usage_direct.total_tokens + ?", tokens),
+		}),
+	}).Create(row).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "累加直接调用用量失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListDirect 查询指定日期直接调用模型的用量（汇总任务使用）
+func (d *UsageDAO) ListDirect(ctx context.Context, statDate string) ([]*gormModel.UsageDirect, error) {
+	var rows []*gormModel.UsageDirect
+	if err := GetDB().WithContext(ctx).Where("stat_date = ?", statDate).Find(&rows).Error; err != nil {
+		g.Log().Errorf(ctx, "查询直接调用用量失败: %v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SumUserDirectTokens 统计用户在指定日期直接调用模型的 token 消耗
+func (d *UsageDAO) SumUserDirectTokens(ctx context.Context, userID, statDate string) (int64, error) {
+	var total int64
+	err := GetDB().WithContext(ctx).Model(&gormModel.UsageDirect{}).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Where("user_id = ? AND stat_date = ?", userID, statDate).
+		Scan(&total).Error
+	if err != nil {
+		g.Log().Errorf(ctx, "统计用户直接调用用量失败: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
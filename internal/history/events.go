@@ -0,0 +1,86 @@
+package history
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+type authorKey struct{}
+
+// WithAuthor 在上下文中记录发送消息的用户，保存本轮的用户消息和助手回答时写入 author_id
+func WithAuthor(ctx context.Context, userID string) context.Context {
+	if userID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, authorKey{}, userID)
+}
+
+// AuthorFromContext 获取上下文中发送消息的用户，没有时返回空字符串
+func AuthorFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(authorKey{}).(string)
+	return userID
+}
+
+// SavedMessage 已保存的消息，多模态内容只包含文本部分
+type SavedMessage struct {
+	MsgID      string
+	ConvID     string
+	Role       string
+	AuthorID   string
+	Content    string
+	CreateTime time.Time
+}
+
+// SavedHandler 消息保存后的回调，在保存消息的 goroutine 中同步执行，应尽快返回
+type SavedHandler func(ctx context.Context, msg *SavedMessage)
+
+var savedHandlers struct {
+	sync.RWMutex
+	list []SavedHandler
+}
+
+// OnMessageSaved 注册消息保存后的回调，通常在包的 init 中调用
+func OnMessageSaved(handler SavedHandler) {
+	savedHandlers.Lock()
+	defer savedHandlers.Unlock()
+	savedHandlers.list = append(savedHandlers.list, handler)
+}
+
+// notifySaved 通知消息已保存，单个回调 panic 不影响其他回调和保存结果
+func notifySaved(ctx context.Context, msg *gormModel.Message, contents []*gormModel.MessageContent) {
+	savedHandlers.RLock()
+	list := append([]SavedHandler(nil), savedHandlers.list...)
+	savedHandlers.RUnlock()
+	if len(list) == 0 {
+		return
+	}
+
+	saved := &SavedMessage{
+		MsgID:  msg.MsgID,
+		ConvID: msg.ConvID,
+		Role:   msg.Role,
+	}
+	// 消息事件只对用户消息给出发送者
+	if msg.Role == string(schema.User) {
+		saved.AuthorID = msg.AuthorID
+	}
+	if msg.CreateTime != nil {
+		saved.CreateTime = *msg.CreateTime
+	}
+	for _, content := range contents {
+		if content.ContentType == "text" {
+			saved.Content += content.TextContent
+		}
+	}
+	for _, handler := range list {
+		func() {
+			defer common.RecoverPanic(ctx, "MessageSavedHandler")
+			handler(ctx, saved)
+		}()
+	}
+}
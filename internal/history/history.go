@@ -2,18 +2,21 @@ package history
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-	"unicode/utf8"
 
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/media"
+	"github.com/Malowking/kbgo/core/tokenizer"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/identity"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -30,10 +33,12 @@ type MessageWithContents struct {
 // MessageWithMetrics 带指标的消息结构
 type MessageWithMetrics struct {
 	*schema.Message
+	ModelID    string // 生成该消息的模型ID（助手消息使用）
 	TokensUsed int
 	LatencyMs  int
 	TraceID    string
 	ToolCalls  []*schema.ToolCall
+	Metadata   map[string]interface{}
 }
 
 // Manager 聊天历史管理器
@@ -48,26 +53,26 @@ func NewManager() *Manager {
 	}
 }
 
-// SaveMessage 保存消息
-func (h *Manager) SaveMessage(message *schema.Message, convID string) error {
-	return h.SaveMessageWithMetadata(message, convID, nil)
+// SaveMessage 保存消息，用户消息的发送者取自上下文（见 WithAuthor）
+func (h *Manager) SaveMessage(ctx context.Context, message *schema.Message, convID string) error {
+	return h.SaveMessageWithMetadata(ctx, message, convID, nil)
 }
 
-// SaveMessageWithMetrics 保存带指标的消息（异步）
-func (h *Manager) SaveMessageWithMetrics(message *MessageWithMetrics, convID string) error {
+// SaveMessageWithMetrics 保存带指标的消息（异步），保存时使用与 ctx 分离但保留其中值的上下文
+func (h *Manager) SaveMessageWithMetrics(ctx context.Context, message *MessageWithMetrics, convID string) error {
 	// 使用全局异步保存器
 	asyncSaver := GetGlobalAsyncSaver()
 
 	// 异步保存，不等待结果（提升性能）
-	asyncSaver.SaveMessageAsync(message, convID)
+	asyncSaver.SaveMessageAsync(ctx, message, convID)
 
 	return nil
 }
 
 // SaveMessageWithMetricsSync 保存带指标的消息（同步）
-func (h *Manager) SaveMessageWithMetricsSync(message *MessageWithMetrics, convID string) error {
+func (h *Manager) SaveMessageWithMetricsSync(ctx context.Context, message *MessageWithMetrics, convID string) error {
 	// 确保对话存在
-	if err := h.ensureConversationExists(convID); err != nil {
+	if err := h.ensureConversationExists(ctx, convID); err != nil {
 		return err
 	}
 
@@ -83,16 +88,29 @@ func (h *Manager) SaveMessageWithMetricsSync(message *MessageWithMetrics, convID
 		toolCallsJSON = gormModel.JSON(data)
 	}
 
+	// 处理元数据
+	var metadataJSON gormModel.JSON
+	if message.Metadata != nil {
+		data, err := json.Marshal(message.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		metadataJSON = gormModel.JSON(data)
+	}
+
 	// 创建消息记录
 	msg := &gormModel.Message{
 		MsgID:      generateMessageID(),
 		ConvID:     convID,
 		Role:       string(message.Role),
+		AuthorID:   AuthorFromContext(ctx),
 		CreateTime: &now,
+		ModelID:    message.ModelID,
 		TokensUsed: message.TokensUsed,
 		LatencyMs:  message.LatencyMs,
 		TraceID:    message.TraceID,
 		ToolCalls:  toolCallsJSON,
+		Metadata:   metadataJSON,
 	}
 
 	// 处理内容块
@@ -105,13 +123,17 @@ func (h *Manager) SaveMessageWithMetricsSync(message *MessageWithMetrics, convID
 	}
 	contents = append(contents, content)
 
-	return dao.Message.CreateWithContents(nil, msg, contents)
+	if err := dao.Message.CreateWithContents(nil, msg, contents); err != nil {
+		return err
+	}
+	notifySaved(ctx, msg, contents)
+	return nil
 }
 
 // SaveMessageWithMetadata 保存带元数据的消息
-func (h *Manager) SaveMessageWithMetadata(message *schema.Message, convID string, metadata map[string]interface{}) error {
+func (h *Manager) SaveMessageWithMetadata(ctx context.Context, message *schema.Message, convID string, metadata map[string]interface{}) error {
 	// 确保对话存在
-	if err := h.ensureConversationExists(convID); err != nil {
+	if err := h.ensureConversationExists(ctx, convID); err != nil {
 		return err
 	}
 
@@ -131,6 +153,7 @@ func (h *Manager) SaveMessageWithMetadata(message *schema.Message, convID string
 		MsgID:      generateMessageID(),
 		ConvID:     convID,
 		Role:       string(message.Role),
+		AuthorID:   AuthorFromContext(ctx),
 		CreateTime: &now,
 		Metadata:   metadataJSON,
 	}
@@ -219,7 +242,11 @@ func (h *Manager) SaveMessageWithMetadata(message *schema.Message, convID string
 		contents = append(contents, content)
 	}
 
-	return dao.Message.CreateWithContents(nil, msg, contents)
+	if err := dao.Message.CreateWithContents(nil, msg, contents); err != nil {
+		return err
+	}
+	notifySaved(ctx, msg, contents)
+	return nil
 }
 
 // GetHistory 获取聊天历史
@@ -341,22 +368,12 @@ func (h *Manager) processImageContent(mediaURL string) (schema.ChatMessagePart,
 		return schema.ChatMessagePart{}, fmt.Errorf("image file not found: %s", mediaURL)
 	}
 
-	// 读取文件
-	data, err := os.ReadFile(mediaURL)
+	// 读取文件并校验内容类型，HEIC/AVIF 转换为 JPEG，超大图片等比缩小
+	dataURI, err := media.ImageDataURI(context.Background(), mediaURL)
 	if err != nil {
-		return schema.ChatMessagePart{}, fmt.Errorf("failed to read image file: %w", err)
+		return schema.ChatMessagePart{}, err
 	}
 
-	// 获取MIME类型
-	ext := filepath.Ext(mediaURL)
-	mimeType := getMimeTypeFromExt(ext)
-
-	// 编码为base64
-	base64Data := base64.StdEncoding.EncodeToString(data)
-
-	// 构造data URI
-	dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
-
 	return schema.ChatMessagePart{
 		Type: schema.ChatMessagePartTypeImageURL,
 		ImageURL: &schema.ChatMessageImageURL{
@@ -388,22 +405,12 @@ func (h *Manager) processAudioContent(mediaURL string) (schema.ChatMessagePart,
 		return schema.ChatMessagePart{}, fmt.Errorf("audio file not found: %s", mediaURL)
 	}
 
-	// 读取文件
-	data, err := os.ReadFile(mediaURL)
+	// 读取文件并校验内容类型
+	dataURI, err := media.FileDataURI(mediaURL)
 	if err != nil {
-		return schema.ChatMessagePart{}, fmt.Errorf("failed to read audio file: %w", err)
+		return schema.ChatMessagePart{}, err
 	}
 
-	// 获取MIME类型
-	ext := filepath.Ext(mediaURL)
-	mimeType := getMimeTypeFromExt(ext)
-
-	// 编码为base64
-	base64Data := base64.StdEncoding.EncodeToString(data)
-
-	// 构造data URI
-	dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
-
 	return schema.ChatMessagePart{
 		Type: schema.ChatMessagePartTypeAudioURL,
 		AudioURL: &schema.ChatMessageAudioURL{
@@ -434,22 +441,12 @@ func (h *Manager) processVideoContent(mediaURL string) (schema.ChatMessagePart,
 		return schema.ChatMessagePart{}, fmt.Errorf("video file not found: %s", mediaURL)
 	}
 
-	// 读取文件
-	data, err := os.ReadFile(mediaURL)
+	// 读取文件并校验内容类型
+	dataURI, err := media.FileDataURI(mediaURL)
 	if err != nil {
-		return schema.ChatMessagePart{}, fmt.Errorf("failed to read video file: %w", err)
+		return schema.ChatMessagePart{}, err
 	}
 
-	// 获取MIME类型
-	ext := filepath.Ext(mediaURL)
-	mimeType := getMimeTypeFromExt(ext)
-
-	// 编码为base64
-	base64Data := base64.StdEncoding.EncodeToString(data)
-
-	// 构造data URI
-	dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
-
 	return schema.ChatMessagePart{
 		Type: schema.ChatMessagePartTypeVideoURL,
 		VideoURL: &schema.ChatMessageVideoURL{
@@ -458,48 +455,6 @@ func (h *Manager) processVideoContent(mediaURL string) (schema.ChatMessagePart,
 	}, nil
 }
 
-// getMimeTypeFromExt 根据文件扩展名获取MIME类型
-func getMimeTypeFromExt(ext string) string {
-	mimeTypes := map[string]string{
-		// 图片格式
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".png":  "image/png",
-		".gif":  "image/gif",
-		".bmp":  "image/bmp",
-		".webp": "image/webp",
-		".svg":  "image/svg+xml",
-		".ico":  "image/x-icon",
-		".tiff": "image/tiff",
-
-		// 音频格式
-		".mp3":  "audio/mpeg",
-		".wav":  "audio/wav",
-		".flac": "audio/flac",
-		".aac":  "audio/aac",
-		".ogg":  "audio/ogg",
-		".m4a":  "audio/mp4",
-		".wma":  "audio/x-ms-wma",
-
-		// 视频格式
-		".mp4":  "video/mp4",
-		".avi":  "video/x-msvideo",
-		".mkv":  "video/x-matroska",
-		".mov":  "video/quicktime",
-		".wmv":  "video/x-ms-wmv",
-		".flv":  "video/x-flv",
-		".webm": "video/webm",
-		".m4v":  "video/mp4",
-		".mpeg": "video/mpeg",
-		".mpg":  "video/mpeg",
-	}
-
-	if mime, ok := mimeTypes[ext]; ok {
-		return mime
-	}
-	return "application/octet-stream"
-}
-
 // GetConversationHistory 获取会话历史消息
 func (h *Manager) GetConversationHistory(convID string) ([]MessageWithContents, error) {
 	var msgs []MessageWithContents
@@ -584,39 +539,26 @@ func (h *Manager) BuildLLMMessages(history []MessageWithContents) []map[string]i
 	return llmMsgs
 }
 
-// TruncateMessagesByToken 根据token数量截断消息
+// TruncateMessagesByToken 根据token数量截断消息，从最新的消息往前保留，总数不超过 maxTokens
+// 按模型对应的分词器计数（未知模型使用启发式估算），至少保留最后一条消息
 func (h *Manager) TruncateMessagesByToken(messages []map[string]interface{}, maxTokens int, model string) []map[string]interface{} {
-	// 粗略估算：1 token 4 英文字符 or 1.5 中文字符
-	// 更准的方式：使用 tiktoken 库（见下方建议）
+	counter := tokenizer.ForModel(model)
 	totalTokens := 0
-	startIdx := 0
+	startIdx := len(messages)
 
-	for i, msg := range messages {
-		content, _ := msg["content"].(string)
-		tokens := h.estimateTokenCount(content) + 10 // + role 开销
-		if totalTokens+tokens > maxTokens {
-			startIdx = i
+	for i := len(messages) - 1; i >= 0; i-- {
+		content, _ := messages[i]["content"].(string)
+		tokens := counter.Count(content) + tokenizer.MessageOverhead
+		if totalTokens+tokens > maxTokens && startIdx < len(messages) {
 			break
 		}
 		totalTokens += tokens
-	}
-
-	// 保证至少包含最后一条用户消息
-	if startIdx >= len(messages) {
-		startIdx = len(messages) - 1
+		startIdx = i
 	}
 
 	return messages[startIdx:]
 }
 
-// estimateTokenCount 估算token数量
-func (h *Manager) estimateTokenCount(text string) int {
-	// 简化版：中文按 1.5 字/词，英文按 4 字/词
-	chinese := utf8.RuneCountInString(regexp.MustCompile(`[\p{Han}]`).ReplaceAllString(text, ""))
-	english := len(regexp.MustCompile(`[a-zA-Z0-9]+`).ReplaceAllString(text, "")) / 4
-	return chinese + english
-}
-
 // extractFileName 从URL中提取文件名
 func extractFileName(url string) string {
 	parts := strings.Split(url, "/")
@@ -626,9 +568,9 @@ func extractFileName(url string) string {
 	return url
 }
 
-// ensureConversationExists 确保对话存在
-func (h *Manager) ensureConversationExists(convID string) error {
-	conversation, err := dao.Conversation.GetByConvID(nil, convID)
+// ensureConversationExists 确保对话存在，新建的对话属于上下文中的用户
+func (h *Manager) ensureConversationExists(ctx context.Context, convID string) error {
+	conversation, err := dao.Conversation.GetByConvID(ctx, convID)
 	if err != nil {
 		return err
 	}
@@ -637,7 +579,7 @@ func (h *Manager) ensureConversationExists(convID string) error {
 		now := time.Now()
 		conversation := &gormModel.Conversation{
 			ConvID:           convID,
-			UserID:           "default_user", // 默认用户ID，实际使用时应从上下文获取
+			UserID:           identity.UserID(ctx),
 			Title:            "New Conversation",
 			ModelName:        "default_model", // 默认模型名
 			ConversationType: "text",
@@ -645,7 +587,7 @@ func (h *Manager) ensureConversationExists(convID string) error {
 			CreateTime:       &now,
 			UpdateTime:       &now,
 		}
-		return dao.Conversation.Create(nil, conversation)
+		return dao.Conversation.Create(ctx, conversation)
 	}
 
 	return nil
@@ -680,34 +622,111 @@ func (h *Manager) GetMessageMetadata(msgID string) (map[string]interface{}, erro
 
 // SaveTask 消息保存任务
 type SaveTask struct {
-	Message *MessageWithMetrics
-	ConvID  string
-	Result  chan error
+	Ctx        context.Context // 与请求分离的上下文，保留 trace 等请求信息
+	Message    *MessageWithMetrics
+	ConvID     string
+	MsgID      string    // 入队时生成，溢出日志回放时用于跳过已保存的消息
+	CreateTime time.Time // 入队时间，作为消息的创建时间，延迟保存时不打乱消息顺序
+	Result     chan error
+}
+
+// SaverConfig 异步消息保存器配置，对应配置文件 messageSaver 段
+type SaverConfig struct {
+	Workers        int           // worker 数量
+	QueueSize      int           // 队列容量
+	SpoolPath      string        // 溢出日志路径，为空时队列满的消息直接丢弃
+	ReplayInterval time.Duration // 溢出日志的回放周期
+	MaxAttempts    int           // 溢出日志中的记录保存失败达到该次数后移入死信文件
+}
+
+// LoadSaverConfig 读取异步消息保存器配置
+func LoadSaverConfig(ctx context.Context) *SaverConfig {
+	return &SaverConfig{
+		Workers:        g.Cfg().MustGet(ctx, "messageSaver.workers", 5).Int(),
+		QueueSize:      g.Cfg().MustGet(ctx, "messageSaver.queueSize", 200).Int(),
+		SpoolPath:      g.Cfg().MustGet(ctx, "messageSaver.spoolPath", config.StatePath("message_spool.wal")).String(),
+		ReplayInterval: g.Cfg().MustGet(ctx, "messageSaver.replayInterval", "30s").Duration(),
+		MaxAttempts:    g.Cfg().MustGet(ctx, "messageSaver.maxAttempts", defaultSpoolMaxAttempts).Int(),
+	}
+}
+
+// SaverStats 异步消息保存器的运行指标，计数从进程启动开始累计
+type SaverStats struct {
+	QueueSize     int   // 当前队列中的消息数
+	QueueCapacity int   // 队列容量
+	Workers       int   // worker 数量
+	Saved         int64 // 已保存的消息数（不含回放）
+	Failed        int64 // 保存失败的消息数，失败的异步消息写入溢出日志
+	Spilled       int64 // 写入溢出日志的消息数
+	Replayed      int64 // 从溢出日志回放保存的消息数
+	Dropped       int64 // 丢失的消息数（未启用溢出日志或写入失败）
+	DeadLettered  int64 // 多次回放失败后移入死信文件的消息数
+	SpoolEnabled  bool  // 是否启用溢出日志
+	SpoolPending  int   // 溢出日志中等待回放的消息数
 }
 
 // AsyncMessageSaver 异步消息保存器
+// 队列满或保存失败时消息写入本地溢出日志，启动时和每个回放周期把日志中的消息写入数据库
 type AsyncMessageSaver struct {
-	db         *gorm.DB
-	taskQueue  chan *SaveTask
-	workerPool int
-	wg         sync.WaitGroup
-	ctx        context.Context
-	cancel     context.CancelFunc
+	db             *gorm.DB
+	taskQueue      chan *SaveTask
+	workerPool     int
+	spool          *messageSpool // 为 nil 时不启用溢出日志
+	replayInterval time.Duration
+	wg             sync.WaitGroup
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	// closeMu 保护 closed 和向 taskQueue 发送，关闭后新消息直接写入溢出日志，避免向已关闭的队列发送
+	closeMu sync.RWMutex
+	closed  bool
+
+	saved        atomic.Int64
+	failed       atomic.Int64
+	spilled      atomic.Int64
+	replayed     atomic.Int64
+	dropped      atomic.Int64
+	deadLettered atomic.Int64
 }
 
-// NewAsyncMessageSaver 创建异步消息保存器
+// NewAsyncMessageSaver 创建异步消息保存器，不启用溢出日志
 func NewAsyncMessageSaver(workerPool int) *AsyncMessageSaver {
+	return newAsyncMessageSaver(context.Background(), &SaverConfig{Workers: workerPool})
+}
+
+// newAsyncMessageSaver 按配置创建异步消息保存器，溢出日志无法打开时记录错误并不启用
+func newAsyncMessageSaver(ctx context.Context, cfg *SaverConfig) *AsyncMessageSaver {
+	workerPool := cfg.Workers
 	if workerPool <= 0 {
 		workerPool = 5 // 默认5个worker
 	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = 200
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	saverCtx, cancel := context.WithCancel(context.Background())
 	saver := &AsyncMessageSaver{
-		db:         dao.GetDB(),
-		taskQueue:  make(chan *SaveTask, 200), // 缓冲队列
-		workerPool: workerPool,
-		ctx:        ctx,
-		cancel:     cancel,
+		db:             dao.GetDB(),
+		taskQueue:      make(chan *SaveTask, queueSize), // 缓冲队列
+		workerPool:     workerPool,
+		replayInterval: cfg.ReplayInterval,
+		ctx:            saverCtx,
+		cancel:         cancel,
+	}
+	if cfg.SpoolPath != "" {
+		spool, err := openSpool(cfg.SpoolPath)
+		if err != nil {
+			g.Log().Errorf(ctx, "Failed to open message spool %s, messages will be lost when the save queue is full: %v", cfg.SpoolPath, err)
+		} else {
+			if cfg.MaxAttempts > 0 {
+				spool.maxAttempts = cfg.MaxAttempts
+			}
+			saver.spool = spool
+			if pending := spool.size(); pending > 0 {
+				g.Log().Infof(ctx, "Message spool %s has %d unsaved messages, replaying", cfg.SpoolPath, pending)
+			}
+		}
 	}
 
 	// 启动worker pool
@@ -716,12 +735,16 @@ func NewAsyncMessageSaver(workerPool int) *AsyncMessageSaver {
 	return saver
 }
 
-// start 启动worker pool
+// start 启动worker pool和溢出日志回放
 func (s *AsyncMessageSaver) start() {
 	for i := 0; i < s.workerPool; i++ {
 		s.wg.Add(1)
 		go s.worker()
 	}
+	if s.spool != nil {
+		s.wg.Add(1)
+		go s.replayLoop()
+	}
 }
 
 // worker 处理消息保存任务
@@ -737,45 +760,162 @@ func (s *AsyncMessageSaver) worker() {
 				return
 			}
 			// 处理消息保存
-			err := s.saveMessageSync(task.Message, task.ConvID)
+			err := s.saveMessage(task.Ctx, task.MsgID, task.CreateTime, task.Message, task.ConvID)
+			if err != nil {
+				s.failed.Add(1)
+			} else {
+				s.saved.Add(1)
+			}
 			if task.Result != nil {
 				task.Result <- err
 				close(task.Result)
+			} else if err != nil {
+				// 不等待结果的消息保存失败时写入溢出日志，稍后重试
+				g.Log().Errorf(task.Ctx, "Failed to save message of conversation %s: %v", task.ConvID, err)
+				s.spill(task)
 			}
 		}
 	}
 }
 
-// saveMessageSync 同步保存消息（worker使用）
-func (s *AsyncMessageSaver) saveMessageSync(message *MessageWithMetrics, convID string) error {
-	// 确保对话存在
-	if err := s.ensureConversationExists(convID); err != nil {
+// replayLoop 启动时和每个回放周期回放溢出日志
+func (s *AsyncMessageSaver) replayLoop() {
+	defer s.wg.Done()
+
+	interval := s.replayInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.replaySpool()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replaySpool 把溢出日志中的消息写入数据库，已保存过的消息（进程在回放中途退出时）直接跳过
+func (s *AsyncMessageSaver) replaySpool() {
+	if s.spool.size() == 0 {
+		return
+	}
+	replayed, deadLettered, err := s.spool.replay(func(record *spoolRecord) error {
+		ctx := identity.WithUser(WithAuthor(s.ctx, record.AuthorID), record.UserID)
+		existing, err := dao.Message.GetByMsgID(ctx, record.MsgID)
+		if err != nil || existing != nil {
+			return err
+		}
+		return s.saveMessage(ctx, record.MsgID, record.CreateTime, record.message(), record.ConvID)
+	}, s.transient)
+	s.replayed.Add(int64(replayed))
+	s.deadLettered.Add(int64(deadLettered))
+	if replayed > 0 {
+		g.Log().Infof(s.ctx, "Replayed %d messages from message spool", replayed)
+	}
+	if deadLettered > 0 {
+		g.Log().Errorf(s.ctx, "Moved %d messages that repeatedly failed to save to %s", deadLettered, s.spool.deadPath())
+	}
+	if err != nil {
+		g.Log().Warningf(s.ctx, "Message spool replay stopped, %d messages pending: %v", s.spool.size(), err)
+	}
+}
+
+// transient 判断回放时的保存错误是否为暂时性错误：上下文取消或数据库无法连接时停止回放，
+// 数据库可用时的错误（如违反约束）属于记录本身
+func (s *AsyncMessageSaver) transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || s.db == nil {
+		return true
+	}
+	sqlDB, dbErr := s.db.DB()
+	if dbErr != nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	return sqlDB.PingContext(ctx) != nil
+}
+
+// spill 把消息写入溢出日志，未启用或写入失败时消息丢失
+func (s *AsyncMessageSaver) spill(task *SaveTask) error {
+	if s.spool == nil {
+		s.dropped.Add(1)
+		g.Log().Warning(task.Ctx, "Message spool is disabled, unsaved message lost")
+		return errors.New("message spool is disabled")
+	}
+	record := &spoolRecord{
+		MsgID:      task.MsgID,
+		ConvID:     task.ConvID,
+		UserID:     identity.UserID(task.Ctx),
+		AuthorID:   AuthorFromContext(task.Ctx),
+		CreateTime: task.CreateTime,
+		Role:       task.Message.Role,
+		Content:    task.Message.Content,
+		ModelID:    task.Message.ModelID,
+		TokensUsed: task.Message.TokensUsed,
+		LatencyMs:  task.Message.LatencyMs,
+		TraceID:    task.Message.TraceID,
+		ToolCalls:  task.Message.ToolCalls,
+		Metadata:   task.Message.Metadata,
+	}
+	if err := s.spool.append(record); err != nil {
+		s.dropped.Add(1)
+		g.Log().Errorf(task.Ctx, "Failed to write message spool, message lost: %v", err)
 		return err
 	}
+	s.spilled.Add(1)
+	return nil
+}
 
-	now := time.Now()
+// saveMessageSync 同步保存消息
+func (s *AsyncMessageSaver) saveMessageSync(ctx context.Context, message *MessageWithMetrics, convID string) error {
+	return s.saveMessage(ctx, generateMessageID(), time.Now(), message, convID)
+}
 
+// saveMessage 使用指定的消息ID和创建时间保存消息（worker和溢出日志回放使用）
+func (s *AsyncMessageSaver) saveMessage(ctx context.Context, msgID string, now time.Time, message *MessageWithMetrics, convID string) error {
+	// 确保对话存在
+	if err := s.ensureConversationExists(ctx, convID); err != nil {
+		return err
+	}
 	// 处理工具调用
 	var toolCallsJSON gormModel.JSON
 	if message.ToolCalls != nil {
 		data, err := json.Marshal(message.ToolCalls)
 		if err != nil {
-			g.Log().Errorf(context.Background(), "failed to marshal tool calls: %v", err)
+			g.Log().Errorf(ctx, "failed to marshal tool calls: %v", err)
 		} else {
 			toolCallsJSON = gormModel.JSON(data)
 		}
 	}
 
+	// 处理元数据
+	var metadataJSON gormModel.JSON
+	if message.Metadata != nil {
+		data, err := json.Marshal(message.Metadata)
+		if err != nil {
+			g.Log().Errorf(ctx, "failed to marshal metadata: %v", err)
+		} else {
+			metadataJSON = gormModel.JSON(data)
+		}
+	}
+
 	// 创建消息记录
 	msg := &gormModel.Message{
-		MsgID:      generateMessageID(),
+		MsgID:      msgID,
 		ConvID:     convID,
 		Role:       string(message.Role),
+		AuthorID:   AuthorFromContext(ctx),
 		CreateTime: &now,
+		ModelID:    message.ModelID,
 		TokensUsed: message.TokensUsed,
 		LatencyMs:  message.LatencyMs,
 		TraceID:    message.TraceID,
 		ToolCalls:  toolCallsJSON,
+		Metadata:   metadataJSON,
 	}
 
 	// 处理内容块
@@ -788,55 +928,76 @@ func (s *AsyncMessageSaver) saveMessageSync(message *MessageWithMetrics, convID
 	}
 	contents = append(contents, content)
 
-	return dao.Message.CreateWithContents(nil, msg, contents)
+	if err := dao.Message.CreateWithContents(ctx, msg, contents); err != nil {
+		return err
+	}
+	notifySaved(ctx, msg, contents)
+	return nil
 }
 
-// SaveMessageAsync 异步保存消息（不等待结果）
-func (s *AsyncMessageSaver) SaveMessageAsync(message *MessageWithMetrics, convID string) {
-	task := &SaveTask{
-		Message: message,
-		ConvID:  convID,
-		Result:  nil, // 不需要结果通知
+// SaveMessageAsync 异步保存消息（不等待结果），队列满或保存器已关闭时写入溢出日志
+func (s *AsyncMessageSaver) SaveMessageAsync(ctx context.Context, message *MessageWithMetrics, convID string) {
+	task := s.newTask(ctx, message, convID)
+	if s.enqueue(task) {
+		return
 	}
+	// 队列满了或正在关闭，写入溢出日志，不阻塞
+	if s.spill(task) == nil {
+		g.Log().Warning(ctx, "Message save queue is full or closed, message spilled to message spool")
+	}
+}
 
+// enqueue 不阻塞地提交任务，队列满或保存器已关闭时返回 false
+func (s *AsyncMessageSaver) enqueue(task *SaveTask) bool {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return false
+	}
 	select {
 	case s.taskQueue <- task:
-		// 任务提交成功
+		return true
 	default:
-		// 队列满了，记录警告但不阻塞
-		g.Log().Warning(context.Background(), "Message save queue is full, message may be lost")
+		return false
+	}
+}
+
+// newTask 创建保存任务，入队时确定消息ID和创建时间
+func (s *AsyncMessageSaver) newTask(ctx context.Context, message *MessageWithMetrics, convID string) *SaveTask {
+	return &SaveTask{
+		Ctx:        common.DetachContext(ctx),
+		Message:    message,
+		ConvID:     convID,
+		MsgID:      generateMessageID(),
+		CreateTime: time.Now(),
 	}
 }
 
 // SaveMessageAsyncWait 异步保存消息（等待结果）
 func (s *AsyncMessageSaver) SaveMessageAsyncWait(ctx context.Context, message *MessageWithMetrics, convID string) error {
-	task := &SaveTask{
-		Message: message,
-		ConvID:  convID,
-		Result:  make(chan error, 1),
-	}
+	task := s.newTask(ctx, message, convID)
+	task.Result = make(chan error, 1)
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.enqueue(task) {
+		// 队列满了或正在关闭，同步保存
+		g.Log().Warning(ctx, "Message save queue is full or closed, saving synchronously")
+		return s.saveMessage(ctx, task.MsgID, task.CreateTime, message, convID)
+	}
+	// 任务提交成功，等待结果
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case s.taskQueue <- task:
-		// 任务提交成功，等待结果
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case err := <-task.Result:
-			return err
-		}
-	default:
-		// 队列满了，同步保存
-		g.Log().Warning(ctx, "Message save queue is full, saving synchronously")
-		return s.saveMessageSync(message, convID)
+	case err := <-task.Result:
+		return err
 	}
 }
 
 // ensureConversationExists 确保对话存在（AsyncMessageSaver使用）
-func (s *AsyncMessageSaver) ensureConversationExists(convID string) error {
-	conversation, err := dao.Conversation.GetByConvID(nil, convID)
+func (s *AsyncMessageSaver) ensureConversationExists(ctx context.Context, convID string) error {
+	conversation, err := dao.Conversation.GetByConvID(ctx, convID)
 	if err != nil {
 		return err
 	}
@@ -845,7 +1006,7 @@ func (s *AsyncMessageSaver) ensureConversationExists(convID string) error {
 		now := time.Now()
 		conversation := &gormModel.Conversation{
 			ConvID:           convID,
-			UserID:           "default_user",
+			UserID:           identity.UserID(ctx),
 			Title:            "New Conversation",
 			ModelName:        "default_model",
 			ConversationType: "text",
@@ -853,17 +1014,31 @@ func (s *AsyncMessageSaver) ensureConversationExists(convID string) error {
 			CreateTime:       &now,
 			UpdateTime:       &now,
 		}
-		return dao.Conversation.Create(nil, conversation)
+		return dao.Conversation.Create(ctx, conversation)
 	}
 
 	return nil
 }
 
-// Shutdown 关闭异步保存器
+// Shutdown 关闭异步保存器，队列中尚未保存的消息写入溢出日志；关闭后提交的消息直接写入溢出日志
 func (s *AsyncMessageSaver) Shutdown() {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return
+	}
+	s.closed = true
 	s.cancel()
 	close(s.taskQueue)
+	s.closeMu.Unlock()
 	s.wg.Wait()
+	for task := range s.taskQueue {
+		err := s.spill(task)
+		if task.Result != nil {
+			task.Result <- err
+			close(task.Result)
+		}
+	}
 }
 
 // GetQueueSize 获取当前队列大小
@@ -871,11 +1046,38 @@ func (s *AsyncMessageSaver) GetQueueSize() int {
 	return len(s.taskQueue)
 }
 
+// Stats 获取运行指标
+func (s *AsyncMessageSaver) Stats() *SaverStats {
+	stats := &SaverStats{
+		QueueSize:     len(s.taskQueue),
+		QueueCapacity: cap(s.taskQueue),
+		Workers:       s.workerPool,
+		Saved:         s.saved.Load(),
+		Failed:        s.failed.Load(),
+		Spilled:       s.spilled.Load(),
+		Replayed:      s.replayed.Load(),
+		Dropped:       s.dropped.Load(),
+		DeadLettered:  s.deadLettered.Load(),
+		SpoolEnabled:  s.spool != nil,
+	}
+	if s.spool != nil {
+		stats.SpoolPending = s.spool.size()
+	}
+	return stats
+}
+
 // 全局异步保存器实例
 var globalAsyncSaver *AsyncMessageSaver
 var saverOnce sync.Once
 
-// GetGlobalAsyncSaver 获取全局异步保存器
+// InitAsyncSaver 按配置文件 messageSaver 段创建全局异步保存器，并回放上次运行留下的溢出日志
+func InitAsyncSaver(ctx context.Context) {
+	saverOnce.Do(func() {
+		globalAsyncSaver = newAsyncMessageSaver(ctx, LoadSaverConfig(ctx))
+	})
+}
+
+// GetGlobalAsyncSaver 获取全局异步保存器，未调用 InitAsyncSaver 时使用默认配置且不启用溢出日志
 func GetGlobalAsyncSaver() *AsyncMessageSaver {
 	saverOnce.Do(func() {
 		globalAsyncSaver = NewAsyncMessageSaver(5)
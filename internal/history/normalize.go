@@ -0,0 +1,76 @@
+package history
+
+import (
+	"strings"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// partPlaceholders 模型不支持的内容块在历史消息中替换成的文本
+var partPlaceholders = map[schema.ChatMessagePartType]string{
+	schema.ChatMessagePartTypeImageURL: "[图片]",
+	schema.ChatMessagePartTypeAudioURL: "[音频]",
+	schema.ChatMessagePartTypeVideoURL: "[视频]",
+}
+
+// NormalizeForModel 将历史消息转换为目标模型格式适配器可以处理的形式
+// 会话中途切换模型后，之前的消息可能包含新模型不支持的内容块（如纯文本模型收到图片），
+// supported 为新模型支持的内容块类型，不支持的内容块替换为文本占位符；
+// 只有用户消息保留多模态内容，其他角色的消息统一合并为文本
+func NormalizeForModel(messages []*schema.Message, supported ...schema.ChatMessagePartType) []*schema.Message {
+	allowed := make(map[schema.ChatMessagePartType]bool, len(supported)+1)
+	allowed[schema.ChatMessagePartTypeText] = true
+	for _, partType := range supported {
+		allowed[partType] = true
+	}
+
+	result := make([]*schema.Message, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.MultiContent) == 0 {
+			result = append(result, msg)
+			continue
+		}
+
+		normalized := *msg
+		normalized.MultiContent = nil
+		multimodal := false
+		var parts []schema.ChatMessagePart
+		for _, part := range msg.MultiContent {
+			if part.Type != schema.ChatMessagePartTypeText && (!allowed[part.Type] || msg.Role != schema.User) {
+				placeholder, ok := partPlaceholders[part.Type]
+				if !ok {
+					continue
+				}
+				part = schema.ChatMessagePart{Type: schema.ChatMessagePartTypeText, Text: placeholder}
+			}
+			if part.Type != schema.ChatMessagePartTypeText {
+				multimodal = true
+			}
+			parts = append(parts, part)
+		}
+
+		if multimodal {
+			normalized.MultiContent = parts
+		} else {
+			// 只剩文本时合并为 Content，兼容只接受字符串内容的模型
+			texts := make([]string, 0, len(parts))
+			for _, part := range parts {
+				if part.Text != "" {
+					texts = append(texts, part.Text)
+				}
+			}
+			normalized.Content = strings.Join(texts, "\n")
+		}
+		result = append(result, &normalized)
+	}
+	return result
+}
+
+// GetHistoryForModel 获取聊天历史并转换为目标模型支持的形式，参见 NormalizeForModel
+func (h *Manager) GetHistoryForModel(convID string, limit int, supported ...schema.ChatMessagePartType) ([]*schema.Message, error) {
+	messages, err := h.GetHistory(convID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeForModel(messages, supported...), nil
+}
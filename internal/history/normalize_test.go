@@ -0,0 +1,60 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestNormalizeForModel(t *testing.T) {
+	image := schema.ChatMessagePart{Type: schema.ChatMessagePartTypeImageURL, ImageURL: &schema.ChatMessageImageURL{URL: "data:image/png;base64,AA=="}}
+	audio := schema.ChatMessagePart{Type: schema.ChatMessagePartTypeAudioURL, AudioURL: &schema.ChatMessageAudioURL{URL: "data:audio/mp3;base64,AA=="}}
+	text := schema.ChatMessagePart{Type: schema.ChatMessagePartTypeText, Text: "这是什么"}
+
+	tests := []struct {
+		name        string
+		msg         *schema.Message
+		supported   []schema.ChatMessagePartType
+		wantContent string
+		wantParts   int
+	}{
+		{
+			name:        "plain text unchanged",
+			msg:         &schema.Message{Role: schema.User, Content: "你好"},
+			wantContent: "你好",
+		},
+		{
+			name:        "image replaced for text model",
+			msg:         &schema.Message{Role: schema.User, MultiContent: []schema.ChatMessagePart{text, image}},
+			wantContent: "这是什么\n[图片]",
+		},
+		{
+			name:      "image kept for multimodal model",
+			msg:       &schema.Message{Role: schema.User, MultiContent: []schema.ChatMessagePart{text, image, audio}},
+			supported: []schema.ChatMessagePartType{schema.ChatMessagePartTypeImageURL},
+			wantParts: 3,
+		},
+		{
+			name:        "assistant message flattened",
+			msg:         &schema.Message{Role: schema.Assistant, MultiContent: []schema.ChatMessagePart{text, image}},
+			supported:   []schema.ChatMessagePartType{schema.ChatMessagePartTypeImageURL},
+			wantContent: "这是什么\n[图片]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeForModel([]*schema.Message{tt.msg}, tt.supported...)
+			if len(got) != 1 {
+				t.Fatalf("NormalizeForModel() returned %d messages", len(got))
+			}
+			if got[0].Content != tt.wantContent || len(got[0].MultiContent) != tt.wantParts {
+				t.Errorf("NormalizeForModel() = %q with %d parts, want %q with %d parts",
+					got[0].Content, len(got[0].MultiContent), tt.wantContent, tt.wantParts)
+			}
+			if tt.wantParts > 0 && got[0].MultiContent[2].Text != "[音频]" {
+				t.Errorf("unsupported audio part = %+v, want placeholder", got[0].MultiContent[2])
+			}
+		})
+	}
+}
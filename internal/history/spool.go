@@ -0,0 +1,219 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// spoolRecord 溢出日志中的一条待保存消息，保留消息ID和入队时间，回放时按原时间写入并跳过已保存的消息
+type spoolRecord struct {
+	MsgID      string                 `json:"msg_id"`
+	ConvID     string                 `json:"conv_id"`
+	UserID     string                 `json:"user_id,omitempty"`   // 发起请求的用户，对话不存在时作为所有者
+	AuthorID   string                 `json:"author_id,omitempty"` // 发送消息的用户，助手消息为本轮提问的用户
+	CreateTime time.Time              `json:"create_time"`
+	Role       schema.RoleType        `json:"role"`
+	Content    string                 `json:"content"`
+	ModelID    string                 `json:"model_id,omitempty"`
+	TokensUsed int                    `json:"tokens_used,omitempty"`
+	LatencyMs  int                    `json:"latency_ms,omitempty"`
+	TraceID    string                 `json:"trace_id,omitempty"`
+	ToolCalls  []*schema.ToolCall     `json:"tool_calls,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Attempts   int                    `json:"attempts,omitempty"`   // 回放时记录本身保存失败的次数（不含数据库不可用）
+	LastError  string                 `json:"last_error,omitempty"` // 最近一次保存失败的原因
+}
+
+// message 还原为待保存的消息
+func (r *spoolRecord) message() *MessageWithMetrics {
+	return &MessageWithMetrics{
+		Message:    &schema.Message{Role: r.Role, Content: r.Content},
+		ModelID:    r.ModelID,
+		TokensUsed: r.TokensUsed,
+		LatencyMs:  r.LatencyMs,
+		TraceID:    r.TraceID,
+		ToolCalls:  r.ToolCalls,
+		Metadata:   r.Metadata,
+	}
+}
+
+// messageSpool 消息溢出日志（WAL），每行一条 JSON 记录，追加后立即刷盘。
+// 回放时先把日志改名为 .replay 文件再逐条保存，回放期间的新记录继续追加到日志中；
+// 进程在回放中途退出时，下次从 .replay 文件继续；多次保存失败的记录移入 .dead 死信文件，不再阻塞其他记录
+type messageSpool struct {
+	path        string
+	maxAttempts int // 记录保存失败达到该次数后移入死信文件
+	mu          sync.Mutex
+	pending     int // 日志和 .replay 文件中尚未回放的记录数
+}
+
+// defaultSpoolMaxAttempts 记录默认的最大保存次数
+const defaultSpoolMaxAttempts = 5
+
+// openSpool 打开溢出日志并统计未回放的记录数
+func openSpool(path string) (*messageSpool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	s := &messageSpool{path: path, maxAttempts: defaultSpoolMaxAttempts}
+	for _, p := range []string{s.replayPath(), path} {
+		records, err := readSpool(p)
+		if err != nil {
+			return nil, err
+		}
+		s.pending += len(records)
+	}
+	return s, nil
+}
+
+func (s *messageSpool) replayPath() string {
+	return s.path + ".replay"
+}
+
+func (s *messageSpool) deadPath() string {
+	return s.path + ".dead"
+}
+
+// append 追加记录并刷盘
+func (s *messageSpool) append(records ...*spoolRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := appendRecords(s.path, records); err != nil {
+		return err
+	}
+	s.pending += len(records)
+	return nil
+}
+
+// appendRecords 把记录追加到文件并刷盘
+func appendRecords(path string, records []*spoolRecord) error {
+	var buf []byte
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// replay 按写入顺序保存日志中的记录，未保存的记录写回日志等待下次回放：
+// transient 判断为暂时性错误（如数据库不可用）时停止回放，其余错误只计入该记录的失败次数并继续回放后面的记录，
+// 失败次数达到 maxAttempts 的记录移入死信文件，返回移入死信文件的记录数
+func (s *messageSpool) replay(save func(*spoolRecord) error, transient func(error) bool) (replayed, deadLettered int, err error) {
+	s.mu.Lock()
+	if _, statErr := os.Stat(s.replayPath()); errors.Is(statErr, os.ErrNotExist) {
+		if err = os.Rename(s.path, s.replayPath()); err != nil {
+			s.mu.Unlock()
+			if errors.Is(err, os.ErrNotExist) {
+				return 0, 0, nil
+			}
+			return 0, 0, err
+		}
+	}
+	s.mu.Unlock()
+
+	records, err := readSpool(s.replayPath())
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		saveErr    error
+		rest, dead []*spoolRecord
+	)
+	for _, record := range records {
+		if saveErr != nil {
+			rest = append(rest, record)
+			continue
+		}
+		recordErr := save(record)
+		switch {
+		case recordErr == nil:
+			replayed++
+		case transient(recordErr):
+			saveErr = recordErr
+			rest = append(rest, record)
+		default:
+			record.Attempts++
+			record.LastError = recordErr.Error()
+			if record.Attempts >= s.maxAttempts {
+				dead = append(dead, record)
+			} else {
+				rest = append(rest, record)
+			}
+		}
+	}
+	if len(dead) > 0 {
+		if err = appendRecords(s.deadPath(), dead); err != nil {
+			// 死信文件写入失败时记录留在日志中，下次回放时再处理
+			rest = append(rest, dead...)
+		} else {
+			deadLettered = len(dead)
+		}
+	}
+	s.mu.Lock()
+	s.pending -= len(records)
+	s.mu.Unlock()
+	if len(rest) > 0 {
+		if err = s.append(rest...); err != nil {
+			// 写回失败时保留 .replay 文件，下次回放时重新处理
+			s.mu.Lock()
+			s.pending += len(rest)
+			s.mu.Unlock()
+			return replayed, deadLettered, err
+		}
+	}
+	if err = os.Remove(s.replayPath()); err != nil {
+		return replayed, deadLettered, err
+	}
+	return replayed, deadLettered, saveErr
+}
+
+// size 尚未回放的记录数
+func (s *messageSpool) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// readSpool 读取日志文件，文件不存在时返回空；跳过无法解析的行（进程崩溃时写了一半的最后一行）
+func readSpool(path string) ([]*spoolRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*spoolRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		record := new(spoolRecord)
+		if json.Unmarshal(scanner.Bytes(), record) == nil && record.MsgID != "" {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,169 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spoolRecords(ids ...string) []*spoolRecord {
+	records := make([]*spoolRecord, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, &spoolRecord{MsgID: id, ConvID: "c1", Role: schema.Assistant, Content: "answer " + id, CreateTime: time.Unix(1700000000, 0)})
+	}
+	return records
+}
+
+// alwaysTransient 把所有保存错误视为数据库不可用
+func alwaysTransient(error) bool { return true }
+
+func TestMessageSpool_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool", "messages.wal")
+	spool, err := openSpool(path)
+	require.NoError(t, err)
+	require.NoError(t, spool.append(spoolRecords("m1", "m2", "m3")...))
+	assert.Equal(t, 3, spool.size())
+
+	// 第二条保存时数据库不可用：停止回放，未保存的记录写回日志
+	var saved []string
+	replayed, _, err := spool.replay(func(record *spoolRecord) error {
+		if record.MsgID == "m2" {
+			return errors.New("database unavailable")
+		}
+		saved = append(saved, record.MsgID)
+		return nil
+	}, alwaysTransient)
+	assert.Error(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, []string{"m1"}, saved)
+	assert.Equal(t, 2, spool.size())
+
+	// 重新打开时统计未回放的记录，回放保持写入顺序并保留原始内容
+	spool, err = openSpool(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, spool.size())
+	var restored []*spoolRecord
+	replayed, _, err = spool.replay(func(record *spoolRecord) error {
+		restored = append(restored, record)
+		return nil
+	}, alwaysTransient)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	require.Len(t, restored, 2)
+	assert.Equal(t, "m2", restored[0].MsgID)
+	assert.Equal(t, "answer m2", restored[0].message().Content)
+	assert.True(t, restored[0].CreateTime.Equal(time.Unix(1700000000, 0)))
+	assert.Equal(t, 0, spool.size())
+	_, err = os.Stat(path + ".replay")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	// 空日志不调用保存函数
+	replayed, _, err = spool.replay(func(*spoolRecord) error {
+		t.Fatal("unexpected save")
+		return nil
+	}, alwaysTransient)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, replayed)
+}
+
+func TestMessageSpool_InterruptedReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.wal")
+	spool, err := openSpool(path)
+	require.NoError(t, err)
+	require.NoError(t, spool.append(spoolRecords("m1")...))
+
+	// 模拟进程在回放中途退出：.replay 文件残留，最后一行只写了一半
+	require.NoError(t, os.Rename(path, path+".replay"))
+	f, err := os.OpenFile(path+".replay", os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"msg_id":"m9","conv_`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	spool, err = openSpool(path)
+	require.NoError(t, err)
+	require.NoError(t, spool.append(spoolRecords("m2")...))
+	assert.Equal(t, 2, spool.size())
+
+	var saved []string
+	replay := func(record *spoolRecord) error {
+		saved = append(saved, record.MsgID)
+		return nil
+	}
+	_, _, err = spool.replay(replay, alwaysTransient)
+	require.NoError(t, err)
+	_, _, err = spool.replay(replay, alwaysTransient)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1", "m2"}, saved)
+	assert.Equal(t, 0, spool.size())
+}
+
+func TestMessageSpool_PoisonRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.wal")
+	spool, err := openSpool(path)
+	require.NoError(t, err)
+	spool.maxAttempts = 2
+	require.NoError(t, spool.append(spoolRecords("m1", "m2", "m3")...))
+
+	// m1 始终违反约束：不阻塞后面的记录，达到最大次数后移入死信文件
+	var saved []string
+	save := func(record *spoolRecord) error {
+		if record.MsgID == "m1" {
+			return errors.New("duplicate key value violates unique constraint")
+		}
+		saved = append(saved, record.MsgID)
+		return nil
+	}
+	never := func(error) bool { return false }
+
+	replayed, dead, err := spool.replay(save, never)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, 0, dead)
+	assert.Equal(t, []string{"m2", "m3"}, saved)
+	assert.Equal(t, 1, spool.size())
+
+	replayed, dead, err = spool.replay(save, never)
+	require.NoError(t, err)
+	assert.Equal(t, 0, replayed)
+	assert.Equal(t, 1, dead)
+	assert.Equal(t, 0, spool.size())
+
+	records, err := readSpool(path + ".dead")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "m1", records[0].MsgID)
+	assert.Equal(t, 2, records[0].Attempts)
+	assert.Contains(t, records[0].LastError, "unique constraint")
+}
+
+// TestAsyncMessageSaver_SaveAfterShutdown 测试关闭期间和关闭后提交的消息写入溢出日志，不会向已关闭的队列发送
+func TestAsyncMessageSaver_SaveAfterShutdown(t *testing.T) {
+	spool, err := openSpool(filepath.Join(t.TempDir(), "messages.wal"))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	saver := &AsyncMessageSaver{taskQueue: make(chan *SaveTask, 4), spool: spool, ctx: ctx, cancel: cancel}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saver.SaveMessageAsync(context.Background(), &MessageWithMetrics{Message: &schema.Message{Role: schema.Assistant, Content: "answer"}}, "c1")
+		}()
+	}
+	saver.Shutdown()
+	wg.Wait()
+	saver.Shutdown()
+
+	saver.SaveMessageAsync(context.Background(), &MessageWithMetrics{Message: &schema.Message{Role: schema.Assistant, Content: "late"}}, "c1")
+	assert.Equal(t, 21, spool.size())
+	assert.Equal(t, int64(21), saver.spilled.Load())
+}
@@ -0,0 +1,119 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/client"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"chat_stream", map[string]int{"chat_stream": 1}, false},
+		{"chat=1, retriever=3", map[string]int{"chat": 1, "retriever": 3}, false},
+		{"search=1", nil, true},
+		{"chat=-1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMix(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseMix(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("ParseMix(%q)[%s] = %d, want %d", tt.input, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestParseRecorded(t *testing.T) {
+	input := `# recorded traffic
+{"endpoint":"chat_stream","request":{"question":"q1","knowledge_id":"kb"}}
+{"endpoint":"retriever","request":{"question":"q2","knowledge_id":"kb","embedding_model_id":"e"}}
+`
+	requests, err := parseRecorded(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0].Chat.Question != "q1" || requests[1].Retriever.EmbeddingModelID != "e" {
+		t.Fatalf("unexpected requests: %+v", requests)
+	}
+
+	source := &recordedSource{requests: requests}
+	first := source.Next()
+	if !strings.HasPrefix(first.Chat.ConvID, convIDPrefix) {
+		t.Errorf("conv id = %q, want generated %s prefix", first.Chat.ConvID, convIDPrefix)
+	}
+	if requests[0].Chat.ConvID != "" {
+		t.Error("Next should not modify the recorded request")
+	}
+
+	if _, err = parseRecorded(strings.NewReader(`{"endpoint":"upload","request":{}}`)); err == nil {
+		t.Error("expected error for unknown endpoint")
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	p := percentiles(samples)
+	if p.P50 != 50 || p.P90 != 90 || p.P99 != 99 || p.Max != 100 || p.Mean != 50.5 {
+		t.Errorf("percentiles() = %+v", p)
+	}
+	if percentiles(nil) != nil {
+		t.Error("percentiles(nil) should be nil")
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "/v1/chat") {
+			_, _ = w.Write([]byte(`{"code":50,"message":"model unavailable"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"message":"","data":{"document":[]}}`))
+	}))
+	defer server.Close()
+
+	source, err := NewSynthetic(SyntheticOptions{
+		Mix:              map[string]int{EndpointChat: 1, EndpointRetriever: 1},
+		KnowledgeID:      "kb",
+		EmbeddingModelID: "e",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Run(context.Background(), client.New(server.URL), source, Options{
+		QPS:         200,
+		Duration:    time.Second,
+		Concurrency: 10,
+		MaxRequests: 20,
+	})
+	if report.Sent+report.Dropped != 20 {
+		t.Fatalf("sent %d + dropped %d, want 20", report.Sent, report.Dropped)
+	}
+	for _, stats := range report.Endpoints {
+		switch stats.Endpoint {
+		case EndpointChat:
+			if stats.Errors != stats.Requests || len(stats.ErrorSamples) != 1 {
+				t.Errorf("chat stats = %+v, want all requests failed", stats)
+			}
+		case EndpointRetriever:
+			if stats.Errors != 0 || stats.Latency == nil {
+				t.Errorf("retriever stats = %+v, want no errors", stats)
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/client"
+)
+
+// maxErrorSamples 每个接口报告中保留的错误种类数
+const maxErrorSamples = 5
+
+// Report 压测报告
+type Report struct {
+	TargetQPS   float64          `json:"target_qps"`
+	ActualQPS   float64          `json:"actual_qps"` // 实际发送速率（不含丢弃的请求）
+	Sent        int              `json:"sent"`
+	Dropped     int              `json:"dropped"` // 达到并发上限未发送的请求数，不为 0 说明实例跟不上目标速率
+	DurationSec float64          `json:"duration_sec"`
+	Endpoints   []*EndpointStats `json:"endpoints"`
+}
+
+// EndpointStats 单个接口的统计
+type EndpointStats struct {
+	Endpoint     string         `json:"endpoint"`
+	Requests     int            `json:"requests"`
+	Errors       int            `json:"errors"`
+	ErrorRate    float64        `json:"error_rate"`
+	Latency      *Percentiles   `json:"latency_ms"`
+	TTFT         *Percentiles   `json:"ttft_ms,omitempty"` // 流式对话首字延迟
+	ErrorSamples []*ErrorSample `json:"error_samples,omitempty"`
+}
+
+// Percentiles 延迟分位数（毫秒）
+type Percentiles struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// ErrorSample 同类错误及出现次数
+type ErrorSample struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// collector 并发收集请求结果
+type collector struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	ttfts     map[string][]time.Duration
+	errors    map[string]map[string]int
+}
+
+func newCollector() *collector {
+	return &collector{
+		latencies: make(map[string][]time.Duration),
+		ttfts:     make(map[string][]time.Duration),
+		errors:    make(map[string]map[string]int),
+	}
+}
+
+func (c *collector) add(res *result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res.err != nil {
+		if c.errors[res.endpoint] == nil {
+			c.errors[res.endpoint] = make(map[string]int)
+		}
+		c.errors[res.endpoint][errorKey(res.err)]++
+		// 失败请求只计入错误率，不计入延迟
+		return
+	}
+	c.latencies[res.endpoint] = append(c.latencies[res.endpoint], res.latency)
+	if res.ttft > 0 {
+		c.ttfts[res.endpoint] = append(c.ttfts[res.endpoint], res.ttft)
+	}
+}
+
+// errorKey 错误归类：服务端错误按状态码和错误信息，其他错误（超时、连接失败）按错误信息
+func errorKey(err error) string {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return fmt.Sprintf("status %d: %s", apiErr.StatusCode, truncate(apiErr.Message, 120))
+	}
+	return truncate(err.Error(), 120)
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
+
+func (c *collector) report(sendDuration, total time.Duration) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report := &Report{DurationSec: total.Seconds()}
+	sent := 0
+	for _, endpoint := range []string{EndpointChat, EndpointChatStream, EndpointRetriever} {
+		latencies := c.latencies[endpoint]
+		errs := c.errors[endpoint]
+		if len(latencies) == 0 && len(errs) == 0 {
+			continue
+		}
+		stats := &EndpointStats{Endpoint: endpoint, Latency: percentiles(latencies), TTFT: percentiles(c.ttfts[endpoint])}
+		for message, count := range errs {
+			stats.Errors += count
+			stats.ErrorSamples = append(stats.ErrorSamples, &ErrorSample{Message: message, Count: count})
+		}
+		sort.Slice(stats.ErrorSamples, func(i, j int) bool {
+			if stats.ErrorSamples[i].Count != stats.ErrorSamples[j].Count {
+				return stats.ErrorSamples[i].Count > stats.ErrorSamples[j].Count
+			}
+			return stats.ErrorSamples[i].Message < stats.ErrorSamples[j].Message
+		})
+		if len(stats.ErrorSamples) > maxErrorSamples {
+			stats.ErrorSamples = stats.ErrorSamples[:maxErrorSamples]
+		}
+		stats.Requests = len(latencies) + stats.Errors
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Requests)
+		sent += stats.Requests
+		report.Endpoints = append(report.Endpoints, stats)
+	}
+	if sendDuration > 0 {
+		report.ActualQPS = float64(sent) / sendDuration.Seconds()
+	}
+	return report
+}
+
+// percentiles 计算延迟分位数（最近秩法），没有样本时返回 nil
+func percentiles(samples []time.Duration) *Percentiles {
+	if len(samples) == 0 {
+		return nil
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	rank := func(p float64) float64 {
+		idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return milliseconds(sorted[max(idx, 0)])
+	}
+	return &Percentiles{
+		Mean: milliseconds(sum / time.Duration(len(sorted))),
+		P50:  rank(50),
+		P90:  rank(90),
+		P95:  rank(95),
+		P99:  rank(99),
+		Max:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+// Write 以表格形式输出报告
+func (r *Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "duration: %.1fs  target qps: %.2f  actual qps: %.2f  sent: %d  dropped: %d\n\n",
+		r.DurationSec, r.TargetQPS, r.ActualQPS, r.Sent, r.Dropped)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "endpoint\trequests\terrors\terror rate\tmetric\tmean\tp50\tp90\tp95\tp99\tmax")
+	for _, stats := range r.Endpoints {
+		prefix := fmt.Sprintf("%s\t%d\t%d\t%.2f%%", stats.Endpoint, stats.Requests, stats.Errors, stats.ErrorRate*100)
+		fmt.Fprintf(tw, "%s\tlatency ms\t%s\n", prefix, formatPercentiles(stats.Latency))
+		if stats.TTFT != nil {
+			fmt.Fprintf(tw, "\t\t\t\tttft ms\t%s\n", formatPercentiles(stats.TTFT))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, stats := range r.Endpoints {
+		if len(stats.ErrorSamples) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s errors:\n", stats.Endpoint)
+		for _, sample := range stats.ErrorSamples {
+			fmt.Fprintf(w, "  %6d  %s\n", sample.Count, sample.Message)
+		}
+	}
+	return nil
+}
+
+func formatPercentiles(p *Percentiles) string {
+	if p == nil {
+		return strings.Repeat("-\t", 5) + "-"
+	}
+	return fmt.Sprintf("%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f", p.Mean, p.P50, p.P90, p.P95, p.P99, p.Max)
+}
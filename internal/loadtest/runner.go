@@ -0,0 +1,127 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/client"
+)
+
+// Options 压测参数
+type Options struct {
+	QPS         float64       // 目标每秒请求数
+	Duration    time.Duration // 发送请求的时长，结束后等待进行中的请求完成
+	Concurrency int           // 最大并发请求数，达到上限时本次请求跳过并计入 Dropped
+	MaxRequests int           // 最多发送的请求数，0 表示不限制
+}
+
+// result 一次请求的结果
+type result struct {
+	endpoint string
+	latency  time.Duration
+	ttft     time.Duration // 流式对话收到第一段回答内容的时间，非流式请求为 0
+	err      error
+}
+
+// Run 按固定速率发送请求直到达到时长或请求数上限，返回统计报告
+// 请求按开环方式发送：服务端变慢时不会降低发送速率，超过并发上限的请求被丢弃并计数
+func Run(ctx context.Context, c *client.Client, source Source, opts Options) *Report {
+	if opts.QPS <= 0 {
+		opts.QPS = 1
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	collector := newCollector()
+	slots := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.QPS))
+	defer ticker.Stop()
+	deadline := time.NewTimer(opts.Duration)
+	defer deadline.Stop()
+
+	start := time.Now()
+	sent, dropped := 0, 0
+loop:
+	for opts.MaxRequests <= 0 || sent+dropped < opts.MaxRequests {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			dropped++
+			continue
+		}
+		sent++
+		req := source.Next()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			collector.add(execute(ctx, c, req))
+		}()
+	}
+	sendDuration := time.Since(start)
+	wg.Wait()
+
+	report := collector.report(sendDuration, time.Since(start))
+	report.TargetQPS = opts.QPS
+	report.Sent = sent
+	report.Dropped = dropped
+	return report
+}
+
+// execute 发送一次请求并计时
+func execute(ctx context.Context, c *client.Client, req *Request) *result {
+	res := &result{endpoint: req.Endpoint}
+	start := time.Now()
+	switch req.Endpoint {
+	case EndpointChat:
+		_, res.err = c.Chat(ctx, req.Chat)
+	case EndpointChatStream:
+		res.ttft, res.err = streamChat(ctx, c, req, start)
+	case EndpointRetriever:
+		_, res.err = c.Retriever(ctx, req.Retriever)
+	default:
+		res.err = fmt.Errorf("unknown endpoint %q", req.Endpoint)
+	}
+	res.latency = time.Since(start)
+	return res
+}
+
+// streamChat 读取完整的流式回答，返回首字延迟
+func streamChat(ctx context.Context, c *client.Client, req *Request, start time.Time) (time.Duration, error) {
+	stream, err := c.ChatStream(ctx, req.Chat)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	var ttft time.Duration
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ttft, err
+		}
+		if ttft == 0 && chunk.Event == client.EventData && chunk.Content != "" {
+			ttft = time.Since(start)
+		}
+	}
+	if ttft == 0 {
+		return 0, fmt.Errorf("stream finished without answer content")
+	}
+	return ttft, nil
+}
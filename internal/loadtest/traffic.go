@@ -0,0 +1,273 @@
+// Package loadtest 对运行中的 kbgo 实例回放录制的或合成的对话/检索流量，统计各接口的延迟分位数、
+// 流式首字延迟和错误率，用于调优异步消息保存、向量库等组件时得到可复现的性能数据
+package loadtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/google/uuid"
+)
+
+// 压测的接口
+const (
+	EndpointChat       = "chat"        // 非流式对话 /v1/chat
+	EndpointChatStream = "chat_stream" // 流式对话 /v1/chat（stream=true），统计首字延迟
+	EndpointRetriever  = "retriever"   // 检索 /v1/retriever
+)
+
+// convIDPrefix 压测生成的会话ID前缀，便于压测后清理
+const convIDPrefix = "loadtest-"
+
+// defaultQuestions 未指定问题文件时合成流量使用的问题
+var defaultQuestions = []string{
+	"这个系统支持哪些文件格式？",
+	"如何创建一个新的知识库？",
+	"文档上传后多久可以被检索到？",
+	"检索模式 rerank 和 rrf 有什么区别？",
+	"如何配置 embedding 模型？",
+	"怎么删除知识库中的某个文档？",
+	"MCP 工具调用失败时如何排查？",
+	"对话历史会保存多久？",
+	"如何提高检索结果的准确率？",
+	"系统支持哪些向量数据库？",
+}
+
+// Request 一次压测请求，Chat 和 Retriever 按 Endpoint 二选一
+type Request struct {
+	Endpoint  string
+	Chat      *v1.ChatReq
+	Retriever *v1.RetrieverReq
+}
+
+// Source 压测流量来源，Next 每次返回一个新的请求
+type Source interface {
+	Next() *Request
+}
+
+// record 录制流量文件中的一行：{"endpoint":"chat","request":{...}}，request 与接口请求体相同
+type record struct {
+	Endpoint string          `json:"endpoint"`
+	Request  json.RawMessage `json:"request"`
+}
+
+// recordedSource 按顺序循环回放录制的请求
+type recordedSource struct {
+	mu       sync.Mutex
+	requests []*Request
+	next     int
+}
+
+// LoadRecorded 读取录制的流量文件（JSONL，每行一个请求）
+func LoadRecorded(path string) (Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	requests, err := parseRecorded(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &recordedSource{requests: requests}, nil
+}
+
+func parseRecorded(r io.Reader) ([]*Request, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var requests []*Request
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		req := &Request{Endpoint: rec.Endpoint}
+		var err error
+		switch rec.Endpoint {
+		case EndpointChat, EndpointChatStream:
+			req.Chat = &v1.ChatReq{}
+			err = json.Unmarshal(rec.Request, req.Chat)
+		case EndpointRetriever:
+			req.Retriever = &v1.RetrieverReq{}
+			err = json.Unmarshal(rec.Request, req.Retriever)
+		default:
+			err = fmt.Errorf("unknown endpoint %q", rec.Endpoint)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests recorded")
+	}
+	return requests, nil
+}
+
+func (s *recordedSource) Next() *Request {
+	s.mu.Lock()
+	req := s.requests[s.next]
+	s.next = (s.next + 1) % len(s.requests)
+	s.mu.Unlock()
+	return req.clone()
+}
+
+// clone 复制请求，未指定会话ID的对话请求使用新会话，避免会话历史随压测增长影响延迟
+func (r *Request) clone() *Request {
+	out := &Request{Endpoint: r.Endpoint}
+	if r.Chat != nil {
+		chat := *r.Chat
+		if chat.ConvID == "" {
+			chat.ConvID = convIDPrefix + uuid.NewString()
+		}
+		out.Chat = &chat
+	}
+	if r.Retriever != nil {
+		retriever := *r.Retriever
+		out.Retriever = &retriever
+	}
+	return out
+}
+
+// SyntheticOptions 合成流量参数
+type SyntheticOptions struct {
+	Questions        []string       // 问题列表，为空时使用内置问题
+	Mix              map[string]int // 各接口的流量权重，如 {"chat": 1, "retriever": 2}
+	KnowledgeID      string         // 为空时对话不启用检索，且不发送检索请求
+	ModelID          string
+	EmbeddingModelID string
+	RerankModelID    string
+	TopK             int
+	Seed             int64
+}
+
+// syntheticSource 按权重随机选择接口，按顺序轮换问题
+type syntheticSource struct {
+	mu        sync.Mutex
+	opts      SyntheticOptions
+	endpoints []string
+	weights   []int
+	total     int
+	rand      *rand.Rand
+	next      int
+}
+
+// NewSynthetic 创建合成流量来源
+func NewSynthetic(opts SyntheticOptions) (Source, error) {
+	if len(opts.Questions) == 0 {
+		opts.Questions = defaultQuestions
+	}
+	s := &syntheticSource{opts: opts, rand: rand.New(rand.NewSource(opts.Seed))}
+	for _, endpoint := range []string{EndpointChat, EndpointChatStream, EndpointRetriever} {
+		weight := opts.Mix[endpoint]
+		if weight <= 0 {
+			continue
+		}
+		if endpoint == EndpointRetriever && (opts.KnowledgeID == "" || opts.EmbeddingModelID == "") {
+			return nil, fmt.Errorf("retriever traffic requires a knowledge base and an embedding model")
+		}
+		s.endpoints = append(s.endpoints, endpoint)
+		s.weights = append(s.weights, weight)
+		s.total += weight
+	}
+	if s.total == 0 {
+		return nil, fmt.Errorf("traffic mix has no endpoint with a positive weight")
+	}
+	return s, nil
+}
+
+func (s *syntheticSource) Next() *Request {
+	s.mu.Lock()
+	question := s.opts.Questions[s.next%len(s.opts.Questions)]
+	s.next++
+	pick := s.rand.Intn(s.total)
+	s.mu.Unlock()
+
+	endpoint := s.endpoints[len(s.endpoints)-1]
+	for i, weight := range s.weights {
+		if pick < weight {
+			endpoint = s.endpoints[i]
+			break
+		}
+		pick -= weight
+	}
+
+	if endpoint == EndpointRetriever {
+		return &Request{Endpoint: endpoint, Retriever: &v1.RetrieverReq{
+			Question:         question,
+			KnowledgeId:      s.opts.KnowledgeID,
+			EmbeddingModelID: s.opts.EmbeddingModelID,
+			RerankModelID:    s.opts.RerankModelID,
+			TopK:             s.opts.TopK,
+		}}
+	}
+	return &Request{Endpoint: endpoint, Chat: &v1.ChatReq{
+		ConvID:           convIDPrefix + uuid.NewString(),
+		Question:         question,
+		ModelID:          s.opts.ModelID,
+		KnowledgeId:      s.opts.KnowledgeID,
+		EnableRetriever:  s.opts.KnowledgeID != "",
+		EmbeddingModelID: s.opts.EmbeddingModelID,
+		RerankModelID:    s.opts.RerankModelID,
+		TopK:             s.opts.TopK,
+	}}
+}
+
+// ParseMix 解析流量权重，格式为 "chat=1,chat_stream=1,retriever=2"
+func ParseMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if name != EndpointChat && name != EndpointChatStream && name != EndpointRetriever {
+			return nil, fmt.Errorf("unknown endpoint %q in traffic mix", name)
+		}
+		weight := 1
+		if ok {
+			var err error
+			if weight, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight %q for endpoint %s", value, name)
+			}
+		}
+		mix[name] = weight
+	}
+	return mix, nil
+}
+
+// LoadQuestions 读取问题文件，每行一个问题
+func LoadQuestions(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var questions []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			questions = append(questions, line)
+		}
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("%s: no questions", path)
+	}
+	return questions, nil
+}
@@ -0,0 +1,294 @@
+// Package agentrun 记录一次工具调用执行（agent run）的摘要：调用的工具及用时、返回的数据行数、
+// 生成的工作区文件和消耗的 token，以事件返回给调用方并保存到助手消息元数据
+package agentrun
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// MetadataKey 助手消息元数据中保存执行摘要的字段
+const MetadataKey = "agent_run"
+
+// 工具调用状态
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+	StatusDenied  = "denied"
+	StatusTimeout = "timeout"
+)
+
+// persistAttempts 非流式对话中等待助手消息异步保存完成的查询次数，persistInterval 为查询间隔
+const (
+	persistAttempts = 10
+	persistInterval = 300 * time.Millisecond
+)
+
+// Recorder 收集一次执行的摘要，方法可在 nil 上调用，上下文中没有 Recorder 时不记录
+type Recorder struct {
+	mu       sync.Mutex
+	start    time.Time
+	finished bool
+	summary  v1.AgentRunSummary
+}
+
+type contextKey struct{}
+
+// WithContext 在上下文中创建执行摘要记录器
+func WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &Recorder{})
+}
+
+// FromContext 获取上下文中的记录器，没有时返回 nil
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(contextKey{}).(*Recorder)
+	return r
+}
+
+// Start 开始计时
+func (r *Recorder) Start() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start = time.Now()
+}
+
+// AddIteration 记录一轮模型调用及其消耗的 token
+func (r *Recorder) AddIteration(tokens int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Iterations++
+	r.summary.TokensUsed += tokens
+}
+
+// RecordPrompt 记录一轮模型调用的输入 token 估算值
+func (r *Recorder) RecordPrompt(tokens int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.PromptTokens = append(r.summary.PromptTokens, tokens)
+}
+
+// Stop 记录提前结束工具调用的原因，只保留第一个原因
+func (r *Recorder) Stop(reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.summary.StopReason == "" {
+		r.summary.StopReason = reason
+	}
+}
+
+// AddTokens 记录不计入轮数的模型调用（如强制生成最终答案）消耗的 token
+func (r *Recorder) AddTokens(tokens int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.TokensUsed += tokens
+}
+
+// RecordTool 记录一次工具调用，成功时按结果内容统计返回的数据行数
+func (r *Recorder) RecordTool(serviceName, toolName, status string, duration time.Duration, content string, err error) {
+	if r == nil {
+		return
+	}
+	run := &v1.AgentToolRun{
+		ServiceName: serviceName,
+		ToolName:    toolName,
+		Status:      status,
+		DurationMs:  duration.Milliseconds(),
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if status == StatusSuccess {
+		run.Rows = CountRows(content)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Tools = append(r.summary.Tools, run)
+	r.summary.RowsReturned += run.Rows
+}
+
+// RecordFile 记录写入会话工作区的文件
+func (r *Recorder) RecordFile(name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.summary.FilesGenerated {
+		if existing == name {
+			return
+		}
+	}
+	r.summary.FilesGenerated = append(r.summary.FilesGenerated, name)
+}
+
+// Finish 结束记录并返回摘要
+func (r *Recorder) Finish() *v1.AgentRunSummary {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	if !r.finished {
+		r.finished = true
+		if !r.start.IsZero() {
+			r.summary.DurationMs = time.Since(r.start).Milliseconds()
+		}
+	}
+	r.mu.Unlock()
+	return r.Summary()
+}
+
+// Summary 返回已结束的执行摘要的副本，未执行或未结束时返回 nil
+func (r *Recorder) Summary() *v1.AgentRunSummary {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.finished {
+		return nil
+	}
+	summary := r.summary
+	summary.Tools = append([]*v1.AgentToolRun{}, r.summary.Tools...)
+	summary.FilesGenerated = append([]string(nil), r.summary.FilesGenerated...)
+	summary.PromptTokens = append([]int(nil), r.summary.PromptTokens...)
+	return &summary
+}
+
+// Tag 在助手消息元数据中记录上下文中已结束的执行摘要
+func Tag(ctx context.Context, metadata map[string]interface{}) map[string]interface{} {
+	summary := FromContext(ctx).Summary()
+	if summary == nil {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata[MetadataKey] = summary
+	return metadata
+}
+
+// Persist 把执行摘要写入本轮的助手消息元数据。非流式对话的工具调用在回答保存之后执行，
+// 而消息是异步保存的，因此在后台等待 since 之后创建的助手消息出现后再更新，写入失败只记录日志
+func Persist(ctx context.Context, convID string, since time.Time, summary *v1.AgentRunSummary) {
+	if summary == nil || convID == "" {
+		return
+	}
+	// 数据库时间可能只精确到秒
+	since = since.Truncate(time.Second)
+	common.SafeGoDetached(ctx, "PersistAgentRunSummary", func(ctx context.Context) {
+		for attempt := 0; attempt < persistAttempts; attempt++ {
+			msg, err := dao.Message.GetLatestByRole(ctx, convID, "assistant")
+			if err != nil {
+				g.Log().Warningf(ctx, "Failed to load assistant message for agent run summary, convID=%s: %v", convID, err)
+				return
+			}
+			if msg != nil && msg.CreateTime != nil && !msg.CreateTime.Before(since) {
+				if err = updateMetadata(ctx, msg, summary); err != nil {
+					g.Log().Warningf(ctx, "Failed to save agent run summary, msgID=%s: %v", msg.MsgID, err)
+				}
+				return
+			}
+			time.Sleep(persistInterval)
+		}
+		g.Log().Warningf(ctx, "Assistant message not saved in time, agent run summary dropped, convID=%s", convID)
+	})
+}
+
+func updateMetadata(ctx context.Context, msg *gormModel.Message, summary *v1.AgentRunSummary) error {
+	metadata := map[string]interface{}{}
+	if len(msg.Metadata) > 0 {
+		if err := json.Unmarshal(msg.Metadata, &metadata); err != nil {
+			return err
+		}
+	}
+	metadata[MetadataKey] = summary
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return dao.Message.UpdateMetadata(ctx, msg.MsgID, gormModel.JSON(data))
+}
+
+// CountRows 统计工具结果中的数据行数：JSON 数组按元素数，包含 rows/data/items/results 数组的 JSON 对象按该数组元素数，
+// Markdown 表格按数据行数（不含表头和分隔行），无法识别时返回 0
+func CountRows(content string) int {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return 0
+	}
+	if strings.HasPrefix(content, "[") || strings.HasPrefix(content, "{") {
+		var value interface{}
+		if err := json.Unmarshal([]byte(content), &value); err == nil {
+			return jsonRows(value)
+		}
+	}
+	return tableRows(content)
+}
+
+func jsonRows(value interface{}) int {
+	switch v := value.(type) {
+	case []interface{}:
+		return len(v)
+	case map[string]interface{}:
+		for _, key := range []string{"rows", "data", "items", "results"} {
+			if list, ok := v[key].([]interface{}); ok {
+				return len(list)
+			}
+		}
+	}
+	return 0
+}
+
+// tableRows 统计 Markdown 表格的数据行数，表格需要有 |---| 分隔行
+func tableRows(content string) int {
+	rows, inTable := 0, false
+	var previous string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			inTable = false
+			previous = line
+			continue
+		}
+		switch {
+		case inTable:
+			rows++
+		case isSeparatorRow(line) && strings.HasPrefix(previous, "|"):
+			inTable = true
+		}
+		previous = line
+	}
+	return rows
+}
+
+func isSeparatorRow(line string) bool {
+	trimmed := strings.Trim(line, "| ")
+	if trimmed == "" || !strings.Contains(trimmed, "-") {
+		return false
+	}
+	return strings.Trim(trimmed, "-:| ") == ""
+}
@@ -0,0 +1,77 @@
+package agentrun
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCountRows 测试工具结果数据行数的识别
+func TestCountRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{name: "empty", content: "", want: 0},
+		{name: "json array", content: `[{"id":1},{"id":2},{"id":3}]`, want: 3},
+		{name: "json object with rows", content: `{"columns":["id"],"rows":[[1],[2]]}`, want: 2},
+		{name: "json object without list", content: `{"status":"ok"}`, want: 0},
+		{name: "markdown table", content: "查询结果：\n| id | name |\n|---|:---:|\n| 1 | a |\n| 2 | b |\n\n共 2 行", want: 2},
+		{name: "pipes without separator", content: "| not | a table |\n| still | text |", want: 0},
+		{name: "plain text", content: "已保存文件 result.csv", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountRows(tt.content); got != tt.want {
+				t.Errorf("CountRows() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRecorder 测试执行摘要的记录，结束前不返回摘要
+func TestRecorder(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Fatal("expected no recorder in background context")
+	}
+	// nil 记录器的方法不做任何事
+	var none *Recorder
+	none.RecordTool("svc", "tool", StatusSuccess, time.Second, "[1]", nil)
+	if none.Finish() != nil {
+		t.Error("nil recorder should return no summary")
+	}
+
+	ctx := WithContext(context.Background())
+	run := FromContext(ctx)
+	run.Start()
+	run.AddIteration(100)
+	run.RecordTool("db", "query", StatusSuccess, 20*time.Millisecond, `[{"a":1},{"a":2}]`, nil)
+	run.RecordTool("db", "drop", StatusDenied, 0, "", errors.New("denied by policy"))
+	run.RecordTool("workspace", "write_file", StatusSuccess, time.Millisecond, "", nil)
+	run.RecordFile("result.csv")
+	run.RecordFile("result.csv")
+	run.AddIteration(50)
+	run.AddTokens(30)
+
+	if Tag(ctx, nil) != nil {
+		t.Error("unfinished run should not be tagged")
+	}
+	summary := run.Finish()
+	if summary == nil {
+		t.Fatal("expected summary after finish")
+	}
+	if summary.Iterations != 2 || summary.TokensUsed != 180 || summary.RowsReturned != 2 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if len(summary.Tools) != 3 || summary.Tools[1].Status != StatusDenied || summary.Tools[1].Error == "" {
+		t.Errorf("tools = %+v", summary.Tools)
+	}
+	if len(summary.FilesGenerated) != 1 || summary.FilesGenerated[0] != "result.csv" {
+		t.Errorf("files = %v", summary.FilesGenerated)
+	}
+	if metadata := Tag(ctx, nil); metadata[MetadataKey] == nil {
+		t.Errorf("finished run should be tagged, got %v", metadata)
+	}
+}
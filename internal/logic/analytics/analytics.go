@@ -0,0 +1,267 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+const (
+	// DateLayout 汇总表使用的日期格式
+	DateLayout = "2006-01-02"
+
+	// FeedbackMetadataKey 助手消息元数据中记录用户反馈的字段
+	FeedbackMetadataKey = "feedback"
+	FeedbackPositive    = "positive"
+	FeedbackNegative    = "negative"
+)
+
+var lowScoreThreshold float64
+
+// InitAnalytics 初始化统计汇总任务
+// 定时刷新今天和昨天的汇总数据，昨天的数据用于覆盖跨零点写入的消息
+func InitAnalytics() {
+	ctx := gctx.New()
+
+	lowScoreThreshold = g.Cfg().MustGet(ctx, "analytics.lowScoreThreshold", 0.3).Float64()
+	if !g.Cfg().MustGet(ctx, "analytics.enable", true).Bool() {
+		g.Log().Info(ctx, "Analytics rollup is disabled")
+		return
+	}
+
+	pattern := g.Cfg().MustGet(ctx, "analytics.rollupCron", "0 */10 * * * *").String()
+	_, err := gcron.AddSingleton(ctx, pattern, func(ctx context.Context) {
+		now := time.Now()
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			if err := RunRollup(ctx, day); err != nil {
+				g.Log().Errorf(ctx, "Analytics rollup failed for %s: %v", day.Format(DateLayout), err)
+			}
+		}
+	}, "analytics-rollup")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule analytics rollup: %v", err)
+	} else {
+		g.Log().Infof(ctx, "Analytics rollup scheduled with pattern: %s", pattern)
+	}
+
+	// 知识缺口挖掘任务（默认每天凌晨3点）
+	gapPattern := g.Cfg().MustGet(ctx, "analytics.gapCron", "0 0 3 * * *").String()
+	_, err = gcron.AddSingleton(ctx, gapPattern, func(ctx context.Context) {
+		if _, _, err := RunKnowledgeGapJob(ctx, 0); err != nil {
+			g.Log().Errorf(ctx, "Knowledge gap job failed: %v", err)
+		}
+	}, "analytics-knowledge-gap")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule knowledge gap job: %v", err)
+	} else {
+		g.Log().Infof(ctx, "Knowledge gap job scheduled with pattern: %s", gapPattern)
+	}
+}
+
+// RunRollup 重新计算指定日期的汇总数据并覆盖写入
+func RunRollup(ctx context.Context, day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	statDate := start.Format(DateLayout)
+
+	dailyRows, err := buildDailyRollups(ctx, statDate, start, end)
+	if err != nil {
+		return err
+	}
+
+	toolStats, err := dao.Analytics.AggregateToolCalls(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	toolRows := make([]*gormModel.AnalyticsToolRollup, 0, len(toolStats))
+	for _, stat := range toolStats {
+		toolRows = append(toolRows, &gormModel.AnalyticsToolRollup{
+			StatDate:    statDate,
+			ServiceName: stat.ServiceName,
+			ToolName:    stat.ToolName,
+			CallCount:   stat.CallCount,
+			FailedCount: stat.FailedCount,
+			AvgDuration: stat.AvgDuration,
+		})
+	}
+
+	unansweredRows, err := buildUnansweredRollups(ctx, statDate, start, end)
+	if err != nil {
+		return err
+	}
+
+	if err = dao.Analytics.ReplaceRollups(ctx, statDate, dailyRows, toolRows, unansweredRows); err != nil {
+		return err
+	}
+
+	g.Log().Debugf(ctx, "Analytics rollup done for %s: %d model rows, %d tool rows, %d unanswered rows",
+		statDate, len(dailyRows), len(toolRows), len(unansweredRows))
+	return nil
+}
+
+// buildDailyRollups 计算按模型分组的会话/消息/反馈汇总
+func buildDailyRollups(ctx context.Context, statDate string, start, end time.Time) ([]*gormModel.AnalyticsDailyRollup, error) {
+	rollups := make(map[string]*gormModel.AnalyticsDailyRollup)
+	get := func(modelName string) *gormModel.AnalyticsDailyRollup {
+		if r, ok := rollups[modelName]; ok {
+			return r
+		}
+		r := &gormModel.AnalyticsDailyRollup{StatDate: statDate, ModelName: modelName}
+		rollups[modelName] = r
+		return r
+	}
+
+	convStats, err := dao.Analytics.CountActiveConversations(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range convStats {
+		get(stat.ModelName).ConversationCount = stat.ConversationCount
+	}
+
+	msgStats, err := dao.Analytics.AggregateMessages(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range msgStats {
+		r := get(stat.ModelName)
+		r.MessageCount = stat.MessageCount
+		r.AssistantCount = stat.AssistantCount
+		r.TotalTokens = stat.TotalTokens
+		r.AvgLatencyMs = stat.AvgLatencyMs
+	}
+
+	toolCounts, err := dao.Analytics.CountToolCallsByModel(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for modelName, count := range toolCounts {
+		get(modelName).ToolCallCount = count
+	}
+
+	// 反馈存放在消息元数据中，数据库间 JSON 查询语法不一致，这里在内存中统计
+	messages, err := dao.Analytics.ListMessageMetadata(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	feedbackByConv := make(map[string][2]int64)
+	for _, msg := range messages {
+		feedback := parseFeedback(msg.Metadata)
+		if feedback == "" {
+			continue
+		}
+		counts := feedbackByConv[msg.ConvID]
+		if feedback == FeedbackPositive {
+			counts[0]++
+		} else {
+			counts[1]++
+		}
+		feedbackByConv[msg.ConvID] = counts
+	}
+	if len(feedbackByConv) > 0 {
+		convIDs := make([]string, 0, len(feedbackByConv))
+		for convID := range feedbackByConv {
+			convIDs = append(convIDs, convID)
+		}
+		convModels, err := dao.Analytics.MapConversationModels(ctx, convIDs)
+		if err != nil {
+			return nil, err
+		}
+		for convID, counts := range feedbackByConv {
+			r := get(convModels[convID])
+			r.PositiveFeedback += counts[0]
+			r.NegativeFeedback += counts[1]
+		}
+	}
+
+	result := make([]*gormModel.AnalyticsDailyRollup, 0, len(rollups))
+	for _, r := range rollups {
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// buildUnansweredRollups 按知识库和归一化问题聚合低分检索记录
+func buildUnansweredRollups(ctx context.Context, statDate string, start, end time.Time) ([]*gormModel.AnalyticsUnansweredRollup, error) {
+	misses, err := dao.Analytics.ListRetrievalMisses(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string]*gormModel.AnalyticsUnansweredRollup)
+	var order []string
+	for _, miss := range misses {
+		question := NormalizeQuestion(miss.Question)
+		if question == "" {
+			continue
+		}
+		key := miss.KnowledgeID + "\x00" + question
+		r, ok := grouped[key]
+		if !ok {
+			r = &gormModel.AnalyticsUnansweredRollup{
+				StatDate:    statDate,
+				KnowledgeID: miss.KnowledgeID,
+				Question:    question,
+				MaxScore:    miss.MaxScore,
+			}
+			grouped[key] = r
+			order = append(order, key)
+		}
+		r.MissCount++
+		if miss.MaxScore > r.MaxScore {
+			r.MaxScore = miss.MaxScore
+		}
+	}
+
+	result := make([]*gormModel.AnalyticsUnansweredRollup, 0, len(order))
+	for _, key := range order {
+		result = append(result, grouped[key])
+	}
+	return result, nil
+}
+
+// RecordRetrievalMiss 当检索无结果或最高分低于阈值时，异步记录该问题
+func RecordRetrievalMiss(ctx context.Context, knowledgeID, question string, maxScore float64, documentCount int) {
+	if documentCount > 0 && maxScore >= lowScoreThreshold {
+		return
+	}
+	common.SafeGoDetached(ctx, "record-retrieval-miss", func(ctx context.Context) {
+		_ = dao.Analytics.CreateRetrievalMiss(ctx, &gormModel.RetrievalMissLog{
+			KnowledgeID:   knowledgeID,
+			Question:      question,
+			MaxScore:      maxScore,
+			DocumentCount: documentCount,
+		})
+	})
+}
+
+// NormalizeQuestion 归一化问题文本（去除首尾空白、合并空白、转小写），用于聚合相同问题
+func NormalizeQuestion(question string) string {
+	return strings.ToLower(strings.Join(strings.Fields(question), " "))
+}
+
+// parseFeedback 从消息元数据中解析反馈值
+func parseFeedback(metadata gormModel.JSON) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(metadata, &m); err != nil {
+		return ""
+	}
+	feedback, _ := m[FeedbackMetadataKey].(string)
+	switch feedback {
+	case FeedbackPositive, FeedbackNegative:
+		return feedback
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,52 @@
+package analytics
+
+import (
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// TestNormalizeQuestion 测试问题归一化
+func TestNormalizeQuestion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Trim and lower", input: "  How To Reset Password  ", expected: "how to reset password"},
+		{name: "Collapse whitespace", input: "如何\t重置\n\n密码", expected: "如何 重置 密码"},
+		{name: "Empty", input: "   ", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQuestion(tt.input); got != tt.expected {
+				t.Errorf("NormalizeQuestion(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseFeedback 测试从消息元数据解析反馈
+func TestParseFeedback(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata gormModel.JSON
+		expected string
+	}{
+		{name: "Positive", metadata: gormModel.JSON(`{"feedback": "positive"}`), expected: FeedbackPositive},
+		{name: "Negative", metadata: gormModel.JSON(`{"feedback": "negative"}`), expected: FeedbackNegative},
+		{name: "Unknown value", metadata: gormModel.JSON(`{"feedback": "meh"}`), expected: ""},
+		{name: "No feedback", metadata: gormModel.JSON(`{"other": 1}`), expected: ""},
+		{name: "Invalid JSON", metadata: gormModel.JSON(`{invalid`), expected: ""},
+		{name: "Empty", metadata: nil, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFeedback(tt.metadata); got != tt.expected {
+				t.Errorf("parseFeedback() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
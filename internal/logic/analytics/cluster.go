@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"math"
+)
+
+// questionCluster 问题语义聚类结果
+type questionCluster struct {
+	centroid []float32 // 聚类中心向量（成员向量均值）
+	members  []int     // 成员在输入中的下标
+}
+
+// clusterBySimilarity 按余弦相似度对向量做单遍贪心聚类
+// 每个向量归入与其中心相似度最高且不低于 threshold 的聚类，否则新建聚类
+func clusterBySimilarity(vectors [][]float32, threshold float64) []*questionCluster {
+	var clusters []*questionCluster
+	for i, vec := range vectors {
+		if len(vec) == 0 {
+			continue
+		}
+		var best *questionCluster
+		bestScore := threshold
+		for _, c := range clusters {
+			if score := cosineSimilarity(vec, c.centroid); score >= bestScore {
+				best = c
+				bestScore = score
+			}
+		}
+		if best == nil {
+			centroid := make([]float32, len(vec))
+			copy(centroid, vec)
+			clusters = append(clusters, &questionCluster{centroid: centroid, members: []int{i}})
+			continue
+		}
+		// 增量更新聚类中心
+		n := float32(len(best.members))
+		for k := range best.centroid {
+			if k < len(vec) {
+				best.centroid[k] = (best.centroid[k]*n + vec[k]) / (n + 1)
+			}
+		}
+		best.members = append(best.members, i)
+	}
+	return clusters
+}
+
+// cosineSimilarity 计算两个向量的余弦相似度，维度不一致或零向量时返回 0
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCosineSimilarity 测试余弦相似度计算
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []float32
+		expected float64
+	}{
+		{name: "Identical", a: []float32{1, 2, 3}, b: []float32{1, 2, 3}, expected: 1},
+		{name: "Orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, expected: 0},
+		{name: "Opposite", a: []float32{1, 0}, b: []float32{-1, 0}, expected: -1},
+		{name: "Dimension mismatch", a: []float32{1, 0}, b: []float32{1}, expected: 0},
+		{name: "Zero vector", a: []float32{0, 0}, b: []float32{1, 1}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.expected) > 1e-6 {
+				t.Errorf("cosineSimilarity() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestClusterBySimilarity 测试贪心聚类
+func TestClusterBySimilarity(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0, 0},
+		{0.95, 0.05, 0},
+		{0, 1, 0},
+		{0, 0.98, 0.02},
+		{0, 0, 1},
+		nil, // 空向量应被忽略
+	}
+
+	clusters := clusterBySimilarity(vectors, 0.9)
+	if len(clusters) != 3 {
+		t.Fatalf("expected 3 clusters, got %d", len(clusters))
+	}
+
+	expected := [][]int{{0, 1}, {2, 3}, {4}}
+	for i, c := range clusters {
+		if len(c.members) != len(expected[i]) {
+			t.Fatalf("cluster %d: expected members %v, got %v", i, expected[i], c.members)
+		}
+		for j := range c.members {
+			if c.members[j] != expected[i][j] {
+				t.Errorf("cluster %d: expected members %v, got %v", i, expected[i], c.members)
+			}
+		}
+	}
+}
+
+// TestBuildKnowledgeGap 测试缺口问题数按不同问题计数，而不是出现次数
+func TestBuildKnowledgeGap(t *testing.T) {
+	questions := []*gapQuestion{
+		{Question: "如何退款", LowScoreCount: 5, NegativeCount: 2},
+		{Question: "怎么申请退款", LowScoreCount: 1},
+		{Question: "发票怎么开", LowScoreCount: 3},
+	}
+	gap := buildKnowledgeGap(questions, &questionCluster{members: []int{0, 1}})
+	if gap.QuestionCount != 2 || gap.LowScoreCount != 6 || gap.NegativeCount != 2 || gap.Topic != "如何退款" {
+		t.Errorf("gap = {topic %q, questions %d, lowScore %d, negative %d}, want {如何退款, 2, 6, 2}",
+			gap.Topic, gap.QuestionCount, gap.LowScoreCount, gap.NegativeCount)
+	}
+}
+
+// TestTraceKnowledgeID 测试按检索排名取第一个能找到知识库的分片
+func TestTraceKnowledgeID(t *testing.T) {
+	chunkKnowledge := map[string]string{"c2": "kb-a", "c3": "kb-b"}
+	if got := traceKnowledgeID([]string{"c1", "c2", "c3"}, chunkKnowledge); got != "kb-a" {
+		t.Errorf("traceKnowledgeID() = %q, want kb-a", got)
+	}
+	if got := traceKnowledgeID(nil, chunkKnowledge); got != "" {
+		t.Errorf("traceKnowledgeID(nil) = %q, want empty", got)
+	}
+}
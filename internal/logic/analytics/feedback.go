@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// RecordFeedback 记录用户对助手消息的反馈和点击的参考分片
+// feedback 为空时保留原有反馈，点击的分片追加到已有记录中（去重）
+func RecordFeedback(ctx context.Context, msgID string, feedback string, clickedChunkIDs []string) (string, []string, error) {
+	msg, err := dao.Message.GetByMsgID(ctx, msgID)
+	if err != nil {
+		return "", nil, err
+	}
+	if msg == nil {
+		return "", nil, gerror.NewCodef(gcode.CodeNotFound, "message not found: %s", msgID)
+	}
+	if msg.Role != "assistant" {
+		return "", nil, gerror.NewCodef(gcode.CodeInvalidParameter, "feedback is only allowed on assistant messages")
+	}
+
+	metadata := map[string]interface{}{}
+	if len(msg.Metadata) > 0 {
+		if err = json.Unmarshal(msg.Metadata, &metadata); err != nil {
+			return "", nil, gerror.Wrapf(err, "invalid message metadata")
+		}
+	}
+	if feedback != "" {
+		metadata[FeedbackMetadataKey] = feedback
+	}
+	var clicked []string
+	if existing, ok := metadata[ClickedChunksMetadataKey].([]interface{}); ok {
+		for _, id := range existing {
+			if s, ok := id.(string); ok {
+				clicked = append(clicked, s)
+			}
+		}
+	}
+	clicked = uniqueStrings(append(clicked, clickedChunkIDs...))
+	if len(clicked) > 0 {
+		metadata[ClickedChunksMetadataKey] = clicked
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", nil, err
+	}
+	if err = dao.Message.UpdateMetadata(ctx, msgID, gormModel.JSON(data)); err != nil {
+		return "", nil, err
+	}
+	current, _ := metadata[FeedbackMetadataKey].(string)
+	return current, clicked, nil
+}
@@ -0,0 +1,240 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+const (
+	// RetrievalMetadataKey 助手消息元数据中记录本轮检索结果（检索轨迹）的字段
+	RetrievalMetadataKey = "retrieval"
+	// ClickedChunksMetadataKey 助手消息元数据中记录用户点击的参考分片的字段
+	ClickedChunksMetadataKey = "clicked_chunks"
+
+	// FinetuneFormatSentenceTransformers 每行一个 {"anchor","positive","negative"} 三元组
+	FinetuneFormatSentenceTransformers = "sentence-transformers"
+	// FinetuneFormatBGE FlagEmbedding（BGE）格式，每行 {"query","pos":[...],"neg":[...]}
+	FinetuneFormatBGE = "bge"
+
+	defaultFinetuneNegatives = 3
+)
+
+// RetrievalTrace 一轮对话的检索轨迹，分片按检索排名排序
+type RetrievalTrace struct {
+	Query  string        `json:"query"`
+	Chunks []*TraceChunk `json:"chunks"`
+}
+
+// TraceChunk 检索返回的分片
+type TraceChunk struct {
+	ID    string  `json:"id"`
+	Score float32 `json:"score"`
+}
+
+// NewRetrievalTrace 根据检索返回的文档构建检索轨迹，跳过 MCP 和本地工具结果，没有分片时返回 nil
+func NewRetrievalTrace(query string, docs []*schema.Document) *RetrievalTrace {
+	trace := &RetrievalTrace{Query: query}
+	for _, doc := range docs {
+		if doc == nil || doc.ID == "" {
+			continue
+		}
+		switch source, _ := doc.MetaData["source"].(string); source {
+		case "mcp", "local_tool", "llm":
+			continue
+		}
+		trace.Chunks = append(trace.Chunks, &TraceChunk{ID: doc.ID, Score: doc.Score})
+	}
+	if len(trace.Chunks) == 0 {
+		return nil
+	}
+	return trace
+}
+
+// FinetuneExportOptions 微调数据导出参数
+type FinetuneExportOptions struct {
+	Start        time.Time
+	End          time.Time
+	KnowledgeID  string // 只导出该知识库的分片（可选）
+	Format       string // 见 FinetuneFormat* 常量
+	MaxNegatives int    // 每个正例最多使用的负例数
+}
+
+// feedbackSample 一条带检索轨迹和用户反馈的助手消息
+type feedbackSample struct {
+	Query    string
+	Feedback string
+	Chunks   []string // 按检索排名排序
+	Clicked  []string
+}
+
+// trainingExample 一个查询的正例和负例分片ID
+type trainingExample struct {
+	Query     string
+	Positives []string
+	Negatives []string
+}
+
+// ExportFinetuneData 从点击、反馈日志和检索轨迹中组装 (查询, 正例分片, 负例分片) 训练数据，按 JSONL 写入 w
+// 返回写入的行数
+func ExportFinetuneData(ctx context.Context, opts *FinetuneExportOptions, w io.Writer) (int, error) {
+	messages, err := dao.Analytics.ListMessageMetadata(ctx, opts.Start, opts.End)
+	if err != nil {
+		return 0, err
+	}
+	var samples []*feedbackSample
+	for _, msg := range messages {
+		if sample := parseFeedbackSample(msg.Metadata); sample != nil {
+			samples = append(samples, sample)
+		}
+	}
+	maxNegatives := opts.MaxNegatives
+	if maxNegatives <= 0 {
+		maxNegatives = defaultFinetuneNegatives
+	}
+	examples := buildTrainingExamples(samples, maxNegatives)
+
+	// 加载分片内容，已删除、停用或不属于指定知识库的分片不参与导出
+	var chunkIDs []string
+	for _, example := range examples {
+		chunkIDs = append(chunkIDs, example.Positives...)
+		chunkIDs = append(chunkIDs, example.Negatives...)
+	}
+	contents, err := dao.Analytics.MapChunkContents(ctx, chunkIDs, opts.KnowledgeID)
+	if err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	lines := 0
+	for _, example := range examples {
+		positives := resolveChunkContents(example.Positives, contents)
+		negatives := resolveChunkContents(example.Negatives, contents)
+		if len(positives) == 0 || len(negatives) == 0 {
+			continue
+		}
+		for _, record := range formatTrainingExample(opts.Format, example.Query, positives, negatives) {
+			if err = encoder.Encode(record); err != nil {
+				return lines, fmt.Errorf("写入微调数据失败: %w", err)
+			}
+			lines++
+		}
+	}
+	return lines, nil
+}
+
+// parseFeedbackSample 从助手消息元数据中解析检索轨迹和反馈，没有检索轨迹或没有反馈/点击时返回 nil
+func parseFeedbackSample(metadata gormModel.JSON) *feedbackSample {
+	if len(metadata) == 0 {
+		return nil
+	}
+	var m struct {
+		Feedback  string          `json:"feedback"`
+		Clicked   []string        `json:"clicked_chunks"`
+		Retrieval *RetrievalTrace `json:"retrieval"`
+	}
+	if err := json.Unmarshal(metadata, &m); err != nil || m.Retrieval == nil || m.Retrieval.Query == "" {
+		return nil
+	}
+	if m.Feedback != FeedbackPositive && m.Feedback != FeedbackNegative {
+		m.Feedback = ""
+	}
+	if m.Feedback == "" && len(m.Clicked) == 0 {
+		return nil
+	}
+	sample := &feedbackSample{Query: m.Retrieval.Query, Feedback: m.Feedback, Clicked: m.Clicked}
+	for _, chunk := range m.Retrieval.Chunks {
+		if chunk != nil && chunk.ID != "" {
+			sample.Chunks = append(sample.Chunks, chunk.ID)
+		}
+	}
+	return sample
+}
+
+// buildTrainingExamples 根据反馈确定正例和负例：
+//   - 用户点击的分片为正例；没有点击且回答为正反馈时，排名第一的分片为正例
+//   - 同一轮检索返回的其余分片按排名作为难负例，最多 maxNegatives 个
+//   - 负反馈且没有点击的样本无法确定正例，不导出
+//
+// 相同查询和正例的样本合并，负例去重
+func buildTrainingExamples(samples []*feedbackSample, maxNegatives int) []*trainingExample {
+	var examples []*trainingExample
+	index := make(map[string]*trainingExample)
+	for _, sample := range samples {
+		positives := uniqueStrings(sample.Clicked)
+		if len(positives) == 0 && sample.Feedback == FeedbackPositive && len(sample.Chunks) > 0 {
+			positives = sample.Chunks[:1]
+		}
+		if len(positives) == 0 {
+			continue
+		}
+		isPositive := make(map[string]bool, len(positives))
+		for _, id := range positives {
+			isPositive[id] = true
+		}
+		var negatives []string
+		for _, id := range sample.Chunks {
+			if !isPositive[id] {
+				negatives = append(negatives, id)
+			}
+		}
+
+		key := NormalizeQuestion(sample.Query) + "\x00" + fmt.Sprint(positives)
+		example, exists := index[key]
+		if !exists {
+			example = &trainingExample{Query: sample.Query, Positives: positives}
+			index[key] = example
+			examples = append(examples, example)
+		}
+		example.Negatives = uniqueStrings(append(example.Negatives, negatives...))
+	}
+	for _, example := range examples {
+		if len(example.Negatives) > maxNegatives {
+			example.Negatives = example.Negatives[:maxNegatives]
+		}
+	}
+	return examples
+}
+
+// formatTrainingExample 将一个训练样本转换为指定格式的 JSONL 记录
+func formatTrainingExample(format, query string, positives, negatives []string) []interface{} {
+	if format == FinetuneFormatBGE {
+		return []interface{}{map[string]interface{}{"query": query, "pos": positives, "neg": negatives}}
+	}
+	records := make([]interface{}, 0, len(positives)*len(negatives))
+	for _, positive := range positives {
+		for _, negative := range negatives {
+			records = append(records, map[string]string{"anchor": query, "positive": positive, "negative": negative})
+		}
+	}
+	return records
+}
+
+func resolveChunkContents(ids []string, contents map[string]string) []string {
+	var result []string
+	for _, id := range ids {
+		if content := contents[id]; content != "" {
+			result = append(result, content)
+		}
+	}
+	return result
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// TestNewRetrievalTrace 测试检索轨迹只记录知识库分片
+func TestNewRetrievalTrace(t *testing.T) {
+	docs := []*schema.Document{
+		{ID: "c1", Score: 0.9},
+		{ID: "mcp_weather_get", MetaData: map[string]interface{}{"source": "mcp"}},
+		{ID: "c2", Score: 0.5},
+		{ID: ""},
+	}
+	trace := NewRetrievalTrace("年假怎么申请", docs)
+	if trace == nil || trace.Query != "年假怎么申请" || len(trace.Chunks) != 2 || trace.Chunks[0].ID != "c1" || trace.Chunks[1].ID != "c2" {
+		t.Fatalf("unexpected trace: %+v", trace)
+	}
+	if NewRetrievalTrace("q", docs[1:2]) != nil {
+		t.Error("expected nil trace without knowledge chunks")
+	}
+}
+
+// TestParseFeedbackSample 测试从消息元数据解析训练样本
+func TestParseFeedbackSample(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		want     *feedbackSample
+	}{
+		{
+			name:     "Feedback with trace",
+			metadata: `{"feedback":"positive","retrieval":{"query":"q","chunks":[{"id":"c1","score":0.9},{"id":"c2","score":0.5}]}}`,
+			want:     &feedbackSample{Query: "q", Feedback: "positive", Chunks: []string{"c1", "c2"}},
+		},
+		{
+			name:     "Clicks without feedback",
+			metadata: `{"clicked_chunks":["c2"],"retrieval":{"query":"q","chunks":[{"id":"c1"},{"id":"c2"}]}}`,
+			want:     &feedbackSample{Query: "q", Chunks: []string{"c1", "c2"}, Clicked: []string{"c2"}},
+		},
+		{name: "No trace", metadata: `{"feedback":"positive"}`},
+		{name: "No feedback", metadata: `{"retrieval":{"query":"q","chunks":[{"id":"c1"}]}}`},
+		{name: "Invalid feedback", metadata: `{"feedback":"meh","retrieval":{"query":"q","chunks":[{"id":"c1"}]}}`},
+		{name: "Invalid JSON", metadata: `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseFeedbackSample(gormModel.JSON(tt.metadata))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeedbackSample() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildTrainingExamples 测试正例和负例的选取
+func TestBuildTrainingExamples(t *testing.T) {
+	samples := []*feedbackSample{
+		// 正反馈：第一名为正例，其余为负例
+		{Query: "如何报销", Feedback: FeedbackPositive, Chunks: []string{"a", "b", "c"}},
+		// 相同查询和正例合并，负例去重
+		{Query: "  如何报销 ", Feedback: FeedbackPositive, Chunks: []string{"a", "c", "d"}},
+		// 点击优先于排名
+		{Query: "年假", Feedback: FeedbackNegative, Chunks: []string{"x", "y", "z"}, Clicked: []string{"z"}},
+		// 负反馈且无点击：跳过
+		{Query: "加班", Feedback: FeedbackNegative, Chunks: []string{"m", "n"}},
+	}
+	got := buildTrainingExamples(samples, 2)
+	want := []*trainingExample{
+		{Query: "如何报销", Positives: []string{"a"}, Negatives: []string{"b", "c"}},
+		{Query: "年假", Positives: []string{"z"}, Negatives: []string{"x", "y"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, e := range got {
+			t.Logf("got %+v", e)
+		}
+		t.Fatalf("buildTrainingExamples() mismatch")
+	}
+}
+
+// TestFormatTrainingExample 测试输出格式
+func TestFormatTrainingExample(t *testing.T) {
+	records := formatTrainingExample(FinetuneFormatSentenceTransformers, "q", []string{"p"}, []string{"n1", "n2"})
+	want := []interface{}{
+		map[string]string{"anchor": "q", "positive": "p", "negative": "n1"},
+		map[string]string{"anchor": "q", "positive": "p", "negative": "n2"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("sentence-transformers records = %v", records)
+	}
+
+	records = formatTrainingExample(FinetuneFormatBGE, "q", []string{"p"}, []string{"n1", "n2"})
+	wantBGE := []interface{}{map[string]interface{}{"query": "q", "pos": []string{"p"}, "neg": []string{"n1", "n2"}}}
+	if !reflect.DeepEqual(records, wantBGE) {
+		t.Errorf("bge records = %v", records)
+	}
+}
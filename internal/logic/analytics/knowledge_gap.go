@@ -0,0 +1,268 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+const (
+	gapSourceLowScore = "low_score"
+	gapSourceNegative = "negative_feedback"
+
+	gapEmbedBatchSize   = 32
+	gapMaxExampleCount  = 5
+	gapDefaultWindowDay = 7
+)
+
+// gapQuestion 待聚类的候选问题
+type gapQuestion struct {
+	KnowledgeID   string
+	Question      string
+	LowScoreCount int
+	NegativeCount int
+}
+
+// RunKnowledgeGapJob 挖掘最近 windowDays 天的低分检索和负反馈问题，语义聚类后生成知识缺口报告
+// 返回本次生成的批次ID和缺口数量
+func RunKnowledgeGapJob(ctx context.Context, windowDays int) (string, int, error) {
+	if windowDays <= 0 {
+		windowDays = g.Cfg().MustGet(ctx, "analytics.gapWindowDays", gapDefaultWindowDay).Int()
+	}
+	end := time.Now()
+	start := end.AddDate(0, 0, -windowDays)
+
+	questions, err := collectGapQuestions(ctx, start, end)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(questions) == 0 {
+		g.Log().Infof(ctx, "Knowledge gap job: no candidate questions in the last %d days", windowDays)
+		return "", 0, nil
+	}
+
+	embedder, dim, err := newGapEmbedder(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	threshold := g.Cfg().MustGet(ctx, "analytics.gapSimilarity", 0.85).Float64()
+	minSize := g.Cfg().MustGet(ctx, "analytics.gapMinClusterSize", 2).Int()
+	batchID := strings.ReplaceAll(uuid.New().String(), "-", "")
+
+	// 按知识库分组聚类，不同知识库的问题不合并
+	byKnowledge := make(map[string][]*gapQuestion)
+	var knowledgeIDs []string
+	for _, q := range questions {
+		if _, ok := byKnowledge[q.KnowledgeID]; !ok {
+			knowledgeIDs = append(knowledgeIDs, q.KnowledgeID)
+		}
+		byKnowledge[q.KnowledgeID] = append(byKnowledge[q.KnowledgeID], q)
+	}
+
+	var gaps []*gormModel.KnowledgeGap
+	for _, knowledgeID := range knowledgeIDs {
+		group := byKnowledge[knowledgeID]
+		vectors, err := embedQuestions(ctx, embedder, dim, group)
+		if err != nil {
+			return "", 0, err
+		}
+
+		for _, cluster := range clusterBySimilarity(vectors, threshold) {
+			gap := buildKnowledgeGap(group, cluster)
+			if gap.QuestionCount < minSize {
+				continue
+			}
+			gap.BatchID = batchID
+			gap.KnowledgeID = knowledgeID
+			gap.PeriodStart = &start
+			gap.PeriodEnd = &end
+			gaps = append(gaps, gap)
+		}
+	}
+
+	if err = dao.Analytics.CreateKnowledgeGaps(ctx, gaps); err != nil {
+		return "", 0, err
+	}
+
+	g.Log().Infof(ctx, "Knowledge gap job done: batch=%s, %d questions -> %d gaps", batchID, len(questions), len(gaps))
+	return batchID, len(gaps), nil
+}
+
+// collectGapQuestions 收集时间段内的低分检索问题和负反馈问题，按知识库和归一化文本去重计数
+func collectGapQuestions(ctx context.Context, start, end time.Time) ([]*gapQuestion, error) {
+	index := make(map[string]*gapQuestion)
+	var result []*gapQuestion
+	add := func(knowledgeID, question, source string) {
+		question = NormalizeQuestion(question)
+		if question == "" {
+			return
+		}
+		key := knowledgeID + "\x00" + question
+		q, ok := index[key]
+		if !ok {
+			q = &gapQuestion{KnowledgeID: knowledgeID, Question: question}
+			index[key] = q
+			result = append(result, q)
+		}
+		if source == gapSourceNegative {
+			q.NegativeCount++
+		} else {
+			q.LowScoreCount++
+		}
+	}
+
+	misses, err := dao.Analytics.ListRetrievalMisses(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, miss := range misses {
+		add(miss.KnowledgeID, miss.Question, gapSourceLowScore)
+	}
+
+	messages, err := dao.Analytics.ListMessageMetadata(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// 负反馈消息的知识库取自其检索轨迹中的分片（按排名），没有检索轨迹时为空
+	type negativeMessage struct {
+		msg    *gormModel.Message
+		chunks []string
+	}
+	var negatives []negativeMessage
+	var chunkIDs []string
+	for _, msg := range messages {
+		if parseFeedback(msg.Metadata) != FeedbackNegative || msg.CreateTime == nil {
+			continue
+		}
+		var chunks []string
+		if sample := parseFeedbackSample(msg.Metadata); sample != nil {
+			chunks = sample.Chunks
+		}
+		negatives = append(negatives, negativeMessage{msg: msg, chunks: chunks})
+		chunkIDs = append(chunkIDs, chunks...)
+	}
+	chunkKnowledge, err := dao.Analytics.MapChunkKnowledgeIDs(ctx, uniqueStrings(chunkIDs))
+	if err != nil {
+		return nil, err
+	}
+	for _, negative := range negatives {
+		question, err := dao.Analytics.GetPrecedingUserText(ctx, negative.msg.ConvID, *negative.msg.CreateTime)
+		if err != nil {
+			return nil, err
+		}
+		add(traceKnowledgeID(negative.chunks, chunkKnowledge), question, gapSourceNegative)
+	}
+
+	return result, nil
+}
+
+// newGapEmbedder 创建用于问题聚类的 embedding 客户端
+// 优先使用 analytics.gapEmbeddingModelID 指定的模型，否则使用注册表中第一个 embedding 模型
+func newGapEmbedder(ctx context.Context) (*common.CustomEmbedder, int, error) {
+	var mc *model.ModelConfig
+	if modelID := g.Cfg().MustGet(ctx, "analytics.gapEmbeddingModelID", "").String(); modelID != "" {
+		mc = model.Registry.Get(modelID)
+	} else if models := model.Registry.GetByType(model.ModelTypeEmbedding); len(models) > 0 {
+		mc = models[0]
+	}
+	if mc == nil {
+		return nil, 0, fmt.Errorf("no embedding model available for knowledge gap clustering")
+	}
+
+	embedder, err := common.NewEmbedding(ctx, &config.RetrieverConfigBase{
+		APIKey:         mc.APIKey,
+		BaseURL:        mc.BaseURL,
+		EmbeddingModel: mc.Name,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create embedder: %w", err)
+	}
+
+	if v, ok := mc.Extra["dimension"].(float64); ok && v > 0 {
+		return embedder, int(v), nil
+	}
+	vectorStoreType := g.Cfg().MustGet(ctx, "vectorStore.type", "milvus").String()
+	return embedder, embedder.ResolveDimension(ctx, g.Cfg().MustGet(ctx, vectorStoreDimKey(vectorStoreType), 1024).Int()), nil
+}
+
+// vectorStoreDimKey 返回向量库维度配置项
+func vectorStoreDimKey(vectorStoreType string) string {
+	switch vectorStoreType {
+	case "pgvector":
+		return "postgres.dim"
+	case "qdrant":
+		return "qdrant.dim"
+	case "elasticsearch":
+		return "elasticsearch.dim"
+	}
+	return "milvus.dim"
+}
+
+// embedQuestions 分批向量化问题文本
+func embedQuestions(ctx context.Context, embedder *common.CustomEmbedder, dim int, questions []*gapQuestion) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(questions))
+	for i := 0; i < len(questions); i += gapEmbedBatchSize {
+		end := i + gapEmbedBatchSize
+		if end > len(questions) {
+			end = len(questions)
+		}
+		texts := make([]string, 0, end-i)
+		for _, q := range questions[i:end] {
+			texts = append(texts, q.Question)
+		}
+		batch, err := embedder.EmbedStrings(ctx, texts, dim)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed questions: %w", err)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// buildKnowledgeGap 将聚类结果转换为缺口报告，出现次数最多的问题作为主题
+func buildKnowledgeGap(questions []*gapQuestion, cluster *questionCluster) *gormModel.KnowledgeGap {
+	members := make([]*gapQuestion, 0, len(cluster.members))
+	for _, idx := range cluster.members {
+		members = append(members, questions[idx])
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].LowScoreCount+members[i].NegativeCount > members[j].LowScoreCount+members[j].NegativeCount
+	})
+
+	gap := &gormModel.KnowledgeGap{Topic: members[0].Question}
+	var examples []string
+	for _, m := range members {
+		gap.LowScoreCount += m.LowScoreCount
+		gap.NegativeCount += m.NegativeCount
+		if len(examples) < gapMaxExampleCount {
+			examples = append(examples, m.Question)
+		}
+	}
+	// 问题数按归一化后的不同问题计数，同一问题重复出现只计一次
+	gap.QuestionCount = len(members)
+	examplesJSON, _ := json.Marshal(examples)
+	gap.ExampleQuestions = gormModel.JSON(examplesJSON)
+	return gap
+}
+
+// traceKnowledgeID 返回检索轨迹中排名最靠前且能找到所属知识库的分片的知识库ID
+func traceKnowledgeID(chunks []string, chunkKnowledge map[string]string) string {
+	for _, chunkID := range chunks {
+		if knowledgeID := chunkKnowledge[chunkID]; knowledgeID != "" {
+			return knowledgeID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+)
+
+// GetUsage 查询按天、按模型的使用统计
+func GetUsage(ctx context.Context, req *v1.AnalyticsUsageReq) ([]*v1.AnalyticsUsageItem, error) {
+	rows, err := dao.Analytics.ListDailyRollups(ctx, req.StartDate, req.EndDate, req.ModelName)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*v1.AnalyticsUsageItem, 0, len(rows))
+	for _, row := range rows {
+		item := &v1.AnalyticsUsageItem{
+			StatDate:          row.StatDate,
+			ModelName:         row.ModelName,
+			ConversationCount: row.ConversationCount,
+			MessageCount:      row.MessageCount,
+			AssistantCount:    row.AssistantCount,
+			TotalTokens:       row.TotalTokens,
+			AvgLatencyMs:      row.AvgLatencyMs,
+			ToolCallCount:     row.ToolCallCount,
+			PositiveFeedback:  row.PositiveFeedback,
+			NegativeFeedback:  row.NegativeFeedback,
+		}
+		if total := row.PositiveFeedback + row.NegativeFeedback; total > 0 {
+			item.SatisfactionRatio = float64(row.PositiveFeedback) / float64(total)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetToolUsage 查询区间内的工具使用情况（跨天合并）
+func GetToolUsage(ctx context.Context, req *v1.AnalyticsToolsReq) ([]*v1.AnalyticsToolItem, error) {
+	rows, err := dao.Analytics.ListToolRollups(ctx, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*v1.AnalyticsToolItem)
+	durationSum := make(map[string]float64)
+	for _, row := range rows {
+		key := row.ServiceName + "__" + row.ToolName
+		item, ok := merged[key]
+		if !ok {
+			item = &v1.AnalyticsToolItem{ServiceName: row.ServiceName, ToolName: row.ToolName}
+			merged[key] = item
+		}
+		item.CallCount += row.CallCount
+		item.FailedCount += row.FailedCount
+		durationSum[key] += row.AvgDuration * float64(row.CallCount)
+	}
+
+	items := make([]*v1.AnalyticsToolItem, 0, len(merged))
+	for key, item := range merged {
+		if item.CallCount > 0 {
+			item.AvgDuration = durationSum[key] / float64(item.CallCount)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CallCount > items[j].CallCount
+	})
+	return items, nil
+}
+
+// GetUnansweredQuestions 查询区间内出现最多的未解答问题
+func GetUnansweredQuestions(ctx context.Context, req *v1.AnalyticsUnansweredReq) ([]*v1.AnalyticsUnansweredItem, error) {
+	rows, err := dao.Analytics.ListUnansweredRollups(ctx, req.StartDate, req.EndDate, req.KnowledgeId)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*v1.AnalyticsUnansweredItem)
+	for _, row := range rows {
+		key := row.KnowledgeID + "\x00" + row.Question
+		item, ok := merged[key]
+		if !ok {
+			item = &v1.AnalyticsUnansweredItem{KnowledgeId: row.KnowledgeID, Question: row.Question, MaxScore: row.MaxScore}
+			merged[key] = item
+		}
+		item.MissCount += row.MissCount
+		if row.MaxScore > item.MaxScore {
+			item.MaxScore = row.MaxScore
+		}
+	}
+
+	items := make([]*v1.AnalyticsUnansweredItem, 0, len(merged))
+	for _, item := range merged {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].MissCount != items[j].MissCount {
+			return items[i].MissCount > items[j].MissCount
+		}
+		return items[i].MaxScore < items[j].MaxScore
+	})
+	if req.Limit > 0 && len(items) > req.Limit {
+		items = items[:req.Limit]
+	}
+	return items, nil
+}
+
+// GetKnowledgeGaps 查询知识缺口报告，未指定批次时返回最近一批
+func GetKnowledgeGaps(ctx context.Context, req *v1.KnowledgeGapListReq) (string, []*v1.KnowledgeGapItem, error) {
+	batchID := req.BatchId
+	if batchID == "" {
+		latest, err := dao.Analytics.GetLatestGapBatchID(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		if latest == "" {
+			return "", []*v1.KnowledgeGapItem{}, nil
+		}
+		batchID = latest
+	}
+
+	gaps, err := dao.Analytics.ListKnowledgeGaps(ctx, batchID, req.KnowledgeId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	items := make([]*v1.KnowledgeGapItem, 0, len(gaps))
+	for _, gap := range gaps {
+		item := &v1.KnowledgeGapItem{
+			KnowledgeId:   gap.KnowledgeID,
+			Topic:         gap.Topic,
+			QuestionCount: gap.QuestionCount,
+			LowScoreCount: gap.LowScoreCount,
+			NegativeCount: gap.NegativeCount,
+		}
+		if len(gap.ExampleQuestions) > 0 {
+			_ = json.Unmarshal(gap.ExampleQuestions, &item.ExampleQuestions)
+		}
+		if gap.PeriodStart != nil {
+			item.PeriodStart = gap.PeriodStart.Format(time.DateTime)
+		}
+		if gap.PeriodEnd != nil {
+			item.PeriodEnd = gap.PeriodEnd.Format(time.DateTime)
+		}
+		items = append(items, item)
+	}
+	return batchID, items, nil
+}
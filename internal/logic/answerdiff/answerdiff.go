@@ -0,0 +1,195 @@
+// Package answerdiff 回答差异：重新生成回答或影子评估产生候选回答时，计算并保存新旧回答的句子级差异，
+// 便于评审人员快速查看模型或提示词版本之间回答的变化
+package answerdiff
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/history"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// 差异来源
+const (
+	SourceRegenerate = "regenerate" // 重新生成的回答与原回答
+	SourceShadow     = "shadow"     // 影子评估的候选回答与线上回答
+)
+
+// regeneration 本轮请求重新生成的原回答，新回答保存后与其对比，只对比第一条保存的助手消息
+type regeneration struct {
+	convID      string
+	baseMsgID   string
+	baseModelID string
+	modelID     string
+	done        atomic.Bool
+}
+
+type regenerationKey struct{}
+
+func init() {
+	history.OnMessageSaved(onMessageSaved)
+}
+
+// WithRegenerate 校验要重新生成的助手消息属于该会话，并在上下文中记录，原回答的模型取会话当前模型
+func WithRegenerate(ctx context.Context, convID, msgID string) (context.Context, error) {
+	msg, err := dao.Message.GetByMsgID(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil || msg.ConvID != convID {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "message %s not found in conversation %s", msgID, convID)
+	}
+	if msg.Role != string(schema.Assistant) {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "message %s is not an assistant message", msgID)
+	}
+	regen := &regeneration{convID: convID, baseMsgID: msgID}
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv != nil {
+		regen.baseModelID = conv.ModelID
+	}
+	return context.WithValue(ctx, regenerationKey{}, regen), nil
+}
+
+// SetModel 记录新回答使用的模型，上下文中没有重新生成标记时不做任何事
+func SetModel(ctx context.Context, modelID string) {
+	if regen, ok := ctx.Value(regenerationKey{}).(*regeneration); ok {
+		regen.modelID = modelID
+	}
+}
+
+// onMessageSaved 重新生成的回答保存后，在后台计算与原回答的差异
+func onMessageSaved(ctx context.Context, msg *history.SavedMessage) {
+	regen, ok := ctx.Value(regenerationKey{}).(*regeneration)
+	if !ok || msg.Role != string(schema.Assistant) || msg.ConvID != regen.convID || regen.done.Swap(true) {
+		return
+	}
+	common.SafeGoDetached(ctx, "AnswerDiff-"+msg.MsgID, func(ctx context.Context) {
+		baseAnswer, err := messageText(ctx, regen.baseMsgID)
+		if err != nil {
+			g.Log().Warningf(ctx, "Failed to load regenerated message %s: %v", regen.baseMsgID, err)
+			return
+		}
+		_, _ = save(ctx, &gormModel.AnswerDiff{
+			ConvID:      msg.ConvID,
+			Source:      SourceRegenerate,
+			BaseMsgID:   regen.baseMsgID,
+			MsgID:       msg.MsgID,
+			BaseModelID: regen.baseModelID,
+			ModelID:     regen.modelID,
+		}, baseAnswer, msg.Content)
+	})
+}
+
+// RecordShadow 保存影子评估中候选回答相对线上回答的差异，候选模型调用失败时不保存
+func RecordShadow(ctx context.Context, result *gormModel.ShadowResult) {
+	if result.Error != "" {
+		return
+	}
+	_, _ = save(ctx, &gormModel.AnswerDiff{
+		ConvID:         result.ConvID,
+		Source:         SourceShadow,
+		ShadowResultID: result.ID,
+		BaseModelID:    result.LiveModelID,
+		ModelID:        result.CandidateModelID,
+	}, result.LiveAnswer, result.CandidateAnswer)
+}
+
+// save 计算差异并保存
+func save(ctx context.Context, record *gormModel.AnswerDiff, baseAnswer, answer string) (*gormModel.AnswerDiff, error) {
+	diff := Compute(baseAnswer, answer)
+	segments, err := json.Marshal(diff.Segments)
+	if err != nil {
+		return nil, err
+	}
+	record.ID = uuid.New().String()
+	record.Segments = gormModel.JSON(segments)
+	record.AddedCount = diff.Added
+	record.RemovedCount = diff.Removed
+	record.UnchangedCount = diff.Unchanged
+	record.Similarity = diff.Similarity
+	if err = dao.AnswerDiff.Create(ctx, record); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Answer diff saved - Source: %s, ConvID: %s, added=%d, removed=%d, similarity=%.3f",
+		record.Source, record.ConvID, diff.Added, diff.Removed, diff.Similarity)
+	return record, nil
+}
+
+// messageText 消息的文本内容
+func messageText(ctx context.Context, msgID string) (string, error) {
+	contents, err := dao.MessageContent.ListByMsgID(ctx, msgID)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, content := range contents {
+		if content.ContentType == "text" {
+			parts = append(parts, content.TextContent)
+		}
+	}
+	return strings.Join(parts, ""), nil
+}
+
+// ListByMessage 获取消息作为原回答或重新生成的回答参与的差异
+func ListByMessage(ctx context.Context, msgID string) ([]*v1.AnswerDiffItem, error) {
+	diffs, err := dao.AnswerDiff.ListByMsgID(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*v1.AnswerDiffItem, 0, len(diffs))
+	for _, diff := range diffs {
+		items = append(items, ToItem(diff))
+	}
+	return items, nil
+}
+
+// ToItem 转换为接口返回的差异
+func ToItem(diff *gormModel.AnswerDiff) *v1.AnswerDiffItem {
+	item := &v1.AnswerDiffItem{
+		Id:             diff.ID,
+		ConvID:         diff.ConvID,
+		Source:         diff.Source,
+		BaseMsgID:      diff.BaseMsgID,
+		MsgID:          diff.MsgID,
+		ShadowResultID: diff.ShadowResultID,
+		BaseModelID:    diff.BaseModelID,
+		ModelID:        diff.ModelID,
+		Added:          []string{},
+		Removed:        []string{},
+		UnchangedCount: diff.UnchangedCount,
+		Similarity:     diff.Similarity,
+		Segments:       []*v1.AnswerDiffSegment{},
+	}
+	var segments []Segment
+	if len(diff.Segments) > 0 {
+		_ = json.Unmarshal(diff.Segments, &segments)
+	}
+	for _, segment := range segments {
+		item.Segments = append(item.Segments, &v1.AnswerDiffSegment{Op: segment.Op, Text: segment.Text})
+		switch segment.Op {
+		case OpAdded:
+			item.Added = append(item.Added, segment.Text)
+		case OpRemoved:
+			item.Removed = append(item.Removed, segment.Text)
+		}
+	}
+	if diff.CreateTime != nil {
+		item.CreateTime = diff.CreateTime.Format(time.RFC3339)
+	}
+	return item
+}
@@ -0,0 +1,45 @@
+package answerdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	got := splitSentences("保修期为两年。版本 1.2 支持导出！\nSee the docs. Done")
+	want := []string{"保修期为两年。", "版本 1.2 支持导出！", "See the docs.", "Done"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSentences() = %q, want %q", got, want)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	diff := Compute("保修期为两年。需要保留发票。可以在线申请。", "保修期为三年。需要保留发票。  可以在线申请。")
+	if diff.Added != 1 || diff.Removed != 1 || diff.Unchanged != 2 {
+		t.Fatalf("Compute() added=%d removed=%d unchanged=%d, want 1 1 2", diff.Added, diff.Removed, diff.Unchanged)
+	}
+	wantOps := []string{OpRemoved, OpAdded, OpEqual, OpEqual}
+	for i, segment := range diff.Segments {
+		if segment.Op != wantOps[i] {
+			t.Errorf("segment %d op = %s, want %s", i, segment.Op, wantOps[i])
+		}
+	}
+	if diff.Segments[0].Text != "保修期为两年。" || diff.Segments[1].Text != "保修期为三年。" {
+		t.Errorf("changed sentences = %q, %q", diff.Segments[0].Text, diff.Segments[1].Text)
+	}
+	if diff.Similarity != 0.667 {
+		t.Errorf("Similarity = %v, want 0.667", diff.Similarity)
+	}
+}
+
+func TestComputeEdgeCases(t *testing.T) {
+	if diff := Compute("", ""); diff.Similarity != 1 || len(diff.Segments) != 0 {
+		t.Errorf("Compute(empty) = %+v", diff)
+	}
+	if diff := Compute("Same answer.", "same   ANSWER."); diff.Unchanged != 1 || diff.Similarity != 1 {
+		t.Errorf("Compute() should ignore case and whitespace: %+v", diff)
+	}
+	if diff := Compute("", "新回答。"); diff.Added != 1 || diff.Similarity != 0 {
+		t.Errorf("Compute(from empty) = %+v", diff)
+	}
+}
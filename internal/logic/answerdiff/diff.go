@@ -0,0 +1,113 @@
+package answerdiff
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 差异片段类型
+const (
+	OpEqual   = "equal"   // 两个回答都有的句子
+	OpAdded   = "added"   // 新回答新增的句子
+	OpRemoved = "removed" // 新回答删除的句子
+)
+
+// Segment 差异片段，一个片段对应一个句子
+type Segment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff 两个回答的句子级差异
+type Diff struct {
+	Segments   []Segment // 按回答顺序排列，同一位置的删除排在新增之前
+	Added      int
+	Removed    int
+	Unchanged  int
+	Similarity float64 // 2 × 未变化句子数 / 两个回答的句子总数
+}
+
+// Compute 按句子计算从 oldText 到 newText 的差异（最长公共子序列），比较时忽略大小写和空白
+func Compute(oldText, newText string) *Diff {
+	oldSentences, newSentences := splitSentences(oldText), splitSentences(newText)
+	oldKeys, newKeys := sentenceKeys(oldSentences), sentenceKeys(newSentences)
+	n, m := len(oldKeys), len(newKeys)
+
+	// lcs[i][j] 为 old[i:] 与 new[j:] 的最长公共子序列长度
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldKeys[i] == newKeys[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := &Diff{Segments: make([]Segment, 0, n+m)}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && oldKeys[i] == newKeys[j]:
+			diff.Segments = append(diff.Segments, Segment{Op: OpEqual, Text: newSentences[j]})
+			diff.Unchanged++
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.Segments = append(diff.Segments, Segment{Op: OpRemoved, Text: oldSentences[i]})
+			diff.Removed++
+			i++
+		default:
+			diff.Segments = append(diff.Segments, Segment{Op: OpAdded, Text: newSentences[j]})
+			diff.Added++
+			j++
+		}
+	}
+
+	diff.Similarity = 1
+	if n+m > 0 {
+		diff.Similarity = math.Round(float64(2*diff.Unchanged)/float64(n+m)*1000) / 1000
+	}
+	return diff
+}
+
+// splitSentences 按句末标点和换行切分句子，句子保留结尾标点，空句忽略
+// 英文句点只在其后为空白或文本结束时视为句末，避免切开小数和版本号
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+	flush := func() {
+		if sentence := strings.TrimSpace(current.String()); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+		if strings.ContainsRune("。！？!?；;", r) || (r == '.' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]))) {
+			flush()
+		}
+	}
+	flush()
+	return sentences
+}
+
+// sentenceKeys 比较用的句子：小写并去掉空白
+func sentenceKeys(sentences []string) []string {
+	keys := make([]string, len(sentences))
+	for i, sentence := range sentences {
+		keys[i] = strings.Join(strings.Fields(strings.ToLower(sentence)), "")
+	}
+	return keys
+}
@@ -0,0 +1,159 @@
+// Package budget 对话请求的延迟预算：剩余时间不足以完成某个可选阶段时按顺序降级
+// （跳过查询重写、减少 TopK、跳过重排、限制工具调用轮数），而不是等到超时，并记录实际执行的降级措施
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 降级措施，按剩余时间从多到少依次生效
+const (
+	DegradeSkipRewrite       = "skip_rewrite"        // 跳过查询重写
+	DegradeReduceTopK        = "reduce_top_k"        // 检索数量减半
+	DegradeSkipRerank        = "skip_rerank"         // 只使用向量检索，不调用重排模型
+	DegradeCapToolIterations = "cap_tool_iterations" // 提前结束多轮工具调用
+)
+
+// Thresholds 各阶段需要的最少剩余时间，剩余时间低于阈值时执行对应降级
+type Thresholds struct {
+	Rewrite       time.Duration // 查询重写
+	FullTopK      time.Duration // 按原 TopK 检索
+	Rerank        time.Duration // 重排
+	ToolIteration time.Duration // 一轮工具调用（LLM 选择工具并执行）
+}
+
+// Budget 一次对话请求的延迟预算，nil 表示不限制
+type Budget struct {
+	total      time.Duration
+	start      time.Time
+	thresholds Thresholds
+
+	mu      sync.Mutex
+	applied []string
+}
+
+type contextKey struct{}
+
+// New 创建延迟预算，budgetMs 为 0 时使用 budget.defaultMs 配置，两者都未设置时返回 nil（不限制）
+func New(ctx context.Context, budgetMs int) *Budget {
+	if budgetMs <= 0 {
+		budgetMs = g.Cfg().MustGet(ctx, "budget.defaultMs", 0).Int()
+	}
+	if budgetMs <= 0 {
+		return nil
+	}
+	return &Budget{
+		total:      time.Duration(budgetMs) * time.Millisecond,
+		start:      time.Now(),
+		thresholds: LoadThresholds(ctx),
+	}
+}
+
+// LoadThresholds 从 budget 配置读取各阶段的降级阈值
+func LoadThresholds(ctx context.Context) Thresholds {
+	return Thresholds{
+		Rewrite:       time.Duration(g.Cfg().MustGet(ctx, "budget.rewriteMinMs", 4000).Int()) * time.Millisecond,
+		FullTopK:      time.Duration(g.Cfg().MustGet(ctx, "budget.fullTopKMinMs", 2500).Int()) * time.Millisecond,
+		Rerank:        time.Duration(g.Cfg().MustGet(ctx, "budget.rerankMinMs", 1500).Int()) * time.Millisecond,
+		ToolIteration: time.Duration(g.Cfg().MustGet(ctx, "budget.toolIterationMs", 3000).Int()) * time.Millisecond,
+	}
+}
+
+// WithContext 把延迟预算放入上下文，b 为 nil 时原样返回
+func WithContext(ctx context.Context, b *Budget) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// FromContext 读取上下文中的延迟预算，没有时返回 nil
+func FromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(contextKey{}).(*Budget)
+	return b
+}
+
+// Remaining 剩余时间，预算耗尽时为 0
+func (b *Budget) Remaining() time.Duration {
+	remaining := b.total - time.Since(b.start)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// PlanRetrieval 根据剩余时间调整检索请求，defaultTopK 为请求未指定 TopK 时使用的默认值
+func (b *Budget) PlanRetrieval(ctx context.Context, req *v1.RetrieverReq, defaultTopK int) {
+	if b == nil {
+		return
+	}
+	remaining := b.Remaining()
+	if req.EnableRewrite && remaining < b.thresholds.Rewrite {
+		req.EnableRewrite = false
+		b.apply(ctx, DegradeSkipRewrite, remaining)
+	}
+	topK := req.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+	if topK > 1 && remaining < b.thresholds.FullTopK {
+		req.TopK = reducedTopK(topK)
+		b.apply(ctx, DegradeReduceTopK, remaining)
+	}
+	if (req.RetrieveMode == "rerank" || req.RetrieveMode == "rrf") && remaining < b.thresholds.Rerank {
+		req.RetrieveMode = "milvus"
+		b.apply(ctx, DegradeSkipRerank, remaining)
+	}
+}
+
+// ContinueToolCalls 是否还有时间进行下一轮工具调用，没有时记录降级
+func (b *Budget) ContinueToolCalls(ctx context.Context) bool {
+	if b == nil {
+		return true
+	}
+	remaining := b.Remaining()
+	if remaining >= b.thresholds.ToolIteration {
+		return true
+	}
+	b.apply(ctx, DegradeCapToolIterations, remaining)
+	return false
+}
+
+// Report 预算使用情况和已执行的降级措施，用于响应元数据
+func (b *Budget) Report() *v1.LatencyBudget {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &v1.LatencyBudget{
+		BudgetMs:     b.total.Milliseconds(),
+		ElapsedMs:    time.Since(b.start).Milliseconds(),
+		Degradations: append([]string{}, b.applied...),
+	}
+}
+
+func (b *Budget) apply(ctx context.Context, degradation string, remaining time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, applied := range b.applied {
+		if applied == degradation {
+			return
+		}
+	}
+	b.applied = append(b.applied, degradation)
+	g.Log().Infof(ctx, "Latency budget degradation applied - %s, remaining: %dms of %dms", degradation, remaining.Milliseconds(), b.total.Milliseconds())
+}
+
+// reducedTopK 检索数量减半，至少保留 1 个
+func reducedTopK(topK int) int {
+	if topK <= 1 {
+		return 1
+	}
+	return topK / 2
+}
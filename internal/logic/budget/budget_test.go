@@ -0,0 +1,89 @@
+package budget
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+)
+
+var testThresholds = Thresholds{
+	Rewrite:       4 * time.Second,
+	FullTopK:      2500 * time.Millisecond,
+	Rerank:        1500 * time.Millisecond,
+	ToolIteration: 3 * time.Second,
+}
+
+func newTestBudget(remaining time.Duration) *Budget {
+	return &Budget{total: 10 * time.Second, start: time.Now().Add(remaining - 10*time.Second), thresholds: testThresholds}
+}
+
+func TestPlanRetrieval(t *testing.T) {
+	tests := []struct {
+		name         string
+		remaining    time.Duration
+		topK         int
+		wantRewrite  bool
+		wantTopK     int
+		wantMode     string
+		degradations string
+	}{
+		{name: "enough time", remaining: 8 * time.Second, topK: 6, wantRewrite: true, wantTopK: 6, wantMode: "rerank", degradations: ""},
+		{name: "skip rewrite", remaining: 3 * time.Second, topK: 6, wantTopK: 6, wantMode: "rerank", degradations: "skip_rewrite"},
+		{name: "reduce default top k", remaining: 2 * time.Second, topK: 0, wantTopK: 2, wantMode: "rerank", degradations: "skip_rewrite,reduce_top_k"},
+		{name: "skip rerank", remaining: time.Second, topK: 6, wantTopK: 3, wantMode: "milvus", degradations: "skip_rewrite,reduce_top_k,skip_rerank"},
+		{name: "exhausted", remaining: -time.Second, topK: 1, wantTopK: 1, wantMode: "milvus", degradations: "skip_rewrite,skip_rerank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBudget(tt.remaining)
+			req := &v1.RetrieverReq{TopK: tt.topK, EnableRewrite: true, RetrieveMode: "rerank"}
+			b.PlanRetrieval(context.Background(), req, 5)
+			if req.EnableRewrite != tt.wantRewrite || req.TopK != tt.wantTopK || req.RetrieveMode != tt.wantMode {
+				t.Errorf("got rewrite=%v topK=%d mode=%s, want rewrite=%v topK=%d mode=%s",
+					req.EnableRewrite, req.TopK, req.RetrieveMode, tt.wantRewrite, tt.wantTopK, tt.wantMode)
+			}
+			if got := strings.Join(b.Report().Degradations, ","); got != tt.degradations {
+				t.Errorf("degradations = %q, want %q", got, tt.degradations)
+			}
+		})
+	}
+}
+
+func TestContinueToolCalls(t *testing.T) {
+	var unlimited *Budget
+	if !unlimited.ContinueToolCalls(context.Background()) || unlimited.Report() != nil {
+		t.Error("nil budget should not limit tool calls or report")
+	}
+
+	if b := newTestBudget(5 * time.Second); !b.ContinueToolCalls(context.Background()) {
+		t.Error("expected another tool iteration with 5s remaining")
+	}
+
+	b := newTestBudget(2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if b.ContinueToolCalls(context.Background()) {
+			t.Error("expected tool calls to stop with 2s remaining")
+		}
+	}
+	report := b.Report()
+	if len(report.Degradations) != 1 || report.Degradations[0] != DegradeCapToolIterations {
+		t.Errorf("degradations = %v, want [%s]", report.Degradations, DegradeCapToolIterations)
+	}
+	if report.BudgetMs != 10000 || report.ElapsedMs < 8000 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if WithContext(ctx, nil) != ctx || FromContext(ctx) != nil {
+		t.Error("nil budget should not be stored in context")
+	}
+	b := newTestBudget(time.Second)
+	if FromContext(WithContext(ctx, b)) != b {
+		t.Error("budget not found in context")
+	}
+}
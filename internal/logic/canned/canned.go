@@ -0,0 +1,258 @@
+package canned
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// MetadataKey 命中预置回答时，助手消息元数据中记录来源的键
+const MetadataKey = "canned_answer"
+
+const (
+	defaultThreshold = 0.92
+	embedBatchSize   = 32
+)
+
+// Settings 预置回答路由配置，可按知识库覆盖全局配置
+type Settings struct {
+	Enabled   bool
+	Threshold float64
+}
+
+// Match 命中的预置回答
+type Match struct {
+	Promotion *gormModel.KBPromotion
+	Score     float64 // 问题相似度，文本完全相同时为 1
+}
+
+// candidate 参与匹配的已审核问答
+type candidate struct {
+	promotion *gormModel.KBPromotion
+	vector    []float32
+}
+
+// vectorCache 缓存已审核问题的向量，键为 embedding 模型ID + 申请ID；审核通过后问题不再修改
+var vectorCache = struct {
+	sync.RWMutex
+	vectors map[string][]float32
+}{vectors: make(map[string][]float32)}
+
+// SettingsFor 读取知识库的预置回答配置：cannedAnswer.knowledgeBases.<知识库ID> 中的配置项覆盖全局配置
+func SettingsFor(ctx context.Context, knowledgeID string) Settings {
+	settings := Settings{
+		Enabled:   g.Cfg().MustGet(ctx, "cannedAnswer.enabled", false).Bool(),
+		Threshold: g.Cfg().MustGet(ctx, "cannedAnswer.threshold", defaultThreshold).Float64(),
+	}
+	if knowledgeID == "" {
+		return settings
+	}
+	override := g.Cfg().MustGet(ctx, "cannedAnswer.knowledgeBases."+knowledgeID).Map()
+	return applyOverride(settings, override)
+}
+
+// applyOverride 使用知识库配置覆盖全局配置，未配置的项保持不变
+func applyOverride(settings Settings, override map[string]interface{}) Settings {
+	if v, ok := override["enabled"].(bool); ok {
+		settings.Enabled = v
+	}
+	switch v := override["threshold"].(type) {
+	case float64:
+		settings.Threshold = v
+	case int:
+		settings.Threshold = float64(v)
+	}
+	return settings
+}
+
+// Find 在知识库已审核通过的问答中查找与问题几乎相同的一条，未启用或没有达到阈值的问答时返回 nil
+func Find(ctx context.Context, knowledgeID, embeddingModelID, question string) (*Match, error) {
+	settings := SettingsFor(ctx, knowledgeID)
+	if !settings.Enabled || knowledgeID == "" || strings.TrimSpace(question) == "" {
+		return nil, nil
+	}
+	promotions, err := dao.KBPromotion.List(ctx, knowledgeID, gormModel.PromotionStatusApproved, "")
+	if err != nil {
+		return nil, err
+	}
+	// 依据的分片已变化的问答不再直接返回
+	if promotions, err = usablePromotions(ctx, knowledgeID, promotions); err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return nil, nil
+	}
+
+	// 文本完全相同（忽略大小写、空白和标点）时无需向量化
+	normalized := normalize(question)
+	for _, promotion := range promotions {
+		if normalized != "" && normalize(promotion.Question) == normalized {
+			return &Match{Promotion: promotion, Score: 1}, nil
+		}
+	}
+
+	modelID, err := resolveEmbeddingModel(ctx, knowledgeID, embeddingModelID)
+	if err != nil {
+		return nil, err
+	}
+	embedder, dim, err := newEmbedder(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := candidateVectors(ctx, embedder, dim, modelID, promotions)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := embedder.EmbedStrings(ctx, []string{question}, dim)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed question: %w", err)
+	}
+	if len(vectors) == 0 {
+		return nil, nil
+	}
+	return bestMatch(vectors[0], candidates, settings.Threshold), nil
+}
+
+// bestMatch 返回相似度最高且不低于阈值的候选
+func bestMatch(query []float32, candidates []*candidate, threshold float64) *Match {
+	var best *Match
+	for _, c := range candidates {
+		score := cosineSimilarity(query, c.vector)
+		if score < threshold {
+			continue
+		}
+		if best == nil || score > best.Score {
+			best = &Match{Promotion: c.promotion, Score: score}
+		}
+	}
+	return best
+}
+
+// normalize 去除空白和标点并转为小写，用于判断问题文本是否完全相同
+func normalize(s string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// resolveEmbeddingModel 确定向量化问题使用的 embedding 模型：配置项 > 请求指定 > 知识库最近索引使用的模型 > 第一个注册的模型
+func resolveEmbeddingModel(ctx context.Context, knowledgeID, embeddingModelID string) (string, error) {
+	if modelID := g.Cfg().MustGet(ctx, "cannedAnswer.embeddingModelID", "").String(); modelID != "" {
+		return modelID, nil
+	}
+	if embeddingModelID != "" {
+		return embeddingModelID, nil
+	}
+	modelID, err := knowledge.GetLatestEmbeddingModelID(ctx, knowledgeID)
+	if err != nil {
+		return "", err
+	}
+	if modelID != "" {
+		return modelID, nil
+	}
+	if models := model.Registry.GetByType(model.ModelTypeEmbedding); len(models) > 0 {
+		return models[0].ModelID, nil
+	}
+	return "", fmt.Errorf("no embedding model available for canned answer matching")
+}
+
+// newEmbedder 创建 embedding 客户端并确定向量维度
+func newEmbedder(ctx context.Context, modelID string) (*common.CustomEmbedder, int, error) {
+	mc := model.Registry.Get(modelID)
+	if mc == nil {
+		return nil, 0, fmt.Errorf("embedding model not found: %s", modelID)
+	}
+	embedder, err := common.NewEmbedding(ctx, &config.RetrieverConfigBase{
+		APIKey:         mc.APIKey,
+		BaseURL:        mc.BaseURL,
+		EmbeddingModel: mc.Name,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create embedder: %w", err)
+	}
+	if v, ok := mc.Extra["dimension"].(float64); ok && v > 0 {
+		return embedder, int(v), nil
+	}
+	dimKey := "milvus.dim"
+	switch g.Cfg().MustGet(ctx, "vectorStore.type", "milvus").String() {
+	case "pgvector":
+		dimKey = "postgres.dim"
+	case "qdrant":
+		dimKey = "qdrant.dim"
+	case "elasticsearch":
+		dimKey = "elasticsearch.dim"
+	}
+	return embedder, embedder.ResolveDimension(ctx, g.Cfg().MustGet(ctx, dimKey, 1024).Int()), nil
+}
+
+// candidateVectors 获取已审核问题的向量，未缓存的问题分批向量化后写入缓存
+func candidateVectors(ctx context.Context, embedder *common.CustomEmbedder, dim int, modelID string, promotions []*gormModel.KBPromotion) ([]*candidate, error) {
+	candidates := make([]*candidate, 0, len(promotions))
+	var missing []*candidate
+	vectorCache.RLock()
+	for _, promotion := range promotions {
+		c := &candidate{promotion: promotion, vector: vectorCache.vectors[cacheKey(modelID, promotion.ID)]}
+		if c.vector == nil {
+			missing = append(missing, c)
+		}
+		candidates = append(candidates, c)
+	}
+	vectorCache.RUnlock()
+
+	for i := 0; i < len(missing); i += embedBatchSize {
+		batch := missing[i:min(i+embedBatchSize, len(missing))]
+		texts := make([]string, 0, len(batch))
+		for _, c := range batch {
+			texts = append(texts, c.promotion.Question)
+		}
+		vectors, err := embedder.EmbedStrings(ctx, texts, dim)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed curated questions: %w", err)
+		}
+		vectorCache.Lock()
+		for j, c := range batch {
+			if j < len(vectors) {
+				c.vector = vectors[j]
+				vectorCache.vectors[cacheKey(modelID, c.promotion.ID)] = c.vector
+			}
+		}
+		vectorCache.Unlock()
+	}
+	return candidates, nil
+}
+
+func cacheKey(modelID, promotionID string) string {
+	return modelID + ":" + promotionID
+}
@@ -0,0 +1,99 @@
+package canned
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"如何重置密码？", "如何 重置密码", true},
+		{"How do I reset my Password?", "how do i reset my password", true},
+		{"如何重置密码", "如何修改密码", false},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.a) == normalize(tt.b); got != tt.same {
+			t.Errorf("normalize(%q) == normalize(%q) = %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	candidates := []*candidate{
+		{promotion: &gormModel.KBPromotion{ID: "a"}, vector: []float32{1, 0}},
+		{promotion: &gormModel.KBPromotion{ID: "b"}, vector: []float32{0.9, 0.1}},
+		{promotion: &gormModel.KBPromotion{ID: "c"}, vector: nil},
+	}
+	tests := []struct {
+		name      string
+		query     []float32
+		threshold float64
+		want      string
+	}{
+		{"closest wins", []float32{1, 0}, 0.9, "a"},
+		{"closer to b", []float32{0.9, 0.12}, 0.9, "b"},
+		{"below threshold", []float32{0, 1}, 0.9, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bestMatch(tt.query, candidates, tt.threshold)
+			id := ""
+			if got != nil {
+				id = got.Promotion.ID
+			}
+			if id != tt.want {
+				t.Errorf("bestMatch() = %q, want %q", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOverride(t *testing.T) {
+	base := Settings{Enabled: false, Threshold: 0.92}
+	got := applyOverride(base, map[string]interface{}{"enabled": true, "threshold": 0.95})
+	if !got.Enabled || got.Threshold != 0.95 {
+		t.Errorf("applyOverride() = %+v", got)
+	}
+	if got = applyOverride(base, nil); got != base {
+		t.Errorf("applyOverride(nil) = %+v, want %+v", got, base)
+	}
+}
+
+func TestSourceChunkIDs(t *testing.T) {
+	p := &gormModel.KBPromotion{ChunkID: "faq1", Sources: gormModel.JSON(`[{"id":"c1","score":0.9},{"id":"faq1"},{"id":""}]`)}
+	ids := sourceChunkIDs(p)
+	if len(ids) != 2 || ids[0] != "faq1" || ids[1] != "c1" {
+		t.Errorf("sourceChunkIDs() = %v, want [faq1 c1]", ids)
+	}
+	if ids := sourceChunkIDs(&gormModel.KBPromotion{}); len(ids) != 0 {
+		t.Errorf("sourceChunkIDs() = %v, want empty", ids)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	sourceCache.valid["kb1"] = map[string]bool{"p1": true}
+	sourceCache.valid["kb2"] = map[string]bool{"p2": true}
+	generation := sourceCache.generation
+
+	kbevent.Publish(context.Background(), &kbevent.Event{Type: kbevent.TypeIndexed, KnowledgeID: "kb1", DocumentID: "d1"})
+	if _, ok := sourceCache.valid["kb1"]; ok {
+		t.Error("expected kb1 to be invalidated")
+	}
+	if _, ok := sourceCache.valid["kb2"]; !ok {
+		t.Error("expected kb2 to be kept")
+	}
+	if sourceCache.generation == generation {
+		t.Error("expected generation to change")
+	}
+
+	kbevent.Publish(context.Background(), &kbevent.Event{Type: kbevent.TypeUpdated})
+	if len(sourceCache.valid) != 0 {
+		t.Errorf("expected all knowledge bases to be invalidated, got %v", sourceCache.valid)
+	}
+}
@@ -0,0 +1,135 @@
+package canned
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
+	"github.com/Malowking/kbgo/internal/logic/promotion"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// sourceCache 已审核问答的参考分片检查结果，按知识库缓存（知识库ID -> 申请ID -> 是否仍可直接返回）；
+// 知识库内容变更时清除该知识库的结果，generation 用于丢弃变更前开始、变更后才写入的检查结果
+var sourceCache = struct {
+	sync.RWMutex
+	generation uint64
+	valid      map[string]map[string]bool
+}{valid: make(map[string]map[string]bool)}
+
+func init() {
+	kbevent.Subscribe(invalidate)
+}
+
+// invalidate 知识库内容变更后清除该知识库的检查结果，未指定知识库时全部清除
+func invalidate(ctx context.Context, event *kbevent.Event) {
+	sourceCache.Lock()
+	defer sourceCache.Unlock()
+	sourceCache.generation++
+	if event.KnowledgeID == "" {
+		sourceCache.valid = make(map[string]map[string]bool)
+		return
+	}
+	if _, ok := sourceCache.valid[event.KnowledgeID]; ok {
+		delete(sourceCache.valid, event.KnowledgeID)
+		g.Log().Debugf(ctx, "Canned answer cache invalidated, knowledgeID=%s, event=%s", event.KnowledgeID, event.Type)
+	}
+}
+
+// usablePromotions 过滤写入的 FAQ 分片或引用的参考分片已被删除、停用或随文档重新索引的问答：
+// 回答所依据的内容已经变化，继续直接返回可能过期，这些问题改为走正常的检索和生成
+func usablePromotions(ctx context.Context, knowledgeID string, promotions []*gormModel.KBPromotion) ([]*gormModel.KBPromotion, error) {
+	if !g.Cfg().MustGet(ctx, "cannedAnswer.checkSources", true).Bool() {
+		return promotions, nil
+	}
+
+	sourceCache.RLock()
+	generation := sourceCache.generation
+	cached := sourceCache.valid[knowledgeID]
+	var unchecked []*gormModel.KBPromotion
+	for _, p := range promotions {
+		if _, ok := cached[p.ID]; !ok {
+			unchecked = append(unchecked, p)
+		}
+	}
+	sourceCache.RUnlock()
+
+	checked, err := checkSources(ctx, unchecked)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceCache.Lock()
+	if sourceCache.generation == generation {
+		if sourceCache.valid[knowledgeID] == nil {
+			sourceCache.valid[knowledgeID] = make(map[string]bool, len(promotions))
+		}
+		for id, valid := range checked {
+			sourceCache.valid[knowledgeID][id] = valid
+		}
+	}
+	cached = sourceCache.valid[knowledgeID]
+	sourceCache.Unlock()
+
+	usable := make([]*gormModel.KBPromotion, 0, len(promotions))
+	for _, p := range promotions {
+		valid, ok := checked[p.ID]
+		if !ok {
+			valid = cached[p.ID]
+		}
+		if valid {
+			usable = append(usable, p)
+		}
+	}
+	return usable, nil
+}
+
+// checkSources 检查问答依赖的分片是否全部仍然有效
+func checkSources(ctx context.Context, promotions []*gormModel.KBPromotion) (map[string]bool, error) {
+	if len(promotions) == 0 {
+		return nil, nil
+	}
+	chunkIDs := make(map[string][]string, len(promotions))
+	var all []string
+	for _, p := range promotions {
+		ids := sourceChunkIDs(p)
+		chunkIDs[p.ID] = ids
+		all = append(all, ids...)
+	}
+	active, err := dao.KnowledgeChunks.GetActiveChunkIDs(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := make(map[string]bool, len(promotions))
+	for _, p := range promotions {
+		valid := true
+		for _, id := range chunkIDs[p.ID] {
+			if !active.Contains(id) {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			g.Log().Infof(ctx, "Canned answer skipped because its source chunks changed, promotionID=%s", p.ID)
+		}
+		checked[p.ID] = valid
+	}
+	return checked, nil
+}
+
+// sourceChunkIDs 问答依赖的分片：写入知识库的 FAQ 分片和回答引用的参考分片
+func sourceChunkIDs(p *gormModel.KBPromotion) []string {
+	var ids []string
+	if p.ChunkID != "" {
+		ids = append(ids, p.ChunkID)
+	}
+	for _, source := range promotion.ParseSources(p.Sources) {
+		if source.ID != "" && source.ID != p.ChunkID {
+			ids = append(ids, source.ID)
+		}
+	}
+	return ids
+}
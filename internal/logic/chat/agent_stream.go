@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// AgentFunc 执行工具调用循环并生成最终答案，答案的增量内容通过 emit 实时输出，返回完整的最终答案
+type AgentFunc func(ctx context.Context, emit func(delta string)) (string, error)
+
+// AgentStreamEnabled 是否流式输出工具调用的最终答案，关闭时在工具调用结束后再基于检索结果生成回答
+func AgentStreamEnabled(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "chat.agentStream", true).Bool()
+}
+
+// StreamAgentAnswer 流式输出工具调用循环生成的最终答案，与 GetAnswerStream 一样保存用户消息和带指标的助手消息
+// 工具调用失败或没有生成答案时改用 GetAnswerStream 基于检索结果回答
+func (x *Chat) StreamAgentAnswer(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, style *ResponseStyle, agent AgentFunc) (*schema.StreamReader[*schema.Message], error) {
+	start := time.Now()
+	streamReader, streamWriter := schema.Pipe[*schema.Message](10)
+
+	go func() {
+		defer streamWriter.Close()
+
+		var mu sync.Mutex
+		var content strings.Builder
+		closed := false
+		emit := func(delta string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if delta == "" || closed {
+				return
+			}
+			content.WriteString(delta)
+			if streamWriter.Send(&schema.Message{Role: schema.Assistant, Content: delta}, nil) {
+				g.Log().Warningf(ctx, "stream writer closed unexpectedly")
+				closed = true
+			}
+		}
+
+		answer, err := agent(ctx, emit)
+		mu.Lock()
+		streamed := content.String()
+		mu.Unlock()
+		switch {
+		case streamed != "":
+			if err != nil {
+				g.Log().Warningf(ctx, "Agent loop failed after streaming part of the answer: %v", err)
+			}
+		case err != nil || answer == "":
+			if err != nil {
+				g.Log().Warningf(ctx, "Agent loop failed, answering from retrieved documents: %v", err)
+			}
+			x.relayAnswerStream(ctx, modelID, convID, docs, question, style, streamWriter)
+			return
+		default:
+			// 最终答案没有以流式生成（如工具调用阶段超时后的兜底调用），一次性输出
+			emit(answer)
+			streamed = answer
+		}
+
+		// 保存使用与请求分离的上下文，客户端断开连接时也能完成
+		ctx := common.DetachContext(ctx)
+		for _, violation := range style.Validate(streamed) {
+			g.Log().Warningf(ctx, "Response style violation: %s", violation)
+		}
+
+		userMessage := &schema.Message{
+			Role:    schema.User,
+			Content: question,
+		}
+		if err := x.eh.SaveMessage(ctx, userMessage, convID); err != nil {
+			g.Log().Errorf(ctx, "save user message err: %v", err)
+			return
+		}
+
+		msgWithMetrics := &history.MessageWithMetrics{
+			Message: &schema.Message{
+				Role:    schema.Assistant,
+				Content: streamed,
+			},
+			ModelID:   servedModelID(ctx, modelID),
+			LatencyMs: int(time.Since(start).Milliseconds()),
+			Metadata:  map[string]interface{}{},
+		}
+		if summary := agentrun.FromContext(ctx).Summary(); summary != nil {
+			msgWithMetrics.TokensUsed = summary.TokensUsed
+		}
+
+		confidence := ScoreAnswer(ctx, docs, streamed, nil)
+		NotifyEscalation(ctx, convID, question, streamed, confidence)
+		if confidence != nil {
+			msgWithMetrics.Metadata[ConfidenceMetadataKey] = confidence
+		}
+
+		tagExperiments(ctx, msgWithMetrics)
+		msgWithMetrics.Metadata = agentrun.Tag(ctx, msgWithMetrics.Metadata)
+		msgWithMetrics.Metadata = tagModelFallback(ctx, msgWithMetrics.Metadata)
+		quota.RecordTokens(ctx, msgWithMetrics.TokensUsed)
+		tagRetrievalTrace(msgWithMetrics, question, docs)
+		if err := x.eh.SaveMessageWithMetrics(ctx, msgWithMetrics, convID); err != nil {
+			g.Log().Errorf(ctx, "save assistant message err: %v", err)
+		}
+	}()
+
+	return streamReader, nil
+}
+
+// relayAnswerStream 基于检索结果流式生成回答并转发到 streamWriter，消息由 GetAnswerStream 保存
+func (x *Chat) relayAnswerStream(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, style *ResponseStyle, streamWriter *schema.StreamWriter[*schema.Message]) {
+	answer, err := x.GetAnswerStream(ctx, modelID, convID, docs, question, false, style)
+	if err != nil {
+		streamWriter.Send(&schema.Message{Role: schema.Assistant}, err)
+		return
+	}
+	defer answer.Close()
+	for {
+		msg, err := answer.Recv()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if closed := streamWriter.Send(msg, err); closed || err != nil {
+			return
+		}
+	}
+}
@@ -2,18 +2,19 @@ package chat
 
 import (
 	"context"
-	"encoding/base64"
-	"errors"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/Malowking/kbgo/core/formatter"
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/media"
 	coreModel "github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/experiment"
+	"github.com/Malowking/kbgo/internal/logic/quota"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
@@ -60,6 +61,9 @@ func parseModelParams(extra map[string]any) *ModelParams {
 	if maxCompletionTokens, ok := extra["maxCompletionTokens"].(int); ok {
 		params.MaxCompletionTokens = ToPointer(maxCompletionTokens)
 	}
+	if contextWindow := coreModel.ContextWindow(extra); contextWindow > 0 {
+		params.ContextWindow = ToPointer(contextWindow)
+	}
 	if freqPenalty, ok := extra["frequencyPenalty"].(float64); ok {
 		params.FrequencyPenalty = ToPointer(float32(freqPenalty))
 	}
@@ -80,33 +84,41 @@ func parseModelParams(extra map[string]any) *ModelParams {
 			params.Stop = stopWords
 		}
 	}
+	if policy, ok := extra["reasoningPolicy"].(string); ok {
+		params.ReasoningPolicy = policy
+	}
+	if frames, ok := extra["videoFrames"].(float64); ok {
+		params.VideoFrames = ToPointer(int(frames))
+	}
+	if transcript, ok := extra["videoTranscript"].(bool); ok {
+		params.VideoTranscript = ToPointer(transcript)
+	}
 
 	return &params
 }
 
 // GetAnswer 使用指定模型生成答案（非流式）
-func (x *Chat) GetAnswer(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, jsonFormat bool) (answer string, err error) {
+func (x *Chat) GetAnswer(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, jsonFormat bool, style *ResponseStyle) (answer string, reasoning string, confidence *v1.AnswerConfidence, err error) {
 	// 获取模型配置
 	mc := coreModel.Registry.Get(modelID)
 	if mc == nil {
-		return "", fmt.Errorf("model not found: %s", modelID)
+		return "", "", nil, fmt.Errorf("model not found: %s", modelID)
 	}
-
-	// 根据模型类型选择格式适配器
-	var msgFormatter formatter.MessageFormatter
-	if IsQwenModel(mc.Name) {
-		msgFormatter = formatter.NewQwenFormatter()
-	} else {
-		msgFormatter = formatter.NewOpenAIFormatter()
+	// 模型及其备用模型都被健康检查标记为不可用时直接失败，不保存用户消息
+	if err := coreModel.Registry.CheckChainAvailable(modelID); err != nil {
+		return "", "", nil, err
 	}
 
+	// 根据模型提供商和名称选择格式适配器
+	msgFormatter := coreModel.FormatterFor(mc)
+
 	// 创建模型服务
-	modelService := coreModel.NewModelService(mc.APIKey, mc.BaseURL, msgFormatter)
+	modelService := coreModel.NewModelServiceFor(mc, msgFormatter)
 
 	// 获取聊天历史
-	chatHistory, err := x.eh.GetHistory(convID, 100)
+	chatHistory, err := x.eh.GetHistoryForModel(convID, 100, historyPartTypes(mc)...)
 	if err != nil {
-		return "", err
+		return "", "", nil, err
 	}
 
 	// 保存用户消息
@@ -114,21 +126,20 @@ func (x *Chat) GetAnswer(ctx context.Context, modelID string, convID string, doc
 		Role:    schema.User,
 		Content: question,
 	}
-	err = x.eh.SaveMessage(userMessage, convID)
+	err = x.eh.SaveMessage(ctx, userMessage, convID)
 	if err != nil {
-		return "", err
+		return "", "", nil, err
 	}
 
 	// 格式化文档为系统提示
-	formattedDocs := formatDocumentsForChat(docs)
+	formattedDocs := formatDocumentsForChat(ctx, docs)
 
 	// 构建消息列表
 	messages := []*schema.Message{
 		{
 			Role: schema.System,
-			Content: "你是一个专业的AI助手，能够根据提供的参考信息准确回答用户问题。" +
-				"如果没有提供参考信息，也请根据你的知识自由回答用户问题。\n\n" +
-				formattedDocs,
+			Content: experiment.SystemPrompt(ctx, role) + "\n\n" +
+				formattedDocs + style.PromptConstraints(),
 		},
 	}
 	messages = append(messages, chatHistory...)
@@ -150,30 +161,37 @@ func (x *Chat) GetAnswer(ctx context.Context, modelID string, convID string, doc
 		Messages:            messages,
 		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
 		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
 		TopP:                getFloat32OrDefault(params.TopP, 0.9),
 		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
 		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
 		N:                   getIntOrDefault(params.N, 1),
-		Stop:                params.Stop,
+		Stop:                stopSequences(ctx, params.Stop),
 		Tools:               params.Tools,
 		ToolChoice:          params.ToolChoice,
 		ResponseFormat:      params.ResponseFormat,
+		LogProbs:            ConfidenceLogProbsEnabled(ctx),
 	}
 
 	// 记录开始时间
 	start := time.Now()
 
 	// 调用模型服务
-	resp, err := modelService.ChatCompletion(ctx, chatParams)
+	resp, err := completeWithContinuation(ctx, modelService, chatParams)
 	if err != nil {
-		return "", fmt.Errorf("API调用失败: %w", err)
+		return "", "", nil, fmt.Errorf("API调用失败: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("received empty choices from API")
+		return "", "", nil, fmt.Errorf("received empty choices from API")
 	}
 
-	answerContent := resp.Choices[0].Message.Content
+	answerContent := style.Apply(ctx, resp.Choices[0].Message.Content)
+	reasoning = visibleReasoning(ctx, reasoningPolicy(ctx, params), resp.Choices[0].Message.ReasoningContent)
+
+	// 计算回答置信度，低于升级阈值时通知升级 webhook
+	confidence = ScoreAnswer(ctx, docs, answerContent, resp.Choices[0].LogProbs)
+	NotifyEscalation(ctx, convID, question, answerContent, confidence)
 
 	// 计算延迟
 	latencyMs := time.Since(start).Milliseconds()
@@ -187,40 +205,48 @@ func (x *Chat) GetAnswer(ctx context.Context, modelID string, convID string, doc
 	// 创建带指标的消息
 	msgWithMetrics := &history.MessageWithMetrics{
 		Message:    assistantMsg,
+		ModelID:    servedModelID(ctx, modelID),
 		LatencyMs:  int(latencyMs),
-		TokensUsed: resp.Usage.TotalTokens,
+		TokensUsed: usageOrEstimate(resp.Usage.TotalTokens, chatParams, answerContent),
 	}
+	if confidence != nil {
+		msgWithMetrics.Metadata = map[string]interface{}{ConfidenceMetadataKey: confidence}
+	}
+	msgWithMetrics.Metadata = agentrun.Tag(ctx, msgWithMetrics.Metadata)
+	msgWithMetrics.Metadata = tagModelFallback(ctx, msgWithMetrics.Metadata)
 
-	err = x.eh.SaveMessageWithMetrics(msgWithMetrics, convID)
+	tagExperiments(ctx, msgWithMetrics)
+	quota.RecordTokens(ctx, msgWithMetrics.TokensUsed)
+	tagRetrievalTrace(msgWithMetrics, question, docs)
+	tagReasoning(msgWithMetrics, reasoning)
+	err = x.eh.SaveMessageWithMetrics(ctx, msgWithMetrics, convID)
 	if err != nil {
 		g.Log().Error(ctx, "save assistant message err: %v", err)
 		return
 	}
 
-	return answerContent, nil
+	return answerContent, reasoning, confidence, nil
 }
 
 // GetAnswerStream 使用指定模型流式生成答案
-func (x *Chat) GetAnswerStream(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, jsonFormat bool) (answer *schema.StreamReader[*schema.Message], err error) {
+func (x *Chat) GetAnswerStream(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, jsonFormat bool, style *ResponseStyle) (answer *schema.StreamReader[*schema.Message], err error) {
 	// 获取模型配置
 	mc := coreModel.Registry.Get(modelID)
 	if mc == nil {
 		return nil, fmt.Errorf("model not found: %s", modelID)
 	}
-
-	// 根据模型类型选择格式适配器
-	var msgFormatter formatter.MessageFormatter
-	if IsQwenModel(mc.Name) {
-		msgFormatter = formatter.NewQwenFormatter()
-	} else {
-		msgFormatter = formatter.NewOpenAIFormatter()
+	if err := coreModel.Registry.CheckChainAvailable(modelID); err != nil {
+		return nil, err
 	}
 
+	// 根据模型提供商和名称选择格式适配器
+	msgFormatter := coreModel.FormatterFor(mc)
+
 	// 创建模型服务
-	modelService := coreModel.NewModelService(mc.APIKey, mc.BaseURL, msgFormatter)
+	modelService := coreModel.NewModelServiceFor(mc, msgFormatter)
 
 	// 获取聊天历史
-	chatHistory, err := x.eh.GetHistory(convID, 100)
+	chatHistory, err := x.eh.GetHistoryForModel(convID, 100, historyPartTypes(mc)...)
 	if err != nil {
 		return nil, err
 	}
@@ -230,21 +256,20 @@ func (x *Chat) GetAnswerStream(ctx context.Context, modelID string, convID strin
 		Role:    schema.User,
 		Content: question,
 	}
-	err = x.eh.SaveMessage(userMessage, convID)
+	err = x.eh.SaveMessage(ctx, userMessage, convID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 格式化文档为系统提示
-	formattedDocs := formatDocumentsForChat(docs)
+	formattedDocs := formatDocumentsForChat(ctx, docs)
 
 	// 构建消息列表
 	messages := []*schema.Message{
 		{
 			Role: schema.System,
-			Content: "你是一个专业的AI助手，能够根据提供的参考信息准确回答用户问题。" +
-				"如果没有提供参考信息，也请根据你的知识自由回答用户问题。\n\n" +
-				formattedDocs,
+			Content: experiment.SystemPrompt(ctx, role) + "\n\n" +
+				formattedDocs + style.PromptConstraints(),
 		},
 	}
 	messages = append(messages, chatHistory...)
@@ -266,11 +291,12 @@ func (x *Chat) GetAnswerStream(ctx context.Context, modelID string, convID strin
 		Messages:            messages,
 		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
 		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
 		TopP:                getFloat32OrDefault(params.TopP, 0.9),
 		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
 		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
 		N:                   getIntOrDefault(params.N, 1),
-		Stop:                params.Stop,
+		Stop:                stopSequences(ctx, params.Stop),
 		Tools:               params.Tools,
 		ToolChoice:          params.ToolChoice,
 		ResponseFormat:      params.ResponseFormat,
@@ -291,78 +317,68 @@ func (x *Chat) GetAnswerStream(ctx context.Context, modelID string, convID strin
 	// 启动goroutine处理流式响应
 	go func() {
 		defer streamWriter.Close()
-		defer stream.Close()
-
-		var fullContent strings.Builder
-		var tokenCount int
-
-		for {
-			response, err := stream.Recv()
-			if errors.Is(err, io.EOF) {
-				// 流结束，保存完整消息
-				assistantMsg := &schema.Message{
-					Role:    schema.Assistant,
-					Content: fullContent.String(),
-				}
 
-				// 计算延迟
-				latencyMs := time.Since(start).Milliseconds()
+		// 转发流式输出，输出因长度限制被截断时自动续写
+		reasoning := newReasoningRelay(ctx, reasoningPolicy(ctx, params), streamWriter)
+		result, ok := relayStream(ctx, modelService, chatParams, stream, streamWriter, reasoning)
+		if !ok {
+			return
+		}
+		content := result.Answer
 
-				// 创建带指标的消息
-				msgWithMetrics := &history.MessageWithMetrics{
-					Message:    assistantMsg,
-					LatencyMs:  int(latencyMs),
-					TokensUsed: tokenCount,
-				}
+		// 流结束后的校验、统计和保存使用与请求分离的上下文，客户端断开连接时也能完成，并保留 trace 等请求信息
+		ctx := common.DetachContext(ctx)
 
-				// 异步保存消息
-				saveErr := x.eh.SaveMessageWithMetrics(msgWithMetrics, convID)
-				if saveErr != nil {
-					g.Log().Errorf(ctx, "save assistant message err: %v", saveErr)
-				}
+		// 流式输出无法改写已发送内容，只做约束校验
+		for _, violation := range style.Validate(content) {
+			g.Log().Warningf(ctx, "Response style violation: %s", violation)
+		}
 
-				return
-			}
+		// 流结束，保存完整消息
+		assistantMsg := &schema.Message{
+			Role:    schema.Assistant,
+			Content: content,
+		}
 
-			if err != nil {
-				g.Log().Errorf(ctx, "stream receive error: %v", err)
-				streamWriter.Send(&schema.Message{
-					Role:    schema.Assistant,
-					Content: "",
-				}, err)
-				return
-			}
+		// 计算延迟
+		latencyMs := time.Since(start).Milliseconds()
 
-			// 处理流式响应
-			if len(response.Choices) > 0 {
-				delta := response.Choices[0].Delta.Content
-				if delta != "" {
-					fullContent.WriteString(delta)
+		// 创建带指标的消息
+		msgWithMetrics := &history.MessageWithMetrics{
+			Message:    assistantMsg,
+			ModelID:    servedModelID(ctx, modelID),
+			LatencyMs:  int(latencyMs),
+			TokensUsed: result.Tokens,
+			Metadata:   map[string]interface{}{},
+		}
+		if result.Truncated != "" {
+			msgWithMetrics.Metadata["truncated"] = result.Truncated
+		}
 
-					// 创建增量消息并发送到流
-					chunk := &schema.Message{
-						Role:    schema.Assistant,
-						Content: delta,
-					}
-					closed := streamWriter.Send(chunk, nil)
-					if closed {
-						g.Log().Warningf(ctx, "stream writer closed unexpectedly")
-						return
-					}
-				}
+		// 流式回答没有 logprobs，置信度只基于检索得分和一致性
+		confidence := ScoreAnswer(ctx, docs, assistantMsg.Content, nil)
+		NotifyEscalation(ctx, convID, question, assistantMsg.Content, confidence)
+		if confidence != nil {
+			msgWithMetrics.Metadata[ConfidenceMetadataKey] = confidence
+		}
 
-				// 累计token数量（如果有usage信息）
-				if response.Usage != nil {
-					tokenCount = response.Usage.TotalTokens
-				}
-			}
+		// 异步保存消息
+		tagExperiments(ctx, msgWithMetrics)
+		msgWithMetrics.Metadata = agentrun.Tag(ctx, msgWithMetrics.Metadata)
+		msgWithMetrics.Metadata = tagModelFallback(ctx, msgWithMetrics.Metadata)
+		quota.RecordTokens(ctx, msgWithMetrics.TokensUsed)
+		tagRetrievalTrace(msgWithMetrics, question, docs)
+		tagReasoning(msgWithMetrics, reasoning.Visible())
+		saveErr := x.eh.SaveMessageWithMetrics(ctx, msgWithMetrics, convID)
+		if saveErr != nil {
+			g.Log().Errorf(ctx, "save assistant message err: %v", saveErr)
 		}
 	}()
 
 	return streamReader, nil
 }
 
-// preprocessMultimodalMessages 预处理多模态消息，将文件路径转换为base64
+// preprocessMultimodalMessages 预处理多模态消息，将文件路径转换为base64 data URI，按文件内容识别类型，图片转换为模型接口接受的格式和尺寸
 func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Message) error {
 	for _, msg := range messages {
 		// 处理 UserInputMultiContent
@@ -381,22 +397,11 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 					if part.Image.URL != nil && *part.Image.URL != "" {
 						urlStr := *part.Image.URL
 						if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-							data, err := os.ReadFile(urlStr)
+							dataURI, err := media.ImageDataURI(ctx, urlStr)
 							if err != nil {
 								g.Log().Warningf(ctx, "Failed to read image file %s: %v, skipping", urlStr, err)
 								continue
 							}
-
-							// 获取MIME类型
-							mimeType := part.Image.MIMEType
-							if mimeType == "" {
-								ext := filepath.Ext(urlStr)
-								mimeType = getMimeType(ext)
-							}
-
-							base64Data := base64.StdEncoding.EncodeToString(data)
-							// 构造data URI格式
-							dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
 							part.Image.URL = &dataURI
 						}
 					}
@@ -413,22 +418,11 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 					if part.Audio.URL != nil && *part.Audio.URL != "" {
 						urlStr := *part.Audio.URL
 						if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-							data, err := os.ReadFile(urlStr)
+							dataURI, err := media.FileDataURI(urlStr)
 							if err != nil {
 								g.Log().Warningf(ctx, "Failed to read audio file %s: %v, skipping", urlStr, err)
 								continue
 							}
-
-							// 获取MIME类型
-							mimeType := part.Audio.MIMEType
-							if mimeType == "" {
-								ext := filepath.Ext(urlStr)
-								mimeType = getMimeType(ext)
-							}
-
-							base64Data := base64.StdEncoding.EncodeToString(data)
-							// 构造data URI格式
-							dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
 							part.Audio.URL = &dataURI
 						}
 					}
@@ -445,22 +439,11 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 					if part.Video.URL != nil && *part.Video.URL != "" {
 						urlStr := *part.Video.URL
 						if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-							data, err := os.ReadFile(urlStr)
+							dataURI, err := media.FileDataURI(urlStr)
 							if err != nil {
 								g.Log().Warningf(ctx, "Failed to read video file %s: %v, skipping", urlStr, err)
 								continue
 							}
-
-							// 获取MIME类型
-							mimeType := part.Video.MIMEType
-							if mimeType == "" {
-								ext := filepath.Ext(urlStr)
-								mimeType = getMimeType(ext)
-							}
-
-							base64Data := base64.StdEncoding.EncodeToString(data)
-							// 构造data URI格式
-							dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
 							part.Video.URL = &dataURI
 						}
 					}
@@ -478,19 +461,12 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 					urlStr := part.ImageURL.URL
 					// 如果是文件路径，读取并转换为base64
 					if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-						data, err := os.ReadFile(urlStr)
+						dataURI, err := media.ImageDataURI(ctx, urlStr)
 						if err != nil {
 							g.Log().Warningf(ctx, "Failed to read image file %s: %v, skipping", urlStr, err)
 							continue
 						}
-
-						// 获取MIME类型
-						ext := filepath.Ext(urlStr)
-						mimeType := getMimeType(ext)
-
-						base64Data := base64.StdEncoding.EncodeToString(data)
-						// 构造data URI格式
-						part.ImageURL.URL = fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
+						part.ImageURL.URL = dataURI
 					}
 				}
 
@@ -499,19 +475,12 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 					urlStr := part.AudioURL.URL
 					// 如果是文件路径，读取并转换为base64
 					if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-						data, err := os.ReadFile(urlStr)
+						dataURI, err := media.FileDataURI(urlStr)
 						if err != nil {
 							g.Log().Warningf(ctx, "Failed to read audio file %s: %v, skipping", urlStr, err)
 							continue
 						}
-
-						// 获取MIME类型
-						ext := filepath.Ext(urlStr)
-						mimeType := getMimeType(ext)
-
-						base64Data := base64.StdEncoding.EncodeToString(data)
-						// 构造data URI格式
-						part.AudioURL.URL = fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
+						part.AudioURL.URL = dataURI
 					}
 				}
 
@@ -520,19 +489,12 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 					urlStr := part.VideoURL.URL
 					// 如果是文件路径，读取并转换为base64
 					if len(urlStr) > 0 && (urlStr[0] == '/' || urlStr[0] == '.') {
-						data, err := os.ReadFile(urlStr)
+						dataURI, err := media.FileDataURI(urlStr)
 						if err != nil {
 							g.Log().Warningf(ctx, "Failed to read video file %s: %v, skipping", urlStr, err)
 							continue
 						}
-
-						// 获取MIME类型
-						ext := filepath.Ext(urlStr)
-						mimeType := getMimeType(ext)
-
-						base64Data := base64.StdEncoding.EncodeToString(data)
-						// 构造data URI格式
-						part.VideoURL.URL = fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
+						part.VideoURL.URL = dataURI
 					}
 				}
 			}
@@ -541,109 +503,11 @@ func preprocessMultimodalMessages(ctx context.Context, messages []*schema.Messag
 	return nil
 }
 
-// getMimeType 根据文件扩展名获取MIME类型
-func getMimeType(ext string) string {
-	mimeTypes := map[string]string{
-		// 图片格式
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".png":  "image/png",
-		".gif":  "image/gif",
-		".bmp":  "image/bmp",
-		".webp": "image/webp",
-		".svg":  "image/svg+xml",
-		".ico":  "image/x-icon",
-		".tiff": "image/tiff",
-
-		// 音频格式
-		".mp3":  "audio/mpeg",
-		".wav":  "audio/wav",
-		".flac": "audio/flac",
-		".aac":  "audio/aac",
-		".ogg":  "audio/ogg",
-		".m4a":  "audio/mp4",
-		".wma":  "audio/x-ms-wma",
-
-		// 视频格式
-		".mp4":  "video/mp4",
-		".avi":  "video/x-msvideo",
-		".mkv":  "video/x-matroska",
-		".mov":  "video/quicktime",
-		".wmv":  "video/x-ms-wmv",
-		".flv":  "video/x-flv",
-		".webm": "video/webm",
-		".m4v":  "video/mp4",
-		".mpeg": "video/mpeg",
-		".mpg":  "video/mpeg",
-	}
-
-	if mime, ok := mimeTypes[ext]; ok {
-		return mime
-	}
-	return "application/octet-stream"
-}
-
 // GenerateWithTools 使用指定模型进行工具调用（支持 Function Calling）
 func (x *Chat) GenerateWithTools(ctx context.Context, modelID string, messages []*schema.Message, tools []*schema.ToolInfo) (*schema.Message, error) {
-	// 获取模型配置
-	mc := coreModel.Registry.Get(modelID)
-	if mc == nil {
-		return nil, fmt.Errorf("model not found: %s", modelID)
-	}
-
-	// 根据模型类型选择格式适配器
-	var msgFormatter formatter.MessageFormatter
-	if IsQwenModel(mc.Name) {
-		msgFormatter = formatter.NewQwenFormatter()
-	} else {
-		msgFormatter = formatter.NewOpenAIFormatter()
-	}
-
-	// 创建模型服务
-	modelService := coreModel.NewModelService(mc.APIKey, mc.BaseURL, msgFormatter)
-
-	// 解析推理参数
-	params := parseModelParams(mc.Extra)
-
-	// 转换 schema.ToolInfo 到 openai.Tool
-	var openaiTools []interface{}
-	if len(tools) > 0 {
-		for _, tool := range tools {
-			// 将ParamsOneOf转换为OpenAPIV3格式
-			var params interface{}
-			if tool.ParamsOneOf != nil {
-				openAPIV3Schema, err := tool.ParamsOneOf.ToOpenAPIV3()
-				if err != nil {
-					g.Log().Warningf(ctx, "Failed to convert tool params to OpenAPIV3: %v", err)
-					continue
-				}
-				params = openAPIV3Schema
-			}
-
-			openaiTools = append(openaiTools, map[string]interface{}{
-				"type": "function",
-				"function": map[string]interface{}{
-					"name":        tool.Name,
-					"description": tool.Desc,
-					"parameters":  params,
-				},
-			})
-		}
-	}
-
-	// 构建请求参数
-	chatParams := coreModel.ChatCompletionParams{
-		ModelName:           mc.Name,
-		Messages:            messages,
-		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
-		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
-		TopP:                getFloat32OrDefault(params.TopP, 0.9),
-		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
-		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
-		N:                   getIntOrDefault(params.N, 1),
-		Stop:                params.Stop,
-		ToolChoice:          "auto", // 让模型自动决定是否调用工具
-		ResponseFormat:      params.ResponseFormat,
+	modelService, chatParams, err := toolCallParams(ctx, modelID, messages, tools)
+	if err != nil {
+		return nil, err
 	}
 
 	// 记录开始时间
@@ -687,36 +551,126 @@ func (x *Chat) GenerateWithTools(ctx context.Context, modelID string, messages [
 	// 添加指标信息到返回的消息中（通过扩展字段）
 	result.Extra = map[string]any{
 		"latency_ms":  latencyMs,
-		"tokens_used": resp.Usage.TotalTokens,
+		"tokens_used": usageOrEstimate(resp.Usage.TotalTokens, chatParams, result.Content+toolCallText(result.ToolCalls)),
 	}
 
 	return result, nil
 }
 
+// toolCallParams 构建工具调用请求，流式和非流式工具调用共用
+func toolCallParams(ctx context.Context, modelID string, messages []*schema.Message, tools []*schema.ToolInfo) (*coreModel.ModelService, coreModel.ChatCompletionParams, error) {
+	// 获取模型配置
+	mc := coreModel.Registry.Get(modelID)
+	if mc == nil {
+		return nil, coreModel.ChatCompletionParams{}, fmt.Errorf("model not found: %s", modelID)
+	}
+
+	// 根据模型提供商和名称选择格式适配器
+	msgFormatter := coreModel.FormatterFor(mc)
+
+	// 创建模型服务
+	modelService := coreModel.NewModelServiceFor(mc, msgFormatter)
+
+	// 解析推理参数
+	params := parseModelParams(mc.Extra)
+
+	// 转换 schema.ToolInfo 到 openai.Tool
+	var openaiTools []openai.Tool
+	for _, tool := range tools {
+		// 将ParamsOneOf转换为OpenAPIV3格式
+		var toolParams any
+		if tool.ParamsOneOf != nil {
+			openAPIV3Schema, err := tool.ParamsOneOf.ToOpenAPIV3()
+			if err != nil {
+				g.Log().Warningf(ctx, "Failed to convert tool params to OpenAPIV3: %v", err)
+				continue
+			}
+			toolParams = openAPIV3Schema
+		}
+
+		openaiTools = append(openaiTools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Desc,
+				Parameters:  toolParams,
+			},
+		})
+	}
+
+	// 构建请求参数
+	chatParams := coreModel.ChatCompletionParams{
+		ModelName:           mc.Name,
+		Messages:            messages,
+		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
+		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
+		TopP:                getFloat32OrDefault(params.TopP, 0.9),
+		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
+		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
+		N:                   getIntOrDefault(params.N, 1),
+		Stop:                stopSequences(ctx, params.Stop),
+		Tools:               openaiTools,
+		ResponseFormat:      params.ResponseFormat,
+	}
+	if len(openaiTools) > 0 {
+		chatParams.ToolChoice = "auto" // 让模型自动决定是否调用工具
+	}
+	return modelService, chatParams, nil
+}
+
+// tagExperiments 在助手消息元数据中记录本轮对话所属的实验分组
+func tagExperiments(ctx context.Context, msg *history.MessageWithMetrics) {
+	assignments := experiment.FromContext(ctx)
+	if len(assignments) == 0 {
+		return
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]interface{}{}
+	}
+	msg.Metadata[experiment.MetadataKey] = assignments
+}
+
+// tagRetrievalTrace 在助手消息元数据中记录本轮检索返回的分片，用于导出嵌入模型微调数据
+func tagRetrievalTrace(msg *history.MessageWithMetrics, question string, docs []*schema.Document) {
+	trace := analytics.NewRetrievalTrace(question, docs)
+	if trace == nil {
+		return
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]interface{}{}
+	}
+	msg.Metadata[analytics.RetrievalMetadataKey] = trace
+}
+
 // SaveMessageWithMetadata 保存带元数据的消息
-func (x *Chat) SaveMessageWithMetadata(message *schema.Message, convID string, metadata map[string]interface{}) error {
-	return x.eh.SaveMessageWithMetadata(message, convID, metadata)
+func (x *Chat) SaveMessageWithMetadata(ctx context.Context, message *schema.Message, convID string, metadata map[string]interface{}) error {
+	return x.eh.SaveMessageWithMetadata(ctx, message, convID, metadata)
 }
 
 // SaveStreamingMessageWithMetadata 保存流式传输的完整消息和元数据
-func (x *Chat) SaveStreamingMessageWithMetadata(convID string, content string, metadata map[string]interface{}) error {
+func (x *Chat) SaveStreamingMessageWithMetadata(ctx context.Context, convID string, content string, metadata map[string]interface{}) error {
 	message := &schema.Message{
 		Role:    schema.Assistant,
 		Content: content,
 	}
-	return x.eh.SaveMessageWithMetadata(message, convID, metadata)
+	return x.eh.SaveMessageWithMetadata(ctx, message, convID, metadata)
 }
 
-// formatDocumentsForChat 格式化文档为聊天上下文
-func formatDocumentsForChat(docs []*schema.Document) string {
+// formatDocumentsForChat 格式化文档为聊天上下文，chat.references.format 为 json 时提供结构化参考资料
+func formatDocumentsForChat(ctx context.Context, docs []*schema.Document) string {
 	if len(docs) == 0 {
 		return ""
 	}
+	if ReferenceFormat(ctx) == ReferenceFormatJSON {
+		return formatReferencesJSON(docs) + formatPreservationHint(docs)
+	}
 
 	var builder strings.Builder
 	builder.WriteString("参考资料:\n")
 	for i, doc := range docs {
 		builder.WriteString(fmt.Sprintf("[%d] %s\n", i+1, doc.Content))
 	}
+	builder.WriteString(formatPreservationHint(docs))
 	return builder.String()
 }
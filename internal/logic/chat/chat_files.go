@@ -4,21 +4,28 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/common"
-	"github.com/Malowking/kbgo/core/formatter"
 	"github.com/Malowking/kbgo/core/indexer"
+	"github.com/Malowking/kbgo/core/media"
 	coreModel "github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/internal/dao"
 	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/logic/convmeta"
+	"github.com/Malowking/kbgo/internal/logic/experiment"
+	"github.com/Malowking/kbgo/internal/logic/outline"
+	"github.com/Malowking/kbgo/internal/logic/quota"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -26,44 +33,39 @@ import (
 )
 
 // GetAnswerWithParsedFiles 使用已解析的文件内容进行多模态对话
-func (x *Chat) GetAnswerWithParsedFiles(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, multimodalFiles []*common.MultimodalFile, fileContent string, fileImages []string, jsonFormat bool) (answer string, err error) {
+func (x *Chat) GetAnswerWithParsedFiles(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, multimodalFiles []*common.MultimodalFile, fileContent string, fileImages []string, jsonFormat bool, style *ResponseStyle) (answer string, reasoning string, confidence *v1.AnswerConfidence, err error) {
 	// 获取模型配置
 	mc := coreModel.Registry.Get(modelID)
 	if mc == nil {
-		return "", fmt.Errorf("model not found: %s", modelID)
+		return "", "", nil, fmt.Errorf("model not found: %s", modelID)
 	}
 
-	// 根据模型类型选择格式适配器
-	var msgFormatter formatter.MessageFormatter
-	if IsQwenModel(mc.Name) {
-		msgFormatter = formatter.NewQwenFormatter()
-	} else {
-		msgFormatter = formatter.NewOpenAIFormatter()
-	}
+	// 根据模型提供商和名称选择格式适配器
+	msgFormatter := coreModel.FormatterFor(mc)
 
 	// 创建模型服务
-	modelService := coreModel.NewModelService(mc.APIKey, mc.BaseURL, msgFormatter)
+	modelService := coreModel.NewModelServiceFor(mc, msgFormatter)
 
 	// 获取聊天历史
-	chatHistory, err := x.eh.GetHistory(convID, 100)
+	chatHistory, err := x.eh.GetHistoryForModel(convID, 100, historyPartTypes(mc)...)
 	if err != nil {
-		return "", err
+		return "", "", nil, err
 	}
 
 	// 构建多模态消息（只包含用户问题和多模态文件）
-	userMessage, err := buildMultimodalMessageWithImages(ctx, question, multimodalFiles, fileImages, mc.Type)
+	userMessage, err := buildMultimodalMessageWithImages(ctx, question, multimodalFiles, fileImages, mc)
 	if err != nil {
-		return "", fmt.Errorf("构建多模态消息失败: %w", err)
+		return "", "", nil, fmt.Errorf("构建多模态消息失败: %w", err)
 	}
 
 	// 保存用户消息
-	err = x.eh.SaveMessage(userMessage, convID)
+	err = x.eh.SaveMessage(ctx, userMessage, convID)
 	if err != nil {
-		return "", err
+		return "", "", nil, err
 	}
 
 	// 构建system提示词
-	systemPrompt := buildSystemPrompt(mc.Type, docs, fileContent, fileImages)
+	systemPrompt := buildSystemPrompt(ctx, mc.Type, docs, fileContent, fileImages) + style.PromptConstraints()
 
 	// 构建消息列表
 	messages := []*schema.Message{
@@ -91,29 +93,37 @@ func (x *Chat) GetAnswerWithParsedFiles(ctx context.Context, modelID string, con
 		Messages:            messages,
 		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
 		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
 		TopP:                getFloat32OrDefault(params.TopP, 0.9),
 		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
 		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
 		N:                   getIntOrDefault(params.N, 1),
+		Stop:                stopSequences(ctx, params.Stop),
 		Tools:               params.Tools,
 		ToolChoice:          params.ToolChoice,
 		ResponseFormat:      params.ResponseFormat,
+		LogProbs:            ConfidenceLogProbsEnabled(ctx),
 	}
 
 	// 记录开始时间
 	start := time.Now()
 
 	// 调用模型服务
-	resp, err := modelService.ChatCompletion(ctx, chatParams)
+	resp, err := completeWithContinuation(ctx, modelService, chatParams)
 	if err != nil {
-		return "", fmt.Errorf("API调用失败: %w", err)
+		return "", "", nil, fmt.Errorf("API调用失败: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("received empty choices from API")
+		return "", "", nil, fmt.Errorf("received empty choices from API")
 	}
 
-	answerContent := resp.Choices[0].Message.Content
+	answerContent := style.Apply(ctx, resp.Choices[0].Message.Content)
+	reasoning = visibleReasoning(ctx, reasoningPolicy(ctx, params), resp.Choices[0].Message.ReasoningContent)
+
+	// 计算回答置信度，低于升级阈值时通知升级 webhook
+	confidence = ScoreAnswer(ctx, docs, answerContent, resp.Choices[0].LogProbs)
+	NotifyEscalation(ctx, convID, question, answerContent, confidence)
 
 	// 计算延迟
 	latencyMs := time.Since(start).Milliseconds()
@@ -127,17 +137,25 @@ func (x *Chat) GetAnswerWithParsedFiles(ctx context.Context, modelID string, con
 	// 创建带指标的消息
 	msgWithMetrics := &history.MessageWithMetrics{
 		Message:    assistantMsg,
+		ModelID:    servedModelID(ctx, modelID),
 		LatencyMs:  int(latencyMs),
-		TokensUsed: resp.Usage.TotalTokens,
+		TokensUsed: usageOrEstimate(resp.Usage.TotalTokens, chatParams, answerContent),
+	}
+	if confidence != nil {
+		msgWithMetrics.Metadata = map[string]interface{}{ConfidenceMetadataKey: confidence}
 	}
 
-	err = x.eh.SaveMessageWithMetrics(msgWithMetrics, convID)
+	tagExperiments(ctx, msgWithMetrics)
+	quota.RecordTokens(ctx, msgWithMetrics.TokensUsed)
+	tagRetrievalTrace(msgWithMetrics, question, docs)
+	tagReasoning(msgWithMetrics, reasoning)
+	err = x.eh.SaveMessageWithMetrics(ctx, msgWithMetrics, convID)
 	if err != nil {
 		g.Log().Error(ctx, "save assistant message err: %v", err)
 		return
 	}
 
-	return answerContent, nil
+	return answerContent, reasoning, confidence, nil
 }
 
 // GetAnswerWithFiles 统一的多模态对话处理（使用新架构）
@@ -148,19 +166,14 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 		return "", fmt.Errorf("model not found: %s", modelID)
 	}
 
-	// 根据模型类型选择格式适配器
-	var msgFormatter formatter.MessageFormatter
-	if IsQwenModel(mc.Name) {
-		msgFormatter = formatter.NewQwenFormatter()
-	} else {
-		msgFormatter = formatter.NewOpenAIFormatter()
-	}
+	// 根据模型提供商和名称选择格式适配器
+	msgFormatter := coreModel.FormatterFor(mc)
 
 	// 创建模型服务
-	modelService := coreModel.NewModelService(mc.APIKey, mc.BaseURL, msgFormatter)
+	modelService := coreModel.NewModelServiceFor(mc, msgFormatter)
 
 	// 获取聊天历史
-	chatHistory, err := x.eh.GetHistory(convID, 100)
+	chatHistory, err := x.eh.GetHistoryForModel(convID, 100, historyPartTypes(mc)...)
 	if err != nil {
 		return "", err
 	}
@@ -179,7 +192,10 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 
 	// 如果本次有新的文档文件上传，解析它们
 	if len(documentFiles) > 0 {
-		fileContent, fileImages, err = parseDocumentFiles(ctx, documentFiles)
+		fileContent, fileImages, err = parseStage(ctx, documentFiles)
+		if ctx.Err() != nil {
+			return "", err
+		}
 		if err != nil {
 			g.Log().Warningf(ctx, "Failed to parse document files: %v", err)
 			fileContent = ""
@@ -200,19 +216,19 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 	}
 
 	// 构建多模态消息（只包含用户问题和多模态文件）
-	userMessage, err := buildMultimodalMessageWithImages(ctx, question, multimodalFiles, fileImages, mc.Type)
+	userMessage, err := buildMultimodalMessageWithImages(ctx, question, multimodalFiles, fileImages, mc)
 	if err != nil {
 		return "", fmt.Errorf("构建多模态消息失败: %w", err)
 	}
 
 	// 保存用户消息
-	err = x.eh.SaveMessage(userMessage, convID)
+	err = x.eh.SaveMessage(ctx, userMessage, convID)
 	if err != nil {
 		return "", err
 	}
 
 	// 构建system提示词
-	systemPrompt := buildSystemPrompt(mc.Type, docs, fileContent, fileImages)
+	systemPrompt := buildSystemPrompt(ctx, mc.Type, docs, fileContent, fileImages)
 
 	// 构建消息列表
 	messages := []*schema.Message{
@@ -233,10 +249,12 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 		Messages:            messages,
 		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
 		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
 		TopP:                getFloat32OrDefault(params.TopP, 0.9),
 		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
 		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
 		N:                   getIntOrDefault(params.N, 1),
+		Stop:                stopSequences(ctx, params.Stop),
 		Tools:               params.Tools,
 		ToolChoice:          params.ToolChoice,
 		ResponseFormat:      params.ResponseFormat,
@@ -246,7 +264,7 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 	start := time.Now()
 
 	// 调用模型服务
-	resp, err := modelService.ChatCompletion(ctx, chatParams)
+	resp, err := completeWithContinuation(ctx, modelService, chatParams)
 	if err != nil {
 		return "", fmt.Errorf("API调用失败: %w", err)
 	}
@@ -256,6 +274,7 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 	}
 
 	answerContent := resp.Choices[0].Message.Content
+	reasoning := visibleReasoning(ctx, reasoningPolicy(ctx, params), resp.Choices[0].Message.ReasoningContent)
 
 	// 计算延迟
 	latencyMs := time.Since(start).Milliseconds()
@@ -269,11 +288,16 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 	// 创建带指标的消息
 	msgWithMetrics := &history.MessageWithMetrics{
 		Message:    assistantMsg,
+		ModelID:    servedModelID(ctx, modelID),
 		LatencyMs:  int(latencyMs),
-		TokensUsed: resp.Usage.TotalTokens,
+		TokensUsed: usageOrEstimate(resp.Usage.TotalTokens, chatParams, answerContent),
 	}
 
-	err = x.eh.SaveMessageWithMetrics(msgWithMetrics, convID)
+	tagExperiments(ctx, msgWithMetrics)
+	quota.RecordTokens(ctx, msgWithMetrics.TokensUsed)
+	tagRetrievalTrace(msgWithMetrics, question, docs)
+	tagReasoning(msgWithMetrics, reasoning)
+	err = x.eh.SaveMessageWithMetrics(ctx, msgWithMetrics, convID)
 	if err != nil {
 		g.Log().Error(ctx, "save assistant message err: %v", err)
 		return
@@ -283,26 +307,21 @@ func (x *Chat) GetAnswerWithFiles(ctx context.Context, modelID string, convID st
 }
 
 // GetAnswerStreamWithFiles 统一的多模态流式对话处理
-func (x *Chat) GetAnswerStreamWithFiles(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, files []*common.MultimodalFile, jsonFormat bool) (answer *schema.StreamReader[*schema.Message], err error) {
+func (x *Chat) GetAnswerStreamWithFiles(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, files []*common.MultimodalFile, jsonFormat bool, style *ResponseStyle) (answer *schema.StreamReader[*schema.Message], err error) {
 	// 获取模型配置
 	mc := coreModel.Registry.Get(modelID)
 	if mc == nil {
 		return nil, fmt.Errorf("model not found: %s", modelID)
 	}
 
-	// 根据模型类型选择格式适配器
-	var msgFormatter formatter.MessageFormatter
-	if IsQwenModel(mc.Name) {
-		msgFormatter = formatter.NewQwenFormatter()
-	} else {
-		msgFormatter = formatter.NewOpenAIFormatter()
-	}
+	// 根据模型提供商和名称选择格式适配器
+	msgFormatter := coreModel.FormatterFor(mc)
 
 	// 创建模型服务
-	modelService := coreModel.NewModelService(mc.APIKey, mc.BaseURL, msgFormatter)
+	modelService := coreModel.NewModelServiceFor(mc, msgFormatter)
 
 	// 获取聊天历史
-	chatHistory, err := x.eh.GetHistory(convID, 100)
+	chatHistory, err := x.eh.GetHistoryForModel(convID, 100, historyPartTypes(mc)...)
 	if err != nil {
 		return nil, err
 	}
@@ -321,7 +340,10 @@ func (x *Chat) GetAnswerStreamWithFiles(ctx context.Context, modelID string, con
 
 	// 如果本次有新的文档文件上传，解析它们
 	if len(documentFiles) > 0 {
-		fileContent, fileImages, err = parseDocumentFiles(ctx, documentFiles)
+		fileContent, fileImages, err = parseStage(ctx, documentFiles)
+		if ctx.Err() != nil {
+			return nil, err
+		}
 		if err != nil {
 			g.Log().Warningf(ctx, "Failed to parse document files: %v", err)
 			fileContent = ""
@@ -342,19 +364,19 @@ func (x *Chat) GetAnswerStreamWithFiles(ctx context.Context, modelID string, con
 	}
 
 	// 构建多模态消息（只包含用户问题和多模态文件）
-	userMessage, err := buildMultimodalMessageWithImages(ctx, question, multimodalFiles, fileImages, mc.Type)
+	userMessage, err := buildMultimodalMessageWithImages(ctx, question, multimodalFiles, fileImages, mc)
 	if err != nil {
 		return nil, fmt.Errorf("构建多模态消息失败: %w", err)
 	}
 
 	// 保存用户消息
-	err = x.eh.SaveMessage(userMessage, convID)
+	err = x.eh.SaveMessage(ctx, userMessage, convID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 构建system提示词
-	systemPrompt := buildSystemPrompt(mc.Type, docs, fileContent, fileImages)
+	systemPrompt := buildSystemPrompt(ctx, mc.Type, docs, fileContent, fileImages) + style.PromptConstraints()
 
 	// 构建消息列表
 	messages := []*schema.Message{
@@ -382,10 +404,12 @@ func (x *Chat) GetAnswerStreamWithFiles(ctx context.Context, modelID string, con
 		Messages:            messages,
 		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
 		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
 		TopP:                getFloat32OrDefault(params.TopP, 0.9),
 		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
 		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
 		N:                   getIntOrDefault(params.N, 1),
+		Stop:                stopSequences(ctx, params.Stop),
 		Tools:               params.Tools,
 		ToolChoice:          params.ToolChoice,
 		ResponseFormat:      params.ResponseFormat,
@@ -406,71 +430,61 @@ func (x *Chat) GetAnswerStreamWithFiles(ctx context.Context, modelID string, con
 	// 启动goroutine处理流式响应
 	go func() {
 		defer streamWriter.Close()
-		defer stream.Close()
-
-		var fullContent strings.Builder
-		var tokenCount int
-
-		for {
-			response, err := stream.Recv()
-			if errors.Is(err, io.EOF) {
-				// 流结束，保存完整消息
-				assistantMsg := &schema.Message{
-					Role:    schema.Assistant,
-					Content: fullContent.String(),
-				}
 
-				// 计算延迟
-				latencyMs := time.Since(start).Milliseconds()
+		// 转发流式输出，输出因长度限制被截断时自动续写
+		reasoning := newReasoningRelay(ctx, reasoningPolicy(ctx, params), streamWriter)
+		result, ok := relayStream(ctx, modelService, chatParams, stream, streamWriter, reasoning)
+		if !ok {
+			return
+		}
+		content := result.Answer
 
-				// 创建带指标的消息
-				msgWithMetrics := &history.MessageWithMetrics{
-					Message:    assistantMsg,
-					LatencyMs:  int(latencyMs),
-					TokensUsed: tokenCount,
-				}
+		// 流结束后的校验、统计和保存使用与请求分离的上下文，客户端断开连接时也能完成，并保留 trace 等请求信息
+		ctx := common.DetachContext(ctx)
 
-				// 异步保存消息
-				saveErr := x.eh.SaveMessageWithMetrics(msgWithMetrics, convID)
-				if saveErr != nil {
-					g.Log().Errorf(ctx, "save assistant message err: %v", saveErr)
-				}
+		// 流式输出无法改写已发送内容，只做约束校验
+		for _, violation := range style.Validate(content) {
+			g.Log().Warningf(ctx, "Response style violation: %s", violation)
+		}
 
-				return
-			}
+		// 流结束，保存完整消息
+		assistantMsg := &schema.Message{
+			Role:    schema.Assistant,
+			Content: content,
+		}
 
-			if err != nil {
-				g.Log().Errorf(ctx, "stream receive error: %v", err)
-				streamWriter.Send(&schema.Message{
-					Role:    schema.Assistant,
-					Content: "",
-				}, err)
-				return
-			}
+		// 计算延迟
+		latencyMs := time.Since(start).Milliseconds()
 
-			// 处理流式响应
-			if len(response.Choices) > 0 {
-				delta := response.Choices[0].Delta.Content
-				if delta != "" {
-					fullContent.WriteString(delta)
+		// 创建带指标的消息
+		msgWithMetrics := &history.MessageWithMetrics{
+			Message:    assistantMsg,
+			ModelID:    servedModelID(ctx, modelID),
+			LatencyMs:  int(latencyMs),
+			TokensUsed: result.Tokens,
+			Metadata:   map[string]interface{}{},
+		}
+		if result.Truncated != "" {
+			msgWithMetrics.Metadata["truncated"] = result.Truncated
+		}
 
-					// 创建增量消息并发送到流
-					chunk := &schema.Message{
-						Role:    schema.Assistant,
-						Content: delta,
-					}
-					closed := streamWriter.Send(chunk, nil)
-					if closed {
-						g.Log().Warningf(ctx, "stream writer closed unexpectedly")
-						return
-					}
-				}
+		// 流式回答没有 logprobs，置信度只基于检索得分和一致性
+		confidence := ScoreAnswer(ctx, docs, assistantMsg.Content, nil)
+		NotifyEscalation(ctx, convID, question, assistantMsg.Content, confidence)
+		if confidence != nil {
+			msgWithMetrics.Metadata[ConfidenceMetadataKey] = confidence
+		}
 
-				// 累计token数量（如果有usage信息）
-				if response.Usage != nil {
-					tokenCount = response.Usage.TotalTokens
-				}
-			}
+		// 异步保存消息
+		tagExperiments(ctx, msgWithMetrics)
+		msgWithMetrics.Metadata = agentrun.Tag(ctx, msgWithMetrics.Metadata)
+		msgWithMetrics.Metadata = tagModelFallback(ctx, msgWithMetrics.Metadata)
+		quota.RecordTokens(ctx, msgWithMetrics.TokensUsed)
+		tagRetrievalTrace(msgWithMetrics, question, docs)
+		tagReasoning(msgWithMetrics, reasoning.Visible())
+		saveErr := x.eh.SaveMessageWithMetrics(ctx, msgWithMetrics, convID)
+		if saveErr != nil {
+			g.Log().Errorf(ctx, "save assistant message err: %v", saveErr)
 		}
 	}()
 
@@ -491,13 +505,16 @@ func formatDocumentsForQwen(docs []*schema.Document) string {
 	return builder.String()
 }
 
-// IsQwenModel 判断是否为Qwen模型
-func IsQwenModel(modelName string) bool {
-	return strings.HasPrefix(strings.ToLower(modelName), "qwen")
+// historyPartTypes 模型可以处理的历史消息内容块类型（格式适配器只转换文本和图片，多模态模型才保留图片）
+func historyPartTypes(mc *coreModel.ModelConfig) []schema.ChatMessagePartType {
+	if mc.Type == coreModel.ModelTypeMultimodal {
+		return []schema.ChatMessagePartType{schema.ChatMessagePartTypeImageURL}
+	}
+	return nil
 }
 
 // buildMultimodalMessageWithImages 构建多模态消息，支持从历史对话中提取文档图片
-func buildMultimodalMessageWithImages(ctx context.Context, text string, files []*common.MultimodalFile, fileImages []string, modelType coreModel.ModelType) (*schema.Message, error) {
+func buildMultimodalMessageWithImages(ctx context.Context, text string, files []*common.MultimodalFile, fileImages []string, mc *coreModel.ModelConfig) (*schema.Message, error) {
 	var userInputParts []schema.MessageInputPart
 
 	// 添加文本部分
@@ -510,7 +527,12 @@ func buildMultimodalMessageWithImages(ctx context.Context, text string, files []
 
 	// 添加用户上传的多模态文件部分
 	for _, file := range files {
-		part, err := buildFilePart(file)
+		// 视频转换为关键帧和语音转写
+		if file.FileType == common.FileTypeVideo {
+			userInputParts = append(userInputParts, buildVideoParts(ctx, file, mc)...)
+			continue
+		}
+		part, err := buildFilePart(ctx, file)
 		if err != nil {
 			g.Log().Errorf(ctx, "Failed to build file part for %s: %v", file.FileName, err)
 			continue
@@ -519,7 +541,7 @@ func buildMultimodalMessageWithImages(ctx context.Context, text string, files []
 	}
 
 	// 如果是多模态模型且有文档图片，读取并添加图片
-	if modelType == coreModel.ModelTypeMultimodal && len(fileImages) > 0 {
+	if mc.Type == coreModel.ModelTypeMultimodal && len(fileImages) > 0 {
 		for _, imgURL := range fileImages {
 			base64Data, mimeType, err := downloadImageFromURL(ctx, imgURL)
 			if err != nil {
@@ -556,19 +578,15 @@ func buildMultimodalMessageWithImages(ctx context.Context, text string, files []
 }
 
 // buildFilePart 构建文件部分
-func buildFilePart(file *common.MultimodalFile) (schema.MessageInputPart, error) {
+func buildFilePart(ctx context.Context, file *common.MultimodalFile) (schema.MessageInputPart, error) {
 	switch file.FileType {
 	case common.FileTypeImage:
-		// 读取图片文件
-		data, err := os.ReadFile(file.FilePath)
+		// 读取图片文件，按内容识别类型，HEIC/AVIF 转换为 JPEG，超大图片等比缩小
+		data, mimeType, err := media.LoadImage(ctx, file.FilePath)
 		if err != nil {
-			return schema.MessageInputPart{}, fmt.Errorf("failed to read image file: %w", err)
+			return schema.MessageInputPart{}, err
 		}
 
-		// 获取MIME类型
-		ext := filepath.Ext(file.FileName)
-		mimeType := getMimeTypeForFile(ext)
-
 		// 编码为base64
 		base64Data := base64.StdEncoding.EncodeToString(data)
 
@@ -592,23 +610,6 @@ func buildFilePart(file *common.MultimodalFile) (schema.MessageInputPart, error)
 	}
 }
 
-// getMimeTypeForFile 获取MIME类型
-func getMimeTypeForFile(ext string) string {
-	mimeTypes := map[string]string{
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".png":  "image/png",
-		".gif":  "image/gif",
-		".bmp":  "image/bmp",
-		".webp": "image/webp",
-	}
-
-	if mime, ok := mimeTypes[ext]; ok {
-		return mime
-	}
-	return "image/jpeg"
-}
-
 // 辅助函数
 func getFloat32OrDefault(val *float32, defaultVal float32) float32 {
 	if val != nil {
@@ -638,9 +639,9 @@ func separateFilesByType(files []*common.MultimodalFile) (multimodalFiles []*com
 	return
 }
 
-// ParseDocumentFiles 解析文档文件，调用Python服务获取全文和图片（公开函数）
+// ParseDocumentFiles 解析文档文件，调用Python服务获取全文和图片（公开函数），解析阶段使用 fileParse.chatTimeout 超时
 func ParseDocumentFiles(ctx context.Context, files []*common.MultimodalFile) (string, []string, error) {
-	return parseDocumentFiles(ctx, files)
+	return parseStage(ctx, files)
 }
 
 // parseDocumentFiles 解析文档文件，调用Python服务获取全文和图片（内部函数）
@@ -653,7 +654,7 @@ func parseDocumentFiles(ctx context.Context, files []*common.MultimodalFile) (st
 	var allImages []string
 
 	// 创建文件解析加载器，chunk_size=-1表示不切分，imageURLFormat=false表示返回相对路径
-	loader, err := indexer.NewFileParseLoaderForChat(ctx, -1, 0, "")
+	loader, err := indexer.NewDocumentParserForChat(ctx, -1, 0, "")
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to create file parse loader: %w", err)
 	}
@@ -665,15 +666,31 @@ func parseDocumentFiles(ctx context.Context, files []*common.MultimodalFile) (st
 		projectRoot = ""
 	}
 
-	for _, file := range files {
+	for i, file := range files {
+		// 超时或取消时停止解析剩余文件，返回已解析的内容
+		if ctx.Err() != nil {
+			g.Log().Warningf(ctx, "Document parsing stopped before %s: %v", file.FileName, ctx.Err())
+			break
+		}
 		g.Log().Infof(ctx, "Parsing document file: %s (type: %s)", file.FileName, file.FileType)
+		progress := ParseProgress{FileName: file.FileName, FileIndex: i + 1, FileCount: len(files)}
+		reportParseProgress(ctx, progress.withStage(ParseStageStart))
 
 		// 调用Python服务解析文件，chunk_size=-1表示不切分，imageURLFormat=false返回相对路径
-		docs, err := loader.Load(ctx, file.FilePath)
+		fileCtx := indexer.WithPageProgress(ctx, func(done, total int) {
+			page := progress.withStage(ParseStagePage)
+			page.Page, page.Pages = done, total
+			reportParseProgress(ctx, page)
+		})
+		docs, err := loader.Load(fileCtx, file.FilePath)
 		if err != nil {
 			g.Log().Errorf(ctx, "Failed to parse file %s: %v", file.FileName, err)
+			failed := progress.withStage(ParseStageFailed)
+			failed.Message = err.Error()
+			reportParseProgress(ctx, failed)
 			continue
 		}
+		reportParseProgress(ctx, progress.withStage(ParseStageDone))
 
 		// 提取文本内容
 		for _, doc := range docs {
@@ -721,18 +738,17 @@ func parseDocumentFiles(ctx context.Context, files []*common.MultimodalFile) (st
 }
 
 // buildSystemPrompt 根据模型类型构建system提示词
-func buildSystemPrompt(modelType coreModel.ModelType, docs []*schema.Document, fileContent string, imageURLs []string) string {
+func buildSystemPrompt(ctx context.Context, modelType coreModel.ModelType, docs []*schema.Document, fileContent string, imageURLs []string) string {
 	var builder strings.Builder
 
-	// 基础提示词
-	builder.WriteString("你是一个专业的AI助手，能够根据提供的参考信息准确回答用户问题。\n")
+	// 基础提示词（实验分组可替换）
+	builder.WriteString(experiment.SystemPrompt(ctx, "你是一个专业的AI助手，能够根据提供的参考信息准确回答用户问题。"))
+	builder.WriteString("\n")
 
 	// 如果有检索到的文档
 	if len(docs) > 0 {
-		builder.WriteString("\n参考资料:\n")
-		for i, doc := range docs {
-			builder.WriteString(fmt.Sprintf("[%d] %s\n", i+1, doc.Content))
-		}
+		builder.WriteString("\n")
+		builder.WriteString(formatDocumentsForChat(ctx, docs))
 	}
 
 	// 如果有文件内容，移除其中的图片占位符（因为图片已通过user消息传入）
@@ -779,13 +795,13 @@ func getConversationDocumentInfo(ctx context.Context, eh *history.Manager, convI
 		return "", nil, nil
 	}
 
-	var metadata map[string]interface{}
-	err = json.Unmarshal(conv.Metadata, &metadata)
+	// 读取元数据并还原转存的大字段（如文档全文）
+	metadata, err := convmeta.Load(ctx, conv)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to unmarshal conversation metadata: %w", err)
+		return "", nil, err
 	}
 
-	fileContent, _ := metadata["file_content"].(string)
+	fileContent, _ := metadata[outline.FileContentMetadataKey].(string)
 
 	var fileImages []string
 	if imgs, ok := metadata["file_images"].([]interface{}); ok {
@@ -812,7 +828,7 @@ func saveConversationDocumentInfo(ctx context.Context, eh *history.Manager, conv
 		return nil
 	}
 
-	// 解析现有metadata
+	// 解析现有metadata，已转存的字段保留引用
 	var metadata map[string]interface{}
 	if len(conv.Metadata) > 0 {
 		err = json.Unmarshal(conv.Metadata, &metadata)
@@ -829,17 +845,12 @@ func saveConversationDocumentInfo(ctx context.Context, eh *history.Manager, conv
 		docPaths[i] = file.FilePath
 	}
 	metadata["document_files"] = docPaths
-	metadata["file_content"] = fileContent
+	metadata[outline.FileContentMetadataKey] = fileContent
+	metadata[outline.FileTocMetadataKey] = outline.Build(fileContent)
 	metadata["file_images"] = fileImages
 
-	// 序列化metadata
-	metadataJSON, err := json.Marshal(metadata)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	// 更新conversation
-	return updateConversationMetadata(convID, metadataJSON)
+	// 更新conversation，文档全文等过大的字段压缩后转存
+	return convmeta.Save(ctx, convID, metadata)
 }
 
 // getConversation 获取会话信息
@@ -847,31 +858,19 @@ func getConversation(convID string) (*gormModel.Conversation, error) {
 	return dao.Conversation.GetByConvID(nil, convID)
 }
 
-// updateConversationMetadata 更新会话metadata
-func updateConversationMetadata(convID string, metadata gormModel.JSON) error {
-	return dao.Conversation.UpdateMetadata(nil, convID, metadata)
-}
-
 // downloadImageFromURL 从URL或本地路径读取图片并返回base64编码的数据
 func downloadImageFromURL(ctx context.Context, imageURL string) (string, string, error) {
 	// 判断是否为本地文件路径（绝对路径）
 	if filepath.IsAbs(imageURL) {
 		// 读取本地文件
 		g.Log().Infof(ctx, "Reading local image file: %s", imageURL)
-		data, err := os.ReadFile(imageURL)
+		data, mimeType, err := media.LoadImage(ctx, imageURL)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to read local image file: %w", err)
 		}
 
-		// 编码为base64
-		base64Data := base64.StdEncoding.EncodeToString(data)
-
-		// 从文件路径获取扩展名并确定MIME类型
-		ext := filepath.Ext(imageURL)
-		mimeType := getMimeTypeForFile(ext)
-
 		g.Log().Infof(ctx, "Successfully read local image: %s, size: %d bytes, mime: %s", imageURL, len(data), mimeType)
-		return base64Data, mimeType, nil
+		return base64.StdEncoding.EncodeToString(data), mimeType, nil
 	}
 
 	// HTTP URL：发送HTTP GET请求
@@ -892,26 +891,41 @@ func downloadImageFromURL(ctx context.Context, imageURL string) (string, string,
 		return "", "", fmt.Errorf("failed to read image data: %w", err)
 	}
 
-	// 编码为base64
-	base64Data := base64.StdEncoding.EncodeToString(data)
-
-	// 从URL获取文件扩展名并确定MIME类型
-	ext := filepath.Ext(imageURL)
-	mimeType := getMimeTypeForFile(ext)
+	// 按内容识别类型（URL 扩展名不可靠），并转换为模型接口接受的格式和尺寸
+	ext := ""
+	if parsed, err := url.Parse(imageURL); err == nil {
+		ext = path.Ext(parsed.Path)
+	}
+	mimeType, err := media.Check(ext, data)
+	if err != nil {
+		return "", "", err
+	}
+	if media.Class(mimeType) != "image" {
+		return "", "", fmt.Errorf("downloaded content is %s, not an image", mimeType)
+	}
+	if data, mimeType, err = media.PrepareImage(ctx, data, mimeType); err != nil {
+		return "", "", err
+	}
 
 	g.Log().Infof(ctx, "Successfully downloaded image: %s, size: %d bytes, mime: %s", imageURL, len(data), mimeType)
-	return base64Data, mimeType, nil
+	return base64.StdEncoding.EncodeToString(data), mimeType, nil
 }
 
 // removeImagePlaceholders 移除文本中的图片占位符
 // 匹配格式: ![image-0](http://127.0.0.1:8002/images/xxx.png)
+// 代码块和公式块内的行原样保留
 func removeImagePlaceholders(text string) string {
 	// 使用strings.Replace移除所有图片占位符
 	// 由于图片占位符格式是 ![image-N](...), 我们需要逐行处理
 	lines := strings.Split(text, "\n")
 	var result []string
+	var tracker common.BlockTracker
 
 	for _, line := range lines {
+		if tracker.Line(line) {
+			result = append(result, line)
+			continue
+		}
 		// 如果这一行只包含图片占位符，跳过
 		trimmed := strings.TrimSpace(line)
 		if strings.HasPrefix(trimmed, "![image-") && strings.Contains(trimmed, "](http") {
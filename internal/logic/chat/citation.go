@@ -0,0 +1,170 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// ReferenceFormatText 参考资料按编号拼接为纯文本（默认）
+	ReferenceFormatText = "text"
+	// ReferenceFormatJSON 参考资料以结构化 JSON 提供给模型，模型按分片ID引用，回答后映射为引用列表
+	ReferenceFormatJSON = "json"
+)
+
+// citationPattern 模型在回答中标注引用的格式，如 [ref:3f2a...]
+var citationPattern = regexp.MustCompile(`\[ref:\s*([^\]\s]+)\s*\]`)
+
+// referenceItem 以 JSON 提供给模型的参考资料
+type referenceItem struct {
+	ID      string  `json:"id"`
+	Title   string  `json:"title,omitempty"`
+	Source  string  `json:"source,omitempty"`
+	Score   float32 `json:"score,omitempty"`
+	Content string  `json:"content"`
+}
+
+// ReferenceFormat 参考资料提供给模型的格式（chat.references.format）
+func ReferenceFormat(ctx context.Context) string {
+	if strings.EqualFold(g.Cfg().MustGet(ctx, "chat.references.format", ReferenceFormatText).String(), ReferenceFormatJSON) {
+		return ReferenceFormatJSON
+	}
+	return ReferenceFormatText
+}
+
+// formatReferencesJSON 把参考资料转换为 JSON 数组，并说明引用标注格式
+func formatReferencesJSON(docs []*schema.Document) string {
+	items := make([]*referenceItem, 0, len(docs))
+	for _, doc := range docs {
+		title, source := referenceTitle(doc), referenceSource(doc)
+		items = append(items, &referenceItem{
+			ID:      doc.ID,
+			Title:   title,
+			Source:  source,
+			Score:   doc.Score,
+			Content: doc.Content,
+		})
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("参考资料（JSON 数组，每项包含分片ID id、标题 title、来源 source、相关性 score 和内容 content）:\n")
+	builder.Write(data)
+	builder.WriteString("\n\n引用要求：使用某条参考资料时，在对应句子末尾用 [ref:分片ID] 标注，如 [ref:")
+	if len(items) > 0 && items[0].ID != "" {
+		builder.WriteString(items[0].ID)
+	} else {
+		builder.WriteString("id")
+	}
+	builder.WriteString("]；只能引用上面列出的分片ID，不要编造。\n")
+	return builder.String()
+}
+
+// ResolveCitations 把回答中的 [ref:分片ID] 标注替换为按首次出现顺序编号的 [n]，并返回对应的引用列表
+// 参考资料中不存在的分片ID视为模型编造，标注直接移除
+func ResolveCitations(answer string, docs []*schema.Document) (string, []*v1.Citation) {
+	byID := make(map[string]*schema.Document, len(docs))
+	for _, doc := range docs {
+		if doc != nil && doc.ID != "" {
+			byID[doc.ID] = doc
+		}
+	}
+
+	var citations []*v1.Citation
+	indexes := map[string]int{}
+	resolved := citationPattern.ReplaceAllStringFunc(answer, func(marker string) string {
+		id := citationPattern.FindStringSubmatch(marker)[1]
+		doc, ok := byID[id]
+		if !ok {
+			return ""
+		}
+		index, ok := indexes[id]
+		if !ok {
+			index = len(citations) + 1
+			indexes[id] = index
+			citations = append(citations, newCitation(index, doc))
+		}
+		citations[index-1].Markers = appendUnique(citations[index-1].Markers, marker)
+		return fmt.Sprintf("[%d]", index)
+	})
+	return resolved, citations
+}
+
+// NumberCitations 按参考资料顺序编号生成引用列表，用于回答中没有 [ref:分片ID] 标注的场景
+func NumberCitations(docs []*schema.Document) []*v1.Citation {
+	citations := make([]*v1.Citation, 0, len(docs))
+	for _, doc := range docs {
+		if doc != nil && doc.ID != "" {
+			citations = append(citations, newCitation(len(citations)+1, doc))
+		}
+	}
+	return citations
+}
+
+func newCitation(index int, doc *schema.Document) *v1.Citation {
+	citation := &v1.Citation{
+		Index:   index,
+		ChunkID: doc.ID,
+		Title:   referenceTitle(doc),
+		Source:  referenceSource(doc),
+		Score:   doc.Score,
+	}
+	if documentID, ok := doc.MetaData[common.DocumentId].(string); ok {
+		citation.DocumentID = documentID
+	}
+	return citation
+}
+
+// referenceTitle 分片所在章节标题，由文档切分时记录的 h1/h2/h3 拼接
+func referenceTitle(doc *schema.Document) string {
+	meta := nestedMetadata(doc)
+	var titles []string
+	for _, key := range []string{common.Title1, common.Title2, common.Title3} {
+		if title, ok := meta[key].(string); ok && strings.TrimSpace(title) != "" {
+			titles = append(titles, strings.TrimSpace(title))
+		}
+	}
+	return strings.Join(titles, " > ")
+}
+
+// referenceSource 分片来源，依次取文档来源、顶层 source 和文档ID
+func referenceSource(doc *schema.Document) string {
+	if source, ok := nestedMetadata(doc)["_source"].(string); ok && source != "" {
+		return source
+	}
+	if source, ok := doc.MetaData["source"].(string); ok && source != "" {
+		return source
+	}
+	if documentID, ok := doc.MetaData[common.DocumentId].(string); ok {
+		return documentID
+	}
+	return ""
+}
+
+// nestedMetadata 文档切分时写入的 metadata 字段
+func nestedMetadata(doc *schema.Document) map[string]interface{} {
+	if meta, ok := doc.MetaData[common.FieldMetadata].(map[string]interface{}); ok {
+		return meta
+	}
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func citationDocs() []*schema.Document {
+	return []*schema.Document{
+		{
+			ID:      "c1",
+			Content: "退款在 7 个工作日内到账。",
+			Score:   0.91,
+			MetaData: map[string]interface{}{
+				"document_id": "doc1",
+				"metadata":    map[string]interface{}{"_source": "售后手册.pdf", "h1": "售后", "h2": "退款"},
+			},
+		},
+		{ID: "c2", Content: "支持七天无理由退货。", MetaData: map[string]interface{}{"document_id": "doc2"}},
+	}
+}
+
+// TestFormatReferencesJSON 测试结构化参考资料
+func TestFormatReferencesJSON(t *testing.T) {
+	formatted := formatReferencesJSON(citationDocs())
+	start, end := strings.Index(formatted, "["), strings.Index(formatted, "]\n")
+	if start < 0 || end < start {
+		t.Fatalf("no JSON array in %q", formatted)
+	}
+	var items []*referenceItem
+	if err := json.Unmarshal([]byte(formatted[start:end+1]), &items); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].ID != "c1" || items[0].Title != "售后 > 退款" || items[0].Source != "售后手册.pdf" {
+		t.Errorf("unexpected first item: %+v", items[0])
+	}
+	if items[1].Source != "doc2" {
+		t.Errorf("expected document id as source, got %q", items[1].Source)
+	}
+	if !strings.Contains(formatted, "[ref:c1]") {
+		t.Errorf("missing citation instruction: %s", formatted)
+	}
+}
+
+// TestResolveCitations 测试引用标注替换为编号
+func TestResolveCitations(t *testing.T) {
+	answer := "支持无理由退货[ref:c2]，退款 7 个工作日到账[ref: c1 ]。再次说明[ref:c2]，编造的引用[ref:c9]。"
+	resolved, citations := ResolveCitations(answer, citationDocs())
+
+	want := "支持无理由退货[1]，退款 7 个工作日到账[2]。再次说明[1]，编造的引用。"
+	if resolved != want {
+		t.Errorf("resolved = %q, want %q", resolved, want)
+	}
+	if len(citations) != 2 {
+		t.Fatalf("expected 2 citations, got %d", len(citations))
+	}
+	if citations[0].ChunkID != "c2" || citations[0].Index != 1 || citations[0].DocumentID != "doc2" {
+		t.Errorf("unexpected first citation: %+v", citations[0])
+	}
+	if citations[1].ChunkID != "c1" || citations[1].Title != "售后 > 退款" || citations[1].Markers[0] != "[ref: c1 ]" {
+		t.Errorf("unexpected second citation: %+v", citations[1])
+	}
+
+	if resolved, citations = ResolveCitations("没有引用", citationDocs()); resolved != "没有引用" || citations != nil {
+		t.Errorf("expected unchanged answer without citations, got %q %v", resolved, citations)
+	}
+}
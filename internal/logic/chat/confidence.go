@@ -0,0 +1,241 @@
+package chat
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/logic/handoff"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// ConfidenceMetadataKey 助手消息元数据中记录回答置信度的字段
+	ConfidenceMetadataKey = "confidence"
+
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+
+	groundedSentenceMinRunes = 6   // 少于该字数的句子（如“好的。”）不参与一致性判断
+	groundedCoverage         = 0.5 // 句子中能在参考资料中找到的二元组比例达到该值时视为有依据
+)
+
+// ConfidenceEnabled 是否计算回答置信度
+func ConfidenceEnabled(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "confidence.enabled", true).Bool()
+}
+
+// ConfidenceLogProbsEnabled 是否在非流式对话中向模型请求 logprobs
+func ConfidenceLogProbsEnabled(ctx context.Context) bool {
+	return ConfidenceEnabled(ctx) && g.Cfg().MustGet(ctx, "confidence.logprobs", false).Bool()
+}
+
+// ScoreAnswer 计算回答置信度，未启用或没有任何可用信号时返回 nil
+// docs 只应包含知识库检索结果；logProbs 为空时不使用 token 概率
+func ScoreAnswer(ctx context.Context, docs []*schema.Document, answer string, logProbs *openai.LogProbs) *v1.AnswerConfidence {
+	if !ConfidenceEnabled(ctx) || strings.TrimSpace(answer) == "" {
+		return nil
+	}
+
+	confidence := &v1.AnswerConfidence{}
+	if score, ok := retrievalConfidence(docs); ok {
+		confidence.Retrieval = &score
+	}
+	if score, ok := groundedness(docs, answer); ok {
+		confidence.Groundedness = &score
+	}
+	if score, ok := tokenProbability(logProbs); ok {
+		confidence.TokenProb = &score
+	}
+
+	weighted := []struct {
+		value  *float64
+		weight float64
+	}{
+		{confidence.Retrieval, g.Cfg().MustGet(ctx, "confidence.weights.retrieval", 0.4).Float64()},
+		{confidence.Groundedness, g.Cfg().MustGet(ctx, "confidence.weights.groundedness", 0.4).Float64()},
+		{confidence.TokenProb, g.Cfg().MustGet(ctx, "confidence.weights.tokenProb", 0.2).Float64()},
+	}
+	var sum, totalWeight float64
+	for _, w := range weighted {
+		if w.value == nil || w.weight <= 0 {
+			continue
+		}
+		sum += *w.value * w.weight
+		totalWeight += w.weight
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	confidence.Score = roundScore(sum / totalWeight)
+	confidence.Level = confidenceLevel(confidence.Score,
+		g.Cfg().MustGet(ctx, "confidence.highThreshold", 0.7).Float64(),
+		g.Cfg().MustGet(ctx, "confidence.lowThreshold", 0.4).Float64())
+	threshold := g.Cfg().MustGet(ctx, "confidence.escalation.threshold", 0).Float64()
+	confidence.Escalated = confidence.Score < threshold
+	return confidence
+}
+
+// NotifyEscalation 置信度低于升级阈值时异步调用升级 webhook，启用人工接管时同时创建转人工工单
+func NotifyEscalation(ctx context.Context, convID string, question string, answer string, confidence *v1.AnswerConfidence) {
+	if confidence == nil || !confidence.Escalated {
+		return
+	}
+	webhook := g.Cfg().MustGet(ctx, "confidence.escalation.webhook", "").String()
+	g.Log().Warningf(ctx, "Low confidence answer, convID=%s, score=%.2f, level=%s", convID, confidence.Score, confidence.Level)
+
+	// 启用人工接管时创建工单，后续消息由人工客服回复
+	if handoff.Enabled(ctx) {
+		score := confidence.Score
+		if _, err := handoff.Open(common.DetachContext(ctx), convID, gormModel.HandoffReasonLowConfidence, question, &score); err != nil {
+			g.Log().Errorf(ctx, "Failed to open handoff ticket for low confidence answer: %v", err)
+		}
+	}
+	if webhook == "" {
+		return
+	}
+
+	payload := g.Map{
+		"event":      "low_confidence",
+		"conv_id":    convID,
+		"question":   question,
+		"answer":     answer,
+		"confidence": confidence,
+		"time":       time.Now().Format(time.RFC3339),
+	}
+	webhookCtx := common.DetachContext(ctx)
+	common.SafeGo(webhookCtx, "ConfidenceEscalation", func() {
+		resp, err := g.Client().Timeout(10*time.Second).ContentJson().Post(webhookCtx, webhook, payload)
+		if err != nil {
+			g.Log().Errorf(webhookCtx, "Failed to call escalation webhook: %v", err)
+			return
+		}
+		defer resp.Close()
+		if resp.StatusCode >= 300 {
+			g.Log().Errorf(webhookCtx, "Escalation webhook returned status %d: %s", resp.StatusCode, resp.ReadAllString())
+		}
+	})
+}
+
+// retrievalConfidence 检索得分信号：最高分与前三名平均分加权，得分已在检索阶段归一化到 0-1
+func retrievalConfidence(docs []*schema.Document) (float64, bool) {
+	var scores []float64
+	for _, doc := range docs {
+		if doc.Score > 0 {
+			scores = append(scores, math.Min(float64(doc.Score), 1))
+		}
+	}
+	if len(scores) == 0 {
+		return 0, false
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+	top := scores[:min(len(scores), 3)]
+	var mean float64
+	for _, score := range top {
+		mean += score
+	}
+	mean /= float64(len(top))
+	return roundScore(0.6*scores[0] + 0.4*mean), true
+}
+
+// groundedness 回答与参考资料的一致性：按句子统计字符二元组在参考资料中的覆盖率
+// 只做字面比对，用于发现明显脱离参考资料的回答，不能判断语义是否正确
+func groundedness(docs []*schema.Document, answer string) (float64, bool) {
+	if len(docs) == 0 {
+		return 0, false
+	}
+	reference := make(map[string]bool)
+	for _, doc := range docs {
+		for _, bigram := range bigrams(doc.Content) {
+			reference[bigram] = true
+		}
+	}
+	if len(reference) == 0 {
+		return 0, false
+	}
+
+	var total, supported int
+	for _, sentence := range splitSentences(answer) {
+		grams := bigrams(sentence)
+		if len(grams)+1 < groundedSentenceMinRunes {
+			continue
+		}
+		hits := 0
+		for _, gram := range grams {
+			if reference[gram] {
+				hits++
+			}
+		}
+		total++
+		if float64(hits)/float64(len(grams)) >= groundedCoverage {
+			supported++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return roundScore(float64(supported) / float64(total)), true
+}
+
+// tokenProbability token 概率信号：token 对数概率均值的指数（几何平均概率）
+func tokenProbability(logProbs *openai.LogProbs) (float64, bool) {
+	if logProbs == nil || len(logProbs.Content) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, token := range logProbs.Content {
+		sum += token.LogProb
+	}
+	return roundScore(math.Exp(sum / float64(len(logProbs.Content)))), true
+}
+
+// confidenceLevel 根据阈值划分置信度等级
+func confidenceLevel(score, high, low float64) string {
+	switch {
+	case score >= high:
+		return ConfidenceHigh
+	case score >= low:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
+
+// splitSentences 按中英文句末标点和换行切分句子
+func splitSentences(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return strings.ContainsRune("。！？!?；;\n", r) || (r == '.')
+	})
+}
+
+// bigrams 提取文本的字符二元组，忽略大小写、空白和标点
+func bigrams(text string) []string {
+	var runes []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	if len(runes) < 2 {
+		return nil
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(math.Max(0, math.Min(score, 1))*1000) / 1000
+}
@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// TestGroundedness 测试回答与参考资料的一致性评分
+func TestGroundedness(t *testing.T) {
+	docs := []*schema.Document{
+		{Content: "KBGO 支持 Milvus 和 PostgreSQL 两种向量数据库，默认使用 Milvus。"},
+		{Content: "文档上传后会自动进行分块和向量化。"},
+	}
+	tests := []struct {
+		name   string
+		docs   []*schema.Document
+		answer string
+		want   float64
+		wantOk bool
+	}{
+		{
+			name:   "Fully grounded",
+			docs:   docs,
+			answer: "KBGO 支持 Milvus 和 PostgreSQL 两种向量数据库。文档上传后会自动分块和向量化。",
+			want:   1,
+			wantOk: true,
+		},
+		{
+			name:   "Half grounded",
+			docs:   docs,
+			answer: "默认使用 Milvus 向量数据库。该系统还内置了实时语音翻译功能！",
+			want:   0.5,
+			wantOk: true,
+		},
+		{
+			name:   "Short sentences are ignored",
+			docs:   docs,
+			answer: "好的。是的！",
+			wantOk: false,
+		},
+		{
+			name:   "No reference documents",
+			answer: "KBGO 支持 Milvus 和 PostgreSQL 两种向量数据库。",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := groundedness(tt.docs, tt.answer)
+			if ok != tt.wantOk {
+				t.Fatalf("groundedness() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("groundedness() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfidenceSignals 测试检索得分、token 概率和等级划分
+func TestConfidenceSignals(t *testing.T) {
+	retrieval, ok := retrievalConfidence([]*schema.Document{{Score: 0.9}, {Score: 0.6}, {Score: 0.3}, {Score: 0.1}, {}})
+	if !ok || retrieval != 0.78 {
+		t.Errorf("retrievalConfidence() = %v, %v, want 0.78, true", retrieval, ok)
+	}
+	if _, ok := retrievalConfidence([]*schema.Document{{Content: "mcp result"}}); ok {
+		t.Error("retrievalConfidence() should be unavailable without scores")
+	}
+
+	logProbs := &openai.LogProbs{Content: []openai.LogProb{{LogProb: math.Log(0.5)}, {LogProb: math.Log(0.5)}}}
+	if prob, ok := tokenProbability(logProbs); !ok || prob != 0.5 {
+		t.Errorf("tokenProbability() = %v, %v, want 0.5, true", prob, ok)
+	}
+	if _, ok := tokenProbability(nil); ok {
+		t.Error("tokenProbability() should be unavailable without logprobs")
+	}
+
+	levels := map[float64]string{0.9: ConfidenceHigh, 0.7: ConfidenceHigh, 0.5: ConfidenceMedium, 0.2: ConfidenceLow}
+	for score, want := range levels {
+		if got := confidenceLevel(score, 0.7, 0.4); got != want {
+			t.Errorf("confidenceLevel(%v) = %s, want %s", score, got, want)
+		}
+	}
+}
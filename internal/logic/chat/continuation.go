@@ -0,0 +1,250 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/tokenizer"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// continuePrompt 回答因 MaxCompletionTokens 被截断后请求模型续写的提示
+	continuePrompt = "你的回答因长度限制被截断了。请从中断的位置直接继续输出剩余内容，不要重复已输出的内容，不要添加任何开场白或说明。" +
+		"如果中断处位于表格、列表或代码块中，请直接续写下一部分并保持原有格式。"
+
+	// repetitionRetryPrompt 检测到重复输出并中止后请求模型续写的提示
+	repetitionRetryPrompt = "你的回答出现了重复的内容，已被中止。请从中断的位置继续完成回答，不要再重复已输出的内容，不要添加任何开场白或说明。"
+
+	// overlapWindow 续写开头与已输出内容比对重叠的最大字节数，流式续写时先缓冲这么多内容再转发
+	overlapWindow = 1024
+	// minOverlap 判定为重复输出的最小重叠字节数，避免误删表格分隔符等短的巧合重复
+	minOverlap = 12
+)
+
+// usageOrEstimate 返回服务商报告的 token 用量，没有报告时按模型分词器估算输入和输出的 token 数
+func usageOrEstimate(reported int, params coreModel.ChatCompletionParams, completion string) int {
+	if reported > 0 {
+		return reported
+	}
+	return tokenizer.CountMessages(params.ModelName, params.Messages) + tokenizer.Count(params.ModelName, completion)
+}
+
+// continuationLimit 单次回答最多自动续写的次数，JSON 输出等无法拼接的场景不续写
+func continuationLimit(ctx context.Context, params coreModel.ChatCompletionParams) int {
+	if params.ResponseFormat != nil || len(params.Tools) > 0 {
+		return 0
+	}
+	return max(g.Cfg().MustGet(ctx, "chat.maxContinuations", 3).Int(), 0)
+}
+
+// retryParams 重复输出中止后的重试参数：在 params 基础上提高惩罚参数，之后的续写也基于返回的参数
+func retryParams(ctx context.Context, params coreModel.ChatCompletionParams) coreModel.ChatCompletionParams {
+	params.FrequencyPenalty, params.PresencePenalty = retryPenalties(ctx, params.FrequencyPenalty, params.PresencePenalty)
+	return params
+}
+
+// continuationParams 在原消息后追加已输出的内容和续写提示
+func continuationParams(params coreModel.ChatCompletionParams, partial string, prompt string) coreModel.ChatCompletionParams {
+	messages := make([]*schema.Message, 0, len(params.Messages)+2)
+	messages = append(messages, params.Messages...)
+	messages = append(messages,
+		&schema.Message{Role: schema.Assistant, Content: partial},
+		&schema.Message{Role: schema.User, Content: prompt},
+	)
+	params.Messages = messages
+	return params
+}
+
+// trimOverlap 去除续写内容开头与已输出内容结尾重复的部分
+func trimOverlap(previous, next string) string {
+	limit := min(len(previous), len(next), overlapWindow)
+	for k := limit; k >= minOverlap; k-- {
+		if k < len(next) && !utf8.RuneStart(next[k]) {
+			continue
+		}
+		if strings.HasSuffix(previous, next[:k]) {
+			return next[k:]
+		}
+	}
+	return next
+}
+
+// completeWithContinuation 非流式对话，输出因长度限制被截断时自动续写并拼接为一条回答
+func completeWithContinuation(ctx context.Context, modelService *coreModel.ModelService, params coreModel.ChatCompletionParams) (*openai.ChatCompletionResponse, error) {
+	resp, err := modelService.ChatCompletion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	limit := continuationLimit(ctx, params)
+	for part := 0; part < limit && len(resp.Choices) > 0 && resp.Choices[0].FinishReason == openai.FinishReasonLength; part++ {
+		g.Log().Infof(ctx, "Answer truncated by max tokens, continuing generation (part %d)", part+2)
+		next, err := modelService.ChatCompletion(ctx, continuationParams(params, resp.Choices[0].Message.Content, continuePrompt))
+		if err != nil {
+			// 续写失败时返回已生成的部分
+			g.Log().Warningf(ctx, "Failed to continue truncated answer: %v", err)
+			break
+		}
+		if len(next.Choices) == 0 {
+			break
+		}
+		choice := &resp.Choices[0]
+		choice.Message.Content += trimOverlap(choice.Message.Content, next.Choices[0].Message.Content)
+		choice.Message.ReasoningContent += next.Choices[0].Message.ReasoningContent
+		choice.FinishReason = next.Choices[0].FinishReason
+		if choice.LogProbs != nil && next.Choices[0].LogProbs != nil {
+			choice.LogProbs.Content = append(choice.LogProbs.Content, next.Choices[0].LogProbs.Content...)
+		}
+		resp.Usage.PromptTokens += next.Usage.PromptTokens
+		resp.Usage.CompletionTokens += next.Usage.CompletionTokens
+		resp.Usage.TotalTokens += next.Usage.TotalTokens
+	}
+	return resp, nil
+}
+
+// streamResult 流式输出的转发结果
+type streamResult struct {
+	Answer    string // 完整回答
+	Tokens    int    // 各次调用的 token 用量之和
+	Truncated string // 回答被中止的原因，正常结束时为空
+}
+
+// relayStream 将模型流式输出转发到 streamWriter
+// 输出因长度限制被截断时自动续写，续写内容去除与已输出内容重叠的部分后继续转发，对 SSE 消费方透明；
+// 检测到重复输出时中止生成，按配置提高惩罚参数重试一次，仍然重复时以当前内容结束并标记为被中止
+// 推理内容交给 reasoning 按可见性策略转发
+// 接收出错或下游已关闭时 ok 为 false
+func relayStream(ctx context.Context, modelService *coreModel.ModelService, params coreModel.ChatCompletionParams,
+	stream *openai.ChatCompletionStream, streamWriter *schema.StreamWriter[*schema.Message], reasoning *reasoningRelay) (result streamResult, ok bool) {
+	var fullContent strings.Builder
+	guard := newRepetitionGuard(ctx)
+	repeated := false
+	emit := func(delta string) bool {
+		if delta == "" {
+			return true
+		}
+		// 开始输出回答说明推理已结束
+		if !reasoning.Flush() {
+			g.Log().Warningf(ctx, "stream writer closed unexpectedly")
+			return false
+		}
+		fullContent.WriteString(delta)
+		repeated = guard.Feed(delta)
+		// 创建增量消息并发送到流
+		if closed := streamWriter.Send(&schema.Message{Role: schema.Assistant, Content: delta}, nil); closed {
+			g.Log().Warningf(ctx, "stream writer closed unexpectedly")
+			return false
+		}
+		return true
+	}
+
+	limit := continuationLimit(ctx, params)
+	continuations := 0
+	retried := !g.Cfg().MustGet(ctx, "chat.repetitionGuard.retry", true).Bool()
+	// base 续写所基于的参数，重试提高的惩罚参数之后的续写继续沿用
+	base := params
+	current := params
+	for part := 0; ; part++ {
+		// 续写的开头先缓冲，与已输出内容去重后再转发
+		var pending strings.Builder
+		trimming := part > 0
+		var finishReason openai.FinishReason
+		var partTokens int
+		partStart := fullContent.Len()
+		// partUsage 本次调用的 token 用量，服务商没有在流中返回用量时按分词器估算
+		partUsage := func() int {
+			return usageOrEstimate(partTokens, current, fullContent.String()[partStart:])
+		}
+
+		for !repeated {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				stream.Close()
+				g.Log().Errorf(ctx, "stream receive error: %v", err)
+				streamWriter.Send(&schema.Message{
+					Role:    schema.Assistant,
+					Content: "",
+				}, err)
+				result.Answer, result.Tokens = fullContent.String(), result.Tokens+partUsage()
+				return result, false
+			}
+			// 记录token数量（如果有usage信息）
+			if response.Usage != nil {
+				partTokens = response.Usage.TotalTokens
+			}
+			if len(response.Choices) == 0 {
+				continue
+			}
+			if response.Choices[0].FinishReason != "" {
+				finishReason = response.Choices[0].FinishReason
+			}
+			if !reasoning.Feed(response.Choices[0].Delta.ReasoningContent) {
+				stream.Close()
+				result.Answer, result.Tokens = fullContent.String(), result.Tokens+partUsage()
+				return result, false
+			}
+			delta := response.Choices[0].Delta.Content
+			if trimming {
+				pending.WriteString(delta)
+				if pending.Len() < overlapWindow {
+					continue
+				}
+				delta = trimOverlap(fullContent.String(), pending.String())
+				trimming = false
+			}
+			if !emit(delta) {
+				stream.Close()
+				result.Answer, result.Tokens = fullContent.String(), result.Tokens+partUsage()
+				return result, false
+			}
+		}
+		stream.Close()
+		result.Tokens += partUsage()
+		if trimming && !emit(trimOverlap(fullContent.String(), pending.String())) {
+			result.Answer = fullContent.String()
+			return result, false
+		}
+
+		var nextParams coreModel.ChatCompletionParams
+		switch {
+		case repeated && !retried:
+			// 中止重复输出，提高惩罚参数后从中断处重试一次
+			retried, repeated = true, false
+			guard.Reset()
+			base = retryParams(ctx, base)
+			g.Log().Warningf(ctx, "Repetitive output detected, retrying with frequency_penalty=%.2f, presence_penalty=%.2f",
+				base.FrequencyPenalty, base.PresencePenalty)
+			nextParams = continuationParams(base, fullContent.String(), repetitionRetryPrompt)
+		case repeated:
+			g.Log().Warningf(ctx, "Repetitive output detected again, aborting generation")
+			result.Answer, result.Truncated = fullContent.String(), TruncatedByRepetitionGuard
+			return result, true
+		case finishReason == openai.FinishReasonLength && continuations < limit:
+			continuations++
+			g.Log().Infof(ctx, "Answer truncated by max tokens, continuing generation (part %d)", continuations+1)
+			nextParams = continuationParams(base, fullContent.String(), continuePrompt)
+		default:
+			result.Answer = fullContent.String()
+			return result, true
+		}
+
+		var err error
+		stream, err = modelService.ChatCompletionStream(ctx, nextParams)
+		if err != nil {
+			// 续写失败时保留已输出的部分
+			g.Log().Warningf(ctx, "Failed to continue answer: %v", err)
+			result.Answer = fullContent.String()
+			return result, true
+		}
+		current = nextParams
+	}
+}
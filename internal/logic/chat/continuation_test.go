@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/tokenizer"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// TestTrimOverlap 测试续写内容与已输出内容的去重
+func TestTrimOverlap(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		next     string
+		expected string
+	}{
+		{
+			name:     "Repeated partial table row",
+			previous: "| 城市 | 人口 |\n|---|---|\n| 北京 | 21",
+			next:     "| 北京 | 2189 万 |\n| 上海 | 2487 万 |",
+			expected: "89 万 |\n| 上海 | 2487 万 |",
+		},
+		{
+			name:     "Clean continuation",
+			previous: "第一部分介绍了安装步骤。",
+			next:     "第二部分介绍配置方法。",
+			expected: "第二部分介绍配置方法。",
+		},
+		{
+			name:     "Short coincidental overlap is kept",
+			previous: "| a | b |",
+			next:     "| c | d |",
+			expected: "| c | d |",
+		},
+		{
+			name:     "Whole continuation repeated",
+			previous: "The quick brown fox jumps over the lazy dog",
+			next:     "over the lazy dog",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimOverlap(tt.previous, tt.next); got != tt.expected {
+				t.Errorf("trimOverlap() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestUsageOrEstimate 服务商没有返回用量时按分词器估算
+func TestUsageOrEstimate(t *testing.T) {
+	params := coreModel.ChatCompletionParams{
+		ModelName: "gpt-4o",
+		Messages:  []*schema.Message{{Role: schema.User, Content: "hello world"}},
+	}
+	if got := usageOrEstimate(42, params, "ignored"); got != 42 {
+		t.Errorf("reported usage should be kept, got %d", got)
+	}
+	want := tokenizer.CountMessages("gpt-4o", params.Messages) + tokenizer.Count("gpt-4o", "hi there")
+	if got := usageOrEstimate(0, params, "hi there"); got != want || got == 0 {
+		t.Errorf("usageOrEstimate() = %d, want %d", got, want)
+	}
+}
+
+// TestContinuationAfterRetry 重复输出重试之后因长度截断续写时，沿用重试提高的惩罚参数，且不再带重试提示
+func TestContinuationAfterRetry(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("chat:\n  repetitionGuard:\n    penaltyBoost: 0.5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	params := coreModel.ChatCompletionParams{
+		Messages:         []*schema.Message{{Role: schema.User, Content: "question"}},
+		FrequencyPenalty: 0.2,
+	}
+	base := retryParams(context.Background(), params)
+	retry := continuationParams(base, "partial", repetitionRetryPrompt)
+	next := continuationParams(base, "partial and more", continuePrompt)
+
+	for name, p := range map[string]coreModel.ChatCompletionParams{"retry": retry, "continuation": next} {
+		if p.FrequencyPenalty != 0.7 || p.PresencePenalty != 0.5 {
+			t.Errorf("%s penalties = %.2f/%.2f, want 0.70/0.50", name, p.FrequencyPenalty, p.PresencePenalty)
+		}
+	}
+	if len(next.Messages) != 3 || next.Messages[1].Content != "partial and more" || next.Messages[2].Content != continuePrompt {
+		t.Errorf("continuation messages should be the original messages, the output so far and the continue prompt")
+	}
+	if params.FrequencyPenalty != 0.2 || len(params.Messages) != 1 {
+		t.Errorf("original params should not be modified")
+	}
+}
@@ -0,0 +1,163 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	defaultFollowUpCount     = 3
+	followUpHistoryMessages  = 6   // 作为会话上下文的最近消息条数
+	followUpDocContentLength = 300 // 每个参考文档截取的最大字符数
+)
+
+// GenerateFollowUpQuestions 基于检索文档和会话上下文生成推荐追问
+// 模型优先使用配置 followUp.modelID，未配置时使用本次对话的模型
+func (x *Chat) GenerateFollowUpQuestions(ctx context.Context, modelID string, convID string, docs []*schema.Document, question string, answer string) ([]string, error) {
+	if configured := g.Cfg().MustGet(ctx, "followUp.modelID", "").String(); configured != "" {
+		modelID = configured
+	}
+	count := g.Cfg().MustGet(ctx, "followUp.count", defaultFollowUpCount).Int()
+	if count <= 0 {
+		count = defaultFollowUpCount
+	}
+
+	mc := coreModel.Registry.Get(modelID)
+	if mc == nil {
+		return nil, fmt.Errorf("model not found: %s", modelID)
+	}
+
+	modelService := coreModel.NewModelServiceFor(mc, coreModel.FormatterFor(mc))
+
+	// 会话上下文只取本轮之前的最近几条消息
+	var historyMessages []*schema.Message
+	if x.eh != nil && convID != "" {
+		chatHistory, err := x.eh.GetHistory(convID, 100)
+		if err != nil {
+			g.Log().Warningf(ctx, "获取会话历史失败，推荐追问不使用上下文: %v", err)
+		} else {
+			historyMessages = previousTurns(chatHistory, question, answer, followUpHistoryMessages)
+		}
+	}
+
+	messages := []*schema.Message{
+		{Role: schema.System, Content: buildFollowUpPrompt(count)},
+		{Role: schema.User, Content: buildFollowUpInput(historyMessages, docs, question, answer)},
+	}
+
+	resp, err := modelService.ChatCompletion(ctx, coreModel.ChatCompletionParams{
+		ModelName:           mc.Name,
+		Messages:            messages,
+		Temperature:         0.5,
+		MaxCompletionTokens: 300,
+		TopP:                0.9,
+		N:                   1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成推荐追问失败: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("received empty choices from API")
+	}
+
+	questions := parseFollowUpQuestions(resp.Choices[0].Message.Content, count)
+	g.Log().Infof(ctx, "Generated %d follow-up questions for conversation %s", len(questions), convID)
+	return questions, nil
+}
+
+// buildFollowUpPrompt 构建推荐追问的系统提示词
+func buildFollowUpPrompt(count int) string {
+	return fmt.Sprintf("你是一个对话助手，负责根据用户的问题、AI的回答以及参考资料，推荐用户接下来可能会问的 %d 个问题。\n"+
+		"要求：\n"+
+		"1. 问题必须能从参考资料或对话上下文中找到依据，不要编造资料中不存在的内容；\n"+
+		"2. 问题要具体、简短，不超过30个字，彼此不重复，也不要与用户刚才的问题重复；\n"+
+		"3. 使用与用户问题相同的语言；\n"+
+		"4. 只输出一个 JSON 字符串数组，例如 [\"问题1\", \"问题2\", \"问题3\"]，不要输出任何其他内容。", count)
+}
+
+// buildFollowUpInput 组装会话上下文、参考资料和本轮问答
+func buildFollowUpInput(history []*schema.Message, docs []*schema.Document, question string, answer string) string {
+	var builder strings.Builder
+	if len(history) > 0 {
+		builder.WriteString("对话上下文:\n")
+		for _, msg := range history {
+			builder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		}
+		builder.WriteString("\n")
+	}
+	if len(docs) > 0 {
+		builder.WriteString("参考资料:\n")
+		for i, doc := range docs {
+			content := []rune(doc.Content)
+			if len(content) > followUpDocContentLength {
+				content = content[:followUpDocContentLength]
+			}
+			builder.WriteString(fmt.Sprintf("[%d] %s\n", i+1, string(content)))
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("用户问题: ")
+	builder.WriteString(question)
+	builder.WriteString("\nAI回答: ")
+	builder.WriteString(answer)
+	return builder.String()
+}
+
+// previousTurns 从历史中去掉本轮问答，返回之前最近的 limit 条用户/助手消息
+func previousTurns(history []*schema.Message, question string, answer string, limit int) []*schema.Message {
+	var turns []*schema.Message
+	for _, msg := range history {
+		if msg.Role != schema.User && msg.Role != schema.Assistant {
+			continue
+		}
+		if msg.Content == "" || msg.Content == question || msg.Content == answer {
+			continue
+		}
+		turns = append(turns, msg)
+	}
+	if len(turns) > limit {
+		turns = turns[len(turns)-limit:]
+	}
+	return turns
+}
+
+var followUpPrefixPattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)、])\s*`)
+
+// parseFollowUpQuestions 解析模型输出的问题列表，优先按 JSON 数组解析，失败时按行解析
+func parseFollowUpQuestions(content string, count int) []string {
+	content = strings.TrimSpace(content)
+
+	var candidates []string
+	if start, end := strings.Index(content, "["), strings.LastIndex(content, "]"); start >= 0 && end > start {
+		if err := json.Unmarshal([]byte(content[start:end+1]), &candidates); err != nil {
+			candidates = nil
+		}
+	}
+	if candidates == nil {
+		for _, line := range strings.Split(content, "\n") {
+			candidates = append(candidates, followUpPrefixPattern.ReplaceAllString(line, ""))
+		}
+	}
+
+	questions := make([]string, 0, count)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		candidate = strings.Trim(strings.TrimSpace(candidate), `"`)
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		questions = append(questions, candidate)
+		if len(questions) >= count {
+			break
+		}
+	}
+	return questions
+}
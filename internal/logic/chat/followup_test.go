@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseFollowUpQuestions 测试推荐追问解析
+func TestParseFollowUpQuestions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		count    int
+		expected []string
+	}{
+		{
+			name:     "JSON array",
+			content:  `["如何配置?", "支持哪些格式?", "有什么限制?"]`,
+			count:    3,
+			expected: []string{"如何配置?", "支持哪些格式?", "有什么限制?"},
+		},
+		{
+			name:     "JSON wrapped in code block",
+			content:  "```json\n[\"问题A\", \"问题B\"]\n```",
+			count:    3,
+			expected: []string{"问题A", "问题B"},
+		},
+		{
+			name:     "Numbered lines",
+			content:  "1. 问题A\n2) 问题B\n- 问题C\n问题D",
+			count:    3,
+			expected: []string{"问题A", "问题B", "问题C"},
+		},
+		{
+			name:     "Duplicates and blanks",
+			content:  `["问题A", "", "问题A", "问题B"]`,
+			count:    3,
+			expected: []string{"问题A", "问题B"},
+		},
+		{
+			name:     "Empty",
+			content:  "",
+			count:    3,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFollowUpQuestions(tt.content, tt.count); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseFollowUpQuestions() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
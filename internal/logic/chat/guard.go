@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"context"
+	"slices"
+	"unicode"
+
+	"github.com/Malowking/kbgo/internal/logic/featureflag"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// maxStopSequences OpenAI 兼容接口允许的最大停止序列数
+	maxStopSequences = 4
+
+	// TruncatedByRepetitionGuard 消息元数据 truncated 字段的取值：回答因重复输出被中止
+	TruncatedByRepetitionGuard = "repetition_guard"
+)
+
+// stopSequences 合并模型配置和 chat.stopSequences 全局配置中的停止序列，去重后最多保留 4 个
+func stopSequences(ctx context.Context, modelStop []string) []string {
+	var stop []string
+	for _, seq := range append(slices.Clone(modelStop), g.Cfg().MustGet(ctx, "chat.stopSequences").Strings()...) {
+		if seq != "" && !slices.Contains(stop, seq) {
+			stop = append(stop, seq)
+		}
+	}
+	if len(stop) > maxStopSequences {
+		g.Log().Warningf(ctx, "Too many stop sequences (%d), only the first %d are used", len(stop), maxStopSequences)
+		stop = stop[:maxStopSequences]
+	}
+	return stop
+}
+
+// repetitionGuard 流式输出的重复检测：同一个 n-gram（按字符）出现次数超过阈值时判定为失控的循环输出
+// 只由标点、空白组成的 n-gram（如表格分隔线、缩进）不计数
+type repetitionGuard struct {
+	n         int
+	threshold int
+	window    []rune
+	counts    map[string]int
+}
+
+// newRepetitionGuard 根据 chat.repetitionGuard 配置和 guardrails 功能开关创建重复检测，未启用时返回 nil
+func newRepetitionGuard(ctx context.Context) *repetitionGuard {
+	if !g.Cfg().MustGet(ctx, "chat.repetitionGuard.enabled", true).Bool() || !featureflag.Enabled(ctx, featureflag.Guardrails, "") {
+		return nil
+	}
+	return &repetitionGuard{
+		n:         max(g.Cfg().MustGet(ctx, "chat.repetitionGuard.ngram", 20).Int(), 4),
+		threshold: max(g.Cfg().MustGet(ctx, "chat.repetitionGuard.threshold", 8).Int(), 2),
+		counts:    make(map[string]int),
+	}
+}
+
+// Feed 追加输出内容，检测到重复时返回 true
+func (r *repetitionGuard) Feed(text string) bool {
+	if r == nil {
+		return false
+	}
+	repeated := false
+	for _, c := range text {
+		r.window = append(r.window, c)
+		if len(r.window) > r.n {
+			r.window = r.window[1:]
+		}
+		if len(r.window) < r.n || !hasWordRune(r.window) {
+			continue
+		}
+		key := string(r.window)
+		r.counts[key]++
+		if r.counts[key] >= r.threshold {
+			repeated = true
+		}
+	}
+	return repeated
+}
+
+// Reset 清空检测状态（重试生成时使用）
+func (r *repetitionGuard) Reset() {
+	if r == nil {
+		return
+	}
+	r.window = r.window[:0]
+	r.counts = make(map[string]int)
+}
+
+func hasWordRune(runes []rune) bool {
+	for _, c := range runes {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryPenalties 重复输出后重试使用的惩罚参数：在原参数基础上提高 frequency/presence penalty（上限 2）
+func retryPenalties(ctx context.Context, frequency, presence float32) (float32, float32) {
+	boost := float32(g.Cfg().MustGet(ctx, "chat.repetitionGuard.penaltyBoost", 0.5).Float64())
+	return min(frequency+boost, 2), min(presence+boost, 2)
+}
@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRepetitionGuard 测试流式输出的重复检测
+func TestRepetitionGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []string
+		expected bool
+	}{
+		{
+			name:     "Runaway loop",
+			chunks:   []string{strings.Repeat("我们需要进一步分析这个问题。", 10)},
+			expected: true,
+		},
+		{
+			name:     "Single character loop across chunks",
+			chunks:   strings.Split(strings.Repeat("哈", 40), ""),
+			expected: true,
+		},
+		{
+			name: "Table separators are ignored",
+			chunks: []string{
+				"| 城市 | 人口 | 面积 |\n" + strings.Repeat("|----------|----------|----------|\n", 12),
+			},
+			expected: false,
+		},
+		{
+			name: "Varied text",
+			chunks: []string{
+				"KBGO 支持多种向量数据库。", "文档上传后会自动分块。", "检索时可以选择 rerank 或 rrf 模式。",
+				"对话接口支持流式输出和多模态输入。", "模型配置变更后会自动重新向量化。",
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := &repetitionGuard{n: 20, threshold: 8, counts: make(map[string]int)}
+			got := false
+			for _, chunk := range tt.chunks {
+				if guard.Feed(chunk) {
+					got = true
+				}
+			}
+			if got != tt.expected {
+				t.Errorf("Feed() detected = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	var guard *repetitionGuard
+	if guard.Feed(strings.Repeat("哈", 100)) {
+		t.Error("nil guard should never detect repetition")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
 )
@@ -89,10 +90,25 @@ func formatDocuments(docs []*schema.Document) string {
 		builder.WriteString(doc.Content)
 		builder.WriteString("\n\n")
 	}
+	builder.WriteString(formatPreservationHint(docs))
 
 	return builder.String()
 }
 
+// formatPreservationHint 参考资料中有包含代码块或公式的分片（content_type）时，提示模型引用时保留原有格式
+func formatPreservationHint(docs []*schema.Document) string {
+	for _, doc := range docs {
+		contentType, ok := doc.MetaData[common.ContentTypeKey].(string)
+		if !ok {
+			contentType, _ = nestedMetadata(doc)[common.ContentTypeKey].(string)
+		}
+		if contentType != "" && contentType != common.ContentTypeText {
+			return "格式要求：参考资料中的代码块和公式引用时请原样保留，代码使用 ``` 围栏代码块，公式使用 $...$ 或 $$...$$ 的 LaTeX 写法，不要改写其中的符号。\n"
+		}
+	}
+	return ""
+}
+
 // buildSystemMessage 构建系统消息
 func buildSystemMessage(formattedDocs string) string {
 	return fmt.Sprintf(systemPromptTemplate, role, formattedDocs)
@@ -105,7 +121,7 @@ func (x *Chat) docsMessages(ctx context.Context, convID string, docs []*schema.D
 		return
 	}
 	// 插入一条用户数据
-	err = x.eh.SaveMessage(&schema.Message{
+	err = x.eh.SaveMessage(ctx, &schema.Message{
 		Role:    schema.User,
 		Content: question,
 	}, convID)
@@ -0,0 +1,34 @@
+package chat
+
+import (
+	"context"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+)
+
+// ModelFallbackMetadataKey 助手消息元数据中记录改用备用模型的字段
+const ModelFallbackMetadataKey = "model_fallback"
+
+// tagModelFallback 本次请求中有模型调用改用了备用模型时，在助手消息元数据中记录请求的模型、实际使用的模型和失败原因
+func tagModelFallback(ctx context.Context, metadata map[string]interface{}) map[string]interface{} {
+	events := coreModel.FallbackRecorderFromContext(ctx).Events()
+	if len(events) == 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata[ModelFallbackMetadataKey] = events
+	return metadata
+}
+
+// servedModelID 返回实际生成回答的模型：请求的模型改用了备用模型时返回最后一次切换到的模型，否则返回请求的模型
+func servedModelID(ctx context.Context, modelID string) string {
+	served := modelID
+	for _, event := range coreModel.FallbackRecorderFromContext(ctx).Events() {
+		if event.RequestedModelID == modelID && event.ServedModelID != "" {
+			served = event.ServedModelID
+		}
+	}
+	return served
+}
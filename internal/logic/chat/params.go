@@ -15,6 +15,9 @@ type ModelParams struct {
 	// MaxCompletionTokens 最大生成token数量
 	MaxCompletionTokens *int `json:"maxCompletionTokens,omitempty" yaml:"maxCompletionTokens,omitempty"`
 
+	// ContextWindow 模型上下文窗口 token 数，设置后按输入长度收缩 MaxCompletionTokens
+	ContextWindow *int `json:"contextWindow,omitempty" yaml:"contextWindow,omitempty"`
+
 	// FrequencyPenalty 频率惩罚，减少重复词汇，-2.0到2.0
 	FrequencyPenalty *float32 `json:"frequencyPenalty,omitempty" yaml:"frequencyPenalty,omitempty"`
 
@@ -38,6 +41,15 @@ type ModelParams struct {
 
 	// ResponseFormat 响应格式
 	ResponseFormat *openaiSDK.ChatCompletionResponseFormat `json:"responseFormat,omitempty" yaml:"responseFormat,omitempty"`
+
+	// ReasoningPolicy 推理内容可见性策略：hide / summarize / show，为空时使用 reasoning.policy 配置
+	ReasoningPolicy string `json:"reasoningPolicy,omitempty" yaml:"reasoningPolicy,omitempty"`
+
+	// VideoFrames 视频附件抽取的关键帧数量，0 表示不发送画面，为空时多模态模型使用 multimodal.video.frames 配置
+	VideoFrames *int `json:"videoFrames,omitempty" yaml:"videoFrames,omitempty"`
+
+	// VideoTranscript 视频附件是否附带语音转写文本，为空时使用 multimodal.video.transcript 配置
+	VideoTranscript *bool `json:"videoTranscript,omitempty" yaml:"videoTranscript,omitempty"`
 }
 
 // Function 函数调用定义
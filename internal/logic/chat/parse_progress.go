@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 文档解析进度阶段
+const (
+	ParseStageStart  = "start"  // 开始解析文件
+	ParseStagePage   = "page"   // 已解析若干页（解析后端支持逐页进度时）
+	ParseStageDone   = "done"   // 文件解析完成
+	ParseStageFailed = "failed" // 文件解析失败，回答时不使用该文件
+)
+
+// defaultParseTimeout 对话上传文档的解析阶段默认总超时
+const defaultParseTimeout = 180 * time.Second
+
+// ParseProgress 对话上传文档的解析进度，流式对话中以 parse_progress 事件发送给调用方
+type ParseProgress struct {
+	FileName  string `json:"file_name"`
+	FileIndex int    `json:"file_index"` // 从 1 开始
+	FileCount int    `json:"file_count"`
+	Stage     string `json:"stage"`             // start / page / done / failed
+	Page      int    `json:"page,omitempty"`    // 已解析页数（page 阶段）
+	Pages     int    `json:"pages,omitempty"`   // 总页数（page 阶段）
+	Message   string `json:"message,omitempty"` // 失败原因
+}
+
+// withStage 返回指定阶段的进度副本
+func (p ParseProgress) withStage(stage string) *ParseProgress {
+	p.Stage = stage
+	return &p
+}
+
+// ParseProgressFunc 接收文档解析进度的回调
+type ParseProgressFunc func(progress *ParseProgress)
+
+type parseProgressKey struct{}
+
+// WithParseProgress 在上下文中设置文档解析进度回调
+func WithParseProgress(ctx context.Context, fn ParseProgressFunc) context.Context {
+	return context.WithValue(ctx, parseProgressKey{}, fn)
+}
+
+func reportParseProgress(ctx context.Context, progress *ParseProgress) {
+	if fn, ok := ctx.Value(parseProgressKey{}).(ParseProgressFunc); ok && fn != nil {
+		fn(progress)
+	}
+}
+
+// parseTimeout 对话上传文档的解析阶段总超时（fileParse.chatTimeout，秒）
+func parseTimeout(ctx context.Context) time.Duration {
+	seconds := g.Cfg().MustGet(ctx, "fileParse.chatTimeout", int(defaultParseTimeout/time.Second)).Int()
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// parseStage 在生成回答之前解析上传的文档：整个阶段有独立的超时，超时后只使用已解析完成的文件继续回答；
+// 请求被取消（如客户端断开）时返回错误，不再调用模型
+func parseStage(ctx context.Context, files []*common.MultimodalFile) (string, []string, error) {
+	stageCtx := ctx
+	if timeout := parseTimeout(ctx); timeout > 0 {
+		var cancel context.CancelFunc
+		stageCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	content, images, err := parseDocumentFiles(stageCtx, files)
+	if ctx.Err() != nil {
+		return "", nil, ctx.Err()
+	}
+	if errors.Is(stageCtx.Err(), context.DeadlineExceeded) {
+		g.Log().Warningf(ctx, "Document parsing timed out, answering with the files parsed so far (%d chars)", len(content))
+		return content, images, nil
+	}
+	return content, images, err
+}
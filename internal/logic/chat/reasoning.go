@@ -0,0 +1,148 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 推理模型思考过程（reasoning_content）的可见性策略
+// 推理内容只返回给调用方并按策略记录到消息元数据，不写入消息正文，因此不会随会话历史回传给模型，也不占用历史上下文
+const (
+	ReasoningHide      = "hide"      // 丢弃，不返回也不保存
+	ReasoningSummarize = "summarize" // 只返回和保存结论部分
+	ReasoningShow      = "show"      // 原样返回和保存
+
+	// ReasoningMetadataKey 助手消息元数据中记录可见推理内容的字段
+	ReasoningMetadataKey = "reasoning"
+
+	defaultReasoningSummaryChars = 300
+)
+
+// reasoningPolicy 获取推理内容的可见性策略：模型配置的 reasoningPolicy 优先，其次为 reasoning.policy 配置（默认 hide）
+func reasoningPolicy(ctx context.Context, params *ModelParams) string {
+	policy := params.ReasoningPolicy
+	if policy == "" {
+		policy = g.Cfg().MustGet(ctx, "reasoning.policy", ReasoningHide).String()
+	}
+	switch policy {
+	case ReasoningShow, ReasoningSummarize:
+		return policy
+	default:
+		return ReasoningHide
+	}
+}
+
+// visibleReasoning 按策略返回对调用方可见的推理内容
+func visibleReasoning(ctx context.Context, policy string, reasoning string) string {
+	reasoning = strings.TrimSpace(reasoning)
+	switch policy {
+	case ReasoningShow:
+		return reasoning
+	case ReasoningSummarize:
+		return summarizeReasoning(reasoning, g.Cfg().MustGet(ctx, "reasoning.summaryMaxChars", defaultReasoningSummaryChars).Int())
+	default:
+		return ""
+	}
+}
+
+// summarizeReasoning 保留推理过程末尾的结论部分：从后向前取完整的句子，总长度不超过 maxChars 个字符，省略的部分以 … 表示
+func summarizeReasoning(reasoning string, maxChars int) string {
+	if maxChars <= 0 {
+		maxChars = defaultReasoningSummaryChars
+	}
+	if utf8.RuneCountInString(reasoning) <= maxChars {
+		return reasoning
+	}
+
+	sentences := splitReasoningSentences(reasoning)
+	start, length := len(sentences), 0
+	for start > 0 {
+		n := utf8.RuneCountInString(sentences[start-1])
+		if length+n > maxChars {
+			break
+		}
+		length += n
+		start--
+	}
+	if start == len(sentences) {
+		// 最后一句已超过上限，截取其末尾
+		runes := []rune(sentences[len(sentences)-1])
+		return "…" + string(runes[len(runes)-maxChars:])
+	}
+	return "…" + strings.TrimSpace(strings.Join(sentences[start:], ""))
+}
+
+// splitReasoningSentences 按中英文句末标点和换行切分句子，标点保留在句子末尾
+func splitReasoningSentences(text string) []string {
+	var sentences []string
+	begin := 0
+	for i, r := range text {
+		switch r {
+		case '。', '！', '？', '.', '!', '?', '\n':
+			end := i + utf8.RuneLen(r)
+			sentences = append(sentences, text[begin:end])
+			begin = end
+		}
+	}
+	if begin < len(text) {
+		sentences = append(sentences, text[begin:])
+	}
+	return sentences
+}
+
+// tagReasoning 在助手消息元数据中记录可见的推理内容
+func tagReasoning(msg *history.MessageWithMetrics, reasoning string) {
+	if reasoning == "" {
+		return
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]interface{}{}
+	}
+	msg.Metadata[ReasoningMetadataKey] = reasoning
+}
+
+// reasoningRelay 按策略转发流式输出中的推理内容：
+// show 逐段转发，summarize 在推理结束（开始输出回答）时发送一次结论部分，hide 不转发
+type reasoningRelay struct {
+	ctx          context.Context
+	policy       string
+	streamWriter *schema.StreamWriter[*schema.Message]
+	full         strings.Builder
+	summarized   bool
+}
+
+func newReasoningRelay(ctx context.Context, policy string, streamWriter *schema.StreamWriter[*schema.Message]) *reasoningRelay {
+	return &reasoningRelay{ctx: ctx, policy: policy, streamWriter: streamWriter}
+}
+
+// Feed 记录一段推理内容，下游已关闭时返回 false
+func (r *reasoningRelay) Feed(delta string) bool {
+	if delta == "" || r.policy == ReasoningHide {
+		return true
+	}
+	r.full.WriteString(delta)
+	if r.policy != ReasoningShow {
+		return true
+	}
+	return !r.streamWriter.Send(&schema.Message{Role: schema.Assistant, ReasoningContent: delta}, nil)
+}
+
+// Flush 推理结束时发送推理结论（仅 summarize 策略，只发送一次），下游已关闭时返回 false
+func (r *reasoningRelay) Flush() bool {
+	if r.policy != ReasoningSummarize || r.summarized || r.full.Len() == 0 {
+		return true
+	}
+	r.summarized = true
+	summary := visibleReasoning(r.ctx, r.policy, r.full.String())
+	return !r.streamWriter.Send(&schema.Message{Role: schema.Assistant, ReasoningContent: summary}, nil)
+}
+
+// Visible 本次回答对调用方可见的推理内容，用于保存到消息元数据
+func (r *reasoningRelay) Visible() string {
+	return visibleReasoning(r.ctx, r.policy, r.full.String())
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// TestSummarizeReasoning 测试推理内容只保留末尾的结论部分
+func TestSummarizeReasoning(t *testing.T) {
+	tests := []struct {
+		name      string
+		reasoning string
+		maxChars  int
+		want      string
+	}{
+		{name: "Short reasoning kept", reasoning: "先查年假规定。结论是可以。", maxChars: 20, want: "先查年假规定。结论是可以。"},
+		{name: "Keep trailing sentences", reasoning: "用户问年假。需要查制度。制度规定五天。所以答五天。", maxChars: 14, want: "…制度规定五天。所以答五天。"},
+		{name: "Mixed punctuation and newlines", reasoning: "First check.\nThen verify!\nAnswer is 5.", maxChars: 13, want: "…Answer is 5."},
+		{name: "Last sentence too long", reasoning: "开头。这是一个非常非常长的结论句子", maxChars: 6, want: "…长的结论句子"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeReasoning(tt.reasoning, tt.maxChars); got != tt.want {
+				t.Errorf("summarizeReasoning() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReasoningRelayFeed 测试流式推理内容按策略转发
+func TestReasoningRelayFeed(t *testing.T) {
+	for _, policy := range []string{ReasoningHide, ReasoningShow} {
+		t.Run(policy, func(t *testing.T) {
+			reader, writer := schema.Pipe[*schema.Message](10)
+			relay := newReasoningRelay(context.Background(), policy, writer)
+			if !relay.Feed("思考一") || !relay.Feed("") || !relay.Feed("思考二") {
+				t.Fatal("Feed() reported closed writer")
+			}
+			writer.Close()
+
+			var forwarded string
+			for {
+				msg, err := reader.Recv()
+				if err != nil {
+					break
+				}
+				if msg.Content != "" {
+					t.Errorf("unexpected content %q", msg.Content)
+				}
+				forwarded += msg.ReasoningContent
+			}
+			want := ""
+			if policy == ReasoningShow {
+				want = "思考一思考二"
+			}
+			if forwarded != want {
+				t.Errorf("forwarded %q, want %q", forwarded, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/answerdiff"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// shadowRunning 正在运行的影子请求数，超过 shadow.maxConcurrency 时丢弃采样，避免影子流量挤占线上资源
+var shadowRunning atomic.Int32
+
+// ShadowRun 被采样的一次对话，线上回答完成后用候选模型在后台重新生成回答
+type ShadowRun struct {
+	x            *Chat
+	convID       string
+	question     string
+	liveModelID  string
+	docs         []*schema.Document
+	candidate    *coreModel.ModelConfig
+	systemPrompt string
+	history      []*schema.Message
+}
+
+// StartShadow 按 shadow 配置采样本轮对话，采中时在调用线上模型前记录会话历史
+// 未启用、未采中或候选模型不可用时返回 nil
+func (x *Chat) StartShadow(ctx context.Context, convID string, liveModelID string, question string, docs []*schema.Document) *ShadowRun {
+	if !g.Cfg().MustGet(ctx, "shadow.enabled", false).Bool() {
+		return nil
+	}
+	if rand.Float64() >= g.Cfg().MustGet(ctx, "shadow.sampleRate", 0.1).Float64() {
+		return nil
+	}
+	candidateID := g.Cfg().MustGet(ctx, "shadow.modelID").String()
+	candidate := coreModel.Registry.Get(candidateID)
+	if candidate == nil {
+		g.Log().Warningf(ctx, "Shadow candidate model not found: %s", candidateID)
+		return nil
+	}
+
+	run := &ShadowRun{
+		x:            x,
+		convID:       convID,
+		question:     question,
+		liveModelID:  liveModelID,
+		docs:         docs,
+		candidate:    candidate,
+		systemPrompt: g.Cfg().MustGet(ctx, "shadow.systemPrompt").String(),
+	}
+	if x.eh != nil && convID != "" {
+		history, err := x.eh.GetHistoryForModel(convID, 100, historyPartTypes(candidate)...)
+		if err != nil {
+			g.Log().Warningf(ctx, "Shadow run skipped, failed to load history: %v", err)
+			return nil
+		}
+		run.history = history
+	}
+	return run
+}
+
+// Finish 在后台调用候选模型并保存两个回答的对比结果，候选回答不会返回给用户
+// run 为 nil 时不做任何事
+func (r *ShadowRun) Finish(ctx context.Context, liveAnswer string, liveLatencyMs int64) {
+	if r == nil || liveAnswer == "" {
+		return
+	}
+	limit := int32(g.Cfg().MustGet(ctx, "shadow.maxConcurrency", 4).Int())
+	if shadowRunning.Add(1) > limit {
+		shadowRunning.Add(-1)
+		g.Log().Warningf(ctx, "Too many running shadow requests, dropping sample for conversation %s", r.convID)
+		return
+	}
+
+	// 线上请求结束后 ctx 会被取消，影子请求使用独立的超时
+	timeout := time.Duration(g.Cfg().MustGet(ctx, "shadow.timeout", 120).Int()) * time.Second
+	shadowCtx, cancel := context.WithTimeout(common.DetachContext(ctx), timeout)
+	common.SafeGo(shadowCtx, "shadow-eval", func() {
+		defer cancel()
+		defer shadowRunning.Add(-1)
+		r.run(shadowCtx, liveAnswer, liveLatencyMs)
+	})
+}
+
+func (r *ShadowRun) run(ctx context.Context, liveAnswer string, liveLatencyMs int64) {
+	result := &gormModel.ShadowResult{
+		ID:               uuid.New().String(),
+		ConvID:           r.convID,
+		Question:         r.question,
+		LiveModelID:      r.liveModelID,
+		LiveAnswer:       liveAnswer,
+		LiveLatencyMs:    liveLatencyMs,
+		CandidateModelID: r.candidate.ModelID,
+	}
+	if score, ok := groundedness(r.docs, liveAnswer); ok {
+		result.LiveGroundedness = &score
+	}
+
+	start := time.Now()
+	answer, tokens, err := r.generate(ctx)
+	result.CandidateLatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		g.Log().Warningf(ctx, "Shadow candidate %s failed: %v", r.candidate.ModelID, err)
+	} else {
+		result.CandidateAnswer = answer
+		result.CandidateTokens = tokens
+		result.Agreement = answerAgreement(liveAnswer, answer)
+		if score, ok := groundedness(r.docs, answer); ok {
+			result.CandidateGroundedness = &score
+		}
+	}
+
+	if err = dao.ShadowResult.Create(ctx, result); err != nil {
+		g.Log().Errorf(ctx, "Failed to save shadow result: %v", err)
+		return
+	}
+	answerdiff.RecordShadow(ctx, result)
+}
+
+// generate 使用候选模型和线上相同的参考资料、会话历史生成回答（不保存到会话）
+func (r *ShadowRun) generate(ctx context.Context) (string, int, error) {
+	mc := r.candidate
+	modelService := coreModel.NewModelServiceFor(mc, coreModel.FormatterFor(mc))
+
+	prompt := r.systemPrompt
+	if prompt == "" {
+		prompt = role
+	}
+	messages := []*schema.Message{{Role: schema.System, Content: prompt + "\n\n" + formatDocumentsForChat(ctx, r.docs)}}
+	messages = append(messages, r.history...)
+	messages = append(messages, &schema.Message{Role: schema.User, Content: r.question})
+
+	params := parseModelParams(mc.Extra)
+	resp, err := modelService.ChatCompletion(ctx, coreModel.ChatCompletionParams{
+		ModelName:           mc.Name,
+		Messages:            messages,
+		Temperature:         getFloat32OrDefault(params.Temperature, 0.7),
+		MaxCompletionTokens: getIntOrDefault(params.MaxCompletionTokens, 2000),
+		ContextWindow:       getIntOrDefault(params.ContextWindow, 0),
+		TopP:                getFloat32OrDefault(params.TopP, 0.9),
+		FrequencyPenalty:    getFloat32OrDefault(params.FrequencyPenalty, 0.0),
+		PresencePenalty:     getFloat32OrDefault(params.PresencePenalty, 0.0),
+		N:                   1,
+		Stop:                stopSequences(ctx, params.Stop),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	if len(resp.Choices) == 0 {
+		return "", 0, fmt.Errorf("received empty choices from API")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), resp.Usage.TotalTokens, nil
+}
+
+// answerAgreement 两个回答的字面相似度：字符二元组集合的 Jaccard 系数
+func answerAgreement(a, b string) float64 {
+	setA := make(map[string]bool)
+	for _, gram := range bigrams(a) {
+		setA[gram] = true
+	}
+	setB := make(map[string]bool)
+	for _, gram := range bigrams(b) {
+		setB[gram] = true
+	}
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+	intersection := 0
+	for gram := range setA {
+		if setB[gram] {
+			intersection++
+		}
+	}
+	return roundScore(float64(intersection) / float64(len(setA)+len(setB)-intersection))
+}
+
+// ShadowSummary 按线上模型和候选模型汇总 [start, end) 区间内的影子评估结果
+func ShadowSummary(ctx context.Context, start, end time.Time) ([]*v1.ShadowSummaryItem, error) {
+	results, err := dao.ShadowResult.ListBetween(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeShadowResults(results), nil
+}
+
+func summarizeShadowResults(results []*gormModel.ShadowResult) []*v1.ShadowSummaryItem {
+	type accumulator struct {
+		item                                   *v1.ShadowSummaryItem
+		liveLatency, candidateLatency, tokens  float64
+		liveGrounded, candidateGrounded, agree float64
+		liveGroundedN, candidateGroundedN, ok  int64
+	}
+	groups := make(map[[2]string]*accumulator)
+	var order [][2]string
+	for _, r := range results {
+		key := [2]string{r.LiveModelID, r.CandidateModelID}
+		acc, exists := groups[key]
+		if !exists {
+			acc = &accumulator{item: &v1.ShadowSummaryItem{LiveModelID: r.LiveModelID, CandidateModelID: r.CandidateModelID}}
+			groups[key] = acc
+			order = append(order, key)
+		}
+		acc.item.SampleCount++
+		acc.liveLatency += float64(r.LiveLatencyMs)
+		if r.LiveGroundedness != nil {
+			acc.liveGrounded += *r.LiveGroundedness
+			acc.liveGroundedN++
+		}
+		if r.Error != "" {
+			acc.item.ErrorCount++
+			continue
+		}
+		acc.ok++
+		acc.candidateLatency += float64(r.CandidateLatencyMs)
+		acc.tokens += float64(r.CandidateTokens)
+		acc.agree += r.Agreement
+		if r.CandidateGroundedness != nil {
+			acc.candidateGrounded += *r.CandidateGroundedness
+			acc.candidateGroundedN++
+		}
+	}
+
+	items := make([]*v1.ShadowSummaryItem, 0, len(order))
+	for _, key := range order {
+		acc := groups[key]
+		item := acc.item
+		item.AvgLiveLatencyMs = acc.liveLatency / float64(item.SampleCount)
+		if acc.ok > 0 {
+			item.AvgCandidateLatencyMs = acc.candidateLatency / float64(acc.ok)
+			item.AvgCandidateTokens = acc.tokens / float64(acc.ok)
+			item.AvgAgreement = roundScore(acc.agree / float64(acc.ok))
+		}
+		if acc.liveGroundedN > 0 {
+			avg := roundScore(acc.liveGrounded / float64(acc.liveGroundedN))
+			item.AvgLiveGroundedness = &avg
+		}
+		if acc.candidateGroundedN > 0 {
+			avg := roundScore(acc.candidateGrounded / float64(acc.candidateGroundedN))
+			item.AvgCandidateGroundedness = &avg
+		}
+		items = append(items, item)
+	}
+	return items
+}
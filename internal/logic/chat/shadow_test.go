@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// TestAnswerAgreement 测试线上回答与候选回答的相似度
+func TestAnswerAgreement(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want float64
+	}{
+		{name: "Identical", a: "年假需要提前三天申请", b: "年假需要提前三天申请", want: 1},
+		{name: "Punctuation and case ignored", a: "Hello, World!", b: "hello world", want: 1},
+		{name: "Disjoint", a: "年假申请", b: "报销流程", want: 0},
+		{name: "Partial overlap", a: "abcd", b: "abxy", want: 0.2},
+		{name: "Both empty", a: "", b: "", want: 1},
+		{name: "One empty", a: "年假申请", b: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := answerAgreement(tt.a, tt.b); got != tt.want {
+				t.Errorf("answerAgreement(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSummarizeShadowResults 测试按模型对汇总影子评估结果
+func TestSummarizeShadowResults(t *testing.T) {
+	score := func(v float64) *float64 { return &v }
+	results := []*gormModel.ShadowResult{
+		{LiveModelID: "live", CandidateModelID: "cand-a", LiveLatencyMs: 100, CandidateLatencyMs: 200, CandidateTokens: 50, Agreement: 0.8, LiveGroundedness: score(0.9), CandidateGroundedness: score(0.6)},
+		{LiveModelID: "live", CandidateModelID: "cand-a", LiveLatencyMs: 300, CandidateLatencyMs: 400, CandidateTokens: 150, Agreement: 0.4, LiveGroundedness: score(0.7)},
+		{LiveModelID: "live", CandidateModelID: "cand-a", LiveLatencyMs: 200, CandidateLatencyMs: 5000, Error: "timeout"},
+		{LiveModelID: "live", CandidateModelID: "cand-b", LiveLatencyMs: 100, Error: "timeout"},
+	}
+	items := summarizeShadowResults(results)
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	a := items[0]
+	if a.CandidateModelID != "cand-a" || a.SampleCount != 3 || a.ErrorCount != 1 {
+		t.Errorf("cand-a counts = %+v", a)
+	}
+	if a.AvgLiveLatencyMs != 200 || a.AvgCandidateLatencyMs != 300 || a.AvgCandidateTokens != 100 {
+		t.Errorf("cand-a latency/tokens = %v/%v/%v, want 200/300/100", a.AvgLiveLatencyMs, a.AvgCandidateLatencyMs, a.AvgCandidateTokens)
+	}
+	if a.AvgAgreement != 0.6 {
+		t.Errorf("cand-a agreement = %v, want 0.6", a.AvgAgreement)
+	}
+	if a.AvgLiveGroundedness == nil || *a.AvgLiveGroundedness != 0.8 {
+		t.Errorf("cand-a live groundedness = %v, want 0.8", a.AvgLiveGroundedness)
+	}
+	if a.AvgCandidateGroundedness == nil || *a.AvgCandidateGroundedness != 0.6 {
+		t.Errorf("cand-a candidate groundedness = %v, want 0.6", a.AvgCandidateGroundedness)
+	}
+
+	b := items[1]
+	if b.SampleCount != 1 || b.ErrorCount != 1 || b.AvgCandidateLatencyMs != 0 || b.AvgCandidateGroundedness != nil {
+		t.Errorf("cand-b = %+v", b)
+	}
+}
@@ -0,0 +1,290 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Malowking/kbgo/internal/logic/persona"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 回答风格
+const (
+	StyleConcise  = "concise"
+	StyleDetailed = "detailed"
+)
+
+// 输出格式
+const (
+	FormatMarkdown = "markdown"
+	FormatPlain    = "plain"
+	FormatBullet   = "bullet"
+	FormatTable    = "table"
+)
+
+// ResponseStyle 请求级的回答风格、输出格式和目标语言控制，以及对话使用的人设
+type ResponseStyle struct {
+	Style    string             // concise / detailed
+	Format   string             // markdown / plain / bullet / table
+	Language string             // 目标语言，如 zh、en、ja 或自然语言描述
+	Persona  *gormModel.Persona // 人设预设（语气、正式程度、表情符号、署名）
+}
+
+// NewResponseStyle 创建回答风格控制，全部为空时返回 nil
+func NewResponseStyle(style, format, language string) *ResponseStyle {
+	style = strings.ToLower(strings.TrimSpace(style))
+	format = strings.ToLower(strings.TrimSpace(format))
+	language = strings.TrimSpace(language)
+	if style == "" && format == "" && language == "" {
+		return nil
+	}
+	return &ResponseStyle{Style: style, Format: format, Language: language}
+}
+
+// WithPersona 附加人设，p 为 nil 时保持不变
+func (s *ResponseStyle) WithPersona(p *gormModel.Persona) *ResponseStyle {
+	if p == nil {
+		return s
+	}
+	if s == nil {
+		s = &ResponseStyle{}
+	}
+	s.Persona = p
+	return s
+}
+
+// languageNames 常见语言代码对应的提示词名称
+var languageNames = map[string]string{
+	"zh":    "简体中文",
+	"zh-cn": "简体中文",
+	"zh-tw": "繁體中文",
+	"en":    "English",
+	"ja":    "日本語",
+	"ko":    "한국어",
+	"fr":    "Français",
+	"de":    "Deutsch",
+	"es":    "Español",
+	"ru":    "Русский",
+}
+
+// PromptConstraints 将人设和风格控制转换为追加到 system 提示词中的说明和约束
+func (s *ResponseStyle) PromptConstraints() string {
+	if s == nil {
+		return ""
+	}
+	return persona.Prompt(s.Persona) + s.outputConstraints()
+}
+
+// outputConstraints 回答风格、输出格式和目标语言约束
+func (s *ResponseStyle) outputConstraints() string {
+
+	var rules []string
+	switch s.Style {
+	case StyleConcise:
+		rules = append(rules, "回答要简洁，直接给出结论，控制在3-5句话以内，不要铺垫和重复。")
+	case StyleDetailed:
+		rules = append(rules, "回答要详细全面，先给出结论，再分点展开说明依据、步骤和注意事项。")
+	}
+
+	switch s.Format {
+	case FormatMarkdown:
+		rules = append(rules, "使用 Markdown 格式输出，合理使用标题、列表和代码块。")
+	case FormatPlain:
+		rules = append(rules, "只输出纯文本，不要使用任何 Markdown 语法（如 #、*、-、`、| 等标记）。")
+	case FormatBullet:
+		rules = append(rules, "以无序列表形式输出，每一行以 \"- \" 开头，每条只表达一个要点。")
+	case FormatTable:
+		rules = append(rules, "以 Markdown 表格形式输出主要内容，表格需包含表头行和分隔行。")
+	}
+
+	if s.Language != "" {
+		rules = append(rules, fmt.Sprintf("无论问题和参考资料使用何种语言，都必须使用%s回答。", s.languageName()))
+	}
+
+	if len(rules) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n\n📌 输出约束（必须遵守）：\n")
+	for _, rule := range rules {
+		builder.WriteString("- ")
+		builder.WriteString(rule)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// Apply 生成后校验回答是否满足约束
+// 纯文本格式会直接清理 Markdown 标记，人设不允许表情符号时移除表情符号并补充署名，其余不满足的约束只记录告警
+func (s *ResponseStyle) Apply(ctx context.Context, answer string) string {
+	if s == nil || answer == "" {
+		return answer
+	}
+	if s.Format == FormatPlain {
+		answer = stripMarkdown(answer)
+	}
+	answer = persona.Finish(s.Persona, answer)
+	for _, violation := range s.Validate(answer) {
+		g.Log().Warningf(ctx, "Response style violation: %s", violation)
+	}
+	return answer
+}
+
+// Validate 检查回答是否满足格式和语言约束，返回不满足的约束描述
+func (s *ResponseStyle) Validate(answer string) []string {
+	if s == nil {
+		return nil
+	}
+
+	var violations []string
+	switch s.Format {
+	case FormatPlain:
+		if markdownPattern.MatchString(answer) || hasUnderscoreEmphasis(answer) {
+			violations = append(violations, "plain format requested but markdown syntax found")
+		}
+	case FormatBullet:
+		if !bulletPattern.MatchString(answer) {
+			violations = append(violations, "bullet format requested but no list items found")
+		}
+	case FormatTable:
+		if !tableSeparatorPattern.MatchString(answer) {
+			violations = append(violations, "table format requested but no markdown table found")
+		}
+	}
+
+	if s.Language != "" && !matchesLanguage(answer, s.Language) {
+		violations = append(violations, fmt.Sprintf("answer does not look like language %q", s.Language))
+	}
+	return violations
+}
+
+// languageName 获取语言在提示词中的显示名称
+func (s *ResponseStyle) languageName() string {
+	if name, ok := languageNames[strings.ToLower(s.Language)]; ok {
+		return name
+	}
+	return s.Language
+}
+
+var (
+	markdownPattern       = regexp.MustCompile("(?m)^\\s{0,3}(#{1,6}\\s|[-*+]\\s|>\\s|```)|\\*\\*[^*\n]+?\\*\\*|`[^`\n]+`")
+	bulletPattern         = regexp.MustCompile(`(?m)^\s*([-*+]|\d+[.)])\s+\S`)
+	tableSeparatorPattern = regexp.MustCompile(`(?m)^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)+\|?\s*$`)
+
+	stripHeadingPattern   = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	stripQuotePattern     = regexp.MustCompile(`(?m)^\s{0,3}>\s?`)
+	stripBulletPattern    = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	stripFencePattern     = regexp.MustCompile("(?m)^\\s*```.*$\n?")
+	codeSpanPattern       = regexp.MustCompile("`([^`\n]+)`")
+	boldPattern           = regexp.MustCompile(`\*\*([^*\n]+?)\*\*`)
+	underscoreBoldPattern = regexp.MustCompile(`__([^_\n]+?)__`)
+	identifierPattern     = regexp.MustCompile(`^\w+$`)
+)
+
+// stripMarkdown 移除常见 Markdown 标记，保留文本内容
+func stripMarkdown(text string) string {
+	text = stripFencePattern.ReplaceAllString(text, "")
+	text = stripHeadingPattern.ReplaceAllString(text, "")
+	text = stripQuotePattern.ReplaceAllString(text, "")
+	text = stripBulletPattern.ReplaceAllString(text, "$1")
+	text = stripInline(text)
+	return strings.TrimSpace(text)
+}
+
+// stripInline 移除成对的 **强调**、__强调__ 标记和行内代码的反引号，代码内容原样保留
+func stripInline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range codeSpanPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(stripEmphasis(text[last:loc[0]]))
+		b.WriteString(text[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	b.WriteString(stripEmphasis(text[last:]))
+	return b.String()
+}
+
+// stripEmphasis 移除成对的强调标记，__ 紧贴字母数字或包围标识符（如 __init__）时不是强调，保留原文
+func stripEmphasis(text string) string {
+	text = boldPattern.ReplaceAllString(text, "$1")
+	var b strings.Builder
+	last := 0
+	for _, loc := range underscoreBoldPattern.FindAllStringSubmatchIndex(text, -1) {
+		if !isUnderscoreEmphasis(text, loc) {
+			continue
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(text[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// hasUnderscoreEmphasis 文本中是否有 __强调__ 标记
+func hasUnderscoreEmphasis(text string) bool {
+	for _, loc := range underscoreBoldPattern.FindAllStringSubmatchIndex(text, -1) {
+		if isUnderscoreEmphasis(text, loc) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnderscoreEmphasis 判断 underscoreBoldPattern 的匹配是否为强调：前后不紧贴字母数字，且内容不是标识符
+func isUnderscoreEmphasis(text string, loc []int) bool {
+	if loc[0] > 0 && isWordByte(text[loc[0]-1]) || loc[1] < len(text) && isWordByte(text[loc[1]]) {
+		return false
+	}
+	return !identifierPattern.MatchString(text[loc[2]:loc[3]])
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// matchesLanguage 基于字符集粗略判断文本语言，无法判断的语言一律视为满足
+func matchesLanguage(text, language string) bool {
+	var han, kana, hangul, latin, cyrillic, total int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			continue
+		}
+		total++
+	}
+	if total == 0 {
+		return true
+	}
+
+	ratio := func(n int) float64 { return float64(n) / float64(total) }
+	switch strings.ToLower(language) {
+	case "zh", "zh-cn", "zh-tw":
+		return ratio(han) >= 0.3
+	case "ja":
+		return ratio(kana+han) >= 0.3 && kana > 0
+	case "ko":
+		return ratio(hangul) >= 0.3
+	case "ru":
+		return ratio(cyrillic) >= 0.3
+	case "en", "fr", "de", "es":
+		return ratio(latin) >= 0.6
+	default:
+		return true
+	}
+}
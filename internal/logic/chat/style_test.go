@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNewResponseStyle 测试空参数时不生成约束
+func TestNewResponseStyle(t *testing.T) {
+	if s := NewResponseStyle("", " ", ""); s != nil {
+		t.Errorf("expected nil style, got %+v", s)
+	}
+	if got := (*ResponseStyle)(nil).PromptConstraints(); got != "" {
+		t.Errorf("nil style should produce no constraints, got %q", got)
+	}
+
+	s := NewResponseStyle("Concise", "TABLE", "en")
+	constraints := s.PromptConstraints()
+	for _, want := range []string{"简洁", "表格", "English"} {
+		if !strings.Contains(constraints, want) {
+			t.Errorf("constraints missing %q: %s", want, constraints)
+		}
+	}
+}
+
+// TestResponseStyleValidate 测试生成后的格式与语言校验
+func TestResponseStyleValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		style      *ResponseStyle
+		answer     string
+		violations int
+	}{
+		{name: "Plain ok", style: &ResponseStyle{Format: FormatPlain}, answer: "这是一段纯文本。", violations: 0},
+		{name: "Plain with markdown", style: &ResponseStyle{Format: FormatPlain}, answer: "## 标题\n**加粗**", violations: 1},
+		{name: "Bullet ok", style: &ResponseStyle{Format: FormatBullet}, answer: "- 第一点\n- 第二点", violations: 0},
+		{name: "Bullet missing", style: &ResponseStyle{Format: FormatBullet}, answer: "没有列表", violations: 1},
+		{name: "Table ok", style: &ResponseStyle{Format: FormatTable}, answer: "| a | b |\n| --- | --- |\n| 1 | 2 |", violations: 0},
+		{name: "Table missing", style: &ResponseStyle{Format: FormatTable}, answer: "a, b", violations: 1},
+		{name: "Language ok", style: &ResponseStyle{Language: "en"}, answer: "The answer is 42.", violations: 0},
+		{name: "Language mismatch", style: &ResponseStyle{Language: "en"}, answer: "答案是四十二。", violations: 1},
+		{name: "Unknown language", style: &ResponseStyle{Language: "Klingon"}, answer: "答案", violations: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.style.Validate(tt.answer); len(got) != tt.violations {
+				t.Errorf("Validate() = %v, want %d violations", got, tt.violations)
+			}
+		})
+	}
+}
+
+// TestStripMarkdown 测试纯文本格式下的 Markdown 清理
+func TestStripMarkdown(t *testing.T) {
+	input := "# 标题\n\n- **要点一**\n- `code`\n> 引用\n```go\nfmt.Println()\n```"
+	got := stripMarkdown(input)
+	if markdownPattern.MatchString(got) {
+		t.Errorf("stripMarkdown() left markdown syntax: %q", got)
+	}
+	if !strings.Contains(got, "要点一") || !strings.Contains(got, "fmt.Println()") {
+		t.Errorf("stripMarkdown() dropped content: %q", got)
+	}
+}
+
+// TestStripMarkdownInline 测试只移除成对的强调标记，保留行内代码内容和 __init__ 这类标识符
+func TestStripMarkdownInline(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "**重点**和__同样重要__", want: "重点和同样重要"},
+		{input: "调用 `obj.__init__()` 初始化", want: "调用 obj.__init__() 初始化"},
+		{input: "Python 的 __init__ 方法", want: "Python 的 __init__ 方法"},
+		{input: "变量 my__var__name 不变", want: "变量 my__var__name 不变"},
+		{input: "2 ** 3 等于 8", want: "2 ** 3 等于 8"},
+		{input: "未闭合的 ` 反引号", want: "未闭合的 ` 反引号"},
+	}
+	for _, tt := range tests {
+		if got := stripMarkdown(tt.input); got != tt.want {
+			t.Errorf("stripMarkdown(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if violations := (&ResponseStyle{Format: FormatPlain}).Validate("Python 的 __init__ 方法"); len(violations) != 0 {
+		t.Errorf("Validate() = %v, want no violations for identifiers", violations)
+	}
+}
@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+// GenerateWithToolsStream 流式工具调用，与 GenerateWithTools 使用相同的请求参数
+// 流中依次返回：回答内容的增量消息（只有 Content）、工具调用的增量消息（ToolCalls 为本次收到的片段），
+// 最后一条消息是完整的响应：Content 为完整回答，ToolCalls 为合并后的工具调用，Extra 包含 latency_ms 和 tokens_used，
+// 调用方以 Extra 不为空识别最后一条消息
+func (x *Chat) GenerateWithToolsStream(ctx context.Context, modelID string, messages []*schema.Message, tools []*schema.ToolInfo) (*schema.StreamReader[*schema.Message], error) {
+	modelService, chatParams, err := toolCallParams(ctx, modelID, messages, tools)
+	if err != nil {
+		return nil, err
+	}
+
+	// 记录开始时间
+	start := time.Now()
+
+	// 调用模型服务流式接口
+	stream, err := modelService.ChatCompletionStream(ctx, chatParams)
+	if err != nil {
+		return nil, fmt.Errorf("API调用失败: %w", err)
+	}
+
+	streamReader, streamWriter := schema.Pipe[*schema.Message](10)
+	go func() {
+		defer streamWriter.Close()
+		defer stream.Close()
+
+		var content strings.Builder
+		var toolCalls []schema.ToolCall
+		var tokens int
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				g.Log().Errorf(ctx, "tool call stream receive error: %v", err)
+				streamWriter.Send(&schema.Message{Role: schema.Assistant}, err)
+				return
+			}
+			if response.Usage != nil {
+				tokens = response.Usage.TotalTokens
+			}
+			if len(response.Choices) == 0 {
+				continue
+			}
+
+			delta := response.Choices[0].Delta
+			if delta.Content != "" {
+				content.WriteString(delta.Content)
+				if closed := streamWriter.Send(&schema.Message{Role: schema.Assistant, Content: delta.Content}, nil); closed {
+					return
+				}
+			}
+			if len(delta.ToolCalls) > 0 {
+				fragments := make([]schema.ToolCall, 0, len(delta.ToolCalls))
+				for _, tc := range delta.ToolCalls {
+					toolCalls = mergeToolCallDelta(toolCalls, tc)
+					fragments = append(fragments, toSchemaToolCall(tc))
+				}
+				if closed := streamWriter.Send(&schema.Message{Role: schema.Assistant, ToolCalls: fragments}, nil); closed {
+					return
+				}
+			}
+		}
+
+		streamWriter.Send(&schema.Message{
+			Role:      schema.Assistant,
+			Content:   content.String(),
+			ToolCalls: toolCalls,
+			Extra: map[string]any{
+				"latency_ms":  time.Since(start).Milliseconds(),
+				"tokens_used": usageOrEstimate(tokens, chatParams, content.String()+toolCallText(toolCalls)),
+			},
+		}, nil)
+	}()
+
+	return streamReader, nil
+}
+
+// toolCallText 拼接工具调用的函数名和参数，用于估算输出 token 数
+func toolCallText(calls []schema.ToolCall) string {
+	var b strings.Builder
+	for _, call := range calls {
+		b.WriteString(call.Function.Name)
+		b.WriteString(call.Function.Arguments)
+	}
+	return b.String()
+}
+
+// mergeToolCallDelta 将流式返回的工具调用片段合并到已收到的工具调用中
+// 片段按 Index 归属，没有 Index 的服务商以新的 ID 表示开始下一个工具调用，否则追加到最后一个工具调用
+func mergeToolCallDelta(calls []schema.ToolCall, delta openai.ToolCall) []schema.ToolCall {
+	pos := -1
+	if delta.Index != nil {
+		for i := range calls {
+			if calls[i].Index != nil && *calls[i].Index == *delta.Index {
+				pos = i
+				break
+			}
+		}
+	} else if len(calls) > 0 && (delta.ID == "" || delta.ID == calls[len(calls)-1].ID) {
+		pos = len(calls) - 1
+	}
+	if pos < 0 {
+		return append(calls, toSchemaToolCall(delta))
+	}
+
+	call := &calls[pos]
+	if call.ID == "" {
+		call.ID = delta.ID
+	}
+	if call.Type == "" {
+		call.Type = string(delta.Type)
+	}
+	// 个别服务商在每个片段中重复返回完整的函数名
+	if delta.Function.Name != call.Function.Name {
+		call.Function.Name += delta.Function.Name
+	}
+	call.Function.Arguments += delta.Function.Arguments
+	return calls
+}
+
+// toSchemaToolCall 转换 OpenAI 工具调用
+func toSchemaToolCall(tc openai.ToolCall) schema.ToolCall {
+	call := schema.ToolCall{
+		ID:   tc.ID,
+		Type: string(tc.Type),
+		Function: schema.FunctionCall{
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		},
+	}
+	if tc.Index != nil {
+		index := *tc.Index
+		call.Index = &index
+	}
+	return call
+}
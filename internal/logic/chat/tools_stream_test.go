@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestMergeToolCallDelta(t *testing.T) {
+	index := func(i int) *int { return &i }
+	tests := []struct {
+		name   string
+		deltas []openai.ToolCall
+		want   []string // 合并后每个工具调用的 "ID name arguments"
+	}{
+		{
+			name: "fragments by index",
+			deltas: []openai.ToolCall{
+				{Index: index(0), ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "search"}},
+				{Index: index(1), ID: "call_2", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "query"}},
+				{Index: index(0), Function: openai.FunctionCall{Arguments: `{"q":`}},
+				{Index: index(1), Function: openai.FunctionCall{Arguments: `{}`}},
+				{Index: index(0), Function: openai.FunctionCall{Arguments: `"kb"}`}},
+			},
+			want: []string{`call_1 search {"q":"kb"}`, `call_2 query {}`},
+		},
+		{
+			name: "without index",
+			deltas: []openai.ToolCall{
+				{ID: "call_1", Function: openai.FunctionCall{Name: "search", Arguments: `{"q"`}},
+				{Function: openai.FunctionCall{Arguments: `:"kb"}`}},
+				{ID: "call_2", Function: openai.FunctionCall{Name: "query", Arguments: `{}`}},
+			},
+			want: []string{`call_1 search {"q":"kb"}`, `call_2 query {}`},
+		},
+		{
+			name: "repeated function name",
+			deltas: []openai.ToolCall{
+				{Index: index(0), ID: "call_1", Function: openai.FunctionCall{Name: "search"}},
+				{Index: index(0), Function: openai.FunctionCall{Name: "search", Arguments: `{}`}},
+			},
+			want: []string{`call_1 search {}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []schema.ToolCall
+			for _, delta := range tt.deltas {
+				calls = mergeToolCallDelta(calls, delta)
+			}
+			if len(calls) != len(tt.want) {
+				t.Fatalf("got %d tool calls, want %d", len(calls), len(tt.want))
+			}
+			for i, call := range calls {
+				got := call.ID + " " + call.Function.Name + " " + call.Function.Arguments
+				if got != tt.want[i] {
+					t.Errorf("tool call %d = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
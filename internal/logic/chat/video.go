@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/Malowking/kbgo/core/media"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultVideoFrames      = 8
+	videoTranscriptFileName = "transcript.txt"
+)
+
+// videoCapability 模型处理视频附件的方式：抽取的关键帧数量和是否附带语音转写
+// 模型配置的 videoFrames/videoTranscript 优先；未配置时多模态模型抽取 multimodal.video.frames 帧，其他模型只发送语音转写
+func videoCapability(ctx context.Context, mc *coreModel.ModelConfig) (frames int, transcript bool) {
+	params := parseModelParams(mc.Extra)
+	switch {
+	case params.VideoFrames != nil:
+		frames = max(*params.VideoFrames, 0)
+	case mc.Type == coreModel.ModelTypeMultimodal:
+		frames = g.Cfg().MustGet(ctx, "multimodal.video.frames", defaultVideoFrames).Int()
+	}
+	if params.VideoTranscript != nil {
+		transcript = *params.VideoTranscript
+	} else {
+		transcript = g.Cfg().MustGet(ctx, "multimodal.video.transcript", true).Bool()
+	}
+	return frames, transcript
+}
+
+// buildVideoParts 把视频附件转换为按时间均匀抽取的关键帧图片和语音转写文本，代替整段视频 base64 内联（大多数模型接口不接受视频）
+// 未安装 ffmpeg 或模型不需要画面和转写时，只发送文件名
+func buildVideoParts(ctx context.Context, file *common.MultimodalFile, mc *coreModel.ModelConfig) []schema.MessageInputPart {
+	placeholder := []schema.MessageInputPart{{
+		Type: schema.ChatMessagePartTypeText,
+		Text: fmt.Sprintf("[视频: %s]", file.FileName),
+	}}
+	frames, withTranscript := videoCapability(ctx, mc)
+	if frames == 0 && !withTranscript {
+		return placeholder
+	}
+
+	sample, err := media.SampleVideo(ctx, file.FilePath, config.VideoCacheDir(ctx), frames)
+	if err != nil {
+		g.Log().Warningf(ctx, "视频抽帧失败，只发送文件名 %s: %v", file.FileName, err)
+		return placeholder
+	}
+
+	header := fmt.Sprintf("[视频: %s", file.FileName)
+	if sample.Duration > 0 {
+		header += "，时长 " + formatTimestamp(sample.Duration)
+	}
+	if len(sample.Frames) > 0 {
+		header += fmt.Sprintf("，以下为按时间均匀抽取的 %d 个关键帧", len(sample.Frames))
+	}
+	parts := []schema.MessageInputPart{{Type: schema.ChatMessagePartTypeText, Text: header + "]"}}
+
+	for i, frame := range sample.Frames {
+		data, mimeType, err := media.LoadImage(ctx, frame)
+		if err != nil {
+			g.Log().Warningf(ctx, "读取视频帧失败 %s: %v", frame, err)
+			continue
+		}
+		base64Data := base64.StdEncoding.EncodeToString(data)
+		framePath := frame
+		parts = append(parts,
+			schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeText,
+				Text: "画面 " + formatTimestamp(sample.Times[i]),
+			},
+			schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeImageURL,
+				Image: &schema.MessageInputImage{
+					MessagePartCommon: schema.MessagePartCommon{
+						URL:        &framePath, // 帧图片路径，会话历史直接引用
+						Base64Data: &base64Data,
+						MIMEType:   mimeType,
+					},
+				},
+			})
+	}
+
+	if withTranscript {
+		transcript, err := transcribeVideo(ctx, sample)
+		if err != nil {
+			g.Log().Warningf(ctx, "视频语音转写失败 %s: %v", file.FileName, err)
+		} else if transcript != "" {
+			parts = append(parts, schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeText,
+				Text: "[视频语音转写]\n" + transcript,
+			})
+		}
+	}
+	return parts
+}
+
+// transcribeVideo 提取视频音轨并调用 multimodal.video.asrModelID 配置的语音识别模型（OpenAI 兼容 /audio/transcriptions 接口）转写
+// 转写结果缓存在抽帧目录，未配置语音识别模型时返回空字符串
+func transcribeVideo(ctx context.Context, sample *media.VideoSample) (string, error) {
+	cached := filepath.Join(sample.Dir, videoTranscriptFileName)
+	if data, err := os.ReadFile(cached); err == nil {
+		return string(data), nil
+	}
+
+	asrModelID := g.Cfg().MustGet(ctx, "multimodal.video.asrModelID", "").String()
+	if asrModelID == "" {
+		return "", nil
+	}
+	asr := coreModel.Registry.Get(asrModelID)
+	if asr == nil || asr.Client == nil {
+		return "", fmt.Errorf("asr model not found: %s", asrModelID)
+	}
+
+	audio, err := sample.Audio(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := asr.Client.CreateTranscription(ctx, openai.AudioRequest{
+		Model:    asr.Name,
+		FilePath: audio,
+	})
+	if err != nil {
+		return "", err
+	}
+	transcript := strings.TrimSpace(resp.Text)
+	if err = os.WriteFile(cached, []byte(transcript), 0644); err != nil {
+		g.Log().Warningf(ctx, "缓存视频语音转写失败 %s: %v", cached, err)
+	}
+	return transcript, nil
+}
+
+// formatTimestamp 将秒数格式化为 mm:ss 或 h:mm:ss
+func formatTimestamp(seconds float64) string {
+	total := int(seconds + 0.5)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
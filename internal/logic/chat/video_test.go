@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+)
+
+func TestVideoCapability(t *testing.T) {
+	mc := &coreModel.ModelConfig{
+		Type:  coreModel.ModelTypeMultimodal,
+		Extra: map[string]any{"videoFrames": float64(4), "videoTranscript": false},
+	}
+	frames, transcript := videoCapability(context.Background(), mc)
+	if frames != 4 || transcript {
+		t.Errorf("videoCapability() = %d, %v, want 4, false", frames, transcript)
+	}
+
+	mc.Extra = map[string]any{"videoFrames": float64(-1), "videoTranscript": true}
+	if frames, transcript = videoCapability(context.Background(), mc); frames != 0 || !transcript {
+		t.Errorf("videoCapability() = %d, %v, want 0, true", frames, transcript)
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	for seconds, want := range map[float64]string{
+		0:      "00:00",
+		75.4:   "01:15",
+		3725.6: "1:02:06",
+	} {
+		if got := formatTimestamp(seconds); got != want {
+			t.Errorf("formatTimestamp(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Package chatcompletions OpenAI 兼容的聊天接口（/v1/chat/completions）：携带工具定义或工具调用消息的请求直接调用模型，
+// tool_calls 原样返回给调用方执行；其他请求转换为对话请求经过对话流程，回答按 OpenAI 的响应和流式增量格式返回
+package chatcompletions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/tokenizer"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/completion"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/Malowking/kbgo/internal/logic/usage"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/bytedance/sonic"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+const (
+	objectCompletion = "chat.completion"
+	objectChunk      = "chat.completion.chunk"
+)
+
+// Passthrough 请求是否直接调用模型：携带工具定义或工具调用消息时，工具由调用方执行，不经过对话流程
+func Passthrough(req *v1.ChatCompletionsReq) bool {
+	if len(req.Tools) > 0 {
+		return true
+	}
+	for _, msg := range req.Messages {
+		if msg.Role == string(schema.Tool) || len(msg.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete 直接调用模型（非流式），计入项目配额和用户用量
+func Complete(ctx context.Context, req *v1.ChatCompletionsReq) (*v1.ChatCompletionRes, error) {
+	ctx, completionReq, err := prepareDirect(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := completion.Complete(ctx, completionReq)
+	if err != nil {
+		return nil, err
+	}
+	quota.RecordTokens(ctx, res.Usage.TotalTokens)
+	recordUsage(ctx, completionReq.ModelID, res.Usage.TotalTokens)
+	return res, nil
+}
+
+// Stream 直接调用模型并按 OpenAI 格式输出 SSE，计入项目配额和用户用量；开始输出后的错误以 data 事件返回
+func Stream(ctx context.Context, req *v1.ChatCompletionsReq) error {
+	ctx, completionReq, err := prepareDirect(ctx, req)
+	if err != nil {
+		return err
+	}
+	httpReq := g.RequestFromCtx(ctx)
+	resp := httpReq.Response
+	defer common.SetStreamFormat(resp, NewChunkFormat(nil, completionReq.ModelID, IncludeUsage(req)))()
+	defer common.StartSSE(ctx, resp)()
+
+	err = completion.Stream(ctx, completionReq, func(chunk *v1.ChatCompletionChunk) error {
+		if chunk.Usage != nil {
+			quota.RecordTokens(ctx, chunk.Usage.TotalTokens)
+			recordUsage(ctx, completionReq.ModelID, chunk.Usage.TotalTokens)
+			if !IncludeUsage(req) {
+				chunk.Usage = nil
+				if len(chunk.Choices) == 0 {
+					return nil
+				}
+			}
+		}
+		data, err := sonic.MarshalString(chunk)
+		if err != nil {
+			return err
+		}
+		common.WriteSSEEvent(resp, "data", data)
+		return nil
+	})
+	if err != nil {
+		common.WriteSSEError(resp, err)
+		return nil
+	}
+	common.WriteSSEEvent(resp, "data", "[DONE]")
+	return nil
+}
+
+// prepareDirect 解析模型并校验项目配额和用户月度 token 配额，转换为模型调用请求
+func prepareDirect(ctx context.Context, req *v1.ChatCompletionsReq) (context.Context, *v1.ChatCompletionReq, error) {
+	modelRef := req.Model
+	p, err := project.Use(ctx, req.ProjectID, req.KnowledgeID)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if p != nil {
+		if modelRef == "" {
+			modelRef = project.ParseSettings(p).ModelID
+		}
+		ctx = project.WithContext(ctx, p.ID)
+	}
+	mc, err := resolveModel(modelRef)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if err = quota.CheckChat(ctx); err != nil {
+		return ctx, nil, err
+	}
+	if err = usage.CheckUser(ctx, identity.UserID(ctx)); err != nil {
+		return ctx, nil, err
+	}
+	return ctx, &v1.ChatCompletionReq{
+		ModelID:     mc.ModelID,
+		Messages:    req.Messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Stream:      req.Stream,
+		Tools:       req.Tools,
+		ToolChoice:  req.ToolChoice,
+	}, nil
+}
+
+// recordUsage 直接调用模型不保存会话消息，token 消耗单独计入调用用户的用量
+func recordUsage(ctx context.Context, modelID string, tokens int) {
+	modelName := ""
+	if mc := model.Registry.Get(modelID); mc != nil {
+		modelName = mc.Name
+	}
+	usage.RecordDirect(ctx, modelID, modelName, tokens)
+}
+
+// resolveModel 按模型ID或名称查找可对话的模型（LLM 或多模态模型）
+func resolveModel(ref string) (*model.ModelConfig, error) {
+	if ref == "" {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, "model is required")
+	}
+	mc := model.Registry.Get(ref)
+	if mc == nil {
+		for _, candidate := range model.Registry.List() {
+			if candidate.Name == ref && chatModel(candidate) {
+				mc = candidate
+				break
+			}
+		}
+	}
+	if mc == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "model not found: %s", ref)
+	}
+	if !chatModel(mc) {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "model %s is not a chat model, type: %s", ref, mc.Type)
+	}
+	return mc, nil
+}
+
+func chatModel(mc *model.ModelConfig) bool {
+	return mc.Type == model.ModelTypeLLM || mc.Type == model.ModelTypeMultimodal
+}
+
+// earlierKey 上下文中等待导入新会话的此前消息
+type earlierKey struct{}
+
+// NewChatReq 把请求转换为对话请求，以最后一条用户消息为问题，已认证的请求以认证用户为提问用户。
+// 未指定会话时分配新的会话ID，此前的 system、user、assistant 消息记录在返回的上下文中，
+// 由对话流程在权限和配额校验通过后通过 ImportHistory 导入，使无状态的 OpenAI 客户端也能多轮对话
+func NewChatReq(ctx context.Context, req *v1.ChatCompletionsReq) (context.Context, *v1.ChatReq, error) {
+	question, earlier, err := splitMessages(req.Messages)
+	if err != nil {
+		return ctx, nil, err
+	}
+	chatReq := &v1.ChatReq{
+		ConvID:          req.ConversationID,
+		UserID:          identity.Resolve(ctx, req.User),
+		Question:        question,
+		KnowledgeId:     req.KnowledgeID,
+		EnableRetriever: req.KnowledgeID != "" || req.RetrievalView != "",
+		UseMCP:          req.UseMCP,
+		Stream:          req.Stream,
+		PersonaID:       req.PersonaID,
+		ProjectID:       req.ProjectID,
+		RetrievalView:   req.RetrievalView,
+	}
+	if req.Model != "" {
+		mc, err := resolveModel(req.Model)
+		if err != nil {
+			return ctx, nil, err
+		}
+		chatReq.ModelID = mc.ModelID
+	}
+	if chatReq.ConvID != "" {
+		return ctx, chatReq, nil
+	}
+
+	chatReq.ConvID = uuid.NewString()
+	if len(earlier) > 0 {
+		ctx = context.WithValue(ctx, earlierKey{}, earlier)
+	}
+	return ctx, chatReq, nil
+}
+
+// ImportHistory 把 NewChatReq 记录的此前消息导入新会话，消息发送者为上下文中的提问用户；没有待导入的消息时不做任何事。
+// 对话流程在权限和配额校验通过后调用，被拒绝的请求不会创建会话或保存消息
+func ImportHistory(ctx context.Context, convID string) error {
+	earlier, _ := ctx.Value(earlierKey{}).([]*schema.Message)
+	if len(earlier) == 0 {
+		return nil
+	}
+	manager := history.NewManager()
+	for _, msg := range earlier {
+		if err := manager.SaveMessage(ctx, msg, convID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitMessages 拆分出最后一条用户消息（问题）和此前需要导入会话历史的消息
+func splitMessages(messages []v1.ChatCompletionMessage) (string, []*schema.Message, error) {
+	if len(messages) == 0 {
+		return "", nil, gerror.NewCode(gcode.CodeInvalidParameter, "messages is required")
+	}
+	last := messages[len(messages)-1]
+	if last.Role != string(schema.User) || strings.TrimSpace(last.Content) == "" {
+		return "", nil, gerror.NewCode(gcode.CodeInvalidParameter, "the last message must be a non-empty user message")
+	}
+	var earlier []*schema.Message
+	for _, msg := range messages[:len(messages)-1] {
+		switch schema.RoleType(msg.Role) {
+		case schema.System, schema.User, schema.Assistant:
+			if msg.Content != "" {
+				earlier = append(earlier, &schema.Message{Role: schema.RoleType(msg.Role), Content: msg.Content})
+			}
+		}
+	}
+	return last.Content, earlier, nil
+}
+
+// ToResponse 把对话流程的回答转换为 OpenAI 响应，用量按模型分词器估算
+func ToResponse(chatReq *v1.ChatReq, res *v1.ChatRes) *v1.ChatCompletionRes {
+	name := modelName(chatReq.ModelID)
+	prompt, answer := tokenizer.Count(name, chatReq.Question), tokenizer.Count(name, res.Answer)
+	return &v1.ChatCompletionRes{
+		ID:      newID(),
+		Object:  objectCompletion,
+		Created: time.Now().Unix(),
+		Model:   name,
+		Choices: []v1.ChatCompletionChoice{{
+			Message: v1.ChatCompletionMessage{
+				Role:    string(schema.Assistant),
+				Content: res.Answer,
+			},
+			FinishReason: "stop",
+		}},
+		Usage: v1.ChatCompletionUsage{
+			PromptTokens:     prompt,
+			CompletionTokens: answer,
+			TotalTokens:      prompt + answer,
+		},
+		ConversationID: chatReq.ConvID,
+		References:     res.References,
+	}
+}
+
+// ChunkFormat 以 chat.completion.chunk 格式输出回答增量（实现 common.StreamFormat）。
+// 经过对话流程时模型在流程中才确定，chatReq 不为空时输出时读取 chatReq.ModelID
+type ChunkFormat struct {
+	id           string
+	created      int64
+	chatReq      *v1.ChatReq
+	modelID      string
+	includeUsage bool
+	first        bool
+	question     string
+	answer       strings.Builder
+}
+
+// NewChunkFormat 创建增量格式，includeUsage 为 true 时结束增量携带（估算的）用量
+func NewChunkFormat(chatReq *v1.ChatReq, modelID string, includeUsage bool) *ChunkFormat {
+	f := &ChunkFormat{id: newID(), created: time.Now().Unix(), chatReq: chatReq, modelID: modelID, includeUsage: includeUsage, first: true}
+	if chatReq != nil {
+		f.question = chatReq.Question
+	}
+	return f
+}
+
+// Chunk 回答增量，第一个增量携带 role
+func (f *ChunkFormat) Chunk(content, reasoning string) string {
+	f.answer.WriteString(content)
+	delta := map[string]any{}
+	if f.first {
+		delta["role"] = string(schema.Assistant)
+		f.first = false
+	}
+	if content != "" {
+		delta["content"] = content
+	}
+	if reasoning != "" {
+		delta["reasoning_content"] = reasoning
+	}
+	return f.marshal(delta, nil, nil)
+}
+
+// Finish 结束增量
+func (f *ChunkFormat) Finish() string {
+	var usage *v1.ChatCompletionUsage
+	if f.includeUsage {
+		name := f.modelName()
+		prompt, answer := tokenizer.Count(name, f.question), tokenizer.Count(name, f.answer.String())
+		usage = &v1.ChatCompletionUsage{PromptTokens: prompt, CompletionTokens: answer, TotalTokens: prompt + answer}
+	}
+	reason := "stop"
+	return f.marshal(map[string]any{}, &reason, usage)
+}
+
+// Error OpenAI 格式的错误，OpenAI SDK 收到后抛出 APIError
+func (f *ChunkFormat) Error(err error) string {
+	data, _ := sonic.MarshalString(map[string]any{
+		"error": map[string]any{"message": err.Error(), "type": "server_error"},
+	})
+	return data
+}
+
+func (f *ChunkFormat) marshal(delta map[string]any, finishReason *string, usage *v1.ChatCompletionUsage) string {
+	chunk := map[string]any{
+		"id":      f.id,
+		"object":  objectChunk,
+		"created": f.created,
+		"model":   f.modelName(),
+		"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+	}
+	if usage != nil {
+		chunk["usage"] = usage
+	}
+	if f.chatReq != nil {
+		chunk["conversation_id"] = f.chatReq.ConvID
+	}
+	data, _ := sonic.MarshalString(chunk)
+	return data
+}
+
+func (f *ChunkFormat) modelName() string {
+	if f.chatReq != nil {
+		return modelName(f.chatReq.ModelID)
+	}
+	return modelName(f.modelID)
+}
+
+// modelName 模型名称，模型未注册时返回模型ID
+func modelName(modelID string) string {
+	if mc := model.Registry.Get(modelID); mc != nil {
+		return mc.Name
+	}
+	return modelID
+}
+
+// IncludeUsage 流式响应是否需要返回用量（stream_options.include_usage）
+func IncludeUsage(req *v1.ChatCompletionsReq) bool {
+	return req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+}
+
+func newID() string {
+	return fmt.Sprintf("chatcmpl-%s", strings.ReplaceAll(uuid.NewString(), "-", ""))
+}
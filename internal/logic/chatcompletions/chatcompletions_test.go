@@ -0,0 +1,114 @@
+package chatcompletions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/bytedance/sonic"
+)
+
+func TestPassthrough(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *v1.ChatCompletionsReq
+		want bool
+	}{
+		{name: "plain chat", req: &v1.ChatCompletionsReq{Messages: []v1.ChatCompletionMessage{{Role: "user", Content: "hi"}}}},
+		{name: "tools", req: &v1.ChatCompletionsReq{Tools: []v1.ChatCompletionTool{{Type: "function"}}}, want: true},
+		{name: "tool result", req: &v1.ChatCompletionsReq{Messages: []v1.ChatCompletionMessage{
+			{Role: "assistant", ToolCalls: []v1.ChatCompletionToolCall{{ID: "call_1"}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "42"},
+		}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Passthrough(tt.req); got != tt.want {
+				t.Errorf("Passthrough() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessages(t *testing.T) {
+	question, earlier, err := splitMessages([]v1.ChatCompletionMessage{
+		{Role: "system", Content: "你是客服"},
+		{Role: "user", Content: "保修多久？"},
+		{Role: "assistant", Content: "两年"},
+		{Role: "user", Content: "怎么申请？"},
+	})
+	if err != nil {
+		t.Fatalf("splitMessages() error = %v", err)
+	}
+	if question != "怎么申请？" || len(earlier) != 3 || earlier[0].Role != schema.System || earlier[2].Content != "两年" {
+		t.Errorf("splitMessages() = %q, %v", question, earlier)
+	}
+
+	if _, _, err = splitMessages([]v1.ChatCompletionMessage{{Role: "assistant", Content: "hi"}}); err == nil {
+		t.Errorf("splitMessages() must reject a trailing assistant message")
+	}
+	if _, _, err = splitMessages(nil); err == nil {
+		t.Errorf("splitMessages() must reject empty messages")
+	}
+}
+
+func TestChunkFormat(t *testing.T) {
+	f := NewChunkFormat(&v1.ChatReq{ModelID: "unregistered", Question: "hi"}, "", true)
+
+	var first, second, finish map[string]any
+	if err := sonic.UnmarshalString(f.Chunk("Hel", ""), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := sonic.UnmarshalString(f.Chunk("lo", ""), &second); err != nil {
+		t.Fatal(err)
+	}
+	if err := sonic.UnmarshalString(f.Finish(), &finish); err != nil {
+		t.Fatal(err)
+	}
+	if first["object"] != objectChunk || first["model"] != "unregistered" || first["id"] != finish["id"] {
+		t.Errorf("chunk header = %v", first)
+	}
+	delta := first["choices"].([]any)[0].(map[string]any)["delta"].(map[string]any)
+	if delta["role"] != "assistant" || delta["content"] != "Hel" {
+		t.Errorf("first delta = %v", delta)
+	}
+	if delta := second["choices"].([]any)[0].(map[string]any)["delta"].(map[string]any); delta["role"] != nil {
+		t.Errorf("only the first delta carries the role, got %v", delta)
+	}
+	if choice := finish["choices"].([]any)[0].(map[string]any); choice["finish_reason"] != "stop" {
+		t.Errorf("finish choice = %v", choice)
+	}
+	if usage, ok := finish["usage"].(map[string]any); !ok || usage["completion_tokens"].(float64) <= 0 {
+		t.Errorf("finish usage = %v", finish["usage"])
+	}
+
+	if got := f.Error(errors.New("boom")); !strings.Contains(got, `"message":"boom"`) {
+		t.Errorf("Error() = %s", got)
+	}
+}
+
+// TestNewChatReqDefersImport 测试新会话的此前消息只记录在上下文中，等待校验通过后导入；提问用户以认证用户为准
+func TestNewChatReqDefersImport(t *testing.T) {
+	ctx := identity.WithUser(context.Background(), "alice")
+	ctx, chatReq, err := NewChatReq(ctx, &v1.ChatCompletionsReq{
+		User: "mallory",
+		Messages: []v1.ChatCompletionMessage{
+			{Role: "system", Content: "be brief"},
+			{Role: "assistant", Content: "hello"},
+			{Role: "user", Content: "question"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewChatReq() error = %v", err)
+	}
+	if chatReq.UserID != "alice" || chatReq.ConvID == "" || chatReq.Question != "question" {
+		t.Errorf("NewChatReq() = %+v", chatReq)
+	}
+	if earlier, _ := ctx.Value(earlierKey{}).([]*schema.Message); len(earlier) != 2 {
+		t.Errorf("pending history = %v, want 2 messages", earlier)
+	}
+}
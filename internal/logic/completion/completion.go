@@ -0,0 +1,230 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Complete OpenAI 风格聊天（非流式），HTTP 和 gRPC 接口共用
+func Complete(ctx context.Context, req *v1.ChatCompletionReq) (*v1.ChatCompletionRes, error) {
+	mc, err := getModel(ctx, req.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	chatReq := BuildRequest(mc.Name, req)
+	chatReq.Stream = false
+	resp, err := mc.Client.CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to call model: %v", err)
+		return nil, err
+	}
+	return ToResponse(resp), nil
+}
+
+// Stream OpenAI 风格流式聊天，每个增量通过 send 返回，send 出错时停止
+func Stream(ctx context.Context, req *v1.ChatCompletionReq, send func(*v1.ChatCompletionChunk) error) error {
+	mc, err := getModel(ctx, req.ModelID)
+	if err != nil {
+		return err
+	}
+
+	chatReq := BuildRequest(mc.Name, req)
+	chatReq.Stream = true
+	chatReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := mc.Client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to call model: %v", err)
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			g.Log().Errorf(ctx, "stream receive error: %v", err)
+			return err
+		}
+		if err = send(ToChunk(resp)); err != nil {
+			return err
+		}
+	}
+}
+
+// getModel 获取模型配置
+func getModel(ctx context.Context, modelID string) (*model.ModelConfig, error) {
+	mc := model.Registry.Get(modelID)
+	if mc == nil {
+		g.Log().Errorf(ctx, "Model not found: %s", modelID)
+		return nil, gerror.Newf("Model not found: %s", modelID)
+	}
+	return mc, nil
+}
+
+// BuildRequest 将接口请求转换为 OpenAI 请求，未设置的参数使用默认值
+func BuildRequest(modelName string, req *v1.ChatCompletionReq) openai.ChatCompletionRequest {
+	// 设置默认值
+	maxTokens, temperature, topP := req.MaxTokens, req.Temperature, req.TopP
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
+	if temperature == 0 {
+		temperature = 0.7
+	}
+	if topP == 0 {
+		topP = 0.9
+	}
+
+	// 转换消息格式
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+			ToolCalls:  toOpenAIToolCalls(msg.ToolCalls),
+		}
+	}
+
+	// 构建请求
+	chatReq := openai.ChatCompletionRequest{
+		Model:            modelName, // 使用模型名称而非UUID
+		Messages:         messages,
+		MaxTokens:        maxTokens,
+		Temperature:      temperature,
+		TopP:             topP,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		Stop:             req.Stop,
+		Stream:           req.Stream,
+	}
+
+	// 转换工具定义
+	if len(req.Tools) > 0 {
+		tools := make([]openai.Tool, len(req.Tools))
+		for i, tool := range req.Tools {
+			tools[i] = openai.Tool{
+				Type: openai.ToolType(tool.Type),
+				Function: &openai.FunctionDefinition{
+					Name:        tool.Function.Name,
+					Description: tool.Function.Description,
+					Parameters:  tool.Function.Parameters,
+				},
+			}
+		}
+		chatReq.Tools = tools
+		chatReq.ToolChoice = req.ToolChoice
+	}
+	return chatReq
+}
+
+// ToResponse 将 OpenAI 响应转换为接口响应
+func ToResponse(resp openai.ChatCompletionResponse) *v1.ChatCompletionRes {
+	choices := make([]v1.ChatCompletionChoice, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		choices[i] = v1.ChatCompletionChoice{
+			Index: choice.Index,
+			Message: v1.ChatCompletionMessage{
+				Role:      choice.Message.Role,
+				Content:   choice.Message.Content,
+				Name:      choice.Message.Name,
+				ToolCalls: fromOpenAIToolCalls(choice.Message.ToolCalls),
+			},
+			FinishReason: string(choice.FinishReason),
+		}
+	}
+
+	return &v1.ChatCompletionRes{
+		ID:      resp.ID,
+		Object:  resp.Object,
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: choices,
+		Usage: v1.ChatCompletionUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}
+}
+
+// ToChunk 将 OpenAI 流式响应转换为接口增量响应
+func ToChunk(resp openai.ChatCompletionStreamResponse) *v1.ChatCompletionChunk {
+	choices := make([]v1.ChatCompletionChunkChoice, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		choices[i] = v1.ChatCompletionChunkChoice{
+			Index: choice.Index,
+			Delta: v1.ChatCompletionMessage{
+				Role:      choice.Delta.Role,
+				Content:   choice.Delta.Content,
+				ToolCalls: fromOpenAIToolCalls(choice.Delta.ToolCalls),
+			},
+			FinishReason: string(choice.FinishReason),
+		}
+	}
+
+	chunk := &v1.ChatCompletionChunk{
+		ID:      resp.ID,
+		Object:  resp.Object,
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: choices,
+	}
+	if resp.Usage != nil {
+		chunk.Usage = &v1.ChatCompletionUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		}
+	}
+	return chunk
+}
+
+// toOpenAIToolCalls 转换工具调用
+func toOpenAIToolCalls(toolCalls []v1.ChatCompletionToolCall) []openai.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	result := make([]openai.ToolCall, len(toolCalls))
+	for i, tc := range toolCalls {
+		result[i] = openai.ToolCall{
+			ID:   tc.ID,
+			Type: openai.ToolType(tc.Type),
+			Function: openai.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		}
+	}
+	return result
+}
+
+// fromOpenAIToolCalls 转换工具调用
+func fromOpenAIToolCalls(toolCalls []openai.ToolCall) []v1.ChatCompletionToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	result := make([]v1.ChatCompletionToolCall, len(toolCalls))
+	for i, tc := range toolCalls {
+		result[i] = v1.ChatCompletionToolCall{
+			ID:   tc.ID,
+			Type: string(tc.Type),
+			Function: v1.ChatCompletionToolCallFunc{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		}
+	}
+	return result
+}
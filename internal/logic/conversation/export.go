@@ -0,0 +1,326 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/file_export"
+	"github.com/Malowking/kbgo/core/media"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/chat"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/model/entity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// exportExcerptRunes 引用摘录的最大字符数
+	exportExcerptRunes = 200
+	// exportToolPayloadRunes 工具参数和结果摘要的最大字符数
+	exportToolPayloadRunes = 300
+	// exportMaxMessages 最多导出的消息数
+	exportMaxMessages = 5000
+	// exportMaxToolCalls 最多导出的工具调用记录数
+	exportMaxToolCalls = 500
+)
+
+// ExportOptions 会话导出内容选项
+type ExportOptions struct {
+	Citations bool // 在助手回答后列出引用的参考资料
+	Images    bool // 嵌入消息中的图片
+	Tools     bool // 附加工具调用摘要
+}
+
+// roleNames 导出报告中的角色名称
+var roleNames = map[string]string{
+	"user":      "用户",
+	"assistant": "助手",
+}
+
+// Export 把会话整理为可导出为 PDF/DOCX 的文档：按时间顺序列出用户和助手消息，
+// 可选附带每条回答引用的参考资料、消息中的图片以及工具调用摘要
+func Export(ctx context.Context, convID string, opts ExportOptions) (*file_export.Document, error) {
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "conversation not found: %s", convID)
+	}
+
+	messages, _, err := dao.Message.ListByConvID(ctx, convID, 1, exportMaxMessages)
+	if err != nil {
+		return nil, err
+	}
+	msgIDs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		msgIDs = append(msgIDs, msg.MsgID)
+	}
+	contents, err := dao.MessageContent.ListByMsgIDs(ctx, msgIDs)
+	if err != nil {
+		return nil, err
+	}
+	contentsByMsg := make(map[string][]*gormModel.MessageContent, len(messages))
+	for _, content := range contents {
+		contentsByMsg[content.MsgID] = append(contentsByMsg[content.MsgID], content)
+	}
+
+	doc := &file_export.Document{Title: conv.Title}
+	if strings.TrimSpace(doc.Title) == "" {
+		doc.Title = "会话记录"
+	}
+	doc.Meta = append(doc.Meta, "会话ID："+conv.ConvID)
+	if conv.ModelName != "" {
+		doc.Meta = append(doc.Meta, "模型："+conv.ModelName)
+	}
+	if conv.CreateTime != nil {
+		doc.Meta = append(doc.Meta, "创建时间："+conv.CreateTime.Format(time.DateTime))
+	}
+	doc.Meta = append(doc.Meta, "导出时间："+time.Now().Format(time.DateTime))
+
+	refs := &referenceLoader{documents: map[string]entity.KnowledgeDocuments{}}
+	for _, msg := range messages {
+		role, ok := roleNames[msg.Role]
+		if !ok {
+			// 系统提示和工具结果消息不导出，工具调用在摘要中体现
+			continue
+		}
+		heading := role
+		if msg.CreateTime != nil {
+			heading += "  " + msg.CreateTime.Format(time.DateTime)
+		}
+		doc.Heading(heading)
+
+		text, images := splitContents(contentsByMsg[msg.MsgID])
+		var citations []string
+		if msg.Role == "assistant" && opts.Citations {
+			text, citations = refs.resolve(ctx, text, messageTrace(msg.Metadata))
+		}
+		if strings.TrimSpace(text) != "" {
+			doc.Paragraph(text)
+		}
+		if opts.Images {
+			for _, path := range images {
+				data, _, err := media.LoadImage(ctx, path)
+				if err != nil {
+					g.Log().Warningf(ctx, "导出会话图片失败 %s: %v", path, err)
+					data = nil
+				}
+				doc.Image(data, filepath.Base(path))
+			}
+		} else if len(images) > 0 {
+			doc.Note(fmt.Sprintf("[%d 张图片未导出]", len(images)))
+		}
+		if len(citations) > 0 {
+			doc.Note("参考资料：\n" + strings.Join(citations, "\n"))
+		}
+	}
+
+	if opts.Tools {
+		if err = appendToolSummary(ctx, doc, convID); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// splitContents 拼接消息的文本内容块，并返回图片文件路径
+func splitContents(contents []*gormModel.MessageContent) (string, []string) {
+	var texts, images []string
+	for _, content := range contents {
+		switch content.ContentType {
+		case "text":
+			if strings.TrimSpace(content.TextContent) != "" {
+				texts = append(texts, content.TextContent)
+			}
+		case "image_url":
+			if content.MediaURL != "" {
+				images = append(images, content.MediaURL)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n")), images
+}
+
+// messageTrace 读取助手消息记录的检索轨迹
+func messageTrace(raw gormModel.JSON) *analytics.RetrievalTrace {
+	if len(raw) == 0 {
+		return nil
+	}
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil
+	}
+	data, ok := metadata[analytics.RetrievalMetadataKey]
+	if !ok {
+		return nil
+	}
+	var trace analytics.RetrievalTrace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return nil
+	}
+	return &trace
+}
+
+// referenceLoader 根据检索轨迹加载参考分片，缓存分片所属文档
+type referenceLoader struct {
+	documents map[string]entity.KnowledgeDocuments
+}
+
+// resolve 把回答中的 [ref:分片ID] 标注替换为编号并列出对应参考资料；
+// 回答没有引用标注时（纯文本参考资料格式）按检索排名列出本轮使用的全部分片
+func (l *referenceLoader) resolve(ctx context.Context, answer string, trace *analytics.RetrievalTrace) (string, []string) {
+	if trace == nil || len(trace.Chunks) == 0 {
+		return answer, nil
+	}
+	docs := l.load(ctx, trace)
+	resolved, citations := chat.ResolveCitations(answer, docs)
+	if len(citations) == 0 {
+		citations = chat.NumberCitations(docs)
+	}
+
+	byID := make(map[string]*schema.Document, len(docs))
+	for _, doc := range docs {
+		byID[doc.ID] = doc
+	}
+	lines := make([]string, 0, len(citations))
+	for _, citation := range citations {
+		lines = append(lines, formatCitation(citation.Index, citation.Source, citation.Title, citation.Score, byID[citation.ChunkID]))
+	}
+	return resolved, lines
+}
+
+// load 按检索排名加载轨迹中仍然存在的分片
+func (l *referenceLoader) load(ctx context.Context, trace *analytics.RetrievalTrace) []*schema.Document {
+	ids := make([]string, 0, len(trace.Chunks))
+	for _, chunk := range trace.Chunks {
+		ids = append(ids, chunk.ID)
+	}
+	var chunks []entity.KnowledgeChunks
+	if err := dao.KnowledgeChunks.Ctx(ctx).WhereIn("id", ids).Scan(&chunks); err != nil {
+		g.Log().Warningf(ctx, "导出会话加载参考分片失败: %v", err)
+		return nil
+	}
+	byID := make(map[string]entity.KnowledgeChunks, len(chunks))
+	for _, chunk := range chunks {
+		byID[chunk.Id] = chunk
+	}
+
+	docs := make([]*schema.Document, 0, len(chunks))
+	for _, traced := range trace.Chunks {
+		chunk, ok := byID[traced.ID]
+		if !ok {
+			continue
+		}
+		metadata := map[string]any{common.DocumentId: chunk.KnowledgeDocId}
+		if document := l.document(ctx, chunk.KnowledgeDocId); document.FileName != "" {
+			metadata["source"] = document.FileName
+		}
+		// 分片 ext 中保存切分时的章节标题等元数据
+		var ext map[string]any
+		if err := json.Unmarshal([]byte(chunk.Ext), &ext); err == nil {
+			metadata[common.FieldMetadata] = ext
+		}
+		docs = append(docs, &schema.Document{ID: chunk.Id, Content: chunk.Content, Score: traced.Score, MetaData: metadata})
+	}
+	return docs
+}
+
+func (l *referenceLoader) document(ctx context.Context, id string) entity.KnowledgeDocuments {
+	if document, ok := l.documents[id]; ok {
+		return document
+	}
+	document, err := knowledge.GetDocumentById(ctx, id)
+	if err != nil {
+		g.Log().Warningf(ctx, "导出会话加载文档失败 %s: %v", id, err)
+	}
+	l.documents[id] = document
+	return document
+}
+
+// formatCitation 格式化一条参考资料：编号、来源、章节、相关性和内容摘录
+func formatCitation(index int, source, title string, score float32, doc *schema.Document) string {
+	line := fmt.Sprintf("[%d] %s", index, source)
+	if title != "" {
+		line += " · " + title
+	}
+	if score > 0 {
+		line += fmt.Sprintf("（相关性 %.2f）", score)
+	}
+	if doc != nil {
+		if excerpt := truncateRunes(strings.Join(strings.Fields(doc.Content), " "), exportExcerptRunes); excerpt != "" {
+			line += "\n    " + excerpt
+		}
+	}
+	return line
+}
+
+// appendToolSummary 附加会话中的工具调用摘要（按调用时间排序）
+func appendToolSummary(ctx context.Context, doc *file_export.Document, convID string) error {
+	logs, total, err := dao.MCPCallLog.ListByConversationID(ctx, convID, 1, exportMaxToolCalls)
+	if err != nil {
+		return err
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	doc.Heading(fmt.Sprintf("工具调用（%d 次）", total))
+	for i := len(logs) - 1; i >= 0; i-- {
+		doc.Note(formatToolCall(logs[i]))
+	}
+	if total > int64(len(logs)) {
+		doc.Note(fmt.Sprintf("仅导出最近 %d 次调用", len(logs)))
+	}
+	return nil
+}
+
+// formatToolCall 格式化一次工具调用：时间、服务和工具名、状态、耗时、参数和结果摘要
+func formatToolCall(log *gormModel.MCPCallLog) string {
+	var b strings.Builder
+	if log.CreateTime != nil {
+		b.WriteString(log.CreateTime.Format(time.DateTime) + "  ")
+	}
+	name := log.ToolName
+	if log.MCPServiceName != "" {
+		name = log.MCPServiceName + "/" + log.ToolName
+	}
+	status := "成功"
+	switch log.Status {
+	case 0:
+		status = "失败"
+	case 2:
+		status = "超时"
+	}
+	fmt.Fprintf(&b, "%s  %s  %dms", name, status, log.Duration)
+	if payload := truncateRunes(log.RequestPayload, exportToolPayloadRunes); payload != "" {
+		b.WriteString("\n参数：" + payload)
+	}
+	if log.Status == 1 {
+		if payload := truncateRunes(log.ResponsePayload, exportToolPayloadRunes); payload != "" {
+			b.WriteString("\n结果：" + payload)
+		}
+	} else if log.ErrorMessage != "" {
+		b.WriteString("\n错误：" + truncateRunes(log.ErrorMessage, exportToolPayloadRunes))
+	}
+	return b.String()
+}
+
+// truncateRunes 截断为最多 n 个字符，超出部分以省略号表示
+func truncateRunes(text string, n int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,58 @@
+package conversation
+
+import (
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestSplitContents(t *testing.T) {
+	text, images := splitContents([]*gormModel.MessageContent{
+		{ContentType: "text", TextContent: "第一段"},
+		{ContentType: "image_url", MediaURL: "upload/a.png"},
+		{ContentType: "text", TextContent: "  "},
+		{ContentType: "audio_url", MediaURL: "upload/a.mp3"},
+		{ContentType: "text", TextContent: "第二段\n"},
+	})
+	if text != "第一段\n第二段" {
+		t.Errorf("text = %q", text)
+	}
+	if len(images) != 1 || images[0] != "upload/a.png" {
+		t.Errorf("images = %v", images)
+	}
+}
+
+func TestFormatToolCall(t *testing.T) {
+	tests := []struct {
+		name string
+		log  *gormModel.MCPCallLog
+		want string
+	}{
+		{
+			name: "success",
+			log:  &gormModel.MCPCallLog{MCPServiceName: "weather", ToolName: "query", Status: 1, Duration: 120, RequestPayload: `{"city":"北京"}`, ResponsePayload: "晴", ErrorMessage: "ignored"},
+			want: "weather/query  成功  120ms\n参数：{\"city\":\"北京\"}\n结果：晴",
+		},
+		{
+			name: "timeout",
+			log:  &gormModel.MCPCallLog{ToolName: "search", Status: 2, Duration: 30000, ErrorMessage: "deadline exceeded"},
+			want: "search  超时  30000ms\n错误：deadline exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatToolCall(tt.log); got != tt.want {
+				t.Errorf("formatToolCall() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes(" 一二三四 ", 3); got != "一二三…" {
+		t.Errorf("truncateRunes() = %q", got)
+	}
+	if got := truncateRunes("abc", 3); got != "abc" {
+		t.Errorf("truncateRunes() = %q", got)
+	}
+}
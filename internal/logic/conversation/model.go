@@ -0,0 +1,83 @@
+package conversation
+
+import (
+	"context"
+	"time"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ResolveModel 确定本轮对话使用的模型
+// 请求未指定模型时使用会话保存的模型；指定的模型与会话保存的不同时视为切换模型，并保存到会话供后续轮次使用
+func ResolveModel(ctx context.Context, convID string, modelID string) (string, error) {
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return "", err
+	}
+	if modelID == "" {
+		if conv == nil || conv.ModelID == "" {
+			return "", gerror.NewCode(gcode.CodeMissingParameter, "model_id is required: the conversation has no selected model")
+		}
+		return conv.ModelID, nil
+	}
+	if conv == nil || conv.ModelID != modelID {
+		if _, err = switchModel(ctx, conv, convID, modelID); err != nil {
+			return "", err
+		}
+	}
+	return modelID, nil
+}
+
+// SwitchModel 切换会话使用的模型，会话不存在时创建会话
+func SwitchModel(ctx context.Context, convID string, modelID string) (*gormModel.Conversation, error) {
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	return switchModel(ctx, conv, convID, modelID)
+}
+
+func switchModel(ctx context.Context, conv *gormModel.Conversation, convID string, modelID string) (*gormModel.Conversation, error) {
+	mc := coreModel.Registry.Get(modelID)
+	if mc == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "model not found: %s", modelID)
+	}
+	if mc.Type != coreModel.ModelTypeLLM && mc.Type != coreModel.ModelTypeMultimodal {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "model %s is a %s model and cannot be used for chat", modelID, mc.Type)
+	}
+
+	if conv == nil {
+		now := time.Now()
+		conv = &gormModel.Conversation{
+			ConvID:           convID,
+			UserID:           identity.UserID(ctx),
+			Title:            "New Conversation",
+			ModelName:        mc.Name,
+			ModelID:          modelID,
+			ConversationType: "text",
+			Status:           "active",
+			CreateTime:       &now,
+			UpdateTime:       &now,
+		}
+		if err := dao.Conversation.Create(ctx, conv); err != nil {
+			return nil, err
+		}
+		return conv, nil
+	}
+
+	if conv.ModelID != "" && conv.ModelID != modelID {
+		g.Log().Infof(ctx, "Conversation %s switched model from %s to %s", convID, conv.ModelID, modelID)
+	}
+	if err := dao.Conversation.UpdateModel(ctx, convID, modelID, mc.Name); err != nil {
+		return nil, err
+	}
+	conv.ModelID = modelID
+	conv.ModelName = mc.Name
+	return conv, nil
+}
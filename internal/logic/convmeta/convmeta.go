@@ -0,0 +1,210 @@
+// Package convmeta 会话元数据的读写：序列化后过大的字段（如上传文档的全文）压缩后转存到 conversation_blobs 表，
+// 会话元数据中只保留引用，避免超出字段长度限制被截断；读取时自动还原，调用方看到的是完整的元数据
+package convmeta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// refKey 转存字段在元数据中的引用：{"$blob": "字段名"}
+const refKey = "$blob"
+
+// encodingGzip 转存内容的编码
+const encodingGzip = "gzip"
+
+// 默认阈值
+const (
+	defaultSpillThreshold = 64 * 1024   // 单个字段超过该字节数时转存
+	defaultMaxInlineSize  = 1024 * 1024 // 元数据内联保存的最大字节数
+)
+
+// Limits 元数据转存阈值
+type Limits struct {
+	SpillThreshold int // 单个字段序列化后超过该字节数时转存，0 表示不按字段转存
+	MaxInlineSize  int // 元数据整体超过该字节数时从最大的字段开始依次转存，0 表示不限制
+}
+
+// LoadLimits 从 conversationMetadata 配置读取转存阈值
+func LoadLimits(ctx context.Context) Limits {
+	return Limits{
+		SpillThreshold: g.Cfg().MustGet(ctx, "conversationMetadata.spillThreshold", defaultSpillThreshold).Int(),
+		MaxInlineSize:  g.Cfg().MustGet(ctx, "conversationMetadata.maxInlineSize", defaultMaxInlineSize).Int(),
+	}
+}
+
+// Load 读取会话元数据并还原转存的字段，会话没有元数据时返回空 map
+func Load(ctx context.Context, conv *gormModel.Conversation) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	if conv == nil || len(conv.Metadata) == 0 {
+		return metadata, nil
+	}
+	if err := json.Unmarshal(conv.Metadata, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation metadata: %w", err)
+	}
+	if metadata == nil {
+		return map[string]interface{}{}, nil
+	}
+	for key, value := range metadata {
+		if !isRef(value) {
+			continue
+		}
+		blob, err := dao.ConversationBlob.Get(ctx, conv.ConvID, key)
+		if err != nil {
+			return nil, err
+		}
+		if blob == nil {
+			g.Log().Warningf(ctx, "Conversation metadata blob missing, convID=%s, key=%s", conv.ConvID, key)
+			delete(metadata, key)
+			continue
+		}
+		restored, err := decode(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore conversation metadata %s: %w", key, err)
+		}
+		metadata[key] = restored
+	}
+	return metadata, nil
+}
+
+// Save 保存会话元数据，超过阈值的字段压缩后转存，元数据中只保留引用
+func Save(ctx context.Context, convID string, metadata map[string]interface{}) error {
+	inline, spilled, err := split(metadata, LoadLimits(ctx))
+	if err != nil {
+		return err
+	}
+	for key, data := range spilled {
+		compressed, err := compress(data)
+		if err != nil {
+			return fmt.Errorf("failed to compress conversation metadata %s: %w", key, err)
+		}
+		if err = dao.ConversationBlob.Put(ctx, &gormModel.ConversationBlob{
+			ConvID:   convID,
+			MetaKey:  key,
+			Encoding: encodingGzip,
+			Size:     len(data),
+			Data:     compressed,
+		}); err != nil {
+			return err
+		}
+		g.Log().Infof(ctx, "Conversation metadata %s spilled to blob store, convID=%s, size=%d, compressed=%d",
+			key, convID, len(data), len(compressed))
+	}
+	data, err := json.Marshal(inline)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return dao.Conversation.UpdateMetadata(ctx, convID, gormModel.JSON(data))
+}
+
+// split 把元数据分为内联保存的部分和需要转存的字段（字段名 -> 字段 JSON），转存的字段在内联部分替换为引用。
+// 先转存超过 SpillThreshold 的字段，整体仍超过 MaxInlineSize 时再从最大的字段开始依次转存
+func split(metadata map[string]interface{}, limits Limits) (map[string]interface{}, map[string][]byte, error) {
+	inline := make(map[string]interface{}, len(metadata))
+	encoded := make(map[string][]byte, len(metadata))
+	for key, value := range metadata {
+		inline[key] = value
+		if isRef(value) {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal metadata %s: %w", key, err)
+		}
+		encoded[key] = data
+	}
+
+	spilled := map[string][]byte{}
+	spill := func(key string) {
+		spilled[key] = encoded[key]
+		inline[key] = map[string]interface{}{refKey: key}
+		delete(encoded, key)
+	}
+	if limits.SpillThreshold > 0 {
+		for key, data := range encoded {
+			if len(data) > limits.SpillThreshold {
+				spill(key)
+			}
+		}
+	}
+	if limits.MaxInlineSize > 0 {
+		// 按字段大小从大到小转存，大小相同时按字段名排序保证结果稳定
+		keys := make([]string, 0, len(encoded))
+		for key := range encoded {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(encoded[keys[i]]) != len(encoded[keys[j]]) {
+				return len(encoded[keys[i]]) > len(encoded[keys[j]])
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			data, err := json.Marshal(inline)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			if len(data) <= limits.MaxInlineSize {
+				break
+			}
+			spill(key)
+		}
+	}
+	return inline, spilled, nil
+}
+
+// isRef 是否为转存字段的引用
+func isRef(value interface{}) bool {
+	ref, ok := value.(map[string]interface{})
+	if !ok || len(ref) != 1 {
+		return false
+	}
+	_, ok = ref[refKey].(string)
+	return ok
+}
+
+// decode 解压转存的字段并还原为 JSON 值
+func decode(blob *gormModel.ConversationBlob) (interface{}, error) {
+	if blob.Encoding != encodingGzip {
+		return nil, fmt.Errorf("unsupported encoding %q", blob.Encoding)
+	}
+	data, err := decompress(blob.Data)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,80 @@
+package convmeta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	large := strings.Repeat("文档内容", 100)
+	tests := []struct {
+		name        string
+		metadata    map[string]interface{}
+		limits      Limits
+		wantSpilled []string
+	}{
+		{
+			name:     "small fields stay inline",
+			metadata: map[string]interface{}{"file_content": "short", "file_images": []interface{}{"a.png"}},
+			limits:   Limits{SpillThreshold: 100, MaxInlineSize: 1000},
+		},
+		{
+			name:        "field over threshold spilled",
+			metadata:    map[string]interface{}{"file_content": large, "document_files": []interface{}{"a.pdf"}},
+			limits:      Limits{SpillThreshold: 100},
+			wantSpilled: []string{"file_content"},
+		},
+		{
+			name:        "largest fields spilled until under max inline size",
+			metadata:    map[string]interface{}{"a": strings.Repeat("x", 300), "b": strings.Repeat("y", 200), "c": "z"},
+			limits:      Limits{MaxInlineSize: 300},
+			wantSpilled: []string{"a"},
+		},
+		{
+			name:     "existing reference kept inline",
+			metadata: map[string]interface{}{"file_content": map[string]interface{}{refKey: "file_content"}},
+			limits:   Limits{SpillThreshold: 1, MaxInlineSize: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inline, spilled, err := split(tt.metadata, tt.limits)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(spilled) != len(tt.wantSpilled) {
+				t.Fatalf("spilled %d fields, want %v", len(spilled), tt.wantSpilled)
+			}
+			for _, key := range tt.wantSpilled {
+				if _, ok := spilled[key]; !ok {
+					t.Errorf("expected %s to be spilled", key)
+				}
+				if !isRef(inline[key]) {
+					t.Errorf("expected %s to be replaced by a reference, got %v", key, inline[key])
+				}
+			}
+			if len(inline) != len(tt.metadata) {
+				t.Errorf("inline metadata has %d keys, want %d", len(inline), len(tt.metadata))
+			}
+		})
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte(`"` + strings.Repeat("第一章 概述 ", 1000) + `"`)
+	compressed, err := compress(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(compressed) >= len(data) {
+		t.Errorf("expected compression, got %d >= %d", len(compressed), len(data))
+	}
+	restored, err := decompress(compressed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(restored, data) {
+		t.Error("round trip mismatch")
+	}
+}
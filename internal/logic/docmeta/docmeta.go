@@ -0,0 +1,163 @@
+// Package docmeta 索引时调用 LLM 提取文档元数据（标题、作者、日期、主题和两句话摘要），
+// 保存到文档记录和分片元数据，用于文档列表展示和检索过滤
+package docmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// MetadataKey 分片元数据中保存文档元数据的字段
+const MetadataKey = "doc_metadata"
+
+const (
+	defaultMaxChars = 6000
+	maxTopics       = 8
+)
+
+const extractPrompt = `你是一个文档元数据提取助手。请阅读下面的文档内容（可能只是开头部分），提取文档元数据，只输出一个 JSON 对象，不要输出其他内容：
+{"title": "文档标题", "author": "作者或发布机构", "date": "文档日期，格式 YYYY-MM-DD，只能确定到月或年时为 YYYY-MM 或 YYYY", "topics": ["3 到 5 个主题词"], "summary": "不超过两句话的摘要"}
+无法确定的字段输出空字符串或空数组，不要编造。
+
+文件名：%s
+文档内容：
+%s`
+
+// datePattern 匹配 2024-03-01、2024/3/1、2024.03、2024年3月1日 等日期
+var datePattern = regexp.MustCompile(`(\d{4})(?:\s*[-/.年]\s*(\d{1,2})(?:\s*[-/.月]\s*(\d{1,2}))?)?`)
+
+// Enabled 是否启用元数据提取
+func Enabled(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "metadataExtraction.enabled", false).Bool()
+}
+
+// Extract 调用 metadataExtraction.modelID 配置的 LLM 提取文档元数据，text 超过 maxChars 时只使用开头部分
+func Extract(ctx context.Context, fileName, text string) (knowledge.DocumentMetadata, error) {
+	modelID := g.Cfg().MustGet(ctx, "metadataExtraction.modelID", "").String()
+	mc := coreModel.Registry.Get(modelID)
+	if mc == nil {
+		return knowledge.DocumentMetadata{}, fmt.Errorf("metadata extraction model not found: %q", modelID)
+	}
+	maxChars := g.Cfg().MustGet(ctx, "metadataExtraction.maxChars", defaultMaxChars).Int()
+	if runes := []rune(text); maxChars > 0 && len(runes) > maxChars {
+		text = string(runes[:maxChars])
+	}
+
+	modelService := coreModel.NewModelServiceFor(mc, coreModel.FormatterFor(mc))
+	resp, err := modelService.ChatCompletion(ctx, coreModel.ChatCompletionParams{
+		ModelName:           mc.Name,
+		Messages:            []*schema.Message{{Role: schema.User, Content: fmt.Sprintf(extractPrompt, fileName, text)}},
+		Temperature:         0,
+		MaxCompletionTokens: 1000,
+		TopP:                1,
+		N:                   1,
+	})
+	if err != nil {
+		return knowledge.DocumentMetadata{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return knowledge.DocumentMetadata{}, fmt.Errorf("received empty choices from API")
+	}
+	return parse(resp.Choices[0].Message.Content)
+}
+
+// parse 解析模型输出的 JSON 对象（允许包含代码块标记等多余内容）并规范化各字段
+func parse(output string) (knowledge.DocumentMetadata, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return knowledge.DocumentMetadata{}, fmt.Errorf("no JSON object in model output: %q", output)
+	}
+	var raw struct {
+		Title   string `json:"title"`
+		Author  string `json:"author"`
+		Date    string `json:"date"`
+		Topics  any    `json:"topics"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &raw); err != nil {
+		return knowledge.DocumentMetadata{}, fmt.Errorf("invalid JSON in model output: %w", err)
+	}
+	return knowledge.DocumentMetadata{
+		Title:   strings.TrimSpace(raw.Title),
+		Author:  strings.TrimSpace(raw.Author),
+		Date:    NormalizeDate(raw.Date),
+		Topics:  normalizeTopics(raw.Topics),
+		Summary: strings.TrimSpace(raw.Summary),
+	}, nil
+}
+
+// NormalizeDate 把日期规范化为 YYYY-MM-DD、YYYY-MM 或 YYYY，无法识别时返回空字符串
+func NormalizeDate(value string) string {
+	match := datePattern.FindStringSubmatch(value)
+	if match == nil {
+		return ""
+	}
+	date := match[1]
+	if month, _ := strconv.Atoi(match[2]); month >= 1 && month <= 12 {
+		date += fmt.Sprintf("-%02d", month)
+		if day, _ := strconv.Atoi(match[3]); day >= 1 && day <= 31 {
+			date += fmt.Sprintf("-%02d", day)
+		}
+	}
+	return date
+}
+
+// normalizeTopics 主题去重去空，模型返回逗号分隔的字符串时拆分
+func normalizeTopics(value any) []string {
+	var topics []string
+	switch v := value.(type) {
+	case string:
+		topics = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == '，' || r == '、' || r == ';' || r == '；' })
+	case []any:
+		for _, item := range v {
+			if text, ok := item.(string); ok {
+				topics = append(topics, text)
+			}
+		}
+	}
+	seen := make(map[string]bool, len(topics))
+	result := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		topic = strings.TrimSpace(topic)
+		key := strings.ToLower(topic)
+		if topic == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, topic)
+		if len(result) == maxTopics {
+			break
+		}
+	}
+	return result
+}
+
+// ChunkMetadata 写入分片元数据的内容
+func ChunkMetadata(metadata knowledge.DocumentMetadata) map[string]any {
+	result := map[string]any{}
+	if metadata.Title != "" {
+		result["title"] = metadata.Title
+	}
+	if metadata.Author != "" {
+		result["author"] = metadata.Author
+	}
+	if metadata.Date != "" {
+		result["date"] = metadata.Date
+	}
+	if len(metadata.Topics) > 0 {
+		result["topics"] = metadata.Topics
+	}
+	if metadata.Summary != "" {
+		result["summary"] = metadata.Summary
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package docmeta
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+)
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2024-03-01", "2024-03-01"},
+		{"2024/3/1", "2024-03-01"},
+		{"2024年3月5日", "2024-03-05"},
+		{"2024.11", "2024-11"},
+		{"发布于 2023 年", "2023"},
+		{"2024-13-01", "2024"},
+		{"未知", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeDate(tt.in); got != tt.want {
+			t.Errorf("NormalizeDate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	output := "```json\n" + `{"title":" 年度报告 ","author":"ACME","date":"2024年3月","topics":["财务","财务"," 战略 ",""],"summary":"公司业绩增长。"}` + "\n```"
+	got, err := parse(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := knowledge.DocumentMetadata{Title: "年度报告", Author: "ACME", Date: "2024-03", Topics: []string{"财务", "战略"}, Summary: "公司业绩增长。"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parse() = %+v, want %+v", got, want)
+	}
+
+	got, err = parse(`{"topics":"合同，采购、风险"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Topics, []string{"合同", "采购", "风险"}) {
+		t.Errorf("parse() topics = %v", got.Topics)
+	}
+
+	if _, err = parse("无法提取"); err == nil {
+		t.Error("parse() should fail without a JSON object")
+	}
+}
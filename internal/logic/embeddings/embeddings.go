@@ -0,0 +1,377 @@
+// Package embeddings OpenAI 兼容的向量化接口（/v1/embeddings）：按模型ID、名称或别名路由到已注册的 embedding 模型，
+// 输入去重后分批并发调用模型服务，按文本缓存向量，并把消耗的 token 计入项目月度配额和调用用户的用量
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/tokenizer"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/Malowking/kbgo/internal/logic/usage"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultBatchSize       = 64
+	defaultConcurrency     = 4
+	defaultMaxInputs       = 2048
+	defaultCacheTTL        = 24 * time.Hour
+	defaultCacheMaxEntries = 50000
+)
+
+// Config 向量化接口配置（embeddingsAPI.*）
+type Config struct {
+	DefaultModel    string            // 未指定模型且项目没有默认 embedding 模型时使用的模型ID或别名
+	Aliases         map[string]string // 模型别名 -> 模型ID，供调用方使用稳定的名称
+	BatchSize       int               // 单次调用模型服务的最大输入条数
+	Concurrency     int               // 同一请求并发调用模型服务的批次数
+	MaxInputs       int               // 单个请求的最大输入条数
+	CacheEnabled    bool              // 是否按文本缓存向量
+	CacheTTL        time.Duration     // 缓存有效期
+	CacheMaxEntries int               // 缓存的最大向量条数（LRU）
+}
+
+// LoadConfig 读取向量化接口配置，未配置的项使用默认值
+func LoadConfig(ctx context.Context) *Config {
+	cfg := &Config{
+		DefaultModel:    g.Cfg().MustGet(ctx, "embeddingsAPI.defaultModel", "").String(),
+		Aliases:         g.Cfg().MustGet(ctx, "embeddingsAPI.aliases").MapStrStr(),
+		BatchSize:       g.Cfg().MustGet(ctx, "embeddingsAPI.batchSize", defaultBatchSize).Int(),
+		Concurrency:     g.Cfg().MustGet(ctx, "embeddingsAPI.concurrency", defaultConcurrency).Int(),
+		MaxInputs:       g.Cfg().MustGet(ctx, "embeddingsAPI.maxInputs", defaultMaxInputs).Int(),
+		CacheEnabled:    g.Cfg().MustGet(ctx, "embeddingsAPI.cache.enabled", true).Bool(),
+		CacheTTL:        g.Cfg().MustGet(ctx, "embeddingsAPI.cache.ttl", defaultCacheTTL.String()).Duration(),
+		CacheMaxEntries: g.Cfg().MustGet(ctx, "embeddingsAPI.cache.maxEntries", defaultCacheMaxEntries).Int(),
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	if cfg.MaxInputs <= 0 {
+		cfg.MaxInputs = defaultMaxInputs
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultCacheTTL
+	}
+	if cfg.CacheMaxEntries <= 0 {
+		cfg.CacheMaxEntries = defaultCacheMaxEntries
+	}
+	return cfg
+}
+
+var (
+	cacheOnce   sync.Once
+	vectorCache *gcache.Cache
+)
+
+// getCache 懒加载向量缓存（内存 LRU）
+func getCache(cfg *Config) *gcache.Cache {
+	cacheOnce.Do(func() {
+		vectorCache = gcache.New(cfg.CacheMaxEntries)
+	})
+	return vectorCache
+}
+
+// Create 生成输入文本的向量：校验项目配额，按批调用模型服务（命中缓存的文本不再调用），并记录 token 用量
+func Create(ctx context.Context, req *v1.EmbeddingsReq) (*v1.EmbeddingsRes, error) {
+	cfg := LoadConfig(ctx)
+	inputs, err := normalizeInput(req.Input)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) > cfg.MaxInputs {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "too many inputs: %d exceeds limit %d", len(inputs), cfg.MaxInputs)
+	}
+
+	// 项目：使用项目默认 embedding 模型，并在上下文中记录所属项目用于配额校验和计量（按用户隔离时要求是项目成员）
+	modelRef := req.Model
+	p, err := project.Use(ctx, req.ProjectID, "")
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		if modelRef == "" {
+			modelRef = project.ParseSettings(p).EmbeddingModelID
+		}
+		ctx = project.WithContext(ctx, p.ID)
+	}
+	if modelRef == "" {
+		modelRef = cfg.DefaultModel
+	}
+	mc, err := resolveModel(cfg, modelRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = quota.CheckTokens(ctx); err != nil {
+		return nil, err
+	}
+	if err = usage.CheckUser(ctx, identity.UserID(ctx)); err != nil {
+		return nil, err
+	}
+
+	b := &batcher{
+		scope:       cacheScope(mc, req.Dimensions),
+		batchSize:   cfg.BatchSize,
+		concurrency: cfg.Concurrency,
+		call: func(ctx context.Context, texts []string) ([][]float32, int, error) {
+			return callModel(ctx, mc, texts, req.Dimensions, req.User)
+		},
+	}
+	if cfg.CacheEnabled {
+		b.cache, b.ttl = getCache(cfg), cfg.CacheTTL
+	}
+	vectors, tokens, err := b.embed(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	quota.RecordTokens(ctx, tokens)
+	usage.RecordDirect(ctx, mc.ModelID, mc.Name, tokens)
+
+	data := make([]*v1.EmbeddingsData, len(vectors))
+	for i, vector := range vectors {
+		data[i] = &v1.EmbeddingsData{Object: "embedding", Index: i, Embedding: encodeVector(vector, req.EncodingFormat)}
+	}
+	return &v1.EmbeddingsRes{
+		Object: "list",
+		Data:   data,
+		Model:  mc.Name,
+		Usage:  v1.EmbeddingUsage{PromptTokens: tokens, TotalTokens: tokens},
+	}, nil
+}
+
+// resolveModel 按别名、模型ID、embedding 模型名称的顺序查找模型
+func resolveModel(cfg *Config, ref string) (*model.ModelConfig, error) {
+	if ref == "" {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, "model is required: no default embedding model configured")
+	}
+	if id, ok := cfg.Aliases[ref]; ok {
+		ref = id
+	}
+	mc := model.Registry.Get(ref)
+	if mc == nil {
+		for _, candidate := range model.Registry.GetByType(model.ModelTypeEmbedding) {
+			if candidate.Name == ref {
+				mc = candidate
+				break
+			}
+		}
+	}
+	if mc == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "embedding model not found: %s", ref)
+	}
+	if mc.Type != model.ModelTypeEmbedding {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "model %s is not an embedding model, type: %s", ref, mc.Type)
+	}
+	return mc, nil
+}
+
+// callModel 调用模型服务生成一批向量，返回的向量按输入顺序排列；服务未返回用量时按分词器估算
+func callModel(ctx context.Context, mc *model.ModelConfig, texts []string, dimensions int, user string) ([][]float32, int, error) {
+	resp, err := mc.Client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input:      texts,
+		Model:      openai.EmbeddingModel(mc.Name),
+		Dimensions: dimensions,
+		User:       user,
+	})
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to create embeddings - Model: %s, err: %v", mc.Name, err)
+		return nil, 0, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, 0, fmt.Errorf("embedding model %s returned %d vectors for %d inputs", mc.Name, len(resp.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, 0, fmt.Errorf("embedding model %s returned invalid index %d", mc.Name, d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	tokens := resp.Usage.PromptTokens
+	if tokens <= 0 {
+		for _, text := range texts {
+			tokens += tokenizer.Count(mc.Name, text)
+		}
+	}
+	return vectors, tokens, nil
+}
+
+// batcher 对输入去重、查缓存，把未命中的文本分批并发调用模型服务
+type batcher struct {
+	scope       string        // 缓存键前缀：模型和维度
+	cache       *gcache.Cache // 为 nil 时不缓存
+	ttl         time.Duration
+	batchSize   int
+	concurrency int
+	call        func(ctx context.Context, texts []string) ([][]float32, int, error)
+}
+
+// embed 返回与输入一一对应的向量和调用模型服务消耗的 token，任一批次失败时返回错误
+func (b *batcher) embed(ctx context.Context, texts []string) ([][]float32, int, error) {
+	vectors := make([][]float32, len(texts))
+	cached := make(map[string][]float32)
+	positions := make(map[string][]int)
+	var pending []string
+	for i, text := range texts {
+		if vector, ok := cached[text]; ok {
+			vectors[i] = vector
+			continue
+		}
+		if _, ok := positions[text]; ok {
+			positions[text] = append(positions[text], i)
+			continue
+		}
+		if vector := b.lookup(ctx, text); vector != nil {
+			cached[text] = vector
+			vectors[i] = vector
+			continue
+		}
+		positions[text] = []int{i}
+		pending = append(pending, text)
+	}
+	if len(pending) == 0 {
+		return vectors, 0, nil
+	}
+
+	batches := splitBatches(pending, b.batchSize)
+	results := make([][][]float32, len(batches))
+	usage := make([]int, len(batches))
+	errs := make([]error, len(batches))
+	semaphore := make(chan struct{}, b.concurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch []string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i], usage[i], errs[i] = b.call(ctx, batch)
+		}(i, batch)
+	}
+	wg.Wait()
+
+	tokens := 0
+	for i, batch := range batches {
+		if errs[i] != nil {
+			return nil, 0, fmt.Errorf("embedding batch %d failed: %w", i, errs[i])
+		}
+		if len(results[i]) != len(batch) {
+			return nil, 0, fmt.Errorf("embedding batch %d returned %d vectors for %d inputs", i, len(results[i]), len(batch))
+		}
+		tokens += usage[i]
+		for j, text := range batch {
+			for _, pos := range positions[text] {
+				vectors[pos] = results[i][j]
+			}
+			b.store(ctx, text, results[i][j])
+		}
+	}
+	return vectors, tokens, nil
+}
+
+func (b *batcher) lookup(ctx context.Context, text string) []float32 {
+	if b.cache == nil {
+		return nil
+	}
+	cached, err := b.cache.Get(ctx, b.key(text))
+	if err != nil || cached == nil {
+		return nil
+	}
+	vector, _ := cached.Val().([]float32)
+	return vector
+}
+
+func (b *batcher) store(ctx context.Context, text string, vector []float32) {
+	if b.cache == nil || len(vector) == 0 {
+		return
+	}
+	if err := b.cache.Set(ctx, b.key(text), vector, b.ttl); err != nil {
+		g.Log().Warningf(ctx, "Failed to cache embedding: %v", err)
+	}
+}
+
+func (b *batcher) key(text string) string {
+	sum := sha256.Sum256([]byte(b.scope + "\n" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheScope 缓存键前缀：模型ID、模型配置指纹（地址、名称等变更后不复用旧向量）和输出维度
+func cacheScope(mc *model.ModelConfig, dimensions int) string {
+	return mc.ModelID + "|" + mc.Fingerprint() + "|" + strconv.Itoa(dimensions)
+}
+
+// splitBatches 按批大小切分文本
+func splitBatches(texts []string, size int) [][]string {
+	var batches [][]string
+	for start := 0; start < len(texts); start += size {
+		end := start + size
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batches = append(batches, texts[start:end])
+	}
+	return batches
+}
+
+// normalizeInput 把字符串或字符串数组形式的输入转换为文本列表，不支持 token 数组
+func normalizeInput(input interface{}) ([]string, error) {
+	var texts []string
+	switch v := input.(type) {
+	case string:
+		texts = []string{v}
+	case []string:
+		texts = v
+	case []interface{}:
+		texts = make([]string, len(v))
+		for i, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "input[%d] must be a string, token arrays are not supported", i)
+			}
+			texts[i] = text
+		}
+	default:
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, "input must be a string or an array of strings")
+	}
+	if len(texts) == 0 {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, "input must not be empty")
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "input[%d] must not be empty", i)
+		}
+	}
+	return texts, nil
+}
+
+// encodeVector 按 encoding_format 编码向量：base64 为小端 float32 字节的 base64，其他返回原始浮点数组
+func encodeVector(vector []float32, format string) interface{} {
+	if format != "base64" {
+		return vector
+	}
+	buf := make([]byte, 4*len(vector))
+	for i, f := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
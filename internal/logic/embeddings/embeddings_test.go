@@ -0,0 +1,138 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gcache"
+)
+
+// fakeModel 按文本长度生成向量，记录每批输入
+type fakeModel struct {
+	mu      sync.Mutex
+	batches [][]string
+	fail    string
+}
+
+func (f *fakeModel) call(_ context.Context, texts []string) ([][]float32, int, error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, append([]string(nil), texts...))
+	f.mu.Unlock()
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if text == f.fail {
+			return nil, 0, errors.New("provider error")
+		}
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, len(texts), nil
+}
+
+func (f *fakeModel) inputs() int {
+	n := 0
+	for _, batch := range f.batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func TestBatcherEmbed(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeModel{}
+	b := &batcher{scope: "m|3", cache: gcache.New(100), ttl: time.Minute, batchSize: 2, concurrency: 2, call: fake.call}
+
+	texts := []string{"a", "bb", "a", "ccc", "dddd", "bb"}
+	vectors, tokens, err := b.embed(ctx, texts)
+	if err != nil {
+		t.Fatalf("embed() error = %v", err)
+	}
+	for i, text := range texts {
+		if len(vectors[i]) != 1 || vectors[i][0] != float32(len(text)) {
+			t.Errorf("vectors[%d] = %v, want [%d]", i, vectors[i], len(text))
+		}
+	}
+	if tokens != 4 || fake.inputs() != 4 || len(fake.batches) != 2 {
+		t.Errorf("tokens = %d, inputs = %d, batches = %d, want 4 unique inputs in 2 batches", tokens, fake.inputs(), len(fake.batches))
+	}
+
+	// 再次请求时命中缓存的文本不再调用模型
+	vectors, tokens, err = b.embed(ctx, []string{"ccc", "eeeee", "ccc"})
+	if err != nil {
+		t.Fatalf("embed() error = %v", err)
+	}
+	if tokens != 1 || fake.inputs() != 5 {
+		t.Errorf("tokens = %d, inputs = %d, want only the uncached text embedded", tokens, fake.inputs())
+	}
+	if vectors[0][0] != 3 || vectors[1][0] != 5 || vectors[2][0] != 3 {
+		t.Errorf("vectors = %v, want [[3] [5] [3]]", vectors)
+	}
+}
+
+func TestBatcherEmbedError(t *testing.T) {
+	fake := &fakeModel{fail: "bad"}
+	b := &batcher{scope: "m|0", batchSize: 1, concurrency: 1, call: fake.call}
+	if _, _, err := b.embed(context.Background(), []string{"ok", "bad"}); err == nil || !strings.Contains(err.Error(), "provider error") {
+		t.Errorf("embed() error = %v, want provider error", err)
+	}
+}
+
+func TestNormalizeInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   interface{}
+		want    int
+		wantErr bool
+	}{
+		{name: "string", input: "hello", want: 1},
+		{name: "array", input: []interface{}{"a", "b"}, want: 2},
+		{name: "token array", input: []interface{}{1, 2}, wantErr: true},
+		{name: "empty array", input: []interface{}{}, wantErr: true},
+		{name: "empty string", input: "", wantErr: true},
+		{name: "number", input: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeInput(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("normalizeInput() = %v, want %d texts", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeVector(t *testing.T) {
+	vector := []float32{1.5, -2}
+	if got, ok := encodeVector(vector, "float").([]float32); !ok || len(got) != 2 {
+		t.Errorf("encodeVector(float) = %v, want float array", got)
+	}
+	encoded, ok := encodeVector(vector, "base64").(string)
+	if !ok {
+		t.Fatalf("encodeVector(base64) is not a string")
+	}
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(buf) != 8 {
+		t.Fatalf("decode = %v, %v", buf, err)
+	}
+	for i, want := range vector {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:])); got != want {
+			t.Errorf("decoded[%d] = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	batches := splitBatches([]string{"a", "b", "c", "d", "e"}, 2)
+	if len(batches) != 3 || len(batches[2]) != 1 {
+		t.Errorf("splitBatches() = %v, want 3 batches with last of size 1", batches)
+	}
+}
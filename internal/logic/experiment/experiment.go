@@ -0,0 +1,214 @@
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// MetadataKey 会话和助手消息元数据中记录实验分组的字段，值为 实验名 -> 分组名
+const MetadataKey = "experiments"
+
+// Experiment A/B 实验，会话按分组权重分配到各分组
+type Experiment struct {
+	Name     string     `json:"name"`
+	Enabled  bool       `json:"enabled"` // 是否分配新流量（停止后仍可查询指标）
+	Variants []*Variant `json:"variants"`
+}
+
+// Variant 实验分组，未配置的字段沿用请求参数
+type Variant struct {
+	Name            string  `json:"name"`
+	Weight          int     `json:"weight"`          // 流量权重
+	ModelID         string  `json:"modelID"`         // 对话模型
+	PromptVersion   string  `json:"promptVersion"`   // 提示词版本标识
+	SystemPrompt    string  `json:"systemPrompt"`    // 替换默认系统提示中的角色说明
+	TopK            int     `json:"topK"`            // 检索返回数量
+	Score           float64 `json:"score"`           // 检索分数阈值
+	RetrieveMode    string  `json:"retrieveMode"`    // 检索模式
+	CostPer1kTokens float64 `json:"costPer1kTokens"` // 每千 token 成本，用于估算分组成本
+}
+
+type contextKey struct{}
+
+// assignment 本轮对话的实验分组
+type assignment struct {
+	variants     map[string]string // 实验名 -> 分组名
+	systemPrompt string
+}
+
+// Load 从 experiments 配置加载实验，跳过没有名称或没有有效分组的实验
+func Load(ctx context.Context) []*Experiment {
+	if !g.Cfg().MustGet(ctx, "experiments.enabled", false).Bool() {
+		return nil
+	}
+	var experiments []*Experiment
+	if err := g.Cfg().MustGet(ctx, "experiments.list").Scan(&experiments); err != nil {
+		g.Log().Errorf(ctx, "Failed to load experiments: %v", err)
+		return nil
+	}
+	return validExperiments(ctx, experiments)
+}
+
+// Get 按名称获取实验
+func Get(ctx context.Context, name string) *Experiment {
+	for _, exp := range Load(ctx) {
+		if exp.Name == name {
+			return exp
+		}
+	}
+	return nil
+}
+
+func validExperiments(ctx context.Context, experiments []*Experiment) []*Experiment {
+	var result []*Experiment
+	seen := make(map[string]bool)
+	for _, exp := range experiments {
+		if exp == nil || exp.Name == "" || seen[exp.Name] {
+			g.Log().Warning(ctx, "Experiment without name or with duplicate name, skipping")
+			continue
+		}
+		if exp.TotalWeight() <= 0 {
+			g.Log().Warningf(ctx, "Experiment %s has no variant with positive weight, skipping", exp.Name)
+			continue
+		}
+		seen[exp.Name] = true
+		result = append(result, exp)
+	}
+	return result
+}
+
+// TotalWeight 有效分组的权重之和
+func (e *Experiment) TotalWeight() int {
+	total := 0
+	for _, v := range e.Variants {
+		if v != nil && v.Name != "" && v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	return total
+}
+
+// Variant 按名称获取分组
+func (e *Experiment) Variant(name string) *Variant {
+	for _, v := range e.Variants {
+		if v != nil && v.Name == name && v.Weight > 0 {
+			return v
+		}
+	}
+	return nil
+}
+
+// pick 按会话ID哈希到分组，同一会话总是得到相同分组
+func (e *Experiment) pick(convID string) *Variant {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(e.Name + "/" + convID))
+	bucket := int(h.Sum32() % uint32(e.TotalWeight()))
+	for _, v := range e.Variants {
+		if v == nil || v.Name == "" || v.Weight <= 0 {
+			continue
+		}
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return nil
+}
+
+// apply 用分组配置覆盖请求参数
+func (v *Variant) apply(req *v1.ChatReq) {
+	if v.ModelID != "" {
+		req.ModelID = v.ModelID
+	}
+	if v.TopK > 0 {
+		req.TopK = v.TopK
+	}
+	if v.Score > 0 {
+		req.Score = v.Score
+	}
+	if v.RetrieveMode != "" {
+		req.RetrieveMode = v.RetrieveMode
+	}
+}
+
+// Assign 将会话分配到进行中的实验分组并按分组覆盖请求参数，返回携带分组信息的 context
+// 分组保存在会话元数据中，实验权重调整后已分配的会话保持原分组
+func Assign(ctx context.Context, req *v1.ChatReq) context.Context {
+	var running []*Experiment
+	for _, exp := range Load(ctx) {
+		if exp.Enabled {
+			running = append(running, exp)
+		}
+	}
+	if len(running) == 0 || req.ConvID == "" {
+		return ctx
+	}
+
+	conv, err := dao.Conversation.GetByConvID(ctx, req.ConvID)
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to load conversation for experiment assignment: %v", err)
+	}
+	metadata := map[string]interface{}{}
+	if conv != nil && len(conv.Metadata) > 0 {
+		if err = json.Unmarshal(conv.Metadata, &metadata); err != nil || metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+	}
+	saved, _ := metadata[MetadataKey].(map[string]interface{})
+
+	a := &assignment{variants: make(map[string]string, len(running))}
+	changed := false
+	for _, exp := range running {
+		name, _ := saved[exp.Name].(string)
+		variant := exp.Variant(name)
+		if variant == nil {
+			variant = exp.pick(req.ConvID)
+			changed = true
+		}
+		variant.apply(req)
+		if variant.SystemPrompt != "" {
+			a.systemPrompt = variant.SystemPrompt
+		}
+		a.variants[exp.Name] = variant.Name
+		g.Log().Debugf(ctx, "Conversation %s assigned to experiment %s variant %s", req.ConvID, exp.Name, variant.Name)
+	}
+
+	if changed && conv != nil {
+		merged := make(map[string]interface{}, len(saved)+len(a.variants))
+		for k, v := range saved {
+			merged[k] = v
+		}
+		for k, v := range a.variants {
+			merged[k] = v
+		}
+		metadata[MetadataKey] = merged
+		if data, err := json.Marshal(metadata); err == nil {
+			if err = dao.Conversation.UpdateMetadata(ctx, req.ConvID, gormModel.JSON(data)); err != nil {
+				g.Log().Warningf(ctx, "Failed to save experiment assignment: %v", err)
+			}
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// FromContext 获取本轮对话的实验分组（实验名 -> 分组名），未参与实验时返回 nil
+func FromContext(ctx context.Context) map[string]string {
+	if a, ok := ctx.Value(contextKey{}).(*assignment); ok {
+		return a.variants
+	}
+	return nil
+}
+
+// SystemPrompt 返回实验分组配置的系统提示角色说明，未配置时返回 defaultPrompt
+func SystemPrompt(ctx context.Context, defaultPrompt string) string {
+	if a, ok := ctx.Value(contextKey{}).(*assignment); ok && a.systemPrompt != "" {
+		return a.systemPrompt
+	}
+	return defaultPrompt
+}
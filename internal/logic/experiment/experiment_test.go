@@ -0,0 +1,75 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestPickVariant(t *testing.T) {
+	exp := &Experiment{Name: "prompt-v2", Variants: []*Variant{
+		{Name: "control", Weight: 80},
+		{Name: "treatment", Weight: 20},
+		{Name: "disabled", Weight: 0},
+	}}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		convID := fmt.Sprintf("conv-%d", i)
+		variant := exp.pick(convID)
+		if variant == nil {
+			t.Fatalf("pick(%s) returned nil", convID)
+		}
+		if again := exp.pick(convID); again != variant {
+			t.Fatalf("pick(%s) is not stable: %s then %s", convID, variant.Name, again.Name)
+		}
+		counts[variant.Name]++
+	}
+	if counts["disabled"] != 0 {
+		t.Errorf("zero weight variant got %d conversations", counts["disabled"])
+	}
+	if ratio := float64(counts["treatment"]) / 10000; math.Abs(ratio-0.2) > 0.03 {
+		t.Errorf("treatment ratio = %.3f, want about 0.2", ratio)
+	}
+}
+
+func TestVariantApply(t *testing.T) {
+	req := &v1.ChatReq{ModelID: "m1", TopK: 5, Score: 0.2, RetrieveMode: "rrf"}
+	(&Variant{ModelID: "m2", TopK: 8}).apply(req)
+	if req.ModelID != "m2" || req.TopK != 8 || req.Score != 0.2 || req.RetrieveMode != "rrf" {
+		t.Errorf("apply() = %+v", req)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	exp := &Experiment{Name: "exp", Variants: []*Variant{
+		{Name: "a", Weight: 1, CostPer1kTokens: 2},
+		{Name: "b", Weight: 1},
+	}}
+	messages := []*gormModel.Message{
+		{ConvID: "c1", TokensUsed: 500, LatencyMs: 100, Metadata: gormModel.JSON(`{"experiments":{"exp":"a"},"feedback":"positive"}`)},
+		{ConvID: "c1", TokensUsed: 1500, LatencyMs: 300, Metadata: gormModel.JSON(`{"experiments":{"exp":"a"},"feedback":"negative"}`)},
+		{ConvID: "c2", TokensUsed: 100, LatencyMs: 0, Metadata: gormModel.JSON(`{"experiments":{"exp":"a","other":"x"}}`)},
+		{ConvID: "c3", TokensUsed: 100, Metadata: gormModel.JSON(`{"experiments":{"exp":"removed"}}`)},
+		{ConvID: "c4", TokensUsed: 100, Metadata: gormModel.JSON(`{"experiments":{"other":"x"}}`)},
+		{ConvID: "c5", TokensUsed: 100, Metadata: gormModel.JSON(`{"confidence":{"score":0.9}}`)},
+	}
+
+	got := aggregate(exp, messages)
+	if len(got) != 3 || got[0].Variant != "a" || got[1].Variant != "b" || got[2].Variant != "removed" {
+		t.Fatalf("aggregate() variants = %+v", got)
+	}
+	a := got[0]
+	if a.ConversationCount != 2 || a.AssistantCount != 3 || a.TotalTokens != 2100 {
+		t.Errorf("variant a counts = %+v", a)
+	}
+	if a.AvgLatencyMs != 200 || a.SatisfactionRatio != 0.5 || math.Abs(a.EstimatedCost-4.2) > 1e-9 {
+		t.Errorf("variant a metrics = %+v", a)
+	}
+	if got[1].AssistantCount != 0 || got[2].AssistantCount != 1 || got[2].EstimatedCost != 0 {
+		t.Errorf("variant b/removed metrics = %+v, %+v", got[1], got[2])
+	}
+}
@@ -0,0 +1,123 @@
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// VariantMetrics 实验分组在统计区间内的对比指标
+type VariantMetrics struct {
+	Variant           string
+	ConversationCount int64
+	AssistantCount    int64
+	AvgLatencyMs      float64
+	TotalTokens       int64
+	PositiveFeedback  int64
+	NegativeFeedback  int64
+	SatisfactionRatio float64 // positive / (positive + negative)，无反馈时为 0
+	EstimatedCost     float64 // TotalTokens / 1000 * CostPer1kTokens
+}
+
+// Metrics 统计实验各分组在 [start, end) 区间内的助手消息指标
+func Metrics(ctx context.Context, exp *Experiment, start, end time.Time) ([]*VariantMetrics, error) {
+	messages, err := dao.Analytics.ListAssistantMetrics(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(exp, messages), nil
+}
+
+// aggregate 按消息元数据中记录的分组汇总指标，结果按配置中的分组顺序排列，
+// 配置中已删除但仍有数据的分组排在最后
+func aggregate(exp *Experiment, messages []*gormModel.Message) []*VariantMetrics {
+	type accumulator struct {
+		metrics      *VariantMetrics
+		conversation map[string]bool
+		latencySum   int64
+		latencyCount int64
+	}
+	groups := make(map[string]*accumulator)
+	get := func(variant string) *accumulator {
+		if acc, ok := groups[variant]; ok {
+			return acc
+		}
+		acc := &accumulator{metrics: &VariantMetrics{Variant: variant}, conversation: make(map[string]bool)}
+		groups[variant] = acc
+		return acc
+	}
+	for _, v := range exp.Variants {
+		if v != nil && v.Name != "" {
+			get(v.Name)
+		}
+	}
+
+	for _, msg := range messages {
+		if len(msg.Metadata) == 0 {
+			continue
+		}
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(msg.Metadata, &metadata); err != nil {
+			continue
+		}
+		assignments, _ := metadata[MetadataKey].(map[string]interface{})
+		variant, _ := assignments[exp.Name].(string)
+		if variant == "" {
+			continue
+		}
+
+		acc := get(variant)
+		acc.conversation[msg.ConvID] = true
+		acc.metrics.AssistantCount++
+		acc.metrics.TotalTokens += int64(msg.TokensUsed)
+		if msg.LatencyMs > 0 {
+			acc.latencySum += int64(msg.LatencyMs)
+			acc.latencyCount++
+		}
+		switch feedback, _ := metadata[analytics.FeedbackMetadataKey].(string); feedback {
+		case analytics.FeedbackPositive:
+			acc.metrics.PositiveFeedback++
+		case analytics.FeedbackNegative:
+			acc.metrics.NegativeFeedback++
+		}
+	}
+
+	order := make(map[string]int, len(exp.Variants))
+	for i, v := range exp.Variants {
+		if v != nil {
+			order[v.Name] = i
+		}
+	}
+	result := make([]*VariantMetrics, 0, len(groups))
+	for name, acc := range groups {
+		m := acc.metrics
+		m.ConversationCount = int64(len(acc.conversation))
+		if acc.latencyCount > 0 {
+			m.AvgLatencyMs = float64(acc.latencySum) / float64(acc.latencyCount)
+		}
+		if total := m.PositiveFeedback + m.NegativeFeedback; total > 0 {
+			m.SatisfactionRatio = float64(m.PositiveFeedback) / float64(total)
+		}
+		if v := exp.Variant(name); v != nil {
+			m.EstimatedCost = float64(m.TotalTokens) / 1000 * v.CostPer1kTokens
+		}
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		oi, iok := order[result[i].Variant]
+		oj, jok := order[result[j].Variant]
+		if iok != jok {
+			return iok
+		}
+		if iok {
+			return oi < oj
+		}
+		return result[i].Variant < result[j].Variant
+	})
+	return result
+}
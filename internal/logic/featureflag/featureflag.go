@@ -0,0 +1,238 @@
+// Package featureflag 运行时功能开关：重排序、输出防护、新检索器灰度等较大的功能由开关控制，
+// 可通过管理接口按项目（租户）开启、关闭或按比例灰度而无需重新部署。
+// 开关设置保存在数据库中并在进程内缓存，环境变量 KBGO_FEATURE_<名称> 优先于数据库设置，用于紧急关闭
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 功能开关
+const (
+	Rerank          = "rerank"           // 检索结果重排序，关闭时 rerank/rrf 模式退化为向量检索
+	Guardrails      = "guardrails"       // 输出防护（流式输出重复检测）
+	HybridRetrieval = "hybrid_retrieval" // 稠密+稀疏混合检索，按知识库灰度
+)
+
+// 设置来源
+const (
+	SourceDefault = "default" // 内置默认值
+	SourceGlobal  = "global"  // 全局设置
+	SourceProject = "project" // 项目设置
+	SourceEnv     = "env"     // 环境变量
+)
+
+// envPrefix 覆盖开关的环境变量前缀，名称转为大写，如 KBGO_FEATURE_RERANK=false、KBGO_FEATURE_HYBRID_RETRIEVAL=20
+const envPrefix = "KBGO_FEATURE_"
+
+// Definition 内置的功能开关
+type Definition struct {
+	Name        string
+	Description string
+	Default     bool // 没有任何设置时是否开启
+}
+
+var definitions = []*Definition{
+	{Name: Rerank, Description: "检索结果重排序，关闭时 rerank/rrf 模式退化为向量检索", Default: true},
+	{Name: Guardrails, Description: "输出防护：流式输出的重复检测", Default: true},
+	{Name: HybridRetrieval, Description: "配置了稀疏模型的知识库使用稠密+稀疏混合检索，灰度比例按知识库分桶", Default: true},
+}
+
+// Definitions 全部内置的功能开关
+func Definitions() []*Definition {
+	return definitions
+}
+
+// Lookup 按名称获取功能开关，不存在时返回 nil
+func Lookup(name string) *Definition {
+	for _, def := range definitions {
+		if def.Name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// State 功能开关在某个项目内的生效设置
+type State struct {
+	Enabled bool
+	Rollout int    // 开启时的灰度比例（0-100）
+	Source  string // 设置来源
+}
+
+// On 判断 key 是否在开启范围内，同一开关和 key 的分桶结果稳定，灰度比例增大时已开启的 key 保持开启
+func (s State) On(name, key string) bool {
+	if !s.Enabled || s.Rollout <= 0 {
+		return false
+	}
+	return s.Rollout >= 100 || bucket(name, key) < s.Rollout
+}
+
+// bucket 将 key 哈希到 0-99 的分桶
+func bucket(name, key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + "/" + key))
+	return int(h.Sum32() % 100)
+}
+
+type scope struct {
+	name      string
+	projectID string
+}
+
+// cache 数据库中的开关设置，超过 featureFlags.refreshInterval 后在下次读取时重新加载，本实例修改后立即重新加载
+var cache struct {
+	sync.Mutex
+	loadedAt time.Time
+	flags    map[scope]*gormModel.FeatureFlag
+}
+
+// settings 获取数据库中的开关设置，加载失败时继续使用上次加载的结果
+func settings(ctx context.Context) map[scope]*gormModel.FeatureFlag {
+	interval := g.Cfg().MustGet(ctx, "featureFlags.refreshInterval", "30s").Duration()
+	cache.Lock()
+	defer cache.Unlock()
+	if !cache.loadedAt.IsZero() && time.Since(cache.loadedAt) < interval {
+		return cache.flags
+	}
+	// 加载失败时也更新时间，避免数据库不可用时每次请求都查询
+	cache.loadedAt = time.Now()
+	rows, err := dao.FeatureFlag.List(ctx)
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to load feature flags, using cached settings: %v", err)
+		return cache.flags
+	}
+	flags := make(map[scope]*gormModel.FeatureFlag, len(rows))
+	for _, row := range rows {
+		flags[scope{name: row.Name, projectID: row.ProjectID}] = row
+	}
+	cache.flags = flags
+	return flags
+}
+
+// invalidate 清除缓存，下次读取时重新加载
+func invalidate() {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.loadedAt = time.Time{}
+}
+
+// Resolve 计算开关在项目内的生效设置：环境变量 > 项目设置 > 全局设置 > 默认值，projectID 为空时只看全局设置
+func Resolve(ctx context.Context, def *Definition, projectID string) State {
+	flags := settings(ctx)
+	var scoped *gormModel.FeatureFlag
+	if projectID != "" {
+		scoped = flags[scope{name: def.Name, projectID: projectID}]
+	}
+	return resolve(ctx, def, os.Getenv(EnvName(def.Name)), flags[scope{name: def.Name}], scoped)
+}
+
+func resolve(ctx context.Context, def *Definition, env string, global, scoped *gormModel.FeatureFlag) State {
+	if env != "" {
+		state, err := parseEnv(env)
+		if err == nil {
+			return state
+		}
+		g.Log().Warningf(ctx, "Ignoring invalid feature flag override %s=%q: %v", EnvName(def.Name), env, err)
+	}
+	if scoped != nil {
+		return State{Enabled: scoped.Enabled, Rollout: scoped.Rollout, Source: SourceProject}
+	}
+	if global != nil {
+		return State{Enabled: global.Enabled, Rollout: global.Rollout, Source: SourceGlobal}
+	}
+	state := State{Enabled: def.Default, Source: SourceDefault}
+	if def.Default {
+		state.Rollout = 100
+	}
+	return state
+}
+
+// EnvName 覆盖开关的环境变量名
+func EnvName(name string) string {
+	return envPrefix + strings.ToUpper(name)
+}
+
+// parseEnv 解析环境变量：true/on/yes 开启，false/off/no 关闭，0-100 的整数为灰度比例
+func parseEnv(value string) (State, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "on", "yes":
+		return State{Enabled: true, Rollout: 100, Source: SourceEnv}, nil
+	case "false", "off", "no":
+		return State{Source: SourceEnv}, nil
+	}
+	rollout, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || rollout < 0 || rollout > 100 {
+		return State{}, fmt.Errorf("expected true/false or a rollout percentage between 0 and 100")
+	}
+	return State{Enabled: rollout > 0, Rollout: rollout, Source: SourceEnv}, nil
+}
+
+// Enabled 判断当前请求所属项目是否开启功能，key 用于灰度分桶（如知识库ID），开关不存在时返回 false
+func Enabled(ctx context.Context, name, key string) bool {
+	def := Lookup(name)
+	if def == nil {
+		g.Log().Warningf(ctx, "Unknown feature flag: %s", name)
+		return false
+	}
+	return Resolve(ctx, def, project.FromContext(ctx)).On(name, key)
+}
+
+// Set 保存开关在全局（projectID 为空）或某个项目的设置，调用方需有修改权限（见 checkManage）
+func Set(ctx context.Context, name, projectID string, enabled bool, rollout int) error {
+	if Lookup(name) == nil {
+		return gerror.NewCodef(gcode.CodeNotFound, "feature flag not found: %s", name)
+	}
+	if rollout < 0 || rollout > 100 {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "rollout must be between 0 and 100")
+	}
+	if projectID != "" {
+		if _, err := project.Get(ctx, projectID); err != nil {
+			return err
+		}
+	}
+	if err := checkManage(ctx, projectID); err != nil {
+		return err
+	}
+	defer invalidate()
+	return dao.FeatureFlag.Save(ctx, &gormModel.FeatureFlag{Name: name, ProjectID: projectID, Enabled: enabled, Rollout: rollout})
+}
+
+// Reset 删除开关在全局或某个项目的设置，恢复为上一级设置，调用方需有修改权限（见 checkManage）
+func Reset(ctx context.Context, name, projectID string) error {
+	if Lookup(name) == nil {
+		return gerror.NewCodef(gcode.CodeNotFound, "feature flag not found: %s", name)
+	}
+	if err := checkManage(ctx, projectID); err != nil {
+		return err
+	}
+	defer invalidate()
+	return dao.FeatureFlag.Delete(ctx, name, projectID)
+}
+
+// checkManage 校验调用方能否修改开关：全局设置只有运维管理员（auth.admins）可以修改，项目设置还允许项目所有者修改；
+// 未配置任何凭证时不做限制
+func checkManage(ctx context.Context, projectID string) error {
+	if projectID != "" {
+		return project.CheckRole(ctx, projectID, project.RoleOwner)
+	}
+	if !identity.IsAdmin(ctx) {
+		return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s is not allowed to change global feature flags", identity.UserID(ctx))
+	}
+	return nil
+}
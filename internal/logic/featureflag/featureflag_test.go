@@ -0,0 +1,102 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+func TestResolve(t *testing.T) {
+	on := &Definition{Name: "on", Default: true}
+	off := &Definition{Name: "off"}
+	global := &gormModel.FeatureFlag{Enabled: true, Rollout: 30}
+	scoped := &gormModel.FeatureFlag{Enabled: false, Rollout: 100}
+	tests := []struct {
+		name   string
+		def    *Definition
+		env    string
+		global *gormModel.FeatureFlag
+		scoped *gormModel.FeatureFlag
+		want   State
+	}{
+		{name: "default on", def: on, want: State{Enabled: true, Rollout: 100, Source: SourceDefault}},
+		{name: "default off", def: off, want: State{Source: SourceDefault}},
+		{name: "global", def: off, global: global, want: State{Enabled: true, Rollout: 30, Source: SourceGlobal}},
+		{name: "project overrides global", def: on, global: global, scoped: scoped, want: State{Rollout: 100, Source: SourceProject}},
+		{name: "env overrides project", def: on, env: "off", global: global, scoped: scoped, want: State{Source: SourceEnv}},
+		{name: "env rollout", def: off, env: " 25 ", want: State{Enabled: true, Rollout: 25, Source: SourceEnv}},
+		{name: "invalid env ignored", def: on, env: "150", scoped: scoped, want: State{Rollout: 100, Source: SourceProject}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolve(context.Background(), tt.def, tt.env, tt.global, tt.scoped); got != tt.want {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateOn(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("kb-%d", i)
+	}
+	count := func(state State) int {
+		n := 0
+		for _, key := range keys {
+			if state.On(HybridRetrieval, key) {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := count(State{Enabled: true, Rollout: 100}); n != len(keys) {
+		t.Errorf("rollout 100 enabled %d keys, want all", n)
+	}
+	if n := count(State{Enabled: false, Rollout: 100}); n != 0 {
+		t.Errorf("disabled flag enabled %d keys, want 0", n)
+	}
+	if n := count(State{Enabled: true, Rollout: 0}); n != 0 {
+		t.Errorf("rollout 0 enabled %d keys, want 0", n)
+	}
+	if n := count(State{Enabled: true, Rollout: 20}); n < 150 || n > 250 {
+		t.Errorf("rollout 20 enabled %d of %d keys, want about 20%%", n, len(keys))
+	}
+
+	// 灰度比例增大时已开启的 key 保持开启
+	for _, key := range keys {
+		if (State{Enabled: true, Rollout: 20}).On(HybridRetrieval, key) && !(State{Enabled: true, Rollout: 50}).On(HybridRetrieval, key) {
+			t.Fatalf("key %s enabled at 20%% but not at 50%%", key)
+		}
+	}
+}
+
+func TestGlobalChangeRequiresAdmin(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("auth:\n  admins: [\"ops\"]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	ctx := identity.WithUser(identity.WithIsolation(context.Background()), "alice")
+	name := Definitions()[0].Name
+	if err = Set(ctx, name, "", true, 100); gerror.Code(err) != gcode.CodeNotAuthorized {
+		t.Errorf("Set() error = %v, want not authorized", err)
+	}
+	if err = Reset(ctx, name, ""); gerror.Code(err) != gcode.CodeNotAuthorized {
+		t.Errorf("Reset() error = %v, want not authorized", err)
+	}
+	if err = checkManage(identity.WithUser(identity.WithIsolation(context.Background()), "ops"), ""); err != nil {
+		t.Errorf("checkManage() for admin error = %v", err)
+	}
+}
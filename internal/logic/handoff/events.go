@@ -0,0 +1,67 @@
+package handoff
+
+import (
+	"sync"
+)
+
+// 推送给用户的事件类型
+const (
+	EventOpened   = "opened"   // 会话已转人工
+	EventMessage  = "message"  // 会话中的新消息（用户或人工客服）
+	EventResolved = "resolved" // 人工接管结束
+)
+
+// subscriberBuffer 每个订阅者的事件缓冲，消费过慢时丢弃新事件（消息已持久化，可从会话历史补齐）
+const subscriberBuffer = 32
+
+// Event 人工接管期间的会话事件
+type Event struct {
+	Type     string `json:"type"`
+	TicketID string `json:"ticket_id"`
+	ConvID   string `json:"conv_id"`
+	Role     string `json:"role,omitempty"`
+	Agent    string `json:"agent,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Created  int64  `json:"created"`
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = make(map[string]map[chan *Event]struct{}) // 会话ID -> 订阅者
+)
+
+// Subscribe 订阅会话事件，返回事件通道和取消订阅函数
+// 事件只在当前进程内分发，多实例部署时需将同一会话的请求路由到同一实例
+func Subscribe(convID string) (<-chan *Event, func()) {
+	ch := make(chan *Event, subscriberBuffer)
+	subscribersMu.Lock()
+	if subscribers[convID] == nil {
+		subscribers[convID] = make(map[chan *Event]struct{})
+	}
+	subscribers[convID][ch] = struct{}{}
+	subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			subscribersMu.Lock()
+			delete(subscribers[convID], ch)
+			if len(subscribers[convID]) == 0 {
+				delete(subscribers, convID)
+			}
+			subscribersMu.Unlock()
+		})
+	}
+}
+
+// publish 向会话的所有订阅者分发事件
+func publish(event *Event) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for ch := range subscribers[event.ConvID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
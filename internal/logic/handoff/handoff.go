@@ -0,0 +1,261 @@
+package handoff
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// 默认的转人工关键词
+var defaultKeywords = []string{"转人工", "人工客服", "真人客服", "human agent", "talk to a human"}
+
+// openMu 串行化工单创建，避免同一会话并发请求创建多个工单
+var openMu sync.Mutex
+
+// Enabled 是否启用人工接管
+func Enabled(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "handoff.enabled", false).Bool()
+}
+
+// Notice 会话转人工后返回给用户的提示
+func Notice(ctx context.Context) string {
+	return g.Cfg().MustGet(ctx, "handoff.notice", "已为您转接人工客服，请稍候，客服回复会实时推送给您。").String()
+}
+
+// HeaderAgentKey 人工客服凭证请求头，与用户凭证（auth 段）分开配置
+const HeaderAgentKey = "X-Helpdesk-Key"
+
+// AuthorizeAgent 校验人工客服凭证（handoff.agentKeys 中凭证到客服名称的映射），返回客服名称；
+// 未配置客服凭证且未配置任何用户凭证（见 identity.Isolated）时不做限制，返回空字符串
+func AuthorizeAgent(ctx context.Context, key string) (string, error) {
+	return authorizeAgent(g.Cfg().MustGet(ctx, "handoff.agentKeys").MapStrStr(), identity.Isolated(ctx), key)
+}
+
+func authorizeAgent(agentKeys map[string]string, isolated bool, key string) (string, error) {
+	if len(agentKeys) == 0 {
+		if isolated {
+			return "", gerror.NewCode(gcode.CodeNotAuthorized, "helpdesk endpoints require handoff.agentKeys to be configured")
+		}
+		return "", nil
+	}
+	if key = strings.TrimSpace(key); key != "" {
+		for candidate, agent := range agentKeys {
+			if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 && agent != "" {
+				return agent, nil
+			}
+		}
+	}
+	return "", gerror.NewCodef(gcode.CodeNotAuthorized, "invalid or missing %s header", HeaderAgentKey)
+}
+
+// RequestsHuman 用户问题中是否包含转人工关键词
+func RequestsHuman(ctx context.Context, question string) bool {
+	keywords := g.Cfg().MustGet(ctx, "handoff.keywords", defaultKeywords).Strings()
+	return containsKeyword(question, keywords)
+}
+
+// ActiveTicket 获取会话未结束的工单，未启用或会话未转人工时返回 nil
+func ActiveTicket(ctx context.Context, convID string) (*gormModel.HandoffTicket, error) {
+	if !Enabled(ctx) || convID == "" {
+		return nil, nil
+	}
+	return dao.HandoffTicket.GetActiveByConvID(ctx, convID)
+}
+
+// Open 为会话创建人工接管工单并通知外部工单系统，会话已有未结束工单时直接返回该工单
+func Open(ctx context.Context, convID, reason, question string, confidence *float64) (*gormModel.HandoffTicket, error) {
+	openMu.Lock()
+	defer openMu.Unlock()
+
+	ticket, err := dao.HandoffTicket.GetActiveByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket != nil {
+		return ticket, nil
+	}
+
+	ticket = &gormModel.HandoffTicket{
+		ID:         uuid.New().String(),
+		ConvID:     convID,
+		Reason:     reason,
+		Question:   question,
+		Confidence: confidence,
+		Status:     gormModel.HandoffStatusOpen,
+	}
+	if err = dao.HandoffTicket.Create(ctx, ticket); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Handoff ticket %s opened for conversation %s, reason=%s", ticket.ID, convID, reason)
+
+	publish(&Event{Type: EventOpened, TicketID: ticket.ID, ConvID: convID, Created: time.Now().Unix()})
+	notifyWebhook(ctx, "handoff_opened", ticket, question)
+	return ticket, nil
+}
+
+// ForwardUserMessage 会话转人工期间保存用户消息并转发给人工客服
+func ForwardUserMessage(ctx context.Context, ticket *gormModel.HandoffTicket, content string) error {
+	message := &schema.Message{Role: schema.User, Content: content}
+	if err := history.NewManager().SaveMessageWithMetadata(ctx, message, ticket.ConvID, map[string]interface{}{
+		"handoff_ticket_id": ticket.ID,
+	}); err != nil {
+		return err
+	}
+	publish(&Event{
+		Type:     EventMessage,
+		TicketID: ticket.ID,
+		ConvID:   ticket.ConvID,
+		Role:     string(schema.User),
+		Content:  content,
+		Created:  time.Now().Unix(),
+	})
+	notifyWebhook(ctx, "handoff_user_message", ticket, content)
+	return nil
+}
+
+// PostAgentMessage 人工客服向会话发送消息，消息保存到会话历史并推送给用户
+func PostAgentMessage(ctx context.Context, ticketID, agent, content string) (*gormModel.HandoffTicket, error) {
+	ticket, err := getActive(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &schema.Message{Role: schema.Assistant, Content: content}
+	if err = history.NewManager().SaveMessageWithMetadata(ctx, message, ticket.ConvID, map[string]interface{}{
+		"handoff_ticket_id": ticket.ID,
+		"agent":             agent,
+	}); err != nil {
+		return nil, err
+	}
+
+	// 人工客服首次回复时工单转为处理中，并记录处理人
+	if ticket.Status == gormModel.HandoffStatusOpen || (agent != "" && ticket.Assignee != agent) {
+		fields := map[string]interface{}{"status": gormModel.HandoffStatusAssigned}
+		if agent != "" {
+			fields["assignee"] = agent
+			ticket.Assignee = agent
+		}
+		if err = dao.HandoffTicket.Update(ctx, ticket.ID, fields); err != nil {
+			return nil, err
+		}
+		ticket.Status = gormModel.HandoffStatusAssigned
+	}
+
+	publish(&Event{
+		Type:     EventMessage,
+		TicketID: ticket.ID,
+		ConvID:   ticket.ConvID,
+		Role:     string(schema.Assistant),
+		Agent:    agent,
+		Content:  content,
+		Created:  time.Now().Unix(),
+	})
+	return ticket, nil
+}
+
+// Resolve 结束工单，会话恢复由 AI 回答
+func Resolve(ctx context.Context, ticketID string) (*gormModel.HandoffTicket, error) {
+	ticket, err := getActive(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err = dao.HandoffTicket.Update(ctx, ticket.ID, map[string]interface{}{
+		"status":      gormModel.HandoffStatusResolved,
+		"resolved_at": &now,
+	}); err != nil {
+		return nil, err
+	}
+	ticket.Status = gormModel.HandoffStatusResolved
+	ticket.ResolvedAt = &now
+	g.Log().Infof(ctx, "Handoff ticket %s resolved", ticket.ID)
+
+	publish(&Event{Type: EventResolved, TicketID: ticket.ID, ConvID: ticket.ConvID, Agent: ticket.Assignee, Created: now.Unix()})
+	notifyWebhook(ctx, "handoff_resolved", ticket, "")
+	return ticket, nil
+}
+
+// GetTicket 获取工单详情
+func GetTicket(ctx context.Context, ticketID string) (*gormModel.HandoffTicket, error) {
+	ticket, err := dao.HandoffTicket.GetByID(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket == nil {
+		return nil, fmt.Errorf("handoff ticket not found: %s", ticketID)
+	}
+	return ticket, nil
+}
+
+// ListTickets 获取工单列表
+func ListTickets(ctx context.Context, convID, status string) ([]*gormModel.HandoffTicket, error) {
+	return dao.HandoffTicket.List(ctx, convID, status)
+}
+
+func getActive(ctx context.Context, ticketID string) (*gormModel.HandoffTicket, error) {
+	ticket, err := GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status == gormModel.HandoffStatusResolved {
+		return nil, fmt.Errorf("handoff ticket %s is already resolved", ticketID)
+	}
+	return ticket, nil
+}
+
+// notifyWebhook 异步通知外部工单系统（handoff.webhook），未配置时跳过
+func notifyWebhook(ctx context.Context, event string, ticket *gormModel.HandoffTicket, content string) {
+	webhook := g.Cfg().MustGet(ctx, "handoff.webhook", "").String()
+	if webhook == "" {
+		return
+	}
+	payload := g.Map{
+		"event":      event,
+		"ticket_id":  ticket.ID,
+		"conv_id":    ticket.ConvID,
+		"reason":     ticket.Reason,
+		"status":     ticket.Status,
+		"assignee":   ticket.Assignee,
+		"question":   ticket.Question,
+		"confidence": ticket.Confidence,
+		"content":    content,
+		"time":       time.Now().Format(time.RFC3339),
+	}
+	webhookCtx := common.DetachContext(ctx)
+	common.SafeGo(webhookCtx, "HandoffWebhook", func() {
+		resp, err := g.Client().Timeout(10*time.Second).ContentJson().Post(webhookCtx, webhook, payload)
+		if err != nil {
+			g.Log().Errorf(webhookCtx, "Failed to call handoff webhook: %v", err)
+			return
+		}
+		defer resp.Close()
+		if resp.StatusCode >= 300 {
+			g.Log().Errorf(webhookCtx, "Handoff webhook returned status %d: %s", resp.StatusCode, resp.ReadAllString())
+		}
+	})
+}
+
+// containsKeyword 文本是否包含任一关键词（不区分大小写）
+func containsKeyword(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
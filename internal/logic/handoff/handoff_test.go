@@ -0,0 +1,85 @@
+package handoff
+
+import (
+	"testing"
+)
+
+// TestContainsKeyword 测试转人工关键词匹配
+func TestContainsKeyword(t *testing.T) {
+	tests := []struct {
+		name     string
+		question string
+		want     bool
+	}{
+		{name: "Chinese keyword", question: "帮我转人工吧", want: true},
+		{name: "Case insensitive", question: "Can I talk to a HUMAN AGENT?", want: true},
+		{name: "No keyword", question: "如何上传文档？", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsKeyword(tt.question, defaultKeywords); got != tt.want {
+				t.Errorf("containsKeyword(%q) = %v, want %v", tt.question, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSubscribe 测试事件只分发给同一会话的订阅者，取消订阅后不再接收
+func TestSubscribe(t *testing.T) {
+	events, cancel := Subscribe("conv-1")
+	other, cancelOther := Subscribe("conv-2")
+	defer cancelOther()
+
+	publish(&Event{Type: EventMessage, ConvID: "conv-1", Content: "您好"})
+	select {
+	case event := <-events:
+		if event.Content != "您好" {
+			t.Errorf("unexpected event content %q", event.Content)
+		}
+	default:
+		t.Fatal("subscriber did not receive event")
+	}
+	select {
+	case event := <-other:
+		t.Fatalf("event leaked to another conversation: %+v", event)
+	default:
+	}
+
+	cancel()
+	cancel()
+	publish(&Event{Type: EventResolved, ConvID: "conv-1"})
+	select {
+	case event := <-events:
+		t.Fatalf("received event after unsubscribe: %+v", event)
+	default:
+	}
+	if _, ok := subscribers["conv-1"]; ok {
+		t.Error("empty subscriber set should be removed")
+	}
+}
+
+func TestAuthorizeAgent(t *testing.T) {
+	keys := map[string]string{"hd-1": "carol"}
+	tests := []struct {
+		name      string
+		keys      map[string]string
+		isolated  bool
+		key       string
+		wantAgent string
+		wantErr   bool
+	}{
+		{name: "Valid key", keys: keys, key: "hd-1", wantAgent: "carol"},
+		{name: "Unknown key", keys: keys, key: "user-key", wantErr: true},
+		{name: "Missing key", keys: keys, wantErr: true},
+		{name: "Open deployment", wantAgent: ""},
+		{name: "User credentials without helpdesk keys", isolated: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := authorizeAgent(tt.keys, tt.isolated, tt.key)
+			if (err != nil) != tt.wantErr || agent != tt.wantAgent {
+				t.Errorf("authorizeAgent() = %q, %v, want %q, error %v", agent, err, tt.wantAgent, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Package identity 识别请求的调用用户：从 JWT、API Key 或可信网关请求头中解析用户ID并写入上下文，
+// 会话创建、消息保存和知识库检索按上下文中的用户隔离
+package identity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// DefaultUserID 请求未携带身份时使用的用户ID（未启用认证时所有请求共用该用户）
+const DefaultUserID = "default_user"
+
+// 请求头
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderAPIKey        = "X-API-Key"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid or expired token")
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrUnauthorized  = errors.New("authentication required")
+)
+
+type (
+	userKey      struct{}
+	isolationKey struct{}
+)
+
+// Config 认证配置，对应配置文件 auth 段
+type Config struct {
+	Required   bool              // 是否要求所有请求携带身份，否则未携带身份的请求使用 DefaultUserID
+	JWTSecret  string            // HS256 JWT 签名密钥，为空表示不接受 JWT
+	UserClaim  string            // JWT 中用户ID所在的字段，默认 sub
+	APIKeys    map[string]string // API Key 到用户ID的映射
+	UserHeader string            // 可信的用户ID请求头，需由上游网关在完成认证后设置，为空表示不使用
+}
+
+// LoadConfig 读取认证配置
+func LoadConfig(ctx context.Context) *Config {
+	return &Config{
+		Required:   g.Cfg().MustGet(ctx, "auth.required", false).Bool(),
+		JWTSecret:  g.Cfg().MustGet(ctx, "auth.jwtSecret").String(),
+		UserClaim:  g.Cfg().MustGet(ctx, "auth.userClaim", "sub").String(),
+		APIKeys:    g.Cfg().MustGet(ctx, "auth.apiKeys").MapStrStr(),
+		UserHeader: g.Cfg().MustGet(ctx, "auth.userHeader").String(),
+	}
+}
+
+// WithUser 在上下文中记录已认证的用户
+func WithUser(ctx context.Context, userID string) context.Context {
+	if userID = strings.TrimSpace(userID); userID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// WithIsolation 标记本次请求按用户隔离：未携带身份的请求视为 DefaultUserID，不能访问其他用户的资源
+func WithIsolation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, isolationKey{}, true)
+}
+
+// Isolated 本次请求是否按用户隔离（已认证或配置了凭证）
+func Isolated(ctx context.Context) bool {
+	if _, ok := FromContext(ctx); ok {
+		return true
+	}
+	isolated, _ := ctx.Value(isolationKey{}).(bool)
+	return isolated
+}
+
+// FromContext 获取已认证的用户，请求未携带身份时返回 false
+func FromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// UserID 获取本次请求的用户，未携带身份时返回 DefaultUserID
+func UserID(ctx context.Context) string {
+	if userID, ok := FromContext(ctx); ok {
+		return userID
+	}
+	return DefaultUserID
+}
+
+// IsAdmin 本次请求的用户是否为运维管理员（auth.admins 中的用户），管理员可以管理任意项目和全局设置；
+// 未配置任何凭证时不区分用户，所有请求都视为管理员
+func IsAdmin(ctx context.Context) bool {
+	if !Isolated(ctx) {
+		return true
+	}
+	userID, ok := FromContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, admin := range g.Cfg().MustGet(ctx, "auth.admins").Strings() {
+		if strings.TrimSpace(admin) == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve 确定请求参数中的用户：按用户隔离时始终使用认证用户（未携带身份时为 DefaultUserID），防止冒用他人身份；
+// 未配置任何凭证时沿用请求参数
+func Resolve(ctx context.Context, requested string) string {
+	if Isolated(ctx) {
+		return UserID(ctx)
+	}
+	return requested
+}
+
+// Owns 判断用户能否访问属于 owner 的资源：无主资源和启用认证前创建的 DefaultUserID 资源不做限制；
+// 未配置任何凭证时未认证的请求不做限制
+func Owns(ctx context.Context, owner string) bool {
+	if !Isolated(ctx) {
+		return true
+	}
+	return owner == "" || owner == DefaultUserID || owner == UserID(ctx)
+}
+
+// Authenticate 从请求头解析用户ID，header 按名称返回请求头的值
+// 依次尝试 Authorization: Bearer（JWT 或 API Key）、X-API-Key 和可信用户请求头；
+// 携带了凭证但校验失败时返回错误，未携带任何凭证时返回空字符串（Required 时返回 ErrUnauthorized）；
+// 未配置 API Key 和 JWT 密钥且不要求认证时忽略 Bearer 凭证（如 OpenAI SDK 总是发送的 api_key），按未携带身份处理
+func (c *Config) Authenticate(header func(string) string, now time.Time) (string, error) {
+	if token, ok := bearerToken(header(HeaderAuthorization)); ok && c.acceptsBearer() {
+		if strings.Count(token, ".") == 2 && c.JWTSecret != "" {
+			return c.verifyJWT(token, now)
+		}
+		return c.lookupAPIKey(token)
+	}
+	if key := strings.TrimSpace(header(HeaderAPIKey)); key != "" {
+		return c.lookupAPIKey(key)
+	}
+	if c.UserHeader != "" {
+		if userID := strings.TrimSpace(header(c.UserHeader)); userID != "" {
+			return userID, nil
+		}
+	}
+	if c.Required {
+		return "", ErrUnauthorized
+	}
+	return "", nil
+}
+
+// Isolated 是否按用户隔离资源：要求认证或配置了 API Key、JWT 密钥、可信用户请求头时，
+// 未携带身份的请求只能访问 DefaultUserID 的资源
+func (c *Config) Isolated() bool {
+	return c.acceptsBearer() || c.UserHeader != ""
+}
+
+// acceptsBearer 是否校验 Bearer 凭证：配置了 API Key 或 JWT 密钥，或者要求认证（此时无法校验的凭证被拒绝）
+func (c *Config) acceptsBearer() bool {
+	return c.Required || c.JWTSecret != "" || len(c.APIKeys) > 0
+}
+
+// bearerToken 解析 Authorization: Bearer <token>
+func bearerToken(value string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// lookupAPIKey 查找 API Key 对应的用户，使用常量时间比较
+func (c *Config) lookupAPIKey(key string) (string, error) {
+	for candidate, userID := range c.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 && userID != "" {
+			return userID, nil
+		}
+	}
+	return "", ErrInvalidAPIKey
+}
+
+// verifyJWT 校验 HS256 签名和过期时间（exp/nbf），返回用户字段的值
+func (c *Config) verifyJWT(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if json.Unmarshal(headerJSON, &header) != nil || header.Alg != "HS256" {
+		return "", ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, []byte(c.JWTSecret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var claims map[string]any
+	if json.Unmarshal(payload, &claims) != nil {
+		return "", ErrInvalidToken
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return "", ErrInvalidToken
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return "", ErrInvalidToken
+	}
+
+	claim := c.UserClaim
+	if claim == "" {
+		claim = "sub"
+	}
+	userID, _ := claims[claim].(string)
+	if userID = strings.TrimSpace(userID); userID == "" {
+		return "", ErrInvalidToken
+	}
+	return userID, nil
+}
@@ -0,0 +1,150 @@
+package identity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// signJWT 生成 HS256 JWT
+func signJWT(secret string, claims map[string]any) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payloadJSON, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cfg := &Config{
+		JWTSecret:  "secret",
+		APIKeys:    map[string]string{"key-1": "alice"},
+		UserHeader: "X-User-ID",
+	}
+	valid := signJWT("secret", map[string]any{"sub": "bob", "exp": now.Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+		wantErr error
+	}{
+		{name: "no credentials", headers: nil, want: ""},
+		{name: "jwt", headers: map[string]string{HeaderAuthorization: "Bearer " + valid}, want: "bob"},
+		{name: "expired jwt", headers: map[string]string{HeaderAuthorization: "Bearer " + signJWT("secret", map[string]any{"sub": "bob", "exp": now.Add(-time.Minute).Unix()})}, wantErr: ErrInvalidToken},
+		{name: "wrong signature", headers: map[string]string{HeaderAuthorization: "Bearer " + signJWT("other", map[string]any{"sub": "bob"})}, wantErr: ErrInvalidToken},
+		{name: "missing subject", headers: map[string]string{HeaderAuthorization: "Bearer " + signJWT("secret", map[string]any{"name": "bob"})}, wantErr: ErrInvalidToken},
+		{name: "bearer api key", headers: map[string]string{HeaderAuthorization: "Bearer key-1"}, want: "alice"},
+		{name: "api key header", headers: map[string]string{HeaderAPIKey: "key-1"}, want: "alice"},
+		{name: "unknown api key", headers: map[string]string{HeaderAPIKey: "key-2"}, wantErr: ErrInvalidAPIKey},
+		{name: "trusted header", headers: map[string]string{"X-User-ID": " carol "}, want: "carol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cfg.Authenticate(func(name string) string { return tt.headers[name] }, now)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Authenticate() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	cfg.Required = true
+	if _, err := cfg.Authenticate(func(string) string { return "" }, now); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// TestAuthenticateUnconfigured 测试未配置任何凭证且不要求认证时忽略 Bearer 凭证（如 OpenAI SDK 的 api_key），要求认证时拒绝
+func TestAuthenticateUnconfigured(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cfg := &Config{UserHeader: "X-User-ID"}
+	headers := map[string]string{HeaderAuthorization: "Bearer sk-anything"}
+	header := func(name string) string { return headers[name] }
+
+	if got, err := cfg.Authenticate(header, now); err != nil || got != "" {
+		t.Errorf("Authenticate() = %q, %v, want anonymous", got, err)
+	}
+	headers["X-User-ID"] = "carol"
+	if got, err := cfg.Authenticate(header, now); err != nil || got != "carol" {
+		t.Errorf("Authenticate() = %q, %v, want carol from trusted header", got, err)
+	}
+
+	cfg.Required = true
+	if _, err := cfg.Authenticate(header, now); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected ErrInvalidAPIKey when auth is required, got %v", err)
+	}
+}
+
+func TestContextUser(t *testing.T) {
+	ctx := context.Background()
+	if got := UserID(ctx); got != DefaultUserID {
+		t.Errorf("UserID() = %q, want %q", got, DefaultUserID)
+	}
+	if got := Resolve(ctx, "requested"); got != "requested" {
+		t.Errorf("Resolve() = %q, want requested", got)
+	}
+	if !Owns(ctx, "anyone") {
+		t.Error("unauthenticated requests should not be restricted")
+	}
+
+	ctx = WithUser(ctx, "alice")
+	if got := Resolve(ctx, "mallory"); got != "alice" {
+		t.Errorf("Resolve() = %q, want alice", got)
+	}
+	if !Owns(ctx, "alice") || !Owns(ctx, DefaultUserID) || Owns(ctx, "bob") {
+		t.Error("unexpected ownership result")
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	ctx := WithIsolation(context.Background())
+	if got := Resolve(ctx, "mallory"); got != DefaultUserID {
+		t.Errorf("Resolve() = %q, want %q", got, DefaultUserID)
+	}
+	if Owns(ctx, "bob") {
+		t.Error("unauthenticated requests should not access other users' resources when credentials are configured")
+	}
+	if !Owns(ctx, DefaultUserID) || !Owns(ctx, "") {
+		t.Error("unauthenticated requests should access default_user and unowned resources")
+	}
+
+	if (&Config{}).Isolated() {
+		t.Error("empty config should not isolate users")
+	}
+	for _, cfg := range []*Config{{Required: true}, {JWTSecret: "s"}, {APIKeys: map[string]string{"k": "alice"}}, {UserHeader: "X-User-ID"}} {
+		if !cfg.Isolated() {
+			t.Errorf("%+v should isolate users", cfg)
+		}
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("auth:\n  admins: [\"ops\"]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	if !IsAdmin(context.Background()) {
+		t.Error("all requests should be admins when users are not isolated")
+	}
+	isolated := WithIsolation(context.Background())
+	if !IsAdmin(WithUser(isolated, "ops")) {
+		t.Error("ops should be an admin")
+	}
+	if IsAdmin(WithUser(isolated, "alice")) || IsAdmin(isolated) {
+		t.Error("alice and unauthenticated requests should not be admins")
+	}
+}
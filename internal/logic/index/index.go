@@ -1,11 +1,14 @@
 package index
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/Malowking/kbgo/core"
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/core/config"
 	"github.com/Malowking/kbgo/core/indexer"
+	coreModel "github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/internal/service"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
@@ -67,3 +70,36 @@ func InitDocumentIndexer() {
 func GetDocIndexSvr() *indexer.DocumentIndexer {
 	return docIndexSvr
 }
+
+// ResolveCollectionDim 确定新建集合的向量维度
+// 指定 embedding 模型时优先使用模型 extra 中的 dimension，否则探测模型实际输出维度；未指定模型时使用配置文件中的 dim
+func ResolveCollectionDim(ctx context.Context, embeddingModelID string) (int, error) {
+	fallback := 1024
+	if indexConfig != nil && indexConfig.Dim > 0 {
+		fallback = indexConfig.Dim
+	}
+	if embeddingModelID == "" {
+		return fallback, nil
+	}
+
+	mc := coreModel.Registry.Get(embeddingModelID)
+	if mc == nil {
+		return 0, fmt.Errorf("embedding model not found: %s", embeddingModelID)
+	}
+	if mc.Type != coreModel.ModelTypeEmbedding {
+		return 0, fmt.Errorf("model %s is not an embedding model, got type: %s", embeddingModelID, mc.Type)
+	}
+	if dim, ok := mc.Extra["dimension"].(float64); ok && dim > 0 {
+		return int(dim), nil
+	}
+
+	embedder, err := common.NewEmbedding(ctx, &config.RetrieverConfigBase{
+		APIKey:         mc.APIKey,
+		BaseURL:        mc.BaseURL,
+		EmbeddingModel: mc.Name,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create embedder: %w", err)
+	}
+	return embedder.DetectDimension(ctx)
+}
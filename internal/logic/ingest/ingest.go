@@ -0,0 +1,172 @@
+// Package ingest 文档索引失败处理：步骤级重试的退避策略，以及重试耗尽后的死信队列（记录、查询、重新提交和 webhook 通知）
+package ingest
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// RetryPolicy 索引步骤的重试策略，第 n 次重试前等待 InitialDelay * 2^(n-1)，不超过 MaxDelay
+type RetryPolicy struct {
+	MaxAttempts  int // 每个步骤的最多尝试次数（含第一次），1 表示不重试
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// LoadRetryPolicy 从 ingest.retry 配置读取重试策略
+func LoadRetryPolicy(ctx context.Context) RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts:  g.Cfg().MustGet(ctx, "ingest.retry.maxAttempts", 3).Int(),
+		InitialDelay: g.Cfg().MustGet(ctx, "ingest.retry.initialDelay", "2s").Duration(),
+		MaxDelay:     g.Cfg().MustGet(ctx, "ingest.retry.maxDelay", "1m").Duration(),
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return policy
+}
+
+// Delay 第 retry 次重试（从 1 开始）前的等待时间
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Wait 等待第 retry 次重试，上下文取消时返回错误
+func (p RetryPolicy) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.Delay(retry))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Failure 重试耗尽的索引失败
+type Failure struct {
+	DocumentID  string
+	KnowledgeID string
+	FileName    string
+	Step        string
+	Err         error
+	Attempts    int
+	// 索引参数，重新提交时使用
+	ModelID     string
+	ChunkSize   int
+	OverlapSize int
+	Separator   string
+}
+
+// DeadLetter 把重试耗尽的失败写入死信队列并通知 ingest.deadLetterWebhook，写入失败只记录日志
+func DeadLetter(ctx context.Context, failure *Failure) {
+	now := time.Now()
+	letter, err := dao.IngestDeadLetter.Save(ctx, &gormModel.IngestDeadLetter{
+		ID:          strings.ReplaceAll(uuid.New().String(), "-", ""),
+		DocumentID:  failure.DocumentID,
+		KnowledgeID: failure.KnowledgeID,
+		FileName:    failure.FileName,
+		Step:        failure.Step,
+		Error:       failure.Err.Error(),
+		Attempts:    failure.Attempts,
+		Failures:    1,
+		ModelID:     failure.ModelID,
+		ChunkSize:   failure.ChunkSize,
+		OverlapSize: failure.OverlapSize,
+		Separator:   failure.Separator,
+		Status:      gormModel.DeadLetterStatusDead,
+		CreateTime:  &now,
+		UpdateTime:  &now,
+	})
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to save ingest dead letter, documentId=%s, err=%v", failure.DocumentID, err)
+		return
+	}
+	g.Log().Warningf(ctx, "Document moved to ingest dead letter queue, documentId=%s, step=%s, attempts=%d, err=%v",
+		failure.DocumentID, failure.Step, failure.Attempts, failure.Err)
+	notifyWebhook(ctx, letter)
+}
+
+// Resolve 文档索引成功后移除其死信
+func Resolve(ctx context.Context, documentID string) {
+	if err := dao.IngestDeadLetter.DeleteByDocumentID(ctx, documentID); err != nil {
+		g.Log().Warningf(ctx, "Failed to remove ingest dead letter, documentId=%s, err=%v", documentID, err)
+	}
+}
+
+// List 分页查询死信
+func List(ctx context.Context, knowledgeID, status string, page, pageSize int) ([]*gormModel.IngestDeadLetter, int64, error) {
+	return dao.IngestDeadLetter.List(ctx, knowledgeID, status, page, pageSize)
+}
+
+// Requeue 标记死信为已重新提交并返回，由调用方按记录的索引参数重新索引文档
+func Requeue(ctx context.Context, id string) (*gormModel.IngestDeadLetter, error) {
+	letter, err := dao.IngestDeadLetter.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "dead letter not found: %s", id)
+	}
+	if letter.Status == gormModel.DeadLetterStatusRequeued {
+		return nil, gerror.NewCodef(gcode.CodeInvalidOperation, "dead letter %s has already been requeued", id)
+	}
+	now := time.Now()
+	if err = dao.IngestDeadLetter.Update(ctx, id, map[string]interface{}{
+		"status":       gormModel.DeadLetterStatusRequeued,
+		"requeue_time": now,
+	}); err != nil {
+		return nil, err
+	}
+	letter.Status = gormModel.DeadLetterStatusRequeued
+	letter.RequeueTime = &now
+	return letter, nil
+}
+
+// notifyWebhook 异步通知 ingest.deadLetterWebhook，未配置时跳过
+func notifyWebhook(ctx context.Context, letter *gormModel.IngestDeadLetter) {
+	webhook := g.Cfg().MustGet(ctx, "ingest.deadLetterWebhook", "").String()
+	if webhook == "" {
+		return
+	}
+	payload := g.Map{
+		"event":          "ingest.dead_letter",
+		"dead_letter_id": letter.ID,
+		"document_id":    letter.DocumentID,
+		"knowledge_id":   letter.KnowledgeID,
+		"file_name":      letter.FileName,
+		"step":           letter.Step,
+		"error":          letter.Error,
+		"attempts":       letter.Attempts,
+		"failures":       letter.Failures,
+		"time":           time.Now().Format(time.RFC3339),
+	}
+	webhookCtx := common.DetachContext(ctx)
+	common.SafeGo(webhookCtx, "IngestDeadLetterWebhook", func() {
+		resp, err := g.Client().Timeout(10*time.Second).ContentJson().Post(webhookCtx, webhook, payload)
+		if err != nil {
+			g.Log().Errorf(webhookCtx, "Failed to call ingest dead letter webhook: %v", err)
+			return
+		}
+		defer resp.Close()
+		if resp.StatusCode >= 300 {
+			g.Log().Errorf(webhookCtx, "Ingest dead letter webhook returned status %d: %s", resp.StatusCode, resp.ReadAllString())
+		}
+	})
+}
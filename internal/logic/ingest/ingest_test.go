@@ -0,0 +1,30 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialDelay: 2 * time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{10, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.Delay(tt.retry); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+
+	unlimited := RetryPolicy{InitialDelay: time.Second}
+	if got := unlimited.Delay(3); got != 4*time.Second {
+		t.Errorf("Delay without MaxDelay = %s, want 4s", got)
+	}
+}
@@ -0,0 +1,204 @@
+// Package kbadvisor 知识库内容分析与优化建议：文档索引完成后抽样统计知识库的语言构成和分片长度，
+// 据此建议更换 embedding 模型（如多语言知识库使用多语言模型）或调整分片大小，更换模型可一键创建迁移任务
+package kbadvisor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/reembed"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// Advice 知识库的内容画像和优化建议
+type Advice struct {
+	Profile          *gormModel.KnowledgeProfile
+	Languages        map[string]float64
+	EmbeddingModelID string // 知识库最近索引文档使用的 embedding 模型
+	Recommendations  []*Recommendation
+}
+
+func init() {
+	kbevent.Subscribe(onKnowledgeChanged)
+}
+
+// onKnowledgeChanged 文档索引或更新后延迟重新分析知识库，知识库删除时清除画像
+func onKnowledgeChanged(ctx context.Context, event *kbevent.Event) {
+	if event.KnowledgeID == "" {
+		return
+	}
+	if event.Type == kbevent.TypeDeleted && event.DocumentID == "" {
+		_ = dao.KnowledgeProfile.Delete(ctx, event.KnowledgeID)
+		return
+	}
+	if !g.Cfg().MustGet(ctx, "kbAdvisor.enabled", true).Bool() {
+		return
+	}
+	schedule(ctx, event.KnowledgeID)
+}
+
+var pending struct {
+	sync.Mutex
+	knowledgeIDs map[string]bool
+}
+
+// schedule 等待 kbAdvisor.analyzeDelay 后重新分析知识库，等待期间同一知识库的多次变更（批量上传）只分析一次
+func schedule(ctx context.Context, knowledgeID string) {
+	pending.Lock()
+	if pending.knowledgeIDs == nil {
+		pending.knowledgeIDs = make(map[string]bool)
+	}
+	if pending.knowledgeIDs[knowledgeID] {
+		pending.Unlock()
+		return
+	}
+	pending.knowledgeIDs[knowledgeID] = true
+	pending.Unlock()
+
+	delay := g.Cfg().MustGet(ctx, "kbAdvisor.analyzeDelay", "1m").Duration()
+	common.SafeGoDetached(ctx, "KBAdvisor-"+knowledgeID, func(ctx context.Context) {
+		time.Sleep(delay)
+		pending.Lock()
+		delete(pending.knowledgeIDs, knowledgeID)
+		pending.Unlock()
+
+		if _, err := Analyze(ctx, knowledgeID); err != nil {
+			g.Log().Warningf(ctx, "Failed to analyze knowledge base %s: %v", knowledgeID, err)
+		}
+	})
+}
+
+// Analyze 抽样分析知识库的分片并保存画像，最多抽样 kbAdvisor.sampleChunks 个分片
+func Analyze(ctx context.Context, knowledgeID string) (*gormModel.KnowledgeProfile, error) {
+	if _, err := knowledge.GetKnowledgeBaseById(ctx, knowledgeID); err != nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "knowledge base not found: %s", knowledgeID)
+	}
+	total, err := dao.KnowledgeProfile.CountChunks(ctx, knowledgeID, int(v1.StatusActive))
+	if err != nil {
+		return nil, err
+	}
+	limit := max(g.Cfg().MustGet(ctx, "kbAdvisor.sampleChunks", 2000).Int(), 1)
+	contents, err := dao.KnowledgeProfile.SampleChunkContents(ctx, knowledgeID, int(v1.StatusActive), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := analyzeChunks(contents)
+	languages, err := json.Marshal(analysis.Languages)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	profile := &gormModel.KnowledgeProfile{
+		KnowledgeID:      knowledgeID,
+		ChunkCount:       int(total),
+		SampledChunks:    analysis.SampledChunks,
+		Languages:        gormModel.JSON(languages),
+		DominantLanguage: analysis.DominantLanguage,
+		DominantShare:    analysis.DominantShare,
+		MixedShare:       analysis.MixedShare,
+		AvgChunkChars:    analysis.AvgChunkChars,
+		AvgChunkTokens:   analysis.AvgChunkTokens,
+		MaxChunkTokens:   analysis.MaxChunkTokens,
+		AnalyzedAt:       &now,
+	}
+	if err = dao.KnowledgeProfile.Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Knowledge base %s analyzed: chunks=%d, sampled=%d, dominant=%s (%.2f), mixed=%.2f, avgTokens=%.0f",
+		knowledgeID, total, analysis.SampledChunks, analysis.DominantLanguage, analysis.DominantShare, analysis.MixedShare, analysis.AvgChunkTokens)
+	return profile, nil
+}
+
+// Get 获取知识库的画像和建议，尚未分析或 refresh 为 true 时立即分析
+// 建议按当前的 embedding 模型和已注册模型实时生成，模型变更后无需重新分析
+func Get(ctx context.Context, knowledgeID string, refresh bool) (*Advice, error) {
+	if _, err := knowledge.GetKnowledgeBaseById(ctx, knowledgeID); err != nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "knowledge base not found: %s", knowledgeID)
+	}
+	profile, err := dao.KnowledgeProfile.Get(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil || refresh {
+		if profile, err = Analyze(ctx, knowledgeID); err != nil {
+			return nil, err
+		}
+	}
+
+	analysis := toAnalysis(profile)
+	modelID, err := knowledge.GetLatestEmbeddingModelID(ctx, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	var current *model.ModelConfig
+	if modelID != "" {
+		current = model.Registry.Get(modelID)
+	}
+	return &Advice{
+		Profile:          profile,
+		Languages:        analysis.Languages,
+		EmbeddingModelID: modelID,
+		Recommendations:  recommend(analysis, current, model.Registry.GetByType(model.ModelTypeEmbedding), thresholds(ctx)),
+	}, nil
+}
+
+// Apply 按建议（或指定的 embedding 模型）创建把知识库切换到新模型的迁移任务
+func Apply(ctx context.Context, knowledgeID, modelID string) (*gormModel.ReembedJob, error) {
+	if modelID == "" {
+		advice, err := Get(ctx, knowledgeID, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range advice.Recommendations {
+			if rec.Type == RecommendEmbeddingModel && rec.ModelID != "" {
+				modelID = rec.ModelID
+				break
+			}
+		}
+		if modelID == "" {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "knowledge base %s has no embedding model recommendation, model_id is required", knowledgeID)
+		}
+	} else if _, err := knowledge.GetKnowledgeBaseById(ctx, knowledgeID); err != nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "knowledge base not found: %s", knowledgeID)
+	}
+	return reembed.StartMigration(ctx, modelID, knowledgeID)
+}
+
+func thresholds(ctx context.Context) Thresholds {
+	return Thresholds{
+		MultilingualShare: g.Cfg().MustGet(ctx, "kbAdvisor.multilingualShare", 0.8).Float64(),
+		MixedShare:        g.Cfg().MustGet(ctx, "kbAdvisor.mixedShare", 0.3).Float64(),
+		MaxChunkTokens:    g.Cfg().MustGet(ctx, "kbAdvisor.maxChunkTokens", 512).Int(),
+		MinChunkTokens:    g.Cfg().MustGet(ctx, "kbAdvisor.minChunkTokens", 64).Int(),
+	}
+}
+
+// toAnalysis 从保存的画像恢复分析结果
+func toAnalysis(profile *gormModel.KnowledgeProfile) *Analysis {
+	analysis := &Analysis{
+		SampledChunks:    profile.SampledChunks,
+		Languages:        map[string]float64{},
+		DominantLanguage: profile.DominantLanguage,
+		DominantShare:    profile.DominantShare,
+		MixedShare:       profile.MixedShare,
+		AvgChunkChars:    profile.AvgChunkChars,
+		AvgChunkTokens:   profile.AvgChunkTokens,
+		MaxChunkTokens:   profile.MaxChunkTokens,
+	}
+	if len(profile.Languages) > 0 {
+		_ = json.Unmarshal(profile.Languages, &analysis.Languages)
+	}
+	return analysis
+}
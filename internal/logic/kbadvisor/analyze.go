@@ -0,0 +1,142 @@
+package kbadvisor
+
+import (
+	"sort"
+	"unicode"
+)
+
+// 语言，按文字体系区分，拉丁字母书写的语言（英文、法文、德文等）不再细分
+const (
+	LangChinese  = "zh"
+	LangJapanese = "ja"
+	LangKorean   = "ko"
+	LangLatin    = "latin"
+	LangCyrillic = "cyrillic"
+	LangArabic   = "arabic"
+	LangOther    = "other"
+)
+
+// mixedMinShare 分片中第二多的语言字符占比达到该值时视为混合多种语言的分片
+const mixedMinShare = 0.2
+
+// Analysis 抽样分片的语言构成和分片特征
+type Analysis struct {
+	SampledChunks    int
+	Languages        map[string]float64 // 各语言分片占比，不含没有文字的分片
+	DominantLanguage string
+	DominantShare    float64
+	MixedShare       float64 // 混合多种语言的分片占比
+	AvgChunkChars    float64
+	AvgChunkTokens   float64
+	MaxChunkTokens   int
+}
+
+// analyzeChunks 统计分片的语言构成和长度，每个分片按字符数最多的语言计入
+func analyzeChunks(contents []string) *Analysis {
+	analysis := &Analysis{SampledChunks: len(contents), Languages: map[string]float64{}}
+	if len(contents) == 0 {
+		return analysis
+	}
+
+	chunkLanguages := make(map[string]int)
+	var totalChars, totalTokens, withText, mixed int
+	for _, content := range contents {
+		counts, chars, tokens := scanChunk(content)
+		totalChars += chars
+		totalTokens += tokens
+		analysis.MaxChunkTokens = max(analysis.MaxChunkTokens, tokens)
+
+		language, share := chunkLanguage(counts)
+		if language == "" {
+			continue
+		}
+		withText++
+		chunkLanguages[language]++
+		if share <= 1-mixedMinShare {
+			mixed++
+		}
+	}
+	analysis.AvgChunkChars = float64(totalChars) / float64(len(contents))
+	analysis.AvgChunkTokens = float64(totalTokens) / float64(len(contents))
+	if withText == 0 {
+		return analysis
+	}
+
+	for language, n := range chunkLanguages {
+		analysis.Languages[language] = float64(n) / float64(withText)
+	}
+	analysis.DominantLanguage, analysis.DominantShare = top(analysis.Languages)
+	analysis.MixedShare = float64(mixed) / float64(withText)
+	return analysis
+}
+
+// scanChunk 按语言统计分片中的文字数，同时返回总字符数和估算的 token 数
+// token 数粗略估算：中日韩文字每字约 1 个 token，其他字符约 4 个字符 1 个 token
+func scanChunk(content string) (counts map[string]int, chars, tokens int) {
+	counts = make(map[string]int)
+	var han, kana, others int
+	for _, r := range content {
+		chars++
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			counts[LangKorean]++
+		case unicode.Is(unicode.Latin, r):
+			counts[LangLatin]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts[LangCyrillic]++
+		case unicode.Is(unicode.Arabic, r):
+			counts[LangArabic]++
+		case unicode.IsLetter(r):
+			counts[LangOther]++
+		}
+		if !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			others++
+		}
+	}
+	// 日文混用汉字和假名，出现假名时汉字计入日文
+	if kana > 0 {
+		counts[LangJapanese] = han + kana
+	} else if han > 0 {
+		counts[LangChinese] = han
+	}
+	cjk := chars - others
+	tokens = cjk + (others+3)/4
+	return counts, chars, tokens
+}
+
+// chunkLanguage 返回分片中文字数最多的语言及其占比，没有文字时返回空
+func chunkLanguage(counts map[string]int) (string, float64) {
+	var total int
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return "", 0
+	}
+	shares := make(map[string]float64, len(counts))
+	for language, n := range counts {
+		shares[language] = float64(n) / float64(total)
+	}
+	return top(shares)
+}
+
+// top 返回占比最高的语言，占比相同时按语言名排序取第一个，保证结果稳定
+func top(shares map[string]float64) (string, float64) {
+	languages := make([]string, 0, len(shares))
+	for language := range shares {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	var best string
+	var bestShare float64
+	for _, language := range languages {
+		if shares[language] > bestShare {
+			best, bestShare = language, shares[language]
+		}
+	}
+	return best, bestShare
+}
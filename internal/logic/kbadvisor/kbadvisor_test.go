@@ -0,0 +1,152 @@
+package kbadvisor
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/model"
+)
+
+var testThresholds = Thresholds{MultilingualShare: 0.8, MixedShare: 0.3, MaxChunkTokens: 512, MinChunkTokens: 64}
+
+func TestAnalyzeChunks(t *testing.T) {
+	analysis := analyzeChunks([]string{
+		"知识库语言分析",
+		"向量检索召回",
+		"Embedding models map text to vectors",
+		"これは日本語の文章です",
+		"12345 ---",
+	})
+	if analysis.SampledChunks != 5 {
+		t.Fatalf("SampledChunks = %d, want 5", analysis.SampledChunks)
+	}
+	want := map[string]float64{LangChinese: 0.5, LangLatin: 0.25, LangJapanese: 0.25}
+	if len(analysis.Languages) != len(want) {
+		t.Fatalf("Languages = %v, want %v", analysis.Languages, want)
+	}
+	for language, share := range want {
+		if math.Abs(analysis.Languages[language]-share) > 1e-9 {
+			t.Errorf("Languages[%s] = %v, want %v", language, analysis.Languages[language], share)
+		}
+	}
+	if analysis.DominantLanguage != LangChinese || analysis.DominantShare != 0.5 {
+		t.Errorf("dominant = %s %v, want zh 0.5", analysis.DominantLanguage, analysis.DominantShare)
+	}
+	if analysis.MixedShare != 0 {
+		t.Errorf("MixedShare = %v, want 0", analysis.MixedShare)
+	}
+}
+
+func TestAnalyzeChunksMixed(t *testing.T) {
+	analysis := analyzeChunks([]string{"使用 bge-m3 embedding 模型", "纯中文内容"})
+	if analysis.MixedShare != 0.5 {
+		t.Errorf("MixedShare = %v, want 0.5", analysis.MixedShare)
+	}
+}
+
+func TestScanChunkTokens(t *testing.T) {
+	tests := []struct {
+		content string
+		chars   int
+		tokens  int
+	}{
+		{"", 0, 0},
+		{"中文", 2, 2},
+		{"abcdefgh", 8, 2},
+		{"中文abcde", 7, 4},
+	}
+	for _, tt := range tests {
+		_, chars, tokens := scanChunk(tt.content)
+		if chars != tt.chars || tokens != tt.tokens {
+			t.Errorf("scanChunk(%q) = %d chars, %d tokens, want %d, %d", tt.content, chars, tokens, tt.chars, tt.tokens)
+		}
+	}
+}
+
+func TestModelCoverage(t *testing.T) {
+	tests := []struct {
+		mc           *model.ModelConfig
+		multilingual bool
+		language     string
+	}{
+		{&model.ModelConfig{Name: "BAAI/bge-m3"}, true, ""},
+		{&model.ModelConfig{Name: "bge-large-zh-v1.5"}, false, LangChinese},
+		{&model.ModelConfig{Name: "bge-large-en-v1.5"}, false, LangLatin},
+		{&model.ModelConfig{Name: "text-embedding-ada-002"}, false, ""},
+		{&model.ModelConfig{Name: "bge-large-en-v1.5", Extra: map[string]any{"multilingual": true}}, true, ""},
+	}
+	for _, tt := range tests {
+		multilingual, language := modelCoverage(tt.mc)
+		if multilingual != tt.multilingual || language != tt.language {
+			t.Errorf("modelCoverage(%s) = %v %q, want %v %q", tt.mc.Name, multilingual, language, tt.multilingual, tt.language)
+		}
+	}
+}
+
+func TestRecommendModel(t *testing.T) {
+	english := &model.ModelConfig{ModelID: "en", Name: "bge-large-en-v1.5", Type: model.ModelTypeEmbedding, Extra: map[string]any{"dimension": 1024.0}}
+	m3 := &model.ModelConfig{ModelID: "m3", Name: "bge-m3", Type: model.ModelTypeEmbedding, Extra: map[string]any{"dimension": 1024.0}}
+	small := &model.ModelConfig{ModelID: "small", Name: "multilingual-e5-small", Type: model.ModelTypeEmbedding, Extra: map[string]any{"dimension": 384.0}}
+	chinese := &model.ModelConfig{ModelID: "zh", Name: "bge-large-zh-v1.5", Type: model.ModelTypeEmbedding}
+	candidates := []*model.ModelConfig{english, small, chinese, m3}
+
+	tests := []struct {
+		name     string
+		analysis *Analysis
+		current  *model.ModelConfig
+		wantRec  bool
+		wantID   string
+	}{
+		{"mixed kb on english model", &Analysis{DominantLanguage: LangLatin, DominantShare: 0.6}, english, true, "m3"},
+		{"chinese kb on english model", &Analysis{DominantLanguage: LangChinese, DominantShare: 0.95}, english, true, "m3"},
+		{"english kb on english model", &Analysis{DominantLanguage: LangLatin, DominantShare: 0.95}, english, false, ""},
+		{"mixed kb on multilingual model", &Analysis{DominantLanguage: LangLatin, DominantShare: 0.5}, m3, false, ""},
+		{"unknown current model", &Analysis{DominantLanguage: LangChinese, DominantShare: 0.5}, &model.ModelConfig{ModelID: "x", Name: "text-embedding-ada-002"}, false, ""},
+		{"no current model", &Analysis{DominantLanguage: LangChinese, DominantShare: 0.5}, nil, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := recommendModel(tt.analysis, tt.current, candidates, testThresholds)
+			if (rec != nil) != tt.wantRec {
+				t.Fatalf("recommendModel() = %+v, want recommendation: %v", rec, tt.wantRec)
+			}
+			if rec != nil && rec.ModelID != tt.wantID {
+				t.Errorf("ModelID = %q, want %q", rec.ModelID, tt.wantID)
+			}
+		})
+	}
+
+	// 没有维度一致的多语言模型时仍给出建议，但不指定模型
+	rec := recommendModel(&Analysis{DominantLanguage: LangLatin, DominantShare: 0.5}, english, []*model.ModelConfig{english, small}, testThresholds)
+	if rec == nil || rec.ModelID != "" || !strings.Contains(rec.Reason, "请先注册") {
+		t.Errorf("recommendModel() without candidates = %+v", rec)
+	}
+}
+
+func TestRecommendChunkSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		analysis *Analysis
+		wantSize int
+	}{
+		{"long chunks", &Analysis{SampledChunks: 10, AvgChunkChars: 3000, AvgChunkTokens: 1000}, 900},
+		{"short chunks", &Analysis{SampledChunks: 10, AvgChunkChars: 40, AvgChunkTokens: 20}, 600},
+		{"normal chunks", &Analysis{SampledChunks: 10, AvgChunkChars: 800, AvgChunkTokens: 250}, 0},
+		{"empty", &Analysis{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := recommendChunkSize(tt.analysis, testThresholds)
+			if tt.wantSize == 0 {
+				if rec != nil {
+					t.Errorf("recommendChunkSize() = %+v, want nil", rec)
+				}
+				return
+			}
+			if rec == nil || rec.ChunkSize != tt.wantSize {
+				t.Errorf("recommendChunkSize() = %+v, want chunk size %d", rec, tt.wantSize)
+			}
+		})
+	}
+}
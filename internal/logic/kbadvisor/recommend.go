@@ -0,0 +1,185 @@
+package kbadvisor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Malowking/kbgo/core/model"
+)
+
+// 建议类型
+const (
+	RecommendEmbeddingModel = "embedding_model" // 更换 embedding 模型，可一键创建迁移任务
+	RecommendChunkSize      = "chunk_size"      // 调整分片大小，之后索引的文档生效
+)
+
+// Thresholds 生成建议的阈值，对应配置 kbAdvisor.*
+type Thresholds struct {
+	MultilingualShare float64 // 主要语言分片占比低于该值时视为多语言知识库
+	MixedShare        float64 // 混合多种语言的分片占比达到该值时视为多语言知识库
+	MaxChunkTokens    int     // 平均分片 token 数超过该值时建议减小分片
+	MinChunkTokens    int     // 平均分片 token 数低于该值时建议增大分片
+}
+
+// Recommendation 知识库优化建议
+type Recommendation struct {
+	Type      string
+	Reason    string
+	ModelID   string // 建议的 embedding 模型，没有合适的已注册模型时为空
+	ModelName string
+	ChunkSize int // 建议的分片大小（字符数）
+}
+
+// multilingualMarkers 名称中包含这些片段的 embedding 模型视为多语言模型
+var multilingualMarkers = []string{
+	"multilingual", "bge-m3", "text-embedding-3", "text-embedding-v", "jina-embeddings-v3",
+	"qwen", "labse", "embed-multilingual", "nomic-embed-text-v2",
+}
+
+// modelCoverage 判断 embedding 模型覆盖的语言：模型 extra 中的 multilingual 优先，否则按模型名称判断；
+// 单语模型返回其语言，无法判断时 multilingual 为 false 且 language 为空
+func modelCoverage(mc *model.ModelConfig) (multilingual bool, language string) {
+	if v, ok := mc.Extra["multilingual"].(bool); ok && v {
+		return true, ""
+	}
+	name := strings.ToLower(mc.Name)
+	for _, marker := range multilingualMarkers {
+		if strings.Contains(name, marker) {
+			return true, ""
+		}
+	}
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '/' || r == '.' || r == ' '
+	}) {
+		switch part {
+		case "zh", "chinese":
+			return false, LangChinese
+		case "en", "english":
+			return false, LangLatin
+		case "ja", "japanese":
+			return false, LangJapanese
+		case "ko", "korean":
+			return false, LangKorean
+		}
+	}
+	return false, ""
+}
+
+// modelDimension 模型 extra 中配置的向量维度，未配置时返回 0
+func modelDimension(mc *model.ModelConfig) int {
+	switch v := mc.Extra["dimension"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// isMultilingual 判断知识库内容是否需要多语言 embedding 模型
+func isMultilingual(analysis *Analysis, t Thresholds) bool {
+	return analysis.DominantLanguage != "" &&
+		(analysis.DominantShare < t.MultilingualShare || analysis.MixedShare >= t.MixedShare)
+}
+
+// recommend 根据分析结果、当前 embedding 模型和已注册的 embedding 模型生成建议
+// 只在能确定当前模型不覆盖知识库语言时建议更换模型，候选模型需覆盖知识库语言且向量维度与当前模型一致（已配置维度时）
+func recommend(analysis *Analysis, current *model.ModelConfig, candidates []*model.ModelConfig, t Thresholds) []*Recommendation {
+	recommendations := make([]*Recommendation, 0)
+	if rec := recommendModel(analysis, current, candidates, t); rec != nil {
+		recommendations = append(recommendations, rec)
+	}
+	if rec := recommendChunkSize(analysis, t); rec != nil {
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations
+}
+
+func recommendModel(analysis *Analysis, current *model.ModelConfig, candidates []*model.ModelConfig, t Thresholds) *Recommendation {
+	if current == nil || analysis.DominantLanguage == "" {
+		return nil
+	}
+	multilingual := isMultilingual(analysis, t)
+	currentMultilingual, currentLanguage := modelCoverage(current)
+	if currentMultilingual || currentLanguage == "" {
+		return nil
+	}
+
+	var reason string
+	switch {
+	case multilingual:
+		reason = fmt.Sprintf("知识库包含多种语言（主要语言 %s 占 %.0f%%，混合语言分片占 %.0f%%），当前 embedding 模型 %s 只支持 %s，建议使用多语言模型",
+			analysis.DominantLanguage, analysis.DominantShare*100, analysis.MixedShare*100, current.Name, currentLanguage)
+	case currentLanguage != analysis.DominantLanguage:
+		reason = fmt.Sprintf("知识库主要语言为 %s（占 %.0f%%），当前 embedding 模型 %s 只支持 %s，建议使用支持该语言的模型",
+			analysis.DominantLanguage, analysis.DominantShare*100, current.Name, currentLanguage)
+	default:
+		return nil
+	}
+
+	rec := &Recommendation{Type: RecommendEmbeddingModel, Reason: reason}
+	if best := pickModel(analysis, current, candidates, multilingual); best != nil {
+		rec.ModelID = best.ModelID
+		rec.ModelName = best.Name
+	} else {
+		rec.Reason += "；当前没有已注册的合适模型，请先注册"
+	}
+	return rec
+}
+
+// pickModel 从已注册的 embedding 模型中选择覆盖知识库语言的模型，多语言模型优先，同类按名称排序
+func pickModel(analysis *Analysis, current *model.ModelConfig, candidates []*model.ModelConfig, multilingual bool) *model.ModelConfig {
+	dim := modelDimension(current)
+	var fits []*model.ModelConfig
+	for _, mc := range candidates {
+		if mc.ModelID == current.ModelID || mc.Type != model.ModelTypeEmbedding {
+			continue
+		}
+		if d := modelDimension(mc); dim > 0 && d > 0 && d != dim {
+			continue
+		}
+		isMulti, language := modelCoverage(mc)
+		if isMulti || (!multilingual && language == analysis.DominantLanguage) {
+			fits = append(fits, mc)
+		}
+	}
+	sort.SliceStable(fits, func(i, j int) bool {
+		mi, _ := modelCoverage(fits[i])
+		mj, _ := modelCoverage(fits[j])
+		if mi != mj {
+			return mi
+		}
+		return fits[i].Name < fits[j].Name
+	})
+	if len(fits) == 0 {
+		return nil
+	}
+	return fits[0]
+}
+
+// recommendChunkSize 平均分片过长时语义被稀释，过短时缺少上下文，建议按平均字符/token 比例换算到目标 token 数的分片大小
+func recommendChunkSize(analysis *Analysis, t Thresholds) *Recommendation {
+	if analysis.SampledChunks == 0 || analysis.AvgChunkTokens <= 0 {
+		return nil
+	}
+	var reason string
+	switch {
+	case t.MaxChunkTokens > 0 && analysis.AvgChunkTokens > float64(t.MaxChunkTokens):
+		reason = fmt.Sprintf("平均分片约 %.0f 个 token，超过 %d，过长的分片会稀释向量语义，建议减小分片大小",
+			analysis.AvgChunkTokens, t.MaxChunkTokens)
+	case t.MinChunkTokens > 0 && analysis.AvgChunkTokens < float64(t.MinChunkTokens):
+		reason = fmt.Sprintf("平均分片约 %.0f 个 token，低于 %d，过短的分片缺少上下文，建议增大分片大小",
+			analysis.AvgChunkTokens, t.MinChunkTokens)
+	default:
+		return nil
+	}
+	target := float64(t.MaxChunkTokens+t.MinChunkTokens) / 2
+	size := analysis.AvgChunkChars / analysis.AvgChunkTokens * target
+	return &Recommendation{
+		Type:      RecommendChunkSize,
+		Reason:    reason,
+		ChunkSize: max(int(math.Round(size/100))*100, 100),
+	}
+}
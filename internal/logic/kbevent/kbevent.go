@@ -0,0 +1,55 @@
+// Package kbevent 知识库内容变更事件：文档索引完成、重新索引、删除以及分片修改时发布，
+// 依赖知识库内容的缓存（如预置回答）订阅后按知识库清除受影响的缓存，避免文档更新后继续返回过期的回答
+package kbevent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 事件类型
+const (
+	TypeIndexed = "indexed" // 文档索引完成
+	TypeUpdated = "updated" // 文档重新索引或分片被修改、删除
+	TypeDeleted = "deleted" // 文档或知识库被删除
+)
+
+// Event 知识库内容变更事件
+type Event struct {
+	Type        string
+	KnowledgeID string // 为空表示无法确定所属知识库，订阅方应清除全部知识库的缓存
+	DocumentID  string // 知识库整体删除或只涉及分片时可能为空
+}
+
+// Handler 事件处理函数，在发布方的 goroutine 中同步执行，应尽快返回
+type Handler func(ctx context.Context, event *Event)
+
+var handlers struct {
+	sync.RWMutex
+	list []Handler
+}
+
+// Subscribe 订阅知识库变更事件，通常在包的 init 中调用
+func Subscribe(handler Handler) {
+	handlers.Lock()
+	defer handlers.Unlock()
+	handlers.list = append(handlers.list, handler)
+}
+
+// Publish 发布知识库变更事件，单个订阅方 panic 不影响其他订阅方和发布方
+func Publish(ctx context.Context, event *Event) {
+	handlers.RLock()
+	list := append([]Handler(nil), handlers.list...)
+	handlers.RUnlock()
+
+	g.Log().Debugf(ctx, "Knowledge base changed: type=%s, knowledgeID=%s, documentID=%s", event.Type, event.KnowledgeID, event.DocumentID)
+	for _, handler := range list {
+		func() {
+			defer common.RecoverPanic(ctx, "KnowledgeBaseEventHandler")
+			handler(ctx, event)
+		}()
+	}
+}
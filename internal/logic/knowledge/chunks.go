@@ -72,6 +72,37 @@ func SaveChunksData(ctx context.Context, documentsId string, chunks []entity.Kno
 	return result.Error
 }
 
+// AppendChunksData 向已有文档追加知识块，保留 ext 中的顺序信息，不修改文档状态
+func AppendChunksData(ctx context.Context, chunks []entity.KnowledgeChunks) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	gormChunks := make([]gormModel.KnowledgeChunks, len(chunks))
+	for i, chunk := range chunks {
+		gormChunks[i] = gormModel.KnowledgeChunks{
+			ID:             chunk.Id,
+			KnowledgeDocID: chunk.KnowledgeDocId,
+			Content:        chunk.Content,
+			CollectionName: chunk.CollectionName,
+			Ext:            chunk.Ext,
+			Status:         int8(chunk.Status),
+		}
+	}
+	if err := dao.GetDB().WithContext(ctx).CreateInBatches(&gormChunks, len(gormChunks)).Error; err != nil {
+		g.Log().Errorf(ctx, "AppendChunksData err=%+v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteChunksByIds 根据ID批量删除知识块
+func DeleteChunksByIds(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return dao.GetDB().WithContext(ctx).Where("id IN ?", ids).Delete(&gormModel.KnowledgeChunks{}).Error
+}
+
 // GetChunksList 查询知识块列表
 func GetChunksList(ctx context.Context, where entity.KnowledgeChunks, page, size int) (list []entity.KnowledgeChunks, total int, err error) {
 	model := dao.KnowledgeChunks.Ctx(ctx)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/Malowking/kbgo/core/vector_store"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/kbevent"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -59,5 +60,6 @@ func DeleteDocumentDataOnly(ctx context.Context, documentId string, vectorStore
 	}
 
 	g.Log().Infof(ctx, "DeleteDocumentDataOnly: Successfully deleted chunks data for document id %s", documentId)
+	kbevent.Publish(ctx, &kbevent.Event{Type: kbevent.TypeUpdated, KnowledgeID: document.KnowledgeId, DocumentID: documentId})
 	return nil
 }
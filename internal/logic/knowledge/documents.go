@@ -2,6 +2,8 @@ package knowledge
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -32,6 +34,14 @@ func SaveDocumentsInfo(ctx context.Context, documents entity.KnowledgeDocuments)
 		RustfsLocation: documents.RustfsLocation,
 		LocalFilePath:  documents.LocalFilePath, // 添加本地文件路径
 		Status:         int8(documents.Status),
+		SecurityLabel:  documents.SecurityLabel,
+		SectionLabels:  documents.SectionLabels,
+		FieldMapping:   documents.FieldMapping,
+		ValidFrom:      toTimePointer(documents.ValidFrom),
+		ValidUntil:     toTimePointer(documents.ValidUntil),
+		VersionGroup:   documents.VersionGroup,
+		Version:        documents.Version,
+		PreviousId:     documents.PreviousId,
 	}
 
 	// 使用 DAO 中的 GORM 数据库连接
@@ -61,6 +71,14 @@ func SaveDocumentsInfoWithTx(ctx context.Context, tx *gorm.DB, documents entity.
 		RustfsLocation: documents.RustfsLocation,
 		LocalFilePath:  documents.LocalFilePath, // 添加本地文件路径
 		Status:         int8(documents.Status),
+		SecurityLabel:  documents.SecurityLabel,
+		SectionLabels:  documents.SectionLabels,
+		FieldMapping:   documents.FieldMapping,
+		ValidFrom:      toTimePointer(documents.ValidFrom),
+		ValidUntil:     toTimePointer(documents.ValidUntil),
+		VersionGroup:   documents.VersionGroup,
+		Version:        documents.Version,
+		PreviousId:     documents.PreviousId,
 	}
 
 	// 如果没有提供事务，则使用默认的数据库连接
@@ -108,6 +126,31 @@ func UpdateDocumentsLocalPath(ctx context.Context, documentsId string, localPath
 	return err
 }
 
+// UpdateDocumentEmbedding 记录文档向量使用的 embedding 模型及其配置指纹
+func UpdateDocumentEmbedding(ctx context.Context, documentsId string, modelID string, fingerprint string) error {
+	data := g.Map{
+		"embedding_model_id":    modelID,
+		"embedding_fingerprint": fingerprint,
+	}
+
+	_, err := dao.KnowledgeDocuments.Ctx(ctx).Where("id", documentsId).Data(data).Update()
+	if err != nil {
+		g.Log().Errorf(ctx, "更新文档 embedding 信息失败: ID=%s, 错误: %v", documentsId, err)
+	}
+
+	return err
+}
+
+// UpdateDocumentToc 保存文档的标题目录（JSON）
+func UpdateDocumentToc(ctx context.Context, documentsId string, toc string) error {
+	_, err := dao.KnowledgeDocuments.Ctx(ctx).Where("id", documentsId).Data(g.Map{"toc": toc}).Update()
+	if err != nil {
+		g.Log().Errorf(ctx, "更新文档目录失败: ID=%s, 错误: %v", documentsId, err)
+	}
+
+	return err
+}
+
 // GetDocumentById 根据ID获取文档信息
 func GetDocumentById(ctx context.Context, id string) (document entity.KnowledgeDocuments, err error) {
 	g.Log().Debugf(ctx, "获取文档信息: ID=%s", id)
@@ -121,6 +164,36 @@ func GetDocumentById(ctx context.Context, id string) (document entity.KnowledgeD
 	return document, nil
 }
 
+// GetCurrentDocumentByName 获取知识库中指定文件名的当前版本文档，不存在时返回空文档
+func GetCurrentDocumentByName(ctx context.Context, knowledgeId, fileName string) (document entity.KnowledgeDocuments, err error) {
+	err = dao.KnowledgeDocuments.Ctx(ctx).
+		Where("knowledge_id", knowledgeId).
+		Where("file_name", fileName).
+		WhereNull("superseded_at").
+		OrderDesc("create_time").
+		Limit(1).
+		Scan(&document)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		g.Log().Errorf(ctx, "根据文件名获取文档信息失败: KnowledgeId=%s, FileName=%s, 错误: %v", knowledgeId, fileName, err)
+		return document, fmt.Errorf("根据文件名获取文档信息失败: %w", err)
+	}
+	return document, nil
+}
+
+// GetLatestEmbeddingModelID 获取知识库中最近索引的文档使用的 embedding 模型ID，没有已索引文档时返回空
+func GetLatestEmbeddingModelID(ctx context.Context, knowledgeId string) (string, error) {
+	value, err := dao.KnowledgeDocuments.Ctx(ctx).
+		Fields("embedding_model_id").
+		Where("knowledge_id", knowledgeId).
+		WhereNot("embedding_model_id", "").
+		OrderDesc("update_time").
+		Value()
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return value.String(), nil
+}
+
 // GetDocumentBySHA256 根据知识库ID和SHA256获取文档信息
 func GetDocumentBySHA256(ctx context.Context, knowledgeId, sha256 string) (document entity.KnowledgeDocuments, err error) {
 	g.Log().Debugf(ctx, "根据SHA256获取文档信息: KnowledgeId=%s, SHA256=%s", knowledgeId, sha256)
@@ -200,6 +273,11 @@ func DeleteDocumentWithTx(ctx context.Context, tx *gorm.DB, id string) error {
 		return fmt.Errorf("删除文档块失败: %w", result.Error)
 	}
 
+	// 维护版本链：删除当前版本时恢复上一个版本
+	if err := unlinkVersionWithTx(ctx, tx, id); err != nil {
+		return err
+	}
+
 	// 再删除文档
 	result = tx.WithContext(ctx).Where("id = ?", id).Delete(&gormModel.KnowledgeDocuments{})
 	if result.Error != nil {
@@ -0,0 +1,71 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// defaultSparseWeight 知识库未设置融合权重时稀疏检索分数的默认权重
+const defaultSparseWeight = 0.3
+
+// sparseModelConfig 稀疏模型配置，实现 common.EmbeddingConfig 接口
+type sparseModelConfig struct {
+	mc *model.ModelConfig
+}
+
+func (c sparseModelConfig) GetAPIKey() string         { return c.mc.APIKey }
+func (c sparseModelConfig) GetBaseURL() string        { return c.mc.BaseURL }
+func (c sparseModelConfig) GetEmbeddingModel() string { return c.mc.Name }
+
+// GetKnowledgeBaseById 根据ID获取知识库
+func GetKnowledgeBaseById(ctx context.Context, id string) (*gormModel.KnowledgeBase, error) {
+	var kb gormModel.KnowledgeBase
+	if err := dao.GetDB().WithContext(ctx).Where("id = ?", id).First(&kb).Error; err != nil {
+		return nil, fmt.Errorf("获取知识库信息失败: %w", err)
+	}
+	return &kb, nil
+}
+
+// ValidateSparseModel 校验模型是否为已注册的稀疏向量化模型
+func ValidateSparseModel(modelID string) (*model.ModelConfig, error) {
+	mc := model.Registry.Get(modelID)
+	if mc == nil {
+		return nil, fmt.Errorf("sparse embedding model not found in registry: %s", modelID)
+	}
+	if mc.Type != model.ModelTypeSparseEmbedding {
+		return nil, fmt.Errorf("model %s is not a sparse embedding model, got type: %s", modelID, mc.Type)
+	}
+	return mc, nil
+}
+
+// NewKBSparseEmbedder 创建知识库配置的稀疏向量化实例，未配置稀疏模型时返回 nil
+// 同时返回该知识库的稀疏检索融合权重
+func NewKBSparseEmbedder(ctx context.Context, kb *gormModel.KnowledgeBase) (common.SparseEmbedder, float64, error) {
+	if kb == nil || kb.SparseModelID == "" {
+		return nil, 0, nil
+	}
+
+	mc, err := ValidateSparseModel(kb.SparseModelID)
+	if err != nil {
+		return nil, 0, err
+	}
+	embedder, err := common.NewSparseEmbedding(ctx, mc.Provider, sparseModelConfig{mc: mc})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create sparse embedder: %w", err)
+	}
+
+	weight := kb.SparseWeight
+	if weight <= 0 {
+		weight = g.Cfg().MustGet(ctx, "retriever.sparseWeight", defaultSparseWeight).Float64()
+	}
+	if weight > 1 {
+		weight = 1
+	}
+	return embedder, weight, nil
+}
@@ -0,0 +1,80 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// DocumentMetadata 索引时由 LLM 提取的文档元数据
+type DocumentMetadata struct {
+	Title   string   `json:"title,omitempty"`
+	Author  string   `json:"author,omitempty"`
+	Date    string   `json:"date,omitempty"` // YYYY-MM-DD、YYYY-MM 或 YYYY
+	Topics  []string `json:"topics,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+}
+
+// IsEmpty 是否未提取到任何字段
+func (m DocumentMetadata) IsEmpty() bool {
+	return m.Title == "" && m.Author == "" && m.Date == "" && len(m.Topics) == 0 && m.Summary == ""
+}
+
+// UpdateDocumentMetadata 保存文档元数据，重新索引时覆盖
+func UpdateDocumentMetadata(ctx context.Context, documentId string, metadata DocumentMetadata) error {
+	topics := ""
+	if len(metadata.Topics) > 0 {
+		raw, err := json.Marshal(metadata.Topics)
+		if err != nil {
+			return err
+		}
+		topics = string(raw)
+	}
+	err := dao.GetDB().WithContext(ctx).Model(&gormModel.KnowledgeDocuments{}).
+		Where("id = ?", documentId).
+		Updates(map[string]interface{}{
+			"meta_title":   metadata.Title,
+			"meta_author":  metadata.Author,
+			"meta_date":    metadata.Date,
+			"meta_topics":  topics,
+			"meta_summary": metadata.Summary,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("更新文档元数据失败: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentsMetadata 批量获取文档元数据，key 为文档ID
+func GetDocumentsMetadata(ctx context.Context, documentIds []string) (map[string]DocumentMetadata, error) {
+	result := make(map[string]DocumentMetadata, len(documentIds))
+	if len(documentIds) == 0 {
+		return result, nil
+	}
+
+	var docs []gormModel.KnowledgeDocuments
+	err := dao.GetDB().WithContext(ctx).
+		Select("id", "meta_title", "meta_author", "meta_date", "meta_topics", "meta_summary").
+		Where("id IN ?", documentIds).
+		Find(&docs).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取文档元数据失败: %w", err)
+	}
+
+	for _, doc := range docs {
+		metadata := DocumentMetadata{
+			Title:   doc.MetaTitle,
+			Author:  doc.MetaAuthor,
+			Date:    doc.MetaDate,
+			Summary: doc.MetaSummary,
+		}
+		if doc.MetaTopics != "" {
+			_ = json.Unmarshal([]byte(doc.MetaTopics), &metadata.Topics)
+		}
+		result[doc.ID] = metadata
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// DocumentValidity 文档的时效信息
+type DocumentValidity struct {
+	ValidFrom    *time.Time
+	ValidUntil   *time.Time
+	CreateTime   *time.Time
+	SupersededAt *time.Time
+}
+
+// IsValidAt 判断文档在指定时间是否处于有效期内，且是当时的有效版本
+// 未设置生效时间时以上传时间作为起点，用于按历史时间点检索
+func (v DocumentValidity) IsValidAt(at time.Time) bool {
+	if start := v.EffectiveTime(); start != nil && at.Before(*start) {
+		return false
+	}
+	if v.ValidUntil != nil && !at.Before(*v.ValidUntil) {
+		return false
+	}
+	if v.SupersededAt != nil && !at.Before(*v.SupersededAt) {
+		return false
+	}
+	return true
+}
+
+// EffectiveTime 文档用于新近度计算的时间：优先使用生效时间，否则使用创建时间
+func (v DocumentValidity) EffectiveTime() *time.Time {
+	if v.ValidFrom != nil {
+		return v.ValidFrom
+	}
+	return v.CreateTime
+}
+
+// GetDocumentsValidity 批量获取文档的时效信息，key 为文档ID
+func GetDocumentsValidity(ctx context.Context, documentIds []string) (map[string]DocumentValidity, error) {
+	result := make(map[string]DocumentValidity, len(documentIds))
+	if len(documentIds) == 0 {
+		return result, nil
+	}
+
+	var docs []gormModel.KnowledgeDocuments
+	err := dao.GetDB().WithContext(ctx).
+		Select("id", "valid_from", "valid_until", "create_time", "superseded_at").
+		Where("id IN ?", documentIds).
+		Find(&docs).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取文档时效信息失败: %w", err)
+	}
+
+	for _, doc := range docs {
+		result[doc.ID] = DocumentValidity{
+			ValidFrom:    doc.ValidFrom,
+			ValidUntil:   doc.ValidUntil,
+			CreateTime:   doc.CreateTime,
+			SupersededAt: doc.SupersededAt,
+		}
+	}
+	return result, nil
+}
+
+// UpdateDocumentValidity 更新文档的有效期，传入 nil 表示清除对应的时间限制
+// 时效在检索时按数据库实时判断，更新后无需重新索引
+func UpdateDocumentValidity(ctx context.Context, documentId string, validFrom, validUntil *gtime.Time) error {
+	if err := ValidateValidity(validFrom, validUntil); err != nil {
+		return err
+	}
+
+	result := dao.GetDB().WithContext(ctx).Model(&gormModel.KnowledgeDocuments{}).
+		Where("id = ?", documentId).
+		Updates(map[string]interface{}{
+			"valid_from":  toTimePointer(validFrom),
+			"valid_until": toTimePointer(validUntil),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("更新文档有效期失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("document not found: %s", documentId)
+	}
+	return nil
+}
+
+// ValidateValidity 校验有效期区间
+func ValidateValidity(validFrom, validUntil *gtime.Time) error {
+	if validFrom != nil && validUntil != nil && !validUntil.After(validFrom) {
+		return fmt.Errorf("valid_until must be later than valid_from")
+	}
+	return nil
+}
+
+// toTimePointer 将 gtime 转换为 time 指针，nil 保持为 nil
+func toTimePointer(t *gtime.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	value := t.Time
+	return &value
+}
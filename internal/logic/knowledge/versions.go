@@ -0,0 +1,124 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/model/entity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"gorm.io/gorm"
+)
+
+// LinkPreviousVersion 为新上传的文档建立版本链
+// 同一知识库中同名文件的重新上传视为新版本，链接到当前最新版本；否则作为版本链的第一个版本
+func LinkPreviousVersion(ctx context.Context, document *entity.KnowledgeDocuments) error {
+	var previous gormModel.KnowledgeDocuments
+	err := dao.GetDB().WithContext(ctx).
+		Where("knowledge_id = ? AND file_name = ? AND id <> ?", document.KnowledgeId, document.FileName, document.Id).
+		Order("version DESC, create_time DESC").
+		First(&previous).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		document.VersionGroup = document.Id
+		document.Version = 1
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询文档历史版本失败: %w", err)
+	}
+
+	document.PreviousId = previous.ID
+	document.VersionGroup = versionGroupOf(previous)
+	document.Version = max(previous.Version, 1) + 1
+	g.Log().Infof(ctx, "文档作为新版本上传: fileName=%s, version=%d, previousId=%s",
+		document.FileName, document.Version, previous.ID)
+	return nil
+}
+
+// SupersedePreviousVersions 新版本索引完成后，将版本链中更早且仍有效的版本标记为已取代
+// 取代时间为新版本的生效时间（未设置时为当前时间），因此预定生效的新版本不会提前隐藏旧版本
+func SupersedePreviousVersions(ctx context.Context, documentId string) error {
+	var current gormModel.KnowledgeDocuments
+	if err := dao.GetDB().WithContext(ctx).Where("id = ?", documentId).First(&current).Error; err != nil {
+		return fmt.Errorf("查询文档失败: %w", err)
+	}
+	if current.PreviousId == "" {
+		return nil
+	}
+
+	supersededAt := time.Now()
+	if current.ValidFrom != nil {
+		supersededAt = *current.ValidFrom
+	}
+
+	result := dao.GetDB().WithContext(ctx).Model(&gormModel.KnowledgeDocuments{}).
+		Where("(version_group = ? OR id = ?) AND version < ? AND superseded_at IS NULL",
+			versionGroupOf(current), versionGroupOf(current), current.Version).
+		Update("superseded_at", supersededAt)
+	if result.Error != nil {
+		return fmt.Errorf("标记历史版本失败: %w", result.Error)
+	}
+	g.Log().Infof(ctx, "已将 %d 个历史版本标记为取代: documentId=%s, version=%d",
+		result.RowsAffected, documentId, current.Version)
+	return nil
+}
+
+// GetDocumentVersions 获取文档所在版本链的全部版本，按版本号降序排列
+func GetDocumentVersions(ctx context.Context, documentId string) (versions []entity.KnowledgeDocuments, err error) {
+	var current gormModel.KnowledgeDocuments
+	if err = dao.GetDB().WithContext(ctx).Where("id = ?", documentId).First(&current).Error; err != nil {
+		return nil, fmt.Errorf("document not found: %s", documentId)
+	}
+
+	group := versionGroupOf(current)
+	err = dao.KnowledgeDocuments.Ctx(ctx).
+		Where("version_group = ? OR id = ?", group, group).
+		OrderDesc("version").
+		Scan(&versions)
+	if err != nil {
+		return nil, fmt.Errorf("获取文档版本失败: %w", err)
+	}
+	return versions, nil
+}
+
+// unlinkVersionWithTx 删除文档前维护版本链
+// 后续版本改为链接到被删除版本的上一个版本；被删除的是当前版本时，上一个版本恢复为当前版本
+func unlinkVersionWithTx(ctx context.Context, tx *gorm.DB, documentId string) error {
+	var document gormModel.KnowledgeDocuments
+	err := tx.WithContext(ctx).Where("id = ?", documentId).First(&document).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询文档失败: %w", err)
+	}
+
+	err = tx.WithContext(ctx).Model(&gormModel.KnowledgeDocuments{}).
+		Where("previous_id = ?", documentId).
+		Update("previous_id", document.PreviousId).Error
+	if err != nil {
+		return fmt.Errorf("更新版本链失败: %w", err)
+	}
+
+	if document.PreviousId != "" && document.SupersededAt == nil {
+		err = tx.WithContext(ctx).Model(&gormModel.KnowledgeDocuments{}).
+			Where("id = ?", document.PreviousId).
+			Update("superseded_at", nil).Error
+		if err != nil {
+			return fmt.Errorf("恢复上一个版本失败: %w", err)
+		}
+		g.Log().Infof(ctx, "删除当前版本，恢复上一个版本: documentId=%s, previousId=%s", documentId, document.PreviousId)
+	}
+	return nil
+}
+
+// versionGroupOf 获取文档的版本链ID，历史数据没有版本链ID时使用文档自身ID
+func versionGroupOf(document gormModel.KnowledgeDocuments) string {
+	if document.VersionGroup != "" {
+		return document.VersionGroup
+	}
+	return document.ID
+}
@@ -0,0 +1,113 @@
+// Package modelhealth 模型健康检查：定时检查 ollama 等本地模型（以及设置了 extra.healthCheck 的模型）是否可用，
+// 不可用的模型在注册表中标记，对话等调用直接返回明确的错误；ollama 模型恢复可用时预加载到内存
+package modelhealth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/core/client"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// Config 模型健康检查配置（modelHealth.*）
+type Config struct {
+	Enabled   bool
+	Cron      string
+	Timeout   time.Duration // 单个模型的检查超时
+	WarmUp    bool          // ollama 模型变为可用时是否预加载
+	KeepAlive string        // 预加载后模型保持在内存中的时间，如 30m
+}
+
+// LoadConfig 读取模型健康检查配置，未配置的项使用默认值
+func LoadConfig(ctx context.Context) *Config {
+	cfg := &Config{
+		Enabled:   g.Cfg().MustGet(ctx, "modelHealth.enabled", true).Bool(),
+		Cron:      g.Cfg().MustGet(ctx, "modelHealth.cron", "*/30 * * * * *").String(),
+		Timeout:   time.Duration(g.Cfg().MustGet(ctx, "modelHealth.timeoutSeconds", 5).Int()) * time.Second,
+		WarmUp:    g.Cfg().MustGet(ctx, "modelHealth.warmUp", true).Bool(),
+		KeepAlive: g.Cfg().MustGet(ctx, "modelHealth.keepAlive", "30m").String(),
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return cfg
+}
+
+// InitModelHealth 启动时检查一次（同时预加载 ollama 模型），之后按 modelHealth.cron 定时检查
+func InitModelHealth() {
+	ctx := gctx.New()
+	cfg := LoadConfig(ctx)
+	if !cfg.Enabled {
+		g.Log().Info(ctx, "Model health check is disabled")
+		return
+	}
+	go Run(ctx, cfg)
+	_, err := gcron.AddSingleton(ctx, cfg.Cron, func(ctx context.Context) {
+		Run(ctx, cfg)
+	}, "model-health")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule model health check: %v", err)
+	} else {
+		g.Log().Infof(ctx, "Model health check scheduled with pattern: %s", cfg.Cron)
+	}
+}
+
+// Run 并发检查需要检查的模型并更新注册表中的健康状态
+func Run(ctx context.Context, cfg *Config) {
+	var wg sync.WaitGroup
+	for _, mc := range model.Registry.List() {
+		if !model.NeedsHealthCheck(mc) {
+			continue
+		}
+		wg.Add(1)
+		go func(mc *model.ModelConfig) {
+			defer wg.Done()
+			check(ctx, cfg, mc)
+		}(mc)
+	}
+	wg.Wait()
+}
+
+// check 检查一个模型，状态变化时记录日志，ollama 模型变为可用时预加载
+func check(ctx context.Context, cfg *Config, mc *model.ModelConfig) {
+	checkCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	err := model.CheckHealth(checkCtx, mc)
+	cancel()
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+	if !model.Registry.SetHealth(mc.ModelID, err == nil, reason) {
+		return
+	}
+	if err != nil {
+		g.Log().Warningf(ctx, "Model %s (%s) marked unavailable: %v", mc.Name, mc.ModelID, err)
+		return
+	}
+	g.Log().Infof(ctx, "Model %s (%s) is available", mc.Name, mc.ModelID)
+
+	if cfg.WarmUp && strings.EqualFold(mc.Provider, client.ProviderOllama) &&
+		(mc.Type == model.ModelTypeLLM || mc.Type == model.ModelTypeMultimodal || mc.Type == model.ModelTypeEmbedding) {
+		start := time.Now()
+		if err := client.WarmUpOllamaModel(ctx, mc.BaseURL, mc.Name, mc.Type == model.ModelTypeEmbedding, cfg.KeepAlive); err != nil {
+			g.Log().Warningf(ctx, "Failed to warm up ollama model %s: %v", mc.Name, err)
+			return
+		}
+		g.Log().Infof(ctx, "Warmed up ollama model %s in %s", mc.Name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// CheckNow 在后台立即检查一次（模型配置重新加载后使用，加载时会清除健康状态）
+func CheckNow() {
+	ctx := gctx.New()
+	if cfg := LoadConfig(ctx); cfg.Enabled {
+		go Run(ctx, cfg)
+	}
+}
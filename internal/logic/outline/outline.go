@@ -0,0 +1,397 @@
+package outline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/convmeta"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/security"
+	"github.com/Malowking/kbgo/internal/model/entity"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// 会话元数据中保存上传文档内容和目录的键
+const (
+	FileContentMetadataKey = "file_content"
+	FileTocMetadataKey     = "file_toc"
+)
+
+const attachedDocumentName = "会话上传的文档"
+
+// PathSeparator 标题路径各级之间的分隔符
+const PathSeparator = " > "
+
+const (
+	// minChunkOverlap 拼接分片时识别为重叠内容的最小字节数
+	minChunkOverlap = 8
+	// maxChunkOverlap 拼接分片时检查重叠的最大字节数
+	maxChunkOverlap = 2000
+)
+
+var (
+	markdownHeadingPattern = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.+?)\s*#*\s*$`)
+	fencePattern           = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	chapterPattern         = regexp.MustCompile(`^\s*(第[一二三四五六七八九十百千零〇两\d]+[章篇部]|(?i:chapter)\s+\d+)(\s|$|[:：、.])`)
+	sectionPattern         = regexp.MustCompile(`^\s*第[一二三四五六七八九十百千零〇两\d]+节(\s|$|[:：、.])`)
+)
+
+// Section 目录中的一个标题，Start/End 为该节（包含子节）在文档文本中的字节范围
+type Section struct {
+	Level int      `json:"level"`
+	Title string   `json:"title"`
+	Path  []string `json:"path"`
+	Start int      `json:"start"`
+	End   int      `json:"end"`
+}
+
+// PathString 标题路径，如 "第三章 安装 > 3.2 配置"
+func (s *Section) PathString() string {
+	return strings.Join(s.Path, PathSeparator)
+}
+
+// Build 根据标题生成文档目录：优先识别 Markdown 标题（忽略代码块），
+// 没有 Markdown 标题时识别"第X章/第X节/Chapter N"形式的标题行
+func Build(text string) []*Section {
+	headings := scanHeadings(text, markdownHeading)
+	if len(headings) == 0 {
+		headings = scanHeadings(text, plainHeading)
+	}
+
+	sections := make([]*Section, 0, len(headings))
+	var stack []*Section
+	for _, section := range headings {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= section.Level {
+			stack[len(stack)-1].End = section.Start
+			stack = stack[:len(stack)-1]
+		}
+		for _, parent := range stack {
+			section.Path = append(section.Path, parent.Title)
+		}
+		section.Path = append(section.Path, section.Title)
+		stack = append(stack, section)
+		sections = append(sections, section)
+	}
+	for _, section := range stack {
+		section.End = len(text)
+	}
+	return sections
+}
+
+// headingFunc 判断一行是否为标题，返回标题级别和标题文本
+type headingFunc func(line string) (int, string, bool)
+
+func markdownHeading(line string) (int, string, bool) {
+	match := markdownHeadingPattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, "", false
+	}
+	return len(match[1]), match[2], true
+}
+
+func plainHeading(line string) (int, string, bool) {
+	// 过长的行是正文中提到的章节而不是标题
+	if utf8.RuneCountInString(line) > 50 {
+		return 0, "", false
+	}
+	if chapterPattern.MatchString(line) {
+		return 1, line, true
+	}
+	if sectionPattern.MatchString(line) {
+		return 2, line, true
+	}
+	return 0, "", false
+}
+
+// scanHeadings 逐行扫描标题，代码块中的内容不作为标题
+func scanHeadings(text string, heading headingFunc) []*Section {
+	var sections []*Section
+	inFence := false
+	offset := 0
+	for offset < len(text) {
+		end := strings.IndexByte(text[offset:], '\n')
+		if end < 0 {
+			end = len(text) - offset
+		}
+		line := strings.TrimRight(text[offset:offset+end], "\r")
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+		} else if !inFence {
+			if level, title, ok := heading(line); ok {
+				if title = cleanTitle(title); title != "" {
+					sections = append(sections, &Section{Level: level, Title: title, Start: offset})
+				}
+			}
+		}
+		offset += end + 1
+	}
+	return sections
+}
+
+// cleanTitle 去除标题中的强调标记和多余空白
+func cleanTitle(title string) string {
+	title = strings.NewReplacer("**", "", "__", "", "`", "").Replace(title)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// Render 将目录渲染为缩进列表，提供给 LLM 选择章节
+func Render(sections []*Section) string {
+	var builder strings.Builder
+	for _, section := range sections {
+		builder.WriteString(strings.Repeat("  ", max(section.Level-1, 0)))
+		builder.WriteString("- ")
+		builder.WriteString(section.Title)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// Find 按标题路径查找章节。路径各级以 ">" 分隔，可以只写最后几级，
+// 每级与标题忽略大小写和空白后完全相同或包含即匹配，完全相同的匹配优先
+func Find(sections []*Section, path string) *Section {
+	var parts []string
+	for _, part := range strings.Split(path, ">") {
+		if part = normalize(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	var best *Section
+	bestScore := 0
+	for _, section := range sections {
+		score := matchPath(section.Path, parts)
+		if score > bestScore {
+			best, bestScore = section, score
+		}
+	}
+	return best
+}
+
+// matchPath 路径的最后一级必须与标题匹配，前面各级按顺序与祖先标题匹配，返回匹配得分（0 表示不匹配）
+func matchPath(sectionPath []string, parts []string) int {
+	if len(parts) > len(sectionPath) {
+		return 0
+	}
+	score := 0
+	j := len(sectionPath) - 1
+	for i := len(parts) - 1; i >= 0; i-- {
+		matched := false
+		for ; j >= 0; j-- {
+			title := normalize(sectionPath[j])
+			if title == parts[i] {
+				score += 2
+			} else if strings.Contains(title, parts[i]) {
+				score++
+			} else if i == len(parts)-1 {
+				return 0
+			} else {
+				continue
+			}
+			matched = true
+			j--
+			break
+		}
+		if !matched {
+			return 0
+		}
+	}
+	return score
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+// JoinChunks 按顺序拼接分片，去除相邻分片之间的重叠内容
+func JoinChunks(chunks []string) string {
+	var builder strings.Builder
+	previous := ""
+	for _, chunk := range chunks {
+		if previous != "" {
+			chunk = trimOverlap(previous, chunk)
+			builder.WriteString("\n")
+		}
+		builder.WriteString(chunk)
+		previous = chunk
+	}
+	return builder.String()
+}
+
+// trimOverlap 去除 next 开头与 previous 结尾重复的部分
+func trimOverlap(previous, next string) string {
+	limit := min(len(previous), len(next), maxChunkOverlap)
+	for k := limit; k >= minChunkOverlap; k-- {
+		if k < len(next) && !utf8.RuneStart(next[k]) {
+			continue
+		}
+		if strings.HasSuffix(previous, next[:k]) {
+			return next[k:]
+		}
+	}
+	return next
+}
+
+// Marshal 将目录序列化为 JSON 保存
+func Marshal(sections []*Section) string {
+	if len(sections) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(sections)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Document 可按章节读取的文档：知识库文档或会话中上传的文档
+type Document struct {
+	Name     string
+	Text     string
+	Sections []*Section
+}
+
+// LoadOutline 读取文档目录：知识库文档使用索引时保存的目录，会话文档使用上传时保存的目录，
+// 没有保存的目录（如功能上线前索引的文档）时加载文档重新生成
+func LoadOutline(ctx context.Context, convID, documentID string) (string, []*Section, error) {
+	if documentID != "" {
+		doc, err := KnowledgeDocument(ctx, documentID)
+		if err != nil {
+			return "", nil, err
+		}
+		// 启用安全标签过滤时，保存的目录包含调用方无权访问的章节标题，按可访问的分片重新生成
+		if sections, ok := unmarshal(doc.Toc); ok && security.AllowedLabels(ctx) == nil {
+			return doc.FileName, sections, nil
+		}
+	} else if metadata, err := conversationMetadata(ctx, convID); err == nil {
+		raw, _ := json.Marshal(metadata[FileTocMetadataKey])
+		if sections, ok := unmarshal(string(raw)); ok {
+			return attachedDocumentName, sections, nil
+		}
+	}
+
+	doc, err := LoadDocument(ctx, convID, documentID)
+	if err != nil {
+		return "", nil, err
+	}
+	return doc.Name, doc.Sections, nil
+}
+
+// LoadDocument 加载文档文本并生成目录，documentID 为空时读取会话中上传的文档
+func LoadDocument(ctx context.Context, convID, documentID string) (*Document, error) {
+	if documentID != "" {
+		return loadKnowledgeDocument(ctx, documentID)
+	}
+	text, err := AttachedContent(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Name: attachedDocumentName, Text: text, Sections: Build(text)}, nil
+}
+
+// loadKnowledgeDocument 按分片顺序还原知识库文档文本并生成目录，启用安全标签过滤时跳过调用方无权访问的分片
+func loadKnowledgeDocument(ctx context.Context, documentID string) (*Document, error) {
+	doc, err := KnowledgeDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := knowledge.GetAllChunksByDocId(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	texts := VisibleChunkTexts(ctx, chunks, doc.SecurityLabel, security.AllowedLabels(ctx))
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("文档 %s 没有可用的分片", documentID)
+	}
+	text := JoinChunks(texts)
+	return &Document{Name: doc.FileName, Text: text, Sections: Build(text)}, nil
+}
+
+// KnowledgeDocument 读取知识库文档记录并校验调用方能否访问文档所属的知识库（见 project.CheckKnowledgeAccess），
+// 文档ID由模型在工具调用中给出，不能据此读取其他项目的知识库
+func KnowledgeDocument(ctx context.Context, documentID string) (entity.KnowledgeDocuments, error) {
+	doc, err := knowledge.GetDocumentById(ctx, documentID)
+	if err != nil {
+		return doc, err
+	}
+	if doc.Id == "" {
+		return doc, fmt.Errorf("文档 %s 不存在", documentID)
+	}
+	if err = project.CheckKnowledgeAccess(ctx, doc.KnowledgeId); err != nil {
+		return entity.KnowledgeDocuments{}, err
+	}
+	return doc, nil
+}
+
+// VisibleChunkTexts 返回可用分片的内容；allowed 不为 nil 时只保留安全标签在其中的分片，
+// 分片 ext 中没有标签（功能上线前索引的分片）时使用文档标签，都没有时使用默认标签
+func VisibleChunkTexts(ctx context.Context, chunks []entity.KnowledgeChunks, documentLabel string, allowed map[string]bool) []string {
+	var texts []string
+	for _, chunk := range chunks {
+		if chunk.Status != 1 || strings.TrimSpace(chunk.Content) == "" {
+			continue
+		}
+		if allowed != nil {
+			metadata := make(map[string]any)
+			if chunk.Ext != "" {
+				_ = json.Unmarshal([]byte(chunk.Ext), &metadata)
+			}
+			if _, ok := metadata[security.MetadataKey]; !ok && documentLabel != "" {
+				metadata[security.MetadataKey] = documentLabel
+			}
+			if !allowed[security.ChunkLabel(ctx, &schema.Document{MetaData: metadata})] {
+				continue
+			}
+		}
+		texts = append(texts, chunk.Content)
+	}
+	return texts
+}
+
+func unmarshal(raw string) ([]*Section, bool) {
+	var sections []*Section
+	if raw == "" || json.Unmarshal([]byte(raw), &sections) != nil || len(sections) == 0 {
+		return nil, false
+	}
+	return sections, true
+}
+
+// conversationMetadata 读取会话元数据，转存的大字段自动还原
+func conversationMetadata(ctx context.Context, convID string) (map[string]interface{}, error) {
+	if convID == "" {
+		return nil, fmt.Errorf("未指定 document_id 且没有会话ID")
+	}
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil || len(conv.Metadata) == 0 {
+		return nil, fmt.Errorf("会话中没有上传的文档，请指定 document_id")
+	}
+	metadata, err := convmeta.Load(ctx, conv)
+	if err != nil {
+		return nil, fmt.Errorf("解析会话元数据失败: %w", err)
+	}
+	return metadata, nil
+}
+
+// AttachedContent 读取会话中上传并解析的文档内容
+func AttachedContent(ctx context.Context, convID string) (string, error) {
+	metadata, err := conversationMetadata(ctx, convID)
+	if err != nil {
+		return "", err
+	}
+	content, _ := metadata[FileContentMetadataKey].(string)
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("会话中没有上传的文档，请指定 document_id")
+	}
+	return content, nil
+}
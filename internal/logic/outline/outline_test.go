@@ -0,0 +1,132 @@
+package outline
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/model/entity"
+)
+
+const markdownDoc = `# 产品手册
+简介
+## 第一章 安装
+安装说明
+### 1.1 环境要求
+Go 1.24
+` + "```" + `
+# 代码块中的注释
+` + "```" + `
+## 第二章 配置
+配置说明
+`
+
+func TestBuild(t *testing.T) {
+	sections := Build(markdownDoc)
+	var paths []string
+	for _, section := range sections {
+		paths = append(paths, section.PathString())
+	}
+	want := []string{
+		"产品手册",
+		"产品手册 > 第一章 安装",
+		"产品手册 > 第一章 安装 > 1.1 环境要求",
+		"产品手册 > 第二章 配置",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Build() paths = %q, want %q", paths, want)
+	}
+
+	install := sections[1]
+	if got := markdownDoc[install.Start:install.End]; got != "## 第一章 安装\n安装说明\n### 1.1 环境要求\nGo 1.24\n```\n# 代码块中的注释\n```\n" {
+		t.Errorf("section content = %q", got)
+	}
+	if sections[0].End != len(markdownDoc) || sections[3].End != len(markdownDoc) {
+		t.Errorf("last sections should end at document end")
+	}
+}
+
+func TestBuildPlainHeadings(t *testing.T) {
+	text := "前言\n第一章 总则\n内容，参见第二章 附则的说明。\n第一节 适用范围\n内容\n第二章 附则\n内容"
+	var paths []string
+	for _, section := range Build(text) {
+		paths = append(paths, section.PathString())
+	}
+	want := []string{"第一章 总则", "第一章 总则 > 第一节 适用范围", "第二章 附则"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Build() paths = %q, want %q", paths, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	sections := Build(markdownDoc)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"第二章 配置", "产品手册 > 第二章 配置"},
+		{"第一章", "产品手册 > 第一章 安装"},
+		{"安装 > 环境要求", "产品手册 > 第一章 安装 > 1.1 环境要求"},
+		{"产品手册", "产品手册"},
+		{"配置 > 环境要求", ""},
+		{"第三章", ""},
+		{" > ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := ""
+			if section := Find(sections, tt.path); section != nil {
+				got = section.PathString()
+			}
+			if got != tt.want {
+				t.Errorf("Find(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinChunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"无重叠", []string{"第一段内容", "第二段内容"}, "第一段内容\n第二段内容"},
+		{"去除重叠", []string{"abcdefghijklmnop", "ijklmnopqrstuv"}, "abcdefghijklmnop\nqrstuv"},
+		{"重叠过短保留", []string{"abcdefg", "efgh"}, "abcdefg\nefgh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JoinChunks(tt.chunks); got != tt.want {
+				t.Errorf("JoinChunks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVisibleChunkTexts 测试启用安全标签过滤时跳过调用方无权访问的分片，分片没有标签时使用文档标签
+func TestVisibleChunkTexts(t *testing.T) {
+	chunks := []entity.KnowledgeChunks{
+		{Content: "# 产品手册", Ext: `{"chunk_index":0,"security_label":"public"}`, Status: 1},
+		{Content: "## 薪酬方案", Ext: `{"chunk_index":1,"security_label":"confidential"}`, Status: 1},
+		{Content: "## 历史分片", Ext: `{"chunk_index":2}`, Status: 1},
+		{Content: "## 未启用分片", Ext: `{"security_label":"public"}`, Status: 0},
+	}
+	tests := []struct {
+		name          string
+		documentLabel string
+		allowed       map[string]bool
+		want          []string
+	}{
+		{name: "Filter disabled", documentLabel: "internal", allowed: nil, want: []string{"# 产品手册", "## 薪酬方案", "## 历史分片"}},
+		{name: "Public clearance", documentLabel: "internal", allowed: map[string]bool{"public": true}, want: []string{"# 产品手册"}},
+		{name: "Unlabeled chunk uses document label", documentLabel: "internal", allowed: map[string]bool{"public": true, "internal": true}, want: []string{"# 产品手册", "## 历史分片"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VisibleChunkTexts(context.Background(), chunks, tt.documentLabel, tt.allowed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VisibleChunkTexts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package participant
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Malowking/kbgo/internal/history"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// subscriberBuffer 每个订阅者的事件缓冲，消费过慢时丢弃新事件（消息已持久化，可从会话历史补齐）
+const subscriberBuffer = 32
+
+// Event 会话中新保存的消息
+type Event struct {
+	MsgID    string `json:"msg_id"`
+	ConvID   string `json:"conv_id"`
+	Role     string `json:"role"`
+	AuthorID string `json:"author_id,omitempty"` // 发送用户消息的参与者
+	Content  string `json:"content"`
+	Created  int64  `json:"created"`
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = make(map[string]map[chan *Event]struct{}) // 会话ID -> 订阅者
+)
+
+func init() {
+	history.OnMessageSaved(func(ctx context.Context, msg *history.SavedMessage) {
+		// 工具消息和系统消息只用于模型上下文，不推送
+		if msg.Role != string(schema.User) && msg.Role != string(schema.Assistant) {
+			return
+		}
+		publish(&Event{
+			MsgID:    msg.MsgID,
+			ConvID:   msg.ConvID,
+			Role:     msg.Role,
+			AuthorID: msg.AuthorID,
+			Content:  msg.Content,
+			Created:  msg.CreateTime.Unix(),
+		})
+	})
+}
+
+// Subscribe 订阅会话中新保存的消息，返回事件通道和取消订阅函数
+// 事件只在当前进程内分发，多实例部署时需将同一会话的请求路由到同一实例
+func Subscribe(convID string) (<-chan *Event, func()) {
+	ch := make(chan *Event, subscriberBuffer)
+	subscribersMu.Lock()
+	if subscribers[convID] == nil {
+		subscribers[convID] = make(map[chan *Event]struct{})
+	}
+	subscribers[convID][ch] = struct{}{}
+	subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			subscribersMu.Lock()
+			delete(subscribers[convID], ch)
+			if len(subscribers[convID]) == 0 {
+				delete(subscribers, convID)
+			}
+			subscribersMu.Unlock()
+		})
+	}
+}
+
+// publish 向会话的所有订阅者分发事件
+func publish(event *Event) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for ch := range subscribers[event.ConvID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package participant 多人共享会话：管理会话参与者及其角色，校验参与者对会话的读写权限，
+// 并把会话中保存的新消息推送给所有在线的参与者。没有参与者的会话为单人会话，只有会话创建者可以访问
+package participant
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// 参与者角色
+const (
+	RoleOwner  = "owner"  // 可读写会话
+	RoleMember = "member" // 可读写会话
+	RoleViewer = "viewer" // 只能读取会话和订阅新消息
+)
+
+// List 获取会话参与者，只有会话创建者和 owner 参与者可以查看
+func List(ctx context.Context, convID string) ([]*gormModel.ConversationParticipant, error) {
+	participants, err := authorizeManage(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+// Save 添加会话参与者或修改参与者角色，会话添加第一个参与者后变为共享会话；只有会话创建者和 owner 参与者可以修改
+func Save(ctx context.Context, convID, userID, role string) (*gormModel.ConversationParticipant, error) {
+	switch role {
+	case RoleOwner, RoleMember, RoleViewer:
+	default:
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "invalid role '%s', must be one of owner/member/viewer", role)
+	}
+	if _, err := authorizeManage(ctx, convID); err != nil {
+		return nil, err
+	}
+	participant := &gormModel.ConversationParticipant{ConvID: convID, UserID: strings.TrimSpace(userID), Role: role}
+	if participant.UserID == "" {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, "user_id is required")
+	}
+	if err := dao.ConversationParticipant.Save(ctx, participant); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// Remove 移除会话参与者，只有会话创建者和 owner 参与者可以移除
+func Remove(ctx context.Context, convID, userID string) error {
+	if _, err := authorizeManage(ctx, convID); err != nil {
+		return err
+	}
+	removed, err := dao.ConversationParticipant.Remove(ctx, convID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return gerror.NewCodef(gcode.CodeNotFound, "user %s is not a participant of conversation %s", userID, convID)
+	}
+	return nil
+}
+
+// CanManage 校验调用者能否管理会话（删除会话、管理参与者）：会话创建者或 owner 参与者
+func CanManage(ctx context.Context, convID string) error {
+	_, err := authorizeManage(ctx, convID)
+	return err
+}
+
+// CanRead 校验用户能否读取会话（订阅新消息、导出）
+func CanRead(ctx context.Context, convID, userID string) error {
+	return authorize(ctx, convID, userID, false)
+}
+
+// CanWrite 校验用户能否在会话中发送消息
+func CanWrite(ctx context.Context, convID, userID string) error {
+	return authorize(ctx, convID, userID, true)
+}
+
+// CanReadMessage 校验调用者能否读取消息所属的会话，消息不存在时返回 NotFound
+func CanReadMessage(ctx context.Context, msgID string) error {
+	msg, err := dao.Message.GetByMsgID(ctx, msgID)
+	if err != nil {
+		return err
+	}
+	if msg == nil {
+		return gerror.NewCodef(gcode.CodeNotFound, "message not found: %s", msgID)
+	}
+	return CanRead(ctx, msg.ConvID, identity.UserID(ctx))
+}
+
+// authorize 已认证的请求以认证用户为准，忽略请求参数中的 user_id
+func authorize(ctx context.Context, convID, userID string, write bool) error {
+	userID = identity.Resolve(ctx, userID)
+	participants, err := dao.ConversationParticipant.List(ctx, convID)
+	if err != nil {
+		return err
+	}
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return err
+	}
+	owner := ""
+	if conv != nil {
+		owner = conv.UserID
+		if len(participants) == 0 && !identity.Owns(ctx, conv.UserID) {
+			return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s cannot access conversation %s", userID, convID)
+		}
+	}
+	return check(participants, owner, convID, userID, write)
+}
+
+// authorizeManage 校验调用者能否管理会话参与者：会话创建者（见 identity.Owns）或 owner 参与者，返回当前的参与者列表
+func authorizeManage(ctx context.Context, convID string) ([]*gormModel.ConversationParticipant, error) {
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "conversation %s not found", convID)
+	}
+	participants, err := dao.ConversationParticipant.List(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkManage(ctx, participants, conv.UserID, convID); err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+// checkManage 会话创建者或 owner 参与者可以管理参与者，未配置凭证时未认证的请求按 identity.Owns 不做限制
+func checkManage(ctx context.Context, participants []*gormModel.ConversationParticipant, owner, convID string) error {
+	if identity.Owns(ctx, owner) {
+		return nil
+	}
+	caller := identity.UserID(ctx)
+	for _, p := range participants {
+		if p.UserID == caller && p.Role == RoleOwner {
+			return nil
+		}
+	}
+	return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s cannot manage participants of conversation %s", caller, convID)
+}
+
+// check 按参与者列表校验权限，会话创建者 owner 不在参与者列表中时也可以读写；没有参与者的单人会话由 authorize 校验创建者
+func check(participants []*gormModel.ConversationParticipant, owner, convID, userID string, write bool) error {
+	if len(participants) == 0 {
+		return nil
+	}
+	if userID == "" {
+		return gerror.NewCodef(gcode.CodeNotAuthorized, "conversation %s is shared, user_id is required", convID)
+	}
+	if userID == owner {
+		return nil
+	}
+	for _, p := range participants {
+		if p.UserID != userID {
+			continue
+		}
+		if write && p.Role == RoleViewer {
+			return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s can only view conversation %s", userID, convID)
+		}
+		return nil
+	}
+	return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s is not a participant of conversation %s", userID, convID)
+}
@@ -0,0 +1,108 @@
+package participant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// TestCheck 测试共享会话的读写权限校验
+func TestCheck(t *testing.T) {
+	shared := []*gormModel.ConversationParticipant{
+		{ConvID: "conv-1", UserID: "alice", Role: RoleOwner},
+		{ConvID: "conv-1", UserID: "bob", Role: RoleMember},
+		{ConvID: "conv-1", UserID: "carol", Role: RoleViewer},
+	}
+	tests := []struct {
+		name         string
+		participants []*gormModel.ConversationParticipant
+		userID       string
+		write        bool
+		allowed      bool
+	}{
+		{name: "Single-user conversation", participants: nil, userID: "", write: true, allowed: true},
+		{name: "Owner writes", participants: shared, userID: "alice", write: true, allowed: true},
+		{name: "Member writes", participants: shared, userID: "bob", write: true, allowed: true},
+		{name: "Viewer reads", participants: shared, userID: "carol", write: false, allowed: true},
+		{name: "Viewer cannot write", participants: shared, userID: "carol", write: true, allowed: false},
+		{name: "Outsider cannot read", participants: shared, userID: "dave", write: false, allowed: false},
+		{name: "Missing user", participants: shared, userID: "", write: false, allowed: false},
+		{name: "Creator not listed as participant writes", participants: shared, userID: "erin", write: true, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(tt.participants, "erin", "conv-1", tt.userID, tt.write)
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("check(%q, write=%v) returned error: %v", tt.userID, tt.write, err)
+				}
+				return
+			}
+			if gerror.Code(err) != gcode.CodeNotAuthorized {
+				t.Errorf("check(%q, write=%v) = %v, want not authorized", tt.userID, tt.write, err)
+			}
+		})
+	}
+}
+
+// TestCheckManage 测试只有会话创建者和 owner 参与者可以管理参与者
+func TestCheckManage(t *testing.T) {
+	shared := []*gormModel.ConversationParticipant{
+		{ConvID: "conv-1", UserID: "alice", Role: RoleOwner},
+		{ConvID: "conv-1", UserID: "bob", Role: RoleMember},
+	}
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		participants []*gormModel.ConversationParticipant
+		allowed      bool
+	}{
+		{name: "Creator", ctx: identity.WithUser(context.Background(), "erin"), participants: nil, allowed: true},
+		{name: "Owner participant", ctx: identity.WithUser(context.Background(), "alice"), participants: shared, allowed: true},
+		{name: "Member cannot manage", ctx: identity.WithUser(context.Background(), "bob"), participants: shared, allowed: false},
+		{name: "Outsider cannot add themselves", ctx: identity.WithUser(context.Background(), "mallory"), participants: nil, allowed: false},
+		{name: "Unauthenticated request", ctx: context.Background(), participants: shared, allowed: true},
+		{name: "Unauthenticated request with credentials configured", ctx: identity.WithIsolation(context.Background()), participants: shared, allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkManage(tt.ctx, tt.participants, "erin", "conv-1")
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("checkManage() returned error: %v", err)
+				}
+				return
+			}
+			if gerror.Code(err) != gcode.CodeNotAuthorized {
+				t.Errorf("checkManage() = %v, want not authorized", err)
+			}
+		})
+	}
+}
+
+// TestSubscribe 测试新消息只推送给同一会话的订阅者
+func TestSubscribe(t *testing.T) {
+	events, cancel := Subscribe("conv-1")
+	defer cancel()
+	other, cancelOther := Subscribe("conv-2")
+	defer cancelOther()
+
+	publish(&Event{ConvID: "conv-1", Role: "user", AuthorID: "alice", Content: "今天的发布计划是什么？"})
+	select {
+	case event := <-events:
+		if event.AuthorID != "alice" {
+			t.Errorf("unexpected event author %q", event.AuthorID)
+		}
+	default:
+		t.Fatal("subscriber did not receive event")
+	}
+	select {
+	case event := <-other:
+		t.Errorf("subscriber of another conversation received %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,267 @@
+// Package persona 管理可复用的回答人设预设，并在组装 system 提示词时与任务提示合并，
+// 避免为每个模型或场景重复粘贴相同的人设文本
+package persona
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// 正式程度
+const (
+	FormalityFormal  = "formal"
+	FormalityNeutral = "neutral"
+	FormalityCasual  = "casual"
+)
+
+// 表情符号策略
+const (
+	EmojiNone    = "none"    // 不使用表情符号，非流式回答会移除模型输出的表情符号
+	EmojiSparing = "sparing" // 偶尔使用
+	EmojiFree    = "free"    // 不限制
+)
+
+// ModelExtraKey 模型 extra 配置中指定默认人设ID的字段
+const ModelExtraKey = "personaID"
+
+// Fields 人设字段，更新时为 nil 的字段保持不变
+type Fields struct {
+	Name         *string
+	Description  *string
+	Tone         *string
+	Formality    *string
+	EmojiPolicy  *string
+	Signature    *string
+	Instructions *string
+}
+
+// Create 创建人设
+func Create(ctx context.Context, fields *Fields) (*gormModel.Persona, error) {
+	p := &gormModel.Persona{ID: strings.ReplaceAll(uuid.New().String(), "-", "")}
+	if err := apply(ctx, p, fields); err != nil {
+		return nil, err
+	}
+	if err := dao.Persona.Create(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update 更新人设
+func Update(ctx context.Context, id string, fields *Fields) (*gormModel.Persona, error) {
+	p, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = apply(ctx, p, fields); err != nil {
+		return nil, err
+	}
+	if err = dao.Persona.Update(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Delete 删除人设，引用该人设作为默认人设的模型会回退到 persona.default 配置
+func Delete(ctx context.Context, id string) error {
+	if _, err := Get(ctx, id); err != nil {
+		return err
+	}
+	return dao.Persona.Delete(ctx, id)
+}
+
+// Get 获取人设，不存在时返回 CodeNotFound 错误
+func Get(ctx context.Context, id string) (*gormModel.Persona, error) {
+	p, err := dao.Persona.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "persona not found: %s", id)
+	}
+	return p, nil
+}
+
+// List 获取全部人设
+func List(ctx context.Context) ([]*gormModel.Persona, error) {
+	return dao.Persona.List(ctx)
+}
+
+// Resolve 获取对话使用的人设：请求指定的人设优先（不存在时返回错误），
+// 其次为模型 extra 中 personaID 指定的默认人设，最后为 persona.default 配置，都未配置时返回 nil
+func Resolve(ctx context.Context, personaID, modelID string) (*gormModel.Persona, error) {
+	if personaID != "" {
+		return Get(ctx, personaID)
+	}
+	if mc := coreModel.Registry.Get(modelID); mc != nil {
+		if id, ok := mc.Extra[ModelExtraKey].(string); ok && id != "" {
+			personaID = id
+		}
+	}
+	if personaID == "" {
+		personaID = g.Cfg().MustGet(ctx, "persona.default", "").String()
+	}
+	if personaID == "" {
+		return nil, nil
+	}
+	p, err := dao.Persona.GetByID(ctx, personaID)
+	if err != nil || p == nil {
+		// 默认人设失效时不影响对话
+		g.Log().Warningf(ctx, "默认人设 %s 不可用，不使用人设: %v", personaID, err)
+		return nil, nil
+	}
+	return p, nil
+}
+
+// Prompt 将人设转换为追加到 system 提示词中的人设说明，任务提示（角色、参考资料）保持不变
+func Prompt(p *gormModel.Persona) string {
+	if p == nil {
+		return ""
+	}
+
+	var rules []string
+	if p.Tone != "" {
+		rules = append(rules, fmt.Sprintf("语气：%s。", strings.TrimRight(p.Tone, "。.")))
+	}
+	switch p.Formality {
+	case FormalityFormal:
+		rules = append(rules, "使用正式、书面的表达，称呼用户为“您”，避免口语和网络用语。")
+	case FormalityNeutral:
+		rules = append(rules, "使用自然、礼貌的表达，不过于口语化也不过于生硬。")
+	case FormalityCasual:
+		rules = append(rules, "使用轻松、口语化的表达，像同事之间交流一样。")
+	}
+	switch p.EmojiPolicy {
+	case EmojiNone:
+		rules = append(rules, "不要使用任何表情符号。")
+	case EmojiSparing:
+		rules = append(rules, "可以偶尔使用表情符号，整段回答不超过一两个。")
+	case EmojiFree:
+		rules = append(rules, "可以适当使用表情符号增强表达。")
+	}
+	if instructions := strings.TrimSpace(p.Instructions); instructions != "" {
+		rules = append(rules, instructions)
+	}
+	if p.Signature != "" {
+		rules = append(rules, fmt.Sprintf("在回答末尾另起一行署名：%s", p.Signature))
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n\n🎭 回答人设（")
+	builder.WriteString(p.Name)
+	builder.WriteString("）：\n")
+	for _, rule := range rules {
+		builder.WriteString("- ")
+		builder.WriteString(rule)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// Finish 按人设整理生成后的回答：不允许表情符号时移除表情符号，缺少署名时在末尾补充署名
+// 流式回答已发送的内容无法改写，只依赖提示词约束
+func Finish(p *gormModel.Persona, answer string) string {
+	if p == nil || answer == "" {
+		return answer
+	}
+	if p.EmojiPolicy == EmojiNone {
+		answer = stripEmoji(answer)
+	}
+	if signature := strings.TrimSpace(p.Signature); signature != "" && !strings.HasSuffix(strings.TrimSpace(answer), signature) {
+		answer = strings.TrimRight(answer, "\n ") + "\n\n" + signature
+	}
+	return answer
+}
+
+// apply 校验并写入人设字段
+func apply(ctx context.Context, p *gormModel.Persona, fields *Fields) error {
+	if fields.Name != nil {
+		name := strings.TrimSpace(*fields.Name)
+		if name == "" {
+			return gerror.NewCode(gcode.CodeInvalidParameter, "persona name is required")
+		}
+		exists, err := dao.Persona.NameExists(ctx, name, p.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "persona name '%s' already exists", name)
+		}
+		p.Name = name
+	}
+	if p.Name == "" {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "persona name is required")
+	}
+	if fields.Formality != nil {
+		if err := validate("formality", *fields.Formality, FormalityFormal, FormalityNeutral, FormalityCasual); err != nil {
+			return err
+		}
+		p.Formality = *fields.Formality
+	}
+	if fields.EmojiPolicy != nil {
+		if err := validate("emoji_policy", *fields.EmojiPolicy, EmojiNone, EmojiSparing, EmojiFree); err != nil {
+			return err
+		}
+		p.EmojiPolicy = *fields.EmojiPolicy
+	}
+	setString(&p.Description, fields.Description)
+	setString(&p.Tone, fields.Tone)
+	setString(&p.Signature, fields.Signature)
+	setString(&p.Instructions, fields.Instructions)
+	return nil
+}
+
+func validate(field, value string, allowed ...string) error {
+	if value == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return gerror.NewCodef(gcode.CodeInvalidParameter, "invalid %s '%s', must be one of %s", field, value, strings.Join(allowed, "/"))
+}
+
+func setString(dst *string, value *string) {
+	if value != nil {
+		*dst = strings.TrimSpace(*value)
+	}
+}
+
+// stripEmoji 移除表情符号（含变体选择符和零宽连接符），并压缩因此产生的多余空格
+func stripEmoji(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		if isEmoji(r) {
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return strings.ReplaceAll(builder.String(), "  ", " ")
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // 表情、符号和象形文字、交通、补充符号
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // 杂项符号、装饰符号
+		return true
+	case r == 0xFE0F || r == 0x200D || (r >= 0x1F3FB && r <= 0x1F3FF):
+		return true
+	}
+	return unicode.Is(unicode.Regional_Indicator, r)
+}
@@ -0,0 +1,70 @@
+package persona
+
+import (
+	"strings"
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// TestPrompt 测试人设说明的生成
+func TestPrompt(t *testing.T) {
+	if got := Prompt(nil); got != "" {
+		t.Errorf("nil persona should produce no prompt, got %q", got)
+	}
+	if got := Prompt(&gormModel.Persona{Name: "空人设"}); got != "" {
+		t.Errorf("persona without rules should produce no prompt, got %q", got)
+	}
+
+	p := &gormModel.Persona{
+		Name:         "客服小K",
+		Tone:         "亲切耐心。",
+		Formality:    FormalityFormal,
+		EmojiPolicy:  EmojiNone,
+		Signature:    "—— 客服小K",
+		Instructions: "遇到无法回答的问题时引导用户联系人工客服",
+	}
+	prompt := Prompt(p)
+	for _, want := range []string{"回答人设（客服小K）", "语气：亲切耐心。", "“您”", "不要使用任何表情符号", "人工客服", "署名：—— 客服小K"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q: %s", want, prompt)
+		}
+	}
+}
+
+// TestFinish 测试生成后按人设整理回答
+func TestFinish(t *testing.T) {
+	tests := []struct {
+		name    string
+		persona *gormModel.Persona
+		answer  string
+		want    string
+	}{
+		{name: "Nil persona", persona: nil, answer: "你好 😀", want: "你好 😀"},
+		{name: "Strip emoji", persona: &gormModel.Persona{EmojiPolicy: EmojiNone}, answer: "好的 👍 马上处理 ✅", want: "好的 马上处理 "},
+		{name: "Keep emoji", persona: &gormModel.Persona{EmojiPolicy: EmojiFree}, answer: "好的 👍", want: "好的 👍"},
+		{name: "Append signature", persona: &gormModel.Persona{Signature: "—— 小K"}, answer: "已处理。\n", want: "已处理。\n\n—— 小K"},
+		{name: "Signature present", persona: &gormModel.Persona{Signature: "—— 小K"}, answer: "已处理。\n\n—— 小K\n", want: "已处理。\n\n—— 小K\n"},
+		{name: "Empty answer", persona: &gormModel.Persona{Signature: "—— 小K"}, answer: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Finish(tt.persona, tt.answer); got != tt.want {
+				t.Errorf("Finish() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidate 测试枚举字段校验
+func TestValidate(t *testing.T) {
+	if err := validate("formality", "", FormalityFormal, FormalityCasual); err != nil {
+		t.Errorf("empty value should be allowed: %v", err)
+	}
+	if err := validate("formality", FormalityCasual, FormalityFormal, FormalityCasual); err != nil {
+		t.Errorf("allowed value rejected: %v", err)
+	}
+	if err := validate("formality", "rude", FormalityFormal, FormalityCasual); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
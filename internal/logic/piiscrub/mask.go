@@ -0,0 +1,147 @@
+package piiscrub
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// 支持脱敏的个人信息类型
+const (
+	TypeEmail    = "email"
+	TypePhone    = "phone"
+	TypeIDNumber = "id_number"
+)
+
+// Types 全部支持的个人信息类型
+var Types = []string{TypeEmail, TypePhone, TypeIDNumber}
+
+var (
+	// emailPattern 邮箱，第 1 组为域名（脱敏后保留，用于统计来源）
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,})`)
+	// idNumberPattern 18 位居民身份证号（校验码另行验证）和美国 SSN
+	idNumberPattern = regexp.MustCompile(`\b[1-9]\d{5}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b|\b\d{3}-\d{2}-\d{4}\b`)
+	// phonePattern 中国大陆手机号（可带 +86 / 86 前缀）和带国家码的国际号码
+	phonePattern = regexp.MustCompile(`(?:\+?\b86[- ]?|\b)1[3-9]\d{9}\b|\+[1-9]\d{0,2}(?:[- ]?\d){6,12}\b`)
+)
+
+// idWeights 18 位身份证号前 17 位的加权因子
+var idWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// idCheckCodes 加权和对 11 取模对应的校验码
+const idCheckCodes = "10X98765432"
+
+// validIDNumber 校验 18 位身份证号的校验码，非 18 位的号码（SSN）直接通过
+func validIDNumber(s string) bool {
+	if len(s) != 18 {
+		return true
+	}
+	sum := 0
+	for i, w := range idWeights {
+		sum += int(s[i]-'0') * w
+	}
+	return strings.ToUpper(s[17:]) == string(idCheckCodes[sum%11])
+}
+
+// Mask 按类型脱敏文本中的个人信息，返回脱敏后的文本和各类型的脱敏数量
+// 脱敏时保留不可识别个人的信息，便于后续统计：邮箱保留域名，手机号保留后 4 位
+func Mask(text string, types []string) (string, map[string]int) {
+	counts := map[string]int{}
+	if text == "" {
+		return text, counts
+	}
+	enabled := make(map[string]bool, len(types))
+	for _, t := range types {
+		enabled[t] = true
+	}
+
+	// 先处理邮箱，避免邮箱中的数字被识别为号码
+	if enabled[TypeEmail] {
+		text = emailPattern.ReplaceAllStringFunc(text, func(m string) string {
+			counts[TypeEmail]++
+			return "[EMAIL]@" + emailPattern.FindStringSubmatch(m)[1]
+		})
+	}
+	if enabled[TypeIDNumber] {
+		text = idNumberPattern.ReplaceAllStringFunc(text, func(m string) string {
+			if !validIDNumber(m) {
+				return m
+			}
+			counts[TypeIDNumber]++
+			return "[ID_NUMBER]"
+		})
+	}
+	if enabled[TypePhone] {
+		text = phonePattern.ReplaceAllStringFunc(text, func(m string) string {
+			digits := strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return r
+				}
+				return -1
+			}, m)
+			counts[TypePhone]++
+			return "[PHONE]" + digits[len(digits)-4:]
+		})
+	}
+	for t, n := range counts {
+		if n == 0 {
+			delete(counts, t)
+		}
+	}
+	return text, counts
+}
+
+// MaskJSON 脱敏 JSON 元数据中所有字符串值（键名保持不变），没有需要脱敏的内容时 changed 为 false
+func MaskJSON(data gormModel.JSON, types []string) (masked gormModel.JSON, counts map[string]int, changed bool) {
+	counts = map[string]int{}
+	if len(data) == 0 {
+		return data, counts, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return data, counts, false
+	}
+	value = maskValue(value, types, counts)
+	if len(counts) == 0 {
+		return data, counts, false
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return data, map[string]int{}, false
+	}
+	return out, counts, true
+}
+
+// maskValue 递归脱敏 JSON 值中的字符串
+func maskValue(value any, types []string, counts map[string]int) any {
+	switch v := value.(type) {
+	case string:
+		masked, c := Mask(v, types)
+		mergeCounts(counts, c)
+		return masked
+	case map[string]any:
+		for key, item := range v {
+			v[key] = maskValue(item, types, counts)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = maskValue(item, types, counts)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// mergeCounts 把 src 的数量累加到 dst
+func mergeCounts(dst, src map[string]int) {
+	for t, n := range src {
+		dst[t] += n
+	}
+}
@@ -0,0 +1,322 @@
+// Package piiscrub 会话个人信息脱敏任务：定时扫描保存超过指定天数的消息，脱敏文本内容和元数据中的邮箱、手机号、证件号，
+// 策略可按项目覆盖（会话按所用模型归属项目），每条被脱敏的消息写入审计记录（不保存原文）
+package piiscrub
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultCron      = "0 30 2 * * *"
+	defaultAfterDays = 90
+	defaultBatchSize = 200
+)
+
+// Config 脱敏任务配置（piiScrub.*），enabled / afterDays / types 为全局策略，可被项目设置覆盖
+type Config struct {
+	Enabled   bool
+	Cron      string
+	AfterDays int
+	Types     []string
+	BatchSize int
+}
+
+// LoadConfig 读取脱敏任务配置，未配置的项使用默认值
+func LoadConfig(ctx context.Context) *Config {
+	cfg := &Config{
+		Enabled:   g.Cfg().MustGet(ctx, "piiScrub.enabled", false).Bool(),
+		Cron:      g.Cfg().MustGet(ctx, "piiScrub.cron", defaultCron).String(),
+		AfterDays: g.Cfg().MustGet(ctx, "piiScrub.afterDays", defaultAfterDays).Int(),
+		Types:     g.Cfg().MustGet(ctx, "piiScrub.types", Types).Strings(),
+		BatchSize: g.Cfg().MustGet(ctx, "piiScrub.batchSize", defaultBatchSize).Int(),
+	}
+	if cfg.AfterDays <= 0 {
+		cfg.AfterDays = defaultAfterDays
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	cfg.Types = normalizeTypes(cfg.Types)
+	return cfg
+}
+
+// Policy 生效的脱敏策略
+type Policy struct {
+	Enabled   bool
+	AfterDays int
+	Types     []string
+}
+
+// PolicyFor 合并全局配置和项目策略，项目未设置的项使用全局配置
+func (c *Config) PolicyFor(settings *v1.PIIScrubPolicy) Policy {
+	policy := Policy{Enabled: c.Enabled, AfterDays: c.AfterDays, Types: c.Types}
+	if settings == nil {
+		return policy
+	}
+	if settings.Enabled != nil {
+		policy.Enabled = *settings.Enabled
+	}
+	if settings.AfterDays > 0 {
+		policy.AfterDays = settings.AfterDays
+	}
+	if types := normalizeTypes(settings.Types); len(types) > 0 {
+		policy.Types = types
+	}
+	if len(policy.Types) == 0 {
+		policy.Enabled = false
+	}
+	return policy
+}
+
+// normalizeTypes 统一为小写并去掉重复和不支持的类型
+func normalizeTypes(types []string) []string {
+	var result []string
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if slices.Contains(Types, t) && !slices.Contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// InitPIIScrub 初始化脱敏定时任务，piiScrub.enabled 为 false 时只有设置了启用策略的项目会被处理
+func InitPIIScrub() {
+	ctx := gctx.New()
+	cfg := LoadConfig(ctx)
+	_, err := gcron.AddSingleton(ctx, cfg.Cron, func(ctx context.Context) {
+		res, err := Run(ctx, false)
+		if err != nil {
+			g.Log().Errorf(ctx, "PII scrubbing job failed: %v", err)
+			return
+		}
+		if res.ScannedMessages > 0 {
+			g.Log().Infof(ctx, "PII scrubbing job %s finished: scanned=%d, scrubbed=%d, masked=%v",
+				res.RunID, res.ScannedMessages, res.ScrubbedMessages, res.Masked)
+		}
+	}, "pii-scrub")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule PII scrubbing job: %v", err)
+	} else {
+		g.Log().Infof(ctx, "PII scrubbing job scheduled with pattern: %s", cfg.Cron)
+	}
+}
+
+// runMu 同一时间只执行一个脱敏任务（定时任务和手动触发共用）
+var runMu sync.Mutex
+
+// convPolicy 会话所属项目和生效策略
+type convPolicy struct {
+	projectID string
+	policy    Policy
+}
+
+// Run 执行一次脱敏任务：按ID顺序分批扫描保存时间超过策略天数、尚未处理的消息
+// 未启用策略的消息和未到期的消息跳过，留待策略变更或到期后处理；dryRun 时只统计不修改
+func Run(ctx context.Context, dryRun bool) (*v1.PIIScrubRunRes, error) {
+	if !runMu.TryLock() {
+		return nil, gerror.NewCode(gcode.CodeOperationFailed, "a PII scrubbing run is already in progress")
+	}
+	defer runMu.Unlock()
+
+	cfg := LoadConfig(ctx)
+	res := &v1.PIIScrubRunRes{RunID: uuid.New().String(), DryRun: dryRun, Masked: map[string]int{}}
+
+	projects, err := dao.Project.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	projectPolicies := make(map[string]Policy, len(projects))
+	minDays := 0
+	if cfg.Enabled {
+		minDays = cfg.AfterDays
+	}
+	for _, p := range projects {
+		policy := cfg.PolicyFor(project.ParseSettings(p).PIIScrub)
+		projectPolicies[p.ID] = policy
+		if policy.Enabled && (minDays == 0 || policy.AfterDays < minDays) {
+			minDays = policy.AfterDays
+		}
+	}
+	if minDays == 0 {
+		return res, nil
+	}
+
+	now := time.Now()
+	before := now.AddDate(0, 0, -minDays)
+	convPolicies := map[string]*convPolicy{}
+	var afterID uint64
+	for {
+		messages, err := dao.PIIScrub.ListCandidates(ctx, before, afterID, cfg.BatchSize)
+		if err != nil {
+			return res, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		afterID = messages[len(messages)-1].ID
+
+		var due []*gormModel.Message
+		var msgIDs []string
+		for _, msg := range messages {
+			cp, err := resolvePolicy(ctx, cfg, projectPolicies, convPolicies, msg.ConvID)
+			if err != nil {
+				return res, err
+			}
+			if !cp.policy.Enabled || msg.CreateTime == nil || msg.CreateTime.After(now.AddDate(0, 0, -cp.policy.AfterDays)) {
+				continue
+			}
+			due = append(due, msg)
+			msgIDs = append(msgIDs, msg.MsgID)
+		}
+		if len(due) == 0 {
+			continue
+		}
+		contents, err := dao.MessageContent.ListByMsgIDs(ctx, msgIDs)
+		if err != nil {
+			return res, err
+		}
+		contentsByMsg := map[string][]*gormModel.MessageContent{}
+		for _, content := range contents {
+			contentsByMsg[content.MsgID] = append(contentsByMsg[content.MsgID], content)
+		}
+
+		var clean []uint64
+		for _, msg := range due {
+			cp := convPolicies[msg.ConvID]
+			res.ScannedMessages++
+			changedContents, fields, counts := scrubMessage(msg, contentsByMsg[msg.MsgID], cp.policy.Types)
+			if len(counts) == 0 {
+				clean = append(clean, msg.ID)
+				continue
+			}
+			res.ScrubbedMessages++
+			mergeCounts(res.Masked, counts)
+			if dryRun {
+				continue
+			}
+			countsJSON, _ := json.Marshal(counts)
+			log := &gormModel.PIIScrubLog{
+				RunID:     res.RunID,
+				ConvID:    msg.ConvID,
+				MsgID:     msg.MsgID,
+				ProjectID: cp.projectID,
+				Fields:    strings.Join(fields, ","),
+				Counts:    string(countsJSON),
+			}
+			if err := dao.PIIScrub.Apply(ctx, msg, changedContents, log, now); err != nil {
+				return res, err
+			}
+		}
+		if !dryRun {
+			if err := dao.PIIScrub.MarkScrubbed(ctx, clean, now); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// resolvePolicy 获取会话的生效策略，结果在本次任务内缓存
+func resolvePolicy(ctx context.Context, cfg *Config, projectPolicies map[string]Policy, cache map[string]*convPolicy, convID string) (*convPolicy, error) {
+	if cp, ok := cache[convID]; ok {
+		return cp, nil
+	}
+	cp := &convPolicy{policy: cfg.PolicyFor(nil)}
+	conv, err := dao.Conversation.GetByConvID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv != nil && conv.ModelID != "" {
+		projectID, err := dao.Project.GetResourceProject(ctx, project.ResourceModel, conv.ModelID)
+		if err != nil {
+			return nil, err
+		}
+		if policy, ok := projectPolicies[projectID]; ok {
+			cp.projectID = projectID
+			cp.policy = policy
+		}
+	}
+	cache[convID] = cp
+	return cp, nil
+}
+
+// scrubMessage 脱敏消息元数据和内容块（原地修改），返回被修改的内容块、被脱敏的字段和各类型数量
+func scrubMessage(msg *gormModel.Message, contents []*gormModel.MessageContent, types []string) ([]*gormModel.MessageContent, []string, map[string]int) {
+	counts := map[string]int{}
+	fieldSet := map[string]bool{}
+
+	if masked, c, changed := MaskJSON(msg.Metadata, types); changed {
+		msg.Metadata = masked
+		mergeCounts(counts, c)
+		fieldSet["metadata"] = true
+	}
+	var changedContents []*gormModel.MessageContent
+	for _, content := range contents {
+		changed := false
+		if text, c := Mask(content.TextContent, types); len(c) > 0 {
+			content.TextContent = text
+			mergeCounts(counts, c)
+			fieldSet["content"] = true
+			changed = true
+		}
+		if masked, c, ok := MaskJSON(content.Metadata, types); ok {
+			content.Metadata = masked
+			mergeCounts(counts, c)
+			fieldSet["content_metadata"] = true
+			changed = true
+		}
+		if changed {
+			changedContents = append(changedContents, content)
+		}
+	}
+
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return changedContents, fields, counts
+}
+
+// ListLogs 分页查询脱敏审计记录
+func ListLogs(ctx context.Context, req *v1.PIIScrubLogListReq) (*v1.PIIScrubLogListRes, error) {
+	logs, total, err := dao.PIIScrub.ListLogs(ctx, req.RunID, req.ConvID, req.ProjectID, req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	res := &v1.PIIScrubLogListRes{List: make([]*v1.PIIScrubLogItem, 0, len(logs)), Total: total, Page: req.Page}
+	for _, log := range logs {
+		item := &v1.PIIScrubLogItem{
+			RunID:     log.RunID,
+			ConvID:    log.ConvID,
+			MsgID:     log.MsgID,
+			ProjectID: log.ProjectID,
+			Fields:    strings.Split(log.Fields, ","),
+			Counts:    map[string]int{},
+		}
+		_ = json.Unmarshal([]byte(log.Counts), &item.Counts)
+		if log.CreateTime != nil {
+			item.CreateTime = log.CreateTime.Format(time.RFC3339)
+		}
+		res.List = append(res.List, item)
+	}
+	return res, nil
+}
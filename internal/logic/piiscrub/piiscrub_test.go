@@ -0,0 +1,98 @@
+package piiscrub
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		types  []string
+		want   string
+		counts map[string]int
+	}{
+		{
+			name:   "email keeps domain",
+			text:   "请联系 zhang.san+kb@example.com.cn 处理",
+			types:  Types,
+			want:   "请联系 [EMAIL]@example.com.cn 处理",
+			counts: map[string]int{TypeEmail: 1},
+		},
+		{
+			name:   "phones keep last 4 digits",
+			text:   "手机13812345678，备用 +86 139-0000-1234，国际 +1 415 555 0100",
+			types:  Types,
+			want:   "手机[PHONE]5678，备用 [PHONE]1234，国际 [PHONE]0100",
+			counts: map[string]int{TypePhone: 3},
+		},
+		{
+			name:   "id number with valid checksum only",
+			text:   "身份证11010519491231002X，错误号码110105194912310021，SSN 123-45-6789",
+			types:  Types,
+			want:   "身份证[ID_NUMBER]，错误号码110105194912310021，SSN [ID_NUMBER]",
+			counts: map[string]int{TypeIDNumber: 2},
+		},
+		{
+			name:   "disabled types untouched",
+			text:   "a@b.com 13812345678",
+			types:  []string{TypePhone},
+			want:   "a@b.com [PHONE]5678",
+			counts: map[string]int{TypePhone: 1},
+		},
+		{
+			name:   "no pii",
+			text:   "订单号 2024010112345678 已发货",
+			types:  Types,
+			want:   "订单号 2024010112345678 已发货",
+			counts: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, counts := Mask(tt.text, tt.types)
+			if got != tt.want || !reflect.DeepEqual(counts, tt.counts) {
+				t.Errorf("Mask() = %q, %v, want %q, %v", got, counts, tt.want, tt.counts)
+			}
+		})
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	data := gormModel.JSON(`{"user":{"email":"a@corp.com","phones":["13812345678"]},"score":0.85,"count":3}`)
+	masked, counts, changed := MaskJSON(data, Types)
+	if !changed || counts[TypeEmail] != 1 || counts[TypePhone] != 1 {
+		t.Fatalf("MaskJSON() changed = %v, counts = %v", changed, counts)
+	}
+	want := `{"count":3,"score":0.85,"user":{"email":"[EMAIL]@corp.com","phones":["[PHONE]5678"]}}`
+	if string(masked) != want {
+		t.Errorf("MaskJSON() = %s, want %s", masked, want)
+	}
+
+	clean := gormModel.JSON(`{"retrieval_view":"faq"}`)
+	if out, _, changed := MaskJSON(clean, Types); changed || string(out) != string(clean) {
+		t.Errorf("MaskJSON(clean) = %s, changed = %v", out, changed)
+	}
+}
+
+func TestPolicyFor(t *testing.T) {
+	cfg := &Config{Enabled: false, AfterDays: 90, Types: Types}
+	if p := cfg.PolicyFor(nil); p.Enabled || p.AfterDays != 90 {
+		t.Errorf("PolicyFor(nil) = %+v", p)
+	}
+
+	enabled := true
+	p := cfg.PolicyFor(&v1.PIIScrubPolicy{Enabled: &enabled, AfterDays: 30, Types: []string{"Email", "bogus", "email"}})
+	if !p.Enabled || p.AfterDays != 30 || !reflect.DeepEqual(p.Types, []string{TypeEmail}) {
+		t.Errorf("PolicyFor(project) = %+v", p)
+	}
+
+	disabled := false
+	if p := (&Config{Enabled: true, AfterDays: 90, Types: Types}).PolicyFor(&v1.PIIScrubPolicy{Enabled: &disabled}); p.Enabled {
+		t.Errorf("project policy must be able to disable scrubbing: %+v", p)
+	}
+}
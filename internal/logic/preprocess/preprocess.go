@@ -0,0 +1,368 @@
+// Package preprocess 知识库索引预处理钩子：在文档解析之后、切分之前按顺序清理文本（正则替换、去除页眉页脚、删除套话）
+// 并可调用 LLM 提取元数据。钩子按知识库声明式配置，每次修改保存为新版本，索引时使用最新版本
+package preprocess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+const (
+	// MetadataVersionKey 分片元数据中记录处理该文档的钩子配置版本
+	MetadataVersionKey = "ingest_hooks_version"
+	// MetadataExtractedKey 分片元数据中 LLM 提取的字段
+	MetadataExtractedKey = "extracted_metadata"
+
+	defaultMinRepeats    = 3
+	defaultMaxLineLength = 60
+	defaultMaxChars      = 4000
+	maxHooks             = 20
+)
+
+const extractPrompt = "你是一个文档元数据提取助手。请从下面的文档内容中提取以下字段：%s。只输出一个 JSON 对象，键为字段名，值为字符串，无法确定的字段输出空字符串，不要输出其他内容。%s\n\n文档内容：\n%s"
+
+// digitsPattern 连续数字，比较页眉页脚时忽略页码等变化的数字
+var digitsPattern = regexp.MustCompile(`[0-9]+`)
+
+// pageNumberPattern 页码行（数字已替换为 #）
+var pageNumberPattern = regexp.MustCompile(`(?i)^(第\s*#\s*页(\s*[,，/／]?\s*共\s*#\s*页)?|page\s*#(\s*(of|/)\s*#)?|#\s*/\s*#|-\s*#\s*-)$`)
+
+// Pipeline 一个版本的钩子配置，实现 indexer.TextProcessor
+type Pipeline struct {
+	Version int
+	hooks   []v1.IngestHook
+	regexps map[int]*regexp.Regexp // 钩子下标 -> 编译后的正则
+}
+
+// Load 加载知识库当前生效的钩子配置，未配置或配置为空时返回 nil
+func Load(ctx context.Context, knowledgeID string) (*Pipeline, error) {
+	if knowledgeID == "" {
+		return nil, nil
+	}
+	config, err := dao.IngestHookConfig.GetLatest(ctx, knowledgeID)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	hooks, err := DecodeHooks(config.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	return newPipeline(config.Version, hooks)
+}
+
+func newPipeline(version int, hooks []v1.IngestHook) (*Pipeline, error) {
+	p := &Pipeline{Version: version, hooks: hooks, regexps: make(map[int]*regexp.Regexp)}
+	for i, hook := range hooks {
+		if hook.Type != v1.IngestHookRegexReplace {
+			continue
+		}
+		re, err := regexp.Compile(hook.Pattern)
+		if err != nil {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: invalid pattern: %v", i+1, err)
+		}
+		p.regexps[i] = re
+	}
+	return p, nil
+}
+
+// Process 按顺序执行钩子。segments 为解析结果：本地切分的后端为整篇文本，服务端切分的后端为各个分片。
+// 返回处理后的文本和需要写入每个分片的元数据；元数据提取失败只记录告警，不影响索引
+func (p *Pipeline) Process(ctx context.Context, segments []string) ([]string, map[string]any) {
+	metadata := map[string]any{MetadataVersionKey: p.Version}
+	extracted := make(map[string]string)
+	for i, hook := range p.hooks {
+		switch hook.Type {
+		case v1.IngestHookRegexReplace:
+			re := p.regexps[i]
+			for j := range segments {
+				segments[j] = re.ReplaceAllString(segments[j], hook.Replacement)
+			}
+		case v1.IngestHookStripHeaderFooter:
+			segments = stripHeaderFooter(segments, hook.MinRepeats, hook.MaxLineLength)
+		case v1.IngestHookRemoveBoilerplate:
+			segments = removeBoilerplate(segments, hook.Phrases)
+		case v1.IngestHookExtractMetadata:
+			fields, err := extractMetadata(ctx, hook, strings.Join(segments, "\n"))
+			if err != nil {
+				g.Log().Warningf(ctx, "Ingest hook %d (extract_metadata) failed, skipping: %v", i+1, err)
+				continue
+			}
+			for k, v := range fields {
+				extracted[k] = v
+			}
+		}
+	}
+	if len(extracted) > 0 {
+		metadata[MetadataExtractedKey] = extracted
+	}
+	return segments, metadata
+}
+
+// stripHeaderFooter 删除在解析结果中重复出现至少 minRepeats 次的短行（页眉页脚，比较时忽略数字）以及页码行
+func stripHeaderFooter(segments []string, minRepeats, maxLineLength int) []string {
+	if minRepeats <= 0 {
+		minRepeats = defaultMinRepeats
+	}
+	if maxLineLength <= 0 {
+		maxLineLength = defaultMaxLineLength
+	}
+	lineKey := func(line string) string {
+		line = strings.TrimSpace(line)
+		if line == "" || utf8.RuneCountInString(line) > maxLineLength {
+			return ""
+		}
+		return digitsPattern.ReplaceAllString(line, "#")
+	}
+
+	counts := make(map[string]int)
+	for _, segment := range segments {
+		var tracker common.BlockTracker
+		for _, line := range strings.Split(segment, "\n") {
+			if tracker.Line(line) {
+				continue
+			}
+			if key := lineKey(line); key != "" {
+				counts[key]++
+			}
+		}
+	}
+	return filterLines(segments, func(line string) bool {
+		key := lineKey(line)
+		return key != "" && (counts[key] >= minRepeats || pageNumberPattern.MatchString(key))
+	})
+}
+
+// removeBoilerplate 删除包含任一套话的行（不区分大小写）
+func removeBoilerplate(segments []string, phrases []string) []string {
+	lowered := make([]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			lowered = append(lowered, phrase)
+		}
+	}
+	if len(lowered) == 0 {
+		return segments
+	}
+	return filterLines(segments, func(line string) bool {
+		line = strings.ToLower(line)
+		for _, phrase := range lowered {
+			if strings.Contains(line, phrase) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// filterLines 删除 remove 返回 true 的行，代码块和公式块内的行不删除
+func filterLines(segments []string, remove func(line string) bool) []string {
+	result := make([]string, len(segments))
+	for i, segment := range segments {
+		lines := strings.Split(segment, "\n")
+		kept := lines[:0]
+		var tracker common.BlockTracker
+		for _, line := range lines {
+			if tracker.Line(line) || !remove(line) {
+				kept = append(kept, line)
+			}
+		}
+		result[i] = strings.Join(kept, "\n")
+	}
+	return result
+}
+
+// extractMetadata 调用 LLM 从文档开头提取元数据字段
+func extractMetadata(ctx context.Context, hook v1.IngestHook, text string) (map[string]string, error) {
+	mc := coreModel.Registry.Get(hook.ModelId)
+	if mc == nil {
+		return nil, fmt.Errorf("model not found: %s", hook.ModelId)
+	}
+	maxChars := hook.MaxChars
+	if maxChars <= 0 {
+		maxChars = defaultMaxChars
+	}
+	if runes := []rune(text); len(runes) > maxChars {
+		text = string(runes[:maxChars])
+	}
+	instructions := ""
+	if hook.Prompt != "" {
+		instructions = "\n" + hook.Prompt
+	}
+
+	modelService := coreModel.NewModelServiceFor(mc, coreModel.FormatterFor(mc))
+	resp, err := modelService.ChatCompletion(ctx, coreModel.ChatCompletionParams{
+		ModelName:           mc.Name,
+		Messages:            []*schema.Message{{Role: schema.User, Content: fmt.Sprintf(extractPrompt, strings.Join(hook.Fields, "、"), instructions, text)}},
+		Temperature:         0,
+		MaxCompletionTokens: 1000,
+		TopP:                1,
+		N:                   1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("received empty choices from API")
+	}
+	return parseMetadata(resp.Choices[0].Message.Content, hook.Fields)
+}
+
+// parseMetadata 解析模型输出的 JSON 对象（允许包含代码块标记等多余内容），只保留要求的非空字段
+func parseMetadata(output string, fields []string) (map[string]string, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in model output: %q", output)
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(output[start:end+1]), &values); err != nil {
+		return nil, fmt.Errorf("invalid JSON in model output: %w", err)
+	}
+	result := make(map[string]string, len(fields))
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok || value == nil {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			text = fmt.Sprint(value)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			result[field] = text
+		}
+	}
+	return result, nil
+}
+
+// Validate 校验钩子配置
+func Validate(hooks []v1.IngestHook) error {
+	if len(hooks) > maxHooks {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "at most %d hooks are allowed", maxHooks)
+	}
+	for i, hook := range hooks {
+		switch hook.Type {
+		case v1.IngestHookRegexReplace:
+			if hook.Pattern == "" {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: pattern is required", i+1)
+			}
+		case v1.IngestHookStripHeaderFooter:
+		case v1.IngestHookRemoveBoilerplate:
+			if len(hook.Phrases) == 0 {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: phrases are required", i+1)
+			}
+		case v1.IngestHookExtractMetadata:
+			if len(hook.Fields) == 0 {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: fields are required", i+1)
+			}
+			mc := coreModel.Registry.Get(hook.ModelId)
+			if mc == nil {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: model not found: %s", i+1, hook.ModelId)
+			}
+			if mc.Type != coreModel.ModelTypeLLM && mc.Type != coreModel.ModelTypeMultimodal {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: model %s is not an LLM", i+1, hook.ModelId)
+			}
+		default:
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "hook %d: unknown type %q", i+1, hook.Type)
+		}
+	}
+	_, err := newPipeline(0, hooks)
+	return err
+}
+
+// Get 获取知识库指定版本的配置，version 为 0 时获取当前生效版本，未配置时返回 nil
+func Get(ctx context.Context, knowledgeID string, version int) (*gormModel.IngestHookConfig, []v1.IngestHook, error) {
+	var config *gormModel.IngestHookConfig
+	var err error
+	if version > 0 {
+		config, err = dao.IngestHookConfig.GetByVersion(ctx, knowledgeID, version)
+		if err == nil && config == nil {
+			err = gerror.NewCodef(gcode.CodeNotFound, "ingest hooks version %d not found", version)
+		}
+	} else {
+		config, err = dao.IngestHookConfig.GetLatest(ctx, knowledgeID)
+	}
+	if err != nil || config == nil {
+		return nil, nil, err
+	}
+	hooks, err := DecodeHooks(config.Hooks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, hooks, nil
+}
+
+// Save 校验并保存为知识库的新版本，之后索引的文档使用新配置
+func Save(ctx context.Context, knowledgeID string, hooks []v1.IngestHook, comment string) (*gormModel.IngestHookConfig, error) {
+	if _, err := knowledge.GetKnowledgeBaseById(ctx, knowledgeID); err != nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "knowledge base not found: %s", knowledgeID)
+	}
+	if err := Validate(hooks); err != nil {
+		return nil, err
+	}
+	if hooks == nil {
+		hooks = []v1.IngestHook{}
+	}
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	config := &gormModel.IngestHookConfig{
+		ID:          strings.ReplaceAll(uuid.New().String(), "-", ""),
+		KnowledgeID: knowledgeID,
+		Hooks:       gormModel.JSON(data),
+		Comment:     comment,
+		CreateTime:  &now,
+	}
+	if err = dao.IngestHookConfig.CreateVersion(ctx, config); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Ingest hooks of knowledge base %s saved as version %d (%d hooks)", knowledgeID, config.Version, len(hooks))
+	return config, nil
+}
+
+// Rollback 把历史版本保存为新版本
+func Rollback(ctx context.Context, knowledgeID string, version int) (*gormModel.IngestHookConfig, error) {
+	_, hooks, err := Get(ctx, knowledgeID, version)
+	if err != nil {
+		return nil, err
+	}
+	return Save(ctx, knowledgeID, hooks, fmt.Sprintf("回滚到版本 %d", version))
+}
+
+// Versions 获取知识库的全部配置版本，按版本号倒序
+func Versions(ctx context.Context, knowledgeID string) ([]*gormModel.IngestHookConfig, error) {
+	return dao.IngestHookConfig.ListByKnowledgeID(ctx, knowledgeID)
+}
+
+// DecodeHooks 解析保存的钩子列表
+func DecodeHooks(raw gormModel.JSON) ([]v1.IngestHook, error) {
+	var hooks []v1.IngestHook
+	if len(raw) == 0 {
+		return hooks, nil
+	}
+	if err := json.Unmarshal(raw, &hooks); err != nil {
+		return nil, fmt.Errorf("invalid ingest hooks config: %w", err)
+	}
+	return hooks, nil
+}
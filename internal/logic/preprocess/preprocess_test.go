@@ -0,0 +1,92 @@
+package preprocess
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+)
+
+func TestStripHeaderFooter(t *testing.T) {
+	pages := []string{
+		"ACME 内部资料\n第一章 概述\n正文一\n第 1 页 共 3 页",
+		"ACME 内部资料\n正文二\n第 2 页 共 3 页",
+		"ACME 内部资料\n正文三\nPage 3 of 3",
+	}
+	got := stripHeaderFooter(pages, 0, 0)
+	want := []string{"第一章 概述\n正文一", "正文二", "正文三"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stripHeaderFooter() = %q, want %q", got, want)
+	}
+
+	// 重复次数不足且不是页码的行保留
+	got = stripHeaderFooter([]string{"标题\n内容\n标题"}, 3, 0)
+	if got[0] != "标题\n内容\n标题" {
+		t.Errorf("stripHeaderFooter() removed lines below min repeats: %q", got[0])
+	}
+
+	// 超过长度的行不视为页眉页脚
+	long := strings.Repeat("长", 20)
+	got = stripHeaderFooter([]string{long, long, long}, 3, 10)
+	if got[0] != long {
+		t.Errorf("stripHeaderFooter() removed long line: %q", got[0])
+	}
+}
+
+func TestRemoveBoilerplate(t *testing.T) {
+	got := removeBoilerplate([]string{"正文\n本文件仅供内部使用\nCopyright 2024 ACME\n结尾"}, []string{"仅供内部使用", "copyright", " "})
+	if got[0] != "正文\n结尾" {
+		t.Errorf("removeBoilerplate() = %q", got[0])
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"plain", `{"author":"张三","date":"2024-01-01","extra":"x"}`, map[string]string{"author": "张三", "date": "2024-01-01"}, false},
+		{"code fence", "```json\n{\"author\": \" 李四 \", \"date\": \"\"}\n```", map[string]string{"author": "李四"}, false},
+		{"non string", `{"author":null,"date":2024}`, map[string]string{"date": "2024"}, false},
+		{"no json", "无法提取", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMetadata(tt.output, []string{"author", "date"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineProcess(t *testing.T) {
+	p, err := newPipeline(2, []v1.IngestHook{
+		{Type: v1.IngestHookRegexReplace, Pattern: `\s{2,}`, Replacement: " "},
+		{Type: v1.IngestHookRemoveBoilerplate, Phrases: []string{"免责声明"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, metadata := p.Process(context.Background(), []string{"第一段   内容\n免责声明：略"})
+	if segments[0] != "第一段 内容" {
+		t.Errorf("Process() segments = %q", segments)
+	}
+	if metadata[MetadataVersionKey] != 2 {
+		t.Errorf("Process() metadata = %v", metadata)
+	}
+	if _, ok := metadata[MetadataExtractedKey]; ok {
+		t.Errorf("Process() should not set extracted metadata without extract hooks")
+	}
+
+	if _, err = newPipeline(1, []v1.IngestHook{{Type: v1.IngestHookRegexReplace, Pattern: "("}}); err == nil {
+		t.Error("newPipeline() should reject invalid pattern")
+	}
+}
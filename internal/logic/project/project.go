@@ -0,0 +1,612 @@
+// Package project 管理项目（资源分组）：把模型、知识库、MCP 服务和人设归入项目，
+// 提供按项目列出资源的接口，并以项目默认设置补全对话请求中未指定的参数
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 资源类型
+const (
+	ResourceKnowledgeBase = "knowledge_base"
+	ResourceModel         = "model"
+	ResourceMCPService    = "mcp_service"
+	ResourcePersona       = "persona"
+)
+
+// ResourceTypes 项目可以包含的资源类型
+var ResourceTypes = []string{ResourceKnowledgeBase, ResourceModel, ResourceMCPService, ResourcePersona}
+
+// 成员角色
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Fields 项目字段，更新时为 nil 的字段保持不变
+type Fields struct {
+	Name        *string
+	Description *string
+	Settings    *v1.ProjectSettings
+	Quota       *v1.ProjectQuota
+}
+
+type contextKey struct{}
+
+// WithContext 在上下文中记录本轮请求所属的项目，供配额校验和用量统计使用
+func WithContext(ctx context.Context, projectID string) context.Context {
+	if projectID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, projectID)
+}
+
+// FromContext 获取本轮请求所属的项目ID，不属于任何项目时返回空字符串
+func FromContext(ctx context.Context) string {
+	projectID, _ := ctx.Value(contextKey{}).(string)
+	return projectID
+}
+
+// Create 创建项目，按用户隔离时创建者成为项目所有者
+func Create(ctx context.Context, fields *Fields) (*gormModel.Project, error) {
+	p := &gormModel.Project{ID: strings.ReplaceAll(uuid.New().String(), "-", "")}
+	if err := apply(ctx, p, fields); err != nil {
+		return nil, err
+	}
+	if err := dao.Project.Create(ctx, p); err != nil {
+		return nil, err
+	}
+	if identity.Isolated(ctx) {
+		if err := dao.Project.SaveMember(ctx, &gormModel.ProjectMember{ProjectID: p.ID, UserID: identity.UserID(ctx), Role: RoleOwner}); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Update 更新项目，只有项目所有者可以修改
+func Update(ctx context.Context, id string, fields *Fields) (*gormModel.Project, error) {
+	p, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckRole(ctx, id, RoleOwner); err != nil {
+		return nil, err
+	}
+	if err = apply(ctx, p, fields); err != nil {
+		return nil, err
+	}
+	if err = dao.Project.Update(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Delete 删除项目，项目中的资源不受影响；只有项目所有者可以删除
+func Delete(ctx context.Context, id string) error {
+	if _, err := Get(ctx, id); err != nil {
+		return err
+	}
+	if err := CheckRole(ctx, id, RoleOwner); err != nil {
+		return err
+	}
+	return dao.Project.Delete(ctx, id)
+}
+
+// Get 获取项目，不存在时返回 CodeNotFound 错误
+func Get(ctx context.Context, id string) (*gormModel.Project, error) {
+	p, err := dao.Project.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "project not found: %s", id)
+	}
+	return p, nil
+}
+
+// List 获取项目列表，userID 不为空时只返回该成员参与的项目
+func List(ctx context.Context, userID string) ([]*gormModel.Project, error) {
+	return dao.Project.List(ctx, userID)
+}
+
+// ParseQuota 解析项目配额，未设置时返回空配额（不限制）
+func ParseQuota(p *gormModel.Project) *v1.ProjectQuota {
+	quota := &v1.ProjectQuota{}
+	if p != nil && p.Quota != "" {
+		_ = json.Unmarshal([]byte(p.Quota), quota)
+	}
+	return quota
+}
+
+// ParseSettings 解析项目默认设置，未设置时返回空设置
+func ParseSettings(p *gormModel.Project) *v1.ProjectSettings {
+	settings := &v1.ProjectSettings{}
+	if p != nil && p.Settings != "" {
+		_ = json.Unmarshal([]byte(p.Settings), settings)
+	}
+	return settings
+}
+
+// AddResources 把资源加入项目，资源必须存在；已属于其他项目的资源移动到该项目。
+// 调用方须为该项目的所有者或编辑者，移动资源时在原项目中也须为所有者或编辑者
+func AddResources(ctx context.Context, projectID, resourceType string, resourceIDs []string) error {
+	if _, err := Get(ctx, projectID); err != nil {
+		return err
+	}
+	if err := CheckRole(ctx, projectID, RoleOwner, RoleEditor); err != nil {
+		return err
+	}
+	resourceIDs = uniqueIDs(resourceIDs)
+	if len(resourceIDs) == 0 {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "resource_ids is required")
+	}
+	for _, id := range resourceIDs {
+		name, err := resourceName(ctx, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return gerror.NewCodef(gcode.CodeNotFound, "%s not found: %s", resourceType, id)
+		}
+		current, err := dao.Project.GetResourceProject(ctx, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if current != "" && current != projectID {
+			if err = CheckRole(ctx, current, RoleOwner, RoleEditor); err != nil {
+				return err
+			}
+		}
+	}
+	return dao.Project.AddResources(ctx, projectID, resourceType, resourceIDs)
+}
+
+// RemoveResources 把资源移出项目，调用方须为项目所有者或编辑者（移出的知识库不再限制项目成员访问）
+func RemoveResources(ctx context.Context, projectID, resourceType string, resourceIDs []string) error {
+	if _, err := Get(ctx, projectID); err != nil {
+		return err
+	}
+	if err := CheckRole(ctx, projectID, RoleOwner, RoleEditor); err != nil {
+		return err
+	}
+	if err := validateResourceType(resourceType); err != nil {
+		return err
+	}
+	resourceIDs = uniqueIDs(resourceIDs)
+	if len(resourceIDs) == 0 {
+		return nil
+	}
+	return dao.Project.RemoveResources(ctx, projectID, resourceType, resourceIDs)
+}
+
+// Resources 列出项目资源及其名称，resourceType 为空时返回全部类型
+func Resources(ctx context.Context, projectID, resourceType string) ([]*v1.ProjectResourceItem, error) {
+	if _, err := Get(ctx, projectID); err != nil {
+		return nil, err
+	}
+	resources, err := dao.Project.ListResources(ctx, projectID, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*v1.ProjectResourceItem, 0, len(resources))
+	for _, r := range resources {
+		name, err := resourceName(ctx, r.ResourceType, r.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &v1.ProjectResourceItem{
+			ResourceType: r.ResourceType,
+			ResourceID:   r.ResourceID,
+			Name:         name,
+			Missing:      name == "",
+		})
+	}
+	return items, nil
+}
+
+// ResourceIDs 项目中指定类型的资源ID，用于按项目过滤资源列表
+func ResourceIDs(ctx context.Context, projectID, resourceType string) ([]string, error) {
+	if _, err := Get(ctx, projectID); err != nil {
+		return nil, err
+	}
+	resources, err := dao.Project.ListResources(ctx, projectID, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(resources))
+	for _, r := range resources {
+		ids = append(ids, r.ResourceID)
+	}
+	return ids, nil
+}
+
+// ResourceCount 项目中各类资源的数量
+func ResourceCount(ctx context.Context, projectID string) (map[string]int, error) {
+	resources, err := dao.Project.ListResources(ctx, projectID, "")
+	if err != nil {
+		return nil, err
+	}
+	count := make(map[string]int, len(ResourceTypes))
+	for _, r := range resources {
+		count[r.ResourceType]++
+	}
+	return count, nil
+}
+
+// SaveMember 添加项目成员或修改成员角色，只有项目所有者可以操作
+func SaveMember(ctx context.Context, projectID, userID, role string) (*gormModel.ProjectMember, error) {
+	if _, err := Get(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if err := CheckRole(ctx, projectID, RoleOwner); err != nil {
+		return nil, err
+	}
+	switch role {
+	case RoleOwner, RoleEditor, RoleViewer:
+	default:
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "invalid role '%s', must be one of owner/editor/viewer", role)
+	}
+	member := &gormModel.ProjectMember{ProjectID: projectID, UserID: strings.TrimSpace(userID), Role: role}
+	if member.UserID == "" {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, "user_id is required")
+	}
+	if err := dao.Project.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember 移除项目成员，只有项目所有者可以操作
+func RemoveMember(ctx context.Context, projectID, userID string) error {
+	if err := CheckRole(ctx, projectID, RoleOwner); err != nil {
+		return err
+	}
+	removed, err := dao.Project.RemoveMember(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return gerror.NewCodef(gcode.CodeNotFound, "user %s is not a member of project %s", userID, projectID)
+	}
+	return nil
+}
+
+// Members 项目成员
+func Members(ctx context.Context, projectID string) ([]*gormModel.ProjectMember, error) {
+	return dao.Project.ListMembers(ctx, projectID)
+}
+
+// CheckKnowledgeAccess 校验用户能否检索知识库：属于项目的知识库只有项目成员可以检索，
+// 不属于任何项目的知识库不做限制，未配置任何凭证时（见 identity.Isolated）未认证的请求不做限制
+func CheckKnowledgeAccess(ctx context.Context, knowledgeIDs ...string) error {
+	if !identity.Isolated(ctx) {
+		return nil
+	}
+	userID := identity.UserID(ctx)
+	checked := make(map[string]bool)
+	for _, knowledgeID := range knowledgeIDs {
+		if knowledgeID == "" {
+			continue
+		}
+		projectID, err := dao.Project.GetResourceProject(ctx, ResourceKnowledgeBase, knowledgeID)
+		if err != nil {
+			return err
+		}
+		if projectID == "" {
+			continue
+		}
+		if _, ok := checked[projectID]; !ok {
+			members, err := dao.Project.ListMembers(ctx, projectID)
+			if err != nil {
+				return err
+			}
+			checked[projectID] = isMember(members, userID)
+		}
+		if !checked[projectID] {
+			return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s is not a member of the project that owns knowledge base %s", userID, knowledgeID)
+		}
+	}
+	return nil
+}
+
+// CheckRole 校验调用方在项目中的角色：按用户隔离时只有角色在 roles 中的成员或运维管理员（auth.admins）可以操作，
+// 未配置任何凭证时不做限制
+func CheckRole(ctx context.Context, projectID string, roles ...string) error {
+	if identity.IsAdmin(ctx) {
+		return nil
+	}
+	members, err := dao.Project.ListMembers(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return authorize(ctx, projectID, members, roles)
+}
+
+// CheckKnowledgeRole 校验调用方在知识库所属项目中的角色：属于项目的知识库要求角色在 roles 中，
+// 不属于任何项目的知识库只有运维管理员可以操作；未配置任何凭证时不做限制
+func CheckKnowledgeRole(ctx context.Context, knowledgeID string, roles ...string) error {
+	if identity.IsAdmin(ctx) {
+		return nil
+	}
+	projectID, err := dao.Project.GetResourceProject(ctx, ResourceKnowledgeBase, knowledgeID)
+	if err != nil {
+		return err
+	}
+	if projectID == "" {
+		return gerror.NewCodef(gcode.CodeNotAuthorized, "knowledge base %s does not belong to any project, only admins can manage it", knowledgeID)
+	}
+	return CheckRole(ctx, projectID, roles...)
+}
+
+// authorize 判断调用方是否为持有 roles 之一的项目成员
+func authorize(ctx context.Context, projectID string, members []*gormModel.ProjectMember, roles []string) error {
+	userID := identity.UserID(ctx)
+	for _, m := range members {
+		if m.UserID != userID {
+			continue
+		}
+		for _, role := range roles {
+			if m.Role == role {
+				return nil
+			}
+		}
+		return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s has role %s in project %s, requires one of %s", userID, m.Role, projectID, strings.Join(roles, "/"))
+	}
+	return gerror.NewCodef(gcode.CodeNotAuthorized, "user %s is not a member of project %s", userID, projectID)
+}
+
+// isMember 判断用户是否为项目成员
+func isMember(members []*gormModel.ProjectMember, userID string) bool {
+	for _, m := range members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyDefaults 用项目默认设置补全对话请求中未指定的参数，并在返回的上下文中记录所属项目
+// 项目按 Use 确定（知识库所属项目优先，按用户隔离时要求是项目成员）；
+// 默认模型只用于尚未选择模型的会话，已有会话沿用会话模型
+func ApplyDefaults(ctx context.Context, req *v1.ChatReq) (context.Context, error) {
+	p, err := Use(ctx, req.ProjectID, req.KnowledgeId)
+	if err != nil || p == nil {
+		return ctx, err
+	}
+	settings := ParseSettings(p)
+
+	if req.ModelID == "" && settings.ModelID != "" {
+		conv, err := dao.Conversation.GetByConvID(ctx, req.ConvID)
+		if err != nil {
+			return ctx, err
+		}
+		if conv == nil || conv.ModelID == "" {
+			req.ModelID = settings.ModelID
+		}
+	}
+	fillDefaults(req, settings)
+	return WithContext(ctx, p.ID), nil
+}
+
+// Use 确定请求计入配额、计量和使用默认设置的项目：请求的知识库属于项目时使用该项目，没有时使用请求指定的项目；
+// 按用户隔离时只有项目成员或运维管理员可以使用项目，防止以其他项目的配额和默认设置调用。没有项目时返回 nil
+func Use(ctx context.Context, requested, knowledgeID string) (*gormModel.Project, error) {
+	var knowledgeProject string
+	if knowledgeID != "" {
+		var err error
+		if knowledgeProject, err = dao.Project.GetResourceProject(ctx, ResourceKnowledgeBase, knowledgeID); err != nil {
+			return nil, err
+		}
+	}
+	projectID, err := pickProject(requested, knowledgeID, knowledgeProject)
+	if err != nil || projectID == "" {
+		return nil, err
+	}
+	p, err := Get(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckRole(ctx, projectID, RoleOwner, RoleEditor, RoleViewer); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// pickProject 合并请求指定的项目和知识库所属的项目，两者不一致时返回错误
+func pickProject(requested, knowledgeID, knowledgeProject string) (string, error) {
+	requested = strings.TrimSpace(requested)
+	if knowledgeProject == "" {
+		return requested, nil
+	}
+	if requested != "" && requested != knowledgeProject {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, "knowledge base %s belongs to project %s, not %s", knowledgeID, knowledgeProject, requested)
+	}
+	return knowledgeProject, nil
+}
+
+// fillDefaults 补全请求中未指定的检索、人设和工具调用参数
+func fillDefaults(req *v1.ChatReq, settings *v1.ProjectSettings) {
+	setDefault(&req.KnowledgeId, settings.KnowledgeID)
+	setDefault(&req.EmbeddingModelID, settings.EmbeddingModelID)
+	setDefault(&req.RerankModelID, settings.RerankModelID)
+	setDefault(&req.PersonaID, settings.PersonaID)
+	setDefault(&req.RetrieveMode, settings.RetrieveMode)
+	if req.TopK <= 0 && settings.TopK > 0 {
+		req.TopK = settings.TopK
+	}
+	if req.Score <= 0 && settings.Score > 0 {
+		req.Score = settings.Score
+	}
+	if req.MaxToolIterations <= 0 && settings.MaxToolIterations > 0 {
+		req.MaxToolIterations = settings.MaxToolIterations
+	}
+}
+
+func setDefault(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// apply 校验并写入项目字段
+func apply(ctx context.Context, p *gormModel.Project, fields *Fields) error {
+	if fields.Name != nil {
+		name := strings.TrimSpace(*fields.Name)
+		if name == "" {
+			return gerror.NewCode(gcode.CodeInvalidParameter, "project name is required")
+		}
+		exists, err := dao.Project.NameExists(ctx, name, p.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "project name '%s' already exists", name)
+		}
+		p.Name = name
+	}
+	if p.Name == "" {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "project name is required")
+	}
+	if fields.Description != nil {
+		p.Description = strings.TrimSpace(*fields.Description)
+	}
+	if fields.Settings != nil {
+		if err := validateSettings(ctx, fields.Settings); err != nil {
+			return err
+		}
+		data, err := json.Marshal(fields.Settings)
+		if err != nil {
+			return err
+		}
+		p.Settings = string(data)
+	}
+	if fields.Quota != nil {
+		q := fields.Quota
+		if q.MonthlyTokens < 0 || q.StorageGB < 0 || q.DailyQueries < 0 || q.DailyToolCalls < 0 {
+			return gerror.NewCode(gcode.CodeInvalidParameter, "quota values must not be negative")
+		}
+		data, err := json.Marshal(q)
+		if err != nil {
+			return err
+		}
+		p.Quota = string(data)
+	}
+	return nil
+}
+
+// validateSettings 校验默认设置引用的模型、知识库和人设存在
+func validateSettings(ctx context.Context, settings *v1.ProjectSettings) error {
+	for _, modelID := range []string{settings.ModelID, settings.EmbeddingModelID, settings.RerankModelID} {
+		if modelID != "" && coreModel.Registry.Get(modelID) == nil {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "model not found: %s", modelID)
+		}
+	}
+	switch settings.RetrieveMode {
+	case "", "milvus", "rerank", "rrf", "hybrid":
+	default:
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "invalid retrieve_mode '%s', must be one of milvus/rerank/rrf/hybrid", settings.RetrieveMode)
+	}
+	if settings.TopK < 0 || settings.Score < 0 || settings.MaxToolIterations < 0 {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "top_k, score and max_tool_iterations must not be negative")
+	}
+	if scrub := settings.PIIScrub; scrub != nil {
+		if scrub.AfterDays < 0 {
+			return gerror.NewCode(gcode.CodeInvalidParameter, "pii_scrub.after_days must not be negative")
+		}
+		for _, t := range scrub.Types {
+			switch t {
+			case "email", "phone", "id_number":
+			default:
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "invalid pii_scrub type '%s', must be one of email/phone/id_number", t)
+			}
+		}
+	}
+	for resourceType, id := range map[string]string{ResourceKnowledgeBase: settings.KnowledgeID, ResourcePersona: settings.PersonaID} {
+		if id == "" {
+			continue
+		}
+		name, err := resourceName(ctx, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "%s not found: %s", resourceType, id)
+		}
+	}
+	return nil
+}
+
+// resourceName 资源名称，资源不存在时返回空字符串
+func resourceName(ctx context.Context, resourceType, id string) (string, error) {
+	switch resourceType {
+	case ResourceKnowledgeBase:
+		kb, err := knowledge.GetKnowledgeBaseById(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return kb.Name, nil
+	case ResourceModel:
+		if mc := coreModel.Registry.Get(id); mc != nil {
+			return mc.Name, nil
+		}
+		return "", nil
+	case ResourceMCPService:
+		registry, err := dao.MCPRegistry.GetByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return registry.Name, nil
+	case ResourcePersona:
+		p, err := dao.Persona.GetByID(ctx, id)
+		if err != nil || p == nil {
+			return "", err
+		}
+		return p.Name, nil
+	}
+	return "", validateResourceType(resourceType)
+}
+
+func validateResourceType(resourceType string) error {
+	for _, t := range ResourceTypes {
+		if t == resourceType {
+			return nil
+		}
+	}
+	return gerror.NewCodef(gcode.CodeInvalidParameter, "invalid resource_type '%s', must be one of %s", resourceType, strings.Join(ResourceTypes, "/"))
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -0,0 +1,142 @@
+package project
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestFillDefaults(t *testing.T) {
+	settings := &v1.ProjectSettings{
+		EmbeddingModelID:  "emb",
+		RerankModelID:     "rerank",
+		KnowledgeID:       "kb-default",
+		PersonaID:         "persona",
+		TopK:              8,
+		Score:             0.4,
+		RetrieveMode:      "rrf",
+		MaxToolIterations: 8,
+	}
+
+	tests := []struct {
+		name string
+		req  *v1.ChatReq
+		want *v1.ChatReq
+	}{
+		{
+			name: "empty request uses project defaults",
+			req:  &v1.ChatReq{},
+			want: &v1.ChatReq{EmbeddingModelID: "emb", RerankModelID: "rerank", KnowledgeId: "kb-default", PersonaID: "persona", TopK: 8, Score: 0.4, RetrieveMode: "rrf", MaxToolIterations: 8},
+		},
+		{
+			name: "request parameters take precedence",
+			req:  &v1.ChatReq{KnowledgeId: "kb", TopK: 3, Score: 0.2, RetrieveMode: "milvus", MaxToolIterations: 2},
+			want: &v1.ChatReq{EmbeddingModelID: "emb", RerankModelID: "rerank", KnowledgeId: "kb", PersonaID: "persona", TopK: 3, Score: 0.2, RetrieveMode: "milvus", MaxToolIterations: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fillDefaults(tt.req, settings)
+			if !reflect.DeepEqual(tt.req, tt.want) {
+				t.Errorf("fillDefaults() = %+v, want %+v", tt.req, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     *v1.ProjectSettings
+	}{
+		{name: "empty", settings: "", want: &v1.ProjectSettings{}},
+		{name: "invalid json", settings: "{", want: &v1.ProjectSettings{}},
+		{name: "settings", settings: `{"model_id":"m","top_k":5}`, want: &v1.ProjectSettings{ModelID: "m", TopK: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSettings(&gormModel.Project{Settings: tt.settings})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	got := uniqueIDs([]string{" a ", "b", "", "a", "c", "b"})
+	want := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestIsMember(t *testing.T) {
+	members := []*gormModel.ProjectMember{{UserID: "alice", Role: RoleOwner}, {UserID: "bob", Role: RoleViewer}}
+	if !isMember(members, "bob") {
+		t.Error("bob should be a member")
+	}
+	if isMember(members, "carol") || isMember(nil, "alice") {
+		t.Error("carol should not be a member")
+	}
+}
+
+// TestAuthorize 测试按用户隔离时项目操作的角色校验：成员和项目设置变更需要所有者，资源变更需要所有者或编辑者，非成员都被拒绝
+func TestAuthorize(t *testing.T) {
+	members := []*gormModel.ProjectMember{{UserID: "alice", Role: RoleOwner}, {UserID: "bob", Role: RoleEditor}, {UserID: "carol", Role: RoleViewer}}
+	ownerOnly := []string{RoleOwner}
+	editors := []string{RoleOwner, RoleEditor}
+	tests := []struct {
+		name    string
+		userID  string
+		roles   []string
+		allowed bool
+	}{
+		{"Owner manages members", "alice", ownerOnly, true},
+		{"Editor cannot manage members", "bob", ownerOnly, false},
+		{"Editor manages resources", "bob", editors, true},
+		{"Viewer cannot manage resources", "carol", editors, false},
+		{"Non-member cannot manage members", "mallory", ownerOnly, false},
+		{"Non-member cannot manage resources", "mallory", editors, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := identity.WithUser(identity.WithIsolation(context.Background()), tt.userID)
+			if err := authorize(ctx, "p1", members, tt.roles); (err == nil) != tt.allowed {
+				t.Errorf("authorize() error = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestPickProject(t *testing.T) {
+	tests := []struct {
+		name             string
+		requested        string
+		knowledgeProject string
+		want             string
+		wantErr          bool
+	}{
+		{name: "no project", want: ""},
+		{name: "requested project", requested: "p1", want: "p1"},
+		{name: "knowledge base project", knowledgeProject: "p2", want: "p2"},
+		{name: "matching projects", requested: "p2", knowledgeProject: "p2", want: "p2"},
+		{name: "mismatched projects", requested: "p1", knowledgeProject: "p2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickProject(tt.requested, "kb", tt.knowledgeProject)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pickProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("pickProject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package promotion
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// correctionRequester 自动捕获的更正申请的提交人
+const correctionRequester = "chat"
+
+// defaultCorrectionTriggers 默认的更正触发词，出现在用户消息开头或某个分句开头时视为更正
+var defaultCorrectionTriggers = []string{
+	"actually", "that's wrong", "that's not right", "that is wrong", "correction:",
+	"其实", "不对", "错了", "应该是", "更正", "纠正一下",
+}
+
+// CaptureCorrection 用户消息像是在更正会话中上一条助手回答时，把更正提交为待审核的更正申请。
+// 更正申请总是需要人工审核，审核通过后与回答沉淀一样以"问/答"分片写入知识库；
+// 不是更正或会话中没有助手回答时返回 nil
+func CaptureCorrection(ctx context.Context, convID, knowledgeID, text string) (*gormModel.KBPromotion, error) {
+	text = strings.TrimSpace(text)
+	if knowledgeID == "" || !IsCorrection(text, correctionTriggers(ctx)) {
+		return nil, nil
+	}
+	msg, err := dao.Message.GetLatestByRole(ctx, convID, string(schema.Assistant))
+	if err != nil || msg == nil {
+		return nil, err
+	}
+	original, err := messageText(ctx, msg.MsgID)
+	if err != nil {
+		return nil, err
+	}
+	question := ""
+	if trace := messageTrace(msg.Metadata); trace != nil {
+		question = trace.Query
+	}
+	if question == "" && msg.CreateTime != nil {
+		if question, err = dao.Analytics.GetPrecedingUserText(ctx, convID, *msg.CreateTime); err != nil {
+			return nil, err
+		}
+	}
+	if question == "" || original == "" {
+		return nil, nil
+	}
+
+	correction := &gormModel.KBPromotion{
+		ID:             uuid.New().String(),
+		KnowledgeID:    knowledgeID,
+		Kind:           gormModel.PromotionKindCorrection,
+		MsgID:          msg.MsgID,
+		ConvID:         convID,
+		Question:       question,
+		Answer:         text,
+		OriginalAnswer: original,
+		Status:         gormModel.PromotionStatusPending,
+		RequestedBy:    correctionRequester,
+	}
+	if err = dao.KBPromotion.Create(ctx, correction); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Correction %s captured for message %s into knowledge base %s", correction.ID, msg.MsgID, knowledgeID)
+	return correction, nil
+}
+
+// correctionTriggers 读取 correction.triggers 配置，未配置时使用默认触发词
+func correctionTriggers(ctx context.Context) []string {
+	triggers := g.Cfg().MustGet(ctx, "correction.triggers").Strings()
+	if len(triggers) == 0 {
+		return defaultCorrectionTriggers
+	}
+	return triggers
+}
+
+// IsCorrection 文本开头或任一分句开头是否为更正触发词（不区分大小写）
+func IsCorrection(text string, triggers []string) bool {
+	clauses := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return strings.ContainsRune(",.!?;，。！？；\n", r)
+	})
+	for _, clause := range clauses {
+		clause = strings.TrimLeftFunc(clause, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) })
+		for _, trigger := range triggers {
+			if trigger = strings.ToLower(strings.TrimSpace(trigger)); trigger != "" && strings.HasPrefix(clause, trigger) {
+				return true
+			}
+		}
+	}
+	return false
+}
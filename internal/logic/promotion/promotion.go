@@ -0,0 +1,365 @@
+package promotion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/index"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/participant"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/model/entity"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+const (
+	// SourceCuratedFAQ 沉淀的 FAQ 分片在元数据 source 字段中的取值
+	SourceCuratedFAQ = "curated_faq"
+
+	defaultDocumentName = "curated_faq"
+	autoReviewer        = "auto"
+)
+
+// approveMu 串行化审核操作，避免并发创建多个 FAQ 文档或重复写入
+var approveMu sync.Mutex
+
+// PromoteOptions 沉淀申请参数，Question/Answer 为空时使用原始问题和回答
+type PromoteOptions struct {
+	MsgID       string
+	KnowledgeID string
+	Question    string
+	Answer      string
+	RequestedBy string
+}
+
+// ReviewOptions 审核参数，Question/Answer 不为空时以审核人修改后的内容写入知识库
+type ReviewOptions struct {
+	Reviewer         string
+	Comment          string
+	Question         string
+	Answer           string
+	EmbeddingModelID string // 为空时使用知识库中最近索引文档的 embedding 模型
+}
+
+// RequireReview 沉淀申请是否需要人工审核，关闭时提交后立即写入知识库
+func RequireReview(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "promotion.requireReview", true).Bool()
+}
+
+// Promote 将助手回答提交为知识库 FAQ 沉淀申请，同一回答对同一知识库只保留一个未驳回的申请；
+// 提交人需要能读取来源会话并能访问目标知识库
+func Promote(ctx context.Context, opts *PromoteOptions) (*gormModel.KBPromotion, error) {
+	if err := participant.CanReadMessage(ctx, opts.MsgID); err != nil {
+		return nil, err
+	}
+	if err := project.CheckKnowledgeAccess(ctx, opts.KnowledgeID); err != nil {
+		return nil, err
+	}
+	msg, err := dao.Message.GetByMsgID(ctx, opts.MsgID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "message not found: %s", opts.MsgID)
+	}
+	if msg.Role != string(schema.Assistant) {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "only assistant messages can be promoted")
+	}
+	if _, err = knowledge.GetKnowledgeBaseById(ctx, opts.KnowledgeID); err != nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "knowledge base not found: %s", opts.KnowledgeID)
+	}
+
+	existing, err := dao.KBPromotion.GetOpenByMsgID(ctx, opts.MsgID, opts.KnowledgeID, gormModel.PromotionKindAnswer)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	trace := messageTrace(msg.Metadata)
+	question := strings.TrimSpace(opts.Question)
+	if question == "" && trace != nil {
+		question = trace.Query
+	}
+	if question == "" && msg.CreateTime != nil {
+		if question, err = dao.Analytics.GetPrecedingUserText(ctx, msg.ConvID, *msg.CreateTime); err != nil {
+			return nil, err
+		}
+	}
+	answer := strings.TrimSpace(opts.Answer)
+	if answer == "" {
+		if answer, err = messageText(ctx, msg.MsgID); err != nil {
+			return nil, err
+		}
+	}
+	if question == "" || answer == "" {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "question and answer are required, the message has no recorded question or text content")
+	}
+
+	promotion := &gormModel.KBPromotion{
+		ID:          uuid.New().String(),
+		KnowledgeID: opts.KnowledgeID,
+		Kind:        gormModel.PromotionKindAnswer,
+		MsgID:       msg.MsgID,
+		ConvID:      msg.ConvID,
+		Question:    question,
+		Answer:      answer,
+		Status:      gormModel.PromotionStatusPending,
+		RequestedBy: opts.RequestedBy,
+	}
+	if trace != nil && len(trace.Chunks) > 0 {
+		sources, _ := json.Marshal(trace.Chunks)
+		promotion.Sources = gormModel.JSON(sources)
+	}
+	if err = dao.KBPromotion.Create(ctx, promotion); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Promotion %s created for message %s into knowledge base %s", promotion.ID, msg.MsgID, opts.KnowledgeID)
+
+	if !RequireReview(ctx) {
+		approveMu.Lock()
+		defer approveMu.Unlock()
+		return approve(ctx, promotion, &ReviewOptions{Reviewer: autoReviewer})
+	}
+	return promotion, nil
+}
+
+// Get 获取沉淀申请
+func Get(ctx context.Context, id string) (*gormModel.KBPromotion, error) {
+	promotion, err := dao.KBPromotion.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promotion == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "promotion not found: %s", id)
+	}
+	return promotion, nil
+}
+
+// List 获取沉淀申请列表，kind 为空时返回全部类型；只有知识库的审核人（见 CheckReviewer）可以查看，
+// 运维管理员以外的用户必须指定知识库
+func List(ctx context.Context, knowledgeID, status, kind string) ([]*gormModel.KBPromotion, error) {
+	if knowledgeID == "" && !identity.IsAdmin(ctx) {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "knowledge_id is required")
+	}
+	if knowledgeID != "" {
+		if err := CheckReviewer(ctx, knowledgeID); err != nil {
+			return nil, err
+		}
+	}
+	return dao.KBPromotion.List(ctx, knowledgeID, status, kind)
+}
+
+// CheckReviewer 校验调用方能否审核知识库的沉淀申请：需要是知识库所属项目的所有者或编辑者
+func CheckReviewer(ctx context.Context, knowledgeID string) error {
+	return project.CheckKnowledgeRole(ctx, knowledgeID, project.RoleOwner, project.RoleEditor)
+}
+
+// Approve 审核通过：将问答写入知识库的 FAQ 文档并向量化，写入失败时申请保持待审核并记录失败原因
+func Approve(ctx context.Context, id string, opts *ReviewOptions) (*gormModel.KBPromotion, error) {
+	approveMu.Lock()
+	defer approveMu.Unlock()
+
+	promotion, err := pendingPromotion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckReviewer(ctx, promotion.KnowledgeID); err != nil {
+		return nil, err
+	}
+	return approve(ctx, promotion, opts)
+}
+
+// approve 写入待审核申请的问答，调用方需持有 approveMu
+func approve(ctx context.Context, promotion *gormModel.KBPromotion, opts *ReviewOptions) (*gormModel.KBPromotion, error) {
+	id := promotion.ID
+	if q := strings.TrimSpace(opts.Question); q != "" {
+		promotion.Question = q
+	}
+	if a := strings.TrimSpace(opts.Answer); a != "" {
+		promotion.Answer = a
+	}
+
+	documentID, chunkID, err := writeChunk(ctx, promotion, opts)
+	if err != nil {
+		_ = dao.KBPromotion.Update(ctx, id, map[string]interface{}{"last_error": err.Error()})
+		return nil, err
+	}
+
+	now := time.Now()
+	fields := map[string]interface{}{
+		"status":         gormModel.PromotionStatusApproved,
+		"question":       promotion.Question,
+		"answer":         promotion.Answer,
+		"reviewer":       opts.Reviewer,
+		"review_comment": opts.Comment,
+		"document_id":    documentID,
+		"chunk_id":       chunkID,
+		"last_error":     "",
+		"reviewed_at":    &now,
+	}
+	if err = dao.KBPromotion.Update(ctx, id, fields); err != nil {
+		return nil, err
+	}
+	g.Log().Infof(ctx, "Promotion %s approved by %s, chunk %s written to document %s", id, opts.Reviewer, chunkID, documentID)
+	return Get(ctx, id)
+}
+
+// Reject 驳回沉淀申请
+func Reject(ctx context.Context, id, reviewer, comment string) (*gormModel.KBPromotion, error) {
+	approveMu.Lock()
+	defer approveMu.Unlock()
+
+	promotion, err := pendingPromotion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckReviewer(ctx, promotion.KnowledgeID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	fields := map[string]interface{}{
+		"status":         gormModel.PromotionStatusRejected,
+		"reviewer":       reviewer,
+		"review_comment": comment,
+		"reviewed_at":    &now,
+	}
+	if err = dao.KBPromotion.Update(ctx, id, fields); err != nil {
+		return nil, err
+	}
+	return Get(ctx, id)
+}
+
+// ParseSources 解析沉淀申请记录的参考分片
+func ParseSources(raw gormModel.JSON) []*analytics.TraceChunk {
+	var sources []*analytics.TraceChunk
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &sources)
+	}
+	return sources
+}
+
+// FAQContent 写入知识库的 FAQ 分片内容
+func FAQContent(question, answer string) string {
+	return fmt.Sprintf("问：%s\n答：%s", strings.TrimSpace(question), strings.TrimSpace(answer))
+}
+
+func pendingPromotion(ctx context.Context, id string) (*gormModel.KBPromotion, error) {
+	promotion, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promotion.Status != gormModel.PromotionStatusPending {
+		return nil, gerror.NewCodef(gcode.CodeInvalidOperation, "promotion %s is already %s", id, promotion.Status)
+	}
+	return promotion, nil
+}
+
+// writeChunk 将问答作为一个分片追加到知识库的 FAQ 文档，分片元数据记录来源消息和参考分片
+func writeChunk(ctx context.Context, promotion *gormModel.KBPromotion, opts *ReviewOptions) (string, string, error) {
+	modelID := opts.EmbeddingModelID
+	if modelID == "" {
+		var err error
+		if modelID, err = knowledge.GetLatestEmbeddingModelID(ctx, promotion.KnowledgeID); err != nil {
+			return "", "", err
+		}
+		if modelID == "" {
+			return "", "", gerror.NewCodef(gcode.CodeInvalidParameter, "knowledge base %s has no indexed documents, embedding_model_id is required", promotion.KnowledgeID)
+		}
+	}
+
+	document, err := curatedDocument(ctx, promotion.KnowledgeID)
+	if err != nil {
+		return "", "", err
+	}
+
+	sourceIDs := make([]string, 0)
+	for _, source := range ParseSources(promotion.Sources) {
+		sourceIDs = append(sourceIDs, source.ID)
+	}
+	chunk := &schema.Document{
+		ID:      uuid.New().String(),
+		Content: FAQContent(promotion.Question, promotion.Answer),
+		MetaData: map[string]interface{}{
+			"source":        SourceCuratedFAQ,
+			"promotion_id":  promotion.ID,
+			"kind":          promotion.Kind,
+			"msg_id":        promotion.MsgID,
+			"conv_id":       promotion.ConvID,
+			"source_chunks": sourceIDs,
+			"reviewer":      opts.Reviewer,
+			"promoted_at":   time.Now().Format(time.RFC3339),
+		},
+	}
+	if err = index.GetDocIndexSvr().AppendChunks(ctx, document.Id, modelID, []*schema.Document{chunk}); err != nil {
+		return "", "", err
+	}
+	return document.Id, chunk.ID, nil
+}
+
+// curatedDocument 获取知识库的 FAQ 文档，不存在时创建；该文档没有源文件，只保存沉淀的问答分片
+func curatedDocument(ctx context.Context, knowledgeID string) (entity.KnowledgeDocuments, error) {
+	name := g.Cfg().MustGet(ctx, "promotion.documentName", defaultDocumentName).String()
+	document, err := knowledge.GetCurrentDocumentByName(ctx, knowledgeID, name)
+	if err != nil || document.Id != "" {
+		return document, err
+	}
+	document = entity.KnowledgeDocuments{
+		Id:             strings.ReplaceAll(uuid.New().String(), "-", ""),
+		KnowledgeId:    knowledgeID,
+		FileName:       name,
+		FileExtension:  "faq",
+		CollectionName: knowledgeID,
+		Status:         int(v1.StatusActive),
+	}
+	return knowledge.SaveDocumentsInfo(ctx, document)
+}
+
+// messageTrace 读取助手消息记录的检索轨迹
+func messageTrace(raw gormModel.JSON) *analytics.RetrievalTrace {
+	if len(raw) == 0 {
+		return nil
+	}
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil
+	}
+	data, ok := metadata[analytics.RetrievalMetadataKey]
+	if !ok {
+		return nil
+	}
+	var trace analytics.RetrievalTrace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return nil
+	}
+	return &trace
+}
+
+// messageText 拼接消息的文本内容块
+func messageText(ctx context.Context, msgID string) (string, error) {
+	contents, err := dao.MessageContent.ListByMsgID(ctx, msgID)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, content := range contents {
+		if content.ContentType == "text" && strings.TrimSpace(content.TextContent) != "" {
+			parts = append(parts, content.TextContent)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n")), nil
+}
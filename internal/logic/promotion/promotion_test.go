@@ -0,0 +1,73 @@
+package promotion
+
+import (
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestMessageTrace(t *testing.T) {
+	tests := []struct {
+		name      string
+		metadata  string
+		wantQuery string
+		wantCount int
+	}{
+		{"有检索轨迹", `{"retrieval":{"query":"年假怎么申请","chunks":[{"id":"c1","score":0.9},{"id":"c2","score":0.5}]},"feedback":"up"}`, "年假怎么申请", 2},
+		{"没有检索轨迹", `{"feedback":"up"}`, "", -1},
+		{"元数据为空", ``, "", -1},
+		{"元数据无效", `not json`, "", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := messageTrace(gormModel.JSON(tt.metadata))
+			if tt.wantCount < 0 {
+				if trace != nil {
+					t.Errorf("messageTrace() = %+v, want nil", trace)
+				}
+				return
+			}
+			if trace == nil || trace.Query != tt.wantQuery || len(trace.Chunks) != tt.wantCount {
+				t.Errorf("messageTrace() = %+v, want query %q with %d chunks", trace, tt.wantQuery, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	sources := ParseSources(gormModel.JSON(`[{"id":"c1","score":0.9}]`))
+	if len(sources) != 1 || sources[0].ID != "c1" || sources[0].Score != 0.9 {
+		t.Errorf("ParseSources() = %+v", sources)
+	}
+	if sources := ParseSources(nil); len(sources) != 0 {
+		t.Errorf("ParseSources(nil) = %+v, want empty", sources)
+	}
+}
+
+func TestFAQContent(t *testing.T) {
+	if got := FAQContent(" 年假怎么申请？ ", "在 OA 系统提交申请。\n"); got != "问：年假怎么申请？\n答：在 OA 系统提交申请。" {
+		t.Errorf("FAQContent() = %q", got)
+	}
+}
+
+func TestIsCorrection(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"actually the warranty is 3 years", true},
+		{"Actually, the warranty is 3 years", true},
+		{"其实保修期是3年", true},
+		{"不对，保修期是三年", true},
+		{"保修期不是1年，应该是3年", true},
+		{"谢谢。更正一下：保修期是3年", true},
+		{"保修期是多久？", false},
+		{"what does actually covered mean", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsCorrection(tt.text, defaultCorrectionTriggers); got != tt.want {
+			t.Errorf("IsCorrection(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package quota 项目配额：月度 token 预算、知识库向量存储、每日对话次数和每日 MCP 工具调用次数，
+// 在对话、文档上传/索引和工具调用入口校验，超出时返回 CodeQuotaExceeded 错误
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 配额项
+const (
+	MetricTokens    = "tokens"
+	MetricStorage   = "storage"
+	MetricQueries   = "queries"
+	MetricToolCalls = "tool_calls"
+)
+
+const (
+	monthLayout = "2006-01"
+	dayLayout   = "2006-01-02"
+	bytesPerGB  = 1 << 30
+
+	// defaultVectorBytes 单个向量的估算字节数（1024 维 float32）
+	defaultVectorBytes = 4096
+)
+
+// CodeQuotaExceeded 超出项目配额
+var CodeQuotaExceeded = gcode.New(429, "Quota Exceeded", nil)
+
+// CheckChat 校验对话所属项目的月度 token 预算和每日对话次数，并累加一次对话；不属于任何项目时不限制
+func CheckChat(ctx context.Context) error {
+	p, q := load(ctx, project.FromContext(ctx))
+	if p == nil {
+		return nil
+	}
+	if err := checkTokens(ctx, p, q); err != nil {
+		return err
+	}
+	return consume(ctx, p, MetricQueries, time.Now().Format(dayLayout), "queries", q.DailyQueries)
+}
+
+// CheckTokens 只校验所属项目的月度 token 预算（不计对话次数），用于向量化等非对话调用；不属于任何项目时不限制
+func CheckTokens(ctx context.Context) error {
+	p, q := load(ctx, project.FromContext(ctx))
+	if p == nil {
+		return nil
+	}
+	return checkTokens(ctx, p, q)
+}
+
+// CheckToolCall 校验对话所属项目的每日工具调用次数，并累加一次调用；不属于任何项目时不限制
+func CheckToolCall(ctx context.Context) error {
+	p, q := load(ctx, project.FromContext(ctx))
+	if p == nil {
+		return nil
+	}
+	return consume(ctx, p, MetricToolCalls, time.Now().Format(dayLayout), "calls", q.DailyToolCalls)
+}
+
+// RecordTokens 累加对话（或调用）所属项目本月消耗的 token，记录失败只记录日志
+func RecordTokens(ctx context.Context, tokens int) {
+	projectID := project.FromContext(ctx)
+	if projectID == "" || tokens <= 0 {
+		return
+	}
+	if err := dao.Project.AddUsage(ctx, projectID, MetricTokens, time.Now().Format(monthLayout), int64(tokens)); err != nil {
+		g.Log().Warningf(ctx, "记录项目 token 用量失败: %v", err)
+	}
+}
+
+// CheckStorage 校验知识库所属项目的向量存储是否已达到上限，知识库不属于任何项目时不限制
+func CheckStorage(ctx context.Context, knowledgeID string) error {
+	projectID, err := dao.Project.GetResourceProject(ctx, project.ResourceKnowledgeBase, knowledgeID)
+	if err != nil {
+		g.Log().Warningf(ctx, "查询知识库所属项目失败，跳过存储配额校验: %v", err)
+		return nil
+	}
+	p, q := load(ctx, projectID)
+	if p == nil || q.StorageGB <= 0 {
+		return nil
+	}
+	used, err := storageGB(ctx, p.ID)
+	if err != nil {
+		return nil
+	}
+	if usage := newUsage(MetricStorage, "", "GB", used, q.StorageGB); usage.Exceeded {
+		return exceeded(p, usage)
+	}
+	return nil
+}
+
+// Usage 项目各配额项的用量
+func Usage(ctx context.Context, p *gormModel.Project) ([]*v1.QuotaUsage, error) {
+	q := project.ParseQuota(p)
+	now := time.Now()
+	month, day := now.Format(monthLayout), now.Format(dayLayout)
+
+	tokens, err := dao.Project.GetUsage(ctx, p.ID, MetricTokens, month)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := storageGB(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	queries, err := dao.Project.GetUsage(ctx, p.ID, MetricQueries, day)
+	if err != nil {
+		return nil, err
+	}
+	toolCalls, err := dao.Project.GetUsage(ctx, p.ID, MetricToolCalls, day)
+	if err != nil {
+		return nil, err
+	}
+	return []*v1.QuotaUsage{
+		newUsage(MetricTokens, month, "tokens", float64(tokens), float64(q.MonthlyTokens)),
+		newUsage(MetricStorage, "", "GB", storage, q.StorageGB),
+		newUsage(MetricQueries, day, "queries", float64(queries), float64(q.DailyQueries)),
+		newUsage(MetricToolCalls, day, "calls", float64(toolCalls), float64(q.DailyToolCalls)),
+	}, nil
+}
+
+// load 获取项目及其配额，项目不存在或查询失败时返回 nil（配额校验不影响请求）
+func load(ctx context.Context, projectID string) (*gormModel.Project, *v1.ProjectQuota) {
+	if projectID == "" {
+		return nil, nil
+	}
+	p, err := dao.Project.GetByID(ctx, projectID)
+	if err != nil || p == nil {
+		g.Log().Warningf(ctx, "加载项目 %s 失败，跳过配额校验: %v", projectID, err)
+		return nil, nil
+	}
+	return p, project.ParseQuota(p)
+}
+
+// checkTokens 本月已用 token 达到预算时返回超限错误，未设置预算时不限制
+func checkTokens(ctx context.Context, p *gormModel.Project, q *v1.ProjectQuota) error {
+	if q.MonthlyTokens <= 0 {
+		return nil
+	}
+	month := time.Now().Format(monthLayout)
+	used, err := dao.Project.GetUsage(ctx, p.ID, MetricTokens, month)
+	if err == nil && used >= q.MonthlyTokens {
+		return exceeded(p, newUsage(MetricTokens, month, "tokens", float64(used), float64(q.MonthlyTokens)))
+	}
+	return nil
+}
+
+// consume 校验计数配额并累加一次，未设置上限时只统计用量
+func consume(ctx context.Context, p *gormModel.Project, metric, period, unit string, limit int64) error {
+	if limit > 0 {
+		used, err := dao.Project.GetUsage(ctx, p.ID, metric, period)
+		if err == nil && used >= limit {
+			return exceeded(p, newUsage(metric, period, unit, float64(used), float64(limit)))
+		}
+	}
+	if err := dao.Project.AddUsage(ctx, p.ID, metric, period, 1); err != nil {
+		g.Log().Warningf(ctx, "记录项目用量失败: %v", err)
+	}
+	return nil
+}
+
+// storageGB 估算项目知识库的向量存储（GB）：分片数 × quota.vectorBytes + 分片内容字节数
+func storageGB(ctx context.Context, projectID string) (float64, error) {
+	chunks, contentBytes, err := dao.Project.KnowledgeStorage(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+	vectorBytes := g.Cfg().MustGet(ctx, "quota.vectorBytes", defaultVectorBytes).Int64()
+	return estimateGB(chunks, contentBytes, vectorBytes), nil
+}
+
+func estimateGB(chunks, contentBytes, vectorBytes int64) float64 {
+	return float64(chunks*vectorBytes+contentBytes) / bytesPerGB
+}
+
+func newUsage(metric, period, unit string, used, limit float64) *v1.QuotaUsage {
+	return &v1.QuotaUsage{
+		Metric:   metric,
+		Period:   period,
+		Unit:     unit,
+		Used:     used,
+		Limit:    limit,
+		Exceeded: limit > 0 && used >= limit,
+	}
+}
+
+func exceeded(p *gormModel.Project, usage *v1.QuotaUsage) error {
+	period := ""
+	if usage.Period != "" {
+		period = fmt.Sprintf(" for %s", usage.Period)
+	}
+	return gerror.NewCodef(CodeQuotaExceeded, "project '%s' %s quota exceeded%s: used %g of %g %s",
+		p.Name, usage.Metric, period, usage.Used, usage.Limit, usage.Unit)
+}
@@ -0,0 +1,46 @@
+package quota
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewUsage(t *testing.T) {
+	tests := []struct {
+		name         string
+		used, limit  float64
+		wantExceeded bool
+	}{
+		{name: "unlimited", used: 1000, limit: 0, wantExceeded: false},
+		{name: "below limit", used: 99, limit: 100, wantExceeded: false},
+		{name: "at limit", used: 100, limit: 100, wantExceeded: true},
+		{name: "over limit", used: 120, limit: 100, wantExceeded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := newUsage(MetricQueries, "2026-10-16", "queries", tt.used, tt.limit)
+			if usage.Exceeded != tt.wantExceeded {
+				t.Errorf("newUsage(%v, %v).Exceeded = %v, want %v", tt.used, tt.limit, usage.Exceeded, tt.wantExceeded)
+			}
+		})
+	}
+}
+
+func TestEstimateGB(t *testing.T) {
+	tests := []struct {
+		name                              string
+		chunks, contentBytes, vectorBytes int64
+		want                              float64
+	}{
+		{name: "empty", want: 0},
+		{name: "vectors only", chunks: 262144, vectorBytes: 4096, want: 1},
+		{name: "vectors and content", chunks: 131072, contentBytes: 1 << 29, vectorBytes: 4096, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateGB(tt.chunks, tt.contentBytes, tt.vectorBytes); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("estimateGB() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package reembed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/index"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/google/uuid"
+)
+
+var (
+	runningMu sync.Mutex
+	running   = make(map[string]bool) // 当前进程中正在执行的任务ID，避免同一任务被重复启动
+)
+
+// InitReembed 恢复服务重启前未完成的重新向量化任务
+func InitReembed() {
+	ctx := gctx.New()
+	jobs, err := dao.ReembedJob.List(ctx, "", gormModel.ReembedStatusRunning)
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to load unfinished re-embedding jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		g.Log().Infof(ctx, "Resuming re-embedding job %s (model=%s, processed=%d/%d)",
+			job.ID, job.ModelID, job.Processed+job.Failed, job.Total)
+		launch(ctx, job.ID)
+	}
+}
+
+// NeedsReembed 判断 embedding 模型配置变更后已有向量是否失效
+func NeedsReembed(before, after *model.ModelConfig) bool {
+	if before == nil || after == nil || after.Type != model.ModelTypeEmbedding {
+		return false
+	}
+	return before.Fingerprint() != after.Fingerprint()
+}
+
+// StartJob 为 embedding 模型创建重新向量化任务并在后台执行
+// 同一模型、同一范围内未完成的旧任务会被取消，新任务按当前模型配置重新统计待处理文档
+func StartJob(ctx context.Context, modelID, knowledgeID string) (*gormModel.ReembedJob, error) {
+	return startJob(ctx, modelID, knowledgeID, false)
+}
+
+// StartMigration 创建把知识库切换到另一个 embedding 模型的迁移任务并在后台执行
+// 知识库中由其他模型生成的文档复用已保存的分片重新向量化，完成后检索默认使用新模型（知识库最近索引文档的模型）
+// 新模型的向量维度需与知识库的向量集合一致，否则文档会处理失败并记录在任务中
+func StartMigration(ctx context.Context, modelID, knowledgeID string) (*gormModel.ReembedJob, error) {
+	if knowledgeID == "" {
+		return nil, fmt.Errorf("knowledge_id is required for an embedding model migration")
+	}
+	return startJob(ctx, modelID, knowledgeID, true)
+}
+
+func startJob(ctx context.Context, modelID, knowledgeID string, migrate bool) (*gormModel.ReembedJob, error) {
+	modelConfig := model.Registry.Get(modelID)
+	if modelConfig == nil {
+		return nil, fmt.Errorf("embedding model not found in registry: %s", modelID)
+	}
+	if modelConfig.Type != model.ModelTypeEmbedding {
+		return nil, fmt.Errorf("model %s is not an embedding model, got type: %s", modelID, modelConfig.Type)
+	}
+
+	canceled, err := dao.ReembedJob.CancelUnfinished(ctx, modelID, knowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	if canceled > 0 {
+		g.Log().Infof(ctx, "Canceled %d unfinished re-embedding jobs for model %s", canceled, modelID)
+	}
+
+	now := time.Now()
+	job := &gormModel.ReembedJob{
+		ID:          uuid.New().String(),
+		ModelID:     modelID,
+		KnowledgeID: knowledgeID,
+		Migrate:     migrate,
+		Fingerprint: modelConfig.Fingerprint(),
+		Status:      gormModel.ReembedStatusRunning,
+		StartedAt:   &now,
+	}
+	total, err := dao.ReembedJob.CountStaleDocuments(ctx, job, int(v1.StatusActive))
+	if err != nil {
+		return nil, err
+	}
+	job.Total = int(total)
+	if err = dao.ReembedJob.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	g.Log().Infof(ctx, "Re-embedding job %s created: model=%s, knowledgeId=%s, migrate=%v, documents=%d",
+		job.ID, modelID, knowledgeID, migrate, job.Total)
+	launch(ctx, job.ID)
+	return job, nil
+}
+
+// PauseJob 暂停任务，当前文档处理完成后停止
+func PauseJob(ctx context.Context, jobID string) (*gormModel.ReembedJob, error) {
+	job, err := getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != gormModel.ReembedStatusRunning {
+		return nil, fmt.Errorf("job %s is %s, only running jobs can be paused", jobID, job.Status)
+	}
+	if err = dao.ReembedJob.Update(ctx, jobID, map[string]interface{}{"status": gormModel.ReembedStatusPaused}); err != nil {
+		return nil, err
+	}
+	job.Status = gormModel.ReembedStatusPaused
+	return job, nil
+}
+
+// ResumeJob 从断点继续执行已暂停的任务
+func ResumeJob(ctx context.Context, jobID string) (*gormModel.ReembedJob, error) {
+	job, err := getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != gormModel.ReembedStatusPaused {
+		return nil, fmt.Errorf("job %s is %s, only paused jobs can be resumed", jobID, job.Status)
+	}
+	if err = dao.ReembedJob.Update(ctx, jobID, map[string]interface{}{"status": gormModel.ReembedStatusRunning}); err != nil {
+		return nil, err
+	}
+	job.Status = gormModel.ReembedStatusRunning
+	launch(ctx, jobID)
+	return job, nil
+}
+
+// GetJob 获取任务详情
+func GetJob(ctx context.Context, jobID string) (*gormModel.ReembedJob, error) {
+	return getJob(ctx, jobID)
+}
+
+// ListJobs 获取任务列表
+func ListJobs(ctx context.Context, modelID, status string) ([]*gormModel.ReembedJob, error) {
+	return dao.ReembedJob.List(ctx, modelID, status)
+}
+
+func getJob(ctx context.Context, jobID string) (*gormModel.ReembedJob, error) {
+	job, err := dao.ReembedJob.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("re-embedding job not found: %s", jobID)
+	}
+	return job, nil
+}
+
+// launch 在后台执行任务，任务已在执行时忽略
+func launch(ctx context.Context, jobID string) {
+	runningMu.Lock()
+	if running[jobID] {
+		runningMu.Unlock()
+		return
+	}
+	running[jobID] = true
+	runningMu.Unlock()
+
+	// 任务生命周期独立于触发它的请求
+	jobCtx := common.DetachContext(ctx)
+	common.SafeGo(jobCtx, fmt.Sprintf("Reembed-%s", jobID), func() {
+		defer func() {
+			runningMu.Lock()
+			delete(running, jobID)
+			runningMu.Unlock()
+		}()
+		run(jobCtx, jobID)
+	})
+}
+
+// run 按文档ID顺序逐个重新向量化，每处理一个文档保存一次断点和进度
+// 处理速度受 reembed.docsPerMinute 限制，避免占满 embedding 服务的配额
+func run(ctx context.Context, jobID string) {
+	batchSize := max(g.Cfg().MustGet(ctx, "reembed.batchSize", 20).Int(), 1)
+	limiter := time.NewTicker(throttleInterval(g.Cfg().MustGet(ctx, "reembed.docsPerMinute", 30).Int()))
+	defer limiter.Stop()
+
+	for {
+		job, err := dao.ReembedJob.GetByID(ctx, jobID)
+		if err != nil || job == nil {
+			g.Log().Errorf(ctx, "Re-embedding job %s stopped: failed to load job: %v", jobID, err)
+			return
+		}
+
+		documentIDs, err := dao.ReembedJob.ListStaleDocumentIDs(ctx, job, int(v1.StatusActive), batchSize)
+		if err != nil {
+			g.Log().Errorf(ctx, "Re-embedding job %s stopped: %v", jobID, err)
+			return
+		}
+		if len(documentIDs) == 0 {
+			finishedAt := time.Now()
+			_ = dao.ReembedJob.Update(ctx, jobID, map[string]interface{}{
+				"status":      gormModel.ReembedStatusCompleted,
+				"finished_at": &finishedAt,
+			})
+			g.Log().Infof(ctx, "Re-embedding job %s completed: processed=%d, failed=%d", jobID, job.Processed, job.Failed)
+			return
+		}
+
+		for _, documentID := range documentIDs {
+			<-limiter.C
+
+			// 每个文档前检查任务状态，及时响应暂停和取消
+			current, err := dao.ReembedJob.GetByID(ctx, jobID)
+			if err != nil || current == nil {
+				g.Log().Errorf(ctx, "Re-embedding job %s stopped: failed to load job: %v", jobID, err)
+				return
+			}
+			if current.Status != gormModel.ReembedStatusRunning {
+				g.Log().Infof(ctx, "Re-embedding job %s is %s, stopping at document %s", jobID, current.Status, current.LastDocumentID)
+				return
+			}
+
+			fields := map[string]interface{}{"last_document_id": documentID}
+			if err := index.GetDocIndexSvr().ReembedDocument(ctx, documentID, job.ModelID); err != nil {
+				g.Log().Errorf(ctx, "Re-embedding job %s failed on document %s: %v", jobID, documentID, err)
+				job.Failed++
+				fields["failed"] = job.Failed
+				fields["last_error"] = fmt.Sprintf("%s: %v", documentID, err)
+			} else {
+				job.Processed++
+				fields["processed"] = job.Processed
+			}
+			if err := dao.ReembedJob.Update(ctx, jobID, fields); err != nil {
+				g.Log().Errorf(ctx, "Re-embedding job %s stopped: failed to save progress: %v", jobID, err)
+				return
+			}
+		}
+	}
+}
+
+// throttleInterval 根据每分钟处理文档数计算处理间隔
+func throttleInterval(docsPerMinute int) time.Duration {
+	if docsPerMinute <= 0 {
+		docsPerMinute = 30
+	}
+	return time.Minute / time.Duration(docsPerMinute)
+}
@@ -0,0 +1,61 @@
+package reembed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/core/model"
+)
+
+func TestNeedsReembed(t *testing.T) {
+	base := func() *model.ModelConfig {
+		return &model.ModelConfig{
+			Name:    "bge-m3",
+			Version: "v1",
+			Type:    model.ModelTypeEmbedding,
+			BaseURL: "http://localhost:8000/v1",
+			APIKey:  "key-a",
+			Extra:   map[string]any{"dimension": float64(1024)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(mc *model.ModelConfig)
+		want   bool
+	}{
+		{name: "unchanged", change: func(mc *model.ModelConfig) {}, want: false},
+		{name: "api key only", change: func(mc *model.ModelConfig) { mc.APIKey = "key-b" }, want: false},
+		{name: "trailing slash", change: func(mc *model.ModelConfig) { mc.BaseURL += "/" }, want: false},
+		{name: "base url", change: func(mc *model.ModelConfig) { mc.BaseURL = "http://embedding:8000/v1" }, want: true},
+		{name: "version", change: func(mc *model.ModelConfig) { mc.Version = "v2" }, want: true},
+		{name: "dimension", change: func(mc *model.ModelConfig) { mc.Extra["dimension"] = float64(768) }, want: true},
+		{name: "not embedding", change: func(mc *model.ModelConfig) { mc.Type = model.ModelTypeLLM; mc.Version = "v2" }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := base()
+			tt.change(after)
+			if got := NeedsReembed(base(), after); got != tt.want {
+				t.Errorf("NeedsReembed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottleInterval(t *testing.T) {
+	tests := []struct {
+		docsPerMinute int
+		want          time.Duration
+	}{
+		{docsPerMinute: 60, want: time.Second},
+		{docsPerMinute: 120, want: 500 * time.Millisecond},
+		{docsPerMinute: 0, want: 2 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := throttleInterval(tt.docsPerMinute); got != tt.want {
+			t.Errorf("throttleInterval(%d) = %v, want %v", tt.docsPerMinute, got, tt.want)
+		}
+	}
+}
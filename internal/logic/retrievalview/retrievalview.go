@@ -0,0 +1,361 @@
+// Package retrievalview 管理保存的检索视图（智能集合）：知识库、元数据过滤条件和检索参数的命名组合，
+// 对话、检索请求和工具调用通过 retrieval_view 按名称引用，避免每次重复传递相同的检索参数
+package retrievalview
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// 检索模式
+const (
+	RetrieveModeMilvus = "milvus"
+	RetrieveModeRerank = "rerank"
+	RetrieveModeRRF    = "rrf"
+	RetrieveModeHybrid = "hybrid"
+)
+
+// Fields 检索视图字段，更新时为 nil 的字段保持不变
+type Fields struct {
+	Name             *string
+	Description      *string
+	KnowledgeIDs     []string                   // 为空时保持不变
+	MetadataFilter   *v1.DocumentMetadataFilter // 没有有效条件时清除过滤条件
+	EmbeddingModelID *string
+	RerankModelID    *string
+	RetrieveMode     *string
+	TopK             *int
+	Score            *float64
+	EnableRewrite    *bool
+}
+
+// View 解码后的检索视图
+type View struct {
+	*gormModel.RetrievalView
+	KnowledgeIDs   []string
+	MetadataFilter *v1.DocumentMetadataFilter
+}
+
+// Create 创建检索视图
+func Create(ctx context.Context, fields *Fields) (*View, error) {
+	view := &gormModel.RetrievalView{ID: strings.ReplaceAll(uuid.New().String(), "-", "")}
+	if err := apply(ctx, view, fields); err != nil {
+		return nil, err
+	}
+	if err := dao.RetrievalView.Create(ctx, view); err != nil {
+		return nil, err
+	}
+	return decode(view), nil
+}
+
+// Update 更新检索视图
+func Update(ctx context.Context, id string, fields *Fields) (*View, error) {
+	view, err := get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = apply(ctx, view, fields); err != nil {
+		return nil, err
+	}
+	if err = dao.RetrievalView.Update(ctx, view); err != nil {
+		return nil, err
+	}
+	return decode(view), nil
+}
+
+// Delete 删除检索视图，引用该视图的请求会返回视图不存在的错误
+func Delete(ctx context.Context, id string) error {
+	if _, err := get(ctx, id); err != nil {
+		return err
+	}
+	return dao.RetrievalView.Delete(ctx, id)
+}
+
+// Get 获取检索视图，不存在时返回 CodeNotFound 错误
+func Get(ctx context.Context, id string) (*View, error) {
+	view, err := get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return decode(view), nil
+}
+
+// List 获取全部检索视图
+func List(ctx context.Context) ([]*View, error) {
+	list, err := dao.RetrievalView.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]*View, 0, len(list))
+	for _, view := range list {
+		views = append(views, decode(view))
+	}
+	return views, nil
+}
+
+// Resolve 按名称或ID获取请求引用的检索视图，名称优先
+func Resolve(ctx context.Context, nameOrID string) (*View, error) {
+	view, err := dao.RetrievalView.GetByName(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if view == nil {
+		if view, err = dao.RetrievalView.GetByID(ctx, nameOrID); err != nil {
+			return nil, err
+		}
+	}
+	if view == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "retrieval view not found: %s", nameOrID)
+	}
+	return decode(view), nil
+}
+
+// ApplyToChat 对话请求引用检索视图时启用检索，请求未指定的知识库、模型和检索参数使用视图设置；
+// 视图中的其他知识库和元数据过滤条件在检索时由 ApplyToRetriever 应用
+func ApplyToChat(ctx context.Context, req *v1.ChatReq) error {
+	if req.RetrievalView == "" {
+		return nil
+	}
+	view, err := Resolve(ctx, req.RetrievalView)
+	if err != nil {
+		return err
+	}
+	applyChat(view, req)
+	if req.EmbeddingModelID == "" {
+		req.EmbeddingModelID = latestEmbeddingModelID(ctx, req.KnowledgeId)
+	}
+	g.Log().Infof(ctx, "Applied retrieval view %s - KnowledgeIds: %v", view.Name, view.KnowledgeIDs)
+	return nil
+}
+
+// ApplyToRetriever 检索请求引用检索视图时，视图的全部知识库参与检索，请求未指定的模型、过滤条件和检索参数使用视图设置
+func ApplyToRetriever(ctx context.Context, req *v1.RetrieverReq) error {
+	if req.RetrievalView == "" {
+		return nil
+	}
+	view, err := Resolve(ctx, req.RetrievalView)
+	if err != nil {
+		return err
+	}
+	applyRetriever(view, req)
+	if req.EmbeddingModelID == "" {
+		req.EmbeddingModelID = latestEmbeddingModelID(ctx, req.KnowledgeId)
+	}
+	if req.EmbeddingModelID == "" {
+		return gerror.NewCodef(gcode.CodeInvalidParameter,
+			"retrieval view %s has no embedding_model_id and knowledge base %s has no indexed documents", view.Name, req.KnowledgeId)
+	}
+	return nil
+}
+
+func applyChat(view *View, req *v1.ChatReq) {
+	req.EnableRetriever = true
+	if req.KnowledgeId == "" && len(view.KnowledgeIDs) > 0 {
+		req.KnowledgeId = view.KnowledgeIDs[0]
+	}
+	setDefault(&req.EmbeddingModelID, view.EmbeddingModelID)
+	setDefault(&req.RerankModelID, view.RerankModelID)
+	setDefault(&req.RetrieveMode, view.RetrieveMode)
+	if req.TopK == 0 {
+		req.TopK = view.TopK
+	}
+	if req.Score == 0 {
+		req.Score = view.Score
+	}
+}
+
+func applyRetriever(view *View, req *v1.RetrieverReq) {
+	if req.KnowledgeId == "" && len(view.KnowledgeIDs) > 0 {
+		req.KnowledgeId = view.KnowledgeIDs[0]
+	}
+	for _, id := range view.KnowledgeIDs {
+		if id != req.KnowledgeId && !contains(req.KnowledgeIds, id) {
+			req.KnowledgeIds = append(req.KnowledgeIds, id)
+		}
+	}
+	if req.MetadataFilter == nil {
+		req.MetadataFilter = view.MetadataFilter
+	}
+	setDefault(&req.EmbeddingModelID, view.EmbeddingModelID)
+	setDefault(&req.RerankModelID, view.RerankModelID)
+	setDefault(&req.RetrieveMode, view.RetrieveMode)
+	if req.TopK == 0 {
+		req.TopK = view.TopK
+	}
+	if req.Score == 0 {
+		req.Score = view.Score
+	}
+	if view.EnableRewrite {
+		req.EnableRewrite = true
+	}
+}
+
+// latestEmbeddingModelID 视图未指定 embedding 模型时使用知识库最近索引文档的模型
+func latestEmbeddingModelID(ctx context.Context, knowledgeID string) string {
+	if knowledgeID == "" {
+		return ""
+	}
+	modelID, err := knowledge.GetLatestEmbeddingModelID(ctx, knowledgeID)
+	if err != nil {
+		g.Log().Warningf(ctx, "Failed to get embedding model of knowledge base %s: %v", knowledgeID, err)
+		return ""
+	}
+	return modelID
+}
+
+func get(ctx context.Context, id string) (*gormModel.RetrievalView, error) {
+	view, err := dao.RetrievalView.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if view == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "retrieval view not found: %s", id)
+	}
+	return view, nil
+}
+
+// apply 校验并写入检索视图字段
+func apply(ctx context.Context, view *gormModel.RetrievalView, fields *Fields) error {
+	if fields.Name != nil {
+		name := strings.TrimSpace(*fields.Name)
+		if name == "" {
+			return gerror.NewCode(gcode.CodeInvalidParameter, "retrieval view name is required")
+		}
+		exists, err := dao.RetrievalView.NameExists(ctx, name, view.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "retrieval view name '%s' already exists", name)
+		}
+		view.Name = name
+	}
+	if view.Name == "" {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "retrieval view name is required")
+	}
+	if len(fields.KnowledgeIDs) > 0 {
+		ids := normalizeIDs(fields.KnowledgeIDs)
+		for _, id := range ids {
+			if _, err := knowledge.GetKnowledgeBaseById(ctx, id); err != nil {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, "knowledge base not found: %s", id)
+			}
+		}
+		data, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+		view.KnowledgeIDs = data
+	}
+	if len(decodeKnowledgeIDs(view.KnowledgeIDs)) == 0 {
+		return gerror.NewCode(gcode.CodeInvalidParameter, "retrieval view requires at least one knowledge base")
+	}
+	if fields.MetadataFilter != nil {
+		view.MetadataFilter = nil
+		if !emptyFilter(fields.MetadataFilter) {
+			data, err := json.Marshal(fields.MetadataFilter)
+			if err != nil {
+				return err
+			}
+			view.MetadataFilter = data
+		}
+	}
+	if fields.RetrieveMode != nil {
+		mode := strings.TrimSpace(*fields.RetrieveMode)
+		switch mode {
+		case "", RetrieveModeMilvus, RetrieveModeRerank, RetrieveModeRRF, RetrieveModeHybrid:
+		default:
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "invalid retrieve_mode '%s', must be one of milvus/rerank/rrf/hybrid", mode)
+		}
+		view.RetrieveMode = mode
+	}
+	setString(&view.Description, fields.Description)
+	setString(&view.EmbeddingModelID, fields.EmbeddingModelID)
+	setString(&view.RerankModelID, fields.RerankModelID)
+	if (view.RetrieveMode == RetrieveModeRerank || view.RetrieveMode == RetrieveModeRRF) && view.RerankModelID == "" {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "rerank_model_id is required when retrieve_mode is %s", view.RetrieveMode)
+	}
+	if fields.TopK != nil {
+		view.TopK = *fields.TopK
+	}
+	if fields.Score != nil {
+		view.Score = *fields.Score
+	}
+	if fields.EnableRewrite != nil {
+		view.EnableRewrite = *fields.EnableRewrite
+	}
+	return nil
+}
+
+func decode(view *gormModel.RetrievalView) *View {
+	result := &View{RetrievalView: view, KnowledgeIDs: decodeKnowledgeIDs(view.KnowledgeIDs)}
+	if len(view.MetadataFilter) > 0 {
+		var filter v1.DocumentMetadataFilter
+		if err := json.Unmarshal(view.MetadataFilter, &filter); err == nil && !emptyFilter(&filter) {
+			result.MetadataFilter = &filter
+		}
+	}
+	return result
+}
+
+func decodeKnowledgeIDs(data gormModel.JSON) []string {
+	var ids []string
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &ids)
+	}
+	return ids
+}
+
+// normalizeIDs 去除空值和重复值，保持顺序
+func normalizeIDs(ids []string) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" && !contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func emptyFilter(filter *v1.DocumentMetadataFilter) bool {
+	if strings.TrimSpace(filter.Title) != "" || strings.TrimSpace(filter.Author) != "" ||
+		strings.TrimSpace(filter.DateFrom) != "" || strings.TrimSpace(filter.DateTo) != "" {
+		return false
+	}
+	for _, topic := range filter.Topics {
+		if strings.TrimSpace(topic) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func setDefault(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+func setString(dst *string, value *string) {
+	if value != nil {
+		*dst = strings.TrimSpace(*value)
+	}
+}
@@ -0,0 +1,97 @@
+package retrievalview
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+// TestDecode 测试检索视图知识库和过滤条件的解码
+func TestDecode(t *testing.T) {
+	view := decode(&gormModel.RetrievalView{
+		Name:           "hr",
+		KnowledgeIDs:   gormModel.JSON(`["kb1","kb2"]`),
+		MetadataFilter: gormModel.JSON(`{"topics":["假期"]}`),
+	})
+	if !reflect.DeepEqual(view.KnowledgeIDs, []string{"kb1", "kb2"}) {
+		t.Errorf("KnowledgeIDs = %v", view.KnowledgeIDs)
+	}
+	if view.MetadataFilter == nil || !reflect.DeepEqual(view.MetadataFilter.Topics, []string{"假期"}) {
+		t.Errorf("MetadataFilter = %+v", view.MetadataFilter)
+	}
+
+	empty := decode(&gormModel.RetrievalView{MetadataFilter: gormModel.JSON(`{"title":" "}`)})
+	if empty.KnowledgeIDs != nil || empty.MetadataFilter != nil {
+		t.Errorf("empty view decoded as %+v", empty)
+	}
+}
+
+// TestApplyRetriever 测试检索请求使用视图设置，请求中已指定的参数优先
+func TestApplyRetriever(t *testing.T) {
+	view := &View{
+		RetrievalView: &gormModel.RetrievalView{
+			Name:             "hr",
+			EmbeddingModelID: "emb",
+			RerankModelID:    "rerank",
+			RetrieveMode:     RetrieveModeRRF,
+			TopK:             8,
+			Score:            0.3,
+			EnableRewrite:    true,
+		},
+		KnowledgeIDs:   []string{"kb1", "kb2", "kb3"},
+		MetadataFilter: &v1.DocumentMetadataFilter{Author: "HR"},
+	}
+
+	tests := []struct {
+		name string
+		req  *v1.RetrieverReq
+		want *v1.RetrieverReq
+	}{
+		{
+			name: "empty request uses view",
+			req:  &v1.RetrieverReq{Question: "q"},
+			want: &v1.RetrieverReq{Question: "q", KnowledgeId: "kb1", KnowledgeIds: []string{"kb2", "kb3"},
+				EmbeddingModelID: "emb", RerankModelID: "rerank", RetrieveMode: RetrieveModeRRF, TopK: 8, Score: 0.3,
+				EnableRewrite: true, MetadataFilter: view.MetadataFilter},
+		},
+		{
+			name: "request fields take precedence",
+			req: &v1.RetrieverReq{Question: "q", KnowledgeId: "kb2", KnowledgeIds: []string{"kb3"}, EmbeddingModelID: "e2",
+				RetrieveMode: RetrieveModeMilvus, TopK: 3, MetadataFilter: &v1.DocumentMetadataFilter{Title: "手册"}},
+			want: &v1.RetrieverReq{Question: "q", KnowledgeId: "kb2", KnowledgeIds: []string{"kb3", "kb1"},
+				EmbeddingModelID: "e2", RerankModelID: "rerank", RetrieveMode: RetrieveModeMilvus, TopK: 3, Score: 0.3,
+				EnableRewrite: true, MetadataFilter: &v1.DocumentMetadataFilter{Title: "手册"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyRetriever(view, tt.req)
+			if !reflect.DeepEqual(tt.req, tt.want) {
+				t.Errorf("applyRetriever() = %+v, want %+v", tt.req, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyChat 测试对话请求引用视图时启用检索并使用视图的第一个知识库
+func TestApplyChat(t *testing.T) {
+	view := &View{
+		RetrievalView: &gormModel.RetrievalView{Name: "hr", TopK: 8},
+		KnowledgeIDs:  []string{"kb1", "kb2"},
+	}
+	req := &v1.ChatReq{Question: "q", TopK: 4}
+	applyChat(view, req)
+	if !req.EnableRetriever || req.KnowledgeId != "kb1" || req.TopK != 4 {
+		t.Errorf("applyChat() = %+v", req)
+	}
+}
+
+// TestNormalizeIDs 测试知识库ID去空去重
+func TestNormalizeIDs(t *testing.T) {
+	got := normalizeIDs([]string{" kb1 ", "", "kb2", "kb1"})
+	if want := []string{"kb1", "kb2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeIDs() = %v, want %v", got, want)
+	}
+}
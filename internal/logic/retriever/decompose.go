@@ -0,0 +1,185 @@
+package retriever
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/sashabaranov/go-openai"
+)
+
+const defaultMaxSubQueries = 4
+
+var (
+	// multiHopPattern 比较、对比类问题通常需要分别检索每个对象
+	multiHopPattern = regexp.MustCompile(`(?i)(比较|对比|区别|差异|异同|不同之处|相比|分别|compare|comparison|difference|differ|versus|\bvs\.?\b)`)
+	// questionSeparator 一次提出多个问题时的分隔符
+	questionSeparator = regexp.MustCompile(`[？?；;\n]+`)
+)
+
+// DecomposeConfig 多跳问题拆分配置
+type DecomposeConfig struct {
+	Enabled       bool   // 是否自动拆分复杂问题
+	ModelID       string // 拆分使用的轻量模型，为空时只按问句分隔符拆分
+	MaxSubQueries int    // 最多拆分的子问题数
+}
+
+// SubQueryResult 一个子问题的检索结果
+type SubQueryResult struct {
+	Query     string
+	Documents []*schema.Document
+}
+
+// LoadDecomposeConfig 读取 queryDecomposition 配置
+func LoadDecomposeConfig(ctx context.Context) *DecomposeConfig {
+	return &DecomposeConfig{
+		Enabled:       g.Cfg().MustGet(ctx, "queryDecomposition.enabled", false).Bool(),
+		ModelID:       g.Cfg().MustGet(ctx, "queryDecomposition.modelID", "").String(),
+		MaxSubQueries: g.Cfg().MustGet(ctx, "queryDecomposition.maxSubQueries", defaultMaxSubQueries).Int(),
+	}
+}
+
+// DecomposeQuery 把多跳问题（如“比较 2023 和 2024 年的退款政策”）拆成可分别检索的子问题
+// 不需要拆分或拆分失败时返回只包含原问题的切片
+func DecomposeQuery(ctx context.Context, cfg *DecomposeConfig, question string) []string {
+	question = strings.TrimSpace(question)
+	if cfg == nil || !cfg.Enabled || !needsDecomposition(question) {
+		return []string{question}
+	}
+	maxSubQueries := cfg.MaxSubQueries
+	if maxSubQueries <= 0 {
+		maxSubQueries = defaultMaxSubQueries
+	}
+	if cfg.ModelID != "" {
+		subQueries, err := decomposeWithModel(ctx, cfg.ModelID, question, maxSubQueries)
+		if err == nil && len(subQueries) > 1 {
+			return subQueries
+		}
+		if err != nil {
+			g.Log().Warningf(ctx, "Query decomposition failed, falling back to splitting questions: %v", err)
+		}
+	}
+	return NormalizeSubQueries(splitQuestions(question), question, maxSubQueries)
+}
+
+// NormalizeSubQueries 去除空白和重复的子问题并限制数量，少于两个子问题时返回原问题
+func NormalizeSubQueries(subQueries []string, question string, maxSubQueries int) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, query := range subQueries {
+		query = strings.TrimSpace(query)
+		if len([]rune(query)) < 2 || seen[query] {
+			continue
+		}
+		seen[query] = true
+		result = append(result, query)
+		if maxSubQueries > 0 && len(result) == maxSubQueries {
+			break
+		}
+	}
+	if len(result) < 2 {
+		return []string{strings.TrimSpace(question)}
+	}
+	return result
+}
+
+// needsDecomposition 问题包含比较类词语或多个问句时需要拆分
+func needsDecomposition(question string) bool {
+	return multiHopPattern.MatchString(question) || len(splitQuestions(question)) > 1
+}
+
+// splitQuestions 按问号、分号和换行拆分多个问句
+func splitQuestions(question string) []string {
+	var parts []string
+	for _, part := range questionSeparator.Split(question, -1) {
+		if part = strings.TrimSpace(part); len([]rune(part)) >= 2 {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// decomposeWithModel 使用轻量模型拆分问题，相同问题复用缓存的结果
+func decomposeWithModel(ctx context.Context, modelID, question string, maxSubQueries int) ([]string, error) {
+	mc := model.Registry.Get(modelID)
+	if mc == nil {
+		return nil, fmt.Errorf("decomposition model not found: %s", modelID)
+	}
+	prompt := fmt.Sprintf("把用户问题拆分为可以分别在知识库中检索的独立子问题，每个子问题只涉及一个对象、时间或方面，"+
+		"并保留原问题中的限定条件。问题不需要拆分时只返回原问题。最多 %d 个子问题。\n"+
+		"只返回 JSON 对象 {\"sub_queries\": [\"...\"]}。\n\n用户问题：\n%s", maxSubQueries, question)
+	chatReq := openai.ChatCompletionRequest{
+		Model: mc.Name,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+		// temperature 为 0 时会被 omitempty 省略，用最小正数代替
+		Temperature:         math.SmallestNonzeroFloat32,
+		MaxCompletionTokens: 300,
+	}
+	resp, err := model.CachedChatCompletion(ctx, mc.BaseURL, chatReq, func() (*openai.ChatCompletionResponse, error) {
+		resp, err := mc.Client.CreateChatCompletion(ctx, chatReq)
+		return &resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from decomposition model")
+	}
+	return parseSubQueries(resp.Choices[0].Message.Content, question, maxSubQueries)
+}
+
+// parseSubQueries 解析拆分模型的输出
+func parseSubQueries(content, question string, maxSubQueries int) ([]string, error) {
+	var result struct {
+		SubQueries []string `json:"sub_queries"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &result); err != nil {
+		return nil, fmt.Errorf("invalid decomposition response %q: %w", content, err)
+	}
+	return NormalizeSubQueries(result.SubQueries, question, maxSubQueries), nil
+}
+
+// ProcessDecomposedRetrieval 按子问题并发检索，结果按子问题顺序返回，其他检索参数使用 req
+func ProcessDecomposedRetrieval(ctx context.Context, req *v1.RetrieverReq, subQueries []string) ([]*SubQueryResult, error) {
+	results := make([]*SubQueryResult, len(subQueries))
+	errs := make([]error, len(subQueries))
+	var wg sync.WaitGroup
+	for i, query := range subQueries {
+		wg.Add(1)
+		common.SafeGo(ctx, "ProcessDecomposedRetrieval", func() {
+			defer wg.Done()
+			subReq := *req
+			subReq.Question = query
+			res, err := ProcessRetrieval(ctx, &subReq)
+			if err != nil {
+				errs[i] = fmt.Errorf("retrieval for sub-query %q failed: %w", query, err)
+				return
+			}
+			results[i] = &SubQueryResult{Query: query, Documents: res.Document}
+		})
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+		if results[i] == nil {
+			return nil, fmt.Errorf("retrieval for sub-query %q failed", subQueries[i])
+		}
+	}
+	return results, nil
+}
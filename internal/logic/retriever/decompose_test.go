@@ -0,0 +1,65 @@
+package retriever
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDecomposeQuery(t *testing.T) {
+	ctx := context.Background()
+	cfg := &DecomposeConfig{Enabled: true, MaxSubQueries: 2}
+	tests := []struct {
+		name     string
+		cfg      *DecomposeConfig
+		question string
+		want     []string
+	}{
+		{name: "disabled", cfg: &DecomposeConfig{}, question: "退款政策？发票怎么开？", want: []string{"退款政策？发票怎么开？"}},
+		{name: "single question", cfg: cfg, question: "退款政策是什么？", want: []string{"退款政策是什么？"}},
+		{name: "multiple questions", cfg: cfg, question: "退款政策是什么？发票怎么开；运费谁承担?", want: []string{"退款政策是什么", "发票怎么开"}},
+		// 比较类问题没有配置拆分模型时无法按问句拆分，使用原问题
+		{name: "comparison without model", cfg: cfg, question: "compare the 2023 and 2024 refund policies", want: []string{"compare the 2023 and 2024 refund policies"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecomposeQuery(ctx, tt.cfg, tt.question); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecomposeQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsDecomposition(t *testing.T) {
+	for question, want := range map[string]bool{
+		"比较 2023 和 2024 年的退款政策":                  true,
+		"What is the difference between A and B": true,
+		"A vs B":                                 true,
+		"退款政策是什么？":                               false,
+		"退款政策？发票？":                               true,
+	} {
+		if got := needsDecomposition(question); got != want {
+			t.Errorf("needsDecomposition(%q) = %v, want %v", question, got, want)
+		}
+	}
+}
+
+func TestParseSubQueries(t *testing.T) {
+	got, err := parseSubQueries(`{"sub_queries": ["2023 年退款政策", " 2024 年退款政策 ", "2023 年退款政策", ""]}`, "q", 4)
+	if err != nil {
+		t.Fatalf("parseSubQueries() error = %v", err)
+	}
+	if want := []string{"2023 年退款政策", "2024 年退款政策"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSubQueries() = %v, want %v", got, want)
+	}
+
+	// 只有一个子问题时使用原问题
+	got, _ = parseSubQueries(`{"sub_queries": ["退款政策"]}`, "退款政策是什么", 4)
+	if !reflect.DeepEqual(got, []string{"退款政策是什么"}) {
+		t.Errorf("parseSubQueries() = %v", got)
+	}
+
+	if _, err = parseSubQueries("not json", "q", 4); err == nil {
+		t.Error("expected error for invalid response")
+	}
+}
@@ -5,11 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/Malowking/kbgo/api/kbgo/v1"
 	"github.com/Malowking/kbgo/core/config"
 	"github.com/Malowking/kbgo/core/model"
 	"github.com/Malowking/kbgo/core/retriever"
+	"github.com/Malowking/kbgo/internal/logic/analytics"
+	"github.com/Malowking/kbgo/internal/logic/docmeta"
+	"github.com/Malowking/kbgo/internal/logic/featureflag"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/retrievalview"
+	"github.com/Malowking/kbgo/internal/logic/warmup"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/Malowking/kbgo/internal/service"
 	"github.com/Malowking/kbgo/pkg/schema"
 	"github.com/gogf/gf/v2/frame/g"
@@ -39,14 +49,14 @@ func InitRetrieverConfig() {
 		g.Log().Warning(ctx, "No embedding model found in database, embedding config will be empty")
 	}
 
-	// 获取第一个启用的 rerank 模型
-	rerankModels := model.Registry.GetByType(model.ModelTypeReranker)
-	var rerankAPIKey, rerankBaseURL, rerankModel string
-	if len(rerankModels) > 0 {
-		rerankAPIKey = rerankModels[0].APIKey
-		rerankBaseURL = rerankModels[0].BaseURL
-		rerankModel = rerankModels[0].Name
-		g.Log().Infof(ctx, "Using default rerank model from database: %s (ID: %s)", rerankModel, rerankModels[0].ModelID)
+	// 默认 rerank 模型：优先使用 retriever.rerankModelID 指定的模型，否则使用第一个启用的 rerank 模型
+	var rerankAPIKey, rerankBaseURL, rerankModel, rerankProvider string
+	if rerankModelConfig := defaultRerankModel(ctx); rerankModelConfig != nil {
+		rerankAPIKey = rerankModelConfig.APIKey
+		rerankBaseURL = rerankModelConfig.BaseURL
+		rerankModel = rerankModelConfig.Name
+		rerankProvider = rerankModelConfig.Provider
+		g.Log().Infof(ctx, "Using default rerank model from database: %s (ID: %s)", rerankModel, rerankModelConfig.ModelID)
 	} else {
 		g.Log().Warning(ctx, "No rerank model found in database, rerank config will be empty")
 	}
@@ -61,6 +71,7 @@ func InitRetrieverConfig() {
 			RerankAPIKey:    rerankAPIKey,
 			RerankBaseURL:   rerankBaseURL,
 			RerankModel:     rerankModel,
+			RerankProvider:  rerankProvider,
 			EnableRewrite:   g.Cfg().MustGet(ctx, "retriever.enableRewrite", false).Bool(),
 			RewriteAttempts: g.Cfg().MustGet(ctx, "retriever.rewriteAttempts", 3).Int(),
 			RetrieveMode:    g.Cfg().MustGet(ctx, "retriever.retrieveMode", "rerank").String(),
@@ -71,6 +82,20 @@ func InitRetrieverConfig() {
 	}
 }
 
+// defaultRerankModel 默认的 rerank 模型，配置的模型不存在或不是 rerank 模型时使用第一个启用的 rerank 模型
+func defaultRerankModel(ctx context.Context) *model.ModelConfig {
+	if modelID := g.Cfg().MustGet(ctx, "retriever.rerankModelID", "").String(); modelID != "" {
+		if mc := model.Registry.Get(modelID); mc != nil && mc.Type == model.ModelTypeReranker {
+			return mc
+		}
+		g.Log().Warningf(ctx, "Configured rerank model %s not found or not a reranker, falling back to the first rerank model", modelID)
+	}
+	if rerankModels := model.Registry.GetByType(model.ModelTypeReranker); len(rerankModels) > 0 {
+		return rerankModels[0]
+	}
+	return nil
+}
+
 // GetRetrieverConfig 获取 RetrieverConfig
 func GetRetrieverConfig() *config.RetrieverConfig {
 	return retrieverConfig
@@ -78,18 +103,150 @@ func GetRetrieverConfig() *config.RetrieverConfig {
 
 // ProcessRetrieval 处理检索请求
 func ProcessRetrieval(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverRes, error) {
+	// 引用检索视图时，请求未指定的参数使用视图设置
+	if err := retrievalview.ApplyToRetriever(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// 已认证的用户只能检索有权访问的知识库
+	if err := project.CheckKnowledgeAccess(ctx, append([]string{req.KnowledgeId}, req.KnowledgeIds...)...); err != nil {
+		return nil, err
+	}
+
 	g.Log().Infof(ctx, "retrieveReq: %v, EmbeddingModelID: %v, RerankModelID: %v, EnableRewrite: %v, RewriteAttempts: %v, RetrieveMode: %v",
 		req, req.EmbeddingModelID, req.RerankModelID, req.EnableRewrite, req.RewriteAttempts, req.RetrieveMode)
 
+	// 启用自适应分数阈值时按阈值下限召回候选结果，合并后再确定实际使用的阈值
+	adaptive, adaptiveEnabled := adaptiveScoreConfig(ctx, req)
+	var minScore *float64
+	if adaptiveEnabled {
+		minScore = &adaptive.Floor
+	}
+
+	msg, err := retrieveKnowledgeBase(ctx, req, req.KnowledgeId, req.EmbeddingModelID, minScore)
+	if err != nil {
+		return nil, err
+	}
+
+	// 多知识库检索：各知识库优先使用其最近索引文档的 embedding 模型，结果按分数合并
+	extraKnowledgeIds := otherKnowledgeIds(req)
+	for _, knowledgeId := range extraKnowledgeIds {
+		embeddingModelID := req.EmbeddingModelID
+		if latest, err := knowledge.GetLatestEmbeddingModelID(ctx, knowledgeId); err != nil {
+			g.Log().Warningf(ctx, "Failed to get embedding model of knowledge base %s, using %s: %v", knowledgeId, embeddingModelID, err)
+		} else if latest != "" {
+			embeddingModelID = latest
+		}
+		docs, err := retrieveKnowledgeBase(ctx, req, knowledgeId, embeddingModelID, minScore)
+		if err != nil {
+			return nil, fmt.Errorf("retrieval from knowledge base %s failed: %w", knowledgeId, err)
+		}
+		msg = append(msg, docs...)
+	}
+
+	// 处理元数据：将JSON字符串解析为map
+	msg = processDocumentMetadata(msg)
+
+	// 按分数降序排序
+	sort.Slice(msg, func(i, j int) bool {
+		return msg[i].Score > msg[j].Score
+	})
+
+	var threshold *v1.ScoreThreshold
+	if adaptiveEnabled {
+		threshold = &v1.ScoreThreshold{Configured: configuredScore(req), Candidates: len(msg)}
+		threshold.Effective, threshold.Adjustment = retriever.AdaptiveThreshold(msg, threshold.Configured, adaptive)
+		msg = retriever.FilterByScore(msg, threshold.Effective, threshold.Adjustment)
+		g.Log().Infof(ctx, "Adaptive score threshold: configured=%.2f, effective=%.2f (%s), candidates=%d, kept=%d",
+			threshold.Configured, threshold.Effective, threshold.Adjustment, threshold.Candidates, len(msg))
+	}
+
+	// 合并多个知识库的结果后按 TopK 截断
+	if len(extraKnowledgeIds) > 0 {
+		topK := req.TopK
+		if topK <= 0 {
+			topK = retrieverConfig.TopK
+		}
+		if topK > 0 && len(msg) > topK {
+			msg = msg[:topK]
+		}
+	}
+
+	// 记录低分检索，供未解答问题统计使用
+	var maxScore float64
+	if len(msg) > 0 {
+		maxScore = float64(msg[0].Score)
+	}
+	analytics.RecordRetrievalMiss(ctx, req.KnowledgeId, req.Question, maxScore, len(msg))
+
+	return &v1.RetrieverRes{
+		Document:       msg,
+		ScoreThreshold: threshold,
+	}, nil
+}
+
+// configuredScore 请求指定的分数阈值，未指定时使用配置的默认值
+func configuredScore(req *v1.RetrieverReq) float64 {
+	if req.Score != 0 {
+		return req.Score
+	}
+	return retrieverConfig.Score
+}
+
+// adaptiveScoreConfig 读取自适应分数阈值配置（retriever.adaptiveScore.*），每次检索时读取，修改配置后即时生效
+// 请求的 adaptive_score 优先于配置的开关；阈值下限不高于请求或配置的阈值
+func adaptiveScoreConfig(ctx context.Context, req *v1.RetrieverReq) (retriever.AdaptiveScoreConfig, bool) {
+	enabled := g.Cfg().MustGet(ctx, "retriever.adaptiveScore.enabled", false).Bool()
+	if req.AdaptiveScore != nil {
+		enabled = *req.AdaptiveScore
+	}
+	if !enabled {
+		return retriever.AdaptiveScoreConfig{}, false
+	}
+	conf := retriever.AdaptiveScoreConfig{
+		Floor:      g.Cfg().MustGet(ctx, "retriever.adaptiveScore.floor", 0.05).Float64(),
+		Ceiling:    g.Cfg().MustGet(ctx, "retriever.adaptiveScore.ceiling", 0.6).Float64(),
+		Step:       g.Cfg().MustGet(ctx, "retriever.adaptiveScore.step", 0.05).Float64(),
+		MinResults: g.Cfg().MustGet(ctx, "retriever.adaptiveScore.minResults", 2).Int(),
+		MaxResults: g.Cfg().MustGet(ctx, "retriever.adaptiveScore.maxResults", 4).Int(),
+	}
+	if base := configuredScore(req); conf.Floor > base {
+		conf.Floor = base
+	}
+	if conf.Floor < 0 {
+		conf.Floor = 0
+	}
+	return conf, true
+}
+
+// otherKnowledgeIds 请求中 knowledge_id 以外的其他知识库，去除重复值
+func otherKnowledgeIds(req *v1.RetrieverReq) []string {
+	var ids []string
+	seen := map[string]bool{req.KnowledgeId: true, "": true}
+	for _, id := range req.KnowledgeIds {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// retrieveKnowledgeBase 使用指定的 embedding 模型检索一个知识库
+// minScore 不为空时按该分数召回候选结果（自适应分数阈值），替代请求的分数阈值
+func retrieveKnowledgeBase(ctx context.Context, req *v1.RetrieverReq, knowledgeId, embeddingModelID string, minScore *float64) ([]*schema.Document, error) {
+	// 记录知识库检索次数，定时预热优先加载常用知识库的集合
+	warmup.RecordKnowledgeUse(knowledgeId)
+
 	// 从 Registry 获取 embedding 模型信息
-	embeddingModelConfig := model.Registry.Get(req.EmbeddingModelID)
+	embeddingModelConfig := model.Registry.Get(embeddingModelID)
 	if embeddingModelConfig == nil {
-		return nil, fmt.Errorf("embedding model not found in registry: %s", req.EmbeddingModelID)
+		return nil, fmt.Errorf("embedding model not found in registry: %s", embeddingModelID)
 	}
 
 	// 验证 embedding 模型类型
 	if embeddingModelConfig.Type != model.ModelTypeEmbedding {
-		return nil, fmt.Errorf("model %s is not an embedding model, got type: %s", req.EmbeddingModelID, embeddingModelConfig.Type)
+		return nil, fmt.Errorf("model %s is not an embedding model, got type: %s", embeddingModelID, embeddingModelConfig.Type)
 	}
 
 	// 创建动态配置，使用从 Registry 获取的模型信息覆盖静态配置
@@ -102,6 +259,7 @@ func ProcessRetrieval(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverR
 			RerankAPIKey:    retrieverConfig.RerankAPIKey, // 先使用静态配置的默认值
 			RerankBaseURL:   retrieverConfig.RerankBaseURL,
 			RerankModel:     retrieverConfig.RerankModel,
+			RerankProvider:  retrieverConfig.RerankProvider,
 			EnableRewrite:   retrieverConfig.EnableRewrite,
 			RewriteAttempts: retrieverConfig.RewriteAttempts,
 			RetrieveMode:    retrieverConfig.RetrieveMode,
@@ -111,6 +269,19 @@ func ProcessRetrieval(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverR
 		VectorStore: retrieverConfig.VectorStore,
 	}
 
+	// 指定了历史时间点时，检索当时有效的文档版本
+	if req.AsOf != nil && !req.AsOf.IsZero() {
+		asOf := req.AsOf.Time
+		dynamicConfig.AsOf = &asOf
+		g.Log().Infof(ctx, "Retrieving document versions valid as of %s", asOf.Format(time.RFC3339))
+	}
+
+	// 按文档元数据过滤
+	if filter := metadataFilter(req.MetadataFilter); filter != nil {
+		dynamicConfig.MetadataFilter = filter
+		g.Log().Infof(ctx, "Filtering retrieval by document metadata: %+v", *filter)
+	}
+
 	// 如果提供了 RerankModelID，则从 Registry 获取 rerank 模型配置
 	if req.RerankModelID != "" {
 		rerankModelConfig := model.Registry.Get(req.RerankModelID)
@@ -127,35 +298,75 @@ func ProcessRetrieval(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverR
 		dynamicConfig.RerankAPIKey = rerankModelConfig.APIKey
 		dynamicConfig.RerankBaseURL = rerankModelConfig.BaseURL
 		dynamicConfig.RerankModel = rerankModelConfig.Name
+		dynamicConfig.RerankProvider = rerankModelConfig.Provider
 
 		g.Log().Infof(ctx, "Using dynamic rerank model: modelID=%s, modelName=%s", req.RerankModelID, rerankModelConfig.Name)
 	}
 
+	// 知识库配置了稀疏模型时，检索结果融合稀疏向量分数；配置了新近度权重时对新文档加权
+	if kb, err := knowledge.GetKnowledgeBaseById(ctx, knowledgeId); err != nil {
+		g.Log().Warningf(ctx, "Failed to load knowledge base %s, sparse retrieval and recency boost disabled: %v", knowledgeId, err)
+	} else {
+		applyKBRecency(ctx, dynamicConfig, kb)
+
+		sparseEmbedder, weight, err := knowledge.NewKBSparseEmbedder(ctx, kb)
+		if sparseEmbedder != nil && !featureflag.Enabled(ctx, featureflag.HybridRetrieval, knowledgeId) {
+			// 混合检索按知识库灰度，未开启的知识库只使用稠密向量检索
+			g.Log().Infof(ctx, "Hybrid retrieval disabled by feature flag for knowledge base %s", knowledgeId)
+			sparseEmbedder = nil
+		}
+		if err != nil {
+			g.Log().Warningf(ctx, "Failed to create sparse embedder for knowledge base %s, sparse retrieval disabled: %v", knowledgeId, err)
+		} else if sparseEmbedder != nil {
+			dynamicConfig.SparseEmbedder = sparseEmbedder
+			dynamicConfig.SparseWeight = weight
+			g.Log().Infof(ctx, "Using sparse model for hybrid retrieval: modelID=%s, weight=%.2f", kb.SparseModelID, weight)
+		}
+	}
+
 	// 构建内部请求，只传递必需参数和显式指定的可选参数
 	retrieveReq := &retriever.RetrieveReq{
 		Query:       req.Question,
-		KnowledgeId: req.KnowledgeId,
+		KnowledgeId: knowledgeId,
+		Explain:     req.Explain,
 	}
 
 	// 只有当请求中明确提供了参数时才覆盖配置默认值
 	if req.TopK != 0 {
 		retrieveReq.TopK = &req.TopK
 	}
-	if req.Score != 0 {
+	if minScore != nil {
+		retrieveReq.Score = minScore
+	} else if req.Score != 0 {
 		retrieveReq.Score = &req.Score
 	}
 
+	// 重排序由功能开关控制，关闭时 rerank/rrf 模式退化为向量检索（hybrid 模式不使用重排序，不受影响）
+	rerankEnabled := featureflag.Enabled(ctx, featureflag.Rerank, knowledgeId)
+
 	// RetrieveMode 是独立的检索模式设置，不依赖于 EnableRewrite
 	if req.RetrieveMode != "" {
 		mode := retriever.RetrieveMode(req.RetrieveMode)
 		retrieveReq.RetrieveMode = &mode
 
-		// 如果使用 rerank 或 rrf 模式，但没有提供 RerankModelID，返回错误
-		if (req.RetrieveMode == "rerank" || req.RetrieveMode == "rrf") && req.RerankModelID == "" {
+		// 如果使用 rerank 或 rrf 模式，但没有提供 RerankModelID 也没有默认 rerank 模型，返回错误
+		if (req.RetrieveMode == "rerank" || req.RetrieveMode == "rrf") && rerankEnabled && req.RerankModelID == "" && retrieverConfig.RerankBaseURL == "" {
 			return nil, fmt.Errorf("rerank_model_id is required when retrieve_mode is %s", req.RetrieveMode)
 		}
 	}
 
+	if !rerankEnabled {
+		mode := retriever.RetrieveMode(dynamicConfig.RetrieveMode)
+		if retrieveReq.RetrieveMode != nil {
+			mode = *retrieveReq.RetrieveMode
+		}
+		if mode == retriever.RetrieveModeRerank || mode == retriever.RetrieveModeRRF {
+			g.Log().Infof(ctx, "Rerank disabled by feature flag, retrieve mode %s falls back to %s", mode, retriever.RetrieveModeMilvus)
+			milvus := retriever.RetrieveModeMilvus
+			retrieveReq.RetrieveMode = &milvus
+		}
+	}
+
 	// EnableRewrite 相关的参数设置
 	if req.EnableRewrite {
 		retrieveReq.EnableRewrite = &req.EnableRewrite
@@ -165,22 +376,7 @@ func ProcessRetrieval(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverR
 	}
 
 	// 使用动态配置调用 retriever
-	msg, err := retriever.Retrieve(ctx, dynamicConfig, retrieveReq)
-	if err != nil {
-		return nil, err
-	}
-
-	// 处理元数据：将JSON字符串解析为map
-	msg = processDocumentMetadata(msg)
-
-	// 按分数降序排序
-	sort.Slice(msg, func(i, j int) bool {
-		return msg[i].Score > msg[j].Score
-	})
-
-	return &v1.RetrieverRes{
-		Document: msg,
-	}, nil
+	return retriever.Retrieve(ctx, dynamicConfig, retrieveReq)
 }
 
 // processDocumentMetadata 处理文档元数据，将JSON字符串解析为map
@@ -201,3 +397,38 @@ func processDocumentMetadata(documents []*schema.Document) []*schema.Document {
 	}
 	return documents
 }
+
+// applyKBRecency 将知识库的新近度加权配置写入检索配置，未设置半衰期时使用全局默认值
+func applyKBRecency(ctx context.Context, conf *config.RetrieverConfig, kb *gormModel.KnowledgeBase) {
+	if kb.RecencyWeight <= 0 {
+		return
+	}
+	conf.RecencyWeight = kb.RecencyWeight
+	conf.RecencyHalfLifeDays = kb.RecencyHalfLifeDays
+	if conf.RecencyHalfLifeDays <= 0 {
+		conf.RecencyHalfLifeDays = g.Cfg().MustGet(ctx, "retriever.recencyHalfLifeDays", 180).Int()
+	}
+	g.Log().Debugf(ctx, "Recency boost enabled: weight=%.2f, halfLifeDays=%d", conf.RecencyWeight, conf.RecencyHalfLifeDays)
+}
+
+// metadataFilter 转换元数据过滤条件并规范化日期，没有有效条件时返回 nil
+func metadataFilter(req *v1.DocumentMetadataFilter) *config.MetadataFilter {
+	if req == nil {
+		return nil
+	}
+	filter := &config.MetadataFilter{
+		Title:    strings.TrimSpace(req.Title),
+		Author:   strings.TrimSpace(req.Author),
+		DateFrom: docmeta.NormalizeDate(req.DateFrom),
+		DateTo:   docmeta.NormalizeDate(req.DateTo),
+	}
+	for _, topic := range req.Topics {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			filter.Topics = append(filter.Topics, topic)
+		}
+	}
+	if filter.Title == "" && filter.Author == "" && len(filter.Topics) == 0 && filter.DateFrom == "" && filter.DateTo == "" {
+		return nil
+	}
+	return filter
+}
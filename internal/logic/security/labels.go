@@ -0,0 +1,246 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// MetadataKey 分片安全标签在向量 metadata 中的字段名
+const MetadataKey = "security_label"
+
+// 内置安全标签，按敏感程度从低到高排列
+const (
+	LabelPublic       = "public"
+	LabelInternal     = "internal"
+	LabelConfidential = "confidential"
+)
+
+var (
+	defaultLevels      = []string{LabelPublic, LabelInternal, LabelConfidential}
+	defaultClearances  = []string{LabelPublic}
+	labelPattern       = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	clearanceSeparator = regexp.MustCompile(`[,\s]+`)
+)
+
+type clearanceKey struct{}
+
+// SectionLabel 分段标签规则：内容包含 Keyword（通常是章节标题）的分片使用 Label
+type SectionLabel struct {
+	Keyword string `json:"keyword"`
+	Label   string `json:"label"`
+}
+
+// Enabled 是否启用分片级安全标签过滤
+func Enabled(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "security.enabled", false).Bool()
+}
+
+// DefaultLabel 未指定标签的文档和历史分片使用的标签
+func DefaultLabel(ctx context.Context) string {
+	return NormalizeLabel(g.Cfg().MustGet(ctx, "security.defaultLabel", LabelPublic).String())
+}
+
+// levels 获取标签等级（从低到高），持有高等级权限可以访问所有低等级标签
+func levels(ctx context.Context) []string {
+	configured := g.Cfg().MustGet(ctx, "security.levels").Strings()
+	if len(configured) == 0 {
+		return defaultLevels
+	}
+	result := make([]string, 0, len(configured))
+	for _, level := range configured {
+		result = append(result, NormalizeLabel(level))
+	}
+	return result
+}
+
+// NormalizeLabel 统一标签格式（小写、去空格）
+func NormalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// ValidateLabel 校验标签格式，空标签视为合法（使用默认标签）
+func ValidateLabel(label string) error {
+	label = NormalizeLabel(label)
+	if label != "" && !labelPattern.MatchString(label) {
+		return fmt.Errorf("invalid security label %q: only lowercase letters, digits, '_' and '-' are allowed (max 32)", label)
+	}
+	return nil
+}
+
+// ParseSectionLabels 解析 JSON 形式的分段标签规则
+func ParseSectionLabels(raw string) ([]SectionLabel, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var rules []SectionLabel
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid section labels: %w", err)
+	}
+	for i := range rules {
+		rules[i].Label = NormalizeLabel(rules[i].Label)
+		if strings.TrimSpace(rules[i].Keyword) == "" || rules[i].Label == "" {
+			return nil, fmt.Errorf("invalid section labels: keyword and label are required")
+		}
+		if err := ValidateLabel(rules[i].Label); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// WithClearances 将调用方的访问权限写入上下文
+func WithClearances(ctx context.Context, clearances []string) context.Context {
+	return context.WithValue(ctx, clearanceKey{}, clearances)
+}
+
+// ParseClearances 解析以逗号或空格分隔的权限列表
+func ParseClearances(raw string) []string {
+	var clearances []string
+	for _, item := range clearanceSeparator.Split(raw, -1) {
+		if item = NormalizeLabel(item); item != "" {
+			clearances = append(clearances, item)
+		}
+	}
+	return clearances
+}
+
+// TrustClearanceHeader 是否信任权限请求头（security.clearanceHeader），仅当请求经由完成认证的网关转发时开启
+func TrustClearanceHeader(ctx context.Context) bool {
+	return g.Cfg().MustGet(ctx, "security.trustClearanceHeader", false).Bool()
+}
+
+// UserClearances 获取已认证用户在 security.userClearances 中配置的权限，未认证或未配置时返回 nil
+func UserClearances(ctx context.Context) []string {
+	userID, ok := identity.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	configured, ok := g.Cfg().MustGet(ctx, "security.userClearances").MapStrVar()[userID]
+	if !ok {
+		return nil
+	}
+	var clearances []string
+	for _, item := range configured.Strings() {
+		clearances = append(clearances, ParseClearances(item)...)
+	}
+	return clearances
+}
+
+// ClearancesFromContext 获取调用方的访问权限，上下文中没有时使用配置的默认权限
+func ClearancesFromContext(ctx context.Context) []string {
+	if clearances, ok := ctx.Value(clearanceKey{}).([]string); ok && len(clearances) > 0 {
+		return clearances
+	}
+	configured := g.Cfg().MustGet(ctx, "security.defaultClearances").Strings()
+	if len(configured) == 0 {
+		return defaultClearances
+	}
+	return configured
+}
+
+// AllowedLabels 计算调用方可访问的标签集合，未启用标签过滤时返回 nil
+func AllowedLabels(ctx context.Context) map[string]bool {
+	if !Enabled(ctx) {
+		return nil
+	}
+	return expandClearances(ClearancesFromContext(ctx), levels(ctx))
+}
+
+// expandClearances 展开权限：等级标签包含所有更低等级，非等级标签只匹配自身
+func expandClearances(clearances []string, levels []string) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, clearance := range clearances {
+		clearance = NormalizeLabel(clearance)
+		allowed[clearance] = true
+		for i, level := range levels {
+			if level != clearance {
+				continue
+			}
+			for _, lower := range levels[:i] {
+				allowed[lower] = true
+			}
+			break
+		}
+	}
+	return allowed
+}
+
+// ChunkLabel 获取分片的安全标签，没有标签的分片（如历史数据）使用默认标签
+func ChunkLabel(ctx context.Context, doc *schema.Document) string {
+	if doc != nil && doc.MetaData != nil {
+		if label, ok := doc.MetaData[MetadataKey].(string); ok && label != "" {
+			return NormalizeLabel(label)
+		}
+	}
+	return DefaultLabel(ctx)
+}
+
+// FilterDocuments 按调用方权限过滤检索结果，未启用标签过滤时原样返回
+func FilterDocuments(ctx context.Context, docs []*schema.Document) []*schema.Document {
+	allowed := AllowedLabels(ctx)
+	if allowed == nil || len(docs) == 0 {
+		return docs
+	}
+
+	filtered := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if allowed[ChunkLabel(ctx, doc)] {
+			filtered = append(filtered, doc)
+		}
+	}
+	if dropped := len(docs) - len(filtered); dropped > 0 {
+		g.Log().Debugf(ctx, "Security label filter dropped %d of %d chunks", dropped, len(docs))
+	}
+	return filtered
+}
+
+// ApplyLabels 在入库前为分片写入安全标签
+// 文档标签作为基础标签，命中分段规则的分片使用规则标签；多条规则命中时取等级最高的标签
+func ApplyLabels(ctx context.Context, chunks []*schema.Document, documentLabel string, sections []SectionLabel) {
+	documentLabel = NormalizeLabel(documentLabel)
+	if documentLabel == "" {
+		documentLabel = DefaultLabel(ctx)
+	}
+	levelList := levels(ctx)
+
+	for _, chunk := range chunks {
+		label := documentLabel
+		sectionLabel := ""
+		for _, rule := range sections {
+			if strings.Contains(chunk.Content, rule.Keyword) && higherLabel(rule.Label, sectionLabel, levelList) {
+				sectionLabel = rule.Label
+			}
+		}
+		if sectionLabel != "" {
+			label = sectionLabel
+		}
+		if chunk.MetaData == nil {
+			chunk.MetaData = make(map[string]any)
+		}
+		chunk.MetaData[MetadataKey] = label
+	}
+}
+
+// higherLabel 判断 a 的等级是否高于 b；不在等级列表中的自定义标签视为最高等级
+func higherLabel(a, b string, levels []string) bool {
+	if b == "" {
+		return true
+	}
+	rank := func(label string) int {
+		for i, level := range levels {
+			if level == label {
+				return i
+			}
+		}
+		return len(levels)
+	}
+	return rank(a) > rank(b)
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// TestExpandClearances 测试等级权限展开
+func TestExpandClearances(t *testing.T) {
+	tests := []struct {
+		name       string
+		clearances []string
+		allowed    []string
+		denied     []string
+	}{
+		{
+			name:       "public only",
+			clearances: []string{"public"},
+			allowed:    []string{"public"},
+			denied:     []string{"internal", "confidential"},
+		},
+		{
+			name:       "confidential grants lower levels",
+			clearances: []string{"Confidential"},
+			allowed:    []string{"public", "internal", "confidential"},
+		},
+		{
+			name:       "custom label matches itself",
+			clearances: []string{"internal", "finance"},
+			allowed:    []string{"public", "internal", "finance"},
+			denied:     []string{"confidential", "hr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := expandClearances(tt.clearances, defaultLevels)
+			for _, label := range tt.allowed {
+				if !allowed[label] {
+					t.Errorf("label %q should be allowed", label)
+				}
+			}
+			for _, label := range tt.denied {
+				if allowed[label] {
+					t.Errorf("label %q should be denied", label)
+				}
+			}
+		})
+	}
+}
+
+// TestApplyAndFilter 测试入库打标签和检索过滤
+func TestApplyAndFilter(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("security:\n  enabled: true\n  defaultClearances: [\"public\"]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	ctx := context.Background()
+	chunks := []*schema.Document{
+		{ID: "1", Content: "产品介绍"},
+		{ID: "2", Content: "## 薪酬结构 与 内部流程"},
+		{ID: "3", Content: "内部流程说明"},
+	}
+	sections := []SectionLabel{
+		{Keyword: "内部流程", Label: LabelInternal},
+		{Keyword: "薪酬", Label: LabelConfidential},
+	}
+	ApplyLabels(ctx, chunks, "", sections)
+
+	wantLabels := []string{LabelPublic, LabelConfidential, LabelInternal}
+	for i, chunk := range chunks {
+		if got := chunk.MetaData[MetadataKey]; got != wantLabels[i] {
+			t.Errorf("chunk %s label = %v, want %s", chunk.ID, got, wantLabels[i])
+		}
+	}
+
+	// 历史分片没有标签，按默认标签处理
+	docs := append(chunks, &schema.Document{ID: "4"})
+	if got := FilterDocuments(ctx, docs); len(got) != 2 || got[0].ID != "1" || got[1].ID != "4" {
+		t.Errorf("default clearance filter returned %d docs, want chunks 1 and 4", len(got))
+	}
+
+	internalCtx := WithClearances(ctx, ParseClearances("internal"))
+	if got := FilterDocuments(internalCtx, docs); len(got) != 3 {
+		t.Errorf("internal clearance filter returned %d docs, want 3", len(got))
+	}
+}
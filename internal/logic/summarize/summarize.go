@@ -0,0 +1,327 @@
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	coreModel "github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/outline"
+	"github.com/Malowking/kbgo/internal/logic/security"
+	"github.com/Malowking/kbgo/internal/model/entity"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 摘要风格
+const (
+	StyleExecutive = "executive" // 管理层摘要：结论、关键数据和建议
+	StyleBullet    = "bullet"    // 分级要点列表
+	StyleFAQ       = "faq"       // 常见问题问答
+
+	// 进度阶段
+	StageMap    = "map"    // 分段摘要
+	StageReduce = "reduce" // 合并摘要
+
+	defaultChunkChars  = 6000
+	defaultConcurrency = 4
+	// maxReduceRounds 合并摘要的最大轮数，避免摘要无法收敛时无限循环
+	maxReduceRounds = 5
+)
+
+const mapPrompt = "你是一个文档摘要助手。下面是一份长文档的第 %d/%d 部分，请提炼这一部分的要点，保留关键事实、数据、结论和专有名词，不要编造内容，不要添加开场白。%s\n\n文档内容：\n%s"
+
+const reducePrompt = "你是一个文档摘要助手。下面是同一份长文档各部分的摘要（按原文顺序），请合并为一份摘要，去除重复内容并保持原文顺序，不要编造内容，不要添加开场白。%s\n\n各部分摘要：\n%s"
+
+var stylePrompts = map[string]string{
+	StyleExecutive: "最终摘要采用管理层摘要格式：先用一段话概括核心结论，然后列出关键数据和事实，最后给出风险与建议。",
+	StyleBullet:    "最终摘要采用分级要点列表格式：按主题分组，每组使用 Markdown 标题和要点列表。",
+	StyleFAQ:       "最终摘要采用常见问题格式：列出读者最可能关心的问题，每个问题以 **问：** 开头，下一行以 答： 给出基于原文的回答。",
+}
+
+// Options 摘要参数
+type Options struct {
+	ConvID     string // 会话ID，用于读取会话附件和会话模型
+	DocumentID string // 知识库文档ID，为空时摘要会话中上传的附件
+	Style      string // 见 Style* 常量，默认 bullet
+	Focus      string // 关注点（可选），如 "重点关注付款条款"
+}
+
+// ProgressFunc 摘要进度回调，done/total 为当前阶段已完成和总的段数
+type ProgressFunc func(stage string, done, total int)
+
+// ValidStyle 是否为支持的摘要风格
+func ValidStyle(style string) bool {
+	_, ok := stylePrompts[style]
+	return ok
+}
+
+// Document 对长文档进行 map-reduce 摘要：分段并行摘要，再逐轮合并为最终的结构化摘要
+func Document(ctx context.Context, opts *Options, onProgress ProgressFunc) (string, error) {
+	style := opts.Style
+	if style == "" {
+		style = StyleBullet
+	}
+	if !ValidStyle(style) {
+		return "", fmt.Errorf("不支持的摘要风格: %s", style)
+	}
+
+	chunkChars := g.Cfg().MustGet(ctx, "summarize.chunkChars", defaultChunkChars).Int()
+	if chunkChars <= 0 {
+		chunkChars = defaultChunkChars
+	}
+	parts, err := loadParts(ctx, opts, chunkChars)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("文档内容为空")
+	}
+
+	mc, err := resolveModel(ctx, opts.ConvID)
+	if err != nil {
+		return "", err
+	}
+	s := &summarizer{
+		mc:          mc,
+		concurrency: max(g.Cfg().MustGet(ctx, "summarize.concurrency", defaultConcurrency).Int(), 1),
+		onProgress:  onProgress,
+	}
+	focus := ""
+	if opts.Focus != "" {
+		focus = "请重点关注：" + opts.Focus + "。"
+	}
+	g.Log().Infof(ctx, "Summarizing document in %d parts with model %s, style %s", len(parts), mc.Name, style)
+
+	// map：各部分并行摘要
+	summaries, err := s.mapParts(ctx, parts, focus)
+	if err != nil {
+		return "", err
+	}
+
+	// reduce：合并后仍超过分段长度时分组合并，直到可以一次生成最终摘要
+	for round := 0; round < maxReduceRounds && len(summaries) > 1; round++ {
+		groups := groupTexts(summaries, chunkChars)
+		if len(groups) == 1 {
+			break
+		}
+		if summaries, err = s.reduceGroups(ctx, groups, focus); err != nil {
+			return "", err
+		}
+	}
+	s.progress(StageReduce, 0, 1)
+	final, err := s.complete(ctx, fmt.Sprintf(reducePrompt, stylePrompts[style]+focus, joinSummaries(summaries)))
+	if err != nil {
+		return "", err
+	}
+	s.progress(StageReduce, 1, 1)
+	return final, nil
+}
+
+// loadParts 读取文档内容并按长度分段：知识库文档按分片顺序合并（启用安全标签过滤时跳过调用方无权访问的分片），会话附件按段落切分
+func loadParts(ctx context.Context, opts *Options, chunkChars int) ([]string, error) {
+	if opts.DocumentID != "" {
+		doc, err := outline.KnowledgeDocument(ctx, opts.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		chunks, err := knowledge.GetAllChunksByDocId(ctx, opts.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		parts := documentParts(ctx, chunks, doc.SecurityLabel, security.AllowedLabels(ctx), chunkChars)
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("文档 %s 没有可用的分片", opts.DocumentID)
+		}
+		return parts, nil
+	}
+
+	content, err := outline.AttachedContent(ctx, opts.ConvID)
+	if err != nil {
+		return nil, err
+	}
+	return splitText(content, chunkChars), nil
+}
+
+// documentParts 按分片顺序合并调用方可访问的分片并按长度分组，过滤规则与 outline.VisibleChunkTexts 相同
+func documentParts(ctx context.Context, chunks []entity.KnowledgeChunks, documentLabel string, allowed map[string]bool, chunkChars int) []string {
+	texts := outline.VisibleChunkTexts(ctx, chunks, documentLabel, allowed)
+	if len(texts) == 0 {
+		return nil
+	}
+	return groupTexts(texts, chunkChars)
+}
+
+// resolveModel 摘要使用的模型：summarize.modelID 配置优先，其次为会话当前模型
+func resolveModel(ctx context.Context, convID string) (*coreModel.ModelConfig, error) {
+	modelID := g.Cfg().MustGet(ctx, "summarize.modelID").String()
+	if modelID == "" && convID != "" {
+		conv, err := dao.Conversation.GetByConvID(ctx, convID)
+		if err != nil {
+			return nil, err
+		}
+		if conv != nil {
+			modelID = conv.ModelID
+		}
+	}
+	mc := coreModel.Registry.Get(modelID)
+	if mc == nil {
+		return nil, fmt.Errorf("摘要模型不可用，请配置 summarize.modelID")
+	}
+	return mc, nil
+}
+
+// summarizer 执行分段摘要和合并摘要的模型调用
+type summarizer struct {
+	mc          *coreModel.ModelConfig
+	concurrency int
+	onProgress  ProgressFunc
+	progressMu  sync.Mutex
+}
+
+// progress 回调可能来自多个 goroutine，串行调用
+func (s *summarizer) progress(stage string, done, total int) {
+	if s.onProgress == nil {
+		return
+	}
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.onProgress(stage, done, total)
+}
+
+// mapParts 并行摘要各部分，单个部分失败时以占位说明代替，全部失败时返回错误
+func (s *summarizer) mapParts(ctx context.Context, parts []string, focus string) ([]string, error) {
+	prompts := make([]string, len(parts))
+	for i, part := range parts {
+		prompts[i] = fmt.Sprintf(mapPrompt, i+1, len(parts), focus, part)
+	}
+	return s.run(ctx, StageMap, prompts, func(i int) string {
+		return fmt.Sprintf("（第 %d 部分摘要失败）", i+1)
+	})
+}
+
+// reduceGroups 将各组摘要分别合并
+func (s *summarizer) reduceGroups(ctx context.Context, groups []string, focus string) ([]string, error) {
+	prompts := make([]string, len(groups))
+	for i, group := range groups {
+		prompts[i] = fmt.Sprintf(reducePrompt, focus, group)
+	}
+	// 合并失败时保留原始的分段摘要
+	return s.run(ctx, StageReduce, prompts, func(i int) string {
+		return groups[i]
+	})
+}
+
+// run 以有限并发执行一批模型调用，按输入顺序返回结果
+func (s *summarizer) run(ctx context.Context, stage string, prompts []string, fallback func(i int) string) ([]string, error) {
+	results := make([]string, len(prompts))
+	errs := make([]error, len(prompts))
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	var doneMu sync.Mutex
+	done := 0
+	s.progress(stage, 0, len(prompts))
+	for i, prompt := range prompts {
+		wg.Add(1)
+		go func(i int, prompt string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i], errs[i] = s.complete(ctx, prompt)
+			doneMu.Lock()
+			done++
+			current := done
+			doneMu.Unlock()
+			s.progress(stage, current, len(prompts))
+		}(i, prompt)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			g.Log().Warningf(ctx, "Summarize %s part %d failed: %v", stage, i+1, err)
+			results[i] = fallback(i)
+		}
+	}
+	if failed == len(prompts) {
+		return nil, fmt.Errorf("摘要模型调用全部失败: %w", errs[0])
+	}
+	return results, nil
+}
+
+// complete 调用模型生成一段摘要
+func (s *summarizer) complete(ctx context.Context, prompt string) (string, error) {
+	modelService := coreModel.NewModelServiceFor(s.mc, coreModel.FormatterFor(s.mc))
+	resp, err := modelService.ChatCompletion(ctx, coreModel.ChatCompletionParams{
+		ModelName:           s.mc.Name,
+		Messages:            []*schema.Message{{Role: schema.User, Content: prompt}},
+		Temperature:         0.3,
+		MaxCompletionTokens: 2000,
+		TopP:                0.9,
+		N:                   1,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("received empty choices from API")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// splitText 按段落将文本切分为不超过 maxChars 个字符的部分，超长段落按字符硬切
+func splitText(text string, maxChars int) []string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		runes := []rune(paragraph)
+		for len(runes) > maxChars {
+			paragraphs = append(paragraphs, string(runes[:maxChars]))
+			runes = runes[maxChars:]
+		}
+		paragraphs = append(paragraphs, string(runes))
+	}
+	return groupTexts(paragraphs, maxChars)
+}
+
+// groupTexts 按顺序将文本合并为不超过 maxChars 个字符的组，单段超长时独立成组
+func groupTexts(texts []string, maxChars int) []string {
+	var groups []string
+	var current strings.Builder
+	length := 0
+	for _, text := range texts {
+		n := utf8.RuneCountInString(text)
+		if length > 0 && length+1+n > maxChars {
+			groups = append(groups, current.String())
+			current.Reset()
+			length = 0
+		}
+		if length > 0 {
+			current.WriteString("\n")
+			length++
+		}
+		current.WriteString(text)
+		length += n
+	}
+	if length > 0 {
+		groups = append(groups, current.String())
+	}
+	return groups
+}
+
+func joinSummaries(summaries []string) string {
+	var builder strings.Builder
+	for i, summary := range summaries {
+		builder.WriteString(fmt.Sprintf("[%d] %s\n", i+1, summary))
+	}
+	return builder.String()
+}
@@ -0,0 +1,76 @@
+package summarize
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/model/entity"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{"短文本", "第一段\n第二段", 20, []string{"第一段\n第二段"}},
+		{"跳过空行", "甲\n\n  \n乙", 20, []string{"甲\n乙"}},
+		{"按段落分组", "一二三\n四五六\n七八九", 7, []string{"一二三\n四五六", "七八九"}},
+		{"超长段落切分", "一二三四五", 2, []string{"一二", "三四", "五"}},
+		{"空文本", "", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitText(tt.text, tt.maxChars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupTexts(t *testing.T) {
+	tests := []struct {
+		name     string
+		texts    []string
+		maxChars int
+		want     []string
+	}{
+		{"全部合并", []string{"ab", "cd"}, 5, []string{"ab\ncd"}},
+		{"分隔符计入长度", []string{"ab", "cd"}, 4, []string{"ab", "cd"}},
+		{"单段超长独立成组", []string{"a", "bcdef", "g"}, 3, []string{"a", "bcdef", "g"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupTexts(tt.texts, tt.maxChars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupTexts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDocumentParts 测试启用安全标签过滤时摘要不包含调用方无权访问的分片
+func TestDocumentParts(t *testing.T) {
+	chunks := []entity.KnowledgeChunks{
+		{Content: "# 产品手册", Ext: `{"chunk_index":0,"security_label":"public"}`, Status: 1},
+		{Content: "## 薪酬方案", Ext: `{"chunk_index":1,"security_label":"confidential"}`, Status: 1},
+		{Content: "## 售后政策", Ext: `{"chunk_index":2}`, Status: 1},
+	}
+	tests := []struct {
+		name    string
+		allowed map[string]bool
+		want    []string
+	}{
+		{"未启用过滤", nil, []string{"# 产品手册\n## 薪酬方案\n## 售后政策"}},
+		{"跳过机密分片", map[string]bool{"public": true}, []string{"# 产品手册\n## 售后政策"}},
+		{"没有可访问的分片", map[string]bool{"internal": true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := documentParts(context.Background(), chunks, "public", tt.allowed, 100); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("documentParts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
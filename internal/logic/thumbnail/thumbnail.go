@@ -0,0 +1,337 @@
+// Package thumbnail 按需生成并缓存上传图片（文档解析提取的图片、对话上传的图片）的缩略图和缩放版本
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Malowking/kbgo/core/media"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	defaultCacheDir    = "upload/.thumbnails"
+	defaultMaxSize     = 2048
+	defaultQuality     = 80
+	defaultMaxSourceMB = 30
+	// defaultMaxPixels 原图像素数上限（宽×高），超过时不解码，防止小文件声明超大尺寸耗尽内存
+	defaultMaxPixels = 40_000_000
+)
+
+var defaultRoots = []string{"upload/image"}
+
+// defaultPresets 预设尺寸（最长边像素），可通过 imageService.presets 覆盖
+var defaultPresets = map[string]int{
+	"thumb":  160,
+	"small":  480,
+	"medium": 1024,
+}
+
+// keyLocks 同一缩放版本同时只由一个请求生成，并发请求等待先到的请求生成完成；
+// 按引用计数在最后一个等待者释放后才删除，避免等待者与新请求各自拿到不同的锁而重复生成
+var (
+	keyLocksMu sync.Mutex
+	keyLocks   = map[string]*keyLock{}
+)
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockKey 获取缩放版本的生成锁，返回释放函数
+func lockKey(key string) func() {
+	keyLocksMu.Lock()
+	l, ok := keyLocks[key]
+	if !ok {
+		l = &keyLock{}
+		keyLocks[key] = l
+	}
+	l.refs++
+	keyLocksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		keyLocksMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(keyLocks, key)
+		}
+		keyLocksMu.Unlock()
+	}
+}
+
+// Options 缩放参数，Width/Height 都为 0 且未指定 Size 时返回原图
+type Options struct {
+	Width   int
+	Height  int
+	Size    string // 预设尺寸名，如 thumb/small/medium
+	Fit     string // contain（默认）/ cover
+	Quality int    // JPEG 质量 1-100
+}
+
+// Image 待返回的图片
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// Variant 请求的图片版本（原图或缩放版本），ETag 只由原图文件信息和缩放参数决定，无需读取文件即可比较
+type Variant struct {
+	ETag string // 带引号的强校验值，原图或缩放参数变化时改变
+
+	file      string
+	ext       string
+	opts      Options
+	resize    bool
+	maxPixels int64
+	cacheDir  string
+}
+
+// Resolve 解析请求的图片和缩放参数，图片不存在或不在允许访问的目录内时返回错误
+func Resolve(ctx context.Context, requestPath string, opts Options) (*Variant, error) {
+	file, err := resolvePath(ctx, requestPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "image not found: %s", requestPath)
+	}
+	if opts, err = normalizeOptions(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	v := &Variant{
+		file:      file,
+		ext:       strings.ToLower(filepath.Ext(file)),
+		opts:      opts,
+		maxPixels: g.Cfg().MustGet(ctx, "imageService.maxPixels", defaultMaxPixels).Int64(),
+		cacheDir:  g.Cfg().MustGet(ctx, "imageService.cacheDir", defaultCacheDir).String(),
+	}
+	maxSource := g.Cfg().MustGet(ctx, "imageService.maxSourceMB", defaultMaxSourceMB).Int64() << 20
+	v.resize = (opts.Width > 0 || opts.Height > 0) && resizable(v.ext) && info.Size() <= maxSource
+
+	version := fmt.Sprintf("%s|%d|%d", file, info.Size(), info.ModTime().UnixNano())
+	if v.resize {
+		version += fmt.Sprintf("|%dx%d|%s|%d", opts.Width, opts.Height, opts.Fit, opts.Quality)
+	}
+	v.ETag = quote(hash(version))
+	return v, nil
+}
+
+// Load 读取图片：需要缩放时返回缓存的缩放版本，没有缓存时生成并写入缓存目录；
+// 原图不大于目标尺寸、格式不支持解码（如 webp、svg）或生成失败时返回原图
+func (v *Variant) Load(ctx context.Context) (*Image, error) {
+	if !v.resize {
+		return v.loadOriginal()
+	}
+
+	key := strings.Trim(v.ETag, `"`)
+	outExt := ".png"
+	if v.ext == ".jpg" || v.ext == ".jpeg" {
+		outExt = ".jpg"
+	}
+	cached := filepath.Join(v.cacheDir, key[:2], key+outExt)
+
+	unlock := lockKey(key)
+	defer unlock()
+
+	if data, err := os.ReadFile(cached); err == nil {
+		return &Image{Data: data, ContentType: mime.TypeByExtension(outExt)}, nil
+	}
+	data, resized, err := generate(v.file, v.opts, v.maxPixels)
+	if err != nil {
+		g.Log().Warningf(ctx, "生成缩略图失败，返回原图 %s: %v", v.file, err)
+		return v.loadOriginal()
+	}
+	if !resized {
+		return v.loadOriginal()
+	}
+	if err = writeCache(cached, data); err != nil {
+		g.Log().Warningf(ctx, "写入缩略图缓存失败 %s: %v", cached, err)
+	}
+	return &Image{Data: data, ContentType: mime.TypeByExtension(outExt)}, nil
+}
+
+// resolvePath 将请求路径转换为文件路径并校验在允许访问的目录内，符号链接解析为实际路径后再校验，
+// 防止目录内的链接指向目录外的文件
+// 支持 upload/image/x.png、/upload/image/x.png 和文档解析返回的 image/x.png 形式
+func resolvePath(ctx context.Context, requestPath string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(requestPath)), "/")
+	if strings.HasPrefix(cleaned, "image/") {
+		cleaned = "upload/" + cleaned
+	}
+	abs, err := filepath.Abs(filepath.FromSlash(cleaned))
+	if err != nil {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, "invalid image path: %s", requestPath)
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return "", gerror.NewCodef(gcode.CodeNotFound, "image not found: %s", requestPath)
+	}
+	roots := g.Cfg().MustGet(ctx, "imageService.roots", defaultRoots).Strings()
+	for _, root := range roots {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if rootAbs, err = filepath.EvalSymlinks(rootAbs); err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(rootAbs, abs); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return abs, nil
+		}
+	}
+	return "", gerror.NewCodef(gcode.CodeInvalidParameter, "image path is outside the allowed directories: %s", requestPath)
+}
+
+// normalizeOptions 展开预设尺寸并限制尺寸和质量范围
+func normalizeOptions(ctx context.Context, opts Options) (Options, error) {
+	if opts.Size != "" {
+		presets := defaultPresets
+		if configured := g.Cfg().MustGet(ctx, "imageService.presets").MapStrVar(); len(configured) > 0 {
+			presets = make(map[string]int, len(configured))
+			for name, value := range configured {
+				presets[name] = value.Int()
+			}
+		}
+		size, ok := presets[opts.Size]
+		if !ok {
+			return opts, gerror.NewCodef(gcode.CodeInvalidParameter, "unknown image size preset: %s", opts.Size)
+		}
+		if opts.Width == 0 && opts.Height == 0 {
+			opts.Width, opts.Height = size, size
+		}
+	}
+	maxSize := g.Cfg().MustGet(ctx, "imageService.maxSize", defaultMaxSize).Int()
+	opts.Width, opts.Height = min(max(opts.Width, 0), maxSize), min(max(opts.Height, 0), maxSize)
+	if opts.Fit != media.FitCover {
+		opts.Fit = media.FitContain
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = g.Cfg().MustGet(ctx, "imageService.quality", defaultQuality).Int()
+	}
+	return opts, nil
+}
+
+// resizable 是否为可以解码缩放的格式
+func resizable(ext string) bool {
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// generate 解码原图并缩放编码，原图不大于目标尺寸时返回 resized=false
+// GIF 只取第一帧并编码为 PNG；原图像素数超过 maxPixels（大于 0 时）返回错误，不解码
+func generate(file string, opts Options, maxPixels int64) ([]byte, bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	// 先只读取尺寸，原图不大于目标尺寸时无需解码整张图片
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, false, err
+	}
+	if pixels := int64(config.Width) * int64(config.Height); maxPixels > 0 && pixels > maxPixels {
+		return nil, false, fmt.Errorf("image is %dx%d, exceeds the %d pixel limit", config.Width, config.Height, maxPixels)
+	}
+	dstW, dstH, crop, ok := media.TargetSize(config.Width, config.Height, opts.Width, opts.Height, opts.Fit)
+	if !ok {
+		return nil, false, nil
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	src, format, err := image.Decode(f)
+	if err != nil {
+		return nil, false, err
+	}
+	dst := media.Resize(src, crop, dstW, dstH)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: opts.Quality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+func (v *Variant) loadOriginal() (*Image, error) {
+	data, err := os.ReadFile(v.file)
+	if err != nil {
+		return nil, err
+	}
+	contentType := mime.TypeByExtension(v.ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// writeCache 先写临时文件再重命名，避免并发读取到不完整的缓存文件
+func writeCache(cached string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cached), "*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), cached)
+}
+
+func hash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func quote(etag string) string {
+	return `"` + etag + `"`
+}
+
+// MatchETag 判断 If-None-Match 请求头是否包含当前 ETag
+func MatchETag(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+func TestMatchETag(t *testing.T) {
+	etag := `"abc"`
+	for header, want := range map[string]bool{
+		`"abc"`:      true,
+		`W/"abc"`:    true,
+		`"x", "abc"`: true,
+		`*`:          true,
+		`"abd"`:      false,
+		``:           false,
+	} {
+		if got := MatchETag(header, etag); got != want {
+			t.Errorf("MatchETag(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// useTestConfig 在临时目录下创建 upload/image/a.png（40x20），并使用默认的相对目录配置
+func useTestConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	adapter, err := gcfg.NewAdapterContent("imageService:\n  quality: 80\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	t.Cleanup(func() { g.Cfg().SetAdapter(original) })
+
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 6), G: uint8(y * 12), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join("upload", "image"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join("upload", "image", "a.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	useTestConfig(t)
+	ctx := context.Background()
+
+	for _, requestPath := range []string{"upload/image/a.png", "/upload/image/a.png", "image/a.png"} {
+		if _, err := resolvePath(ctx, requestPath); err != nil {
+			t.Errorf("resolvePath(%q) error = %v", requestPath, err)
+		}
+	}
+	for _, requestPath := range []string{
+		"../a.png",
+		"upload/image/../../config/config.yaml",
+		"image/../../../etc/passwd",
+		"/etc/passwd",
+		"upload/image",
+		"upload/.thumbnails/ab/x.png",
+	} {
+		if file, err := resolvePath(ctx, requestPath); err == nil {
+			t.Errorf("resolvePath(%q) = %q, want error", requestPath, file)
+		}
+	}
+
+	// 目录内指向目录外文件的符号链接同样拒绝
+	outside := filepath.Join(t.TempDir(), "secret.png")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join("upload", "image", "link.png")); err != nil {
+		t.Fatal(err)
+	}
+	if file, err := resolvePath(ctx, "image/link.png"); err == nil {
+		t.Errorf("resolvePath(symlink) = %q, want error", file)
+	}
+}
+
+func TestGenerateMaxPixels(t *testing.T) {
+	useTestConfig(t)
+	file := filepath.Join("upload", "image", "a.png")
+
+	if _, _, err := generate(file, Options{Width: 10, Fit: "contain"}, 799); err == nil {
+		t.Error("generate() with 40x20 image over 799 pixel limit error = nil, want error")
+	}
+	if _, resized, err := generate(file, Options{Width: 10, Fit: "contain"}, 800); err != nil || !resized {
+		t.Errorf("generate() at pixel limit = %v, %v, want resized", resized, err)
+	}
+}
+
+func TestLoadResizesAndCaches(t *testing.T) {
+	useTestConfig(t)
+	ctx := context.Background()
+
+	v, err := Resolve(ctx, "image/a.png", Options{Width: 10})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	img, err := v.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("decode thumbnail: %v", err)
+	}
+	if config.Width != 10 || config.Height != 5 || img.ContentType != "image/png" {
+		t.Errorf("thumbnail = %dx%d %s, want 10x5 image/png", config.Width, config.Height, img.ContentType)
+	}
+
+	// 再次读取同一版本命中缓存文件，不重新生成
+	key := v.ETag[1 : len(v.ETag)-1]
+	cached := filepath.Join(defaultCacheDir, key[:2], key+".png")
+	if err = os.WriteFile(cached, []byte("cached"), 0644); err != nil {
+		t.Fatalf("cache file missing: %v", err)
+	}
+	again, err := Resolve(ctx, "image/a.png", Options{Width: 10})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if again.ETag != v.ETag {
+		t.Errorf("ETag = %s, want %s", again.ETag, v.ETag)
+	}
+	if img, err = again.Load(ctx); err != nil || string(img.Data) != "cached" {
+		t.Errorf("Load() = %q, %v, want cached data", img.Data, err)
+	}
+}
+
+func TestLockKey(t *testing.T) {
+	var inside, maxInside atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := lockKey("k")
+			n := inside.Add(1)
+			for {
+				m := maxInside.Load()
+				if n <= m || maxInside.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			inside.Add(-1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+	if maxInside.Load() != 1 {
+		t.Errorf("max concurrent holders = %d, want 1", maxInside.Load())
+	}
+	keyLocksMu.Lock()
+	defer keyLocksMu.Unlock()
+	if len(keyLocks) != 0 {
+		t.Errorf("keyLocks not released: %v", keyLocks)
+	}
+}
@@ -0,0 +1,205 @@
+// Package toolconfig 校验工具配置：在保存或使用前检查配置引用的知识库、检索视图、MCP 服务和本地工具
+// 是否存在且可用，返回结构化的就绪报告，避免配置错误到对话时才暴露
+package toolconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/knowledge"
+	"github.com/Malowking/kbgo/internal/logic/project"
+	"github.com/Malowking/kbgo/internal/logic/retrievalview"
+	"github.com/Malowking/kbgo/internal/mcp"
+	"github.com/Malowking/kbgo/internal/mcp/client"
+	"gorm.io/gorm"
+)
+
+// 工具类型
+const (
+	TypeKnowledgeBase = "knowledge_base"
+	TypeRetrievalView = "retrieval_view"
+	TypeMCP           = "mcp"
+	TypeLocal         = "local"
+)
+
+// 检查结果
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// serviceCheckTimeout 连接一个 MCP 服务或执行一个插件的超时时间
+const serviceCheckTimeout = 15 * time.Second
+
+// Validate 逐项校验工具配置，所有配置项的检查都通过时报告为就绪
+func Validate(ctx context.Context, tools []*v1.ToolConfig) *v1.ToolConfigValidateRes {
+	res := &v1.ToolConfigValidateRes{Ready: true, Tools: make([]*v1.ToolConfigReport, 0, len(tools))}
+	for i, tool := range tools {
+		report := &v1.ToolConfigReport{Index: i, Type: tool.Type}
+		switch tool.Type {
+		case TypeKnowledgeBase:
+			report.Checks = checkKnowledgeBases(ctx, tool.KnowledgeIDs)
+		case TypeRetrievalView:
+			report.Checks = checkRetrievalView(ctx, tool.RetrievalView)
+		case TypeMCP:
+			report.Checks = checkMCPService(ctx, tool.ServiceName, tool.ToolNames)
+		case TypeLocal:
+			report.Checks = checkLocalService(ctx, tool.ServiceName, tool.ToolNames)
+		default:
+			report.Checks = []*v1.ToolConfigCheck{failed("type:"+tool.Type,
+				fmt.Sprintf("unsupported tool type, expected one of %s/%s/%s/%s", TypeKnowledgeBase, TypeRetrievalView, TypeMCP, TypeLocal))}
+		}
+		report.Ready = ready(report.Checks)
+		res.Ready = res.Ready && report.Ready
+		res.Tools = append(res.Tools, report)
+	}
+	return res
+}
+
+// checkKnowledgeBases 知识库存在、已启用且当前用户有权检索
+func checkKnowledgeBases(ctx context.Context, knowledgeIDs []string) []*v1.ToolConfigCheck {
+	if len(knowledgeIDs) == 0 {
+		return []*v1.ToolConfigCheck{failed(TypeKnowledgeBase, "knowledge_ids is required")}
+	}
+	checks := make([]*v1.ToolConfigCheck, 0, len(knowledgeIDs))
+	for _, id := range knowledgeIDs {
+		target := TypeKnowledgeBase + ":" + id
+		kb, err := knowledge.GetKnowledgeBaseById(ctx, id)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			checks = append(checks, failed(target, "knowledge base not found"))
+		case err != nil:
+			checks = append(checks, failed(target, err.Error()))
+		case kb.Status != 1:
+			checks = append(checks, failed(target, "knowledge base is disabled"))
+		default:
+			if err = project.CheckKnowledgeAccess(ctx, id); err != nil {
+				checks = append(checks, failed(target, err.Error()))
+			} else {
+				checks = append(checks, passed(target, kb.Name))
+			}
+		}
+	}
+	return checks
+}
+
+// checkRetrievalView 检索视图存在，且视图中的知识库都可以检索
+func checkRetrievalView(ctx context.Context, nameOrID string) []*v1.ToolConfigCheck {
+	if nameOrID == "" {
+		return []*v1.ToolConfigCheck{failed(TypeRetrievalView, "retrieval_view is required")}
+	}
+	target := TypeRetrievalView + ":" + nameOrID
+	view, err := retrievalview.Resolve(ctx, nameOrID)
+	if err != nil {
+		return []*v1.ToolConfigCheck{failed(target, err.Error())}
+	}
+	return append([]*v1.ToolConfigCheck{passed(target, view.Name)}, checkKnowledgeBases(ctx, view.KnowledgeIDs)...)
+}
+
+// checkMCPService MCP 服务已注册并启用、可以连接，指定的工具在服务端存在且参数定义有效
+func checkMCPService(ctx context.Context, serviceName string, toolNames []string) []*v1.ToolConfigCheck {
+	if serviceName == "" {
+		return []*v1.ToolConfigCheck{failed(TypeMCP, "service_name is required")}
+	}
+	target := TypeMCP + ":" + serviceName
+	registry, err := dao.MCPRegistry.GetByName(ctx, serviceName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []*v1.ToolConfigCheck{failed(target, "MCP service not registered")}
+	}
+	if err != nil {
+		return []*v1.ToolConfigCheck{failed(target, err.Error())}
+	}
+	if registry.Status != 1 {
+		return []*v1.ToolConfigCheck{failed(target, "MCP service is disabled")}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serviceCheckTimeout)
+	defer cancel()
+	mcpClient := client.NewMCPClient(registry)
+	defer mcpClient.Close()
+	if err = mcpClient.Initialize(ctx, map[string]interface{}{
+		"name":    "kbgo",
+		"version": "1.0.0",
+	}); err != nil {
+		return []*v1.ToolConfigCheck{failed(target, "MCP service is unhealthy: "+err.Error())}
+	}
+	tools, err := mcpClient.ListTools(ctx)
+	if err != nil {
+		return []*v1.ToolConfigCheck{failed(target, "failed to list MCP tools: "+err.Error())}
+	}
+	if len(tools) == 0 {
+		return []*v1.ToolConfigCheck{failed(target, "MCP service provides no tools")}
+	}
+	checks := []*v1.ToolConfigCheck{passed(target, fmt.Sprintf("%d tools available", len(tools)))}
+
+	// 参数定义无效的工具不会提供给 LLM，以缓存的校验结果为准
+	schemaErrors := make(map[string]string)
+	for _, info := range mcp.ParseCachedTools(registry.Tools) {
+		if info.SchemaError != "" {
+			schemaErrors[info.Name] = info.SchemaError
+		}
+	}
+	for _, name := range toolNames {
+		toolTarget := target + "/" + name
+		found := slices.ContainsFunc(tools, func(tool client.MCPTool) bool { return tool.Name == name })
+		switch {
+		case !found:
+			checks = append(checks, failed(toolTarget, "tool not found on MCP service"))
+		case schemaErrors[name] != "":
+			checks = append(checks, failed(toolTarget, "invalid input schema: "+schemaErrors[name]))
+		default:
+			checks = append(checks, passed(toolTarget, ""))
+		}
+	}
+	return checks
+}
+
+// checkLocalService 本地工具服务已注册且可以执行，指定的工具存在
+func checkLocalService(ctx context.Context, serviceName string, toolNames []string) []*v1.ToolConfigCheck {
+	if serviceName == "" {
+		return []*v1.ToolConfigCheck{failed(TypeLocal, "service_name is required")}
+	}
+	target := TypeLocal + ":" + serviceName
+	available := mcp.LocalServiceTools(serviceName)
+	if len(available) == 0 {
+		return []*v1.ToolConfigCheck{failed(target, "local tool service not found")}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serviceCheckTimeout)
+	defer cancel()
+	if err := mcp.CheckLocalService(ctx, serviceName); err != nil {
+		return []*v1.ToolConfigCheck{failed(target, "local tool service is not runnable: "+err.Error())}
+	}
+	checks := []*v1.ToolConfigCheck{passed(target, fmt.Sprintf("%d tools available", len(available)))}
+	for _, name := range toolNames {
+		if slices.Contains(available, name) {
+			checks = append(checks, passed(target+"/"+name, ""))
+		} else {
+			checks = append(checks, failed(target+"/"+name, "tool not found in local tool service"))
+		}
+	}
+	return checks
+}
+
+func passed(target, message string) *v1.ToolConfigCheck {
+	return &v1.ToolConfigCheck{Target: target, Status: StatusOK, Message: message}
+}
+
+func failed(target, message string) *v1.ToolConfigCheck {
+	return &v1.ToolConfigCheck{Target: target, Status: StatusError, Message: message}
+}
+
+// ready 所有检查都通过
+func ready(checks []*v1.ToolConfigCheck) bool {
+	for _, check := range checks {
+		if check.Status != StatusOK {
+			return false
+		}
+	}
+	return true
+}
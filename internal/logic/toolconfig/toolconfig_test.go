@@ -0,0 +1,38 @@
+package toolconfig
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+)
+
+func TestValidateLocalAndUnsupported(t *testing.T) {
+	res := Validate(context.Background(), []*v1.ToolConfig{
+		{Type: TypeLocal, ServiceName: "workspace", ToolNames: []string{"read_file", "write_file"}},
+		{Type: TypeLocal, ServiceName: "document", ToolNames: []string{"missing_tool"}},
+		{Type: TypeLocal, ServiceName: "no_such_service"},
+		{Type: "datasource"},
+		{Type: TypeMCP},
+	})
+
+	if res.Ready || len(res.Tools) != 5 {
+		t.Fatalf("Validate() = ready %v, %d reports", res.Ready, len(res.Tools))
+	}
+	if report := res.Tools[0]; !report.Ready || len(report.Checks) != 3 {
+		t.Errorf("workspace report = %+v", report)
+	}
+	wantFailed := map[int]string{
+		1: "local:document/missing_tool",
+		2: "local:no_such_service",
+		3: "type:datasource",
+		4: "mcp",
+	}
+	for i, target := range wantFailed {
+		report := res.Tools[i]
+		last := report.Checks[len(report.Checks)-1]
+		if report.Ready || last.Status != StatusError || last.Target != target {
+			t.Errorf("report %d = ready %v, last check %+v, want failed %s", i, report.Ready, last, target)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package toolexample 管理工具调用 few-shot 示例（问题 → 调用的工具及参数），
+// 工具调用前按与用户问题的相似度选取示例注入提示词，提高领域措辞下的工具选择准确率
+package toolexample
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+const defaultMaxExamples = 3
+
+// Fields 示例字段，更新时为 nil 的字段保持不变
+type Fields struct {
+	ModelID     *string
+	Question    *string
+	ServiceName *string
+	ToolName    *string
+	Arguments   map[string]interface{} // 为 nil 时保持不变
+	Note        *string
+	Enabled     *bool
+}
+
+// Create 创建工具调用示例
+func Create(ctx context.Context, fields *Fields) (*gormModel.ToolExample, error) {
+	example := &gormModel.ToolExample{ID: strings.ReplaceAll(uuid.New().String(), "-", ""), Enabled: true}
+	if err := apply(example, fields); err != nil {
+		return nil, err
+	}
+	if err := dao.ToolExample.Create(ctx, example); err != nil {
+		return nil, err
+	}
+	return example, nil
+}
+
+// Update 更新工具调用示例
+func Update(ctx context.Context, id string, fields *Fields) (*gormModel.ToolExample, error) {
+	example, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = apply(example, fields); err != nil {
+		return nil, err
+	}
+	if err = dao.ToolExample.Update(ctx, example); err != nil {
+		return nil, err
+	}
+	return example, nil
+}
+
+// Delete 删除工具调用示例
+func Delete(ctx context.Context, id string) error {
+	if _, err := Get(ctx, id); err != nil {
+		return err
+	}
+	return dao.ToolExample.Delete(ctx, id)
+}
+
+// Get 获取工具调用示例，不存在时返回 CodeNotFound 错误
+func Get(ctx context.Context, id string) (*gormModel.ToolExample, error) {
+	example, err := dao.ToolExample.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if example == nil {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, "tool example not found: %s", id)
+	}
+	return example, nil
+}
+
+// List 获取工具调用示例，可按模型和服务过滤
+func List(ctx context.Context, modelID, serviceName string) ([]*gormModel.ToolExample, error) {
+	return dao.ToolExample.List(ctx, modelID, serviceName)
+}
+
+// Select 选取注入提示词的示例：只保留 tools（LLM 工具名 服务名__工具名）中可用工具的示例，
+// 按与问题的相似度从高到低取 toolExamples.maxExamples 条；查询失败时只记录日志，不影响工具调用
+func Select(ctx context.Context, modelID, question string, tools []string) []*gormModel.ToolExample {
+	maxExamples := g.Cfg().MustGet(ctx, "toolExamples.maxExamples", defaultMaxExamples).Int()
+	if maxExamples <= 0 || len(tools) == 0 {
+		return nil
+	}
+	examples, err := dao.ToolExample.ListEnabledForModel(ctx, modelID)
+	if err != nil {
+		g.Log().Warningf(ctx, "加载工具调用示例失败: %v", err)
+		return nil
+	}
+	minSimilarity := g.Cfg().MustGet(ctx, "toolExamples.minSimilarity", 0).Float64()
+	return rank(examples, question, tools, maxExamples, minSimilarity)
+}
+
+// rank 按相似度排序并截取示例，相似度相同时保持创建顺序
+func rank(examples []*gormModel.ToolExample, question string, tools []string, limit int, minSimilarity float64) []*gormModel.ToolExample {
+	available := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		available[tool] = true
+	}
+
+	type scored struct {
+		example *gormModel.ToolExample
+		score   float64
+	}
+	var candidates []scored
+	for _, example := range examples {
+		if !available[LLMToolName(example)] {
+			continue
+		}
+		if score := Similarity(question, example.Question); score >= minSimilarity {
+			candidates = append(candidates, scored{example: example, score: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	selected := make([]*gormModel.ToolExample, 0, min(limit, len(candidates)))
+	for _, c := range candidates {
+		if len(selected) >= limit {
+			break
+		}
+		selected = append(selected, c.example)
+	}
+	return selected
+}
+
+// Prompt 把示例转换为追加到工具调用 system 提示词中的 few-shot 说明
+func Prompt(examples []*gormModel.ToolExample) string {
+	if len(examples) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("\n\n工具调用示例（遇到相似问题时参考示例选择工具和填写参数，参数需按当前问题调整）：\n")
+	for i, example := range examples {
+		arguments := example.Arguments
+		if arguments == "" {
+			arguments = "{}"
+		}
+		builder.WriteString(fmt.Sprintf("示例%d：\n问题：%s\n调用：%s(%s)\n", i+1, example.Question, LLMToolName(example), arguments))
+		if example.Note != "" {
+			builder.WriteString("说明：")
+			builder.WriteString(example.Note)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+// LLMToolName 示例对应的 LLM 工具名（服务名__工具名）
+func LLMToolName(example *gormModel.ToolExample) string {
+	return example.ServiceName + "__" + example.ToolName
+}
+
+// Similarity 两个问题字符二元组集合的 Dice 系数，忽略大小写、空白和标点
+func Similarity(a, b string) float64 {
+	gramsA, gramsB := bigramSet(a), bigramSet(b)
+	if len(gramsA) == 0 || len(gramsB) == 0 {
+		return 0
+	}
+	common := 0
+	for gram := range gramsA {
+		if gramsB[gram] {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(gramsA)+len(gramsB))
+}
+
+func bigramSet(text string) map[string]bool {
+	var runes []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	grams := make(map[string]bool, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])] = true
+	}
+	return grams
+}
+
+// apply 校验并写入示例字段
+func apply(example *gormModel.ToolExample, fields *Fields) error {
+	setString(&example.ModelID, fields.ModelID)
+	setString(&example.Question, fields.Question)
+	setString(&example.ServiceName, fields.ServiceName)
+	setString(&example.ToolName, fields.ToolName)
+	setString(&example.Note, fields.Note)
+	if fields.Enabled != nil {
+		example.Enabled = *fields.Enabled
+	}
+	if fields.Arguments != nil {
+		data, err := json.Marshal(fields.Arguments)
+		if err != nil {
+			return gerror.WrapCode(gcode.CodeInvalidParameter, err, "invalid tool example arguments")
+		}
+		example.Arguments = string(data)
+	}
+
+	switch {
+	case example.Question == "":
+		return gerror.NewCode(gcode.CodeInvalidParameter, "tool example question is required")
+	case example.ServiceName == "" || example.ToolName == "":
+		return gerror.NewCode(gcode.CodeInvalidParameter, "tool example service_name and tool_name are required")
+	case strings.Contains(example.ServiceName, "__"):
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "service_name must not contain '__': %s", example.ServiceName)
+	}
+	return nil
+}
+
+func setString(dst *string, value *string) {
+	if value != nil {
+		*dst = strings.TrimSpace(*value)
+	}
+}
@@ -0,0 +1,73 @@
+package toolexample
+
+import (
+	"strings"
+	"testing"
+
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func testExamples() []*gormModel.ToolExample {
+	return []*gormModel.ToolExample{
+		{ID: "e1", Question: "帮我查一下订单 123 的物流", ServiceName: "erp", ToolName: "query_shipment", Arguments: `{"order_id":"123"}`},
+		{ID: "e2", Question: "上个月华东区的销售额是多少", ServiceName: "bi", ToolName: "run_report", Note: "销售额统一用报表工具"},
+		{ID: "e3", Question: "订单 456 发货了吗", ServiceName: "erp", ToolName: "query_shipment"},
+	}
+}
+
+// TestRank 测试按相似度和可用工具选取示例
+func TestRank(t *testing.T) {
+	tools := []string{"erp__query_shipment", "bi__run_report"}
+
+	selected := rank(testExamples(), "订单 789 的物流到哪了", tools, 2, 0)
+	if len(selected) != 2 || selected[0].ID != "e1" {
+		t.Fatalf("expected e1 first, got %v", exampleIDs(selected))
+	}
+
+	// 工具不可用的示例不注入
+	selected = rank(testExamples(), "华东区销售额", []string{"erp__query_shipment"}, 3, 0)
+	for _, example := range selected {
+		if example.ID == "e2" {
+			t.Errorf("example for unavailable tool selected: %v", exampleIDs(selected))
+		}
+	}
+
+	// 低于最低相似度的示例不注入
+	if selected = rank(testExamples(), "今天天气怎么样", tools, 3, 0.2); len(selected) != 0 {
+		t.Errorf("expected no examples above threshold, got %v", exampleIDs(selected))
+	}
+}
+
+// TestPrompt 测试 few-shot 提示词
+func TestPrompt(t *testing.T) {
+	if Prompt(nil) != "" {
+		t.Error("expected empty prompt without examples")
+	}
+	prompt := Prompt(testExamples()[:2])
+	for _, want := range []string{"示例1", `erp__query_shipment({"order_id":"123"})`, "bi__run_report({})", "说明：销售额统一用报表工具"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q: %s", want, prompt)
+		}
+	}
+}
+
+// TestSimilarity 测试问题相似度
+func TestSimilarity(t *testing.T) {
+	if got := Similarity("订单物流", "订单物流？"); got != 1 {
+		t.Errorf("expected 1 for identical questions ignoring punctuation, got %f", got)
+	}
+	if got := Similarity("订单物流", "天气预报"); got != 0 {
+		t.Errorf("expected 0 for unrelated questions, got %f", got)
+	}
+	if got := Similarity("", "订单"); got != 0 {
+		t.Errorf("expected 0 for empty question, got %f", got)
+	}
+}
+
+func exampleIDs(examples []*gormModel.ToolExample) []string {
+	ids := make([]string, 0, len(examples))
+	for _, example := range examples {
+		ids = append(ids, example.ID)
+	}
+	return ids
+}
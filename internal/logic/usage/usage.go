@@ -0,0 +1,229 @@
+// Package usage token 用量统计：定时把消息表的 TokensUsed 和直接调用模型的用量按天、按用户、按模型汇总到 usage_daily 表，
+// 提供用量查询，并按配置限制用户每月可消耗的 token
+package usage
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const (
+	// DateLayout 用量表使用的日期格式
+	DateLayout  = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// InitUsage 初始化用量汇总任务，定时刷新今天和昨天的用量，昨天的数据用于覆盖跨零点写入的消息
+func InitUsage() {
+	ctx := gctx.New()
+	if !g.Cfg().MustGet(ctx, "usage.enabled", true).Bool() {
+		g.Log().Info(ctx, "Usage rollup is disabled")
+		return
+	}
+
+	pattern := g.Cfg().MustGet(ctx, "usage.rollupCron", "0 */5 * * * *").String()
+	_, err := gcron.AddSingleton(ctx, pattern, func(ctx context.Context) {
+		now := time.Now()
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			if err := RunRollup(ctx, day); err != nil {
+				g.Log().Errorf(ctx, "Usage rollup failed for %s: %v", day.Format(DateLayout), err)
+			}
+		}
+	}, "usage-rollup")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule usage rollup: %v", err)
+	} else {
+		g.Log().Infof(ctx, "Usage rollup scheduled with pattern: %s", pattern)
+	}
+}
+
+// RunRollup 重新汇总指定日期的 token 用量并覆盖写入：会话消息计入消息所属用户（本轮提问的用户）和生成消息的模型，
+// 直接调用模型的用量（usage_direct）计入调用用户和所调用的模型
+func RunRollup(ctx context.Context, day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	statDate := start.Format(DateLayout)
+
+	stats, err := dao.Usage.AggregateTokens(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	direct, err := dao.Usage.ListDirect(ctx, statDate)
+	if err != nil {
+		return err
+	}
+	rows := mergeDaily(statDate, stats, direct)
+	if err = dao.Usage.ReplaceDaily(ctx, statDate, rows); err != nil {
+		return err
+	}
+
+	g.Log().Debugf(ctx, "Usage rollup done for %s: %d rows", statDate, len(rows))
+	return nil
+}
+
+// mergeDaily 把会话消息的统计和直接调用模型的用量按用户、模型合并为当天的汇总行
+func mergeDaily(statDate string, stats []*dao.UsageStatRow, direct []*gormModel.UsageDirect) []*gormModel.UsageDaily {
+	rows := make([]*gormModel.UsageDaily, 0, len(stats)+len(direct))
+	index := make(map[[2]string]*gormModel.UsageDaily)
+	get := func(userID, modelID, modelName string) *gormModel.UsageDaily {
+		key := [2]string{userID, modelID}
+		if row, ok := index[key]; ok {
+			return row
+		}
+		row := &gormModel.UsageDaily{StatDate: statDate, UserID: userID, ModelID: modelID, ModelName: modelName}
+		index[key] = row
+		rows = append(rows, row)
+		return row
+	}
+	for _, stat := range stats {
+		row := get(stat.UserID, stat.ModelID, stat.ModelName)
+		row.MessageCount += stat.MessageCount
+		row.TotalTokens += stat.TotalTokens
+	}
+	for _, d := range direct {
+		row := get(d.UserID, d.ModelID, d.ModelName)
+		row.DirectRequests += d.RequestCount
+		row.TotalTokens += d.TotalTokens
+	}
+	return rows
+}
+
+// RecordDirect 记录一次直接调用模型（不保存会话消息）的 token 消耗，计入本次请求的用户，记录失败只记录日志
+func RecordDirect(ctx context.Context, modelID, modelName string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	if err := dao.Usage.AddDirect(ctx, time.Now().Format(DateLayout), identity.UserID(ctx), modelID, modelName, int64(tokens)); err != nil {
+		g.Log().Warningf(ctx, "记录直接调用模型的 token 用量失败: %v", err)
+	}
+}
+
+// Query 查询日期区间内的用量；已认证的请求只能查询自己的用量，指定了用户时同时返回该用户本月的 token 配额
+func Query(ctx context.Context, req *v1.UsageReq) (*v1.UsageRes, error) {
+	req.UserID = identity.Resolve(ctx, req.UserID)
+	rows, err := dao.Usage.ListDaily(ctx, req.StartDate, req.EndDate, req.UserID, req.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &v1.UsageRes{List: make([]*v1.UsageItem, 0, len(rows))}
+	for _, row := range rows {
+		res.List = append(res.List, &v1.UsageItem{
+			StatDate:       row.StatDate,
+			UserID:         row.UserID,
+			ModelID:        row.ModelID,
+			ModelName:      row.ModelName,
+			MessageCount:   row.MessageCount,
+			DirectRequests: row.DirectRequests,
+			TotalTokens:    row.TotalTokens,
+		})
+		res.TotalTokens += row.TotalTokens
+	}
+	if req.UserID != "" {
+		if res.Quota, err = UserQuota(ctx, req.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// MonthlyLimit 用户每月可消耗的 token，0 表示不限制
+func MonthlyLimit(ctx context.Context, userID string) int64 {
+	return monthlyLimit(
+		g.Cfg().MustGet(ctx, "usage.quota.monthlyTokens", 0).Int64(),
+		g.Cfg().MustGet(ctx, "usage.quota.users").Map(),
+		userID,
+	)
+}
+
+// monthlyLimit usage.quota.users 中为用户单独配置的上限优先于默认上限（用户ID作为键，不按 "." 拆分）
+func monthlyLimit(defaultLimit int64, users map[string]any, userID string) int64 {
+	if limit, ok := users[userID]; ok {
+		return gconv.Int64(limit)
+	}
+	return defaultLimit
+}
+
+// UserQuota 用户本月的 token 用量和配额
+func UserQuota(ctx context.Context, userID string) (*v1.QuotaUsage, error) {
+	now := time.Now()
+	used, err := monthTokens(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	limit := MonthlyLimit(ctx, userID)
+	return &v1.QuotaUsage{
+		Metric:   quota.MetricTokens,
+		Period:   now.Format(monthLayout),
+		Unit:     "tokens",
+		Used:     float64(used),
+		Limit:    float64(limit),
+		Exceeded: limit > 0 && used >= limit,
+	}, nil
+}
+
+// CheckUser 用户本月消耗的 token 达到配额时返回 quota.CodeQuotaExceeded 错误；未设置配额或查询失败时不限制
+func CheckUser(ctx context.Context, userID string) error {
+	if userID == "" || MonthlyLimit(ctx, userID) <= 0 {
+		return nil
+	}
+	q, err := UserQuota(ctx, userID)
+	if err != nil {
+		g.Log().Warningf(ctx, "查询用户 %s 的 token 用量失败，跳过配额校验: %v", userID, err)
+		return nil
+	}
+	if q.Exceeded {
+		return exceeded(userID, q)
+	}
+	return nil
+}
+
+// CheckChat 校验对话计费用户的月度 token 配额：与汇总规则一致，计入提问用户（共享会话中为各参与者本人），
+// 请求没有提问用户时计入会话所属用户
+func CheckChat(ctx context.Context, convID, userID string) error {
+	if userID == "" && convID != "" {
+		if conv, err := dao.Conversation.GetByConvID(ctx, convID); err == nil && conv != nil {
+			userID = conv.UserID
+		}
+	}
+	return CheckUser(ctx, userID)
+}
+
+// monthTokens 用户本月的 token 消耗：今天之前的天数读取汇总表，今天直接统计消息表和直接调用模型的用量，不必等待下一次汇总
+func monthTokens(ctx context.Context, userID string, now time.Time) (int64, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var total int64
+	if today.After(monthStart) {
+		closed, err := dao.Usage.SumUserTokens(ctx, userID, monthStart.Format(DateLayout), today.AddDate(0, 0, -1).Format(DateLayout))
+		if err != nil {
+			return 0, err
+		}
+		total += closed
+	}
+	live, err := dao.Usage.SumLiveUserTokens(ctx, userID, today, today.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, err
+	}
+	direct, err := dao.Usage.SumUserDirectTokens(ctx, userID, today.Format(DateLayout))
+	if err != nil {
+		return 0, err
+	}
+	return total + live + direct, nil
+}
+
+func exceeded(userID string, q *v1.QuotaUsage) error {
+	return gerror.NewCodef(quota.CodeQuotaExceeded, "user '%s' monthly token quota exceeded for %s: used %g of %g tokens",
+		userID, q.Period, q.Used, q.Limit)
+}
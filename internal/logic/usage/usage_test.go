@@ -0,0 +1,63 @@
+package usage
+
+import (
+	"testing"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+func TestMonthlyLimit(t *testing.T) {
+	users := map[string]any{"alice@example.com": 5000, "bob": "0"}
+	tests := []struct {
+		name   string
+		userID string
+		want   int64
+	}{
+		{name: "default limit", userID: "carol", want: 1000},
+		{name: "user override with dots in id", userID: "alice@example.com", want: 5000},
+		{name: "user override disables limit", userID: "bob", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := monthlyLimit(1000, users, tt.userID); got != tt.want {
+				t.Errorf("monthlyLimit(%q) = %d, want %d", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceeded(t *testing.T) {
+	err := exceeded("alice", &v1.QuotaUsage{Period: "2026-10", Used: 1200, Limit: 1000})
+	if gerror.Code(err) != quota.CodeQuotaExceeded {
+		t.Errorf("code = %v, want %v", gerror.Code(err), quota.CodeQuotaExceeded)
+	}
+	if want := "user 'alice' monthly token quota exceeded for 2026-10: used 1200 of 1000 tokens"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestMergeDaily(t *testing.T) {
+	stats := []*dao.UsageStatRow{
+		{UserID: "alice", ModelID: "m1", ModelName: "gpt", MessageCount: 3, TotalTokens: 300},
+	}
+	direct := []*gormModel.UsageDirect{
+		{UserID: "alice", ModelID: "m1", ModelName: "gpt", RequestCount: 2, TotalTokens: 50},
+		{UserID: "bob", ModelID: "m1", ModelName: "gpt", RequestCount: 1, TotalTokens: 20},
+	}
+	rows := mergeDaily("2026-10-16", stats, direct)
+	if len(rows) != 2 {
+		t.Fatalf("len(rows) = %d, want 2", len(rows))
+	}
+	alice := rows[0]
+	if alice.UserID != "alice" || alice.MessageCount != 3 || alice.DirectRequests != 2 || alice.TotalTokens != 350 {
+		t.Errorf("alice row = %+v, want 3 messages, 2 direct requests, 350 tokens", alice)
+	}
+	bob := rows[1]
+	if bob.UserID != "bob" || bob.StatDate != "2026-10-16" || bob.DirectRequests != 1 || bob.TotalTokens != 20 {
+		t.Errorf("bob row = %+v, want 1 direct request, 20 tokens", bob)
+	}
+}
@@ -0,0 +1,288 @@
+// Package warmup 启动时和定时预热：把常用知识库的向量集合加载到内存，用一次极小的请求建立常用模型的连接，
+// 并连接调用最多的 MCP 服务，减少发布后第一批请求的延迟
+package warmup
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/core/client"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/internal/dao"
+	mcpclient "github.com/Malowking/kbgo/internal/mcp/client"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/Malowking/kbgo/internal/service"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Config 预热配置（warmUp.*）
+type Config struct {
+	Enabled        bool
+	Cron           string
+	Timeout        time.Duration // 单项预热（加载一个集合、一次模型请求、连接一个服务）的超时
+	Collections    int           // 预加载的集合数量
+	KnowledgeIDs   []string      // 总是预加载的知识库
+	Models         int           // 预热的常用聊天模型数量（按近期会话数），embedding 模型全部预热
+	MCPServices    int           // 预连接的 MCP 服务数量（按近期调用次数）
+	LookbackWindow time.Duration // 统计常用模型和 MCP 服务的时间范围
+}
+
+// LoadConfig 读取预热配置，未配置的项使用默认值
+func LoadConfig(ctx context.Context) *Config {
+	cfg := &Config{
+		Enabled:        g.Cfg().MustGet(ctx, "warmUp.enabled", true).Bool(),
+		Cron:           g.Cfg().MustGet(ctx, "warmUp.cron", "0 */10 * * * *").String(),
+		Timeout:        time.Duration(g.Cfg().MustGet(ctx, "warmUp.timeoutSeconds", 30).Int()) * time.Second,
+		Collections:    g.Cfg().MustGet(ctx, "warmUp.collections", 10).Int(),
+		KnowledgeIDs:   g.Cfg().MustGet(ctx, "warmUp.knowledgeIds").Strings(),
+		Models:         g.Cfg().MustGet(ctx, "warmUp.models", 3).Int(),
+		MCPServices:    g.Cfg().MustGet(ctx, "warmUp.mcpServices", 5).Int(),
+		LookbackWindow: time.Duration(g.Cfg().MustGet(ctx, "warmUp.lookbackDays", 7).Int()) * 24 * time.Hour,
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return cfg
+}
+
+// usage 进程启动以来各知识库的检索次数，用于选择定时预热的集合
+var usage = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: make(map[string]int)}
+
+// RecordKnowledgeUse 记录一次知识库检索
+func RecordKnowledgeUse(knowledgeID string) {
+	if knowledgeID == "" {
+		return
+	}
+	usage.Lock()
+	usage.counts[knowledgeID]++
+	usage.Unlock()
+}
+
+// frequentKnowledgeIDs 按检索次数从多到少返回知识库ID
+func frequentKnowledgeIDs() []string {
+	usage.Lock()
+	defer usage.Unlock()
+	ids := make([]string, 0, len(usage.counts))
+	for id := range usage.counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if usage.counts[ids[i]] != usage.counts[ids[j]] {
+			return usage.counts[ids[i]] > usage.counts[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// Report 一次预热的结果
+type Report struct {
+	Collections []string          // 已加载的集合
+	Models      []string          // 已预热的模型
+	MCPServices []string          // 已连接的 MCP 服务
+	Failures    map[string]string // 失败项 -> 原因
+	Duration    time.Duration
+
+	mu sync.Mutex
+}
+
+func (r *Report) add(list *[]string, name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.Failures[name] = err.Error()
+		return
+	}
+	*list = append(*list, name)
+}
+
+// InitWarmUp 启动时预热一次，之后按 warmUp.cron 定时预热（Milvus 重启释放集合、连接空闲断开后重新建立）
+func InitWarmUp() {
+	ctx := gctx.New()
+	cfg := LoadConfig(ctx)
+	if !cfg.Enabled {
+		g.Log().Info(ctx, "Warm-up is disabled")
+		return
+	}
+	go Run(ctx, cfg)
+	_, err := gcron.AddSingleton(ctx, cfg.Cron, func(ctx context.Context) {
+		Run(ctx, cfg)
+	}, "warm-up")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule warm-up: %v", err)
+	} else {
+		g.Log().Infof(ctx, "Warm-up scheduled with pattern: %s", cfg.Cron)
+	}
+}
+
+// Run 并发预热集合、模型和 MCP 服务，单项失败只记录，不影响其他项
+func Run(ctx context.Context, cfg *Config) *Report {
+	start := time.Now()
+	report := &Report{Failures: make(map[string]string)}
+	var wg sync.WaitGroup
+	for _, step := range []func(context.Context, *Config, *Report){warmCollections, warmModels, warmMCPServices} {
+		wg.Add(1)
+		go func(step func(context.Context, *Config, *Report)) {
+			defer wg.Done()
+			step(ctx, cfg, report)
+		}(step)
+	}
+	wg.Wait()
+	report.Duration = time.Since(start)
+
+	g.Log().Infof(ctx, "Warm-up finished in %s: %d collections, %d models, %d MCP services, %d failures",
+		report.Duration.Round(time.Millisecond), len(report.Collections), len(report.Models), len(report.MCPServices), len(report.Failures))
+	for name, reason := range report.Failures {
+		g.Log().Warningf(ctx, "Warm-up failed for %s: %s", name, reason)
+	}
+	return report
+}
+
+// selectKnowledgeIDs 选择预加载的知识库：先取配置的知识库，再按本进程的检索次数补足；
+// 刚启动还没有检索记录时，用最近更新的知识库补足
+func selectKnowledgeIDs(ctx context.Context, cfg *Config) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] && len(ids) < cfg.Collections {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range cfg.KnowledgeIDs {
+		add(id)
+	}
+	frequent := frequentKnowledgeIDs()
+	for _, id := range frequent {
+		add(id)
+	}
+	if len(frequent) == 0 && len(ids) < cfg.Collections {
+		var recent []string
+		err := dao.GetDB().WithContext(ctx).Model(&gormModel.KnowledgeBase{}).
+			Where("status = ?", 1).
+			Order("update_time DESC").
+			Limit(cfg.Collections).
+			Pluck("id", &recent).Error
+		if err != nil {
+			g.Log().Warningf(ctx, "Failed to list recently updated knowledge bases for warm-up: %v", err)
+		}
+		for _, id := range recent {
+			add(id)
+		}
+	}
+	return ids
+}
+
+// warmCollections 把选中知识库的向量集合加载到内存，向量存储不支持预加载时跳过
+func warmCollections(ctx context.Context, cfg *Config, report *Report) {
+	if cfg.Collections <= 0 {
+		return
+	}
+	store, err := service.GetVectorStore()
+	if err != nil {
+		report.add(&report.Collections, "vector store", err)
+		return
+	}
+	loader, ok := store.(vector_store.CollectionLoader)
+	if !ok {
+		return
+	}
+	for _, knowledgeID := range selectKnowledgeIDs(ctx, cfg) {
+		collection := knowledgeID
+		var kb gormModel.KnowledgeBase
+		if err := dao.GetDB().WithContext(ctx).Where("id = ?", knowledgeID).First(&kb).Error; err == nil && kb.CollectionName != "" {
+			collection = kb.CollectionName
+		}
+		loadCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		err := loader.LoadCollection(loadCtx, collection)
+		cancel()
+		report.add(&report.Collections, collection, err)
+	}
+}
+
+// warmModels 用一次极小的请求建立常用聊天模型和 embedding 模型的连接；
+// ollama 模型由模型健康检查预加载，被标记为不可用的模型跳过
+func warmModels(ctx context.Context, cfg *Config, report *Report) {
+	var models []*model.ModelConfig
+	if cfg.Models > 0 {
+		ids, err := dao.Conversation.TopModelIDs(ctx, time.Now().Add(-cfg.LookbackWindow), cfg.Models)
+		if err != nil {
+			report.add(&report.Models, "chat models", err)
+		}
+		for _, id := range ids {
+			if mc := model.Registry.Get(id); mc != nil {
+				models = append(models, mc)
+			}
+		}
+	}
+	models = append(models, model.Registry.GetByType(model.ModelTypeEmbedding)...)
+
+	var wg sync.WaitGroup
+	for _, mc := range models {
+		if mc.Client == nil || strings.EqualFold(mc.Provider, client.ProviderOllama) || model.Registry.CheckAvailable(mc.ModelID) != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(mc *model.ModelConfig) {
+			defer wg.Done()
+			primeCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+			report.add(&report.Models, mc.Name, primeModel(primeCtx, mc))
+		}(mc)
+	}
+	wg.Wait()
+}
+
+// primeModel 向模型发送一次极小的请求：embedding 模型向量化一个短文本，聊天模型只生成 1 个 token
+func primeModel(ctx context.Context, mc *model.ModelConfig) error {
+	if mc.Type == model.ModelTypeEmbedding {
+		_, err := mc.Client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+			Input: []string{"warm up"},
+			Model: openai.EmbeddingModel(mc.Name),
+		})
+		return err
+	}
+	_, err := mc.Client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:               mc.Name,
+		Messages:            []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+		MaxCompletionTokens: 1,
+	})
+	return err
+}
+
+// warmMCPServices 初始化近期调用最多的 MCP 服务的连接
+func warmMCPServices(ctx context.Context, cfg *Config, report *Report) {
+	if cfg.MCPServices <= 0 {
+		return
+	}
+	ids, err := dao.MCPCallLog.TopRegistryIDs(ctx, time.Now().Add(-cfg.LookbackWindow), cfg.MCPServices)
+	if err != nil {
+		report.add(&report.MCPServices, "MCP services", err)
+		return
+	}
+	for _, id := range ids {
+		registry, err := dao.MCPRegistry.GetByID(ctx, id)
+		if err != nil || registry.Status != 1 {
+			continue
+		}
+		connectCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		mcpClient := mcpclient.NewMCPClient(registry)
+		err = mcpClient.Initialize(connectCtx, map[string]interface{}{
+			"name":    "kbgo",
+			"version": "1.0.0",
+		})
+		_ = mcpClient.Close()
+		cancel()
+		report.add(&report.MCPServices, registry.Name, err)
+	}
+}
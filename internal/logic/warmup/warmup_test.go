@@ -0,0 +1,40 @@
+package warmup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestSelectKnowledgeIDs 测试先取配置的知识库，再按检索次数补足，数量不超过上限
+func TestSelectKnowledgeIDs(t *testing.T) {
+	for _, id := range []string{"kb-b", "kb-a", "kb-b", "kb-c", "kb-b", "kb-a", "kb-pinned", ""} {
+		RecordKnowledgeUse(id)
+	}
+	t.Cleanup(func() {
+		usage.Lock()
+		usage.counts = make(map[string]int)
+		usage.Unlock()
+	})
+
+	if got, want := frequentKnowledgeIDs(), []string{"kb-b", "kb-a", "kb-c", "kb-pinned"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frequentKnowledgeIDs() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		cfg  *Config
+		want []string
+	}{
+		{name: "Configured first", cfg: &Config{Collections: 3, KnowledgeIDs: []string{"kb-pinned"}}, want: []string{"kb-pinned", "kb-b", "kb-a"}},
+		{name: "Limited by collections", cfg: &Config{Collections: 1}, want: []string{"kb-b"}},
+		{name: "Disabled", cfg: &Config{Collections: 0, KnowledgeIDs: []string{"kb-pinned"}}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectKnowledgeIDs(context.Background(), tt.cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectKnowledgeIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
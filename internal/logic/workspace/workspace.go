@@ -0,0 +1,253 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Malowking/kbgo/core/config"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// RefPrefix 工具参数中引用工作区文件的前缀，如 workspace://result.csv
+const RefPrefix = "workspace://"
+
+const (
+	defaultMaxFileSize  = 10 << 20  // 单文件上限 10MB
+	defaultMaxTotalSize = 100 << 20 // 单会话总容量上限 100MB
+	defaultMaxFiles     = 50
+	maxNameLength       = 128
+)
+
+var (
+	ErrInvalidName   = errors.New("invalid workspace file name")
+	ErrFileNotFound  = errors.New("workspace file not found")
+	ErrQuotaExceeded = errors.New("workspace quota exceeded")
+)
+
+// FileInfo 工作区文件信息
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Quota 工作区配额
+type Quota struct {
+	MaxFileSize  int64
+	MaxTotalSize int64
+	MaxFiles     int
+}
+
+// convLocks 同一会话的写入串行化，保证配额检查准确
+var convLocks sync.Map
+
+func lockConv(convID string) func() {
+	value, _ := convLocks.LoadOrStore(convID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// GetQuota 读取工作区配额配置
+func GetQuota(ctx context.Context) Quota {
+	return Quota{
+		MaxFileSize:  g.Cfg().MustGet(ctx, "workspace.maxFileSize", defaultMaxFileSize).Int64(),
+		MaxTotalSize: g.Cfg().MustGet(ctx, "workspace.maxTotalSize", defaultMaxTotalSize).Int64(),
+		MaxFiles:     g.Cfg().MustGet(ctx, "workspace.maxFiles", defaultMaxFiles).Int(),
+	}
+}
+
+// convDir 获取会话工作区目录
+func convDir(ctx context.Context, convID string) (string, error) {
+	if err := validateName(convID); err != nil {
+		return "", fmt.Errorf("invalid conversation id %q: %w", convID, err)
+	}
+	root := g.Cfg().MustGet(ctx, "workspace.root", config.StatePath("workspace")).String()
+	return filepath.Join(root, convID), nil
+}
+
+// validateName 校验文件名，只允许工作区根目录下的普通文件名
+func validateName(name string) error {
+	if name == "" || len(name) > maxNameLength || strings.HasPrefix(name, ".") ||
+		strings.ContainsAny(name, `/\`) || filepath.Base(name) != name {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+// WriteFile 写入（或覆盖）会话工作区中的文件
+func WriteFile(ctx context.Context, convID string, name string, data []byte) (*FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, fmt.Errorf("%w: %q", err, name)
+	}
+	dir, err := convDir(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := lockConv(convID)
+	defer unlock()
+
+	quota := GetQuota(ctx)
+	if quota.MaxFileSize > 0 && int64(len(data)) > quota.MaxFileSize {
+		return nil, fmt.Errorf("%w: file size %d exceeds limit %d", ErrQuotaExceeded, len(data), quota.MaxFileSize)
+	}
+
+	files, totalSize, err := listDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var existingSize int64
+	exists := false
+	for _, f := range files {
+		if f.Name == name {
+			existingSize, exists = f.Size, true
+			break
+		}
+	}
+	if !exists && quota.MaxFiles > 0 && len(files) >= quota.MaxFiles {
+		return nil, fmt.Errorf("%w: file count limit %d reached", ErrQuotaExceeded, quota.MaxFiles)
+	}
+	if quota.MaxTotalSize > 0 && totalSize-existingSize+int64(len(data)) > quota.MaxTotalSize {
+		return nil, fmt.Errorf("%w: total size limit %d reached", ErrQuotaExceeded, quota.MaxTotalSize)
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		g.Log().Errorf(ctx, "Failed to create workspace directory %s: %v", dir, err)
+		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免读到写了一半的文件
+	path := filepath.Join(dir, name)
+	tmpPath := filepath.Join(dir, "."+name+".tmp")
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write workspace file: %w", err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write workspace file: %w", err)
+	}
+
+	g.Log().Infof(ctx, "Workspace file written - ConvID: %s, Name: %s, Size: %d", convID, name, len(data))
+	return &FileInfo{Name: name, Size: int64(len(data)), ModTime: time.Now()}, nil
+}
+
+// ReadFile 读取会话工作区中的文件
+func ReadFile(ctx context.Context, convID string, name string) ([]byte, error) {
+	if err := validateName(name); err != nil {
+		return nil, fmt.Errorf("%w: %q", err, name)
+	}
+	dir, err := convDir(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to read workspace file: %w", err)
+	}
+	return data, nil
+}
+
+// ListFiles 列出会话工作区中的文件，返回文件列表和总大小
+func ListFiles(ctx context.Context, convID string) ([]*FileInfo, int64, error) {
+	dir, err := convDir(ctx, convID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return listDir(dir)
+}
+
+// DeleteFile 删除会话工作区中的文件
+func DeleteFile(ctx context.Context, convID string, name string) error {
+	if err := validateName(name); err != nil {
+		return fmt.Errorf("%w: %q", err, name)
+	}
+	dir, err := convDir(ctx, convID)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(filepath.Join(dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrFileNotFound, name)
+		}
+		return fmt.Errorf("failed to delete workspace file: %w", err)
+	}
+	return nil
+}
+
+// Clear 删除整个会话工作区（会话删除时调用）
+func Clear(ctx context.Context, convID string) error {
+	dir, err := convDir(ctx, convID)
+	if err != nil {
+		return err
+	}
+
+	unlock := lockConv(convID)
+	defer unlock()
+
+	if err = os.RemoveAll(dir); err != nil {
+		g.Log().Errorf(ctx, "Failed to clear workspace %s: %v", dir, err)
+		return err
+	}
+	convLocks.Delete(convID)
+	g.Log().Infof(ctx, "Workspace cleared - ConvID: %s", convID)
+	return nil
+}
+
+// ResolveReferences 将工具参数中 workspace://name 形式的字符串替换为对应文件内容
+func ResolveReferences(ctx context.Context, convID string, args map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		str, ok := value.(string)
+		if !ok || !strings.HasPrefix(str, RefPrefix) {
+			resolved[key] = value
+			continue
+		}
+		data, err := ReadFile(ctx, convID, strings.TrimPrefix(str, RefPrefix))
+		if err != nil {
+			return nil, err
+		}
+		resolved[key] = string(data)
+	}
+	return resolved, nil
+}
+
+// listDir 列出目录中的普通文件（忽略临时文件），目录不存在时返回空列表
+func listDir(dir string) ([]*FileInfo, int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*FileInfo{}, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to list workspace: %w", err)
+	}
+
+	files := make([]*FileInfo, 0, len(entries))
+	var totalSize int64
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, &FileInfo{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		totalSize += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, totalSize, nil
+}
@@ -0,0 +1,94 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// TestValidateName 测试工作区文件名校验
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "result.csv", valid: true},
+		{name: "报告_2024.md", valid: true},
+		{name: "", valid: false},
+		{name: "../secret", valid: false},
+		{name: "a/b.txt", valid: false},
+		{name: `a\b.txt`, valid: false},
+		{name: ".hidden", valid: false},
+		{name: "..", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateName(tt.name); (err == nil) != tt.valid {
+				t.Errorf("validateName(%q) error = %v, want valid %v", tt.name, err, tt.valid)
+			}
+		})
+	}
+}
+
+// TestWorkspaceQuota 测试读写、引用解析和配额限制
+func TestWorkspaceQuota(t *testing.T) {
+	ctx := context.Background()
+	adapter, err := gcfg.NewAdapterContent(fmt.Sprintf(
+		"workspace:\n  root: %q\n  maxFileSize: 8\n  maxTotalSize: 12\n  maxFiles: 2\n", t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	convID := "conv_test"
+	if _, err = WriteFile(ctx, convID, "a.txt", []byte("hello")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	args, err := ResolveReferences(ctx, convID, map[string]interface{}{"input": RefPrefix + "a.txt", "limit": 10})
+	if err != nil {
+		t.Fatalf("ResolveReferences() error = %v", err)
+	}
+	if args["input"] != "hello" || args["limit"] != 10 {
+		t.Errorf("ResolveReferences() = %v", args)
+	}
+
+	// 单文件超限
+	if _, err = WriteFile(ctx, convID, "big.txt", []byte("123456789")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected file size quota error, got %v", err)
+	}
+	// 总容量超限（5 + 8 > 12）
+	if _, err = WriteFile(ctx, convID, "b.txt", []byte("12345678")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected total size quota error, got %v", err)
+	}
+	// 覆盖已有文件只计算增量
+	if _, err = WriteFile(ctx, convID, "a.txt", []byte("12345678")); err != nil {
+		t.Errorf("overwrite error = %v", err)
+	}
+	if _, err = WriteFile(ctx, convID, "b.txt", []byte("1234")); err != nil {
+		t.Errorf("WriteFile() error = %v", err)
+	}
+	// 文件数超限
+	if _, err = WriteFile(ctx, convID, "c.txt", []byte("")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected file count quota error, got %v", err)
+	}
+
+	files, total, err := ListFiles(ctx, convID)
+	if err != nil || len(files) != 2 || total != 12 {
+		t.Errorf("ListFiles() = %d files, total %d, err %v", len(files), total, err)
+	}
+
+	if err = Clear(ctx, convID); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err = ReadFile(ctx, convID, "a.txt"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected not found after Clear, got %v", err)
+	}
+}
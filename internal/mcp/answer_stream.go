@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Malowking/kbgo/internal/logic/chat"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// toolCallHoldback 流式输出模型回答前先缓冲的字节数：模型可能在工具调用前输出一段说明，
+// 缓冲期间出现工具调用时丢弃这段内容，只有确定是最终答案的内容才输出给客户端
+const toolCallHoldback = 64
+
+type answerStreamKey struct{}
+
+// WithAnswerStream 在上下文中设置最终答案的输出函数，工具调用循环改为流式调用模型，最终答案的增量内容实时交给 fn
+func WithAnswerStream(ctx context.Context, fn func(delta string)) context.Context {
+	return context.WithValue(ctx, answerStreamKey{}, fn)
+}
+
+// answerStreamFromContext 获取上下文中的最终答案输出函数，没有时返回 nil
+func answerStreamFromContext(ctx context.Context) func(delta string) {
+	fn, _ := ctx.Value(answerStreamKey{}).(func(delta string))
+	return fn
+}
+
+// generate 调用模型，上下文中设置了最终答案输出函数时使用流式调用并转发回答内容
+func generate(ctx context.Context, chatInstance *chat.Chat, modelID string, messages []*schema.Message, tools []*schema.ToolInfo) (*schema.Message, error) {
+	sink := answerStreamFromContext(ctx)
+	if sink == nil {
+		return chatInstance.GenerateWithTools(ctx, modelID, messages, tools)
+	}
+
+	stream, err := chatInstance.GenerateWithToolsStream(ctx, modelID, messages, tools)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	relay := &answerRelay{sink: sink}
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("tool call stream ended without a final message")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if relay.feed(msg) {
+			if relay.leaked {
+				g.Log().Warningf(ctx, "模型在已输出的内容之后调用了工具，已输出的内容无法撤回")
+			}
+			return msg, nil
+		}
+	}
+}
+
+// answerRelay 转发一次流式调用中属于最终答案的内容
+type answerRelay struct {
+	sink        func(delta string)
+	pending     strings.Builder
+	forwarding  bool // 已超过缓冲长度，后续内容直接转发
+	toolCalling bool // 本次响应包含工具调用，不再转发内容
+	leaked      bool // 转发内容之后才出现工具调用
+}
+
+// feed 处理流中的一条消息，收到最后一条完整响应时返回 true
+func (r *answerRelay) feed(msg *schema.Message) bool {
+	final := msg.Extra != nil
+	if len(msg.ToolCalls) > 0 && !r.toolCalling {
+		r.toolCalling = true
+		r.leaked = r.forwarding
+		r.pending.Reset()
+	}
+	if final {
+		// 没有工具调用的短回答在结束时一次性输出
+		if !r.toolCalling && r.pending.Len() > 0 {
+			r.sink(r.pending.String())
+		}
+		return true
+	}
+	if r.toolCalling || msg.Content == "" {
+		return false
+	}
+	if r.forwarding {
+		r.sink(msg.Content)
+		return false
+	}
+	r.pending.WriteString(msg.Content)
+	if r.pending.Len() >= toolCallHoldback {
+		r.forwarding = true
+		r.sink(r.pending.String())
+		r.pending.Reset()
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestAnswerRelay(t *testing.T) {
+	long := strings.Repeat("a", toolCallHoldback)
+	toolCall := &schema.Message{ToolCalls: []schema.ToolCall{{ID: "call_1"}}}
+	final := &schema.Message{Extra: map[string]any{"tokens_used": 0}}
+	tests := []struct {
+		name       string
+		messages   []*schema.Message
+		want       string
+		wantLeaked bool
+	}{
+		{
+			name:     "short answer flushed at the end",
+			messages: []*schema.Message{{Content: "你好"}, {Content: "！"}, final},
+			want:     "你好！",
+		},
+		{
+			name:     "long answer forwarded after holdback",
+			messages: []*schema.Message{{Content: long}, {Content: "b"}, final},
+			want:     long + "b",
+		},
+		{
+			name:     "preamble before tool call dropped",
+			messages: []*schema.Message{{Content: "我来查询一下"}, toolCall, final},
+			want:     "",
+		},
+		{
+			name:       "tool call after forwarded content",
+			messages:   []*schema.Message{{Content: long}, toolCall, {Content: "c"}, final},
+			want:       long,
+			wantLeaked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			relay := &answerRelay{sink: func(delta string) { got.WriteString(delta) }}
+			for i, msg := range tt.messages {
+				if done := relay.feed(msg); done != (i == len(tt.messages)-1) {
+					t.Fatalf("feed(%d) = %v", i, done)
+				}
+			}
+			if got.String() != tt.want {
+				t.Errorf("forwarded %q, want %q", got.String(), tt.want)
+			}
+			if relay.leaked != tt.wantLeaked {
+				t.Errorf("leaked = %v, want %v", relay.leaked, tt.wantLeaked)
+			}
+		})
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
@@ -17,6 +19,9 @@ import (
 	"github.com/gogf/gf/v2/frame/g"
 )
 
+// ErrTimeout MCP 请求超时（HTTP 请求超过服务的超时时间或等待 SSE 响应超时）
+var ErrTimeout = errors.New("MCP request timed out")
+
 // MCPClient MCP 客户端
 type MCPClient struct {
 	registry      *gormModel.MCPRegistry
@@ -243,6 +248,9 @@ func (c *MCPClient) sendHTTPRequest(ctx context.Context, mcpReq MCPRequest) (*MC
 	// 发送请求
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
+		if os.IsTimeout(err) {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
 		return nil, fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
@@ -308,6 +316,9 @@ func (c *MCPClient) sendSSERequest(ctx context.Context, mcpReq MCPRequest) (*MCP
 	// 发送消息
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
+		if os.IsTimeout(err) {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
 		return nil, fmt.Errorf("failed to send message: %v", err)
 	}
 	defer resp.Body.Close()
@@ -328,9 +339,12 @@ func (c *MCPClient) sendSSERequest(ctx context.Context, mcpReq MCPRequest) (*MCP
 		}
 		return nil, fmt.Errorf("received nil response")
 	case <-ctx.Done():
-		return nil, fmt.Errorf("request timeout: %v", ctx.Err())
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, ctx.Err())
+		}
+		return nil, fmt.Errorf("request cancelled: %v", ctx.Err())
 	case <-time.After(30 * time.Second): // 额外的超时保护
-		return nil, fmt.Errorf("SSE response timeout")
+		return nil, fmt.Errorf("%w: SSE response timeout", ErrTimeout)
 	}
 }
 
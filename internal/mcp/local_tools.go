@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/google/uuid"
+)
+
+// LocalTool 在本进程内执行的自定义工具，与 MCP 工具一起提供给 LLM，工具名为 服务名__工具名
+// 编译进程序的工具在 init 中调用 RegisterLocalTool 注册，外部进程工具由 LoadPlugins 根据配置注册
+type LocalTool interface {
+	// Info 工具定义，Name 为不带服务前缀的工具名
+	Info() *schema.ToolInfo
+	// Call 执行工具，convID 为当前会话ID（可能为空）
+	Call(ctx context.Context, convID string, args map[string]interface{}) (string, error)
+}
+
+var (
+	localToolsMu sync.RWMutex
+	localTools   = make(map[string]map[string]LocalTool) // 服务名 -> 工具名 -> 工具
+)
+
+// RegisterLocalTool 注册本地工具，同一服务下工具名重复时返回错误
+func RegisterLocalTool(serviceName string, tool LocalTool) error {
+	info := tool.Info()
+	if info == nil || info.Name == "" {
+		return fmt.Errorf("本地工具缺少名称")
+	}
+	if serviceName == "" || strings.Contains(serviceName, "__") || strings.Contains(info.Name, "__") {
+		return fmt.Errorf("服务名和工具名不能为空且不能包含 __: %s/%s", serviceName, info.Name)
+	}
+	if serviceName == WorkspaceServiceName {
+		return fmt.Errorf("服务名 %s 为内置工作区工具保留", serviceName)
+	}
+
+	localToolsMu.Lock()
+	defer localToolsMu.Unlock()
+	if localTools[serviceName] == nil {
+		localTools[serviceName] = make(map[string]LocalTool)
+	}
+	if _, exists := localTools[serviceName][info.Name]; exists {
+		return fmt.Errorf("本地工具 %s__%s 已注册", serviceName, info.Name)
+	}
+	localTools[serviceName][info.Name] = tool
+	return nil
+}
+
+// UnregisterLocalService 移除服务下的所有本地工具（重新加载插件时使用）
+func UnregisterLocalService(serviceName string) {
+	localToolsMu.Lock()
+	defer localToolsMu.Unlock()
+	delete(localTools, serviceName)
+}
+
+// LocalServiceTools 本地工具服务（含内置工作区工具）提供的工具名，服务不存在时返回 nil
+func LocalServiceTools(serviceName string) []string {
+	if serviceName == WorkspaceServiceName {
+		return []string{workspaceToolWrite, workspaceToolRead, workspaceToolList}
+	}
+	localToolsMu.RLock()
+	defer localToolsMu.RUnlock()
+	var names []string
+	for name := range localTools[serviceName] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupLocalTool 查找本地工具
+func lookupLocalTool(serviceName, toolName string) LocalTool {
+	localToolsMu.RLock()
+	defer localToolsMu.RUnlock()
+	return localTools[serviceName][toolName]
+}
+
+// localLLMTools 获取本地工具定义，过滤规则与 MCP 工具相同：
+// serviceToolsFilter 不为 nil 时，只返回其中列出的服务的指定工具
+func localLLMTools(serviceToolsFilter map[string][]string) []*schema.ToolInfo {
+	localToolsMu.RLock()
+	defer localToolsMu.RUnlock()
+
+	var llmTools []*schema.ToolInfo
+	for serviceName, tools := range localTools {
+		var allowedTools []string
+		if serviceToolsFilter != nil {
+			var exists bool
+			if allowedTools, exists = serviceToolsFilter[serviceName]; !exists || len(allowedTools) == 0 {
+				continue
+			}
+		}
+		for toolName, tool := range tools {
+			if allowedTools != nil && !containsString(allowedTools, toolName) {
+				continue
+			}
+			info := *tool.Info()
+			info.Name = serviceName + "__" + toolName
+			llmTools = append(llmTools, &info)
+		}
+	}
+	// map 遍历无序，排序保证每次提供给 LLM 的工具顺序一致（利于模型侧的提示缓存）
+	sort.Slice(llmTools, func(i, j int) bool {
+		return llmTools[i].Name < llmTools[j].Name
+	})
+	return llmTools
+}
+
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// callLocalTool 调用本地工具，返回结构与 MCP 工具调用一致
+func callLocalTool(ctx context.Context, tool LocalTool, serviceName, toolName string, args map[string]interface{}, convID string) (*schema.Document, *v1.MCPResult, error) {
+	g.Log().Debugf(ctx, "调用本地工具: %s.%s，参数: %v", serviceName, toolName, args)
+
+	startTime := time.Now()
+	content, err := tool.Call(ctx, convID, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	g.Log().Debugf(ctx, "本地工具 %s.%s 完成，耗时 %v", serviceName, toolName, time.Since(startTime))
+
+	content = strings.TrimSpace(content)
+	doc := &schema.Document{
+		ID:      strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Content: content,
+		MetaData: map[string]interface{}{
+			"source":    "local_tool",
+			"service":   serviceName,
+			"tool":      toolName,
+			"tool_desc": tool.Info().Desc,
+		},
+	}
+	mcpResult := &v1.MCPResult{
+		ServiceName: serviceName,
+		ToolName:    toolName,
+		Content:     content,
+	}
+	return doc, mcpResult, nil
+}
@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+type echoTool struct {
+	name string
+}
+
+func (t *echoTool) Info() *schema.ToolInfo {
+	return &schema.ToolInfo{Name: t.name, Desc: "echo"}
+}
+
+func (t *echoTool) Call(ctx context.Context, convID string, args map[string]interface{}) (string, error) {
+	return convID, nil
+}
+
+func TestLocalToolRegistry(t *testing.T) {
+	defer UnregisterLocalService("test")
+
+	if err := RegisterLocalTool("test", &echoTool{name: "a"}); err != nil {
+		t.Fatalf("RegisterLocalTool() error = %v", err)
+	}
+	if err := RegisterLocalTool("test", &echoTool{name: "b"}); err != nil {
+		t.Fatalf("RegisterLocalTool() error = %v", err)
+	}
+
+	invalid := []struct {
+		name    string
+		service string
+		tool    string
+	}{
+		{name: "duplicate", service: "test", tool: "a"},
+		{name: "separator in tool name", service: "test", tool: "x__y"},
+		{name: "separator in service name", service: "te__st", tool: "c"},
+		{name: "reserved workspace service", service: WorkspaceServiceName, tool: "c"},
+		{name: "empty tool name", service: "test", tool: ""},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RegisterLocalTool(tt.service, &echoTool{name: tt.tool}); err == nil {
+				t.Errorf("RegisterLocalTool(%q, %q) expected error", tt.service, tt.tool)
+			}
+		})
+	}
+
+	filters := []struct {
+		name   string
+		filter map[string][]string
+		want   []string
+	}{
+		{name: "no filter", want: []string{"document__get_outline", "document__read_section", "document__summarize_document", "retrieval_view__search", "test__a", "test__b"}},
+		{name: "selected tool", filter: map[string][]string{"test": {"b"}}, want: []string{"test__b"}},
+		{name: "other service", filter: map[string][]string{"other": {"a"}}},
+	}
+	for _, tt := range filters {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, info := range localLLMTools(tt.filter) {
+				got = append(got, info.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("localLLMTools() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("localLLMTools() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if lookupLocalTool("test", "a") == nil || lookupLocalTool("test", "missing") != nil {
+		t.Error("lookupLocalTool() returned unexpected result")
+	}
+}
+
+func TestPluginTool(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	script := filepath.Join(t.TempDir(), "plugin.sh")
+	content := `#!/bin/sh
+read -r line
+case "$line" in
+  *'"method":"list"'*) echo '{"tools":[{"name":"greet","description":"打招呼","input_schema":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}}]}' ;;
+  *'"name":"fail"'*) echo '{"error":"bad name"}' ;;
+  *) echo '{"content":"hello"}' ;;
+esac
+`
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	plugin := &PluginConfig{Name: "plugin", Command: "sh", Args: []string{script}, Timeout: 10}
+	defer UnregisterLocalService(plugin.Name)
+
+	count, err := loadPlugin(ctx, plugin)
+	if err != nil || count != 1 {
+		t.Fatalf("loadPlugin() = %d, %v", count, err)
+	}
+	tool := lookupLocalTool("plugin", "greet")
+	if tool == nil {
+		t.Fatal("plugin tool not registered")
+	}
+	if tool.Info().ParamsOneOf == nil {
+		t.Error("plugin tool params not parsed")
+	}
+
+	doc, result, err := callLocalTool(ctx, tool, "plugin", "greet", map[string]interface{}{"name": "kbgo"}, "conv")
+	if err != nil {
+		t.Fatalf("callLocalTool() error = %v", err)
+	}
+	if result.Content != "hello" || doc.MetaData["tool_desc"] != "打招呼" {
+		t.Errorf("callLocalTool() = %q, %v", result.Content, doc.MetaData)
+	}
+
+	if _, err = tool.Call(ctx, "conv", map[string]interface{}{"name": "fail"}); err == nil || err.Error() != "bad name" {
+		t.Errorf("Call() error = %v, want bad name", err)
+	}
+}
@@ -3,13 +3,20 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
 	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/logic/budget"
 	"github.com/Malowking/kbgo/internal/logic/chat"
+	"github.com/Malowking/kbgo/internal/logic/quota"
+	"github.com/Malowking/kbgo/internal/logic/toolexample"
+	"github.com/Malowking/kbgo/internal/logic/workspace"
 	"github.com/Malowking/kbgo/internal/mcp/client"
 	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
 	"github.com/Malowking/kbgo/pkg/schema"
@@ -29,6 +36,14 @@ type MCPToolCaller struct {
 	services map[string]*MCPServiceClient // 服务名 -> 服务客户端
 }
 
+// toolCallingSystemPrompt 工具调用的 system 提示词
+const toolCallingSystemPrompt = "你是一个智能助手，可以使用工具来帮助回答用户问题。\n" +
+	"规则：\n" +
+	"1. 根据用户问题判断是否需要使用工具\n" +
+	"2. 如果需要工具，选择最合适的工具并提供正确的参数\n" +
+	"3. 如果不需要工具，直接回答问题\n" +
+	"4. 收到工具执行结果后，基于结果生成最终答案"
+
 // NewMCPToolCaller 创建 MCP 工具调用器
 func NewMCPToolCaller(ctx context.Context) (*MCPToolCaller, error) {
 	// 获取所有启用的MCP服务
@@ -57,45 +72,22 @@ func NewMCPToolCaller(ctx context.Context) (*MCPToolCaller, error) {
 			continue
 		}
 
-		// 获取工具列表
-		var tools []client.MCPTool
-
-		// 首先尝试从数据库缓存中获取
-		if registry.Tools != "" && registry.Tools != "[]" {
-			var toolInfos []v1.MCPToolInfo
-			if err := json.Unmarshal([]byte(registry.Tools), &toolInfos); err == nil {
-				tools = make([]client.MCPTool, len(toolInfos))
-				for i, info := range toolInfos {
-					tools[i] = client.MCPTool{
-						Name:        info.Name,
-						Description: info.Description,
-						InputSchema: info.InputSchema,
-					}
-				}
-			}
-		}
+		// 获取工具列表：优先使用数据库缓存，已弃用和参数定义无效的工具不提供给 LLM
+		cached := ParseCachedTools(registry.Tools)
+		tools := usableTools(cached)
 
-		// 如果缓存中没有，从远程获取
-		if len(tools) == 0 {
-			tools, err = mcpClient.ListTools(ctx)
+		// 缓存中没有工具时从远程获取并更新缓存
+		if len(cached) == 0 {
+			live, err := mcpClient.ListTools(ctx)
 			if err != nil {
 				g.Log().Errorf(ctx, "Failed to list tools for service %s: %v", registry.Name, err)
 				continue
 			}
-
-			// 更新缓存
-			if len(tools) > 0 {
-				toolInfos := make([]v1.MCPToolInfo, len(tools))
-				for i, tool := range tools {
-					toolInfos[i] = v1.MCPToolInfo{
-						Name:        tool.Name,
-						Description: tool.Description,
-						InputSchema: tool.InputSchema,
-					}
+			if len(live) > 0 {
+				if _, err := SaveLiveTools(ctx, registry, live); err != nil {
+					g.Log().Warningf(ctx, "Failed to cache tools for service %s: %v", registry.Name, err)
 				}
-				toolsJSON, _ := json.Marshal(toolInfos)
-				registry.Tools = string(toolsJSON)
-				dao.MCPRegistry.Update(ctx, registry)
+				tools = usableTools(ParseCachedTools(registry.Tools))
 			}
 		}
 
@@ -167,74 +159,89 @@ func (tc *MCPToolCaller) convertMCPToolToLLMTool(serviceName string, mcpTool cli
 		Desc: mcpTool.Description,
 	}
 
-	// 如果有 InputSchema，将其转换为 ParameterInfo map
-	if mcpTool.InputSchema != nil && len(mcpTool.InputSchema) > 0 {
-		params := make(map[string]*schema.ParameterInfo)
+	toolInfo.ParamsOneOf = paramsFromInputSchema(mcpTool.InputSchema)
 
-		// 从 InputSchema 中提取 properties
-		if properties, ok := mcpTool.InputSchema["properties"].(map[string]interface{}); ok {
-			for paramName, paramDefRaw := range properties {
-				if paramDef, ok := paramDefRaw.(map[string]interface{}); ok {
-					paramInfo := &schema.ParameterInfo{}
+	return toolInfo
+}
 
-					// 设置类型
-					if typeStr, ok := paramDef["type"].(string); ok {
-						paramInfo.Type = typeStr
-					}
+// paramsFromInputSchema 将 JSON Schema 形式的工具参数定义转换为 ParamsOneOf，没有参数时返回 nil
+func paramsFromInputSchema(inputSchema map[string]interface{}) *schema.ParamsOneOf {
+	if len(inputSchema) == 0 {
+		return nil
+	}
+	params := make(map[string]*schema.ParameterInfo)
 
-					// 设置描述
-					if desc, ok := paramDef["description"].(string); ok {
-						paramInfo.Desc = desc
-					}
+	// 从 InputSchema 中提取 properties
+	if properties, ok := inputSchema["properties"].(map[string]interface{}); ok {
+		for paramName, paramDefRaw := range properties {
+			if paramDef, ok := paramDefRaw.(map[string]interface{}); ok {
+				paramInfo := &schema.ParameterInfo{}
+
+				// 设置类型
+				if typeStr, ok := paramDef["type"].(string); ok {
+					paramInfo.Type = typeStr
+				}
+
+				// 设置描述
+				if desc, ok := paramDef["description"].(string); ok {
+					paramInfo.Desc = desc
+				}
 
-					// 设置是否必需
-					if required, ok := mcpTool.InputSchema["required"].([]interface{}); ok {
-						for _, req := range required {
-							if reqName, ok := req.(string); ok && reqName == paramName {
-								paramInfo.Required = true
-								break
-							}
+				// 设置是否必需
+				if required, ok := inputSchema["required"].([]interface{}); ok {
+					for _, req := range required {
+						if reqName, ok := req.(string); ok && reqName == paramName {
+							paramInfo.Required = true
+							break
 						}
 					}
-
-					params[paramName] = paramInfo
 				}
-			}
-		}
 
-		// 如果成功解析了参数，使用 NewParamsOneOfByParams
-		if len(params) > 0 {
-			toolInfo.ParamsOneOf = schema.NewParamsOneOfByParams(params)
+				params[paramName] = paramInfo
+			}
 		}
 	}
 
-	return toolInfo
+	// 如果成功解析了参数，使用 NewParamsOneOfByParams
+	if len(params) == 0 {
+		return nil
+	}
+	return schema.NewParamsOneOfByParams(params)
 }
 
 // CallToolsWithLLM 使用 LLM 智能选择并调用工具
+// userQuestion: 用户原始问题，用于工具策略的条件判断（question 可能附带检索结果等上下文）
 // serviceToolsFilter: 如果不为 nil，则只允许 LLM 调用指定服务的指定工具
-func (tc *MCPToolCaller) CallToolsWithLLM(ctx context.Context, modelID string, question string, convID string, serviceToolsFilter map[string][]string) ([]*schema.Document, []*v1.MCPResult, error) {
+func (tc *MCPToolCaller) CallToolsWithLLM(ctx context.Context, modelID string, question string, userQuestion string, convID string, serviceToolsFilter map[string][]string) ([]*schema.Document, []*v1.MCPResult, error) {
 	// 1. 准备工具列表（根据过滤器）
 	llmTools := tc.GetAllLLMTools(serviceToolsFilter)
+	llmTools = append(llmTools, localLLMTools(serviceToolsFilter)...)
 	if len(llmTools) == 0 {
 		g.Log().Info(ctx, "没有可用的MCP工具")
 		return nil, nil, nil
 	}
 
+	// 有会话ID时附加内置工作区工具，工具间可通过工作区文件跨轮次传递中间结果
+	if convID != "" {
+		llmTools = append(llmTools, workspaceLLMTools(serviceToolsFilter)...)
+	}
+
 	g.Log().Infof(ctx, "准备 %d 个 MCP 工具", len(llmTools))
 
-	// 2. 构建初始消息
-	systemPrompt := "你是一个智能助手，可以使用工具来帮助回答用户问题。\n" +
-		"规则：\n" +
-		"1. 根据用户问题判断是否需要使用工具\n" +
-		"2. 如果需要工具，选择最合适的工具并提供正确的参数\n" +
-		"3. 如果不需要工具，直接回答问题\n" +
-		"4. 收到工具执行结果后，基于结果生成最终答案"
+	// 工具使用策略：根据模型、用户问题和已成功调用的工具决定每轮可用的工具
+	policy := LoadToolPolicy(ctx)
+	policyState := policy.newState(ctx, modelID, userQuestion, convID)
+
+	// 2. 构建初始消息（附加与用户问题相似的工具调用示例）
+	examples := toolexample.Select(ctx, modelID, userQuestion, llmToolNames(llmTools))
+	if len(examples) > 0 {
+		g.Log().Infof(ctx, "注入 %d 个工具调用示例", len(examples))
+	}
 
 	messages := []*schema.Message{
 		{
 			Role:    schema.System,
-			Content: systemPrompt,
+			Content: toolCallingSystemPrompt + toolexample.Prompt(examples),
 		},
 		{
 			Role:    schema.User,
@@ -242,105 +249,130 @@ func (tc *MCPToolCaller) CallToolsWithLLM(ctx context.Context, modelID string, q
 		},
 	}
 
-	// 3. 调用 LLM（最多循环 5 次以支持多轮工具调用）
+	// 执行摘要：调用的工具、用时、返回行数、生成的文件和消耗的 token
+	run := agentrun.FromContext(ctx)
+	run.Start()
+	defer run.Finish()
+
+	// 工具调用超时：单个工具调用和整个工具调用阶段分别计时，阶段超时后基于已有的工具结果生成最终答案
+	timeouts := LoadToolTimeouts(ctx)
+	timeouts.Tools = tc.toolTimeouts()
+	execCtx := ctx
+	if timeouts.Total > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(ctx, timeouts.Total)
+		defer cancel()
+	}
+
+	// token 守卫：下一轮的输入预计超出模型上下文窗口或累计 token、费用上限时不再调用工具，直接生成最终答案
+	guard := LoadToolGuard(ctx).newState(modelID)
+
+	// 3. 调用 LLM（多轮工具调用，最大轮数见 iterationLimit），模型反复以相同参数调用同一工具时提前结束
 	chatInstance := chat.GetChat()
-	maxIterations := 5
+	maxIterations := iterationLimit(ctx)
+	loops := newLoopDetector(ctx)
 	var allDocuments []*schema.Document
 	var allMCPResults []*v1.MCPResult
 	var finalAnswer string                    // 保存 LLM 的最终文本回答
 	var toolCallLogs []map[string]interface{} // 记录工具调用日志
 
 	for iteration := 0; iteration < maxIterations; iteration++ {
+		// 每轮调用前重新评估策略，上一轮的工具结果可能解锁新的工具
+		allowedTools, denied := policy.Filter(policyState, llmTools)
+		for _, decision := range denied {
+			g.Log().Infof(ctx, "[工具策略] 第 %d 轮禁止工具 %s，规则: %s，条件: %s",
+				iteration+1, decision.Tool, decision.Rule, decision.Reason)
+		}
+
+		prompt, stopReason, detail := guard.check(messages, allowedTools)
+		if stopReason != "" {
+			g.Log().Warningf(ctx, "[token 守卫] 第 %d 轮前结束工具调用（%s），尝试获取最终答案", iteration+1, detail)
+			run.Stop(stopReason)
+			finalAnswer = forceFinalAnswer(ctx, chatInstance, modelID, guard.fitFinalAnswer(messages))
+			break
+		}
+
 		// 调用 LLM
-		response, err := chatInstance.GenerateWithTools(ctx, modelID, messages, llmTools)
+		response, err := generate(execCtx, chatInstance, modelID, messages, allowedTools)
 		if err != nil {
+			if execCtx.Err() != nil && ctx.Err() == nil {
+				g.Log().Warningf(ctx, "工具调用阶段超过 %s，第 %d 轮停止调用工具，尝试获取最终答案", timeouts.Total, iteration+1)
+				run.Stop(StopTimeout)
+				finalAnswer = forceFinalAnswer(ctx, chatInstance, modelID, guard.fitFinalAnswer(messages))
+				break
+			}
 			return nil, nil, fmt.Errorf("LLM 调用失败: %w", err)
 		}
 
-		// 将 LLM 响应添加到消息历史
-		messages = append(messages, response)
+		run.AddIteration(tokensUsed(response))
+		run.RecordPrompt(prompt)
+		guard.record(prompt, tokensUsed(response))
 
 		// 4. 检查是否有工具调用
 		if len(response.ToolCalls) == 0 {
 			// 没有工具调用，LLM 已经给出最终答案
+			messages = append(messages, response)
 			finalAnswer = response.Content
 			g.Log().Infof(ctx, "LLM 未调用任何工具，给出最终答案（长度: %d）", len(finalAnswer))
 			break
 		}
 
+		// 重复调用：不执行本轮工具调用（也不加入消息历史），提示模型基于已有结果直接回答
+		if toolName, count := loops.observe(response.ToolCalls); toolName != "" {
+			g.Log().Warningf(ctx, "第 %d 轮检测到重复的工具调用：%s 已使用相同参数请求 %d 次，尝试获取最终答案", iteration+1, toolName, count)
+			run.Stop(StopLoopDetected)
+			finalAnswer = forceFinalAnswer(ctx, chatInstance, modelID, guard.fitFinalAnswer(append(messages, loopNote(toolName, count))))
+			break
+		}
+
+		// 将 LLM 响应添加到消息历史
+		messages = append(messages, response)
+
 		// 5. 执行所有工具调用
 		g.Log().Infof(ctx, "调用 %d 个工具", len(response.ToolCalls))
 
-		for idx, toolCall := range response.ToolCalls {
-			// 解析工具名（格式：serviceName__toolName）
-			serviceName, toolName := client.ParseToolName(toolCall.Function.Name)
-
-			// 解析参数
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-				errMsg := fmt.Sprintf("参数解析错误: %v", err)
-				g.Log().Errorf(ctx, "[工具 %d/%d] %s", idx+1, len(response.ToolCalls), errMsg)
-
-				// 添加错误响应到消息历史
-				messages = append(messages, &schema.Message{
-					Role:       schema.Tool,
-					Content:    errMsg,
-					ToolCallID: toolCall.ID,
-				})
-				continue
-			}
+		// 同一轮的多个工具调用可并发执行，结果按工具调用的顺序写入消息历史
+		round := &toolRound{
+			execCtx:     execCtx,
+			convID:      convID,
+			run:         run,
+			timeouts:    timeouts,
+			total:       len(response.ToolCalls),
+			policy:      policy,
+			policyState: policyState,
+		}
+		concurrency := toolConcurrency(ctx, response.ToolCalls, policy)
+		if concurrency > 1 && len(response.ToolCalls) > 1 {
+			g.Log().Infof(ctx, "并发执行 %d 个工具调用（并发数 %d）", len(response.ToolCalls), concurrency)
+		}
+		outcomes := make([]*toolOutcome, len(response.ToolCalls))
+		runConcurrently(ctx, len(response.ToolCalls), concurrency, func(idx int) {
+			toolCall := response.ToolCalls[idx]
+			outcomes[idx] = safeToolOutcome(ctx, round, idx, toolCall, func() *toolOutcome {
+				return tc.executeToolCall(ctx, round, idx, toolCall)
+			})
+		})
 
-			// 调用工具
-			result, mcpResult, err := tc.callSingleTool(ctx, serviceName, toolName, args, convID)
-			if err != nil {
-				errMsg := fmt.Sprintf("工具调用失败: %v", err)
-				g.Log().Errorf(ctx, "[工具 %d/%d] %s", idx+1, len(response.ToolCalls), errMsg)
-
-				// 添加错误响应到消息历史
-				messages = append(messages, &schema.Message{
-					Role:       schema.Tool,
-					Content:    errMsg,
-					ToolCallID: toolCall.ID,
-				})
+		for _, outcome := range outcomes {
+			// 【关键】将工具执行结果添加到消息历史，供 LLM 下次调用时使用
+			messages = append(messages, outcome.message)
+			if outcome.document == nil {
 				continue
 			}
 
 			// 收集结果
-			allDocuments = append(allDocuments, result)
-			if mcpResult != nil {
-				allMCPResults = append(allMCPResults, mcpResult)
-			}
-
-			// 记录工具调用日志
-			toolCallLog := map[string]interface{}{
-				"service_name": serviceName,
-				"tool_name":    toolName,
-				"arguments":    args,
-				"result":       mcpResult.Content,
+			allDocuments = append(allDocuments, outcome.document)
+			if outcome.mcpResult != nil {
+				allMCPResults = append(allMCPResults, outcome.mcpResult)
 			}
-			toolCallLogs = append(toolCallLogs, toolCallLog)
-
-			// 【关键】将工具执行结果添加到消息历史，供 LLM 下次调用时使用
-			toolResultMsg := &schema.Message{
-				Role:       schema.Tool,
-				Content:    mcpResult.Content,
-				ToolCallID: toolCall.ID,
-			}
-			messages = append(messages, toolResultMsg)
+			toolCallLogs = append(toolCallLogs, outcome.log)
 		}
 
-		// 如果这是最后一次迭代，需要再调用一次 LLM 让它基于工具结果给出最终答案
-		if iteration == maxIterations-1 {
-			g.Log().Warning(ctx, "达到最大工具调用迭代次数，尝试获取最终答案")
-
-			// 最后一次调用 LLM，不再提供工具（强制它给出最终答案）
-			finalResponse, err := chatInstance.GenerateWithTools(ctx, modelID, messages, nil)
-			if err != nil {
-				g.Log().Errorf(ctx, "获取最终答案失败: %v", err)
-			} else {
-				finalAnswer = finalResponse.Content
-				g.Log().Debugf(ctx, "获取到最终答案（长度: %d）", len(finalAnswer))
-			}
+		// 如果这是最后一次迭代、工具调用阶段已超时或延迟预算不足以再进行一轮，需要再调用一次 LLM 让它基于工具结果给出最终答案
+		if stopReason = loopStopReason(ctx, execCtx, iteration, maxIterations); stopReason != "" {
+			g.Log().Warningf(ctx, "第 %d 轮后结束工具调用（最多 %d 轮，原因: %s），尝试获取最终答案", iteration+1, maxIterations, stopReason)
+			run.Stop(stopReason)
+			finalAnswer = forceFinalAnswer(ctx, chatInstance, modelID, guard.fitFinalAnswer(messages))
 			break
 		}
 	}
@@ -377,6 +409,157 @@ func (tc *MCPToolCaller) CallToolsWithLLM(ctx context.Context, modelID string, q
 	return allDocuments, allMCPResults, nil
 }
 
+// executeToolCall 执行 LLM 返回的一个工具调用，并发执行时在独立的 goroutine 中调用
+func (tc *MCPToolCaller) executeToolCall(ctx context.Context, round *toolRound, idx int, toolCall schema.ToolCall) *toolOutcome {
+	// 解析工具名（格式：serviceName__toolName）
+	serviceName, toolName := client.ParseToolName(toolCall.Function.Name)
+	toolStart := time.Now()
+	reply := func(content string) *toolOutcome {
+		return &toolOutcome{message: &schema.Message{
+			Role:       schema.Tool,
+			Content:    content,
+			ToolCallID: toolCall.ID,
+		}}
+	}
+
+	// 工具调用阶段已超时，剩余的工具不再执行
+	if round.execCtx.Err() != nil {
+		errMsg := fmt.Sprintf("工具调用阶段已超过 %s，未执行工具 %s", round.timeouts.Total, toolCall.Function.Name)
+		g.Log().Warningf(ctx, "[工具 %d/%d] %s", idx+1, round.total, errMsg)
+		round.run.RecordTool(serviceName, toolName, agentrun.StatusTimeout, 0, "", errors.New(errMsg))
+		return reply(errMsg)
+	}
+
+	// 解析参数
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		errMsg := fmt.Sprintf("参数解析错误: %v", err)
+		g.Log().Errorf(ctx, "[工具 %d/%d] %s", idx+1, round.total, errMsg)
+		round.run.RecordTool(serviceName, toolName, agentrun.StatusError, time.Since(toolStart), "", err)
+		return reply(errMsg)
+	}
+
+	// LLM 调用了本轮未提供的工具时同样按策略拒绝
+	if decision := round.check(toolCall.Function.Name); decision != nil {
+		errMsg := fmt.Sprintf("工具 %s 被策略 %s 禁止（%s）", toolCall.Function.Name, decision.Rule, decision.Reason)
+		g.Log().Warningf(ctx, "[工具 %d/%d] %s", idx+1, round.total, errMsg)
+		round.run.RecordTool(serviceName, toolName, agentrun.StatusDenied, 0, "", errors.New(errMsg))
+		return reply(errMsg)
+	}
+
+	// 内置工作区工具在本地执行
+	if serviceName == WorkspaceServiceName {
+		content, err := callWorkspaceTool(ctx, round.convID, toolName, args)
+		if err != nil {
+			round.run.RecordTool(serviceName, toolName, agentrun.StatusError, time.Since(toolStart), "", err)
+			content = fmt.Sprintf("工具调用失败: %v", err)
+			g.Log().Errorf(ctx, "[工具 %d/%d] %s", idx+1, round.total, content)
+		} else {
+			round.run.RecordTool(serviceName, toolName, agentrun.StatusSuccess, time.Since(toolStart), "", nil)
+			if name, _ := args["name"].(string); toolName == workspaceToolWrite && name != "" {
+				round.run.RecordFile(name)
+			}
+			round.recordSuccess(toolCall.Function.Name)
+		}
+		return reply(content)
+	}
+
+	// 将参数中 workspace://文件名 的引用替换为文件内容
+	if round.convID != "" {
+		var err error
+		args, err = workspace.ResolveReferences(ctx, round.convID, args)
+		if err != nil {
+			errMsg := fmt.Sprintf("工作区文件引用解析失败: %v", err)
+			g.Log().Errorf(ctx, "[工具 %d/%d] %s", idx+1, round.total, errMsg)
+			round.run.RecordTool(serviceName, toolName, agentrun.StatusError, time.Since(toolStart), "", err)
+			return reply(errMsg)
+		}
+	}
+
+	// 调用工具（本地注册的工具在进程内或插件进程中执行），超时或工具调用阶段结束时取消
+	timeout := round.timeouts.For(toolCall.Function.Name)
+	result, mcpResult, err := callWithTimeout(round.execCtx, toolCall.Function.Name, timeout,
+		func(ctx context.Context) (*schema.Document, *v1.MCPResult, error) {
+			if localTool := lookupLocalTool(serviceName, toolName); localTool != nil {
+				return callLocalTool(ctx, localTool, serviceName, toolName, args, round.convID)
+			}
+			return tc.callSingleTool(ctx, serviceName, toolName, args, round.convID)
+		})
+	// 单个工具超时（工具调用阶段仍未结束）时返回结构化的超时结果，由 LLM 决定重试、改用其他工具或直接回答
+	if err != nil && round.execCtx.Err() == nil && (errors.Is(err, ErrToolTimeout) || errors.Is(err, client.ErrTimeout)) {
+		if !errors.Is(err, ErrToolTimeout) {
+			timeout = tc.serviceTimeout(serviceName)
+		}
+		round.run.RecordTool(serviceName, toolName, agentrun.StatusTimeout, time.Since(toolStart), "", err)
+		g.Log().Warningf(ctx, "[工具 %d/%d] 工具调用超时: %v", idx+1, round.total, err)
+		return reply(toolTimeoutMessage(toolCall.Function.Name, timeout))
+	}
+	if err != nil {
+		status := agentrun.StatusError
+		if errors.Is(err, ErrToolTimeout) || round.execCtx.Err() != nil {
+			status = agentrun.StatusTimeout
+		}
+		round.run.RecordTool(serviceName, toolName, status, time.Since(toolStart), "", err)
+		errMsg := fmt.Sprintf("工具调用失败: %v", err)
+		g.Log().Errorf(ctx, "[工具 %d/%d] %s", idx+1, round.total, errMsg)
+		return reply(errMsg)
+	}
+
+	round.recordSuccess(toolCall.Function.Name)
+	round.run.RecordTool(serviceName, toolName, agentrun.StatusSuccess, time.Since(toolStart), mcpResult.Content, nil)
+
+	return &toolOutcome{
+		message: &schema.Message{
+			Role:       schema.Tool,
+			Content:    mcpResult.Content,
+			ToolCallID: toolCall.ID,
+		},
+		document:  result,
+		mcpResult: mcpResult,
+		// 记录工具调用日志
+		log: map[string]interface{}{
+			"service_name": serviceName,
+			"tool_name":    toolName,
+			"arguments":    args,
+			"result":       mcpResult.Content,
+		},
+	}
+}
+
+// loopStopReason 一轮工具调用结束后是否还能进行下一轮，不能时返回原因：已是最后一轮、工具调用阶段已超时或延迟预算不足
+func loopStopReason(ctx, execCtx context.Context, iteration, maxIterations int) string {
+	switch {
+	case iteration == maxIterations-1:
+		return StopMaxIterations
+	case execCtx.Err() != nil:
+		return StopTimeout
+	case !budget.FromContext(ctx).ContinueToolCalls(ctx):
+		return StopLatencyBudget
+	}
+	return ""
+}
+
+// forceFinalAnswer 最后一次调用 LLM，不再提供工具（强制它基于已有的工具结果给出最终答案），失败时返回空字符串
+func forceFinalAnswer(ctx context.Context, chatInstance *chat.Chat, modelID string, messages []*schema.Message) string {
+	finalResponse, err := generate(ctx, chatInstance, modelID, messages, nil)
+	if err != nil {
+		g.Log().Errorf(ctx, "获取最终答案失败: %v", err)
+		return ""
+	}
+	agentrun.FromContext(ctx).AddTokens(tokensUsed(finalResponse))
+	g.Log().Debugf(ctx, "获取到最终答案（长度: %d）", len(finalResponse.Content))
+	return finalResponse.Content
+}
+
+// tokensUsed 读取 GenerateWithTools 或 GenerateWithToolsStream 返回消息中记录的 token 消耗
+func tokensUsed(msg *schema.Message) int {
+	if msg == nil {
+		return 0
+	}
+	tokens, _ := msg.Extra["tokens_used"].(int)
+	return tokens
+}
+
 // callSingleTool 调用单个工具
 func (tc *MCPToolCaller) callSingleTool(
 	ctx context.Context,
@@ -393,6 +576,11 @@ func (tc *MCPToolCaller) callSingleTool(
 
 	g.Log().Debugf(ctx, "调用 MCP 工具: %s.%s，参数: %v", serviceName, toolName, arguments)
 
+	// 项目配额：每日工具调用次数
+	if err := quota.CheckToolCall(ctx); err != nil {
+		return nil, nil, err
+	}
+
 	startTime := time.Now()
 
 	// 调用工具
@@ -410,6 +598,9 @@ func (tc *MCPToolCaller) callSingleTool(
 	errorMsg := ""
 	if err != nil {
 		logStatus = 0 // 失败
+		if errors.Is(err, client.ErrTimeout) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			logStatus = 2 // 超时
+		}
 		errorMsg = err.Error()
 	}
 
@@ -427,7 +618,8 @@ func (tc *MCPToolCaller) callSingleTool(
 		Duration:        duration,
 	}
 
-	if logErr := dao.MCPCallLog.Create(ctx, callLog); logErr != nil {
+	// 工具调用超时后上下文已取消，调用日志仍需写入
+	if logErr := dao.MCPCallLog.Create(common.DetachContext(ctx), callLog); logErr != nil {
 		g.Log().Errorf(ctx, "创建 MCP 调用日志失败: %v", logErr)
 	}
 
@@ -474,6 +666,39 @@ func (tc *MCPToolCaller) callSingleTool(
 	return doc, mcpResult, nil
 }
 
+// PreviewToolCalls 只让 LLM 为问题选择工具并生成参数，不执行工具，用于测试工具调用示例的效果
+// withExamples 为 false 时不注入示例，便于对比；返回 LLM 的工具调用和注入的示例
+func (tc *MCPToolCaller) PreviewToolCalls(ctx context.Context, modelID string, question string, serviceToolsFilter map[string][]string, withExamples bool) ([]schema.ToolCall, []*gormModel.ToolExample, error) {
+	llmTools := tc.GetAllLLMTools(serviceToolsFilter)
+	llmTools = append(llmTools, localLLMTools(serviceToolsFilter)...)
+	if len(llmTools) == 0 {
+		return nil, nil, nil
+	}
+
+	var examples []*gormModel.ToolExample
+	if withExamples {
+		examples = toolexample.Select(ctx, modelID, question, llmToolNames(llmTools))
+	}
+	messages := []*schema.Message{
+		{Role: schema.System, Content: toolCallingSystemPrompt + toolexample.Prompt(examples)},
+		{Role: schema.User, Content: question},
+	}
+	response, err := chat.GetChat().GenerateWithTools(ctx, modelID, messages, llmTools)
+	if err != nil {
+		return nil, nil, fmt.Errorf("LLM 调用失败: %w", err)
+	}
+	return response.ToolCalls, examples, nil
+}
+
+// llmToolNames LLM 工具名列表
+func llmToolNames(tools []*schema.ToolInfo) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
 // Close 关闭所有 MCP 客户端连接
 func (tc *MCPToolCaller) Close() {
 	for _, service := range tc.services {
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Malowking/kbgo/internal/logic/outline"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	documentToolOutline     = "get_outline"
+	documentToolReadSection = "read_section"
+
+	// defaultMaxSectionChars 单次返回的章节内容最大字符数
+	defaultMaxSectionChars = 8000
+)
+
+func init() {
+	for _, tool := range []LocalTool{&outlineTool{}, &readSectionTool{}} {
+		if err := RegisterLocalTool(DocumentServiceName, tool); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var documentIDParam = &schema.ParameterInfo{Type: "string", Desc: "知识库文档ID（可选），为空时使用会话中上传的文档"}
+
+// outlineTool 返回文档的标题目录
+type outlineTool struct{}
+
+func (t *outlineTool) Info() *schema.ToolInfo {
+	return &schema.ToolInfo{
+		Name: documentToolOutline,
+		Desc: "获取文档的标题目录。用户询问某一章节（如\"第三章讲了什么\"）时，先用此工具查看目录，再用 read_section 读取对应章节",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"document_id": documentIDParam,
+		}),
+	}
+}
+
+func (t *outlineTool) Call(ctx context.Context, convID string, args map[string]interface{}) (string, error) {
+	documentID, _ := args["document_id"].(string)
+	name, sections, err := outline.LoadOutline(ctx, convID, documentID)
+	if err != nil {
+		return "", err
+	}
+	if len(sections) == 0 {
+		return fmt.Sprintf("文档《%s》中没有识别到标题", name), nil
+	}
+	return fmt.Sprintf("文档《%s》的目录：\n%s", name, outline.Render(sections)), nil
+}
+
+// readSectionTool 按标题路径读取章节内容
+type readSectionTool struct{}
+
+func (t *readSectionTool) Info() *schema.ToolInfo {
+	return &schema.ToolInfo{
+		Name: documentToolReadSection,
+		Desc: "按标题读取文档中某一章节的完整内容（包含子章节）。标题可以写完整路径，各级用 > 分隔，如 \"第三章 部署 > 3.2 配置\"",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"section":     {Type: "string", Desc: "章节标题或标题路径", Required: true},
+			"document_id": documentIDParam,
+		}),
+	}
+}
+
+func (t *readSectionTool) Call(ctx context.Context, convID string, args map[string]interface{}) (string, error) {
+	path, _ := args["section"].(string)
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("缺少章节标题")
+	}
+	documentID, _ := args["document_id"].(string)
+	doc, err := outline.LoadDocument(ctx, convID, documentID)
+	if err != nil {
+		return "", err
+	}
+	section := outline.Find(doc.Sections, path)
+	if section == nil {
+		return fmt.Sprintf("文档《%s》中没有找到章节 \"%s\"，目录：\n%s", doc.Name, path, outline.Render(doc.Sections)), nil
+	}
+
+	content := strings.TrimSpace(doc.Text[section.Start:section.End])
+	maxChars := g.Cfg().MustGet(ctx, "outline.maxSectionChars", defaultMaxSectionChars).Int()
+	if maxChars > 0 && utf8.RuneCountInString(content) > maxChars {
+		content = string([]rune(content)[:maxChars]) + "\n……（章节内容过长，已截断，可读取其中的子章节）"
+	}
+	return fmt.Sprintf("章节：%s\n\n%s", section.PathString(), content), nil
+}
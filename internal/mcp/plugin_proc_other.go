@@ -0,0 +1,8 @@
+//go:build !unix
+
+package mcp
+
+import "os/exec"
+
+// setProcessGroup 非 Unix 平台超时时只终止插件进程本身，由 WaitDelay 保证不会等待仍持有输出管道的子进程
+func setProcessGroup(cmd *exec.Cmd) {}
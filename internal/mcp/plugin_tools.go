@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 插件协议：每次请求启动一次插件进程，向 stdin 写入一个 JSON 请求，从 stdout 读取一个 JSON 响应
+//
+//	{"method":"list"}
+//	  -> {"tools":[{"name":"...","description":"...","input_schema":{JSON Schema}}]}
+//	{"method":"call","tool":"...","arguments":{...},"conv_id":"..."}
+//	  -> {"content":"..."} 或 {"error":"..."}
+//
+// 进程退出码非 0 时视为失败，stderr 内容作为错误信息
+const (
+	pluginMethodList = "list"
+	pluginMethodCall = "call"
+
+	// maxPluginOutput 插件输出的最大字节数
+	maxPluginOutput = 4 << 20
+	// defaultPluginTimeout 插件单次调用的默认超时（秒）
+	defaultPluginTimeout = 30
+	// pluginWaitDelay 插件退出或超时被终止后，等待输出管道关闭的最长时间；
+	// 插件启动的子进程继承了 stdout/stderr 时，避免一直阻塞到子进程退出
+	pluginWaitDelay = 2 * time.Second
+)
+
+// PluginConfig 外部进程工具插件配置（localTools.plugins）
+type PluginConfig struct {
+	Name    string   `json:"name"`    // 服务名，工具名为 服务名__工具名
+	Command string   `json:"command"` // 可执行文件路径
+	Args    []string `json:"args"`    // 启动参数
+	Env     []string `json:"env"`     // 额外的环境变量，KEY=VALUE 格式
+	Dir     string   `json:"dir"`     // 工作目录
+	Timeout int      `json:"timeout"` // 单次调用超时（秒，默认 30）
+}
+
+type pluginRequest struct {
+	Method    string                 `json:"method"`
+	Tool      string                 `json:"tool,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	ConvID    string                 `json:"conv_id,omitempty"`
+}
+
+type pluginToolDef struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type pluginResponse struct {
+	Tools   []pluginToolDef `json:"tools"`
+	Content string          `json:"content"`
+	Error   string          `json:"error"`
+}
+
+// pluginTool 由外部进程执行的本地工具
+type pluginTool struct {
+	plugin *PluginConfig
+	info   *schema.ToolInfo
+}
+
+func (t *pluginTool) Info() *schema.ToolInfo {
+	return t.info
+}
+
+func (t *pluginTool) Call(ctx context.Context, convID string, args map[string]interface{}) (string, error) {
+	resp, err := runPlugin(ctx, t.plugin, &pluginRequest{
+		Method:    pluginMethodCall,
+		Tool:      t.info.Name,
+		Arguments: args,
+		ConvID:    convID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// LoadPlugins 根据 localTools.plugins 配置加载外部进程工具，单个插件加载失败时跳过
+func LoadPlugins(ctx context.Context) {
+	var plugins []*PluginConfig
+	if err := g.Cfg().MustGet(ctx, "localTools.plugins").Scan(&plugins); err != nil {
+		g.Log().Errorf(ctx, "Invalid localTools.plugins configuration: %v", err)
+		return
+	}
+	for _, plugin := range plugins {
+		count, err := loadPlugin(ctx, plugin)
+		if err != nil {
+			g.Log().Errorf(ctx, "Failed to load tool plugin %s: %v", plugin.Name, err)
+			continue
+		}
+		g.Log().Infof(ctx, "Loaded tool plugin %s with %d tools", plugin.Name, count)
+	}
+}
+
+// CheckLocalService 检查本地工具服务能否执行：外部进程插件重新查询一次工具列表，编译进程序的工具总是可以执行
+func CheckLocalService(ctx context.Context, serviceName string) error {
+	var plugin *PluginConfig
+	localToolsMu.RLock()
+	for _, tool := range localTools[serviceName] {
+		if pt, ok := tool.(*pluginTool); ok {
+			plugin = pt.plugin
+			break
+		}
+	}
+	localToolsMu.RUnlock()
+	if plugin == nil {
+		return nil
+	}
+	_, err := runPlugin(ctx, plugin, &pluginRequest{Method: pluginMethodList})
+	return err
+}
+
+// loadPlugin 向插件查询工具列表并注册
+func loadPlugin(ctx context.Context, plugin *PluginConfig) (int, error) {
+	if plugin.Name == "" || plugin.Command == "" {
+		return 0, fmt.Errorf("插件缺少 name 或 command")
+	}
+	resp, err := runPlugin(ctx, plugin, &pluginRequest{Method: pluginMethodList})
+	if err != nil {
+		return 0, err
+	}
+
+	UnregisterLocalService(plugin.Name)
+	count := 0
+	for _, def := range resp.Tools {
+		tool := &pluginTool{
+			plugin: plugin,
+			info: &schema.ToolInfo{
+				Name:        def.Name,
+				Desc:        def.Description,
+				ParamsOneOf: paramsFromInputSchema(def.InputSchema),
+			},
+		}
+		if err = RegisterLocalTool(plugin.Name, tool); err != nil {
+			g.Log().Warningf(ctx, "Skip tool %s of plugin %s: %v", def.Name, plugin.Name, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// runPlugin 启动插件进程执行一次请求
+func runPlugin(ctx context.Context, plugin *PluginConfig, req *pluginRequest) (*pluginResponse, error) {
+	timeout := plugin.Timeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, plugin.Command, plugin.Args...)
+	cmd.Dir = plugin.Dir
+	cmd.Env = append(os.Environ(), plugin.Env...)
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{limit: maxPluginOutput}
+	stderr := &limitedBuffer{limit: maxPluginOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.WaitDelay = pluginWaitDelay
+	setProcessGroup(cmd)
+
+	if err = cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("插件 %s 执行超时（%d 秒）", plugin.Name, timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("插件 %s 执行失败: %v: %s", plugin.Name, err, msg)
+		}
+		return nil, fmt.Errorf("插件 %s 执行失败: %w", plugin.Name, err)
+	}
+	if stdout.truncated {
+		return nil, fmt.Errorf("插件 %s 输出超过 %d 字节", plugin.Name, maxPluginOutput)
+	}
+
+	var resp pluginResponse
+	if err = json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("插件 %s 输出不是有效的 JSON: %w", plugin.Name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// limitedBuffer 超过上限后丢弃后续输出
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
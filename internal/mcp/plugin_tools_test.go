@@ -0,0 +1,24 @@
+package mcp
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestRunPluginTimeoutKillsChildren 测试插件超时时不会等待仍持有输出管道的子进程
+func TestRunPluginTimeoutKillsChildren(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	plugin := &PluginConfig{Name: "slow", Command: "sh", Args: []string{"-c", "sleep 30 & sleep 30"}, Timeout: 1}
+
+	start := time.Now()
+	if _, err := runPlugin(context.Background(), plugin, &pluginRequest{Method: pluginMethodList}); err == nil {
+		t.Fatal("runPlugin() error = nil, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 1*time.Second+pluginWaitDelay+time.Second {
+		t.Errorf("runPlugin() returned after %s, want about 1s", elapsed)
+	}
+}
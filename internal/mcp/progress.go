@@ -0,0 +1,30 @@
+package mcp
+
+import "context"
+
+// ToolProgress 耗时工具的执行进度，流式对话中以 tool_progress 事件发送给调用方
+type ToolProgress struct {
+	ServiceName string `json:"service_name"`
+	ToolName    string `json:"tool_name"`
+	Stage       string `json:"stage"`             // 工具自定义的阶段名称
+	Done        int    `json:"done"`              // 当前阶段已完成数
+	Total       int    `json:"total"`             // 当前阶段总数
+	Message     string `json:"message,omitempty"` // 说明（可选）
+}
+
+// ProgressFunc 接收工具进度的回调，可能被多个 goroutine 调用
+type ProgressFunc func(progress *ToolProgress)
+
+type progressKey struct{}
+
+// WithProgress 在上下文中设置工具进度回调
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress 上报工具进度，上下文中没有回调时忽略
+func ReportProgress(ctx context.Context, progress *ToolProgress) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(progress)
+	}
+}
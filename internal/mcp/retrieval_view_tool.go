@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/logic/retriever"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// RetrievalViewServiceName 检索视图工具的服务名，可通过 mcp_service_tools 只开放该服务
+const RetrievalViewServiceName = "retrieval_view"
+
+const retrievalViewToolSearch = "search"
+
+func init() {
+	if err := RegisterLocalTool(RetrievalViewServiceName, &retrievalViewSearchTool{}); err != nil {
+		panic(err)
+	}
+}
+
+// retrievalViewSearchTool 按检索视图的知识库、过滤条件和检索参数检索
+type retrievalViewSearchTool struct{}
+
+func (t *retrievalViewSearchTool) Info() *schema.ToolInfo {
+	return &schema.ToolInfo{
+		Name: retrievalViewToolSearch,
+		Desc: "在保存的检索视图（一组知识库及其过滤条件）中检索与问题相关的内容，返回参考片段及来源。" +
+			"比较多个对象或包含多个问题时可以在 sub_queries 中给出拆分后的子问题，结果按子问题分组返回",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"view":        {Type: "string", Desc: "检索视图名称", Required: true},
+			"question":    {Type: "string", Desc: "检索问题", Required: true},
+			"sub_queries": {Type: "string", Desc: "拆分后的子问题（可选），每行一个，分别检索"},
+		}),
+	}
+}
+
+func (t *retrievalViewSearchTool) Call(ctx context.Context, convID string, args map[string]interface{}) (string, error) {
+	view, _ := args["view"].(string)
+	question, _ := args["question"].(string)
+	if strings.TrimSpace(view) == "" || strings.TrimSpace(question) == "" {
+		return "", fmt.Errorf("缺少检索视图名称或检索问题")
+	}
+
+	// 调用方给出的子问题优先，否则按配置自动拆分多跳问题
+	cfg := retriever.LoadDecomposeConfig(ctx)
+	var subQueries []string
+	if raw, _ := args["sub_queries"].(string); strings.TrimSpace(raw) != "" {
+		subQueries = retriever.NormalizeSubQueries(strings.Split(raw, "\n"), question, cfg.MaxSubQueries)
+	} else {
+		subQueries = retriever.DecomposeQuery(ctx, cfg, question)
+	}
+
+	req := &v1.RetrieverReq{Question: question, RetrievalView: view}
+	if len(subQueries) == 1 {
+		req.Question = subQueries[0]
+		res, err := retriever.ProcessRetrieval(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		if len(res.Document) == 0 {
+			return fmt.Sprintf("检索视图 %s 中没有找到与问题相关的内容", view), nil
+		}
+		return formatDocuments(res.Document, "", nil), nil
+	}
+
+	g.Log().Infof(ctx, "Retrieval view %s - question decomposed into %d sub-queries: %v", view, len(subQueries), subQueries)
+	results, err := retriever.ProcessDecomposedRetrieval(ctx, req, subQueries)
+	if err != nil {
+		return "", err
+	}
+	return formatSubQueryResults(results), nil
+}
+
+// formatSubQueryResults 按子问题分组列出参考片段，已在前面的子问题中列出的片段只给出引用编号
+func formatSubQueryResults(results []*retriever.SubQueryResult) string {
+	var builder strings.Builder
+	seen := make(map[string]string)
+	for i, result := range results {
+		fmt.Fprintf(&builder, "## 子问题 %d：%s\n", i+1, result.Query)
+		if len(result.Documents) == 0 {
+			builder.WriteString("没有找到相关内容\n\n")
+			continue
+		}
+		builder.WriteString(formatDocuments(result.Documents, fmt.Sprintf("%d.", i+1), seen))
+		builder.WriteString("\n\n")
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// formatDocuments 按编号列出参考片段，prefix 为子问题编号前缀（如 "2."）；
+// seen 不为 nil 时记录已列出片段的编号，重复的片段只给出引用编号
+func formatDocuments(docs []*schema.Document, prefix string, seen map[string]string) string {
+	var builder strings.Builder
+	for i, doc := range docs {
+		label := fmt.Sprintf("%s%d", prefix, i+1)
+		if seen != nil && doc.ID != "" {
+			if first, ok := seen[doc.ID]; ok {
+				fmt.Fprintf(&builder, "[%s] 同 [%s]\n\n", label, first)
+				continue
+			}
+			seen[doc.ID] = label
+		}
+		fmt.Fprintf(&builder, "[%s] (score %.2f)", label, doc.Score)
+		if source := documentSource(doc); source != "" {
+			fmt.Fprintf(&builder, " 来源：%s", source)
+		}
+		builder.WriteString("\n")
+		builder.WriteString(strings.TrimSpace(doc.Content))
+		builder.WriteString("\n\n")
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// documentSource 分片来源文档，依次取切分时记录的文档来源和文档ID
+func documentSource(doc *schema.Document) string {
+	if metadata, ok := doc.MetaData["metadata"].(map[string]interface{}); ok {
+		if source, ok := metadata["_source"].(string); ok && source != "" {
+			return source
+		}
+	}
+	documentID, _ := doc.MetaData[common.DocumentId].(string)
+	return documentID
+}
@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/internal/logic/retriever"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestFormatSubQueryResults(t *testing.T) {
+	shared := &schema.Document{ID: "c1", Content: "退款需在 7 天内申请", Score: 0.9, MetaData: map[string]any{"metadata": map[string]interface{}{"_source": "policy-2023.pdf"}}}
+	results := []*retriever.SubQueryResult{
+		{Query: "2023 年退款政策", Documents: []*schema.Document{shared}},
+		{Query: "2024 年退款政策", Documents: []*schema.Document{{ID: "c2", Content: "退款需在 14 天内申请", Score: 0.8}, shared}},
+		{Query: "2025 年退款政策"},
+	}
+	got := formatSubQueryResults(results)
+	want := strings.Join([]string{
+		"## 子问题 1：2023 年退款政策",
+		"[1.1] (score 0.90) 来源：policy-2023.pdf",
+		"退款需在 7 天内申请",
+		"",
+		"## 子问题 2：2024 年退款政策",
+		"[2.1] (score 0.80)",
+		"退款需在 14 天内申请",
+		"",
+		"[2.2] 同 [1.1]",
+		"",
+		"## 子问题 3：2025 年退款政策",
+		"没有找到相关内容",
+	}, "\n")
+	if got != want {
+		t.Errorf("formatSubQueryResults() =\n%s\nwant\n%s", got, want)
+	}
+}
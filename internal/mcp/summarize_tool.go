@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Malowking/kbgo/internal/logic/summarize"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// DocumentServiceName 内置文档工具的服务名
+const DocumentServiceName = "document"
+
+const documentToolSummarize = "summarize_document"
+
+func init() {
+	if err := RegisterLocalTool(DocumentServiceName, &summarizeTool{}); err != nil {
+		panic(err)
+	}
+}
+
+// summarizeTool 长文档 map-reduce 摘要工具：对会话附件或知识库文档分段并行摘要，再合并为结构化摘要
+type summarizeTool struct{}
+
+func (t *summarizeTool) Info() *schema.ToolInfo {
+	return &schema.ToolInfo{
+		Name: documentToolSummarize,
+		Desc: "对长文档（如上百页的 PDF）生成完整摘要。用户要求总结、概括整份文档时使用，" +
+			"不指定 document_id 时摘要当前会话中上传的文档",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"document_id": {Type: "string", Desc: "知识库文档ID（可选），为空时使用会话中上传的文档"},
+			"style":       {Type: "string", Desc: "摘要风格：executive（管理层摘要）、bullet（要点列表，默认）、faq（常见问题）"},
+			"focus":       {Type: "string", Desc: "需要重点关注的内容（可选）"},
+		}),
+	}
+}
+
+func (t *summarizeTool) Call(ctx context.Context, convID string, args map[string]interface{}) (string, error) {
+	opts := &summarize.Options{ConvID: convID}
+	opts.DocumentID, _ = args["document_id"].(string)
+	opts.Style, _ = args["style"].(string)
+	opts.Focus, _ = args["focus"].(string)
+	if opts.Style != "" && !summarize.ValidStyle(opts.Style) {
+		return "", fmt.Errorf("不支持的摘要风格: %s", opts.Style)
+	}
+
+	return summarize.Document(ctx, opts, func(stage string, done, total int) {
+		ReportProgress(ctx, &ToolProgress{
+			ServiceName: DocumentServiceName,
+			ToolName:    documentToolSummarize,
+			Stage:       stage,
+			Done:        done,
+			Total:       total,
+		})
+	})
+}
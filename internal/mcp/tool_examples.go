@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/mcp/client"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// TestToolExamples 用样例问题测试工具调用示例：逐个问题让 LLM 生成工具调用（不执行），
+// 与期望工具比对并统计准确率；compare 为 true 时同时测试不注入示例的结果
+func (tc *MCPToolCaller) TestToolExamples(ctx context.Context, req *v1.ToolExampleTestReq) *v1.ToolExampleTestRes {
+	res := &v1.ToolExampleTestRes{Results: make([]*v1.ToolExampleTestResult, 0, len(req.Cases))}
+	var expected, matched, baselineMatched int
+
+	for _, testCase := range req.Cases {
+		result := &v1.ToolExampleTestResult{Question: testCase.Question, ExampleIDs: []string{}}
+		res.Results = append(res.Results, result)
+
+		calls, examples, err := tc.PreviewToolCalls(ctx, req.ModelID, testCase.Question, req.MCPServiceTools, true)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.ToolCalls = toolCallPreviews(calls)
+		for _, example := range examples {
+			result.ExampleIDs = append(result.ExampleIDs, example.ID)
+		}
+
+		if req.Compare {
+			baseline, _, err := tc.PreviewToolCalls(ctx, req.ModelID, testCase.Question, req.MCPServiceTools, false)
+			if err != nil {
+				result.Error = "baseline: " + err.Error()
+			} else {
+				result.BaselineToolCalls = toolCallPreviews(baseline)
+			}
+		}
+
+		if testCase.ExpectedTool == "" {
+			continue
+		}
+		expected++
+		ok := callsExpectedTool(result.ToolCalls, testCase)
+		result.Matched = &ok
+		if ok {
+			matched++
+		}
+		if req.Compare {
+			baselineOK := callsExpectedTool(result.BaselineToolCalls, testCase)
+			result.BaselineMatched = &baselineOK
+			if baselineOK {
+				baselineMatched++
+			}
+		}
+	}
+
+	if expected > 0 {
+		accuracy := float64(matched) / float64(expected)
+		res.Accuracy = &accuracy
+		if req.Compare {
+			baselineAccuracy := float64(baselineMatched) / float64(expected)
+			res.BaselineAccuracy = &baselineAccuracy
+		}
+	}
+	return res
+}
+
+func toolCallPreviews(calls []schema.ToolCall) []*v1.ToolCallPreview {
+	previews := make([]*v1.ToolCallPreview, 0, len(calls))
+	for _, call := range calls {
+		serviceName, toolName := client.ParseToolName(call.Function.Name)
+		previews = append(previews, &v1.ToolCallPreview{
+			ServiceName: serviceName,
+			ToolName:    toolName,
+			Arguments:   call.Function.Arguments,
+		})
+	}
+	return previews
+}
+
+// callsExpectedTool 是否调用了期望的工具，未指定期望服务时只比较工具名
+func callsExpectedTool(calls []*v1.ToolCallPreview, testCase *v1.ToolExampleTestCase) bool {
+	for _, call := range calls {
+		if call.ToolName == testCase.ExpectedTool &&
+			(testCase.ExpectedService == "" || call.ServiceName == testCase.ExpectedService) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/tokenizer"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 工具调用循环提前结束的原因，记录在执行摘要的 stop_reason 中
+const (
+	StopMaxIterations = "max_iterations" // 达到最大轮数
+	StopTimeout       = "timeout"        // 工具调用阶段超时
+	StopLatencyBudget = "latency_budget" // 延迟预算不足
+	StopContextWindow = "context_window" // 下一轮的输入预计超出模型上下文窗口
+	StopPromptBudget  = "prompt_budget"  // 累计输入 token 超出上限
+	StopCostBudget    = "cost_budget"    // 累计费用超出上限
+)
+
+// defaultReserveTokens 按上下文窗口检查时为模型输出预留的 token 数
+const defaultReserveTokens = 1024
+
+// truncatedToolResult 截断工具结果时追加的说明
+const truncatedToolResult = "\n...（工具结果过长，已截断）"
+
+// ToolGuard 工具调用循环的 token 守卫（agentGuard 配置）：每轮调用模型前估算输入 token，
+// 预计超出模型上下文窗口、累计输入 token 上限或累计费用上限时不再进行下一轮，改为基于已有的工具结果生成最终答案
+type ToolGuard struct {
+	MaxPromptTokens int     // 一次工具调用循环各轮输入 token 之和的上限，0 表示不限制
+	MaxCost         float64 // 一次工具调用循环的累计费用上限，按模型 extra 中的 costPer1kTokens 估算，0 表示不限制
+	ReserveTokens   int     // 按模型上下文窗口（extra 中的 context_window）检查时为输出预留的 token 数
+}
+
+// LoadToolGuard 从 agentGuard 配置读取守卫参数
+func LoadToolGuard(ctx context.Context) *ToolGuard {
+	return &ToolGuard{
+		MaxPromptTokens: g.Cfg().MustGet(ctx, "agentGuard.maxPromptTokens", 0).Int(),
+		MaxCost:         g.Cfg().MustGet(ctx, "agentGuard.maxCost", 0).Float64(),
+		ReserveTokens:   g.Cfg().MustGet(ctx, "agentGuard.reserveTokens", defaultReserveTokens).Int(),
+	}
+}
+
+// guardState 一次工具调用循环中守卫的累计状态
+type guardState struct {
+	guard         *ToolGuard
+	modelName     string
+	contextWindow int     // 模型上下文窗口，0 表示未配置，不检查
+	costPer1k     float64 // 每千 token 费用，0 表示未配置，不检查费用上限
+	promptTokens  int     // 已进行的各轮输入 token 之和（估算）
+	tokensUsed    int     // 已进行的各轮消耗的 token（模型返回的用量）
+}
+
+// newState 按模型配置创建循环状态
+func (t *ToolGuard) newState(modelID string) *guardState {
+	state := &guardState{guard: t}
+	if mc := model.Registry.Get(modelID); mc != nil {
+		state.modelName = mc.Name
+		state.contextWindow = extraInt(mc.Extra, "context_window")
+		state.costPer1k = extraFloat(mc.Extra, "costPer1kTokens")
+	}
+	return state
+}
+
+// check 估算下一轮的输入 token（消息和工具定义），超出限制时返回结束原因和说明，可以继续时返回空字符串
+func (s *guardState) check(messages []*schema.Message, tools []*schema.ToolInfo) (prompt int, reason string, detail string) {
+	prompt = tokenizer.CountMessages(s.modelName, messages) + toolInfoTokens(s.modelName, tools)
+	if s.contextWindow > 0 && prompt+s.guard.ReserveTokens > s.contextWindow {
+		return prompt, StopContextWindow, fmt.Sprintf("输入约 %d token，加上预留的 %d token 超出上下文窗口 %d", prompt, s.guard.ReserveTokens, s.contextWindow)
+	}
+	if s.guard.MaxPromptTokens > 0 && s.promptTokens+prompt > s.guard.MaxPromptTokens {
+		return prompt, StopPromptBudget, fmt.Sprintf("累计输入约 %d token，超出上限 %d", s.promptTokens+prompt, s.guard.MaxPromptTokens)
+	}
+	if s.guard.MaxCost > 0 && s.costPer1k > 0 {
+		if cost := float64(s.tokensUsed+prompt) / 1000 * s.costPer1k; cost > s.guard.MaxCost {
+			return prompt, StopCostBudget, fmt.Sprintf("累计费用预计 %.4f，超出上限 %.4f", cost, s.guard.MaxCost)
+		}
+	}
+	return prompt, "", ""
+}
+
+// record 记录一轮模型调用的输入 token 估算值和实际消耗
+func (s *guardState) record(prompt, tokensUsed int) {
+	s.promptTokens += prompt
+	s.tokensUsed += tokensUsed
+}
+
+// fitFinalAnswer 生成最终答案前，输入预计超出上下文窗口时从最长的工具结果开始截断，直到可以放入窗口；
+// 返回新的消息列表，原消息不修改
+func (s *guardState) fitFinalAnswer(messages []*schema.Message) []*schema.Message {
+	if s.contextWindow <= 0 {
+		return messages
+	}
+	limit := s.contextWindow - s.guard.ReserveTokens
+	excess := tokenizer.CountMessages(s.modelName, messages) - limit
+	if excess <= 0 {
+		return messages
+	}
+
+	fitted := append([]*schema.Message(nil), messages...)
+	suffixTokens := tokenizer.Count(s.modelName, truncatedToolResult)
+	for excess > 0 {
+		longest := -1
+		longestTokens := 0
+		for i, msg := range fitted {
+			if msg.Role != schema.Tool {
+				continue
+			}
+			if tokens := tokenizer.Count(s.modelName, msg.Content); tokens > longestTokens {
+				longest, longestTokens = i, tokens
+			}
+		}
+		// 没有可以截断的工具结果，或工具结果已截断到很短
+		if longest < 0 || longestTokens <= 64 {
+			break
+		}
+		content := strings.TrimSuffix(fitted[longest].Content, truncatedToolResult)
+		keep := max(longestTokens-excess-suffixTokens, longestTokens/2, 32)
+		truncated := *fitted[longest]
+		truncated.Content = truncateTokens(s.modelName, content, keep) + truncatedToolResult
+		if tokenizer.Count(s.modelName, truncated.Content) >= longestTokens {
+			break
+		}
+		fitted[longest] = &truncated
+		excess = tokenizer.CountMessages(s.modelName, fitted) - limit
+	}
+	return fitted
+}
+
+// truncateTokens 按比例保留文本开头约 tokens 个 token 的内容
+func truncateTokens(modelName, text string, tokens int) string {
+	runes := []rune(text)
+	total := tokenizer.Count(modelName, text)
+	if total <= tokens || total == 0 {
+		return text
+	}
+	return string(runes[:len(runes)*tokens/total])
+}
+
+// toolInfoTokens 估算工具定义（名称、描述和参数 JSON Schema）占用的输入 token
+func toolInfoTokens(modelName string, tools []*schema.ToolInfo) int {
+	tokens := 0
+	for _, tool := range tools {
+		tokens += tokenizer.Count(modelName, tool.Name) + tokenizer.Count(modelName, tool.Desc)
+		if params, err := tool.ParamsOneOf.ToOpenAPIV3(); err == nil && params != nil {
+			if data, err := json.Marshal(params); err == nil {
+				tokens += tokenizer.Count(modelName, string(data))
+			}
+		}
+	}
+	return tokens
+}
+
+// extraInt 读取模型 extra 中的整数，从数据库加载的 JSON 数字为 float64
+func extraInt(extra map[string]any, key string) int {
+	switch value := extra[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	}
+	return 0
+}
+
+// extraFloat 读取模型 extra 中的数字
+func extraFloat(extra map[string]any, key string) float64 {
+	switch value := extra[key].(type) {
+	case float64:
+		return value
+	case int:
+		return float64(value)
+	}
+	return 0
+}
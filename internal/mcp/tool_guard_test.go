@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/tokenizer"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func guardMessages(toolResult string) []*schema.Message {
+	return []*schema.Message{
+		{Role: schema.System, Content: "你是一个助手"},
+		{Role: schema.User, Content: "查询上个月的订单"},
+		{Role: schema.Assistant, ToolCalls: []schema.ToolCall{{ID: "c1", Function: schema.FunctionCall{Name: "db__query", Arguments: "{}"}}}},
+		{Role: schema.Tool, ToolCallID: "c1", Content: toolResult},
+	}
+}
+
+func TestGuardStateCheck(t *testing.T) {
+	messages := guardMessages(strings.Repeat("order row ", 200))
+	prompt := tokenizer.CountMessages("", messages)
+
+	state := &guardState{guard: &ToolGuard{ReserveTokens: 100}, contextWindow: prompt + 200}
+	if _, reason, _ := state.check(messages, nil); reason != "" {
+		t.Errorf("expected prompt to fit, got %s", reason)
+	}
+	state.contextWindow = prompt + 50
+	if _, reason, _ := state.check(messages, nil); reason != StopContextWindow {
+		t.Errorf("expected %s, got %q", StopContextWindow, reason)
+	}
+
+	// 工具定义同样计入输入
+	tools := []*schema.ToolInfo{{Name: "db__query", Desc: strings.Repeat("run a read only sql query ", 50)}}
+	state.contextWindow = prompt + 200
+	if got, _, _ := state.check(messages, tools); got <= prompt {
+		t.Errorf("expected tool definitions to be counted, got %d (messages %d)", got, prompt)
+	}
+
+	// 累计输入 token：第一轮可以进行，第二轮超出上限
+	state = &guardState{guard: &ToolGuard{MaxPromptTokens: prompt*2 - 1}}
+	got, reason, _ := state.check(messages, nil)
+	if reason != "" {
+		t.Fatalf("expected first iteration to run, got %s", reason)
+	}
+	state.record(got, got+10)
+	if _, reason, _ = state.check(messages, nil); reason != StopPromptBudget {
+		t.Errorf("expected %s, got %q", StopPromptBudget, reason)
+	}
+
+	// 费用上限只在模型配置了单价时检查
+	state = &guardState{guard: &ToolGuard{MaxCost: 0.01}, tokensUsed: 5000}
+	if _, reason, _ = state.check(messages, nil); reason != "" {
+		t.Errorf("expected no cost check without price, got %s", reason)
+	}
+	state.costPer1k = 0.002
+	if _, reason, _ = state.check(messages, nil); reason != StopCostBudget {
+		t.Errorf("expected %s, got %q", StopCostBudget, reason)
+	}
+}
+
+func TestGuardStateFitFinalAnswer(t *testing.T) {
+	messages := guardMessages(strings.Repeat("order row ", 2000))
+	state := &guardState{guard: &ToolGuard{ReserveTokens: 100}, contextWindow: 600}
+
+	fitted := state.fitFinalAnswer(messages)
+	if tokens := tokenizer.CountMessages("", fitted); tokens > 500 {
+		t.Errorf("expected messages to fit in 500 tokens, got %d", tokens)
+	}
+	if !strings.HasSuffix(fitted[3].Content, truncatedToolResult) || fitted[1].Content != messages[1].Content {
+		t.Errorf("expected only the tool result to be truncated: %q", fitted[3].Content)
+	}
+	if messages[3].Content != strings.Repeat("order row ", 2000) {
+		t.Error("original messages must not be modified")
+	}
+
+	// 未配置上下文窗口时不修改
+	state.contextWindow = 0
+	if fitted = state.fitFinalAnswer(messages); fitted[3] != messages[3] {
+		t.Error("expected messages unchanged without context window")
+	}
+}
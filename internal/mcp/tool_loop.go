@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 工具调用轮数和循环检测的默认值
+const (
+	defaultMaxIterations      = 5  // 工具调用最大轮数
+	defaultMaxIterationsLimit = 20 // 请求或项目可设置的最大轮数上限
+	defaultLoopThreshold      = 2  // 同一工具以相同参数被调用的次数达到该值时判定为循环
+)
+
+// StopLoopDetected 工具调用循环提前结束的原因：模型重复以相同参数调用同一工具
+const StopLoopDetected = "loop_detected"
+
+type maxIterationsKey struct{}
+
+// WithMaxIterations 在上下文中设置本次对话的工具调用最大轮数，0 表示使用 toolExecution.maxIterations 配置
+func WithMaxIterations(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, maxIterationsKey{}, n)
+}
+
+// iterationLimit 本次对话的工具调用最大轮数：上下文中的设置（请求参数或项目默认值）优先，其次为 toolExecution.maxIterations，
+// 不超过 toolExecution.maxIterationsLimit
+func iterationLimit(ctx context.Context) int {
+	n, _ := ctx.Value(maxIterationsKey{}).(int)
+	if n <= 0 {
+		n = g.Cfg().MustGet(ctx, "toolExecution.maxIterations", defaultMaxIterations).Int()
+	}
+	if limit := g.Cfg().MustGet(ctx, "toolExecution.maxIterationsLimit", defaultMaxIterationsLimit).Int(); limit > 0 && n > limit {
+		n = limit
+	}
+	return max(n, 1)
+}
+
+// loopDetector 检测模型反复以相同参数调用同一工具（工具结果不会变化，继续调用只会消耗轮数）
+type loopDetector struct {
+	threshold int // 0 表示不检测
+	counts    map[string]int
+}
+
+// newLoopDetector 按 toolExecution.loopThreshold 创建循环检测器
+func newLoopDetector(ctx context.Context) *loopDetector {
+	return &loopDetector{
+		threshold: g.Cfg().MustGet(ctx, "toolExecution.loopThreshold", defaultLoopThreshold).Int(),
+		counts:    make(map[string]int),
+	}
+}
+
+// observe 记录一轮的工具调用，有调用达到重复次数阈值时返回该工具名和调用次数
+func (d *loopDetector) observe(toolCalls []schema.ToolCall) (toolName string, count int) {
+	if d.threshold <= 0 {
+		return "", 0
+	}
+	for _, toolCall := range toolCalls {
+		key := toolCall.Function.Name + "\x00" + canonicalArguments(toolCall.Function.Arguments)
+		d.counts[key]++
+		if d.counts[key] >= d.threshold && toolName == "" {
+			toolName, count = toolCall.Function.Name, d.counts[key]
+		}
+	}
+	return toolName, count
+}
+
+// canonicalArguments 规范化工具参数 JSON（按键排序、去除空白），参数相同但键顺序或格式不同的调用视为相同
+func canonicalArguments(arguments string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return strings.TrimSpace(arguments)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return strings.TrimSpace(arguments)
+	}
+	return string(data)
+}
+
+// loopNote 检测到循环时提示模型停止调用工具、直接回答的说明
+func loopNote(toolName string, count int) *schema.Message {
+	return &schema.Message{
+		Role: schema.User,
+		Content: fmt.Sprintf("检测到重复的工具调用：工具 %s 已使用相同的参数请求了 %d 次，再次调用不会得到新的结果。"+
+			"请不要再调用工具，直接基于上文已有的工具结果回答问题；如果信息不足，请说明还缺少哪些信息。", toolName, count),
+	}
+}
@@ -0,0 +1,52 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func loopCall(name, arguments string) schema.ToolCall {
+	return schema.ToolCall{Function: schema.FunctionCall{Name: name, Arguments: arguments}}
+}
+
+func TestLoopDetector(t *testing.T) {
+	d := &loopDetector{threshold: 2, counts: map[string]int{}}
+	if name, _ := d.observe([]schema.ToolCall{loopCall("db__query", `{"sql":"select 1","limit":10}`)}); name != "" {
+		t.Fatalf("first call reported as loop: %s", name)
+	}
+	// 参数不同不算重复
+	if name, _ := d.observe([]schema.ToolCall{loopCall("db__query", `{"sql":"select 2"}`)}); name != "" {
+		t.Fatalf("different arguments reported as loop: %s", name)
+	}
+	// 键顺序和空白不同的相同参数视为重复
+	name, count := d.observe([]schema.ToolCall{
+		loopCall("weather__now", `{"city":"北京"}`),
+		loopCall("db__query", "{ \"limit\": 10, \"sql\": \"select 1\" }"),
+	})
+	if name != "db__query" || count != 2 {
+		t.Errorf("expected db__query repeated twice, got %q %d", name, count)
+	}
+
+	disabled := &loopDetector{counts: map[string]int{}}
+	for i := 0; i < 3; i++ {
+		if name, _ := disabled.observe([]schema.ToolCall{loopCall("db__query", "{}")}); name != "" {
+			t.Fatalf("loop detection should be disabled, got %s", name)
+		}
+	}
+}
+
+func TestCanonicalArguments(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"b":1,"a":[1, 2]}`, `{"a":[1,2],"b":1}`},
+		{"  not json ", "not json"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalArguments(tt.in); got != tt.want {
+			t.Errorf("canonicalArguments(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/logic/agentrun"
+	"github.com/Malowking/kbgo/internal/mcp/client"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// defaultToolConcurrency 同一轮中并发执行的工具调用数
+const defaultToolConcurrency = 4
+
+// toolConcurrency LLM 一次返回多个工具调用时的并发数（toolExecution.maxConcurrency），1 表示按顺序执行。
+// 工作区工具之间可能存在先写后读的依赖，本轮包含工作区工具时按顺序执行；
+// 策略规则带有 afterTools 条件时，同一轮中前面工具的成功会影响后面工具的检查结果，也按顺序执行
+func toolConcurrency(ctx context.Context, toolCalls []schema.ToolCall, policy *ToolPolicy) int {
+	if policy.dependsOnToolHistory() {
+		return 1
+	}
+	for _, toolCall := range toolCalls {
+		if serviceName, _ := client.ParseToolName(toolCall.Function.Name); serviceName == WorkspaceServiceName {
+			return 1
+		}
+	}
+	return g.Cfg().MustGet(ctx, "toolExecution.maxConcurrency", defaultToolConcurrency).Int()
+}
+
+// runConcurrently 以最多 concurrency 个 goroutine 执行 fn(0..n-1)，全部完成后返回；concurrency <= 1 时按顺序执行
+func runConcurrently(ctx context.Context, n, concurrency int, fn func(idx int)) {
+	if concurrency <= 1 || n <= 1 {
+		for idx := 0; idx < n; idx++ {
+			fn(idx)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for idx := 0; idx < n; idx++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		common.SafeGo(ctx, "CallToolConcurrently", func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(idx)
+		})
+	}
+	wg.Wait()
+}
+
+// safeToolOutcome 执行单个工具调用并恢复其中的 panic，panic 时返回该工具调用的错误结果，
+// 保证顺序和并发执行时每个工具调用都有对应的工具消息
+func safeToolOutcome(ctx context.Context, round *toolRound, idx int, toolCall schema.ToolCall, exec func() *toolOutcome) (outcome *toolOutcome) {
+	defer func() {
+		r := recover()
+		if r == nil && outcome != nil {
+			return
+		}
+		err := fmt.Errorf("工具执行未返回结果")
+		if r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			g.Log().Criticalf(ctx, "[工具 %d/%d] 工具 %s 执行时发生 panic: %v\n%s", idx+1, round.total, toolCall.Function.Name, r, debug.Stack())
+		}
+		serviceName, toolName := client.ParseToolName(toolCall.Function.Name)
+		round.run.RecordTool(serviceName, toolName, agentrun.StatusError, 0, "", err)
+		outcome = &toolOutcome{message: &schema.Message{
+			Role:       schema.Tool,
+			Content:    fmt.Sprintf("工具调用失败: %v", err),
+			ToolCallID: toolCall.ID,
+		}}
+	}()
+	return exec()
+}
+
+// toolRound 一轮工具调用共享的状态，并发执行时策略检查和成功记录互斥
+type toolRound struct {
+	execCtx     context.Context // 工具调用阶段的上下文，阶段超时后取消
+	convID      string
+	run         *agentrun.Recorder
+	timeouts    ToolTimeouts
+	total       int // 本轮的工具调用数
+	mu          sync.Mutex
+	policy      *ToolPolicy
+	policyState *toolPolicyState
+}
+
+// check 按策略检查工具是否允许调用，返回 nil 表示允许
+func (r *toolRound) check(toolName string) *ToolPolicyDecision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.policy.Check(r.policyState, toolName)
+}
+
+// recordSuccess 记录成功调用的工具，后续的策略检查可据此放行依赖它的工具
+func (r *toolRound) recordSuccess(toolName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policyState.recordSuccess(toolName)
+}
+
+// toolOutcome 单个工具调用的结果
+type toolOutcome struct {
+	message   *schema.Message        // 写入消息历史的工具结果
+	document  *schema.Document       // 工具调用成功时的结果文档
+	mcpResult *v1.MCPResult          // 工具调用成功时的结果
+	log       map[string]interface{} // 工具调用日志
+}
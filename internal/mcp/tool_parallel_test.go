@@ -0,0 +1,104 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		concurrency int
+		wantMax     int32
+	}{
+		{name: "sequential", n: 4, concurrency: 1, wantMax: 1},
+		{name: "bounded", n: 6, concurrency: 2, wantMax: 2},
+		{name: "more workers than calls", n: 3, concurrency: 8, wantMax: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning int32
+			results := make([]int, tt.n)
+			runConcurrently(context.Background(), tt.n, tt.concurrency, func(idx int) {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					prev := atomic.LoadInt32(&maxRunning)
+					if current <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, current) {
+						break
+					}
+				}
+				// 后面的调用先完成，结果仍按下标保存
+				time.Sleep(time.Duration(tt.n-idx) * 5 * time.Millisecond)
+				results[idx] = idx * 10
+				atomic.AddInt32(&running, -1)
+			})
+			if maxRunning != tt.wantMax {
+				t.Errorf("max concurrent calls = %d, want %d", maxRunning, tt.wantMax)
+			}
+			for idx, got := range results {
+				if got != idx*10 {
+					t.Errorf("results[%d] = %d, want %d", idx, got, idx*10)
+				}
+			}
+		})
+	}
+}
+
+// TestToolConcurrencySequential 测试本轮包含工作区工具或策略规则依赖已成功的工具时按顺序执行
+func TestToolConcurrencySequential(t *testing.T) {
+	ctx := context.Background()
+	calls := []schema.ToolCall{
+		{Function: schema.FunctionCall{Name: "crm__search"}},
+		{Function: schema.FunctionCall{Name: "crm__delete"}},
+	}
+	afterTools := newToolPolicy(ctx, []*ToolPolicyRule{
+		{Name: "no-delete-after-search", Tools: []string{"crm__delete"}, Effect: ToolPolicyDeny, AfterTools: []string{"crm__search"}},
+	})
+	if got := toolConcurrency(ctx, calls, afterTools); got != 1 {
+		t.Errorf("toolConcurrency() with afterTools rule = %d, want 1", got)
+	}
+
+	workspaceCalls := append(calls, schema.ToolCall{Function: schema.FunctionCall{Name: WorkspaceServiceName + "__write_file"}})
+	if got := toolConcurrency(ctx, workspaceCalls, nil); got != 1 {
+		t.Errorf("toolConcurrency() with workspace tool = %d, want 1", got)
+	}
+}
+
+// TestSafeToolOutcome 测试工具调用 panic 时顺序和并发执行都为该调用生成错误结果
+func TestSafeToolOutcome(t *testing.T) {
+	calls := []schema.ToolCall{
+		{ID: "call-0", Function: schema.FunctionCall{Name: "crm__search"}},
+		{ID: "call-1", Function: schema.FunctionCall{Name: "crm__broken"}},
+		{ID: "call-2", Function: schema.FunctionCall{Name: "crm__empty"}},
+	}
+	for _, concurrency := range []int{1, 4} {
+		ctx := context.Background()
+		round := &toolRound{total: len(calls)}
+		outcomes := make([]*toolOutcome, len(calls))
+		runConcurrently(ctx, len(calls), concurrency, func(idx int) {
+			outcomes[idx] = safeToolOutcome(ctx, round, idx, calls[idx], func() *toolOutcome {
+				switch idx {
+				case 1:
+					panic("boom")
+				case 2:
+					return nil
+				}
+				return &toolOutcome{message: &schema.Message{Role: schema.Tool, Content: "ok", ToolCallID: calls[idx].ID}}
+			})
+		})
+		for idx, outcome := range outcomes {
+			if outcome == nil || outcome.message == nil || outcome.message.ToolCallID != calls[idx].ID {
+				t.Fatalf("concurrency %d: outcomes[%d] = %+v, want tool message for %s", concurrency, idx, outcome, calls[idx].ID)
+			}
+		}
+		if outcomes[0].message.Content != "ok" || !strings.Contains(outcomes[1].message.Content, "boom") {
+			t.Errorf("concurrency %d: contents = %q, %q", concurrency, outcomes[0].message.Content, outcomes[1].message.Content)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 工具策略效果
+const (
+	ToolPolicyDeny    = "deny"    // 条件满足时禁止工具
+	ToolPolicyRequire = "require" // 条件满足时才允许工具
+)
+
+// ToolPolicyRule 工具使用策略规则
+// 条件之间为“且”关系，未配置任何条件时规则始终满足
+type ToolPolicyRule struct {
+	Name             string   `json:"name"`
+	Models           []string `json:"models"`           // 生效的对话模型ID，为空时对所有模型生效
+	Tools            []string `json:"tools"`            // 作用的工具（serviceName__toolName），支持通配符，如 "*__file_export"
+	Effect           string   `json:"effect"`           // deny 或 require
+	QuestionKeywords []string `json:"questionKeywords"` // 条件：用户问题包含任一关键词（不区分大小写）
+	QuestionPattern  string   `json:"questionPattern"`  // 条件：用户问题匹配正则表达式
+	AfterTools       []string `json:"afterTools"`       // 条件：本会话中已成功调用过任一工具，支持通配符
+
+	questionRegexp *regexp.Regexp
+}
+
+// ToolPolicy 工具使用策略，每轮调用 LLM 前根据会话上下文过滤可用工具
+type ToolPolicy struct {
+	rules []*ToolPolicyRule
+}
+
+// ToolPolicyDecision 策略决策（被禁止的工具及原因）
+type ToolPolicyDecision struct {
+	Rule   string
+	Tool   string
+	Reason string
+}
+
+// toolPolicyState 策略评估所需的会话上下文
+type toolPolicyState struct {
+	modelID   string
+	question  string
+	succeeded map[string]bool // 本会话中已成功调用的工具（serviceName__toolName）
+}
+
+// LoadToolPolicy 从 toolPolicy 配置加载策略，未启用或没有有效规则时返回 nil
+func LoadToolPolicy(ctx context.Context) *ToolPolicy {
+	if !g.Cfg().MustGet(ctx, "toolPolicy.enabled", false).Bool() {
+		return nil
+	}
+	var rules []*ToolPolicyRule
+	if err := g.Cfg().MustGet(ctx, "toolPolicy.rules").Scan(&rules); err != nil {
+		g.Log().Errorf(ctx, "Failed to load tool policy rules: %v", err)
+		return nil
+	}
+	return newToolPolicy(ctx, rules)
+}
+
+// newToolPolicy 校验规则，跳过配置错误的规则
+func newToolPolicy(ctx context.Context, rules []*ToolPolicyRule) *ToolPolicy {
+	policy := &ToolPolicy{}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		rule.Effect = strings.ToLower(strings.TrimSpace(rule.Effect))
+		if rule.Effect != ToolPolicyDeny && rule.Effect != ToolPolicyRequire {
+			g.Log().Warningf(ctx, "Tool policy %s has invalid effect %q, skipping", rule.Name, rule.Effect)
+			continue
+		}
+		if len(rule.Tools) == 0 {
+			g.Log().Warningf(ctx, "Tool policy %s has no tools, skipping", rule.Name)
+			continue
+		}
+		if rule.QuestionPattern != "" {
+			re, err := regexp.Compile(rule.QuestionPattern)
+			if err != nil {
+				g.Log().Warningf(ctx, "Tool policy %s has invalid question pattern, skipping: %v", rule.Name, err)
+				continue
+			}
+			rule.questionRegexp = re
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	if len(policy.rules) == 0 {
+		return nil
+	}
+	return policy
+}
+
+// dependsOnToolHistory 是否有规则以已成功调用的工具（afterTools）为条件
+func (p *ToolPolicy) dependsOnToolHistory() bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.rules {
+		if len(rule.AfterTools) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newState 构建会话上下文，加载本会话之前轮次中成功调用过的 MCP 工具
+func (p *ToolPolicy) newState(ctx context.Context, modelID, question, convID string) *toolPolicyState {
+	if p == nil {
+		return nil
+	}
+	state := &toolPolicyState{
+		modelID:   modelID,
+		question:  question,
+		succeeded: make(map[string]bool),
+	}
+	if convID != "" {
+		logs, err := dao.MCPCallLog.ListSucceededTools(ctx, convID)
+		if err != nil {
+			g.Log().Warningf(ctx, "Failed to load tool history for policy evaluation: %v", err)
+		}
+		for _, log := range logs {
+			state.succeeded[log.MCPServiceName+"__"+log.ToolName] = true
+		}
+	}
+	return state
+}
+
+// recordSuccess 记录本轮成功调用的工具
+func (s *toolPolicyState) recordSuccess(toolName string) {
+	if s != nil {
+		s.succeeded[toolName] = true
+	}
+}
+
+// Filter 过滤本轮可提供给 LLM 的工具，返回允许的工具和被禁止工具的决策
+func (p *ToolPolicy) Filter(state *toolPolicyState, tools []*schema.ToolInfo) ([]*schema.ToolInfo, []ToolPolicyDecision) {
+	if p == nil || state == nil {
+		return tools, nil
+	}
+	allowed := make([]*schema.ToolInfo, 0, len(tools))
+	var denied []ToolPolicyDecision
+	for _, tool := range tools {
+		if decision := p.Check(state, tool.Name); decision != nil {
+			denied = append(denied, *decision)
+			continue
+		}
+		allowed = append(allowed, tool)
+	}
+	return allowed, denied
+}
+
+// Check 检查工具是否被策略禁止，允许时返回 nil
+func (p *ToolPolicy) Check(state *toolPolicyState, toolName string) *ToolPolicyDecision {
+	if p == nil || state == nil {
+		return nil
+	}
+	for _, rule := range p.rules {
+		if !rule.appliesTo(state.modelID, toolName) {
+			continue
+		}
+		matched, condition := rule.matches(state)
+		switch {
+		case rule.Effect == ToolPolicyDeny && matched:
+			return &ToolPolicyDecision{Rule: rule.Name, Tool: toolName, Reason: condition}
+		case rule.Effect == ToolPolicyRequire && !matched:
+			return &ToolPolicyDecision{Rule: rule.Name, Tool: toolName, Reason: "unmet: " + condition}
+		}
+	}
+	return nil
+}
+
+// appliesTo 规则是否作用于该模型和工具
+func (r *ToolPolicyRule) appliesTo(modelID, toolName string) bool {
+	if len(r.Models) > 0 && !slices.Contains(r.Models, modelID) {
+		return false
+	}
+	return matchAnyTool(r.Tools, toolName)
+}
+
+// matches 判断规则条件是否满足，返回用于日志的条件描述（不满足时为第一个不满足的条件）
+func (r *ToolPolicyRule) matches(state *toolPolicyState) (bool, string) {
+	var conditions []string
+	if len(r.QuestionKeywords) > 0 {
+		question := strings.ToLower(state.question)
+		keyword := ""
+		for _, k := range r.QuestionKeywords {
+			if k != "" && strings.Contains(question, strings.ToLower(k)) {
+				keyword = k
+				break
+			}
+		}
+		if keyword == "" {
+			return false, fmt.Sprintf("question mentions one of %v", r.QuestionKeywords)
+		}
+		conditions = append(conditions, fmt.Sprintf("question mentions %q", keyword))
+	}
+	if r.questionRegexp != nil {
+		if !r.questionRegexp.MatchString(state.question) {
+			return false, fmt.Sprintf("question matches /%s/", r.QuestionPattern)
+		}
+		conditions = append(conditions, fmt.Sprintf("question matches /%s/", r.QuestionPattern))
+	}
+	if len(r.AfterTools) > 0 {
+		called := ""
+		for toolName := range state.succeeded {
+			if matchAnyTool(r.AfterTools, toolName) {
+				called = toolName
+				break
+			}
+		}
+		if called == "" {
+			return false, fmt.Sprintf("successful call of %v", r.AfterTools)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s succeeded", called))
+	}
+	if len(conditions) == 0 {
+		return true, "always"
+	}
+	return true, strings.Join(conditions, ", ")
+}
+
+// matchAnyTool 工具名是否匹配任一模式（path.Match 通配符语法）
+func matchAnyTool(patterns []string, toolName string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, toolName); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
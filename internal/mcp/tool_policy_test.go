@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestToolPolicyCheck(t *testing.T) {
+	policy := newToolPolicy(context.Background(), []*ToolPolicyRule{
+		{Name: "export-after-query", Tools: []string{"*__file_export"}, Effect: "require", AfterTools: []string{"*__nl2sql"}},
+		{Name: "no-tools-on-pii", Tools: []string{"*"}, Effect: "Deny", QuestionKeywords: []string{"身份证", "SSN"}},
+		{Name: "model-scoped", Models: []string{"model-a"}, Tools: []string{"crm__*"}, Effect: "deny"},
+		{Name: "invalid", Tools: []string{"*"}, Effect: "allow"},
+	})
+
+	tests := []struct {
+		name      string
+		modelID   string
+		question  string
+		succeeded []string
+		tool      string
+		wantRule  string
+	}{
+		{name: "export before query", question: "导出报表", tool: "data__file_export", wantRule: "export-after-query"},
+		{name: "export after query", question: "导出报表", succeeded: []string{"data__nl2sql"}, tool: "data__file_export"},
+		{name: "pii keyword denies all tools", question: "查询张三的身份证号", tool: "data__nl2sql", wantRule: "no-tools-on-pii"},
+		{name: "keyword is case insensitive", question: "what is my ssn", tool: "workspace__read_file", wantRule: "no-tools-on-pii"},
+		{name: "unrelated tool allowed", question: "今天天气", tool: "weather__query"},
+		{name: "model scoped rule applies", modelID: "model-a", question: "客户列表", tool: "crm__list", wantRule: "model-scoped"},
+		{name: "model scoped rule other model", modelID: "model-b", question: "客户列表", tool: "crm__list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &toolPolicyState{modelID: tt.modelID, question: tt.question, succeeded: map[string]bool{}}
+			for _, name := range tt.succeeded {
+				state.recordSuccess(name)
+			}
+			decision := policy.Check(state, tt.tool)
+			gotRule := ""
+			if decision != nil {
+				gotRule = decision.Rule
+			}
+			if gotRule != tt.wantRule {
+				t.Errorf("Check(%s) denied by %q, want %q", tt.tool, gotRule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestToolPolicyFilter(t *testing.T) {
+	policy := newToolPolicy(context.Background(), []*ToolPolicyRule{
+		{Name: "export-after-query", Tools: []string{"*__file_export"}, Effect: "require", AfterTools: []string{"*__nl2sql"}},
+	})
+	tools := []*schema.ToolInfo{{Name: "data__nl2sql"}, {Name: "data__file_export"}}
+	state := policy.newState(context.Background(), "", "导出报表", "")
+
+	allowed, denied := policy.Filter(state, tools)
+	if len(allowed) != 1 || allowed[0].Name != "data__nl2sql" || len(denied) != 1 {
+		t.Fatalf("before query: allowed=%v denied=%v", allowed, denied)
+	}
+
+	// 查询成功后下一轮解锁导出工具
+	state.recordSuccess("data__nl2sql")
+	if allowed, denied = policy.Filter(state, tools); len(allowed) != 2 || len(denied) != 0 {
+		t.Fatalf("after query: allowed=%v denied=%v", allowed, denied)
+	}
+
+	// 未启用策略时不过滤
+	var disabled *ToolPolicy
+	if allowed, _ = disabled.Filter(disabled.newState(context.Background(), "", "", ""), tools); len(allowed) != 2 {
+		t.Fatalf("nil policy should keep all tools, got %v", allowed)
+	}
+}
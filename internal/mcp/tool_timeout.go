@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 默认超时
+const (
+	defaultToolTimeout  = 60 * time.Second  // 单个工具调用
+	defaultTotalTimeout = 180 * time.Second // 一次对话的工具调用阶段
+
+	// maxToolTimeout MCP 服务按工具设置的超时上限（秒）
+	maxToolTimeout = 3600
+)
+
+// ErrToolTimeout 工具调用超时
+var ErrToolTimeout = errors.New("工具调用超时")
+
+// ToolTimeoutRule 按工具名覆盖单个工具调用的超时
+type ToolTimeoutRule struct {
+	Pattern string `json:"pattern"` // 工具名模式，格式为 服务名__工具名，支持 path.Match 通配
+	Timeout int    `json:"timeout"` // 超时（秒），0 表示不限制
+}
+
+// ToolTimeouts 工具调用超时配置：单个工具调用超时后返回错误给 LLM，整个工具调用阶段超时后不再调用工具，
+// 基于已有的工具结果生成最终答案，避免无响应的外部 MCP 服务阻塞整轮对话
+type ToolTimeouts struct {
+	Default time.Duration            // 单个工具调用的默认超时，0 表示不限制
+	Total   time.Duration            // 工具调用阶段的总超时，0 表示不限制
+	Tools   map[string]time.Duration // MCP 服务按工具设置的超时（服务名__工具名），优先于 Rules
+	Rules   []*ToolTimeoutRule
+}
+
+// LoadToolTimeouts 从 toolTimeout 配置读取超时
+func LoadToolTimeouts(ctx context.Context) ToolTimeouts {
+	timeouts := ToolTimeouts{
+		Default: time.Duration(g.Cfg().MustGet(ctx, "toolTimeout.default", int(defaultToolTimeout/time.Second)).Int()) * time.Second,
+		Total:   time.Duration(g.Cfg().MustGet(ctx, "toolTimeout.total", int(defaultTotalTimeout/time.Second)).Int()) * time.Second,
+	}
+	if err := g.Cfg().MustGet(ctx, "toolTimeout.tools").Scan(&timeouts.Rules); err != nil {
+		g.Log().Errorf(ctx, "Failed to load tool timeout rules: %v", err)
+	}
+	return timeouts
+}
+
+// For 工具调用的超时：优先使用服务为该工具设置的超时，其次按顺序使用第一条匹配的规则，都没有时使用默认超时
+func (t ToolTimeouts) For(toolName string) time.Duration {
+	if timeout, ok := t.Tools[toolName]; ok {
+		return timeout
+	}
+	for _, rule := range t.Rules {
+		if rule == nil {
+			continue
+		}
+		if ok, err := path.Match(rule.Pattern, toolName); err == nil && ok {
+			return time.Duration(rule.Timeout) * time.Second
+		}
+	}
+	return t.Default
+}
+
+// toolCall 在上下文中执行一次工具调用
+type toolCall func(ctx context.Context) (*schema.Document, *v1.MCPResult, error)
+
+// callWithTimeout 在独立的超时上下文中执行工具调用，取消通过上下文传递给 MCP 请求和本地工具；
+// 工具不响应取消时也在超时后返回，调用在后台结束
+func callWithTimeout(ctx context.Context, toolName string, timeout time.Duration, call toolCall) (*schema.Document, *v1.MCPResult, error) {
+	if timeout <= 0 {
+		return call(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type callResult struct {
+		doc    *schema.Document
+		result *v1.MCPResult
+		err    error
+	}
+	done := make(chan callResult, 1)
+	common.SafeGo(callCtx, "CallTool", func() {
+		doc, result, err := call(callCtx)
+		done <- callResult{doc: doc, result: result, err: err}
+	})
+
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, nil, fmt.Errorf("%w: %s 超过 %s", ErrToolTimeout, toolName, timeout)
+		}
+		return res.doc, res.result, res.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, fmt.Errorf("%w: %s 超过 %s", ErrToolTimeout, toolName, timeout)
+	}
+}
+
+// toolTimeouts 各 MCP 服务按工具设置的超时，键为 服务名__工具名
+func (tc *MCPToolCaller) toolTimeouts() map[string]time.Duration {
+	timeouts := make(map[string]time.Duration)
+	for serviceName, service := range tc.services {
+		for toolName, seconds := range ParseToolTimeouts(service.Registry.ToolTimeouts) {
+			timeouts[serviceName+"__"+toolName] = time.Duration(seconds) * time.Second
+		}
+	}
+	return timeouts
+}
+
+// serviceTimeout MCP 服务的请求超时（HTTP 客户端超时，未设置时为 30 秒），工具超时较长时可能先于它触发
+func (tc *MCPToolCaller) serviceTimeout(serviceName string) time.Duration {
+	if service, ok := tc.services[serviceName]; ok && service.Registry.Timeout > 0 {
+		return time.Duration(service.Registry.Timeout) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// ParseToolTimeouts 解析 MCP 服务按工具设置的超时（JSON，工具名 -> 秒），为空或格式错误时返回 nil
+func ParseToolTimeouts(raw string) map[string]int {
+	if raw == "" {
+		return nil
+	}
+	var timeouts map[string]int
+	if err := json.Unmarshal([]byte(raw), &timeouts); err != nil {
+		return nil
+	}
+	return timeouts
+}
+
+// EncodeToolTimeouts 校验并序列化按工具设置的超时，超时须在 0 到 maxToolTimeout 秒之间，没有设置时返回空字符串
+func EncodeToolTimeouts(timeouts map[string]int) (string, error) {
+	if len(timeouts) == 0 {
+		return "", nil
+	}
+	for name, seconds := range timeouts {
+		if name == "" {
+			return "", errors.New("tool name must not be empty")
+		}
+		if seconds < 0 || seconds > maxToolTimeout {
+			return "", fmt.Errorf("timeout of tool %s must be between 0 and %d seconds, got %d", name, maxToolTimeout, seconds)
+		}
+	}
+	data, err := json.Marshal(timeouts)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// toolTimeoutResult 工具超时后返回给 LLM 的结构化工具结果
+type toolTimeoutResult struct {
+	Status         string `json:"status"` // timeout
+	Tool           string `json:"tool"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	Error          string `json:"error"`
+	Hint           string `json:"hint"`
+}
+
+// toolTimeoutMessage 工具超时的工具消息：说明超时时长且没有部分结果，由 LLM 决定重试、改用其他工具或不使用该工具直接回答，
+// 而不是让整个请求失败
+func toolTimeoutMessage(toolName string, timeout time.Duration) string {
+	seconds := int(timeout.Round(time.Second) / time.Second)
+	data, _ := json.Marshal(toolTimeoutResult{
+		Status:         "timeout",
+		Tool:           toolName,
+		TimeoutSeconds: seconds,
+		Error:          fmt.Sprintf("timed out after %ds, partial results unavailable", seconds),
+		Hint:           "可以稍后用相同或更精简的参数重试、改用其他工具，或在没有该工具结果的情况下回答并说明缺少的信息",
+	})
+	return string(data)
+}
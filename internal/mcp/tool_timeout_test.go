@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+func TestToolTimeoutsFor(t *testing.T) {
+	timeouts := ToolTimeouts{
+		Default: time.Minute,
+		Rules: []*ToolTimeoutRule{
+			{Pattern: "*__nl2sql", Timeout: 120},
+			{Pattern: "slow__*", Timeout: 0},
+			{Pattern: "*", Timeout: 5},
+		},
+	}
+	tests := []struct {
+		toolName string
+		want     time.Duration
+	}{
+		{"db__nl2sql", 120 * time.Second},
+		{"slow__report", 0},
+		{"weather__query", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := timeouts.For(tt.toolName); got != tt.want {
+			t.Errorf("For(%q) = %v, want %v", tt.toolName, got, tt.want)
+		}
+	}
+	if got := (ToolTimeouts{Default: time.Minute}).For("db__query"); got != time.Minute {
+		t.Errorf("expected default timeout, got %v", got)
+	}
+
+	// 服务按工具设置的超时优先于规则
+	timeouts.Tools = map[string]time.Duration{"db__nl2sql": 10 * time.Second, "slow__report": 0}
+	if got := timeouts.For("db__nl2sql"); got != 10*time.Second {
+		t.Errorf("expected per-tool timeout, got %v", got)
+	}
+	if got := timeouts.For("weather__query"); got != 5*time.Second {
+		t.Errorf("expected rule timeout for tools without a setting, got %v", got)
+	}
+}
+
+func TestEncodeToolTimeouts(t *testing.T) {
+	raw, err := EncodeToolTimeouts(map[string]int{"nl2sql": 120, "report": 0})
+	if err != nil {
+		t.Fatalf("EncodeToolTimeouts() error = %v", err)
+	}
+	if got := ParseToolTimeouts(raw); len(got) != 2 || got["nl2sql"] != 120 || got["report"] != 0 {
+		t.Errorf("ParseToolTimeouts(%s) = %v", raw, got)
+	}
+	if raw, err = EncodeToolTimeouts(map[string]int{}); err != nil || raw != "" {
+		t.Errorf("empty settings should encode to \"\", got %q, %v", raw, err)
+	}
+	for _, invalid := range []map[string]int{{"nl2sql": -1}, {"nl2sql": maxToolTimeout + 1}, {"": 10}} {
+		if _, err = EncodeToolTimeouts(invalid); err == nil {
+			t.Errorf("EncodeToolTimeouts(%v) should fail", invalid)
+		}
+	}
+	if got := ParseToolTimeouts("not json"); got != nil {
+		t.Errorf("invalid JSON should parse to nil, got %v", got)
+	}
+}
+
+func TestToolTimeoutMessage(t *testing.T) {
+	var result toolTimeoutResult
+	if err := json.Unmarshal([]byte(toolTimeoutMessage("db__nl2sql", 1500*time.Millisecond)), &result); err != nil {
+		t.Fatalf("timeout message is not JSON: %v", err)
+	}
+	if result.Status != "timeout" || result.Tool != "db__nl2sql" || result.TimeoutSeconds != 2 ||
+		result.Error != "timed out after 2s, partial results unavailable" || result.Hint == "" {
+		t.Errorf("unexpected timeout message: %+v", result)
+	}
+}
+
+func TestCallWithTimeout(t *testing.T) {
+	hung := func(ctx context.Context) (*schema.Document, *v1.MCPResult, error) {
+		time.Sleep(time.Second)
+		return &schema.Document{}, &v1.MCPResult{}, nil
+	}
+	start := time.Now()
+	_, _, err := callWithTimeout(context.Background(), "slow__tool", 20*time.Millisecond, hung)
+	if !errors.Is(err, ErrToolTimeout) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("hung tool blocked the caller for %v", time.Since(start))
+	}
+
+	fast := func(ctx context.Context) (*schema.Document, *v1.MCPResult, error) {
+		return &schema.Document{Content: "ok"}, &v1.MCPResult{Content: "ok"}, nil
+	}
+	doc, result, err := callWithTimeout(context.Background(), "fast__tool", time.Second, fast)
+	if err != nil || doc.Content != "ok" || result.Content != "ok" {
+		t.Errorf("unexpected result: %v %v %v", doc, result, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancellable := func(ctx context.Context) (*schema.Document, *v1.MCPResult, error) {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}
+	if _, _, err = callWithTimeout(ctx, "fast__tool", time.Second, cancellable); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
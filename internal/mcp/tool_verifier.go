@@ -0,0 +1,244 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/dao"
+	"github.com/Malowking/kbgo/internal/mcp/client"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// ToolVerifyConfig 工具缓存后台校验配置
+type ToolVerifyConfig struct {
+	MinInterval     time.Duration // 同一服务两次校验的最小间隔
+	MaxServices     int           // 每轮最多校验的服务数
+	ServiceInterval time.Duration // 相邻两个服务校验之间的等待时间，避免集中请求
+}
+
+// InitToolVerifier 按 mcpToolVerify 配置定时在后台核对 MCP 服务的工具缓存
+func InitToolVerifier() {
+	ctx := gctx.New()
+	if !g.Cfg().MustGet(ctx, "mcpToolVerify.enabled", true).Bool() {
+		g.Log().Info(ctx, "MCP tool verification is disabled")
+		return
+	}
+	cfg := &ToolVerifyConfig{
+		MinInterval:     time.Duration(g.Cfg().MustGet(ctx, "mcpToolVerify.minIntervalMinutes", 60).Int()) * time.Minute,
+		MaxServices:     g.Cfg().MustGet(ctx, "mcpToolVerify.maxServicesPerRun", 5).Int(),
+		ServiceInterval: time.Duration(g.Cfg().MustGet(ctx, "mcpToolVerify.serviceIntervalMs", 2000).Int()) * time.Millisecond,
+	}
+	pattern := g.Cfg().MustGet(ctx, "mcpToolVerify.cron", "0 */15 * * * *").String()
+	_, err := gcron.AddSingleton(ctx, pattern, func(ctx context.Context) {
+		RunToolVerification(ctx, cfg)
+	}, "mcp-tool-verify")
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to schedule MCP tool verification: %v", err)
+	} else {
+		g.Log().Infof(ctx, "MCP tool verification scheduled with pattern: %s", pattern)
+	}
+}
+
+// RunToolVerification 校验到期的已启用服务，最久未校验的服务优先
+func RunToolVerification(ctx context.Context, cfg *ToolVerifyConfig) {
+	registries, err := dao.MCPRegistry.ListActive(ctx)
+	if err != nil {
+		g.Log().Errorf(ctx, "MCP tool verification failed to list services: %v", err)
+		return
+	}
+	due := dueServices(registries, time.Now(), cfg.MinInterval, cfg.MaxServices)
+	for i, registry := range due {
+		if i > 0 && cfg.ServiceInterval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(cfg.ServiceInterval):
+			}
+		}
+		res, err := VerifyTools(ctx, registry)
+		if err != nil {
+			g.Log().Warningf(ctx, "MCP tool verification failed for service %s: %v", registry.Name, err)
+			continue
+		}
+		if len(res.Added)+len(res.Updated)+len(res.Deprecated)+len(res.Restored)+len(res.Invalid) > 0 {
+			g.Log().Infof(ctx, "MCP tools of service %s changed - added: %v, updated: %v, deprecated: %v, restored: %v, invalid: %v",
+				registry.Name, res.Added, res.Updated, res.Deprecated, res.Restored, res.Invalid)
+		}
+	}
+}
+
+// dueServices 选出距上次校验超过 minInterval 的服务，从未校验的服务最先，最多 limit 个（limit <= 0 不限制）
+func dueServices(registries []*gormModel.MCPRegistry, now time.Time, minInterval time.Duration, limit int) []*gormModel.MCPRegistry {
+	var due []*gormModel.MCPRegistry
+	for _, registry := range registries {
+		if registry.VerifiedAt == nil || now.Sub(*registry.VerifiedAt) >= minInterval {
+			due = append(due, registry)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		a, b := due[i].VerifiedAt, due[j].VerifiedAt
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+// VerifyTools 从服务端重新获取工具列表并与缓存核对，更新缓存后返回变更
+func VerifyTools(ctx context.Context, registry *gormModel.MCPRegistry) (*v1.MCPVerifyToolsRes, error) {
+	mcpClient := client.NewMCPClient(registry)
+	if err := mcpClient.Initialize(ctx, map[string]interface{}{
+		"name":    "kbgo",
+		"version": "1.0.0",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize MCP connection: %w", err)
+	}
+	live, err := mcpClient.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list MCP tools: %w", err)
+	}
+	return SaveLiveTools(ctx, registry, live)
+}
+
+// SaveLiveTools 用服务端返回的工具列表更新服务的工具缓存：
+// 服务端已删除的工具保留在缓存中并标记为已弃用，参数定义无效的工具记录原因，这两类工具不再提供给 LLM
+func SaveLiveTools(ctx context.Context, registry *gormModel.MCPRegistry, live []client.MCPTool) (*v1.MCPVerifyToolsRes, error) {
+	now := time.Now()
+	tools, res := reconcileTools(ParseCachedTools(registry.Tools), live, now)
+	toolsJSON, err := json.Marshal(tools)
+	if err != nil {
+		return nil, err
+	}
+	registry.Tools = string(toolsJSON)
+	registry.VerifiedAt = &now
+	if err = dao.MCPRegistry.Update(ctx, registry); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ParseCachedTools 解析服务缓存的工具列表，缓存为空或格式错误时返回 nil
+func ParseCachedTools(raw string) []v1.MCPToolInfo {
+	if raw == "" || raw == "[]" {
+		return nil
+	}
+	var tools []v1.MCPToolInfo
+	if err := json.Unmarshal([]byte(raw), &tools); err != nil {
+		return nil
+	}
+	return tools
+}
+
+// usableTools 缓存中可以提供给 LLM 的工具（排除已弃用和参数定义无效的工具）
+func usableTools(infos []v1.MCPToolInfo) []client.MCPTool {
+	tools := make([]client.MCPTool, 0, len(infos))
+	for _, info := range infos {
+		if info.Deprecated || info.SchemaError != "" {
+			continue
+		}
+		tools = append(tools, client.MCPTool{
+			Name:        info.Name,
+			Description: info.Description,
+			InputSchema: info.InputSchema,
+		})
+	}
+	return tools
+}
+
+// reconcileTools 合并缓存和服务端的工具列表：服务端的工具按服务端顺序在前，已弃用的工具在后
+func reconcileTools(cached []v1.MCPToolInfo, live []client.MCPTool, now time.Time) ([]v1.MCPToolInfo, *v1.MCPVerifyToolsRes) {
+	res := &v1.MCPVerifyToolsRes{VerifiedAt: now.Format(time.RFC3339)}
+	previous := make(map[string]v1.MCPToolInfo, len(cached))
+	for _, info := range cached {
+		previous[info.Name] = info
+	}
+
+	tools := make([]v1.MCPToolInfo, 0, len(live)+len(cached))
+	seen := make(map[string]bool, len(live))
+	for _, tool := range live {
+		if seen[tool.Name] {
+			continue
+		}
+		seen[tool.Name] = true
+		info := v1.MCPToolInfo{Name: tool.Name, Description: tool.Description, InputSchema: tool.InputSchema}
+		if err := validateInputSchema(tool.InputSchema); err != nil {
+			info.SchemaError = err.Error()
+			res.Invalid = append(res.Invalid, tool.Name)
+		}
+		old, ok := previous[tool.Name]
+		switch {
+		case !ok:
+			res.Added = append(res.Added, tool.Name)
+		case old.Deprecated:
+			res.Restored = append(res.Restored, tool.Name)
+		case old.Description != info.Description || !reflect.DeepEqual(old.InputSchema, info.InputSchema) || old.SchemaError != info.SchemaError:
+			res.Updated = append(res.Updated, tool.Name)
+		}
+		tools = append(tools, info)
+	}
+
+	for _, info := range cached {
+		if seen[info.Name] {
+			continue
+		}
+		seen[info.Name] = true
+		if !info.Deprecated {
+			info.Deprecated = true
+			info.DeprecatedAt = res.VerifiedAt
+			res.Deprecated = append(res.Deprecated, info.Name)
+		}
+		tools = append(tools, info)
+	}
+	res.Tools = tools
+	return tools, res
+}
+
+// validateInputSchema 校验工具参数定义（JSON Schema）的基本结构：
+// 顶层类型为 object，properties 的每个参数都是对象，required 只引用已定义的参数
+func validateInputSchema(inputSchema map[string]interface{}) error {
+	if len(inputSchema) == 0 {
+		return nil
+	}
+	if typ, ok := inputSchema["type"]; ok && typ != "object" {
+		return fmt.Errorf("input schema type must be object, got %v", typ)
+	}
+	var properties map[string]interface{}
+	if raw, ok := inputSchema["properties"]; ok && raw != nil {
+		if properties, ok = raw.(map[string]interface{}); !ok {
+			return fmt.Errorf("input schema properties must be an object")
+		}
+		for name, def := range properties {
+			if _, ok := def.(map[string]interface{}); !ok {
+				return fmt.Errorf("definition of parameter %s must be an object", name)
+			}
+		}
+	}
+	if raw, ok := inputSchema["required"]; ok && raw != nil {
+		required, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("input schema required must be an array")
+		}
+		for _, item := range required {
+			name, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("input schema required must contain parameter names")
+			}
+			if _, defined := properties[name]; !defined {
+				return fmt.Errorf("required parameter %s is not defined in properties", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/mcp/client"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+)
+
+func TestReconcileTools(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	schemaA := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"q": map[string]interface{}{"type": "string"}}}
+	cached := []v1.MCPToolInfo{
+		{Name: "search", Description: "old", InputSchema: schemaA},
+		{Name: "same", InputSchema: schemaA},
+		{Name: "removed"},
+		{Name: "gone", Deprecated: true, DeprecatedAt: "2025-12-01T00:00:00Z"},
+		{Name: "back", Deprecated: true, DeprecatedAt: "2025-12-01T00:00:00Z"},
+	}
+	live := []client.MCPTool{
+		{Name: "search", Description: "new", InputSchema: schemaA},
+		{Name: "same", InputSchema: schemaA},
+		{Name: "back"},
+		{Name: "fresh", InputSchema: map[string]interface{}{"type": "object", "required": []interface{}{"x"}}},
+	}
+
+	tools, res := reconcileTools(cached, live, now)
+
+	check := func(name string, got, want []string) {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	check("added", res.Added, []string{"fresh"})
+	check("updated", res.Updated, []string{"search"})
+	check("deprecated", res.Deprecated, []string{"removed"})
+	check("restored", res.Restored, []string{"back"})
+	check("invalid", res.Invalid, []string{"fresh"})
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	check("order", names, []string{"search", "same", "back", "fresh", "removed", "gone"})
+	if tools[4].DeprecatedAt != "2026-01-02T03:04:05Z" || tools[5].DeprecatedAt != "2025-12-01T00:00:00Z" {
+		t.Errorf("unexpected deprecation times: %q, %q", tools[4].DeprecatedAt, tools[5].DeprecatedAt)
+	}
+	if tools[2].Deprecated {
+		t.Error("restored tool should no longer be deprecated")
+	}
+
+	// 已弃用和参数定义无效的工具不提供给 LLM
+	var usable []string
+	for _, tool := range usableTools(tools) {
+		usable = append(usable, tool.Name)
+	}
+	check("usable", usable, []string{"search", "same", "back"})
+}
+
+func TestValidateInputSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  map[string]interface{}
+		wantErr bool
+	}{
+		{name: "empty", schema: nil},
+		{name: "valid", schema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"q": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"q"},
+		}},
+		{name: "not object", schema: map[string]interface{}{"type": "string"}, wantErr: true},
+		{name: "bad properties", schema: map[string]interface{}{"properties": []interface{}{"q"}}, wantErr: true},
+		{name: "bad parameter", schema: map[string]interface{}{"properties": map[string]interface{}{"q": "string"}}, wantErr: true},
+		{name: "undefined required", schema: map[string]interface{}{"properties": map[string]interface{}{}, "required": []interface{}{"q"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateInputSchema(tt.schema); (err != nil) != tt.wantErr {
+				t.Errorf("validateInputSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDueServices(t *testing.T) {
+	now := time.Now()
+	recent, old, older := now.Add(-10*time.Minute), now.Add(-2*time.Hour), now.Add(-3*time.Hour)
+	registries := []*gormModel.MCPRegistry{
+		{Name: "recent", VerifiedAt: &recent},
+		{Name: "old", VerifiedAt: &old},
+		{Name: "never"},
+		{Name: "older", VerifiedAt: &older},
+	}
+
+	var names []string
+	for _, registry := range dueServices(registries, now, time.Hour, 2) {
+		names = append(names, registry.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"never", "older"}) {
+		t.Errorf("dueServices() = %v", names)
+	}
+	if got := dueServices(registries, now, time.Hour, 0); len(got) != 3 {
+		t.Errorf("expected 3 due services without limit, got %d", len(got))
+	}
+}
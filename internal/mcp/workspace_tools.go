@@ -0,0 +1,104 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Malowking/kbgo/internal/logic/workspace"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// WorkspaceServiceName 内置会话工作区工具的服务名，与 MCP 服务名共用 serviceName__toolName 命名
+const WorkspaceServiceName = "workspace"
+
+const (
+	workspaceToolWrite = "write_file"
+	workspaceToolRead  = "read_file"
+	workspaceToolList  = "list_files"
+)
+
+// workspaceLLMTools 获取内置工作区工具定义
+// serviceToolsFilter 不为 nil 时，只返回 workspace 服务下允许的工具
+func workspaceLLMTools(serviceToolsFilter map[string][]string) []*schema.ToolInfo {
+	tools := []*schema.ToolInfo{
+		{
+			Name: WorkspaceServiceName + "__" + workspaceToolWrite,
+			Desc: "将内容保存到当前会话的工作区文件中，后续轮次的工具可以通过 " + workspace.RefPrefix + "文件名 引用该文件内容",
+			ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+				"name":    {Type: "string", Desc: "文件名，如 result.csv", Required: true},
+				"content": {Type: "string", Desc: "文件内容", Required: true},
+			}),
+		},
+		{
+			Name: WorkspaceServiceName + "__" + workspaceToolRead,
+			Desc: "读取当前会话工作区中的文件内容",
+			ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+				"name": {Type: "string", Desc: "文件名", Required: true},
+			}),
+		},
+		{
+			Name: WorkspaceServiceName + "__" + workspaceToolList,
+			Desc: "列出当前会话工作区中的所有文件",
+		},
+	}
+
+	if serviceToolsFilter == nil {
+		return tools
+	}
+	allowedTools, exists := serviceToolsFilter[WorkspaceServiceName]
+	if !exists {
+		return nil
+	}
+	var filtered []*schema.ToolInfo
+	for _, tool := range tools {
+		_, toolName, _ := strings.Cut(tool.Name, "__")
+		for _, allowed := range allowedTools {
+			if allowed == toolName {
+				filtered = append(filtered, tool)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// callWorkspaceTool 执行内置工作区工具
+func callWorkspaceTool(ctx context.Context, convID string, toolName string, args map[string]interface{}) (string, error) {
+	if convID == "" {
+		return "", fmt.Errorf("工作区工具需要会话ID")
+	}
+
+	name, _ := args["name"].(string)
+	switch toolName {
+	case workspaceToolWrite:
+		content, _ := args["content"].(string)
+		info, err := workspace.WriteFile(ctx, convID, name, []byte(content))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已保存文件 %s（%d 字节），可通过 %s%s 引用", info.Name, info.Size, workspace.RefPrefix, info.Name), nil
+	case workspaceToolRead:
+		data, err := workspace.ReadFile(ctx, convID, name)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case workspaceToolList:
+		files, totalSize, err := workspace.ListFiles(ctx, convID)
+		if err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "工作区为空", nil
+		}
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("共 %d 个文件，总大小 %d 字节:\n", len(files), totalSize))
+		for _, f := range files {
+			builder.WriteString(fmt.Sprintf("- %s (%d 字节)\n", f.Name, f.Size))
+		}
+		return builder.String(), nil
+	default:
+		return "", fmt.Errorf("未知的工作区工具: %s", toolName)
+	}
+}
@@ -7,13 +7,17 @@ import (
 
 // KnowledgeBase is the golang structure of table knowledge_base for DAO operations like Where/Data.
 type KnowledgeBase struct {
-	g.Meta         `orm:"table:knowledge_base, do:true"`
-	Id             interface{} // 主键ID
-	Name           interface{} // 知识库名称
-	Description    interface{} // 知识库描述
-	Category       interface{} // 知识库分类
-	CollectionName interface{} // milvus collection name
-	Status         interface{} // 状态：0-禁用，1-启用
-	CreateTime     *gtime.Time // 创建时间
-	UpdateTime     *gtime.Time // 更新时间
+	g.Meta              `orm:"table:knowledge_base, do:true"`
+	Id                  interface{} // 主键ID
+	Name                interface{} // 知识库名称
+	Description         interface{} // 知识库描述
+	Category            interface{} // 知识库分类
+	CollectionName      interface{} // milvus collection name
+	Status              interface{} // 状态：0-禁用，1-启用
+	SparseModelId       interface{} // 稀疏向量模型ID
+	SparseWeight        interface{} // 稀疏检索融合权重
+	RecencyWeight       interface{} // 新近度加权权重
+	RecencyHalfLifeDays interface{} // 新近度半衰期（天）
+	CreateTime          *gtime.Time // 创建时间
+	UpdateTime          *gtime.Time // 更新时间
 }
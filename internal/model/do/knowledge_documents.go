@@ -11,16 +11,33 @@ import (
 
 // KnowledgeDocuments is the golang structure of table knowledge_documents for DAO operations like Where/Data.
 type KnowledgeDocuments struct {
-	g.Meta         `orm:"table:knowledge_documents, do:true"`
-	Id             interface{} //
-	KnowledgeId    interface{} //
-	FileName       interface{} //
-	FileExtension  interface{} // 添加文件后缀名字段
-	CollectionName interface{} //
-	SHA256         interface{} //
-	RustfsBucket   interface{} //
-	RustfsLocation interface{} //
-	Status         interface{} //
-	CreateTime     *gtime.Time //
-	UpdateTime     *gtime.Time //
+	g.Meta               `orm:"table:knowledge_documents, do:true"`
+	Id                   interface{} //
+	KnowledgeId          interface{} //
+	FileName             interface{} //
+	FileExtension        interface{} // 添加文件后缀名字段
+	CollectionName       interface{} //
+	SHA256               interface{} //
+	RustfsBucket         interface{} //
+	RustfsLocation       interface{} //
+	Status               interface{} //
+	SecurityLabel        interface{} // 文档安全标签
+	SectionLabels        interface{} // 分段安全标签规则（JSON）
+	FieldMapping         interface{} // 结构化数据字段映射（JSON）
+	ValidFrom            *gtime.Time // 生效时间
+	ValidUntil           *gtime.Time // 失效时间
+	VersionGroup         interface{} // 版本链ID
+	Version              interface{} // 版本号
+	PreviousId           interface{} // 上一个版本的文档ID
+	SupersededAt         *gtime.Time // 被新版本取代的时间
+	EmbeddingModelId     interface{} // 生成向量使用的 embedding 模型ID
+	EmbeddingFingerprint interface{} // 生成向量时的 embedding 模型配置指纹
+	Toc                  interface{} // 标题目录（JSON）
+	MetaTitle            interface{} // LLM 提取的文档标题
+	MetaAuthor           interface{} // LLM 提取的作者
+	MetaDate             interface{} // LLM 提取的日期
+	MetaTopics           interface{} // LLM 提取的主题（JSON）
+	MetaSummary          interface{} // LLM 生成的摘要
+	CreateTime           *gtime.Time //
+	UpdateTime           *gtime.Time //
 }
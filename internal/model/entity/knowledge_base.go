@@ -6,12 +6,16 @@ import (
 
 // KnowledgeBase is the golang structure for table knowledge_base.
 type KnowledgeBase struct {
-	Id             string      `json:"id"               orm:"id"                 description:"主键ID"`         // 主键ID
-	Name           string      `json:"name"             orm:"name"               description:"知识库名称"`        // 知识库名称
-	Description    string      `json:"description"      orm:"description"        description:"知识库描述"`        // 知识库描述
-	Category       string      `json:"category"         orm:"category"           description:"知识库分类"`        // 知识库分类
-	CollectionName string      `json:"collectionName"   orm:"collection_name"    description:"Milvus文本集合名"`  // Milvus文本集合名
-	Status         int         `json:"status"           orm:"status"             description:"状态：0-禁用，1-启用"` // 状态：0-禁用，1-启用
-	CreateTime     *gtime.Time `json:"createTime"       orm:"create_time"        description:"创建时间"`         // 创建时间
-	UpdateTime     *gtime.Time `json:"updateTime"       orm:"update_time"        description:"更新时间"`         // 更新时间
+	Id                  string      `json:"id"               orm:"id"                 description:"主键ID"`             // 主键ID
+	Name                string      `json:"name"             orm:"name"               description:"知识库名称"`            // 知识库名称
+	Description         string      `json:"description"      orm:"description"        description:"知识库描述"`            // 知识库描述
+	Category            string      `json:"category"         orm:"category"           description:"知识库分类"`            // 知识库分类
+	CollectionName      string      `json:"collectionName"   orm:"collection_name"    description:"Milvus文本集合名"`      // Milvus文本集合名
+	Status              int         `json:"status"           orm:"status"             description:"状态：0-禁用，1-启用"`     // 状态：0-禁用，1-启用
+	SparseModelId       string      `json:"sparseModelId"    orm:"sparse_model_id"    description:"稀疏向量模型ID"`         // 稀疏向量模型ID
+	SparseWeight        float64     `json:"sparseWeight"     orm:"sparse_weight"      description:"稀疏检索融合权重"`         // 稀疏检索融合权重
+	RecencyWeight       float64     `json:"recencyWeight"       orm:"recency_weight"         description:"新近度加权权重"`   // 新近度加权权重
+	RecencyHalfLifeDays int         `json:"recencyHalfLifeDays" orm:"recency_half_life_days" description:"新近度半衰期（天）"` // 新近度半衰期（天）
+	CreateTime          *gtime.Time `json:"createTime"       orm:"create_time"        description:"创建时间"`             // 创建时间
+	UpdateTime          *gtime.Time `json:"updateTime"       orm:"update_time"        description:"更新时间"`             // 更新时间
 }
@@ -10,16 +10,33 @@ import (
 
 // KnowledgeDocuments is the golang structure for table knowledge_documents.
 type KnowledgeDocuments struct {
-	Id             string      `json:"id"                orm:"id"                  description:""` //
-	KnowledgeId    string      `json:"knowledgeId"       orm:"knowledge_id"        description:""` //
-	FileName       string      `json:"fileName"          orm:"file_name"           description:""` //
-	FileExtension  string      `json:"fileExtension"     orm:"file_extension"      description:""` // 添加文件后缀名字段
-	CollectionName string      `json:"collectionName"    orm:"collection_name"     description:""` //
-	SHA256         string      `json:"sha256"            orm:"sha256"              description:""` //
-	RustfsBucket   string      `json:"rustfsBucket"      orm:"rustfs_bucket"       description:""` //
-	RustfsLocation string      `json:"rustfsLocation"    orm:"rustfs_location"     description:""` //
-	LocalFilePath  string      `json:"localFilePath"     orm:"local_file_path"     description:""` // 本地文件路径
-	Status         int         `json:"status"            orm:"status"              description:""` //
-	CreateTime     *gtime.Time `json:"CreateTime"        orm:"create_time"         description:""` //
-	UpdateTime     *gtime.Time `json:"UpdateTime"        orm:"update_time"         description:""` //
+	Id                   string      `json:"id"                orm:"id"                  description:""`      //
+	KnowledgeId          string      `json:"knowledgeId"       orm:"knowledge_id"        description:""`      //
+	FileName             string      `json:"fileName"          orm:"file_name"           description:""`      //
+	FileExtension        string      `json:"fileExtension"     orm:"file_extension"      description:""`      // 添加文件后缀名字段
+	CollectionName       string      `json:"collectionName"    orm:"collection_name"     description:""`      //
+	SHA256               string      `json:"sha256"            orm:"sha256"              description:""`      //
+	RustfsBucket         string      `json:"rustfsBucket"      orm:"rustfs_bucket"       description:""`      //
+	RustfsLocation       string      `json:"rustfsLocation"    orm:"rustfs_location"     description:""`      //
+	LocalFilePath        string      `json:"localFilePath"     orm:"local_file_path"     description:""`      // 本地文件路径
+	Status               int         `json:"status"            orm:"status"              description:""`      //
+	SecurityLabel        string      `json:"securityLabel"     orm:"security_label"      description:""`      // 文档安全标签
+	SectionLabels        string      `json:"sectionLabels"     orm:"section_labels"      description:""`      // 分段安全标签规则（JSON）
+	FieldMapping         string      `json:"fieldMapping"      orm:"field_mapping"       description:""`      // 结构化数据字段映射（JSON）
+	ValidFrom            *gtime.Time `json:"validFrom"         orm:"valid_from"          description:""`      // 生效时间
+	ValidUntil           *gtime.Time `json:"validUntil"        orm:"valid_until"         description:""`      // 失效时间
+	VersionGroup         string      `json:"versionGroup"      orm:"version_group"       description:""`      // 版本链ID
+	Version              int         `json:"version"           orm:"version"             description:""`      // 版本号
+	PreviousId           string      `json:"previousId"        orm:"previous_id"         description:""`      // 上一个版本的文档ID
+	SupersededAt         *gtime.Time `json:"supersededAt"      orm:"superseded_at"       description:""`      // 被新版本取代的时间
+	EmbeddingModelId     string      `json:"embeddingModelId"     orm:"embedding_model_id"    description:""` // 生成向量使用的 embedding 模型ID
+	EmbeddingFingerprint string      `json:"embeddingFingerprint" orm:"embedding_fingerprint" description:""` // 生成向量时的 embedding 模型配置指纹
+	Toc                  string      `json:"toc"               orm:"toc"                 description:""`      // 标题目录（JSON）
+	MetaTitle            string      `json:"metaTitle"         orm:"meta_title"          description:""`      // LLM 提取的文档标题
+	MetaAuthor           string      `json:"metaAuthor"        orm:"meta_author"         description:""`      // LLM 提取的作者
+	MetaDate             string      `json:"metaDate"          orm:"meta_date"           description:""`      // LLM 提取的日期
+	MetaTopics           string      `json:"metaTopics"        orm:"meta_topics"         description:""`      // LLM 提取的主题（JSON）
+	MetaSummary          string      `json:"metaSummary"       orm:"meta_summary"        description:""`      // LLM 生成的摘要
+	CreateTime           *gtime.Time `json:"CreateTime"        orm:"create_time"         description:""`      //
+	UpdateTime           *gtime.Time `json:"UpdateTime"        orm:"update_time"         description:""`      //
 }
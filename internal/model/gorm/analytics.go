@@ -0,0 +1,94 @@
+package gorm
+
+import (
+	"time"
+)
+
+// AnalyticsDailyRollup 按天、按模型汇总的会话统计表（由定时任务写入）
+type AnalyticsDailyRollup struct {
+	ID                uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	StatDate          string     `gorm:"column:stat_date;type:varchar(10);not null;uniqueIndex:idx_daily_date_model"`  // 统计日期 yyyy-MM-dd
+	ModelName         string     `gorm:"column:model_name;type:varchar(64);not null;uniqueIndex:idx_daily_date_model"` // 模型名称（会话维度）
+	ConversationCount int64      `gorm:"column:conversation_count;default:0"`                                          // 当天活跃会话数
+	MessageCount      int64      `gorm:"column:message_count;default:0"`                                               // 当天消息数
+	AssistantCount    int64      `gorm:"column:assistant_count;default:0"`                                             // 当天助手回复数
+	TotalTokens       int64      `gorm:"column:total_tokens;default:0"`                                                // token 消耗总量
+	AvgLatencyMs      float64    `gorm:"column:avg_latency_ms;default:0"`                                              // 助手回复平均延迟（毫秒）
+	ToolCallCount     int64      `gorm:"column:tool_call_count;default:0"`                                             // 工具调用次数
+	PositiveFeedback  int64      `gorm:"column:positive_feedback;default:0"`                                           // 正向反馈数
+	NegativeFeedback  int64      `gorm:"column:negative_feedback;default:0"`                                           // 负向反馈数
+	UpdateTime        *time.Time `gorm:"column:update_time;autoUpdateTime"`                                            // 更新时间
+}
+
+// TableName 设置表名
+func (AnalyticsDailyRollup) TableName() string {
+	return "analytics_daily_rollup"
+}
+
+// AnalyticsToolRollup 按天、按工具汇总的 MCP 调用统计表
+type AnalyticsToolRollup struct {
+	ID          uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	StatDate    string     `gorm:"column:stat_date;type:varchar(10);not null;uniqueIndex:idx_tool_date_name"`     // 统计日期 yyyy-MM-dd
+	ServiceName string     `gorm:"column:service_name;type:varchar(100);not null;uniqueIndex:idx_tool_date_name"` // MCP服务名称
+	ToolName    string     `gorm:"column:tool_name;type:varchar(100);not null;uniqueIndex:idx_tool_date_name"`    // 工具名称
+	CallCount   int64      `gorm:"column:call_count;default:0"`                                                   // 调用次数
+	FailedCount int64      `gorm:"column:failed_count;default:0"`                                                 // 失败次数
+	AvgDuration float64    `gorm:"column:avg_duration;default:0"`                                                 // 平均耗时（毫秒）
+	UpdateTime  *time.Time `gorm:"column:update_time;autoUpdateTime"`                                             // 更新时间
+}
+
+// TableName 设置表名
+func (AnalyticsToolRollup) TableName() string {
+	return "analytics_tool_rollup"
+}
+
+// RetrievalMissLog 低分检索记录（原始事件，供汇总任务聚合未解答问题）
+type RetrievalMissLog struct {
+	ID            uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	KnowledgeID   string     `gorm:"column:knowledge_id;type:varchar(64);index"` // 知识库ID
+	Question      string     `gorm:"column:question;type:text;not null"`         // 用户问题
+	MaxScore      float64    `gorm:"column:max_score;default:0"`                 // 检索最高分
+	DocumentCount int        `gorm:"column:document_count;default:0"`            // 返回文档数
+	CreateTime    *time.Time `gorm:"column:create_time;autoCreateTime;index"`    // 创建时间
+}
+
+// TableName 设置表名
+func (RetrievalMissLog) TableName() string {
+	return "retrieval_miss_log"
+}
+
+// AnalyticsUnansweredRollup 按天汇总的未解答问题统计表
+type AnalyticsUnansweredRollup struct {
+	ID          uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	StatDate    string     `gorm:"column:stat_date;type:varchar(10);not null;index"` // 统计日期 yyyy-MM-dd
+	KnowledgeID string     `gorm:"column:knowledge_id;type:varchar(64);index"`       // 知识库ID
+	Question    string     `gorm:"column:question;type:text;not null"`               // 问题（归一化后）
+	MissCount   int64      `gorm:"column:miss_count;default:0"`                      // 出现次数
+	MaxScore    float64    `gorm:"column:max_score;default:0"`                       // 期间最高检索分
+	UpdateTime  *time.Time `gorm:"column:update_time;autoUpdateTime"`                // 更新时间
+}
+
+// TableName 设置表名
+func (AnalyticsUnansweredRollup) TableName() string {
+	return "analytics_unanswered_rollup"
+}
+
+// KnowledgeGap 知识缺口报告（由语义聚类低分检索和负反馈问题得到）
+type KnowledgeGap struct {
+	ID               uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	BatchID          string     `gorm:"column:batch_id;type:varchar(64);not null;index"` // 生成批次ID（每次任务运行一批）
+	KnowledgeID      string     `gorm:"column:knowledge_id;type:varchar(64);index"`      // 知识库ID（负反馈来源可能为空）
+	Topic            string     `gorm:"column:topic;type:text"`                          // 代表性问题
+	QuestionCount    int        `gorm:"column:question_count;default:0"`                 // 聚类中的问题数量
+	LowScoreCount    int        `gorm:"column:low_score_count;default:0"`                // 来自低分检索的问题数
+	NegativeCount    int        `gorm:"column:negative_count;default:0"`                 // 来自负反馈的问题数
+	ExampleQuestions JSON       `gorm:"column:example_questions;type:json"`              // 示例问题列表
+	PeriodStart      *time.Time `gorm:"column:period_start"`                             // 统计区间开始
+	PeriodEnd        *time.Time `gorm:"column:period_end"`                               // 统计区间结束
+	CreateTime       *time.Time `gorm:"column:create_time;autoCreateTime"`               // 创建时间
+}
+
+// TableName 设置表名
+func (KnowledgeGap) TableName() string {
+	return "knowledge_gaps"
+}
@@ -0,0 +1,28 @@
+package gorm
+
+import (
+	"time"
+)
+
+// AnswerDiff 同一问题新旧两个回答的句子级差异：重新生成的回答与原回答，或影子评估中候选回答与线上回答
+type AnswerDiff struct {
+	ID             string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	ConvID         string     `gorm:"column:conv_id;type:varchar(64);index"`          // 会话ID
+	Source         string     `gorm:"column:source;type:varchar(16);index"`           // 来源：regenerate / shadow
+	BaseMsgID      string     `gorm:"column:base_msg_id;type:varchar(64);index"`      // 原回答的消息ID（影子评估为空）
+	MsgID          string     `gorm:"column:msg_id;type:varchar(64);index"`           // 新回答的消息ID（影子评估为空）
+	ShadowResultID string     `gorm:"column:shadow_result_id;type:varchar(64);index"` // 影子评估结果ID
+	BaseModelID    string     `gorm:"column:base_model_id;type:varchar(64)"`          // 原回答使用的模型
+	ModelID        string     `gorm:"column:model_id;type:varchar(64)"`               // 新回答使用的模型
+	Segments       JSON       `gorm:"column:segments;type:json"`                      // 按顺序排列的差异片段
+	AddedCount     int        `gorm:"column:added_count"`                             // 新增的句子数
+	RemovedCount   int        `gorm:"column:removed_count"`                           // 删除的句子数
+	UnchangedCount int        `gorm:"column:unchanged_count"`                         // 未变化的句子数
+	Similarity     float64    `gorm:"column:similarity"`                              // 句子级相似度（0-1）
+	CreateTime     *time.Time `gorm:"column:create_time;autoCreateTime;index"`        // 创建时间
+}
+
+// TableName 设置表名
+func (AnswerDiff) TableName() string {
+	return "answer_diffs"
+}
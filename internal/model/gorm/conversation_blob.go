@@ -0,0 +1,22 @@
+package gorm
+
+import (
+	"time"
+)
+
+// ConversationBlob 会话元数据中转存的大字段（如上传文档的全文），内容经压缩后保存，
+// 会话元数据中只保留引用，读取时自动还原
+type ConversationBlob struct {
+	ID         uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	ConvID     string     `gorm:"column:conv_id;type:varchar(64);not null;uniqueIndex:idx_conv_blob_key"`  // 会话ID
+	MetaKey    string     `gorm:"column:meta_key;type:varchar(64);not null;uniqueIndex:idx_conv_blob_key"` // 元数据字段名
+	Encoding   string     `gorm:"column:encoding;type:varchar(16);not null"`                               // 内容编码：gzip
+	Size       int        `gorm:"column:size;default:0"`                                                   // 压缩前的字节数
+	Data       []byte     `gorm:"column:data"`                                                             // 压缩后的字段 JSON
+	CreateTime *time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TableName 设置表名
+func (ConversationBlob) TableName() string {
+	return "conversation_blobs"
+}
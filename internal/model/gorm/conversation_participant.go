@@ -0,0 +1,21 @@
+package gorm
+
+import (
+	"time"
+)
+
+// ConversationParticipant 会话参与者：多人共享的会话（如团队频道）中可以读写会话的用户，
+// 没有参与者的会话为单人会话，不做权限校验
+type ConversationParticipant struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	ConvID     string     `gorm:"column:conv_id;type:varchar(64);not null;uniqueIndex:idx_conversation_participant"`
+	UserID     string     `gorm:"column:user_id;type:varchar(100);not null;uniqueIndex:idx_conversation_participant;index"`
+	Role       string     `gorm:"column:role;type:varchar(16);not null"` // owner / member / viewer
+	CreateTime *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (ConversationParticipant) TableName() string {
+	return "conversation_participants"
+}
@@ -13,6 +13,7 @@ type Conversation struct {
 	UserID           string     `gorm:"column:user_id;type:varchar(64);not null;index"`           // 用户ID
 	Title            string     `gorm:"column:title;type:varchar(255)"`                           // 会话标题
 	ModelName        string     `gorm:"column:model_name;type:varchar(64);not null"`              // 模型名称
+	ModelID          string     `gorm:"column:model_id;type:varchar(64)"`                         // 会话当前使用的LLM模型UUID（切换模型后对后续轮次生效）
 	ConversationType string     `gorm:"column:conversation_type;type:varchar(32);default:'text'"` // 会话类型
 	Status           string     `gorm:"column:status;type:varchar(20);default:'active'"`          // 状态
 	Metadata         JSON       `gorm:"column:metadata;type:json"`                                // 扩展元数据
@@ -0,0 +1,21 @@
+package gorm
+
+import (
+	"time"
+)
+
+// FeatureFlag 运行时功能开关，project_id 为空的记录是全局设置，非空的记录覆盖该项目（租户）的设置
+type FeatureFlag struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	Name       string     `gorm:"column:name;type:varchar(64);not null;uniqueIndex:idx_feature_flag_scope"`                  // 开关名称
+	ProjectID  string     `gorm:"column:project_id;type:varchar(64);not null;default:'';uniqueIndex:idx_feature_flag_scope"` // 项目ID，空表示全局
+	Enabled    bool       `gorm:"column:enabled;not null"`                                                                   // 是否开启
+	Rollout    int        `gorm:"column:rollout;not null"`                                                                   // 开启时的灰度比例（0-100）
+	CreateTime *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (FeatureFlag) TableName() string {
+	return "feature_flags"
+}
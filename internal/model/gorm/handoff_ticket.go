@@ -0,0 +1,37 @@
+package gorm
+
+import (
+	"time"
+)
+
+// 人工接管工单状态
+const (
+	HandoffStatusOpen     = "open"     // 待人工处理，会话已冻结
+	HandoffStatusAssigned = "assigned" // 人工客服已介入
+	HandoffStatusResolved = "resolved" // 已结束，会话恢复由 AI 回答
+)
+
+// 转人工原因
+const (
+	HandoffReasonLowConfidence = "low_confidence" // 回答置信度低于升级阈值
+	HandoffReasonUserRequest   = "user_request"   // 用户要求人工服务
+)
+
+// HandoffTicket 人工接管工单，工单未结束期间会话由人工客服回复
+type HandoffTicket struct {
+	ID         string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	ConvID     string     `gorm:"column:conv_id;type:varchar(255);not null;index"` // 会话ID
+	Reason     string     `gorm:"column:reason;type:varchar(32);not null"`         // 转人工原因
+	Question   string     `gorm:"column:question;type:text"`                       // 触发转人工的用户问题
+	Confidence *float64   `gorm:"column:confidence"`                               // 触发时的回答置信度（低置信度转人工时记录）
+	Status     string     `gorm:"column:status;type:varchar(16);not null;index"`   // 工单状态
+	Assignee   string     `gorm:"column:assignee;type:varchar(255)"`               // 处理的人工客服
+	ResolvedAt *time.Time `gorm:"column:resolved_at"`                              // 结束时间
+	CreateTime *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (HandoffTicket) TableName() string {
+	return "handoff_tickets"
+}
@@ -0,0 +1,36 @@
+package gorm
+
+import (
+	"time"
+)
+
+// 死信状态
+const (
+	DeadLetterStatusDead     = "dead"     // 重试耗尽，等待处理
+	DeadLetterStatusRequeued = "requeued" // 已重新提交索引
+)
+
+// IngestDeadLetter 文档索引步骤重试耗尽后记录的死信，每个文档只保留最近一次失败
+type IngestDeadLetter struct {
+	ID          string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	DocumentID  string     `gorm:"column:document_id;type:varchar(255);uniqueIndex;not null"` // 文档ID
+	KnowledgeID string     `gorm:"column:knowledge_id;type:varchar(64);index"`                // 知识库ID
+	FileName    string     `gorm:"column:file_name;type:varchar(255)"`                        // 文件名快照
+	Step        string     `gorm:"column:step;type:varchar(64)"`                              // 失败的步骤
+	Error       string     `gorm:"column:error;type:text"`                                    // 最后一次失败原因
+	Attempts    int        `gorm:"column:attempts;default:0"`                                 // 失败步骤的尝试次数
+	Failures    int        `gorm:"column:failures;default:1"`                                 // 进入死信队列的次数（重新提交后再次失败时累加）
+	ModelID     string     `gorm:"column:model_id;type:varchar(64)"`                          // 索引参数：embedding 模型ID
+	ChunkSize   int        `gorm:"column:chunk_size"`                                         // 索引参数：分片大小
+	OverlapSize int        `gorm:"column:overlap_size"`                                       // 索引参数：分片重叠
+	Separator   string     `gorm:"column:separator;type:varchar(64)"`                         // 索引参数：自定义分隔符
+	Status      string     `gorm:"column:status;type:varchar(16);not null;index"`             // 死信状态
+	RequeueTime *time.Time `gorm:"column:requeue_time"`                                       // 最近一次重新提交时间
+	CreateTime  *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime  *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (IngestDeadLetter) TableName() string {
+	return "ingest_dead_letters"
+}
@@ -0,0 +1,20 @@
+package gorm
+
+import (
+	"time"
+)
+
+// IngestHookConfig 知识库索引预处理钩子配置，每次修改保存为一个新版本，最新版本生效
+type IngestHookConfig struct {
+	ID          string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	KnowledgeID string     `gorm:"column:knowledge_id;type:varchar(64);not null;uniqueIndex:idx_ingest_hook_kb_version"` // 知识库ID
+	Version     int        `gorm:"column:version;not null;uniqueIndex:idx_ingest_hook_kb_version"`                       // 版本号，从 1 开始
+	Hooks       JSON       `gorm:"column:hooks;type:json"`                                                               // 钩子列表，按顺序执行，空列表表示不处理
+	Comment     string     `gorm:"column:comment;type:varchar(500)"`                                                     // 修改说明
+	CreateTime  *time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TableName 设置表名
+func (IngestHookConfig) TableName() string {
+	return "ingest_hook_configs"
+}
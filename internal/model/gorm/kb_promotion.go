@@ -0,0 +1,46 @@
+package gorm
+
+import (
+	"time"
+)
+
+// 回答沉淀审核状态
+const (
+	PromotionStatusPending  = "pending"  // 待审核
+	PromotionStatusApproved = "approved" // 已通过，已写入知识库
+	PromotionStatusRejected = "rejected" // 已驳回
+)
+
+// 沉淀申请类型
+const (
+	PromotionKindAnswer     = "answer"     // 用户提交的助手回答
+	PromotionKindCorrection = "correction" // 对话中用户对助手回答的更正
+)
+
+// KBPromotion 将对话中经过验证的助手回答沉淀为知识库 FAQ 分片的申请，审核通过后写入知识库
+type KBPromotion struct {
+	ID             string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	KnowledgeID    string     `gorm:"column:knowledge_id;type:varchar(255);not null;index"`       // 目标知识库ID
+	Kind           string     `gorm:"column:kind;type:varchar(16);not null;default:answer;index"` // 申请类型：answer / correction
+	MsgID          string     `gorm:"column:msg_id;type:varchar(64);not null;index"`              // 来源助手消息ID（更正申请为被更正的回答）
+	ConvID         string     `gorm:"column:conv_id;type:varchar(64);index"`                      // 来源会话ID
+	Question       string     `gorm:"column:question;type:text"`                                  // FAQ 问题
+	Answer         string     `gorm:"column:answer;type:text"`                                    // FAQ 答案（更正申请初始为用户的更正内容）
+	OriginalAnswer string     `gorm:"column:original_answer;type:text"`                           // 被更正的助手回答（仅更正申请）
+	Sources        JSON       `gorm:"column:sources;type:json"`                                   // 回答引用的参考分片
+	Status         string     `gorm:"column:status;type:varchar(16);not null;index"`              // 审核状态
+	RequestedBy    string     `gorm:"column:requested_by;type:varchar(255)"`                      // 提交人
+	Reviewer       string     `gorm:"column:reviewer;type:varchar(255)"`                          // 审核人
+	ReviewComment  string     `gorm:"column:review_comment;type:text"`                            // 审核意见
+	DocumentID     string     `gorm:"column:document_id;type:varchar(255)"`                       // 写入的知识库文档ID
+	ChunkID        string     `gorm:"column:chunk_id;type:varchar(255)"`                          // 写入的分片ID
+	LastError      string     `gorm:"column:last_error;type:text"`                                // 最近一次写入知识库失败的原因
+	ReviewedAt     *time.Time `gorm:"column:reviewed_at"`                                         // 审核时间
+	CreateTime     *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime     *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (KBPromotion) TableName() string {
+	return "kb_promotions"
+}
@@ -6,14 +6,18 @@ import (
 
 // KnowledgeBase GORM模型定义
 type KnowledgeBase struct {
-	ID             string     `gorm:"primaryKey;column:id;type:varchar(64)"`
-	Name           string     `gorm:"column:name;type:varchar(36)"`
-	Description    string     `gorm:"column:description;type:varchar(255)"`
-	Category       string     `gorm:"column:category;type:varchar(255)"`
-	CollectionName string     `gorm:"column:collection_name;type:varchar(255)"` // milvus collection name
-	Status         int8       `gorm:"column:status;not null;default:1"`
-	CreateTime     *time.Time `gorm:"column:create_time;autoCreateTime"`
-	UpdateTime     *time.Time `gorm:"column:update_time;autoUpdateTime"`
+	ID                  string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	Name                string     `gorm:"column:name;type:varchar(36)"`
+	Description         string     `gorm:"column:description;type:varchar(255)"`
+	Category            string     `gorm:"column:category;type:varchar(255)"`
+	CollectionName      string     `gorm:"column:collection_name;type:varchar(255)"` // milvus collection name
+	Status              int8       `gorm:"column:status;not null;default:1"`
+	SparseModelID       string     `gorm:"column:sparse_model_id;type:varchar(64)"` // 稀疏向量模型ID，为空表示仅稠密检索
+	SparseWeight        float64    `gorm:"column:sparse_weight;default:0"`          // 稀疏检索分数融合权重，0 表示使用配置默认值
+	RecencyWeight       float64    `gorm:"column:recency_weight;default:0"`         // 新近度加权权重，0 表示不启用
+	RecencyHalfLifeDays int        `gorm:"column:recency_half_life_days;default:0"` // 新近度半衰期（天），0 表示使用配置默认值
+	CreateTime          *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime          *time.Time `gorm:"column:update_time;autoUpdateTime"`
 }
 
 // TableName 设置表名
@@ -6,18 +6,35 @@ import (
 
 // KnowledgeDocuments GORM模型定义
 type KnowledgeDocuments struct {
-	ID             string     `gorm:"primaryKey;column:id;varchar(255)"`
-	KnowledgeId    string     `gorm:"column:knowledge_id;type:varchar(255);not null"`
-	FileName       string     `gorm:"column:file_name;type:varchar(255)"`
-	FileExtension  string     `gorm:"column:file_extension;type:varchar(255)"` // 添加文件后缀名字段
-	CollectionName string     `gorm:"column:collection_name;type:varchar(255)"`
-	SHA256         string     `gorm:"column:sha256;type:varchar(64);index"`
-	RustfsBucket   string     `gorm:"column:rustfs_bucket;type:varchar(255)"`
-	RustfsLocation string     `gorm:"column:rustfs_location;type:varchar(255)"`
-	LocalFilePath  string     `gorm:"column:local_file_path;type:varchar(512)"` // 本地文件路径
-	Status         int8       `gorm:"column:status;not null;default:0"`
-	CreateTime     *time.Time `gorm:"column:create_time;type:timestamp;autoCreateTime"`
-	UpdateTime     *time.Time `gorm:"column:update_time;type:timestamp;autoUpdateTime"`
+	ID                   string     `gorm:"primaryKey;column:id;varchar(255)"`
+	KnowledgeId          string     `gorm:"column:knowledge_id;type:varchar(255);not null"`
+	FileName             string     `gorm:"column:file_name;type:varchar(255)"`
+	FileExtension        string     `gorm:"column:file_extension;type:varchar(255)"` // 添加文件后缀名字段
+	CollectionName       string     `gorm:"column:collection_name;type:varchar(255)"`
+	SHA256               string     `gorm:"column:sha256;type:varchar(64);index"`
+	RustfsBucket         string     `gorm:"column:rustfs_bucket;type:varchar(255)"`
+	RustfsLocation       string     `gorm:"column:rustfs_location;type:varchar(255)"`
+	LocalFilePath        string     `gorm:"column:local_file_path;type:varchar(512)"` // 本地文件路径
+	Status               int8       `gorm:"column:status;not null;default:0"`
+	SecurityLabel        string     `gorm:"column:security_label;type:varchar(32)"`           // 文档安全标签，为空时使用默认标签
+	SectionLabels        string     `gorm:"column:section_labels;type:text"`                  // 分段安全标签规则（JSON）
+	FieldMapping         string     `gorm:"column:field_mapping;type:text"`                   // 结构化数据字段映射（JSON），CSV/JSON Lines 按行生成分片
+	ValidFrom            *time.Time `gorm:"column:valid_from;type:timestamp"`                 // 生效时间，为空表示立即生效
+	ValidUntil           *time.Time `gorm:"column:valid_until;type:timestamp"`                // 失效时间，为空表示长期有效
+	VersionGroup         string     `gorm:"column:version_group;type:varchar(255);index"`     // 版本链ID（首个版本的文档ID）
+	Version              int        `gorm:"column:version;not null;default:1"`                // 版本号，从 1 开始
+	PreviousId           string     `gorm:"column:previous_id;type:varchar(255)"`             // 上一个版本的文档ID
+	SupersededAt         *time.Time `gorm:"column:superseded_at;type:timestamp"`              // 被新版本取代的时间，为空表示当前版本
+	EmbeddingModelID     string     `gorm:"column:embedding_model_id;type:varchar(64);index"` // 生成向量使用的 embedding 模型ID
+	EmbeddingFingerprint string     `gorm:"column:embedding_fingerprint;type:varchar(32)"`    // 生成向量时的 embedding 模型配置指纹
+	Toc                  string     `gorm:"column:toc;type:text"`                             // 标题目录（JSON），索引时根据分片内容生成
+	MetaTitle            string     `gorm:"column:meta_title;type:varchar(512)"`              // LLM 提取的文档标题
+	MetaAuthor           string     `gorm:"column:meta_author;type:varchar(255);index"`       // LLM 提取的作者
+	MetaDate             string     `gorm:"column:meta_date;type:varchar(16);index"`          // LLM 提取的日期（YYYY-MM-DD、YYYY-MM 或 YYYY）
+	MetaTopics           string     `gorm:"column:meta_topics;type:text"`                     // LLM 提取的主题（JSON 字符串数组）
+	MetaSummary          string     `gorm:"column:meta_summary;type:text"`                    // LLM 生成的两句话摘要
+	CreateTime           *time.Time `gorm:"column:create_time;type:timestamp;autoCreateTime"`
+	UpdateTime           *time.Time `gorm:"column:update_time;type:timestamp;autoUpdateTime"`
 }
 
 // TableName 设置表名
@@ -0,0 +1,27 @@
+package gorm
+
+import (
+	"time"
+)
+
+// KnowledgeProfile 知识库内容画像：文档索引完成后按抽样分片统计的语言构成和分片特征，用于给出 embedding 模型和分片大小建议
+type KnowledgeProfile struct {
+	KnowledgeID      string     `gorm:"primaryKey;column:knowledge_id;type:varchar(64)"`
+	ChunkCount       int        `gorm:"column:chunk_count;default:0"`              // 知识库有效分片总数
+	SampledChunks    int        `gorm:"column:sampled_chunks;default:0"`           // 参与统计的分片数
+	Languages        JSON       `gorm:"column:languages;type:json"`                // 各语言分片占比，如 {"zh":0.7,"latin":0.3}
+	DominantLanguage string     `gorm:"column:dominant_language;type:varchar(16)"` // 占比最高的语言
+	DominantShare    float64    `gorm:"column:dominant_share;default:0"`           // 占比最高的语言的分片占比
+	MixedShare       float64    `gorm:"column:mixed_share;default:0"`              // 同一分片内混合多种语言的分片占比
+	AvgChunkChars    float64    `gorm:"column:avg_chunk_chars;default:0"`          // 平均分片字符数
+	AvgChunkTokens   float64    `gorm:"column:avg_chunk_tokens;default:0"`         // 平均分片估算 token 数
+	MaxChunkTokens   int        `gorm:"column:max_chunk_tokens;default:0"`         // 最大分片估算 token 数
+	AnalyzedAt       *time.Time `gorm:"column:analyzed_at"`                        // 分析时间
+	CreateTime       *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime       *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (KnowledgeProfile) TableName() string {
+	return "knowledge_profiles"
+}
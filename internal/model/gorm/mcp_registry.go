@@ -6,17 +6,19 @@ import (
 
 // MCPRegistry MCP服务注册表 GORM模型定义
 type MCPRegistry struct {
-	ID          string     `gorm:"primaryKey;column:id;type:varchar(64)"`              // MCP服务唯一ID
-	Name        string     `gorm:"column:name;type:varchar(100);not null;uniqueIndex"` // MCP服务名称（唯一）
-	Description string     `gorm:"column:description;type:varchar(500)"`               // 服务描述
-	Endpoint    string     `gorm:"column:endpoint;type:varchar(500);not null"`         // SSE端点URL
-	ApiKey      string     `gorm:"column:api_key;type:varchar(500)"`                   // 认证密钥（加密存储）
-	Headers     string     `gorm:"column:headers;type:text"`                           // 自定义请求头（JSON格式）
-	Timeout     int        `gorm:"column:timeout;default:30"`                          // 超时时间（秒）
-	Status      int8       `gorm:"column:status;default:1"`                            // 状态：1-启用，0-禁用
-	Tools       string     `gorm:"column:tools;type:text"`                             // 工具列表（JSON格式存储）
-	CreateTime  *time.Time `gorm:"column:create_time;autoCreateTime"`                  // 创建时间
-	UpdateTime  *time.Time `gorm:"column:update_time;autoUpdateTime"`                  // 更新时间
+	ID           string     `gorm:"primaryKey;column:id;type:varchar(64)"`              // MCP服务唯一ID
+	Name         string     `gorm:"column:name;type:varchar(100);not null;uniqueIndex"` // MCP服务名称（唯一）
+	Description  string     `gorm:"column:description;type:varchar(500)"`               // 服务描述
+	Endpoint     string     `gorm:"column:endpoint;type:varchar(500);not null"`         // SSE端点URL
+	ApiKey       string     `gorm:"column:api_key;type:varchar(500)"`                   // 认证密钥（加密存储）
+	Headers      string     `gorm:"column:headers;type:text"`                           // 自定义请求头（JSON格式）
+	Timeout      int        `gorm:"column:timeout;default:30"`                          // 超时时间（秒）
+	ToolTimeouts string     `gorm:"column:tool_timeouts;type:text"`                     // 按工具设置的执行超时（JSON，工具名 -> 秒，0 表示不限制），优先于 toolTimeout 配置
+	Status       int8       `gorm:"column:status;default:1"`                            // 状态：1-启用，0-禁用
+	Tools        string     `gorm:"column:tools;type:text"`                             // 工具列表（JSON格式存储）
+	VerifiedAt   *time.Time `gorm:"column:tools_verified_at"`                           // 工具列表最近一次与服务端核对的时间
+	CreateTime   *time.Time `gorm:"column:create_time;autoCreateTime"`                  // 创建时间
+	UpdateTime   *time.Time `gorm:"column:update_time;autoUpdateTime"`                  // 更新时间
 }
 
 // TableName 设置表名
@@ -6,18 +6,21 @@ import (
 
 // Message 消息表
 type Message struct {
-	ID         uint64     `gorm:"primaryKey;column:id;type:bigint"`
-	MsgID      string     `gorm:"column:msg_id;type:varchar(64);uniqueIndex;not null"` // 消息ID
-	ConvID     string     `gorm:"column:conv_id;type:varchar(64);not null;index"`      // 会话ID
-	Role       string     `gorm:"column:role;type:varchar(20);not null"`               // 角色
-	ToolCalls  JSON       `gorm:"column:tool_calls;type:json"`                         // 工具调用
-	ToolCallID string     `gorm:"column:tool_call_id;type:varchar(64)"`                // 工具调用ID
-	ToolName   string     `gorm:"column:tool_name;type:varchar(128)"`                  // 工具名称
-	TokensUsed int        `gorm:"column:tokens_used;type:int"`                         // 使用的token数
-	LatencyMs  int        `gorm:"column:latency_ms;type:int"`                          // 延迟毫秒数
-	TraceID    string     `gorm:"column:trace_id;type:varchar(64)"`                    // 链路追踪ID
-	Metadata   JSON       `gorm:"column:metadata;type:json"`                           // 自定义扩展
-	CreateTime *time.Time `gorm:"column:create_time"`                                  // 创建时间
+	ID            uint64     `gorm:"primaryKey;column:id;type:bigint"`
+	MsgID         string     `gorm:"column:msg_id;type:varchar(64);uniqueIndex;not null"` // 消息ID
+	ConvID        string     `gorm:"column:conv_id;type:varchar(64);not null;index"`      // 会话ID
+	Role          string     `gorm:"column:role;type:varchar(20);not null"`               // 角色
+	AuthorID      string     `gorm:"column:author_id;type:varchar(100)"`                  // 发送消息的用户ID（多人会话中区分参与者；助手消息为本轮提问的用户，用于按用户统计 token 用量）
+	ToolCalls     JSON       `gorm:"column:tool_calls;type:json"`                         // 工具调用
+	ToolCallID    string     `gorm:"column:tool_call_id;type:varchar(64)"`                // 工具调用ID
+	ToolName      string     `gorm:"column:tool_name;type:varchar(128)"`                  // 工具名称
+	ModelID       string     `gorm:"column:model_id;type:varchar(64)"`                    // 生成该消息的模型ID（助手消息使用，改用备用模型时为实际使用的模型）
+	TokensUsed    int        `gorm:"column:tokens_used;type:int"`                         // 使用的token数
+	LatencyMs     int        `gorm:"column:latency_ms;type:int"`                          // 延迟毫秒数
+	TraceID       string     `gorm:"column:trace_id;type:varchar(64)"`                    // 链路追踪ID
+	Metadata      JSON       `gorm:"column:metadata;type:json"`                           // 自定义扩展
+	CreateTime    *time.Time `gorm:"column:create_time"`                                  // 创建时间
+	PIIScrubbedAt *time.Time `gorm:"column:pii_scrubbed_at;index"`                        // 个人信息脱敏任务处理的时间，未处理时为空
 }
 
 // TableName 设置表名
@@ -20,6 +20,32 @@ func Migrate(db *gorm.DB) error {
 		&MCPRegistry{},
 		&MCPCallLog{},
 		&AIModel{},
+		&AnalyticsDailyRollup{},
+		&AnalyticsToolRollup{},
+		&RetrievalMissLog{},
+		&AnalyticsUnansweredRollup{},
+		&KnowledgeGap{},
+		&ReembedJob{},
+		&HandoffTicket{},
+		&ShadowResult{},
+		&KBPromotion{},
+		&Persona{},
+		&ToolExample{},
+		&Project{},
+		&ProjectResource{},
+		&ProjectMember{},
+		&ProjectUsage{},
+		&IngestDeadLetter{},
+		&IngestHookConfig{},
+		&RetrievalView{},
+		&ConversationBlob{},
+		&FeatureFlag{},
+		&ConversationParticipant{},
+		&KnowledgeProfile{},
+		&AnswerDiff{},
+		&PIIScrubLog{},
+		&UsageDaily{},
+		&UsageDirect{},
 	)
 	if err != nil {
 		glog.Error(context.Background(), "数据库迁移失败:", err)
@@ -0,0 +1,24 @@
+package gorm
+
+import (
+	"time"
+)
+
+// Persona 可复用的回答人设预设（语气、正式程度、表情符号策略、署名），可在对话请求中指定或作为模型的默认人设
+type Persona struct {
+	ID           string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	Name         string     `gorm:"column:name;type:varchar(100);not null;uniqueIndex"` // 人设名称（唯一）
+	Description  string     `gorm:"column:description;type:varchar(500)"`               // 人设说明
+	Tone         string     `gorm:"column:tone;type:varchar(500)"`                      // 语气，如 亲切耐心、专业严谨
+	Formality    string     `gorm:"column:formality;type:varchar(16)"`                  // 正式程度：formal / neutral / casual
+	EmojiPolicy  string     `gorm:"column:emoji_policy;type:varchar(16)"`               // 表情符号策略：none / sparing / free
+	Signature    string     `gorm:"column:signature;type:varchar(500)"`                 // 回答末尾的署名
+	Instructions string     `gorm:"column:instructions;type:text"`                      // 其他人设要求
+	CreateTime   *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime   *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (Persona) TableName() string {
+	return "personas"
+}
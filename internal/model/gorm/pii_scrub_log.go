@@ -0,0 +1,22 @@
+package gorm
+
+import (
+	"time"
+)
+
+// PIIScrubLog 个人信息脱敏审计记录：每条被脱敏的消息一条，只记录脱敏的字段和各类信息的数量，不保存原文
+type PIIScrubLog struct {
+	ID         uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	RunID      string     `gorm:"column:run_id;type:varchar(64);not null;index"` // 脱敏任务执行ID
+	ConvID     string     `gorm:"column:conv_id;type:varchar(64);not null;index"`
+	MsgID      string     `gorm:"column:msg_id;type:varchar(64);not null;index"`
+	ProjectID  string     `gorm:"column:project_id;type:varchar(64);index"` // 会话所属项目（按会话模型所属项目确定），为空时使用全局策略
+	Fields     string     `gorm:"column:fields;type:varchar(255)"`          // 被脱敏的字段，逗号分隔：content / metadata / content_metadata
+	Counts     string     `gorm:"column:counts;type:varchar(255)"`          // 各类信息的脱敏数量（JSON），如 {"email":1,"phone":2}
+	CreateTime *time.Time `gorm:"column:create_time;autoCreateTime;index"`
+}
+
+// TableName 设置表名
+func (PIIScrubLog) TableName() string {
+	return "pii_scrub_logs"
+}
@@ -0,0 +1,66 @@
+package gorm
+
+import (
+	"time"
+)
+
+// Project 项目（资源分组）：把模型、知识库、MCP 服务和人设等资源归入同一项目，
+// 项目默认设置作为对话请求参数的默认值，便于按团队或业务线管理资源
+type Project struct {
+	ID          string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	Name        string     `gorm:"column:name;type:varchar(100);not null;uniqueIndex"` // 项目名称（唯一）
+	Description string     `gorm:"column:description;type:varchar(500)"`
+	Settings    string     `gorm:"column:settings;type:text"` // 默认设置（JSON），见 project.Settings
+	Quota       string     `gorm:"column:quota;type:text"`    // 配额（JSON），见 quota.Limits
+	CreateTime  *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime  *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (Project) TableName() string {
+	return "projects"
+}
+
+// ProjectResource 项目包含的资源，每个资源最多属于一个项目
+type ProjectResource struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	ProjectID    string     `gorm:"column:project_id;type:varchar(64);not null;index"`
+	ResourceType string     `gorm:"column:resource_type;type:varchar(32);not null;uniqueIndex:idx_project_resource"` // knowledge_base / model / mcp_service / persona
+	ResourceID   string     `gorm:"column:resource_id;type:varchar(64);not null;uniqueIndex:idx_project_resource"`
+	CreateTime   *time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TableName 设置表名
+func (ProjectResource) TableName() string {
+	return "project_resources"
+}
+
+// ProjectMember 项目成员
+type ProjectMember struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	ProjectID  string     `gorm:"column:project_id;type:varchar(64);not null;uniqueIndex:idx_project_member"`
+	UserID     string     `gorm:"column:user_id;type:varchar(100);not null;uniqueIndex:idx_project_member"`
+	Role       string     `gorm:"column:role;type:varchar(16);not null"` // owner / editor / viewer
+	CreateTime *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (ProjectMember) TableName() string {
+	return "project_members"
+}
+
+// ProjectUsage 项目按周期累计的用量（月度 token、每日对话次数、每日工具调用次数），用于配额校验
+type ProjectUsage struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	ProjectID  string     `gorm:"column:project_id;type:varchar(64);not null;uniqueIndex:idx_project_usage"`
+	Metric     string     `gorm:"column:metric;type:varchar(32);not null;uniqueIndex:idx_project_usage"` // tokens / queries / tool_calls
+	Period     string     `gorm:"column:period;type:varchar(10);not null;uniqueIndex:idx_project_usage"` // 统计周期：月度 yyyy-MM，每日 yyyy-MM-dd
+	Amount     int64      `gorm:"column:amount;not null;default:0"`
+	UpdateTime *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (ProjectUsage) TableName() string {
+	return "project_usage"
+}
@@ -0,0 +1,37 @@
+package gorm
+
+import (
+	"time"
+)
+
+// 重新向量化任务状态
+const (
+	ReembedStatusRunning   = "running"   // 执行中
+	ReembedStatusPaused    = "paused"    // 已暂停
+	ReembedStatusCompleted = "completed" // 已完成
+	ReembedStatusCanceled  = "canceled"  // 已被同一模型的新任务取代
+)
+
+// ReembedJob embedding 模型配置变更后的重新向量化任务
+type ReembedJob struct {
+	ID             string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	ModelID        string     `gorm:"column:model_id;type:varchar(64);not null;index"` // embedding 模型ID
+	KnowledgeID    string     `gorm:"column:knowledge_id;type:varchar(64)"`            // 限定知识库，为空表示该模型的全部文档
+	Migrate        bool       `gorm:"column:migrate;default:false"`                    // 迁移任务：知识库中由其他模型生成的文档也切换到该模型
+	Fingerprint    string     `gorm:"column:fingerprint;type:varchar(32)"`             // 目标模型配置指纹
+	Status         string     `gorm:"column:status;type:varchar(16);not null;index"`   // 任务状态
+	Total          int        `gorm:"column:total;default:0"`                          // 需要重新向量化的文档数
+	Processed      int        `gorm:"column:processed;default:0"`                      // 已成功处理的文档数
+	Failed         int        `gorm:"column:failed;default:0"`                         // 处理失败的文档数
+	LastDocumentID string     `gorm:"column:last_document_id;type:varchar(255)"`       // 断点：最后处理的文档ID（按ID顺序处理）
+	LastError      string     `gorm:"column:last_error;type:text"`                     // 最近一次失败原因
+	StartedAt      *time.Time `gorm:"column:started_at"`                               // 开始时间
+	FinishedAt     *time.Time `gorm:"column:finished_at"`                              // 完成时间
+	CreateTime     *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime     *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (ReembedJob) TableName() string {
+	return "reembed_jobs"
+}
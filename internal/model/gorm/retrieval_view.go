@@ -0,0 +1,28 @@
+package gorm
+
+import (
+	"time"
+)
+
+// RetrievalView 保存的检索视图（智能集合）：知识库、元数据过滤条件和检索参数的命名组合，
+// 可在对话请求、检索请求和工具调用中按名称引用
+type RetrievalView struct {
+	ID               string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	Name             string     `gorm:"column:name;type:varchar(100);not null;uniqueIndex"` // 视图名称（唯一）
+	Description      string     `gorm:"column:description;type:varchar(500)"`               // 视图说明
+	KnowledgeIDs     JSON       `gorm:"column:knowledge_ids;type:json"`                     // 检索的知识库ID列表
+	MetadataFilter   JSON       `gorm:"column:metadata_filter;type:json"`                   // 文档元数据过滤条件
+	EmbeddingModelID string     `gorm:"column:embedding_model_id;type:varchar(64)"`         // Embedding模型ID，为空时使用知识库最近索引文档的模型
+	RerankModelID    string     `gorm:"column:rerank_model_id;type:varchar(64)"`            // Rerank模型ID
+	RetrieveMode     string     `gorm:"column:retrieve_mode;type:varchar(16)"`              // 检索模式：milvus / rerank / rrf
+	TopK             int        `gorm:"column:top_k;default:0"`                             // 返回数量，0 表示使用默认值
+	Score            float64    `gorm:"column:score;default:0"`                             // 最低分数，0 表示使用默认值
+	EnableRewrite    bool       `gorm:"column:enable_rewrite;default:false"`                // 是否启用查询重写
+	CreateTime       *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime       *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (RetrievalView) TableName() string {
+	return "retrieval_views"
+}
@@ -0,0 +1,29 @@
+package gorm
+
+import (
+	"time"
+)
+
+// ShadowResult 影子评估结果：同一请求的线上回答与候选模型回答（候选回答不返回给用户）
+type ShadowResult struct {
+	ID                    string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	ConvID                string     `gorm:"column:conv_id;type:varchar(64);index"`            // 会话ID
+	Question              string     `gorm:"column:question;type:text"`                        // 用户问题
+	LiveModelID           string     `gorm:"column:live_model_id;type:varchar(64)"`            // 线上模型ID
+	LiveAnswer            string     `gorm:"column:live_answer;type:text"`                     // 线上回答
+	LiveLatencyMs         int64      `gorm:"column:live_latency_ms"`                           // 线上回答耗时（毫秒）
+	LiveGroundedness      *float64   `gorm:"column:live_groundedness"`                         // 线上回答与参考资料的一致性（无参考资料时为空）
+	CandidateModelID      string     `gorm:"column:candidate_model_id;type:varchar(64);index"` // 候选模型ID
+	CandidateAnswer       string     `gorm:"column:candidate_answer;type:text"`                // 候选回答
+	CandidateLatencyMs    int64      `gorm:"column:candidate_latency_ms"`                      // 候选回答耗时（毫秒）
+	CandidateTokens       int        `gorm:"column:candidate_tokens"`                          // 候选回答 token 消耗
+	CandidateGroundedness *float64   `gorm:"column:candidate_groundedness"`                    // 候选回答与参考资料的一致性
+	Agreement             float64    `gorm:"column:agreement"`                                 // 两个回答的字面相似度（0-1）
+	Error                 string     `gorm:"column:error;type:text"`                           // 候选模型调用失败原因
+	CreateTime            *time.Time `gorm:"column:create_time;autoCreateTime;index"`          // 创建时间
+}
+
+// TableName 设置表名
+func (ShadowResult) TableName() string {
+	return "shadow_results"
+}
@@ -0,0 +1,24 @@
+package gorm
+
+import (
+	"time"
+)
+
+// ToolExample 工具调用示例（问题 → 调用的工具及参数），作为 few-shot 示例注入工具调用提示词
+type ToolExample struct {
+	ID          string     `gorm:"primaryKey;column:id;type:varchar(64)"`
+	ModelID     string     `gorm:"column:model_id;type:varchar(64);index"`         // 适用的模型，为空时适用于所有模型
+	Question    string     `gorm:"column:question;type:text;not null"`             // 示例问题
+	ServiceName string     `gorm:"column:service_name;type:varchar(100);not null"` // MCP 服务名
+	ToolName    string     `gorm:"column:tool_name;type:varchar(100);not null"`    // 工具名
+	Arguments   string     `gorm:"column:arguments;type:text"`                     // 调用参数（JSON 对象）
+	Note        string     `gorm:"column:note;type:varchar(500)"`                  // 说明，如为什么选择该工具
+	Enabled     bool       `gorm:"column:enabled;not null;default:true"`           // 是否启用
+	CreateTime  *time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime  *time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+// TableName 设置表名
+func (ToolExample) TableName() string {
+	return "tool_examples"
+}
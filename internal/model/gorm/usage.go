@@ -0,0 +1,41 @@
+package gorm
+
+import (
+	"time"
+)
+
+// UsageDaily 按天、按用户、按模型汇总的 token 用量表（由定时任务从消息表和 usage_direct 写入）
+type UsageDaily struct {
+	ID             uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	StatDate       string     `gorm:"column:stat_date;type:varchar(10);not null;uniqueIndex:idx_usage_date_user_model"`                    // 统计日期 yyyy-MM-dd
+	UserID         string     `gorm:"column:user_id;type:varchar(64);not null;uniqueIndex:idx_usage_date_user_model;index:idx_usage_user"` // 提问用户ID（直接调用模型时为调用用户）
+	ModelID        string     `gorm:"column:model_id;type:varchar(64);not null;uniqueIndex:idx_usage_date_user_model"`                     // 模型ID（会话维度）
+	ModelName      string     `gorm:"column:model_name;type:varchar(64)"`                                                                  // 模型名称
+	MessageCount   int64      `gorm:"column:message_count;default:0"`                                                                      // 当天消息数
+	DirectRequests int64      `gorm:"column:direct_requests;default:0"`                                                                    // 当天直接调用模型的请求数
+	TotalTokens    int64      `gorm:"column:total_tokens;default:0"`                                                                       // 当天 token 消耗（会话消息和直接调用模型的请求）
+	UpdateTime     *time.Time `gorm:"column:update_time;autoUpdateTime"`                                                                   // 更新时间
+}
+
+// TableName 设置表名
+func (UsageDaily) TableName() string {
+	return "usage_daily"
+}
+
+// UsageDirect 直接调用模型（不保存会话消息，如 /v1/chat/completions 透传请求）的 token 用量，按天、按用户、按模型累加，
+// 汇总任务把它合并到 usage_daily
+type UsageDirect struct {
+	ID           uint64     `gorm:"primaryKey;column:id;autoIncrement"`
+	StatDate     string     `gorm:"column:stat_date;type:varchar(10);not null;uniqueIndex:idx_usage_direct_date_user_model"` // 统计日期 yyyy-MM-dd
+	UserID       string     `gorm:"column:user_id;type:varchar(64);not null;uniqueIndex:idx_usage_direct_date_user_model"`   // 调用用户ID
+	ModelID      string     `gorm:"column:model_id;type:varchar(64);not null;uniqueIndex:idx_usage_direct_date_user_model"`  // 模型ID
+	ModelName    string     `gorm:"column:model_name;type:varchar(64)"`                                                      // 模型名称
+	RequestCount int64      `gorm:"column:request_count;default:0"`                                                          // 请求数
+	TotalTokens  int64      `gorm:"column:total_tokens;default:0"`                                                           // token 消耗
+	UpdateTime   *time.Time `gorm:"column:update_time;autoUpdateTime"`                                                       // 更新时间
+}
+
+// TableName 设置表名
+func (UsageDirect) TableName() string {
+	return "usage_direct"
+}
@@ -64,3 +64,15 @@ func (CollectionSchema) GetFields(dim string) []*entity.Field {
 func GetStandardCollectionFields(dim string) []*entity.Field {
 	return CollectionSchema{}.GetFields(dim)
 }
+
+// SparseVectorField 混合检索集合中稀疏向量字段名
+const SparseVectorField = "sparse_vector"
+
+// GetHybridCollectionFields 返回同时包含稠密向量和稀疏向量（SPLADE/BM42 等）字段的集合定义
+func GetHybridCollectionFields(dim string) []*entity.Field {
+	return append(GetStandardCollectionFields(dim), &entity.Field{
+		Name:        SparseVectorField,
+		DataType:    entity.FieldTypeSparseVector,
+		Description: "Document chunk sparse term-weight vector",
+	})
+}
@@ -40,7 +40,7 @@ type FieldDefinition struct {
 type IndexDefinition struct {
 	Name        string
 	Fields      []string
-	IndexType   string // e.g., "btree", "hnsw"
+	IndexType   string // e.g., "btree", "hnsw", "gin"
 	IndexOps    string // e.g., "vector_cosine_ops", empty for standard btree
 	Description string
 }
@@ -107,6 +107,13 @@ func (TableSchema) GetIndexes(tableName string) []IndexDefinition {
 			IndexOps:    "",
 			Description: "B-tree index for fast document_id lookups",
 		},
+		{
+			Name:        fmt.Sprintf("%s_text_tsv_idx", tableName),
+			Fields:      []string{TextSearchVector},
+			IndexType:   "gin",
+			IndexOps:    "",
+			Description: "GIN index for keyword (full-text) search",
+		},
 	}
 }
 
@@ -154,6 +161,12 @@ func (t TableSchema) GenerateCreateIndexSQL(schemaName, tableName string) []stri
 				"CREATE INDEX IF NOT EXISTS %s ON %s USING %s (%s %s)",
 				idx.Name, fullTableName, idx.IndexType, idx.Fields[0], idx.IndexOps,
 			)
+		} else if idx.IndexType == "gin" {
+			// GIN expression index
+			sqls[i] = fmt.Sprintf(
+				"CREATE INDEX IF NOT EXISTS %s ON %s USING gin ((%s))",
+				idx.Name, fullTableName, idx.Fields[0],
+			)
 		} else {
 			// Standard btree index
 			sqls[i] = fmt.Sprintf(
@@ -175,3 +188,16 @@ func GetStandardTableFields(dim int) []FieldDefinition {
 func GetStandardTableIndexes(tableName string) []IndexDefinition {
 	return TableSchema{}.GetIndexes(tableName)
 }
+
+// SparseVectorColumn 混合检索表中稀疏向量列名
+const SparseVectorColumn = "sparse_vector"
+
+// GenerateAddSparseColumnSQL generates the ALTER TABLE SQL statement adding the sparse vector column
+// sparsevec 的 HNSW 索引最多支持 1000 个非零项，SPLADE 文档向量可能超出，因此不建索引
+func (t TableSchema) GenerateAddSparseColumnSQL(schemaName, tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS %s sparsevec", schemaName, tableName, SparseVectorColumn)
+}
+
+// TextSearchVector 关键词检索使用的全文检索表达式，查询条件必须与 GIN 索引表达式一致才能使用索引
+// simple 配置不做词干化和停用词处理，与检索时对查询的分词方式一致；中文没有分词，检索时按字符二元组匹配
+const TextSearchVector = "to_tsvector('simple', text)"
@@ -0,0 +1,30 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// CodecName gRPC 消息编码名称，客户端需通过 grpc.CallContentSubtype(CodecName) 或
+// grpc.ForceCodec 使用，请求头 content-type 为 application/grpc+json
+const CodecName = "json"
+
+// jsonCodec 使用 JSON 编码 gRPC 消息，消息结构与 HTTP 接口相同（api/kbgo/v1），无需维护 proto 定义
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return CodecName
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
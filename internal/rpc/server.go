@@ -0,0 +1,234 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Malowking/kbgo/api/kbgo"
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/security"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer 创建 gRPC 服务，controller 为 HTTP 接口使用的控制器，stream 为流式聊天实现
+// clearanceHeader 为携带调用方安全权限的可信元数据名称（为空表示不读取），auth 为识别调用用户的认证配置（与 HTTP 接口相同）
+func NewServer(controller kbgo.IKbgoV1, stream StreamFunc, clearanceHeader string, auth *identity.Config) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoveryUnaryInterceptor, loggingUnaryInterceptor, identityUnaryInterceptor(auth), clearanceUnaryInterceptor(clearanceHeader), validationUnaryInterceptor),
+		grpc.ChainStreamInterceptor(recoveryStreamInterceptor, loggingStreamInterceptor, identityStreamInterceptor(auth), clearanceStreamInterceptor(clearanceHeader)),
+	)
+	server.RegisterService(&serviceDesc, &service{controller: controller, stream: stream})
+	return server
+}
+
+// Start 按 grpc 配置在后台启动 gRPC 服务，返回停止服务的函数；未启用时不做任何事
+func Start(ctx context.Context, controller kbgo.IKbgoV1, stream StreamFunc) (stop func(), err error) {
+	if !g.Cfg().MustGet(ctx, "grpc.enabled", false).Bool() {
+		return func() {}, nil
+	}
+	address := g.Cfg().MustGet(ctx, "grpc.address", ":9000").String()
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, gerror.Wrapf(err, "failed to listen on gRPC address %s", address)
+	}
+	// 未开启 security.trustClearanceHeader 时不读取客户端传入的权限元数据
+	clearanceHeader := ""
+	if security.TrustClearanceHeader(ctx) {
+		clearanceHeader = g.Cfg().MustGet(ctx, "security.clearanceHeader", "X-Security-Clearance").String()
+	}
+	server := NewServer(controller, stream, clearanceHeader, identity.LoadConfig(ctx))
+	common.SafeGo(ctx, "grpc-server", func() {
+		g.Log().Infof(ctx, "gRPC server is serving at %s", listener.Addr())
+		if err := server.Serve(listener); err != nil {
+			g.Log().Errorf(ctx, "gRPC server stopped: %v", err)
+		}
+	})
+	return func() { gracefulStop(server, gracefulStopTimeout) }, nil
+}
+
+// gracefulStopTimeout 停止 gRPC 服务时等待进行中请求（包括流式对话）结束的最长时间
+const gracefulStopTimeout = 30 * time.Second
+
+// gracefulStop 停止接收新请求并等待进行中的请求结束，超时后强制关闭
+func gracefulStop(server *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
+
+// validate 使用请求结构体上的 v 标签校验参数，与 HTTP 接口的校验规则一致
+func validate(ctx context.Context, req any) error {
+	if err := g.Validator().Data(req).Run(ctx); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// toStatus 将业务错误转换为 gRPC 状态
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if gerror.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if gerror.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	switch gerror.Code(err) {
+	case gcode.CodeValidationFailed, gcode.CodeInvalidParameter, gcode.CodeMissingParameter:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case gcode.CodeNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case gcode.CodeNotAuthorized:
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// withClearances 确定调用方的安全权限（与 HTTP 接口规则相同）：header 不为空时读取同名的请求元数据，
+// 否则或元数据中没有权限时使用认证用户在 security.userClearances 中的配置
+func withClearances(ctx context.Context, header string) context.Context {
+	var clearances []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && header != "" {
+		clearances = security.ParseClearances(strings.Join(md.Get(header), ","))
+	}
+	if len(clearances) == 0 {
+		clearances = security.UserClearances(ctx)
+	}
+	if len(clearances) > 0 {
+		ctx = security.WithClearances(ctx, clearances)
+	}
+	return ctx
+}
+
+func clearanceUnaryInterceptor(header string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withClearances(ctx, header), req)
+	}
+}
+
+func clearanceStreamInterceptor(header string) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: stream, ctx: withClearances(stream.Context(), header)})
+	}
+}
+
+// authenticate 从请求元数据识别调用用户，凭证无效或缺少必需的凭证时返回 Unauthenticated；
+// 与 HTTP 接口相同，配置了凭证时按用户隔离，未携带身份的请求按 DefaultUserID 处理
+func authenticate(ctx context.Context, auth *identity.Config) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	userID, err := auth.Authenticate(header, time.Now())
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if auth.Isolated() {
+		ctx = identity.WithIsolation(ctx)
+	}
+	return identity.WithUser(ctx, userID), nil
+}
+
+func identityUnaryInterceptor(auth *identity.Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func identityStreamInterceptor(auth *identity.Config) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// contextStream 替换流的上下文
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func validationUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := validate(ctx, req); err != nil {
+		return nil, err
+	}
+	res, err := handler(ctx, req)
+	return res, toStatus(err)
+}
+
+func loggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	if err != nil {
+		g.Log().Warningf(ctx, "gRPC %s failed in %v: %v", info.FullMethod, time.Since(start), err)
+	} else {
+		g.Log().Infof(ctx, "gRPC %s completed in %v", info.FullMethod, time.Since(start))
+	}
+	return res, err
+}
+
+func loggingStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := toStatus(handler(srv, stream))
+	if err != nil {
+		g.Log().Warningf(stream.Context(), "gRPC %s failed in %v: %v", info.FullMethod, time.Since(start), err)
+	} else {
+		g.Log().Infof(stream.Context(), "gRPC %s completed in %v", info.FullMethod, time.Since(start))
+	}
+	return err
+}
+
+func recoveryUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.Log().Errorf(ctx, "gRPC %s panic: %v", info.FullMethod, r)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoveryStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.Log().Errorf(stream.Context(), "gRPC %s panic: %v", info.FullMethod, r)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(srv, stream)
+}
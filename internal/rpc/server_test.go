@@ -0,0 +1,203 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/api/kbgo"
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/internal/logic/identity"
+	"github.com/Malowking/kbgo/internal/logic/security"
+	"github.com/Malowking/kbgo/pkg/schema"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeController 只实现测试用到的方法
+type fakeController struct {
+	kbgo.IKbgoV1
+}
+
+func (fakeController) Retriever(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverRes, error) {
+	clearances := security.ClearancesFromContext(ctx)
+	// 按用户隔离时请求参数中的用户被忽略，使用认证用户（未携带身份时为 DefaultUserID）
+	userID := identity.Resolve(ctx, "spoofed")
+	return &v1.RetrieverRes{Document: []*schema.Document{{ID: req.KnowledgeId, Content: req.Question + "|" + clearances[0] + "|" + userID}}}, nil
+}
+
+func fakeStream(ctx context.Context, req *v1.ChatCompletionReq, send func(*v1.ChatCompletionChunk) error) error {
+	for _, content := range []string{"你", "好"} {
+		chunk := &v1.ChatCompletionChunk{Model: req.ModelID, Choices: []v1.ChatCompletionChunkChoice{{Delta: v1.ChatCompletionMessage{Content: content}}}}
+		if err := send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dial(t *testing.T) *grpc.ClientConn {
+	return dialServer(t, NewServer(fakeController{}, fakeStream, "X-Security-Clearance", &identity.Config{APIKeys: map[string]string{"key-1": "alice"}}))
+}
+
+func dialServer(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(CodecName)),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRetrieve(t *testing.T) {
+	conn := dial(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-security-clearance", "internal")
+
+	res := new(v1.RetrieverRes)
+	req := &v1.RetrieverReq{Question: "q", EmbeddingModelID: "m", KnowledgeId: "kb"}
+	if err := conn.Invoke(ctx, MethodRetrieve, req, res); err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if len(res.Document) != 1 || res.Document[0].ID != "kb" || res.Document[0].Content != "q|internal|default_user" {
+		t.Errorf("unexpected response: %+v", res.Document)
+	}
+
+	// 缺少必填参数时返回 InvalidArgument
+	err := conn.Invoke(ctx, MethodRetrieve, &v1.RetrieverReq{Question: "q"}, res)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestRetrieveIdentity(t *testing.T) {
+	conn := dial(t)
+	req := &v1.RetrieverReq{Question: "q", EmbeddingModelID: "m", KnowledgeId: "kb"}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-security-clearance", "internal", "x-api-key", "key-1")
+	res := new(v1.RetrieverRes)
+	if err := conn.Invoke(ctx, MethodRetrieve, req, res); err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if res.Document[0].Content != "q|internal|alice" {
+		t.Errorf("unexpected response: %+v", res.Document)
+	}
+
+	// 无效的 API Key 返回 Unauthenticated
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	err := conn.Invoke(ctx, MethodRetrieve, req, res)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	conn := dial(t)
+	stream, err := conn.NewStream(context.Background(), &serviceDesc.Streams[0], MethodChatCompletionStream)
+	if err != nil {
+		t.Fatalf("new stream: %v", err)
+	}
+	req := &v1.ChatCompletionReq{ModelID: "m", Messages: []v1.ChatCompletionMessage{{Role: "user", Content: "hi"}}}
+	if err = stream.SendMsg(req); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err = stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+
+	var answer string
+	for {
+		chunk := new(v1.ChatCompletionChunk)
+		if err = stream.RecvMsg(chunk); err != nil {
+			break
+		}
+		answer += chunk.Choices[0].Delta.Content
+	}
+	if answer != "你好" {
+		t.Errorf("got answer %q, want %q", answer, "你好")
+	}
+}
+
+// TestUnauthenticatedIsolation 测试配置了凭证时未携带身份的请求按 DefaultUserID 隔离，不能冒用请求参数中的用户；
+// 未配置任何凭证时沿用请求参数
+func TestUnauthenticatedIsolation(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("security:\n  defaultClearances: [\"public\"]\n")
+	if err != nil {
+		t.Fatalf("NewAdapterContent() error = %v", err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	req := &v1.RetrieverReq{Question: "q", EmbeddingModelID: "m", KnowledgeId: "kb"}
+	for _, tt := range []struct {
+		name string
+		auth *identity.Config
+		want string
+	}{
+		{name: "Isolated", auth: &identity.Config{APIKeys: map[string]string{"key-1": "alice"}}, want: "q|public|default_user"},
+		{name: "No credentials", auth: &identity.Config{}, want: "q|public|spoofed"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialServer(t, NewServer(fakeController{}, fakeStream, "", tt.auth))
+			res := new(v1.RetrieverRes)
+			if err := conn.Invoke(context.Background(), MethodRetrieve, req, res); err != nil {
+				t.Fatalf("invoke: %v", err)
+			}
+			if res.Document[0].Content != tt.want {
+				t.Errorf("got %q, want %q", res.Document[0].Content, tt.want)
+			}
+		})
+	}
+}
+
+// TestChatCompletionStreamClearance 测试流式接口同样使用认证用户在 security.userClearances 中配置的权限
+func TestChatCompletionStreamClearance(t *testing.T) {
+	adapter, err := gcfg.NewAdapterContent("security:\n  userClearances:\n    alice: [\"internal\"]\n")
+	if err != nil {
+		t.Fatalf("NewAdapterContent() error = %v", err)
+	}
+	original := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	defer g.Cfg().SetAdapter(original)
+
+	clearanceStream := func(ctx context.Context, req *v1.ChatCompletionReq, send func(*v1.ChatCompletionChunk) error) error {
+		content := strings.Join(security.ClearancesFromContext(ctx), ",")
+		return send(&v1.ChatCompletionChunk{Choices: []v1.ChatCompletionChunkChoice{{Delta: v1.ChatCompletionMessage{Content: content}}}})
+	}
+	conn := dialServer(t, NewServer(fakeController{}, clearanceStream, "", &identity.Config{APIKeys: map[string]string{"key-1": "alice"}}))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "key-1")
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], MethodChatCompletionStream)
+	if err != nil {
+		t.Fatalf("new stream: %v", err)
+	}
+	req := &v1.ChatCompletionReq{ModelID: "m", Messages: []v1.ChatCompletionMessage{{Role: "user", Content: "hi"}}}
+	if err = stream.SendMsg(req); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err = stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+	chunk := new(v1.ChatCompletionChunk)
+	if err = stream.RecvMsg(chunk); err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if got := chunk.Choices[0].Delta.Content; got != "internal" {
+		t.Errorf("clearances = %q, want %q", got, "internal")
+	}
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/Malowking/kbgo/api/kbgo"
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"google.golang.org/grpc"
+)
+
+// ServiceName gRPC 服务名
+const ServiceName = "kbgo.v1.Kbgo"
+
+// 方法全名，供客户端 Invoke/NewStream 使用
+const (
+	MethodRetrieve             = "/" + ServiceName + "/Retrieve"
+	MethodIndexDocuments       = "/" + ServiceName + "/IndexDocuments"
+	MethodChatCompletion       = "/" + ServiceName + "/ChatCompletion"
+	MethodChatCompletionStream = "/" + ServiceName + "/ChatCompletionStream"
+)
+
+// StreamFunc 流式聊天的实现，与 HTTP 接口共用 completion.Stream
+type StreamFunc func(ctx context.Context, req *v1.ChatCompletionReq, send func(*v1.ChatCompletionChunk) error) error
+
+// service 面向内部服务的 gRPC 接口，检索、索引和聊天直接调用 HTTP 控制器使用的同一套实现
+type service struct {
+	controller kbgo.IKbgoV1
+	stream     StreamFunc
+}
+
+// serviceDesc 手写的服务描述（消息使用 JSON 编码，不依赖 protoc 生成代码）
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Retrieve", Handler: unaryHandler(MethodRetrieve, func(s *service) func(context.Context, *v1.RetrieverReq) (*v1.RetrieverRes, error) {
+			return s.controller.Retriever
+		})},
+		{MethodName: "IndexDocuments", Handler: unaryHandler(MethodIndexDocuments, func(s *service) func(context.Context, *v1.IndexDocumentsReq) (*v1.IndexDocumentsRes, error) {
+			return s.controller.IndexDocuments
+		})},
+		{MethodName: "ChatCompletion", Handler: unaryHandler(MethodChatCompletion, func(s *service) func(context.Context, *v1.ChatCompletionReq) (*v1.ChatCompletionRes, error) {
+			return s.controller.ChatCompletion
+		})},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "ChatCompletionStream", Handler: chatCompletionStreamHandler, ServerStreams: true},
+	},
+}
+
+// unaryHandler 将控制器方法包装为 gRPC 一元方法处理函数
+func unaryHandler[Req any, Res any](fullMethod string, method func(*service) func(context.Context, *Req) (*Res, error)) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		call := method(srv.(*service))
+		if interceptor == nil {
+			return call(ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+			return call(ctx, req.(*Req))
+		})
+	}
+}
+
+// chatCompletionStreamHandler 服务端流式聊天
+func chatCompletionStreamHandler(srv any, stream grpc.ServerStream) error {
+	req := new(v1.ChatCompletionReq)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	if err := validate(stream.Context(), req); err != nil {
+		return err
+	}
+	return srv.(*service).stream(stream.Context(), req, func(chunk *v1.ChatCompletionChunk) error {
+		return stream.SendMsg(chunk)
+	})
+}
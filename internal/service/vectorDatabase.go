@@ -17,14 +17,36 @@ var (
 )
 
 // GetVectorStore returns the singleton vector database client
+// 配置了只读副本时，检索查询路由到只读副本（失败时回退到主库），写入和删除在主库执行
 func GetVectorStore() (vector_store.VectorStore, error) {
 	once.Do(func() {
 		ctx := gctx.New()
 		vectorClient, initError = initializeVectorStore(ctx)
+		if initError == nil {
+			vectorClient = withReadReplicas(ctx, vectorClient)
+		}
 	})
 	return vectorClient, initError
 }
 
+// withReadReplicas 连接当前向量数据库类型配置的只读副本，没有可用副本时返回主库
+func withReadReplicas(ctx context.Context, primary vector_store.VectorStore) vector_store.VectorStore {
+	var replicas []vector_store.VectorStore
+	var names []string
+	switch g.Cfg().MustGet(ctx, "vectorStore.type", "milvus").String() {
+	case "milvus":
+		replicas, names = vector_store.InitializeMilvusReplicas(ctx)
+	case "pgvector":
+		replicas, names = vector_store.InitializePostgresReplicas(ctx)
+	}
+	if len(replicas) == 0 {
+		return primary
+	}
+	cooldown := g.Cfg().MustGet(ctx, "vectorStore.replicaCooldown", "30s").Duration()
+	g.Log().Infof(ctx, "Vector store retrieval routed to %d read replicas: %v", len(replicas), names)
+	return vector_store.NewReplicaStore(primary, replicas, names, cooldown)
+}
+
 // initializeVectorStore determines which client to use based on configuration
 func initializeVectorStore(ctx context.Context) (vector_store.VectorStore, error) {
 	// Read the vector database type from configuration
@@ -47,11 +69,25 @@ func initializeVectorStore(ctx context.Context) (vector_store.VectorStore, error
 		}
 		g.Log().Info(ctx, "PostgreSQL vector store initialized successfully")
 		return store, nil
+	case "qdrant":
+		store, err := vector_store.InitializeQdrantStore(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Qdrant vector store: %w", err)
+		}
+		g.Log().Info(ctx, "Qdrant vector store initialized successfully")
+		return store, nil
+	case "elasticsearch":
+		store, err := vector_store.InitializeElasticsearchStore(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Elasticsearch vector store: %w", err)
+		}
+		g.Log().Info(ctx, "Elasticsearch vector store initialized successfully")
+		return store, nil
 	//case "pinecone":
 	//	return initializePineconeClient(ctx)
 	//case "weaviate":
 	//	return initializeWeaviateClient(ctx)
 	default:
-		return nil, fmt.Errorf("unsupported vector database type: %s. Supported types: milvus, postgresql", dbType)
+		return nil, fmt.Errorf("unsupported vector database type: %s. Supported types: milvus, pgvector, qdrant, elasticsearch", dbType)
 	}
 }
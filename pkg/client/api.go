@@ -0,0 +1,475 @@
+package client
+
+import (
+	"context"
+	"io"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+)
+
+// 以下方法与 api/kbgo/kbgo.go 中的 IKbgoV1 接口一一对应，新增接口时同步添加
+
+func call[Res any](ctx context.Context, c *Client, req any) (*Res, error) {
+	res := new(Res)
+	if err := c.Do(ctx, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Chat related interfaces
+
+// Chat 非流式对话（忽略 req.Stream），流式对话使用 ChatStream
+func (c *Client) Chat(ctx context.Context, req *v1.ChatReq) (*v1.ChatRes, error) {
+	chatReq := *req
+	chatReq.Stream = false
+	return call[v1.ChatRes](ctx, c, &chatReq)
+}
+
+// Document related interfaces
+
+func (c *Client) DocumentsList(ctx context.Context, req *v1.DocumentsListReq) (*v1.DocumentsListRes, error) {
+	return call[v1.DocumentsListRes](ctx, c, req)
+}
+
+func (c *Client) DocumentsDelete(ctx context.Context, req *v1.DocumentsDeleteReq) (*v1.DocumentsDeleteRes, error) {
+	return call[v1.DocumentsDeleteRes](ctx, c, req)
+}
+
+func (c *Client) DocumentsUpdateValidity(ctx context.Context, req *v1.DocumentsUpdateValidityReq) (*v1.DocumentsUpdateValidityRes, error) {
+	return call[v1.DocumentsUpdateValidityRes](ctx, c, req)
+}
+
+func (c *Client) DocumentsVersions(ctx context.Context, req *v1.DocumentsVersionsReq) (*v1.DocumentsVersionsRes, error) {
+	return call[v1.DocumentsVersionsRes](ctx, c, req)
+}
+
+// Indexing related interfaces
+
+func (c *Client) IndexDocuments(ctx context.Context, req *v1.IndexDocumentsReq) (*v1.IndexDocumentsRes, error) {
+	return call[v1.IndexDocumentsRes](ctx, c, req)
+}
+
+func (c *Client) ParserHealth(ctx context.Context, req *v1.ParserHealthReq) (*v1.ParserHealthRes, error) {
+	return call[v1.ParserHealthRes](ctx, c, req)
+}
+
+func (c *Client) IngestDeadLetterList(ctx context.Context, req *v1.IngestDeadLetterListReq) (*v1.IngestDeadLetterListRes, error) {
+	return call[v1.IngestDeadLetterListRes](ctx, c, req)
+}
+
+func (c *Client) IngestDeadLetterRequeue(ctx context.Context, req *v1.IngestDeadLetterRequeueReq) (*v1.IngestDeadLetterRequeueRes, error) {
+	return call[v1.IngestDeadLetterRequeueRes](ctx, c, req)
+}
+
+// Chunk related interfaces
+
+func (c *Client) ChunksList(ctx context.Context, req *v1.ChunksListReq) (*v1.ChunksListRes, error) {
+	return call[v1.ChunksListRes](ctx, c, req)
+}
+
+func (c *Client) ChunkDelete(ctx context.Context, req *v1.ChunkDeleteReq) (*v1.ChunkDeleteRes, error) {
+	return call[v1.ChunkDeleteRes](ctx, c, req)
+}
+
+func (c *Client) UpdateChunk(ctx context.Context, req *v1.UpdateChunkReq) (*v1.UpdateChunkRes, error) {
+	return call[v1.UpdateChunkRes](ctx, c, req)
+}
+
+// Knowledge base related interfaces
+
+func (c *Client) KBGetList(ctx context.Context, req *v1.KBGetListReq) (*v1.KBGetListRes, error) {
+	return call[v1.KBGetListRes](ctx, c, req)
+}
+
+func (c *Client) KBCreate(ctx context.Context, req *v1.KBCreateReq) (*v1.KBCreateRes, error) {
+	return call[v1.KBCreateRes](ctx, c, req)
+}
+
+func (c *Client) KBDelete(ctx context.Context, req *v1.KBDeleteReq) (*v1.KBDeleteRes, error) {
+	return call[v1.KBDeleteRes](ctx, c, req)
+}
+
+func (c *Client) KBIngestHooksGet(ctx context.Context, req *v1.KBIngestHooksGetReq) (*v1.KBIngestHooksGetRes, error) {
+	return call[v1.KBIngestHooksGetRes](ctx, c, req)
+}
+
+func (c *Client) KBIngestHooksUpdate(ctx context.Context, req *v1.KBIngestHooksUpdateReq) (*v1.KBIngestHooksUpdateRes, error) {
+	return call[v1.KBIngestHooksUpdateRes](ctx, c, req)
+}
+
+func (c *Client) KBIngestHooksVersions(ctx context.Context, req *v1.KBIngestHooksVersionsReq) (*v1.KBIngestHooksVersionsRes, error) {
+	return call[v1.KBIngestHooksVersionsRes](ctx, c, req)
+}
+
+func (c *Client) KBIngestHooksRollback(ctx context.Context, req *v1.KBIngestHooksRollbackReq) (*v1.KBIngestHooksRollbackRes, error) {
+	return call[v1.KBIngestHooksRollbackRes](ctx, c, req)
+}
+
+// Upload related interfaces
+
+// UploadFile 上传文档，file 为 nil 时按 req.URL 上传网络文件
+func (c *Client) UploadFile(ctx context.Context, req *v1.UploadFileReq, filename string, file io.Reader) (*v1.UploadFileRes, error) {
+	var files []formFile
+	if file != nil {
+		files = append(files, formFile{field: "file", filename: filename, reader: file})
+	}
+	httpReq, err := c.newMultipartRequest(ctx, req, files...)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	res := new(v1.UploadFileRes)
+	if err = decodeResponse(httpResp, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Retriever related interfaces
+
+func (c *Client) Retriever(ctx context.Context, req *v1.RetrieverReq) (*v1.RetrieverRes, error) {
+	return call[v1.RetrieverRes](ctx, c, req)
+}
+
+// MCP related interfaces
+
+func (c *Client) MCPRegistryCreate(ctx context.Context, req *v1.MCPRegistryCreateReq) (*v1.MCPRegistryCreateRes, error) {
+	return call[v1.MCPRegistryCreateRes](ctx, c, req)
+}
+
+func (c *Client) MCPRegistryUpdate(ctx context.Context, req *v1.MCPRegistryUpdateReq) (*v1.MCPRegistryUpdateRes, error) {
+	return call[v1.MCPRegistryUpdateRes](ctx, c, req)
+}
+
+func (c *Client) MCPRegistryDelete(ctx context.Context, req *v1.MCPRegistryDeleteReq) (*v1.MCPRegistryDeleteRes, error) {
+	return call[v1.MCPRegistryDeleteRes](ctx, c, req)
+}
+
+func (c *Client) MCPRegistryGetOne(ctx context.Context, req *v1.MCPRegistryGetOneReq) (*v1.MCPRegistryGetOneRes, error) {
+	return call[v1.MCPRegistryGetOneRes](ctx, c, req)
+}
+
+func (c *Client) MCPRegistryGetList(ctx context.Context, req *v1.MCPRegistryGetListReq) (*v1.MCPRegistryGetListRes, error) {
+	return call[v1.MCPRegistryGetListRes](ctx, c, req)
+}
+
+// Model management interfaces
+
+func (c *Client) ReloadModels(ctx context.Context, req *v1.ReloadModelsReq) (*v1.ReloadModelsRes, error) {
+	return call[v1.ReloadModelsRes](ctx, c, req)
+}
+
+func (c *Client) ListModels(ctx context.Context, req *v1.ListModelsReq) (*v1.ListModelsRes, error) {
+	return call[v1.ListModelsRes](ctx, c, req)
+}
+
+func (c *Client) GetModel(ctx context.Context, req *v1.GetModelReq) (*v1.GetModelRes, error) {
+	return call[v1.GetModelRes](ctx, c, req)
+}
+
+func (c *Client) OllamaModelList(ctx context.Context, req *v1.OllamaModelListReq) (*v1.OllamaModelListRes, error) {
+	return call[v1.OllamaModelListRes](ctx, c, req)
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req *v1.ChatCompletionReq) (*v1.ChatCompletionRes, error) {
+	return call[v1.ChatCompletionRes](ctx, c, req)
+}
+
+func (c *Client) EmbeddingCompletion(ctx context.Context, req *v1.EmbeddingReq) (*v1.EmbeddingRes, error) {
+	return call[v1.EmbeddingRes](ctx, c, req)
+}
+
+func (c *Client) Embeddings(ctx context.Context, req *v1.EmbeddingsReq) (*v1.EmbeddingsRes, error) {
+	return call[v1.EmbeddingsRes](ctx, c, req)
+}
+
+// ChatCompletions OpenAI 兼容的聊天接口（忽略 req.Stream，只支持非流式）
+func (c *Client) ChatCompletions(ctx context.Context, req *v1.ChatCompletionsReq) (*v1.ChatCompletionRes, error) {
+	completionsReq := *req
+	completionsReq.Stream = false
+	return call[v1.ChatCompletionRes](ctx, c, &completionsReq)
+}
+
+func (c *Client) ReembedStart(ctx context.Context, req *v1.ReembedStartReq) (*v1.ReembedStartRes, error) {
+	return call[v1.ReembedStartRes](ctx, c, req)
+}
+
+func (c *Client) ReembedJobList(ctx context.Context, req *v1.ReembedJobListReq) (*v1.ReembedJobListRes, error) {
+	return call[v1.ReembedJobListRes](ctx, c, req)
+}
+
+func (c *Client) ReembedJobGet(ctx context.Context, req *v1.ReembedJobGetReq) (*v1.ReembedJobGetRes, error) {
+	return call[v1.ReembedJobGetRes](ctx, c, req)
+}
+
+func (c *Client) ReembedJobPause(ctx context.Context, req *v1.ReembedJobPauseReq) (*v1.ReembedJobPauseRes, error) {
+	return call[v1.ReembedJobPauseRes](ctx, c, req)
+}
+
+func (c *Client) ReembedJobResume(ctx context.Context, req *v1.ReembedJobResumeReq) (*v1.ReembedJobResumeRes, error) {
+	return call[v1.ReembedJobResumeRes](ctx, c, req)
+}
+
+// Analytics interfaces
+
+func (c *Client) AnalyticsUsage(ctx context.Context, req *v1.AnalyticsUsageReq) (*v1.AnalyticsUsageRes, error) {
+	return call[v1.AnalyticsUsageRes](ctx, c, req)
+}
+
+func (c *Client) AnalyticsTools(ctx context.Context, req *v1.AnalyticsToolsReq) (*v1.AnalyticsToolsRes, error) {
+	return call[v1.AnalyticsToolsRes](ctx, c, req)
+}
+
+func (c *Client) AnalyticsUnanswered(ctx context.Context, req *v1.AnalyticsUnansweredReq) (*v1.AnalyticsUnansweredRes, error) {
+	return call[v1.AnalyticsUnansweredRes](ctx, c, req)
+}
+
+func (c *Client) AnalyticsRollup(ctx context.Context, req *v1.AnalyticsRollupReq) (*v1.AnalyticsRollupRes, error) {
+	return call[v1.AnalyticsRollupRes](ctx, c, req)
+}
+
+func (c *Client) KnowledgeGapRun(ctx context.Context, req *v1.KnowledgeGapRunReq) (*v1.KnowledgeGapRunRes, error) {
+	return call[v1.KnowledgeGapRunRes](ctx, c, req)
+}
+
+func (c *Client) KnowledgeGapList(ctx context.Context, req *v1.KnowledgeGapListReq) (*v1.KnowledgeGapListRes, error) {
+	return call[v1.KnowledgeGapListRes](ctx, c, req)
+}
+
+// FinetuneExport 导出嵌入模型微调数据，JSONL 内容写入 w，返回写入的字节数
+func (c *Client) FinetuneExport(ctx context.Context, req *v1.FinetuneExportReq, w io.Writer) (int64, error) {
+	return c.download(ctx, req, w)
+}
+
+// Experiment interfaces
+
+func (c *Client) ExperimentList(ctx context.Context, req *v1.ExperimentListReq) (*v1.ExperimentListRes, error) {
+	return call[v1.ExperimentListRes](ctx, c, req)
+}
+
+func (c *Client) ExperimentMetrics(ctx context.Context, req *v1.ExperimentMetricsReq) (*v1.ExperimentMetricsRes, error) {
+	return call[v1.ExperimentMetricsRes](ctx, c, req)
+}
+
+// Shadow interfaces
+
+func (c *Client) ShadowResultList(ctx context.Context, req *v1.ShadowResultListReq) (*v1.ShadowResultListRes, error) {
+	return call[v1.ShadowResultListRes](ctx, c, req)
+}
+
+func (c *Client) ShadowSummary(ctx context.Context, req *v1.ShadowSummaryReq) (*v1.ShadowSummaryRes, error) {
+	return call[v1.ShadowSummaryRes](ctx, c, req)
+}
+
+// Conversation interfaces
+
+func (c *Client) ConversationDelete(ctx context.Context, req *v1.ConversationDeleteReq) (*v1.ConversationDeleteRes, error) {
+	return call[v1.ConversationDeleteRes](ctx, c, req)
+}
+
+func (c *Client) ConversationModelUpdate(ctx context.Context, req *v1.ConversationModelUpdateReq) (*v1.ConversationModelUpdateRes, error) {
+	return call[v1.ConversationModelUpdateRes](ctx, c, req)
+}
+
+// ConversationExport 下载会话导出的 PDF 或 DOCX 报告并写入 w，返回写入的字节数
+func (c *Client) ConversationExport(ctx context.Context, req *v1.ConversationExportReq, w io.Writer) (int64, error) {
+	return c.download(ctx, req, w)
+}
+
+func (c *Client) MessageFeedback(ctx context.Context, req *v1.MessageFeedbackReq) (*v1.MessageFeedbackRes, error) {
+	return call[v1.MessageFeedbackRes](ctx, c, req)
+}
+
+func (c *Client) MessageSaverStats(ctx context.Context, req *v1.MessageSaverStatsReq) (*v1.MessageSaverStatsRes, error) {
+	return call[v1.MessageSaverStatsRes](ctx, c, req)
+}
+
+func (c *Client) WorkspaceList(ctx context.Context, req *v1.WorkspaceListReq) (*v1.WorkspaceListRes, error) {
+	return call[v1.WorkspaceListRes](ctx, c, req)
+}
+
+func (c *Client) WorkspaceFileDelete(ctx context.Context, req *v1.WorkspaceFileDeleteReq) (*v1.WorkspaceFileDeleteRes, error) {
+	return call[v1.WorkspaceFileDeleteRes](ctx, c, req)
+}
+
+func (c *Client) PIIScrubRun(ctx context.Context, req *v1.PIIScrubRunReq) (*v1.PIIScrubRunRes, error) {
+	return call[v1.PIIScrubRunRes](ctx, c, req)
+}
+
+func (c *Client) PIIScrubLogList(ctx context.Context, req *v1.PIIScrubLogListReq) (*v1.PIIScrubLogListRes, error) {
+	return call[v1.PIIScrubLogListRes](ctx, c, req)
+}
+
+// Handoff interfaces
+
+func (c *Client) HandoffTicketList(ctx context.Context, req *v1.HandoffTicketListReq) (*v1.HandoffTicketListRes, error) {
+	return call[v1.HandoffTicketListRes](ctx, c, req)
+}
+
+func (c *Client) HandoffTicketGet(ctx context.Context, req *v1.HandoffTicketGetReq) (*v1.HandoffTicketGetRes, error) {
+	return call[v1.HandoffTicketGetRes](ctx, c, req)
+}
+
+func (c *Client) HandoffMessage(ctx context.Context, req *v1.HandoffMessageReq) (*v1.HandoffMessageRes, error) {
+	return call[v1.HandoffMessageRes](ctx, c, req)
+}
+
+func (c *Client) HandoffResolve(ctx context.Context, req *v1.HandoffResolveReq) (*v1.HandoffResolveRes, error) {
+	return call[v1.HandoffResolveRes](ctx, c, req)
+}
+
+// Promotion interfaces
+
+func (c *Client) PromoteMessage(ctx context.Context, req *v1.PromoteMessageReq) (*v1.PromoteMessageRes, error) {
+	return call[v1.PromoteMessageRes](ctx, c, req)
+}
+
+func (c *Client) PromotionList(ctx context.Context, req *v1.PromotionListReq) (*v1.PromotionListRes, error) {
+	return call[v1.PromotionListRes](ctx, c, req)
+}
+
+func (c *Client) PromotionApprove(ctx context.Context, req *v1.PromotionApproveReq) (*v1.PromotionApproveRes, error) {
+	return call[v1.PromotionApproveRes](ctx, c, req)
+}
+
+func (c *Client) PromotionReject(ctx context.Context, req *v1.PromotionRejectReq) (*v1.PromotionRejectRes, error) {
+	return call[v1.PromotionRejectRes](ctx, c, req)
+}
+
+// Persona interfaces
+
+func (c *Client) PersonaCreate(ctx context.Context, req *v1.PersonaCreateReq) (*v1.PersonaCreateRes, error) {
+	return call[v1.PersonaCreateRes](ctx, c, req)
+}
+
+func (c *Client) PersonaUpdate(ctx context.Context, req *v1.PersonaUpdateReq) (*v1.PersonaUpdateRes, error) {
+	return call[v1.PersonaUpdateRes](ctx, c, req)
+}
+
+func (c *Client) PersonaDelete(ctx context.Context, req *v1.PersonaDeleteReq) (*v1.PersonaDeleteRes, error) {
+	return call[v1.PersonaDeleteRes](ctx, c, req)
+}
+
+func (c *Client) PersonaGet(ctx context.Context, req *v1.PersonaGetReq) (*v1.PersonaGetRes, error) {
+	return call[v1.PersonaGetRes](ctx, c, req)
+}
+
+func (c *Client) PersonaList(ctx context.Context, req *v1.PersonaListReq) (*v1.PersonaListRes, error) {
+	return call[v1.PersonaListRes](ctx, c, req)
+}
+
+// Retrieval view interfaces
+
+func (c *Client) RetrievalViewCreate(ctx context.Context, req *v1.RetrievalViewCreateReq) (*v1.RetrievalViewCreateRes, error) {
+	return call[v1.RetrievalViewCreateRes](ctx, c, req)
+}
+
+func (c *Client) RetrievalViewUpdate(ctx context.Context, req *v1.RetrievalViewUpdateReq) (*v1.RetrievalViewUpdateRes, error) {
+	return call[v1.RetrievalViewUpdateRes](ctx, c, req)
+}
+
+func (c *Client) RetrievalViewDelete(ctx context.Context, req *v1.RetrievalViewDeleteReq) (*v1.RetrievalViewDeleteRes, error) {
+	return call[v1.RetrievalViewDeleteRes](ctx, c, req)
+}
+
+func (c *Client) RetrievalViewGet(ctx context.Context, req *v1.RetrievalViewGetReq) (*v1.RetrievalViewGetRes, error) {
+	return call[v1.RetrievalViewGetRes](ctx, c, req)
+}
+
+func (c *Client) RetrievalViewList(ctx context.Context, req *v1.RetrievalViewListReq) (*v1.RetrievalViewListRes, error) {
+	return call[v1.RetrievalViewListRes](ctx, c, req)
+}
+
+// Tool example interfaces
+
+func (c *Client) ToolExampleCreate(ctx context.Context, req *v1.ToolExampleCreateReq) (*v1.ToolExampleCreateRes, error) {
+	return call[v1.ToolExampleCreateRes](ctx, c, req)
+}
+
+func (c *Client) ToolExampleUpdate(ctx context.Context, req *v1.ToolExampleUpdateReq) (*v1.ToolExampleUpdateRes, error) {
+	return call[v1.ToolExampleUpdateRes](ctx, c, req)
+}
+
+func (c *Client) ToolExampleDelete(ctx context.Context, req *v1.ToolExampleDeleteReq) (*v1.ToolExampleDeleteRes, error) {
+	return call[v1.ToolExampleDeleteRes](ctx, c, req)
+}
+
+func (c *Client) ToolExampleList(ctx context.Context, req *v1.ToolExampleListReq) (*v1.ToolExampleListRes, error) {
+	return call[v1.ToolExampleListRes](ctx, c, req)
+}
+
+func (c *Client) ToolExampleTest(ctx context.Context, req *v1.ToolExampleTestReq) (*v1.ToolExampleTestRes, error) {
+	return call[v1.ToolExampleTestRes](ctx, c, req)
+}
+
+func (c *Client) ToolConfigValidate(ctx context.Context, req *v1.ToolConfigValidateReq) (*v1.ToolConfigValidateRes, error) {
+	return call[v1.ToolConfigValidateRes](ctx, c, req)
+}
+
+// Project interfaces
+
+func (c *Client) ProjectCreate(ctx context.Context, req *v1.ProjectCreateReq) (*v1.ProjectCreateRes, error) {
+	return call[v1.ProjectCreateRes](ctx, c, req)
+}
+
+func (c *Client) ProjectUpdate(ctx context.Context, req *v1.ProjectUpdateReq) (*v1.ProjectUpdateRes, error) {
+	return call[v1.ProjectUpdateRes](ctx, c, req)
+}
+
+func (c *Client) ProjectDelete(ctx context.Context, req *v1.ProjectDeleteReq) (*v1.ProjectDeleteRes, error) {
+	return call[v1.ProjectDeleteRes](ctx, c, req)
+}
+
+func (c *Client) ProjectGet(ctx context.Context, req *v1.ProjectGetReq) (*v1.ProjectGetRes, error) {
+	return call[v1.ProjectGetRes](ctx, c, req)
+}
+
+func (c *Client) ProjectList(ctx context.Context, req *v1.ProjectListReq) (*v1.ProjectListRes, error) {
+	return call[v1.ProjectListRes](ctx, c, req)
+}
+
+func (c *Client) ProjectResourceAdd(ctx context.Context, req *v1.ProjectResourceAddReq) (*v1.ProjectResourceAddRes, error) {
+	return call[v1.ProjectResourceAddRes](ctx, c, req)
+}
+
+func (c *Client) ProjectResourceRemove(ctx context.Context, req *v1.ProjectResourceRemoveReq) (*v1.ProjectResourceRemoveRes, error) {
+	return call[v1.ProjectResourceRemoveRes](ctx, c, req)
+}
+
+func (c *Client) ProjectResourceList(ctx context.Context, req *v1.ProjectResourceListReq) (*v1.ProjectResourceListRes, error) {
+	return call[v1.ProjectResourceListRes](ctx, c, req)
+}
+
+func (c *Client) ProjectMemberSave(ctx context.Context, req *v1.ProjectMemberSaveReq) (*v1.ProjectMemberSaveRes, error) {
+	return call[v1.ProjectMemberSaveRes](ctx, c, req)
+}
+
+func (c *Client) ProjectMemberRemove(ctx context.Context, req *v1.ProjectMemberRemoveReq) (*v1.ProjectMemberRemoveRes, error) {
+	return call[v1.ProjectMemberRemoveRes](ctx, c, req)
+}
+
+func (c *Client) ProjectUsage(ctx context.Context, req *v1.ProjectUsageReq) (*v1.ProjectUsageRes, error) {
+	return call[v1.ProjectUsageRes](ctx, c, req)
+}
+
+// Usage interfaces
+
+func (c *Client) Usage(ctx context.Context, req *v1.UsageReq) (*v1.UsageRes, error) {
+	return call[v1.UsageRes](ctx, c, req)
+}
+
+func (c *Client) UsageRollup(ctx context.Context, req *v1.UsageRollupReq) (*v1.UsageRollupRes, error) {
+	return call[v1.UsageRollupRes](ctx, c, req)
+}
+
+// Image interfaces
+
+// ImageGet 下载上传图片（可指定尺寸返回缩略图），将图片内容写入 w，返回写入的字节数
+func (c *Client) ImageGet(ctx context.Context, req *v1.ImageGetReq, w io.Writer) (int64, error) {
+	return c.download(ctx, req, w)
+}
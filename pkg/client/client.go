@@ -0,0 +1,317 @@
+// Package client kbgo 服务的 Go 客户端
+// 请求/响应直接使用 api/kbgo/v1 中的类型，接口路径和方法从请求结构体的 g.Meta 标签读取，与服务端路由保持一致
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/gogf/gf/v2/util/gmeta"
+)
+
+const (
+	// DefaultPrefix 服务端接口的路由分组前缀
+	DefaultPrefix = "/api"
+
+	defaultTimeout = 5 * time.Minute
+)
+
+// Client kbgo 客户端，可在多个 goroutine 中并发使用
+type Client struct {
+	baseURL    string
+	prefix     string
+	httpClient *http.Client
+	header     http.Header
+}
+
+// Option 客户端配置项
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client（流式接口不受 Timeout 限制时需传入 Timeout 为 0 的客户端）
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout 设置请求超时时间（默认 5 分钟）
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithHeader 为每个请求添加请求头，如认证信息
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithPrefix 设置接口路由前缀（默认 /api）
+func WithPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.prefix = "/" + strings.Trim(prefix, "/")
+		if c.prefix == "/" {
+			c.prefix = ""
+		}
+	}
+}
+
+// New 创建客户端，baseURL 为服务地址，如 http://localhost:8000
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		prefix:     DefaultPrefix,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		header:     make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error 服务端返回的业务错误或非 2xx 响应
+type Error struct {
+	StatusCode int    // HTTP 状态码
+	Code       int    // 响应体中的业务错误码
+	Message    string // 错误信息
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("kbgo: status %d, code %d: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// response 服务端统一响应结构
+type response struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Do 调用 req 对应的接口并将响应数据解码到 res
+// req 为 api/kbgo/v1 中的请求结构体指针，res 为对应响应结构体指针（可为 nil）
+func (c *Client) Do(ctx context.Context, req any, res any) error {
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	return decodeResponse(httpResp, res)
+}
+
+// download 调用返回文件内容（非统一 JSON 响应结构）的接口，将响应体写入 w
+// 服务端返回 JSON 时视为错误响应
+func (c *Client) download(ctx context.Context, req any, w io.Writer) (int64, error) {
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer httpResp.Body.Close()
+	if strings.HasPrefix(httpResp.Header.Get("Content-Type"), "application/json") || httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		if err = decodeResponse(httpResp, nil); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("kbgo: unexpected content type %q for download", httpResp.Header.Get("Content-Type"))
+	}
+	return io.Copy(w, httpResp.Body)
+}
+
+// newRequest 根据请求结构体构建 HTTP 请求：GET/DELETE 参数放在查询字符串中，其余方法以 JSON 请求体发送
+func (c *Client) newRequest(ctx context.Context, req any) (*http.Request, error) {
+	method, path, params, err := resolveRoute(req)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	contentType := ""
+	if method == http.MethodGet || method == http.MethodDelete {
+		if query := encodeQuery(params); query != "" {
+			path += "?" + query
+		}
+	} else {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+	return c.buildRequest(ctx, method, path, body, contentType)
+}
+
+// formFile multipart 请求中的一个文件
+type formFile struct {
+	field    string
+	filename string
+	reader   io.Reader
+}
+
+// newMultipartRequest 构建 multipart/form-data 请求，用于文件上传
+func (c *Client) newMultipartRequest(ctx context.Context, req any, files ...formFile) (*http.Request, error) {
+	method, path, params, err := resolveRoute(req)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for key, value := range params {
+		if err = writer.WriteField(key, gconv.String(value)); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file.field, file.filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.Copy(part, file.reader); err != nil {
+			return nil, err
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return c.buildRequest(ctx, method, path, &buf, writer.FormDataContentType())
+}
+
+func (c *Client) buildRequest(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+c.prefix+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	return httpReq, nil
+}
+
+// resolveRoute 读取请求结构体 g.Meta 中的路径和方法，将路径参数（{id} 或 :id）替换为对应字段的值
+// 返回的 params 不包含路径参数和零值字段
+func resolveRoute(req any) (method, path string, params map[string]any, err error) {
+	path = gmeta.Get(req, "path").String()
+	method = strings.ToUpper(gmeta.Get(req, "method").String())
+	if path == "" || method == "" {
+		return "", "", nil, fmt.Errorf("kbgo: request %T has no path or method in g.Meta", req)
+	}
+
+	params = make(map[string]any)
+	for key, value := range gconv.Map(req) {
+		if !isEmptyParam(value) {
+			params[key] = value
+		}
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		name := ""
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name = segment[1:]
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			name = segment[1 : len(segment)-1]
+		default:
+			continue
+		}
+		key, ok := matchParam(params, name)
+		if !ok {
+			return "", "", nil, fmt.Errorf("kbgo: missing path parameter %q for %T", name, req)
+		}
+		segments[i] = url.PathEscape(gconv.String(params[key]))
+		delete(params, key)
+	}
+	return method, strings.Join(segments, "/"), params, nil
+}
+
+// matchParam 按服务端的规则（忽略大小写和下划线）查找路径参数对应的字段
+func matchParam(params map[string]any, name string) (string, bool) {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	}
+	for key := range params {
+		if normalize(key) == normalize(name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// isEmptyParam 零值字段不发送，由服务端使用默认值（d 标签）；需要显式传零值的字段在请求结构体中为指针类型
+// 文件字段通过 multipart 单独发送
+func isEmptyParam(value any) bool {
+	if value == nil {
+		return true
+	}
+	switch value.(type) {
+	case []*multipart.FileHeader, *multipart.FileHeader, *ghttp.UploadFile, ghttp.UploadFiles:
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+// encodeQuery 编码查询参数，切片使用 key[]=a&key[]=b 的形式
+func encodeQuery(params map[string]any) string {
+	values := url.Values{}
+	for key, value := range params {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice {
+			for _, item := range gconv.Strings(value) {
+				values.Add(key+"[]", item)
+			}
+			continue
+		}
+		values.Set(key, gconv.String(value))
+	}
+	return values.Encode()
+}
+
+// decodeResponse 解析统一响应结构，业务错误码非 0 或 HTTP 状态码非 2xx 时返回 *Error
+func decodeResponse(httpResp *http.Response, res any) error {
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	var resp response
+	if err = json.Unmarshal(body, &resp); err != nil {
+		if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+			return &Error{StatusCode: httpResp.StatusCode, Message: strings.TrimSpace(string(body))}
+		}
+		return fmt.Errorf("kbgo: invalid response: %w", err)
+	}
+	if resp.Code != 0 || httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return &Error{StatusCode: httpResp.StatusCode, Code: resp.Code, Message: resp.Message}
+	}
+	if res == nil || len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, res)
+}
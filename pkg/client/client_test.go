@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+)
+
+func TestResolveRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        any
+		wantMethod string
+		wantPath   string
+		wantParams []string
+	}{
+		{"colon param", &v1.HandoffMessageReq{TicketID: "t 1", Content: "hi"}, "POST", "/v1/handoff/tickets/t%201/messages", []string{"content"}},
+		{"brace param", &v1.KBDeleteReq{Id: "kb1"}, "DELETE", "/v1/kb/kb1", nil},
+		{"query params", &v1.HandoffTicketListReq{Status: "open"}, "GET", "/v1/handoff/tickets", []string{"status"}},
+		{"p tags", &v1.IndexDocumentsReq{DocumentIds: []string{"d1"}, EmbeddingModelID: "m"}, "POST", "/v1/index", []string{"document_ids", "embedding_model_id"}},
+	}
+	for _, tt := range tests {
+		method, path, params, err := resolveRoute(tt.req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if method != tt.wantMethod || path != tt.wantPath {
+			t.Errorf("%s: got %s %s, want %s %s", tt.name, method, path, tt.wantMethod, tt.wantPath)
+		}
+		if len(params) != len(tt.wantParams) {
+			t.Errorf("%s: got params %v, want keys %v", tt.name, params, tt.wantParams)
+		}
+		for _, key := range tt.wantParams {
+			if _, ok := params[key]; !ok {
+				t.Errorf("%s: missing param %q in %v", tt.name, key, params)
+			}
+		}
+	}
+
+	if _, _, _, err := resolveRoute(&v1.KBDeleteReq{}); err == nil {
+		t.Error("expected error for empty path parameter")
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/handoff/tickets/t1/resolve":
+			if r.Method != http.MethodPost {
+				t.Errorf("unexpected method %s", r.Method)
+			}
+			io.WriteString(w, `{"code":0,"message":"OK","data":{"ticket":{"ticket_id":"t1","status":"resolved"}}}`)
+		default:
+			io.WriteString(w, `{"code":52,"message":"ticket not found","data":null}`)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL)
+
+	res, err := c.HandoffResolve(context.Background(), &v1.HandoffResolveReq{TicketID: "t1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Ticket == nil || res.Ticket.Status != "resolved" {
+		t.Errorf("unexpected response: %+v", res)
+	}
+
+	_, err = c.HandoffTicketGet(context.Background(), &v1.HandoffTicketGetReq{TicketID: "missing"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 52 || apiErr.Message != "ticket not found" {
+		t.Errorf("expected api error, got %v", err)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("expected stream request, got %v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "retry: 3000\n\n")
+		io.WriteString(w, "parse_progress:{\"file_name\":\"a.pdf\",\"file_index\":1,\"file_count\":1,\"stage\":\"page\",\"page\":3,\"pages\":10}\n\n")
+		io.WriteString(w, "tool_progress:{\"service_name\":\"document\",\"tool_name\":\"summarize_document\",\"stage\":\"map\",\"done\":1,\"total\":2}\n\n")
+		io.WriteString(w, "agent_summary:{\"iterations\":2,\"tokens_used\":120,\"rows_returned\":3,\"tools\":[{\"service_name\":\"db\",\"tool_name\":\"query\",\"status\":\"success\",\"duration_ms\":15,\"rows\":3}]}\n\n")
+		io.WriteString(w, "documents:{\"id\":\"a\",\"document\":[{\"id\":\"doc1\",\"content\":\"c\"}]}\n\n")
+		io.WriteString(w, ": ping\n\n")
+		io.WriteString(w, "reasoning:{\"id\":\"a\",\"reasoning_content\":\"先问候\"}\n\n")
+		io.WriteString(w, "data:{\"id\":\"a\",\"content\":\"你好\"}\n\n")
+		io.WriteString(w, "data:{\"id\":\"a\",\"content\":\"，世界\"}\n\n")
+		io.WriteString(w, "confidence:{\"id\":\"a\",\"confidence\":{\"score\":0.8,\"level\":\"high\"}}\n\n")
+		io.WriteString(w, "follow_up:{\"id\":\"a\",\"follow_up\":[\"q1\"]}\n\n")
+		io.WriteString(w, "data:[DONE]\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := New(server.URL).ChatStream(context.Background(), &v1.ChatReq{ConvID: "c", Question: "q", ModelID: "m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := stream.Collect()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Answer != "你好，世界" || res.ReasoningContent != "先问候" || len(res.References) != 1 || res.Confidence == nil || res.Confidence.Level != "high" || len(res.FollowUpQuestions) != 1 {
+		t.Errorf("unexpected collected response: %+v", res)
+	}
+	if res.AgentSummary == nil || res.AgentSummary.RowsReturned != 3 || len(res.AgentSummary.Tools) != 1 {
+		t.Errorf("unexpected agent summary: %+v", res.AgentSummary)
+	}
+}
+
+func TestChatStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data:{\"id\":\"a\",\"content\":\"部分\"}\n\n")
+		io.WriteString(w, "event: error\ndata: model unavailable\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := New(server.URL).ChatStream(context.Background(), &v1.ChatReq{ConvID: "c", Question: "q", ModelID: "m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = stream.Collect()
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Message != "model unavailable" {
+		t.Errorf("expected stream error, got %v", err)
+	}
+}
+
+func TestChatStreamWithFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("expected multipart request: %v", err)
+		}
+		if r.FormValue("stream") != "true" || r.FormValue("question") != "q" {
+			t.Errorf("unexpected form values: %v", r.MultipartForm.Value)
+		}
+		files := r.MultipartForm.File["files"]
+		if len(files) != 2 || files[0].Filename != "a.txt" || files[1].Filename != "b.txt" {
+			t.Errorf("unexpected files: %v", files)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data:{\"id\":\"a\",\"content\":\"ok\"}\n\n")
+		io.WriteString(w, "data:[DONE]\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := New(server.URL).ChatStreamWithFiles(context.Background(), &v1.ChatReq{ConvID: "c", Question: "q"}, []Attachment{
+		{Filename: "a.txt", Reader: strings.NewReader("alpha")},
+		{Filename: "b.txt", Reader: strings.NewReader("beta")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := stream.Collect()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Answer != "ok" {
+		t.Errorf("unexpected answer: %q", res.Answer)
+	}
+}
@@ -0,0 +1,315 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Malowking/kbgo/api/kbgo/v1"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// 服务端流式接口的事件名称
+const (
+	EventData       = "data"       // 回答增量内容
+	EventDocuments  = "documents"  // 检索到的参考文档，在回答内容之前发送
+	EventReasoning  = "reasoning"  // 推理模型的思考过程（服务端可见性策略为 show 或 summarize 时发送）
+	EventConfidence = "confidence" // 回答置信度，在结束前发送
+	EventFollowUp   = "follow_up"  // 推荐追问，在结束前发送
+	EventHandoff    = "handoff"    // 人工接管事件（工单创建、客服消息、工单结束）
+	// EventToolProgress 耗时工具（如长文档摘要）的执行进度，在回答内容之前发送
+	EventToolProgress = "tool_progress"
+	// EventParseProgress 上传文档的解析进度（逐个文件，支持时逐页），在回答内容之前发送
+	EventParseProgress = "parse_progress"
+	// EventAgentSummary 工具调用执行摘要，在工具调用结束后、回答内容之前发送
+	EventAgentSummary = "agent_summary"
+
+	doneData = "[DONE]"
+)
+
+// Event 一条 SSE 事件
+type Event struct {
+	Name string
+	Data []byte
+}
+
+// Decode 将事件数据解码到 v
+func (e *Event) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// EventStream 服务端流式响应的事件读取器
+// 服务端每行以 "名称:数据" 的形式发送一个事件，错误以 "event: error" 加 "data: 错误信息" 发送
+type EventStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+func newEventStream(body io.ReadCloser) *EventStream {
+	scanner := bufio.NewScanner(body)
+	// 参考文档事件可能较大
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &EventStream{body: body, scanner: scanner}
+}
+
+// Recv 读取下一条事件，收到结束标记或连接关闭时返回 io.EOF
+func (s *EventStream) Recv() (*Event, error) {
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		// 空行和以冒号开头的注释（心跳）跳过
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, data, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, data = strings.TrimSpace(name), strings.TrimSpace(data)
+		// retry 字段是给 EventSource 的重连间隔，不是事件
+		if name == "retry" {
+			continue
+		}
+		if name == "event" && data == "error" {
+			return nil, s.readError()
+		}
+		if name == EventData && data == doneData {
+			return nil, io.EOF
+		}
+		return &Event{Name: name, Data: []byte(data)}, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// readError 读取 error 事件后的错误信息
+func (s *EventStream) readError() error {
+	for s.scanner.Scan() {
+		if _, message, ok := strings.Cut(s.scanner.Text(), ":"); ok {
+			return &Error{StatusCode: http.StatusOK, Message: strings.TrimSpace(message)}
+		}
+	}
+	return &Error{StatusCode: http.StatusOK, Message: "stream error"}
+}
+
+// Close 关闭连接
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
+
+// openStream 以 JSON 请求体打开流式接口
+func (c *Client) openStream(ctx context.Context, req any) (*EventStream, error) {
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.doStream(httpReq)
+}
+
+// doStream 发送请求并在响应为事件流时返回读取器，否则按统一响应结构解析错误
+func (c *Client) doStream(httpReq *http.Request) (*EventStream, error) {
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(httpResp.Header.Get("Content-Type"), "text/event-stream") {
+		defer httpResp.Body.Close()
+		if err = decodeResponse(httpResp, nil); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("kbgo: unexpected content type %q for stream", httpResp.Header.Get("Content-Type"))
+	}
+	return newEventStream(httpResp.Body), nil
+}
+
+// ChatChunk 流式对话的一条事件
+type ChatChunk struct {
+	Event      string               // 事件名称，见 Event* 常量
+	ID         string               // 同一条回答的所有事件 ID 相同
+	Created    int64                // 回答开始生成的时间（Unix 秒）
+	Content    string               // 回答增量内容（data 事件）
+	Reasoning  string               // 推理内容（reasoning 事件）
+	Documents  []*schema.Document   // 参考文档（documents 事件）
+	FollowUp   []string             // 推荐追问（follow_up 事件）
+	Confidence *v1.AnswerConfidence // 回答置信度（confidence 事件）
+	Progress   *ToolProgress        // 工具执行进度（tool_progress 事件）
+	Agent      *v1.AgentRunSummary  // 工具调用执行摘要（agent_summary 事件）
+	Parse      *ParseProgress       // 上传文档的解析进度（parse_progress 事件）
+}
+
+// ToolProgress 工具执行进度（tool_progress 事件数据）
+type ToolProgress struct {
+	ServiceName string `json:"service_name"`
+	ToolName    string `json:"tool_name"`
+	Stage       string `json:"stage"` // 如长文档摘要的 map（分段摘要）、reduce（合并摘要）
+	Done        int    `json:"done"`
+	Total       int    `json:"total"`
+	Message     string `json:"message,omitempty"`
+}
+
+// ParseProgress 上传文档的解析进度（parse_progress 事件数据）
+type ParseProgress struct {
+	FileName  string `json:"file_name"`
+	FileIndex int    `json:"file_index"` // 从 1 开始
+	FileCount int    `json:"file_count"`
+	Stage     string `json:"stage"`             // start / page / done / failed
+	Page      int    `json:"page,omitempty"`    // 已解析页数（page 阶段）
+	Pages     int    `json:"pages,omitempty"`   // 总页数（page 阶段）
+	Message   string `json:"message,omitempty"` // 失败原因
+}
+
+// chatStreamData 流式对话事件数据，与服务端 common.StreamData 一致
+type chatStreamData struct {
+	Id         string               `json:"id"`
+	Created    int64                `json:"created"`
+	Content    string               `json:"content"`
+	Reasoning  string               `json:"reasoning_content"`
+	Document   []*schema.Document   `json:"document"`
+	FollowUp   []string             `json:"follow_up"`
+	Confidence *v1.AnswerConfidence `json:"confidence"`
+}
+
+// ChatStream 流式对话读取器
+type ChatStream struct {
+	stream *EventStream
+}
+
+// Recv 读取下一条事件，回答结束时返回 io.EOF
+func (s *ChatStream) Recv() (*ChatChunk, error) {
+	event, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if event.Name == EventToolProgress {
+		var progress ToolProgress
+		if err = event.Decode(&progress); err != nil {
+			return nil, fmt.Errorf("kbgo: invalid %s event: %w", event.Name, err)
+		}
+		return &ChatChunk{Event: event.Name, Progress: &progress}, nil
+	}
+	if event.Name == EventParseProgress {
+		var progress ParseProgress
+		if err = event.Decode(&progress); err != nil {
+			return nil, fmt.Errorf("kbgo: invalid %s event: %w", event.Name, err)
+		}
+		return &ChatChunk{Event: event.Name, Parse: &progress}, nil
+	}
+	if event.Name == EventAgentSummary {
+		var summary v1.AgentRunSummary
+		if err = event.Decode(&summary); err != nil {
+			return nil, fmt.Errorf("kbgo: invalid %s event: %w", event.Name, err)
+		}
+		return &ChatChunk{Event: event.Name, Agent: &summary}, nil
+	}
+	var data chatStreamData
+	if err = event.Decode(&data); err != nil {
+		return nil, fmt.Errorf("kbgo: invalid %s event: %w", event.Name, err)
+	}
+	return &ChatChunk{
+		Event:      event.Name,
+		ID:         data.Id,
+		Created:    data.Created,
+		Content:    data.Content,
+		Reasoning:  data.Reasoning,
+		Documents:  data.Document,
+		FollowUp:   data.FollowUp,
+		Confidence: data.Confidence,
+	}, nil
+}
+
+// Collect 读取剩余的全部事件并汇总为与非流式对话相同的响应，读取完成后关闭连接
+func (s *ChatStream) Collect() (*v1.ChatRes, error) {
+	defer s.Close()
+	res := &v1.ChatRes{}
+	var answer, reasoning strings.Builder
+	for {
+		chunk, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch chunk.Event {
+		case EventData:
+			answer.WriteString(chunk.Content)
+		case EventReasoning:
+			reasoning.WriteString(chunk.Reasoning)
+		case EventDocuments:
+			res.References = chunk.Documents
+		case EventConfidence:
+			res.Confidence = chunk.Confidence
+		case EventFollowUp:
+			res.FollowUpQuestions = chunk.FollowUp
+		case EventAgentSummary:
+			res.AgentSummary = chunk.Agent
+		}
+	}
+	res.Answer = answer.String()
+	res.ReasoningContent = reasoning.String()
+	return res, nil
+}
+
+// Close 关闭连接
+func (s *ChatStream) Close() error {
+	return s.stream.Close()
+}
+
+// ChatStream 流式对话（忽略 req.Stream，始终以流式请求）
+func (c *Client) ChatStream(ctx context.Context, req *v1.ChatReq) (*ChatStream, error) {
+	streamReq := *req
+	streamReq.Stream = true
+	stream, err := c.openStream(ctx, &streamReq)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatStream{stream: stream}, nil
+}
+
+// Attachment 对话附带的文件（图片、音频、视频等多模态文件）
+type Attachment struct {
+	Filename string
+	Reader   io.Reader
+}
+
+// ChatStreamWithFiles 附带文件的流式对话，以 multipart/form-data 发送，文件对应请求的 files 字段
+func (c *Client) ChatStreamWithFiles(ctx context.Context, req *v1.ChatReq, files []Attachment) (*ChatStream, error) {
+	streamReq := *req
+	streamReq.Stream = true
+	formFiles := make([]formFile, 0, len(files))
+	for _, file := range files {
+		formFiles = append(formFiles, formFile{field: "files", filename: file.Filename, reader: file.Reader})
+	}
+	httpReq, err := c.newMultipartRequest(ctx, &streamReq, formFiles...)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.doStream(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatStream{stream: stream}, nil
+}
+
+// HandoffEvent 人工接管事件数据（handoff 事件）
+type HandoffEvent struct {
+	Type     string `json:"type"` // opened / message / resolved
+	TicketID string `json:"ticket_id"`
+	ConvID   string `json:"conv_id"`
+	Role     string `json:"role,omitempty"`
+	Agent    string `json:"agent,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Created  int64  `json:"created"`
+}
+
+// HandoffStream 订阅会话的人工接管事件，事件数据可通过 Event.Decode 解码为 HandoffEvent
+func (c *Client) HandoffStream(ctx context.Context, req *v1.HandoffStreamReq) (*EventStream, error) {
+	return c.openStream(ctx, req)
+}
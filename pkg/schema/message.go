@@ -30,6 +30,10 @@ type Message struct {
 	// ToolCallID 工具调用ID（Tool消息使用）
 	ToolCallID string `json:"tool_call_id,omitempty"`
 
+	// ReasoningContent 推理模型的思考过程（Assistant消息使用）
+	// 只用于返回给调用方，格式适配器不会将其回传给模型（部分服务商会拒绝请求中的该字段）
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+
 	// Extra 扩展字段，用于存储额外信息
 	Extra map[string]any `json:"extra,omitempty"`
 }
@@ -0,0 +1,9 @@
+// Package testkit 为下游集成测试提供 kbgo 外部依赖的假实现和测试数据：
+//   - FakeVectorStore：内存向量库，实现 vector_store.VectorStore
+//   - ModelServer：OpenAI 兼容的假模型服务，按脚本返回回答和工具调用，并提供向量化接口
+//   - MCPServer：HTTP 传输的假 MCP 服务，工具由测试注册
+//   - HashEmbedding：确定性的文本向量，假向量库和假模型服务使用同一算法，相同文本得到相同向量
+//   - Conversation、ToolExchange、Documents 等对话和文档测试数据
+//
+// 所有假服务基于 httptest，传入的 testing.TB 结束时自动关闭
+package testkit
@@ -0,0 +1,83 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+// Conversation 按用户、助手交替的顺序构造对话历史，第一条为用户消息
+func Conversation(turns ...string) []*schema.Message {
+	messages := make([]*schema.Message, 0, len(turns))
+	for i, content := range turns {
+		role := schema.User
+		if i%2 == 1 {
+			role = schema.Assistant
+		}
+		messages = append(messages, &schema.Message{Role: role, Content: content})
+	}
+	return messages
+}
+
+// WithSystem 在对话前加入系统提示词
+func WithSystem(prompt string, messages []*schema.Message) []*schema.Message {
+	return append([]*schema.Message{{Role: schema.System, Content: prompt}}, messages...)
+}
+
+// ToolExchange 构造一轮工具调用：助手发起调用的消息和携带工具结果的消息
+// arguments 为字符串时原样使用，其他值序列化为 JSON
+func ToolExchange(callID, toolName string, arguments any, result string) []*schema.Message {
+	args, ok := arguments.(string)
+	if !ok {
+		data, _ := json.Marshal(arguments)
+		args = string(data)
+	}
+	return []*schema.Message{
+		{
+			Role: schema.Assistant,
+			ToolCalls: []schema.ToolCall{{
+				ID:       callID,
+				Type:     "function",
+				Function: schema.FunctionCall{Name: toolName, Arguments: args},
+			}},
+		},
+		{Role: schema.Tool, Content: result, ToolCallID: callID},
+	}
+}
+
+// Documents 构造属于同一知识库、同一文档的 chunk，ID 依次为 <documentID>-chunk-1、-2 ...
+func Documents(knowledgeID, documentID string, contents ...string) []*schema.Document {
+	docs := make([]*schema.Document, 0, len(contents))
+	for i, content := range contents {
+		docs = append(docs, &schema.Document{
+			ID:      fmt.Sprintf("%s-chunk-%d", documentID, i+1),
+			Content: content,
+			MetaData: map[string]interface{}{
+				common.KnowledgeId: knowledgeID,
+				common.DocumentId:  documentID,
+			},
+		})
+	}
+	return docs
+}
+
+// Embeddings 用 HashEmbedding 计算 chunk 的向量，可直接传给 InsertVectors
+func Embeddings(docs []*schema.Document, dim int) [][]float32 {
+	vectors := make([][]float32, 0, len(docs))
+	for _, doc := range docs {
+		vectors = append(vectors, HashEmbedding(doc.Content, dim))
+	}
+	return vectors
+}
+
+// RefundPolicyDocuments 一组退款政策文档，覆盖不同年份和主题，可用于检索、多跳拆分和引用测试
+func RefundPolicyDocuments(knowledgeID string) []*schema.Document {
+	return Documents(knowledgeID, "refund-policy",
+		"2023 年退款政策：购买后 7 天内可无理由退款，退款在 5 个工作日内原路返回。",
+		"2024 年退款政策：购买后 14 天内可无理由退款，会员退款在 3 个工作日内到账。",
+		"发票说明：电子发票在付款成功后 24 小时内发送到预留邮箱。",
+		"Shipping policy: orders ship within 2 business days and tracking numbers are sent by email.",
+	)
+}
@@ -0,0 +1,209 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// MCPSessionID 假 MCP 服务在 initialize 响应中下发的会话ID
+const MCPSessionID = "testkit-session"
+
+// MCPToolHandler 工具实现，返回的错误作为 isError 结果返回给调用方
+type MCPToolHandler func(arguments map[string]any) (string, error)
+
+// MCPCall 一次工具调用记录
+type MCPCall struct {
+	Tool      string
+	Arguments map[string]any
+}
+
+// MCPServer HTTP（Streamable HTTP）传输的假 MCP 服务，支持 initialize、ping、tools/list 和 tools/call，
+// 响应以 SSE 格式返回。Endpoint 可直接作为 MCP 服务注册的地址（不含 /sse，kbgo 使用 HTTP 模式连接）
+type MCPServer struct {
+	// Endpoint MCP 服务地址
+	Endpoint string
+
+	server  *httptest.Server
+	mu      sync.Mutex
+	tools   map[string]mcpTool
+	order   []string
+	calls   []MCPCall
+	methods []string
+}
+
+type mcpTool struct {
+	description string
+	inputSchema map[string]any
+	handler     MCPToolHandler
+}
+
+// NewMCPServer 启动假 MCP 服务，测试结束时自动关闭
+func NewMCPServer(t testing.TB) *MCPServer {
+	t.Helper()
+	s := &MCPServer{tools: make(map[string]mcpTool)}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.Endpoint = s.server.URL + "/mcp"
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// AddTool 注册或替换工具，inputSchema 为 nil 时使用无参数的 object 定义
+func (s *MCPServer) AddTool(name, description string, inputSchema map[string]any, handler MCPToolHandler) {
+	if inputSchema == nil {
+		inputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tools[name]; !ok {
+		s.order = append(s.order, name)
+	}
+	s.tools[name] = mcpTool{description: description, inputSchema: inputSchema, handler: handler}
+}
+
+// RemoveTool 移除工具，用于模拟服务端下线工具
+func (s *MCPServer) RemoveTool(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tools, name)
+	for i, n := range s.order {
+		if n == name {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Calls 返回所有工具调用记录
+func (s *MCPServer) Calls() []MCPCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]MCPCall(nil), s.calls...)
+}
+
+// Methods 返回收到的所有 JSON-RPC 方法名（包括通知）
+func (s *MCPServer) Methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.methods...)
+}
+
+type jsonRPCRequest struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      any             `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *MCPServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req jsonRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeMessage(w, nil, nil, &jsonRPCError{Code: -32700, Message: "parse error: " + err.Error()})
+		return
+	}
+	s.mu.Lock()
+	s.methods = append(s.methods, req.Method)
+	s.mu.Unlock()
+
+	// 通知没有 id，不需要响应
+	if req.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	switch req.Method {
+	case "initialize":
+		w.Header().Set("mcp-session-id", MCPSessionID)
+		s.writeMessage(w, req.ID, map[string]any{
+			"protocolVersion": "2024-11-05",
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "testkit", "version": "1.0.0"},
+		}, nil)
+	case "ping":
+		s.writeMessage(w, req.ID, map[string]any{}, nil)
+	case "tools/list":
+		s.writeMessage(w, req.ID, map[string]any{"tools": s.listTools()}, nil)
+	case "tools/call":
+		result, rpcErr := s.callTool(req.Params)
+		s.writeMessage(w, req.ID, result, rpcErr)
+	default:
+		s.writeMessage(w, req.ID, nil, &jsonRPCError{Code: -32601, Message: "method not found: " + req.Method})
+	}
+}
+
+func (s *MCPServer) listTools() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tools := make([]map[string]any, 0, len(s.order))
+	for _, name := range s.order {
+		tool := s.tools[name]
+		tools = append(tools, map[string]any{
+			"name":        name,
+			"description": tool.description,
+			"inputSchema": tool.inputSchema,
+		})
+	}
+	return tools
+}
+
+// callTool 调用工具，工具不存在时返回 JSON-RPC 错误，工具返回错误时返回 isError 结果
+func (s *MCPServer) callTool(raw json.RawMessage) (any, *jsonRPCError) {
+	var params struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &jsonRPCError{Code: -32602, Message: "invalid params: " + err.Error()}
+	}
+	s.mu.Lock()
+	tool, ok := s.tools[params.Name]
+	s.calls = append(s.calls, MCPCall{Tool: params.Name, Arguments: params.Arguments})
+	s.mu.Unlock()
+	if !ok {
+		return nil, &jsonRPCError{Code: -32602, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+	}
+
+	text, err := "", error(nil)
+	if tool.handler != nil {
+		text, err = tool.handler(params.Arguments)
+	}
+	if err != nil {
+		return map[string]any{"content": []map[string]any{{"type": "text", "text": err.Error()}}, "isError": true}, nil
+	}
+	return map[string]any{"content": []map[string]any{{"type": "text", "text": text}}}, nil
+}
+
+// writeMessage 以 SSE 事件返回一条 JSON-RPC 响应
+func (s *MCPServer) writeMessage(w http.ResponseWriter, id any, result any, rpcErr *jsonRPCError) {
+	resp := map[string]any{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	data, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
+
+// EchoTool 以 JSON 返回收到的参数（键按字母排序），便于断言模型传给工具的参数
+func EchoTool(arguments map[string]any) (string, error) {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,357 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/sashabaranov/go-openai"
+)
+
+// streamChunkRunes 流式回答每个分片的字符数，让调用方的分片拼接逻辑得到覆盖
+const streamChunkRunes = 8
+
+// Reply 假模型服务对一次对话请求的回答
+type Reply struct {
+	Content          string
+	ReasoningContent string
+	ToolCalls        []ToolCallReply
+	// Status 大于 0 时返回该 HTTP 状态码和 Error 作为错误信息，用于模拟服务商故障
+	Status int
+	Error  string
+}
+
+// ToolCallReply 回答中的一个工具调用
+type ToolCallReply struct {
+	ID        string // 为空时自动生成 call_N
+	Name      string
+	Arguments any // 字符串原样返回，其他值序列化为 JSON
+}
+
+// Text 返回文本回答
+func Text(content string) Reply {
+	return Reply{Content: content}
+}
+
+// Call 构造一个工具调用
+func Call(name string, arguments any) ToolCallReply {
+	return ToolCallReply{Name: name, Arguments: arguments}
+}
+
+// ToolCalls 返回只包含工具调用的回答
+func ToolCalls(calls ...ToolCallReply) Reply {
+	return Reply{ToolCalls: calls}
+}
+
+// Failure 返回 HTTP 错误
+func Failure(status int, message string) Reply {
+	return Reply{Status: status, Error: message}
+}
+
+// ModelServer OpenAI 兼容的假模型服务：
+// /chat/completions 按 Script 的顺序依次返回回答（支持流式和非流式），脚本用完后交给 Responder，两者都没有时返回 500；
+// /embeddings 返回 HashEmbedding 向量，与 FakeVectorStore 的默认向量一致
+type ModelServer struct {
+	// URL 模型服务的 Base URL（包含 /v1），可直接用作 ModelConfig.BaseURL
+	URL string
+	// EmbeddingDim 请求未指定 dimensions 时的向量维度
+	EmbeddingDim int
+
+	server     *httptest.Server
+	mu         sync.Mutex
+	replies    []Reply
+	responder  func(req openai.ChatCompletionRequest) Reply
+	requests   []openai.ChatCompletionRequest
+	embeddings []string
+	nextCallID int
+}
+
+// NewModelServer 启动假模型服务，测试结束时自动关闭
+func NewModelServer(t testing.TB) *ModelServer {
+	t.Helper()
+	s := &ModelServer{EmbeddingDim: DefaultEmbeddingDim}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL + "/v1"
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Script 追加按顺序返回的回答
+func (s *ModelServer) Script(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// SetResponder 设置脚本用完后根据请求生成回答的函数
+func (s *ModelServer) SetResponder(fn func(req openai.ChatCompletionRequest) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = fn
+}
+
+// Pending 返回尚未使用的脚本回答数量，可用于断言模型被调用了预期的次数
+func (s *ModelServer) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.replies)
+}
+
+// Requests 返回收到的所有对话请求
+func (s *ModelServer) Requests() []openai.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), s.requests...)
+}
+
+// EmbeddingInputs 返回所有向量化请求的输入文本
+func (s *ModelServer) EmbeddingInputs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.embeddings...)
+}
+
+// RegisterModel 把指向假模型服务的模型注册到 model.Registry，测试结束时移除
+// 模型名称与 modelID 相同，向量化模型的 Extra 中记录 dimension
+func (s *ModelServer) RegisterModel(t testing.TB, modelID string, modelType model.ModelType) *model.ModelConfig {
+	t.Helper()
+	mc := &model.ModelConfig{
+		ModelID:  modelID,
+		Name:     modelID,
+		Type:     modelType,
+		Provider: "testkit",
+		BaseURL:  s.URL,
+		APIKey:   "testkit",
+	}
+	if modelType == model.ModelTypeEmbedding {
+		mc.Extra = map[string]any{"dimension": s.EmbeddingDim}
+	}
+	model.Registry.Register(mc)
+	t.Cleanup(func() {
+		model.Registry.Unregister(modelID)
+	})
+	return mc
+}
+
+func (s *ModelServer) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		s.handleChat(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/embeddings"):
+		s.handleEmbeddings(w, r)
+	default:
+		writeAPIError(w, http.StatusNotFound, "testkit: unsupported endpoint "+r.URL.Path)
+	}
+}
+
+func (s *ModelServer) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "testkit: invalid chat request: "+err.Error())
+		return
+	}
+	reply, ok := s.nextReply(req)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "testkit: no scripted reply left")
+		return
+	}
+	if reply.Status > 0 {
+		writeAPIError(w, reply.Status, reply.Error)
+		return
+	}
+
+	message := openai.ChatCompletionMessage{
+		Role:             openai.ChatMessageRoleAssistant,
+		Content:          reply.Content,
+		ReasoningContent: reply.ReasoningContent,
+		ToolCalls:        s.toolCalls(reply.ToolCalls),
+	}
+	finishReason := openai.FinishReasonStop
+	if len(message.ToolCalls) > 0 {
+		finishReason = openai.FinishReasonToolCalls
+	}
+	usage := openai.Usage{PromptTokens: promptTokens(req.Messages), CompletionTokens: len(Tokenize(reply.Content))}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	if req.Stream {
+		s.writeStream(w, req, message, finishReason, usage)
+		return
+	}
+	writeJSON(w, http.StatusOK, openai.ChatCompletionResponse{
+		ID:      "chatcmpl-testkit",
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []openai.ChatCompletionChoice{{Index: 0, Message: message, FinishReason: finishReason}},
+		Usage:   usage,
+	})
+}
+
+// nextReply 记录请求并取出下一条回答
+func (s *ModelServer) nextReply(req openai.ChatCompletionRequest) (Reply, bool) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if len(s.replies) > 0 {
+		reply := s.replies[0]
+		s.replies = s.replies[1:]
+		s.mu.Unlock()
+		return reply, true
+	}
+	responder := s.responder
+	s.mu.Unlock()
+	if responder == nil {
+		return Reply{}, false
+	}
+	return responder(req), true
+}
+
+// toolCalls 转换为 OpenAI 格式，补全缺失的调用ID
+func (s *ModelServer) toolCalls(calls []ToolCallReply) []openai.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]openai.ToolCall, 0, len(calls))
+	for i, call := range calls {
+		id := call.ID
+		if id == "" {
+			s.nextCallID++
+			id = fmt.Sprintf("call_%d", s.nextCallID)
+		}
+		arguments, ok := call.Arguments.(string)
+		if !ok {
+			data, _ := json.Marshal(call.Arguments)
+			arguments = string(data)
+		}
+		result = append(result, openai.ToolCall{
+			Index:    ptr(i),
+			ID:       id,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: call.Name, Arguments: arguments},
+		})
+	}
+	return result
+}
+
+// writeStream 以 SSE 分片返回回答：思考过程、按 streamChunkRunes 切分的内容、工具调用、结束原因，请求要求时最后返回用量
+func (s *ModelServer) writeStream(w http.ResponseWriter, req openai.ChatCompletionRequest, message openai.ChatCompletionMessage, finishReason openai.FinishReason, usage openai.Usage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	send := func(choices []openai.ChatCompletionStreamChoice, usage *openai.Usage) {
+		data, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+			ID:      "chatcmpl-testkit",
+			Object:  "chat.completion.chunk",
+			Created: time.Now().Unix(),
+			Model:   req.Model,
+			Choices: choices,
+			Usage:   usage,
+		})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	delta := func(d openai.ChatCompletionStreamChoiceDelta) {
+		send([]openai.ChatCompletionStreamChoice{{Index: 0, Delta: d}}, nil)
+	}
+
+	delta(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant, ReasoningContent: message.ReasoningContent})
+	content := []rune(message.Content)
+	for start := 0; start < len(content); start += streamChunkRunes {
+		end := min(start+streamChunkRunes, len(content))
+		delta(openai.ChatCompletionStreamChoiceDelta{Content: string(content[start:end])})
+	}
+	for _, call := range message.ToolCalls {
+		delta(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{call}})
+	}
+	send([]openai.ChatCompletionStreamChoice{{Index: 0, FinishReason: finishReason}}, nil)
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		send([]openai.ChatCompletionStreamChoice{}, &usage)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (s *ModelServer) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input      json.RawMessage `json:"input"`
+		Model      string          `json:"model"`
+		Dimensions int             `json:"dimensions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "testkit: invalid embedding request: "+err.Error())
+		return
+	}
+	// input 可以是单个字符串或字符串数组
+	var inputs []string
+	if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		var input string
+		if err = json.Unmarshal(req.Input, &input); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "testkit: input must be a string or an array of strings")
+			return
+		}
+		inputs = []string{input}
+	}
+	dim := req.Dimensions
+	if dim <= 0 {
+		dim = s.EmbeddingDim
+	}
+
+	s.mu.Lock()
+	s.embeddings = append(s.embeddings, inputs...)
+	s.mu.Unlock()
+
+	type embedding struct {
+		Object    string    `json:"object"`
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	}
+	data := make([]embedding, 0, len(inputs))
+	tokens := 0
+	for i, input := range inputs {
+		data = append(data, embedding{Object: "embedding", Index: i, Embedding: HashEmbedding(input, dim)})
+		tokens += len(Tokenize(input))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"model":  req.Model,
+		"data":   data,
+		"usage":  map[string]int{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+}
+
+// promptTokens 按 Tokenize 粗略估算输入用量
+func promptTokens(messages []openai.ChatCompletionMessage) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += len(Tokenize(msg.Content))
+		for _, part := range msg.MultiContent {
+			tokens += len(Tokenize(part.Text))
+		}
+	}
+	return tokens
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError 按 OpenAI 错误格式返回，go-openai 会解析为 *openai.APIError
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"message": message, "type": "testkit_error"},
+	})
+}
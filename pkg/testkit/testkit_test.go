@@ -0,0 +1,214 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/model"
+	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/internal/mcp/client"
+	gormModel "github.com/Malowking/kbgo/internal/model/gorm"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestFakeVectorStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFakeVectorStore()
+	if err := store.CreateCollection(ctx, "kb"); err != nil {
+		t.Fatal(err)
+	}
+	docs := append(RefundPolicyDocuments("kb1"), Documents("kb2", "other", "2024 年退款政策（另一个知识库）")...)
+	if _, err := store.InsertVectors(ctx, "kb", docs, Embeddings(docs, DefaultEmbeddingDim)); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := store.VectorSearchOnly(ctx, nil, "2024 年退款政策", "kb", 2, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Score < results[1].Score {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	r, err := store.NewRetriever(ctx, nil, "kb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = r.Retrieve(ctx, "2024 年退款政策", vector_store.WithKnowledgeID("kb1"), vector_store.WithTopK(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != "refund-policy-chunk-2" {
+		t.Fatalf("expected the 2024 policy chunk, got %+v", results)
+	}
+
+	keyword, err := store.KeywordSearch(ctx, "kb", "tracking email", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyword) != 1 || keyword[0].ID != "refund-policy-chunk-4" || keyword[0].Score != 2 {
+		t.Fatalf("unexpected keyword results: %+v", keyword)
+	}
+
+	if err = store.DeleteByDocumentID(ctx, "kb", "refund-policy"); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Count("kb"); got != 1 {
+		t.Fatalf("expected 1 chunk after delete, got %d", got)
+	}
+
+	failure := errors.New("store down")
+	store.SetError(failure)
+	if _, err = store.KeywordSearch(ctx, "kb", "x", 1); !errors.Is(err, failure) {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+}
+
+func TestFakeVectorStoreSparse(t *testing.T) {
+	ctx := context.Background()
+	store := NewFakeVectorStore()
+	docs := Documents("kb", "d", "a", "b")
+	if err := store.CreateCollection(ctx, "plain"); err != nil {
+		t.Fatal(err)
+	}
+	sparse := []common.SparseVector{{Indices: []uint32{1}, Values: []float32{1}}, {Indices: []uint32{1, 2}, Values: []float32{2, 1}}}
+	if _, err := store.InsertHybridVectors(ctx, "plain", docs, Embeddings(docs, DefaultEmbeddingDim), sparse); err == nil {
+		t.Fatal("expected error inserting sparse vectors into a dense collection")
+	}
+	if err := store.CreateHybridCollection(ctx, "hybrid", DefaultEmbeddingDim); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.InsertHybridVectors(ctx, "hybrid", docs, Embeddings(docs, DefaultEmbeddingDim), sparse); err != nil {
+		t.Fatal(err)
+	}
+	results, err := store.SparseSearch(ctx, "hybrid", common.SparseVector{Indices: []uint32{1}, Values: []float32{1}}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "d-chunk-2" || results[0].Score != 2 {
+		t.Fatalf("unexpected sparse results: %+v", results)
+	}
+}
+
+func TestModelServer(t *testing.T) {
+	ctx := context.Background()
+	server := NewModelServer(t)
+	mc := server.RegisterModel(t, "test-llm", model.ModelTypeLLM)
+	if model.Registry.Get("test-llm") != mc {
+		t.Fatal("model not registered")
+	}
+	server.Script(
+		ToolCalls(Call("search", map[string]any{"query": "退款"})),
+		Text("根据 2024 年政策，14 天内可以退款。"),
+		Failure(429, "rate limited"),
+	)
+
+	resp, err := mc.Client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:    mc.Name,
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "怎么退款"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "search" || calls[0].Function.Arguments != `{"query":"退款"}` || calls[0].ID != "call_1" {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+
+	stream, err := mc.Client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{Model: mc.Name, Stream: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content strings.Builder
+	for {
+		chunk, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		if recvErr != nil {
+			t.Fatal(recvErr)
+		}
+		if len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	stream.Close()
+	if content.String() != "根据 2024 年政策，14 天内可以退款。" {
+		t.Fatalf("unexpected streamed content: %q", content.String())
+	}
+
+	_, err = mc.Client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{Model: mc.Name})
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != 429 {
+		t.Fatalf("expected scripted 429, got %v", err)
+	}
+	if _, err = mc.Client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{Model: mc.Name}); err == nil {
+		t.Fatal("expected error once the script is exhausted")
+	}
+	if got := len(server.Requests()); got != 4 || server.Pending() != 0 {
+		t.Fatalf("expected 4 recorded requests, got %d (pending %d)", got, server.Pending())
+	}
+
+	embeddings, err := mc.Client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{Input: []string{"退款政策"}, Model: "embed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cosine(embeddings.Data[0].Embedding, HashEmbedding("退款政策", DefaultEmbeddingDim)) < 0.999 {
+		t.Fatal("embedding endpoint should match HashEmbedding")
+	}
+}
+
+func TestMCPServer(t *testing.T) {
+	ctx := context.Background()
+	server := NewMCPServer(t)
+	server.AddTool("echo", "返回参数", nil, EchoTool)
+	server.AddTool("fail", "总是失败", nil, func(map[string]any) (string, error) {
+		return "", errors.New("boom")
+	})
+
+	mcpClient := client.NewMCPClient(&gormModel.MCPRegistry{Endpoint: server.Endpoint})
+	if err := mcpClient.Initialize(ctx, map[string]interface{}{"name": "test"}); err != nil {
+		t.Fatal(err)
+	}
+	tools, err := mcpClient.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+
+	result, err := mcpClient.CallTool(ctx, "echo", map[string]interface{}{"b": 1, "a": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || result.Content[0].Text != `{"a":"x","b":1}` {
+		t.Fatalf("unexpected echo result: %+v", result)
+	}
+	if result, err = mcpClient.CallTool(ctx, "fail", nil); err != nil || !result.IsError {
+		t.Fatalf("expected isError result, got %+v, %v", result, err)
+	}
+
+	server.RemoveTool("fail")
+	if tools, err = mcpClient.ListTools(ctx); err != nil || len(tools) != 1 {
+		t.Fatalf("expected 1 tool after removal, got %+v, %v", tools, err)
+	}
+	if calls := server.Calls(); len(calls) != 2 || calls[1].Tool != "fail" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	messages := WithSystem("你是客服", append(Conversation("你好", "您好，有什么可以帮您？", "怎么退款"),
+		ToolExchange("call_1", "search", map[string]any{"query": "退款"}, "7 天内可退款")...))
+	if len(messages) != 6 || messages[0].Role != "system" || messages[2].Role != "assistant" || messages[3].Role != "user" {
+		t.Fatalf("unexpected conversation: %+v", messages)
+	}
+	if messages[4].ToolCalls[0].Function.Arguments != `{"query":"退款"}` || messages[5].ToolCallID != "call_1" {
+		t.Fatalf("unexpected tool exchange: %+v %+v", messages[4], messages[5])
+	}
+}
@@ -0,0 +1,396 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Malowking/kbgo/core/common"
+	"github.com/Malowking/kbgo/core/vector_store"
+	"github.com/Malowking/kbgo/pkg/schema"
+)
+
+var _ vector_store.VectorStore = (*FakeVectorStore)(nil)
+
+// FakeVectorStore 内存向量库，集合、写入、删除和三种检索方式的行为与真实向量库一致：
+// 检索时用 Embed 向量化查询并按余弦相似度排序，支持 WithTopK、WithScoreThreshold 和 WithKnowledgeID（Filter 表达式会被忽略）
+type FakeVectorStore struct {
+	// Dim CreateCollection 使用的向量维度
+	Dim int
+	// Embed 检索时向量化查询文本，默认使用 HashEmbedding，需与写入向量的模型一致
+	Embed func(ctx context.Context, text string) ([]float32, error)
+
+	mu          sync.RWMutex
+	collections map[string]*fakeCollection
+	err         error
+	nextID      int
+}
+
+type fakeCollection struct {
+	dim    int
+	hybrid bool
+	points []*fakePoint
+}
+
+type fakePoint struct {
+	doc    *schema.Document
+	vector []float32
+	sparse common.SparseVector
+}
+
+// NewFakeVectorStore 创建向量维度为 DefaultEmbeddingDim、使用 HashEmbedding 的内存向量库
+func NewFakeVectorStore() *FakeVectorStore {
+	return &FakeVectorStore{
+		Dim: DefaultEmbeddingDim,
+		Embed: func(_ context.Context, text string) ([]float32, error) {
+			return HashEmbedding(text, DefaultEmbeddingDim), nil
+		},
+		collections: make(map[string]*fakeCollection),
+	}
+}
+
+// SetError 之后的所有操作都返回 err，用于模拟向量库故障；传入 nil 恢复正常
+func (s *FakeVectorStore) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Count 返回集合中的 chunk 数量，集合不存在时返回 0
+func (s *FakeVectorStore) Count(collectionName string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c, ok := s.collections[collectionName]; ok {
+		return len(c.points)
+	}
+	return 0
+}
+
+// Documents 按写入顺序返回集合中的 chunk
+func (s *FakeVectorStore) Documents(collectionName string) []*schema.Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.collections[collectionName]
+	if !ok {
+		return nil
+	}
+	docs := make([]*schema.Document, 0, len(c.points))
+	for _, p := range c.points {
+		docs = append(docs, cloneDocument(p.doc, 0))
+	}
+	return docs
+}
+
+// Collections 返回所有集合名称（按名称排序）
+func (s *FakeVectorStore) Collections() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.collections))
+	for name := range s.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateCollection 使用 Dim 创建集合
+func (s *FakeVectorStore) CreateCollection(ctx context.Context, collectionName string) error {
+	return s.CreateCollectionWithDim(ctx, collectionName, s.Dim)
+}
+
+// CreateCollectionWithDim 使用指定向量维度创建集合，集合已存在时不做任何操作
+func (s *FakeVectorStore) CreateCollectionWithDim(_ context.Context, collectionName string, dim int) error {
+	return s.createCollection(collectionName, dim, false)
+}
+
+// CreateHybridCollection 创建可写入稀疏向量的集合
+func (s *FakeVectorStore) CreateHybridCollection(_ context.Context, collectionName string, dim int) error {
+	return s.createCollection(collectionName, dim, true)
+}
+
+func (s *FakeVectorStore) createCollection(collectionName string, dim int, hybrid bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if collectionName == "" {
+		return fmt.Errorf("collection name cannot be empty")
+	}
+	if dim <= 0 {
+		return fmt.Errorf("invalid vector dimension: %d", dim)
+	}
+	if _, ok := s.collections[collectionName]; !ok {
+		s.collections[collectionName] = &fakeCollection{dim: dim, hybrid: hybrid}
+	}
+	return nil
+}
+
+// CollectionExists 检查集合是否存在
+func (s *FakeVectorStore) CollectionExists(_ context.Context, collectionName string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	_, ok := s.collections[collectionName]
+	return ok, nil
+}
+
+// DeleteCollection 删除集合，集合不存在时不报错
+func (s *FakeVectorStore) DeleteCollection(_ context.Context, collectionName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	delete(s.collections, collectionName)
+	return nil
+}
+
+// CreateDatabaseIfNotExists 内存向量库不区分数据库
+func (s *FakeVectorStore) CreateDatabaseIfNotExists(context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+// GetClient 返回向量库本身
+func (s *FakeVectorStore) GetClient() interface{} {
+	return s
+}
+
+// InsertVectors 写入 chunk 和向量，chunk 没有 ID 时自动生成
+func (s *FakeVectorStore) InsertVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32) ([]string, error) {
+	return s.insert(collectionName, chunks, vectors, nil)
+}
+
+// InsertHybridVectors 同时写入稠密向量和稀疏向量，集合需由 CreateHybridCollection 创建
+func (s *FakeVectorStore) InsertHybridVectors(ctx context.Context, collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	if len(sparseVectors) != len(chunks) {
+		return nil, fmt.Errorf("sparse vectors count %d does not match chunks count %d", len(sparseVectors), len(chunks))
+	}
+	return s.insert(collectionName, chunks, vectors, sparseVectors)
+}
+
+func (s *FakeVectorStore) insert(collectionName string, chunks []*schema.Document, vectors [][]float32, sparseVectors []common.SparseVector) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	c, ok := s.collections[collectionName]
+	if !ok {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+	if sparseVectors != nil && !c.hybrid {
+		return nil, fmt.Errorf("collection '%s' does not support sparse vectors", collectionName)
+	}
+	if len(vectors) != len(chunks) {
+		return nil, fmt.Errorf("vectors count %d does not match chunks count %d", len(vectors), len(chunks))
+	}
+	ids := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		if len(vectors[i]) != c.dim {
+			return nil, fmt.Errorf("vector dimension %d does not match collection dimension %d", len(vectors[i]), c.dim)
+		}
+		doc := cloneDocument(chunk, 0)
+		if doc.ID == "" {
+			s.nextID++
+			doc.ID = fmt.Sprintf("chunk-%d", s.nextID)
+		}
+		point := &fakePoint{doc: doc, vector: append([]float32(nil), vectors[i]...)}
+		if sparseVectors != nil {
+			point.sparse = sparseVectors[i]
+		}
+		// 相同 ID 覆盖写入
+		replaced := false
+		for j, existing := range c.points {
+			if existing.doc.ID == doc.ID {
+				c.points[j] = point
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.points = append(c.points, point)
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, nil
+}
+
+// DeleteByDocumentID 删除元数据 document_id 等于 documentID 的所有 chunk
+func (s *FakeVectorStore) DeleteByDocumentID(_ context.Context, collectionName string, documentID string) error {
+	return s.deleteWhere(collectionName, func(doc *schema.Document) bool {
+		return fmt.Sprint(doc.MetaData[common.DocumentId]) == documentID
+	})
+}
+
+// DeleteByChunkID 删除单个 chunk
+func (s *FakeVectorStore) DeleteByChunkID(_ context.Context, collectionName string, chunkID string) error {
+	return s.deleteWhere(collectionName, func(doc *schema.Document) bool {
+		return doc.ID == chunkID
+	})
+}
+
+func (s *FakeVectorStore) deleteWhere(collectionName string, match func(doc *schema.Document) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	c, ok := s.collections[collectionName]
+	if !ok {
+		return fmt.Errorf("collection '%s' not found", collectionName)
+	}
+	kept := c.points[:0]
+	for _, p := range c.points {
+		if !match(p.doc) {
+			kept = append(kept, p)
+		}
+	}
+	c.points = kept
+	return nil
+}
+
+// NewRetriever 创建集合的检索器，conf 实现 GeneralRetrieverConfig 时用其 TopK 和 Score 作为默认值
+func (s *FakeVectorStore) NewRetriever(ctx context.Context, conf interface{}, collectionName string) (vector_store.Retriever, error) {
+	if collectionName == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+	exists, err := s.CollectionExists(ctx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+	r := &fakeRetriever{store: s, collectionName: collectionName}
+	if c, ok := conf.(vector_store.GeneralRetrieverConfig); ok {
+		r.defaults.TopK, r.defaults.ScoreThreshold = ptr(c.GetTopK()), ptr(c.GetScore())
+	}
+	return r, nil
+}
+
+// VectorSearchOnly 仅使用向量检索，与 Qdrant 一样以 knowledgeId 作为集合名称
+func (s *FakeVectorStore) VectorSearchOnly(ctx context.Context, conf vector_store.GeneralRetrieverConfig, query string, knowledgeId string, topK int, score float64) ([]*schema.Document, error) {
+	r, err := s.NewRetriever(ctx, conf, knowledgeId)
+	if err != nil {
+		return nil, err
+	}
+	return r.Retrieve(ctx, query, vector_store.WithTopK(topK), vector_store.WithScoreThreshold(score))
+}
+
+// SparseSearch 按稀疏向量内积降序返回文档
+func (s *FakeVectorStore) SparseSearch(_ context.Context, collectionName string, query common.SparseVector, topK int, opts ...vector_store.Option) ([]*schema.Document, error) {
+	weights := make(map[uint32]float32, len(query.Indices))
+	for i, index := range query.Indices {
+		weights[index] += query.Values[i]
+	}
+	return s.search(collectionName, topK, vector_store.GetCommonOptions(nil, opts...), func(p *fakePoint) float64 {
+		var score float64
+		for i, index := range p.sparse.Indices {
+			score += float64(weights[index] * p.sparse.Values[i])
+		}
+		return score
+	})
+}
+
+// KeywordSearch 按查询词在内容中出现的次数降序返回文档
+func (s *FakeVectorStore) KeywordSearch(_ context.Context, collectionName string, query string, topK int, opts ...vector_store.Option) ([]*schema.Document, error) {
+	terms := make(map[string]bool)
+	for _, token := range Tokenize(query) {
+		terms[token] = true
+	}
+	return s.search(collectionName, topK, vector_store.GetCommonOptions(nil, opts...), func(p *fakePoint) float64 {
+		var score float64
+		for _, token := range Tokenize(p.doc.Content) {
+			if terms[token] {
+				score++
+			}
+		}
+		return score
+	})
+}
+
+// search 对集合中的 chunk 打分，过滤知识库和分数（未设置阈值时丢弃不大于 0 的结果）后按分数降序返回前 topK 个
+func (s *FakeVectorStore) search(collectionName string, topK int, options *vector_store.Options, score func(p *fakePoint) float64) ([]*schema.Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	c, ok := s.collections[collectionName]
+	if !ok {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+	var docs []*schema.Document
+	for _, p := range c.points {
+		if options.KnowledgeID != "" && fmt.Sprint(p.doc.MetaData[common.KnowledgeId]) != options.KnowledgeID {
+			continue
+		}
+		value := score(p)
+		if options.ScoreThreshold != nil && value < *options.ScoreThreshold || options.ScoreThreshold == nil && value <= 0 {
+			continue
+		}
+		docs = append(docs, cloneDocument(p.doc, float32(value)))
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+	if topK > 0 && len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// fakeRetriever FakeVectorStore 的稠密向量检索器
+type fakeRetriever struct {
+	store          *FakeVectorStore
+	collectionName string
+	defaults       vector_store.Options
+}
+
+// Retrieve 向量化查询后按余弦相似度降序返回文档
+func (r *fakeRetriever) Retrieve(ctx context.Context, query string, opts ...vector_store.Option) ([]*schema.Document, error) {
+	options := vector_store.GetCommonOptions(&r.defaults, opts...)
+	queryVector, err := r.store.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	topK := 0
+	if options.TopK != nil {
+		topK = *options.TopK
+	}
+	return r.store.search(r.collectionName, topK, options, func(p *fakePoint) float64 {
+		return cosine(queryVector, p.vector)
+	})
+}
+
+// GetType 返回检索器类型
+func (r *fakeRetriever) GetType() string {
+	return "fake"
+}
+
+// IsCallbacksEnabled 假检索器不触发回调
+func (r *fakeRetriever) IsCallbacksEnabled() bool {
+	return false
+}
+
+// cloneDocument 复制文档和元数据，避免测试修改返回值影响库中的数据
+func cloneDocument(doc *schema.Document, score float32) *schema.Document {
+	clone := &schema.Document{ID: doc.ID, Content: doc.Content, Score: score}
+	if doc.MetaData != nil {
+		clone.MetaData = make(map[string]interface{}, len(doc.MetaData))
+		for k, v := range doc.MetaData {
+			clone.MetaData[k] = v
+		}
+	}
+	return clone
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}